@@ -0,0 +1,44 @@
+// Package admin serves aws-in-a-box's own API, for inspecting and controlling the emulator
+// from tests, rather than emulating an AWS API.
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// PathPrefix is the prefix of all admin API paths.
+// S3 bucket names can't start with an underscore, so these never clash with path-style S3 requests.
+const PathPrefix = "/_admin/"
+
+// Registry maps paths, relative to PathPrefix, to their handlers. For example "sns/messages".
+type Registry = map[string]http.HandlerFunc
+
+// NewHandler returns a handler for the server's handler chain, which handles admin API requests.
+func NewHandler(registry Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		path, ok := strings.CutPrefix(r.URL.Path, PathPrefix)
+		if !ok {
+			return false
+		}
+		handler, ok := registry[path]
+		if !ok {
+			http.NotFound(w, r)
+			return true
+		}
+		handler(w, r)
+		return true
+	}
+}
+
+// WriteJSON writes the value as a JSON response.
+func WriteJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
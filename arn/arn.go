@@ -0,0 +1,110 @@
+package arn
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ARN is an Amazon Resource Name, split into its parts.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference-arns.html
+type ARN struct {
+	Partition string
+	Service   string
+	// Empty for global services, such as IAM.
+	Region string
+	// Empty for resources which don't belong to an account, such as S3 buckets, and "aws" for
+	// AWS managed IAM policies.
+	AccountId string
+	// The resource, which usually starts with its type, such as stream/name or function:name.
+	Resource string
+}
+
+var partitions = []string{"aws", "aws-cn", "aws-us-gov", "aws-iso", "aws-iso-b", "aws-iso-e", "aws-iso-f", "aws-eusc"}
+
+var (
+	servicePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+	regionPattern  = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+	accountPattern = regexp.MustCompile(`^([0-9]{12}|aws)$`)
+)
+
+// Parse splits the ARN into its parts, and checks each of them is valid.
+func Parse(s string) (ARN, error) {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ARN{}, fmt.Errorf("%q is not an ARN, which are arn:partition:service:region:account:resource", s)
+	}
+	a := ARN{
+		Partition: parts[1],
+		Service:   parts[2],
+		Region:    parts[3],
+		AccountId: parts[4],
+		Resource:  parts[5],
+	}
+	switch {
+	case !slices.Contains(partitions, a.Partition):
+		return ARN{}, fmt.Errorf("ARN %q has an invalid partition %q", s, a.Partition)
+	case !servicePattern.MatchString(a.Service):
+		return ARN{}, fmt.Errorf("ARN %q has an invalid service %q", s, a.Service)
+	case a.Region != "" && !regionPattern.MatchString(a.Region):
+		return ARN{}, fmt.Errorf("ARN %q has an invalid region %q", s, a.Region)
+	case a.AccountId != "" && !accountPattern.MatchString(a.AccountId):
+		return ARN{}, fmt.Errorf("ARN %q has an invalid account %q", s, a.AccountId)
+	case a.Resource == "":
+		return ARN{}, fmt.Errorf("ARN %q has no resource", s)
+	}
+	return a, nil
+}
+
+func (a ARN) String() string {
+	return strings.Join([]string{"arn", a.Partition, a.Service, a.Region, a.AccountId, a.Resource}, ":")
+}
+
+// ResourceType returns the type the resource starts with, before the first / or :, or "" if it
+// doesn't have one, like SQS queues and SNS topics.
+func (a ARN) ResourceType() string {
+	resourceType, _ := a.splitResource()
+	return resourceType
+}
+
+// ResourceId returns the resource after its type, such as the name of a stream, or the whole
+// resource if it doesn't have a type.
+func (a ARN) ResourceId() string {
+	_, id := a.splitResource()
+	return id
+}
+
+func (a ARN) splitResource() (string, string) {
+	i := strings.IndexAny(a.Resource, "/:")
+	if i < 0 {
+		return "", a.Resource
+	}
+	return a.Resource[:i], a.Resource[i+1:]
+}
+
+// Resolve returns the ID of the resource the ARN names, if it's a resource of the service and
+// type which can be one of the emulator's: it must be in the generator's account and region, if
+// the ARN has them. resourceType is empty for services whose ARNs don't have one, like SQS.
+func (g Generator) Resolve(s string, service string, resourceType string) (string, error) {
+	a, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if a.Service != service {
+		return "", fmt.Errorf("ARN %q is for %s, not %s", s, a.Service, service)
+	}
+	if a.AccountId != "" && a.AccountId != g.AwsAccountId {
+		return "", fmt.Errorf("ARN %q is in account %s, not %s", s, a.AccountId, g.AwsAccountId)
+	}
+	if a.Region != "" && a.Region != g.Region {
+		return "", fmt.Errorf("ARN %q is in region %s, not %s", s, a.Region, g.Region)
+	}
+	if resourceType == "" {
+		return a.Resource, nil
+	}
+	if a.ResourceType() != resourceType {
+		return "", fmt.Errorf("ARN %q is not a %s", s, resourceType)
+	}
+	return a.ResourceId(), nil
+}
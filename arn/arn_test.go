@@ -0,0 +1,118 @@
+package arn
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		arn          string
+		want         ARN
+		resourceType string
+		resourceId   string
+	}{
+		{
+			arn:          "arn:aws:kinesis:us-east-1:123456789012:stream/orders",
+			want:         ARN{Partition: "aws", Service: "kinesis", Region: "us-east-1", AccountId: "123456789012", Resource: "stream/orders"},
+			resourceType: "stream",
+			resourceId:   "orders",
+		},
+		{
+			arn:          "arn:aws-cn:lambda:cn-north-1:123456789012:function:handler:live",
+			want:         ARN{Partition: "aws-cn", Service: "lambda", Region: "cn-north-1", AccountId: "123456789012", Resource: "function:handler:live"},
+			resourceType: "function",
+			resourceId:   "handler:live",
+		},
+		{
+			arn:          "arn:aws:iam::123456789012:role/service/deployer",
+			want:         ARN{Partition: "aws", Service: "iam", AccountId: "123456789012", Resource: "role/service/deployer"},
+			resourceType: "role",
+			resourceId:   "service/deployer",
+		},
+		{
+			arn:        "arn:aws:sqs:us-gov-west-1:123456789012:jobs",
+			want:       ARN{Partition: "aws", Service: "sqs", Region: "us-gov-west-1", AccountId: "123456789012", Resource: "jobs"},
+			resourceId: "jobs",
+		},
+		{
+			arn:        "arn:aws:s3:::bucket",
+			want:       ARN{Partition: "aws", Service: "s3", Resource: "bucket"},
+			resourceId: "bucket",
+		},
+		{
+			arn:          "arn:aws:iam::aws:policy/ReadOnlyAccess",
+			want:         ARN{Partition: "aws", Service: "iam", AccountId: "aws", Resource: "policy/ReadOnlyAccess"},
+			resourceType: "policy",
+			resourceId:   "ReadOnlyAccess",
+		},
+	} {
+		got, err := Parse(tc.arn)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.arn, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tc.arn, got, tc.want)
+		}
+		if got.String() != tc.arn {
+			t.Errorf("Parse(%q).String() = %q", tc.arn, got.String())
+		}
+		if got.ResourceType() != tc.resourceType || got.ResourceId() != tc.resourceId {
+			t.Errorf("Parse(%q) has resource %q %q, want %q %q",
+				tc.arn, got.ResourceType(), got.ResourceId(), tc.resourceType, tc.resourceId)
+		}
+	}
+
+	for _, arn := range []string{
+		"",
+		"orders",
+		"arn:aws:kinesis:us-east-1:123456789012",
+		"arm:aws:kinesis:us-east-1:123456789012:stream/orders",
+		"arn:amazon:kinesis:us-east-1:123456789012:stream/orders",
+		"arn:aws:Kinesis:us-east-1:123456789012:stream/orders",
+		"arn:aws::us-east-1:123456789012:stream/orders",
+		"arn:aws:kinesis:useast1:123456789012:stream/orders",
+		"arn:aws:kinesis:us-east-1:1234:stream/orders",
+		"arn:aws:kinesis:us-east-1:123456789012:",
+	} {
+		if _, err := Parse(arn); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", arn)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	g := Generator{AwsAccountId: "123456789012", Region: "us-east-1"}
+	for _, tc := range []struct {
+		arn          string
+		service      string
+		resourceType string
+		want         string
+	}{
+		{g.Generate("kinesis", "stream", "orders"), "kinesis", "stream", "orders"},
+		{g.Generate("glue", "schema", "registry/schema"), "glue", "schema", "registry/schema"},
+		{g.GenerateWithoutType("sqs", "jobs"), "sqs", "", "jobs"},
+		{"arn:aws:iam::123456789012:role/deployer", "iam", "role", "deployer"},
+	} {
+		got, err := g.Resolve(tc.arn, tc.service, tc.resourceType)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tc.arn, err)
+		} else if got != tc.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tc.arn, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		arn          string
+		service      string
+		resourceType string
+	}{
+		{"orders", "kinesis", "stream"},
+		{"arn:aws:kinesis:us-east-1:123456789012:stream/orders", "sqs", ""},
+		{"arn:aws:kinesis:us-east-1:123456789012:stream/orders", "kinesis", "consumer"},
+		{"arn:aws:kinesis:us-east-1:210987654321:stream/orders", "kinesis", "stream"},
+		{"arn:aws:kinesis:eu-west-1:123456789012:stream/orders", "kinesis", "stream"},
+	} {
+		if _, err := g.Resolve(tc.arn, tc.service, tc.resourceType); err == nil {
+			t.Errorf("Expected Resolve(%q, %q, %q) to fail", tc.arn, tc.service, tc.resourceType)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+func TestParseCredentials(t *testing.T) {
+	credentials, err := ParseCredentials("AKIA1:secret1,AKIA2:secret2:111122223333,AKIA3:secret3:111122223333:arn:aws:iam::111122223333:role/ci", "123456789012")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Credential{
+		{AccessKeyId: "AKIA1", SecretAccessKey: "secret1", Account: "123456789012", Principal: "arn:aws:iam::123456789012:root"},
+		{AccessKeyId: "AKIA2", SecretAccessKey: "secret2", Account: "111122223333", Principal: "arn:aws:iam::111122223333:root"},
+		{AccessKeyId: "AKIA3", SecretAccessKey: "secret3", Account: "111122223333", Principal: "arn:aws:iam::111122223333:role/ci"},
+	}
+	if !reflect.DeepEqual(credentials, want) {
+		t.Fatalf("got %+v, want %+v", credentials, want)
+	}
+
+	for _, invalid := range []string{"AKIA1", "AKIA1:", ":secret"} {
+		if _, err := ParseCredentials(invalid, "123456789012"); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestAccessKeyId(t *testing.T) {
+	for _, test := range []struct {
+		authorization string
+		url           string
+		want          string
+	}{
+		{"AWS4-HMAC-SHA256 Credential=AKIA1/20240102/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=abc", "/", "AKIA1"},
+		{"AWS AKIA2:signature", "/", "AKIA2"},
+		{"", "/bucket/key?X-Amz-Credential=AKIA3%2F20240102%2Fus-east-1%2Fs3%2Faws4_request&X-Amz-Signature=abc", "AKIA3"},
+		{"", "/bucket/key?AWSAccessKeyId=AKIA4&Signature=abc", "AKIA4"},
+		{"", "/", ""},
+		{"Bearer token", "/", ""},
+	} {
+		request := httptest.NewRequest(http.MethodGet, test.url, nil)
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		if got := AccessKeyId(request); got != test.want {
+			t.Errorf("AccessKeyId(%q, %q) = %q, want %q", test.authorization, test.url, got, test.want)
+		}
+	}
+}
+
+func signed(request *http.Request, accessKeyId string) *http.Request {
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyId+"/20240102/us-east-1/sqs/aws4_request")
+	return request
+}
+
+func TestAuthenticate(t *testing.T) {
+	// Without any credentials, any access key is accepted.
+	anyKey := New(Options{DefaultAccount: "123456789012"})
+	request, result := anyKey.Authenticate(signed(httptest.NewRequest(http.MethodGet, "/", nil), "AKIAANY"))
+	if caller, ok := Caller(request); result != Accepted || !ok || caller.Principal != "arn:aws:iam::123456789012:root" {
+		t.Fatal("Unexpected caller", result, caller)
+	}
+	if _, result := anyKey.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil)); result != Accepted {
+		t.Fatal("Expected anonymous requests to be accepted", result)
+	}
+
+	strict := New(Options{
+		Credentials:     []Credential{{AccessKeyId: "AKIA1", Account: "111122223333", Principal: "arn:aws:iam::111122223333:role/ci"}},
+		DefaultAccount:  "123456789012",
+		RejectAnonymous: true,
+	})
+	request, result = strict.Authenticate(signed(httptest.NewRequest(http.MethodGet, "/", nil), "AKIA1"))
+	if caller, ok := Caller(request); result != Accepted || !ok || caller.Account != "111122223333" {
+		t.Fatal("Unexpected caller", result, caller)
+	}
+	if _, result := strict.Authenticate(signed(httptest.NewRequest(http.MethodGet, "/", nil), "AKIAOTHER")); result != UnknownAccessKey {
+		t.Fatal("Expected other access keys to be rejected", result)
+	}
+	strict.Add(Credential{AccessKeyId: "ASIA1", Account: "111122223333"})
+	if _, result := strict.Authenticate(signed(httptest.NewRequest(http.MethodGet, "/", nil), "ASIA1")); result != Accepted {
+		t.Fatal("Expected added access keys to be accepted", result)
+	}
+
+	if _, result := strict.Authenticate(httptest.NewRequest(http.MethodGet, "/bucket/key", nil)); result != MissingAuthentication {
+		t.Fatal("Expected anonymous requests to be rejected", result)
+	}
+	unsigned := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	unsigned.Header.Set("X-Amz-Target", "AWSCognitoIdentityProviderService.InitiateAuth")
+	if _, result := strict.Authenticate(unsigned); result != Accepted {
+		t.Fatal("Expected signing in to Cognito to be accepted", result)
+	}
+	for _, target := range []string{
+		"/_admin/calls",
+		"/execute-api/abc123/items",
+		"/lambda-url/abc123/",
+		"http://abc123.execute-api.us-east-1.amazonaws.com/prod",
+	} {
+		if _, result := strict.Authenticate(httptest.NewRequest(http.MethodGet, target, nil)); result != Accepted {
+			t.Fatal("Expected unsigned requests to be accepted", target, result)
+		}
+	}
+	if _, result := strict.Authenticate(httptest.NewRequest(http.MethodGet, "http://my.execute-api.example.com/", nil)); result != MissingAuthentication {
+		t.Fatal("Expected hosts which only contain execute-api to be signed", result)
+	}
+
+	// Only S3 POST uploads, which are made to the bucket, are unsigned multipart POSTs.
+	multipart := func(target string) *http.Request {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+		request.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+		return request
+	}
+	for _, target := range []string{"/bucket", "/bucket/", "http://bucket.s3.us-east-1.amazonaws.com/"} {
+		if _, result := strict.Authenticate(multipart(target)); result != Accepted {
+			t.Fatal("Expected S3 POST uploads to be accepted", target, result)
+		}
+	}
+	for _, target := range []string{"/", "/bucket/key", "http://sqs.us-east-1.amazonaws.com/queue"} {
+		if _, result := strict.Authenticate(multipart(target)); result != MissingAuthentication {
+			t.Fatal("Expected other multipart POSTs to be signed", target, result)
+		}
+	}
+	toDynamoDB := multipart("/table")
+	toDynamoDB.Header.Set("X-Amz-Target", "DynamoDB_20120810.PutItem")
+	if _, result := strict.Authenticate(toDynamoDB); result != MissingAuthentication {
+		t.Fatal("Expected multipart POSTs to JSON services to be signed", result)
+	}
+
+	// The body of a Query request is still there for the handler.
+	form := "Action=AssumeRoleWithWebIdentity&RoleArn=arn"
+	unsigned = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form))
+	unsigned.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request, result = strict.Authenticate(unsigned)
+	if body, _ := io.ReadAll(request.Body); result != Accepted || string(body) != form {
+		t.Fatal("Unexpected result", result, string(body))
+	}
+}
+
+func TestCheckRequestTime(t *testing.T) {
+	a := New(Options{CheckRequestTime: true})
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	withDate := func(date time.Time) *http.Request {
+		request := signed(httptest.NewRequest(http.MethodGet, "/", nil), "AKIA1")
+		request.Header.Set("X-Amz-Date", FormatAmzDate(date))
+		return request
+	}
+	presigned := func(date time.Time, expires int) *http.Request {
+		return httptest.NewRequest(http.MethodGet, fmt.Sprintf(
+			"/bucket/key?X-Amz-Credential=AKIA1%%2F20240102%%2Fus-east-1%%2Fs3%%2Faws4_request&X-Amz-Date=%s&X-Amz-Expires=%d&X-Amz-Signature=abc",
+			FormatAmzDate(date), expires), nil)
+	}
+	v2Presigned := func(expires time.Time) *http.Request {
+		return httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bucket/key?AWSAccessKeyId=AKIA1&Expires=%d&Signature=abc", expires.Unix()), nil)
+	}
+	withHTTPDate := func(date time.Time) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "AWS AKIA1:signature")
+		request.Header.Set("Date", date.Format(http.TimeFormat))
+		return request
+	}
+
+	for _, test := range []struct {
+		name    string
+		request *http.Request
+		want    Result
+	}{
+		{"current", withDate(now), Accepted},
+		{"slightly behind", withDate(now.Add(-14 * time.Minute)), Accepted},
+		{"slightly ahead", withDate(now.Add(14 * time.Minute)), Accepted},
+		{"too far behind", withDate(now.Add(-16 * time.Minute)), RequestTimeTooSkewed},
+		{"too far ahead", withDate(now.Add(16 * time.Minute)), RequestTimeTooSkewed},
+		{"Date header", withHTTPDate(now.Add(-time.Hour)), RequestTimeTooSkewed},
+		{"presigned", presigned(now.Add(-time.Hour), 7200), Accepted},
+		{"presigned expired", presigned(now.Add(-time.Hour), 3600), RequestExpired},
+		{"presigned in the future", presigned(now.Add(time.Hour), 7200), RequestExpired},
+		{"v2 presigned", v2Presigned(now.Add(time.Minute)), Accepted},
+		{"v2 presigned expired", v2Presigned(now.Add(-time.Minute)), RequestExpired},
+		{"no date", signed(httptest.NewRequest(http.MethodGet, "/", nil), "AKIA1"), Accepted},
+	} {
+		if _, result := a.Authenticate(test.request); result != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, result, test.want)
+		}
+	}
+
+	// Request times are only checked when asked to.
+	unchecked := New(Options{})
+	unchecked.now = a.now
+	if _, result := unchecked.Authenticate(withDate(now.Add(-time.Hour))); result != Accepted {
+		t.Fatal("Expected the request to be accepted", result)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	credentials := aws.Credentials{AccessKeyID: "AKIA1", SecretAccessKey: "secret"}
+	now := time.Now()
+	sign := func(method string, url string, body string, service string, headers map[string]string) *http.Request {
+		request, _ := http.NewRequest(method, url, strings.NewReader(body))
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		hash := sha256.Sum256([]byte(body))
+		payloadHash := hex.EncodeToString(hash[:])
+		signer := v4.NewSigner()
+		if service == "s3" {
+			payloadHash = "UNSIGNED-PAYLOAD"
+			request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+			signer = v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+		}
+		if err := signer.SignHTTP(context.Background(), credentials, request, payloadHash, service, "us-east-1", now); err != nil {
+			t.Fatal(err)
+		}
+		return received(request, body)
+	}
+
+	for _, test := range []struct {
+		name    string
+		request *http.Request
+	}{
+		{"S3", sign(http.MethodPut, "http://localhost:4566/bucket/a%20key/b~c?tagging", "data", "s3", nil)},
+		{"S3 virtual host", sign(http.MethodGet, "http://bucket.s3.amazonaws.com/key?list-type=2&prefix=a%2Fb", "", "s3", nil)},
+		{"JSON", sign(http.MethodPost, "http://localhost:4566/", "{}", "kinesis", map[string]string{
+			"X-Amz-Target": "Kinesis_20131202.ListStreams",
+			"Content-Type": "application/x-amz-json-1.1",
+		})},
+		{"Query", sign(http.MethodPost, "http://localhost:4566/", "Action=ListQueues&QueueNamePrefix=a%20b", "sqs", map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		})},
+	} {
+		if err := VerifySignature(test.request, "secret"); err != nil {
+			t.Errorf("%s: %v\n%s\n%s", test.name, err, err.CanonicalRequest, err.StringToSign)
+		}
+		if err := VerifySignature(test.request, "wrong"); err == nil || err.CanonicalRequest == "" {
+			t.Errorf("%s: expected the wrong secret to be rejected", test.name)
+		}
+	}
+
+	// The body is signed, and is still there for the handler.
+	request := sign(http.MethodPost, "http://localhost:4566/", "{}", "kinesis", nil)
+	if err := VerifySignature(request, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(request.Body); string(body) != "{}" {
+		t.Fatal("Unexpected body", string(body))
+	}
+	tampered := sign(http.MethodPost, "http://localhost:4566/", "{}", "kinesis", nil)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"Limit":1}`))
+	if err := VerifySignature(tampered, "secret"); err == nil {
+		t.Fatal("Expected a changed body to be rejected")
+	}
+
+	// Presigned URLs.
+	unsigned, _ := http.NewRequest(http.MethodGet, "http://localhost:4566/bucket/key?versionId=1", nil)
+	presignedURL, _, err := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true }).PresignHTTP(
+		context.Background(), credentials, unsigned, "UNSIGNED-PAYLOAD", "s3", "us-east-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presigned := httptest.NewRequest(http.MethodGet, presignedURL, nil)
+	if err := VerifySignature(presigned, "secret"); err != nil {
+		t.Fatalf("%v\n%s", err, err.CanonicalRequest)
+	}
+	if err := VerifySignature(httptest.NewRequest(http.MethodGet, strings.Replace(presignedURL, "versionId=1", "versionId=2", 1), nil), "secret"); err == nil {
+		t.Fatal("Expected a changed query to be rejected")
+	}
+
+	v2 := httptest.NewRequest(http.MethodGet, "/", nil)
+	v2.Header.Set("Authorization", "AWS AKIA1:signature")
+	if err := VerifySignature(v2, "secret"); err == nil {
+		t.Fatal("Expected signature version 2 to be rejected")
+	}
+
+	// Authenticate only verifies signatures when asked to.
+	a := New(Options{Credentials: []Credential{{AccessKeyId: "AKIA1", SecretAccessKey: "wrong"}}, ValidateSignatures: true})
+	request, result := a.Authenticate(sign(http.MethodPost, "http://localhost:4566/", "{}", "kinesis", nil))
+	if err, ok := SignatureFailure(request); result != InvalidSignature || !ok || err.StringToSign == "" {
+		t.Fatal("Expected the signature to be rejected", result, err)
+	}
+	a = New(Options{Credentials: []Credential{{AccessKeyId: "AKIA1", SecretAccessKey: "wrong"}}})
+	if _, result := a.Authenticate(sign(http.MethodPost, "http://localhost:4566/", "{}", "kinesis", nil)); result != Accepted {
+		t.Fatal("Expected the signature not to be verified", result)
+	}
+}
+
+// received returns the request as the server receives the client's request.
+func received(request *http.Request, body string) *http.Request {
+	r := httptest.NewRequest(request.Method, request.URL.String(), strings.NewReader(body))
+	r.Header = request.Header.Clone()
+	return r
+}
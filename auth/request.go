@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/endpoint"
+)
+
+// AccessKeyId returns the access key the request is signed with, from its Authorization header
+// or, for presigned URLs, its query. It's empty if the request isn't signed.
+func AccessKeyId(r *http.Request) string {
+	if credential, ok := sigV4Credential(r); ok {
+		accessKeyId, _, _ := strings.Cut(credential, "/")
+		return accessKeyId
+	}
+	// Signature version 2, which S3 still accepts.
+	if rest, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS "); ok {
+		accessKeyId, _, _ := strings.Cut(rest, ":")
+		return accessKeyId
+	}
+	return r.URL.Query().Get("AWSAccessKeyId")
+}
+
+// SigningRegion returns the region the request's Signature Version 4 signature is scoped to, or ""
+// if it isn't signed with signature version 4.
+func SigningRegion(r *http.Request) string {
+	credential, _ := sigV4Credential(r)
+	// accessKeyId/date/region/service/aws4_request
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 {
+		return ""
+	}
+	return parts[2]
+}
+
+// sigV4Credential returns the Credential of the request's Signature Version 4 signature, from its
+// Authorization header or, for presigned URLs, its query, and false if it isn't signed with
+// signature version 4.
+func sigV4Credential(r *http.Request) (string, bool) {
+	// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+	if rest, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "); ok {
+		for _, part := range strings.Split(rest, ",") {
+			if credential, ok := strings.CutPrefix(strings.TrimSpace(part), "Credential="); ok {
+				return credential, true
+			}
+		}
+		return "", true
+	}
+	credential := r.URL.Query().Get("X-Amz-Credential")
+	return credential, credential != ""
+}
+
+// SigningTime is when a request was signed and, for presigned URLs, when they expire.
+type SigningTime struct {
+	// Zero for presigned URLs signed with signature version 2, which only have their expiry.
+	Signed time.Time
+	// Zero unless the request is a presigned URL.
+	Expires time.Time
+}
+
+// amzDateFormat is the format of X-Amz-Date.
+const amzDateFormat = "20060102T150405Z"
+
+// RequestSigningTime returns when the request was signed, from its X-Amz-Date or Date header or,
+// for presigned URLs, its query. It returns false if the request doesn't say.
+func RequestSigningTime(r *http.Request) (SigningTime, bool) {
+	query := r.URL.Query()
+	if date := query.Get("X-Amz-Date"); date != "" {
+		signed, err := time.Parse(amzDateFormat, date)
+		if err != nil {
+			return SigningTime{}, false
+		}
+		t := SigningTime{Signed: signed}
+		if seconds, err := strconv.Atoi(query.Get("X-Amz-Expires")); err == nil {
+			t.Expires = signed.Add(time.Duration(seconds) * time.Second)
+		}
+		return t, true
+	}
+	// Signature version 2's presigned URLs.
+	if expires := query.Get("Expires"); expires != "" && query.Has("AWSAccessKeyId") {
+		seconds, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return SigningTime{}, false
+		}
+		return SigningTime{Expires: time.Unix(seconds, 0)}, true
+	}
+
+	if date := r.Header.Get("X-Amz-Date"); date != "" {
+		signed, err := time.Parse(amzDateFormat, date)
+		return SigningTime{Signed: signed}, err == nil
+	}
+	if date := r.Header.Get("Date"); date != "" {
+		signed, err := http.ParseTime(date)
+		return SigningTime{Signed: signed}, err == nil
+	}
+	return SigningTime{}, false
+}
+
+// FormatAmzDate formats the time like X-Amz-Date, as errors about signing times show them.
+func FormatAmzDate(t time.Time) string {
+	return t.UTC().Format(amzDateFormat)
+}
+
+// Operations AWS doesn't require signing for, since they're how callers without credentials
+// get them.
+var unsignedTargets = map[string]bool{
+	"AWSCognitoIdentityProviderService.ConfirmForgotPassword":  true,
+	"AWSCognitoIdentityProviderService.ConfirmSignUp":          true,
+	"AWSCognitoIdentityProviderService.ForgotPassword":         true,
+	"AWSCognitoIdentityProviderService.GetUser":                true,
+	"AWSCognitoIdentityProviderService.InitiateAuth":           true,
+	"AWSCognitoIdentityProviderService.ResendConfirmationCode": true,
+	"AWSCognitoIdentityProviderService.RespondToAuthChallenge": true,
+	"AWSCognitoIdentityProviderService.SignUp":                 true,
+	"AWSCognitoIdentityService.GetCredentialsForIdentity":      true,
+	"AWSCognitoIdentityService.GetId":                          true,
+	"AWSCognitoIdentityService.GetOpenIdToken":                 true,
+}
+
+var unsignedActions = map[string]bool{
+	"AssumeRoleWithSAML":        true,
+	"AssumeRoleWithWebIdentity": true,
+}
+
+// isUnsigned returns whether the request doesn't need to be signed: the admin API, invocations of
+// API Gateway APIs and Lambda function URLs, S3 POST uploads, and operations AWS doesn't require
+// signing for.
+func isUnsigned(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, admin.PathPrefix) {
+		return true
+	}
+	if _, ok := endpoint.InvocationFromRequest(r); ok {
+		return true
+	}
+	if unsignedTargets[r.Header.Get("X-Amz-Target")] {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	// S3 POST uploads are signed by a field of the form.
+	if r.Method == http.MethodPost && mediaType == "multipart/form-data" {
+		return isBucketUpload(r)
+	}
+	if r.Method == http.MethodPost && mediaType == "application/x-www-form-urlencoded" {
+		// Reading the action consumes the body, which is restored for the handlers.
+		body, err := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return false
+		}
+		form, _ := url.ParseQuery(string(body))
+		return unsignedActions[form.Get("Action")]
+	}
+	return unsignedActions[r.URL.Query().Get("Action")]
+}
+
+// isBucketUpload returns whether the multipart POST is an S3 POST upload, which is made to the
+// bucket: to /bucket, or to / of the bucket's virtual-hosted-style hostname.
+func isBucketUpload(r *http.Request) bool {
+	if r.Header.Get("X-Amz-Target") != "" {
+		return false
+	}
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if e, ok := endpoint.FromRequest(r); ok {
+		if e.Service != "s3" {
+			return false
+		}
+		if e.Bucket != "" {
+			return path == ""
+		}
+	}
+	bucket, ok := strings.CutPrefix(path, "/")
+	return ok && bucket != "" && !strings.Contains(bucket, "/")
+}
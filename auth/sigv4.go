@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// SignatureError is why a request's signature wasn't accepted.
+type SignatureError struct {
+	Message string
+	// What the emulator signed, like AWS reports it, to compare with what the client signed. Empty
+	// if the signature couldn't be checked at all, such as when its Authorization is malformed.
+	CanonicalRequest string
+	StringToSign     string
+}
+
+func (e *SignatureError) Error() string {
+	return e.Message
+}
+
+// sigV4 is the parts of a request's signature, from its Authorization header or, for presigned
+// URLs, its query.
+type sigV4 struct {
+	accessKeyId string
+	// date/region/service/aws4_request
+	scope         string
+	date          string
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+	amzDate       string
+	presigned     bool
+}
+
+func parseSigV4(r *http.Request) (*sigV4, *SignatureError) {
+	s := &sigV4{}
+	var credential, signedHeaders string
+	if rest, ok := strings.CutPrefix(r.Header.Get("Authorization"), sigV4Algorithm+" "); ok {
+		for _, part := range strings.Split(rest, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				s.signature = value
+			}
+		}
+		s.amzDate = r.Header.Get("X-Amz-Date")
+		if s.amzDate == "" {
+			if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+				s.amzDate = FormatAmzDate(date)
+			}
+		}
+	} else {
+		query := r.URL.Query()
+		if query.Get("X-Amz-Algorithm") != sigV4Algorithm {
+			return nil, &SignatureError{Message: "Only AWS Signature Version 4 is accepted when signatures are validated."}
+		}
+		s.presigned = true
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		s.signature = query.Get("X-Amz-Signature")
+		s.amzDate = query.Get("X-Amz-Date")
+	}
+
+	if credential == "" || signedHeaders == "" || s.signature == "" {
+		return nil, &SignatureError{Message: "The request signature is missing its Credential, SignedHeaders or Signature."}
+	}
+	if s.amzDate == "" {
+		return nil, &SignatureError{Message: "The request must have an X-Amz-Date or Date."}
+	}
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return nil, &SignatureError{Message: fmt.Sprintf("Credential %q should be accessKeyId/date/region/service/aws4_request.", credential)}
+	}
+	s.accessKeyId, s.date, s.region, s.service = parts[0], parts[1], parts[2], parts[3]
+	s.scope = strings.Join(parts[1:], "/")
+	if !strings.HasPrefix(s.amzDate, s.date) {
+		return nil, &SignatureError{Message: fmt.Sprintf("Credential date %s doesn't match the request's date %s.", s.date, s.amzDate)}
+	}
+	s.signedHeaders = strings.Split(signedHeaders, ";")
+	if !slices.Contains(s.signedHeaders, "host") {
+		return nil, &SignatureError{Message: "The host header must be signed."}
+	}
+	return s, nil
+}
+
+// VerifySignature checks the request's Signature Version 4 signature, from its Authorization
+// header or its presigned URL's query, with the secret access key. Requests signed with signature
+// version 2, or not signed at all, aren't accepted. The request's body is read to hash it, unless
+// it has an X-Amz-Content-Sha256, and is restored for the handlers.
+//
+// The hash in X-Amz-Content-Sha256 is signed, but isn't compared with the body, and the chunks of
+// streaming uploads aren't checked.
+func VerifySignature(r *http.Request, secretAccessKey string) *SignatureError {
+	s, err := parseSigV4(r)
+	if err != nil {
+		return err
+	}
+	payloadHash, hashErr := s.payloadHash(r)
+	if hashErr != nil {
+		return &SignatureError{Message: hashErr.Error()}
+	}
+	canonicalRequest := s.canonicalRequest(r, payloadHash)
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, s.amzDate, s.scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{s.date, s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(s.signature)) {
+		return &SignatureError{
+			Message:          "The request signature we calculated does not match the signature you provided. Check your key and signing method.",
+			CanonicalRequest: canonicalRequest,
+			StringToSign:     stringToSign,
+		}
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// payloadHash returns the hash of the body the client signed.
+func (s *sigV4) payloadHash(r *http.Request) (string, error) {
+	if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != "" {
+		return hash, nil
+	}
+	if s.presigned {
+		if hash := r.URL.Query().Get("X-Amz-Content-Sha256"); hash != "" {
+			return hash, nil
+		}
+		// S3's presigned URLs can be used with any body.
+		if s.service == "s3" {
+			return unsignedPayload, nil
+		}
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+	}
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (s *sigV4) canonicalRequest(r *http.Request, payloadHash string) string {
+	// The path as the client sent it, before handlers rewrite it, such as for S3's virtual hosts.
+	rawPath := r.URL.EscapedPath()
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		rawPath = u.EscapedPath()
+	}
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		path = rawPath
+	}
+	if path == "" {
+		path = "/"
+	}
+	// S3 signs the path as it is, and other services sign it encoded again.
+	canonicalURI := uriEncode(path, false)
+	if s.service != "s3" {
+		canonicalURI = uriEncode(canonicalURI, false)
+	}
+
+	// Parameters are sorted by name, then value.
+	query, _ := url.ParseQuery(r.URL.RawQuery)
+	var params [][2]string
+	for key, values := range query {
+		if s.presigned && key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, [2]string{uriEncode(key, true), uriEncode(value, true)})
+		}
+	}
+	slices.SortFunc(params, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return strings.Compare(a[1], b[1])
+	})
+	var canonicalQuery []string
+	for _, param := range params {
+		canonicalQuery = append(canonicalQuery, param[0]+"="+param[1])
+	}
+
+	var headers strings.Builder
+	for _, name := range s.signedHeaders {
+		headers.WriteString(name + ":" + canonicalHeaderValue(r, name) + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		canonicalURI,
+		strings.Join(canonicalQuery, "&"),
+		headers.String(),
+		strings.Join(s.signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func canonicalHeaderValue(r *http.Request, name string) string {
+	var values []string
+	switch name {
+	// Go moves these out of the request's headers.
+	case "host":
+		values = []string{r.Host}
+	case "content-length":
+		values = slices.Clone(r.Header.Values(name))
+		if len(values) == 0 && r.ContentLength >= 0 {
+			values = []string{fmt.Sprint(r.ContentLength)}
+		}
+	case "transfer-encoding":
+		values = slices.Clone(r.TransferEncoding)
+	default:
+		values = slices.Clone(r.Header.Values(name))
+	}
+	for i, value := range values {
+		values[i] = strings.Join(strings.Fields(value), " ")
+	}
+	return strings.Join(values, ",")
+}
+
+// uriEncode encodes everything but unreserved characters, as SigV4 requires, and slashes unless
+// they're encoded too, such as in query parameters.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -9,6 +9,11 @@ type ErrorBody struct {
 	Type          string `json:"__type"`
 	Message       string `json:"Message,omitempty"`
 	LegacyMessage string `json:"message,omitempty"`
+	// Item is returned by DynamoDB when a condition check fails,
+	// if ReturnValuesOnConditionCheckFailure is ALL_OLD.
+	Item any `json:"Item,omitempty"`
+	// ExpectedSequenceToken is returned by CloudWatch Logs when PutLogEvents has an old sequence token.
+	ExpectedSequenceToken string `json:"expectedSequenceToken,omitempty"`
 }
 
 func Generate400Exception(typ, message string) *Error {
@@ -0,0 +1,62 @@
+package calls
+
+import (
+	"net/http"
+	"slices"
+
+	"aws-in-a-box/admin"
+)
+
+// RegisterAdminHandlers adds the call counts to the admin API. GET calls returns the operations
+// each service has handled, and the latest errors, which can be filtered with ?service= and
+// ?operation=. DELETE calls resets them, and the slow calls.
+func RegisterAdminHandlers(adminRegistry admin.Registry, registry *Registry) {
+	adminRegistry["calls"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			service := r.URL.Query().Get("service")
+			operation := r.URL.Query().Get("operation")
+
+			services := registry.Services()
+			if service != "" {
+				services = slices.DeleteFunc(services, func(s Service) bool { return s.Name != service })
+			}
+			if operation != "" {
+				for i := range services {
+					services[i].Operations = slices.DeleteFunc(services[i].Operations, func(o Operation) bool {
+						return o.Name != operation
+					})
+				}
+				services = slices.DeleteFunc(services, func(s Service) bool { return len(s.Operations) == 0 })
+			}
+			errors := slices.DeleteFunc(registry.Errors(), func(e Error) bool {
+				return (service != "" && e.Service != service) || (operation != "" && e.Operation != operation)
+			})
+			admin.WriteJSON(w, struct {
+				Services []Service
+				Errors   []Error
+			}{services, errors})
+		case http.MethodDelete:
+			registry.Reset()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+
+	// GET calls/slow returns the latest calls which took longer than the server's threshold, which
+	// can be filtered like calls.
+	adminRegistry["calls/slow"] = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		service := r.URL.Query().Get("service")
+		operation := r.URL.Query().Get("operation")
+		admin.WriteJSON(w, struct {
+			SlowCalls []SlowCall
+		}{slices.DeleteFunc(registry.SlowCalls(), func(c SlowCall) bool {
+			return (service != "" && c.Service != service) || (operation != "" && c.Operation != operation)
+		})})
+	}
+}
@@ -0,0 +1,267 @@
+// Package calls counts the operations each service handles and keeps their latest errors, so tests
+// can check which calls their code made without a proxy in front of the emulator.
+package calls
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// MaxErrors is how many of the latest errors are kept, and MaxSlowCalls how many of the latest
+// slow calls.
+const (
+	MaxErrors    = 100
+	MaxSlowCalls = 100
+)
+
+// Operation counts an operation's calls, including those which failed.
+type Operation struct {
+	Name   string
+	Calls  int
+	Errors int
+}
+
+// Service has a service's operations which have been called.
+type Service struct {
+	Name       string
+	Operations []Operation
+}
+
+// Error is an error returned by an operation.
+type Error struct {
+	Time       time.Time
+	Service    string
+	Operation  string
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// SlowCall is a call which took longer than the server's threshold.
+type SlowCall struct {
+	Time      time.Time
+	Service   string
+	Operation string
+	// Resource is what the call was for, such as the bucket and key, or the table, queue or
+	// stream, if it's known.
+	Resource      string
+	DurationMs    float64
+	StatusCode    int
+	RequestBytes  int64
+	ResponseBytes int64
+}
+
+// Registry counts the calls of every service's operations.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]map[string]*Operation
+	// The latest errors and slow calls, oldest first.
+	errors    []Error
+	slowCalls []SlowCall
+
+	// The method registry's targets which have been wrapped, so each service only wraps its own.
+	wrappedTargets map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		services:       make(map[string]map[string]*Operation),
+		wrappedTargets: make(map[string]bool),
+	}
+}
+
+type contextKey struct{}
+
+type callKey struct{}
+
+// Call is what Record knows about the operation a request called, for handlers outside the
+// server's handler chain, such as the request log.
+type Call struct {
+	// Service is empty if the request's service isn't counted.
+	Service   string
+	Operation string
+	Error     *awserrors.Error
+}
+
+// WithCall returns the request with a Call, which is filled in once the operation the request
+// called has returned. The Call is empty if no operation handled it. A request which already has a
+// Call is returned as it is.
+func WithCall(req *http.Request) (*http.Request, *Call) {
+	if c, ok := req.Context().Value(callKey{}).(*Call); ok {
+		return req, c
+	}
+	c := &Call{}
+	return req.WithContext(context.WithValue(req.Context(), callKey{}, c)), c
+}
+
+// tracker is stored in the context of requests for a service which are counted.
+type tracker struct {
+	registry *Registry
+	service  string
+}
+
+func (r *Registry) track(req *http.Request, service string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), contextKey{}, &tracker{registry: r, service: service}))
+}
+
+// Handler counts the calls of the service's operations handled by the handler, which is one of the
+// server's handler chain. It returns the handler as it is if the registry is nil.
+func (r *Registry) Handler(service string, handler func(w http.ResponseWriter, r *http.Request) bool) func(w http.ResponseWriter, r *http.Request) bool {
+	if r == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		tracked := r.track(req, service)
+		if handler(w, tracked) {
+			return true
+		}
+		// The tracked request is a copy, so the body and form a handler read and restored, to see
+		// whether the request was for it, are passed on to the rest of the chain.
+		req.Body = tracked.Body
+		req.Form, req.PostForm, req.MultipartForm = tracked.Form, tracked.PostForm, tracked.MultipartForm
+		return false
+	}
+}
+
+// WrapMethods counts the calls of the JSON protocols' method registry's targets which haven't been
+// wrapped yet as the service's. Services share the method registry, so it's called right after the
+// service registers its handlers.
+func (r *Registry) WrapMethods(service string, methodRegistry map[string]http.HandlerFunc) {
+	if r == nil {
+		return
+	}
+	for target, handler := range methodRegistry {
+		if r.wrappedTargets[target] {
+			continue
+		}
+		r.wrappedTargets[target] = true
+		handler := handler
+		methodRegistry[target] = func(w http.ResponseWriter, req *http.Request) {
+			handler(w, r.track(req, service))
+		}
+	}
+}
+
+// Record counts a call of the operation by the request, and its error if it has one, and fills in
+// the request's Call if it has one. It's called by the protocols' handlers once the operation has
+// returned, and doesn't count the call if the request's service isn't counted.
+func Record(req *http.Request, operation string, awserr *awserrors.Error) {
+	t, ok := req.Context().Value(contextKey{}).(*tracker)
+	if c, hasCall := req.Context().Value(callKey{}).(*Call); hasCall {
+		c.Operation, c.Error = operation, awserr
+		if ok {
+			c.Service = t.service
+		}
+	}
+	if !ok {
+		return
+	}
+	t.registry.record(t.service, operation, awserr)
+}
+
+func (r *Registry) record(service string, operation string, awserr *awserrors.Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	operations, ok := r.services[service]
+	if !ok {
+		operations = make(map[string]*Operation)
+		r.services[service] = operations
+	}
+	o, ok := operations[operation]
+	if !ok {
+		o = &Operation{Name: operation}
+		operations[operation] = o
+	}
+	o.Calls++
+	if awserr == nil {
+		return
+	}
+	o.Errors++
+	message := awserr.Body.Message
+	if message == "" {
+		message = awserr.Body.LegacyMessage
+	}
+	r.errors = append(r.errors, Error{
+		Time:       time.Now().UTC(),
+		Service:    service,
+		Operation:  operation,
+		StatusCode: awserr.Code,
+		Code:       awserr.Body.Type,
+		Message:    message,
+	})
+	if len(r.errors) > MaxErrors {
+		r.errors = slices.Delete(r.errors, 0, len(r.errors)-MaxErrors)
+	}
+}
+
+// Services returns the services and their operations which have been called, sorted by name.
+func (r *Registry) Services() []Service {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	services := make([]Service, 0, len(r.services))
+	for name, operations := range r.services {
+		service := Service{Name: name}
+		for _, o := range operations {
+			service.Operations = append(service.Operations, *o)
+		}
+		sort.Slice(service.Operations, func(i, j int) bool {
+			return service.Operations[i].Name < service.Operations[j].Name
+		})
+		services = append(services, service)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
+// Errors returns the latest errors, newest first.
+func (r *Registry) Errors() []Error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errors := make([]Error, len(r.errors))
+	copy(errors, r.errors)
+	slices.Reverse(errors)
+	return errors
+}
+
+// RecordSlow keeps a slow call.
+func (r *Registry) RecordSlow(call SlowCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.slowCalls = append(r.slowCalls, call)
+	if len(r.slowCalls) > MaxSlowCalls {
+		r.slowCalls = slices.Delete(r.slowCalls, 0, len(r.slowCalls)-MaxSlowCalls)
+	}
+}
+
+// SlowCalls returns the latest slow calls, newest first.
+func (r *Registry) SlowCalls() []SlowCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slowCalls := make([]SlowCall, len(r.slowCalls))
+	copy(slowCalls, r.slowCalls)
+	slices.Reverse(slowCalls)
+	return slowCalls
+}
+
+// Reset clears the counts, errors and slow calls.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.services = make(map[string]map[string]*Operation)
+	r.errors = nil
+	r.slowCalls = nil
+}
@@ -0,0 +1,144 @@
+package calls
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/awserrors"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	methodRegistry := map[string]http.HandlerFunc{
+		"Kinesis_20131202.PutRecord": func(w http.ResponseWriter, r *http.Request) {
+			Record(r, "PutRecord", nil)
+		},
+	}
+	registry.WrapMethods("kinesis", methodRegistry)
+	handler := registry.Handler("s3", func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut {
+			Record(r, "PutObject", nil)
+		} else {
+			Record(r, "GetObject", awserrors.Generate400Exception("NoSuchKey", "The specified key does not exist."))
+		}
+		return true
+	})
+
+	methodRegistry["Kinesis_20131202.PutRecord"](httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/bucket/key", nil))
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bucket/key", nil))
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bucket/key", nil))
+	// Requests which aren't counted are ignored.
+	Record(httptest.NewRequest(http.MethodGet, "/", nil), "ListBuckets", nil)
+
+	want := []Service{
+		{Name: "kinesis", Operations: []Operation{{Name: "PutRecord", Calls: 1}}},
+		{Name: "s3", Operations: []Operation{{Name: "GetObject", Calls: 2, Errors: 2}, {Name: "PutObject", Calls: 1}}},
+	}
+	if services := registry.Services(); !reflect.DeepEqual(services, want) {
+		t.Errorf("got %+v, want %+v", services, want)
+	}
+	errors := registry.Errors()
+	if len(errors) != 2 || errors[0].Code != "NoSuchKey" || errors[0].StatusCode != 400 || errors[0].Operation != "GetObject" {
+		t.Fatalf("Unexpected errors %+v", errors)
+	}
+
+	// Only the latest errors are kept.
+	for i := 0; i < MaxErrors; i++ {
+		registry.record("sqs", "SendMessage", awserrors.Generate400Exception("InvalidParameterValue", fmt.Sprint(i)))
+	}
+	errors = registry.Errors()
+	if len(errors) != MaxErrors || errors[0].Message != fmt.Sprint(MaxErrors-1) || errors[MaxErrors-1].Service != "sqs" {
+		t.Fatalf("Unexpected errors %d, newest %+v", len(errors), errors[0])
+	}
+}
+
+func TestHandlerPassesBodyOn(t *testing.T) {
+	registry := NewRegistry()
+	// Like the Query protocol's handler, it reads the body and restores it before declining the request.
+	declining := registry.Handler("sqs", func(w http.ResponseWriter, r *http.Request) bool {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return false
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Action=GetCallerIdentity"))
+	if declining(httptest.NewRecorder(), request) {
+		t.Fatal("Expected the request not to be handled")
+	}
+	if body, _ := io.ReadAll(request.Body); string(body) != "Action=GetCallerIdentity" {
+		t.Fatalf("Expected the body to be passed on, got %q", body)
+	}
+}
+
+func TestWithCall(t *testing.T) {
+	registry := NewRegistry()
+	handler := registry.Handler("s3", func(w http.ResponseWriter, r *http.Request) bool {
+		Record(r, "GetObject", awserrors.Generate400Exception("NoSuchKey", "The specified key does not exist."))
+		return true
+	})
+
+	request, call := WithCall(httptest.NewRequest(http.MethodGet, "/bucket/key", nil))
+	handler(httptest.NewRecorder(), request)
+	if call.Service != "s3" || call.Operation != "GetObject" || call.Error == nil || call.Error.Body.Type != "NoSuchKey" {
+		t.Fatalf("Unexpected call %+v", call)
+	}
+
+	// Calls of services which aren't counted still have their operation.
+	request, call = WithCall(httptest.NewRequest(http.MethodGet, "/", nil))
+	Record(request, "ListBuckets", nil)
+	if call.Service != "" || call.Operation != "ListBuckets" || call.Error != nil {
+		t.Fatalf("Unexpected call %+v", call)
+	}
+}
+
+func TestAdminHandlers(t *testing.T) {
+	registry := NewRegistry()
+	registry.record("s3", "PutObject", nil)
+	registry.record("s3", "GetObject", awserrors.Generate400Exception("NoSuchKey", "The specified key does not exist."))
+	registry.record("sqs", "SendMessage", nil)
+	registry.RecordSlow(SlowCall{Service: "s3", Operation: "PutObject", Resource: "bucket/key"})
+	registry.RecordSlow(SlowCall{Service: "sqs", Operation: "ReceiveMessage"})
+	adminRegistry := admin.Registry{}
+	RegisterAdminHandlers(adminRegistry, registry)
+
+	response := httptest.NewRecorder()
+	adminRegistry["calls"](response, httptest.NewRequest(http.MethodGet, "/_admin/calls?service=s3&operation=PutObject", nil))
+	var got struct {
+		Services []Service
+		Errors   []Error
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Service{{Name: "s3", Operations: []Operation{{Name: "PutObject", Calls: 1}}}}
+	if !reflect.DeepEqual(got.Services, want) || len(got.Errors) != 0 {
+		t.Fatalf("Unexpected response %s", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	adminRegistry["calls/slow"](response, httptest.NewRequest(http.MethodGet, "/_admin/calls/slow?service=s3", nil))
+	var slow struct {
+		SlowCalls []SlowCall
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &slow); err != nil {
+		t.Fatal(err)
+	}
+	if len(slow.SlowCalls) != 1 || slow.SlowCalls[0].Resource != "bucket/key" {
+		t.Fatalf("Unexpected response %s", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	adminRegistry["calls"](response, httptest.NewRequest(http.MethodDelete, "/_admin/calls", nil))
+	if response.Code != http.StatusNoContent || len(registry.Services()) != 0 || len(registry.Errors()) != 0 || len(registry.SlowCalls()) != 0 {
+		t.Fatal("Expected the calls to be reset, got", response.Code, registry.Services(), registry.Errors(), registry.SlowCalls())
+	}
+}
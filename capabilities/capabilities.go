@@ -0,0 +1,96 @@
+// Package capabilities lists the operations each enabled service supports, so test harnesses can
+// skip tests of unimplemented operations, and users can see what's covered at a glance.
+package capabilities
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"aws-in-a-box/admin"
+	awshttp "aws-in-a-box/http"
+)
+
+// Registry maps services' names, such as "sqs", to the operations they support.
+type Registry struct {
+	services map[string][]string
+	// The method registry's targets which have been recorded, so each service only records its own.
+	recordedTargets map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		services:        make(map[string][]string),
+		recordedTargets: make(map[string]bool),
+	}
+}
+
+// Add records operations the service supports. Operations served with several protocols, like
+// SQS's Query and JSON protocols, are only listed once. It does nothing if the registry is nil,
+// so handlers can be created without one in tests.
+func (r *Registry) Add(service string, operations ...string) {
+	if r == nil {
+		return
+	}
+	for _, operation := range operations {
+		if !slices.Contains(r.services[service], operation) {
+			r.services[service] = append(r.services[service], operation)
+		}
+	}
+}
+
+// AddMethods records the operations of the JSON protocols' method registry which haven't been
+// recorded yet as the service's. Services share the method registry, so it's called right after
+// the service registers its handlers.
+func (r *Registry) AddMethods(service string, methodRegistry awshttp.Registry) {
+	if r == nil {
+		return
+	}
+	for target := range methodRegistry {
+		if r.recordedTargets[target] {
+			continue
+		}
+		r.recordedTargets[target] = true
+		// Targets are like Kinesis_20131202.CreateStream.
+		r.Add(service, target[strings.LastIndex(target, ".")+1:])
+	}
+}
+
+type Service struct {
+	Name       string
+	Operations []string
+}
+
+// Services returns the services, and their operations, sorted by name.
+func (r *Registry) Services() []Service {
+	services := make([]Service, 0, len(r.services))
+	for name, operations := range r.services {
+		operations = slices.Clone(operations)
+		slices.Sort(operations)
+		services = append(services, Service{Name: name, Operations: operations})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
+// RegisterAdminHandlers adds capability discovery to the admin API. GET capabilities returns the
+// enabled services and their operations, which can be filtered with ?service=.
+func RegisterAdminHandlers(adminRegistry admin.Registry, registry *Registry, version string) {
+	adminRegistry["capabilities"] = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		services := registry.Services()
+		if name := r.URL.Query().Get("service"); name != "" {
+			services = slices.DeleteFunc(services, func(s Service) bool { return s.Name != name })
+		}
+		admin.WriteJSON(w, struct {
+			Version  string
+			Services []Service
+		}{version, services})
+	}
+}
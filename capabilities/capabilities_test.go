@@ -0,0 +1,33 @@
+package capabilities
+
+import (
+	"reflect"
+	"testing"
+
+	awshttp "aws-in-a-box/http"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	methodRegistry := make(awshttp.Registry)
+	methodRegistry["Kinesis_20131202.CreateStream"] = nil
+	methodRegistry["Kinesis_20131202.PutRecord"] = nil
+	registry.AddMethods("kinesis", methodRegistry)
+
+	// Only the targets registered since are the next service's.
+	methodRegistry["AmazonSQS.SendMessage"] = nil
+	registry.AddMethods("sqs", methodRegistry)
+	registry.Add("sqs", "SendMessage", "CreateQueue")
+
+	want := []Service{
+		{Name: "kinesis", Operations: []string{"CreateStream", "PutRecord"}},
+		{Name: "sqs", Operations: []string{"CreateQueue", "SendMessage"}},
+	}
+	if got := registry.Services(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Got %+v, want %+v", got, want)
+	}
+
+	// Handlers can be created without a registry.
+	var nilRegistry *Registry
+	nilRegistry.Add("sqs", "SendMessage")
+}
@@ -0,0 +1,23 @@
+// Package clock is the emulator's current time, which scenarios can move forward to test expiry,
+// such as SQS visibility timeouts and message retention, without waiting for it.
+package clock
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// offset is how far the clock has been advanced, in nanoseconds.
+var offset atomic.Int64
+
+// Now returns the current time, plus however far the clock has been advanced.
+func Now() time.Time {
+	return time.Now().Add(time.Duration(offset.Load()))
+}
+
+// Advance moves the clock forward by d, or back if it's negative. Timers which are already
+// running, such as Lambda timeouts, aren't affected, but everything which reads the clock sees the
+// new time.
+func Advance(d time.Duration) {
+	offset.Add(int64(d))
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/services/route53"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/secretsmanager"
+	"aws-in-a-box/services/ssm"
+	"aws-in-a-box/state"
+)
+
+const stateCommandUsage = `Usage:
+  aws-in-a-box dump [-addr localhost:4569] [-o state.tar.gz] [-snapshot]
+  aws-in-a-box load [-addr localhost:4569] state.tar.gz
+
+dump writes the state of a running emulator's services to a tar.gz archive, and load replaces
+their state with an archive's. Use - for stdout or stdin. dump -snapshot has the emulator copy the
+archive to a temporary file before sending it, so the services aren't held up while it's downloaded.
+Each service's state is copied at a different moment, so a request which changed several services
+may only be in some of them.
+`
+
+const diffCommandUsage = `Usage:
+  aws-in-a-box diff [-addr localhost:4569] [-json] before.tar.gz [after.tar.gz]
+
+diff prints the resources which were created (+), modified (~) or deleted (-) between two archives
+written by dump, or between an archive and the state of a running emulator if there's only one.
+It exits with status 1 if there are differences.
+`
+
+// stateResources describes the resources in the archive's entries of each service, by the names
+// main registers them with.
+var stateResources = map[string]state.ResourcesFunc{
+	"dynamodb":       dynamodb.StateResources,
+	"kinesis":        kinesis.StateResources,
+	"kms":            kms.StateResources,
+	"route53":        route53.StateResources,
+	"s3":             s3.StateResources,
+	"secretsmanager": secretsmanager.StateResources,
+	"ssm":            ssm.StateResources,
+}
+
+func stateURL(addr string) string {
+	return "http://" + addr + admin.PathPrefix + "state"
+}
+
+func snapshotURL(addr string) string {
+	return "http://" + addr + admin.PathPrefix + "snapshot"
+}
+
+// errUsage is returned by commands called with the wrong arguments, once they've printed their usage.
+var errUsage = errors.New("invalid arguments")
+
+// checkResponse returns an error if the admin API didn't succeed.
+func checkResponse(command string, resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed: %s: %s", command, resp.Status, body)
+	}
+	return nil
+}
+
+// exitOnCommandError exits if a command failed, with status 2 if it was called with the wrong arguments.
+func exitOnCommandError(err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		log.Fatal(err)
+	}
+}
+
+// runStateCommand runs the dump or load command, which export and import the state of a running
+// emulator through its admin API.
+func runStateCommand(command string, args []string) {
+	exitOnCommandError(stateCommand(command, args, os.Stdin, os.Stdout))
+}
+
+func stateCommand(command string, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), stateCommandUsage)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", "localhost:4569", "Address of the running emulator")
+	output := flags.String("o", "-", "File to write the archive to, for dump")
+	snapshot := flags.Bool("snapshot", false, "Download a snapshot, which is sent with its length so an interrupted download is detected, for dump")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	url := stateURL(*addr)
+	var resp *http.Response
+	var err error
+	switch command {
+	case "dump":
+		if flags.NArg() != 0 {
+			flags.Usage()
+			return errUsage
+		}
+		if *snapshot {
+			url = snapshotURL(*addr)
+		}
+		resp, err = http.Get(url)
+	case "load":
+		if flags.NArg() != 1 {
+			flags.Usage()
+			return errUsage
+		}
+		input := stdin
+		if flags.Arg(0) != "-" {
+			file, err := os.Open(flags.Arg(0))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+		resp, err = http.Post(url, "application/gzip", input)
+	default:
+		return fmt.Errorf("unknown command %s", command)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(command, resp); err != nil {
+		return err
+	}
+
+	if command == "dump" && *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, resp.Body); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+	_, err = io.Copy(stdout, resp.Body)
+	return err
+}
+
+// runDiffCommand runs the diff command, which compares two archives or an archive and the state of
+// a running emulator.
+func runDiffCommand(args []string) {
+	differs, err := diffCommand(args, os.Stdout)
+	exitOnCommandError(err)
+	if differs {
+		os.Exit(1)
+	}
+}
+
+// diffCommand prints the differences, and returns whether there are any.
+func diffCommand(args []string, stdout io.Writer) (bool, error) {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), diffCommandUsage)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", "localhost:4569", "Address of the running emulator, if there's one archive")
+	asJSON := flags.Bool("json", false, "Print the differences as a JSON array")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return false, err
+		}
+		return false, errUsage
+	}
+	if flags.NArg() != 1 && flags.NArg() != 2 {
+		flags.Usage()
+		return false, errUsage
+	}
+
+	before, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	var after []byte
+	if flags.NArg() == 2 {
+		after, err = os.ReadFile(flags.Arg(1))
+	} else {
+		var resp *http.Response
+		resp, err = http.Get(stateURL(*addr))
+		if err == nil {
+			defer resp.Body.Close()
+			err = checkResponse("diff", resp)
+		}
+		if err == nil {
+			after, err = io.ReadAll(resp.Body)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := state.Diff(bytes.NewReader(before), bytes.NewReader(after), stateResources)
+	if err != nil {
+		return false, err
+	}
+	if *asJSON {
+		if changes == nil {
+			changes = []state.Change{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(stdout, string(data))
+	} else {
+		symbols := map[state.ChangeKind]string{state.Created: "+", state.Modified: "~", state.Deleted: "-"}
+		service := ""
+		for _, change := range changes {
+			if change.Service != service {
+				service = change.Service
+				fmt.Fprintf(stdout, "%s:\n", service)
+			}
+			fmt.Fprintf(stdout, "  %s %s\n", symbols[change.Kind], change.Resource)
+		}
+	}
+	return len(changes) > 0, nil
+}
@@ -0,0 +1,188 @@
+// Package endpoint recognizes AWS's hostnames, such as kinesis.us-east-1.amazonaws.com, so clients
+// whose DNS points them at the emulator reach the right service without overriding their endpoints.
+package endpoint
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Endpoint is the service, region and, for S3's virtual-hosted-style requests, bucket a hostname is
+// for.
+type Endpoint struct {
+	// Service is the emulator's name for the service, such as ses for email.us-east-1.amazonaws.com.
+	Service string
+	// Region is empty for global endpoints, such as sts.amazonaws.com.
+	Region string
+	// Bucket is only set for S3's virtual-hosted-style hostnames.
+	Bucket string
+}
+
+// suffixes are the domains of AWS's endpoints, including China's and dual-stack endpoints.
+var suffixes = []string{".amazonaws.com", ".amazonaws.com.cn", ".api.aws"}
+
+// serviceNames maps the hostnames' prefixes which aren't the emulator's names for the services.
+var serviceNames = map[string]string{
+	"appconfigdata": "appconfig",
+	"email":         "ses",
+	"events":        "eventbridge",
+	"logs":          "cloudwatchlogs",
+	"monitoring":    "cloudwatch",
+	"queue":         "sqs",
+	"states":        "stepfunctions",
+}
+
+var (
+	serviceLabelPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+	regionPattern       = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+)
+
+// Parse returns the endpoint the host, which may have a port, is for. It returns false for hosts
+// which aren't AWS's, and for those the services recognize themselves, such as API Gateway's and
+// Lambda function URLs' hostnames.
+func Parse(host string) (Endpoint, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var labels []string
+	for _, suffix := range suffixes {
+		if rest, ok := strings.CutSuffix(host, suffix); ok {
+			labels = strings.Split(rest, ".")
+			break
+		}
+	}
+	if len(labels) == 0 {
+		return Endpoint{}, false
+	}
+
+	if e, ok := parseS3(labels); ok {
+		return e, true
+	}
+
+	var service, region string
+	switch {
+	case len(labels) == 3 && labels[0] == "api" && labels[1] == "ecr":
+		service, region = "ecr", labels[2]
+	case len(labels) == 2 && labels[1] == "queue":
+		// SQS's legacy hostnames, such as eu-west-1.queue.amazonaws.com.
+		service, region = "queue", labels[0]
+	default:
+		labels = removeDualStack(labels)
+		if len(labels) > 2 {
+			return Endpoint{}, false
+		}
+		service = strings.TrimSuffix(labels[0], "-fips")
+		if len(labels) == 2 {
+			region = labels[1]
+		}
+	}
+	if !serviceLabelPattern.MatchString(service) || (region != "" && !regionPattern.MatchString(region)) {
+		return Endpoint{}, false
+	}
+	if name, ok := serviceNames[service]; ok {
+		service = name
+	}
+	return Endpoint{Service: service, Region: region}, true
+}
+
+// parseS3 recognizes S3's hostnames, which can start with a bucket, and can have the region after a
+// dash rather than a dot, such as s3.amazonaws.com, bucket.s3.eu-west-1.amazonaws.com and
+// bucket.s3-eu-west-1.amazonaws.com.
+func parseS3(labels []string) (Endpoint, bool) {
+	for i := len(labels) - 1; i >= 0; i-- {
+		var region string
+		switch label := labels[i]; {
+		case label == "s3" || label == "s3-fips":
+		case label == "s3-external-1":
+			region = "us-east-1"
+		case strings.HasPrefix(label, "s3-") && regionPattern.MatchString(label[len("s3-"):]):
+			region = label[len("s3-"):]
+		default:
+			continue
+		}
+
+		rest := removeDualStack(labels[i+1:])
+		if len(rest) > 1 || (len(rest) == 1 && (region != "" || !regionPattern.MatchString(rest[0]))) {
+			return Endpoint{}, false
+		}
+		if len(rest) == 1 {
+			region = rest[0]
+		}
+		return Endpoint{Service: "s3", Region: region, Bucket: strings.Join(labels[:i], ".")}, true
+	}
+	return Endpoint{}, false
+}
+
+func removeDualStack(labels []string) []string {
+	var removed []string
+	for _, label := range labels {
+		if label != "dualstack" {
+			removed = append(removed, label)
+		}
+	}
+	return removed
+}
+
+// FromRequest returns the endpoint the request's Host header is for.
+func FromRequest(r *http.Request) (Endpoint, bool) {
+	return Parse(r.Host)
+}
+
+// Invocation is a request to invoke an API Gateway API or a Lambda function URL, rather than a
+// call to a service's API. Invocations are addressed by the API's or URL's own hostname, or by the
+// emulator's paths for them.
+type Invocation struct {
+	// Service is execute-api for API Gateway APIs, and lambda-url for function URLs.
+	Service string
+	// Id is the API's ID, or the function URL's.
+	Id string
+}
+
+var invocationIdPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// InvocationFromRequest returns the API or function URL the request invokes, from its path, such as
+// /execute-api/abc123/items, or its hostname, such as abc123.execute-api.us-east-1.amazonaws.com or
+// abc123.lambda-url.us-east-1.on.aws, in any domain. It returns false for other requests.
+func InvocationFromRequest(r *http.Request) (Invocation, bool) {
+	for _, service := range []string{"execute-api", "lambda-url"} {
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/"+service+"/"); ok {
+			id, _, _ := strings.Cut(rest, "/")
+			if invocationIdPattern.MatchString(id) {
+				return Invocation{Service: service, Id: id}, true
+			}
+		}
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(host, ".")), ".")
+	if len(labels) < 4 {
+		return Invocation{}, false
+	}
+	// The domain can be any, for setups with wildcard DNS.
+	id, service, region := labels[0], labels[1], labels[2]
+	if service != "execute-api" && service != "lambda-url" {
+		return Invocation{}, false
+	}
+	if !invocationIdPattern.MatchString(id) || !regionPattern.MatchString(region) {
+		return Invocation{}, false
+	}
+	return Invocation{Service: service, Id: id}, true
+}
+
+// Handler skips the handler, which is one of the server's handler chain, for requests to another
+// service's hostname, so requests which more than one service could handle, like those the S3
+// handler accepts whatever their path, reach the service they're for.
+func Handler(service string, handler func(w http.ResponseWriter, r *http.Request) bool) func(w http.ResponseWriter, r *http.Request) bool {
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		if e, ok := FromRequest(r); ok && e.Service != service {
+			return false
+		}
+		return handler(w, r)
+	}
+}
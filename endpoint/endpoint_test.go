@@ -0,0 +1,80 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for host, want := range map[string]Endpoint{
+		"kinesis.us-east-1.amazonaws.com":               {Service: "kinesis", Region: "us-east-1"},
+		"kinesis.us-east-1.amazonaws.com:443":           {Service: "kinesis", Region: "us-east-1"},
+		"DynamoDB.EU-West-1.amazonaws.com":              {Service: "dynamodb", Region: "eu-west-1"},
+		"sqs-fips.us-gov-west-1.amazonaws.com":          {Service: "sqs", Region: "us-gov-west-1"},
+		"eu-west-1.queue.amazonaws.com":                 {Service: "sqs", Region: "eu-west-1"},
+		"email.eu-central-1.amazonaws.com":              {Service: "ses", Region: "eu-central-1"},
+		"events.us-east-1.amazonaws.com":                {Service: "eventbridge", Region: "us-east-1"},
+		"api.ecr.ap-southeast-2.amazonaws.com":          {Service: "ecr", Region: "ap-southeast-2"},
+		"lambda.cn-north-1.amazonaws.com.cn":            {Service: "lambda", Region: "cn-north-1"},
+		"dynamodb.us-east-1.api.aws":                    {Service: "dynamodb", Region: "us-east-1"},
+		"sts.amazonaws.com":                             {Service: "sts"},
+		"route53.amazonaws.com":                         {Service: "route53"},
+		"s3.amazonaws.com":                              {Service: "s3"},
+		"s3.dualstack.eu-west-1.amazonaws.com":          {Service: "s3", Region: "eu-west-1"},
+		"mybucket.s3.eu-west-1.amazonaws.com":           {Service: "s3", Region: "eu-west-1", Bucket: "mybucket"},
+		"my.dotted.bucket.s3.amazonaws.com":             {Service: "s3", Bucket: "my.dotted.bucket"},
+		"mybucket.s3-eu-west-1.amazonaws.com":           {Service: "s3", Region: "eu-west-1", Bucket: "mybucket"},
+		"mybucket.s3-external-1.amazonaws.com":          {Service: "s3", Region: "us-east-1", Bucket: "mybucket"},
+		"mybucket.s3.dualstack.us-east-1.amazonaws.com": {Service: "s3", Region: "us-east-1", Bucket: "mybucket"},
+	} {
+		got, ok := Parse(host)
+		if !ok || got != want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", host, got, ok, want)
+		}
+	}
+
+	for _, host := range []string{
+		"localhost:4566",
+		"127.0.0.1",
+		"example.com",
+		"amazonaws.com",
+		"abc123.execute-api.us-east-1.amazonaws.com",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		"kinesis.useast1.amazonaws.com",
+		"mybucket.s3.eu-west-1.extra.amazonaws.com",
+	} {
+		if got, ok := Parse(host); ok {
+			t.Errorf("Expected Parse(%q) to fail, got %+v", host, got)
+		}
+	}
+}
+
+func TestInvocationFromRequest(t *testing.T) {
+	for target, want := range map[string]Invocation{
+		"http://localhost:4566/execute-api/abc123/items":                  {Service: "execute-api", Id: "abc123"},
+		"http://localhost:4566/lambda-url/xyz789/":                        {Service: "lambda-url", Id: "xyz789"},
+		"https://abc123.execute-api.us-east-1.amazonaws.com/prod/items":   {Service: "execute-api", Id: "abc123"},
+		"https://xyz789.lambda-url.eu-west-1.on.aws/":                     {Service: "lambda-url", Id: "xyz789"},
+		"http://abc123.execute-api.us-east-1.localhost.localstack.cloud/": {Service: "execute-api", Id: "abc123"},
+	} {
+		got, ok := InvocationFromRequest(httptest.NewRequest(http.MethodGet, target, nil))
+		if !ok || got != want {
+			t.Errorf("InvocationFromRequest(%q) = %+v, %v, want %+v", target, got, ok, want)
+		}
+	}
+
+	for _, target := range []string{
+		"http://localhost:4566/",
+		"http://localhost:4566/execute-api/",
+		"http://localhost:4566/bucket/execute-api/abc123",
+		"http://sqs.us-east-1.amazonaws.com/",
+		"http://my.execute-api.bucket.s3.amazonaws.com/",
+		"http://abc123.execute-api.amazonaws.com/",
+		"http://abc123.lambda-url.us-east-1/",
+	} {
+		if got, ok := InvocationFromRequest(httptest.NewRequest(http.MethodGet, target, nil)); ok {
+			t.Errorf("Expected InvocationFromRequest(%q) to fail, got %+v", target, got)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"unicode"
+)
+
+const envPrefix = "AWS_IN_A_BOX_"
+
+// envName returns the environment variable a flag can be set with: the flag's name in upper snake
+// case, such as AWS_IN_A_BOX_ROUTE53_DNS_ADDR for route53DNSAddr.
+func envName(flagName string) string {
+	runes := []rune(flagName)
+	name := envPrefix
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				name += "_"
+			}
+		}
+		name += string(unicode.ToUpper(r))
+	}
+	return name
+}
+
+// setFlagsFromEnv sets each flag which has an environment variable. It's called before the
+// command line is parsed, so flags on the command line take precedence.
+func setFlagsFromEnv(flags *flag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, envName(f.Name), setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,481 @@
+// Package eventpattern matches JSON against EventBridge's event patterns, which EventBridge rules,
+// archives and Pipes' filters use, and which SNS's filter policies are a subset of.
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
+// https://docs.aws.amazon.com/sns/latest/dg/message-filtering.html
+package eventpattern
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Options are what a pattern can contain, for the services whose syntax is narrower than
+// EventBridge's. The zero value is EventBridge's.
+type Options struct {
+	// Whether the pattern can't have nested objects, like SNS's filter policies on message
+	// attributes.
+	Flat bool
+	// Whether only SNS's operators are allowed: not wildcard, nor equals-ignore-case inside prefix,
+	// suffix or anything-but, and prefix, suffix and equals-ignore-case need non-empty strings.
+	FilterPolicy bool
+}
+
+// Pattern is a validated pattern.
+type Pattern struct {
+	pattern map[string]any
+	keys    int
+}
+
+// Decode decodes JSON like patterns and the values they match need: numbers keep their text, so
+// that they can be told apart from strings and compared exactly.
+func Decode(data string, v any) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// New validates the pattern, decoded with Decode. Its errors are the reason the pattern isn't valid,
+// in AWS's words, for services to wrap in their own error.
+func New(pattern map[string]any, options Options) (*Pattern, error) {
+	keys, err := options.validateObject(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{pattern: pattern, keys: keys}, nil
+}
+
+// Keys returns how many fields the pattern matches on, counting the alternatives of an $or with the
+// most, which SNS limits.
+func (p *Pattern) Keys() int {
+	return p.keys
+}
+
+// Matches reports whether the data, decoded with Decode, matches the pattern.
+func (p *Pattern) Matches(data map[string]any) bool {
+	return matchObject(p.pattern, data)
+}
+
+// validateObject checks every field of the object is either a nested object or an array of
+// conditions, and that every $or has alternative objects. It returns how many keys it matches on.
+func (o Options) validateObject(pattern map[string]any) (int, error) {
+	keys := 0
+	for key, value := range pattern {
+		if key == "$or" {
+			alternatives, ok := value.([]any)
+			if !ok || len(alternatives) < 2 {
+				return 0, errors.New("$or must be an array with at least two objects")
+			}
+			mostKeys := 0
+			for _, alternative := range alternatives {
+				object, ok := alternative.(map[string]any)
+				if !ok || len(object) == 0 {
+					return 0, errors.New("$or must be an array with at least two objects")
+				}
+				alternativeKeys, err := o.validateObject(object)
+				if err != nil {
+					return 0, err
+				}
+				mostKeys = max(mostKeys, alternativeKeys)
+			}
+			keys += mostKeys
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if o.Flat {
+				return 0, errors.New("\"" + key + "\" must be an object or an array")
+			}
+			if len(v) == 0 {
+				return 0, errors.New("Empty objects are not allowed")
+			}
+			nestedKeys, err := o.validateObject(v)
+			if err != nil {
+				return 0, err
+			}
+			keys += nestedKeys
+		case []any:
+			if len(v) == 0 {
+				return 0, errors.New("Empty arrays are not allowed for \"" + key + "\"")
+			}
+			for _, condition := range v {
+				if err := o.validateCondition(condition); err != nil {
+					return 0, err
+				}
+			}
+			keys++
+		default:
+			return 0, errors.New("\"" + key + "\" must be an object or an array")
+		}
+	}
+	return keys, nil
+}
+
+func (o Options) validateCondition(condition any) error {
+	switch c := condition.(type) {
+	case string, json.Number, bool, nil:
+		return nil
+	case map[string]any:
+		if len(c) != 1 {
+			return errors.New("Only one operator is allowed in each condition")
+		}
+		for operator, value := range c {
+			return o.validateOperator(operator, value)
+		}
+	}
+	return errors.New("Match value must be String, number, true, false, or null")
+}
+
+func (o Options) validateOperator(operator string, value any) error {
+	switch operator {
+	case "prefix", "suffix":
+		// Prefixes and suffixes can also be matched ignoring case, like {"prefix": {"equals-ignore-case": "a"}}.
+		if nested, ok := value.(map[string]any); ok && !o.FilterPolicy {
+			argument, ok := nested["equals-ignore-case"].(string)
+			if len(nested) != 1 || !ok || argument == "" {
+				return errors.New("Value of " + operator + " must be a string or an equals-ignore-case object")
+			}
+			return nil
+		}
+		return o.validateString(operator, value)
+	case "equals-ignore-case":
+		return o.validateString(operator, value)
+	case "wildcard":
+		if o.FilterPolicy {
+			return errors.New("Unrecognized match type " + operator)
+		}
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("wildcard match pattern must be a string")
+		}
+		if strings.Contains(s, "**") {
+			return errors.New("Consecutive wildcard characters at pos " + strconv.Itoa(strings.Index(s, "**")+1))
+		}
+	case "exists":
+		if _, ok := value.(bool); !ok {
+			return errors.New("exists match pattern must be either true or false")
+		}
+	case "anything-but":
+		switch v := value.(type) {
+		case string, json.Number:
+		case []any:
+			if len(v) == 0 {
+				return errors.New("Empty arrays are not allowed in anything-but")
+			}
+			for _, element := range v {
+				switch element.(type) {
+				case string, json.Number:
+				default:
+					return errors.New("Inside anything-but list, only strings and numbers are allowed")
+				}
+			}
+		case map[string]any:
+			if len(v) != 1 {
+				return errors.New("Value of anything-but must be an array or single string/number value")
+			}
+			for nestedOperator, nestedValue := range v {
+				switch {
+				case nestedOperator == "prefix" || nestedOperator == "suffix":
+					return o.validateString(nestedOperator, nestedValue)
+				case (nestedOperator == "equals-ignore-case" || nestedOperator == "wildcard") && !o.FilterPolicy:
+					// These can also exclude a list of values.
+					values, ok := nestedValue.([]any)
+					if !ok {
+						values = []any{nestedValue}
+					}
+					for _, element := range values {
+						if err := o.validateOperator(nestedOperator, element); err != nil {
+							return err
+						}
+					}
+					return nil
+				default:
+					return errors.New("Unsupported anything-but pattern: " + nestedOperator)
+				}
+			}
+		default:
+			return errors.New("Value of anything-but must be an array or single string/number value")
+		}
+	case "numeric":
+		return validateNumeric(value)
+	case "cidr":
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("cidr match pattern must be a string")
+		}
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return errors.New("Malformed CIDR, one '/' required")
+		}
+	default:
+		return errors.New("Unrecognized match type " + operator)
+	}
+	return nil
+}
+
+// validateString checks the operator's value is a string, which SNS requires to be non-empty.
+func (o Options) validateString(operator string, value any) error {
+	s, ok := value.(string)
+	if o.FilterPolicy && (!ok || s == "") {
+		return errors.New(operator + " match pattern must be a non-empty string")
+	}
+	if !ok {
+		return errors.New(operator + " match pattern must be a string")
+	}
+	return nil
+}
+
+// Numeric conditions are either ["=", n], or a lower and/or upper bound like [">", 0, "<=", 5].
+func validateNumeric(value any) error {
+	parts, ok := value.([]any)
+	if !ok || len(parts) == 0 || len(parts)%2 != 0 || len(parts) > 4 {
+		return errors.New("Value of numeric must be an array.")
+	}
+	var hasLower, hasUpper bool
+	var lower, upper float64
+	for i := 0; i < len(parts); i += 2 {
+		operator, _ := parts[i].(string)
+		n, ok := number(parts[i+1])
+		if !ok {
+			return errors.New("Value of " + operator + " must be numeric")
+		}
+		switch operator {
+		case "=":
+			if len(parts) != 2 {
+				return errors.New("Value of = must be the only numeric condition")
+			}
+		case ">", ">=":
+			if hasLower {
+				return errors.New("Too many elements in numeric expression")
+			}
+			hasLower, lower = true, n
+		case "<", "<=":
+			if hasUpper {
+				return errors.New("Too many elements in numeric expression")
+			}
+			hasUpper, upper = true, n
+		default:
+			return errors.New("Unrecognized numeric range operator: " + operator)
+		}
+	}
+	if hasLower && hasUpper && lower >= upper {
+		return errors.New("Bottom must be less than top")
+	}
+	return nil
+}
+
+// matchObject matches every field of the pattern, and one of the alternatives of any $or.
+func matchObject(pattern map[string]any, data map[string]any) bool {
+	for key, condition := range pattern {
+		if key == "$or" {
+			matched := false
+			for _, alternative := range condition.([]any) {
+				if matchObject(alternative.(map[string]any), data) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+
+		value, present := data[key]
+		switch c := condition.(type) {
+		case map[string]any:
+			// A missing object is matched as empty, so that {"exists": false} conditions inside it match.
+			nested, ok := value.(map[string]any)
+			if present && !ok {
+				return false
+			}
+			if !matchObject(c, nested) {
+				return false
+			}
+		case []any:
+			matched := false
+			for _, element := range c {
+				if matchCondition(element, value, present) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchCondition(condition any, value any, present bool) bool {
+	if operator, ok := condition.(map[string]any); ok {
+		if exists, ok := operator["exists"]; ok {
+			// Only values exist, not objects.
+			_, isObject := value.(map[string]any)
+			return (present && !isObject) == exists.(bool)
+		}
+	}
+	if !present {
+		return false
+	}
+	// Arrays match if any of their values match.
+	if values, ok := value.([]any); ok {
+		for _, v := range values {
+			if matchValue(condition, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return matchValue(condition, value)
+}
+
+func matchValue(condition any, value any) bool {
+	switch c := condition.(type) {
+	case string:
+		s, ok := value.(string)
+		return ok && s == c
+	case json.Number:
+		expected, _ := number(c)
+		n, ok := number(value)
+		return ok && n == expected
+	case bool:
+		b, ok := value.(bool)
+		return ok && b == c
+	case nil:
+		return value == nil
+	}
+
+	for operator, argument := range condition.(map[string]any) {
+		s, isString := value.(string)
+		switch operator {
+		case "prefix":
+			if nested, ok := argument.(map[string]any); ok {
+				prefix := nested["equals-ignore-case"].(string)
+				return isString && len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+			}
+			return isString && strings.HasPrefix(s, argument.(string))
+		case "suffix":
+			if nested, ok := argument.(map[string]any); ok {
+				suffix := nested["equals-ignore-case"].(string)
+				return isString && len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+			}
+			return isString && strings.HasSuffix(s, argument.(string))
+		case "equals-ignore-case":
+			return isString && strings.EqualFold(s, argument.(string))
+		case "wildcard":
+			return isString && matchWildcard(argument.(string), s)
+		case "anything-but":
+			return matchAnythingBut(argument, value)
+		case "numeric":
+			n, ok := number(value)
+			return ok && matchNumeric(argument.([]any), n)
+		case "cidr":
+			if !isString {
+				return false
+			}
+			_, network, _ := net.ParseCIDR(argument.(string))
+			ip := net.ParseIP(s)
+			return ip != nil && network.Contains(ip)
+		}
+	}
+	return false
+}
+
+// matchAnythingBut reports whether the value matches none of the excluded values or operators.
+func matchAnythingBut(argument any, value any) bool {
+	switch a := argument.(type) {
+	case []any:
+		for _, excluded := range a {
+			if matchValue(excluded, value) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		for operator, nested := range a {
+			if values, ok := nested.([]any); ok {
+				for _, excluded := range values {
+					if matchValue(map[string]any{operator: excluded}, value) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+	}
+	return !matchValue(argument, value)
+}
+
+// matchWildcard matches the string against the pattern, where * matches any characters and \* is a literal *.
+func matchWildcard(pattern string, s string) bool {
+	// Split the pattern into its literal parts between wildcards.
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern) && (pattern[i+1] == '*' || pattern[i+1] == '\\'):
+			part.WriteByte(pattern[i+1])
+			i++
+		case pattern[i] == '*':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(pattern[i])
+		}
+	}
+	parts = append(parts, part.String())
+
+	if len(parts) == 1 {
+		return s == parts[0]
+	}
+	// The first part must be a prefix and the last a suffix, and the others must appear in order between them.
+	first, last := parts[0], parts[len(parts)-1]
+	if len(s) < len(first)+len(last) || !strings.HasPrefix(s, first) || !strings.HasSuffix(s, last) {
+		return false
+	}
+	middle := s[len(first) : len(s)-len(last)]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(middle, p)
+		if i < 0 {
+			return false
+		}
+		middle = middle[i+len(p):]
+	}
+	return true
+}
+
+func number(value any) (float64, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+func matchNumeric(parts []any, n float64) bool {
+	for i := 0; i < len(parts); i += 2 {
+		bound, _ := number(parts[i+1])
+		var ok bool
+		switch parts[i].(string) {
+		case "=":
+			ok = n == bound
+		case ">":
+			ok = n > bound
+		case ">=":
+			ok = n >= bound
+		case "<":
+			ok = n < bound
+		case "<=":
+			ok = n <= bound
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
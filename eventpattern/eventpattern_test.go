@@ -0,0 +1,76 @@
+package eventpattern
+
+import (
+	"testing"
+)
+
+func parse(t *testing.T, value string, options Options) (*Pattern, error) {
+	t.Helper()
+	var object map[string]any
+	if err := Decode(value, &object); err != nil {
+		t.Fatal(value, err)
+	}
+	return New(object, options)
+}
+
+func TestMatches(t *testing.T) {
+	var data map[string]any
+	if err := Decode(`{"state": "terminated", "count": 5, "tags": ["prod", "web"], "detail": {"ip": "10.0.0.1"}}`, &data); err != nil {
+		t.Fatal(err)
+	}
+	for pattern, want := range map[string]bool{
+		`{"state": ["terminated"]}`:                               true,
+		`{"count": [5.0]}`:                                        true,
+		`{"count": ["5"]}`:                                        false,
+		`{"tags": ["web"]}`:                                       true,
+		`{"detail": {"ip": [{"cidr": "10.0.0.0/24"}]}}`:           true,
+		`{"detail": [{"exists": true}]}`:                          false,
+		`{"missing": {"field": [{"exists": false}]}}`:             true,
+		`{"state": [{"wildcard": "term*ted"}]}`:                   true,
+		`{"count": [{"numeric": [">", 1, "<", 5]}]}`:              false,
+		`{"$or": [{"state": ["running"]}, {"count": [5]}]}`:       true,
+		`{"state": [{"anything-but": {"prefix": "term"}}]}`:       false,
+		`{"state": [{"prefix": {"equals-ignore-case": "TERM"}}]}`: true,
+	} {
+		p, err := parse(t, pattern, Options{})
+		if err != nil {
+			t.Fatal(pattern, err)
+		}
+		if p.Matches(data) != want {
+			t.Error("Wrong result for", pattern)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		options Options
+		valid   bool
+	}{
+		{`{"a": {"b": ["c"]}}`, Options{}, true},
+		{`{"a": {"b": ["c"]}}`, Options{Flat: true}, false},
+		{`{"a": [{"wildcard": "c*"}]}`, Options{}, true},
+		{`{"a": [{"wildcard": "c*"}]}`, Options{FilterPolicy: true}, false},
+		{`{"a": [{"prefix": ""}]}`, Options{}, true},
+		{`{"a": [{"prefix": ""}]}`, Options{FilterPolicy: true}, false},
+		{`{"a": [{"prefix": {"equals-ignore-case": "c"}}]}`, Options{FilterPolicy: true}, false},
+		{`{"a": [{"anything-but": {"equals-ignore-case": "c"}}]}`, Options{FilterPolicy: true}, false},
+		{`{"a": [{"anything-but": {"prefix": "c"}}]}`, Options{FilterPolicy: true}, true},
+	} {
+		_, err := parse(t, test.pattern, test.options)
+		if (err == nil) != test.valid {
+			t.Errorf("%s with %+v: got %v, want valid %v", test.pattern, test.options, err, test.valid)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	p, err := parse(t, `{"a": ["1"], "b": {"c": [1], "d": [2]}, "$or": [{"e": [1]}, {"f": [1], "g": [1]}]}`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Keys() != 5 {
+		t.Fatal("Unexpected keys", p.Keys())
+	}
+}
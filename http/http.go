@@ -15,6 +15,8 @@ import (
 	"github.com/fxamacker/cbor/v2"
 
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/calls"
+	"aws-in-a-box/validation"
 )
 
 const (
@@ -94,11 +96,24 @@ func Register[Input any, Output any](
 	service string,
 	method string,
 	handler func(input Input) (*Output, *awserrors.Error),
+) {
+	RegisterWithRequest(logger, registry, service, method, func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+		return handler(input)
+	})
+}
+
+// RegisterWithRequest is like Register, but also passes the handler the request, for operations
+// which depend on more than their parameters, such as the region they're for.
+func RegisterWithRequest[Input any, Output any](
+	logger *slog.Logger,
+	registry map[string]http.HandlerFunc,
+	service string,
+	method string,
+	handler func(r *http.Request, input Input) (*Output, *awserrors.Error),
 ) {
 	logger = logger.With("method", method)
+	validator := validation.New[Input]()
 	registry[service+"."+method] = func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("Handling request")
-
 		contentType := r.Header.Get("Content-Type")
 
 		var input Input
@@ -107,10 +122,17 @@ func Register[Input any, Output any](
 			logger.Error("Unmarshaling input", "err", err)
 			panic(fmt.Errorf("%s: %v", method, err))
 		}
+		if awserr := validator.Validate(input); awserr != nil {
+			logger.Debug("Invalid input", "error", awserr)
+			calls.Record(r, method, awserr)
+			writeResponse(w, nil, awserr, contentType)
+			return
+		}
 		logger.Debug("Parsed input", "input", input)
 
-		output, awserr := handler(input)
+		output, awserr := handler(r, input)
 		logger.Debug("Got output", "output", output, "error", awserr)
+		calls.Record(r, method, awserr)
 
 		writeResponse(w, output, awserr, contentType)
 	}
@@ -124,11 +146,24 @@ func RegisterOutputStream[Input any, Output any](
 	service string,
 	method string,
 	handler func(input Input) (chan *Output, *awserrors.Error),
+) {
+	RegisterOutputStreamWithRequest(logger, registry, service, method, func(r *http.Request, input Input) (chan *Output, *awserrors.Error) {
+		return handler(input)
+	})
+}
+
+// RegisterOutputStreamWithRequest is like RegisterOutputStream, but also passes the handler the
+// request, like RegisterWithRequest.
+func RegisterOutputStreamWithRequest[Input any, Output any](
+	logger *slog.Logger,
+	registry map[string]http.HandlerFunc,
+	service string,
+	method string,
+	handler func(r *http.Request, input Input) (chan *Output, *awserrors.Error),
 ) {
 	logger = logger.With("method", method)
+	validator := validation.New[Input]()
 	registry[service+"."+method] = func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("Handling request")
-
 		contentType := r.Header.Get("Content-Type")
 
 		var input Input
@@ -137,8 +172,15 @@ func RegisterOutputStream[Input any, Output any](
 			logger.Error("Unmarshaling input", "err", err)
 			panic(fmt.Errorf("%s: %v", method, err))
 		}
+		if awserr := validator.Validate(input); awserr != nil {
+			logger.Debug("Invalid input", "error", awserr)
+			calls.Record(r, method, awserr)
+			writeResponse(w, nil, awserr, contentType)
+			return
+		}
 
-		outputCh, awserr := handler(input)
+		outputCh, awserr := handler(r, input)
+		calls.Record(r, method, awserr)
 
 		w.WriteHeader(http.StatusOK)
 		w.Write(encodeEvent("initial-response", nil, awserr))
@@ -0,0 +1,471 @@
+package query
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/calls"
+	"aws-in-a-box/validation"
+)
+
+// This package implements the AWS Query protocol, which older services (and older SDKs for SQS) use.
+// See https://smithy.io/2.0/aws/protocols/aws-query-protocol.html
+//
+// Fields use their Go name unless they have a `query:"Name"` tag. Lists are sent as Name.member.1,
+// Name.member.2, ... and maps as lists of entries (Name.entry.1.key, Name.entry.1.value), unless
+// the protocol is flattened, in which case they're Name.1, Name.2, ... and Name.1.Name, Name.1.Value.
+// Maps with a `query:"Name,Key,Value"` tag use Key and Value rather than the default entry keys.
+// Timestamps are ISO 8601.
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Protocol describes how a service uses the Query protocol.
+type Protocol struct {
+	// Version is the API version clients send with every request, such as 2012-11-05.
+	Version      string
+	XMLNamespace string
+	// ErrorCodes maps the error types used by the service to their Query protocol codes,
+	// where they differ.
+	ErrorCodes map[string]string
+	Flattened  bool
+}
+
+// Registry holds the handlers for a service's actions.
+type Registry struct {
+	protocol Protocol
+	handlers map[string]http.HandlerFunc
+}
+
+func NewRegistry(protocol Protocol) *Registry {
+	return &Registry{
+		protocol: protocol,
+		handlers: make(map[string]http.HandlerFunc),
+	}
+}
+
+func Register[Input any, Output any](
+	logger *slog.Logger,
+	registry *Registry,
+	method string,
+	handler func(input Input) (*Output, *awserrors.Error),
+) {
+	RegisterWithRequest(logger, registry, method, func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+		return handler(input)
+	})
+}
+
+// RegisterWithRequest is like Register, but also passes the handler the request, for actions which
+// depend on more than their parameters, such as who the caller is.
+func RegisterWithRequest[Input any, Output any](
+	logger *slog.Logger,
+	registry *Registry,
+	method string,
+	handler func(r *http.Request, input Input) (*Output, *awserrors.Error),
+) {
+	p := &registry.protocol
+	logger = logger.With("method", method)
+	validator := validation.New[Input]()
+	registry.handlers[method] = func(w http.ResponseWriter, r *http.Request) {
+		var input Input
+		err := p.unmarshal(r.Form, "", reflect.ValueOf(&input).Elem())
+		if err != nil {
+			logger.Error("Unmarshaling input", "err", err)
+			panic(fmt.Errorf("%s: %v", method, err))
+		}
+		logger.Debug("Parsed input", "input", input)
+
+		var output *Output
+		awserr := validator.Validate(input)
+		if awserr == nil {
+			output, awserr = handler(r, input)
+		}
+		logger.Debug("Got output", "output", output, "error", awserr)
+		calls.Record(r, method, awserr)
+
+		requestId := uuid.Must(uuid.NewV4()).String()
+		if awserr != nil {
+			p.marshalError(w, awserr, requestId)
+		} else {
+			p.marshal(w, method, output, requestId)
+		}
+	}
+}
+
+// Operations returns the names of the registry's actions.
+func (r *Registry) Operations() []string {
+	operations := make([]string, 0, len(r.handlers))
+	for action := range r.handlers {
+		operations = append(operations, action)
+	}
+	slices.Sort(operations)
+	return operations
+}
+
+// NewHandler returns a handler for the server's handler chain, which handles Query requests
+// for the registry's actions.
+func NewHandler(registry *Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		// Actions can also be requested with GET, such as the links in SNS subscription confirmations.
+		isGet := r.Method == http.MethodGet && r.URL.Query().Has("Action")
+		if mediaType != "application/x-www-form-urlencoded" && !isGet {
+			return false
+		}
+
+		// ParseForm consumes the body, which is restored for the rest of the chain,
+		// such as function URLs, in case the request isn't for this registry.
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ParseForm()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// Services may have actions with the same name, so the version tells them apart.
+		if version := r.Form.Get("Version"); version != "" && version != registry.protocol.Version {
+			return false
+		}
+		handler, ok := registry.handlers[r.Form.Get("Action")]
+		if !ok {
+			return false
+		}
+		handler(w, r)
+		return true
+	}
+}
+
+// queryName returns the name of the field in Query requests and responses,
+// and for maps, the names of the entry keys and values.
+func (p *Protocol) queryName(field reflect.StructField) (string, string, string) {
+	parts := strings.Split(field.Tag.Get("query"), ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	keyName, valueName := p.entryNames()
+	if len(parts) > 1 && parts[1] != "" {
+		keyName = parts[1]
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		valueName = parts[2]
+	}
+	return name, keyName, valueName
+}
+
+// entryNames returns the default names of map entries' keys and values.
+func (p *Protocol) entryNames() (string, string) {
+	if p.Flattened {
+		return "Name", "Value"
+	}
+	return "key", "value"
+}
+
+// listPrefix returns the prefix of the i'th member of the list or map with the given prefix.
+func (p *Protocol) listPrefix(prefix string, kind reflect.Kind, i int) string {
+	if p.Flattened {
+		return fmt.Sprintf("%s.%d", prefix, i)
+	}
+	if kind == reflect.Map {
+		return fmt.Sprintf("%s.entry.%d", prefix, i)
+	}
+	return fmt.Sprintf("%s.member.%d", prefix, i)
+}
+
+// hasPrefix returns whether the form has any values for the parameter or its members.
+func hasPrefix(form url.Values, name string) bool {
+	for k := range form {
+		if k == name || strings.HasPrefix(k, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshal decodes the form parameters under the prefix into v.
+func (p *Protocol) unmarshal(form url.Values, prefix string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			if !form.Has(prefix) {
+				return nil
+			}
+			t, err := time.Parse(time.RFC3339, form.Get(prefix))
+			if err != nil {
+				return fmt.Errorf("%s: %v", prefix, err)
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+		ty := v.Type()
+		for i := 0; i < ty.NumField(); i++ {
+			field := ty.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, keyName, valueName := p.queryName(field)
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			if v.Field(i).Kind() == reflect.Map {
+				err := p.unmarshalMap(form, name, keyName, valueName, v.Field(i))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err := p.unmarshal(form, name, v.Field(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Pointer:
+		if !hasPrefix(form, prefix) {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		return p.unmarshal(form, prefix, v.Elem())
+	case reflect.Map:
+		// Maps which are fields are handled with their tags above, so these are maps in lists or maps.
+		keyName, valueName := p.entryNames()
+		return p.unmarshalMap(form, prefix, keyName, valueName, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if !form.Has(prefix) {
+				return nil
+			}
+			data, err := base64.StdEncoding.DecodeString(form.Get(prefix))
+			if err != nil {
+				return fmt.Errorf("%s: %v", prefix, err)
+			}
+			v.SetBytes(data)
+			return nil
+		}
+		for i := 1; hasPrefix(form, p.listPrefix(prefix, reflect.Slice, i)); i++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err := p.unmarshal(form, p.listPrefix(prefix, reflect.Slice, i), elem)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+		return nil
+	}
+
+	// Scalars are left as the zero value if they're missing.
+	if !form.Has(prefix) {
+		return nil
+	}
+	value := form.Get(prefix)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %v", prefix, err)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %v", prefix, err)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", prefix, err)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("%s: unsupported type %v", prefix, v.Type())
+	}
+	return nil
+}
+
+func (p *Protocol) unmarshalMap(form url.Values, prefix string, keyName string, valueName string, v reflect.Value) error {
+	for i := 1; hasPrefix(form, p.listPrefix(prefix, reflect.Map, i)); i++ {
+		entry := p.listPrefix(prefix, reflect.Map, i)
+		if !form.Has(entry + "." + keyName) {
+			return fmt.Errorf("%s: missing %s", entry, keyName)
+		}
+		value := reflect.New(v.Type().Elem()).Elem()
+		err := p.unmarshal(form, entry+"."+valueName, value)
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(reflect.ValueOf(form.Get(entry+"."+keyName)), value)
+	}
+	return nil
+}
+
+func (p *Protocol) marshal(w http.ResponseWriter, method string, output any, requestId string) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(http.StatusOK)
+
+	e := xml.NewEncoder(w)
+	response := xml.StartElement{
+		Name: xml.Name{Local: method + "Response"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: p.XMLNamespace}},
+	}
+	encodeToken(e, response)
+	if v := reflect.ValueOf(output); !v.IsNil() {
+		p.encodeValue(e, method+"Result", "", "", v.Elem())
+	}
+	p.encodeValue(e, "ResponseMetadata", "", "", reflect.ValueOf(ResponseMetadata{RequestId: requestId}))
+	encodeToken(e, response.End())
+	if err := e.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+type ResponseMetadata struct {
+	RequestId string
+}
+
+// https://smithy.io/2.0/aws/protocols/aws-query-protocol.html#operation-error-serialization
+func (p *Protocol) marshalError(w http.ResponseWriter, awserr *awserrors.Error, requestId string) {
+	code, ok := p.ErrorCodes[awserr.Body.Type]
+	if !ok {
+		code = awserr.Body.Type
+	}
+	errorType := "Sender"
+	if awserr.Code >= 500 {
+		errorType = "Receiver"
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(awserr.Code)
+	err := xml.NewEncoder(w).Encode(errorResponse{
+		Error: queryError{
+			Type:    errorType,
+			Code:    code,
+			Message: awserr.Body.Message,
+		},
+		RequestId: requestId,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Error     queryError
+	RequestId string
+}
+
+type queryError struct {
+	Type    string
+	Code    string
+	Message string
+}
+
+func encodeToken(e *xml.Encoder, token xml.Token) {
+	if err := e.EncodeToken(token); err != nil {
+		panic(err)
+	}
+}
+
+func encodeElement(e *xml.Encoder, name string, value string) {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	encodeToken(e, start)
+	encodeToken(e, xml.CharData(value))
+	encodeToken(e, start.End())
+}
+
+// encodeValue writes v as an element with the given name. Zero values are omitted,
+// except for the top level structs.
+func (p *Protocol) encodeValue(e *xml.Encoder, name string, keyName string, valueName string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			encodeElement(e, name, v.Interface().(time.Time).UTC().Format(time.RFC3339))
+			return
+		}
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		encodeToken(e, start)
+		ty := v.Type()
+		for i := 0; i < ty.NumField(); i++ {
+			field := ty.Field(i)
+			if !field.IsExported() || v.Field(i).IsZero() {
+				continue
+			}
+			fieldName, fieldKeyName, fieldValueName := p.queryName(field)
+			p.encodeValue(e, fieldName, fieldKeyName, fieldValueName, v.Field(i))
+		}
+		encodeToken(e, start.End())
+	case reflect.Pointer:
+		if !v.IsNil() {
+			p.encodeValue(e, name, keyName, valueName, v.Elem())
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			encodeElement(e, name, base64.StdEncoding.EncodeToString(v.Bytes()))
+			return
+		}
+		if p.Flattened {
+			for i := 0; i < v.Len(); i++ {
+				p.encodeValue(e, name, "", "", v.Index(i))
+			}
+			return
+		}
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		encodeToken(e, start)
+		for i := 0; i < v.Len(); i++ {
+			p.encodeValue(e, "member", "", "", v.Index(i))
+		}
+		encodeToken(e, start.End())
+	case reflect.Map:
+		if keyName == "" {
+			keyName, valueName = p.entryNames()
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(a.String(), b.String())
+		})
+		entryName := name
+		if !p.Flattened {
+			start := xml.StartElement{Name: xml.Name{Local: name}}
+			encodeToken(e, start)
+			defer encodeToken(e, start.End())
+			entryName = "entry"
+		}
+		for _, key := range keys {
+			start := xml.StartElement{Name: xml.Name{Local: entryName}}
+			encodeToken(e, start)
+			encodeElement(e, keyName, key.String())
+			p.encodeValue(e, valueName, "", "", v.MapIndex(key))
+			encodeToken(e, start.End())
+		}
+	case reflect.String:
+		encodeElement(e, name, v.String())
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		encodeElement(e, name, strconv.FormatInt(v.Int(), 10))
+	case reflect.Float64:
+		encodeElement(e, name, strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	case reflect.Bool:
+		encodeElement(e, name, strconv.FormatBool(v.Bool()))
+	default:
+		panic(fmt.Sprintf("%s: unsupported type %v", name, v.Type()))
+	}
+}
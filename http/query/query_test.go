@@ -0,0 +1,383 @@
+package query
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+type attribute struct {
+	Name  string
+	Value *string
+}
+
+type unmarshalInput struct {
+	QueueName  string
+	Delay      int
+	Ratio      float64
+	Enabled    bool
+	Body       []byte
+	Timestamp  time.Time
+	Limit      *int
+	Names      []string `query:"AttributeName"`
+	Attributes []attribute
+	Nested     [][]string
+	Tags       map[string]string            `query:"Tag,Key,Value"`
+	Entries    map[string]string            `query:"Entry"`
+	Structured map[string]attribute         `query:"Structured"`
+	Lists      map[string][]string          `query:"Lists"`
+	Inner      *struct{ Names []string }    `query:"Inner"`
+	Deep       map[string]map[string]string `query:"Deep"`
+	unexported string
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestUnmarshal(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		flattened bool
+		form      string
+		want      unmarshalInput
+	}{
+		{
+			name: "scalars",
+			form: "QueueName=q&Delay=5&Ratio=0.5&Enabled=true&Body=aGk%3D&Timestamp=2023-01-02T03:04:05Z&Limit=0",
+			want: unmarshalInput{
+				QueueName: "q",
+				Delay:     5,
+				Ratio:     0.5,
+				Enabled:   true,
+				Body:      []byte("hi"),
+				Timestamp: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+				Limit:     intPtr(0),
+			},
+		},
+		{
+			name: "missing values are zero",
+			form: "Unknown=1",
+		},
+		{
+			name: "lists",
+			form: "AttributeName.member.1=a&AttributeName.member.2=b&AttributeName.member.3=c",
+			want: unmarshalInput{Names: []string{"a", "b", "c"}},
+		},
+		{
+			name: "lists stop at the first gap",
+			form: "AttributeName.member.1=a&AttributeName.member.3=c",
+			want: unmarshalInput{Names: []string{"a"}},
+		},
+		{
+			name: "lists start at 1",
+			form: "AttributeName.member.0=a&AttributeName.member.2=b",
+		},
+		{
+			name: "lists of structs",
+			form: "Attributes.member.1.Name=a&Attributes.member.1.Value=1&Attributes.member.2.Name=b",
+			want: unmarshalInput{Attributes: []attribute{{Name: "a", Value: strPtr("1")}, {Name: "b"}}},
+		},
+		{
+			name: "nested lists",
+			form: "Nested.member.1.member.1=a&Nested.member.1.member.2=b&Nested.member.2.member.1=c",
+			want: unmarshalInput{Nested: [][]string{{"a", "b"}, {"c"}}},
+		},
+		{
+			name: "maps with named keys and values",
+			form: "Tag.entry.1.Key=env&Tag.entry.1.Value=prod&Tag.entry.2.Key=team&Tag.entry.2.Value=",
+			want: unmarshalInput{Tags: map[string]string{"env": "prod", "team": ""}},
+		},
+		{
+			name: "maps with default keys and values",
+			form: "Entry.entry.1.key=a&Entry.entry.1.value=1",
+			want: unmarshalInput{Entries: map[string]string{"a": "1"}},
+		},
+		{
+			name: "maps of structs",
+			form: "Structured.entry.1.key=a&Structured.entry.1.value.Name=n&Structured.entry.1.value.Value=v",
+			want: unmarshalInput{Structured: map[string]attribute{"a": {Name: "n", Value: strPtr("v")}}},
+		},
+		{
+			name: "maps of lists",
+			form: "Lists.entry.1.key=a&Lists.entry.1.value.member.1=x&Lists.entry.1.value.member.2=y",
+			want: unmarshalInput{Lists: map[string][]string{"a": {"x", "y"}}},
+		},
+		{
+			name: "nested structs",
+			form: "Inner.Names.member.1=a",
+			want: unmarshalInput{Inner: &struct{ Names []string }{Names: []string{"a"}}},
+		},
+		{
+			name:      "flattened lists",
+			flattened: true,
+			form:      "AttributeName.1=a&AttributeName.2=b&Attributes.1.Name=c",
+			want:      unmarshalInput{Names: []string{"a", "b"}, Attributes: []attribute{{Name: "c"}}},
+		},
+		{
+			name:      "flattened maps",
+			flattened: true,
+			form:      "Entry.1.Name=a&Entry.1.Value=1&Entry.2.Name=b&Entry.2.Value=2&Tag.1.Key=k&Tag.1.Value=v",
+			want: unmarshalInput{
+				Entries: map[string]string{"a": "1", "b": "2"},
+				Tags:    map[string]string{"k": "v"},
+			},
+		},
+		{
+			name:      "flattened nested lists",
+			flattened: true,
+			form:      "Nested.1.1=a&Nested.1.2=b&Nested.2.1=c",
+			want:      unmarshalInput{Nested: [][]string{{"a", "b"}, {"c"}}},
+		},
+		{
+			name:      "flattened nested maps",
+			flattened: true,
+			form:      "Deep.1.Name=a&Deep.1.Value.1.Name=b&Deep.1.Value.1.Value=c",
+			want:      unmarshalInput{Deep: map[string]map[string]string{"a": {"b": "c"}}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			form, err := url.ParseQuery(test.form)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Protocol{Flattened: test.flattened}
+			var got unmarshalInput
+			if err := p.unmarshal(form, "", reflect.ValueOf(&got).Elem()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, test := range []struct {
+		form string
+		want string
+	}{
+		{"Delay=soon", "Delay"},
+		{"Ratio=half", "Ratio"},
+		{"Enabled=maybe", "Enabled"},
+		{"Body=%21%21", "Body"},
+		{"Timestamp=yesterday", "Timestamp"},
+		{"Limit=x", "Limit"},
+		{"Tag.entry.1.Value=v", "Tag.entry.1: missing Key"},
+		{"Lists.entry.1.key=a&Lists.entry.2.value.member.1=x", "Lists.entry.2: missing key"},
+	} {
+		t.Run(test.form, func(t *testing.T) {
+			form, err := url.ParseQuery(test.form)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got unmarshalInput
+			err = (&Protocol{}).unmarshal(form, "", reflect.ValueOf(&got).Elem())
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+type echoOutput struct {
+	Names      []string `query:"AttributeName"`
+	Attributes []attribute
+	Tags       map[string]string `query:"Tag,Key,Value"`
+	Entries    map[string]string `query:"Entry"`
+	Deep       map[string]map[string]string
+	Count      int
+	Ratio      float64
+	Enabled    bool
+	Body       []byte
+	Timestamp  time.Time
+	Empty      string
+}
+
+func serve(t *testing.T, protocol Protocol, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	registry := NewRegistry(protocol)
+	Register(slog.Default(), registry, "Echo", func(input unmarshalInput) (*echoOutput, *awserrors.Error) {
+		if input.QueueName == "missing" {
+			return nil, awserrors.Generate400Exception("QueueDoesNotExist", "The queue doesn't exist")
+		}
+		if input.QueueName == "broken" {
+			return nil, &awserrors.Error{Code: 500, Body: awserrors.ErrorBody{Type: "InternalError", Message: "Broken"}}
+		}
+		return &echoOutput{
+			Names:      input.Names,
+			Attributes: input.Attributes,
+			Tags:       input.Tags,
+			Entries:    input.Entries,
+			Deep:       input.Deep,
+			Count:      len(input.Names),
+			Ratio:      0.25,
+			Enabled:    true,
+			Body:       []byte("hi"),
+			Timestamp:  time.Date(2023, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)),
+		}, nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if !NewHandler(registry)(w, r) {
+		t.Fatal("request wasn't handled")
+	}
+	return w
+}
+
+func TestMarshal(t *testing.T) {
+	form := url.Values{
+		"Action":                           {"Echo"},
+		"Version":                          {"2012-11-05"},
+		"AttributeName.member.1":           {"a"},
+		"AttributeName.member.2":           {"b"},
+		"Attributes.member.1.Name":         {"n"},
+		"Tag.entry.1.Key":                  {"z"},
+		"Tag.entry.1.Value":                {"1"},
+		"Tag.entry.2.Key":                  {"y"},
+		"Tag.entry.2.Value":                {"2"},
+		"Deep.entry.1.key":                 {"a"},
+		"Deep.entry.1.value.entry.1.key":   {"b"},
+		"Deep.entry.1.value.entry.1.value": {"c"},
+	}
+	w := serve(t, Protocol{Version: "2012-11-05", XMLNamespace: "http://queue.amazonaws.com/doc/2012-11-05/"}, form)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/xml" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`<EchoResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/"><EchoResult>`,
+		`<AttributeName><member>a</member><member>b</member></AttributeName>`,
+		`<Attributes><member><Name>n</Name></member></Attributes>`,
+		// Map entries are sorted by key.
+		`<Tag><entry><Key>y</Key><Value>2</Value></entry><entry><Key>z</Key><Value>1</Value></entry></Tag>`,
+		`<Deep><entry><key>a</key><value><entry><key>b</key><value>c</value></entry></value></entry></Deep>`,
+		`<Count>2</Count><Ratio>0.25</Ratio><Enabled>true</Enabled><Body>aGk=</Body>`,
+		`<Timestamp>2023-01-02T02:04:05Z</Timestamp></EchoResult>`,
+		`<ResponseMetadata><RequestId>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response doesn't contain %s:\n%s", want, body)
+		}
+	}
+	// Zero values are omitted.
+	for _, unwanted := range []string{"<Empty>", "<Entry>"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("response contains %s:\n%s", unwanted, body)
+		}
+	}
+}
+
+func TestMarshalFlattened(t *testing.T) {
+	form := url.Values{
+		"Action":               {"Echo"},
+		"AttributeName.1":      {"a"},
+		"AttributeName.2":      {"b"},
+		"Entry.1.Name":         {"k"},
+		"Entry.1.Value":        {"v"},
+		"Attributes.1.Name":    {"n"},
+		"Deep.1.Name":          {"a"},
+		"Deep.1.Value.1.Name":  {"b"},
+		"Deep.1.Value.1.Value": {"c"},
+	}
+	w := serve(t, Protocol{Flattened: true}, form)
+	body := w.Body.String()
+	for _, want := range []string{
+		`<AttributeName>a</AttributeName><AttributeName>b</AttributeName>`,
+		`<Attributes><Name>n</Name></Attributes>`,
+		`<Entry><Name>k</Name><Value>v</Value></Entry>`,
+		`<Deep><Name>a</Name><Value><Name>b</Name><Value>c</Value></Value></Deep>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response doesn't contain %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	for _, test := range []struct {
+		queueName string
+		codes     map[string]string
+		status    int
+		want      errorResponse
+	}{
+		{
+			queueName: "missing",
+			status:    http.StatusBadRequest,
+			want:      errorResponse{Error: queryError{Type: "Sender", Code: "QueueDoesNotExist", Message: "The queue doesn't exist"}},
+		},
+		{
+			queueName: "missing",
+			codes:     map[string]string{"QueueDoesNotExist": "AWS.SimpleQueueService.NonExistentQueue"},
+			status:    http.StatusBadRequest,
+			want:      errorResponse{Error: queryError{Type: "Sender", Code: "AWS.SimpleQueueService.NonExistentQueue", Message: "The queue doesn't exist"}},
+		},
+		{
+			queueName: "broken",
+			status:    http.StatusInternalServerError,
+			want:      errorResponse{Error: queryError{Type: "Receiver", Code: "InternalError", Message: "Broken"}},
+		},
+	} {
+		t.Run(test.want.Error.Code, func(t *testing.T) {
+			w := serve(t, Protocol{ErrorCodes: test.codes}, url.Values{"Action": {"Echo"}, "QueueName": {test.queueName}})
+			if w.Code != test.status || w.Header().Get("Content-Type") != "text/xml" {
+				t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+			}
+			var got errorResponse
+			if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.RequestId == "" {
+				t.Error("missing RequestId")
+			}
+			got.XMLName, got.RequestId = xml.Name{}, ""
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewHandlerSkipsOtherRequests(t *testing.T) {
+	registry := NewRegistry(Protocol{Version: "2012-11-05"})
+	Register(slog.Default(), registry, "Echo", func(input unmarshalInput) (*echoOutput, *awserrors.Error) {
+		return &echoOutput{}, nil
+	})
+	handler := NewHandler(registry)
+
+	for name, r := range map[string]*http.Request{
+		"other action":  formRequest("Action=Other"),
+		"other version": formRequest("Action=Echo&Version=2010-03-31"),
+		"json":          httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Action": "Echo"}`)),
+	} {
+		if handler(httptest.NewRecorder(), r) {
+			t.Errorf("%s: request was handled", name)
+		}
+	}
+
+	get := httptest.NewRequest(http.MethodGet, "/?Action=Echo", nil)
+	if !handler(httptest.NewRecorder(), get) {
+		t.Error("GET request wasn't handled")
+	}
+}
+
+func formRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
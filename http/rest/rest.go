@@ -0,0 +1,256 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// This package implements the HTTP bindings the AWS REST protocols share, which
+// the restjson and restxml packages build on.
+// See https://smithy.io/2.0/spec/http-bindings.html
+//
+// Operations are routed by method and path pattern, such as "/2015-03-31/functions/{FunctionName}".
+// A segment like {Key+} matches the rest of the path. Patterns can also select on the query string,
+// like "/{Bucket}?tagging" or "/{Bucket}?list-type=2", and requests are routed to the pattern
+// with the most selectors that match.
+//
+// Input and output fields are in the protocol's body, unless they have a `rest` tag:
+//   - `rest:"path:Name"` is the path segment matched by {Name}
+//   - `rest:"query:Name"` is a query parameter
+//   - `rest:"header:Name"` is a header
+//   - `rest:"body"` is the whole body, as a []byte or an io.Reader
+//   - `rest:"status"` is the output's status code, if it isn't 200
+
+// Router routes requests to the handlers of a service's operations.
+type Router struct {
+	routes []route
+}
+
+type route struct {
+	method   string
+	segments []string
+	// query maps the query parameters the route selects on to their values,
+	// or to "" if any value matches.
+	query   map[string]string
+	handler func(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+}
+
+// Add routes requests matching the method and pattern to the handler.
+func (router *Router) Add(
+	method string,
+	pattern string,
+	handler func(w http.ResponseWriter, r *http.Request, pathParams map[string]string),
+) {
+	path, rawQuery, _ := strings.Cut(pattern, "?")
+	query := make(map[string]string)
+	if rawQuery != "" {
+		for _, selector := range strings.Split(rawQuery, "&") {
+			name, value, _ := strings.Cut(selector, "=")
+			query[name] = value
+		}
+	}
+	router.routes = append(router.routes, route{
+		method:   method,
+		segments: strings.Split(strings.Trim(path, "/"), "/"),
+		query:    query,
+		handler:  handler,
+	})
+}
+
+// Dispatch calls the handler of the route matching the request, returning whether there was one.
+func (router *Router) Dispatch(w http.ResponseWriter, r *http.Request) bool {
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	query := r.URL.Query()
+
+	var best *route
+	var bestPathParams map[string]string
+	for i := range router.routes {
+		route := &router.routes[i]
+		if route.method != r.Method || !route.selects(query) {
+			continue
+		}
+		if best != nil && len(route.query) <= len(best.query) {
+			continue
+		}
+		if pathParams, ok := match(route.segments, segments); ok {
+			best, bestPathParams = route, pathParams
+		}
+	}
+	if best == nil {
+		return false
+	}
+	best.handler(w, r, bestPathParams)
+	return true
+}
+
+func (route *route) selects(query url.Values) bool {
+	for name, value := range route.query {
+		if !query.Has(name) || (value != "" && query.Get(name) != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// match matches the request's path segments against the route's, returning the path parameters.
+func match(pattern []string, segments []string) (map[string]string, bool) {
+	pathParams := make(map[string]string)
+	for i, p := range pattern {
+		if name, ok := strings.CutPrefix(p, "{"); ok {
+			name = strings.TrimSuffix(name, "}")
+			if name, ok := strings.CutSuffix(name, "+"); ok {
+				if i >= len(segments) {
+					return nil, false
+				}
+				value, err := url.PathUnescape(strings.Join(segments[i:], "/"))
+				if err != nil {
+					return nil, false
+				}
+				pathParams[name] = value
+				return pathParams, true
+			}
+			if i >= len(segments) || segments[i] == "" {
+				return nil, false
+			}
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return nil, false
+			}
+			pathParams[name] = value
+		} else if i >= len(segments) || segments[i] != p {
+			return nil, false
+		}
+	}
+	return pathParams, len(pattern) == len(segments)
+}
+
+// Unmarshal sets the input's fields with a `rest` tag from the request. If the input has no body field,
+// it returns the body for the protocol to decode into the other fields.
+func Unmarshal(r *http.Request, pathParams map[string]string, v reflect.Value) ([]byte, error) {
+	ty := v.Type()
+	hasBodyField := false
+	for i := 0; i < ty.NumField(); i++ {
+		tag := ty.Field(i).Tag.Get("rest")
+		if tag == "" {
+			continue
+		}
+		f := v.Field(i)
+		var err error
+		if tag == "body" {
+			hasBodyField = true
+			err = setBody(f, r.Body)
+		} else if name, ok := strings.CutPrefix(tag, "path:"); ok {
+			err = setString(f, pathParams[name], true)
+		} else if name, ok := strings.CutPrefix(tag, "query:"); ok {
+			query := r.URL.Query()
+			if f.Kind() == reflect.Slice {
+				f.Set(reflect.ValueOf(query[name]))
+			} else {
+				err = setString(f, query.Get(name), query.Has(name))
+			}
+		} else if name, ok := strings.CutPrefix(tag, "header:"); ok {
+			err = setString(f, r.Header.Get(name), r.Header.Get(name) != "")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ty.Field(i).Name, err)
+		}
+	}
+
+	if hasBodyField {
+		return nil, nil
+	}
+	return io.ReadAll(r.Body)
+}
+
+// setBody sets the []byte or io.Reader field to the body. Readers aren't read, so large bodies can be streamed.
+func setBody(f reflect.Value, body io.Reader) error {
+	if f.Kind() == reflect.Interface {
+		f.Set(reflect.ValueOf(body))
+		return nil
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.SetBytes(data)
+	return nil
+}
+
+// setString sets the string, int or bool field, or the pointer to one, from its text.
+func setString(f reflect.Value, s string, present bool) error {
+	if f.Kind() == reflect.Pointer {
+		if !present {
+			return nil
+		}
+		f.Set(reflect.New(f.Type().Elem()))
+		f = f.Elem()
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if !present {
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Bool:
+		if !present {
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported kind %v", f.Kind())
+	}
+	return nil
+}
+
+// Marshal sets the headers from the output's fields with a `rest` tag, and returns its status code.
+// If the output has a body field, it also returns the body, which may be nil; otherwise the protocol
+// encodes the other fields as the body.
+func Marshal(w http.ResponseWriter, v reflect.Value) (status int, body io.Reader, hasBodyField bool) {
+	ty := v.Type()
+	status = http.StatusOK
+	for i := 0; i < ty.NumField(); i++ {
+		tag := ty.Field(i).Tag.Get("rest")
+		f := v.Field(i)
+		if tag == "body" {
+			hasBodyField = true
+			if f.Kind() == reflect.Interface {
+				if !f.IsNil() {
+					body = f.Interface().(io.Reader)
+				}
+			} else {
+				body = bytes.NewReader(f.Bytes())
+			}
+		} else if tag == "status" {
+			if f.Int() != 0 {
+				status = int(f.Int())
+			}
+		} else if name, ok := strings.CutPrefix(tag, "header:"); ok {
+			if f.Kind() == reflect.Pointer {
+				if f.IsNil() {
+					continue
+				}
+				f = f.Elem()
+			}
+			if s := fmt.Sprint(f.Interface()); s != "" {
+				w.Header().Set(name, s)
+			}
+		}
+	}
+	return status, body, hasBodyField
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	var router Router
+	var got string
+	add := func(method, pattern string) {
+		router.Add(method, pattern, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			got = pattern + " " + pathParams["Bucket"] + " " + pathParams["Key"]
+		})
+	}
+	add(http.MethodGet, "/{Bucket}/{Key+}")
+	add(http.MethodGet, "/{Bucket}/{Key+}?tagging")
+	add(http.MethodGet, "/{Bucket}?list-type=2")
+
+	tests := map[string]string{
+		"/bucket/a/b%2Fc":        "/{Bucket}/{Key+} bucket a/b/c",
+		"/bucket/key?tagging":    "/{Bucket}/{Key+}?tagging bucket key",
+		"/bucket?list-type=2":    "/{Bucket}?list-type=2 bucket ",
+		"/bucket?list-type=1":    "",
+		"/bucket/key?versionId=": "/{Bucket}/{Key+} bucket key",
+	}
+	for target, want := range tests {
+		got = ""
+		handled := router.Dispatch(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		if got != want || handled != (want != "") {
+			t.Errorf("%s: got %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestUnmarshalAndMarshal(t *testing.T) {
+	type input struct {
+		Id       string  `rest:"path:Id"`
+		MaxItems *int    `rest:"query:maxitems"`
+		Marker   *string `rest:"query:marker"`
+		Token    string  `rest:"header:x-token"`
+		Name     string
+	}
+	r := httptest.NewRequest(http.MethodGet, "/zone/Z1?maxitems=5", nil)
+	r.Header.Set("x-token", "secret")
+	var in input
+	if _, err := Unmarshal(r, map[string]string{"Id": "Z1"}, reflect.ValueOf(&in).Elem()); err != nil {
+		t.Fatal(err)
+	}
+	if in.Id != "Z1" || in.MaxItems == nil || *in.MaxItems != 5 || in.Marker != nil || in.Token != "secret" {
+		t.Fatalf("Unexpected input %+v", in)
+	}
+
+	type output struct {
+		Location string `rest:"header:Location"`
+		Status   int    `rest:"status"`
+		Body     []byte `rest:"body"`
+	}
+	w := httptest.NewRecorder()
+	status, body, hasBodyField := Marshal(w, reflect.ValueOf(output{Location: "/zone/Z1", Status: 201}))
+	if status != http.StatusCreated || body == nil || !hasBodyField || w.Header().Get("Location") != "/zone/Z1" {
+		t.Fatal("Unexpected output", status, body, hasBodyField, w.Header())
+	}
+}
@@ -0,0 +1,137 @@
+package restjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/calls"
+	"aws-in-a-box/http/rest"
+	"aws-in-a-box/validation"
+)
+
+// This package implements the AWS REST-JSON protocol, which services like Lambda use.
+// See https://smithy.io/2.0/aws/protocols/aws-restjson1-protocol.html
+//
+// Requests are routed and bound as described in the rest package. Fields without a `rest` tag
+// are in the JSON body, so fields with one should also have a `json:"-"` tag.
+
+// Registry holds the routes for a service's operations.
+type Registry struct {
+	router rest.Router
+	// The names of the registered operations.
+	operations []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func Register[Input any, Output any](
+	logger *slog.Logger,
+	registry *Registry,
+	method string,
+	pattern string,
+	operation string,
+	handler func(input Input) (*Output, *awserrors.Error),
+) {
+	RegisterWithRequest(logger, registry, method, pattern, operation, func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+		return handler(input)
+	})
+}
+
+// RegisterWithRequest is like Register, but also passes the handler the request, for operations
+// which depend on more than their input, such as which region the request is for.
+func RegisterWithRequest[Input any, Output any](
+	logger *slog.Logger,
+	registry *Registry,
+	method string,
+	pattern string,
+	operation string,
+	handler func(r *http.Request, input Input) (*Output, *awserrors.Error),
+) {
+	logger = logger.With("method", operation)
+	validator := validation.New[Input]()
+	registry.operations = append(registry.operations, operation)
+	registry.router.Add(method, pattern, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		var input Input
+		err := unmarshal(r, pathParams, reflect.ValueOf(&input).Elem())
+		if err != nil {
+			logger.Error("Unmarshaling input", "err", err)
+			panic(fmt.Errorf("%s: %v", operation, err))
+		}
+		logger.Debug("Parsed input", "input", input)
+
+		var output *Output
+		awserr := validator.Validate(input)
+		if awserr == nil {
+			output, awserr = handler(r, input)
+		}
+		logger.Debug("Got output", "output", output, "error", awserr)
+		calls.Record(r, operation, awserr)
+
+		w.Header().Set("x-amzn-RequestId", uuid.Must(uuid.NewV4()).String())
+		if awserr != nil {
+			marshalError(w, awserr)
+		} else {
+			marshal(w, output)
+		}
+	})
+}
+
+// Operations returns the names of the registry's operations, in the order they were registered.
+func (r *Registry) Operations() []string {
+	return r.operations
+}
+
+// NewHandler returns a handler for the server's handler chain, which handles requests
+// matching the registry's routes.
+func NewHandler(registry *Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	return registry.router.Dispatch
+}
+
+func unmarshal(r *http.Request, pathParams map[string]string, v reflect.Value) error {
+	body, err := rest.Unmarshal(r, pathParams, v)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		return json.Unmarshal(body, v.Addr().Interface())
+	}
+	return nil
+}
+
+func marshal(w http.ResponseWriter, output any) {
+	status, body, hasBodyField := rest.Marshal(w, reflect.ValueOf(output).Elem())
+	if !hasBodyField {
+		data, err := json.Marshal(output)
+		if err != nil {
+			panic(err)
+		}
+		body = bytes.NewReader(data)
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	if body != nil {
+		// Statuses like 204 don't allow a body, so the error is ignored.
+		io.Copy(w, body)
+	}
+}
+
+func marshalError(w http.ResponseWriter, awserr *awserrors.Error) {
+	data, err := json.Marshal(awserr.Body)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-ErrorType", awserr.Body.Type)
+	w.WriteHeader(awserr.Code)
+	w.Write(data)
+}
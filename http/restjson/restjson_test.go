@@ -0,0 +1,181 @@
+package restjson
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aws-in-a-box/awserrors"
+)
+
+type invokeInput struct {
+	FunctionName string  `rest:"path:FunctionName" json:"-"`
+	Qualifier    *string `rest:"query:Qualifier" json:"-"`
+	Type         string  `rest:"header:X-Amz-Invocation-Type" json:"-"`
+	Environment  map[string]map[string]string
+	Layers       [][]string
+	Tags         map[string][]string
+}
+
+type invokeOutput struct {
+	Version     string `rest:"header:X-Amz-Executed-Version" json:"-"`
+	Status      int    `rest:"status" json:"-"`
+	Name        string
+	Environment map[string]map[string]string
+	Layers      [][]string
+	Tags        map[string][]string
+}
+
+type emptyOutput struct {
+	Status int `rest:"status" json:"-"`
+}
+
+type payloadOutput struct {
+	ContentType string `rest:"header:Content-Type"`
+	Payload     []byte `rest:"body"`
+}
+
+func newHandler() func(w http.ResponseWriter, r *http.Request) bool {
+	registry := NewRegistry()
+	Register(slog.Default(), registry, http.MethodPost, "/2015-03-31/functions/{FunctionName}/configuration", "UpdateFunctionConfiguration",
+		func(input invokeInput) (*invokeOutput, *awserrors.Error) {
+			switch input.FunctionName {
+			case "missing":
+				return nil, &awserrors.Error{Code: 404, Body: awserrors.ErrorBody{Type: "ResourceNotFoundException", Message: "Function not found"}}
+			case "legacy":
+				return nil, awserrors.Generate400ExceptionWithLegacyMesageField("InvalidParameterValueException", "Bad value")
+			}
+			version := "$LATEST"
+			if input.Qualifier != nil {
+				version = *input.Qualifier
+			}
+			return &invokeOutput{
+				Version:     version,
+				Status:      http.StatusAccepted,
+				Name:        input.FunctionName + " " + input.Type,
+				Environment: input.Environment,
+				Layers:      input.Layers,
+				Tags:        input.Tags,
+			}, nil
+		})
+	Register(slog.Default(), registry, http.MethodDelete, "/2015-03-31/functions/{FunctionName}", "DeleteFunction",
+		func(input invokeInput) (*emptyOutput, *awserrors.Error) {
+			return &emptyOutput{Status: http.StatusNoContent}, nil
+		})
+	Register(slog.Default(), registry, http.MethodPost, "/2015-03-31/functions/{FunctionName}/invocations", "Invoke",
+		func(input invokeInput) (*payloadOutput, *awserrors.Error) {
+			return &payloadOutput{ContentType: "text/plain", Payload: []byte(input.FunctionName)}, nil
+		})
+	return NewHandler(registry)
+}
+
+func TestRoundTrip(t *testing.T) {
+	body := `{
+		"Environment": {"Variables": {"A": "1", "B": "2"}, "Empty": {}},
+		"Layers": [["arn:1", "arn:2"], []],
+		"Tags": {"team": ["a", "b"]}
+	}`
+	r := httptest.NewRequest(http.MethodPost, "/2015-03-31/functions/fn/configuration?Qualifier=2", strings.NewReader(body))
+	r.Header.Set("X-Amz-Invocation-Type", "Event")
+	w := httptest.NewRecorder()
+	if !newHandler()(w, r) {
+		t.Fatal("request wasn't handled")
+	}
+
+	if w.Code != http.StatusAccepted || w.Header().Get("X-Amz-Executed-Version") != "2" ||
+		w.Header().Get("Content-Type") != "application/json" || w.Header().Get("x-amzn-RequestId") == "" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	// Fields bound to the request or response's headers and status aren't in the body.
+	want := map[string]any{
+		"Name": "fn Event",
+		"Environment": map[string]any{
+			"Variables": map[string]any{"A": "1", "B": "2"},
+			"Empty":     map[string]any{},
+		},
+		"Layers": []any{[]any{"arn:1", "arn:2"}, []any{}},
+		"Tags":   map[string]any{"team": []any{"a", "b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	handler := newHandler()
+	for _, test := range []struct {
+		name        string
+		method      string
+		target      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"empty body", http.MethodPost, "/2015-03-31/functions/fn/configuration", http.StatusAccepted, "application/json",
+			`{"Name":"fn ","Environment":null,"Layers":null,"Tags":null}`},
+		{"only status", http.MethodDelete, "/2015-03-31/functions/fn", http.StatusNoContent, "application/json", "{}"},
+		{"body field", http.MethodPost, "/2015-03-31/functions/fn/invocations", http.StatusOK, "text/plain", "fn"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if !handler(w, httptest.NewRequest(test.method, test.target, nil)) {
+				t.Fatal("request wasn't handled")
+			}
+			if w.Code != test.status || w.Header().Get("Content-Type") != test.contentType || w.Body.String() != test.body {
+				t.Errorf("got %d %q %q, want %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String(),
+					test.status, test.contentType, test.body)
+			}
+		})
+	}
+
+	if handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/2015-03-31/functions/fn/invocations", nil)) {
+		t.Error("request with an unregistered method was handled")
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	for _, test := range []struct {
+		function string
+		status   int
+		want     string
+	}{
+		{"missing", http.StatusNotFound, `{"__type":"ResourceNotFoundException","Message":"Function not found"}`},
+		{"legacy", http.StatusBadRequest, `{"__type":"InvalidParameterValueException","message":"Bad value"}`},
+	} {
+		t.Run(test.function, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/2015-03-31/functions/"+test.function+"/configuration", nil)
+			if !newHandler()(w, r) {
+				t.Fatal("request wasn't handled")
+			}
+			if w.Code != test.status || w.Header().Get("Content-Type") != "application/json" || w.Body.String() != test.want {
+				t.Errorf("got %d %v %s, want %d %s", w.Code, w.Header(), w.Body.String(), test.status, test.want)
+			}
+			var body awserrors.ErrorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Header().Get("X-Amzn-ErrorType") != body.Type {
+				t.Errorf("got error type header %q, want %q", w.Header().Get("X-Amzn-ErrorType"), body.Type)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRejectsInvalidJSON(t *testing.T) {
+	for _, body := range []string{`{"Layers": [["a"]`, `{"Environment": {"A": "not a map"}}`, `{"Layers": "a"}`} {
+		var input invokeInput
+		r := httptest.NewRequest(http.MethodPost, "/2015-03-31/functions/fn/configuration", strings.NewReader(body))
+		if err := unmarshal(r, map[string]string{"FunctionName": "fn"}, reflect.ValueOf(&input).Elem()); err == nil {
+			t.Errorf("%s: expected an error", body)
+		}
+	}
+}
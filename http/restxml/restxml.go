@@ -0,0 +1,189 @@
+package restxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/calls"
+	"aws-in-a-box/http/rest"
+	"aws-in-a-box/validation"
+)
+
+// This package implements the AWS REST-XML protocol, which services like S3 and Route 53 use.
+// See https://smithy.io/2.0/aws/protocols/aws-restxml-protocol.html
+//
+// Requests are routed and bound as described in the rest package. Fields without a `rest` tag
+// are in the XML body, so fields with one should also have an `xml:"-"` tag. Outputs are only
+// encoded as XML if they have an XMLName field naming the root element.
+
+// Protocol describes how a service uses the REST-XML protocol.
+type Protocol struct {
+	// XMLNamespace is the namespace of responses' root elements.
+	XMLNamespace string
+	// NoErrorWrapping is set by services like S3, whose error responses are an Error element,
+	// rather than an Error element wrapped in an ErrorResponse.
+	NoErrorWrapping bool
+}
+
+// Registry holds the routes for a service's operations.
+type Registry struct {
+	protocol Protocol
+	router   rest.Router
+	// The names of the registered operations.
+	operations []string
+}
+
+func NewRegistry(protocol Protocol) *Registry {
+	return &Registry{protocol: protocol}
+}
+
+func Register[Input any, Output any](
+	logger *slog.Logger,
+	registry *Registry,
+	method string,
+	pattern string,
+	operation string,
+	handler func(input Input) (*Output, *awserrors.Error),
+) {
+	p := &registry.protocol
+	logger = logger.With("method", operation)
+	validator := validation.New[Input]()
+	registry.operations = append(registry.operations, operation)
+	registry.router.Add(method, pattern, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		var input Input
+		err := unmarshal(r, pathParams, reflect.ValueOf(&input).Elem())
+		if err != nil {
+			logger.Error("Unmarshaling input", "err", err)
+			panic(fmt.Errorf("%s: %v", operation, err))
+		}
+		logger.Debug("Parsed input", "input", input)
+
+		var output *Output
+		awserr := validator.Validate(input)
+		if awserr == nil {
+			output, awserr = handler(input)
+		}
+		logger.Debug("Got output", "output", output, "error", awserr)
+		calls.Record(r, operation, awserr)
+
+		requestId := uuid.Must(uuid.NewV4()).String()
+		w.Header().Set("x-amzn-RequestId", requestId)
+		if awserr != nil {
+			p.MarshalError(w, awserr, requestId)
+		} else {
+			p.Marshal(w, output)
+		}
+	})
+}
+
+// Operations returns the names of the registry's operations, in the order they were registered.
+func (r *Registry) Operations() []string {
+	return r.operations
+}
+
+// NewHandler returns a handler for the server's handler chain, which handles requests
+// matching the registry's routes.
+func NewHandler(registry *Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	return registry.router.Dispatch
+}
+
+func unmarshal(r *http.Request, pathParams map[string]string, v reflect.Value) error {
+	body, err := rest.Unmarshal(r, pathParams, v)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		return xml.Unmarshal(body, v.Addr().Interface())
+	}
+	return nil
+}
+
+// Marshal writes the output's response. Like MarshalError, it's exported for requests services
+// handle themselves, such as S3's browser uploads.
+func (p *Protocol) Marshal(w http.ResponseWriter, output any) {
+	v := reflect.ValueOf(output).Elem()
+	status, body, hasBodyField := rest.Marshal(w, v)
+	if hasBodyField {
+		w.WriteHeader(status)
+		if body != nil {
+			if _, err := io.Copy(w, body); err != nil {
+				panic(err)
+			}
+		}
+		return
+	}
+
+	field, ok := v.Type().FieldByName("XMLName")
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+	// The namespace is added to the root element, so types can leave it out of their tags.
+	name, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if p.XMLNamespace != "" {
+		start.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: p.XMLNamespace}}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).EncodeElement(output, start); err != nil {
+		panic(err)
+	}
+}
+
+// MarshalError writes the error response.
+// https://smithy.io/2.0/aws/protocols/aws-restxml-protocol.html#operation-error-serialization
+func (p *Protocol) MarshalError(w http.ResponseWriter, awserr *awserrors.Error, requestId string) {
+	errorType := "Sender"
+	if awserr.Code >= 500 {
+		errorType = "Receiver"
+	}
+	xmlErr := xmlError{
+		Type:    errorType,
+		Code:    awserr.Body.Type,
+		Message: awserr.Body.Message,
+	}
+
+	var response any = errorResponse{
+		Xmlns:     p.XMLNamespace,
+		Error:     xmlErr,
+		RequestId: requestId,
+	}
+	if p.NoErrorWrapping {
+		xmlErr.Type = ""
+		xmlErr.RequestId = requestId
+		response = xmlErr
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(awserr.Code)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Xmlns     string   `xml:"xmlns,attr,omitempty"`
+	Error     xmlError
+	RequestId string
+}
+
+type xmlError struct {
+	XMLName   xml.Name `xml:"Error"`
+	Type      string   `xml:",omitempty"`
+	Code      string
+	Message   string
+	RequestId string `xml:",omitempty"`
+}
@@ -0,0 +1,210 @@
+package restxml
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aws-in-a-box/awserrors"
+)
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+type putInput struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Bucket  string   `rest:"path:Bucket" xml:"-"`
+	Key     string   `rest:"path:Key" xml:"-"`
+	Version *string  `rest:"query:versionId" xml:"-"`
+	Owner   string   `rest:"header:x-amz-expected-bucket-owner" xml:"-"`
+	Tags    []tag    `xml:"TagSet>Tag"`
+	Groups  []group  `xml:"Group"`
+}
+
+type group struct {
+	Name    string
+	Members []string `xml:"Members>Member"`
+}
+
+type putOutput struct {
+	XMLName xml.Name `xml:"Result"`
+	Version string   `rest:"header:x-amz-version-id" xml:"-"`
+	Status  int      `rest:"status" xml:"-"`
+	Path    string
+	Tags    []tag   `xml:"TagSet>Tag"`
+	Groups  []group `xml:"Group"`
+}
+
+type emptyOutput struct {
+	Status int `rest:"status"`
+}
+
+type bodyOutput struct {
+	ContentType string `rest:"header:Content-Type"`
+	Body        []byte `rest:"body"`
+}
+
+func newHandler(protocol Protocol) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := NewRegistry(protocol)
+	Register(slog.Default(), registry, http.MethodPut, "/{Bucket}/{Key+}?tagging", "PutObjectTagging",
+		func(input putInput) (*putOutput, *awserrors.Error) {
+			switch input.Bucket {
+			case "missing":
+				return nil, &awserrors.Error{Code: 404, Body: awserrors.ErrorBody{Type: "NoSuchBucket", Message: "The specified bucket does not exist"}}
+			case "broken":
+				return nil, &awserrors.Error{Code: 500, Body: awserrors.ErrorBody{Type: "InternalError", Message: "Broken"}}
+			}
+			version := ""
+			if input.Version != nil {
+				version = *input.Version
+			}
+			return &putOutput{
+				Version: version,
+				Status:  http.StatusCreated,
+				Path:    input.Bucket + "/" + input.Key + " " + input.Owner,
+				Tags:    input.Tags,
+				Groups:  input.Groups,
+			}, nil
+		})
+	Register(slog.Default(), registry, http.MethodDelete, "/{Bucket}/{Key+}?tagging", "DeleteObjectTagging",
+		func(input putInput) (*emptyOutput, *awserrors.Error) {
+			return &emptyOutput{Status: http.StatusNoContent}, nil
+		})
+	Register(slog.Default(), registry, http.MethodGet, "/{Bucket}/{Key+}", "GetObject",
+		func(input putInput) (*bodyOutput, *awserrors.Error) {
+			return &bodyOutput{ContentType: "text/plain", Body: []byte(input.Key)}, nil
+		})
+	return NewHandler(registry)
+}
+
+func TestRoundTrip(t *testing.T) {
+	body := `<Tagging>
+  <TagSet><Tag><Key>a</Key><Value>1</Value></Tag><Tag><Key>b</Key><Value>2</Value></Tag></TagSet>
+  <Group><Name>x</Name><Members><Member>m1</Member><Member>m2</Member></Members></Group>
+  <Group><Name>y</Name></Group>
+</Tagging>`
+	r := httptest.NewRequest(http.MethodPut, "/bucket/dir/key?tagging&versionId=v1", strings.NewReader(body))
+	r.Header.Set("x-amz-expected-bucket-owner", "123")
+	w := httptest.NewRecorder()
+	handler := newHandler(Protocol{XMLNamespace: "http://s3.amazonaws.com/doc/2006-03-01/"})
+	if !handler(w, r) {
+		t.Fatal("request wasn't handled")
+	}
+
+	if w.Code != http.StatusCreated || w.Header().Get("x-amz-version-id") != "v1" ||
+		w.Header().Get("Content-Type") != "application/xml" || w.Header().Get("x-amzn-RequestId") == "" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if !strings.HasPrefix(w.Body.String(), xml.Header+`<Result xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`) {
+		t.Fatalf("unexpected root element: %s", w.Body.String())
+	}
+	var got putOutput
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := putOutput{
+		XMLName: xml.Name{Space: "http://s3.amazonaws.com/doc/2006-03-01/", Local: "Result"},
+		Path:    "bucket/dir/key 123",
+		Tags:    []tag{{"a", "1"}, {"b", "2"}},
+		Groups:  []group{{Name: "x", Members: []string{"m1", "m2"}}, {Name: "y"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	handler := newHandler(Protocol{})
+	for _, test := range []struct {
+		name        string
+		method      string
+		target      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"no XMLName", http.MethodDelete, "/bucket/key?tagging", http.StatusNoContent, "", ""},
+		{"body field", http.MethodGet, "/bucket/a/b", http.StatusOK, "text/plain", "a/b"},
+		{"no namespace", http.MethodPut, "/bucket/key?tagging", http.StatusCreated, "application/xml",
+			xml.Header + "<Result><Path>bucket/key </Path><TagSet></TagSet></Result>"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if !handler(w, httptest.NewRequest(test.method, test.target, nil)) {
+				t.Fatal("request wasn't handled")
+			}
+			if w.Code != test.status || w.Header().Get("Content-Type") != test.contentType || w.Body.String() != test.body {
+				t.Errorf("got %d %q %q, want %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String(),
+					test.status, test.contentType, test.body)
+			}
+		})
+	}
+
+	if handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/bucket/key?tagging", nil)) {
+		t.Error("request with an unregistered method was handled")
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		protocol Protocol
+		bucket   string
+		status   int
+		want     string
+	}{
+		{
+			name:     "wrapped",
+			protocol: Protocol{XMLNamespace: "https://route53.amazonaws.com/doc/2013-04-01/"},
+			bucket:   "missing",
+			status:   http.StatusNotFound,
+			want: `<ErrorResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><Error><Type>Sender</Type>` +
+				`<Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error><RequestId>REQUEST</RequestId></ErrorResponse>`,
+		},
+		{
+			name:     "server error",
+			protocol: Protocol{},
+			bucket:   "broken",
+			status:   http.StatusInternalServerError,
+			want: `<ErrorResponse><Error><Type>Receiver</Type><Code>InternalError</Code><Message>Broken</Message></Error>` +
+				`<RequestId>REQUEST</RequestId></ErrorResponse>`,
+		},
+		{
+			name:     "not wrapped",
+			protocol: Protocol{XMLNamespace: "http://s3.amazonaws.com/doc/2006-03-01/", NoErrorWrapping: true},
+			bucket:   "missing",
+			status:   http.StatusNotFound,
+			want:     `<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message><RequestId>REQUEST</RequestId></Error>`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/"+test.bucket+"/key?tagging", nil)
+			if !newHandler(test.protocol)(w, r) {
+				t.Fatal("request wasn't handled")
+			}
+			requestId := w.Header().Get("x-amzn-RequestId")
+			if w.Code != test.status || w.Header().Get("Content-Type") != "application/xml" || requestId == "" {
+				t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+			}
+			want := xml.Header + strings.Replace(test.want, "REQUEST", requestId, 1)
+			if w.Body.String() != want {
+				t.Errorf("got %s, want %s", w.Body.String(), want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRejectsInvalidXML(t *testing.T) {
+	var input putInput
+	r := httptest.NewRequest(http.MethodPut, "/bucket/key?tagging", strings.NewReader("<Tagging><TagSet>"))
+	if err := unmarshal(r, map[string]string{"Bucket": "bucket", "Key": "key"}, reflect.ValueOf(&input).Elem()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
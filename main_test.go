@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/services/ssm"
+	"aws-in-a-box/state"
+)
+
+func TestEnvName(t *testing.T) {
+	for flagName, want := range map[string]string{
+		"addr":                  "AWS_IN_A_BOX_ADDR",
+		"persistDir":            "AWS_IN_A_BOX_PERSIST_DIR",
+		"enableSTS":             "AWS_IN_A_BOX_ENABLE_STS",
+		"route53DNSAddr":        "AWS_IN_A_BOX_ROUTE53_DNS_ADDR",
+		"s3DefaultRegion":       "AWS_IN_A_BOX_S3_DEFAULT_REGION",
+		"kinesisShardsPerHTTP2": "AWS_IN_A_BOX_KINESIS_SHARDS_PER_HTTP2",
+		"enableKMSAliases":      "AWS_IN_A_BOX_ENABLE_KMS_ALIASES",
+		"a":                     "AWS_IN_A_BOX_A",
+	} {
+		if got := envName(flagName); got != want {
+			t.Errorf("envName(%q) = %q, want %q", flagName, got, want)
+		}
+	}
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:4569", "")
+	persistDir := flags.String("persistDir", "", "")
+	enableSTS := flags.Bool("enableSTS", false, "")
+	t.Setenv("AWS_IN_A_BOX_ADDR", "localhost:0")
+	t.Setenv("AWS_IN_A_BOX_ENABLE_STS", "true")
+	if err := setFlagsFromEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+	// The command line takes precedence.
+	if err := flags.Parse([]string{"-addr", "localhost:1234"}); err != nil {
+		t.Fatal(err)
+	}
+	if *addr != "localhost:1234" || *persistDir != "" || !*enableSTS {
+		t.Errorf("unexpected flags %q %q %v", *addr, *persistDir, *enableSTS)
+	}
+
+	t.Setenv("AWS_IN_A_BOX_ENABLE_STS", "maybe")
+	err := setFlagsFromEnv(flags)
+	if err == nil || !strings.Contains(err.Error(), "AWS_IN_A_BOX_ENABLE_STS") {
+		t.Errorf("expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestWritePortFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "port")
+	for _, addr := range []string{"127.0.0.1:1234", "127.0.0.1:5678"} {
+		if err := writePortFile(path, addr); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != addr+"\n" {
+			t.Errorf("got %q, want %q", data, addr+"\n")
+		}
+	}
+	// The temporary file was renamed into place.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the port file, got %v", entries)
+	}
+
+	if err := writePortFile(filepath.Join(dir, "missing", "port"), "127.0.0.1:1234"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+// newEmulator serves the admin API of an emulator with only SSM, returning its address.
+func newEmulator(t *testing.T) (*ssm.SSM, string) {
+	t.Helper()
+	service := ssm.New(ssm.Options{})
+	adminRegistry := make(admin.Registry)
+	state.RegisterAdminHandlers(adminRegistry, state.Registry{"ssm": service}, "test")
+	handler := admin.NewHandler(adminRegistry)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !handler(w, r) {
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return service, strings.TrimPrefix(server.URL, "http://")
+}
+
+func putParameter(t *testing.T, service *ssm.SSM, name, value string) {
+	t.Helper()
+	_, awserr := service.PutParameter(ssm.PutParameterInput{Name: name, Value: value, Type: "String", Overwrite: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+// dump writes the emulator's state to a file in dir, returning its path.
+func dump(t *testing.T, addr string, dir string, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := stateCommand("dump", []string{"-addr", addr, "-o", path}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStateCommands(t *testing.T) {
+	dir := t.TempDir()
+	source, sourceAddr := newEmulator(t)
+	putParameter(t, source, "/app/a", "1")
+	archive := dump(t, sourceAddr, dir, "state.tar.gz")
+
+	var stdout bytes.Buffer
+	if err := stateCommand("dump", []string{"-addr", sourceAddr, "-snapshot"}, nil, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := state.Diff(bytes.NewReader(stdout.Bytes()), bytes.NewReader(archived), stateResources); err != nil {
+		t.Fatalf("snapshot isn't an archive: %v", err)
+	}
+
+	for _, fromStdin := range []bool{false, true} {
+		target, targetAddr := newEmulator(t)
+		args := []string{"-addr", targetAddr, archive}
+		var stdin io.Reader
+		if fromStdin {
+			args[2] = "-"
+			stdin = bytes.NewReader(archived)
+		}
+		stdout.Reset()
+		if err := stateCommand("load", args, stdin, &stdout); err != nil {
+			t.Fatal(err)
+		}
+		var result struct{ Imported []string }
+		if err := json.Unmarshal(stdout.Bytes(), &result); err != nil || !reflect.DeepEqual(result.Imported, []string{"ssm"}) {
+			t.Errorf("unexpected load output %s", stdout.String())
+		}
+		output, awserr := target.GetParameter(ssm.GetParameterInput{Name: "/app/a"})
+		if awserr != nil || output.Parameter.Value != "1" {
+			t.Errorf("parameter wasn't loaded: %v %v", output, awserr)
+		}
+	}
+
+	_, addr := newEmulator(t)
+	invalid := filepath.Join(dir, "invalid.tar.gz")
+	if err := os.WriteFile(invalid, []byte("not an archive"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := stateCommand("load", []string{"-addr", addr, invalid}, nil, nil); err == nil || !strings.Contains(err.Error(), "load failed: 400") {
+		t.Errorf("expected the emulator's error, got %v", err)
+	}
+	if err := stateCommand("load", []string{"-addr", addr, filepath.Join(dir, "missing.tar.gz")}, nil, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	for _, test := range []struct {
+		command string
+		args    []string
+	}{
+		{"dump", []string{"extra"}},
+		{"dump", []string{"-unknown"}},
+		{"load", nil},
+		{"load", []string{"a.tar.gz", "b.tar.gz"}},
+		{"diff", nil},
+		{"diff", []string{"a.tar.gz", "b.tar.gz", "c.tar.gz"}},
+	} {
+		var err error
+		if test.command == "diff" {
+			_, err = diffCommand(test.args, nil)
+		} else {
+			err = stateCommand(test.command, test.args, nil, nil)
+		}
+		if !errors.Is(err, errUsage) {
+			t.Errorf("%s %v: got %v, want a usage error", test.command, test.args, err)
+		}
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	service, addr := newEmulator(t)
+	putParameter(t, service, "/app/a", "1")
+	putParameter(t, service, "/app/b", "1")
+	before := dump(t, addr, dir, "before.tar.gz")
+	putParameter(t, service, "/app/a", "2")
+	putParameter(t, service, "/app/c", "1")
+	if _, awserr := service.DeleteParameter(ssm.DeleteParameterInput{Name: "/app/b"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	after := dump(t, addr, dir, "after.tar.gz")
+
+	want := "ssm:\n  ~ parameter//app/a\n  - parameter//app/b\n  + parameter//app/c\n"
+	for name, args := range map[string][]string{
+		"archives": {before, after},
+		"emulator": {"-addr", addr, before},
+	} {
+		var stdout bytes.Buffer
+		differs, err := diffCommand(args, &stdout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !differs || stdout.String() != want {
+			t.Errorf("%s: got %v %q, want %q", name, differs, stdout.String(), want)
+		}
+	}
+
+	var stdout bytes.Buffer
+	if _, err := diffCommand([]string{"-json", before, after}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	var changes []state.Change
+	if err := json.Unmarshal(stdout.Bytes(), &changes); err != nil {
+		t.Fatal(err)
+	}
+	wantChanges := []state.Change{
+		{Service: "ssm", Resource: "parameter//app/a", Kind: state.Modified},
+		{Service: "ssm", Resource: "parameter//app/b", Kind: state.Deleted},
+		{Service: "ssm", Resource: "parameter//app/c", Kind: state.Created},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("got %+v, want %+v", changes, wantChanges)
+	}
+
+	stdout.Reset()
+	differs, err := diffCommand([]string{"-json", after, after}, &stdout)
+	if err != nil || differs || strings.TrimSpace(stdout.String()) != "[]" {
+		t.Errorf("unchanged archives: got %v %v %q", differs, err, stdout.String())
+	}
+
+	if _, err := diffCommand([]string{"-addr", "localhost:1", before}, &stdout); err == nil {
+		t.Error("expected an error without an emulator")
+	}
+}
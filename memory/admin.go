@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/admin"
+)
+
+// RegisterAdminHandlers adds memory usage to the admin API. GET memory returns each service's usage,
+// which can be filtered with ?service=, and which of the enabled services don't report it. GET
+// metrics returns the same in the Prometheus text format, for scraping.
+func RegisterAdminHandlers(adminRegistry admin.Registry, registry Registry, enabled []string) {
+	adminRegistry["memory"] = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		report := NewReport(registry, enabled)
+		if service := r.URL.Query().Get("service"); service != "" {
+			report.Services = slices.DeleteFunc(report.Services, func(u Usage) bool { return u.Service != service })
+			report.Unreported = slices.DeleteFunc(report.Unreported, func(name string) bool { return name != service })
+		}
+		admin.WriteJSON(w, report)
+	}
+	adminRegistry["metrics"] = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, NewReport(registry, enabled))
+	}
+}
+
+// https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+func writeMetrics(w http.ResponseWriter, report Report) {
+	fmt.Fprintln(w, "# HELP aws_in_a_box_heap_bytes Bytes allocated on the Go heap.")
+	fmt.Fprintln(w, "# TYPE aws_in_a_box_heap_bytes gauge")
+	fmt.Fprintf(w, "aws_in_a_box_heap_bytes %d\n", report.HeapBytes)
+
+	fmt.Fprintln(w, "# HELP aws_in_a_box_service_bytes Approximate bytes held by each service's resources.")
+	fmt.Fprintln(w, "# TYPE aws_in_a_box_service_bytes gauge")
+	for _, usage := range report.Services {
+		fmt.Fprintf(w, "aws_in_a_box_service_bytes{service=%s} %d\n", quoteLabel(usage.Service), usage.Bytes)
+	}
+
+	fmt.Fprintln(w, "# HELP aws_in_a_box_resource_bytes Approximate bytes held by each resource, such as a bucket, stream, table or queue.")
+	fmt.Fprintln(w, "# TYPE aws_in_a_box_resource_bytes gauge")
+	for _, usage := range report.Services {
+		for _, resource := range usage.Resources {
+			fmt.Fprintf(w, "aws_in_a_box_resource_bytes{service=%s,resource=%s} %d\n",
+				quoteLabel(usage.Service), quoteLabel(resource.Name), resource.Bytes)
+		}
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,76 @@
+// Package memory reports roughly how much memory each service's resources take up, to find which
+// fixture is using it all in a long-running emulator.
+package memory
+
+import (
+	"runtime"
+	"sort"
+)
+
+// Service is a service which can tell how much memory its resources take up.
+type Service interface {
+	MemoryUsage() []Resource
+}
+
+// Registry maps service names, like "s3", to their services.
+type Registry map[string]Service
+
+// Resource is the memory a single resource, such as a bucket, stream, table or queue, takes up. Bytes only counts
+// contents, such as objects, records, items or messages, and not the emulator's overhead, so it
+// underestimates small resources.
+type Resource struct {
+	Name  string
+	Bytes int64
+	// How many objects, records, items, messages or versions it holds.
+	Count int
+}
+
+// Usage is the memory a service's resources take up, largest first.
+type Usage struct {
+	Service   string
+	Bytes     int64
+	Resources []Resource
+}
+
+// Report is the memory every service's resources take up, largest first.
+type Report struct {
+	// The Go heap, which also holds everything which isn't counted by the services.
+	HeapBytes uint64
+	Services  []Usage
+	// The enabled services which aren't in the registry, so their resources are only counted in
+	// HeapBytes, sorted by name.
+	Unreported []string
+}
+
+// NewReport asks each service in the registry for its usage. enabled is the names of the enabled
+// services, to list those which don't report their usage.
+func NewReport(registry Registry, enabled []string) Report {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	report := Report{HeapBytes: memStats.HeapAlloc, Unreported: []string{}}
+	for _, name := range enabled {
+		if _, ok := registry[name]; !ok {
+			report.Unreported = append(report.Unreported, name)
+		}
+	}
+	sort.Strings(report.Unreported)
+	for name, service := range registry {
+		usage := Usage{Service: name, Resources: service.MemoryUsage()}
+		for _, resource := range usage.Resources {
+			usage.Bytes += resource.Bytes
+		}
+		sort.Slice(usage.Resources, func(i, j int) bool {
+			a, b := usage.Resources[i], usage.Resources[j]
+			return a.Bytes > b.Bytes || (a.Bytes == b.Bytes && a.Name < b.Name)
+		})
+		if usage.Resources == nil {
+			usage.Resources = []Resource{}
+		}
+		report.Services = append(report.Services, usage)
+	}
+	sort.Slice(report.Services, func(i, j int) bool {
+		a, b := report.Services[i], report.Services[j]
+		return a.Bytes > b.Bytes || (a.Bytes == b.Bytes && a.Service < b.Service)
+	})
+	return report
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aws-in-a-box/admin"
+)
+
+type fakeService []Resource
+
+func (f fakeService) MemoryUsage() []Resource {
+	return f
+}
+
+func TestReport(t *testing.T) {
+	registry := Registry{
+		"sqs": fakeService{},
+		"s3": fakeService{
+			{Name: "small", Bytes: 10, Count: 1},
+			{Name: "fixtures", Bytes: 1000, Count: 3},
+		},
+		"kinesis": fakeService{{Name: `say "hi"`, Bytes: 50, Count: 5}},
+	}
+
+	report := NewReport(registry, []string{"sqs", "sts", "kinesis", "iam", "s3"})
+	want := []Usage{
+		{Service: "s3", Bytes: 1010, Resources: []Resource{{"fixtures", 1000, 3}, {"small", 10, 1}}},
+		{Service: "kinesis", Bytes: 50, Resources: []Resource{{`say "hi"`, 50, 5}}},
+		{Service: "sqs", Bytes: 0, Resources: []Resource{}},
+	}
+	if !reflect.DeepEqual(report.Services, want) {
+		t.Errorf("got %+v, want %+v", report.Services, want)
+	}
+	if report.HeapBytes == 0 {
+		t.Error("Expected the heap size")
+	}
+	if !reflect.DeepEqual(report.Unreported, []string{"iam", "sts"}) {
+		t.Errorf("got unreported services %v, want [iam sts]", report.Unreported)
+	}
+
+	adminRegistry := make(admin.Registry)
+	RegisterAdminHandlers(adminRegistry, registry, []string{"kinesis", "sts"})
+
+	response := httptest.NewRecorder()
+	adminRegistry["memory"](response, httptest.NewRequest(http.MethodGet, "/_admin/memory?service=kinesis", nil))
+	var filtered Report
+	if err := json.Unmarshal(response.Body.Bytes(), &filtered); err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Services) != 1 || filtered.Services[0].Service != "kinesis" || len(filtered.Unreported) != 0 {
+		t.Fatalf("Unexpected report %+v", filtered)
+	}
+
+	response = httptest.NewRecorder()
+	adminRegistry["memory"](response, httptest.NewRequest(http.MethodGet, "/_admin/memory?service=sts", nil))
+	if err := json.Unmarshal(response.Body.Bytes(), &filtered); err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Services) != 0 || !reflect.DeepEqual(filtered.Unreported, []string{"sts"}) {
+		t.Fatalf("Unexpected report %+v", filtered)
+	}
+
+	response = httptest.NewRecorder()
+	adminRegistry["metrics"](response, httptest.NewRequest(http.MethodGet, "/_admin/metrics", nil))
+	for _, line := range []string{
+		`aws_in_a_box_service_bytes{service="s3"} 1010`,
+		`aws_in_a_box_resource_bytes{service="s3",resource="fixtures"} 1000`,
+		`aws_in_a_box_resource_bytes{service="kinesis",resource="say \"hi\""} 50`,
+	} {
+		if !strings.Contains(response.Body.String(), line+"\n") {
+			t.Errorf("Expected %s in metrics:\n%s", line, response.Body.String())
+		}
+	}
+}
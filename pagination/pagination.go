@@ -0,0 +1,57 @@
+// Package pagination pages through the lists List operations return. Their NextTokens are the
+// index of the next page's first item.
+package pagination
+
+import (
+	"strconv"
+
+	"aws-in-a-box/awserrors"
+)
+
+// Parse returns the page size, which is maxResults or, if it's 0, defaultMax, and the index of the
+// page's first item. invalidMaxResults and invalidNextToken are the service's errors, returned if
+// maxResults isn't between 1 and max, or the token isn't one Page returned.
+func Parse(maxResults int, defaultMax int, max int, nextToken string, invalidMaxResults *awserrors.Error, invalidNextToken *awserrors.Error) (int, int, *awserrors.Error) {
+	if maxResults == 0 {
+		maxResults = defaultMax
+	}
+	if maxResults < 1 || maxResults > max {
+		return 0, 0, invalidMaxResults
+	}
+	start := 0
+	if nextToken != "" {
+		var err error
+		start, err = strconv.Atoi(nextToken)
+		if err != nil || start < 0 {
+			return 0, 0, invalidNextToken
+		}
+	}
+	return maxResults, start, nil
+}
+
+// ParseString is like Parse, for services whose MaxResults is a string, such as REST services'
+// query parameters. An empty maxResults is the default.
+func ParseString(maxResults string, defaultMax int, max int, nextToken string, invalidMaxResults *awserrors.Error, invalidNextToken *awserrors.Error) (int, int, *awserrors.Error) {
+	n := 0
+	if maxResults != "" {
+		var err error
+		n, err = strconv.Atoi(maxResults)
+		if err != nil || n == 0 {
+			return 0, 0, invalidMaxResults
+		}
+	}
+	return Parse(n, defaultMax, max, nextToken, invalidMaxResults, invalidNextToken)
+}
+
+// Page returns the page of at most limit items starting at start, and the next page's token if
+// there are more.
+func Page[T any](items []T, limit int, start int) ([]T, string) {
+	if start >= len(items) {
+		return []T{}, ""
+	}
+	end := start + min(limit, len(items)-start)
+	if end < len(items) {
+		return items[start:end], strconv.Itoa(end)
+	}
+	return items[start:end], ""
+}
@@ -0,0 +1,80 @@
+package pagination
+
+import (
+	"math"
+	"testing"
+
+	"aws-in-a-box/awserrors"
+)
+
+var (
+	invalidMaxResults = awserrors.Generate400Exception("ValidationException", "MaxResults")
+	invalidNextToken  = awserrors.Generate400Exception("ValidationException", "NextToken")
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		maxResults int
+		nextToken  string
+		limit      int
+		start      int
+		err        *awserrors.Error
+	}{
+		{0, "", 10, 0, nil},
+		{5, "15", 5, 15, nil},
+		{100, "", 100, 0, nil},
+		{101, "", 0, 0, invalidMaxResults},
+		{-1, "", 0, 0, invalidMaxResults},
+		{5, "abc", 0, 0, invalidNextToken},
+		{5, "-1", 0, 0, invalidNextToken},
+	} {
+		limit, start, err := Parse(test.maxResults, 10, 100, test.nextToken, invalidMaxResults, invalidNextToken)
+		if limit != test.limit || start != test.start || err != test.err {
+			t.Errorf("Parse(%d, %q) = %d, %d, %v", test.maxResults, test.nextToken, limit, start, err)
+		}
+	}
+}
+
+func TestParseString(t *testing.T) {
+	for _, test := range []struct {
+		maxResults string
+		limit      int
+		err        *awserrors.Error
+	}{
+		{"", 10, nil},
+		{"5", 5, nil},
+		{"0", 0, invalidMaxResults},
+		{"five", 0, invalidMaxResults},
+		{"101", 0, invalidMaxResults},
+	} {
+		limit, _, err := ParseString(test.maxResults, 10, 100, "", invalidMaxResults, invalidNextToken)
+		if limit != test.limit || err != test.err {
+			t.Errorf("ParseString(%q) = %d, %v", test.maxResults, limit, err)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4}
+	var all []int
+	for next, pages := "", 0; ; pages++ {
+		_, start, _ := Parse(2, 2, 2, next, nil, nil)
+		var page []int
+		page, next = Page(items, 2, start)
+		all = append(all, page...)
+		if next == "" {
+			if pages != 2 || len(all) != len(items) {
+				t.Fatal("Unexpected pages", pages, all)
+			}
+			break
+		}
+	}
+
+	if page, next := Page(items, 2, 10); len(page) != 0 || page == nil || next != "" {
+		t.Fatal("Expected an empty page past the end", page, next)
+	}
+	// Everything can be listed at once.
+	if page, next := Page(items, math.MaxInt, 1); len(page) != 4 || next != "" {
+		t.Fatal("Expected the rest of the items", page, next)
+	}
+}
@@ -0,0 +1,63 @@
+package plugins
+
+import (
+	"fmt"
+	"net/http"
+
+	"aws-in-a-box/admin"
+)
+
+// Persist persists each service's state, returning the first error.
+func Persist(services []Service) error {
+	for _, service := range services {
+		if err := service.Persist(); err != nil {
+			return fmt.Errorf("persisting %s: %v", service.Name(), err)
+		}
+	}
+	return nil
+}
+
+// RegisterAdminHandlers adds the plugins' services to the admin API. GET plugins lists them, and
+// POST plugins/reset and POST plugins/persist reset or persist them, or only the one named by
+// ?service=.
+func RegisterAdminHandlers(adminRegistry admin.Registry, services []Service) {
+	adminRegistry["plugins"] = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		names := []string{}
+		for _, service := range services {
+			names = append(names, service.Name())
+		}
+		admin.WriteJSON(w, struct{ Services []string }{names})
+	}
+	adminRegistry["plugins/reset"] = actionHandler(services, Service.Reset)
+	adminRegistry["plugins/persist"] = actionHandler(services, Service.Persist)
+}
+
+func actionHandler(services []Service, action func(Service) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("service")
+		found := false
+		for _, service := range services {
+			if name != "" && service.Name() != name {
+				continue
+			}
+			found = true
+			if err := action(service); err != nil {
+				http.Error(w, fmt.Sprintf("%s: %v", service.Name(), err), http.StatusInternalServerError)
+				return
+			}
+		}
+		if name != "" && !found {
+			http.Error(w, "no plugin service named "+name, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,95 @@
+// Package plugins lets other packages add service emulations to aws-in-a-box without forking it.
+//
+// A plugin is a Go plugin, built with go build -buildmode=plugin against the same version of
+// aws-in-a-box, which registers its services when it's loaded:
+//
+//	package main
+//
+//	func init() {
+//		plugins.Register(func(options plugins.Options) (plugins.Service, error) {
+//			return mq.New(options)
+//		})
+//	}
+//
+// and is loaded with -plugins mq.so. Its services are served alongside the built-in ones.
+package plugins
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"plugin"
+
+	"aws-in-a-box/arn"
+	awshttp "aws-in-a-box/http"
+)
+
+// Service is a service emulation added by a plugin.
+type Service interface {
+	// Name is the service's name, such as "mq", which it's logged and listed in the admin API with.
+	Name() string
+	// RegisterHTTPHandlers adds the service's operations to the server. Operations of JSON protocols
+	// are added to the method registry by their X-Amz-Target, like the built-in services' are with
+	// http.Register. Other requests are offered to the returned handler, which returns whether it
+	// handled them, like the rest of the server's handler chain. It may be nil.
+	RegisterHTTPHandlers(logger *slog.Logger, methodRegistry awshttp.Registry) func(w http.ResponseWriter, r *http.Request) bool
+	// Reset deletes all the service's resources, as if it had just started without persisted state.
+	Reset() error
+	// Persist writes the service's state to its persist directory, if it has one. It's called when
+	// the emulator is stopped with SIGINT or SIGTERM, and through the admin API.
+	Persist() error
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The address the server runs on, for URLs in responses.
+	Addr string
+	// -persistDir, or "" if state isn't persisted. Services keep their files in a subdirectory
+	// named after them.
+	PersistDir string
+}
+
+// Factory creates a plugin's service.
+type Factory func(options Options) (Service, error)
+
+var factories []Factory
+
+// Register adds a service. Plugins call it from an init function, so their services are created
+// when they're loaded.
+func Register(factory Factory) {
+	factories = append(factories, factory)
+}
+
+// Load opens the plugins at the paths, which register their services. Go plugins need cgo, so
+// binaries built without it, like the Docker image, can't load them.
+func Load(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return fmt.Errorf("loading plugin %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// New creates the registered services. Names must be unique.
+func New(options Options) ([]Service, error) {
+	var services []Service
+	names := make(map[string]bool)
+	for _, factory := range factories {
+		service, err := factory(options)
+		if err != nil {
+			return nil, err
+		}
+		name := service.Name()
+		if name == "" {
+			return nil, fmt.Errorf("plugin services must have a name")
+		}
+		if names[name] {
+			return nil, fmt.Errorf("more than one plugin has a service named %s", name)
+		}
+		names[name] = true
+		services = append(services, service)
+	}
+	return services, nil
+}
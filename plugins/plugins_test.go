@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aws-in-a-box/admin"
+	awshttp "aws-in-a-box/http"
+)
+
+type fakeService struct {
+	name      string
+	resets    int
+	persisted int
+}
+
+func (f *fakeService) Name() string { return f.name }
+
+func (f *fakeService) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry awshttp.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	return nil
+}
+
+func (f *fakeService) Reset() error {
+	f.resets++
+	return nil
+}
+
+func (f *fakeService) Persist() error {
+	f.persisted++
+	return nil
+}
+
+func TestPlugins(t *testing.T) {
+	factories = nil
+	defer func() { factories = nil }()
+	Register(func(options Options) (Service, error) { return &fakeService{name: "mq"}, nil })
+	Register(func(options Options) (Service, error) { return &fakeService{name: "other"}, nil })
+
+	services, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminRegistry := admin.Registry{}
+	RegisterAdminHandlers(adminRegistry, services)
+
+	response := httptest.NewRecorder()
+	adminRegistry["plugins"](response, httptest.NewRequest(http.MethodGet, "/_admin/plugins", nil))
+	var listed struct{ Services []string }
+	json.Unmarshal(response.Body.Bytes(), &listed)
+	if len(listed.Services) != 2 || listed.Services[0] != "mq" {
+		t.Fatalf("Unexpected response %s", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	adminRegistry["plugins/reset"](response, httptest.NewRequest(http.MethodPost, "/_admin/plugins/reset?service=mq", nil))
+	mq, other := services[0].(*fakeService), services[1].(*fakeService)
+	if response.Code != http.StatusNoContent || mq.resets != 1 || other.resets != 0 {
+		t.Fatal("Expected only mq to be reset, got", response.Code, mq.resets, other.resets)
+	}
+	response = httptest.NewRecorder()
+	adminRegistry["plugins/reset"](response, httptest.NewRequest(http.MethodPost, "/_admin/plugins/reset?service=missing", nil))
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected unknown services to be reported, got", response.Code)
+	}
+
+	if err := Persist(services); err != nil || mq.persisted != 1 || other.persisted != 1 {
+		t.Fatal("Expected every service to be persisted", err)
+	}
+
+	Register(func(options Options) (Service, error) { return &fakeService{name: "mq"}, nil })
+	if _, err := New(Options{}); err == nil {
+		t.Fatal("Expected duplicate names to be rejected")
+	}
+}
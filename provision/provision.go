@@ -0,0 +1,551 @@
+// Package provision creates the resources described in a provisioning file at startup, so
+// applications can rely on their buckets, keys, queues, streams and tables existing without a
+// bootstrap script.
+//
+// Files are YAML or JSON. Keys are matched case-insensitively, so both name and Name work:
+//
+//	buckets:
+//	  - name: assets
+//	    objects:
+//	      - key: config.json
+//	        content: '{"debug": true}'
+//	      - key: images/logo.png
+//	        file: logo.png
+//	keys:
+//	  - aliases: [alias/app-key]
+//	    keyId: 1234abcd-12ab-34cd-56ef-1234567890ab
+//	queues:
+//	  - name: jobs
+//	    attributes:
+//	      VisibilityTimeout: 60
+//	streams:
+//	  - name: orders
+//	    shards: 4
+//	    retention: 48h
+//	tables:
+//	  - tableName: users
+//	    attributeDefinitions: [{attributeName: id, attributeType: S}]
+//	    keySchema: [{attributeName: id, keyType: HASH}]
+//	    billingMode: PAY_PER_REQUEST
+package provision
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/validation"
+	"aws-in-a-box/yaml"
+)
+
+// File describes the resources to create.
+type File struct {
+	Buckets []Bucket
+	Keys    []Key
+	Queues  []Queue
+	Streams []Stream
+	// Tables have CreateTable's parameters.
+	Tables []dynamodb.CreateTableInput
+}
+
+type Bucket struct {
+	Name string
+	// Objects are only put when the bucket is created, so they don't overwrite later changes.
+	Objects []Object
+}
+
+// Object is a bucket's seed object. Its data is either Content, or read from File.
+type Object struct {
+	Key         string
+	ContentType string
+	Content     string
+	// Relative paths are relative to the provisioning file's directory.
+	File string
+}
+
+type Key struct {
+	// Optional. If set, the key has this ID rather than a random one, which must be a UUID.
+	KeyId       string
+	Description string
+	KeySpec     string
+	KeyUsage    string
+	Tags        Strings
+	// Keys are only created if their ID, or if they don't have one, their first alias doesn't exist
+	// yet. Keys with neither are created every time the file is provisioned.
+	Aliases []string
+}
+
+type Queue struct {
+	Name       string
+	Attributes Strings
+	Tags       Strings
+}
+
+type Stream struct {
+	Name string
+	// Defaults to 1.
+	Shards int64
+	// In whole hours. Defaults to the Kinesis service's default retention.
+	Retention Duration
+}
+
+// Duration is a time.Duration, written like 48h.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("durations must be strings like 48h")
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// ParseBuckets parses bucket names written like bucket1,bucket2, as -s3InitialBuckets takes them.
+func ParseBuckets(value string) []Bucket {
+	var buckets []Bucket
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			buckets = append(buckets, Bucket{Name: name})
+		}
+	}
+	return buckets
+}
+
+// ParseStreams parses streams written like orders:4:48h,clicks:1, as -kinesisInitialStreams takes
+// them. The shard count and retention are optional, and streams without a shard count get
+// defaultShards.
+func ParseStreams(value string, defaultShards int64) ([]Stream, error) {
+	var streams []Stream
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid stream %q, expected name[:shards[:retention]]", entry)
+		}
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid stream %q, which has no name", entry)
+		}
+		stream := Stream{Name: parts[0], Shards: defaultShards}
+		if len(parts) > 1 && parts[1] != "" {
+			shards, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil || shards < 1 {
+				return nil, fmt.Errorf("invalid shard count %q for stream %s", parts[1], stream.Name)
+			}
+			stream.Shards = shards
+		}
+		if len(parts) > 2 {
+			retention, err := time.ParseDuration(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid retention %q for stream %s", parts[2], stream.Name)
+			}
+			stream.Retention = Duration(retention)
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// Strings is a map of strings, whose values can also be written as YAML numbers and booleans,
+// such as VisibilityTimeout: 60.
+type Strings map[string]string
+
+func (s *Strings) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return err
+	}
+	*s = make(Strings, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case string:
+			(*s)[key] = v
+		case json.Number:
+			(*s)[key] = v.String()
+		case bool:
+			(*s)[key] = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("%s must be a string", key)
+		}
+	}
+	return nil
+}
+
+// Decode decodes a YAML or JSON document into v. The document is decoded through JSON, so YAML
+// values decode the same way as JSON ones, and fields v doesn't have are rejected.
+func Decode(data []byte, v any) error {
+	var document any
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		if err := json.Unmarshal(data, &document); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		document, err = yaml.Parse(string(data), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return fmt.Errorf("%s must be a %s", typeError.Field, typeError.Type)
+		}
+		return err
+	}
+	return nil
+}
+
+// Parse parses a YAML or JSON provisioning file.
+func Parse(data []byte) (*File, error) {
+	file := &File{}
+	if err := Decode(data, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Load reads and parses a provisioning file. Objects' relative file paths are resolved against
+// its directory.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	file.ResolvePaths(filepath.Dir(path))
+	return file, nil
+}
+
+// ResolvePaths resolves objects' relative file paths against dir.
+func (f *File) ResolvePaths(dir string) {
+	for i := range f.Buckets {
+		for j := range f.Buckets[i].Objects {
+			object := &f.Buckets[i].Objects[j]
+			if object.File != "" && !filepath.IsAbs(object.File) {
+				object.File = filepath.Join(dir, object.File)
+			}
+		}
+	}
+}
+
+// ParseKeys parses keys written like alias/app-key,alias/other=1234abcd-12ab-34cd-56ef-1234567890ab,
+// as -kmsInitialKeys takes them. Each key has an alias, and optionally a fixed key ID. Aliases
+// without the alias/ prefix get it.
+func ParseKeys(value string) ([]Key, error) {
+	var keys []Key
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		alias, keyId, _ := strings.Cut(entry, "=")
+		if alias == "" {
+			return nil, fmt.Errorf("invalid key %q, expected alias[=keyId]", entry)
+		}
+		if !strings.HasPrefix(alias, "alias/") {
+			alias = "alias/" + alias
+		}
+		keys = append(keys, Key{KeyId: keyId, Aliases: []string{alias}})
+	}
+	return keys, nil
+}
+
+// Buckets is the local S3 service.
+type Buckets interface {
+	CreateBucket(input s3.CreateBucketInput) (*s3.CreateBucketOutput, *awserrors.Error)
+	HeadBucket(input s3.HeadBucketInput) (*s3.HeadBucketOutput, *awserrors.Error)
+	PutObject(input s3.PutObjectInput) (*s3.PutObjectOutput, *awserrors.Error)
+}
+
+// Keys is the local KMS service.
+type Keys interface {
+	CreateKey(input kms.CreateKeyInput) (*kms.CreateKeyOutput, *awserrors.Error)
+	CreateKeyWithId(keyId string, input kms.CreateKeyInput) (*kms.CreateKeyOutput, *awserrors.Error)
+	CreateAlias(input kms.CreateAliasInput) (*kms.CreateAliasOutput, *awserrors.Error)
+	DescribeKey(input kms.DescribeKeyInput) (*kms.DescribeKeyOutput, *awserrors.Error)
+}
+
+// Queues is the local SQS service.
+type Queues interface {
+	CreateQueue(input sqs.CreateQueueInput) (*sqs.CreateQueueOutput, *awserrors.Error)
+	GetQueueUrl(input sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, *awserrors.Error)
+}
+
+// Streams is the local Kinesis service.
+type Streams interface {
+	CreateStream(input kinesis.CreateStreamInput) (*kinesis.CreateStreamOutput, *awserrors.Error)
+	IncreaseStreamRetentionPeriod(input kinesis.IncreaseStreamRetentionPeriodInput) (*kinesis.IncreaseStreamRetentionPeriodOutput, *awserrors.Error)
+	DecreaseStreamRetentionPeriod(input kinesis.DecreaseStreamRetentionPeriodInput) (*kinesis.DecreaseStreamRetentionPeriodOutput, *awserrors.Error)
+	DescribeStreamSummary(input kinesis.DescribeStreamSummaryInput) (*kinesis.DescribeStreamSummaryOutput, *awserrors.Error)
+}
+
+// Tables is the local DynamoDB service.
+type Tables interface {
+	CreateTable(input dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, *awserrors.Error)
+	DescribeTable(input dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, *awserrors.Error)
+}
+
+type Options struct {
+	Logger *slog.Logger
+	// All optional. Resources of services which aren't given fail to provision.
+	S3       Buckets
+	KMS      Keys
+	SQS      Queues
+	Kinesis  Streams
+	DynamoDB Tables
+	// The retention Kinesis creates streams with, its DefaultRetention. Streams with a longer one
+	// have it increased, and those with a shorter one decreased.
+	KinesisDefaultRetention time.Duration
+}
+
+func serviceError(awserr *awserrors.Error) error {
+	return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+}
+
+func notEnabled(service string) error {
+	return fmt.Errorf("%s is not enabled", service)
+}
+
+// create validates the input, like requests are, and calls the service.
+func create[Input any, Output any](handler func(input Input) (*Output, *awserrors.Error), input Input) (*Output, error) {
+	if awserr := validation.New[Input]().Validate(input); awserr != nil {
+		return nil, serviceError(awserr)
+	}
+	output, awserr := handler(input)
+	if awserr != nil {
+		return nil, serviceError(awserr)
+	}
+	return output, nil
+}
+
+// Provision creates the file's resources. Resources which already exist, such as ones persisted by
+// an earlier run, are left as they are, so the same file can be provisioned on every startup.
+func Provision(options Options, file *File) error {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	logger := options.Logger
+
+	for _, bucket := range file.Buckets {
+		if err := provisionBucket(logger, options.S3, bucket); err != nil {
+			return fmt.Errorf("bucket %s: %v", bucket.Name, err)
+		}
+	}
+	for i, key := range file.Keys {
+		if err := provisionKey(logger, options.KMS, key); err != nil {
+			return fmt.Errorf("key %d: %v", i, err)
+		}
+	}
+	for _, queue := range file.Queues {
+		if err := provisionQueue(logger, options.SQS, queue); err != nil {
+			return fmt.Errorf("queue %s: %v", queue.Name, err)
+		}
+	}
+	for _, stream := range file.Streams {
+		if err := provisionStream(logger, options.Kinesis, options.KinesisDefaultRetention, stream); err != nil {
+			return fmt.Errorf("stream %s: %v", stream.Name, err)
+		}
+	}
+	for _, table := range file.Tables {
+		if err := provisionTable(logger, options.DynamoDB, table); err != nil {
+			return fmt.Errorf("table %s: %v", table.TableName, err)
+		}
+	}
+	return nil
+}
+
+func provisionBucket(logger *slog.Logger, buckets Buckets, bucket Bucket) error {
+	if buckets == nil {
+		return notEnabled("S3")
+	}
+	if _, awserr := buckets.HeadBucket(s3.HeadBucketInput{Bucket: bucket.Name}); awserr == nil {
+		logger.Debug("Bucket already exists", "bucket", bucket.Name)
+		return nil
+	}
+	if _, err := create(buckets.CreateBucket, s3.CreateBucketInput{Bucket: bucket.Name}); err != nil {
+		return err
+	}
+	for _, object := range bucket.Objects {
+		var data io.Reader = strings.NewReader(object.Content)
+		if object.File != "" {
+			f, err := os.Open(object.File)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			data = f
+		}
+		_, err := create(buckets.PutObject, s3.PutObjectInput{
+			Bucket:      bucket.Name,
+			Key:         object.Key,
+			ContentType: object.ContentType,
+			Data:        data,
+		})
+		if err != nil {
+			return fmt.Errorf("object %s: %v", object.Key, err)
+		}
+	}
+	logger.Info("Provisioned bucket", "bucket", bucket.Name, "objects", len(bucket.Objects))
+	return nil
+}
+
+func provisionKey(logger *slog.Logger, keys Keys, key Key) error {
+	if keys == nil {
+		return notEnabled("KMS")
+	}
+	existing := key.KeyId
+	if existing == "" && len(key.Aliases) > 0 {
+		existing = key.Aliases[0]
+	}
+	if existing != "" {
+		if _, awserr := keys.DescribeKey(kms.DescribeKeyInput{KeyId: existing}); awserr == nil {
+			logger.Debug("Key already exists", "key", existing)
+			return nil
+		}
+	}
+
+	input := kms.CreateKeyInput{
+		Description: key.Description,
+		KeySpec:     key.KeySpec,
+		KeyUsage:    key.KeyUsage,
+	}
+	for tagKey, value := range key.Tags {
+		input.Tags = append(input.Tags, kms.APITag{TagKey: tagKey, TagValue: value})
+	}
+	slices.SortFunc(input.Tags, func(a, b kms.APITag) int {
+		return strings.Compare(a.TagKey, b.TagKey)
+	})
+	createKey := keys.CreateKey
+	if key.KeyId != "" {
+		createKey = func(input kms.CreateKeyInput) (*kms.CreateKeyOutput, *awserrors.Error) {
+			return keys.CreateKeyWithId(key.KeyId, input)
+		}
+	}
+	output, err := create(createKey, input)
+	if err != nil {
+		return err
+	}
+	keyId := output.KeyMetadata.KeyId
+	for _, alias := range key.Aliases {
+		if _, err := create(keys.CreateAlias, kms.CreateAliasInput{AliasName: alias, TargetKeyId: keyId}); err != nil {
+			return fmt.Errorf("alias %s: %v", alias, err)
+		}
+	}
+	logger.Info("Provisioned key", "keyId", keyId, "aliases", key.Aliases)
+	return nil
+}
+
+func provisionQueue(logger *slog.Logger, queues Queues, queue Queue) error {
+	if queues == nil {
+		return notEnabled("SQS")
+	}
+	if _, awserr := queues.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: queue.Name}); awserr == nil {
+		logger.Debug("Queue already exists", "queue", queue.Name)
+		return nil
+	}
+	_, err := create(queues.CreateQueue, sqs.CreateQueueInput{
+		QueueName:  queue.Name,
+		Attributes: queue.Attributes,
+		Tags:       queue.Tags,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Provisioned queue", "queue", queue.Name)
+	return nil
+}
+
+func provisionStream(logger *slog.Logger, streams Streams, defaultRetention time.Duration, stream Stream) error {
+	if streams == nil {
+		return notEnabled("Kinesis")
+	}
+	if _, awserr := streams.DescribeStreamSummary(kinesis.DescribeStreamSummaryInput{StreamName: stream.Name}); awserr == nil {
+		logger.Debug("Stream already exists", "stream", stream.Name)
+		return nil
+	}
+	shards := stream.Shards
+	if shards == 0 {
+		shards = 1
+	}
+	retention := time.Duration(stream.Retention)
+	if retention%time.Hour != 0 {
+		return fmt.Errorf("retention %s must be a whole number of hours", retention)
+	}
+	if retention == 0 {
+		retention = defaultRetention
+	}
+	_, err := create(streams.CreateStream, kinesis.CreateStreamInput{
+		StreamName: stream.Name,
+		ShardCount: shards,
+	})
+	if err != nil {
+		return err
+	}
+	hours := int32(retention / time.Hour)
+	if retention > defaultRetention {
+		_, err = create(streams.IncreaseStreamRetentionPeriod, kinesis.IncreaseStreamRetentionPeriodInput{
+			StreamName:           stream.Name,
+			RetentionPeriodHours: hours,
+		})
+	} else if retention < defaultRetention {
+		_, err = create(streams.DecreaseStreamRetentionPeriod, kinesis.DecreaseStreamRetentionPeriodInput{
+			StreamName:           stream.Name,
+			RetentionPeriodHours: hours,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("Provisioned stream", "stream", stream.Name, "shards", shards, "retention", retention)
+	return nil
+}
+
+func provisionTable(logger *slog.Logger, tables Tables, table dynamodb.CreateTableInput) error {
+	if tables == nil {
+		return notEnabled("DynamoDB")
+	}
+	if _, awserr := tables.DescribeTable(dynamodb.DescribeTableInput{TableName: table.TableName}); awserr == nil {
+		logger.Debug("Table already exists", "table", table.TableName)
+		return nil
+	}
+	if _, err := create(tables.CreateTable, table); err != nil {
+		return err
+	}
+	logger.Info("Provisioned table", "table", table.TableName)
+	return nil
+}
@@ -0,0 +1,225 @@
+package provision
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+)
+
+const testFile = `
+buckets:
+  - name: assets
+    objects:
+      - key: config.json
+        content: '{"debug": true}'
+        contentType: application/json
+      - key: seed.csv
+        file: seed.csv
+keys:
+  - description: App key
+    aliases: [alias/app-key]
+  - keyId: 1234abcd-12ab-34cd-56ef-1234567890ab
+queues:
+  - name: jobs
+    attributes:
+      VisibilityTimeout: 60
+streams:
+  - name: orders
+    shards: 4
+    retention: 48h
+tables:
+  - tableName: users
+    attributeDefinitions: [{attributeName: id, attributeType: S}]
+    keySchema: [{attributeName: id, keyType: HASH}]
+    billingMode: PAY_PER_REQUEST
+`
+
+func TestProvision(t *testing.T) {
+	generator := arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}
+	s3Service, err := s3.New(s3.Options{PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kmsService, err := kms.New(kms.Options{ArnGenerator: generator, PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqsService := sqs.New(sqs.Options{ArnGenerator: generator, Addr: "localhost:4566"})
+	kinesisService := kinesis.New(kinesis.Options{ArnGenerator: generator})
+	dynamoDBService, err := dynamodb.New(dynamodb.Options{ArnGenerator: generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := Options{
+		S3:       s3Service,
+		KMS:      kmsService,
+		SQS:      sqsService,
+		Kinesis:  kinesisService,
+		DynamoDB: dynamoDBService,
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resources.yaml")
+	if err := os.WriteFile(path, []byte(testFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "seed.csv"), []byte("id\n1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Provisioning again leaves the resources as they are.
+	for i := 0; i < 2; i++ {
+		if err := Provision(options, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	object, awserr := s3Service.GetObject(s3.GetObjectInput{Bucket: "assets", Key: "seed.csv"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	data, _ := io.ReadAll(object.Body)
+	if string(data) != "id\n1\n" {
+		t.Fatalf("Unexpected object %q", data)
+	}
+
+	aliases, _ := kmsService.ListAliases(kms.ListAliasesInput{})
+	if len(aliases.Aliases) != 1 || aliases.Aliases[0].AliasName != "alias/app-key" {
+		t.Fatalf("Unexpected aliases %+v", aliases.Aliases)
+	}
+	keys, _ := kmsService.ListKeys(kms.ListKeysInput{})
+	if len(keys.Keys) != 2 {
+		t.Fatalf("Unexpected keys %+v", keys.Keys)
+	}
+	if _, awserr := kmsService.DescribeKey(kms.DescribeKeyInput{KeyId: "1234abcd-12ab-34cd-56ef-1234567890ab"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	queueUrl, awserr := sqsService.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "jobs"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	attributes, _ := sqsService.GetQueueAttributes(sqs.GetQueueAttributesInput{
+		QueueUrl:       queueUrl.QueueUrl,
+		AttributeNames: []string{"VisibilityTimeout"},
+	})
+	if attributes.Attributes["VisibilityTimeout"] != "60" {
+		t.Fatalf("Unexpected attributes %v", attributes.Attributes)
+	}
+
+	stream, awserr := kinesisService.DescribeStreamSummary(kinesis.DescribeStreamSummaryInput{StreamName: "orders"})
+	if awserr != nil || stream.StreamDescriptionSummary.OpenShardCount != 4 || stream.StreamDescriptionSummary.RetentionPeriodHours != 48 {
+		t.Fatal("Unexpected stream", stream, awserr)
+	}
+
+	if _, awserr := dynamoDBService.DescribeTable(dynamodb.DescribeTableInput{TableName: "users"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestProvisionStreamRetention(t *testing.T) {
+	kinesisService := kinesis.New(kinesis.Options{
+		ArnGenerator:     arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		DefaultRetention: 72 * time.Hour,
+	})
+	options := Options{Kinesis: kinesisService, KinesisDefaultRetention: 72 * time.Hour}
+	file := &File{Streams: []Stream{
+		{Name: "shorter", Retention: Duration(48 * time.Hour)},
+		{Name: "longer", Retention: Duration(96 * time.Hour)},
+		{Name: "default"},
+	}}
+	if err := Provision(options, file); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, hours := range map[string]int32{"shorter": 48, "longer": 96, "default": 72} {
+		stream, awserr := kinesisService.DescribeStreamSummary(kinesis.DescribeStreamSummaryInput{StreamName: name})
+		if awserr != nil || stream.StreamDescriptionSummary.RetentionPeriodHours != hours {
+			t.Fatal("Unexpected stream", name, stream, awserr)
+		}
+	}
+}
+
+func TestProvisionErrors(t *testing.T) {
+	if _, err := Parse([]byte("buckets:\n  - nmae: typo\n")); err == nil {
+		t.Fatal("Expected unknown fields to be rejected")
+	}
+
+	file, err := Parse([]byte(`{"Streams": [{"Name": "orders"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Provision(Options{}, file); err == nil || err.Error() != "stream orders: Kinesis is not enabled" {
+		t.Fatal("Unexpected error", err)
+	}
+
+	file = &File{Streams: []Stream{{Name: "invalid name"}}}
+	if err := Provision(Options{Kinesis: kinesis.New(kinesis.Options{})}, file); err == nil {
+		t.Fatal("Expected invalid names to be rejected")
+	}
+}
+
+func TestParseStreams(t *testing.T) {
+	streams, err := ParseStreams("orders:4:48h,clicks:1,,events", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Stream{
+		{Name: "orders", Shards: 4, Retention: Duration(48 * time.Hour)},
+		{Name: "clicks", Shards: 1},
+		{Name: "events", Shards: 2},
+	}
+	if !reflect.DeepEqual(streams, want) {
+		t.Errorf("got %+v, want %+v", streams, want)
+	}
+
+	if streams, err := ParseStreams("", 2); err != nil || len(streams) != 0 {
+		t.Fatal("Expected no streams, got", streams, err)
+	}
+	for _, value := range []string{":4", ":", "orders:0", "orders:four", "orders:1:2d", "orders:1:48h:extra"} {
+		if _, err := ParseStreams(value, 2); err == nil {
+			t.Error("Expected an error for", value)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("alias/app-key,other=1234abcd-12ab-34cd-56ef-1234567890ab,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Key{
+		{Aliases: []string{"alias/app-key"}},
+		{KeyId: "1234abcd-12ab-34cd-56ef-1234567890ab", Aliases: []string{"alias/other"}},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %+v, want %+v", keys, want)
+	}
+	if _, err := ParseKeys("=1234abcd-12ab-34cd-56ef-1234567890ab"); err == nil {
+		t.Error("Expected keys without an alias to be rejected")
+	}
+}
+
+func TestParseBuckets(t *testing.T) {
+	buckets := ParseBuckets("bucket1, bucket2,")
+	want := []Bucket{{Name: "bucket1"}, {Name: "bucket2"}}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Errorf("got %+v, want %+v", buckets, want)
+	}
+	if buckets := ParseBuckets(""); len(buckets) != 0 {
+		t.Error("Expected no buckets, got", buckets)
+	}
+}
@@ -0,0 +1,21 @@
+// Package random generates the random IDs, keys and secrets services issue.
+package random
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// String returns a cryptographically random string of the alphabet's characters.
+func String(alphabet string, length int) string {
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			panic(err)
+		}
+		sb.WriteByte(alphabet[n.Int64()])
+	}
+	return sb.String()
+}
@@ -0,0 +1,16 @@
+package random
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	s := String("ab", 64)
+	if len(s) != 64 || strings.Trim(s, "ab") != "" {
+		t.Fatal("Unexpected string", s)
+	}
+	if String("ab", 64) == s {
+		t.Fatal("Expected strings to differ")
+	}
+}
@@ -0,0 +1,221 @@
+// Package region works out which region requests are for, so services can keep each region's
+// resources apart like AWS does, rather than a stream created in us-east-1 being readable by a
+// client configured for eu-west-1.
+package region
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/auth"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/endpoint"
+)
+
+// Header overrides the region requests are for, such as for those which aren't signed, or are
+// signed with signature version 2, whose signatures don't say.
+const Header = "X-Aws-In-A-Box-Region"
+
+var pattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// FromRequest returns the region the request is for: its Header's, the region its Signature
+// Version 4 signature is scoped to, or its AWS hostname's, in that order. It's empty if none of
+// them say, or aren't valid regions, for the emulator's default region.
+func FromRequest(r *http.Request) string {
+	if region := r.Header.Get(Header); pattern.MatchString(region) {
+		return region
+	}
+	if region := auth.SigningRegion(r); pattern.MatchString(region) {
+		return region
+	}
+	if e, ok := endpoint.FromRequest(r); ok {
+		return e.Region
+	}
+	return ""
+}
+
+// Known is AWS's regions, which requests can be for unless the emulator is configured with others.
+// https://docs.aws.amazon.com/global-infrastructure/latest/regions/aws-regions.html
+var Known = []string{
+	"af-south-1",
+	"ap-east-1",
+	"ap-northeast-1",
+	"ap-northeast-2",
+	"ap-northeast-3",
+	"ap-south-1",
+	"ap-south-2",
+	"ap-southeast-1",
+	"ap-southeast-2",
+	"ap-southeast-3",
+	"ap-southeast-4",
+	"ap-southeast-5",
+	"ap-southeast-7",
+	"ca-central-1",
+	"ca-west-1",
+	"cn-north-1",
+	"cn-northwest-1",
+	"eu-central-1",
+	"eu-central-2",
+	"eu-north-1",
+	"eu-south-1",
+	"eu-south-2",
+	"eu-west-1",
+	"eu-west-2",
+	"eu-west-3",
+	"il-central-1",
+	"me-central-1",
+	"me-south-1",
+	"mx-central-1",
+	"sa-east-1",
+	"us-east-1",
+	"us-east-2",
+	"us-gov-east-1",
+	"us-gov-west-1",
+	"us-west-1",
+	"us-west-2",
+}
+
+// Regions keeps a service's instance for each region, each with its own resources. Instances for
+// regions other than the default are created when they're first used.
+type Regions[T any] struct {
+	defaultRegion string
+	allowed       map[string]bool
+	newInstance   func(region string) (T, error)
+
+	mu        sync.Mutex
+	instances map[string]T
+}
+
+// New returns the regions of a service whose instance for the default region is defaultInstance.
+// newInstance creates the instances for the other allowed regions, or for the Known ones if
+// allowed is empty, so the number of instances is bounded whatever regions requests say. It's also
+// used to reset the regions imported state has nothing for, so it shouldn't do more than create
+// the instance, such as start goroutines or read files.
+func New[T any](defaultRegion string, allowed []string, defaultInstance T, newInstance func(region string) (T, error)) *Regions[T] {
+	if len(allowed) == 0 {
+		allowed = Known
+	}
+	r := &Regions[T]{
+		defaultRegion: defaultRegion,
+		allowed:       map[string]bool{defaultRegion: true},
+		newInstance:   newInstance,
+		instances:     map[string]T{defaultRegion: defaultInstance},
+	}
+	for _, region := range allowed {
+		r.allowed[region] = true
+	}
+	return r
+}
+
+// Get returns the instance for the region, or for the default region if it's empty. It fails if
+// the region isn't allowed, rather than the request reaching another region's resources.
+func (r *Regions[T]) Get(region string) (T, error) {
+	if region == "" {
+		region = r.defaultRegion
+	}
+	if !r.allowed[region] {
+		var zero T
+		return zero, fmt.Errorf("region %s isn't enabled; the emulator's regions are set with -regions", region)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance, ok := r.instances[region]
+	if !ok {
+		var err error
+		instance, err = r.newInstance(region)
+		if err != nil {
+			return instance, err
+		}
+		r.instances[region] = instance
+	}
+	return instance, nil
+}
+
+// Default returns the instance for the default region.
+func (r *Regions[T]) Default() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.instances[r.defaultRegion]
+}
+
+// ForRequest returns the instance for the region the request is for, as FromRequest says.
+func (r *Regions[T]) ForRequest(req *http.Request) (T, *awserrors.Error) {
+	instance, err := r.Get(FromRequest(req))
+	if err != nil {
+		return instance, awserrors.Generate400Exception("InvalidRegionException", err.Error())
+	}
+	return instance, nil
+}
+
+// ForARN returns the instance for the region of the resource with the ARN, for services which use
+// other services' resources by ARN, such as EventBridge's targets. ARNs without a region, or which
+// aren't valid, are the default region's, whose instance reports them as it always has.
+func (r *Regions[T]) ForARN(resourceARN string) (T, error) {
+	a, err := arn.Parse(resourceARN)
+	if err != nil {
+		return r.Default(), nil
+	}
+	return r.Get(a.Region)
+}
+
+// Allowed returns the regions requests can be for, the default region first and the others
+// sorted, whether or not they've been used.
+func (r *Regions[T]) Allowed() []string {
+	regions := []string{r.defaultRegion}
+	for region := range r.allowed {
+		if region != r.defaultRegion {
+			regions = append(regions, region)
+		}
+	}
+	slices.Sort(regions[1:])
+	return regions
+}
+
+// Others returns the regions other than the default which have been used, sorted.
+func (r *Regions[T]) Others() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lockedOthers()
+}
+
+func (r *Regions[T]) lockedOthers() []string {
+	var regions []string
+	for region := range r.instances {
+		if region != r.defaultRegion {
+			regions = append(regions, region)
+		}
+	}
+	slices.Sort(regions)
+	return regions
+}
+
+// All returns the instances of the regions which have been used, the default region's first, such
+// as for background work like expiring resources, which one goroutine does for every region.
+func (r *Regions[T]) All() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instances := []T{r.instances[r.defaultRegion]}
+	for _, region := range r.lockedOthers() {
+		instances = append(instances, r.instances[region])
+	}
+	return instances
+}
+
+// Method returns a handler which calls the method of the instance for the region each request is
+// for, for registering with the protocols' RegisterWithRequest.
+func Method[T any, Input any, Output any](
+	regions *Regions[T],
+	method func(instance T, input Input) (*Output, *awserrors.Error),
+) func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+	return func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+		instance, awserr := regions.ForRequest(r)
+		if awserr != nil {
+			return nil, awserr
+		}
+		return method(instance, input)
+	}
+}
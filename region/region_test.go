@@ -0,0 +1,106 @@
+package region
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		url     string
+		headers map[string]string
+		want    string
+	}{
+		{"unsigned", "http://localhost:4569/", nil, ""},
+		{"signed", "http://localhost:4569/", map[string]string{
+			"Authorization": "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/eu-west-1/kinesis/aws4_request, SignedHeaders=host, Signature=abc",
+		}, "eu-west-1"},
+		{"presigned", "http://localhost:4569/bucket/key?X-Amz-Credential=AKIA1%2F20240102%2Fap-south-1%2Fs3%2Faws4_request", nil, "ap-south-1"},
+		{"signature version 2", "http://localhost:4569/", map[string]string{"Authorization": "AWS AKIA1:signature"}, ""},
+		{"header", "http://localhost:4569/", map[string]string{
+			Header:          "us-west-2",
+			"Authorization": "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/eu-west-1/kinesis/aws4_request",
+		}, "us-west-2"},
+		{"invalid header", "http://localhost:4569/", map[string]string{Header: "mars"}, ""},
+		{"hostname", "http://kinesis.eu-central-1.amazonaws.com/", nil, "eu-central-1"},
+		{"signature over hostname", "http://kinesis.eu-central-1.amazonaws.com/", map[string]string{
+			"Authorization": "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/eu-west-1/kinesis/aws4_request",
+		}, "eu-west-1"},
+	} {
+		request := httptest.NewRequest(http.MethodPost, test.url, nil)
+		for name, value := range test.headers {
+			request.Header.Set(name, value)
+		}
+		if got := FromRequest(request); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRegions(t *testing.T) {
+	created := 0
+	regions := New("us-east-1", nil, "default", func(region string) (string, error) {
+		created++
+		return region, nil
+	})
+	get := func(region string) string {
+		t.Helper()
+		instance, err := regions.Get(region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return instance
+	}
+	if get("") != "default" || get("us-east-1") != "default" {
+		t.Fatal("Expected the default region's instance")
+	}
+	if get("eu-west-1") != "eu-west-1" || get("eu-west-1") != "eu-west-1" || created != 1 {
+		t.Fatal("Expected eu-west-1's instance to be created once", created)
+	}
+	get("ap-south-1")
+	// Regions which aren't known fail, rather than each creating an instance or reaching the
+	// default region's.
+	for _, region := range []string{"xx-nowhere-1", "us-east-1-"} {
+		if _, err := regions.Get(region); err == nil || created != 2 {
+			t.Fatal("Expected an unknown region to fail", region, created)
+		}
+	}
+	if others := regions.Others(); len(others) != 2 || others[0] != "ap-south-1" || others[1] != "eu-west-1" {
+		t.Fatal("Unexpected regions", others)
+	}
+	if all := regions.All(); len(all) != 3 || all[0] != "default" || all[1] != "ap-south-1" {
+		t.Fatal("Unexpected instances", all)
+	}
+	if instance, err := regions.ForARN("arn:aws:kinesis:eu-west-1:123456789012:stream/orders"); err != nil || instance != "eu-west-1" {
+		t.Fatal("Expected the ARN's region's instance", instance, err)
+	}
+	if instance, err := regions.ForARN("arn:aws:s3:::bucket"); err != nil || instance != "default" {
+		t.Fatal("Expected ARNs without a region to be the default region's", instance, err)
+	}
+}
+
+func TestAllowedRegions(t *testing.T) {
+	regions := New("us-east-1", []string{"local-dev-1"}, "default", func(region string) (string, error) {
+		return region, nil
+	})
+	if instance, err := regions.Get("local-dev-1"); err != nil || instance != "local-dev-1" {
+		t.Fatal("Expected the allowed region to have its own instance", instance, err)
+	}
+	if instance, err := regions.Get("us-east-1"); err != nil || instance != "default" {
+		t.Fatal("Expected the default region to be allowed", instance, err)
+	}
+	if _, err := regions.Get("eu-west-1"); err == nil {
+		t.Fatal("Expected only the allowed regions to be enabled", regions.Others())
+	}
+	if allowed := regions.Allowed(); !slices.Equal(allowed, []string{"us-east-1", "local-dev-1"}) {
+		t.Fatal("Unexpected allowed regions", allowed)
+	}
+	request := httptest.NewRequest(http.MethodPost, "http://localhost:4569/", nil)
+	request.Header.Set(Header, "eu-west-1")
+	if _, awserr := regions.ForRequest(request); awserr == nil || awserr.Body.Type != "InvalidRegionException" {
+		t.Fatal("Expected a request for a region which isn't enabled to fail", awserr)
+	}
+}
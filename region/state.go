@@ -0,0 +1,185 @@
+package region
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/memory"
+	"aws-in-a-box/state"
+)
+
+// regionsDir has the entries of the regions other than the default, each in a subdirectory named
+// after its region. The default region's entries are where they were before services had regions.
+const regionsDir = "regions"
+
+func regionDir(region string) string {
+	return path.Join(regionsDir, region)
+}
+
+// archiveRegions returns the regions other than the default which the reader has entries for,
+// sorted.
+func archiveRegions(r *state.Reader) []string {
+	var regions []string
+	for _, name := range r.Names() {
+		rest, ok := strings.CutPrefix(name, regionsDir+"/")
+		region, _, _ := strings.Cut(rest, "/")
+		if ok && !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+func (r *Regions[T]) instance(region string) T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.instances[region]
+}
+
+// TryPause locks the regions, so none are added, and the mutex of every region's instance, as
+// state.TryLock does, for services whose instances each guard their state with one mutex.
+func (r *Regions[T]) TryPause(mutex func(instance T) *sync.Mutex) (resume func(), ok bool) {
+	if !r.mu.TryLock() {
+		return nil, false
+	}
+	mutexes := make([]*sync.Mutex, 0, len(r.instances))
+	for _, instance := range r.instances {
+		mutexes = append(mutexes, mutex(instance))
+	}
+	unlock, ok := state.TryLock(mutexes...)
+	if !ok {
+		r.mu.Unlock()
+		return nil, false
+	}
+	return func() {
+		unlock()
+		r.mu.Unlock()
+	}, true
+}
+
+// ExportState writes the state of each region which has been used with export: the default
+// region's to the service's directory, and each other region's to regions/<region>/ in it.
+func (r *Regions[T]) ExportState(w *state.Writer, export func(instance T, w *state.Writer) error) error {
+	unlock := w.Lock(&r.mu)
+	others := r.lockedOthers()
+	instances := make([]T, len(others))
+	for i, region := range others {
+		instances[i] = r.instances[region]
+	}
+	defaultInstance := r.instances[r.defaultRegion]
+	unlock()
+
+	if err := export(defaultInstance, w); err != nil {
+		return err
+	}
+	for i, region := range others {
+		if err := export(instances[i], w.Dir(regionDir(region))); err != nil {
+			return fmt.Errorf("%s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// ImportState checks the state of each region in the archive with importState, and returns a
+// function which replaces every region's. Regions which have been used but which the archive has
+// nothing for are reset to the state export writes for a new instance, so the services' defaults,
+// like EventBridge's default event bus, are kept.
+func (r *Regions[T]) ImportState(
+	reader *state.Reader,
+	importState func(instance T, r *state.Reader) (func() error, error),
+	export func(instance T, w *state.Writer) error,
+) (func() error, error) {
+	// Every region is checked before any is replaced, so a bad archive doesn't leave some of them
+	// imported.
+	replace, err := importState(r.Default(), reader)
+	if err != nil {
+		return nil, err
+	}
+	replacements := []func() error{replace}
+
+	imported := archiveRegions(reader)
+	for _, region := range imported {
+		if region == r.defaultRegion {
+			return nil, fmt.Errorf("%s is the default region, whose state isn't in %s", region, regionsDir)
+		}
+		instance, err := r.Get(region)
+		if err != nil {
+			return nil, err
+		}
+		replace, err := importState(instance, reader.Dir(regionDir(region)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", region, err)
+		}
+		replacements = append(replacements, replace)
+	}
+	for _, region := range r.Others() {
+		if slices.Contains(imported, region) {
+			continue
+		}
+		fresh, err := r.newInstance(region)
+		if err != nil {
+			return nil, err
+		}
+		empty, err := state.ReadExported(func(w *state.Writer) error {
+			return export(fresh, w)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", region, err)
+		}
+		replace, err := importState(r.instance(region), empty)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", region, err)
+		}
+		replacements = append(replacements, replace)
+	}
+
+	return func() error {
+		for _, replace := range replacements {
+			if err := replace(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// StateResources returns a state.ResourcesFunc which lists the resources of each region in the
+// archive with resources, for diffing archives. Resources outside the default region are prefixed
+// with their region, such as eu-west-1/stream/orders.
+func StateResources(resources state.ResourcesFunc) state.ResourcesFunc {
+	return func(r *state.Reader) (map[string]any, error) {
+		listed, err := resources(r)
+		if err != nil {
+			return nil, err
+		}
+		if listed == nil {
+			listed = make(map[string]any)
+		}
+		for _, region := range archiveRegions(r) {
+			regionListed, err := resources(r.Dir(regionDir(region)))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", region, err)
+			}
+			for name, v := range regionListed {
+				listed[region+"/"+name] = v
+			}
+		}
+		return listed, nil
+	}
+}
+
+// MemoryUsage returns the resources of each region which has been used, as usage lists them.
+// Resources outside the default region are prefixed with their region.
+func (r *Regions[T]) MemoryUsage(usage func(instance T) []memory.Resource) []memory.Resource {
+	resources := usage(r.Default())
+	for _, region := range r.Others() {
+		for _, resource := range usage(r.instance(region)) {
+			resource.Name = region + "/" + resource.Name
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
@@ -0,0 +1,129 @@
+package region
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"aws-in-a-box/memory"
+	"aws-in-a-box/state"
+)
+
+// queues is a service whose new instances have a default queue.
+type queues struct {
+	regions *Regions[*queues]
+
+	mu    sync.Mutex
+	names []string
+}
+
+type queuesState struct {
+	Names []string
+}
+
+func newQueues() *queues {
+	q := &queues{names: []string{"default"}}
+	q.regions = New("us-east-1", nil, q, func(region string) (*queues, error) {
+		return &queues{regions: q.regions, names: []string{"default"}}, nil
+	})
+	return q
+}
+
+func (q *queues) in(t *testing.T, region string) *queues {
+	t.Helper()
+	instance, err := q.regions.Get(region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instance
+}
+
+func (q *queues) ExportState(w *state.Writer) error {
+	return q.regions.ExportState(w, (*queues).exportState)
+}
+
+func (q *queues) exportState(w *state.Writer) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return w.WriteJSON("queues.json", queuesState{Names: q.names})
+}
+
+func (q *queues) ImportState(r *state.Reader) (func() error, error) {
+	return q.regions.ImportState(r, (*queues).importState, (*queues).exportState)
+}
+
+func (q *queues) importState(r *state.Reader) (func() error, error) {
+	var imported queuesState
+	if err := r.ReadJSON("queues.json", &imported); err != nil {
+		return nil, err
+	}
+	return func() error {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.names = imported.Names
+		return nil
+	}, nil
+}
+
+func (q *queues) memoryUsage() []memory.Resource {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var resources []memory.Resource
+	for _, name := range q.names {
+		resources = append(resources, memory.Resource{Name: name, Count: 1})
+	}
+	return resources
+}
+
+func TestExportImportState(t *testing.T) {
+	exported := newQueues()
+	exported.names = append(exported.names, "orders")
+	exported.in(t, "eu-west-1").names = []string{"europe"}
+
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"queues": exported}, ""); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+
+	imported := newQueues()
+	// Regions the archive has nothing for are reset to a new instance's state.
+	imported.in(t, "ap-south-1").names = []string{"removed"}
+	if _, _, err := state.Import(bytes.NewReader(archive), state.Registry{"queues": imported}); err != nil {
+		t.Fatal(err)
+	}
+	if names := imported.names; len(names) != 2 || names[1] != "orders" {
+		t.Fatal("Unexpected default region queues", names)
+	}
+	if names := imported.in(t, "eu-west-1").names; len(names) != 1 || names[0] != "europe" {
+		t.Fatal("Unexpected eu-west-1 queues", names)
+	}
+	if names := imported.in(t, "ap-south-1").names; len(names) != 1 || names[0] != "default" {
+		t.Fatal("Expected ap-south-1 to be reset", names)
+	}
+
+	resources := imported.regions.MemoryUsage((*queues).memoryUsage)
+	var names []string
+	for _, resource := range resources {
+		names = append(names, resource.Name)
+	}
+	want := []string{"default", "orders", "ap-south-1/default", "eu-west-1/europe"}
+	if len(names) != len(want) {
+		t.Fatal("Unexpected memory usage", names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatal("Unexpected memory usage", names)
+		}
+	}
+
+	// Archives with regions which aren't enabled aren't imported.
+	restricted := newQueues()
+	restricted.regions.allowed = map[string]bool{"us-east-1": true}
+	if _, _, err := state.Import(bytes.NewReader(archive), state.Registry{"queues": restricted}); err == nil {
+		t.Fatal("Expected eu-west-1's queues not to be imported")
+	}
+	if names := restricted.names; len(names) != 1 {
+		t.Fatal("Expected the default region to be left as it was", names)
+	}
+}
@@ -0,0 +1,313 @@
+// Package scenario runs a timeline of steps against the emulator, such as creating resources,
+// publishing records and advancing the clock, so failure and recovery scenarios can be reproduced
+// the same way every time.
+//
+// Files are YAML or JSON:
+//
+//	steps:
+//	  - provision:
+//	      streams: [{name: orders}]
+//	      queues: [{name: jobs, attributes: {VisibilityTimeout: 30}}]
+//	  - at: 2s
+//	    call: Kinesis_20131202.PutRecord
+//	    input: {StreamName: orders, PartitionKey: a, Data: aGVsbG8=}
+//	  - at: 2s
+//	    query: {Action: SendMessage, QueueUrl: "http://localhost:4569/123456789012/jobs", MessageBody: hello}
+//	  - at: 2s
+//	    request: {method: PUT, path: /bucket/key, body: hello}
+//	  - at: 3s
+//	    fault: {name: throttle, target: Kinesis_20131202.PutRecord, fault: error=ProvisionedThroughputExceededException}
+//	  - at: 5s
+//	    advanceClock: 1h
+//	  - at: 10s
+//	    clearFault: throttle
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/clock"
+	"aws-in-a-box/provision"
+	"aws-in-a-box/server"
+)
+
+type Scenario struct {
+	Steps []Step
+}
+
+// Step is an event on the timeline. Each step has exactly one of Provision, Call, Query, Request,
+// AdvanceClock, Fault and ClearFault.
+type Step struct {
+	// When the step runs, relative to the start of the scenario. Steps run in order, so a step
+	// whose time has already passed runs as soon as the one before it has finished.
+	At provision.Duration
+
+	// Resources to create, like a provisioning file's. Those which exist are left as they are.
+	Provision *provision.File
+
+	// An operation of a service with a JSON protocol, by its target, such as
+	// Kinesis_20131202.PutRecord. Input is the operation's request, and defaults to {}.
+	Call  string
+	Input json.RawMessage
+
+	// The parameters of an operation of a service with the Query protocol, such as SQS or SNS,
+	// including its Action. Values are strings, numbers or booleans; lists and maps are written
+	// out flattened, as in the request, such as Attribute.1.Name.
+	Query map[string]any
+
+	// A request to a service with a REST protocol, such as S3.
+	Request *Request
+
+	// How far to move the emulator's clock forward.
+	AdvanceClock provision.Duration
+
+	// A fault rule to add to the server, replacing the rule with the same name if there is one.
+	Fault *server.FaultRule
+	// The name of a fault rule to delete.
+	ClearFault string
+}
+
+// Request is a REST request. It fails unless it has a 2xx response.
+type Request struct {
+	Method string
+	// The path, with its query string.
+	Path    string
+	Headers map[string]string
+	Body    string
+}
+
+// Parse parses a YAML or JSON scenario file.
+func Parse(data []byte) (*Scenario, error) {
+	scenario := &Scenario{}
+	if err := provision.Decode(data, scenario); err != nil {
+		return nil, err
+	}
+	var previous provision.Duration
+	for i, step := range scenario.Steps {
+		actions := 0
+		if step.Provision != nil {
+			actions++
+		}
+		if step.Call != "" {
+			actions++
+		}
+		if step.Query != nil {
+			actions++
+		}
+		if step.Request != nil {
+			actions++
+		}
+		if step.AdvanceClock != 0 {
+			actions++
+		}
+		if step.Fault != nil {
+			actions++
+		}
+		if step.ClearFault != "" {
+			actions++
+		}
+		if actions != 1 {
+			return nil, fmt.Errorf("step %d must have exactly one of provision, call, query, request, advanceClock, fault and clearFault", i+1)
+		}
+		if step.Query != nil {
+			if _, err := queryForm(step.Query); err != nil {
+				return nil, fmt.Errorf("step %d: %v", i+1, err)
+			}
+		}
+		if step.Request != nil && (step.Request.Method == "" || !strings.HasPrefix(step.Request.Path, "/")) {
+			return nil, fmt.Errorf("step %d's request must have a method and a path starting with /", i+1)
+		}
+		if step.Input != nil && step.Call == "" {
+			return nil, fmt.Errorf("step %d has an input, but no call", i+1)
+		}
+		if step.AdvanceClock < 0 {
+			return nil, fmt.Errorf("step %d can't move the clock backwards", i+1)
+		}
+		if step.At < previous {
+			return nil, fmt.Errorf("step %d is at %v, before the step before it", i+1, time.Duration(step.At))
+		}
+		previous = step.At
+	}
+	return scenario, nil
+}
+
+// Load reads and parses a scenario file. Objects' relative file paths are resolved against its
+// directory, as they are in provisioning files.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scenario, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, step := range scenario.Steps {
+		if step.Provision != nil {
+			step.Provision.ResolvePaths(filepath.Dir(path))
+		}
+	}
+	return scenario, nil
+}
+
+type Options struct {
+	Logger *slog.Logger
+	// The services provision steps create resources with.
+	Provision provision.Options
+	// The JSON protocols' method registry, which call steps are made through.
+	Methods map[string]http.HandlerFunc
+	// The server's handler chain, which query and request steps are made through.
+	Handler server.HandlerFunc
+	// The server's fault rules, which fault and clearFault steps change.
+	Faults *server.FaultRules
+}
+
+// Run runs the scenario's steps at their times. It stops at the first step which fails.
+func Run(options Options, scenario *Scenario) error {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.Provision.Logger == nil {
+		options.Provision.Logger = options.Logger
+	}
+	logger := options.Logger
+
+	start := time.Now()
+	for i, step := range scenario.Steps {
+		if wait := time.Duration(step.At) - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		logger.Info("Running step", "step", i+1, "at", time.Duration(step.At))
+		if err := runStep(options, step); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func runStep(options Options, step Step) error {
+	switch {
+	case step.Provision != nil:
+		return provision.Provision(options.Provision, step.Provision)
+	case step.Call != "":
+		return call(options.Methods, step.Call, step.Input)
+	case step.Query != nil:
+		form, err := queryForm(step.Query)
+		if err != nil {
+			return err
+		}
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return send(options.Handler, form.Get("Action"), request)
+	case step.Request != nil:
+		request := httptest.NewRequest(step.Request.Method, step.Request.Path, strings.NewReader(step.Request.Body))
+		for name, value := range step.Request.Headers {
+			request.Header.Set(name, value)
+		}
+		if host := request.Header.Get("Host"); host != "" {
+			request.Host = host
+		}
+		return send(options.Handler, step.Request.Method+" "+step.Request.Path, request)
+	case step.Fault != nil:
+		if options.Faults == nil {
+			return fmt.Errorf("fault rules aren't enabled")
+		}
+		if err := options.Faults.Set(*step.Fault); err != nil {
+			return err
+		}
+		options.Logger.Info("Set fault rule", "rule", step.Fault.Name, "fault", step.Fault.Fault)
+		return nil
+	case step.ClearFault != "":
+		if options.Faults == nil {
+			return fmt.Errorf("fault rules aren't enabled")
+		}
+		if !options.Faults.Delete(step.ClearFault) {
+			return fmt.Errorf("there's no fault rule named %s", step.ClearFault)
+		}
+		options.Logger.Info("Deleted fault rule", "rule", step.ClearFault)
+		return nil
+	default:
+		clock.Advance(time.Duration(step.AdvanceClock))
+		options.Logger.Info("Advanced the clock", "by", time.Duration(step.AdvanceClock), "now", clock.Now())
+		return nil
+	}
+}
+
+// call makes the request through the method registry, as if it had been sent to the server.
+func call(methods map[string]http.HandlerFunc, target string, input json.RawMessage) (err error) {
+	handler, ok := methods[target]
+	if !ok {
+		return fmt.Errorf("unknown operation %s, or its service isn't enabled", target)
+	}
+	if input == nil {
+		input = json.RawMessage("{}")
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(input))
+	request.Header.Set("X-Amz-Target", target)
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	response := httptest.NewRecorder()
+	// Handlers panic on malformed input, which the server would turn into a failed request.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", target, r)
+		}
+	}()
+	handler(response, request)
+	if response.Code != http.StatusOK {
+		return fmt.Errorf("%s: %s", target, response.Body.String())
+	}
+	return nil
+}
+
+// queryForm returns the Query protocol parameters as a form.
+func queryForm(params map[string]any) (url.Values, error) {
+	if _, ok := params["Action"]; !ok {
+		return nil, fmt.Errorf("query has no Action")
+	}
+	form := make(url.Values)
+	for name, value := range params {
+		switch value := value.(type) {
+		case string:
+			form.Set(name, value)
+		case float64:
+			form.Set(name, strconv.FormatFloat(value, 'f', -1, 64))
+		case bool:
+			form.Set(name, strconv.FormatBool(value))
+		default:
+			return nil, fmt.Errorf("query parameter %s must be a string, number or boolean", name)
+		}
+	}
+	return form, nil
+}
+
+// send makes the request through the server's handler chain, as if it had been sent to the server.
+func send(handler server.HandlerFunc, operation string, request *http.Request) (err error) {
+	if handler == nil {
+		return fmt.Errorf("%s: requests can't be made without the server's handlers", operation)
+	}
+	response := httptest.NewRecorder()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", operation, r)
+		}
+	}()
+	if !handler(response, request) {
+		return fmt.Errorf("%s: no enabled service handles the request", operation)
+	}
+	if response.Code < 200 || response.Code >= 300 {
+		return fmt.Errorf("%s: %d %s", operation, response.Code, response.Body.String())
+	}
+	return nil
+}
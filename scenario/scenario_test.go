@@ -0,0 +1,206 @@
+package scenario
+
+import (
+	"log/slog"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/http"
+	"aws-in-a-box/provision"
+	"aws-in-a-box/server"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+)
+
+const testScenario = `
+steps:
+  - provision:
+      streams: [{name: orders}]
+  - at: 10ms
+    call: Kinesis_20131202.PutRecord
+    input: {StreamName: orders, PartitionKey: a, Data: aGVsbG8=}
+  - at: 20ms
+    advanceClock: 1h
+`
+
+func TestRun(t *testing.T) {
+	k := kinesis.New(kinesis.Options{ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}})
+	methods := make(http.Registry)
+	k.RegisterHTTPHandlers(slog.Default(), methods)
+	options := Options{
+		Provision: provision.Options{Kinesis: k},
+		Methods:   methods,
+	}
+
+	scenario, err := Parse([]byte(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := Run(options, scenario); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatal("Expected the steps to run at their times, the scenario took", elapsed)
+	}
+
+	shards, awserr := k.ListShards(kinesis.ListShardsInput{StreamName: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	iterator, _ := k.GetShardIterator(kinesis.GetShardIteratorInput{
+		StreamName:        "orders",
+		ShardId:           shards.Shards[0].ShardId,
+		ShardIteratorType: "TRIM_HORIZON",
+	})
+	records, _ := k.GetRecords(kinesis.GetRecordsInput{ShardIterator: iterator.ShardIterator})
+	if len(records.Records) != 1 || records.Records[0].Data != "aGVsbG8=" {
+		t.Fatalf("Unexpected records %+v", records.Records)
+	}
+	if skew := clock.Now().Sub(time.Now()); skew < time.Hour-time.Second {
+		t.Fatal("Expected the clock to be advanced, it's ahead by", skew)
+	}
+
+	failures := []struct {
+		call  string
+		input string
+		want  string
+	}{
+		{"Unknown.Operation", "{}", "step 1: unknown operation Unknown.Operation"},
+		{"Kinesis_20131202.PutRecord", "[]", "step 1: Kinesis_20131202.PutRecord: "},
+		{"Kinesis_20131202.PutRecord", `{"StreamName": "missing", "PartitionKey": "a", "Data": ""}`, "Stream does not exist"},
+	}
+	for _, failure := range failures {
+		scenario := &Scenario{Steps: []Step{{Call: failure.call, Input: []byte(failure.input)}}}
+		if err := Run(options, scenario); err == nil || !strings.Contains(err.Error(), failure.want) {
+			t.Errorf("Expected an error containing %q, got %v", failure.want, err)
+		}
+	}
+}
+
+func TestQueryAndRequestSteps(t *testing.T) {
+	generator := arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}
+	queues := sqs.New(sqs.Options{ArnGenerator: generator, Addr: "localhost:4569"})
+	buckets, err := s3.New(s3.Options{PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	capabilityRegistry := capabilities.NewRegistry()
+	handler := server.Chain(
+		sqs.NewHandler(slog.Default(), queues, capabilityRegistry),
+		s3.NewHandler(slog.Default(), buckets, capabilityRegistry),
+	)
+	options := Options{Handler: handler}
+
+	scenario, err := Parse([]byte(`
+steps:
+  - query: {Action: CreateQueue, QueueName: jobs, Attribute.1.Name: VisibilityTimeout, Attribute.1.Value: 45}
+  - query: {Action: SendMessage, QueueUrl: "http://localhost:4569/123456789012/jobs", MessageBody: hello}
+  - request: {method: PUT, path: /bucket}
+  - request: {method: PUT, path: /bucket/key, headers: {Content-Type: text/plain}, body: hello}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(options, scenario); err != nil {
+		t.Fatal(err)
+	}
+
+	queueUrl := "http://localhost:4569/123456789012/jobs"
+	attributes, awserr := queues.GetQueueAttributes(sqs.GetQueueAttributesInput{QueueUrl: queueUrl, AttributeNames: []string{"VisibilityTimeout"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if attributes.Attributes["VisibilityTimeout"] != "45" {
+		t.Fatalf("Unexpected attributes %v", attributes.Attributes)
+	}
+	messages, awserr := queues.ReceiveMessage(sqs.ReceiveMessageInput{QueueUrl: queueUrl, MaxNumberOfMessages: 10})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(messages.Messages) != 1 || messages.Messages[0].Body != "hello" {
+		t.Fatalf("Unexpected messages %+v", messages.Messages)
+	}
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(nethttp.MethodGet, "/bucket/key", nil))
+	if w.Code != nethttp.StatusOK || w.Body.String() != "hello" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("Unexpected object %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	failures := []struct {
+		options Options
+		step    Step
+		want    string
+	}{
+		{options, Step{Query: map[string]any{"Action": "SendMessage", "QueueUrl": queueUrl}}, "SendMessage: 400"},
+		{options, Step{Query: map[string]any{"Action": "Unknown"}}, "no enabled service handles the request"},
+		{options, Step{Request: &Request{Method: nethttp.MethodGet, Path: "/bucket/missing"}}, "GET /bucket/missing: 404"},
+		{Options{}, Step{Request: &Request{Method: nethttp.MethodGet, Path: "/bucket/key"}}, "without the server's handlers"},
+	}
+	for _, failure := range failures {
+		if err := Run(failure.options, &Scenario{Steps: []Step{failure.step}}); err == nil || !strings.Contains(err.Error(), failure.want) {
+			t.Errorf("Expected an error containing %q, got %v", failure.want, err)
+		}
+	}
+}
+
+func TestFaultSteps(t *testing.T) {
+	faults := server.NewFaultRules()
+	options := Options{Faults: faults}
+	scenario, err := Parse([]byte(`
+steps:
+  - fault: {name: throttle, target: Kinesis_20131202.PutRecord, fault: error=ThrottlingException, count: 2}
+  - fault: {name: slow, action: SendMessage, fault: latency=1s}
+  - clearFault: slow
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(options, scenario); err != nil {
+		t.Fatal(err)
+	}
+	rules := faults.List()
+	if len(rules) != 1 || rules[0].Name != "throttle" || rules[0].Target != "Kinesis_20131202.PutRecord" || rules[0].Count != 2 {
+		t.Fatalf("Unexpected rules %+v", rules)
+	}
+
+	failures := []struct {
+		options Options
+		step    Step
+		want    string
+	}{
+		{options, Step{ClearFault: "missing"}, "no fault rule named missing"},
+		{options, Step{Fault: &server.FaultRule{Name: "bad", Fault: "unknown=1"}}, "invalid fault"},
+		{Options{}, Step{ClearFault: "throttle"}, "aren't enabled"},
+	}
+	for _, failure := range failures {
+		if err := Run(failure.options, &Scenario{Steps: []Step{failure.step}}); err == nil || !strings.Contains(err.Error(), failure.want) {
+			t.Errorf("Expected an error containing %q, got %v", failure.want, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		`{"Steps": [{"At": "1s"}]}`:                                             "exactly one of",
+		`{"Steps": [{"Call": "A.B", "AdvanceClock": "1h"}]}`:                    "exactly one of",
+		`{"Steps": [{"AdvanceClock": "-1h"}]}`:                                  "backwards",
+		`{"Steps": [{"ClearFault": "a", "Fault": {"Name": "b"}}]}`:              "exactly one of",
+		`{"Steps": [{"At": "2s", "Call": "A.B"}, {"At": "1s", "Call": "A.B"}]}`: "before the step before it",
+		`{"Steps": [{"Cal": "A.B"}]}`:                                           "unknown field",
+		`{"Steps": [{"Query": {"QueueUrl": "q"}}]}`:                             "no Action",
+		`{"Steps": [{"Query": {"Action": "A", "Tags": ["a"]}}]}`:                "must be a string, number or boolean",
+		`{"Steps": [{"Request": {"Method": "GET", "Path": "bucket"}}]}`:         "starting with /",
+	} {
+		if _, err := Parse([]byte(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", input, want, err)
+		}
+	}
+}
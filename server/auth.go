@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"aws-in-a-box/auth"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+)
+
+// Authenticate rejects requests whose access keys the authenticator doesn't accept, with the
+// error the service would return, and passes the others on with their caller attached. Responses'
+// Date is the emulator's time, which SDKs correct their clocks by when requests are too skewed.
+func Authenticate(logger *slog.Logger, authenticator *auth.Authenticator, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", clock.Now().UTC().Format(http.TimeFormat))
+		r, result := authenticator.Authenticate(r)
+		switch result {
+		case auth.MissingAuthentication:
+			logger.Warn("Rejecting unsigned request", "path", r.URL.Path)
+			writeError(w, r, missingAuthentication(r))
+		case auth.UnknownAccessKey:
+			logger.Warn("Rejecting unknown access key", "accessKeyId", auth.AccessKeyId(r))
+			writeError(w, r, unknownAccessKey(r))
+		case auth.RequestTimeTooSkewed:
+			signingTime, _ := auth.RequestSigningTime(r)
+			logger.Warn("Rejecting request signed too far from the emulator's time", "signed", signingTime.Signed)
+			writeError(w, r, requestTimeTooSkewed(r, signingTime.Signed))
+		case auth.RequestExpired:
+			signingTime, _ := auth.RequestSigningTime(r)
+			logger.Warn("Rejecting expired presigned URL", "signed", signingTime.Signed, "expires", signingTime.Expires)
+			writeError(w, r, requestExpired(r, signingTime))
+		case auth.InvalidSignature:
+			err, _ := auth.SignatureFailure(r)
+			logger.Warn("Rejecting request with an invalid signature", "accessKeyId", auth.AccessKeyId(r), "reason", err.Message,
+				"canonicalRequest", err.CanonicalRequest, "stringToSign", err.StringToSign)
+			writeError(w, r, invalidSignature(r, err))
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+func missingAuthentication(r *http.Request) *awserrors.Error {
+	typ, message := "AccessDenied", "Access Denied"
+	if isJSON(r) {
+		typ, message = "MissingAuthenticationTokenException", "Missing Authentication Token"
+	} else if isQuery(r) {
+		typ, message = "MissingAuthenticationToken", "Request is missing Authentication Token"
+	}
+	return &awserrors.Error{
+		Code: http.StatusForbidden,
+		Body: awserrors.ErrorBody{Type: typ, Message: message},
+	}
+}
+
+func unknownAccessKey(r *http.Request) *awserrors.Error {
+	typ, message := "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records."
+	if isJSON(r) {
+		typ, message = "UnrecognizedClientException", "The security token included in the request is invalid."
+	} else if isQuery(r) {
+		typ, message = "InvalidClientTokenId", "The security token included in the request is invalid."
+	}
+	return &awserrors.Error{
+		Code: http.StatusForbidden,
+		Body: awserrors.ErrorBody{Type: typ, Message: message},
+	}
+}
+
+// signatureExpired is the error services other than S3 return for requests signed too long before
+// or after their time.
+func signatureExpired(r *http.Request, signed time.Time) *awserrors.Error {
+	now := clock.Now()
+	minutes := int(auth.MaxRequestSkew.Minutes())
+	var message string
+	if signed.After(now) {
+		message = fmt.Sprintf("Signature not yet current: %s is still later than %s (%s + %d min.)",
+			auth.FormatAmzDate(signed), auth.FormatAmzDate(now.Add(auth.MaxRequestSkew)), auth.FormatAmzDate(now), minutes)
+	} else {
+		message = fmt.Sprintf("Signature expired: %s is now earlier than %s (%s - %d min.)",
+			auth.FormatAmzDate(signed), auth.FormatAmzDate(now.Add(-auth.MaxRequestSkew)), auth.FormatAmzDate(now), minutes)
+	}
+	if isJSON(r) {
+		return &awserrors.Error{
+			Code: http.StatusBadRequest,
+			Body: awserrors.ErrorBody{Type: "InvalidSignatureException", Message: message},
+		}
+	}
+	return &awserrors.Error{
+		Code: http.StatusForbidden,
+		Body: awserrors.ErrorBody{Type: "SignatureDoesNotMatch", Message: message},
+	}
+}
+
+func requestTimeTooSkewed(r *http.Request, signed time.Time) *awserrors.Error {
+	if isJSON(r) || isQuery(r) {
+		return signatureExpired(r, signed)
+	}
+	return &awserrors.Error{
+		Code: http.StatusForbidden,
+		Body: awserrors.ErrorBody{
+			Type:    "RequestTimeTooSkewed",
+			Message: "The difference between the request time and the current time is too large.",
+		},
+	}
+}
+
+func requestExpired(r *http.Request, signingTime auth.SigningTime) *awserrors.Error {
+	if isJSON(r) || isQuery(r) {
+		signed := signingTime.Signed
+		if signed.IsZero() {
+			signed = signingTime.Expires
+		}
+		return signatureExpired(r, signed)
+	}
+	message := "Request has expired"
+	if signingTime.Expires.After(clock.Now()) {
+		message = "Request is not yet valid"
+	}
+	return &awserrors.Error{
+		Code: http.StatusForbidden,
+		Body: awserrors.ErrorBody{Type: "AccessDenied", Message: message},
+	}
+}
+
+func invalidSignature(r *http.Request, err *auth.SignatureError) *awserrors.Error {
+	typ := "SignatureDoesNotMatch"
+	if isJSON(r) {
+		typ = "InvalidSignatureException"
+	}
+	return &awserrors.Error{
+		Code: http.StatusForbidden,
+		Body: awserrors.ErrorBody{Type: typ, Message: err.Message},
+	}
+}
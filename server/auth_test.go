@@ -0,0 +1,114 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/auth"
+	"aws-in-a-box/clock"
+)
+
+func TestAuthenticate(t *testing.T) {
+	authenticator := auth.New(auth.Options{
+		Credentials:     []auth.Credential{{AccessKeyId: "AKIA1", Account: "123456789012"}},
+		RejectAnonymous: true,
+	})
+	handler := Authenticate(slog.Default(), authenticator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := auth.Caller(r)
+		w.Write([]byte(caller.AccessKeyId))
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/us-east-1/s3/aws4_request")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || response.Body.String() != "AKIA1" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA2/20240102/us-east-1/s3/aws4_request")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "<Code>InvalidAccessKeyId</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set("X-Amz-Target", "Kinesis_20131202.ListStreams")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || response.Header().Get("X-Amzn-ErrorType") != "MissingAuthenticationTokenException" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Action=ListQueues"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "<ErrorResponse") ||
+		!strings.Contains(response.Body.String(), "<Code>MissingAuthenticationToken</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+}
+
+func TestAuthenticateRequestTime(t *testing.T) {
+	authenticator := auth.New(auth.Options{CheckRequestTime: true})
+	handler := Authenticate(slog.Default(), authenticator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	signed := clock.Now().Add(-time.Hour)
+
+	request := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/us-east-1/s3/aws4_request")
+	request.Header.Set("X-Amz-Date", auth.FormatAmzDate(signed))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "<Code>RequestTimeTooSkewed</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	if date, err := http.ParseTime(response.Header().Get("Date")); err != nil || clock.Now().Sub(date) > time.Minute {
+		t.Fatal("Expected the response's Date to be the emulator's time", response.Header().Get("Date"))
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/us-east-1/kinesis/aws4_request")
+	request.Header.Set("X-Amz-Date", auth.FormatAmzDate(signed))
+	request.Header.Set("X-Amz-Target", "Kinesis_20131202.ListStreams")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest || response.Header().Get("X-Amzn-ErrorType") != "InvalidSignatureException" ||
+		!strings.Contains(response.Body.String(), "Signature expired: "+auth.FormatAmzDate(signed)) {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+}
+
+func TestAuthenticateSignature(t *testing.T) {
+	authenticator := auth.New(auth.Options{
+		Credentials:        []auth.Credential{{AccessKeyId: "AKIA1", SecretAccessKey: "secret", Account: "123456789012"}},
+		ValidateSignatures: true,
+	})
+	handler := Authenticate(slog.Default(), authenticator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	date := auth.FormatAmzDate(clock.Now())
+
+	request := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA1/"+date[:8]+"/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=abc")
+	request.Header.Set("X-Amz-Date", date)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "<Code>SignatureDoesNotMatch</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA1/"+date[:8]+"/us-east-1/kinesis/aws4_request, SignedHeaders=host;x-amz-date, Signature=abc")
+	request.Header.Set("X-Amz-Date", date)
+	request.Header.Set("X-Amz-Target", "Kinesis_20131202.ListStreams")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || response.Header().Get("X-Amzn-ErrorType") != "InvalidSignatureException" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+}
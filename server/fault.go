@@ -0,0 +1,140 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// FaultHeader makes a single request fail, as a comma-separated list of faults:
+//   - error=<code> responds with that error instead of handling the request, with status=<code>
+//     (400 by default) and message=<message> if given.
+//   - latency=<duration> waits that long before handling the request, or failing it.
+//   - truncate=<bytes> cuts the response off after that many bytes of its body, and drops the
+//     connection.
+//
+// Example: error=ThrottlingException,latency=2s
+const FaultHeader = "x-aws-in-a-box-fault"
+
+type fault struct {
+	errorType string
+	status    int
+	message   string
+	latency   time.Duration
+	// -1 to not truncate.
+	truncate int64
+}
+
+func parseFault(header string) (*fault, error) {
+	f := &fault{
+		status:   http.StatusBadRequest,
+		message:  "Injected by the " + FaultHeader + " header",
+		truncate: -1,
+	}
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q isn't a key=value pair", part)
+		}
+		var err error
+		switch key {
+		case "error":
+			f.errorType = value
+		case "status":
+			f.status, err = strconv.Atoi(value)
+			if err == nil && (f.status < 400 || f.status > 599) {
+				err = fmt.Errorf("status %d isn't an error status", f.status)
+			}
+		case "message":
+			f.message = value
+		case "latency":
+			f.latency, err = time.ParseDuration(value)
+		case "truncate":
+			f.truncate, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && f.truncate < 0 {
+				err = fmt.Errorf("can't truncate to %d bytes", f.truncate)
+			}
+		default:
+			err = fmt.Errorf("unknown fault, expected error, status, message, latency or truncate")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return f, nil
+}
+
+// Fault injects the faults requests ask for with FaultHeader, so a test can make a single call
+// fail without configuring the whole emulator, and those of the first of the rules which matches
+// requests without the header. Rules may be nil.
+func Fault(logger *slog.Logger, rules *FaultRules, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(FaultHeader)
+		if header == "" {
+			if rules != nil {
+				if rule, f := rules.match(r); f != nil {
+					logger.Info("Injecting fault", "rule", rule.Name, "fault", rule.Fault)
+					injectFault(w, r, f, handler)
+					return
+				}
+			}
+			handler.ServeHTTP(w, r)
+			return
+		}
+		f, err := parseFault(header)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s header: %v", FaultHeader, err), http.StatusBadRequest)
+			return
+		}
+		logger.Info("Injecting fault", "fault", header)
+		injectFault(w, r, f, handler)
+	})
+}
+
+func injectFault(w http.ResponseWriter, r *http.Request, f *fault, handler http.Handler) {
+	if f.latency > 0 {
+		select {
+		case <-time.After(f.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if f.errorType != "" {
+		writeError(w, r, &awserrors.Error{
+			Code: f.status,
+			Body: awserrors.ErrorBody{
+				Type:    f.errorType,
+				Message: f.message,
+			},
+		})
+		return
+	}
+	if f.truncate >= 0 {
+		w = &truncatingWriter{ResponseWriter: w, remaining: f.truncate}
+	}
+	handler.ServeHTTP(w, r)
+}
+
+type truncatingWriter struct {
+	http.ResponseWriter
+	remaining int64
+}
+
+func (t *truncatingWriter) Write(data []byte) (int, error) {
+	if int64(len(data)) <= t.remaining {
+		t.remaining -= int64(len(data))
+		return t.ResponseWriter.Write(data)
+	}
+	t.ResponseWriter.Write(data[:t.remaining])
+	if flusher, ok := t.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	// Aborting the handler drops the connection without finishing the response, so the client
+	// sees it cut short rather than a complete, shorter body.
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFault(t *testing.T) {
+	handled := false
+	handler := Fault(slog.Default(), nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled = true
+		w.Write([]byte(`{"Records":[]}`))
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set("X-Amz-Target", "Kinesis_20131202.GetRecords")
+	request.Header.Set(FaultHeader, "error=ProvisionedThroughputExceededException, latency=50ms")
+	response := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest || response.Header().Get("X-Amzn-ErrorType") != "ProvisionedThroughputExceededException" || handled {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("Expected the latency to be injected")
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	request.Header.Set(FaultHeader, "error=InternalError,status=500")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusInternalServerError || !strings.Contains(response.Body.String(), "<Code>InternalError</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	request.Header.Set(FaultHeader, "truncate=1ms")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "truncate") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	// Truncated responses are cut off, rather than ending early.
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	request, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	request.Header.Set(FaultHeader, "truncate=5")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil || string(body) != `{"Rec` {
+		t.Fatal("Unexpected body", string(body), err)
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/admin"
+)
+
+// FaultRule injects a fault into every request it matches, until it's deleted or has matched Count
+// requests. Fields which are empty match any request, and admin API requests never match.
+type FaultRule struct {
+	// Name identifies the rule, to replace or delete it.
+	Name string
+	// The operation of a service with a JSON protocol, by its X-Amz-Target, such as
+	// Kinesis_20131202.PutRecord.
+	Target string
+	// The action of a service with the Query protocol, such as SendMessage.
+	Action string
+	// The method and path prefix of requests to REST services, such as PUT and /bucket/.
+	Method string
+	Path   string
+	// The fault, in the same format as FaultHeader, such as error=ThrottlingException,latency=2s.
+	Fault string
+	// How many requests to inject the fault into before the rule is deleted. 0 has no limit.
+	Count int
+}
+
+type activeFaultRule struct {
+	FaultRule
+	fault *fault
+}
+
+// FaultRules holds the rules the Fault handler injects faults with, for requests which don't have
+// a FaultHeader. They're managed through the admin API and scenarios' fault steps.
+type FaultRules struct {
+	mu    sync.Mutex
+	rules []*activeFaultRule
+}
+
+func NewFaultRules() *FaultRules {
+	return &FaultRules{}
+}
+
+// Set adds the rule, replacing the rule with the same name if there is one.
+func (f *FaultRules) Set(rule FaultRule) error {
+	if rule.Name == "" {
+		return errors.New("fault rules must have a name")
+	}
+	if rule.Count < 0 {
+		return fmt.Errorf("count %d is negative", rule.Count)
+	}
+	parsed, err := parseFault(rule.Fault)
+	if err != nil {
+		return fmt.Errorf("invalid fault: %w", err)
+	}
+	if !strings.Contains(rule.Fault, "message=") {
+		parsed.message = "Injected by the " + rule.Name + " fault rule"
+	}
+	active := &activeFaultRule{FaultRule: rule, fault: parsed}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, existing := range f.rules {
+		if existing.Name == rule.Name {
+			f.rules[i] = active
+			return nil
+		}
+	}
+	f.rules = append(f.rules, active)
+	return nil
+}
+
+// Delete removes the rule with the name, returning whether there was one.
+func (f *FaultRules) Delete(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, rule := range f.rules {
+		if rule.Name == name {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the rules, in the order they were added. Counts are how many more requests each
+// rule will inject its fault into.
+func (f *FaultRules) List() []FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rules := make([]FaultRule, 0, len(f.rules))
+	for _, rule := range f.rules {
+		rules = append(rules, rule.FaultRule)
+	}
+	return rules
+}
+
+// queryAction returns the action of a Query protocol request, restoring the body if it's read.
+func queryAction(r *http.Request) string {
+	if action := r.URL.Query().Get("Action"); action != "" || !isQuery(r) {
+		return action
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	form, _ := url.ParseQuery(string(body))
+	return form.Get("Action")
+}
+
+// match returns the first rule which matches the request, and its fault, counting the request
+// against the rule's count.
+func (f *FaultRules) match(r *http.Request) (FaultRule, *fault) {
+	if strings.HasPrefix(r.URL.Path, admin.PathPrefix) {
+		return FaultRule{}, nil
+	}
+	f.mu.Lock()
+	hasActions := false
+	for _, rule := range f.rules {
+		hasActions = hasActions || rule.Action != ""
+	}
+	f.mu.Unlock()
+	// The body is read without holding the lock, as the client may be slow to send it.
+	action := ""
+	if hasActions {
+		action = queryAction(r)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, rule := range f.rules {
+		if (rule.Target != "" && rule.Target != r.Header.Get("X-Amz-Target")) ||
+			(rule.Action != "" && rule.Action != action) ||
+			(rule.Method != "" && !strings.EqualFold(rule.Method, r.Method)) ||
+			!strings.HasPrefix(r.URL.Path, rule.Path) {
+			continue
+		}
+		if rule.Count > 0 {
+			rule.Count--
+			if rule.Count == 0 {
+				f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			}
+		}
+		return rule.FaultRule, rule.fault
+	}
+	return FaultRule{}, nil
+}
+
+// RegisterAdminHandlers adds managing the rules to the admin API. GET faults lists them, PUT or
+// POST faults sets the rule in the body, and DELETE faults?name=<name> deletes one, or all of them
+// without a name.
+func (f *FaultRules) RegisterAdminHandlers(registry admin.Registry) {
+	registry["faults"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			admin.WriteJSON(w, f.List())
+		case http.MethodPut, http.MethodPost:
+			var rule FaultRule
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&rule); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := f.Set(rule); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			admin.WriteJSON(w, rule)
+		case http.MethodDelete:
+			if !r.URL.Query().Has("name") {
+				f.mu.Lock()
+				f.rules = nil
+				f.mu.Unlock()
+				return
+			}
+			if !f.Delete(r.URL.Query().Get("name")) {
+				http.NotFound(w, r)
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aws-in-a-box/admin"
+)
+
+func TestFaultRules(t *testing.T) {
+	rules := NewFaultRules()
+	var handledBody string
+	handler := Fault(slog.Default(), rules, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handledBody = string(body)
+		w.Write([]byte("ok"))
+	}))
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		handledBody = ""
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	jsonRequest := func(target string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		r.Header.Set("X-Amz-Target", target)
+		return r
+	}
+	queryRequest := func(action string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Action="+action+"&QueueUrl=q"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	for _, rule := range []FaultRule{
+		{Name: "throttle", Target: "Kinesis_20131202.PutRecord", Fault: "error=ProvisionedThroughputExceededException", Count: 2},
+		{Name: "sqs", Action: "SendMessage", Fault: "error=InternalError,status=500,message=Down"},
+		{Name: "s3", Method: "put", Path: "/bucket/", Fault: "error=SlowDown,status=503"},
+	} {
+		if err := rules.Set(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Rules with a count are deleted once they've injected that many faults.
+	for i := 0; i < 2; i++ {
+		w := serve(jsonRequest("Kinesis_20131202.PutRecord"))
+		if w.Code != http.StatusBadRequest || w.Header().Get("X-Amzn-ErrorType") != "ProvisionedThroughputExceededException" ||
+			!strings.Contains(w.Body.String(), "Injected by the throttle fault rule") {
+			t.Fatalf("request %d: unexpected response %d %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(jsonRequest("Kinesis_20131202.PutRecord")); w.Code != http.StatusOK {
+		t.Fatalf("expected the rule to be used up, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(jsonRequest("Kinesis_20131202.GetRecords")); w.Code != http.StatusOK {
+		t.Fatalf("other targets shouldn't match, got %d", w.Code)
+	}
+
+	w := serve(queryRequest("SendMessage"))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "<ErrorResponse>") ||
+		!strings.Contains(w.Body.String(), "<Message>Down</Message>") {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	// The body is still there for the handler after the action has been read.
+	if w := serve(queryRequest("ReceiveMessage")); w.Code != http.StatusOK || handledBody != "Action=ReceiveMessage&QueueUrl=q" {
+		t.Fatalf("unexpected response %d, body %q", w.Code, handledBody)
+	}
+	if w := serve(httptest.NewRequest(http.MethodGet, "/?Action=SendMessage", nil)); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected actions in the query string to match, got %d", w.Code)
+	}
+
+	if w := serve(httptest.NewRequest(http.MethodPut, "/bucket/key", nil)); w.Code != http.StatusServiceUnavailable ||
+		!strings.Contains(w.Body.String(), "<Code>SlowDown</Code>") {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/bucket/key", nil),
+		httptest.NewRequest(http.MethodPut, "/other/key", nil),
+		httptest.NewRequest(http.MethodPut, admin.PathPrefix+"bucket/", nil),
+	} {
+		if w := serve(r); w.Code != http.StatusOK {
+			t.Fatalf("%s %s shouldn't match, got %d", r.Method, r.URL.Path, w.Code)
+		}
+	}
+
+	// The header takes precedence over the rules.
+	r := httptest.NewRequest(http.MethodPut, "/bucket/key", nil)
+	r.Header.Set(FaultHeader, "error=AccessDenied,status=403")
+	if w := serve(r); w.Code != http.StatusForbidden {
+		t.Fatalf("expected the header's fault, got %d", w.Code)
+	}
+
+	// Setting a rule with the same name replaces it.
+	if err := rules.Set(FaultRule{Name: "s3", Path: "/other/", Fault: "error=SlowDown,status=503"}); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(httptest.NewRequest(http.MethodPut, "/bucket/key", nil)); w.Code != http.StatusOK {
+		t.Fatalf("expected the rule to be replaced, got %d", w.Code)
+	}
+	if !rules.Delete("s3") || rules.Delete("s3") {
+		t.Fatal("expected the rule to be deleted once")
+	}
+	if got := rules.List(); len(got) != 1 || got[0].Name != "sqs" {
+		t.Fatalf("unexpected rules %+v", got)
+	}
+
+	for _, invalid := range []FaultRule{
+		{Fault: "error=InternalError"},
+		{Name: "a", Fault: "error=InternalError", Count: -1},
+		{Name: "a", Fault: "unknown=1"},
+		{Name: "a"},
+	} {
+		if err := rules.Set(invalid); err == nil {
+			t.Errorf("%+v: expected an error", invalid)
+		}
+	}
+}
+
+func TestFaultRulesAdminAPI(t *testing.T) {
+	rules := NewFaultRules()
+	registry := make(admin.Registry)
+	rules.RegisterAdminHandlers(registry)
+	handler := admin.NewHandler(registry)
+	call := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, admin.PathPrefix+target, strings.NewReader(body)))
+		return w
+	}
+
+	if w := call(http.MethodPut, "faults", `{"Name": "a", "Target": "A.B", "Fault": "latency=1s"}`); w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if w := call(http.MethodPost, "faults", `{"Name": "b", "Fault": "error=InternalError", "Count": 3}`); w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	for _, invalid := range []string{`{"Name": "c", "Fault": "bad"}`, `{"Name": "c", "Unknown": 1}`, `[`} {
+		if w := call(http.MethodPut, "faults", invalid); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", invalid, w.Code)
+		}
+	}
+
+	var listed []FaultRule
+	if err := json.Unmarshal(call(http.MethodGet, "faults", "").Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Name != "a" || listed[1].Count != 3 {
+		t.Fatalf("unexpected rules %+v", listed)
+	}
+
+	if w := call(http.MethodDelete, "faults?name=a", ""); w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d", w.Code)
+	}
+	if w := call(http.MethodDelete, "faults?name=a", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a missing rule to be reported, got %d", w.Code)
+	}
+	call(http.MethodDelete, "faults", "")
+	if got := rules.List(); len(got) != 0 {
+		t.Fatalf("expected every rule to be deleted, got %+v", got)
+	}
+}
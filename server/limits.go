@@ -0,0 +1,208 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/http/restxml"
+)
+
+// Limits protect instances shared by many clients from runaway ones.
+type Limits struct {
+	// JSON and Query protocol requests with larger bodies are rejected. 0 means no limit.
+	MaxBodyBytes int64
+	// Requests to REST APIs, like S3's uploads, with larger bodies are rejected. 0 means no limit.
+	MaxUploadBodyBytes int64
+	// Requests beyond this many in flight are rejected with a 503, which the SDKs retry with backoff.
+	// 0 means no limit.
+	MaxInFlightRequests int
+}
+
+// Limit rejects requests beyond the limits before they reach the handler.
+func Limit(logger *slog.Logger, limits Limits, handler http.Handler) http.Handler {
+	var inFlight chan struct{}
+	if limits.MaxInFlightRequests > 0 {
+		inFlight = make(chan struct{}, limits.MaxInFlightRequests)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inFlight != nil {
+			select {
+			case inFlight <- struct{}{}:
+				defer func() { <-inFlight }()
+			default:
+				logger.Warn("Too many requests in flight", "limit", limits.MaxInFlightRequests)
+				w.Header().Set("Retry-After", "1")
+				writeError(w, r, serviceUnavailable(r))
+				return
+			}
+		}
+
+		if max := limits.maxBodyBytes(r); max > 0 {
+			if r.ContentLength > max {
+				logger.Warn("Request body too large", "size", r.ContentLength, "limit", max)
+				writeError(w, r, entityTooLarge(r, max))
+				return
+			}
+			// Bodies of unknown length are counted as they're read rather than buffered, so large
+			// uploads don't have to fit in memory. Once one is too large, the error is the response.
+			if r.ContentLength < 0 {
+				lw := &limitedResponseWriter{ResponseWriter: w}
+				r.Body = &limitedBody{ReadCloser: r.Body, remaining: max, tooLarge: func() {
+					logger.Warn("Request body too large", "limit", max)
+					if !lw.wroteHeader {
+						writeError(lw.ResponseWriter, r, entityTooLarge(r, max))
+					}
+					lw.discard = true
+				}}
+				w = lw
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// maxBodyBytes returns the limit for the request's body. JSON and Query protocol requests are
+// small, but REST APIs' bodies can be S3 objects or Lambda functions' code.
+func (l Limits) maxBodyBytes(r *http.Request) int64 {
+	if r.Header.Get("X-Amz-Target") != "" || isQuery(r) {
+		return l.MaxBodyBytes
+	}
+	return l.MaxUploadBodyBytes
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// limitedBody is a body which calls tooLarge, and fails, once more than remaining bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	tooLarge  func()
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	// One more byte than remains is read, to tell a body which is exactly the limit from a larger one.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.exceeded = true
+		b.tooLarge()
+		return n, errBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// limitedResponseWriter discards the handler's response once the body was too large, and the error
+// was written instead.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *limitedResponseWriter) WriteHeader(statusCode int) {
+	if w.discard {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *limitedResponseWriter) Write(data []byte) (int, error) {
+	if w.discard {
+		return len(data), nil
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *limitedResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && !w.discard {
+		flusher.Flush()
+	}
+}
+
+func (w *limitedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isJSON returns whether the request is for a JSON service, rather than an XML one like S3 or SQS's Query protocol.
+func isJSON(r *http.Request) bool {
+	return r.Header.Get("X-Amz-Target") != "" || strings.Contains(r.Header.Get("Content-Type"), "json")
+}
+
+// isQuery returns whether the request is for a Query protocol service, like SQS or STS, whose
+// requests are POSTed forms.
+func isQuery(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html#ErrorCodeList
+func entityTooLarge(r *http.Request, maxBodyBytes int64) *awserrors.Error {
+	if isJSON(r) {
+		return &awserrors.Error{
+			Code: http.StatusRequestEntityTooLarge,
+			Body: awserrors.ErrorBody{
+				Type:    "RequestEntityTooLarge",
+				Message: fmt.Sprintf("Request body exceeds the maximum size of %d bytes", maxBodyBytes),
+			},
+		}
+	}
+	return awserrors.Generate400Exception("EntityTooLarge",
+		fmt.Sprintf("Your proposed upload exceeds the maximum allowed size of %d bytes", maxBodyBytes))
+}
+
+func serviceUnavailable(r *http.Request) *awserrors.Error {
+	typ := "SlowDown"
+	if isJSON(r) {
+		typ = "ThrottlingException"
+	}
+	return &awserrors.Error{
+		Code: http.StatusServiceUnavailable,
+		Body: awserrors.ErrorBody{
+			Type:    typ,
+			Message: "Too many requests are in flight, please reduce your request rate",
+		},
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, awserr *awserrors.Error) {
+	requestId := uuid.Must(uuid.NewV4()).String()
+	w.Header().Set("x-amzn-RequestId", requestId)
+	// The connection is closed, so the rest of the body doesn't have to be read.
+	w.Header().Set("Connection", "close")
+
+	if isJSON(r) {
+		data, err := json.Marshal(awserr.Body)
+		if err != nil {
+			panic(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-ErrorType", awserr.Body.Type)
+		w.WriteHeader(awserr.Code)
+		w.Write(data)
+		return
+	}
+
+	// Query protocol errors are wrapped in an ErrorResponse. S3's aren't.
+	protocol := restxml.Protocol{NoErrorWrapping: !isQuery(r)}
+	protocol.MarshalError(w, awserr, requestId)
+}
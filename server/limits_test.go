@@ -0,0 +1,96 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	var received string
+	handler := Limit(slog.Default(), Limits{MaxBodyBytes: 5, MaxUploadBodyBytes: 10}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		received = string(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}))
+
+	request := httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("12345678901"))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "<Code>EntityTooLarge</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	// Uploads have their own limit.
+	request = httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("123456"))
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || received != "123456" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{\"a\":1}"))
+	request.Header.Set("X-Amz-Target", "Kinesis_20131202.PutRecord")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusRequestEntityTooLarge || response.Header().Get("X-Amzn-ErrorType") != "RequestEntityTooLarge" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	// Bodies of unknown length are checked as they're read, and the handler's response is replaced.
+	request = httptest.NewRequest(http.MethodPut, "/bucket/key", io.MultiReader(strings.NewReader("123456"), strings.NewReader("789012")))
+	request.ContentLength = -1
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "<Code>EntityTooLarge</Code>") ||
+		received != "1234567890" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String(), received)
+	}
+
+	request = httptest.NewRequest(http.MethodPut, "/bucket/key", io.MultiReader(strings.NewReader("12345"), strings.NewReader("67890")))
+	request.ContentLength = -1
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || received != "1234567890" {
+		t.Fatal("Unexpected response", response.Code, received)
+	}
+}
+
+func TestInFlightLimit(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := Limit(slog.Default(), Limits{MaxInFlightRequests: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	<-started
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Action=ListQueues"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusServiceUnavailable || response.Header().Get("Retry-After") == "" ||
+		!strings.Contains(response.Body.String(), "<ErrorResponse>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	close(release)
+	<-done
+	go func() { <-started }()
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected the request to be served once the first finished, got", response.Code)
+	}
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"aws-in-a-box/calls"
+)
+
+// LogFilter matches requests by their service, operation and status. A request matches if it
+// matches one of each kind of condition the filter has.
+type LogFilter struct {
+	Services   []string
+	Operations []string
+	// Statuses are codes, such as 404, or classes, such as 5xx.
+	Statuses []string
+}
+
+var statusPattern = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// ParseLogFilter parses a comma-separated list of conditions, such as "service=s3,status=5xx".
+func ParseLogFilter(s string) (LogFilter, error) {
+	var f LogFilter
+	if s == "" {
+		return f, nil
+	}
+	for _, condition := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(condition, "=")
+		if !ok || value == "" {
+			return f, fmt.Errorf("invalid condition %q, expected key=value", condition)
+		}
+		switch key {
+		case "service":
+			f.Services = append(f.Services, value)
+		case "operation":
+			f.Operations = append(f.Operations, value)
+		case "status":
+			if !statusPattern.MatchString(value) {
+				return f, fmt.Errorf("invalid status %q, expected a code such as 404 or a class such as 5xx", value)
+			}
+			f.Statuses = append(f.Statuses, value)
+		default:
+			return f, fmt.Errorf("invalid condition %q, expected service, operation or status", condition)
+		}
+	}
+	return f, nil
+}
+
+func (f LogFilter) empty() bool {
+	return len(f.Services) == 0 && len(f.Operations) == 0 && len(f.Statuses) == 0
+}
+
+func (f LogFilter) matches(call *calls.Call, status int) bool {
+	if len(f.Services) > 0 && !slices.Contains(f.Services, call.Service) {
+		return false
+	}
+	if len(f.Operations) > 0 && !slices.Contains(f.Operations, call.Operation) {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		code := strconv.Itoa(status)
+		return slices.ContainsFunc(f.Statuses, func(s string) bool {
+			return s == code || (strings.HasSuffix(s, "xx") && s[0] == code[0])
+		})
+	}
+	return true
+}
+
+// LogOptions choose which requests are logged, and how much of them.
+type LogOptions struct {
+	// If it has any conditions, only requests matching them are logged.
+	Include LogFilter
+	// If it has any conditions, requests matching them aren't logged.
+	Exclude LogFilter
+	// Up to this many bytes of requests' and responses' bodies are logged, with secrets redacted.
+	// 0 doesn't log bodies.
+	MaxBodyBytes int
+	// The fractions of requests which are logged, for those which succeed and those which fail.
+	// 1 logs every request.
+	SampleRate      float64
+	ErrorSampleRate float64
+	// Calls which take at least this long are logged as slow, whatever the other options, and kept
+	// in SlowCalls if it's set. 0 doesn't look for slow calls. Calls with an injected fault aren't.
+	SlowThreshold time.Duration
+	SlowCalls     *calls.Registry
+}
+
+// chooses returns whether the request is logged.
+func (o LogOptions) chooses(call *calls.Call, status int) bool {
+	if !o.Include.empty() && !o.Include.matches(call, status) {
+		return false
+	}
+	if !o.Exclude.empty() && o.Exclude.matches(call, status) {
+		return false
+	}
+	rate := o.SampleRate
+	if status >= 400 {
+		rate = o.ErrorSampleRate
+	}
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// LogRequests logs a line for each request the options choose, and each slow call, once it's been
+// handled.
+func LogRequests(logger *slog.Logger, options LogOptions, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, call := calls.WithCall(r)
+		lw := &loggingWriter{ResponseWriter: w}
+		var requestBody, resourceBody *cappedBuffer
+		var bodyCopies []io.Writer
+		if options.MaxBodyBytes > 0 {
+			patterns := secretPatternsFor(r)
+			requestBody = &cappedBuffer{max: options.MaxBodyBytes, secrets: patterns}
+			lw.body = &cappedBuffer{max: options.MaxBodyBytes, secrets: patterns}
+			bodyCopies = append(bodyCopies, requestBody)
+		}
+		if options.SlowThreshold > 0 {
+			resourceBody = &cappedBuffer{max: resourceBytes}
+			bodyCopies = append(bodyCopies, resourceBody)
+		}
+		if len(bodyCopies) > 0 && r.Body != nil && r.Body != http.NoBody {
+			r.Body = &teeBody{ReadCloser: r.Body, w: io.MultiWriter(bodyCopies...)}
+		}
+		// Requests whose handler panics, such as ones whose response was truncated, are still logged.
+		defer func() {
+			duration := time.Since(start)
+			status := lw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if options.SlowThreshold > 0 && duration >= options.SlowThreshold && r.Header.Get(FaultHeader) == "" {
+				slow := calls.SlowCall{
+					Time:          start.UTC(),
+					Service:       call.Service,
+					Operation:     call.Operation,
+					Resource:      callResource(r.URL.Path, resourceBody.data),
+					DurationMs:    float64(duration) / float64(time.Millisecond),
+					StatusCode:    status,
+					RequestBytes:  max(r.ContentLength, int64(resourceBody.total)),
+					ResponseBytes: lw.written,
+				}
+				logger.Warn("Slow call", "service", slow.Service, "operation", slow.Operation, "resource", slow.Resource,
+					"duration", duration, "status", status, "requestBytes", slow.RequestBytes, "responseBytes", slow.ResponseBytes)
+				if options.SlowCalls != nil {
+					options.SlowCalls.RecordSlow(slow)
+				}
+			}
+			if !options.chooses(call, status) {
+				return
+			}
+
+			attrs := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"service", call.Service,
+				"operation", call.Operation,
+				"status", status,
+				"duration", duration,
+			}
+			if call.Error != nil {
+				attrs = append(attrs, "error", call.Error.Body.Type)
+			}
+			if requestBody != nil {
+				attrs = append(attrs, "requestBody", requestBody.String(), "responseBody", lw.body.String())
+			}
+			logger.Info("Request", attrs...)
+		}()
+		handler.ServeHTTP(lw, r)
+	})
+}
+
+// loggingWriter records the response's status and size, and the start of its body if it's logged.
+type loggingWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+	body    *cappedBuffer
+}
+
+func (l *loggingWriter) WriteHeader(status int) {
+	// Informational responses, such as 100 Continue, come before the real one.
+	if l.status == 0 && status >= 200 {
+		l.status = status
+	}
+	l.ResponseWriter.WriteHeader(status)
+}
+
+func (l *loggingWriter) Write(data []byte) (int, error) {
+	if l.status == 0 {
+		l.status = http.StatusOK
+	}
+	if l.body != nil {
+		l.body.Write(data)
+	}
+	n, err := l.ResponseWriter.Write(data)
+	l.written += int64(n)
+	return n, err
+}
+
+// Flush lets streaming responses, such as Kinesis' SubscribeToShard, still be flushed.
+func (l *loggingWriter) Flush() {
+	if flusher, ok := l.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (l *loggingWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
+
+// teeBody copies what the handler reads of the request's body, so only what's read is buffered.
+type teeBody struct {
+	io.ReadCloser
+	w io.Writer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.w.Write(p[:n])
+	return n, err
+}
+
+// cappedBuffer keeps the first max bytes written to it, and counts the rest.
+type cappedBuffer struct {
+	data  []byte
+	max   int
+	total int
+	// The patterns of the secrets redacted from the logged body, or the default ones if nil.
+	secrets map[*regexp.Regexp]string
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	c.total += len(p)
+	if remaining := c.max - len(c.data); remaining > 0 {
+		c.data = append(c.data, p[:min(remaining, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func (c *cappedBuffer) String() string {
+	if c.total == 0 {
+		return ""
+	}
+	data := c.data
+	truncated := c.total > len(data)
+	if truncated {
+		// The cap can split a character in two.
+		for i := 0; i < utf8.UTFMax-1 && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return fmt.Sprintf("<%d bytes of binary data>", c.total)
+	}
+	secrets := c.secrets
+	if secrets == nil {
+		secrets = secretPatterns
+	}
+	s := redact(string(data), secrets)
+	if truncated {
+		s += fmt.Sprintf("... (%d bytes)", c.total)
+	}
+	return s
+}
+
+// secretNames are the parameters whose values are redacted from logged bodies: credentials,
+// passwords and tokens, and the plaintext of secrets and keys.
+var secretNames = []string{
+	"AccessToken",
+	"ClientSecret",
+	"IdToken",
+	"NEW_PASSWORD",
+	"NewPassword",
+	"PASSWORD",
+	"Password",
+	"Plaintext",
+	"PreviousPassword",
+	"ProposedPassword",
+	"RefreshToken",
+	"REFRESH_TOKEN",
+	"SAMLAssertion",
+	"SECRET_HASH",
+	"SecretAccessKey",
+	"SecretBinary",
+	"SecretHash",
+	"SecretString",
+	"SessionToken",
+	"TemporaryPassword",
+	"WebIdentityToken",
+}
+
+// serviceSecretNames are parameters which are only secret in some services' requests and
+// responses, by the prefix of their X-Amz-Target, such as SSM parameters' Value, which is the
+// decrypted value of SecureString parameters in PutParameter and GetParameters.
+var serviceSecretNames = map[string][]string{
+	"AmazonSSM.": {"Value"},
+}
+
+var (
+	secretPatterns        = compileSecretPatterns(secretNames)
+	serviceSecretPatterns = func() map[string]map[*regexp.Regexp]string {
+		patterns := make(map[string]map[*regexp.Regexp]string)
+		for prefix, names := range serviceSecretNames {
+			patterns[prefix] = compileSecretPatterns(append(slices.Clone(secretNames), names...))
+		}
+		return patterns
+	}()
+)
+
+// compileSecretPatterns returns the patterns which redact the parameters' values. They match
+// values which the cap cut short, too.
+func compileSecretPatterns(secretNames []string) map[*regexp.Regexp]string {
+	names := strings.Join(secretNames, "|")
+	return map[*regexp.Regexp]string{
+		// JSON values, which stay valid JSON.
+		regexp.MustCompile(`("(?:` + names + `)"\s*:\s*)"(?:[^"\\]|\\.)*(?:"|$)`): `$1"REDACTED"`,
+		// XML elements.
+		regexp.MustCompile(`(<(?:` + names + `)>)[^<]*`): `${1}REDACTED`,
+		// Form parameters, such as the Query protocol's, which may be members of a structure.
+		regexp.MustCompile(`((?:^|&)(?:[^&=]*\.)?(?:` + names + `)=)[^&]*`): `${1}REDACTED`,
+	}
+}
+
+// secretPatternsFor returns the patterns which redact the secrets of the request's service.
+func secretPatternsFor(r *http.Request) map[*regexp.Regexp]string {
+	target := r.Header.Get("X-Amz-Target")
+	for prefix, patterns := range serviceSecretPatterns {
+		if strings.HasPrefix(target, prefix) {
+			return patterns
+		}
+	}
+	return secretPatterns
+}
+
+func redactSecrets(s string) string {
+	return redact(s, secretPatterns)
+}
+
+func redact(s string, patterns map[*regexp.Regexp]string) string {
+	for pattern, replacement := range patterns {
+		s = pattern.ReplaceAllString(s, replacement)
+	}
+	return s
+}
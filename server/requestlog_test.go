@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/calls"
+)
+
+func TestParseLogFilter(t *testing.T) {
+	f, err := ParseLogFilter("service=sqs,service=s3,status=5xx")
+	if err != nil || len(f.Services) != 2 || len(f.Statuses) != 1 {
+		t.Fatal("Unexpected filter", f, err)
+	}
+	for _, invalid := range []string{"service", "region=us-east-1", "status=600", "status=5x"} {
+		if _, err := ParseLogFilter(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	registry := calls.NewRegistry()
+	chain := chainHandler([]HandlerFunc{registry.Handler("sqs", func(w http.ResponseWriter, r *http.Request) bool {
+		io.ReadAll(r.Body)
+		if r.URL.Path == "/missing" {
+			awserr := awserrors.Generate400Exception("QueueDoesNotExist", "The queue doesn't exist.")
+			calls.Record(r, "GetQueueUrl", awserr)
+			w.WriteHeader(awserr.Code)
+			return true
+		}
+		calls.Record(r, "ReceiveMessage", nil)
+		w.Write([]byte(`{"Messages":[{"Body":"hello"}]}`))
+		return true
+	})})
+
+	logged := func(options LogOptions, path string, body string) string {
+		var buf bytes.Buffer
+		handler := LogRequests(slog.New(slog.NewTextHandler(&buf, nil)), options, chain)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return buf.String()
+	}
+
+	all := LogOptions{SampleRate: 1, ErrorSampleRate: 1}
+	line := logged(all, "/missing", "")
+	for _, want := range []string{"service=sqs", "operation=GetQueueUrl", "status=400", "error=QueueDoesNotExist"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in %q", want, line)
+		}
+	}
+	if strings.Contains(line, "requestBody") {
+		t.Error("Expected bodies not to be logged", line)
+	}
+
+	onlyErrors := all
+	onlyErrors.Include, _ = ParseLogFilter("status=4xx,status=5xx")
+	if logged(onlyErrors, "/", "") != "" || logged(onlyErrors, "/missing", "") == "" {
+		t.Error("Expected only errors to be logged")
+	}
+	noPolls := all
+	noPolls.Exclude, _ = ParseLogFilter("service=sqs,operation=ReceiveMessage")
+	if logged(noPolls, "/", "") != "" || logged(noPolls, "/missing", "") == "" {
+		t.Error("Expected ReceiveMessage not to be logged")
+	}
+	sampled := LogOptions{SampleRate: 0, ErrorSampleRate: 1}
+	if logged(sampled, "/", "") != "" || logged(sampled, "/missing", "") == "" {
+		t.Error("Expected only errors to be sampled")
+	}
+
+	withBodies := all
+	withBodies.MaxBodyBytes = 1000
+	line = logged(withBodies, "/", `{"QueueUrl":"q","Password":"hunter2"}`)
+	if !strings.Contains(line, `\"Password\":\"REDACTED\"`) || strings.Contains(line, "hunter2") || !strings.Contains(line, "hello") {
+		t.Error("Unexpected bodies", line)
+	}
+	withBodies.MaxBodyBytes = 10
+	line = logged(withBodies, "/", "a=1&SecretAccessKey=abcdefgh")
+	if !strings.Contains(line, `requestBody="a=1&Secret... (28 bytes)"`) {
+		t.Error("Expected the body to be truncated", line)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"SecretString": "s3cr\"t", "Name": "n"}`:                    `{"SecretString": "REDACTED", "Name": "n"}`,
+		`{"AuthParameters":{"USERNAME":"u","PASSWORD":"p"}}`:          `{"AuthParameters":{"USERNAME":"u","PASSWORD":"REDACTED"}}`,
+		`<Credentials><SessionToken>tok</SessionToken></Credentials>`: `<Credentials><SessionToken>REDACTED</SessionToken></Credentials>`,
+		`Action=AssumeRole&Credentials.SecretAccessKey=abc&X=1`:       `Action=AssumeRole&Credentials.SecretAccessKey=REDACTED&X=1`,
+		// Values cut short by the cap are still redacted.
+		`{"Plaintext":"abc`: `{"Plaintext":"REDACTED"`,
+	} {
+		if got := redactSecrets(body); got != want {
+			t.Errorf("redactSecrets(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestSlowCalls(t *testing.T) {
+	registry := calls.NewRegistry()
+	chain := chainHandler([]HandlerFunc{registry.Handler("dynamodb", func(w http.ResponseWriter, r *http.Request) bool {
+		io.ReadAll(r.Body)
+		calls.Record(r, "Query", nil)
+		w.Write([]byte(`{"Items":[]}`))
+		return true
+	})})
+	serve := func(threshold time.Duration, header string) string {
+		var buf bytes.Buffer
+		// Only slow calls are logged, so sampling doesn't apply to them.
+		options := LogOptions{SampleRate: 0, ErrorSampleRate: 0, SlowThreshold: threshold, SlowCalls: registry}
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"TableName":"orders","Limit":10}`))
+		if header != "" {
+			request.Header.Set(FaultHeader, header)
+		}
+		LogRequests(slog.New(slog.NewTextHandler(&buf, nil)), options, chain).ServeHTTP(httptest.NewRecorder(), request)
+		return buf.String()
+	}
+
+	if line := serve(time.Hour, ""); line != "" || len(registry.SlowCalls()) != 0 {
+		t.Fatal("Expected no slow calls", line)
+	}
+	line := serve(time.Nanosecond, "")
+	if !strings.Contains(line, `msg="Slow call"`) || !strings.Contains(line, "resource=orders") {
+		t.Fatal("Expected a slow call to be logged", line)
+	}
+	slow := registry.SlowCalls()
+	want := calls.SlowCall{Service: "dynamodb", Operation: "Query", Resource: "orders", StatusCode: 200, RequestBytes: 33, ResponseBytes: 12}
+	if len(slow) != 1 || slow[0].DurationMs <= 0 {
+		t.Fatalf("Unexpected slow calls %+v", slow)
+	}
+	slow[0].Time, slow[0].DurationMs = time.Time{}, 0
+	if slow[0] != want {
+		t.Fatalf("Got slow call %+v, want %+v", slow[0], want)
+	}
+
+	// Injected latency isn't the emulator being slow.
+	if line := serve(time.Nanosecond, "latency=1ms"); line != "" {
+		t.Fatal("Expected calls with a fault not to be slow", line)
+	}
+}
+
+func TestCallResource(t *testing.T) {
+	for _, test := range []struct {
+		path, body, want string
+	}{
+		{"/", `{"TableName":"orders","IndexName":"byDate"}`, "orders"},
+		{"/", "Action=DeleteMessage&ReceiptHandle=abc&QueueUrl=http%3A%2F%2Flocalhost%3A4569%2F123456789012%2Fjobs", "http://localhost:4569/123456789012/jobs"},
+		{"/bucket/key", "object contents", "bucket/key"},
+		{"/", `{"Limit":10}`, ""},
+	} {
+		if got := callResource(test.path, []byte(test.body)); got != test.want {
+			t.Errorf("callResource(%q, %q) = %q, want %q", test.path, test.body, got, test.want)
+		}
+	}
+}
+
+func TestRedactServiceSecrets(t *testing.T) {
+	ssm := httptest.NewRequest(http.MethodPost, "/", nil)
+	ssm.Header.Set("X-Amz-Target", "AmazonSSM.GetParameter")
+	body := `{"Parameter":{"Name":"db-password","Type":"SecureString","Value":"hunter2"}}`
+	want := `{"Parameter":{"Name":"db-password","Type":"SecureString","Value":"REDACTED"}}`
+	if got := redact(body, secretPatternsFor(ssm)); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// Other services' values aren't secret.
+	dynamodb := httptest.NewRequest(http.MethodPost, "/", nil)
+	dynamodb.Header.Set("X-Amz-Target", "DynamoDB_20120810.PutItem")
+	if body := `{"Key":"k","Value":"v","Password":"p"}`; redact(body, secretPatternsFor(dynamodb)) != `{"Key":"k","Value":"v","Password":"REDACTED"}` {
+		t.Error("Unexpected redaction", redact(body, secretPatternsFor(dynamodb)))
+	}
+}
@@ -1,14 +1,14 @@
 package server
 
 import (
-	"bytes"
-	"io"
 	"log/slog"
 	"net/http"
 
 	"github.com/gofrs/uuid/v5"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+
+	"aws-in-a-box/auth"
 )
 
 func New(handler http.HandlerFunc) *http.Server {
@@ -21,27 +21,43 @@ func New(handler http.HandlerFunc) *http.Server {
 type HandlerFunc = func(w http.ResponseWriter, r *http.Request) bool
 
 func NewWithHandlerChain(chain ...HandlerFunc) *http.Server {
-	return New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return New(chainHandler(chain))
+}
+
+// NewWithLimits is like NewWithHandlerChain, but rejects requests beyond the limits, and those the
+// authenticator doesn't accept, if there is one, injects the faults requests ask for and those of
+// the fault rules, and logs the requests the log options choose, including those which were rejected.
+func NewWithLimits(logger *slog.Logger, limits Limits, logOptions LogOptions, authenticator *auth.Authenticator, faultRules *FaultRules, chain ...HandlerFunc) *http.Server {
+	var handler http.Handler = chainHandler(chain)
+	if authenticator != nil {
+		handler = Authenticate(logger, authenticator, handler)
+	}
+	return New(LogRequests(logger, logOptions, Limit(logger, limits, Fault(logger, faultRules, handler))).ServeHTTP)
+}
+
+func chainHandler(chain []HandlerFunc) http.HandlerFunc {
+	handler := Chain(chain...)
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r)
+	}
+}
+
+// Chain returns a handler which tries each of the handlers in turn, until one handles the request.
+func Chain(chain ...HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) bool {
 		for _, handler := range chain {
 			if handler(w, r) {
-				break
+				return true
 			}
 		}
-	}))
+		return false
+	}
 }
 
 func HandlerFuncFromRegistry(logger *slog.Logger, registry map[string]http.HandlerFunc) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) bool {
-		buf, err := io.ReadAll(r.Body)
-		if err != nil {
-			logger.Error("Reading body", "err", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return true
-		}
-		r.Body = io.NopCloser(bytes.NewBuffer(buf))
-
 		// The target endpoint is specified in the `X-Amz-Target` header.
-		// If it's missing, this request is for S3.
+		// If it's missing, this request is for another handler.
 		target := r.Header.Get("X-Amz-Target")
 		if target == "" {
 			return false
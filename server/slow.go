@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// resourceBytes is how much of requests' bodies is kept to find which resource they were for.
+const resourceBytes = 4096
+
+// resourceNames are the parameters naming the resource a call is for, in order of preference, so a
+// queue's URL is chosen over a message's receipt handle.
+var resourceNames = []string{
+	"TableName",
+	"QueueUrl",
+	"QueueName",
+	"StreamARN",
+	"StreamName",
+	"FunctionName",
+	"TopicArn",
+	"TargetArn",
+	"StateMachineArn",
+	"ExecutionArn",
+	"LogGroupName",
+	"SecretId",
+	"KeyId",
+	"UserPoolId",
+	"IdentityPoolId",
+	"EventBusName",
+	"RepositoryName",
+	"ResourceArn",
+	"Name",
+}
+
+var (
+	jsonResourcePattern = regexp.MustCompile(`"(` + strings.Join(resourceNames, "|") + `)"\s*:\s*"([^"]*)"`)
+	formResourcePattern = regexp.MustCompile(`(?:^|&)(` + strings.Join(resourceNames, "|") + `)=([^&]*)`)
+)
+
+// callResource returns what the call was for, from the start of its body, such as a table's name
+// or a queue's URL. Calls of REST protocols, like S3's, are for their path.
+func callResource(path string, body []byte) string {
+	var found map[string]string
+	if matches := jsonResourcePattern.FindAllSubmatch(body, -1); len(matches) > 0 {
+		found = make(map[string]string)
+		for _, m := range matches {
+			found[string(m[1])] = string(m[2])
+		}
+	} else if matches := formResourcePattern.FindAllSubmatch(body, -1); len(matches) > 0 {
+		found = make(map[string]string)
+		for _, m := range matches {
+			if value, err := url.QueryUnescape(string(m[2])); err == nil {
+				found[string(m[1])] = value
+			}
+		}
+	}
+	for _, name := range resourceNames {
+		if value, ok := found[name]; ok && value != "" {
+			return value
+		}
+	}
+	return strings.TrimPrefix(path, "/")
+}
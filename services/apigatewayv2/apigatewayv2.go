@@ -0,0 +1,421 @@
+package apigatewayv2
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/random"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/lambda"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultStageName                 = "$default"
+	defaultRouteKey                  = "$default"
+	httpRouteSelectionExpression     = "${request.method} ${request.path}"
+	defaultApiKeySelectionExpression = "$request.header.x-api-key"
+)
+
+var (
+	apiNameRegex   = regexp.MustCompile(`^.{1,128}$`)
+	stageNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+)
+
+// LambdaInvoker invokes the functions of AWS_PROXY integrations.
+type LambdaInvoker interface {
+	Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error)
+}
+
+// UserPools verifies tokens issued by Cognito user pools, which JWT authorizers accept.
+type UserPools interface {
+	VerifyToken(userPoolId string, token string) (map[string]any, error)
+}
+
+type Api struct {
+	Config    APIApi
+	CreatedAt time.Time
+
+	// By ID.
+	routes       map[string]*APIRoute
+	integrations map[string]*APIIntegration
+	authorizers  map[string]*APIAuthorizer
+	// By name.
+	stages map[string]*APIStage
+}
+
+type APIGateway struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// APIs are served at this address.
+	addr string
+	// Nil if Lambda is not enabled.
+	lambda LambdaInvoker
+	// Nil if Cognito user pools are not enabled.
+	userPools UserPools
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu       sync.Mutex
+	apisById map[string]*Api
+	// WebSocket APIs' open connections.
+	connectionsById map[string]*connection
+	// Shared by the instances of all regions, which each have their own APIs and WebSocket connections.
+	regions *region.Regions[*APIGateway]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The address the server is listening on, which APIs' endpoints use.
+	Addr string
+	// AWS_PROXY integrations invoke functions with this.
+	Lambda LambdaInvoker
+	// JWT authorizers verify tokens from Cognito user pools with this.
+	UserPools UserPools
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *APIGateway {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Integrations' functions are found by their ARNs, and user pools by their IDs, which say their
+	// regions, so every region uses the same services.
+	a := newRegion(options)
+	a.regions = region.New(options.ArnGenerator.Region, options.Regions, a, func(name string) (*APIGateway, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = a.regions
+		return r, nil
+	})
+	return a
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *APIGateway {
+	return &APIGateway{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		addr:         options.Addr,
+		lambda:       options.Lambda,
+		userPools:    options.UserPools,
+		clock:        clock.Now,
+		apisById:     make(map[string]*Api),
+
+		connectionsById: make(map[string]*connection),
+	}
+}
+
+// InRegion returns the APIs and WebSocket connections of the region. It fails if the region isn't
+// one of Options.Regions.
+func (a *APIGateway) InRegion(name string) (*APIGateway, error) {
+	return a.regions.Get(name)
+}
+
+// randomId returns a random ID of lowercase letters and digits, like API Gateway's.
+func randomId(length int) string {
+	return random.String("abcdefghijklmnopqrstuvwxyz0123456789", length)
+}
+
+// sortedValues returns the map's values, sorted by the key function.
+func sortedValues[T any](m map[string]*T, key func(*T) string) []T {
+	values := []T{}
+	for _, value := range m {
+		values = append(values, *value)
+	}
+	slices.SortFunc(values, func(a, b T) int {
+		return strings.Compare(key(&a), key(&b))
+	})
+	return values
+}
+
+func (a *APIGateway) apiEndpoint(apiId string, protocolType string) string {
+	scheme := "http://"
+	if protocolType == "WEBSOCKET" {
+		scheme = "ws://"
+	}
+	return scheme + a.addr + executeApiPathPrefix + apiId
+}
+
+func (a *APIGateway) lockedGetApi(apiId string) (*Api, *awserrors.Error) {
+	api, ok := a.apisById[apiId]
+	if !ok {
+		return nil, NotFoundException(fmt.Sprintf("Invalid API identifier specified %s:%s", a.arnGenerator.AwsAccountId, apiId))
+	}
+	return api, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis.html#CreateApi
+func (a *APIGateway) CreateApi(input CreateApiInput) (*CreateApiOutput, *awserrors.Error) {
+	if !apiNameRegex.MatchString(input.Name) {
+		return nil, BadRequestException("Invalid API name specified, must be between 1 and 128 characters.")
+	}
+	switch input.ProtocolType {
+	case "HTTP":
+		if input.RouteSelectionExpression != "" && input.RouteSelectionExpression != httpRouteSelectionExpression {
+			return nil, BadRequestException("HTTP APIs only support the route selection expression " + httpRouteSelectionExpression)
+		}
+		input.RouteSelectionExpression = httpRouteSelectionExpression
+	case "WEBSOCKET":
+		if _, ok := parseRouteSelectionExpression(input.RouteSelectionExpression); !ok {
+			return nil, BadRequestException("Invalid route selection expression " + input.RouteSelectionExpression + ", must be like $request.body.action")
+		}
+		if input.Target != "" {
+			return nil, BadRequestException("Quick create is only supported for HTTP APIs.")
+		}
+		if input.CorsConfiguration != nil {
+			return nil, BadRequestException("CORS configuration is only supported for HTTP APIs.")
+		}
+	default:
+		return nil, BadRequestException("Invalid protocol type " + input.ProtocolType + ", must be HTTP or WEBSOCKET.")
+	}
+	if input.ApiKeySelectionExpression == "" {
+		input.ApiKeySelectionExpression = defaultApiKeySelectionExpression
+	}
+	if input.Target == "" && input.RouteKey != "" {
+		return nil, BadRequestException("RouteKey can only be specified with Target.")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := randomId(10)
+	now := a.clock()
+	api := &Api{
+		Config: APIApi{
+			ApiEndpoint:               a.apiEndpoint(id, input.ProtocolType),
+			ApiId:                     id,
+			ApiKeySelectionExpression: input.ApiKeySelectionExpression,
+			CorsConfiguration:         input.CorsConfiguration,
+			CreatedDate:               timestamp.ISO8601(now),
+			Description:               input.Description,
+			DisableExecuteApiEndpoint: input.DisableExecuteApiEndpoint,
+			Name:                      input.Name,
+			ProtocolType:              input.ProtocolType,
+			RouteSelectionExpression:  input.RouteSelectionExpression,
+			Tags:                      input.Tags,
+			Version:                   input.Version,
+		},
+		CreatedAt:    now,
+		routes:       make(map[string]*APIRoute),
+		integrations: make(map[string]*APIIntegration),
+		authorizers:  make(map[string]*APIAuthorizer),
+		stages:       make(map[string]*APIStage),
+	}
+
+	// Quick create makes everything the API needs to invoke the target.
+	if input.Target != "" {
+		routeKey := input.RouteKey
+		if routeKey == "" {
+			routeKey = defaultRouteKey
+		}
+		if _, _, ok := parseRouteKey(routeKey); !ok && routeKey != defaultRouteKey {
+			return nil, BadRequestException("Invalid route key " + routeKey)
+		}
+		integration, awserr := newIntegration(input.ProtocolType, CreateIntegrationInput{
+			IntegrationType: "AWS_PROXY",
+			IntegrationUri:  input.Target,
+		})
+		if awserr != nil {
+			return nil, awserr
+		}
+		integration.ApiGatewayManaged = true
+		api.integrations[integration.IntegrationId] = integration
+		route := &APIRoute{
+			ApiGatewayManaged: true,
+			AuthorizationType: "NONE",
+			RouteId:           randomId(7),
+			RouteKey:          routeKey,
+			Target:            "integrations/" + integration.IntegrationId,
+		}
+		api.routes[route.RouteId] = route
+		api.stages[defaultStageName] = &APIStage{
+			ApiGatewayManaged: true,
+			AutoDeploy:        true,
+			CreatedDate:       timestamp.ISO8601(now),
+			LastUpdatedDate:   timestamp.ISO8601(now),
+			StageName:         defaultStageName,
+		}
+	}
+
+	a.apisById[id] = api
+	return &CreateApiOutput{StatusCode: 201, APIApi: api.Config}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid.html#GetApi
+func (a *APIGateway) GetApi(input GetApiInput) (*GetApiOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	config := api.Config
+	return &config, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis.html#GetApis
+func (a *APIGateway) GetApis(input GetApisInput) (*GetApisOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.ParseString(input.MaxResults, math.MaxInt, math.MaxInt, input.NextToken,
+		BadRequestException("MaxResults must be a positive number."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var apis []*Api
+	for _, api := range a.apisById {
+		apis = append(apis, api)
+	}
+	slices.SortFunc(apis, func(x, y *Api) int {
+		if cmp := x.CreatedAt.Compare(y.CreatedAt); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(x.Config.ApiId, y.Config.ApiId)
+	})
+	configs := []APIApi{}
+	for _, api := range apis {
+		configs = append(configs, api.Config)
+	}
+	output := &GetApisOutput{}
+	output.Items, output.NextToken = pagination.Page(configs, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid.html#DeleteApi
+func (a *APIGateway) DeleteApi(input DeleteApiInput) (*DeleteApiOutput, *awserrors.Error) {
+	a.mu.Lock()
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		a.mu.Unlock()
+		return nil, awserr
+	}
+	delete(a.apisById, api.Config.ApiId)
+	connections := a.lockedConnections(api.Config.ApiId)
+	a.mu.Unlock()
+
+	// Closing the connections runs their $disconnect routes, which are gone too.
+	for _, connection := range connections {
+		connection.ws.Close()
+	}
+	return &DeleteApiOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-stages.html#CreateStage
+func (a *APIGateway) CreateStage(input CreateStageInput) (*CreateStageOutput, *awserrors.Error) {
+	if input.StageName != defaultStageName && !stageNameRegex.MatchString(input.StageName) {
+		return nil, BadRequestException("Invalid stage name " + input.StageName)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// WebSocket clients connect to a stage by name.
+	if input.StageName == defaultStageName && api.Config.ProtocolType == "WEBSOCKET" {
+		return nil, BadRequestException("The $default stage is only supported for HTTP APIs.")
+	}
+	if _, ok := api.stages[input.StageName]; ok {
+		return nil, ConflictException("Stage already exists")
+	}
+	now := timestamp.ISO8601(a.clock())
+	stage := &APIStage{
+		AutoDeploy:      input.AutoDeploy,
+		CreatedDate:     now,
+		DeploymentId:    input.DeploymentId,
+		Description:     input.Description,
+		LastUpdatedDate: now,
+		StageName:       input.StageName,
+		StageVariables:  input.StageVariables,
+		Tags:            input.Tags,
+	}
+	api.stages[stage.StageName] = stage
+	return &CreateStageOutput{StatusCode: 201, APIStage: *stage}, nil
+}
+
+func (api *Api) getStage(stageName string) (*APIStage, *awserrors.Error) {
+	stage, ok := api.stages[stageName]
+	if !ok {
+		return nil, NotFoundException(fmt.Sprintf("Invalid stage identifier specified %s", stageName))
+	}
+	return stage, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-stages-stagename.html#GetStage
+func (a *APIGateway) GetStage(input GetStageInput) (*GetStageOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	stage, awserr := api.getStage(input.StageName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := *stage
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-stages.html#GetStages
+func (a *APIGateway) GetStages(input GetStagesInput) (*GetStagesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.ParseString(input.MaxResults, math.MaxInt, math.MaxInt, input.NextToken,
+		BadRequestException("MaxResults must be a positive number."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	stages := sortedValues(api.stages, func(stage *APIStage) string { return stage.StageName })
+	output := &GetStagesOutput{}
+	output.Items, output.NextToken = pagination.Page(stages, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-stages-stagename.html#DeleteStage
+func (a *APIGateway) DeleteStage(input DeleteStageInput) (*DeleteStageOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	stage, awserr := api.getStage(input.StageName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(api.stages, stage.StageName)
+	return &DeleteStageOutput{StatusCode: 204}, nil
+}
@@ -0,0 +1,253 @@
+package apigatewayv2
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+const testFunctionArn = "arn:aws:lambda:us-east-1:123456789012:function:handler"
+
+func newAPIGateway(options Options) *APIGateway {
+	options.ArnGenerator = generator
+	options.Addr = "localhost:4569"
+	a := New(options)
+	a.clock = func() time.Time { return time.Unix(1700000000, 0) }
+	return a
+}
+
+func createApi(t *testing.T, a *APIGateway, input CreateApiInput) string {
+	if input.ProtocolType == "" {
+		input.ProtocolType = "HTTP"
+	}
+	output, awserr := a.CreateApi(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.ApiId
+}
+
+func createIntegration(t *testing.T, a *APIGateway, apiId string, uri string) string {
+	output, awserr := a.CreateIntegration(CreateIntegrationInput{
+		ApiId:           apiId,
+		IntegrationType: "AWS_PROXY",
+		IntegrationUri:  uri,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.IntegrationId
+}
+
+func createRoute(t *testing.T, a *APIGateway, input CreateRouteInput) string {
+	output, awserr := a.CreateRoute(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.RouteId
+}
+
+func TestApis(t *testing.T) {
+	a := newAPIGateway(Options{})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders"})
+	createApi(t, a, CreateApiInput{Name: "payments"})
+
+	api, awserr := a.GetApi(GetApiInput{ApiId: apiId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if api.ApiEndpoint != "http://localhost:4569/execute-api/"+apiId ||
+		api.RouteSelectionExpression != "${request.method} ${request.path}" ||
+		api.CreatedDate != "2023-11-14T22:13:20Z" {
+		t.Fatalf("Unexpected API: %+v", api)
+	}
+
+	for name, input := range map[string]CreateApiInput{
+		"missing name":        {ProtocolType: "HTTP"},
+		"WEBSOCKET":           {Name: "chat", ProtocolType: "WEBSOCKET"},
+		"selection":           {Name: "orders", ProtocolType: "HTTP", RouteSelectionExpression: "$request.body.action"},
+		"route key no target": {Name: "orders", ProtocolType: "HTTP", RouteKey: "GET /"},
+	} {
+		if _, awserr := a.CreateApi(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected BadRequestException for", name, awserr)
+		}
+	}
+
+	list, awserr := a.GetApis(GetApisInput{MaxResults: "1"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Items) != 1 || list.NextToken == "" {
+		t.Fatalf("Unexpected APIs: %+v", list)
+	}
+	list, awserr = a.GetApis(GetApisInput{NextToken: list.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Items) != 1 || list.NextToken != "" {
+		t.Fatalf("Unexpected APIs: %+v", list)
+	}
+
+	if _, awserr := a.DeleteApi(DeleteApiInput{ApiId: apiId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := a.GetApi(GetApiInput{ApiId: apiId}); awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected NotFoundException", awserr)
+	}
+}
+
+func TestQuickCreate(t *testing.T) {
+	a := newAPIGateway(Options{})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders", Target: testFunctionArn})
+
+	routes, awserr := a.GetRoutes(GetRoutesInput{ApiId: apiId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(routes.Items) != 1 || routes.Items[0].RouteKey != "$default" || !routes.Items[0].ApiGatewayManaged {
+		t.Fatalf("Unexpected routes: %+v", routes)
+	}
+	integrations, awserr := a.GetIntegrations(GetIntegrationsInput{ApiId: apiId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	integration := integrations.Items[0]
+	if routes.Items[0].Target != "integrations/"+integration.IntegrationId ||
+		integration.PayloadFormatVersion != "2.0" || integration.TimeoutInMillis != 30000 {
+		t.Fatalf("Unexpected integration: %+v", integration)
+	}
+	stage, awserr := a.GetStage(GetStageInput{ApiId: apiId, StageName: "$default"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !stage.AutoDeploy {
+		t.Fatalf("Unexpected stage: %+v", stage)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	a := newAPIGateway(Options{})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders"})
+	invocationArn := "arn:aws:apigateway:us-east-1:lambda:path/2015-03-31/functions/" + testFunctionArn + "/invocations"
+	integrationId := createIntegration(t, a, apiId, invocationArn)
+	routeId := createRoute(t, a, CreateRouteInput{
+		ApiId:    apiId,
+		RouteKey: "GET /orders/{orderId}",
+		Target:   "integrations/" + integrationId,
+	})
+
+	for name, input := range map[string]CreateRouteInput{
+		"method":         {ApiId: apiId, RouteKey: "FETCH /orders"},
+		"path":           {ApiId: apiId, RouteKey: "GET orders"},
+		"greedy":         {ApiId: apiId, RouteKey: "GET /{proxy+}/orders"},
+		"target":         {ApiId: apiId, RouteKey: "GET /orders", Target: integrationId},
+		"authorization":  {ApiId: apiId, RouteKey: "GET /orders", AuthorizationType: "CUSTOM"},
+		"authorizer":     {ApiId: apiId, RouteKey: "GET /orders", AuthorizerId: "abc123"},
+		"scopes if NONE": {ApiId: apiId, RouteKey: "GET /orders", AuthorizationScopes: []string{"read"}},
+	} {
+		if _, awserr := a.CreateRoute(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected BadRequestException for", name, awserr)
+		}
+	}
+	if _, awserr := a.CreateRoute(CreateRouteInput{ApiId: apiId, RouteKey: "GET /orders/{orderId}"}); awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Error("Expected ConflictException", awserr)
+	}
+	if _, awserr := a.CreateRoute(CreateRouteInput{ApiId: apiId, RouteKey: "GET /", Target: "integrations/missing"}); awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Error("Expected NotFoundException", awserr)
+	}
+
+	for name, input := range map[string]CreateIntegrationInput{
+		"type":    {ApiId: apiId, IntegrationType: "HTTP_PROXY", IntegrationUri: "https://example.com"},
+		"uri":     {ApiId: apiId, IntegrationType: "AWS_PROXY", IntegrationUri: "arn:aws:sqs:us-east-1:123456789012:queue"},
+		"payload": {ApiId: apiId, IntegrationType: "AWS_PROXY", IntegrationUri: testFunctionArn, PayloadFormatVersion: "1.0"},
+		"timeout": {ApiId: apiId, IntegrationType: "AWS_PROXY", IntegrationUri: testFunctionArn, TimeoutInMillis: 60000},
+	} {
+		if _, awserr := a.CreateIntegration(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected BadRequestException for", name, awserr)
+		}
+	}
+
+	// Integrations can't be deleted while routes use them.
+	if _, awserr := a.DeleteIntegration(DeleteIntegrationInput{ApiId: apiId, IntegrationId: integrationId}); awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatal("Expected BadRequestException", awserr)
+	}
+	if _, awserr := a.DeleteRoute(DeleteRouteInput{ApiId: apiId, RouteId: routeId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := a.DeleteIntegration(DeleteIntegrationInput{ApiId: apiId, IntegrationId: integrationId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestStages(t *testing.T) {
+	a := newAPIGateway(Options{})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders"})
+	for _, name := range []string{"prod", "$default"} {
+		if _, awserr := a.CreateStage(CreateStageInput{ApiId: apiId, StageName: name}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	if _, awserr := a.CreateStage(CreateStageInput{ApiId: apiId, StageName: "prod"}); awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatal("Expected ConflictException", awserr)
+	}
+	if _, awserr := a.CreateStage(CreateStageInput{ApiId: apiId, StageName: "$prod"}); awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatal("Expected BadRequestException", awserr)
+	}
+
+	stages, awserr := a.GetStages(GetStagesInput{ApiId: apiId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(stages.Items) != 2 || stages.Items[0].StageName != "$default" || stages.Items[1].StageName != "prod" {
+		t.Fatalf("Unexpected stages: %+v", stages)
+	}
+	if _, awserr := a.DeleteStage(DeleteStageInput{ApiId: apiId, StageName: "prod"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := a.GetStage(GetStageInput{ApiId: apiId, StageName: "prod"}); awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected NotFoundException", awserr)
+	}
+}
+
+func TestAuthorizers(t *testing.T) {
+	a := newAPIGateway(Options{})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders"})
+	for name, input := range map[string]CreateAuthorizerInput{
+		"type":   {ApiId: apiId, Name: "cognito", AuthorizerType: "REQUEST"},
+		"source": {ApiId: apiId, Name: "cognito", AuthorizerType: "JWT", IdentitySource: []string{"Authorization"}, JwtConfiguration: &APIJWTConfiguration{Issuer: "http://localhost/pool"}},
+		"issuer": {ApiId: apiId, Name: "cognito", AuthorizerType: "JWT", IdentitySource: []string{"$request.header.Authorization"}},
+	} {
+		if _, awserr := a.CreateAuthorizer(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected BadRequestException for", name, awserr)
+		}
+	}
+
+	output, awserr := a.CreateAuthorizer(CreateAuthorizerInput{
+		ApiId:            apiId,
+		Name:             "cognito",
+		AuthorizerType:   "JWT",
+		IdentitySource:   []string{"$request.header.Authorization"},
+		JwtConfiguration: &APIJWTConfiguration{Issuer: "http://localhost:4569/us-east-1_abc", Audience: []string{"client"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	createRoute(t, a, CreateRouteInput{
+		ApiId:             apiId,
+		RouteKey:          "GET /orders",
+		AuthorizationType: "JWT",
+		AuthorizerId:      output.AuthorizerId,
+	})
+	if _, awserr := a.CreateRoute(CreateRouteInput{ApiId: apiId, RouteKey: "POST /orders", AuthorizationType: "JWT", AuthorizerId: "missing"}); awserr == nil || awserr.Code != 404 {
+		t.Error("Expected NotFoundException", awserr)
+	}
+	if _, awserr := a.DeleteAuthorizer(DeleteAuthorizerInput{ApiId: apiId, AuthorizerId: output.AuthorizerId}); awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatal("Expected BadRequestException", awserr)
+	}
+}
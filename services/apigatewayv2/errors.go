@@ -0,0 +1,57 @@
+package apigatewayv2
+
+import "aws-in-a-box/awserrors"
+
+func BadRequestException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BadRequestException", message)
+}
+
+func ConflictException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 409,
+		Body: awserrors.ErrorBody{
+			Type:    "ConflictException",
+			Message: message,
+		},
+	}
+}
+
+func ForbiddenException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 403,
+		Body: awserrors.ErrorBody{
+			Type:    "ForbiddenException",
+			Message: message,
+		},
+	}
+}
+
+func GoneException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 410,
+		Body: awserrors.ErrorBody{
+			Type:    "GoneException",
+			Message: message,
+		},
+	}
+}
+
+func NotFoundException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    "NotFoundException",
+			Message: message,
+		},
+	}
+}
+
+func PayloadTooLargeException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 413,
+		Body: awserrors.ErrorBody{
+			Type:    "PayloadTooLargeException",
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package apigatewayv2
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// API Gateway v2 only supports the REST-JSON protocol.
+func NewHandler(logger *slog.Logger, a *APIGateway, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	const apiPath = "/v2/apis/{ApiId}"
+	const routePath = apiPath + "/routes/{RouteId}"
+	const integrationPath = apiPath + "/integrations/{IntegrationId}"
+	const stagePath = apiPath + "/stages/{StageName}"
+	const authorizerPath = apiPath + "/authorizers/{AuthorizerId}"
+
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v2/apis", "CreateApi", region.Method(a.regions, (*APIGateway).CreateApi))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v2/apis", "GetApis", region.Method(a.regions, (*APIGateway).GetApis))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, apiPath, "GetApi", region.Method(a.regions, (*APIGateway).GetApi))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, apiPath, "DeleteApi", region.Method(a.regions, (*APIGateway).DeleteApi))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, apiPath+"/routes", "CreateRoute", region.Method(a.regions, (*APIGateway).CreateRoute))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, apiPath+"/routes", "GetRoutes", region.Method(a.regions, (*APIGateway).GetRoutes))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, routePath, "GetRoute", region.Method(a.regions, (*APIGateway).GetRoute))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, routePath, "DeleteRoute", region.Method(a.regions, (*APIGateway).DeleteRoute))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, apiPath+"/integrations", "CreateIntegration", region.Method(a.regions, (*APIGateway).CreateIntegration))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, apiPath+"/integrations", "GetIntegrations", region.Method(a.regions, (*APIGateway).GetIntegrations))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, integrationPath, "GetIntegration", region.Method(a.regions, (*APIGateway).GetIntegration))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, integrationPath, "DeleteIntegration", region.Method(a.regions, (*APIGateway).DeleteIntegration))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, apiPath+"/stages", "CreateStage", region.Method(a.regions, (*APIGateway).CreateStage))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, apiPath+"/stages", "GetStages", region.Method(a.regions, (*APIGateway).GetStages))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, stagePath, "GetStage", region.Method(a.regions, (*APIGateway).GetStage))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, stagePath, "DeleteStage", region.Method(a.regions, (*APIGateway).DeleteStage))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, apiPath+"/authorizers", "CreateAuthorizer", region.Method(a.regions, (*APIGateway).CreateAuthorizer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, apiPath+"/authorizers", "GetAuthorizers", region.Method(a.regions, (*APIGateway).GetAuthorizers))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, authorizerPath, "GetAuthorizer", region.Method(a.regions, (*APIGateway).GetAuthorizer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, authorizerPath, "DeleteAuthorizer", region.Method(a.regions, (*APIGateway).DeleteAuthorizer))
+	capabilityRegistry.Add("apigateway", registry.Operations()...)
+	handler := restjson.NewHandler(registry)
+
+	// The API Gateway Management API is called at a WebSocket API's endpoint, followed by the stage.
+	const connectionPath = "/{ApiId}/{Stage}/@connections/{ConnectionId}"
+	connectionsRegistry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, connectionsRegistry, http.MethodPost, connectionPath, "PostToConnection", region.Method(a.regions, (*APIGateway).PostToConnection))
+	restjson.RegisterWithRequest(logger, connectionsRegistry, http.MethodGet, connectionPath, "GetConnection", region.Method(a.regions, (*APIGateway).GetConnection))
+	restjson.RegisterWithRequest(logger, connectionsRegistry, http.MethodDelete, connectionPath, "DeleteConnection", region.Method(a.regions, (*APIGateway).DeleteConnection))
+	capabilityRegistry.Add("apigateway", connectionsRegistry.Operations()...)
+	connectionsHandler := restjson.NewHandler(connectionsRegistry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		if apiId, path, ok := parseInvokeRequest(r); ok && strings.Contains(path, "/@connections/") {
+			// Match the path within the API, whether the API was addressed by host or path.
+			connectionsRequest := r.Clone(r.Context())
+			connectionsRequest.URL.Path, connectionsRequest.URL.RawPath = "/"+apiId+path, ""
+			if connectionsHandler(w, connectionsRequest) {
+				return true
+			}
+		}
+		// Requests to APIs are plain HTTP requests, rather than API calls.
+		if a.serveApi(w, r) {
+			return true
+		}
+		return handler(w, r)
+	}
+}
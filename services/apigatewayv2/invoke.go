@@ -0,0 +1,482 @@
+package apigatewayv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/lambda"
+)
+
+const (
+	// APIs are served at http://<addr>/execute-api/<apiId>/, or at http://<apiId>.execute-api.<anything>/
+	// for clients that can resolve that host to this server.
+	executeApiPathPrefix = "/execute-api/"
+	executeApiHostInfix  = ".execute-api."
+
+	httpEventTimeFormat = "02/Jan/2006:15:04:05 -0700"
+	maxRequestSize      = 10 * 1024 * 1024
+)
+
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-develop-integrations-lambda.html
+type httpEvent struct {
+	Version               string             `json:"version"`
+	RouteKey              string             `json:"routeKey"`
+	RawPath               string             `json:"rawPath"`
+	RawQueryString        string             `json:"rawQueryString"`
+	Cookies               []string           `json:"cookies,omitempty"`
+	Headers               map[string]string  `json:"headers"`
+	QueryStringParameters map[string]string  `json:"queryStringParameters,omitempty"`
+	PathParameters        map[string]string  `json:"pathParameters,omitempty"`
+	StageVariables        map[string]string  `json:"stageVariables,omitempty"`
+	RequestContext        httpRequestContext `json:"requestContext"`
+	Body                  string             `json:"body,omitempty"`
+	IsBase64Encoded       bool               `json:"isBase64Encoded"`
+}
+
+type httpRequestContext struct {
+	AccountId    string             `json:"accountId"`
+	ApiId        string             `json:"apiId"`
+	Authorizer   *httpAuthorizer    `json:"authorizer,omitempty"`
+	DomainName   string             `json:"domainName"`
+	DomainPrefix string             `json:"domainPrefix"`
+	Http         httpRequestDetails `json:"http"`
+	RequestId    string             `json:"requestId"`
+	RouteKey     string             `json:"routeKey"`
+	Stage        string             `json:"stage"`
+	Time         string             `json:"time"`
+	TimeEpoch    int64              `json:"timeEpoch"`
+}
+
+type httpAuthorizer struct {
+	Jwt httpJwtAuthorizer `json:"jwt"`
+}
+
+type httpJwtAuthorizer struct {
+	Claims map[string]string `json:"claims"`
+	Scopes []string          `json:"scopes"`
+}
+
+type httpRequestDetails struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	SourceIp  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// parseInvokeRequest returns the ID of the API the request is for, and the path within it,
+// or false if the request isn't for an API.
+func parseInvokeRequest(r *http.Request) (string, string, bool) {
+	if i := strings.Index(r.Host, executeApiHostInfix); i > 0 {
+		return r.Host[:i], r.URL.Path, true
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, executeApiPathPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, path, _ := strings.Cut(rest, "/")
+	return id, "/" + path, true
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// routeMatch is a route matching a request, with the values of its path variables.
+type routeMatch struct {
+	route          *APIRoute
+	pathParameters map[string]string
+
+	// For picking the most specific match.
+	greedy          bool
+	literalSegments int
+	anyMethod       bool
+}
+
+// moreSpecific returns whether m takes precedence over other. Routes without greedy variables
+// win, then those with more literal segments, then those for the request's method rather than ANY.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-develop-routes.html#http-api-develop-routes.evaluation
+func (m *routeMatch) moreSpecific(other *routeMatch) bool {
+	if m.greedy != other.greedy {
+		return !m.greedy
+	}
+	if m.literalSegments != other.literalSegments {
+		return m.literalSegments > other.literalSegments
+	}
+	return !m.anyMethod && other.anyMethod
+}
+
+// matchRoute returns how the route matches the request's method and path segments, if it does.
+func matchRoute(route *APIRoute, method string, segments []string) *routeMatch {
+	routeMethod, routeSegments, ok := parseRouteKey(route.RouteKey)
+	if !ok || (routeMethod != "ANY" && routeMethod != method) {
+		return nil
+	}
+	match := &routeMatch{
+		route:     route,
+		anyMethod: routeMethod == "ANY",
+	}
+	for i, routeSegment := range routeSegments {
+		if i >= len(segments) {
+			return nil
+		}
+		if !strings.HasPrefix(routeSegment, "{") {
+			if routeSegment != segments[i] {
+				return nil
+			}
+			match.literalSegments++
+			continue
+		}
+		name := strings.Trim(routeSegment, "{}")
+		if name, ok := strings.CutSuffix(name, "+"); ok {
+			// Greedy variables match the rest of the path, which has at least one segment.
+			match.greedy = true
+			match.addPathParameter(name, strings.Join(segments[i:], "/"))
+			return match
+		}
+		match.addPathParameter(name, segments[i])
+	}
+	if len(routeSegments) != len(segments) {
+		return nil
+	}
+	return match
+}
+
+func (m *routeMatch) addPathParameter(name string, value string) {
+	if m.pathParameters == nil {
+		m.pathParameters = make(map[string]string)
+	}
+	m.pathParameters[name] = value
+}
+
+// lockedResolve returns the stage and the route the request is for. Named stages are served
+// under their name, and the $default stage at the API's root.
+func (api *Api) lockedResolve(method string, path string) (*APIStage, *routeMatch) {
+	segments := splitPath(path)
+	stage := api.stages[defaultStageName]
+	if len(segments) > 0 && segments[0] != defaultStageName {
+		if named, ok := api.stages[segments[0]]; ok {
+			stage = named
+			segments = segments[1:]
+		}
+	}
+	if stage == nil {
+		return nil, nil
+	}
+
+	// Stages always serve the API's current routes, as if they were all deployed automatically.
+	var best *routeMatch
+	var defaultRoute *APIRoute
+	for _, route := range api.routes {
+		if route.RouteKey == defaultRouteKey {
+			defaultRoute = route
+			continue
+		}
+		match := matchRoute(route, method, segments)
+		if match != nil && (best == nil || match.moreSpecific(best)) {
+			best = match
+		}
+	}
+	if best == nil && defaultRoute != nil {
+		best = &routeMatch{route: defaultRoute}
+	}
+	return stage, best
+}
+
+// claimString formats a claim the way API Gateway passes claims to functions.
+func claimString(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []any:
+		values := []string{}
+		for _, v := range value {
+			values = append(values, claimString(v))
+		}
+		return "[" + strings.Join(values, " ") + "]"
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+}
+
+// authorize verifies the request's JWT with the authorizer, returning the authorizer context
+// for the event, or the status to respond with.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-jwt-authorizer.html
+func (a *APIGateway) authorize(r *http.Request, authorizer *APIAuthorizer, scopes []string) (*httpAuthorizer, int) {
+	if a.userPools == nil {
+		a.logger.Warn("JWT authorizer needs Cognito user pools to be enabled", "authorizerId", authorizer.AuthorizerId)
+		return nil, http.StatusUnauthorized
+	}
+
+	match := identitySourceRegex.FindStringSubmatch(authorizer.IdentitySource[0])
+	var token string
+	if match[1] == "header" {
+		token = r.Header.Get(match[2])
+	} else {
+		token = r.URL.Query().Get(match[2])
+	}
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	if token == "" {
+		return nil, http.StatusUnauthorized
+	}
+
+	// Tokens are verified by the local user pool whose ID the issuer ends with, so authorizers can
+	// be configured with either the real or the local issuer.
+	issuer := strings.TrimSuffix(authorizer.JwtConfiguration.Issuer, "/")
+	userPoolId := issuer[strings.LastIndex(issuer, "/")+1:]
+	claims, err := a.userPools.VerifyToken(userPoolId, token)
+	if err != nil {
+		a.logger.Debug("Rejected JWT", "authorizerId", authorizer.AuthorizerId, "err", err)
+		return nil, http.StatusUnauthorized
+	}
+
+	// ID tokens have an audience, and access tokens a client ID.
+	audience, _ := claims["aud"].(string)
+	if audience == "" {
+		audience, _ = claims["client_id"].(string)
+	}
+	if !slices.Contains(authorizer.JwtConfiguration.Audience, audience) {
+		return nil, http.StatusUnauthorized
+	}
+
+	var tokenScopes []string
+	if scope, ok := claims["scope"].(string); ok {
+		tokenScopes = strings.Fields(scope)
+	}
+	if len(scopes) > 0 && !slices.ContainsFunc(scopes, func(scope string) bool {
+		return slices.Contains(tokenScopes, scope)
+	}) {
+		return nil, http.StatusForbidden
+	}
+
+	context := &httpAuthorizer{
+		Jwt: httpJwtAuthorizer{
+			Claims: make(map[string]string),
+			Scopes: tokenScopes,
+		},
+	}
+	for name, value := range claims {
+		context.Jwt.Claims[name] = claimString(value)
+	}
+	return context, 0
+}
+
+// invokeFunction invokes an AWS_PROXY integration's function, returning its response,
+// or false if it failed.
+func (a *APIGateway) invokeFunction(functionArn string, payload []byte) ([]byte, bool) {
+	if a.lambda == nil {
+		a.logger.Warn("AWS_PROXY integrations need Lambda to be enabled", "function", functionArn)
+		return nil, false
+	}
+	output, awserr := a.lambda.Invoke(lambda.InvokeInput{
+		FunctionName: functionArn,
+		Payload:      payload,
+	})
+	if awserr != nil {
+		a.logger.Warn("Invoking integration failed", "function", functionArn, "err", awserr.Body.Message)
+		return nil, false
+	}
+	if output.FunctionError != "" {
+		return nil, false
+	}
+	return output.Payload, true
+}
+
+// serveApi invokes the integration of the route the request is for, if it's for an API.
+func (a *APIGateway) serveApi(w http.ResponseWriter, r *http.Request) bool {
+	apiId, path, ok := parseInvokeRequest(r)
+	if !ok {
+		return false
+	}
+	apis, err := a.forApi(r, apiId)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return true
+	}
+	apis.invokeApi(w, r, apiId, path)
+	return true
+}
+
+// forApi returns the instance of the region whose API the request is for. Requests to APIs needn't
+// be signed, and their endpoints' paths don't say their regions, so unless the request says its
+// region, such as with its hostname, every region is looked in for the API.
+func (a *APIGateway) forApi(r *http.Request, apiId string) (*APIGateway, error) {
+	if name := region.FromRequest(r); name != "" {
+		return a.regions.Get(name)
+	}
+	for _, apis := range a.regions.All() {
+		apis.mu.Lock()
+		_, ok := apis.apisById[apiId]
+		apis.mu.Unlock()
+		if ok {
+			return apis, nil
+		}
+	}
+	return a.regions.Default(), nil
+}
+
+// invokeApi invokes the integration of the route the request to the API is for.
+func (a *APIGateway) invokeApi(w http.ResponseWriter, r *http.Request, apiId string, path string) {
+	requestId := uuid.Must(uuid.NewV4()).String()
+	w.Header().Set("Apigw-Requestid", requestId)
+
+	a.mu.Lock()
+	api, ok := a.apisById[apiId]
+	if !ok || api.Config.DisableExecuteApiEndpoint {
+		a.mu.Unlock()
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if api.Config.ProtocolType == "WEBSOCKET" {
+		a.mu.Unlock()
+		a.serveWebSocket(w, r, apiId, path)
+		return
+	}
+	var cors *lambda.Cors
+	if c := api.Config.CorsConfiguration; c != nil {
+		cors = &lambda.Cors{
+			AllowCredentials: c.AllowCredentials,
+			AllowHeaders:     c.AllowHeaders,
+			AllowMethods:     c.AllowMethods,
+			AllowOrigins:     c.AllowOrigins,
+			ExposeHeaders:    c.ExposeHeaders,
+			MaxAge:           c.MaxAge,
+		}
+	}
+	stage, match := api.lockedResolve(r.Method, path)
+	var stageName string
+	var stageVariables map[string]string
+	if stage != nil {
+		stageName, stageVariables = stage.StageName, stage.StageVariables
+	}
+	var route APIRoute
+	var integration *APIIntegration
+	var authorizer *APIAuthorizer
+	if match != nil {
+		route = *match.route
+		integration = api.integrations[strings.TrimPrefix(route.Target, integrationTargetPrefix)]
+		authorizer = api.authorizers[route.AuthorizerId]
+	}
+	a.mu.Unlock()
+
+	// API Gateway answers preflight requests itself when the API has a CORS configuration.
+	if cors != nil && lambda.SetCorsHeaders(w, r, cors) {
+		return
+	}
+	if stage == nil || match == nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	var authorizerContext *httpAuthorizer
+	if route.AuthorizationType == "JWT" && authorizer != nil {
+		var status int
+		authorizerContext, status = a.authorize(r, authorizer, route.AuthorizationScopes)
+		if authorizerContext == nil {
+			writeMessage(w, status, http.StatusText(status))
+			return
+		}
+	}
+
+	if integration == nil {
+		// Routes without integrations exist, but can't be invoked.
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(body) > maxRequestSize {
+		writeMessage(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+		return
+	}
+
+	event := a.httpEvent(r, apiId, stageName, stageVariables, path, requestId, body)
+	event.RouteKey = route.RouteKey
+	event.RequestContext.RouteKey = route.RouteKey
+	event.RequestContext.Authorizer = authorizerContext
+	event.PathParameters = match.pathParameters
+	payload, _ := json.Marshal(event)
+
+	functionArn, _ := functionArn(integration.IntegrationUri)
+	response, ok := a.invokeFunction(functionArn, payload)
+	if !ok {
+		writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	lambda.WriteHTTPResponse(w, response)
+}
+
+// httpEvent is the payload format version 2.0 event for the request. The raw path includes the
+// stage for named stages, unlike the path routes match.
+func (a *APIGateway) httpEvent(r *http.Request, apiId string, stageName string, stageVariables map[string]string, rawPath string, requestId string, body []byte) httpEvent {
+	now := a.clock()
+	sourceIp, _, _ := net.SplitHostPort(r.RemoteAddr)
+	event := httpEvent{
+		Version:        "2.0",
+		RawPath:        rawPath,
+		RawQueryString: r.URL.RawQuery,
+		Headers:        make(map[string]string),
+		StageVariables: stageVariables,
+		RequestContext: httpRequestContext{
+			AccountId:    a.arnGenerator.AwsAccountId,
+			ApiId:        apiId,
+			DomainName:   r.Host,
+			DomainPrefix: apiId,
+			Http: httpRequestDetails{
+				Method:    r.Method,
+				Path:      rawPath,
+				Protocol:  r.Proto,
+				SourceIp:  sourceIp,
+				UserAgent: r.UserAgent(),
+			},
+			RequestId: requestId,
+			Stage:     stageName,
+			Time:      now.UTC().Format(httpEventTimeFormat),
+			TimeEpoch: now.UnixMilli(),
+		},
+	}
+
+	// Header names are lowercase, and repeated headers are joined with commas.
+	for name, values := range r.Header {
+		if name == "Cookie" {
+			for _, cookie := range r.Cookies() {
+				event.Cookies = append(event.Cookies, cookie.String())
+			}
+			continue
+		}
+		event.Headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		event.QueryStringParameters = make(map[string]string)
+		for name, values := range query {
+			event.QueryStringParameters[name] = strings.Join(values, ",")
+		}
+	}
+
+	if len(body) > 0 {
+		if lambda.IsTextContent(r.Header.Get("Content-Type"), body) {
+			event.Body = string(body)
+		} else {
+			event.Body = base64.StdEncoding.EncodeToString(body)
+			event.IsBase64Encoded = true
+		}
+	}
+	return event
+}
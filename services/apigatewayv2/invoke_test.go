@@ -0,0 +1,306 @@
+package apigatewayv2
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/lambda"
+)
+
+// fakeLambda responds to invocations with the status code, 200 by default, and the event as the body.
+type fakeLambda struct {
+	mu         sync.Mutex
+	invoked    []string
+	fail       bool
+	statusCode int
+}
+
+func (l *fakeLambda) Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.invoked = append(l.invoked, input.FunctionName)
+	if l.fail {
+		return &lambda.InvokeOutput{StatusCode: 200, FunctionError: "Unhandled", Payload: []byte(`{"errorMessage":"boom"}`)}, nil
+	}
+	statusCode := l.statusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+	response, _ := json.Marshal(map[string]any{
+		"statusCode": statusCode,
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       string(input.Payload),
+	})
+	return &lambda.InvokeOutput{StatusCode: 200, Payload: response}, nil
+}
+
+func (l *fakeLambda) invocations() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.invoked)
+}
+
+type fakeUserPools map[string]map[string]any
+
+func (p fakeUserPools) VerifyToken(userPoolId string, token string) (map[string]any, error) {
+	claims, ok := p[userPoolId+"/"+token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func serve(t *testing.T, a *APIGateway, r *http.Request) (*httptest.ResponseRecorder, httpEvent) {
+	w := httptest.NewRecorder()
+	if !a.serveApi(w, r) {
+		t.Fatal("Request not served", r.URL)
+	}
+	var event httpEvent
+	if w.Code == 200 && w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &event); err != nil {
+			t.Fatal(err, w.Body.String())
+		}
+	}
+	return w, event
+}
+
+func TestInvokeRouting(t *testing.T) {
+	fake := &fakeLambda{}
+	a := newAPIGateway(Options{Lambda: fake})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders", Target: testFunctionArn})
+	integrationId := createIntegration(t, a, apiId, testFunctionArn+":live")
+	for _, routeKey := range []string{"GET /orders/{orderId}", "GET /orders/latest", "ANY /orders/{proxy+}"} {
+		createRoute(t, a, CreateRouteInput{ApiId: apiId, RouteKey: routeKey, Target: "integrations/" + integrationId})
+	}
+	if _, awserr := a.CreateStage(CreateStageInput{ApiId: apiId, StageName: "prod", StageVariables: map[string]string{"env": "prod"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	for path, expected := range map[string]struct {
+		routeKey       string
+		stage          string
+		pathParameters map[string]string
+	}{
+		"/orders/latest":      {"GET /orders/latest", "$default", nil},
+		"/orders/42":          {"GET /orders/{orderId}", "$default", map[string]string{"orderId": "42"}},
+		"/orders/42/items":    {"ANY /orders/{proxy+}", "$default", map[string]string{"proxy": "42/items"}},
+		"/prod/orders/42":     {"GET /orders/{orderId}", "prod", map[string]string{"orderId": "42"}},
+		"/customers":          {"$default", "$default", nil},
+		"/$default/orders/42": {"$default", "$default", nil},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:4569/execute-api/"+apiId+path+"?a=1&a=2", nil)
+		w, event := serve(t, a, r)
+		if w.Code != 200 {
+			t.Fatal("Unexpected status", path, w.Code, w.Body.String())
+		}
+		if event.RouteKey != expected.routeKey || event.RequestContext.Stage != expected.stage ||
+			event.RawPath != path || event.QueryStringParameters["a"] != "1,2" ||
+			len(event.PathParameters) != len(expected.pathParameters) {
+			t.Fatalf("Unexpected event for %s: %+v", path, event)
+		}
+		for name, value := range expected.pathParameters {
+			if event.PathParameters[name] != value {
+				t.Fatalf("Unexpected path parameters for %s: %+v", path, event.PathParameters)
+			}
+		}
+		if expected.stage == "prod" && event.StageVariables["env"] != "prod" {
+			t.Fatalf("Unexpected stage variables: %+v", event.StageVariables)
+		}
+	}
+	if !slices.Contains(fake.invoked, testFunctionArn+":live") {
+		t.Fatal("Unexpected functions", fake.invoked)
+	}
+
+	// APIs are also served at their own host.
+	r := httptest.NewRequest(http.MethodPost, "http://"+apiId+".execute-api.localhost:4569/orders/42", strings.NewReader(`{"quantity":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	_, event := serve(t, a, r)
+	if event.RouteKey != "ANY /orders/{proxy+}" || event.Body != `{"quantity":1}` || event.IsBase64Encoded {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+	r = httptest.NewRequest(http.MethodPut, "http://localhost:4569/execute-api/"+apiId+"/upload", strings.NewReader("\xff\xfe"))
+	r.Header.Set("Content-Type", "application/octet-stream")
+	if _, event := serve(t, a, r); event.Body != "//4=" || !event.IsBase64Encoded {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "http://localhost:4569/execute-api/missing/orders", nil)
+	if w, _ := serve(t, a, r); w.Code != 404 {
+		t.Fatal("Expected 404", w.Code)
+	}
+	fake.fail = true
+	r = httptest.NewRequest(http.MethodGet, "http://localhost:4569/execute-api/"+apiId+"/orders/42", nil)
+	if w, _ := serve(t, a, r); w.Code != 500 || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Fatal("Expected 500", w.Code, w.Body.String())
+	}
+
+	if a.serveApi(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:4569/v2/apis", nil)) {
+		t.Fatal("API call served as an API request")
+	}
+}
+
+func TestInvokeWithoutStage(t *testing.T) {
+	a := newAPIGateway(Options{Lambda: &fakeLambda{}})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders"})
+	integrationId := createIntegration(t, a, apiId, testFunctionArn)
+	createRoute(t, a, CreateRouteInput{ApiId: apiId, RouteKey: "GET /orders", Target: "integrations/" + integrationId})
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost:4569/execute-api/"+apiId+"/orders", nil)
+	if w, _ := serve(t, a, r); w.Code != 404 || !strings.Contains(w.Body.String(), "Not Found") {
+		t.Fatal("Expected 404", w.Code, w.Body.String())
+	}
+}
+
+func TestJWTAuthorizer(t *testing.T) {
+	userPools := fakeUserPools{
+		"us-east-1_abc/id-token": {
+			"sub":            "user-1",
+			"aud":            "client",
+			"token_use":      "id",
+			"exp":            float64(1700003600),
+			"cognito:groups": []any{"admins", "users"},
+		},
+		"us-east-1_abc/access-token": {
+			"sub":       "user-1",
+			"client_id": "client",
+			"token_use": "access",
+			"scope":     "orders/read aws.cognito.signin.user.admin",
+		},
+		"us-east-1_abc/other-client": {
+			"sub": "user-2",
+			"aud": "other",
+		},
+	}
+	a := newAPIGateway(Options{Lambda: &fakeLambda{}, UserPools: userPools})
+	apiId := createApi(t, a, CreateApiInput{Name: "orders", Target: testFunctionArn})
+	integrationId := createIntegration(t, a, apiId, testFunctionArn)
+	authorizer, awserr := a.CreateAuthorizer(CreateAuthorizerInput{
+		ApiId:            apiId,
+		Name:             "cognito",
+		AuthorizerType:   "JWT",
+		IdentitySource:   []string{"$request.header.Authorization"},
+		JwtConfiguration: &APIJWTConfiguration{Issuer: "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc", Audience: []string{"client"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	createRoute(t, a, CreateRouteInput{
+		ApiId:             apiId,
+		RouteKey:          "GET /orders",
+		Target:            "integrations/" + integrationId,
+		AuthorizationType: "JWT",
+		AuthorizerId:      authorizer.AuthorizerId,
+	})
+	createRoute(t, a, CreateRouteInput{
+		ApiId:               apiId,
+		RouteKey:            "POST /orders",
+		Target:              "integrations/" + integrationId,
+		AuthorizationType:   "JWT",
+		AuthorizerId:        authorizer.AuthorizerId,
+		AuthorizationScopes: []string{"orders/write"},
+	})
+
+	request := func(method string, token string) *http.Request {
+		r := httptest.NewRequest(method, "http://localhost:4569/execute-api/"+apiId+"/orders", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+	w, event := serve(t, a, request(http.MethodGet, "id-token"))
+	if w.Code != 200 {
+		t.Fatal("Unexpected status", w.Code, w.Body.String())
+	}
+	claims := event.RequestContext.Authorizer.Jwt.Claims
+	if claims["sub"] != "user-1" || claims["exp"] != "1700003600" || claims["cognito:groups"] != "[admins users]" {
+		t.Fatalf("Unexpected claims: %+v", claims)
+	}
+	_, event = serve(t, a, request(http.MethodGet, "access-token"))
+	if scopes := event.RequestContext.Authorizer.Jwt.Scopes; len(scopes) != 2 || scopes[0] != "orders/read" {
+		t.Fatalf("Unexpected scopes: %+v", scopes)
+	}
+
+	for name, test := range map[string]struct {
+		method string
+		token  string
+		status int
+	}{
+		"no token":     {http.MethodGet, "", 401},
+		"invalid":      {http.MethodGet, "forged", 401},
+		"audience":     {http.MethodGet, "other-client", 401},
+		"scopes":       {http.MethodPost, "access-token", 403},
+		"no id scopes": {http.MethodPost, "id-token", 403},
+	} {
+		if w, _ := serve(t, a, request(test.method, test.token)); w.Code != test.status {
+			t.Error("Unexpected status for", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCors(t *testing.T) {
+	fake := &fakeLambda{}
+	a := newAPIGateway(Options{Lambda: fake})
+	apiId := createApi(t, a, CreateApiInput{
+		Name:   "orders",
+		Target: testFunctionArn,
+		CorsConfiguration: &APICors{
+			AllowOrigins: []string{"https://example.com"},
+			AllowMethods: []string{"GET", "POST"},
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodOptions, "http://localhost:4569/execute-api/"+apiId+"/orders", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w, _ := serve(t, a, r)
+	if w.Code != 200 || w.Header().Get("Access-Control-Allow-Methods") != "GET,POST" || len(fake.invoked) != 0 {
+		t.Fatal("Unexpected preflight response", w.Code, w.Header())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "http://localhost:4569/execute-api/"+apiId+"/orders", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w, _ = serve(t, a, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://example.com" || len(fake.invoked) != 1 {
+		t.Fatal("Unexpected response", w.Code, w.Header())
+	}
+}
+
+func TestInvokeRegions(t *testing.T) {
+	fake := &fakeLambda{}
+	a := newAPIGateway(Options{Lambda: fake})
+	if _, err := a.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := a.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiId := createApi(t, europe, CreateApiInput{Name: "orders", Target: testFunctionArn})
+	if _, awserr := a.GetApi(GetApiInput{ApiId: apiId}); awserr == nil {
+		t.Fatal("Expected the API not to be in us-east-1")
+	}
+
+	// Endpoints' paths don't say their APIs' regions, so they're found in whichever region has them,
+	// unless the request says its region.
+	for requestRegion, status := range map[string]int{"": 200, "eu-west-1": 200, "us-east-1": 404, "xx-nowhere-1": 404} {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:4569/execute-api/"+apiId+"/orders", nil)
+		if requestRegion != "" {
+			r.Header.Set(region.Header, requestRegion)
+		}
+		if w, _ := serve(t, a, r); w.Code != status {
+			t.Fatal("Unexpected status for", requestRegion, w.Code, w.Body.String())
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "http://"+apiId+".execute-api.eu-west-1.amazonaws.com/orders", nil)
+	if w, _ := serve(t, a, r); w.Code != 200 {
+		t.Fatal("Unexpected status", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,477 @@
+package apigatewayv2
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+)
+
+var (
+	routeMethods = []string{"ANY", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT"}
+
+	functionArnRegex        = regexp.MustCompile(`^arn:aws[a-z-]*:lambda:[a-z0-9-]+:\d{12}:function:[a-zA-Z0-9_-]+(:[a-zA-Z0-9$_-]+)?$`)
+	invocationArnRegex      = regexp.MustCompile(`^arn:aws[a-z-]*:apigateway:[a-z0-9-]+:lambda:path/2015-03-31/functions/(.+)/invocations$`)
+	identitySourceRegex     = regexp.MustCompile(`^\$request\.(header|querystring)\.([^.\s]+)$`)
+	pathVariableRegex       = regexp.MustCompile(`^\{[a-zA-Z0-9_]+\+?\}$`)
+	integrationTargetPrefix = "integrations/"
+)
+
+// parseRouteKey returns the method and path segments of a route key such as "GET /pets/{petId}".
+// Only the last segment may be greedy, such as {proxy+}.
+func parseRouteKey(routeKey string) (string, []string, bool) {
+	method, path, ok := strings.Cut(routeKey, " ")
+	if !ok || !slices.Contains(routeMethods, method) || !strings.HasPrefix(path, "/") {
+		return "", nil, false
+	}
+	segments := splitPath(path)
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "{}") {
+			if !pathVariableRegex.MatchString(segment) {
+				return "", nil, false
+			}
+			if strings.HasSuffix(segment, "+}") && i != len(segments)-1 {
+				return "", nil, false
+			}
+		}
+	}
+	return method, segments, true
+}
+
+// splitPath returns the path's segments, ignoring empty ones.
+func splitPath(path string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// functionArn returns the ARN of the function an AWS_PROXY integration invokes.
+func functionArn(integrationUri string) (string, bool) {
+	if match := invocationArnRegex.FindStringSubmatch(integrationUri); match != nil {
+		integrationUri = match[1]
+	}
+	return integrationUri, functionArnRegex.MatchString(integrationUri)
+}
+
+func (api *Api) getRoute(routeId string) (*APIRoute, *awserrors.Error) {
+	route, ok := api.routes[routeId]
+	if !ok {
+		return nil, NotFoundException(fmt.Sprintf("Invalid Route identifier specified %s", routeId))
+	}
+	return route, nil
+}
+
+func (api *Api) getIntegration(integrationId string) (*APIIntegration, *awserrors.Error) {
+	integration, ok := api.integrations[integrationId]
+	if !ok {
+		return nil, NotFoundException(fmt.Sprintf("Invalid Integration identifier specified %s", integrationId))
+	}
+	return integration, nil
+}
+
+func (api *Api) getAuthorizer(authorizerId string) (*APIAuthorizer, *awserrors.Error) {
+	authorizer, ok := api.authorizers[authorizerId]
+	if !ok {
+		return nil, NotFoundException(fmt.Sprintf("Invalid Authorizer identifier specified %s", authorizerId))
+	}
+	return authorizer, nil
+}
+
+func validateHttpRoute(input CreateRouteInput) *awserrors.Error {
+	if input.RouteKey != defaultRouteKey {
+		if _, _, ok := parseRouteKey(input.RouteKey); !ok {
+			return BadRequestException("Invalid route key " + input.RouteKey)
+		}
+	}
+	if input.RouteResponseSelectionExpression != "" {
+		return BadRequestException("RouteResponseSelectionExpression is only supported for WebSocket APIs.")
+	}
+
+	switch input.AuthorizationType {
+	case "NONE", "AWS_IAM":
+		// AWS_IAM routes would require signed requests, but credentials aren't checked.
+		if input.AuthorizerId != "" {
+			return BadRequestException("AuthorizerId can only be specified for JWT or CUSTOM routes.")
+		}
+		if len(input.AuthorizationScopes) > 0 {
+			return BadRequestException("AuthorizationScopes can only be specified for JWT routes.")
+		}
+	case "JWT":
+	default:
+		// TODO: Lambda (CUSTOM) authorizers.
+		return BadRequestException("Unsupported authorization type " + input.AuthorizationType)
+	}
+	return nil
+}
+
+func validateWebSocketRoute(input CreateRouteInput) *awserrors.Error {
+	if input.RouteKey == "" || len(input.RouteKey) > 128 {
+		return BadRequestException("Invalid route key, must be between 1 and 128 characters.")
+	}
+	// Only $default is supported, which sends the integration's response to the client.
+	if input.RouteResponseSelectionExpression != "" && input.RouteResponseSelectionExpression != "$default" {
+		return BadRequestException("Invalid route response selection expression " + input.RouteResponseSelectionExpression)
+	}
+
+	switch input.AuthorizationType {
+	case "NONE":
+	case "AWS_IAM":
+		// AWS_IAM routes would require signed requests, but credentials aren't checked.
+		if input.RouteKey != connectRouteKey {
+			return BadRequestException("Authorization is only supported for the $connect route.")
+		}
+	default:
+		// TODO: Lambda (CUSTOM) authorizers.
+		return BadRequestException("Unsupported authorization type " + input.AuthorizationType + " for WebSocket APIs")
+	}
+	if input.AuthorizerId != "" || len(input.AuthorizationScopes) > 0 {
+		return BadRequestException("AuthorizerId and AuthorizationScopes can't be specified for WebSocket routes.")
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-routes.html#CreateRoute
+func (a *APIGateway) CreateRoute(input CreateRouteInput) (*CreateRouteOutput, *awserrors.Error) {
+	if input.AuthorizationType == "" {
+		input.AuthorizationType = "NONE"
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if api.Config.ProtocolType == "WEBSOCKET" {
+		awserr = validateWebSocketRoute(input)
+	} else {
+		awserr = validateHttpRoute(input)
+	}
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, route := range api.routes {
+		if route.RouteKey == input.RouteKey {
+			return nil, ConflictException(fmt.Sprintf("Route with key %s already exists for this API", input.RouteKey))
+		}
+	}
+	if input.Target != "" {
+		integrationId, ok := strings.CutPrefix(input.Target, integrationTargetPrefix)
+		if !ok {
+			return nil, BadRequestException("Invalid target " + input.Target + ", must be integrations/{integrationId}")
+		}
+		if _, awserr := api.getIntegration(integrationId); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if input.AuthorizationType == "JWT" {
+		if _, awserr := api.getAuthorizer(input.AuthorizerId); awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	route := &APIRoute{
+		ApiKeyRequired:      input.ApiKeyRequired,
+		AuthorizationScopes: input.AuthorizationScopes,
+		AuthorizationType:   input.AuthorizationType,
+		AuthorizerId:        input.AuthorizerId,
+		OperationName:       input.OperationName,
+		RouteId:             randomId(7),
+		RouteKey:            input.RouteKey,
+		Target:              input.Target,
+
+		RouteResponseSelectionExpression: input.RouteResponseSelectionExpression,
+	}
+	api.routes[route.RouteId] = route
+	return &CreateRouteOutput{StatusCode: 201, APIRoute: *route}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-routes-routeid.html#GetRoute
+func (a *APIGateway) GetRoute(input GetRouteInput) (*GetRouteOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	route, awserr := api.getRoute(input.RouteId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := *route
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-routes.html#GetRoutes
+func (a *APIGateway) GetRoutes(input GetRoutesInput) (*GetRoutesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.ParseString(input.MaxResults, math.MaxInt, math.MaxInt, input.NextToken,
+		BadRequestException("MaxResults must be a positive number."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	routes := sortedValues(api.routes, func(route *APIRoute) string { return route.RouteKey })
+	output := &GetRoutesOutput{}
+	output.Items, output.NextToken = pagination.Page(routes, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-routes-routeid.html#DeleteRoute
+func (a *APIGateway) DeleteRoute(input DeleteRouteInput) (*DeleteRouteOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	route, awserr := api.getRoute(input.RouteId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(api.routes, route.RouteId)
+	return &DeleteRouteOutput{StatusCode: 204}, nil
+}
+
+// newIntegration validates the integration for an API with the protocol type. WebSocket APIs'
+// events are only sent in payload format version 1.0, and HTTP APIs' only in 2.0.
+func newIntegration(protocolType string, input CreateIntegrationInput) (*APIIntegration, *awserrors.Error) {
+	// TODO: HTTP_PROXY and AWS service integrations.
+	if input.IntegrationType != "AWS_PROXY" {
+		return nil, BadRequestException("Unsupported integration type " + input.IntegrationType + ", only AWS_PROXY is supported.")
+	}
+	if _, ok := functionArn(input.IntegrationUri); !ok {
+		return nil, BadRequestException("Invalid integration URI " + input.IntegrationUri + ", must be a Lambda function ARN.")
+	}
+	if input.IntegrationMethod != "" && input.IntegrationMethod != "POST" {
+		return nil, BadRequestException("AWS_PROXY integrations must use the POST method.")
+	}
+	payloadFormatVersion := "2.0"
+	if protocolType == "WEBSOCKET" {
+		payloadFormatVersion = "1.0"
+	}
+	if input.PayloadFormatVersion == "" {
+		input.PayloadFormatVersion = payloadFormatVersion
+	}
+	// TODO: Payload format version 1.0 for HTTP APIs.
+	if input.PayloadFormatVersion != payloadFormatVersion {
+		return nil, BadRequestException("Unsupported payload format version " + input.PayloadFormatVersion + ", only " + payloadFormatVersion + " is supported.")
+	}
+	if input.TimeoutInMillis == 0 {
+		input.TimeoutInMillis = 30000
+	}
+	if input.TimeoutInMillis < 50 || input.TimeoutInMillis > 30000 {
+		return nil, BadRequestException("TimeoutInMillis must be between 50 and 30000.")
+	}
+	if input.ConnectionType == "" {
+		input.ConnectionType = "INTERNET"
+	}
+	if input.ConnectionType != "INTERNET" {
+		return nil, BadRequestException("Unsupported connection type " + input.ConnectionType)
+	}
+
+	return &APIIntegration{
+		ConnectionType:       input.ConnectionType,
+		Description:          input.Description,
+		IntegrationId:        randomId(7),
+		IntegrationMethod:    "POST",
+		IntegrationType:      input.IntegrationType,
+		IntegrationUri:       input.IntegrationUri,
+		PayloadFormatVersion: input.PayloadFormatVersion,
+		TimeoutInMillis:      input.TimeoutInMillis,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-integrations.html#CreateIntegration
+func (a *APIGateway) CreateIntegration(input CreateIntegrationInput) (*CreateIntegrationOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	integration, awserr := newIntegration(api.Config.ProtocolType, input)
+	if awserr != nil {
+		return nil, awserr
+	}
+	api.integrations[integration.IntegrationId] = integration
+	return &CreateIntegrationOutput{StatusCode: 201, APIIntegration: *integration}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-integrations-integrationid.html#GetIntegration
+func (a *APIGateway) GetIntegration(input GetIntegrationInput) (*GetIntegrationOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	integration, awserr := api.getIntegration(input.IntegrationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := *integration
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-integrations.html#GetIntegrations
+func (a *APIGateway) GetIntegrations(input GetIntegrationsInput) (*GetIntegrationsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.ParseString(input.MaxResults, math.MaxInt, math.MaxInt, input.NextToken,
+		BadRequestException("MaxResults must be a positive number."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	integrations := sortedValues(api.integrations, func(integration *APIIntegration) string { return integration.IntegrationId })
+	output := &GetIntegrationsOutput{}
+	output.Items, output.NextToken = pagination.Page(integrations, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-integrations-integrationid.html#DeleteIntegration
+func (a *APIGateway) DeleteIntegration(input DeleteIntegrationInput) (*DeleteIntegrationOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	integration, awserr := api.getIntegration(input.IntegrationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, route := range api.routes {
+		if route.Target == integrationTargetPrefix+integration.IntegrationId {
+			return nil, BadRequestException(fmt.Sprintf("Cannot delete Integration %s, it is used by Route %s", integration.IntegrationId, route.RouteId))
+		}
+	}
+	delete(api.integrations, integration.IntegrationId)
+	return &DeleteIntegrationOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-authorizers.html#CreateAuthorizer
+func (a *APIGateway) CreateAuthorizer(input CreateAuthorizerInput) (*CreateAuthorizerOutput, *awserrors.Error) {
+	if input.Name == "" || len(input.Name) > 128 {
+		return nil, BadRequestException("Invalid authorizer name, must be between 1 and 128 characters.")
+	}
+	// TODO: Lambda (REQUEST) authorizers.
+	if input.AuthorizerType != "JWT" {
+		return nil, BadRequestException("Unsupported authorizer type " + input.AuthorizerType + ", only JWT is supported.")
+	}
+	if len(input.IdentitySource) != 1 || !identitySourceRegex.MatchString(input.IdentitySource[0]) {
+		return nil, BadRequestException("JWT authorizers need a single identity source, such as $request.header.Authorization.")
+	}
+	if input.JwtConfiguration == nil || input.JwtConfiguration.Issuer == "" {
+		return nil, BadRequestException("JWT authorizers need an issuer.")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if api.Config.ProtocolType == "WEBSOCKET" {
+		return nil, BadRequestException("JWT authorizers are only supported for HTTP APIs.")
+	}
+	authorizer := &APIAuthorizer{
+		AuthorizerId:     randomId(6),
+		AuthorizerType:   input.AuthorizerType,
+		IdentitySource:   input.IdentitySource,
+		JwtConfiguration: input.JwtConfiguration,
+		Name:             input.Name,
+	}
+	api.authorizers[authorizer.AuthorizerId] = authorizer
+	return &CreateAuthorizerOutput{StatusCode: 201, APIAuthorizer: *authorizer}, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-authorizers-authorizerid.html#GetAuthorizer
+func (a *APIGateway) GetAuthorizer(input GetAuthorizerInput) (*GetAuthorizerOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	authorizer, awserr := api.getAuthorizer(input.AuthorizerId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := *authorizer
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-authorizers.html#GetAuthorizers
+func (a *APIGateway) GetAuthorizers(input GetAuthorizersInput) (*GetAuthorizersOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.ParseString(input.MaxResults, math.MaxInt, math.MaxInt, input.NextToken,
+		BadRequestException("MaxResults must be a positive number."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	authorizers := sortedValues(api.authorizers, func(authorizer *APIAuthorizer) string { return authorizer.Name })
+	output := &GetAuthorizersOutput{}
+	output.Items, output.NextToken = pagination.Page(authorizers, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-authorizers-authorizerid.html#DeleteAuthorizer
+func (a *APIGateway) DeleteAuthorizer(input DeleteAuthorizerInput) (*DeleteAuthorizerOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	api, awserr := a.lockedGetApi(input.ApiId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	authorizer, awserr := api.getAuthorizer(input.AuthorizerId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, route := range api.routes {
+		if route.AuthorizerId == authorizer.AuthorizerId {
+			return nil, BadRequestException(fmt.Sprintf("Cannot delete Authorizer %s, it is used by Route %s", authorizer.AuthorizerId, route.RouteId))
+		}
+	}
+	delete(api.authorizers, authorizer.AuthorizerId)
+	return &DeleteAuthorizerOutput{StatusCode: 204}, nil
+}
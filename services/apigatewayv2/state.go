@@ -0,0 +1,120 @@
+package apigatewayv2
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type apiState struct {
+	Config    APIApi
+	CreatedAt time.Time
+	// Sorted by ID.
+	Routes       []APIRoute
+	Integrations []APIIntegration
+	Authorizers  []APIAuthorizer
+	// Sorted by name.
+	Stages []APIStage
+}
+
+type apisState struct {
+	Apis []apiState
+}
+
+// TryPause pauses changes to each region's APIs while a snapshot copies them.
+func (a *APIGateway) TryPause() (func(), bool) {
+	return a.regions.TryPause(func(a *APIGateway) *sync.Mutex { return &a.mu })
+}
+
+// ExportState writes each region's APIs with their routes, integrations, authorizers and stages to
+// the archive. WebSocket APIs' connections aren't exported.
+func (a *APIGateway) ExportState(w *state.Writer) error {
+	return a.regions.ExportState(w, (*APIGateway).exportState)
+}
+
+func (a *APIGateway) exportState(w *state.Writer) error {
+	unlock := w.Lock(&a.mu)
+	defer unlock()
+
+	var exported apisState
+	for _, api := range a.apisById {
+		exported.Apis = append(exported.Apis, apiState{
+			Config:       api.Config,
+			CreatedAt:    api.CreatedAt,
+			Routes:       sortedValues(api.routes, func(r *APIRoute) string { return r.RouteId }),
+			Integrations: sortedValues(api.integrations, func(i *APIIntegration) string { return i.IntegrationId }),
+			Authorizers:  sortedValues(api.authorizers, func(a *APIAuthorizer) string { return a.AuthorizerId }),
+			Stages:       sortedValues(api.stages, func(s *APIStage) string { return s.StageName }),
+		})
+	}
+	slices.SortFunc(exported.Apis, func(a, b apiState) int {
+		return strings.Compare(a.Config.ApiId, b.Config.ApiId)
+	})
+	return w.WriteJSON("apis.json", exported)
+}
+
+// ImportState replaces each region's APIs with the archive's, and closes WebSocket APIs'
+// connections. The APIs' endpoints are generated again, for this emulator's address.
+func (a *APIGateway) ImportState(r *state.Reader) (func() error, error) {
+	return a.regions.ImportState(r, (*APIGateway).importState, (*APIGateway).exportState)
+}
+
+func (a *APIGateway) importState(r *state.Reader) (func() error, error) {
+	var imported apisState
+	if err := r.ReadJSON("apis.json", &imported); err != nil {
+		return nil, err
+	}
+	apisById := make(map[string]*Api)
+	for _, s := range imported.Apis {
+		if s.Config.ApiId == "" || !apiNameRegex.MatchString(s.Config.Name) {
+			return nil, fmt.Errorf("invalid API %q", s.Config.ApiId)
+		}
+		if s.Config.ProtocolType == "WEBSOCKET" {
+			if _, ok := parseRouteSelectionExpression(s.Config.RouteSelectionExpression); !ok {
+				return nil, fmt.Errorf("API %s: invalid route selection expression %q", s.Config.ApiId, s.Config.RouteSelectionExpression)
+			}
+		}
+		api := &Api{
+			Config:       s.Config,
+			CreatedAt:    s.CreatedAt,
+			routes:       make(map[string]*APIRoute),
+			integrations: make(map[string]*APIIntegration),
+			authorizers:  make(map[string]*APIAuthorizer),
+			stages:       make(map[string]*APIStage),
+		}
+		api.Config.ApiEndpoint = a.apiEndpoint(api.Config.ApiId, api.Config.ProtocolType)
+		for i := range s.Routes {
+			api.routes[s.Routes[i].RouteId] = &s.Routes[i]
+		}
+		for i := range s.Integrations {
+			api.integrations[s.Integrations[i].IntegrationId] = &s.Integrations[i]
+		}
+		for i := range s.Authorizers {
+			api.authorizers[s.Authorizers[i].AuthorizerId] = &s.Authorizers[i]
+		}
+		for i := range s.Stages {
+			api.stages[s.Stages[i].StageName] = &s.Stages[i]
+		}
+		apisById[api.Config.ApiId] = api
+	}
+
+	return func() error {
+		a.mu.Lock()
+		var connections []*connection
+		for id := range a.apisById {
+			connections = append(connections, a.lockedConnections(id)...)
+		}
+		a.apisById = apisById
+		a.mu.Unlock()
+
+		// Closing the connections runs their $disconnect routes, which are gone too.
+		for _, connection := range connections {
+			connection.ws.Close()
+		}
+		return nil
+	}, nil
+}
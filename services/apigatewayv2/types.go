@@ -0,0 +1,349 @@
+package apigatewayv2
+
+// Fields are camelCase, and timestamps are ISO 8601 strings, unlike most REST-JSON services.
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid.html#apis-apiid-model-cors
+type APICors struct {
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	AllowHeaders     []string `json:"allowHeaders,omitempty"`
+	AllowMethods     []string `json:"allowMethods,omitempty"`
+	AllowOrigins     []string `json:"allowOrigins,omitempty"`
+	ExposeHeaders    []string `json:"exposeHeaders,omitempty"`
+	MaxAge           int32    `json:"maxAge,omitempty"`
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid.html#apis-apiid-model-api
+type APIApi struct {
+	ApiEndpoint               string            `json:"apiEndpoint"`
+	ApiId                     string            `json:"apiId"`
+	ApiKeySelectionExpression string            `json:"apiKeySelectionExpression"`
+	CorsConfiguration         *APICors          `json:"corsConfiguration,omitempty"`
+	CreatedDate               string            `json:"createdDate"`
+	Description               string            `json:"description,omitempty"`
+	DisableExecuteApiEndpoint bool              `json:"disableExecuteApiEndpoint"`
+	Name                      string            `json:"name"`
+	ProtocolType              string            `json:"protocolType"`
+	RouteSelectionExpression  string            `json:"routeSelectionExpression"`
+	Tags                      map[string]string `json:"tags,omitempty"`
+	Version                   string            `json:"version,omitempty"`
+}
+
+type CreateApiInput struct {
+	Name                      string            `json:"name" required:"true" length:"1,128" error:"BadRequestException"`
+	ProtocolType              string            `json:"protocolType" required:"true" enum:"HTTP|WEBSOCKET" error:"BadRequestException"`
+	Description               string            `json:"description" length:",1024" error:"BadRequestException"`
+	CorsConfiguration         *APICors          `json:"corsConfiguration"`
+	RouteSelectionExpression  string            `json:"routeSelectionExpression"`
+	ApiKeySelectionExpression string            `json:"apiKeySelectionExpression"`
+	DisableExecuteApiEndpoint bool              `json:"disableExecuteApiEndpoint"`
+	DisableSchemaValidation   bool              `json:"disableSchemaValidation"`
+	CredentialsArn            string            `json:"credentialsArn" length:",2048" error:"BadRequestException"`
+	Tags                      map[string]string `json:"tags" length:",50" error:"BadRequestException"`
+	Version                   string            `json:"version" length:"1,64" error:"BadRequestException"`
+	// For quick create, which creates a route with the key to an integration with the target,
+	// and the $default stage.
+	RouteKey string `json:"routeKey"`
+	Target   string `json:"target"`
+}
+
+type CreateApiOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+	APIApi
+}
+
+type GetApiInput struct {
+	ApiId string `json:"-" rest:"path:ApiId"`
+}
+
+type GetApiOutput = APIApi
+
+type GetApisInput struct {
+	MaxResults string `json:"-" rest:"query:maxResults"`
+	NextToken  string `json:"-" rest:"query:nextToken"`
+}
+
+type GetApisOutput struct {
+	Items     []APIApi `json:"items"`
+	NextToken string   `json:"nextToken,omitempty"`
+}
+
+type DeleteApiInput struct {
+	ApiId string `json:"-" rest:"path:ApiId"`
+}
+
+type DeleteApiOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-routes-routeid.html#apis-apiid-routes-routeid-model-route
+type APIRoute struct {
+	ApiGatewayManaged   bool     `json:"apiGatewayManaged,omitempty"`
+	ApiKeyRequired      bool     `json:"apiKeyRequired"`
+	AuthorizationScopes []string `json:"authorizationScopes,omitempty"`
+	// NONE, JWT or AWS_IAM.
+	AuthorizationType string `json:"authorizationType"`
+	AuthorizerId      string `json:"authorizerId,omitempty"`
+	OperationName     string `json:"operationName,omitempty"`
+	RouteId           string `json:"routeId"`
+	// $default, or a method and path such as "GET /pets/{petId}". WebSocket APIs' route keys are
+	// $connect, $disconnect, $default, or a value of their route selection expression.
+	RouteKey string `json:"routeKey"`
+	// $default for WebSocket routes whose integration's response is sent to the client.
+	RouteResponseSelectionExpression string `json:"routeResponseSelectionExpression,omitempty"`
+	// integrations/<integrationId>.
+	Target string `json:"target,omitempty"`
+}
+
+type CreateRouteInput struct {
+	ApiId                            string   `json:"-" rest:"path:ApiId"`
+	ApiKeyRequired                   bool     `json:"apiKeyRequired"`
+	AuthorizationScopes              []string `json:"authorizationScopes"`
+	AuthorizationType                string   `json:"authorizationType" enum:"NONE|AWS_IAM|CUSTOM|JWT" error:"BadRequestException"`
+	AuthorizerId                     string   `json:"authorizerId" length:",64" error:"BadRequestException"`
+	OperationName                    string   `json:"operationName" length:",128" error:"BadRequestException"`
+	RouteKey                         string   `json:"routeKey" required:"true" error:"BadRequestException"`
+	RouteResponseSelectionExpression string   `json:"routeResponseSelectionExpression"`
+	Target                           string   `json:"target"`
+}
+
+type CreateRouteOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+	APIRoute
+}
+
+type GetRouteInput struct {
+	ApiId   string `json:"-" rest:"path:ApiId"`
+	RouteId string `json:"-" rest:"path:RouteId"`
+}
+
+type GetRouteOutput = APIRoute
+
+type GetRoutesInput struct {
+	ApiId      string `json:"-" rest:"path:ApiId"`
+	MaxResults string `json:"-" rest:"query:maxResults"`
+	NextToken  string `json:"-" rest:"query:nextToken"`
+}
+
+type GetRoutesOutput struct {
+	Items     []APIRoute `json:"items"`
+	NextToken string     `json:"nextToken,omitempty"`
+}
+
+type DeleteRouteInput struct {
+	ApiId   string `json:"-" rest:"path:ApiId"`
+	RouteId string `json:"-" rest:"path:RouteId"`
+}
+
+type DeleteRouteOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-integrations-integrationid.html#apis-apiid-integrations-integrationid-model-integration
+type APIIntegration struct {
+	ApiGatewayManaged bool   `json:"apiGatewayManaged,omitempty"`
+	ConnectionType    string `json:"connectionType"`
+	Description       string `json:"description,omitempty"`
+	IntegrationId     string `json:"integrationId"`
+	IntegrationMethod string `json:"integrationMethod,omitempty"`
+	// Only AWS_PROXY, which invokes a Lambda function.
+	IntegrationType string `json:"integrationType"`
+	// The function's ARN, or its invocation ARN.
+	IntegrationUri       string `json:"integrationUri"`
+	PayloadFormatVersion string `json:"payloadFormatVersion"`
+	TimeoutInMillis      int    `json:"timeoutInMillis"`
+}
+
+type CreateIntegrationInput struct {
+	ApiId                string `json:"-" rest:"path:ApiId"`
+	ConnectionType       string `json:"connectionType" enum:"INTERNET|VPC_LINK" error:"BadRequestException"`
+	CredentialsArn       string `json:"credentialsArn" length:",2048" error:"BadRequestException"`
+	Description          string `json:"description" length:",1024" error:"BadRequestException"`
+	IntegrationMethod    string `json:"integrationMethod" enum:"ANY|GET|HEAD|POST|PUT|PATCH|DELETE|OPTIONS" error:"BadRequestException"`
+	IntegrationType      string `json:"integrationType" required:"true" enum:"AWS|HTTP|MOCK|HTTP_PROXY|AWS_PROXY" error:"BadRequestException"`
+	IntegrationUri       string `json:"integrationUri"`
+	PayloadFormatVersion string `json:"payloadFormatVersion" enum:"1.0|2.0" error:"BadRequestException"`
+	TimeoutInMillis      int    `json:"timeoutInMillis" range:"50,30000" error:"BadRequestException"`
+}
+
+type CreateIntegrationOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+	APIIntegration
+}
+
+type GetIntegrationInput struct {
+	ApiId         string `json:"-" rest:"path:ApiId"`
+	IntegrationId string `json:"-" rest:"path:IntegrationId"`
+}
+
+type GetIntegrationOutput = APIIntegration
+
+type GetIntegrationsInput struct {
+	ApiId      string `json:"-" rest:"path:ApiId"`
+	MaxResults string `json:"-" rest:"query:maxResults"`
+	NextToken  string `json:"-" rest:"query:nextToken"`
+}
+
+type GetIntegrationsOutput struct {
+	Items     []APIIntegration `json:"items"`
+	NextToken string           `json:"nextToken,omitempty"`
+}
+
+type DeleteIntegrationInput struct {
+	ApiId         string `json:"-" rest:"path:ApiId"`
+	IntegrationId string `json:"-" rest:"path:IntegrationId"`
+}
+
+type DeleteIntegrationOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-stages-stagename.html#apis-apiid-stages-stagename-model-stage
+type APIStage struct {
+	ApiGatewayManaged bool              `json:"apiGatewayManaged,omitempty"`
+	AutoDeploy        bool              `json:"autoDeploy"`
+	CreatedDate       string            `json:"createdDate"`
+	DeploymentId      string            `json:"deploymentId,omitempty"`
+	Description       string            `json:"description,omitempty"`
+	LastUpdatedDate   string            `json:"lastUpdatedDate"`
+	StageName         string            `json:"stageName"`
+	StageVariables    map[string]string `json:"stageVariables,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+}
+
+type CreateStageInput struct {
+	ApiId          string            `json:"-" rest:"path:ApiId"`
+	AutoDeploy     bool              `json:"autoDeploy"`
+	DeploymentId   string            `json:"deploymentId"`
+	Description    string            `json:"description" length:",1024" error:"BadRequestException"`
+	StageName      string            `json:"stageName" required:"true" length:",128" error:"BadRequestException"`
+	StageVariables map[string]string `json:"stageVariables"`
+	Tags           map[string]string `json:"tags" length:",50" error:"BadRequestException"`
+}
+
+type CreateStageOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+	APIStage
+}
+
+type GetStageInput struct {
+	ApiId     string `json:"-" rest:"path:ApiId"`
+	StageName string `json:"-" rest:"path:StageName"`
+}
+
+type GetStageOutput = APIStage
+
+type GetStagesInput struct {
+	ApiId      string `json:"-" rest:"path:ApiId"`
+	MaxResults string `json:"-" rest:"query:maxResults"`
+	NextToken  string `json:"-" rest:"query:nextToken"`
+}
+
+type GetStagesOutput struct {
+	Items     []APIStage `json:"items"`
+	NextToken string     `json:"nextToken,omitempty"`
+}
+
+type DeleteStageInput struct {
+	ApiId     string `json:"-" rest:"path:ApiId"`
+	StageName string `json:"-" rest:"path:StageName"`
+}
+
+type DeleteStageOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-authorizers-authorizerid.html#apis-apiid-authorizers-authorizerid-model-jwtconfiguration
+type APIJWTConfiguration struct {
+	Audience []string `json:"audience,omitempty" length:",50" error:"BadRequestException"`
+	Issuer   string   `json:"issuer,omitempty"`
+}
+
+// https://docs.aws.amazon.com/apigatewayv2/latest/api-reference/apis-apiid-authorizers-authorizerid.html#apis-apiid-authorizers-authorizerid-model-authorizer
+type APIAuthorizer struct {
+	AuthorizerId string `json:"authorizerId"`
+	// Only JWT.
+	AuthorizerType string `json:"authorizerType"`
+	// Where the token is, such as $request.header.Authorization.
+	IdentitySource   []string             `json:"identitySource"`
+	JwtConfiguration *APIJWTConfiguration `json:"jwtConfiguration,omitempty"`
+	Name             string               `json:"name"`
+}
+
+type CreateAuthorizerInput struct {
+	ApiId            string               `json:"-" rest:"path:ApiId"`
+	AuthorizerType   string               `json:"authorizerType" required:"true" enum:"REQUEST|JWT" error:"BadRequestException"`
+	IdentitySource   []string             `json:"identitySource"`
+	JwtConfiguration *APIJWTConfiguration `json:"jwtConfiguration"`
+	Name             string               `json:"name" required:"true" length:"1,128" error:"BadRequestException"`
+}
+
+type CreateAuthorizerOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+	APIAuthorizer
+}
+
+type GetAuthorizerInput struct {
+	ApiId        string `json:"-" rest:"path:ApiId"`
+	AuthorizerId string `json:"-" rest:"path:AuthorizerId"`
+}
+
+type GetAuthorizerOutput = APIAuthorizer
+
+type GetAuthorizersInput struct {
+	ApiId      string `json:"-" rest:"path:ApiId"`
+	MaxResults string `json:"-" rest:"query:maxResults"`
+	NextToken  string `json:"-" rest:"query:nextToken"`
+}
+
+type GetAuthorizersOutput struct {
+	Items     []APIAuthorizer `json:"items"`
+	NextToken string          `json:"nextToken,omitempty"`
+}
+
+type DeleteAuthorizerInput struct {
+	ApiId        string `json:"-" rest:"path:ApiId"`
+	AuthorizerId string `json:"-" rest:"path:AuthorizerId"`
+}
+
+type DeleteAuthorizerOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+// The API Gateway Management API, which WebSocket APIs' backends use to reach their clients.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-how-to-call-websocket-api-connections.html
+
+type APIIdentity struct {
+	SourceIp  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+type PostToConnectionInput struct {
+	ApiId        string `json:"-" rest:"path:ApiId"`
+	Stage        string `json:"-" rest:"path:Stage"`
+	ConnectionId string `json:"-" rest:"path:ConnectionId"`
+	Data         []byte `json:"-" rest:"body"`
+}
+
+type PostToConnectionOutput struct{}
+
+type GetConnectionInput struct {
+	ApiId        string `json:"-" rest:"path:ApiId"`
+	Stage        string `json:"-" rest:"path:Stage"`
+	ConnectionId string `json:"-" rest:"path:ConnectionId"`
+}
+
+type GetConnectionOutput struct {
+	ConnectedAt  string      `json:"connectedAt"`
+	Identity     APIIdentity `json:"identity"`
+	LastActiveAt string      `json:"lastActiveAt"`
+}
+
+type DeleteConnectionInput struct {
+	ApiId        string `json:"-" rest:"path:ApiId"`
+	Stage        string `json:"-" rest:"path:Stage"`
+	ConnectionId string `json:"-" rest:"path:ConnectionId"`
+}
+
+type DeleteConnectionOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
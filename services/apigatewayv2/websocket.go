@@ -0,0 +1,490 @@
+package apigatewayv2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+	"golang.org/x/net/websocket"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/random"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	connectRouteKey    = "$connect"
+	disconnectRouteKey = "$disconnect"
+
+	// https://docs.aws.amazon.com/apigateway/latest/developerguide/limits.html#apigateway-execution-service-websocket-limits-table
+	maxWebSocketMessageSize = 128 * 1024
+)
+
+// connection is a client's connection to a WebSocket API's stage.
+type connection struct {
+	id    string
+	apiId string
+	stage string
+	// Snapshots of the API and stage when the client connected.
+	routeSelectionExpression string
+	stageVariables           map[string]string
+
+	domainName  string
+	sourceIp    string
+	userAgent   string
+	connectedAt time.Time
+	// Safe for concurrent use, so PostToConnection can send while messages are received.
+	ws *websocket.Conn
+
+	// Guarded by APIGateway.mu.
+	lastActiveAt time.Time
+	// Whether DeleteConnection or DeleteApi closed the connection, rather than the client.
+	closedByServer bool
+}
+
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-websocket-api-mapping-template-reference.html
+type webSocketEvent struct {
+	Headers                         map[string]string       `json:"headers,omitempty"`
+	MultiValueHeaders               map[string][]string     `json:"multiValueHeaders,omitempty"`
+	QueryStringParameters           map[string]string       `json:"queryStringParameters,omitempty"`
+	MultiValueQueryStringParameters map[string][]string     `json:"multiValueQueryStringParameters,omitempty"`
+	RequestContext                  webSocketRequestContext `json:"requestContext"`
+	Body                            string                  `json:"body,omitempty"`
+	IsBase64Encoded                 bool                    `json:"isBase64Encoded"`
+	StageVariables                  map[string]string       `json:"stageVariables,omitempty"`
+}
+
+type webSocketRequestContext struct {
+	ApiId                string      `json:"apiId"`
+	ConnectedAt          int64       `json:"connectedAt"`
+	ConnectionId         string      `json:"connectionId"`
+	DisconnectReason     string      `json:"disconnectReason,omitempty"`
+	DisconnectStatusCode int         `json:"disconnectStatusCode,omitempty"`
+	DomainName           string      `json:"domainName"`
+	EventType            string      `json:"eventType"`
+	ExtendedRequestId    string      `json:"extendedRequestId"`
+	Identity             APIIdentity `json:"identity"`
+	MessageDirection     string      `json:"messageDirection"`
+	MessageId            string      `json:"messageId,omitempty"`
+	RequestId            string      `json:"requestId"`
+	RequestTime          string      `json:"requestTime"`
+	RequestTimeEpoch     int64       `json:"requestTimeEpoch"`
+	RouteKey             string      `json:"routeKey"`
+	Stage                string      `json:"stage"`
+}
+
+// webSocketTarget is the route a WebSocket event is for, and the function its integration invokes,
+// if it has one.
+type webSocketTarget struct {
+	route       APIRoute
+	functionArn string
+}
+
+// webSocketMessage is a text or binary message from a client.
+type webSocketMessage struct {
+	data   []byte
+	binary bool
+}
+
+var messageCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v any) error {
+		message := v.(*webSocketMessage)
+		message.data = data
+		message.binary = payloadType == websocket.BinaryFrame
+		return nil
+	},
+}
+
+// newConnectionId returns a random ID, like API Gateway's connection and message IDs, which are
+// 11 bytes in URL-safe base64.
+func newConnectionId() string {
+	return random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_", 15) + "="
+}
+
+// parseRouteSelectionExpression returns the path of the field WebSocket messages are routed by,
+// for expressions like $request.body.action or ${request.body.action}.
+func parseRouteSelectionExpression(expression string) ([]string, bool) {
+	if inner, ok := strings.CutPrefix(expression, "${"); ok {
+		inner, ok = strings.CutSuffix(inner, "}")
+		if !ok {
+			return nil, false
+		}
+		expression = "$" + inner
+	}
+	field, ok := strings.CutPrefix(expression, "$request.body.")
+	if !ok {
+		return nil, false
+	}
+	path := strings.Split(field, ".")
+	if slices.Contains(path, "") {
+		return nil, false
+	}
+	return path, true
+}
+
+// selectRouteKey evaluates the route selection expression against a message, returning
+// an empty key if the message isn't JSON or the field isn't a string.
+func selectRouteKey(expression string, body []byte) string {
+	path, _ := parseRouteSelectionExpression(expression)
+	var value any
+	if json.Unmarshal(body, &value) != nil {
+		return ""
+	}
+	for _, name := range path {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = fields[name]
+	}
+	routeKey, _ := value.(string)
+	return routeKey
+}
+
+// webSocketResponse returns the part of an integration's response which is sent to the client,
+// or nil if there is none.
+func webSocketResponse(payload []byte) []byte {
+	var response struct {
+		Body *string `json:"body"`
+	}
+	if json.Unmarshal(payload, &response) == nil && response.Body != nil {
+		return []byte(*response.Body)
+	}
+	var s string
+	if json.Unmarshal(payload, &s) == nil {
+		return []byte(s)
+	}
+	if len(payload) == 0 || bytes.Equal(bytes.TrimSpace(payload), []byte("null")) {
+		return nil
+	}
+	return payload
+}
+
+func (c *connection) event(now time.Time, eventType string) webSocketEvent {
+	requestId := uuid.Must(uuid.NewV4()).String()
+	return webSocketEvent{
+		RequestContext: webSocketRequestContext{
+			ApiId:             c.apiId,
+			ConnectedAt:       c.connectedAt.UnixMilli(),
+			ConnectionId:      c.id,
+			DomainName:        c.domainName,
+			EventType:         eventType,
+			ExtendedRequestId: requestId,
+			Identity: APIIdentity{
+				SourceIp:  c.sourceIp,
+				UserAgent: c.userAgent,
+			},
+			MessageDirection: "IN",
+			RequestId:        requestId,
+			RequestTime:      now.UTC().Format(httpEventTimeFormat),
+			RequestTimeEpoch: now.UnixMilli(),
+			Stage:            c.stage,
+		},
+		StageVariables: c.stageVariables,
+	}
+}
+
+// send sends data to the client, as a text message if it's UTF-8.
+func (c *connection) send(data []byte) error {
+	if utf8.Valid(data) {
+		return websocket.Message.Send(c.ws, string(data))
+	}
+	return websocket.Message.Send(c.ws, data)
+}
+
+// sendError tells the client its message couldn't be handled, the way API Gateway does.
+func (c *connection) sendError(message string, requestId string) {
+	data, _ := json.Marshal(map[string]string{
+		"message":      message,
+		"connectionId": c.id,
+		"requestId":    requestId,
+	})
+	c.send(data)
+}
+
+// lockedWebSocketTarget returns the target of the API's route with the key, or nil if there is no such route.
+func (api *Api) lockedWebSocketTarget(routeKey string) *webSocketTarget {
+	for _, route := range api.routes {
+		if route.RouteKey != routeKey {
+			continue
+		}
+		target := &webSocketTarget{route: *route}
+		if integration, ok := api.integrations[strings.TrimPrefix(route.Target, integrationTargetPrefix)]; ok {
+			target.functionArn, _ = functionArn(integration.IntegrationUri)
+		}
+		return target
+	}
+	return nil
+}
+
+// lockedConnections returns the API's open connections for the caller to close, marking them
+// as closed by the server.
+func (a *APIGateway) lockedConnections(apiId string) []*connection {
+	var connections []*connection
+	for _, connection := range a.connectionsById {
+		if connection.apiId == apiId {
+			connection.closedByServer = true
+			connections = append(connections, connection)
+		}
+	}
+	return connections
+}
+
+// invokeWebSocketTarget invokes the target's function with the event, returning its response,
+// or false if it failed. Routes without an integration succeed without a response.
+func (a *APIGateway) invokeWebSocketTarget(target *webSocketTarget, event webSocketEvent) ([]byte, bool) {
+	if target.functionArn == "" {
+		return nil, true
+	}
+	payload, _ := json.Marshal(event)
+	return a.invokeFunction(target.functionArn, payload)
+}
+
+// serveWebSocket connects a client to a WebSocket API's stage, at the API's endpoint followed by
+// the stage's name. The $connect route can reject the connection.
+func (a *APIGateway) serveWebSocket(w http.ResponseWriter, r *http.Request, apiId string, path string) {
+	segments := splitPath(path)
+	a.mu.Lock()
+	api, ok := a.apisById[apiId]
+	var stage *APIStage
+	if ok && len(segments) == 1 {
+		stage = api.stages[segments[0]]
+	}
+	if stage == nil {
+		a.mu.Unlock()
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		a.mu.Unlock()
+		writeMessage(w, http.StatusUpgradeRequired, "Upgrade Required")
+		return
+	}
+	now := a.clock()
+	sourceIp, _, _ := net.SplitHostPort(r.RemoteAddr)
+	conn := &connection{
+		id:                       newConnectionId(),
+		apiId:                    apiId,
+		stage:                    stage.StageName,
+		routeSelectionExpression: api.Config.RouteSelectionExpression,
+		stageVariables:           stage.StageVariables,
+		domainName:               r.Host,
+		sourceIp:                 sourceIp,
+		userAgent:                r.UserAgent(),
+		connectedAt:              now,
+		lastActiveAt:             now,
+	}
+	target := api.lockedWebSocketTarget(connectRouteKey)
+	a.mu.Unlock()
+
+	if target != nil {
+		event := conn.event(now, "CONNECT")
+		event.RequestContext.RouteKey = connectRouteKey
+		event.Headers = map[string]string{"Host": r.Host}
+		event.MultiValueHeaders = map[string][]string{"Host": {r.Host}}
+		for name, values := range r.Header {
+			event.Headers[name] = strings.Join(values, ",")
+			event.MultiValueHeaders[name] = values
+		}
+		if query := r.URL.Query(); len(query) > 0 {
+			event.QueryStringParameters = make(map[string]string)
+			event.MultiValueQueryStringParameters = query
+			for name, values := range query {
+				event.QueryStringParameters[name] = values[len(values)-1]
+			}
+		}
+
+		payload, ok := a.invokeWebSocketTarget(target, event)
+		if !ok {
+			writeMessage(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		var response struct {
+			StatusCode int `json:"statusCode"`
+		}
+		json.Unmarshal(payload, &response)
+		if response.StatusCode >= 300 {
+			writeMessage(w, response.StatusCode, http.StatusText(response.StatusCode))
+			return
+		}
+	}
+
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			a.handleConnection(conn, ws)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// handleConnection routes the client's messages until it disconnects, then runs the $disconnect route.
+func (a *APIGateway) handleConnection(conn *connection, ws *websocket.Conn) {
+	ws.MaxPayloadBytes = maxWebSocketMessageSize
+	a.mu.Lock()
+	conn.ws = ws
+	a.connectionsById[conn.id] = conn
+	a.mu.Unlock()
+	a.logger.Debug("WebSocket connected", "apiId", conn.apiId, "connectionId", conn.id)
+
+	// https://www.rfc-editor.org/rfc/rfc6455#section-7.4.1
+	var err error
+	for err == nil {
+		var message webSocketMessage
+		if err = messageCodec.Receive(ws, &message); err == nil {
+			a.handleMessage(conn, message)
+		}
+	}
+	statusCode, reason := 1006, err.Error()
+	if errors.Is(err, io.EOF) {
+		statusCode, reason = 1000, ""
+	} else if errors.Is(err, websocket.ErrFrameTooLarge) {
+		statusCode, reason = 1009, "Message too big"
+	}
+
+	a.mu.Lock()
+	delete(a.connectionsById, conn.id)
+	if conn.closedByServer {
+		statusCode, reason = 1001, "Going away"
+	}
+	var target *webSocketTarget
+	if api, ok := a.apisById[conn.apiId]; ok {
+		target = api.lockedWebSocketTarget(disconnectRouteKey)
+	}
+	a.mu.Unlock()
+	a.logger.Debug("WebSocket disconnected", "apiId", conn.apiId, "connectionId", conn.id, "statusCode", statusCode)
+
+	if target != nil {
+		event := conn.event(a.clock(), "DISCONNECT")
+		event.RequestContext.RouteKey = disconnectRouteKey
+		event.RequestContext.DisconnectStatusCode = statusCode
+		event.RequestContext.DisconnectReason = reason
+		a.invokeWebSocketTarget(target, event)
+	}
+}
+
+// handleMessage invokes the route the message is for, which is $default if the route selection
+// expression doesn't select one of the API's routes.
+func (a *APIGateway) handleMessage(conn *connection, message webSocketMessage) {
+	now := a.clock()
+	event := conn.event(now, "MESSAGE")
+	event.RequestContext.MessageId = newConnectionId()
+	var routeKey string
+	if message.binary {
+		event.Body = base64.StdEncoding.EncodeToString(message.data)
+		event.IsBase64Encoded = true
+	} else {
+		event.Body = string(message.data)
+		routeKey = selectRouteKey(conn.routeSelectionExpression, message.data)
+	}
+
+	a.mu.Lock()
+	conn.lastActiveAt = now
+	var target *webSocketTarget
+	if api, ok := a.apisById[conn.apiId]; ok {
+		if routeKey != "" && routeKey != connectRouteKey && routeKey != disconnectRouteKey {
+			target = api.lockedWebSocketTarget(routeKey)
+		}
+		if target == nil {
+			target = api.lockedWebSocketTarget(defaultRouteKey)
+		}
+	}
+	a.mu.Unlock()
+
+	requestId := event.RequestContext.RequestId
+	if target == nil {
+		conn.sendError("Forbidden", requestId)
+		return
+	}
+	event.RequestContext.RouteKey = target.route.RouteKey
+	payload, ok := a.invokeWebSocketTarget(target, event)
+	if !ok {
+		conn.sendError("Internal server error", requestId)
+		return
+	}
+	// Routes with a route response selection expression send the integration's response to the client.
+	if target.route.RouteResponseSelectionExpression != "" {
+		if response := webSocketResponse(payload); response != nil {
+			conn.send(response)
+		}
+	}
+}
+
+// The API Gateway Management API's operations are called at a stage's endpoint, so they're only
+// for the stage's connections.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-how-to-call-websocket-api-connections.html
+
+func (a *APIGateway) lockedGetConnection(apiId string, stage string, connectionId string) (*connection, *awserrors.Error) {
+	if api, ok := a.apisById[apiId]; !ok || api.Config.ProtocolType != "WEBSOCKET" {
+		return nil, ForbiddenException("Forbidden")
+	}
+	conn, ok := a.connectionsById[connectionId]
+	if !ok || conn.apiId != apiId || conn.stage != stage {
+		return nil, GoneException("Connection " + connectionId + " is gone")
+	}
+	return conn, nil
+}
+
+// https://docs.aws.amazon.com/apigateway/latest/api/API_PostToConnection.html
+func (a *APIGateway) PostToConnection(input PostToConnectionInput) (*PostToConnectionOutput, *awserrors.Error) {
+	if len(input.Data) > maxWebSocketMessageSize {
+		return nil, PayloadTooLargeException("Message must be smaller than 131072 bytes")
+	}
+
+	a.mu.Lock()
+	conn, awserr := a.lockedGetConnection(input.ApiId, input.Stage, input.ConnectionId)
+	if awserr != nil {
+		a.mu.Unlock()
+		return nil, awserr
+	}
+	conn.lastActiveAt = a.clock()
+	a.mu.Unlock()
+
+	if err := conn.send(input.Data); err != nil {
+		return nil, GoneException("Connection " + input.ConnectionId + " is gone")
+	}
+	return &PostToConnectionOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/apigateway/latest/api/API_GetConnection.html
+func (a *APIGateway) GetConnection(input GetConnectionInput) (*GetConnectionOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn, awserr := a.lockedGetConnection(input.ApiId, input.Stage, input.ConnectionId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetConnectionOutput{
+		ConnectedAt: timestamp.ISO8601(conn.connectedAt),
+		Identity: APIIdentity{
+			SourceIp:  conn.sourceIp,
+			UserAgent: conn.userAgent,
+		},
+		LastActiveAt: timestamp.ISO8601(conn.lastActiveAt),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/apigateway/latest/api/API_DeleteConnection.html
+func (a *APIGateway) DeleteConnection(input DeleteConnectionInput) (*DeleteConnectionOutput, *awserrors.Error) {
+	a.mu.Lock()
+	conn, awserr := a.lockedGetConnection(input.ApiId, input.Stage, input.ConnectionId)
+	if awserr != nil {
+		a.mu.Unlock()
+		return nil, awserr
+	}
+	conn.closedByServer = true
+	a.mu.Unlock()
+
+	// Closing the connection ends its handler, which runs the $disconnect route.
+	conn.ws.Close()
+	return &DeleteConnectionOutput{StatusCode: 204}, nil
+}
@@ -0,0 +1,234 @@
+package apigatewayv2
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const functionArnPrefix = "arn:aws:lambda:us-east-1:123456789012:function:"
+
+func newWebSocketServer(t *testing.T, fake *fakeLambda) (*APIGateway, *httptest.Server, string) {
+	a := newAPIGateway(Options{Lambda: fake})
+	apiId := createApi(t, a, CreateApiInput{
+		Name:                     "chat",
+		ProtocolType:             "WEBSOCKET",
+		RouteSelectionExpression: "$request.body.action",
+	})
+	for _, routeKey := range []string{"$connect", "$disconnect", "$default", "echo"} {
+		integrationId := createIntegration(t, a, apiId, functionArnPrefix+strings.Trim(routeKey, "$"))
+		input := CreateRouteInput{
+			ApiId:    apiId,
+			RouteKey: routeKey,
+			Target:   "integrations/" + integrationId,
+		}
+		if routeKey == "echo" {
+			input.RouteResponseSelectionExpression = "$default"
+		}
+		createRoute(t, a, input)
+	}
+	if _, awserr := a.CreateStage(CreateStageInput{ApiId: apiId, StageName: "prod"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	handler := NewHandler(slog.Default(), a, nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !handler(w, r) {
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return a, server, apiId
+}
+
+func dial(t *testing.T, server *httptest.Server, apiId string) *websocket.Conn {
+	url := strings.Replace(server.URL, "http://", "ws://", 1) + "/execute-api/" + apiId + "/prod"
+	ws, err := websocket.Dial(url, "", "http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func receive(t *testing.T, ws *websocket.Conn) map[string]any {
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message string
+	if err := websocket.Message.Receive(ws, &message); err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(message), &fields); err != nil {
+		t.Fatal(err, message)
+	}
+	return fields
+}
+
+func waitForInvocation(t *testing.T, fake *fakeLambda, function string) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if slices.Contains(fake.invocations(), function) {
+			return
+		}
+	}
+	t.Fatal("Not invoked", function, fake.invocations())
+}
+
+func TestWebSocketRoutes(t *testing.T) {
+	fake := &fakeLambda{}
+	_, server, apiId := newWebSocketServer(t, fake)
+	ws := dial(t, server, apiId)
+	if invoked := fake.invocations(); len(invoked) != 1 || invoked[0] != functionArnPrefix+"connect" {
+		t.Fatal("Unexpected invocations", invoked)
+	}
+
+	// Only the echo route sends its integration's response back.
+	websocket.Message.Send(ws, `{"action":"typing"}`)
+	websocket.Message.Send(ws, `{"action":"echo","text":"hi"}`)
+	event := receive(t, ws)
+	requestContext := event["requestContext"].(map[string]any)
+	if event["body"] != `{"action":"echo","text":"hi"}` || requestContext["routeKey"] != "echo" || requestContext["eventType"] != "MESSAGE" || requestContext["stage"] != "prod" {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+	if invoked := fake.invocations(); len(invoked) != 3 || invoked[1] != functionArnPrefix+"default" {
+		t.Fatal("Unexpected invocations", invoked)
+	}
+
+	ws.Close()
+	waitForInvocation(t, fake, functionArnPrefix+"disconnect")
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	fake := &fakeLambda{}
+	a, server, apiId := newWebSocketServer(t, fake)
+	ws := dial(t, server, apiId)
+	fake.mu.Lock()
+	fake.fail = true
+	fake.mu.Unlock()
+	websocket.Message.Send(ws, `{"action":"echo"}`)
+	if message := receive(t, ws); message["message"] != "Internal server error" || message["connectionId"] == "" {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	// Without a $default route, messages for other routes are forbidden.
+	routes, _ := a.GetRoutes(GetRoutesInput{ApiId: apiId})
+	for _, route := range routes.Items {
+		if route.RouteKey == "$default" {
+			a.DeleteRoute(DeleteRouteInput{ApiId: apiId, RouteId: route.RouteId})
+		}
+	}
+	websocket.Message.Send(ws, `not json`)
+	if message := receive(t, ws); message["message"] != "Forbidden" {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	// $connect can reject connections.
+	fake.mu.Lock()
+	fake.fail = false
+	fake.statusCode = 401
+	fake.mu.Unlock()
+	url := strings.Replace(server.URL, "http://", "ws://", 1) + "/execute-api/" + apiId + "/prod"
+	if _, err := websocket.Dial(url, "", "http://localhost/"); err == nil {
+		t.Fatal("Expected the connection to be rejected")
+	}
+	if _, err := websocket.Dial(url+"/missing", "", "http://localhost/"); err == nil {
+		t.Fatal("Expected the connection to a missing stage to fail")
+	}
+}
+
+func TestConnectionsAPI(t *testing.T) {
+	fake := &fakeLambda{}
+	a, server, apiId := newWebSocketServer(t, fake)
+	ws := dial(t, server, apiId)
+	websocket.Message.Send(ws, `{"action":"echo"}`)
+	connectionId := receive(t, ws)["requestContext"].(map[string]any)["connectionId"].(string)
+
+	connectionUrl := server.URL + "/execute-api/" + apiId + "/prod/@connections/" + connectionId
+	resp, err := http.Post(connectionUrl, "application/json", strings.NewReader(`{"text":"hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatal("Unexpected status", resp.StatusCode)
+	}
+	if message := receive(t, ws); message["text"] != "hello" {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	output, awserr := a.GetConnection(GetConnectionInput{ApiId: apiId, Stage: "prod", ConnectionId: connectionId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.ConnectedAt != "2023-11-14T22:13:20Z" || output.Identity.SourceIp != "127.0.0.1" {
+		t.Fatalf("Unexpected connection: %+v", output)
+	}
+	if _, awserr := a.GetConnection(GetConnectionInput{ApiId: apiId, Stage: "dev", ConnectionId: connectionId}); awserr == nil || awserr.Code != 410 {
+		t.Fatal("Expected GoneException", awserr)
+	}
+	if _, awserr := a.PostToConnection(PostToConnectionInput{ApiId: apiId, Stage: "prod", ConnectionId: connectionId, Data: make([]byte, 200000)}); awserr == nil || awserr.Code != 413 {
+		t.Fatal("Expected PayloadTooLargeException", awserr)
+	}
+
+	request, _ := http.NewRequest(http.MethodDelete, connectionUrl, nil)
+	resp, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 204 {
+		t.Fatal("Unexpected status", resp.StatusCode)
+	}
+	waitForInvocation(t, fake, functionArnPrefix+"disconnect")
+	resp, err = http.Post(connectionUrl, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 410 || resp.Header.Get("X-Amzn-ErrorType") != "GoneException" {
+		t.Fatal("Unexpected status", resp.StatusCode)
+	}
+}
+
+func TestWebSocketApis(t *testing.T) {
+	a := newAPIGateway(Options{})
+	for name, input := range map[string]CreateApiInput{
+		"selection": {Name: "chat", ProtocolType: "WEBSOCKET", RouteSelectionExpression: "$request.header.action"},
+		"target":    {Name: "chat", ProtocolType: "WEBSOCKET", RouteSelectionExpression: "$request.body.action", Target: testFunctionArn},
+	} {
+		if _, awserr := a.CreateApi(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected BadRequestException for", name, awserr)
+		}
+	}
+	apiId := createApi(t, a, CreateApiInput{Name: "chat", ProtocolType: "WEBSOCKET", RouteSelectionExpression: "${request.body.message.type}"})
+	api, _ := a.GetApi(GetApiInput{ApiId: apiId})
+	if api.ApiEndpoint != "ws://localhost:4569/execute-api/"+apiId {
+		t.Fatalf("Unexpected API: %+v", api)
+	}
+	if key := selectRouteKey(api.RouteSelectionExpression, []byte(`{"message":{"type":"join"}}`)); key != "join" {
+		t.Fatal("Unexpected route key", key)
+	}
+
+	if _, awserr := a.CreateStage(CreateStageInput{ApiId: apiId, StageName: "$default"}); awserr == nil {
+		t.Error("Expected BadRequestException for $default stage")
+	}
+	if _, awserr := a.CreateIntegration(CreateIntegrationInput{ApiId: apiId, IntegrationType: "AWS_PROXY", IntegrationUri: testFunctionArn, PayloadFormatVersion: "2.0"}); awserr == nil {
+		t.Error("Expected BadRequestException for payload format version 2.0")
+	}
+	for name, input := range map[string]CreateRouteInput{
+		"IAM on message": {ApiId: apiId, RouteKey: "join", AuthorizationType: "AWS_IAM"},
+		"JWT":            {ApiId: apiId, RouteKey: "$connect", AuthorizationType: "JWT"},
+		"response":       {ApiId: apiId, RouteKey: "join", RouteResponseSelectionExpression: "$request.body.type"},
+	} {
+		if _, awserr := a.CreateRoute(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected BadRequestException for", name, awserr)
+		}
+	}
+	createRoute(t, a, CreateRouteInput{ApiId: apiId, RouteKey: "$connect", AuthorizationType: "AWS_IAM"})
+}
@@ -0,0 +1,317 @@
+package appconfig
+
+import (
+	"io"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/random"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/ssm"
+)
+
+const (
+	defaultListResults = 50
+	maxListResults     = 50
+	idLength           = 7
+	idCharacters       = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// Objects is the local S3 service, which holds configurations with s3:// location URIs.
+type Objects interface {
+	GetObject(input s3.GetObjectInput) (*s3.GetObjectOutput, *awserrors.Error)
+}
+
+// Parameters is the local SSM service, which holds configurations with ssm-parameter:// location URIs.
+type Parameters interface {
+	GetParameter(input ssm.GetParameterInput) (*ssm.GetParameterOutput, *awserrors.Error)
+	// InRegion returns the region's parameters, which that region's configurations are read from.
+	InRegion(name string) (*ssm.SSM, error)
+}
+
+type Application struct {
+	Id          string
+	Arn         string
+	Name        string
+	Description string
+	Tags        map[string]string
+	// Keyed by ID.
+	environments map[string]*Environment
+	profiles     map[string]*ConfigurationProfile
+}
+
+func (a *Application) toAPI() APIApplication {
+	return APIApplication{
+		Id:          a.Id,
+		Name:        a.Name,
+		Description: a.Description,
+	}
+}
+
+type AppConfig struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	s3           Objects
+	ssm          Parameters
+	// Deployment durations and bake times are multiplied by this.
+	timeScale float64
+	// Overridden in tests.
+	clock func() time.Time
+	// Overridden in tests.
+	random func() float64
+
+	mu sync.Mutex
+	// Keyed by ID.
+	applications map[string]*Application
+	strategies   map[string]*DeploymentStrategy
+	// Keyed by the token for the session's next poll.
+	sessions map[string]*session
+	// Shared by the instances of all regions, which each have their own applications, deployment
+	// strategies and configuration sessions.
+	regions *region.Regions[*AppConfig]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	S3           Objects
+	SSM          Parameters
+	// Multiplies deployment durations and bake times, so gradual deployments can be simulated
+	// faster than in AWS. 0 completes deployments immediately. Defaults to 1.
+	DeploymentTimeScale *float64
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *AppConfig {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// S3 is global, so every region reads configurations from the same buckets.
+	a := newRegion(options)
+	a.regions = region.New(options.ArnGenerator.Region, options.Regions, a, func(name string) (*AppConfig, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.SSM != nil {
+			parameters, err := options.SSM.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.SSM = parameters
+		}
+		r := newRegion(regionOptions)
+		r.regions = a.regions
+		return r, nil
+	})
+	return a
+}
+
+// newRegion returns the instance for the options' region, with the predefined deployment
+// strategies.
+func newRegion(options Options) *AppConfig {
+	timeScale := 1.0
+	if options.DeploymentTimeScale != nil {
+		timeScale = *options.DeploymentTimeScale
+	}
+
+	a := &AppConfig{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		s3:           options.S3,
+		ssm:          options.SSM,
+		timeScale:    timeScale,
+		clock:        clock.Now,
+		random:       rand.Float64,
+		applications: make(map[string]*Application),
+		strategies:   make(map[string]*DeploymentStrategy),
+		sessions:     make(map[string]*session),
+	}
+	for _, strategy := range predefinedStrategies {
+		strategy := strategy
+		strategy.Arn = a.arnGenerator.Generate("appconfig", "deploymentstrategy", strategy.Id)
+		strategy.Tags = make(map[string]string)
+		a.strategies[strategy.Id] = &strategy
+	}
+	return a
+}
+
+// InRegion returns the applications, deployment strategies and configuration sessions of the
+// region. It fails if the region isn't one of Options.Regions.
+func (a *AppConfig) InRegion(name string) (*AppConfig, error) {
+	return a.regions.Get(name)
+}
+
+// lockedNewId returns a random ID like AppConfig's, which isn't used by any resource yet.
+func (a *AppConfig) lockedNewId() string {
+	for {
+		id := random.String(idCharacters, idLength)
+		if !a.lockedIdInUse(id) {
+			return id
+		}
+	}
+}
+
+func (a *AppConfig) lockedIdInUse(id string) bool {
+	if _, ok := a.strategies[id]; ok {
+		return true
+	}
+	for _, application := range a.applications {
+		if application.Id == id || application.environments[id] != nil || application.profiles[id] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate returns a page of items, with the token for the next page.
+func paginate[T any](items []T, maxResults int, nextToken string) ([]T, string, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(maxResults, defaultListResults, maxListResults, nextToken,
+		BadRequestException("MaxResults must be between 1 and 50"),
+		BadRequestException("Invalid NextToken"))
+	if awserr != nil {
+		return nil, "", awserr
+	}
+	page, next := pagination.Page(items, limit, start)
+	return page, next, nil
+}
+
+func (a *AppConfig) lockedGetApplication(id string) (*Application, *awserrors.Error) {
+	application, ok := a.applications[id]
+	if !ok {
+		return nil, ResourceNotFoundException("Application " + id + " not found")
+	}
+	return application, nil
+}
+
+// lockedFindApplication finds an application by its ID or, failing that, its name.
+func (a *AppConfig) lockedFindApplication(identifier string) *Application {
+	if application, ok := a.applications[identifier]; ok {
+		return application
+	}
+	for _, application := range a.applications {
+		if application.Name == identifier {
+			return application
+		}
+	}
+	return nil
+}
+
+// lockedGetTags returns the tags of the resource with the ARN.
+func (a *AppConfig) lockedGetTags(resourceArn string) (map[string]string, *awserrors.Error) {
+	for _, strategy := range a.strategies {
+		if strategy.Arn == resourceArn && !strategy.predefined {
+			return strategy.Tags, nil
+		}
+	}
+	for _, application := range a.applications {
+		if application.Arn == resourceArn {
+			return application.Tags, nil
+		}
+		for _, environment := range application.environments {
+			if environment.Arn == resourceArn {
+				return environment.Tags, nil
+			}
+			for _, deployment := range environment.deployments {
+				if deployment.Arn == resourceArn {
+					return deployment.Tags, nil
+				}
+			}
+		}
+		for _, profile := range application.profiles {
+			if profile.Arn == resourceArn {
+				return profile.Tags, nil
+			}
+		}
+	}
+	return nil, ResourceNotFoundException("Resource " + resourceArn + " not found")
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_TagResource.html
+func (a *AppConfig) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tags, awserr := a.lockedGetTags(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for key, value := range input.Tags {
+		tags[key] = value
+	}
+	return &TagResourceOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_UntagResource.html
+func (a *AppConfig) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tags, awserr := a.lockedGetTags(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, key := range input.TagKeys {
+		delete(tags, key)
+	}
+	return &UntagResourceOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListTagsForResource.html
+func (a *AppConfig) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tags, awserr := a.lockedGetTags(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListTagsForResourceOutput{Tags: copyTags(tags)}, nil
+}
+
+// copyTags returns a copy of the tags which is never nil.
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
+
+// readAll reads an S3 object's body.
+func readAll(body io.Reader) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	if closer, ok := body.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return io.ReadAll(body)
+}
+
+func validateName(name string) *awserrors.Error {
+	if name == "" || len(name) > 64 {
+		return BadRequestException("Name must be between 1 and 64 characters")
+	}
+	if strings.TrimSpace(name) != name {
+		return BadRequestException("Name must not begin or end with whitespace")
+	}
+	return nil
+}
+
+func validateDescription(description string) *awserrors.Error {
+	if len(description) > 1024 {
+		return BadRequestException("Description must be at most 1024 characters")
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package appconfig
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+func newAppConfig(t *testing.T) (*AppConfig, *time.Time) {
+	a := New(Options{ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}})
+	now := time.Unix(1700000000, 0)
+	a.clock = func() time.Time {
+		return now
+	}
+	return a, &now
+}
+
+func createApplication(t *testing.T, a *AppConfig, name string) string {
+	output, awserr := a.CreateApplication(CreateApplicationInput{Name: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Id
+}
+
+func createEnvironment(t *testing.T, a *AppConfig, applicationId, name string) string {
+	output, awserr := a.CreateEnvironment(CreateEnvironmentInput{ApplicationId: applicationId, Name: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Id
+}
+
+func createHostedProfile(t *testing.T, a *AppConfig, applicationId, name, profileType string) string {
+	output, awserr := a.CreateConfigurationProfile(CreateConfigurationProfileInput{
+		ApplicationId: applicationId,
+		Name:          name,
+		LocationUri:   "hosted",
+		Type:          profileType,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Id
+}
+
+func createVersion(t *testing.T, a *AppConfig, applicationId, profileId, content string) int {
+	output, awserr := a.CreateHostedConfigurationVersion(CreateHostedConfigurationVersionInput{
+		ApplicationId:          applicationId,
+		ConfigurationProfileId: profileId,
+		Content:                []byte(content),
+		ContentType:            "application/json",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.VersionNumber
+}
+
+func TestApplicationsAndEnvironments(t *testing.T) {
+	a, _ := newAppConfig(t)
+	applicationId := createApplication(t, a, "shop")
+	if len(applicationId) != 7 {
+		t.Fatalf("Unexpected ID %q", applicationId)
+	}
+
+	description := "The shop"
+	updated, awserr := a.UpdateApplication(UpdateApplicationInput{ApplicationId: applicationId, Description: &description})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.Name != "shop" || updated.Description != description {
+		t.Fatalf("Unexpected application %+v", updated)
+	}
+
+	environmentId := createEnvironment(t, a, applicationId, "prod")
+	_, awserr = a.CreateEnvironment(CreateEnvironmentInput{ApplicationId: applicationId, Name: "prod"})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatalf("Expected ConflictException, got %v", awserr)
+	}
+	environment, awserr := a.GetEnvironment(GetEnvironmentInput{ApplicationId: applicationId, EnvironmentId: environmentId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if environment.State != "READY_FOR_DEPLOYMENT" {
+		t.Fatalf("Unexpected state %s", environment.State)
+	}
+
+	_, awserr = a.DeleteApplication(DeleteApplicationInput{ApplicationId: applicationId})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatalf("Expected ConflictException, got %v", awserr)
+	}
+	_, awserr = a.DeleteEnvironment(DeleteEnvironmentInput{ApplicationId: applicationId, EnvironmentId: environmentId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = a.DeleteApplication(DeleteApplicationInput{ApplicationId: applicationId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = a.GetApplication(GetApplicationInput{ApplicationId: applicationId})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatalf("Expected ResourceNotFoundException, got %v", awserr)
+	}
+}
+
+func TestListApplicationsPagination(t *testing.T) {
+	a, _ := newAppConfig(t)
+	for _, name := range []string{"c", "a", "b"} {
+		createApplication(t, a, name)
+	}
+	var names []string
+	nextToken := ""
+	for {
+		output, awserr := a.ListApplications(ListApplicationsInput{MaxResults: 2, NextToken: nextToken})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		for _, application := range output.Items {
+			names = append(names, application.Name)
+		}
+		if output.NextToken == "" {
+			break
+		}
+		nextToken = output.NextToken
+	}
+	if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "c" {
+		t.Fatalf("Unexpected applications %v", names)
+	}
+}
+
+func TestHostedConfigurationVersions(t *testing.T) {
+	a, _ := newAppConfig(t)
+	applicationId := createApplication(t, a, "shop")
+	profileId := createHostedProfile(t, a, applicationId, "settings", "")
+
+	first := createVersion(t, a, applicationId, profileId, `{"color": "red"}`)
+	latest := 0
+	_, awserr := a.CreateHostedConfigurationVersion(CreateHostedConfigurationVersionInput{
+		ApplicationId:          applicationId,
+		ConfigurationProfileId: profileId,
+		Content:                []byte(`{"color": "blue"}`),
+		ContentType:            "application/json",
+		LatestVersionNumber:    &latest,
+	})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatalf("Expected ConflictException, got %v", awserr)
+	}
+	second, awserr := a.CreateHostedConfigurationVersion(CreateHostedConfigurationVersionInput{
+		ApplicationId:          applicationId,
+		ConfigurationProfileId: profileId,
+		Content:                []byte(`{"color": "blue"}`),
+		ContentType:            "application/json",
+		LatestVersionNumber:    &first,
+		VersionLabel:           "v2",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if first != 1 || second.VersionNumber != 2 || second.Status != 201 {
+		t.Fatalf("Unexpected versions %d, %+v", first, second)
+	}
+
+	got, awserr := a.GetHostedConfigurationVersion(GetHostedConfigurationVersionInput{
+		ApplicationId:          applicationId,
+		ConfigurationProfileId: profileId,
+		VersionNumber:          2,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if string(got.Content) != `{"color": "blue"}` || got.VersionLabel != "v2" {
+		t.Fatalf("Unexpected version %+v", got)
+	}
+
+	list, awserr := a.ListHostedConfigurationVersions(ListHostedConfigurationVersionsInput{
+		ApplicationId:          applicationId,
+		ConfigurationProfileId: profileId,
+		VersionLabel:           "v*",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Items) != 1 || list.Items[0].VersionNumber != 2 {
+		t.Fatalf("Unexpected versions %+v", list.Items)
+	}
+
+	_, awserr = a.DeleteConfigurationProfile(DeleteConfigurationProfileInput{ApplicationId: applicationId, ConfigurationProfileId: profileId})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatalf("Expected ConflictException, got %v", awserr)
+	}
+}
+
+func TestFeatureFlagValidation(t *testing.T) {
+	a, _ := newAppConfig(t)
+	applicationId := createApplication(t, a, "shop")
+	profileId := createHostedProfile(t, a, applicationId, "flags", "AWS.AppConfig.FeatureFlags")
+
+	for _, content := range []string{
+		`not json`,
+		`{"flags": {}, "values": {"checkout": {"enabled": true}}}`,
+	} {
+		_, awserr := a.CreateHostedConfigurationVersion(CreateHostedConfigurationVersionInput{
+			ApplicationId:          applicationId,
+			ConfigurationProfileId: profileId,
+			Content:                []byte(content),
+			ContentType:            "application/json",
+		})
+		if awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Fatalf("Expected BadRequestException for %s, got %v", content, awserr)
+		}
+	}
+
+	_, awserr := a.CreateConfigurationProfile(CreateConfigurationProfileInput{
+		ApplicationId: applicationId,
+		Name:          "remote-flags",
+		LocationUri:   "s3://bucket/flags.json",
+		Type:          "AWS.AppConfig.FeatureFlags",
+	})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatalf("Expected BadRequestException, got %v", awserr)
+	}
+}
+
+func TestDeploymentStrategies(t *testing.T) {
+	a, _ := newAppConfig(t)
+	_, awserr := a.DeleteDeploymentStrategy(DeleteDeploymentStrategyInput{DeploymentStrategyId: "AppConfig.AllAtOnce"})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatalf("Expected BadRequestException, got %v", awserr)
+	}
+	_, awserr = a.CreateDeploymentStrategy(CreateDeploymentStrategyInput{Name: "bad", GrowthFactor: 0})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatalf("Expected BadRequestException, got %v", awserr)
+	}
+
+	created, awserr := a.CreateDeploymentStrategy(CreateDeploymentStrategyInput{
+		Name:                        "slow",
+		DeploymentDurationInMinutes: 10,
+		GrowthFactor:                25,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.GrowthType != "LINEAR" || created.ReplicateTo != "NONE" {
+		t.Fatalf("Unexpected strategy %+v", created)
+	}
+	list, awserr := a.ListDeploymentStrategies(ListDeploymentStrategiesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Items) != len(predefinedStrategies)+1 {
+		t.Fatalf("Unexpected strategies %+v", list.Items)
+	}
+	_, awserr = a.DeleteDeploymentStrategy(DeleteDeploymentStrategyInput{DeploymentStrategyId: created.Id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestTags(t *testing.T) {
+	a, _ := newAppConfig(t)
+	output, awserr := a.CreateApplication(CreateApplicationInput{Name: "shop", Tags: map[string]string{"team": "web"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	resourceArn := "arn:aws:appconfig:us-east-1:123456789012:application/" + output.Id
+
+	_, awserr = a.TagResource(TagResourceInput{ResourceArn: resourceArn, Tags: map[string]string{"stage": "prod"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = a.UntagResource(UntagResourceInput{ResourceArn: resourceArn, TagKeys: []string{"team"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := a.ListTagsForResource(ListTagsForResourceInput{ResourceArn: resourceArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tags.Tags) != 1 || tags.Tags["stage"] != "prod" {
+		t.Fatalf("Unexpected tags %v", tags.Tags)
+	}
+
+	_, awserr = a.ListTagsForResource(ListTagsForResourceInput{ResourceArn: resourceArn + "x"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatalf("Expected ResourceNotFoundException, got %v", awserr)
+	}
+}
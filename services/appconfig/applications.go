@@ -0,0 +1,307 @@
+package appconfig
+
+import (
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+type Environment struct {
+	ApplicationId string
+	Id            string
+	Arn           string
+	Name          string
+	Description   string
+	Monitors      []APIMonitor
+	Tags          map[string]string
+	// In the order they were started, so deployment N is at index N-1.
+	deployments []*Deployment
+}
+
+func (a *AppConfig) lockedEnvironmentToAPI(environment *Environment) APIEnvironment {
+	return APIEnvironment{
+		ApplicationId: environment.ApplicationId,
+		Id:            environment.Id,
+		Name:          environment.Name,
+		Description:   environment.Description,
+		State:         a.lockedEnvironmentState(environment),
+		Monitors:      append([]APIMonitor{}, environment.Monitors...),
+	}
+}
+
+// lockedEnvironmentState returns the environment's state, which follows its latest deployment.
+func (a *AppConfig) lockedEnvironmentState(environment *Environment) string {
+	if len(environment.deployments) == 0 {
+		return "READY_FOR_DEPLOYMENT"
+	}
+	switch a.deploymentState(environment.deployments[len(environment.deployments)-1]) {
+	case "DEPLOYING", "BAKING":
+		return "DEPLOYING"
+	case "ROLLED_BACK":
+		return "ROLLED_BACK"
+	}
+	return "READY_FOR_DEPLOYMENT"
+}
+
+func sortedApplications(applications map[string]*Application) []*Application {
+	sorted := make([]*Application, 0, len(applications))
+	for _, application := range applications {
+		sorted = append(sorted, application)
+	}
+	slices.SortFunc(sorted, func(a, b *Application) int {
+		return strings.Compare(a.Name+"/"+a.Id, b.Name+"/"+b.Id)
+	})
+	return sorted
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_CreateApplication.html
+func (a *AppConfig) CreateApplication(input CreateApplicationInput) (*CreateApplicationOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateDescription(input.Description); awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := a.lockedNewId()
+	application := &Application{
+		Id:           id,
+		Arn:          a.arnGenerator.Generate("appconfig", "application", id),
+		Name:         input.Name,
+		Description:  input.Description,
+		Tags:         copyTags(input.Tags),
+		environments: make(map[string]*Environment),
+		profiles:     make(map[string]*ConfigurationProfile),
+	}
+	a.applications[id] = application
+	return &CreateApplicationOutput{APIApplication: application.toAPI(), Status: 201}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_GetApplication.html
+func (a *AppConfig) GetApplication(input GetApplicationInput) (*GetApplicationOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := application.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_UpdateApplication.html
+func (a *AppConfig) UpdateApplication(input UpdateApplicationInput) (*UpdateApplicationOutput, *awserrors.Error) {
+	if input.Name != nil {
+		if awserr := validateName(*input.Name); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if input.Description != nil {
+		if awserr := validateDescription(*input.Description); awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Name != nil {
+		application.Name = *input.Name
+	}
+	if input.Description != nil {
+		application.Description = *input.Description
+	}
+	output := application.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeleteApplication.html
+func (a *AppConfig) DeleteApplication(input DeleteApplicationInput) (*DeleteApplicationOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(application.environments) > 0 || len(application.profiles) > 0 {
+		return nil, ConflictException("Application " + application.Id + " has environments or configuration profiles")
+	}
+	delete(a.applications, application.Id)
+	return &DeleteApplicationOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListApplications.html
+func (a *AppConfig) ListApplications(input ListApplicationsInput) (*ListApplicationsOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var items []APIApplication
+	for _, application := range sortedApplications(a.applications) {
+		items = append(items, application.toAPI())
+	}
+	page, nextToken, awserr := paginate(items, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListApplicationsOutput{Items: page, NextToken: nextToken}, nil
+}
+
+func (a *AppConfig) lockedGetEnvironment(applicationId, environmentId string) (*Environment, *awserrors.Error) {
+	application, awserr := a.lockedGetApplication(applicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	environment, ok := application.environments[environmentId]
+	if !ok {
+		return nil, ResourceNotFoundException("Environment " + environmentId + " not found")
+	}
+	return environment, nil
+}
+
+func validateMonitors(monitors []APIMonitor) *awserrors.Error {
+	if len(monitors) > 5 {
+		return BadRequestException("An environment can have at most 5 monitors")
+	}
+	for _, monitor := range monitors {
+		if !strings.HasPrefix(monitor.AlarmArn, "arn:") {
+			return BadRequestException("Invalid AlarmArn: " + monitor.AlarmArn)
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_CreateEnvironment.html
+func (a *AppConfig) CreateEnvironment(input CreateEnvironmentInput) (*CreateEnvironmentOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateDescription(input.Description); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateMonitors(input.Monitors); awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, environment := range application.environments {
+		if environment.Name == input.Name {
+			return nil, ConflictException("Environment " + input.Name + " already exists")
+		}
+	}
+	id := a.lockedNewId()
+	environment := &Environment{
+		ApplicationId: application.Id,
+		Id:            id,
+		Arn:           a.arnGenerator.Generate("appconfig", "application", application.Id+"/environment/"+id),
+		Name:          input.Name,
+		Description:   input.Description,
+		Monitors:      input.Monitors,
+		Tags:          copyTags(input.Tags),
+	}
+	application.environments[id] = environment
+	return &CreateEnvironmentOutput{APIEnvironment: a.lockedEnvironmentToAPI(environment), Status: 201}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_GetEnvironment.html
+func (a *AppConfig) GetEnvironment(input GetEnvironmentInput) (*GetEnvironmentOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	environment, awserr := a.lockedGetEnvironment(input.ApplicationId, input.EnvironmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := a.lockedEnvironmentToAPI(environment)
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_UpdateEnvironment.html
+func (a *AppConfig) UpdateEnvironment(input UpdateEnvironmentInput) (*UpdateEnvironmentOutput, *awserrors.Error) {
+	if input.Name != nil {
+		if awserr := validateName(*input.Name); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if input.Description != nil {
+		if awserr := validateDescription(*input.Description); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if awserr := validateMonitors(input.Monitors); awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	environment, awserr := a.lockedGetEnvironment(input.ApplicationId, input.EnvironmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Name != nil {
+		environment.Name = *input.Name
+	}
+	if input.Description != nil {
+		environment.Description = *input.Description
+	}
+	if input.Monitors != nil {
+		environment.Monitors = input.Monitors
+	}
+	output := a.lockedEnvironmentToAPI(environment)
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeleteEnvironment.html
+func (a *AppConfig) DeleteEnvironment(input DeleteEnvironmentInput) (*DeleteEnvironmentOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	environment, awserr := a.lockedGetEnvironment(input.ApplicationId, input.EnvironmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if a.lockedEnvironmentState(environment) == "DEPLOYING" {
+		return nil, ConflictException("Environment " + environment.Id + " has a deployment in progress")
+	}
+	delete(a.applications[environment.ApplicationId].environments, environment.Id)
+	return &DeleteEnvironmentOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListEnvironments.html
+func (a *AppConfig) ListEnvironments(input ListEnvironmentsInput) (*ListEnvironmentsOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var items []APIEnvironment
+	for _, environment := range application.environments {
+		items = append(items, a.lockedEnvironmentToAPI(environment))
+	}
+	slices.SortFunc(items, func(a, b APIEnvironment) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, nextToken, awserr := paginate(items, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListEnvironmentsOutput{Items: page, NextToken: nextToken}, nil
+}
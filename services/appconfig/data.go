@@ -0,0 +1,154 @@
+package appconfig
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+const (
+	defaultPollIntervalInSeconds = 60
+	minPollIntervalInSeconds     = 15
+	maxPollIntervalInSeconds     = 86400
+	// Configuration tokens can be used for 24 hours.
+	tokenLifetime = 24 * time.Hour
+)
+
+// session is an AppConfigData configuration session, which polls for the latest configuration.
+type session struct {
+	applicationId string
+	environmentId string
+	profileId     string
+	pollInterval  int
+	expires       time.Time
+	// Between 0 and 1. Gradual deployments reach the sessions in the lowest buckets first.
+	bucket float64
+	// The deployment whose configuration the session last got, so it isn't returned again.
+	lastServed *Deployment
+}
+
+func (a *AppConfig) lockedExpireSessions() {
+	now := a.clock()
+	for token, session := range a.sessions {
+		if now.After(session.expires) {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+// findEnvironment finds an environment by its ID or, failing that, its name.
+func findEnvironment(application *Application, identifier string) *Environment {
+	if environment, ok := application.environments[identifier]; ok {
+		return environment
+	}
+	for _, environment := range application.environments {
+		if environment.Name == identifier {
+			return environment
+		}
+	}
+	return nil
+}
+
+// findProfile finds a configuration profile by its ID or, failing that, its name.
+func findProfile(application *Application, identifier string) *ConfigurationProfile {
+	if profile, ok := application.profiles[identifier]; ok {
+		return profile
+	}
+	for _, profile := range application.profiles {
+		if profile.Name == identifier {
+			return profile
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_appconfigdata_StartConfigurationSession.html
+func (a *AppConfig) StartConfigurationSession(input StartConfigurationSessionInput) (*StartConfigurationSessionOutput, *awserrors.Error) {
+	if input.ApplicationIdentifier == "" || input.EnvironmentIdentifier == "" || input.ConfigurationProfileIdentifier == "" {
+		return nil, BadRequestException("ApplicationIdentifier, EnvironmentIdentifier and ConfigurationProfileIdentifier are required")
+	}
+	pollInterval := input.RequiredMinimumPollIntervalInSeconds
+	if pollInterval == 0 {
+		pollInterval = defaultPollIntervalInSeconds
+	}
+	if pollInterval < minPollIntervalInSeconds || pollInterval > maxPollIntervalInSeconds {
+		return nil, BadRequestException("RequiredMinimumPollIntervalInSeconds must be between 15 and 86400")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application := a.lockedFindApplication(input.ApplicationIdentifier)
+	if application == nil {
+		return nil, ResourceNotFoundException("Application " + input.ApplicationIdentifier + " not found")
+	}
+	environment := findEnvironment(application, input.EnvironmentIdentifier)
+	if environment == nil {
+		return nil, ResourceNotFoundException("Environment " + input.EnvironmentIdentifier + " not found")
+	}
+	profile := findProfile(application, input.ConfigurationProfileIdentifier)
+	if profile == nil {
+		return nil, ResourceNotFoundException("Configuration profile " + input.ConfigurationProfileIdentifier + " not found")
+	}
+
+	a.lockedExpireSessions()
+	token := uuid.Must(uuid.NewV4()).String()
+	a.sessions[token] = &session{
+		applicationId: application.Id,
+		environmentId: environment.Id,
+		profileId:     profile.Id,
+		pollInterval:  pollInterval,
+		expires:       a.clock().Add(tokenLifetime),
+		bucket:        a.random(),
+	}
+	return &StartConfigurationSessionOutput{InitialConfigurationToken: token, Status: 201}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_appconfigdata_GetLatestConfiguration.html
+func (a *AppConfig) GetLatestConfiguration(input GetLatestConfigurationInput) (*GetLatestConfigurationOutput, *awserrors.Error) {
+	if input.ConfigurationToken == "" {
+		return nil, BadRequestException("ConfigurationToken is required")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.lockedExpireSessions()
+	current, ok := a.sessions[input.ConfigurationToken]
+	if !ok {
+		return nil, BadRequestException("Invalid or expired ConfigurationToken")
+	}
+	environment, awserr := a.lockedGetEnvironment(current.applicationId, current.environmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, awserr := a.lockedGetProfile(current.applicationId, current.profileId); awserr != nil {
+		return nil, awserr
+	}
+	deployment := a.lockedServedDeployment(environment, current.profileId, current.bucket)
+	if deployment == nil {
+		// The token isn't used up, so the client can poll again once there's a deployment.
+		return nil, ResourceNotFoundException("No configuration has been deployed to environment " + environment.Name)
+	}
+
+	// Each token can only be used once.
+	delete(a.sessions, input.ConfigurationToken)
+	next := *current
+	next.expires = a.clock().Add(tokenLifetime)
+	next.lastServed = deployment
+	token := uuid.Must(uuid.NewV4()).String()
+	a.sessions[token] = &next
+
+	output := &GetLatestConfigurationOutput{
+		NextPollConfigurationToken: token,
+		NextPollIntervalInSeconds:  current.pollInterval,
+	}
+	if deployment != current.lastServed {
+		output.Configuration = deployment.content
+		output.ContentType = deployment.contentType
+		output.VersionLabel = deployment.VersionLabel
+	}
+	return output, nil
+}
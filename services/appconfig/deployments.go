@@ -0,0 +1,394 @@
+package appconfig
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/ssm"
+	"aws-in-a-box/timestamp"
+)
+
+type Deployment struct {
+	ApplicationId          string
+	EnvironmentId          string
+	DeploymentNumber       int
+	Arn                    string
+	Description            string
+	ConfigurationProfileId string
+	ConfigurationName      string
+	ConfigurationLocation  string
+	ConfigurationVersion   string
+	VersionLabel           string
+	// A copy of the strategy when the deployment started.
+	Strategy  DeploymentStrategy
+	Tags      map[string]string
+	StartedAt time.Time
+	// Zero unless the deployment was stopped.
+	StoppedAt time.Time
+	// The configuration as AppConfigData returns it, as of when the deployment started.
+	content     []byte
+	contentType string
+	// The deployment duration and bake time, scaled by the time scale when the deployment started.
+	duration time.Duration
+	bakeTime time.Duration
+}
+
+// steps returns the percentages of clients the deployment reaches in turn, ending with 100.
+// Linear deployments grow by the growth factor at each step, and exponential deployments double
+// from it.
+func (d *Deployment) steps() []float64 {
+	var steps []float64
+	for i := 0; len(steps) == 0 || steps[len(steps)-1] < 100; i++ {
+		var percentage float64
+		if d.Strategy.GrowthType == "EXPONENTIAL" {
+			percentage = d.Strategy.GrowthFactor * math.Pow(2, float64(i))
+		} else {
+			percentage = d.Strategy.GrowthFactor * float64(i+1)
+		}
+		steps = append(steps, min(percentage, 100))
+	}
+	return steps
+}
+
+// progress returns the deployment's state and the percentage of clients it had reached at the
+// given time. Stopped deployments are rolled back.
+func (d *Deployment) progress(now time.Time) (string, float64) {
+	if !d.StoppedAt.IsZero() {
+		_, percentage := d.scheduledProgress(d.StoppedAt)
+		return "ROLLED_BACK", percentage
+	}
+	return d.scheduledProgress(now)
+}
+
+// scheduledProgress returns the deployment's state and percentage at the given time if it isn't
+// stopped. Steps are evenly spaced over the deployment duration, which is followed by the bake time.
+func (d *Deployment) scheduledProgress(now time.Time) (string, float64) {
+	elapsed := now.Sub(d.StartedAt)
+	switch {
+	case elapsed >= d.duration+d.bakeTime:
+		return "COMPLETE", 100
+	case elapsed >= d.duration:
+		return "BAKING", 100
+	}
+	steps := d.steps()
+	interval := d.duration / time.Duration(len(steps))
+	if interval == 0 {
+		return "DEPLOYING", 100
+	}
+	return "DEPLOYING", steps[min(int(elapsed/interval), len(steps)-1)]
+}
+
+// events returns the deployment's event log up to the given time, most recent first.
+func (d *Deployment) events(now time.Time) []APIDeploymentEvent {
+	cutoff := now
+	if !d.StoppedAt.IsZero() {
+		cutoff = d.StoppedAt
+	}
+	var events []APIDeploymentEvent
+	add := func(at time.Time, eventType, triggeredBy, description string) {
+		if !at.After(cutoff) {
+			events = append(events, APIDeploymentEvent{
+				EventType:   eventType,
+				TriggeredBy: triggeredBy,
+				Description: description,
+				OccurredAt:  timestamp.EpochSeconds(at),
+			})
+		}
+	}
+
+	add(d.StartedAt, "DEPLOYMENT_STARTED", "USER", "Deployment started")
+	steps := d.steps()
+	interval := d.duration / time.Duration(len(steps))
+	for i, percentage := range steps {
+		// Steps at the same time, as in deployments without a duration, are reported once.
+		if i+1 < len(steps) && interval == 0 {
+			continue
+		}
+		add(d.StartedAt.Add(time.Duration(i)*interval), "PERCENTAGE_UPDATED", "APPCONFIG",
+			"Configuration deployed to "+strconv.FormatFloat(percentage, 'f', -1, 64)+"% of clients")
+	}
+	add(d.StartedAt.Add(d.duration), "BAKE_TIME_STARTED", "APPCONFIG", "Deployment bake time started")
+	add(d.StartedAt.Add(d.duration+d.bakeTime), "DEPLOYMENT_COMPLETED", "APPCONFIG", "Deployment completed")
+	if !d.StoppedAt.IsZero() {
+		add(d.StoppedAt, "ROLLBACK_STARTED", "USER", "Rollback initiated by user")
+		add(d.StoppedAt, "ROLLBACK_COMPLETED", "APPCONFIG", "Rollback completed")
+	}
+	slices.Reverse(events)
+	return events
+}
+
+func (a *AppConfig) deploymentState(d *Deployment) string {
+	state, _ := d.progress(a.clock())
+	return state
+}
+
+func (a *AppConfig) deploymentToAPI(d *Deployment) APIDeployment {
+	now := a.clock()
+	state, percentage := d.progress(now)
+	output := APIDeployment{
+		ApplicationId:               d.ApplicationId,
+		EnvironmentId:               d.EnvironmentId,
+		DeploymentStrategyId:        d.Strategy.Id,
+		ConfigurationProfileId:      d.ConfigurationProfileId,
+		DeploymentNumber:            d.DeploymentNumber,
+		ConfigurationName:           d.ConfigurationName,
+		ConfigurationLocationUri:    d.ConfigurationLocation,
+		ConfigurationVersion:        d.ConfigurationVersion,
+		Description:                 d.Description,
+		DeploymentDurationInMinutes: d.Strategy.DeploymentDurationInMinutes,
+		GrowthType:                  d.Strategy.GrowthType,
+		GrowthFactor:                d.Strategy.GrowthFactor,
+		FinalBakeTimeInMinutes:      d.Strategy.FinalBakeTimeInMinutes,
+		State:                       state,
+		EventLog:                    d.events(now),
+		PercentageComplete:          percentage,
+		StartedAt:                   timestamp.EpochSeconds(d.StartedAt),
+		VersionLabel:                d.VersionLabel,
+	}
+	switch state {
+	case "COMPLETE":
+		output.CompletedAt = timestamp.EpochSeconds(d.StartedAt.Add(d.duration + d.bakeTime))
+	case "ROLLED_BACK":
+		output.CompletedAt = timestamp.EpochSeconds(d.StoppedAt)
+	}
+	return output
+}
+
+// lockedFetchConfiguration returns the content, content type and version label of a version of a
+// profile's configuration.
+func (a *AppConfig) lockedFetchConfiguration(profile *ConfigurationProfile, version string) ([]byte, string, string, *awserrors.Error) {
+	switch {
+	case profile.LocationUri == hostedLocation:
+		number, err := strconv.Atoi(version)
+		if err != nil {
+			return nil, "", "", BadRequestException("ConfigurationVersion must be a hosted configuration version number")
+		}
+		hosted := profile.findVersion(number)
+		if hosted == nil {
+			return nil, "", "", ResourceNotFoundException("Hosted configuration version " + version + " not found")
+		}
+		return hosted.Content, hosted.ContentType, hosted.VersionLabel, nil
+
+	case strings.HasPrefix(profile.LocationUri, "ssm-parameter://"):
+		if a.ssm == nil {
+			return nil, "", "", BadRequestException("SSM is not enabled")
+		}
+		name := strings.TrimPrefix(profile.LocationUri, "ssm-parameter://")
+		if version != "" {
+			name += ":" + version
+		}
+		output, awserr := a.ssm.GetParameter(ssm.GetParameterInput{Name: name, WithDecryption: true})
+		if awserr != nil {
+			return nil, "", "", BadRequestException("Getting configuration from SSM: " + awserr.Body.Message)
+		}
+		return []byte(output.Parameter.Value), "text/plain", "", nil
+
+	default:
+		if a.s3 == nil {
+			return nil, "", "", BadRequestException("S3 is not enabled")
+		}
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(profile.LocationUri, "s3://"), "/")
+		output, awserr := a.s3.GetObject(s3.GetObjectInput{Bucket: bucket, Key: key})
+		if awserr != nil {
+			return nil, "", "", BadRequestException("Getting configuration from S3: " + awserr.Body.Message)
+		}
+		content, err := readAll(output.Body)
+		if err != nil {
+			return nil, "", "", BadRequestException("Getting configuration from S3: " + err.Error())
+		}
+		return content, output.ContentType, "", nil
+	}
+}
+
+// featureFlagValues returns the values of a feature flag configuration, which is what
+// AppConfigData returns for it.
+func featureFlagValues(content []byte) ([]byte, error) {
+	var flags struct {
+		Values map[string]json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(content, &flags); err != nil {
+		return nil, err
+	}
+	if flags.Values == nil {
+		flags.Values = make(map[string]json.RawMessage)
+	}
+	return json.Marshal(flags.Values)
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_StartDeployment.html
+func (a *AppConfig) StartDeployment(input StartDeploymentInput) (*StartDeploymentOutput, *awserrors.Error) {
+	if input.ConfigurationVersion == "" {
+		return nil, BadRequestException("ConfigurationVersion is required")
+	}
+	if awserr := validateDescription(input.Description); awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	environment, awserr := a.lockedGetEnvironment(input.ApplicationId, input.EnvironmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	profile, awserr := a.lockedGetProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	strategy, awserr := a.lockedGetStrategy(input.DeploymentStrategyId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if a.lockedEnvironmentState(environment) == "DEPLOYING" {
+		return nil, ConflictException("Environment " + environment.Id + " already has a deployment in progress")
+	}
+
+	content, contentType, versionLabel, awserr := a.lockedFetchConfiguration(profile, input.ConfigurationVersion)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateContent(profile, content); awserr != nil {
+		return nil, awserr
+	}
+	if profile.Type == featureFlagsType {
+		values, err := featureFlagValues(content)
+		if err != nil {
+			return nil, BadRequestException("Invalid feature flag configuration: " + err.Error())
+		}
+		content, contentType = values, "application/json"
+	}
+
+	number := len(environment.deployments) + 1
+	deployment := &Deployment{
+		ApplicationId:          environment.ApplicationId,
+		EnvironmentId:          environment.Id,
+		DeploymentNumber:       number,
+		Arn:                    environment.Arn + "/deployment/" + strconv.Itoa(number),
+		Description:            input.Description,
+		ConfigurationProfileId: profile.Id,
+		ConfigurationName:      profile.Name,
+		ConfigurationLocation:  profile.LocationUri,
+		ConfigurationVersion:   input.ConfigurationVersion,
+		VersionLabel:           versionLabel,
+		Strategy:               *strategy,
+		Tags:                   copyTags(input.Tags),
+		StartedAt:              a.clock(),
+		content:                append([]byte{}, content...),
+		contentType:            contentType,
+		duration:               a.scaleMinutes(strategy.DeploymentDurationInMinutes),
+		bakeTime:               a.scaleMinutes(strategy.FinalBakeTimeInMinutes),
+	}
+	environment.deployments = append(environment.deployments, deployment)
+	a.logger.Info("Started deployment",
+		"application", environment.ApplicationId, "environment", environment.Name,
+		"profile", profile.Name, "version", input.ConfigurationVersion, "strategy", strategy.Name)
+	return &StartDeploymentOutput{APIDeployment: a.deploymentToAPI(deployment), Status: 201}, nil
+}
+
+func (a *AppConfig) scaleMinutes(minutes int) time.Duration {
+	return time.Duration(float64(minutes) * float64(time.Minute) * a.timeScale)
+}
+
+func (a *AppConfig) lockedGetDeployment(applicationId, environmentId string, number int) (*Deployment, *awserrors.Error) {
+	environment, awserr := a.lockedGetEnvironment(applicationId, environmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if number < 1 || number > len(environment.deployments) {
+		return nil, ResourceNotFoundException("Deployment " + strconv.Itoa(number) + " not found")
+	}
+	return environment.deployments[number-1], nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_GetDeployment.html
+func (a *AppConfig) GetDeployment(input GetDeploymentInput) (*GetDeploymentOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deployment, awserr := a.lockedGetDeployment(input.ApplicationId, input.EnvironmentId, input.DeploymentNumber)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := a.deploymentToAPI(deployment)
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_StopDeployment.html
+func (a *AppConfig) StopDeployment(input StopDeploymentInput) (*StopDeploymentOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deployment, awserr := a.lockedGetDeployment(input.ApplicationId, input.EnvironmentId, input.DeploymentNumber)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if state := a.deploymentState(deployment); state != "DEPLOYING" && state != "BAKING" {
+		return nil, BadRequestException("Deployment " + strconv.Itoa(deployment.DeploymentNumber) + " is " + state + " and can't be stopped")
+	}
+	// Clients go back to the previously deployed configuration.
+	deployment.StoppedAt = a.clock()
+	return &StopDeploymentOutput{APIDeployment: a.deploymentToAPI(deployment), Status: 202}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListDeployments.html
+func (a *AppConfig) ListDeployments(input ListDeploymentsInput) (*ListDeploymentsOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	environment, awserr := a.lockedGetEnvironment(input.ApplicationId, input.EnvironmentId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var items []APIDeploymentSummary
+	// The most recent deployments come first.
+	for i := len(environment.deployments) - 1; i >= 0; i-- {
+		deployment := a.deploymentToAPI(environment.deployments[i])
+		items = append(items, APIDeploymentSummary{
+			DeploymentNumber:            deployment.DeploymentNumber,
+			ConfigurationName:           deployment.ConfigurationName,
+			ConfigurationVersion:        deployment.ConfigurationVersion,
+			DeploymentDurationInMinutes: deployment.DeploymentDurationInMinutes,
+			GrowthType:                  deployment.GrowthType,
+			GrowthFactor:                deployment.GrowthFactor,
+			FinalBakeTimeInMinutes:      deployment.FinalBakeTimeInMinutes,
+			State:                       deployment.State,
+			PercentageComplete:          deployment.PercentageComplete,
+			StartedAt:                   deployment.StartedAt,
+			CompletedAt:                 deployment.CompletedAt,
+			VersionLabel:                deployment.VersionLabel,
+		})
+	}
+	page, nextToken, awserr := paginate(items, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListDeploymentsOutput{Items: page, NextToken: nextToken}, nil
+}
+
+// lockedServedDeployment returns the deployment whose configuration of the profile a client in the
+// given bucket, between 0 and 1, gets from the environment, or nil if none has been deployed. A
+// deployment in progress reaches the clients in the lowest buckets first, and the others keep the
+// configuration deployed before it.
+func (a *AppConfig) lockedServedDeployment(environment *Environment, profileId string, bucket float64) *Deployment {
+	now := a.clock()
+	for i := len(environment.deployments) - 1; i >= 0; i-- {
+		deployment := environment.deployments[i]
+		if deployment.ConfigurationProfileId != profileId {
+			continue
+		}
+		state, percentage := deployment.progress(now)
+		if state == "ROLLED_BACK" {
+			continue
+		}
+		if state != "DEPLOYING" || bucket*100 < percentage {
+			return deployment
+		}
+	}
+	return nil
+}
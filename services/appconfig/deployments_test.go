@@ -0,0 +1,303 @@
+package appconfig
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/ssm"
+)
+
+func startDeployment(t *testing.T, a *AppConfig, applicationId, environmentId, profileId string, version string, strategyId string) *StartDeploymentOutput {
+	output, awserr := a.StartDeployment(StartDeploymentInput{
+		ApplicationId:          applicationId,
+		EnvironmentId:          environmentId,
+		ConfigurationProfileId: profileId,
+		ConfigurationVersion:   version,
+		DeploymentStrategyId:   strategyId,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+// startSession starts a configuration session whose clients are in the given bucket.
+func startSession(t *testing.T, a *AppConfig, applicationId, environmentId, profileId string, bucket float64) string {
+	a.random = func() float64 { return bucket }
+	output, awserr := a.StartConfigurationSession(StartConfigurationSessionInput{
+		ApplicationIdentifier:          applicationId,
+		EnvironmentIdentifier:          environmentId,
+		ConfigurationProfileIdentifier: profileId,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.InitialConfigurationToken
+}
+
+// poll gets the latest configuration, returning it and the token for the next poll.
+func poll(t *testing.T, a *AppConfig, token string) (string, string) {
+	output, awserr := a.GetLatestConfiguration(GetLatestConfigurationInput{ConfigurationToken: token})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.NextPollIntervalInSeconds != defaultPollIntervalInSeconds {
+		t.Fatalf("Unexpected poll interval %d", output.NextPollIntervalInSeconds)
+	}
+	return string(output.Configuration), output.NextPollConfigurationToken
+}
+
+func TestGradualDeployment(t *testing.T) {
+	a, now := newAppConfig(t)
+	applicationId := createApplication(t, a, "shop")
+	environmentId := createEnvironment(t, a, applicationId, "prod")
+	profileId := createHostedProfile(t, a, applicationId, "settings", "")
+	createVersion(t, a, applicationId, profileId, "v1")
+	createVersion(t, a, applicationId, profileId, "v2")
+
+	// Sessions can start before anything is deployed, but there's nothing to get yet.
+	early := startSession(t, a, "shop", "prod", "settings", 0.5)
+	_, awserr := a.GetLatestConfiguration(GetLatestConfigurationInput{ConfigurationToken: early})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatalf("Expected ResourceNotFoundException, got %v", awserr)
+	}
+
+	first := startDeployment(t, a, applicationId, environmentId, profileId, "1", "AppConfig.AllAtOnce")
+	if first.State != "BAKING" || first.PercentageComplete != 100 || first.DeploymentNumber != 1 {
+		t.Fatalf("Unexpected deployment %+v", first.APIDeployment)
+	}
+	*now = now.Add(10 * time.Minute)
+	got, awserr := a.GetDeployment(GetDeploymentInput{ApplicationId: applicationId, EnvironmentId: environmentId, DeploymentNumber: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.State != "COMPLETE" || got.CompletedAt != float64(now.Unix()) {
+		t.Fatalf("Unexpected deployment %+v", got)
+	}
+	content, _ := poll(t, a, early)
+	if content != "v1" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+
+	low := startSession(t, a, applicationId, environmentId, profileId, 0.25)
+	high := startSession(t, a, applicationId, environmentId, profileId, 0.75)
+	second := startDeployment(t, a, applicationId, environmentId, profileId, "2", "AppConfig.Linear50PercentEvery30Seconds")
+	if second.State != "DEPLOYING" || second.PercentageComplete != 50 {
+		t.Fatalf("Unexpected deployment %+v", second.APIDeployment)
+	}
+	_, awserr = a.StartDeployment(StartDeploymentInput{
+		ApplicationId:          applicationId,
+		EnvironmentId:          environmentId,
+		ConfigurationProfileId: profileId,
+		ConfigurationVersion:   "1",
+		DeploymentStrategyId:   "AppConfig.AllAtOnce",
+	})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatalf("Expected ConflictException, got %v", awserr)
+	}
+
+	// Half the clients get the new configuration at first.
+	content, low = poll(t, a, low)
+	if content != "v2" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+	content, high = poll(t, a, high)
+	if content != "v1" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+	// Tokens can only be used once.
+	_, awserr = a.GetLatestConfiguration(GetLatestConfigurationInput{ConfigurationToken: early})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatalf("Expected BadRequestException, got %v", awserr)
+	}
+
+	*now = now.Add(30 * time.Second)
+	content, _ = poll(t, a, high)
+	if content != "v2" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+	// Unchanged configuration isn't returned again.
+	content, _ = poll(t, a, low)
+	if content != "" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+
+	*now = now.Add(30 * time.Second)
+	environment, awserr := a.GetEnvironment(GetEnvironmentInput{ApplicationId: applicationId, EnvironmentId: environmentId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if environment.State != "DEPLOYING" {
+		t.Fatalf("Unexpected state %s while baking", environment.State)
+	}
+	*now = now.Add(time.Minute)
+	got, awserr = a.GetDeployment(GetDeploymentInput{ApplicationId: applicationId, EnvironmentId: environmentId, DeploymentNumber: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var eventTypes []string
+	for _, event := range got.EventLog {
+		eventTypes = append(eventTypes, event.EventType)
+	}
+	expected := []string{"DEPLOYMENT_COMPLETED", "BAKE_TIME_STARTED", "PERCENTAGE_UPDATED", "PERCENTAGE_UPDATED", "DEPLOYMENT_STARTED"}
+	if got.State != "COMPLETE" || len(eventTypes) != len(expected) {
+		t.Fatalf("Unexpected deployment %+v", got)
+	}
+	for i := range expected {
+		if eventTypes[i] != expected[i] {
+			t.Fatalf("Unexpected events %v", eventTypes)
+		}
+	}
+
+	list, awserr := a.ListDeployments(ListDeploymentsInput{ApplicationId: applicationId, EnvironmentId: environmentId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Items) != 2 || list.Items[0].DeploymentNumber != 2 {
+		t.Fatalf("Unexpected deployments %+v", list.Items)
+	}
+}
+
+func TestStopDeployment(t *testing.T) {
+	a, now := newAppConfig(t)
+	applicationId := createApplication(t, a, "shop")
+	environmentId := createEnvironment(t, a, applicationId, "prod")
+	profileId := createHostedProfile(t, a, applicationId, "settings", "")
+	createVersion(t, a, applicationId, profileId, "v1")
+	createVersion(t, a, applicationId, profileId, "v2")
+	startDeployment(t, a, applicationId, environmentId, profileId, "1", "AppConfig.AllAtOnce")
+	*now = now.Add(10 * time.Minute)
+
+	token := startSession(t, a, applicationId, environmentId, profileId, 0.05)
+	_, token = poll(t, a, token)
+	startDeployment(t, a, applicationId, environmentId, profileId, "2", "AppConfig.Canary10Percent20Minutes")
+	content, token := poll(t, a, token)
+	if content != "v2" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+
+	stopped, awserr := a.StopDeployment(StopDeploymentInput{ApplicationId: applicationId, EnvironmentId: environmentId, DeploymentNumber: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if stopped.State != "ROLLED_BACK" || stopped.EventLog[0].EventType != "ROLLBACK_COMPLETED" {
+		t.Fatalf("Unexpected deployment %+v", stopped.APIDeployment)
+	}
+	// The client goes back to the configuration deployed before.
+	content, _ = poll(t, a, token)
+	if content != "v1" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+	environment, awserr := a.GetEnvironment(GetEnvironmentInput{ApplicationId: applicationId, EnvironmentId: environmentId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if environment.State != "ROLLED_BACK" {
+		t.Fatalf("Unexpected state %s", environment.State)
+	}
+
+	_, awserr = a.StopDeployment(StopDeploymentInput{ApplicationId: applicationId, EnvironmentId: environmentId, DeploymentNumber: 1})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatalf("Expected BadRequestException, got %v", awserr)
+	}
+}
+
+func TestFeatureFlagsWithTimeScale(t *testing.T) {
+	timeScale := 0.0
+	a := New(Options{
+		ArnGenerator:        arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		DeploymentTimeScale: &timeScale,
+	})
+	applicationId := createApplication(t, a, "shop")
+	environmentId := createEnvironment(t, a, applicationId, "prod")
+	profileId := createHostedProfile(t, a, applicationId, "flags", "AWS.AppConfig.FeatureFlags")
+	createVersion(t, a, applicationId, profileId, `{
+		"version": "1",
+		"flags": {"checkout": {"name": "checkout"}},
+		"values": {"checkout": {"enabled": true}}
+	}`)
+
+	deployment := startDeployment(t, a, applicationId, environmentId, profileId, "1", "AppConfig.Linear20PercentEvery6Minutes")
+	if deployment.State != "COMPLETE" {
+		t.Fatalf("Unexpected deployment %+v", deployment.APIDeployment)
+	}
+	token := startSession(t, a, applicationId, environmentId, profileId, 0.99)
+	content, _ := poll(t, a, token)
+	if content != `{"checkout":{"enabled":true}}` {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+}
+
+func TestSSMConfiguration(t *testing.T) {
+	generator := arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}
+	parameters := ssm.New(ssm.Options{ArnGenerator: generator})
+	_, awserr := parameters.PutParameter(ssm.PutParameterInput{Name: "/shop/settings", Value: "color=red", Type: "String"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	a, _ := newAppConfig(t)
+	a.ssm = parameters
+	applicationId := createApplication(t, a, "shop")
+	environmentId := createEnvironment(t, a, applicationId, "prod")
+	profile, awserr := a.CreateConfigurationProfile(CreateConfigurationProfileInput{
+		ApplicationId: applicationId,
+		Name:          "settings",
+		LocationUri:   "ssm-parameter:///shop/settings",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	startDeployment(t, a, applicationId, environmentId, profile.Id, "1", "AppConfig.AllAtOnce")
+
+	token := startSession(t, a, applicationId, environmentId, profile.Id, 0)
+	content, _ := poll(t, a, token)
+	if content != "color=red" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	generator := arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}
+	parameters := ssm.New(ssm.Options{ArnGenerator: generator})
+	a := New(Options{ArnGenerator: generator, SSM: parameters})
+	if _, err := a.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := a.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applicationId := createApplication(t, europe, "shop")
+	if _, awserr := a.GetApplication(GetApplicationInput{ApplicationId: applicationId}); awserr == nil {
+		t.Fatal("Expected the application not to be in us-east-1")
+	}
+	resourceArn := "arn:aws:appconfig:eu-west-1:123456789012:application/" + applicationId
+	if _, awserr := europe.TagResource(TagResourceInput{ResourceArn: resourceArn, Tags: map[string]string{"stage": "prod"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	// Configurations are read from the region's parameters.
+	europeParameters, err := parameters.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, awserr := europeParameters.PutParameter(ssm.PutParameterInput{Name: "/shop/settings", Value: "color=blue", Type: "String"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	environmentId := createEnvironment(t, europe, applicationId, "prod")
+	profile, awserr := europe.CreateConfigurationProfile(CreateConfigurationProfileInput{
+		ApplicationId: applicationId,
+		Name:          "settings",
+		LocationUri:   "ssm-parameter:///shop/settings",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	startDeployment(t, europe, applicationId, environmentId, profile.Id, "1", "AppConfig.AllAtOnce")
+	token := startSession(t, europe, applicationId, environmentId, profile.Id, 0)
+	if content, _ := poll(t, europe, token); content != "color=blue" {
+		t.Fatalf("Unexpected configuration %q", content)
+	}
+}
@@ -0,0 +1,27 @@
+package appconfig
+
+import "aws-in-a-box/awserrors"
+
+func BadRequestException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BadRequestException", message)
+}
+
+func ConflictException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 409,
+		Body: awserrors.ErrorBody{
+			Type:    "ConflictException",
+			Message: message,
+		},
+	}
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    "ResourceNotFoundException",
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package appconfig
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// AppConfig and AppConfigData only support the REST-JSON protocol.
+func NewHandler(logger *slog.Logger, a *AppConfig, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/applications", "CreateApplication", region.Method(a.regions, (*AppConfig).CreateApplication))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications", "ListApplications", region.Method(a.regions, (*AppConfig).ListApplications))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}", "GetApplication", region.Method(a.regions, (*AppConfig).GetApplication))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPatch, "/applications/{ApplicationId}", "UpdateApplication", region.Method(a.regions, (*AppConfig).UpdateApplication))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/applications/{ApplicationId}", "DeleteApplication", region.Method(a.regions, (*AppConfig).DeleteApplication))
+
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/applications/{ApplicationId}/environments", "CreateEnvironment", region.Method(a.regions, (*AppConfig).CreateEnvironment))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/environments", "ListEnvironments", region.Method(a.regions, (*AppConfig).ListEnvironments))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/environments/{EnvironmentId}", "GetEnvironment", region.Method(a.regions, (*AppConfig).GetEnvironment))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPatch, "/applications/{ApplicationId}/environments/{EnvironmentId}", "UpdateEnvironment", region.Method(a.regions, (*AppConfig).UpdateEnvironment))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/applications/{ApplicationId}/environments/{EnvironmentId}", "DeleteEnvironment", region.Method(a.regions, (*AppConfig).DeleteEnvironment))
+
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/applications/{ApplicationId}/configurationprofiles", "CreateConfigurationProfile", region.Method(a.regions, (*AppConfig).CreateConfigurationProfile))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/configurationprofiles", "ListConfigurationProfiles", region.Method(a.regions, (*AppConfig).ListConfigurationProfiles))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}", "GetConfigurationProfile", region.Method(a.regions, (*AppConfig).GetConfigurationProfile))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPatch, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}", "UpdateConfigurationProfile", region.Method(a.regions, (*AppConfig).UpdateConfigurationProfile))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}", "DeleteConfigurationProfile", region.Method(a.regions, (*AppConfig).DeleteConfigurationProfile))
+
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}/hostedconfigurationversions", "CreateHostedConfigurationVersion", region.Method(a.regions, (*AppConfig).CreateHostedConfigurationVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}/hostedconfigurationversions", "ListHostedConfigurationVersions", region.Method(a.regions, (*AppConfig).ListHostedConfigurationVersions))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}/hostedconfigurationversions/{VersionNumber}", "GetHostedConfigurationVersion", region.Method(a.regions, (*AppConfig).GetHostedConfigurationVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/applications/{ApplicationId}/configurationprofiles/{ConfigurationProfileId}/hostedconfigurationversions/{VersionNumber}", "DeleteHostedConfigurationVersion", region.Method(a.regions, (*AppConfig).DeleteHostedConfigurationVersion))
+
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/deploymentstrategies", "CreateDeploymentStrategy", region.Method(a.regions, (*AppConfig).CreateDeploymentStrategy))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/deploymentstrategies", "ListDeploymentStrategies", region.Method(a.regions, (*AppConfig).ListDeploymentStrategies))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/deploymentstrategies/{DeploymentStrategyId}", "GetDeploymentStrategy", region.Method(a.regions, (*AppConfig).GetDeploymentStrategy))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPatch, "/deploymentstrategies/{DeploymentStrategyId}", "UpdateDeploymentStrategy", region.Method(a.regions, (*AppConfig).UpdateDeploymentStrategy))
+	// AWS misspells the path for deleting a deployment strategy.
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/deployementstrategies/{DeploymentStrategyId}", "DeleteDeploymentStrategy", region.Method(a.regions, (*AppConfig).DeleteDeploymentStrategy))
+
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/applications/{ApplicationId}/environments/{EnvironmentId}/deployments", "StartDeployment", region.Method(a.regions, (*AppConfig).StartDeployment))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/environments/{EnvironmentId}/deployments", "ListDeployments", region.Method(a.regions, (*AppConfig).ListDeployments))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/applications/{ApplicationId}/environments/{EnvironmentId}/deployments/{DeploymentNumber}", "GetDeployment", region.Method(a.regions, (*AppConfig).GetDeployment))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/applications/{ApplicationId}/environments/{EnvironmentId}/deployments/{DeploymentNumber}", "StopDeployment", region.Method(a.regions, (*AppConfig).StopDeployment))
+
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/tags/{ResourceArn}", "ListTagsForResource", region.Method(a.regions, (*AppConfig).ListTagsForResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/tags/{ResourceArn}", "TagResource", region.Method(a.regions, (*AppConfig).TagResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/tags/{ResourceArn}", "UntagResource", region.Method(a.regions, (*AppConfig).UntagResource))
+
+	// AppConfigData
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/configurationsessions", "StartConfigurationSession", region.Method(a.regions, (*AppConfig).StartConfigurationSession))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/configuration", "GetLatestConfiguration", region.Method(a.regions, (*AppConfig).GetLatestConfiguration))
+	capabilityRegistry.Add("appconfig", registry.Operations()...)
+	handler := restjson.NewHandler(registry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		// Other services have the same tagging routes, so only handle the tags of AppConfig resources.
+		if strings.HasPrefix(r.URL.Path, "/tags/") && !strings.Contains(r.URL.Path, ":appconfig:") {
+			return false
+		}
+		return handler(w, r)
+	}
+}
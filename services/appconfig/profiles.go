@@ -0,0 +1,426 @@
+package appconfig
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+const (
+	freeformType     = "AWS.Freeform"
+	featureFlagsType = "AWS.AppConfig.FeatureFlags"
+	hostedLocation   = "hosted"
+	// Hosted configurations can be at most 2 MB.
+	maxHostedContentSize = 2 * 1024 * 1024
+)
+
+type ConfigurationProfile struct {
+	ApplicationId    string
+	Id               string
+	Arn              string
+	Name             string
+	Description      string
+	LocationUri      string
+	RetrievalRoleArn string
+	Validators       []APIValidator
+	Type             string
+	Tags             map[string]string
+	// Hosted configuration versions, in the order they were created.
+	versions          []*HostedConfigurationVersion
+	nextVersionNumber int
+}
+
+func (p *ConfigurationProfile) toAPI() APIConfigurationProfile {
+	return APIConfigurationProfile{
+		ApplicationId:    p.ApplicationId,
+		Id:               p.Id,
+		Name:             p.Name,
+		Description:      p.Description,
+		LocationUri:      p.LocationUri,
+		RetrievalRoleArn: p.RetrievalRoleArn,
+		Validators:       append([]APIValidator{}, p.Validators...),
+		Type:             p.Type,
+	}
+}
+
+type HostedConfigurationVersion struct {
+	VersionNumber int
+	Description   string
+	ContentType   string
+	VersionLabel  string
+	Content       []byte
+}
+
+func validateLocationUri(locationUri string) *awserrors.Error {
+	switch {
+	case locationUri == hostedLocation:
+	case strings.HasPrefix(locationUri, "ssm-parameter://") && len(locationUri) > len("ssm-parameter://"):
+	case strings.HasPrefix(locationUri, "s3://") && strings.Contains(strings.TrimPrefix(locationUri, "s3://"), "/"):
+	default:
+		return BadRequestException("LocationUri must be hosted, ssm-parameter://NAME or s3://BUCKET/KEY")
+	}
+	return nil
+}
+
+func validateValidators(validators []APIValidator) *awserrors.Error {
+	if len(validators) > 2 {
+		return BadRequestException("A configuration profile can have at most 2 validators")
+	}
+	for _, validator := range validators {
+		switch validator.Type {
+		case "JSON_SCHEMA":
+			if !json.Valid([]byte(validator.Content)) {
+				return BadRequestException("JSON_SCHEMA validator content must be a JSON schema")
+			}
+		case "LAMBDA":
+		default:
+			return BadRequestException("Validator Type must be JSON_SCHEMA or LAMBDA")
+		}
+	}
+	return nil
+}
+
+// validateContent checks configuration content against the profile's type and validators. JSON
+// schema validators only check that the content is JSON, and Lambda validators aren't invoked.
+func validateContent(profile *ConfigurationProfile, content []byte) *awserrors.Error {
+	if profile.Type == featureFlagsType {
+		var flags struct {
+			Flags  map[string]json.RawMessage
+			Values map[string]json.RawMessage
+		}
+		if err := json.Unmarshal(content, &flags); err != nil {
+			return BadRequestException("Feature flag configuration must be JSON: " + err.Error())
+		}
+		for name := range flags.Values {
+			if _, ok := flags.Flags[name]; !ok {
+				return BadRequestException("Feature flag configuration has a value for undefined flag " + name)
+			}
+		}
+	}
+	for _, validator := range profile.Validators {
+		if validator.Type == "JSON_SCHEMA" && !json.Valid(content) {
+			return BadRequestException("Configuration content is not valid JSON")
+		}
+	}
+	return nil
+}
+
+func (a *AppConfig) lockedGetProfile(applicationId, profileId string) (*ConfigurationProfile, *awserrors.Error) {
+	application, awserr := a.lockedGetApplication(applicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	profile, ok := application.profiles[profileId]
+	if !ok {
+		return nil, ResourceNotFoundException("Configuration profile " + profileId + " not found")
+	}
+	return profile, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_CreateConfigurationProfile.html
+func (a *AppConfig) CreateConfigurationProfile(input CreateConfigurationProfileInput) (*CreateConfigurationProfileOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateDescription(input.Description); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateLocationUri(input.LocationUri); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateValidators(input.Validators); awserr != nil {
+		return nil, awserr
+	}
+	switch input.Type {
+	case "":
+		input.Type = freeformType
+	case freeformType, featureFlagsType:
+	default:
+		return nil, BadRequestException("Type must be AWS.Freeform or AWS.AppConfig.FeatureFlags")
+	}
+	if input.Type == featureFlagsType && input.LocationUri != hostedLocation {
+		return nil, BadRequestException("Feature flag configurations must be hosted")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	id := a.lockedNewId()
+	profile := &ConfigurationProfile{
+		ApplicationId:     application.Id,
+		Id:                id,
+		Arn:               a.arnGenerator.Generate("appconfig", "application", application.Id+"/configurationprofile/"+id),
+		Name:              input.Name,
+		Description:       input.Description,
+		LocationUri:       input.LocationUri,
+		RetrievalRoleArn:  input.RetrievalRoleArn,
+		Validators:        input.Validators,
+		Type:              input.Type,
+		Tags:              copyTags(input.Tags),
+		nextVersionNumber: 1,
+	}
+	application.profiles[id] = profile
+	return &CreateConfigurationProfileOutput{APIConfigurationProfile: profile.toAPI(), Status: 201}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_GetConfigurationProfile.html
+func (a *AppConfig) GetConfigurationProfile(input GetConfigurationProfileInput) (*GetConfigurationProfileOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := profile.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_UpdateConfigurationProfile.html
+func (a *AppConfig) UpdateConfigurationProfile(input UpdateConfigurationProfileInput) (*UpdateConfigurationProfileOutput, *awserrors.Error) {
+	if input.Name != nil {
+		if awserr := validateName(*input.Name); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if input.Description != nil {
+		if awserr := validateDescription(*input.Description); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if awserr := validateValidators(input.Validators); awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Name != nil {
+		profile.Name = *input.Name
+	}
+	if input.Description != nil {
+		profile.Description = *input.Description
+	}
+	if input.RetrievalRoleArn != nil {
+		profile.RetrievalRoleArn = *input.RetrievalRoleArn
+	}
+	if input.Validators != nil {
+		profile.Validators = input.Validators
+	}
+	output := profile.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeleteConfigurationProfile.html
+func (a *AppConfig) DeleteConfigurationProfile(input DeleteConfigurationProfileInput) (*DeleteConfigurationProfileOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(profile.versions) > 0 {
+		return nil, ConflictException("Configuration profile " + profile.Id + " has hosted configuration versions")
+	}
+	delete(a.applications[profile.ApplicationId].profiles, profile.Id)
+	return &DeleteConfigurationProfileOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListConfigurationProfiles.html
+func (a *AppConfig) ListConfigurationProfiles(input ListConfigurationProfilesInput) (*ListConfigurationProfilesOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	application, awserr := a.lockedGetApplication(input.ApplicationId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var items []APIConfigurationProfileSummary
+	for _, profile := range application.profiles {
+		if input.Type != "" && profile.Type != input.Type {
+			continue
+		}
+		summary := APIConfigurationProfileSummary{
+			ApplicationId:  profile.ApplicationId,
+			Id:             profile.Id,
+			Name:           profile.Name,
+			LocationUri:    profile.LocationUri,
+			ValidatorTypes: []string{},
+			Type:           profile.Type,
+		}
+		for _, validator := range profile.Validators {
+			summary.ValidatorTypes = append(summary.ValidatorTypes, validator.Type)
+		}
+		items = append(items, summary)
+	}
+	slices.SortFunc(items, func(a, b APIConfigurationProfileSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, nextToken, awserr := paginate(items, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListConfigurationProfilesOutput{Items: page, NextToken: nextToken}, nil
+}
+
+func (a *AppConfig) lockedGetHostedProfile(applicationId, profileId string) (*ConfigurationProfile, *awserrors.Error) {
+	profile, awserr := a.lockedGetProfile(applicationId, profileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if profile.LocationUri != hostedLocation {
+		return nil, BadRequestException("Configuration profile " + profile.Id + " isn't hosted")
+	}
+	return profile, nil
+}
+
+func (p *ConfigurationProfile) findVersion(versionNumber int) *HostedConfigurationVersion {
+	for _, version := range p.versions {
+		if version.VersionNumber == versionNumber {
+			return version
+		}
+	}
+	return nil
+}
+
+func versionOutput(profile *ConfigurationProfile, version *HostedConfigurationVersion) *HostedConfigurationVersionOutput {
+	return &HostedConfigurationVersionOutput{
+		ApplicationId:          profile.ApplicationId,
+		ConfigurationProfileId: profile.Id,
+		VersionNumber:          version.VersionNumber,
+		Description:            version.Description,
+		ContentType:            version.ContentType,
+		VersionLabel:           version.VersionLabel,
+		Content:                version.Content,
+	}
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_CreateHostedConfigurationVersion.html
+func (a *AppConfig) CreateHostedConfigurationVersion(input CreateHostedConfigurationVersionInput) (*HostedConfigurationVersionOutput, *awserrors.Error) {
+	if input.ContentType == "" {
+		return nil, BadRequestException("Content-Type is required")
+	}
+	if len(input.Content) > maxHostedContentSize {
+		return nil, BadRequestException("Content must be at most 2 MB")
+	}
+	if awserr := validateDescription(input.Description); awserr != nil {
+		return nil, awserr
+	}
+	if _, err := strconv.Atoi(input.VersionLabel); err == nil {
+		return nil, BadRequestException("VersionLabel must not be a number")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetHostedProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.LatestVersionNumber != nil && *input.LatestVersionNumber != profile.nextVersionNumber-1 {
+		return nil, ConflictException("Latest-Version-Number " + strconv.Itoa(*input.LatestVersionNumber) + " isn't the latest version")
+	}
+	if awserr := validateContent(profile, input.Content); awserr != nil {
+		return nil, awserr
+	}
+	version := &HostedConfigurationVersion{
+		VersionNumber: profile.nextVersionNumber,
+		Description:   input.Description,
+		ContentType:   input.ContentType,
+		VersionLabel:  input.VersionLabel,
+		Content:       append([]byte{}, input.Content...),
+	}
+	profile.nextVersionNumber++
+	profile.versions = append(profile.versions, version)
+	output := versionOutput(profile, version)
+	output.Status = 201
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_GetHostedConfigurationVersion.html
+func (a *AppConfig) GetHostedConfigurationVersion(input GetHostedConfigurationVersionInput) (*HostedConfigurationVersionOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetHostedProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	version := profile.findVersion(input.VersionNumber)
+	if version == nil {
+		return nil, ResourceNotFoundException("Hosted configuration version " + strconv.Itoa(input.VersionNumber) + " not found")
+	}
+	return versionOutput(profile, version), nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeleteHostedConfigurationVersion.html
+func (a *AppConfig) DeleteHostedConfigurationVersion(input DeleteHostedConfigurationVersionInput) (*DeleteHostedConfigurationVersionOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetHostedProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	version := profile.findVersion(input.VersionNumber)
+	if version == nil {
+		return nil, ResourceNotFoundException("Hosted configuration version " + strconv.Itoa(input.VersionNumber) + " not found")
+	}
+	// Deployments have their own copy of the content, so deleting the version doesn't affect them.
+	profile.versions = slices.DeleteFunc(profile.versions, func(v *HostedConfigurationVersion) bool {
+		return v == version
+	})
+	return &DeleteHostedConfigurationVersionOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListHostedConfigurationVersions.html
+func (a *AppConfig) ListHostedConfigurationVersions(input ListHostedConfigurationVersionsInput) (*ListHostedConfigurationVersionsOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, awserr := a.lockedGetHostedProfile(input.ApplicationId, input.ConfigurationProfileId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var items []APIHostedConfigurationVersionSummary
+	// The newest versions come first.
+	for i := len(profile.versions) - 1; i >= 0; i-- {
+		version := profile.versions[i]
+		if input.VersionLabel != "" && !matchVersionLabel(input.VersionLabel, version.VersionLabel) {
+			continue
+		}
+		items = append(items, APIHostedConfigurationVersionSummary{
+			ApplicationId:          profile.ApplicationId,
+			ConfigurationProfileId: profile.Id,
+			VersionNumber:          version.VersionNumber,
+			Description:            version.Description,
+			ContentType:            version.ContentType,
+			VersionLabel:           version.VersionLabel,
+		})
+	}
+	page, nextToken, awserr := paginate(items, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListHostedConfigurationVersionsOutput{Items: page, NextToken: nextToken}, nil
+}
+
+// matchVersionLabel matches a version label against a filter, which may end with a * wildcard.
+func matchVersionLabel(filter string, label string) bool {
+	if prefix, ok := strings.CutSuffix(filter, "*"); ok {
+		return strings.HasPrefix(label, prefix)
+	}
+	return filter == label
+}
@@ -0,0 +1,197 @@
+package appconfig
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type deploymentState struct {
+	*Deployment
+	Content     []byte
+	ContentType string
+	Duration    time.Duration
+	BakeTime    time.Duration
+}
+
+type environmentState struct {
+	*Environment
+	// In the order they were started.
+	Deployments []deploymentState
+}
+
+type profileState struct {
+	*ConfigurationProfile
+	// In the order they were created.
+	Versions          []*HostedConfigurationVersion
+	NextVersionNumber int
+}
+
+type applicationState struct {
+	*Application
+	// Sorted by ID.
+	Environments          []environmentState
+	ConfigurationProfiles []profileState
+}
+
+type strategyState struct {
+	*DeploymentStrategy
+	Predefined bool `json:",omitempty"`
+}
+
+type applicationsState struct {
+	Applications         []applicationState
+	DeploymentStrategies []strategyState
+}
+
+// TryPause pauses changes to each region's applications while a snapshot copies them.
+func (a *AppConfig) TryPause() (func(), bool) {
+	return a.regions.TryPause(func(a *AppConfig) *sync.Mutex { return &a.mu })
+}
+
+// ExportState writes each region's applications with their environments, deployments,
+// configuration profiles and hosted configuration versions, and its deployment strategies, to the
+// archive. Configuration sessions aren't exported.
+func (a *AppConfig) ExportState(w *state.Writer) error {
+	return a.regions.ExportState(w, (*AppConfig).exportState)
+}
+
+func (a *AppConfig) exportState(w *state.Writer) error {
+	unlock := w.Lock(&a.mu)
+	defer unlock()
+
+	var exported applicationsState
+	for _, application := range a.applications {
+		app := applicationState{Application: application}
+		for _, environment := range application.environments {
+			e := environmentState{Environment: environment}
+			for _, deployment := range environment.deployments {
+				e.Deployments = append(e.Deployments, deploymentState{
+					Deployment:  deployment,
+					Content:     deployment.content,
+					ContentType: deployment.contentType,
+					Duration:    deployment.duration,
+					BakeTime:    deployment.bakeTime,
+				})
+			}
+			app.Environments = append(app.Environments, e)
+		}
+		slices.SortFunc(app.Environments, func(a, b environmentState) int {
+			return strings.Compare(a.Id, b.Id)
+		})
+		for _, profile := range application.profiles {
+			app.ConfigurationProfiles = append(app.ConfigurationProfiles, profileState{
+				ConfigurationProfile: profile,
+				Versions:             profile.versions,
+				NextVersionNumber:    profile.nextVersionNumber,
+			})
+		}
+		slices.SortFunc(app.ConfigurationProfiles, func(a, b profileState) int {
+			return strings.Compare(a.Id, b.Id)
+		})
+		exported.Applications = append(exported.Applications, app)
+	}
+	slices.SortFunc(exported.Applications, func(a, b applicationState) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	for _, strategy := range a.strategies {
+		exported.DeploymentStrategies = append(exported.DeploymentStrategies, strategyState{
+			DeploymentStrategy: strategy,
+			Predefined:         strategy.predefined,
+		})
+	}
+	slices.SortFunc(exported.DeploymentStrategies, func(a, b strategyState) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return w.WriteJSON("applications.json", exported)
+}
+
+// ImportState replaces each region's applications and deployment strategies with the archive's,
+// and ends configuration sessions. Deployments which were in progress carry on from where they were.
+func (a *AppConfig) ImportState(r *state.Reader) (func() error, error) {
+	return a.regions.ImportState(r, (*AppConfig).importState, (*AppConfig).exportState)
+}
+
+func (a *AppConfig) importState(r *state.Reader) (func() error, error) {
+	var imported applicationsState
+	if err := r.ReadJSON("applications.json", &imported); err != nil {
+		return nil, err
+	}
+
+	applications := make(map[string]*Application)
+	for _, app := range imported.Applications {
+		application := app.Application
+		if application == nil || application.Id == "" || application.Arn == "" {
+			return nil, fmt.Errorf("invalid application")
+		}
+		if application.Tags == nil {
+			application.Tags = make(map[string]string)
+		}
+		application.environments = make(map[string]*Environment)
+		for _, e := range app.Environments {
+			environment := e.Environment
+			if environment == nil || environment.Id == "" || environment.ApplicationId != application.Id {
+				return nil, fmt.Errorf("application %s: invalid environment", application.Id)
+			}
+			if environment.Tags == nil {
+				environment.Tags = make(map[string]string)
+			}
+			environment.deployments = nil
+			for i, d := range e.Deployments {
+				deployment := d.Deployment
+				if deployment == nil || deployment.DeploymentNumber != i+1 {
+					return nil, fmt.Errorf("environment %s: deployments aren't numbered in order", environment.Id)
+				}
+				deployment.content = d.Content
+				deployment.contentType = d.ContentType
+				deployment.duration = d.Duration
+				deployment.bakeTime = d.BakeTime
+				environment.deployments = append(environment.deployments, deployment)
+			}
+			application.environments[environment.Id] = environment
+		}
+		application.profiles = make(map[string]*ConfigurationProfile)
+		for _, p := range app.ConfigurationProfiles {
+			profile := p.ConfigurationProfile
+			if profile == nil || profile.Id == "" || profile.ApplicationId != application.Id {
+				return nil, fmt.Errorf("application %s: invalid configuration profile", application.Id)
+			}
+			if awserr := validateLocationUri(profile.LocationUri); awserr != nil {
+				return nil, fmt.Errorf("configuration profile %s: %s", profile.Id, awserr.Body.Message)
+			}
+			if profile.Tags == nil {
+				profile.Tags = make(map[string]string)
+			}
+			profile.versions = p.Versions
+			profile.nextVersionNumber = p.NextVersionNumber
+			application.profiles[profile.Id] = profile
+		}
+		applications[application.Id] = application
+	}
+
+	strategies := make(map[string]*DeploymentStrategy)
+	for _, s := range imported.DeploymentStrategies {
+		strategy := s.DeploymentStrategy
+		if strategy == nil || strategy.Id == "" || strategy.Arn == "" {
+			return nil, fmt.Errorf("invalid deployment strategy")
+		}
+		if strategy.Tags == nil {
+			strategy.Tags = make(map[string]string)
+		}
+		strategy.predefined = s.Predefined
+		strategies[strategy.Id] = strategy
+	}
+
+	return func() error {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.applications = applications
+		a.strategies = strategies
+		a.sessions = make(map[string]*session)
+		return nil
+	}, nil
+}
@@ -0,0 +1,235 @@
+package appconfig
+
+import (
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+// The longest deployment duration and bake time, in minutes.
+const maxStrategyMinutes = 1440
+
+type DeploymentStrategy struct {
+	Id                          string
+	Arn                         string
+	Name                        string
+	Description                 string
+	DeploymentDurationInMinutes int
+	// LINEAR or EXPONENTIAL.
+	GrowthType             string
+	GrowthFactor           float64
+	FinalBakeTimeInMinutes int
+	ReplicateTo            string
+	Tags                   map[string]string
+	// Predefined strategies can't be changed or deleted.
+	predefined bool
+}
+
+func (s *DeploymentStrategy) toAPI() APIDeploymentStrategy {
+	return APIDeploymentStrategy{
+		Id:                          s.Id,
+		Name:                        s.Name,
+		Description:                 s.Description,
+		DeploymentDurationInMinutes: s.DeploymentDurationInMinutes,
+		GrowthType:                  s.GrowthType,
+		GrowthFactor:                s.GrowthFactor,
+		FinalBakeTimeInMinutes:      s.FinalBakeTimeInMinutes,
+		ReplicateTo:                 s.ReplicateTo,
+	}
+}
+
+// https://docs.aws.amazon.com/appconfig/latest/userguide/appconfig-creating-deployment-strategy.html#appconfig-creating-deployment-strategy-predefined
+var predefinedStrategies = []DeploymentStrategy{
+	{
+		Id:                          "AppConfig.AllAtOnce",
+		Name:                        "AppConfig.AllAtOnce",
+		Description:                 "Quick",
+		DeploymentDurationInMinutes: 0,
+		GrowthType:                  "LINEAR",
+		GrowthFactor:                100,
+		FinalBakeTimeInMinutes:      10,
+		ReplicateTo:                 "NONE",
+		predefined:                  true,
+	},
+	{
+		Id:                          "AppConfig.Linear50PercentEvery30Seconds",
+		Name:                        "AppConfig.Linear50PercentEvery30Seconds",
+		Description:                 "Test/Demonstration",
+		DeploymentDurationInMinutes: 1,
+		GrowthType:                  "LINEAR",
+		GrowthFactor:                50,
+		FinalBakeTimeInMinutes:      1,
+		ReplicateTo:                 "NONE",
+		predefined:                  true,
+	},
+	{
+		Id:                          "AppConfig.Canary10Percent20Minutes",
+		Name:                        "AppConfig.Canary10Percent20Minutes",
+		Description:                 "AWS Recommended",
+		DeploymentDurationInMinutes: 20,
+		GrowthType:                  "EXPONENTIAL",
+		GrowthFactor:                10,
+		FinalBakeTimeInMinutes:      10,
+		ReplicateTo:                 "NONE",
+		predefined:                  true,
+	},
+	{
+		Id:                          "AppConfig.Linear20PercentEvery6Minutes",
+		Name:                        "AppConfig.Linear20PercentEvery6Minutes",
+		Description:                 "AWS Recommended",
+		DeploymentDurationInMinutes: 30,
+		GrowthType:                  "LINEAR",
+		GrowthFactor:                20,
+		FinalBakeTimeInMinutes:      30,
+		ReplicateTo:                 "NONE",
+		predefined:                  true,
+	},
+}
+
+func validateStrategy(strategy *DeploymentStrategy) *awserrors.Error {
+	if strategy.DeploymentDurationInMinutes < 0 || strategy.DeploymentDurationInMinutes > maxStrategyMinutes {
+		return BadRequestException("DeploymentDurationInMinutes must be between 0 and 1440")
+	}
+	if strategy.FinalBakeTimeInMinutes < 0 || strategy.FinalBakeTimeInMinutes > maxStrategyMinutes {
+		return BadRequestException("FinalBakeTimeInMinutes must be between 0 and 1440")
+	}
+	if strategy.GrowthFactor < 1 || strategy.GrowthFactor > 100 {
+		return BadRequestException("GrowthFactor must be between 1 and 100")
+	}
+	if strategy.GrowthType != "LINEAR" && strategy.GrowthType != "EXPONENTIAL" {
+		return BadRequestException("GrowthType must be LINEAR or EXPONENTIAL")
+	}
+	return validateDescription(strategy.Description)
+}
+
+func (a *AppConfig) lockedGetStrategy(id string) (*DeploymentStrategy, *awserrors.Error) {
+	strategy, ok := a.strategies[id]
+	if !ok {
+		return nil, ResourceNotFoundException("Deployment strategy " + id + " not found")
+	}
+	return strategy, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_CreateDeploymentStrategy.html
+func (a *AppConfig) CreateDeploymentStrategy(input CreateDeploymentStrategyInput) (*CreateDeploymentStrategyOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if input.GrowthType == "" {
+		input.GrowthType = "LINEAR"
+	}
+	switch input.ReplicateTo {
+	case "":
+		input.ReplicateTo = "NONE"
+	case "NONE", "SSM_DOCUMENT":
+	default:
+		return nil, BadRequestException("ReplicateTo must be NONE or SSM_DOCUMENT")
+	}
+	strategy := &DeploymentStrategy{
+		Name:                        input.Name,
+		Description:                 input.Description,
+		DeploymentDurationInMinutes: input.DeploymentDurationInMinutes,
+		GrowthType:                  input.GrowthType,
+		GrowthFactor:                input.GrowthFactor,
+		FinalBakeTimeInMinutes:      input.FinalBakeTimeInMinutes,
+		ReplicateTo:                 input.ReplicateTo,
+		Tags:                        copyTags(input.Tags),
+	}
+	if awserr := validateStrategy(strategy); awserr != nil {
+		return nil, awserr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	strategy.Id = a.lockedNewId()
+	strategy.Arn = a.arnGenerator.Generate("appconfig", "deploymentstrategy", strategy.Id)
+	a.strategies[strategy.Id] = strategy
+	return &CreateDeploymentStrategyOutput{APIDeploymentStrategy: strategy.toAPI(), Status: 201}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_GetDeploymentStrategy.html
+func (a *AppConfig) GetDeploymentStrategy(input GetDeploymentStrategyInput) (*GetDeploymentStrategyOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	strategy, awserr := a.lockedGetStrategy(input.DeploymentStrategyId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := strategy.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_UpdateDeploymentStrategy.html
+func (a *AppConfig) UpdateDeploymentStrategy(input UpdateDeploymentStrategyInput) (*UpdateDeploymentStrategyOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	strategy, awserr := a.lockedGetStrategy(input.DeploymentStrategyId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if strategy.predefined {
+		return nil, BadRequestException("Predefined deployment strategy " + strategy.Id + " can't be updated")
+	}
+	updated := *strategy
+	if input.Description != nil {
+		updated.Description = *input.Description
+	}
+	if input.DeploymentDurationInMinutes != nil {
+		updated.DeploymentDurationInMinutes = *input.DeploymentDurationInMinutes
+	}
+	if input.FinalBakeTimeInMinutes != nil {
+		updated.FinalBakeTimeInMinutes = *input.FinalBakeTimeInMinutes
+	}
+	if input.GrowthFactor != nil {
+		updated.GrowthFactor = *input.GrowthFactor
+	}
+	if input.GrowthType != nil {
+		updated.GrowthType = *input.GrowthType
+	}
+	if awserr := validateStrategy(&updated); awserr != nil {
+		return nil, awserr
+	}
+	// Deployments already started keep the strategy they started with.
+	*strategy = updated
+	output := strategy.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeleteDeploymentStrategy.html
+func (a *AppConfig) DeleteDeploymentStrategy(input DeleteDeploymentStrategyInput) (*DeleteDeploymentStrategyOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	strategy, awserr := a.lockedGetStrategy(input.DeploymentStrategyId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if strategy.predefined {
+		return nil, BadRequestException("Predefined deployment strategy " + strategy.Id + " can't be deleted")
+	}
+	delete(a.strategies, strategy.Id)
+	return &DeleteDeploymentStrategyOutput{Status: 204}, nil
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ListDeploymentStrategies.html
+func (a *AppConfig) ListDeploymentStrategies(input ListDeploymentStrategiesInput) (*ListDeploymentStrategiesOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var items []APIDeploymentStrategy
+	for _, strategy := range a.strategies {
+		items = append(items, strategy.toAPI())
+	}
+	slices.SortFunc(items, func(a, b APIDeploymentStrategy) int {
+		return strings.Compare(a.Name+"/"+a.Id, b.Name+"/"+b.Id)
+	})
+	page, nextToken, awserr := paginate(items, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListDeploymentStrategiesOutput{Items: page, NextToken: nextToken}, nil
+}
@@ -0,0 +1,480 @@
+package appconfig
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_Application.html
+type APIApplication struct {
+	Id          string
+	Name        string
+	Description string `json:",omitempty"`
+}
+
+type CreateApplicationInput struct {
+	Name        string            `required:"true" length:"1,64" error:"BadRequestException"`
+	Description string            `length:",1024" error:"BadRequestException"`
+	Tags        map[string]string `length:",50" error:"BadRequestException"`
+}
+
+type CreateApplicationOutput struct {
+	APIApplication
+	Status int `json:"-" rest:"status"`
+}
+
+type GetApplicationInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+}
+
+type GetApplicationOutput = APIApplication
+
+type UpdateApplicationInput struct {
+	ApplicationId string  `json:"-" rest:"path:ApplicationId"`
+	Name          *string `length:"1,64" error:"BadRequestException"`
+	Description   *string `length:",1024" error:"BadRequestException"`
+}
+
+type UpdateApplicationOutput = APIApplication
+
+type DeleteApplicationInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+}
+
+type DeleteApplicationOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type ListApplicationsInput struct {
+	MaxResults int    `json:"-" rest:"query:max_results" range:"1,50" error:"BadRequestException"`
+	NextToken  string `json:"-" rest:"query:next_token"`
+}
+
+type ListApplicationsOutput struct {
+	Items     []APIApplication
+	NextToken string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_Monitor.html
+type APIMonitor struct {
+	AlarmArn     string
+	AlarmRoleArn string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_Environment.html
+type APIEnvironment struct {
+	ApplicationId string
+	Id            string
+	Name          string
+	Description   string `json:",omitempty"`
+	// READY_FOR_DEPLOYMENT, DEPLOYING, ROLLING_BACK or ROLLED_BACK.
+	State    string
+	Monitors []APIMonitor
+}
+
+type CreateEnvironmentInput struct {
+	ApplicationId string            `json:"-" rest:"path:ApplicationId"`
+	Name          string            `required:"true" length:"1,64" error:"BadRequestException"`
+	Description   string            `length:",1024" error:"BadRequestException"`
+	Monitors      []APIMonitor      `length:",5" error:"BadRequestException"`
+	Tags          map[string]string `length:",50" error:"BadRequestException"`
+}
+
+type CreateEnvironmentOutput struct {
+	APIEnvironment
+	Status int `json:"-" rest:"status"`
+}
+
+type GetEnvironmentInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId string `json:"-" rest:"path:EnvironmentId"`
+}
+
+type GetEnvironmentOutput = APIEnvironment
+
+type UpdateEnvironmentInput struct {
+	ApplicationId string       `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId string       `json:"-" rest:"path:EnvironmentId"`
+	Name          *string      `length:"1,64" error:"BadRequestException"`
+	Description   *string      `length:",1024" error:"BadRequestException"`
+	Monitors      []APIMonitor `length:",5" error:"BadRequestException"`
+}
+
+type UpdateEnvironmentOutput = APIEnvironment
+
+type DeleteEnvironmentInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId string `json:"-" rest:"path:EnvironmentId"`
+}
+
+type DeleteEnvironmentOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type ListEnvironmentsInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+	MaxResults    int    `json:"-" rest:"query:max_results" range:"1,50" error:"BadRequestException"`
+	NextToken     string `json:"-" rest:"query:next_token"`
+}
+
+type ListEnvironmentsOutput struct {
+	Items     []APIEnvironment
+	NextToken string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_Validator.html
+type APIValidator struct {
+	// JSON_SCHEMA or LAMBDA.
+	Type    string `required:"true" enum:"JSON_SCHEMA|LAMBDA" error:"BadRequestException"`
+	Content string `required:"true" length:",32768" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ConfigurationProfile.html
+type APIConfigurationProfile struct {
+	ApplicationId    string
+	Id               string
+	Name             string
+	Description      string `json:",omitempty"`
+	LocationUri      string
+	RetrievalRoleArn string `json:",omitempty"`
+	Validators       []APIValidator
+	// AWS.Freeform or AWS.AppConfig.FeatureFlags.
+	Type string
+}
+
+type CreateConfigurationProfileInput struct {
+	ApplicationId    string            `json:"-" rest:"path:ApplicationId"`
+	Name             string            `required:"true" length:"1,128" error:"BadRequestException"`
+	Description      string            `length:",1024" error:"BadRequestException"`
+	LocationUri      string            `required:"true" length:"1,2048" error:"BadRequestException"`
+	RetrievalRoleArn string            `length:"20,2048" error:"BadRequestException"`
+	Validators       []APIValidator    `length:",2" error:"BadRequestException"`
+	Type             string            `pattern:"[a-zA-Z\\.]+" error:"BadRequestException"`
+	Tags             map[string]string `length:",50" error:"BadRequestException"`
+}
+
+type CreateConfigurationProfileOutput struct {
+	APIConfigurationProfile
+	Status int `json:"-" rest:"status"`
+}
+
+type GetConfigurationProfileInput struct {
+	ApplicationId          string `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string `json:"-" rest:"path:ConfigurationProfileId"`
+}
+
+type GetConfigurationProfileOutput = APIConfigurationProfile
+
+type UpdateConfigurationProfileInput struct {
+	ApplicationId          string  `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string  `json:"-" rest:"path:ConfigurationProfileId"`
+	Name                   *string `length:"1,128" error:"BadRequestException"`
+	Description            *string `length:",1024" error:"BadRequestException"`
+	RetrievalRoleArn       *string
+	Validators             []APIValidator `length:",2" error:"BadRequestException"`
+}
+
+type UpdateConfigurationProfileOutput = APIConfigurationProfile
+
+type DeleteConfigurationProfileInput struct {
+	ApplicationId          string `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string `json:"-" rest:"path:ConfigurationProfileId"`
+}
+
+type DeleteConfigurationProfileOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type ListConfigurationProfilesInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+	Type          string `json:"-" rest:"query:type"`
+	MaxResults    int    `json:"-" rest:"query:max_results" range:"1,50" error:"BadRequestException"`
+	NextToken     string `json:"-" rest:"query:next_token"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_ConfigurationProfileSummary.html
+type APIConfigurationProfileSummary struct {
+	ApplicationId  string
+	Id             string
+	Name           string
+	LocationUri    string
+	ValidatorTypes []string
+	Type           string
+}
+
+type ListConfigurationProfilesOutput struct {
+	Items     []APIConfigurationProfileSummary
+	NextToken string `json:",omitempty"`
+}
+
+type CreateHostedConfigurationVersionInput struct {
+	ApplicationId          string `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string `json:"-" rest:"path:ConfigurationProfileId"`
+	Content                []byte `json:"-" rest:"body"`
+	ContentType            string `json:"-" rest:"header:Content-Type" required:"true" length:"1,255" error:"BadRequestException"`
+	Description            string `json:"-" rest:"header:Description" length:",1024" error:"BadRequestException"`
+	// If given, the version is only created if this is the latest version's number.
+	LatestVersionNumber *int   `json:"-" rest:"header:Latest-Version-Number"`
+	VersionLabel        string `json:"-" rest:"header:VersionLabel" length:"1,64" error:"BadRequestException"`
+}
+
+// HostedConfigurationVersionOutput is a hosted configuration version, with its content as the body.
+type HostedConfigurationVersionOutput struct {
+	ApplicationId          string `json:"-" rest:"header:Application-Id"`
+	ConfigurationProfileId string `json:"-" rest:"header:Configuration-Profile-Id"`
+	VersionNumber          int    `json:"-" rest:"header:Version-Number"`
+	Description            string `json:"-" rest:"header:Description"`
+	ContentType            string `json:"-" rest:"header:Content-Type"`
+	VersionLabel           string `json:"-" rest:"header:VersionLabel"`
+	Content                []byte `json:"-" rest:"body"`
+	Status                 int    `json:"-" rest:"status"`
+}
+
+type GetHostedConfigurationVersionInput struct {
+	ApplicationId          string `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string `json:"-" rest:"path:ConfigurationProfileId"`
+	VersionNumber          int    `json:"-" rest:"path:VersionNumber"`
+}
+
+type DeleteHostedConfigurationVersionInput struct {
+	ApplicationId          string `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string `json:"-" rest:"path:ConfigurationProfileId"`
+	VersionNumber          int    `json:"-" rest:"path:VersionNumber"`
+}
+
+type DeleteHostedConfigurationVersionOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type ListHostedConfigurationVersionsInput struct {
+	ApplicationId          string `json:"-" rest:"path:ApplicationId"`
+	ConfigurationProfileId string `json:"-" rest:"path:ConfigurationProfileId"`
+	VersionLabel           string `json:"-" rest:"query:version_label" length:"1,64" error:"BadRequestException"`
+	MaxResults             int    `json:"-" rest:"query:max_results" range:"1,50" error:"BadRequestException"`
+	NextToken              string `json:"-" rest:"query:next_token"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_HostedConfigurationVersionSummary.html
+type APIHostedConfigurationVersionSummary struct {
+	ApplicationId          string
+	ConfigurationProfileId string
+	VersionNumber          int
+	Description            string `json:",omitempty"`
+	ContentType            string
+	VersionLabel           string `json:",omitempty"`
+}
+
+type ListHostedConfigurationVersionsOutput struct {
+	Items     []APIHostedConfigurationVersionSummary
+	NextToken string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeploymentStrategy.html
+type APIDeploymentStrategy struct {
+	Id                          string
+	Name                        string
+	Description                 string `json:",omitempty"`
+	DeploymentDurationInMinutes int
+	// LINEAR or EXPONENTIAL.
+	GrowthType             string
+	GrowthFactor           float64
+	FinalBakeTimeInMinutes int
+	// NONE or SSM_DOCUMENT.
+	ReplicateTo string
+}
+
+type CreateDeploymentStrategyInput struct {
+	Name                        string            `required:"true" length:"1,64" error:"BadRequestException"`
+	Description                 string            `length:",1024" error:"BadRequestException"`
+	DeploymentDurationInMinutes int               `range:"0,1440" error:"BadRequestException"`
+	FinalBakeTimeInMinutes      int               `range:"0,1440" error:"BadRequestException"`
+	GrowthFactor                float64           `required:"true" range:"1,100" error:"BadRequestException"`
+	GrowthType                  string            `enum:"LINEAR|EXPONENTIAL" error:"BadRequestException"`
+	ReplicateTo                 string            `enum:"NONE|SSM_DOCUMENT" error:"BadRequestException"`
+	Tags                        map[string]string `length:",50" error:"BadRequestException"`
+}
+
+type CreateDeploymentStrategyOutput struct {
+	APIDeploymentStrategy
+	Status int `json:"-" rest:"status"`
+}
+
+type GetDeploymentStrategyInput struct {
+	DeploymentStrategyId string `json:"-" rest:"path:DeploymentStrategyId"`
+}
+
+type GetDeploymentStrategyOutput = APIDeploymentStrategy
+
+type UpdateDeploymentStrategyInput struct {
+	DeploymentStrategyId        string   `json:"-" rest:"path:DeploymentStrategyId"`
+	Description                 *string  `length:",1024" error:"BadRequestException"`
+	DeploymentDurationInMinutes *int     `range:"0,1440" error:"BadRequestException"`
+	FinalBakeTimeInMinutes      *int     `range:"0,1440" error:"BadRequestException"`
+	GrowthFactor                *float64 `range:"1,100" error:"BadRequestException"`
+	GrowthType                  *string  `enum:"LINEAR|EXPONENTIAL" error:"BadRequestException"`
+}
+
+type UpdateDeploymentStrategyOutput = APIDeploymentStrategy
+
+type DeleteDeploymentStrategyInput struct {
+	DeploymentStrategyId string `json:"-" rest:"path:DeploymentStrategyId"`
+}
+
+type DeleteDeploymentStrategyOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type ListDeploymentStrategiesInput struct {
+	MaxResults int    `json:"-" rest:"query:max_results" range:"1,50" error:"BadRequestException"`
+	NextToken  string `json:"-" rest:"query:next_token"`
+}
+
+type ListDeploymentStrategiesOutput struct {
+	Items     []APIDeploymentStrategy
+	NextToken string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeploymentEvent.html
+type APIDeploymentEvent struct {
+	// DEPLOYMENT_STARTED, PERCENTAGE_UPDATED, BAKE_TIME_STARTED, DEPLOYMENT_COMPLETED,
+	// ROLLBACK_STARTED or ROLLBACK_COMPLETED.
+	EventType string
+	// USER or APPCONFIG.
+	TriggeredBy string
+	Description string
+	OccurredAt  float64
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_StartDeployment.html#API_StartDeployment_ResponseSyntax
+type APIDeployment struct {
+	ApplicationId               string
+	EnvironmentId               string
+	DeploymentStrategyId        string
+	ConfigurationProfileId      string
+	DeploymentNumber            int
+	ConfigurationName           string
+	ConfigurationLocationUri    string
+	ConfigurationVersion        string
+	Description                 string `json:",omitempty"`
+	DeploymentDurationInMinutes int
+	GrowthType                  string
+	GrowthFactor                float64
+	FinalBakeTimeInMinutes      int
+	// BAKING, VALIDATING, DEPLOYING, COMPLETE, ROLLING_BACK or ROLLED_BACK.
+	State              string
+	EventLog           []APIDeploymentEvent
+	PercentageComplete float64
+	StartedAt          float64
+	CompletedAt        float64 `json:",omitempty"`
+	VersionLabel       string  `json:",omitempty"`
+}
+
+type StartDeploymentInput struct {
+	ApplicationId          string            `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId          string            `json:"-" rest:"path:EnvironmentId"`
+	DeploymentStrategyId   string            `required:"true" error:"BadRequestException"`
+	ConfigurationProfileId string            `required:"true" error:"BadRequestException"`
+	ConfigurationVersion   string            `required:"true" length:"1,1024" error:"BadRequestException"`
+	Description            string            `length:",1024" error:"BadRequestException"`
+	Tags                   map[string]string `length:",50" error:"BadRequestException"`
+}
+
+type StartDeploymentOutput struct {
+	APIDeployment
+	Status int `json:"-" rest:"status"`
+}
+
+type GetDeploymentInput struct {
+	ApplicationId    string `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId    string `json:"-" rest:"path:EnvironmentId"`
+	DeploymentNumber int    `json:"-" rest:"path:DeploymentNumber"`
+}
+
+type GetDeploymentOutput = APIDeployment
+
+type StopDeploymentInput struct {
+	ApplicationId    string `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId    string `json:"-" rest:"path:EnvironmentId"`
+	DeploymentNumber int    `json:"-" rest:"path:DeploymentNumber"`
+}
+
+type StopDeploymentOutput struct {
+	APIDeployment
+	Status int `json:"-" rest:"status"`
+}
+
+type ListDeploymentsInput struct {
+	ApplicationId string `json:"-" rest:"path:ApplicationId"`
+	EnvironmentId string `json:"-" rest:"path:EnvironmentId"`
+	MaxResults    int    `json:"-" rest:"query:max_results" range:"1,50" error:"BadRequestException"`
+	NextToken     string `json:"-" rest:"query:next_token"`
+}
+
+// https://docs.aws.amazon.com/appconfig/2019-10-09/APIReference/API_DeploymentSummary.html
+type APIDeploymentSummary struct {
+	DeploymentNumber            int
+	ConfigurationName           string
+	ConfigurationVersion        string
+	DeploymentDurationInMinutes int
+	GrowthType                  string
+	GrowthFactor                float64
+	FinalBakeTimeInMinutes      int
+	State                       string
+	PercentageComplete          float64
+	StartedAt                   float64
+	CompletedAt                 float64 `json:",omitempty"`
+	VersionLabel                string  `json:",omitempty"`
+}
+
+type ListDeploymentsOutput struct {
+	Items     []APIDeploymentSummary
+	NextToken string `json:",omitempty"`
+}
+
+type TagResourceInput struct {
+	ResourceArn string            `json:"-" rest:"path:ResourceArn"`
+	Tags        map[string]string `required:"true" length:",50" error:"BadRequestException"`
+}
+
+type TagResourceOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type UntagResourceInput struct {
+	ResourceArn string   `json:"-" rest:"path:ResourceArn"`
+	TagKeys     []string `json:"-" rest:"query:tagKeys" required:"true" length:",50" error:"BadRequestException"`
+}
+
+type UntagResourceOutput struct {
+	Status int `json:"-" rest:"status"`
+}
+
+type ListTagsForResourceInput struct {
+	ResourceArn string `json:"-" rest:"path:ResourceArn"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags map[string]string
+}
+
+// AppConfigData
+
+type StartConfigurationSessionInput struct {
+	ApplicationIdentifier                string `required:"true" length:"1,128" error:"BadRequestException"`
+	EnvironmentIdentifier                string `required:"true" length:"1,128" error:"BadRequestException"`
+	ConfigurationProfileIdentifier       string `required:"true" length:"1,128" error:"BadRequestException"`
+	RequiredMinimumPollIntervalInSeconds int    `range:"15,86400" error:"BadRequestException"`
+}
+
+type StartConfigurationSessionOutput struct {
+	InitialConfigurationToken string
+	Status                    int `json:"-" rest:"status"`
+}
+
+type GetLatestConfigurationInput struct {
+	ConfigurationToken string `json:"-" rest:"query:configuration_token" required:"true" error:"BadRequestException"`
+}
+
+type GetLatestConfigurationOutput struct {
+	NextPollConfigurationToken string `json:"-" rest:"header:Next-Poll-Configuration-Token"`
+	NextPollIntervalInSeconds  int    `json:"-" rest:"header:Next-Poll-Interval-In-Seconds"`
+	ContentType                string `json:"-" rest:"header:Content-Type"`
+	VersionLabel               string `json:"-" rest:"header:Version-Label"`
+	// Empty if the configuration hasn't changed since the session's last poll.
+	Configuration []byte `json:"-" rest:"body"`
+}
@@ -0,0 +1,112 @@
+package athena
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/glue"
+	"aws-in-a-box/services/s3"
+)
+
+// Catalog is the Glue Data Catalog, which has the tables that are queried.
+type Catalog interface {
+	GetTable(input glue.GetTableInput) (*glue.GetTableOutput, *awserrors.Error)
+	GetPartitions(input glue.GetPartitionsInput) (*glue.GetPartitionsOutput, *awserrors.Error)
+	// InRegion returns the region's Data Catalog, which that region's queries read.
+	InRegion(name string) (*glue.Glue, error)
+}
+
+// Objects is the local S3 service, which tables are read from and results are written to.
+type Objects interface {
+	ListObjectsV2(input s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, *awserrors.Error)
+	GetObject(input s3.GetObjectInput) (*s3.GetObjectOutput, *awserrors.Error)
+	PutObject(input s3.PutObjectInput) (*s3.PutObjectOutput, *awserrors.Error)
+}
+
+const (
+	primaryWorkGroup = "primary"
+	engineVersion    = "Athena engine version 3"
+)
+
+type Athena struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	catalog      Catalog
+	s3           Objects
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu         sync.Mutex
+	workGroups map[string]*WorkGroup
+	executions map[string]*QueryExecution
+	// Execution IDs in the order they were started.
+	executionIds []string
+	// Execution IDs keyed by their ClientRequestToken, so retried requests aren't run twice.
+	clientRequestTokens map[string]string
+	// Shared by the instances of all regions, which each have their own work groups and query
+	// executions.
+	regions *region.Regions[*Athena]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// Optional, but queries of tables fail without it.
+	Catalog Catalog
+	// Optional, but queries fail without it, since their results can't be written.
+	S3 Objects
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Athena {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// S3 is global, so every region reads tables from and writes results to the same buckets.
+	a := newRegion(options)
+	a.regions = region.New(options.ArnGenerator.Region, options.Regions, a, func(name string) (*Athena, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.Catalog != nil {
+			catalog, err := options.Catalog.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.Catalog = catalog
+		}
+		r := newRegion(regionOptions)
+		r.regions = a.regions
+		return r, nil
+	})
+	return a
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *Athena {
+	a := &Athena{
+		logger:              options.Logger,
+		arnGenerator:        options.ArnGenerator,
+		catalog:             options.Catalog,
+		s3:                  options.S3,
+		clock:               clock.Now,
+		workGroups:          make(map[string]*WorkGroup),
+		executions:          make(map[string]*QueryExecution),
+		clientRequestTokens: make(map[string]string),
+	}
+	// Every account has the primary work group, which can't be deleted.
+	a.workGroups[primaryWorkGroup] = &WorkGroup{
+		Name:    primaryWorkGroup,
+		State:   "ENABLED",
+		Created: a.clock(),
+	}
+	return a
+}
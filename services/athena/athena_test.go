@@ -0,0 +1,353 @@
+package athena
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/glue"
+	"aws-in-a-box/services/s3"
+)
+
+func newAthena(t *testing.T) (*Athena, *glue.Glue, *s3.S3) {
+	generator := arn.Generator{
+		AwsAccountId: "123456789012",
+		Region:       "us-east-1",
+	}
+	s3Service, err := s3.New(s3.Options{PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	glueService := glue.New(glue.Options{ArnGenerator: generator})
+	a := New(Options{
+		ArnGenerator: generator,
+		Catalog:      glueService,
+		S3:           s3Service,
+	})
+	a.clock = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	return a, glueService, s3Service
+}
+
+func putObject(t *testing.T, s *s3.S3, bucket string, key string, content string) {
+	_, awserr := s.PutObject(s3.PutObjectInput{Bucket: bucket, Key: key, Data: strings.NewReader(content)})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+// createTables creates a partitioned CSV table of orders and a JSON table of customers.
+func createTables(t *testing.T, g *glue.Glue, s *s3.S3) {
+	for _, bucket := range []string{"data", "results"} {
+		if _, awserr := s.CreateBucket(s3.CreateBucketInput{Bucket: bucket}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	putObject(t, s, "data", "orders/year=2023/part-0.csv", "id,customer,amount\n1,alice,10.5\n2,bob,20\n")
+	putObject(t, s, "data", "orders/year=2024/part-0.csv", "id,customer,amount\n3,\"alice, jr\",4.5\n")
+	putObject(t, s, "data", "orders/year=2024/_SUCCESS", "")
+	putObject(t, s, "data", "customers/customers.json", `{"Name": "alice", "tier": 1}`+"\n"+`{"name": "bob", "tier": 2, "tags": ["new"]}`+"\n")
+
+	if _, awserr := g.CreateDatabase(glue.CreateDatabaseInput{DatabaseInput: glue.APIDatabaseInput{Name: "sales"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr := g.CreateTable(glue.CreateTableInput{
+		DatabaseName: "sales",
+		TableInput: glue.APITableInput{
+			Name: "orders",
+			StorageDescriptor: &glue.APIStorageDescriptor{
+				Columns:   []glue.APIColumn{{Name: "id", Type: "int"}, {Name: "customer", Type: "string"}, {Name: "amount", Type: "double"}},
+				Location:  "s3://data/orders/",
+				SerdeInfo: &glue.APISerDeInfo{SerializationLibrary: "org.apache.hadoop.hive.serde2.OpenCSVSerde"},
+			},
+			PartitionKeys: []glue.APIColumn{{Name: "year", Type: "int"}},
+			Parameters:    map[string]string{"skip.header.line.count": "1"},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	for _, year := range []string{"2023", "2024"} {
+		_, awserr := g.CreatePartition(glue.CreatePartitionInput{
+			DatabaseName: "sales",
+			TableName:    "orders",
+			PartitionInput: glue.APIPartitionInput{
+				Values:            []string{year},
+				StorageDescriptor: &glue.APIStorageDescriptor{Location: "s3://data/orders/year=" + year},
+			},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	_, awserr = g.CreateTable(glue.CreateTableInput{
+		DatabaseName: "sales",
+		TableInput: glue.APITableInput{
+			Name: "customers",
+			StorageDescriptor: &glue.APIStorageDescriptor{
+				Columns:   []glue.APIColumn{{Name: "name", Type: "string"}, {Name: "tier", Type: "bigint"}, {Name: "tags", Type: "array<string>"}},
+				Location:  "s3://data/customers",
+				SerdeInfo: &glue.APISerDeInfo{SerializationLibrary: "org.openx.data.jsonserde.JsonSerDe"},
+			},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func runQuery(t *testing.T, a *Athena, query string) *GetQueryExecutionOutput {
+	started, awserr := a.StartQueryExecution(StartQueryExecutionInput{
+		QueryString:           query,
+		QueryExecutionContext: &APIQueryExecutionContext{Database: "sales"},
+		ResultConfiguration:   &APIResultConfiguration{OutputLocation: "s3://results/athena"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	execution, awserr := a.GetQueryExecution(GetQueryExecutionInput{QueryExecutionId: started.QueryExecutionId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return execution
+}
+
+func resultRows(t *testing.T, a *Athena, id string) []string {
+	results, awserr := a.GetQueryResults(GetQueryResultsInput{QueryExecutionId: id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var rows []string
+	for _, row := range results.ResultSet.Rows {
+		var values []string
+		for _, datum := range row.Data {
+			if datum.VarCharValue == nil {
+				values = append(values, "NULL")
+			} else {
+				values = append(values, *datum.VarCharValue)
+			}
+		}
+		rows = append(rows, strings.Join(values, "|"))
+	}
+	return rows
+}
+
+func TestQueries(t *testing.T) {
+	a, g, s := newAthena(t)
+	createTables(t, g, s)
+
+	for query, expected := range map[string][]string{
+		"SELECT * FROM orders ORDER BY id": {
+			"id|customer|amount|year",
+			"1|alice|10.5|2023",
+			"2|bob|20.0|2023",
+			"3|alice, jr|4.5|2024",
+		},
+		"SELECT year, sum(amount) AS total FROM sales.orders WHERE year >= 2023 GROUP BY year ORDER BY year": {
+			"year|total",
+			"2023|30.5",
+			"2024|4.5",
+		},
+		`SELECT name, tier, tags FROM "sales"."customers" WHERE tier > 0 ORDER BY name`: {
+			"name|tier|tags",
+			"alice|1|NULL",
+			`bob|2|["new"]`,
+		},
+	} {
+		execution := runQuery(t, a, query)
+		status := execution.QueryExecution.Status
+		if status.State != "SUCCEEDED" {
+			t.Fatal(query, "unexpected status", status)
+		}
+		if rows := resultRows(t, a, execution.QueryExecution.QueryExecutionId); !slices.Equal(rows, expected) {
+			t.Fatal(query, "unexpected rows", rows)
+		}
+	}
+
+	execution := runQuery(t, a, "SELECT id, customer FROM orders WHERE id = 3")
+	id := execution.QueryExecution.QueryExecutionId
+	if execution.QueryExecution.ResultConfiguration.OutputLocation != "s3://results/athena/"+id+".csv" {
+		t.Fatal("Unexpected output location", execution.QueryExecution.ResultConfiguration)
+	}
+	if execution.QueryExecution.Statistics.DataScannedInBytes == 0 {
+		t.Fatal("Expected data to be scanned")
+	}
+	object, awserr := s.GetObject(s3.GetObjectInput{Bucket: "results", Key: "athena/" + id + ".csv"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	content, err := io.ReadAll(object.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, []byte("\"id\",\"customer\"\n\"3\",\"alice, jr\"\n")) {
+		t.Fatal("Unexpected results file", string(content))
+	}
+	results, awserr := a.GetQueryResults(GetQueryResultsInput{QueryExecutionId: id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	columns := results.ResultSet.ResultSetMetadata.ColumnInfo
+	if len(columns) != 2 || columns[0].Type != "integer" || columns[1].Type != "varchar" || !columns[1].CaseSensitive {
+		t.Fatal("Unexpected columns", columns)
+	}
+}
+
+func TestFailedQueries(t *testing.T) {
+	a, g, s := newAthena(t)
+	createTables(t, g, s)
+
+	for query, expected := range map[string]string{
+		"SELECT * FROM missing":         "TABLE_NOT_FOUND: Table 'awsdatacatalog.sales.missing' does not exist",
+		"SELECT FROM orders":            "SYNTAX_ERROR: ",
+		"DROP TABLE orders":             "SYNTAX_ERROR: only SELECT queries are supported",
+		"SELECT unknown FROM customers": "Column 'unknown' cannot be resolved",
+	} {
+		execution := runQuery(t, a, query)
+		status := execution.QueryExecution.Status
+		if status.State != "FAILED" || !strings.HasPrefix(status.StateChangeReason, expected) || status.AthenaError == nil {
+			t.Fatal(query, "unexpected status", status)
+		}
+		_, awserr := a.GetQueryResults(GetQueryResultsInput{QueryExecutionId: execution.QueryExecution.QueryExecutionId})
+		if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+			t.Fatal(query, "expected results to be unavailable", awserr)
+		}
+	}
+
+	_, awserr := a.StartQueryExecution(StartQueryExecutionInput{QueryString: "SELECT 1"})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Fatal("Expected an output location to be required", awserr)
+	}
+}
+
+func TestWorkGroups(t *testing.T) {
+	a, _, s := newAthena(t)
+	if _, awserr := s.CreateBucket(s3.CreateBucketInput{Bucket: "results"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	_, awserr := a.CreateWorkGroup(CreateWorkGroupInput{
+		Name: "analysts",
+		Configuration: &APIWorkGroupConfiguration{
+			ResultConfiguration:           &APIResultConfiguration{OutputLocation: "s3://results/analysts/"},
+			EnforceWorkGroupConfiguration: true,
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = a.CreateWorkGroup(CreateWorkGroupInput{Name: "analysts"})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Fatal("Expected already created", awserr)
+	}
+	workGroups, awserr := a.ListWorkGroups(ListWorkGroupsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(workGroups.WorkGroups) != 2 || workGroups.WorkGroups[0].Name != "analysts" || workGroups.WorkGroups[1].Name != "primary" {
+		t.Fatal("Unexpected work groups", workGroups.WorkGroups)
+	}
+
+	// The enforced output location is used rather than the request's.
+	started, awserr := a.StartQueryExecution(StartQueryExecutionInput{
+		QueryString:         "SELECT 1",
+		WorkGroup:           "analysts",
+		ResultConfiguration: &APIResultConfiguration{OutputLocation: "s3://elsewhere/"},
+		ClientRequestToken:  "token",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	retried, awserr := a.StartQueryExecution(StartQueryExecutionInput{QueryString: "SELECT 1", WorkGroup: "analysts", ClientRequestToken: "token"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if retried.QueryExecutionId != started.QueryExecutionId {
+		t.Fatal("Expected the retried query to have the same ID", retried.QueryExecutionId)
+	}
+	execution, awserr := a.GetQueryExecution(GetQueryExecutionInput{QueryExecutionId: started.QueryExecutionId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if execution.QueryExecution.Status.State != "SUCCEEDED" ||
+		execution.QueryExecution.ResultConfiguration.OutputLocation != "s3://results/analysts/"+started.QueryExecutionId+".csv" {
+		t.Fatal("Unexpected execution", execution.QueryExecution)
+	}
+
+	listed, awserr := a.ListQueryExecutions(ListQueryExecutionsInput{WorkGroup: "analysts"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(listed.QueryExecutionIds, []string{started.QueryExecutionId}) {
+		t.Fatal("Unexpected executions", listed.QueryExecutionIds)
+	}
+
+	_, awserr = a.DeleteWorkGroup(DeleteWorkGroupInput{WorkGroup: "analysts"})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Fatal("Expected a non-empty work group to not be deleted", awserr)
+	}
+	_, awserr = a.DeleteWorkGroup(DeleteWorkGroupInput{WorkGroup: "analysts", RecursiveDeleteOption: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = a.GetQueryExecution(GetQueryExecutionInput{QueryExecutionId: started.QueryExecutionId})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Fatal("Expected the execution to be deleted", awserr)
+	}
+	_, awserr = a.DeleteWorkGroup(DeleteWorkGroupInput{WorkGroup: "primary"})
+	if awserr == nil {
+		t.Fatal("Expected the primary work group to not be deleted")
+	}
+}
+
+func TestRegions(t *testing.T) {
+	a, g, s := newAthena(t)
+	createTables(t, g, s)
+	if _, err := a.regions.Get("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := a.regions.Get("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	europe.clock = a.clock
+
+	// Queries read the Data Catalog of their region, which has none of us-east-1's tables.
+	execution := runQuery(t, europe, "SELECT id FROM orders")
+	if status := execution.QueryExecution.Status; status.State != "FAILED" {
+		t.Fatal("Expected the table not to be in eu-west-1", status)
+	}
+	europeCatalog, err := g.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europeCatalog.CreateDatabase(glue.CreateDatabaseInput{DatabaseInput: glue.APIDatabaseInput{Name: "sales"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if execution := runQuery(t, europe, "SELECT 1"); execution.QueryExecution.Status.State != "SUCCEEDED" {
+		t.Fatal("Unexpected status", execution.QueryExecution.Status)
+	}
+
+	// Each region has its own work groups and query executions.
+	if _, awserr := europe.GetWorkGroup(GetWorkGroupInput{WorkGroup: primaryWorkGroup}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := europe.CreateWorkGroup(CreateWorkGroupInput{Name: "analysts"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := a.GetWorkGroup(GetWorkGroupInput{WorkGroup: "analysts"}); awserr == nil {
+		t.Fatal("Expected the work group not to be in us-east-1")
+	}
+	executions, awserr := a.ListQueryExecutions(ListQueryExecutionsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(executions.QueryExecutionIds) != 0 {
+		t.Fatal("Expected eu-west-1's executions not to be in us-east-1", executions.QueryExecutionIds)
+	}
+}
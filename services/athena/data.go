@@ -0,0 +1,361 @@
+package athena
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/services/glue"
+	"aws-in-a-box/services/s3"
+)
+
+type tableColumn struct {
+	// In lower case.
+	name string
+	// The Hive type, such as string or bigint.
+	typ string
+}
+
+type tableData struct {
+	// The table's columns, followed by its partition keys.
+	columns []tableColumn
+	rows    []map[string]any
+	// The size of the objects which were read.
+	scannedBytes int64
+}
+
+// rowFormat is how a table's objects are parsed, which depends on its SerDe.
+// See https://docs.aws.amazon.com/athena/latest/ug/supported-serdes.html
+type rowFormat struct {
+	json bool
+	// For CSV and delimited text.
+	delimiter rune
+	// Whether fields can be quoted, which OpenCSVSerde supports but LazySimpleSerDe doesn't.
+	quoted bool
+	// Lines to skip at the start of each object.
+	skipHeaderLines int
+	// The representation of NULL in delimited text.
+	nullValue string
+}
+
+func tableRowFormat(storageDescriptor *glue.APIStorageDescriptor, tableParameters map[string]string) (rowFormat, error) {
+	format := rowFormat{delimiter: ',', quoted: true, nullValue: ""}
+	if skip := tableParameters["skip.header.line.count"]; skip != "" {
+		n, err := strconv.Atoi(skip)
+		if err != nil || n < 0 {
+			return format, fmt.Errorf("invalid skip.header.line.count %q", skip)
+		}
+		format.skipHeaderLines = n
+	}
+
+	var library string
+	var parameters map[string]string
+	if storageDescriptor != nil && storageDescriptor.SerdeInfo != nil {
+		library = strings.ToLower(storageDescriptor.SerdeInfo.SerializationLibrary)
+		parameters = storageDescriptor.SerdeInfo.Parameters
+	}
+	switch {
+	case strings.Contains(library, "json"):
+		format.json = true
+	case strings.Contains(library, "opencsvserde"):
+		if separator := parameters["separatorChar"]; separator != "" {
+			format.delimiter = []rune(separator)[0]
+		}
+	case strings.Contains(library, "lazysimpleserde"):
+		// Hive's default delimiter is Ctrl-A.
+		format.delimiter = '\x01'
+		format.quoted = false
+		format.nullValue = `\N`
+		for _, key := range []string{"field.delim", "serialization.format"} {
+			if delimiter := parameters[key]; delimiter != "" && !isDigits(delimiter) {
+				format.delimiter = []rune(delimiter)[0]
+				break
+			}
+		}
+		if nullValue, ok := parameters["serialization.null.format"]; ok {
+			format.nullValue = nullValue
+		}
+	case library == "":
+		// Tables without a SerDe, which Glue allows, are read according to their classification.
+		format.json = strings.EqualFold(tableParameters["classification"], "json")
+	default:
+		return format, fmt.Errorf("HIVE_UNSUPPORTED_FORMAT: SerDe %s is not supported", storageDescriptor.SerdeInfo.SerializationLibrary)
+	}
+	return format, nil
+}
+
+func isDigits(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// parseS3Location splits an s3://bucket/prefix location into the bucket and prefix, which ends with /.
+func parseS3Location(location string) (string, string, error) {
+	withoutScheme, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		withoutScheme, ok = strings.CutPrefix(location, "s3a://")
+	}
+	if !ok {
+		return "", "", fmt.Errorf("location %q is not in S3", location)
+	}
+	bucket, prefix, _ := strings.Cut(withoutScheme, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("location %q has no bucket", location)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return bucket, prefix, nil
+}
+
+func (a *Athena) loadTable(reference *tableReference, defaultDatabase string) (*tableData, error) {
+	if a.catalog == nil {
+		return nil, errors.New("Glue must be enabled to query tables")
+	}
+	database := reference.database
+	if database == "" {
+		database = defaultDatabase
+	}
+	tableOutput, awserr := a.catalog.GetTable(glue.GetTableInput{DatabaseName: database, Name: reference.table})
+	if awserr != nil {
+		if awserr.Body.Type == "EntityNotFoundException" {
+			return nil, fmt.Errorf("TABLE_NOT_FOUND: Table 'awsdatacatalog.%s.%s' does not exist", database, reference.table)
+		}
+		return nil, errors.New(awserr.Body.Message)
+	}
+	table := tableOutput.Table
+	if table.TableType == "VIRTUAL_VIEW" {
+		return nil, errors.New("NOT_SUPPORTED: Views are not supported")
+	}
+
+	data := &tableData{}
+	if table.StorageDescriptor != nil {
+		for _, column := range table.StorageDescriptor.Columns {
+			data.columns = append(data.columns, tableColumn{strings.ToLower(column.Name), column.Type})
+		}
+	}
+	for _, key := range table.PartitionKeys {
+		data.columns = append(data.columns, tableColumn{strings.ToLower(key.Name), key.Type})
+	}
+
+	if len(table.PartitionKeys) == 0 {
+		if table.StorageDescriptor == nil || table.StorageDescriptor.Location == "" {
+			return data, nil
+		}
+		err := a.loadLocation(data, table.StorageDescriptor, table.Parameters, nil)
+		return data, err
+	}
+
+	// Each partition's location is read, and its values are added to its rows.
+	input := glue.GetPartitionsInput{DatabaseName: database, TableName: reference.table}
+	for {
+		partitions, awserr := a.catalog.GetPartitions(input)
+		if awserr != nil {
+			return nil, errors.New(awserr.Body.Message)
+		}
+		for _, partition := range partitions.Partitions {
+			storageDescriptor := partition.StorageDescriptor
+			if storageDescriptor == nil || storageDescriptor.Location == "" {
+				continue
+			}
+			if storageDescriptor.SerdeInfo == nil && table.StorageDescriptor != nil {
+				withSerDe := *storageDescriptor
+				withSerDe.SerdeInfo = table.StorageDescriptor.SerdeInfo
+				storageDescriptor = &withSerDe
+			}
+			values := make(map[string]any, len(table.PartitionKeys))
+			for i, key := range table.PartitionKeys {
+				if i < len(partition.Values) {
+					values[strings.ToLower(key.Name)] = convertField(partition.Values[i], key.Type)
+				}
+			}
+			if err := a.loadLocation(data, storageDescriptor, table.Parameters, values); err != nil {
+				return nil, err
+			}
+		}
+		if partitions.NextToken == "" {
+			return data, nil
+		}
+		input.NextToken = partitions.NextToken
+	}
+}
+
+// loadLocation reads the rows of every object under a location, adding the partition's values to them.
+func (a *Athena) loadLocation(data *tableData, storageDescriptor *glue.APIStorageDescriptor, tableParameters map[string]string, partitionValues map[string]any) error {
+	format, err := tableRowFormat(storageDescriptor, tableParameters)
+	if err != nil {
+		return err
+	}
+	bucket, prefix, err := parseS3Location(storageDescriptor.Location)
+	if err != nil {
+		return err
+	}
+
+	input := s3.ListObjectsV2Input{Bucket: bucket, Prefix: &prefix}
+	for {
+		list, awserr := a.s3.ListObjectsV2(input)
+		if awserr != nil {
+			return fmt.Errorf("HIVE_CANNOT_OPEN_SPLIT: Error listing s3://%s/%s", bucket, prefix)
+		}
+		for _, object := range list.Contents {
+			// Directory markers and hidden files, such as _SUCCESS, aren't data.
+			name := path.Base(object.Key)
+			if strings.HasSuffix(object.Key, "/") || strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+				continue
+			}
+			rows, size, err := a.readObject(bucket, object.Key, format, data.columns)
+			if err != nil {
+				return fmt.Errorf("HIVE_BAD_DATA: Error reading s3://%s/%s: %v", bucket, object.Key, err)
+			}
+			data.scannedBytes += size
+			for _, row := range rows {
+				for key, value := range partitionValues {
+					row[key] = value
+				}
+				data.rows = append(data.rows, row)
+			}
+		}
+		if !list.IsTruncated {
+			return nil
+		}
+		token := list.NextContinuationToken
+		input.ContinuationToken = &token
+	}
+}
+
+func (a *Athena) readObject(bucket string, key string, format rowFormat, columns []tableColumn) ([]map[string]any, int64, error) {
+	object, awserr := a.s3.GetObject(s3.GetObjectInput{Bucket: bucket, Key: key})
+	if awserr != nil {
+		return nil, 0, errors.New("object not found")
+	}
+	if closer, ok := object.Body.(io.Closer); ok {
+		defer closer.Close()
+	}
+	content, err := io.ReadAll(object.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	size := int64(len(content))
+	if strings.HasSuffix(key, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, 0, err
+		}
+		content, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if format.json {
+		rows, err := parseJSONRows(content, columns)
+		return rows, size, err
+	}
+	rows, err := parseDelimitedRows(content, format, columns)
+	return rows, size, err
+}
+
+func parseDelimitedRows(content []byte, format rowFormat, columns []tableColumn) ([]map[string]any, error) {
+	var records [][]string
+	if format.quoted {
+		reader := csv.NewReader(bytes.NewReader(content))
+		reader.Comma = format.delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		var err error
+		records, err = reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(nil, 16<<20)
+		for scanner.Scan() {
+			line := strings.TrimSuffix(scanner.Text(), "\r")
+			if line == "" {
+				continue
+			}
+			records = append(records, strings.Split(line, string(format.delimiter)))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	records = records[min(format.skipHeaderLines, len(records)):]
+
+	rows := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		row := make(map[string]any, len(columns))
+		// Missing fields are NULL, and extra fields are ignored.
+		for i, column := range columns {
+			if i < len(record) && !(format.nullValue != "" && record[i] == format.nullValue) {
+				row[column.name] = convertField(record[i], column.typ)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSONRows parses objects with a JSON object on each line, whose keys are case insensitive.
+func parseJSONRows(content []byte, columns []tableColumn) ([]map[string]any, error) {
+	var rows []map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	for {
+		var object map[string]any
+		if err := decoder.Decode(&object); err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]any, len(object))
+		for key, value := range object {
+			fields[strings.ToLower(key)] = value
+		}
+		row := make(map[string]any, len(columns))
+		for _, column := range columns {
+			switch value := fields[column.name].(type) {
+			case nil:
+			case string:
+				row[column.name] = convertField(value, column.typ)
+			case json.Number:
+				row[column.name] = convertField(value.String(), column.typ)
+			case bool:
+				row[column.name] = convertField(strconv.FormatBool(value), column.typ)
+			default:
+				// Arrays, maps and structs are returned as JSON.
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+				row[column.name] = string(encoded)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// convertField converts a field to its column's type, or NULL if it isn't valid.
+func convertField(field string, typ string) any {
+	valueType, _ := parseValueType(typ)
+	if valueType == typeVarchar {
+		return field
+	}
+	if strings.TrimSpace(field) == "" {
+		return nil
+	}
+	value, err := convertValue(field, valueType)
+	if err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,11 @@
+package athena
+
+import "aws-in-a-box/awserrors"
+
+func InvalidRequestException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidRequestException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
@@ -0,0 +1,412 @@
+package athena
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/timestamp"
+)
+
+// Queries run synchronously in StartQueryExecution, so executions have always finished by the time
+// they can be fetched.
+type QueryExecution struct {
+	Id                  string
+	Query               string
+	WorkGroup           string
+	Context             APIQueryExecutionContext
+	ResultConfiguration APIResultConfiguration
+	ExecutionParameters []string
+	EngineVersion       APIEngineVersion
+
+	State             string
+	StateChangeReason string
+	Submitted         time.Time
+	Completed         time.Time
+	DataScanned       int64
+	result            *queryResult
+}
+
+func (e *QueryExecution) output() APIQueryExecution {
+	elapsed := e.Completed.Sub(e.Submitted).Milliseconds()
+	execution := APIQueryExecution{
+		QueryExecutionId:      e.Id,
+		Query:                 e.Query,
+		StatementType:         "DML",
+		SubstatementType:      "SELECT",
+		ResultConfiguration:   e.ResultConfiguration,
+		QueryExecutionContext: e.Context,
+		Status: APIQueryExecutionStatus{
+			State:              e.State,
+			StateChangeReason:  e.StateChangeReason,
+			SubmissionDateTime: timestamp.EpochSeconds(e.Submitted),
+			CompletionDateTime: timestamp.EpochSeconds(e.Completed),
+		},
+		Statistics: APIQueryExecutionStatistics{
+			EngineExecutionTimeInMillis: elapsed,
+			TotalExecutionTimeInMillis:  elapsed,
+			DataScannedInBytes:          e.DataScanned,
+		},
+		WorkGroup:           e.WorkGroup,
+		EngineVersion:       e.EngineVersion,
+		ExecutionParameters: e.ExecutionParameters,
+	}
+	if e.State == "FAILED" {
+		execution.Status.AthenaError = &APIAthenaError{
+			// A user error, such as a query which doesn't parse.
+			ErrorCategory: 2,
+			ErrorType:     1006,
+			ErrorMessage:  e.StateChangeReason,
+		}
+	}
+	return execution
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_StartQueryExecution.html
+func (a *Athena) StartQueryExecution(input StartQueryExecutionInput) (*StartQueryExecutionOutput, *awserrors.Error) {
+	if len(input.QueryString) < 1 || len(input.QueryString) > 262144 {
+		return nil, InvalidRequestException("QueryString must be between 1 and 262144 characters.")
+	}
+	if input.WorkGroup == "" {
+		input.WorkGroup = primaryWorkGroup
+	}
+
+	a.mu.Lock()
+	if id, ok := a.clientRequestTokens[input.ClientRequestToken]; ok && input.ClientRequestToken != "" {
+		a.mu.Unlock()
+		return &StartQueryExecutionOutput{QueryExecutionId: id}, nil
+	}
+	workGroup, err := a.lockedGetWorkGroup(input.WorkGroup)
+	if err != nil {
+		a.mu.Unlock()
+		return nil, err
+	}
+	if workGroup.State != "ENABLED" {
+		a.mu.Unlock()
+		return nil, InvalidRequestException("WorkGroup " + workGroup.Name + " is disabled.")
+	}
+
+	// The work group's result configuration is the default, and it can't be overridden if it's enforced.
+	var resultConfiguration APIResultConfiguration
+	if workGroup.Configuration.ResultConfiguration != nil {
+		resultConfiguration = *workGroup.Configuration.ResultConfiguration
+	}
+	if input.ResultConfiguration != nil && !workGroup.Configuration.EnforceWorkGroupConfiguration {
+		if input.ResultConfiguration.OutputLocation != "" {
+			resultConfiguration.OutputLocation = input.ResultConfiguration.OutputLocation
+		}
+		if input.ResultConfiguration.EncryptionConfiguration != nil {
+			resultConfiguration.EncryptionConfiguration = input.ResultConfiguration.EncryptionConfiguration
+		}
+		if input.ResultConfiguration.ExpectedBucketOwner != "" {
+			resultConfiguration.ExpectedBucketOwner = input.ResultConfiguration.ExpectedBucketOwner
+		}
+		if input.ResultConfiguration.AclConfiguration != nil {
+			resultConfiguration.AclConfiguration = input.ResultConfiguration.AclConfiguration
+		}
+	}
+	if resultConfiguration.OutputLocation == "" {
+		a.mu.Unlock()
+		return nil, InvalidRequestException("No output location provided. An output location is required either through the Workgroup result configuration setting or as an API input.")
+	}
+	bucket, prefix, parseErr := parseS3Location(resultConfiguration.OutputLocation)
+	if parseErr != nil {
+		a.mu.Unlock()
+		return nil, InvalidRequestException(parseErr.Error())
+	}
+
+	execution := &QueryExecution{
+		Id:                  uuid.Must(uuid.NewV4()).String(),
+		Query:               input.QueryString,
+		WorkGroup:           workGroup.Name,
+		ExecutionParameters: input.ExecutionParameters,
+		EngineVersion:       workGroup.engineVersion(),
+		Submitted:           a.clock(),
+	}
+	if input.QueryExecutionContext != nil {
+		execution.Context = *input.QueryExecutionContext
+	}
+	// Athena reports the location of the results file rather than the prefix it was written under.
+	resultConfiguration.OutputLocation = "s3://" + bucket + "/" + prefix + execution.Id + ".csv"
+	execution.ResultConfiguration = resultConfiguration
+	cutoff := workGroup.Configuration.BytesScannedCutoffPerQuery
+	a.mu.Unlock()
+
+	// Queries run without the lock held, since they read from the catalog and S3.
+	result, scanned, runErr := a.runQuery(execution, cutoff)
+	if runErr == nil {
+		runErr = a.writeResults(bucket, prefix+execution.Id+".csv", result)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	execution.Completed = a.clock()
+	execution.DataScanned = scanned
+	if runErr != nil {
+		execution.State = "FAILED"
+		execution.StateChangeReason = runErr.Error()
+		a.logger.Info("Athena query failed", "id", execution.Id, "error", runErr)
+	} else {
+		execution.State = "SUCCEEDED"
+		execution.result = result
+	}
+	a.executions[execution.Id] = execution
+	a.executionIds = append(a.executionIds, execution.Id)
+	if input.ClientRequestToken != "" {
+		a.clientRequestTokens[input.ClientRequestToken] = execution.Id
+	}
+	return &StartQueryExecutionOutput{QueryExecutionId: execution.Id}, nil
+}
+
+// runQuery returns the query's result and the number of bytes it scanned.
+func (a *Athena) runQuery(execution *QueryExecution, bytesScannedCutoff int64) (*queryResult, int64, error) {
+	if a.s3 == nil {
+		return nil, 0, errors.New("S3 must be enabled to run queries")
+	}
+	statement, err := parseQuery(execution.Query, execution.ExecutionParameters)
+	if err != nil {
+		return nil, 0, fmt.Errorf("SYNTAX_ERROR: %v", err)
+	}
+
+	var data *tableData
+	if statement.from != nil {
+		database := execution.Context.Database
+		if database == "" {
+			database = "default"
+		}
+		data, err = a.loadTable(statement.from, database)
+		if err != nil {
+			return nil, 0, err
+		}
+		if bytesScannedCutoff > 0 && data.scannedBytes > bytesScannedCutoff {
+			return nil, data.scannedBytes, fmt.Errorf("Query exhausted the data scanned limit of %d bytes", bytesScannedCutoff)
+		}
+	}
+
+	result, err := executeSelect(statement, data)
+	if data != nil {
+		return result, data.scannedBytes, err
+	}
+	return result, 0, err
+}
+
+// writeResults writes a CSV file with a header row, where every value is quoted and NULL is empty.
+func (a *Athena) writeResults(bucket string, key string, result *queryResult) error {
+	var content bytes.Buffer
+	writeRow := func(values []*string) {
+		for i, v := range values {
+			if i > 0 {
+				content.WriteByte(',')
+			}
+			if v != nil {
+				content.WriteString(`"` + strings.ReplaceAll(*v, `"`, `""`) + `"`)
+			}
+		}
+		content.WriteByte('\n')
+	}
+	var header []*string
+	for _, column := range result.columns {
+		name := column.name
+		header = append(header, &name)
+	}
+	writeRow(header)
+	for _, row := range result.rows {
+		writeRow(formatRow(row))
+	}
+
+	_, awserr := a.s3.PutObject(s3.PutObjectInput{
+		Bucket:      bucket,
+		Key:         key,
+		Data:        &content,
+		ContentType: "text/csv",
+	})
+	if awserr != nil {
+		return fmt.Errorf("Unable to write results to s3://%s/%s: %s", bucket, key, awserr.Body.Message)
+	}
+	return nil
+}
+
+func formatRow(row []any) []*string {
+	formatted := make([]*string, len(row))
+	for i, v := range row {
+		if v != nil {
+			s := formatValue(v)
+			formatted[i] = &s
+		}
+	}
+	return formatted
+}
+
+func (a *Athena) lockedGetQueryExecution(id string) (*QueryExecution, *awserrors.Error) {
+	execution, ok := a.executions[id]
+	if !ok {
+		return nil, InvalidRequestException("QueryExecution " + id + " was not found")
+	}
+	return execution, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_GetQueryExecution.html
+func (a *Athena) GetQueryExecution(input GetQueryExecutionInput) (*GetQueryExecutionOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	execution, err := a.lockedGetQueryExecution(input.QueryExecutionId)
+	if err != nil {
+		return nil, err
+	}
+	return &GetQueryExecutionOutput{QueryExecution: execution.output()}, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_BatchGetQueryExecution.html
+func (a *Athena) BatchGetQueryExecution(input BatchGetQueryExecutionInput) (*BatchGetQueryExecutionOutput, *awserrors.Error) {
+	if len(input.QueryExecutionIds) < 1 || len(input.QueryExecutionIds) > 50 {
+		return nil, InvalidRequestException("QueryExecutionIds must have between 1 and 50 items.")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	output := &BatchGetQueryExecutionOutput{
+		QueryExecutions:              []APIQueryExecution{},
+		UnprocessedQueryExecutionIds: []APIUnprocessedQueryExecutionId{},
+	}
+	for _, id := range input.QueryExecutionIds {
+		execution, err := a.lockedGetQueryExecution(id)
+		if err != nil {
+			output.UnprocessedQueryExecutionIds = append(output.UnprocessedQueryExecutionIds, APIUnprocessedQueryExecutionId{
+				QueryExecutionId: id,
+				ErrorCode:        err.Body.Type,
+				ErrorMessage:     err.Body.Message,
+			})
+			continue
+		}
+		output.QueryExecutions = append(output.QueryExecutions, execution.output())
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_GetQueryResults.html
+func (a *Athena) GetQueryResults(input GetQueryResultsInput) (*GetQueryResultsOutput, *awserrors.Error) {
+	limit, start, err := pagination.Parse(input.MaxResults, 1000, 1000, input.NextToken,
+		InvalidRequestException("MaxResults must be between 1 and 1000."),
+		InvalidRequestException("Invalid NextToken."))
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	execution, err := a.lockedGetQueryExecution(input.QueryExecutionId)
+	if err != nil {
+		return nil, err
+	}
+	if execution.State != "SUCCEEDED" {
+		return nil, InvalidRequestException("Query did not finish successfully. Final query state: " + execution.State + "\n" + execution.StateChangeReason)
+	}
+
+	// The first row of the first page has the column names.
+	rows := []APIRow{}
+	var columnInfo []APIColumnInfo
+	var header []APIDatum
+	for _, column := range execution.result.columns {
+		name := column.name
+		header = append(header, APIDatum{VarCharValue: &name})
+		columnInfo = append(columnInfo, APIColumnInfo{
+			CatalogName:   "hive",
+			Name:          column.name,
+			Label:         column.name,
+			Type:          column.typ,
+			Precision:     columnPrecision(column.typ),
+			Nullable:      "UNKNOWN",
+			CaseSensitive: column.typ == "varchar",
+		})
+	}
+	rows = append(rows, APIRow{Data: header})
+	for _, row := range execution.result.rows {
+		var data []APIDatum
+		for _, v := range formatRow(row) {
+			data = append(data, APIDatum{VarCharValue: v})
+		}
+		rows = append(rows, APIRow{Data: data})
+	}
+
+	rows, nextToken := pagination.Page(rows, limit, start)
+	return &GetQueryResultsOutput{
+		ResultSet: APIResultSet{
+			Rows:              rows,
+			ResultSetMetadata: APIResultSetMetadata{ColumnInfo: columnInfo},
+		},
+		NextToken: nextToken,
+	}, nil
+}
+
+// columnPrecision returns the precision Athena reports for a type, which is the number of digits for
+// numbers and the maximum length of strings.
+func columnPrecision(typ string) int {
+	switch typ {
+	case "tinyint":
+		return 3
+	case "smallint":
+		return 5
+	case "integer":
+		return 10
+	case "bigint":
+		return 19
+	case "real", "double":
+		return 17
+	case "varchar":
+		return 2147483647
+	}
+	return 0
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_StopQueryExecution.html
+func (a *Athena) StopQueryExecution(input StopQueryExecutionInput) (*StopQueryExecutionOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Queries have already finished, so there's nothing to stop.
+	if _, err := a.lockedGetQueryExecution(input.QueryExecutionId); err != nil {
+		return nil, err
+	}
+	return &StopQueryExecutionOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_ListQueryExecutions.html
+func (a *Athena) ListQueryExecutions(input ListQueryExecutionsInput) (*ListQueryExecutionsOutput, *awserrors.Error) {
+	limit, start, err := pagination.Parse(input.MaxResults, 50, 50, input.NextToken,
+		InvalidRequestException("MaxResults must be between 1 and 50."),
+		InvalidRequestException("Invalid NextToken."))
+	if err != nil {
+		return nil, err
+	}
+	if input.WorkGroup == "" {
+		input.WorkGroup = primaryWorkGroup
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.lockedGetWorkGroup(input.WorkGroup); err != nil {
+		return nil, err
+	}
+	// The most recent executions are first.
+	ids := []string{}
+	for i := len(a.executionIds) - 1; i >= 0; i-- {
+		id := a.executionIds[i]
+		if a.executions[id].WorkGroup == input.WorkGroup {
+			ids = append(ids, id)
+		}
+	}
+	ids, nextToken := pagination.Page(ids, limit, start)
+	return &ListQueryExecutionsOutput{QueryExecutionIds: ids, NextToken: nextToken}, nil
+}
@@ -0,0 +1,740 @@
+package athena
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/sqllike"
+)
+
+// Values are nil for NULL, bool, int64, float64 or string. Dates and timestamps are strings, which compare
+// correctly when they're formatted as ISO 8601.
+
+type evalContext struct {
+	// The row's values, keyed by column name.
+	row map[string]any
+	// The names of the columns which can be referenced.
+	columns map[string]bool
+	// The rows being aggregated, or nil outside of aggregation.
+	group []map[string]any
+}
+
+type expression interface {
+	eval(ctx *evalContext) (any, error)
+}
+
+type literal struct {
+	value any
+}
+
+func (e *literal) eval(ctx *evalContext) (any, error) {
+	return e.value, nil
+}
+
+type columnReference struct {
+	name string
+}
+
+func (e *columnReference) eval(ctx *evalContext) (any, error) {
+	if !ctx.columns[e.name] {
+		return nil, fmt.Errorf("Column '%s' cannot be resolved", e.name)
+	}
+	return ctx.row[e.name], nil
+}
+
+type logicalExpression struct {
+	// AND or OR.
+	operator string
+	left     expression
+	right    expression
+}
+
+// eval uses three-valued logic, where NULL is unknown.
+func (e *logicalExpression) eval(ctx *evalContext) (any, error) {
+	left, err := evalBoolean(e.left, ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalBoolean(e.right, ctx)
+	if err != nil {
+		return nil, err
+	}
+	decisive := e.operator == "OR"
+	if left == decisive || right == decisive {
+		return decisive, nil
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	return !decisive, nil
+}
+
+func evalBoolean(e expression, ctx *evalContext) (any, error) {
+	v, err := e.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(bool); !ok && v != nil {
+		return nil, fmt.Errorf("Type of condition must be boolean, not %s", typeName(v))
+	}
+	return v, nil
+}
+
+type notExpression struct {
+	operand expression
+}
+
+func (e *notExpression) eval(ctx *evalContext) (any, error) {
+	v, err := evalBoolean(e.operand, ctx)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+type comparisonExpression struct {
+	operator string
+	left     expression
+	right    expression
+}
+
+func (e *comparisonExpression) eval(ctx *evalContext) (any, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	cmp, err := compareValues(left, right)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot apply operator: %s %s %s", typeName(left), e.operator, typeName(right))
+	}
+	switch e.operator {
+	case "=":
+		return cmp == 0, nil
+	case "<>", "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", e.operator)
+}
+
+// compareValues compares two non-NULL values, which must be of comparable types.
+func compareValues(a any, b any) (int, error) {
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a, b), nil
+		case float64:
+			return compareOrdered(float64(a), b), nil
+		}
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a, float64(b)), nil
+		case float64:
+			return compareOrdered(a, b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, nil
+			case b:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, errors.New("incomparable types")
+}
+
+func compareOrdered[T int64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type isNullExpression struct {
+	operand expression
+	negated bool
+}
+
+func (e *isNullExpression) eval(ctx *evalContext) (any, error) {
+	v, err := e.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.negated, nil
+}
+
+type inExpression struct {
+	operand expression
+	list    []expression
+	negated bool
+}
+
+func (e *inExpression) eval(ctx *evalContext) (any, error) {
+	v, err := e.operand.eval(ctx)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	sawNull := false
+	for _, item := range e.list {
+		itemValue, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if itemValue == nil {
+			sawNull = true
+			continue
+		}
+		cmp, err := compareValues(v, itemValue)
+		if err != nil {
+			return nil, fmt.Errorf("IN value and list items must be the same type: %s", typeName(v))
+		}
+		if cmp == 0 {
+			return !e.negated, nil
+		}
+	}
+	if sawNull {
+		return nil, nil
+	}
+	return e.negated, nil
+}
+
+type betweenExpression struct {
+	operand expression
+	low     expression
+	high    expression
+	negated bool
+}
+
+func (e *betweenExpression) eval(ctx *evalContext) (any, error) {
+	result, err := (&logicalExpression{
+		operator: "AND",
+		left:     &comparisonExpression{operator: ">=", left: e.operand, right: e.low},
+		right:    &comparisonExpression{operator: "<=", left: e.operand, right: e.high},
+	}).eval(ctx)
+	if result == nil || err != nil || !e.negated {
+		return result, err
+	}
+	return !result.(bool), nil
+}
+
+type likeExpression struct {
+	operand expression
+	pattern expression
+	negated bool
+	// The compiled pattern, if it's the same for every row.
+	compiled *regexp.Regexp
+}
+
+func (e *likeExpression) eval(ctx *evalContext) (any, error) {
+	v, err := e.operand.eval(ctx)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("Left side of LIKE expression must evaluate to a varchar, not %s", typeName(v))
+	}
+	regex := e.compiled
+	if regex == nil {
+		pattern, err := e.pattern.eval(ctx)
+		if pattern == nil || err != nil {
+			return nil, err
+		}
+		patternString, ok := pattern.(string)
+		if !ok {
+			return nil, fmt.Errorf("LIKE pattern must evaluate to a varchar, not %s", typeName(pattern))
+		}
+		regex = sqllike.Regexp(patternString)
+		if _, ok := e.pattern.(*literal); ok {
+			e.compiled = regex
+		}
+	}
+	return regex.MatchString(s) != e.negated, nil
+}
+
+type arithmeticExpression struct {
+	operator string
+	left     expression
+	right    expression
+}
+
+func (e *arithmeticExpression) eval(ctx *evalContext) (any, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	if e.operator == "||" {
+		return formatValue(left) + formatValue(right), nil
+	}
+
+	a, aIsInt := left.(int64)
+	b, bIsInt := right.(int64)
+	if aIsInt && bIsInt {
+		switch e.operator {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/", "%":
+			if b == 0 {
+				return nil, errors.New("Division by zero")
+			}
+			if e.operator == "/" {
+				return a / b, nil
+			}
+			return a % b, nil
+		}
+	}
+
+	x, ok := toFloat(left)
+	y, ok2 := toFloat(right)
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("Cannot apply operator: %s %s %s", typeName(left), e.operator, typeName(right))
+	}
+	switch e.operator {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		return x / y, nil
+	case "%":
+		return math.Mod(x, y), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", e.operator)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+type caseWhen struct {
+	condition expression
+	result    expression
+}
+
+type caseExpression struct {
+	// Nil for searched CASE expressions, which have conditions rather than values to compare to.
+	operand   expression
+	whens     []caseWhen
+	otherwise expression
+}
+
+func (e *caseExpression) eval(ctx *evalContext) (any, error) {
+	for _, when := range e.whens {
+		condition := when.condition
+		if e.operand != nil {
+			condition = &comparisonExpression{operator: "=", left: e.operand, right: when.condition}
+		}
+		matched, err := evalBoolean(condition, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if matched == true {
+			return when.result.eval(ctx)
+		}
+	}
+	if e.otherwise != nil {
+		return e.otherwise.eval(ctx)
+	}
+	return nil, nil
+}
+
+type castExpression struct {
+	operand expression
+	typ     string
+	// TRY_CAST returns NULL rather than failing.
+	try bool
+}
+
+func (e *castExpression) eval(ctx *evalContext) (any, error) {
+	v, err := e.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	valueType, _ := parseValueType(e.typ)
+	converted, err := convertValue(v, valueType)
+	if err != nil {
+		if e.try {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return converted, nil
+}
+
+type valueType int
+
+const (
+	typeVarchar valueType = iota
+	typeBigint
+	typeDouble
+	typeBoolean
+)
+
+// parseValueType returns the type values of a SQL or Hive type are represented with.
+func parseValueType(typ string) (valueType, bool) {
+	typ, _, _ = strings.Cut(strings.ToLower(typ), "(")
+	switch strings.TrimSpace(typ) {
+	case "varchar", "char", "string", "date", "timestamp", "json":
+		return typeVarchar, true
+	case "tinyint", "smallint", "int", "integer", "bigint":
+		return typeBigint, true
+	case "double", "float", "real", "decimal":
+		return typeDouble, true
+	case "boolean":
+		return typeBoolean, true
+	}
+	return typeVarchar, false
+}
+
+// sqlTypeName returns the name Athena reports a Hive type with, such as integer for int.
+func sqlTypeName(typ string) string {
+	base, _, _ := strings.Cut(strings.ToLower(typ), "(")
+	switch base {
+	case "int":
+		return "integer"
+	case "string":
+		return "varchar"
+	case "float":
+		return "real"
+	}
+	return base
+}
+
+func convertValue(v any, to valueType) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch to {
+	case typeVarchar:
+		return formatValue(v), nil
+	case typeBigint:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(math.Round(v)), nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot cast '%s' to BIGINT", v)
+			}
+			return n, nil
+		}
+	case typeDouble:
+		switch v := v.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case bool:
+			if v {
+				return 1.0, nil
+			}
+			return 0.0, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot cast '%s' to DOUBLE", v)
+			}
+			return f, nil
+		}
+	case typeBoolean:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+			return nil, fmt.Errorf("Cannot cast '%s' to BOOLEAN", v)
+		}
+	}
+	return nil, fmt.Errorf("Cannot cast %s", typeName(v))
+}
+
+// formatValue formats a non-NULL value like Athena does in results.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatDouble(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatDouble formats doubles like Java, such as 2.0 and 1.0E10.
+func formatDouble(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	abs := math.Abs(f)
+	if abs == 0 || (abs >= 1e-3 && abs < 1e7) {
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'E', -1, 64), "E")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(n)
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "unknown"
+	case string:
+		return "varchar"
+	case int64:
+		return "bigint"
+	case float64:
+		return "double"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// valueKey returns a string which is equal for equal values, for grouping and DISTINCT.
+func valueKey(v any) string {
+	if v == nil {
+		return "n"
+	}
+	return typeName(v)[:1] + formatValue(v)
+}
+
+type aggregateExpression struct {
+	function string
+	// Nil for COUNT(*).
+	argument expression
+	distinct bool
+}
+
+var aggregateFunctions = map[string]bool{
+	"approx_distinct": true,
+	"arbitrary":       true,
+	"avg":             true,
+	"count":           true,
+	"max":             true,
+	"min":             true,
+	"sum":             true,
+}
+
+func (e *aggregateExpression) eval(ctx *evalContext) (any, error) {
+	if ctx.group == nil {
+		return nil, fmt.Errorf("'%s' cannot be used here, since it's an aggregate", e.function)
+	}
+	if e.argument == nil {
+		return int64(len(ctx.group)), nil
+	}
+	if _, ok := e.argument.(*aggregateExpression); ok {
+		return nil, errors.New("Cannot nest aggregations")
+	}
+
+	var values []any
+	seen := make(map[string]bool)
+	for _, row := range ctx.group {
+		v, err := e.argument.eval(&evalContext{row: row, columns: ctx.columns})
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		if e.distinct || e.function == "approx_distinct" {
+			key := valueKey(v)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		values = append(values, v)
+	}
+
+	switch e.function {
+	case "count", "approx_distinct":
+		return int64(len(values)), nil
+	case "arbitrary":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[0], nil
+	case "min", "max":
+		var result any
+		for _, v := range values {
+			if result == nil {
+				result = v
+				continue
+			}
+			cmp, err := compareValues(v, result)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot compare %s and %s", typeName(v), typeName(result))
+			}
+			if (cmp < 0) == (e.function == "min") && cmp != 0 {
+				result = v
+			}
+		}
+		return result, nil
+	case "sum", "avg":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		var intSum int64
+		var floatSum float64
+		allInts := true
+		for _, v := range values {
+			switch v := v.(type) {
+			case int64:
+				intSum += v
+				floatSum += float64(v)
+			case float64:
+				allInts = false
+				floatSum += v
+			default:
+				return nil, fmt.Errorf("Unexpected parameters (%s) for function %s", typeName(v), e.function)
+			}
+		}
+		if e.function == "avg" {
+			return floatSum / float64(len(values)), nil
+		}
+		if allInts {
+			return intSum, nil
+		}
+		return floatSum, nil
+	}
+	return nil, fmt.Errorf("function '%s' not registered", e.function)
+}
+
+// containsAggregate returns whether an expression aggregates rows.
+func containsAggregate(e expression) bool {
+	found := false
+	walkExpression(e, func(e expression) {
+		if _, ok := e.(*aggregateExpression); ok {
+			found = true
+		}
+	})
+	return found
+}
+
+func walkExpression(e expression, fn func(expression)) {
+	if e == nil {
+		return
+	}
+	fn(e)
+	switch e := e.(type) {
+	case *logicalExpression:
+		walkExpression(e.left, fn)
+		walkExpression(e.right, fn)
+	case *notExpression:
+		walkExpression(e.operand, fn)
+	case *comparisonExpression:
+		walkExpression(e.left, fn)
+		walkExpression(e.right, fn)
+	case *isNullExpression:
+		walkExpression(e.operand, fn)
+	case *inExpression:
+		walkExpression(e.operand, fn)
+		for _, item := range e.list {
+			walkExpression(item, fn)
+		}
+	case *betweenExpression:
+		walkExpression(e.operand, fn)
+		walkExpression(e.low, fn)
+		walkExpression(e.high, fn)
+	case *likeExpression:
+		walkExpression(e.operand, fn)
+		walkExpression(e.pattern, fn)
+	case *arithmeticExpression:
+		walkExpression(e.left, fn)
+		walkExpression(e.right, fn)
+	case *caseExpression:
+		walkExpression(e.operand, fn)
+		for _, when := range e.whens {
+			walkExpression(when.condition, fn)
+			walkExpression(when.result, fn)
+		}
+		walkExpression(e.otherwise, fn)
+	case *castExpression:
+		walkExpression(e.operand, fn)
+	case *functionExpression:
+		for _, argument := range e.arguments {
+			walkExpression(argument, fn)
+		}
+	}
+}
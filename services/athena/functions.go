@@ -0,0 +1,300 @@
+package athena
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// https://trino.io/docs/current/functions.html
+
+type scalarFunction struct {
+	minArgs int
+	// -1 for any number of arguments.
+	maxArgs int
+	// Most functions return NULL if any argument is NULL, without being called.
+	handlesNull bool
+	call        func(args []any) (any, error)
+}
+
+type functionExpression struct {
+	name      string
+	function  scalarFunction
+	arguments []expression
+}
+
+func (e *functionExpression) eval(ctx *evalContext) (any, error) {
+	args := make([]any, len(e.arguments))
+	for i, argument := range e.arguments {
+		v, err := argument.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil && !e.function.handlesNull {
+			return nil, nil
+		}
+		args[i] = v
+	}
+	v, err := e.function.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", e.name, err)
+	}
+	return v, nil
+}
+
+var scalarFunctions = map[string]scalarFunction{
+	"abs": {1, 1, false, numeric(math.Abs, func(n int64) int64 {
+		if n < 0 {
+			return -n
+		}
+		return n
+	})},
+	"ceil":    {1, 1, false, numeric(math.Ceil, nil)},
+	"ceiling": {1, 1, false, numeric(math.Ceil, nil)},
+	"floor":   {1, 1, false, numeric(math.Floor, nil)},
+	"round": {1, 2, false, func(args []any) (any, error) {
+		digits := int64(0)
+		if len(args) == 2 {
+			d, ok := args[1].(int64)
+			if !ok {
+				return nil, fmt.Errorf("digits must be an integer, not %s", typeName(args[1]))
+			}
+			digits = d
+		}
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			scale := math.Pow(10, float64(digits))
+			return math.Round(v*scale) / scale, nil
+		}
+		return nil, fmt.Errorf("unexpected argument %s", typeName(args[0]))
+	}},
+	"mod": {2, 2, false, func(args []any) (any, error) {
+		return (&arithmeticExpression{operator: "%", left: &literal{args[0]}, right: &literal{args[1]}}).eval(nil)
+	}},
+	"power": {2, 2, false, func(args []any) (any, error) {
+		x, ok := toFloat(args[0])
+		y, ok2 := toFloat(args[1])
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("unexpected arguments %s, %s", typeName(args[0]), typeName(args[1]))
+		}
+		return math.Pow(x, y), nil
+	}},
+	"sqrt": {1, 1, false, func(args []any) (any, error) {
+		x, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("unexpected argument %s", typeName(args[0]))
+		}
+		return math.Sqrt(x), nil
+	}},
+
+	"coalesce": {1, -1, true, func(args []any) (any, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	}},
+	"if": {2, 3, true, func(args []any) (any, error) {
+		if args[0] == true {
+			return args[1], nil
+		}
+		if len(args) == 3 {
+			return args[2], nil
+		}
+		return nil, nil
+	}},
+	"nullif": {2, 2, true, func(args []any) (any, error) {
+		if args[0] != nil && args[1] != nil {
+			if cmp, err := compareValues(args[0], args[1]); err == nil && cmp == 0 {
+				return nil, nil
+			}
+		}
+		return args[0], nil
+	}},
+	"greatest": {1, -1, false, extreme(1)},
+	"least":    {1, -1, false, extreme(-1)},
+
+	"concat": {1, -1, false, func(args []any) (any, error) {
+		var s strings.Builder
+		for _, arg := range args {
+			s.WriteString(formatValue(arg))
+		}
+		return s.String(), nil
+	}},
+	"length": {1, 1, false, str(func(s string) any { return int64(utf8.RuneCountInString(s)) })},
+	"lower":  {1, 1, false, str(func(s string) any { return strings.ToLower(s) })},
+	"upper":  {1, 1, false, str(func(s string) any { return strings.ToUpper(s) })},
+	"trim":   {1, 1, false, str(func(s string) any { return strings.TrimSpace(s) })},
+	"ltrim":  {1, 1, false, str(func(s string) any { return strings.TrimLeft(s, " \t\n\r") })},
+	"rtrim":  {1, 1, false, str(func(s string) any { return strings.TrimRight(s, " \t\n\r") })},
+	"reverse": {1, 1, false, str(func(s string) any {
+		runes := []rune(s)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes)
+	})},
+	"replace": {2, 3, false, func(args []any) (any, error) {
+		strs, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		replacement := ""
+		if len(strs) == 3 {
+			replacement = strs[2]
+		}
+		return strings.ReplaceAll(strs[0], strs[1], replacement), nil
+	}},
+	"strpos": {2, 2, false, func(args []any) (any, error) {
+		strs, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(strs[0], strs[1])
+		if i < 0 {
+			return int64(0), nil
+		}
+		return int64(utf8.RuneCountInString(strs[0][:i]) + 1), nil
+	}},
+	"substr":    {2, 3, false, substr},
+	"substring": {2, 3, false, substr},
+	"split_part": {3, 3, false, func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		delimiter, ok2 := args[1].(string)
+		index, ok3 := args[2].(int64)
+		if !ok || !ok2 || !ok3 {
+			return nil, fmt.Errorf("unexpected arguments %s, %s, %s", typeName(args[0]), typeName(args[1]), typeName(args[2]))
+		}
+		if index < 1 {
+			return nil, fmt.Errorf("index must be greater than zero")
+		}
+		parts := strings.Split(s, delimiter)
+		if int(index) > len(parts) {
+			return nil, nil
+		}
+		return parts[index-1], nil
+	}},
+	"regexp_like": {2, 2, false, func(args []any) (any, error) {
+		strs, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		regex, err := regexp.Compile(strs[1])
+		if err != nil {
+			return nil, err
+		}
+		return regex.MatchString(strs[0]), nil
+	}},
+	"regexp_extract": {2, 3, false, func(args []any) (any, error) {
+		strs, err := stringArgs(args[:2])
+		if err != nil {
+			return nil, err
+		}
+		group := int64(0)
+		if len(args) == 3 {
+			g, ok := args[2].(int64)
+			if !ok {
+				return nil, fmt.Errorf("group must be an integer, not %s", typeName(args[2]))
+			}
+			group = g
+		}
+		regex, err := regexp.Compile(strs[1])
+		if err != nil {
+			return nil, err
+		}
+		if group < 0 || int(group) > regex.NumSubexp() {
+			return nil, fmt.Errorf("pattern has %d groups. Cannot access group %d", regex.NumSubexp(), group)
+		}
+		match := regex.FindStringSubmatch(strs[0])
+		if match == nil {
+			return nil, nil
+		}
+		return match[group], nil
+	}},
+}
+
+// numeric returns a function of one number. Integers are passed to integerFn if there is one, and
+// are otherwise returned as they are.
+func numeric(floatFn func(float64) float64, integerFn func(int64) int64) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		switch v := args[0].(type) {
+		case int64:
+			if integerFn != nil {
+				return integerFn(v), nil
+			}
+			return v, nil
+		case float64:
+			return floatFn(v), nil
+		}
+		return nil, fmt.Errorf("unexpected argument %s", typeName(args[0]))
+	}
+}
+
+func str(fn func(string) any) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected argument %s", typeName(args[0]))
+		}
+		return fn(s), nil
+	}
+}
+
+// stringArgs checks that all arguments are strings.
+func stringArgs(args []any) ([]string, error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected argument %s", typeName(arg))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+func extreme(sign int) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			cmp, err := compareValues(arg, result)
+			if err != nil {
+				return nil, fmt.Errorf("arguments must be the same type")
+			}
+			if cmp*sign > 0 {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+}
+
+// substr returns the characters starting at a 1-based position, which counts from the end if it's negative.
+func substr(args []any) (any, error) {
+	s, ok := args[0].(string)
+	start, ok2 := args[1].(int64)
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("unexpected arguments %s, %s", typeName(args[0]), typeName(args[1]))
+	}
+	runes := []rune(s)
+	if start < 0 {
+		start = int64(len(runes)) + start + 1
+	}
+	if start < 1 || start > int64(len(runes)) {
+		return "", nil
+	}
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length, ok := args[2].(int64)
+		if !ok {
+			return nil, fmt.Errorf("length must be an integer, not %s", typeName(args[2]))
+		}
+		end = min(end, start-1+max(length, 0))
+	}
+	return string(runes[start-1 : end]), nil
+}
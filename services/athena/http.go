@@ -0,0 +1,23 @@
+package athena
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AmazonAthena"
+
+func (a *Athena) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchGetQueryExecution", region.Method(a.regions, (*Athena).BatchGetQueryExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateWorkGroup", region.Method(a.regions, (*Athena).CreateWorkGroup))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteWorkGroup", region.Method(a.regions, (*Athena).DeleteWorkGroup))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetQueryExecution", region.Method(a.regions, (*Athena).GetQueryExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetQueryResults", region.Method(a.regions, (*Athena).GetQueryResults))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetWorkGroup", region.Method(a.regions, (*Athena).GetWorkGroup))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListQueryExecutions", region.Method(a.regions, (*Athena).ListQueryExecutions))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListWorkGroups", region.Method(a.regions, (*Athena).ListWorkGroups))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StartQueryExecution", region.Method(a.regions, (*Athena).StartQueryExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StopQueryExecution", region.Method(a.regions, (*Athena).StopQueryExecution))
+}
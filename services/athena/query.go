@@ -0,0 +1,322 @@
+package athena
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type resultColumn struct {
+	name string
+	// The SQL type, such as varchar or bigint.
+	typ string
+}
+
+type queryResult struct {
+	columns []resultColumn
+	rows    [][]any
+}
+
+type outputColumn struct {
+	expression expression
+	name       string
+}
+
+// executeSelect runs a statement over a table's rows, or a single empty row if it has no FROM clause.
+func executeSelect(statement *selectStatement, data *tableData) (*queryResult, error) {
+	columns := make(map[string]bool)
+	columnTypes := make(map[string]string)
+	rows := []map[string]any{{}}
+	if data != nil {
+		for _, column := range data.columns {
+			columns[column.name] = true
+			columnTypes[column.name] = column.typ
+		}
+		rows = data.rows
+	}
+
+	var outputs []outputColumn
+	for _, item := range statement.items {
+		if item.expression == nil {
+			if data == nil {
+				return nil, errors.New("SELECT * not allowed in queries without FROM clause")
+			}
+			for _, column := range data.columns {
+				outputs = append(outputs, outputColumn{&columnReference{column.name}, column.name})
+			}
+			continue
+		}
+		name := item.alias
+		if name == "" {
+			if reference, ok := item.expression.(*columnReference); ok {
+				name = reference.name
+			} else {
+				name = fmt.Sprintf("_col%d", len(outputs))
+			}
+		}
+		outputs = append(outputs, outputColumn{item.expression, name})
+	}
+
+	if statement.where != nil {
+		if containsAggregate(statement.where) {
+			return nil, errors.New("WHERE clause cannot contain aggregations")
+		}
+		var filtered []map[string]any
+		for _, row := range rows {
+			matches, err := evalBoolean(statement.where, &evalContext{row: row, columns: columns})
+			if err != nil {
+				return nil, err
+			}
+			if matches == true {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	contexts, err := groupRows(statement, outputs, rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	type resultRow struct {
+		values []any
+		// The values sorted by, if there's an ORDER BY clause.
+		keys []any
+	}
+	var results []resultRow
+	seen := make(map[string]bool)
+	for _, ctx := range contexts {
+		values := make([]any, len(outputs))
+		for i, output := range outputs {
+			v, err := output.expression.eval(ctx)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+
+		if statement.distinct {
+			key := rowKey(values)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+
+		// ORDER BY can refer to output columns by their names or ordinals, as well as to input columns.
+		var keys []any
+		if len(statement.orderBy) > 0 {
+			orderCtx := &evalContext{row: make(map[string]any), columns: make(map[string]bool), group: ctx.group}
+			for name, v := range ctx.row {
+				orderCtx.row[name] = v
+			}
+			for name := range ctx.columns {
+				orderCtx.columns[name] = true
+			}
+			for i, output := range outputs {
+				orderCtx.row[output.name] = values[i]
+				orderCtx.columns[output.name] = true
+			}
+			for _, item := range statement.orderBy {
+				if ordinal, ok := ordinalReference(item.expression); ok {
+					if ordinal < 1 || ordinal > len(outputs) {
+						return nil, fmt.Errorf("ORDER BY position %d is not in select list", ordinal)
+					}
+					keys = append(keys, values[ordinal-1])
+					continue
+				}
+				v, err := item.expression.eval(orderCtx)
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, v)
+			}
+		}
+		results = append(results, resultRow{values, keys})
+	}
+
+	if len(statement.orderBy) > 0 {
+		slices.SortStableFunc(results, func(a, b resultRow) int {
+			for i, item := range statement.orderBy {
+				if cmp := compareSortKeys(a.keys[i], b.keys[i], item); cmp != 0 {
+					return cmp
+				}
+			}
+			return 0
+		})
+	}
+	if statement.limit >= 0 && statement.limit < len(results) {
+		results = results[:statement.limit]
+	}
+
+	result := &queryResult{}
+	for i, output := range outputs {
+		var values []any
+		for _, row := range results {
+			values = append(values, row.values[i])
+		}
+		result.columns = append(result.columns, resultColumn{output.name, resultType(output.expression, columnTypes, values)})
+	}
+	for _, row := range results {
+		result.rows = append(result.rows, row.values)
+	}
+	return result, nil
+}
+
+// groupRows returns the contexts the select list is evaluated in: one per row, or one per group if
+// the query aggregates.
+func groupRows(statement *selectStatement, outputs []outputColumn, rows []map[string]any, columns map[string]bool) ([]*evalContext, error) {
+	aggregating := len(statement.groupBy) > 0 || statement.having != nil
+	for _, output := range outputs {
+		aggregating = aggregating || containsAggregate(output.expression)
+	}
+	for _, item := range statement.orderBy {
+		aggregating = aggregating || containsAggregate(item.expression)
+	}
+	if !aggregating {
+		contexts := make([]*evalContext, len(rows))
+		for i, row := range rows {
+			contexts[i] = &evalContext{row: row, columns: columns}
+		}
+		return contexts, nil
+	}
+
+	// GROUP BY can refer to select items by their ordinals.
+	groupBy := make([]expression, len(statement.groupBy))
+	for i, e := range statement.groupBy {
+		if containsAggregate(e) {
+			return nil, errors.New("GROUP BY clause cannot contain aggregations")
+		}
+		groupBy[i] = e
+		if ordinal, ok := ordinalReference(e); ok {
+			if ordinal < 1 || ordinal > len(outputs) {
+				return nil, fmt.Errorf("GROUP BY position %d is not in select list", ordinal)
+			}
+			groupBy[i] = outputs[ordinal-1].expression
+		}
+	}
+
+	// Groups are kept in the order they're first seen. Without GROUP BY, every row is in one group, even
+	// if there are no rows.
+	var groups [][]map[string]any
+	if len(groupBy) == 0 {
+		// A nil group means there's no aggregation, so an empty one must be non-nil.
+		groups = [][]map[string]any{append([]map[string]any{}, rows...)}
+	} else {
+		indexes := make(map[string]int)
+		for _, row := range rows {
+			values := make([]any, len(groupBy))
+			for i, e := range groupBy {
+				v, err := e.eval(&evalContext{row: row, columns: columns})
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			key := rowKey(values)
+			index, ok := indexes[key]
+			if !ok {
+				index = len(groups)
+				indexes[key] = index
+				groups = append(groups, nil)
+			}
+			groups[index] = append(groups[index], row)
+		}
+	}
+
+	var contexts []*evalContext
+	for _, group := range groups {
+		// Grouped columns have the same value in every row, so they're read from the first.
+		row := map[string]any{}
+		if len(group) > 0 {
+			row = group[0]
+		}
+		ctx := &evalContext{row: row, columns: columns, group: group}
+		if statement.having != nil {
+			matches, err := evalBoolean(statement.having, ctx)
+			if err != nil {
+				return nil, err
+			}
+			if matches != true {
+				continue
+			}
+		}
+		contexts = append(contexts, ctx)
+	}
+	return contexts, nil
+}
+
+// ordinalReference returns the position an integer literal in GROUP BY or ORDER BY refers to.
+func ordinalReference(e expression) (int, bool) {
+	if l, ok := e.(*literal); ok {
+		if n, ok := l.value.(int64); ok {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+func rowKey(values []any) string {
+	keys := make([]string, len(values))
+	for i, v := range values {
+		keys[i] = valueKey(v)
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// compareSortKeys orders NULLs last unless NULLS FIRST is specified, regardless of the direction.
+func compareSortKeys(a any, b any, item orderItem) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil || b == nil:
+		if (a == nil) == item.nullsFirst {
+			return -1
+		}
+		return 1
+	}
+	cmp, err := compareValues(a, b)
+	if err != nil {
+		// Values of different types are ordered by type so sorting is still deterministic.
+		cmp = strings.Compare(typeName(a), typeName(b))
+	}
+	if item.descending {
+		return -cmp
+	}
+	return cmp
+}
+
+// resultType returns the SQL type of an output column, which is inferred from its values if it
+// isn't known from the expression.
+func resultType(e expression, columnTypes map[string]string, values []any) string {
+	switch e := e.(type) {
+	case *columnReference:
+		if typ, ok := columnTypes[e.name]; ok {
+			return sqlTypeName(typ)
+		}
+	case *castExpression:
+		return sqlTypeName(e.typ)
+	case *aggregateExpression:
+		switch e.function {
+		case "count", "approx_distinct":
+			return "bigint"
+		case "avg":
+			return "double"
+		}
+	}
+	for _, v := range values {
+		switch v.(type) {
+		case bool:
+			return "boolean"
+		case int64:
+			return "bigint"
+		case float64:
+			return "double"
+		case string:
+			return "varchar"
+		}
+	}
+	return "varchar"
+}
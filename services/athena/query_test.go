@@ -0,0 +1,147 @@
+package athena
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func ordersData() *tableData {
+	return &tableData{
+		columns: []tableColumn{{"id", "int"}, {"customer", "string"}, {"amount", "double"}, {"region", "string"}},
+		rows: []map[string]any{
+			{"id": int64(1), "customer": "alice", "amount": 10.5, "region": "eu"},
+			{"id": int64(2), "customer": "bob", "amount": 20.0, "region": "us"},
+			{"id": int64(3), "customer": "alice", "amount": 4.5, "region": "eu"},
+			{"id": int64(4), "customer": "carol", "region": "us"},
+		},
+	}
+}
+
+func formatResult(result *queryResult) []string {
+	var rows []string
+	for _, row := range result.rows {
+		var values []string
+		for _, v := range formatRow(row) {
+			if v == nil {
+				values = append(values, "NULL")
+			} else {
+				values = append(values, *v)
+			}
+		}
+		rows = append(rows, strings.Join(values, ","))
+	}
+	return rows
+}
+
+func TestExecuteSelect(t *testing.T) {
+	for _, tc := range []struct {
+		query   string
+		columns []string
+		types   []string
+		rows    []string
+	}{
+		{
+			query:   "SELECT * FROM orders WHERE id = 1",
+			columns: []string{"id", "customer", "amount", "region"},
+			types:   []string{"integer", "varchar", "double", "varchar"},
+			rows:    []string{"1,alice,10.5,eu"},
+		},
+		{
+			query:   "SELECT customer, amount * 2 AS doubled FROM orders WHERE amount > 5 ORDER BY doubled DESC",
+			columns: []string{"customer", "doubled"},
+			types:   []string{"varchar", "double"},
+			rows:    []string{"bob,40.0", "alice,21.0"},
+		},
+		{
+			query:   "SELECT customer, count(*), sum(amount) FROM orders GROUP BY customer ORDER BY 2 DESC, 1",
+			columns: []string{"customer", "_col1", "_col2"},
+			types:   []string{"varchar", "bigint", "double"},
+			rows:    []string{"alice,2,15.0", "bob,1,20.0", "carol,1,NULL"},
+		},
+		{
+			query:   "SELECT region, avg(amount) avg_amount FROM orders GROUP BY region HAVING count(amount) > 1",
+			columns: []string{"region", "avg_amount"},
+			types:   []string{"varchar", "double"},
+			rows:    []string{"eu,7.5"},
+		},
+		{
+			query:   "SELECT DISTINCT customer FROM orders ORDER BY customer LIMIT 2",
+			columns: []string{"customer"},
+			types:   []string{"varchar"},
+			rows:    []string{"alice", "bob"},
+		},
+		{
+			query:   "SELECT id FROM orders ORDER BY amount NULLS FIRST",
+			columns: []string{"id"},
+			types:   []string{"integer"},
+			rows:    []string{"4", "3", "1", "2"},
+		},
+		{
+			query:   "SELECT count(*) FROM orders WHERE customer = 'nobody'",
+			columns: []string{"_col0"},
+			types:   []string{"bigint"},
+			rows:    []string{"0"},
+		},
+		{
+			query:   "SELECT upper(customer) || '-' || CAST(id AS varchar) FROM orders WHERE customer LIKE 'c%'",
+			columns: []string{"_col0"},
+			types:   []string{"varchar"},
+			rows:    []string{"CAROL-4"},
+		},
+		{
+			query:   "SELECT 1 + 2, 'a'",
+			columns: []string{"_col0", "_col1"},
+			types:   []string{"bigint", "varchar"},
+			rows:    []string{"3,a"},
+		},
+	} {
+		statement, err := parseQuery(tc.query, nil)
+		if err != nil {
+			t.Fatal(tc.query, err)
+		}
+		var data *tableData
+		if statement.from != nil {
+			data = ordersData()
+		}
+		result, err := executeSelect(statement, data)
+		if err != nil {
+			t.Fatal(tc.query, err)
+		}
+		var columns, types []string
+		for _, column := range result.columns {
+			columns = append(columns, column.name)
+			types = append(types, column.typ)
+		}
+		if !slices.Equal(columns, tc.columns) || !slices.Equal(types, tc.types) {
+			t.Fatal(tc.query, "unexpected columns", columns, types)
+		}
+		if rows := formatResult(result); !slices.Equal(rows, tc.rows) {
+			t.Fatal(tc.query, "unexpected rows", rows)
+		}
+	}
+}
+
+func TestExecuteSelectErrors(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT missing FROM orders":                       "Column 'missing' cannot be resolved",
+		"SELECT id FROM orders WHERE count(*) > 1":         "WHERE clause cannot contain aggregations",
+		"SELECT id FROM orders ORDER BY 3":                 "ORDER BY position 3 is not in select list",
+		"SELECT *":                                         "SELECT * not allowed in queries without FROM clause",
+		"SELECT id FROM orders WHERE customer":             "Type of condition must be boolean, not varchar",
+		"SELECT id, count(*) FROM orders GROUP BY sum(id)": "GROUP BY clause cannot contain aggregations",
+	} {
+		statement, err := parseQuery(query, nil)
+		if err != nil {
+			t.Fatal(query, err)
+		}
+		var data *tableData
+		if statement.from != nil {
+			data = ordersData()
+		}
+		_, err = executeSelect(statement, data)
+		if err == nil || err.Error() != expected {
+			t.Fatal(query, "unexpected error", err)
+		}
+	}
+}
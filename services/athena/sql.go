@@ -0,0 +1,804 @@
+package athena
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Queries are a subset of Trino's SELECT statement:
+//
+//	SELECT [DISTINCT] expression [[AS] alias], ... | *
+//	[FROM [[catalog.]database.]table [[AS] alias]]
+//	[WHERE condition]
+//	[GROUP BY expression, ...]
+//	[HAVING condition]
+//	[ORDER BY expression [ASC|DESC] [NULLS FIRST|LAST], ...]
+//	[LIMIT count]
+//
+// See https://docs.aws.amazon.com/athena/latest/ug/select.html
+
+type selectStatement struct {
+	distinct bool
+	items    []selectItem
+	// Nil for queries without a FROM clause, such as SELECT 1.
+	from    *tableReference
+	where   expression
+	groupBy []expression
+	having  expression
+	orderBy []orderItem
+	// -1 for no limit.
+	limit int
+}
+
+type selectItem struct {
+	// Nil for *.
+	expression expression
+	alias      string
+}
+
+type tableReference struct {
+	database string
+	table    string
+	alias    string
+}
+
+type orderItem struct {
+	expression expression
+	descending bool
+	nullsFirst bool
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenQuotedIdentifier
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits a query into tokens, replacing ? with the execution parameters.
+func tokenize(query string, parameters []string) ([]token, error) {
+	var tokens []token
+	usedParameters := 0
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			j := i + 2
+			for j+1 < len(runes) && (runes[j] != '*' || runes[j+1] != '/') {
+				j++
+			}
+			if j+1 >= len(runes) {
+				return nil, errors.New("unterminated comment")
+			}
+			i = j + 2
+		case r == '\'' || r == '"':
+			var text strings.Builder
+			j := i + 1
+			for {
+				if j >= len(runes) {
+					return nil, errors.New("unterminated quoted string")
+				}
+				if runes[j] == r {
+					// Quotes are escaped by doubling them.
+					if j+1 < len(runes) && runes[j+1] == r {
+						text.WriteRune(r)
+						j += 2
+						continue
+					}
+					break
+				}
+				text.WriteRune(runes[j])
+				j++
+			}
+			kind := tokenString
+			if r == '"' {
+				kind = tokenQuotedIdentifier
+			}
+			tokens = append(tokens, token{kind, text.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			if j < len(runes) && (runes[j] == 'e' || runes[j] == 'E') {
+				j++
+				if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+					j++
+				}
+				for j < len(runes) && unicode.IsDigit(runes[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdentifier, string(runes[i:j])})
+			i = j
+		case r == '?':
+			if usedParameters >= len(parameters) {
+				return nil, errors.New("not enough execution parameters")
+			}
+			parameterTokens, err := tokenize(parameters[usedParameters], nil)
+			if err != nil {
+				return nil, fmt.Errorf("execution parameter %d: %v", usedParameters+1, err)
+			}
+			tokens = append(tokens, parameterTokens...)
+			usedParameters++
+			i++
+		default:
+			symbol := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "<=", ">=", "<>", "!=", "||":
+					symbol = two
+				}
+			}
+			if !strings.Contains("=<>!|+-*/%(),.;", symbol[:1]) || symbol == "!" || symbol == "|" {
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, token{tokenSymbol, symbol})
+			i += len(symbol)
+		}
+	}
+	if usedParameters != len(parameters) {
+		return nil, errors.New("too many execution parameters")
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseQuery(query string, parameters []string) (*selectStatement, error) {
+	tokens, err := tokenize(query, parameters)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if !p.isKeyword("SELECT") {
+		return nil, errors.New("only SELECT queries are supported")
+	}
+	statement, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	p.acceptSymbol(";")
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("mismatched input '%s'", p.peek().text)
+	}
+	return statement, nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: tokenEOF}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenIdentifier && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	t := p.peek()
+	if t.kind == tokenSymbol && t.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.unexpected("'" + symbol + "'")
+	}
+	return nil
+}
+
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("mismatched input '<EOF>'. Expecting: %s", expected)
+	}
+	return fmt.Errorf("mismatched input '%s'. Expecting: %s", t.text, expected)
+}
+
+// Keywords which can't be used as unquoted aliases.
+var reservedWords = []string{
+	"ALL", "AND", "AS", "ASC", "BETWEEN", "BY", "CASE", "CAST", "CROSS", "DESC", "DISTINCT", "ELSE", "END",
+	"EXCEPT", "FALSE", "FROM", "FULL", "GROUP", "HAVING", "IN", "INNER", "INTERSECT", "IS", "JOIN", "LEFT",
+	"LIKE", "LIMIT", "NOT", "NULL", "NULLS", "OFFSET", "ON", "OR", "ORDER", "RIGHT", "SELECT", "THEN", "TRUE",
+	"UNION", "WHEN", "WHERE", "WITH",
+}
+
+func isReserved(t token) bool {
+	if t.kind != tokenIdentifier {
+		return false
+	}
+	return slices.Contains(reservedWords, strings.ToUpper(t.text))
+}
+
+// parseIdentifier parses an identifier, which is case insensitive.
+func (p *parser) parseIdentifier() (string, error) {
+	t := p.peek()
+	if (t.kind == tokenIdentifier && !isReserved(t)) || t.kind == tokenQuotedIdentifier {
+		p.pos++
+		return strings.ToLower(t.text), nil
+	}
+	return "", p.unexpected("an identifier")
+}
+
+// parseAlias parses an optional alias, with or without AS.
+func (p *parser) parseAlias() (string, error) {
+	if p.acceptKeyword("AS") {
+		return p.parseIdentifier()
+	}
+	t := p.peek()
+	if (t.kind == tokenIdentifier && !isReserved(t)) || t.kind == tokenQuotedIdentifier {
+		return p.parseIdentifier()
+	}
+	return "", nil
+}
+
+func (p *parser) parseSelect() (*selectStatement, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	statement := &selectStatement{limit: -1}
+	if p.acceptKeyword("DISTINCT") {
+		statement.distinct = true
+	} else {
+		p.acceptKeyword("ALL")
+	}
+
+	for {
+		if p.acceptSymbol("*") {
+			statement.items = append(statement.items, selectItem{})
+		} else {
+			e, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			alias, err := p.parseAlias()
+			if err != nil {
+				return nil, err
+			}
+			statement.items = append(statement.items, selectItem{expression: e, alias: alias})
+		}
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if p.acceptKeyword("FROM") {
+		from, err := p.parseTableReference()
+		if err != nil {
+			return nil, err
+		}
+		statement.from = from
+		for _, keyword := range []string{"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS"} {
+			if p.isKeyword(keyword) {
+				return nil, errors.New("joins are not supported")
+			}
+		}
+		if p.peek().kind == tokenSymbol && p.peek().text == "," {
+			return nil, errors.New("joins are not supported")
+		}
+	}
+
+	if p.acceptKeyword("WHERE") {
+		where, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		statement.where = where
+	}
+
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		groupBy, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		statement.groupBy = groupBy
+	}
+
+	if p.acceptKeyword("HAVING") {
+		having, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		statement.having = having
+	}
+
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			item := orderItem{expression: e}
+			if p.acceptKeyword("DESC") {
+				item.descending = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			if p.acceptKeyword("NULLS") {
+				if p.acceptKeyword("FIRST") {
+					item.nullsFirst = true
+				} else if err := p.expectKeyword("LAST"); err != nil {
+					return nil, err
+				}
+			}
+			statement.orderBy = append(statement.orderBy, item)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("LIMIT") {
+		t := p.next()
+		limit, err := strconv.Atoi(t.text)
+		if t.kind != tokenNumber || err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid LIMIT '%s'", t.text)
+		}
+		statement.limit = limit
+	}
+
+	if p.isKeyword("UNION") || p.isKeyword("INTERSECT") || p.isKeyword("EXCEPT") {
+		return nil, fmt.Errorf("%s is not supported", strings.ToUpper(p.peek().text))
+	}
+	return statement, nil
+}
+
+func (p *parser) parseTableReference() (*tableReference, error) {
+	if p.peek().kind == tokenSymbol && p.peek().text == "(" {
+		return nil, errors.New("subqueries are not supported")
+	}
+	parts := []string{}
+	for {
+		part, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if !p.acceptSymbol(".") {
+			break
+		}
+	}
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("too many dots in table name '%s'", strings.Join(parts, "."))
+	}
+	reference := &tableReference{table: parts[len(parts)-1]}
+	if len(parts) >= 2 {
+		reference.database = parts[len(parts)-2]
+	}
+	if len(parts) == 3 && parts[0] != "awsdatacatalog" {
+		return nil, fmt.Errorf("catalog '%s' does not exist", parts[0])
+	}
+	alias, err := p.parseAlias()
+	if err != nil {
+		return nil, err
+	}
+	reference.alias = alias
+	return reference, nil
+}
+
+func (p *parser) parseExpressionList() ([]expression, error) {
+	var expressions []expression
+	for {
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, e)
+		if !p.acceptSymbol(",") {
+			return expressions, nil
+		}
+	}
+}
+
+func (p *parser) parseExpression() (expression, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{operator: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{operator: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (expression, error) {
+	if p.acceptKeyword("NOT") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{e}, nil
+	}
+	return p.parsePredicate()
+}
+
+var comparisonOperators = []string{"=", "<>", "!=", "<", "<=", ">", ">="}
+
+func (p *parser) parsePredicate() (expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind == tokenSymbol && slices.Contains(comparisonOperators, t.text) {
+			p.pos++
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			left = &comparisonExpression{operator: t.text, left: left, right: right}
+			continue
+		}
+
+		if p.acceptKeyword("IS") {
+			negated := p.acceptKeyword("NOT")
+			if err := p.expectKeyword("NULL"); err != nil {
+				return nil, err
+			}
+			left = &isNullExpression{operand: left, negated: negated}
+			continue
+		}
+
+		start := p.pos
+		negated := p.acceptKeyword("NOT")
+		switch {
+		case p.acceptKeyword("IN"):
+			if err := p.expectSymbol("("); err != nil {
+				return nil, err
+			}
+			if p.isKeyword("SELECT") {
+				return nil, errors.New("subqueries are not supported")
+			}
+			list, err := p.parseExpressionList()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			left = &inExpression{operand: left, list: list, negated: negated}
+		case p.acceptKeyword("BETWEEN"):
+			low, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("AND"); err != nil {
+				return nil, err
+			}
+			high, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			left = &betweenExpression{operand: left, low: low, high: high, negated: negated}
+		case p.acceptKeyword("LIKE"):
+			pattern, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			left = &likeExpression{operand: left, pattern: pattern, negated: negated}
+		default:
+			p.pos = start
+			return left, nil
+		}
+	}
+}
+
+func (p *parser) parseAdditive() (expression, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenSymbol || (t.text != "+" && t.text != "-" && t.text != "||") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticExpression{operator: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenSymbol || (t.text != "*" && t.text != "/" && t.text != "%") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticExpression{operator: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expression, error) {
+	if p.acceptSymbol("-") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithmeticExpression{operator: "-", left: &literal{int64(0)}, right: e}, nil
+	}
+	p.acceptSymbol("+")
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expression, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenEOF:
+		return nil, p.unexpected("an expression")
+	case tokenString:
+		p.pos++
+		return &literal{t.text}, nil
+	case tokenNumber:
+		p.pos++
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return &literal{n}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", t.text)
+		}
+		return &literal{f}, nil
+	case tokenSymbol:
+		if p.acceptSymbol("(") {
+			if p.isKeyword("SELECT") {
+				return nil, errors.New("subqueries are not supported")
+			}
+			e, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expectSymbol(")")
+		}
+		return nil, p.unexpected("an expression")
+	}
+
+	if t.kind == tokenIdentifier {
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			p.pos++
+			return &literal{nil}, nil
+		case "TRUE":
+			p.pos++
+			return &literal{true}, nil
+		case "FALSE":
+			p.pos++
+			return &literal{false}, nil
+		case "CASE":
+			p.pos++
+			return p.parseCase()
+		case "CAST", "TRY_CAST":
+			p.pos++
+			return p.parseCast(strings.EqualFold(t.text, "TRY_CAST"))
+		case "DATE", "TIMESTAMP":
+			// Typed literals, such as DATE '2024-01-01', are kept as strings, which compare correctly.
+			if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == tokenString {
+				p.pos += 2
+				return &literal{p.tokens[p.pos-1].text}, nil
+			}
+		}
+	}
+
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if p.acceptSymbol("(") {
+		return p.parseFunction(name)
+	}
+	// Columns may be qualified by the table's name or alias, which is ignored since there are no joins.
+	for p.acceptSymbol(".") {
+		name, err = p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &columnReference{name}, nil
+}
+
+func (p *parser) parseCase() (expression, error) {
+	e := &caseExpression{}
+	if !p.isKeyword("WHEN") {
+		operand, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		e.operand = operand
+	}
+	for p.acceptKeyword("WHEN") {
+		condition, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		result, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		e.whens = append(e.whens, caseWhen{condition, result})
+	}
+	if len(e.whens) == 0 {
+		return nil, p.unexpected("WHEN")
+	}
+	if p.acceptKeyword("ELSE") {
+		otherwise, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		e.otherwise = otherwise
+	}
+	return e, p.expectKeyword("END")
+}
+
+func (p *parser) parseCast(try bool) (expression, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AS"); err != nil {
+		return nil, err
+	}
+	typ, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	// Parameters, such as varchar(10) or decimal(10,2), are ignored.
+	if p.acceptSymbol("(") {
+		for !p.acceptSymbol(")") {
+			if p.next().kind == tokenEOF {
+				return nil, p.unexpected("')'")
+			}
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	if _, ok := parseValueType(typ); !ok {
+		return nil, fmt.Errorf("unknown type: %s", typ)
+	}
+	return &castExpression{operand: operand, typ: typ, try: try}, nil
+}
+
+func (p *parser) parseFunction(name string) (expression, error) {
+	if _, ok := aggregateFunctions[name]; ok {
+		e := &aggregateExpression{function: name}
+		if name == "count" && p.acceptSymbol("*") {
+			return e, p.expectSymbol(")")
+		}
+		e.distinct = p.acceptKeyword("DISTINCT")
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		e.argument = arg
+		return e, p.expectSymbol(")")
+	}
+
+	function, ok := scalarFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("function '%s' not registered", name)
+	}
+	e := &functionExpression{name: name, function: function}
+	if !p.acceptSymbol(")") {
+		args, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		e.arguments = args
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(e.arguments) < function.minArgs || (function.maxArgs >= 0 && len(e.arguments) > function.maxArgs) {
+		return nil, fmt.Errorf("unexpected number of arguments to function '%s'", name)
+	}
+	return e, nil
+}
@@ -0,0 +1,135 @@
+package athena
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type resultColumnState struct {
+	Name string
+	Type string
+}
+
+type executionState struct {
+	*QueryExecution
+	// Only for succeeded executions. Values are exported as GetQueryResults formats them.
+	ResultColumns []resultColumnState `json:",omitempty"`
+	ResultRows    [][]*string         `json:",omitempty"`
+}
+
+type workGroupsState struct {
+	WorkGroups []*WorkGroup
+	// In the order they were started.
+	QueryExecutions []executionState
+	// Execution IDs by client request token.
+	ClientRequestTokens map[string]string
+}
+
+// TryPause pauses changes to each region's work groups and query executions while a snapshot copies them.
+func (a *Athena) TryPause() (func(), bool) {
+	return a.regions.TryPause(func(a *Athena) *sync.Mutex { return &a.mu })
+}
+
+// ExportState writes each region's work groups and query executions, with their results, to the
+// archive.
+func (a *Athena) ExportState(w *state.Writer) error {
+	return a.regions.ExportState(w, (*Athena).exportState)
+}
+
+func (a *Athena) exportState(w *state.Writer) error {
+	unlock := w.Lock(&a.mu)
+	defer unlock()
+
+	exported := workGroupsState{ClientRequestTokens: a.clientRequestTokens}
+	for _, workGroup := range a.workGroups {
+		exported.WorkGroups = append(exported.WorkGroups, workGroup)
+	}
+	slices.SortFunc(exported.WorkGroups, func(a, b *WorkGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, id := range a.executionIds {
+		execution := a.executions[id]
+		e := executionState{QueryExecution: execution}
+		if execution.result != nil {
+			for _, column := range execution.result.columns {
+				e.ResultColumns = append(e.ResultColumns, resultColumnState{Name: column.name, Type: column.typ})
+			}
+			for _, row := range execution.result.rows {
+				e.ResultRows = append(e.ResultRows, formatRow(row))
+			}
+		}
+		exported.QueryExecutions = append(exported.QueryExecutions, e)
+	}
+	return w.WriteJSON("workgroups.json", exported)
+}
+
+// ImportState replaces each region's work groups and query executions with the archive's.
+func (a *Athena) ImportState(r *state.Reader) (func() error, error) {
+	return a.regions.ImportState(r, (*Athena).importState, (*Athena).exportState)
+}
+
+func (a *Athena) importState(r *state.Reader) (func() error, error) {
+	var imported workGroupsState
+	if err := r.ReadJSON("workgroups.json", &imported); err != nil {
+		return nil, err
+	}
+
+	workGroups := make(map[string]*WorkGroup)
+	for _, workGroup := range imported.WorkGroups {
+		if workGroup == nil || !workGroupNameRegex.MatchString(workGroup.Name) {
+			return nil, fmt.Errorf("invalid work group")
+		}
+		if workGroup.Tags == nil {
+			workGroup.Tags = make(map[string]string)
+		}
+		workGroups[workGroup.Name] = workGroup
+	}
+	if _, ok := workGroups[primaryWorkGroup]; !ok {
+		return nil, fmt.Errorf("there's no %s work group", primaryWorkGroup)
+	}
+
+	executions := make(map[string]*QueryExecution)
+	var executionIds []string
+	for _, e := range imported.QueryExecutions {
+		execution := e.QueryExecution
+		if execution == nil || execution.Id == "" {
+			return nil, fmt.Errorf("invalid query execution")
+		}
+		if execution.State == "SUCCEEDED" {
+			result := &queryResult{}
+			for _, column := range e.ResultColumns {
+				result.columns = append(result.columns, resultColumn{name: column.Name, typ: column.Type})
+			}
+			for _, values := range e.ResultRows {
+				row := make([]any, len(values))
+				for i, v := range values {
+					if v != nil {
+						row[i] = *v
+					}
+				}
+				result.rows = append(result.rows, row)
+			}
+			execution.result = result
+		}
+		executions[execution.Id] = execution
+		executionIds = append(executionIds, execution.Id)
+	}
+	clientRequestTokens := imported.ClientRequestTokens
+	if clientRequestTokens == nil {
+		clientRequestTokens = make(map[string]string)
+	}
+
+	return func() error {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.workGroups = workGroups
+		a.executions = executions
+		a.executionIds = executionIds
+		a.clientRequestTokens = clientRequestTokens
+		return nil
+	}, nil
+}
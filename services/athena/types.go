@@ -0,0 +1,245 @@
+package athena
+
+type APITag struct {
+	Key   string `length:"1,128" error:"InvalidRequestException"`
+	Value string `length:",256" error:"InvalidRequestException"`
+}
+
+type APIEncryptionConfiguration struct {
+	EncryptionOption string `required:"true" enum:"SSE_S3|SSE_KMS|CSE_KMS" error:"InvalidRequestException"`
+	KmsKey           string `json:",omitempty"`
+}
+
+type APIAclConfiguration struct {
+	S3AclOption string `required:"true" enum:"BUCKET_OWNER_FULL_CONTROL" error:"InvalidRequestException"`
+}
+
+type APIResultConfiguration struct {
+	OutputLocation          string                      `json:",omitempty"`
+	EncryptionConfiguration *APIEncryptionConfiguration `json:",omitempty"`
+	ExpectedBucketOwner     string                      `json:",omitempty"`
+	AclConfiguration        *APIAclConfiguration        `json:",omitempty"`
+}
+
+type APIQueryExecutionContext struct {
+	Database string `json:",omitempty" length:"1,255" error:"InvalidRequestException"`
+	Catalog  string `json:",omitempty" length:"1,256" error:"InvalidRequestException"`
+}
+
+type APIResultReuseByAgeConfiguration struct {
+	Enabled         bool
+	MaxAgeInMinutes int `json:",omitempty" range:"0,10080" error:"InvalidRequestException"`
+}
+
+type APIResultReuseConfiguration struct {
+	ResultReuseByAgeConfiguration *APIResultReuseByAgeConfiguration `json:",omitempty"`
+}
+
+type APIAthenaError struct {
+	ErrorCategory int
+	ErrorType     int
+	Retryable     bool
+	ErrorMessage  string
+}
+
+type APIQueryExecutionStatus struct {
+	State              string
+	StateChangeReason  string          `json:",omitempty"`
+	SubmissionDateTime float64         `json:",omitempty"`
+	CompletionDateTime float64         `json:",omitempty"`
+	AthenaError        *APIAthenaError `json:",omitempty"`
+}
+
+type APIQueryExecutionStatistics struct {
+	EngineExecutionTimeInMillis      int64
+	DataScannedInBytes               int64
+	TotalExecutionTimeInMillis       int64
+	QueryQueueTimeInMillis           int64
+	QueryPlanningTimeInMillis        int64
+	ServicePreProcessingTimeInMillis int64
+	ServiceProcessingTimeInMillis    int64
+}
+
+type APIEngineVersion struct {
+	SelectedEngineVersion  string `json:",omitempty"`
+	EffectiveEngineVersion string `json:",omitempty"`
+}
+
+type APIQueryExecution struct {
+	QueryExecutionId      string
+	Query                 string
+	StatementType         string
+	ResultConfiguration   APIResultConfiguration
+	QueryExecutionContext APIQueryExecutionContext
+	Status                APIQueryExecutionStatus
+	Statistics            APIQueryExecutionStatistics
+	WorkGroup             string
+	EngineVersion         APIEngineVersion
+	ExecutionParameters   []string `json:",omitempty"`
+	SubstatementType      string   `json:",omitempty"`
+}
+
+type APIDatum struct {
+	// Nil for NULL.
+	VarCharValue *string `json:",omitempty"`
+}
+
+type APIRow struct {
+	Data []APIDatum
+}
+
+type APIColumnInfo struct {
+	CatalogName   string
+	SchemaName    string
+	TableName     string
+	Name          string
+	Label         string
+	Type          string
+	Precision     int
+	Scale         int
+	Nullable      string
+	CaseSensitive bool
+}
+
+type APIResultSetMetadata struct {
+	ColumnInfo []APIColumnInfo
+}
+
+type APIResultSet struct {
+	Rows              []APIRow
+	ResultSetMetadata APIResultSetMetadata
+}
+
+type APICustomerContentEncryptionConfiguration struct {
+	KmsKey string
+}
+
+type APIWorkGroupConfiguration struct {
+	ResultConfiguration                    *APIResultConfiguration                    `json:",omitempty"`
+	EnforceWorkGroupConfiguration          bool                                       `json:",omitempty"`
+	PublishCloudWatchMetricsEnabled        bool                                       `json:",omitempty"`
+	BytesScannedCutoffPerQuery             int64                                      `json:",omitempty"`
+	RequesterPaysEnabled                   bool                                       `json:",omitempty"`
+	EngineVersion                          *APIEngineVersion                          `json:",omitempty"`
+	AdditionalConfiguration                string                                     `json:",omitempty"`
+	ExecutionRole                          string                                     `json:",omitempty"`
+	CustomerContentEncryptionConfiguration *APICustomerContentEncryptionConfiguration `json:",omitempty"`
+	EnableMinimumEncryptionConfiguration   bool                                       `json:",omitempty"`
+}
+
+type APIWorkGroup struct {
+	Name          string
+	State         string
+	Configuration APIWorkGroupConfiguration
+	Description   string `json:",omitempty"`
+	CreationTime  float64
+}
+
+type APIWorkGroupSummary struct {
+	Name          string
+	State         string
+	Description   string `json:",omitempty"`
+	CreationTime  float64
+	EngineVersion APIEngineVersion
+}
+
+type StartQueryExecutionInput struct {
+	QueryString              string `required:"true" length:"1,262144" error:"InvalidRequestException"`
+	ClientRequestToken       string `length:"32,128" error:"InvalidRequestException"`
+	QueryExecutionContext    *APIQueryExecutionContext
+	ResultConfiguration      *APIResultConfiguration
+	WorkGroup                string   `pattern:"[a-zA-Z0-9._-]{1,128}" error:"InvalidRequestException"`
+	ExecutionParameters      []string `length:"1," error:"InvalidRequestException"`
+	ResultReuseConfiguration *APIResultReuseConfiguration
+}
+
+type StartQueryExecutionOutput struct {
+	QueryExecutionId string
+}
+
+type GetQueryExecutionInput struct {
+	QueryExecutionId string `required:"true" length:"1,128" error:"InvalidRequestException"`
+}
+
+type GetQueryExecutionOutput struct {
+	QueryExecution APIQueryExecution
+}
+
+type BatchGetQueryExecutionInput struct {
+	QueryExecutionIds []string `required:"true" length:"1,50" error:"InvalidRequestException"`
+}
+
+type APIUnprocessedQueryExecutionId struct {
+	QueryExecutionId string
+	ErrorCode        string
+	ErrorMessage     string
+}
+
+type BatchGetQueryExecutionOutput struct {
+	QueryExecutions              []APIQueryExecution
+	UnprocessedQueryExecutionIds []APIUnprocessedQueryExecutionId
+}
+
+type GetQueryResultsInput struct {
+	QueryExecutionId string `required:"true" length:"1,128" error:"InvalidRequestException"`
+	NextToken        string
+	MaxResults       int    `range:"1,1000" error:"InvalidRequestException"`
+	QueryResultType  string `enum:"DATA_MANIFEST|DATA_ROWS" error:"InvalidRequestException"`
+}
+
+type GetQueryResultsOutput struct {
+	UpdateCount int64
+	ResultSet   APIResultSet
+	NextToken   string `json:",omitempty"`
+}
+
+type StopQueryExecutionInput struct {
+	QueryExecutionId string `required:"true" length:"1,128" error:"InvalidRequestException"`
+}
+
+type StopQueryExecutionOutput struct{}
+
+type ListQueryExecutionsInput struct {
+	NextToken  string
+	MaxResults int    `range:"0,50" error:"InvalidRequestException"`
+	WorkGroup  string `pattern:"[a-zA-Z0-9._-]{1,128}" error:"InvalidRequestException"`
+}
+
+type ListQueryExecutionsOutput struct {
+	QueryExecutionIds []string
+	NextToken         string `json:",omitempty"`
+}
+
+type CreateWorkGroupInput struct {
+	Name          string `required:"true" pattern:"[a-zA-Z0-9._-]{1,128}" error:"InvalidRequestException"`
+	Configuration *APIWorkGroupConfiguration
+	Description   string   `length:",1024" error:"InvalidRequestException"`
+	Tags          []APITag `length:",50" error:"InvalidRequestException"`
+}
+
+type CreateWorkGroupOutput struct{}
+
+type GetWorkGroupInput struct {
+	WorkGroup string `required:"true" pattern:"[a-zA-Z0-9._-]{1,128}" error:"InvalidRequestException"`
+}
+
+type GetWorkGroupOutput struct {
+	WorkGroup APIWorkGroup
+}
+
+type ListWorkGroupsInput struct {
+	NextToken  string
+	MaxResults int `range:"1,50" error:"InvalidRequestException"`
+}
+
+type ListWorkGroupsOutput struct {
+	WorkGroups []APIWorkGroupSummary
+	NextToken  string `json:",omitempty"`
+}
+
+type DeleteWorkGroupInput struct {
+	WorkGroup             string `required:"true" pattern:"[a-zA-Z0-9._-]{1,128}" error:"InvalidRequestException"`
+	RecursiveDeleteOption bool
+}
+
+type DeleteWorkGroupOutput struct{}
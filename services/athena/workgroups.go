@@ -0,0 +1,165 @@
+package athena
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+type WorkGroup struct {
+	Name          string
+	State         string
+	Description   string
+	Configuration APIWorkGroupConfiguration
+	Tags          map[string]string
+	Created       time.Time
+}
+
+func (w *WorkGroup) engineVersion() APIEngineVersion {
+	selected := "AUTO"
+	if w.Configuration.EngineVersion != nil && w.Configuration.EngineVersion.SelectedEngineVersion != "" {
+		selected = w.Configuration.EngineVersion.SelectedEngineVersion
+	}
+	return APIEngineVersion{SelectedEngineVersion: selected, EffectiveEngineVersion: engineVersion}
+}
+
+func (w *WorkGroup) output() APIWorkGroup {
+	configuration := w.Configuration
+	engineVersion := w.engineVersion()
+	configuration.EngineVersion = &engineVersion
+	return APIWorkGroup{
+		Name:          w.Name,
+		State:         w.State,
+		Configuration: configuration,
+		Description:   w.Description,
+		CreationTime:  timestamp.EpochSeconds(w.Created),
+	}
+}
+
+var workGroupNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+
+func (a *Athena) lockedGetWorkGroup(name string) (*WorkGroup, *awserrors.Error) {
+	workGroup, ok := a.workGroups[name]
+	if !ok {
+		return nil, InvalidRequestException("WorkGroup " + name + " is not found.")
+	}
+	return workGroup, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_CreateWorkGroup.html
+func (a *Athena) CreateWorkGroup(input CreateWorkGroupInput) (*CreateWorkGroupOutput, *awserrors.Error) {
+	if !workGroupNameRegex.MatchString(input.Name) {
+		return nil, InvalidRequestException("WorkGroup name must match " + workGroupNameRegex.String())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.workGroups[input.Name]; ok {
+		return nil, InvalidRequestException("WorkGroup is already created")
+	}
+	workGroup := &WorkGroup{
+		Name:        input.Name,
+		State:       "ENABLED",
+		Description: input.Description,
+		Tags:        make(map[string]string),
+		Created:     a.clock(),
+	}
+	if input.Configuration != nil {
+		workGroup.Configuration = *input.Configuration
+	}
+	if location := workGroup.Configuration.ResultConfiguration; location != nil && location.OutputLocation != "" {
+		if _, _, err := parseS3Location(location.OutputLocation); err != nil {
+			return nil, InvalidRequestException(err.Error())
+		}
+	}
+	for _, tag := range input.Tags {
+		workGroup.Tags[tag.Key] = tag.Value
+	}
+	a.workGroups[input.Name] = workGroup
+	return &CreateWorkGroupOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_GetWorkGroup.html
+func (a *Athena) GetWorkGroup(input GetWorkGroupInput) (*GetWorkGroupOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	workGroup, err := a.lockedGetWorkGroup(input.WorkGroup)
+	if err != nil {
+		return nil, err
+	}
+	return &GetWorkGroupOutput{WorkGroup: workGroup.output()}, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_ListWorkGroups.html
+func (a *Athena) ListWorkGroups(input ListWorkGroupsInput) (*ListWorkGroupsOutput, *awserrors.Error) {
+	limit, start, err := pagination.Parse(input.MaxResults, 50, 50, input.NextToken,
+		InvalidRequestException("MaxResults must be between 1 and 50."),
+		InvalidRequestException("Invalid NextToken."))
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var summaries []APIWorkGroupSummary
+	for _, workGroup := range a.workGroups {
+		summaries = append(summaries, APIWorkGroupSummary{
+			Name:          workGroup.Name,
+			State:         workGroup.State,
+			Description:   workGroup.Description,
+			CreationTime:  timestamp.EpochSeconds(workGroup.Created),
+			EngineVersion: workGroup.engineVersion(),
+		})
+	}
+	slices.SortFunc(summaries, func(a, b APIWorkGroupSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	summaries, nextToken := pagination.Page(summaries, limit, start)
+	return &ListWorkGroupsOutput{WorkGroups: summaries, NextToken: nextToken}, nil
+}
+
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_DeleteWorkGroup.html
+func (a *Athena) DeleteWorkGroup(input DeleteWorkGroupInput) (*DeleteWorkGroupOutput, *awserrors.Error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if input.WorkGroup == primaryWorkGroup {
+		return nil, InvalidRequestException("Primary WorkGroup cannot be deleted")
+	}
+	if _, err := a.lockedGetWorkGroup(input.WorkGroup); err != nil {
+		return nil, err
+	}
+
+	var remaining []string
+	for _, id := range a.executionIds {
+		if a.executions[id].WorkGroup != input.WorkGroup {
+			remaining = append(remaining, id)
+		}
+	}
+	if len(remaining) != len(a.executionIds) {
+		if !input.RecursiveDeleteOption {
+			return nil, InvalidRequestException("WorkGroup " + input.WorkGroup + " is not empty. Use RecursiveDeleteOption to delete it and its contents")
+		}
+		for _, id := range a.executionIds {
+			if a.executions[id].WorkGroup == input.WorkGroup {
+				delete(a.executions, id)
+			}
+		}
+		for token, id := range a.clientRequestTokens {
+			if _, ok := a.executions[id]; !ok {
+				delete(a.clientRequestTokens, token)
+			}
+		}
+		a.executionIds = remaining
+	}
+	delete(a.workGroups, input.WorkGroup)
+	return &DeleteWorkGroupOutput{}, nil
+}
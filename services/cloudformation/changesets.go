@@ -0,0 +1,319 @@
+package cloudformation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+type ChangeSet struct {
+	Id          string
+	Name        string
+	Type        string
+	Description string
+	// CREATE_COMPLETE, or FAILED if there's nothing to change.
+	Status       string
+	StatusReason string
+	// AVAILABLE, UNAVAILABLE, EXECUTE_IN_PROGRESS, EXECUTE_COMPLETE, EXECUTE_FAILED or OBSOLETE.
+	ExecutionStatus  string
+	CreationTime     time.Time
+	Capabilities     []string
+	Tags             []APITag
+	NotificationARNs []string
+	OnStackFailure   string
+	Changes          []APIChange
+
+	stack      *Stack
+	deployment *deployment
+}
+
+// lockedGetChangeSet returns a change set by its ARN, or by its name and its stack's name or ID.
+func (c *CloudFormation) lockedGetChangeSet(stackName string, nameOrArn string) (*ChangeSet, *awserrors.Error) {
+	if strings.HasPrefix(nameOrArn, "arn:") {
+		for _, id := range c.stackIds {
+			for _, changeSet := range c.stacksById[id].changeSets {
+				if changeSet.Id == nameOrArn {
+					return changeSet, nil
+				}
+			}
+		}
+		return nil, ChangeSetNotFoundException(fmt.Sprintf("ChangeSet [%s] does not exist", nameOrArn))
+	}
+
+	if stackName == "" {
+		return nil, ValidationError("StackName must be specified if ChangeSetName is not specified as an ARN.")
+	}
+	stack, awserr := c.lockedGetStack(stackName)
+	if awserr != nil {
+		return nil, ChangeSetNotFoundException(fmt.Sprintf("ChangeSet [%s] does not exist", nameOrArn))
+	}
+	for _, changeSet := range stack.changeSets {
+		if changeSet.Name == nameOrArn {
+			return changeSet, nil
+		}
+	}
+	return nil, ChangeSetNotFoundException(fmt.Sprintf("ChangeSet [%s] does not exist", nameOrArn))
+}
+
+// lockedExpireChangeSets marks the stack's change sets, other than the given one, as obsolete once
+// the stack has been changed by something else.
+func (c *CloudFormation) lockedExpireChangeSets(stack *Stack, except *ChangeSet) {
+	for _, changeSet := range stack.changeSets {
+		if changeSet != except && changeSet.ExecutionStatus == "AVAILABLE" {
+			changeSet.ExecutionStatus = "OBSOLETE"
+		}
+	}
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_CreateChangeSet.html
+func (c *CloudFormation) CreateChangeSet(input CreateChangeSetInput) (*CreateChangeSetOutput, *awserrors.Error) {
+	if awserr := validateStackName(input.StackName); awserr != nil {
+		return nil, awserr
+	}
+	if !stackNameRegex.MatchString(input.ChangeSetName) {
+		return nil, ValidationError(fmt.Sprintf(
+			"1 validation error detected: Value '%s' at 'changeSetName' failed to satisfy constraint: Member must satisfy regular expression pattern: [a-zA-Z][-a-zA-Z0-9]*", input.ChangeSetName))
+	}
+	switch input.OnStackFailure {
+	case "", "DO_NOTHING", "ROLLBACK", "DELETE":
+	default:
+		return nil, ValidationError("OnStackFailure must be one of DO_NOTHING, ROLLBACK or DELETE")
+	}
+	changeSetType := input.ChangeSetType
+	if changeSetType == "" {
+		changeSetType = "UPDATE"
+	}
+
+	c.mu.Lock()
+	stack, awserr := c.lockedGetStack(input.StackName)
+	var identity stackIdentity
+	var previous *deployment
+	switch changeSetType {
+	case "CREATE":
+		if awserr == nil && stack.Status != "REVIEW_IN_PROGRESS" {
+			c.mu.Unlock()
+			return nil, AlreadyExistsException(fmt.Sprintf("Stack [%s] already exists and cannot be created again with the changeSet [%s].", input.StackName, input.ChangeSetName))
+		}
+		identity = stackIdentity{id: c.newStackId(input.StackName), name: input.StackName}
+		if awserr == nil {
+			identity.id = stack.Id
+		}
+	case "UPDATE":
+		if awserr != nil {
+			c.mu.Unlock()
+			return nil, ValidationError(fmt.Sprintf("Stack [%s] does not exist", input.StackName))
+		}
+		identity = stackIdentity{id: stack.Id, name: stack.Name, notificationARNs: stack.NotificationARNs}
+		previous = stack.deployment
+	case "IMPORT":
+		c.mu.Unlock()
+		return nil, ValidationError("ChangeSetType IMPORT is not supported")
+	default:
+		c.mu.Unlock()
+		return nil, ValidationError("ChangeSetType must be one of CREATE, UPDATE or IMPORT")
+	}
+	if input.NotificationARNs != nil {
+		identity.notificationARNs = input.NotificationARNs
+	}
+	c.mu.Unlock()
+
+	source := templateSource{body: input.TemplateBody, url: input.TemplateURL, usePrevious: input.UsePreviousTemplate}
+	d, awserr := c.newDeployment(identity, source, input.Parameters, previous)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The stack may have been created or deleted in the meantime.
+	stack, awserr = c.lockedGetStack(identity.id)
+	if changeSetType == "CREATE" {
+		if awserr != nil {
+			stack = c.lockedNewStack(identity.id, input.StackName, "REVIEW_IN_PROGRESS")
+			stack.StatusReason = "User Initiated"
+			c.lockedAddEvent(stack, nil, "")
+		} else if stack.Status != "REVIEW_IN_PROGRESS" {
+			return nil, AlreadyExistsException(fmt.Sprintf("Stack [%s] already exists and cannot be created again with the changeSet [%s].", input.StackName, input.ChangeSetName))
+		}
+	} else {
+		if awserr != nil {
+			return nil, ValidationError(fmt.Sprintf("Stack [%s] does not exist", input.StackName))
+		}
+		if !slices.Contains(updatableStatuses, stack.Status) {
+			return nil, ValidationError(fmt.Sprintf("Stack:%s is in %s state and can not be updated.", stack.Id, stack.Status))
+		}
+	}
+	for _, changeSet := range stack.changeSets {
+		if changeSet.Name == input.ChangeSetName {
+			return nil, AlreadyExistsException(fmt.Sprintf("ChangeSet [%s] already exists", input.ChangeSetName))
+		}
+	}
+
+	d.evaluator.resources = stack.Resources
+	d.evaluator.exports = c.lockedExports(stack)
+	changeSet := &ChangeSet{
+		Id:               c.arnGenerator.Generate("cloudformation", "changeSet", input.ChangeSetName+"/"+uuid.Must(uuid.NewV4()).String()),
+		Name:             input.ChangeSetName,
+		Type:             changeSetType,
+		Description:      input.Description,
+		Status:           "CREATE_COMPLETE",
+		ExecutionStatus:  "AVAILABLE",
+		CreationTime:     c.clock(),
+		Capabilities:     input.Capabilities,
+		Tags:             input.Tags,
+		NotificationARNs: identity.notificationARNs,
+		OnStackFailure:   input.OnStackFailure,
+		Changes:          c.lockedChanges(stack, d),
+		stack:            stack,
+		deployment:       d,
+	}
+	if changeSetType == "UPDATE" && len(changeSet.Changes) == 0 &&
+		d.templateBody == stack.deployment.templateBody && slices.Equal(d.parameters, stack.deployment.parameters) {
+		changeSet.Status = "FAILED"
+		changeSet.StatusReason = "The submitted information didn't contain changes. Submit different information to create a change set."
+		changeSet.ExecutionStatus = "UNAVAILABLE"
+	}
+	stack.changeSets = append(stack.changeSets, changeSet)
+
+	return &CreateChangeSetOutput{
+		Id:      changeSet.Id,
+		StackId: stack.Id,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_DescribeChangeSet.html
+func (c *CloudFormation) DescribeChangeSet(input DescribeChangeSetInput) (*DescribeChangeSetOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changeSet, awserr := c.lockedGetChangeSet(input.StackName, input.ChangeSetName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeChangeSetOutput{
+		Capabilities:     changeSet.Capabilities,
+		ChangeSetId:      changeSet.Id,
+		ChangeSetName:    changeSet.Name,
+		Changes:          changeSet.Changes,
+		CreationTime:     formatTime(changeSet.CreationTime),
+		Description:      changeSet.Description,
+		ExecutionStatus:  changeSet.ExecutionStatus,
+		NotificationARNs: changeSet.NotificationARNs,
+		OnStackFailure:   changeSet.OnStackFailure,
+		Parameters:       changeSet.deployment.parameters,
+		StackId:          changeSet.stack.Id,
+		StackName:        changeSet.stack.Name,
+		Status:           changeSet.Status,
+		StatusReason:     changeSet.StatusReason,
+		Tags:             changeSet.Tags,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_ExecuteChangeSet.html
+func (c *CloudFormation) ExecuteChangeSet(input ExecuteChangeSetInput) (*ExecuteChangeSetOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changeSet, awserr := c.lockedGetChangeSet(input.StackName, input.ChangeSetName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if changeSet.ExecutionStatus != "AVAILABLE" {
+		return nil, InvalidChangeSetStatusException(fmt.Sprintf(
+			"ChangeSet [%s] cannot be executed in its current execution status of [%s]", changeSet.Id, changeSet.ExecutionStatus))
+	}
+	stack := changeSet.stack
+	update := changeSet.Type == "UPDATE"
+	if update && !slices.Contains(updatableStatuses, stack.Status) {
+		return nil, ValidationError(fmt.Sprintf("Stack:%s is in %s state and can not be updated.", stack.Id, stack.Status))
+	}
+	if !update && stack.Status != "REVIEW_IN_PROGRESS" {
+		return nil, InvalidChangeSetStatusException(fmt.Sprintf("Stack:%s is in %s state and can not be created.", stack.Id, stack.Status))
+	}
+
+	// Executing a change set deletes the stack's other change sets.
+	stack.changeSets = []*ChangeSet{changeSet}
+	changeSet.ExecutionStatus = "EXECUTE_IN_PROGRESS"
+
+	d := changeSet.deployment
+	stack.NotificationARNs = changeSet.NotificationARNs
+	if changeSet.Capabilities != nil {
+		stack.Capabilities = changeSet.Capabilities
+	}
+	if changeSet.Tags != nil {
+		stack.Tags = changeSet.Tags
+	}
+	if update {
+		stack.DisableRollback = input.DisableRollback || changeSet.OnStackFailure == "DO_NOTHING"
+		c.lockedSetStackStatus(stack, "UPDATE_IN_PROGRESS", "User Initiated")
+	} else {
+		stack.Description = d.template.Description
+		stack.DisableRollback = input.DisableRollback
+		stack.OnFailure = changeSet.OnStackFailure
+		c.lockedSetStackStatus(stack, "CREATE_IN_PROGRESS", "User Initiated")
+	}
+	c.lockedRun(stack, func() {
+		c.deploy(stack, d, update)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if strings.HasSuffix(stack.Status, "_COMPLETE") && !strings.Contains(stack.Status, "ROLLBACK") && stack.Status != "DELETE_COMPLETE" {
+			changeSet.ExecutionStatus = "EXECUTE_COMPLETE"
+		} else {
+			changeSet.ExecutionStatus = "EXECUTE_FAILED"
+		}
+	})
+
+	return &ExecuteChangeSetOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_DeleteChangeSet.html
+func (c *CloudFormation) DeleteChangeSet(input DeleteChangeSetInput) (*DeleteChangeSetOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changeSet, awserr := c.lockedGetChangeSet(input.StackName, input.ChangeSetName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if changeSet.ExecutionStatus == "EXECUTE_IN_PROGRESS" {
+		return nil, InvalidChangeSetStatusException(fmt.Sprintf(
+			"Cannot delete ChangeSet [%s] in its current execution status of [%s]", changeSet.Id, changeSet.ExecutionStatus))
+	}
+	changeSet.stack.changeSets = slices.DeleteFunc(changeSet.stack.changeSets, func(other *ChangeSet) bool {
+		return other == changeSet
+	})
+	return &DeleteChangeSetOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_ListChangeSets.html
+func (c *CloudFormation) ListChangeSets(input ListChangeSetsInput) (*ListChangeSetsOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stack, awserr := c.lockedGetStack(input.StackName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListChangeSetsOutput{}
+	for _, changeSet := range stack.changeSets {
+		output.Summaries = append(output.Summaries, APIChangeSetSummary{
+			ChangeSetId:     changeSet.Id,
+			ChangeSetName:   changeSet.Name,
+			CreationTime:    formatTime(changeSet.CreationTime),
+			Description:     changeSet.Description,
+			ExecutionStatus: changeSet.ExecutionStatus,
+			StackId:         stack.Id,
+			StackName:       stack.Name,
+			Status:          changeSet.Status,
+			StatusReason:    changeSet.StatusReason,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,464 @@
+package cloudformation
+
+import (
+	"strings"
+	"testing"
+
+	"aws-in-a-box/services/ecr"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/services/ssm"
+)
+
+const changeSetTemplate = `
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: app-queue
+      VisibilityTimeout: 30
+  OldQueue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: app-old-queue
+`
+
+const updatedChangeSetTemplate = `
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: app-queue
+      VisibilityTimeout: 60
+  Parameter:
+    Type: AWS::SSM::Parameter
+    Properties:
+      Name: /app/queue
+      Type: String
+      Value: !Ref Queue
+`
+
+func executeChangeSet(t *testing.T, c *CloudFormation, changeSetId string) *DescribeChangeSetOutput {
+	_, awserr := c.ExecuteChangeSet(ExecuteChangeSetInput{ChangeSetName: changeSetId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	description, awserr := c.DescribeChangeSet(DescribeChangeSetInput{ChangeSetName: changeSetId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	waitForStack(t, c, description.StackId)
+	description, awserr = c.DescribeChangeSet(DescribeChangeSetInput{ChangeSetName: changeSetId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return description
+}
+
+func TestChangeSets(t *testing.T) {
+	c, s := newCloudFormation(t)
+
+	created, awserr := c.CreateChangeSet(CreateChangeSetInput{
+		StackName:     "app",
+		ChangeSetName: "create",
+		ChangeSetType: "CREATE",
+		TemplateBody:  changeSetTemplate,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(created.Id, "arn:aws:cloudformation:us-east-1:123456789012:changeSet/create/") {
+		t.Errorf("unexpected change set ID %s", created.Id)
+	}
+	stack := waitForStack(t, c, created.StackId)
+	if stack.StackStatus != "REVIEW_IN_PROGRESS" {
+		t.Errorf("got status %s", stack.StackStatus)
+	}
+	description, awserr := c.DescribeChangeSet(DescribeChangeSetInput{StackName: "app", ChangeSetName: "create"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if description.Status != "CREATE_COMPLETE" || description.ExecutionStatus != "AVAILABLE" || len(description.Changes) != 2 {
+		t.Fatalf("got change set %+v", description)
+	}
+	for _, change := range description.Changes {
+		if change.ResourceChange.Action != "Add" {
+			t.Errorf("got change %+v", change)
+		}
+	}
+	if _, awserr := s.sqs.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "app-queue"}); awserr == nil {
+		t.Error("the queue was created before the change set was executed")
+	}
+
+	description = executeChangeSet(t, c, created.Id)
+	if description.ExecutionStatus != "EXECUTE_COMPLETE" {
+		t.Errorf("got execution status %s", description.ExecutionStatus)
+	}
+	stack = waitForStack(t, c, created.StackId)
+	if stack.StackStatus != "CREATE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+
+	// A change set can't be executed twice.
+	_, awserr = c.ExecuteChangeSet(ExecuteChangeSetInput{ChangeSetName: created.Id})
+	if awserr == nil || awserr.Body.Type != "InvalidChangeSetStatusException" {
+		t.Errorf("expected InvalidChangeSetStatusException, got %v", awserr)
+	}
+
+	updated, awserr := c.CreateChangeSet(CreateChangeSetInput{
+		StackName:     "app",
+		ChangeSetName: "update",
+		TemplateBody:  updatedChangeSetTemplate,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	description, awserr = c.DescribeChangeSet(DescribeChangeSetInput{ChangeSetName: updated.Id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	actions := make(map[string]string)
+	for _, change := range description.Changes {
+		actions[change.ResourceChange.LogicalResourceId] = change.ResourceChange.Action
+	}
+	if actions["Queue"] != "Modify" || actions["Parameter"] != "Add" || actions["OldQueue"] != "Remove" || len(actions) != 3 {
+		t.Errorf("got actions %v", actions)
+	}
+
+	description = executeChangeSet(t, c, updated.Id)
+	if description.ExecutionStatus != "EXECUTE_COMPLETE" {
+		t.Errorf("got execution status %s", description.ExecutionStatus)
+	}
+	stack = waitForStack(t, c, updated.StackId)
+	if stack.StackStatus != "UPDATE_COMPLETE" || stack.LastUpdatedTime == "" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	if _, awserr := s.sqs.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "app-old-queue"}); awserr == nil {
+		t.Error("the removed queue still exists")
+	}
+	queueUrl, awserr := s.sqs.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "app-queue"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	parameter, awserr := s.ssm.GetParameter(ssm.GetParameterInput{Name: "/app/queue"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if parameter.Parameter.Value != queueUrl.QueueUrl {
+		t.Errorf("got parameter %s, want %s", parameter.Parameter.Value, queueUrl.QueueUrl)
+	}
+
+	// The other change sets were deleted when it was executed.
+	list, awserr := c.ListChangeSets(ListChangeSetsInput{StackName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Summaries) != 1 || list.Summaries[0].ChangeSetName != "update" {
+		t.Errorf("got change sets %+v", list.Summaries)
+	}
+
+	empty, awserr := c.CreateChangeSet(CreateChangeSetInput{
+		StackName:     "app",
+		ChangeSetName: "empty",
+		TemplateBody:  updatedChangeSetTemplate,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	description, awserr = c.DescribeChangeSet(DescribeChangeSetInput{ChangeSetName: empty.Id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if description.Status != "FAILED" || !strings.Contains(description.StatusReason, "didn't contain changes") {
+		t.Errorf("got change set %+v", description)
+	}
+	if _, awserr := c.DeleteChangeSet(DeleteChangeSetInput{ChangeSetName: empty.Id}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.DescribeChangeSet(DescribeChangeSetInput{ChangeSetName: empty.Id})
+	if awserr == nil || awserr.Body.Type != "ChangeSetNotFoundException" {
+		t.Errorf("expected ChangeSetNotFoundException, got %v", awserr)
+	}
+
+	_, awserr = c.UpdateStack(UpdateStackInput{StackName: "app", UsePreviousTemplate: true})
+	if awserr == nil || awserr.Body.Message != "No updates are to be performed." {
+		t.Errorf("expected no updates, got %v", awserr)
+	}
+}
+
+func TestOutputsAndExports(t *testing.T) {
+	c, s := newCloudFormation(t)
+
+	exporting, awserr := c.CreateStack(CreateStackInput{
+		StackName: "network",
+		TemplateBody: `
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: shared-queue
+Outputs:
+  QueueUrl:
+    Description: The shared queue
+    Value: !Ref Queue
+    Export:
+      Name: !Sub "${AWS::StackName}-QueueUrl"
+  QueueArn:
+    Value: !GetAtt Queue.Arn
+`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	stack := waitForStack(t, c, exporting.StackId)
+	if stack.StackStatus != "CREATE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	queueUrl, awserr := s.sqs.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "shared-queue"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	want := []APIOutput{
+		{OutputKey: "QueueArn", OutputValue: "arn:aws:sqs:us-east-1:123456789012:shared-queue"},
+		{Description: "The shared queue", ExportName: "network-QueueUrl", OutputKey: "QueueUrl", OutputValue: queueUrl.QueueUrl},
+	}
+	if len(stack.Outputs) != 2 || stack.Outputs[0] != want[0] || stack.Outputs[1] != want[1] {
+		t.Errorf("got outputs %+v, want %+v", stack.Outputs, want)
+	}
+
+	importing, awserr := c.CreateStack(CreateStackInput{
+		StackName: "app",
+		TemplateBody: `
+Resources:
+  Parameter:
+    Type: AWS::SSM::Parameter
+    Properties:
+      Name: /app/queue
+      Type: String
+      Value: !ImportValue network-QueueUrl
+`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if stack := waitForStack(t, c, importing.StackId); stack.StackStatus != "CREATE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	parameter, awserr := s.ssm.GetParameter(ssm.GetParameterInput{Name: "/app/queue"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if parameter.Parameter.Value != queueUrl.QueueUrl {
+		t.Errorf("got parameter %s", parameter.Parameter.Value)
+	}
+
+	exports, awserr := c.ListExports(ListExportsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(exports.Exports) != 1 || exports.Exports[0].ExportingStackId != exporting.StackId {
+		t.Errorf("got exports %+v", exports.Exports)
+	}
+	imports, awserr := c.ListImports(ListImportsInput{ExportName: "network-QueueUrl"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(imports.Imports) != 1 || imports.Imports[0] != "app" {
+		t.Errorf("got imports %v", imports.Imports)
+	}
+
+	// Exports can't be deleted while they're imported.
+	_, awserr = c.DeleteStack(DeleteStackInput{StackName: "network"})
+	if awserr == nil || !strings.Contains(awserr.Body.Message, "in use by app") {
+		t.Errorf("expected an error, got %v", awserr)
+	}
+	// Or exported by another stack.
+	conflicting, awserr := c.CreateStack(CreateStackInput{
+		StackName: "conflicting",
+		TemplateBody: `
+Resources:
+  Handle:
+    Type: AWS::CloudFormation::WaitConditionHandle
+Outputs:
+  Url:
+    Value: x
+    Export:
+      Name: network-QueueUrl
+`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	stack = waitForStack(t, c, conflicting.StackId)
+	if stack.StackStatus != "ROLLBACK_COMPLETE" || !strings.Contains(stack.StackStatusReason, "already exported by stack network") {
+		t.Errorf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+
+	if _, awserr := c.DeleteStack(DeleteStackInput{StackName: "app"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	waitForStack(t, c, importing.StackId)
+	if _, awserr := c.DeleteStack(DeleteStackInput{StackName: "network"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if stack := waitForStack(t, c, exporting.StackId); stack.StackStatus != "DELETE_COMPLETE" {
+		t.Errorf("got status %s", stack.StackStatus)
+	}
+	_, awserr = c.ListImports(ListImportsInput{ExportName: "network-QueueUrl"})
+	if awserr == nil {
+		t.Error("expected an error for an export which isn't imported")
+	}
+}
+
+func TestTemplateURL(t *testing.T) {
+	c, s := newCloudFormation(t)
+
+	if _, awserr := s.s3.CreateBucket(s3.CreateBucketInput{Bucket: "templates"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr := s.s3.PutObject(s3.PutObjectInput{
+		Bucket: "templates",
+		Key:    "stack.yaml",
+		Data:   strings.NewReader(changeSetTemplate),
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	for i, templateURL := range []string{
+		"https://templates.s3.us-east-1.amazonaws.com/stack.yaml",
+		"http://localhost:4566/templates/stack.yaml",
+	} {
+		output, awserr := c.CreateChangeSet(CreateChangeSetInput{
+			StackName:     "app",
+			ChangeSetName: "change" + string(rune('a'+i)),
+			ChangeSetType: "CREATE",
+			TemplateURL:   templateURL,
+		})
+		if awserr != nil {
+			t.Fatalf("%s: %v", templateURL, awserr)
+		}
+		template, awserr := c.GetTemplate(GetTemplateInput{StackName: "app", ChangeSetName: output.Id})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if template.TemplateBody != changeSetTemplate {
+			t.Errorf("%s: got template %q", templateURL, template.TemplateBody)
+		}
+	}
+
+	_, awserr = c.CreateStack(CreateStackInput{StackName: "missing", TemplateURL: "https://templates.s3.amazonaws.com/missing.yaml"})
+	if awserr == nil || !strings.Contains(awserr.Body.Message, "Unable to get object") {
+		t.Errorf("expected an error, got %v", awserr)
+	}
+}
+
+// A cut down version of the template `cdk bootstrap` deploys.
+const bootstrapTemplate = `
+Parameters:
+  Qualifier:
+    Type: String
+    Default: hnb659fds
+Resources:
+  StagingBucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "cdk-${Qualifier}-assets-${AWS::AccountId}-${AWS::Region}"
+  StagingBucketPolicy:
+    Type: AWS::S3::BucketPolicy
+    Properties:
+      Bucket: !Ref StagingBucket
+      PolicyDocument:
+        Statement:
+          - Action: s3:*
+            Effect: Deny
+            Principal: "*"
+            Resource: !Sub "${StagingBucket.Arn}/*"
+  ContainerAssetsRepository:
+    Type: AWS::ECR::Repository
+    Properties:
+      RepositoryName: !Sub "cdk-${Qualifier}-container-assets-${AWS::AccountId}-${AWS::Region}"
+      ImageTagMutability: IMMUTABLE
+  DeploymentActionRole:
+    Type: AWS::IAM::Role
+    Properties:
+      RoleName: !Sub "cdk-${Qualifier}-deploy-role-${AWS::AccountId}-${AWS::Region}"
+      AssumeRolePolicyDocument:
+        Statement:
+          - Action: sts:AssumeRole
+            Effect: Allow
+  DeploymentActionPolicy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: deploy
+      Roles: [!Ref DeploymentActionRole]
+  CdkBootstrapVersion:
+    Type: AWS::SSM::Parameter
+    Properties:
+      Type: String
+      Name: !Sub "/cdk-bootstrap/${Qualifier}/version"
+      Value: "19"
+Outputs:
+  BucketName:
+    Value: !Ref StagingBucket
+  ImageRepositoryName:
+    Value: !Ref ContainerAssetsRepository
+  RepositoryUri:
+    Value: !GetAtt ContainerAssetsRepository.RepositoryUri
+  DeployRoleArn:
+    Value: !GetAtt DeploymentActionRole.Arn
+  BootstrapVersion:
+    Value: !GetAtt CdkBootstrapVersion.Value
+`
+
+func TestBootstrapStack(t *testing.T) {
+	c, s := newCloudFormation(t)
+
+	output, awserr := c.CreateStack(CreateStackInput{
+		StackName:    "CDKToolkit",
+		Capabilities: []string{"CAPABILITY_NAMED_IAM"},
+		TemplateBody: bootstrapTemplate,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	stack := waitForStack(t, c, output.StackId)
+	if stack.StackStatus != "CREATE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	outputs := make(map[string]string)
+	for _, output := range stack.Outputs {
+		outputs[output.OutputKey] = output.OutputValue
+	}
+	if outputs["BucketName"] != "cdk-hnb659fds-assets-123456789012-us-east-1" ||
+		outputs["ImageRepositoryName"] != "cdk-hnb659fds-container-assets-123456789012-us-east-1" ||
+		outputs["DeployRoleArn"] != "arn:aws:iam::123456789012:role/cdk-hnb659fds-deploy-role-123456789012-us-east-1" ||
+		outputs["BootstrapVersion"] != "19" ||
+		!strings.HasSuffix(outputs["RepositoryUri"], "/cdk-hnb659fds-container-assets-123456789012-us-east-1") {
+		t.Errorf("got outputs %v", outputs)
+	}
+
+	if _, awserr := s.s3.HeadBucket(s3.HeadBucketInput{Bucket: outputs["BucketName"]}); awserr != nil {
+		t.Error(awserr)
+	}
+	repositories, awserr := s.ecr.DescribeRepositories(ecr.DescribeRepositoriesInput{RepositoryNames: []string{outputs["ImageRepositoryName"]}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if repositories.Repositories[0].ImageTagMutability != "IMMUTABLE" {
+		t.Errorf("got repository %+v", repositories.Repositories[0])
+	}
+	if _, awserr := s.ssm.GetParameter(ssm.GetParameterInput{Name: "/cdk-bootstrap/hnb659fds/version"}); awserr != nil {
+		t.Error(awserr)
+	}
+
+	if _, awserr := c.DeleteStack(DeleteStackInput{StackName: "CDKToolkit"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if stack := waitForStack(t, c, output.StackId); stack.StackStatus != "DELETE_COMPLETE" {
+		t.Errorf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	if _, awserr := s.ecr.DescribeRepositories(ecr.DescribeRepositoriesInput{RepositoryNames: []string{outputs["ImageRepositoryName"]}}); awserr == nil {
+		t.Error("the repository still exists")
+	}
+}
@@ -0,0 +1,198 @@
+package cloudformation
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/ecr"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/services/ssm"
+)
+
+// Buckets is the local S3 service, which creates AWS::S3::Bucket resources.
+type Buckets interface {
+	CreateBucket(input s3.CreateBucketInput) (*s3.CreateBucketOutput, *awserrors.Error)
+	DeleteBucket(input s3.DeleteBucketInput) (*s3.Response204, *awserrors.Error)
+	// Reads templates given by TemplateURL.
+	GetObject(input s3.GetObjectInput) (*s3.GetObjectOutput, *awserrors.Error)
+}
+
+// Streams is the local Kinesis service, which creates AWS::Kinesis::Stream resources.
+type Streams interface {
+	CreateStream(input kinesis.CreateStreamInput) (*kinesis.CreateStreamOutput, *awserrors.Error)
+	DeleteStream(input kinesis.DeleteStreamInput) (*kinesis.DeleteStreamOutput, *awserrors.Error)
+	DescribeStreamSummary(input kinesis.DescribeStreamSummaryInput) (*kinesis.DescribeStreamSummaryOutput, *awserrors.Error)
+	// InRegion returns the region's Kinesis, in which that region's stacks create streams.
+	InRegion(name string) (*kinesis.Kinesis, error)
+}
+
+// Keys is the local KMS service, which creates AWS::KMS::Key and AWS::KMS::Alias resources.
+type Keys interface {
+	CreateKey(input kms.CreateKeyInput) (*kms.CreateKeyOutput, *awserrors.Error)
+	DisableKey(input kms.DisableKeyInput) (*kms.DisableKeyOutput, *awserrors.Error)
+	CreateAlias(input kms.CreateAliasInput) (*kms.CreateAliasOutput, *awserrors.Error)
+	DeleteAlias(input kms.DeleteAliasInput) (*kms.DeleteAliasOutput, *awserrors.Error)
+	// InRegion returns the region's KMS, in which that region's stacks create keys and aliases.
+	InRegion(name string) (*kms.KMS, error)
+}
+
+// Repositories is the local ECR service, which creates AWS::ECR::Repository resources.
+type Repositories interface {
+	CreateRepository(input ecr.CreateRepositoryInput) (*ecr.CreateRepositoryOutput, *awserrors.Error)
+	DeleteRepository(input ecr.DeleteRepositoryInput) (*ecr.DeleteRepositoryOutput, *awserrors.Error)
+	SetRepositoryPolicy(input ecr.SetRepositoryPolicyInput) (*ecr.SetRepositoryPolicyOutput, *awserrors.Error)
+	// InRegion returns the region's ECR, in which that region's stacks create repositories.
+	InRegion(name string) (*ecr.ECR, error)
+}
+
+// Parameters is the local SSM service, which creates AWS::SSM::Parameter resources.
+type Parameters interface {
+	PutParameter(input ssm.PutParameterInput) (*ssm.PutParameterOutput, *awserrors.Error)
+	DeleteParameter(input ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, *awserrors.Error)
+	// InRegion returns the region's SSM, in which that region's stacks create parameters.
+	InRegion(name string) (*ssm.SSM, error)
+}
+
+// Queues is the local SQS service, which creates AWS::SQS::Queue resources.
+type Queues interface {
+	CreateQueue(input sqs.CreateQueueInput) (*sqs.CreateQueueOutput, *awserrors.Error)
+	DeleteQueue(input sqs.DeleteQueueInput) (*sqs.DeleteQueueOutput, *awserrors.Error)
+	GetQueueAttributes(input sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, *awserrors.Error)
+	// InRegion returns the region's SQS, in which that region's stacks create queues.
+	InRegion(name string) (*sqs.SQS, error)
+}
+
+// Tables is the local DynamoDB service, which creates AWS::DynamoDB::Table resources.
+type Tables interface {
+	CreateTable(input dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, *awserrors.Error)
+	DeleteTable(input dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, *awserrors.Error)
+	// InRegion returns the region's DynamoDB, in which that region's stacks create tables.
+	InRegion(name string) (*dynamodb.DynamoDB, error)
+}
+
+type CloudFormation struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Each of these is nil if its service isn't enabled, in which case its resources fail to create.
+	buckets      Buckets
+	streams      Streams
+	keys         Keys
+	repositories Repositories
+	parameters   Parameters
+	queues       Queues
+	tables       Tables
+	// Overridden in tests.
+	clock        func() time.Time
+	pollInterval time.Duration
+
+	mu         sync.Mutex
+	stacksById map[string]*Stack
+	// Stack IDs in the order they were created.
+	stackIds []string
+	// Shared by the instances of all regions, which each have their own stacks.
+	regions *region.Regions[*CloudFormation]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// All optional. Templates can't create resources of services which aren't given.
+	S3       Buckets
+	Kinesis  Streams
+	KMS      Keys
+	ECR      Repositories
+	SSM      Parameters
+	SQS      Queues
+	DynamoDB Tables
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *CloudFormation {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// S3 is global, so every region's stacks create buckets in, and read templates from, the same
+	// S3. The other services are regional, so each region's stacks create resources in their own.
+	c := newRegion(options)
+	c.regions = region.New(options.ArnGenerator.Region, options.Regions, c, func(name string) (*CloudFormation, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.Kinesis != nil {
+			streams, err := options.Kinesis.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.Kinesis = streams
+		}
+		if options.KMS != nil {
+			keys, err := options.KMS.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.KMS = keys
+		}
+		if options.ECR != nil {
+			repositories, err := options.ECR.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.ECR = repositories
+		}
+		if options.SSM != nil {
+			parameters, err := options.SSM.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.SSM = parameters
+		}
+		if options.SQS != nil {
+			queues, err := options.SQS.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.SQS = queues
+		}
+		if options.DynamoDB != nil {
+			tables, err := options.DynamoDB.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.DynamoDB = tables
+		}
+		r := newRegion(regionOptions)
+		r.regions = c.regions
+		return r, nil
+	})
+	return c
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *CloudFormation {
+	return &CloudFormation{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		buckets:      options.S3,
+		streams:      options.Kinesis,
+		keys:         options.KMS,
+		repositories: options.ECR,
+		parameters:   options.SSM,
+		queues:       options.SQS,
+		tables:       options.DynamoDB,
+		clock:        clock.Now,
+		pollInterval: time.Second,
+		stacksById:   make(map[string]*Stack),
+	}
+}
@@ -0,0 +1,401 @@
+package cloudformation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/ecr"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/services/ssm"
+)
+
+type services struct {
+	s3       *s3.S3
+	kinesis  *kinesis.Kinesis
+	kms      *kms.KMS
+	ecr      *ecr.ECR
+	ssm      *ssm.SSM
+	sqs      *sqs.SQS
+	dynamodb *dynamodb.DynamoDB
+}
+
+func newCloudFormation(t *testing.T) (*CloudFormation, *services) {
+	generator := arn.Generator{
+		AwsAccountId: "123456789012",
+		Region:       "us-east-1",
+	}
+	s3Service, err := s3.New(s3.Options{PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kmsService, err := kms.New(kms.Options{ArnGenerator: generator, PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecrService, err := ecr.New(ecr.Options{ArnGenerator: generator, PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamoDBService, err := dynamodb.New(dynamodb.Options{ArnGenerator: generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &services{
+		s3: s3Service,
+		kinesis: kinesis.New(kinesis.Options{
+			ArnGenerator:         generator,
+			StreamCreateDuration: 10 * time.Millisecond,
+		}),
+		kms:      kmsService,
+		ecr:      ecrService,
+		ssm:      ssm.New(ssm.Options{ArnGenerator: generator, KMS: kmsService}),
+		sqs:      sqs.New(sqs.Options{ArnGenerator: generator, Addr: "localhost:4566"}),
+		dynamodb: dynamoDBService,
+	}
+	c := New(Options{
+		ArnGenerator: generator,
+		S3:           s.s3,
+		Kinesis:      s.kinesis,
+		KMS:          s.kms,
+		ECR:          s.ecr,
+		SSM:          s.ssm,
+		SQS:          s.sqs,
+		DynamoDB:     s.dynamodb,
+	})
+	c.clock = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	c.pollInterval = time.Millisecond
+	return c, s
+}
+
+// waitForStack waits for the stack's current operation to finish and returns its description.
+func waitForStack(t *testing.T, c *CloudFormation, stackId string) APIStack {
+	c.mu.Lock()
+	done := c.stacksById[stackId].done
+	c.mu.Unlock()
+	<-done
+
+	output, awserr := c.DescribeStacks(DescribeStacksInput{StackName: stackId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Stacks[0]
+}
+
+const stackTemplate = `
+Description: Test stack
+Parameters:
+  Env:
+    Type: String
+    Default: dev
+    AllowedValues: [dev, prod]
+  Secret:
+    Type: String
+    NoEcho: true
+    Default: hunter2
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${AWS::StackName}-${Env}-bucket"
+  DeadLetterQueue:
+    Type: AWS::SQS::Queue
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: !Join ["-", [!Ref "AWS::StackName", queue]]
+      VisibilityTimeout: 60
+      RedrivePolicy:
+        deadLetterTargetArn: !GetAtt DeadLetterQueue.Arn
+        maxReceiveCount: 3
+  Stream:
+    Type: AWS::Kinesis::Stream
+    Properties:
+      ShardCount: 2
+  ProdStream:
+    Type: AWS::Kinesis::Stream
+    Condition: IsProd
+  Key:
+    Type: AWS::KMS::Key
+    Properties:
+      Description: !Sub "Key for ${Bucket}"
+  Alias:
+    Type: AWS::KMS::Alias
+    Properties:
+      AliasName: alias/test-stack
+      TargetKeyId: !Ref Key
+  Table:
+    Type: AWS::DynamoDB::Table
+    DeletionPolicy: Retain
+    Properties:
+      TableName: test-table
+      BillingMode: PAY_PER_REQUEST
+      AttributeDefinitions:
+        - AttributeName: id
+          AttributeType: S
+      KeySchema:
+        - AttributeName: id
+          KeyType: HASH
+      StreamSpecification:
+        StreamViewType: NEW_IMAGE
+`
+
+func TestCreateAndDeleteStack(t *testing.T) {
+	c, s := newCloudFormation(t)
+
+	output, awserr := c.CreateStack(CreateStackInput{
+		StackName:    "test-stack",
+		TemplateBody: stackTemplate,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(output.StackId, "arn:aws:cloudformation:us-east-1:123456789012:stack/test-stack/") {
+		t.Errorf("unexpected stack ID %s", output.StackId)
+	}
+
+	stack := waitForStack(t, c, output.StackId)
+	if stack.StackStatus != "CREATE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	if stack.Description != "Test stack" {
+		t.Errorf("got description %q", stack.Description)
+	}
+	if len(stack.Parameters) != 2 || stack.Parameters[1].ParameterValue != "****" {
+		t.Errorf("got parameters %v", stack.Parameters)
+	}
+
+	_, awserr = c.CreateStack(CreateStackInput{StackName: "test-stack", TemplateBody: stackTemplate})
+	if awserr == nil || awserr.Body.Type != "AlreadyExistsException" {
+		t.Errorf("expected AlreadyExistsException, got %v", awserr)
+	}
+
+	resources, awserr := c.DescribeStackResources(DescribeStackResourcesInput{StackName: "test-stack"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	physicalIds := make(map[string]string)
+	for _, resource := range resources.StackResources {
+		if resource.ResourceStatus != "CREATE_COMPLETE" {
+			t.Errorf("%s has status %s", resource.LogicalResourceId, resource.ResourceStatus)
+		}
+		physicalIds[resource.LogicalResourceId] = resource.PhysicalResourceId
+	}
+	if _, ok := physicalIds["ProdStream"]; ok || len(physicalIds) != 7 {
+		t.Errorf("got resources %v", physicalIds)
+	}
+
+	if physicalIds["Bucket"] != "test-stack-dev-bucket" {
+		t.Errorf("got bucket %s", physicalIds["Bucket"])
+	}
+	if _, awserr := s.s3.HeadBucket(s3.HeadBucketInput{Bucket: "test-stack-dev-bucket"}); awserr != nil {
+		t.Error(awserr)
+	}
+
+	attributes, awserr := s.sqs.GetQueueAttributes(sqs.GetQueueAttributesInput{
+		AttributeNames: []string{"All"},
+		QueueUrl:       physicalIds["Queue"],
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if attributes.Attributes["VisibilityTimeout"] != "60" || !strings.Contains(attributes.Attributes["RedrivePolicy"], "test-stack-DeadLetterQueue-") {
+		t.Errorf("got queue attributes %v", attributes.Attributes)
+	}
+
+	summary, awserr := s.kinesis.DescribeStreamSummary(kinesis.DescribeStreamSummaryInput{StreamName: physicalIds["Stream"]})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if summary.StreamDescriptionSummary.OpenShardCount != 2 {
+		t.Errorf("got %d shards", summary.StreamDescriptionSummary.OpenShardCount)
+	}
+
+	key, awserr := s.kms.DescribeKey(kms.DescribeKeyInput{KeyId: "alias/test-stack"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if key.KeyMetadata.KeyId != physicalIds["Key"] || key.KeyMetadata.Description != "Key for test-stack-dev-bucket" {
+		t.Errorf("got key %+v", key.KeyMetadata)
+	}
+
+	table, awserr := s.dynamodb.DescribeTable(dynamodb.DescribeTableInput{TableName: "test-table"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if table.Table.LatestStreamArn == "" {
+		t.Error("expected the table to have a stream")
+	}
+
+	_, awserr = c.DeleteStack(DeleteStackInput{StackName: "test-stack"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	stack = waitForStack(t, c, output.StackId)
+	if stack.StackStatus != "DELETE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	if _, awserr := s.s3.HeadBucket(s3.HeadBucketInput{Bucket: "test-stack-dev-bucket"}); awserr == nil {
+		t.Error("expected the bucket to be deleted")
+	}
+	if _, awserr := s.sqs.GetQueueAttributes(sqs.GetQueueAttributesInput{QueueUrl: physicalIds["Queue"]}); awserr == nil {
+		t.Error("expected the queue to be deleted")
+	}
+	// The table is retained.
+	if _, awserr := s.dynamodb.DescribeTable(dynamodb.DescribeTableInput{TableName: "test-table"}); awserr != nil {
+		t.Error(awserr)
+	}
+
+	// Deleted stacks can only be described by their ID.
+	if _, awserr := c.DescribeStacks(DescribeStacksInput{StackName: "test-stack"}); awserr == nil {
+		t.Error("expected an error describing a deleted stack by name")
+	}
+	stacks, awserr := c.DescribeStacks(DescribeStacksInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(stacks.Stacks) != 0 {
+		t.Errorf("got stacks %v", stacks.Stacks)
+	}
+
+	events, awserr := c.DescribeStackEvents(DescribeStackEventsInput{StackName: output.StackId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	first, last := events.StackEvents[len(events.StackEvents)-1], events.StackEvents[0]
+	if first.ResourceStatus != "CREATE_IN_PROGRESS" || first.ResourceStatusReason != "User Initiated" {
+		t.Errorf("got first event %+v", first)
+	}
+	if last.ResourceStatus != "DELETE_COMPLETE" || last.LogicalResourceId != "test-stack" {
+		t.Errorf("got last event %+v", last)
+	}
+}
+
+func TestCreateStackRollback(t *testing.T) {
+	c, s := newCloudFormation(t)
+	if _, awserr := s.sqs.CreateQueue(sqs.CreateQueueInput{QueueName: "taken", Attributes: map[string]string{"DelaySeconds": "5"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	template := `{
+		"Resources": {
+			"Bucket": {"Type": "AWS::S3::Bucket", "Properties": {"BucketName": "rollback-bucket"}},
+			"Queue": {
+				"Type": "AWS::SQS::Queue",
+				"DependsOn": "Bucket",
+				"Properties": {"QueueName": "taken", "DelaySeconds": 10}
+			}
+		}
+	}`
+
+	for _, test := range []struct {
+		onFailure string
+		status    string
+		bucket    bool
+	}{
+		{"ROLLBACK", "ROLLBACK_COMPLETE", false},
+		{"DO_NOTHING", "CREATE_FAILED", true},
+	} {
+		t.Run(test.onFailure, func(t *testing.T) {
+			output, awserr := c.CreateStack(CreateStackInput{
+				StackName:    "rollback-" + strings.ToLower(strings.ReplaceAll(test.onFailure, "_", "-")),
+				TemplateBody: template,
+				OnFailure:    test.onFailure,
+			})
+			if awserr != nil {
+				t.Fatal(awserr)
+			}
+			stack := waitForStack(t, c, output.StackId)
+			if stack.StackStatus != test.status {
+				t.Errorf("got status %s, want %s", stack.StackStatus, test.status)
+			}
+			if !strings.Contains(stack.StackStatusReason, "The following resource(s) failed to create: [Queue]") {
+				t.Errorf("got reason %q", stack.StackStatusReason)
+			}
+			_, awserr = s.s3.HeadBucket(s3.HeadBucketInput{Bucket: "rollback-bucket"})
+			if (awserr == nil) != test.bucket {
+				t.Errorf("bucket exists: %v, want %v", awserr == nil, test.bucket)
+			}
+		})
+	}
+}
+
+func TestCreateStackValidation(t *testing.T) {
+	c, _ := newCloudFormation(t)
+	for _, test := range []struct {
+		input CreateStackInput
+		err   string
+	}{
+		{CreateStackInput{StackName: "1stack", TemplateBody: `{"Resources": {"Q": {"Type": "AWS::SQS::Queue"}}}`}, "failed to satisfy constraint"},
+		{CreateStackInput{StackName: "stack"}, "Either Template URL or Template Body must be specified."},
+		{CreateStackInput{StackName: "stack", TemplateBody: `{"Resources": {"Q": {"Type": "AWS::SNS::Topic"}}}`}, "Unrecognized resource types: [AWS::SNS::Topic]"},
+		{CreateStackInput{StackName: "stack", TemplateBody: `{"Parameters": {"P": {"Type": "String"}}, "Resources": {"Q": {"Type": "AWS::SQS::Queue"}}}`}, "Parameters: [P] must have values"},
+	} {
+		_, awserr := c.CreateStack(test.input)
+		if awserr == nil || awserr.Body.Type != "ValidationError" || !strings.Contains(awserr.Body.Message, test.err) {
+			t.Errorf("%+v: got %v, want %q", test.input, awserr, test.err)
+		}
+	}
+}
+
+func TestRegions(t *testing.T) {
+	c, s := newCloudFormation(t)
+	if _, err := c.regions.Get("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected an unknown region to fail")
+	}
+	europe, err := c.regions.Get("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	europe.pollInterval = time.Millisecond
+
+	output, awserr := europe.CreateStack(CreateStackInput{
+		StackName: "regional-stack",
+		TemplateBody: `
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: regional-bucket
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: regional-queue
+`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(output.StackId, "arn:aws:cloudformation:eu-west-1:123456789012:stack/regional-stack/") {
+		t.Errorf("unexpected stack ID %s", output.StackId)
+	}
+	if stack := waitForStack(t, europe, output.StackId); stack.StackStatus != "CREATE_COMPLETE" {
+		t.Fatalf("got status %s (%s)", stack.StackStatus, stack.StackStatusReason)
+	}
+	if _, awserr := c.DescribeStacks(DescribeStacksInput{StackName: "regional-stack"}); awserr == nil {
+		t.Error("Expected another region's stack to be hidden")
+	}
+
+	// The queue is in the stack's region, but the bucket is global.
+	europeQueues, err := s.sqs.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europeQueues.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "regional-queue"}); awserr != nil {
+		t.Error(awserr)
+	}
+	if _, awserr := s.sqs.GetQueueUrl(sqs.GetQueueUrlInput{QueueName: "regional-queue"}); awserr == nil {
+		t.Error("Expected the queue to be hidden from other regions")
+	}
+	if _, awserr := s.s3.HeadBucket(s3.HeadBucketInput{Bucket: "regional-bucket"}); awserr != nil {
+		t.Error(awserr)
+	}
+}
@@ -0,0 +1,479 @@
+package cloudformation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/s3"
+)
+
+// deployment is a template and the parameters a stack is, or will be, created or updated with.
+type deployment struct {
+	template     *template
+	templateBody string
+	// The parameters as they were given, so they can be reused with UsePreviousValue.
+	inputs []APIParameter
+	// The parameters as they're described, with NoEcho values masked.
+	parameters []APIParameter
+	evaluator  *evaluator
+}
+
+// templateSource is where a deployment's template comes from.
+type templateSource struct {
+	body        string
+	url         string
+	usePrevious bool
+}
+
+// stackIdentity is the stack a deployment is for, which may not exist yet.
+type stackIdentity struct {
+	id               string
+	name             string
+	notificationARNs []string
+}
+
+// newDeployment loads the template and evaluates its parameters and conditions. It must be called
+// without the lock held, since the template may be read from S3. previous is the stack's current
+// deployment, if any, which is used for UsePreviousTemplate and UsePreviousValue.
+func (c *CloudFormation) newDeployment(stack stackIdentity, source templateSource, inputs []APIParameter, previous *deployment) (*deployment, *awserrors.Error) {
+	body := source.body
+	switch {
+	case source.usePrevious:
+		if previous == nil {
+			return nil, ValidationError("UsePreviousTemplate can only be used with an existing stack")
+		}
+		body = previous.templateBody
+	case body == "" && source.url != "":
+		var awserr *awserrors.Error
+		body, awserr = c.fetchTemplate(source.url)
+		if awserr != nil {
+			return nil, awserr
+		}
+	case body == "":
+		return nil, ValidationError("Either Template URL or Template Body must be specified.")
+	}
+
+	t, err := parseTemplate(body)
+	if err != nil {
+		return nil, ValidationError(err.Error())
+	}
+
+	given := make([]APIParameter, 0, len(inputs))
+	for _, input := range inputs {
+		if input.UsePreviousValue {
+			var found bool
+			if previous != nil {
+				for _, p := range previous.inputs {
+					if p.ParameterKey == input.ParameterKey {
+						input.ParameterValue = p.ParameterValue
+						found = true
+					}
+				}
+			}
+			if !found {
+				return nil, ValidationError(fmt.Sprintf("Invalid input for parameter key %s. Cannot specify usePreviousValue as true for a parameter key not in the previous template", input.ParameterKey))
+			}
+			input.UsePreviousValue = false
+		}
+		given = append(given, input)
+	}
+	parameters, err := resolveParameters(t, given)
+	if err != nil {
+		return nil, ValidationError(err.Error())
+	}
+
+	var notificationARNs []any
+	for _, notificationArn := range stack.notificationARNs {
+		notificationARNs = append(notificationARNs, notificationArn)
+	}
+	parameters["AWS::AccountId"] = c.arnGenerator.AwsAccountId
+	parameters["AWS::NotificationARNs"] = notificationARNs
+	parameters["AWS::Partition"] = "aws"
+	parameters["AWS::Region"] = c.arnGenerator.Region
+	parameters["AWS::StackId"] = stack.id
+	parameters["AWS::StackName"] = stack.name
+	parameters["AWS::URLSuffix"] = "amazonaws.com"
+
+	d := &deployment{
+		template:     t,
+		templateBody: body,
+		inputs:       given,
+		evaluator: &evaluator{
+			template:   t,
+			parameters: parameters,
+			conditions: make(map[string]bool),
+			imports:    make(map[string]bool),
+		},
+	}
+
+	// Conditions only depend on parameters, so they're evaluated up front to report errors.
+	conditionNames := make([]string, 0, len(t.Conditions))
+	for name := range t.Conditions {
+		conditionNames = append(conditionNames, name)
+	}
+	slices.Sort(conditionNames)
+	for _, name := range conditionNames {
+		if _, err := d.evaluator.condition(name); err != nil {
+			return nil, ValidationError(err.Error())
+		}
+	}
+	for _, name := range t.order {
+		if condition := t.Resources[name].Condition; condition != "" {
+			if _, ok := t.Conditions[condition]; !ok {
+				return nil, ValidationError(fmt.Sprintf("Template format error: Unresolved dependencies [%s]. Cannot reference resources in the Conditions block of the template", condition))
+			}
+		}
+	}
+
+	// NoEcho parameters are masked in descriptions.
+	parameterNames := make([]string, 0, len(t.Parameters))
+	for name := range t.Parameters {
+		parameterNames = append(parameterNames, name)
+	}
+	slices.Sort(parameterNames)
+	for _, name := range parameterNames {
+		value := formatScalar(parameters[name])
+		if list, ok := parameters[name].([]any); ok {
+			parts := make([]string, len(list))
+			for i, item := range list {
+				parts[i] = formatScalar(item)
+			}
+			value = strings.Join(parts, ",")
+		}
+		if t.Parameters[name].NoEcho {
+			value = "****"
+		}
+		d.parameters = append(d.parameters, APIParameter{ParameterKey: name, ParameterValue: value})
+	}
+	return d, nil
+}
+
+// fetchTemplate reads a template from the local S3 service. URLs can be path style, like
+// https://s3.us-east-1.amazonaws.com/bucket/key or the server's own address, or virtual hosted
+// style, like https://bucket.s3.us-east-1.amazonaws.com/key.
+func (c *CloudFormation) fetchTemplate(templateURL string) (string, *awserrors.Error) {
+	if c.buckets == nil {
+		return "", ValidationError("TemplateURL requires the S3 service to be enabled")
+	}
+	u, err := url.Parse(templateURL)
+	if err != nil || u.Host == "" {
+		return "", ValidationError("TemplateURL must be a supported URL.")
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	var bucket, key string
+	if prefix, _, found := strings.Cut(u.Hostname(), ".s3"); found && prefix != "" && strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		bucket, key = prefix, path
+	} else {
+		bucket, key, _ = strings.Cut(path, "/")
+	}
+	if bucket == "" || key == "" {
+		return "", ValidationError("TemplateURL must be a supported URL.")
+	}
+
+	output, awserr := c.buckets.GetObject(s3.GetObjectInput{Bucket: bucket, Key: key})
+	if awserr != nil {
+		return "", ValidationError(fmt.Sprintf("S3 error: Unable to get object %s from bucket %s", key, bucket))
+	}
+	body, err := io.ReadAll(output.Body)
+	if err != nil {
+		return "", ValidationError(fmt.Sprintf("S3 error: %v", err))
+	}
+	return string(body), nil
+}
+
+// activeResources returns the logical IDs of the deployment's resources whose conditions are true.
+func activeResources(d *deployment) []string {
+	var active []string
+	for _, logicalId := range d.template.order {
+		definition := d.template.Resources[logicalId]
+		if definition.Condition == "" || d.evaluator.conditions[definition.Condition] {
+			active = append(active, logicalId)
+		}
+	}
+	return active
+}
+
+// lockedChanges returns the changes a deployment would make to the stack. Changed resources are
+// replaced.
+func (c *CloudFormation) lockedChanges(stack *Stack, d *deployment) []APIChange {
+	var changes []APIChange
+	active := activeResources(d)
+	for _, logicalId := range active {
+		definition := d.template.Resources[logicalId]
+		change := APIResourceChange{
+			LogicalResourceId: logicalId,
+			ResourceType:      definition.Type,
+		}
+		existing, ok := stack.Resources[logicalId]
+		if !ok {
+			change.Action = "Add"
+		} else {
+			change.PhysicalResourceId = existing.PhysicalId
+			properties, err := d.evaluator.resolve(definition.Properties)
+			switch {
+			case err != nil:
+				// Properties which refer to resources that will be replaced can't be resolved yet.
+				change.Action = "Modify"
+				change.Replacement = "Conditional"
+			case existing.Type != definition.Type || existing.properties != encodeProperties(properties):
+				change.Action = "Modify"
+				change.Replacement = "True"
+			default:
+				continue
+			}
+		}
+		changes = append(changes, APIChange{Type: "Resource", ResourceChange: change})
+	}
+	for _, logicalId := range stack.resourceOrder {
+		if !slices.Contains(active, logicalId) {
+			resource := stack.Resources[logicalId]
+			changes = append(changes, APIChange{Type: "Resource", ResourceChange: APIResourceChange{
+				Action:             "Remove",
+				LogicalResourceId:  logicalId,
+				PhysicalResourceId: resource.PhysicalId,
+				ResourceType:       resource.Type,
+			}})
+		}
+	}
+	return changes
+}
+
+func encodeProperties(properties any) string {
+	encoded, _ := json.Marshal(properties)
+	return string(encoded)
+}
+
+// lockedAddResource adds a resource to the stack, after the resources it already has.
+func (c *CloudFormation) lockedAddResource(stack *Stack, resource *StackResource) {
+	stack.Resources[resource.LogicalId] = resource
+	stack.resourceOrder = append(stack.resourceOrder, resource.LogicalId)
+}
+
+func (c *CloudFormation) removeResource(stack *Stack, logicalId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(stack.Resources, logicalId)
+	stack.resourceOrder = slices.DeleteFunc(stack.resourceOrder, func(id string) bool {
+		return id == logicalId
+	})
+}
+
+// deploy creates or updates the stack's resources to match the deployment.
+// Resources whose properties have changed are replaced: the old one is deleted before the new one
+// is created, so that it can have the same name.
+func (c *CloudFormation) deploy(stack *Stack, d *deployment, update bool) {
+	c.mu.Lock()
+	d.evaluator.resources = stack.Resources
+	d.evaluator.exports = c.lockedExports(stack)
+	if !update {
+		stack.deployment = d
+	}
+	c.mu.Unlock()
+
+	operation := "CREATE"
+	if update {
+		operation = "UPDATE"
+	}
+
+	var added []string
+	var failed []string
+	var failure string
+	active := activeResources(d)
+	for _, logicalId := range active {
+		definition := d.template.Resources[logicalId]
+		properties, err := d.evaluator.resolve(definition.Properties)
+		encoded := ""
+		if err == nil {
+			encoded = encodeProperties(properties)
+		}
+
+		c.mu.Lock()
+		resource, exists := stack.Resources[logicalId]
+		if exists && err == nil && resource.Type == definition.Type && resource.properties == encoded && resource.PhysicalId != "" {
+			resource.DeletionPolicy = definition.DeletionPolicy
+			c.mu.Unlock()
+			continue
+		}
+		action := "CREATE"
+		reason := ""
+		if exists {
+			action = "UPDATE"
+			reason = "Requested update requires the creation of a new physical resource; hence creating one."
+		} else {
+			resource = &StackResource{LogicalId: logicalId, Type: definition.Type}
+			c.lockedAddResource(stack, resource)
+			added = append(added, logicalId)
+		}
+		resource.DeletionPolicy = definition.DeletionPolicy
+		c.mu.Unlock()
+		c.setResourceStatus(stack, resource, action+"_IN_PROGRESS", reason, encoded)
+
+		if err == nil && exists && resource.PhysicalId != "" {
+			if err = resourceProviders[resource.Type].delete(c, resource); err == nil {
+				c.mu.Lock()
+				resource.PhysicalId = ""
+				resource.attributes = nil
+				c.mu.Unlock()
+			}
+		}
+		if err == nil {
+			properties, _ := properties.(map[string]any)
+			var physicalId string
+			var attributes map[string]string
+			physicalId, attributes, err = resourceProviders[definition.Type].create(c, &resourceRequest{
+				stackName:  stack.Name,
+				logicalId:  logicalId,
+				properties: properties,
+			})
+			if err == nil {
+				c.mu.Lock()
+				resource.Type = definition.Type
+				resource.PhysicalId = physicalId
+				resource.attributes = attributes
+				resource.properties = encoded
+				c.mu.Unlock()
+			}
+		}
+		if err != nil {
+			c.logger.Error("Deploying stack resource", "stack", stack.Name, "resource", logicalId, "err", err)
+			c.setResourceStatus(stack, resource, action+"_FAILED", err.Error(), "")
+			failed = append(failed, logicalId)
+			failure = fmt.Sprintf("The following resource(s) failed to %s: [%s]. ", strings.ToLower(operation), logicalId)
+			break
+		}
+		c.setResourceStatus(stack, resource, action+"_COMPLETE", "", "")
+	}
+
+	var outputs []APIOutput
+	if failure == "" {
+		var err error
+		if outputs, err = c.evaluateOutputs(stack, d); err != nil {
+			failure = err.Error() + " "
+		}
+	}
+
+	if failure == "" {
+		if update {
+			c.setStackStatus(stack, "UPDATE_COMPLETE_CLEANUP_IN_PROGRESS", "")
+			var removed []string
+			c.mu.Lock()
+			for _, logicalId := range stack.resourceOrder {
+				if !slices.Contains(active, logicalId) {
+					removed = append(removed, logicalId)
+				}
+			}
+			c.mu.Unlock()
+			slices.Reverse(removed)
+			// Resources which fail to delete during cleanup don't fail the update.
+			c.deleteStackResources(stack, removed, nil)
+			for _, logicalId := range removed {
+				c.removeResource(stack, logicalId)
+			}
+		}
+		c.mu.Lock()
+		stack.deployment = d
+		stack.Description = d.template.Description
+		stack.outputs = outputs
+		stack.imports = d.evaluator.imports
+		if update {
+			stack.LastUpdatedTime = c.clock()
+		}
+		c.mu.Unlock()
+		c.setStackStatus(stack, operation+"_COMPLETE", "")
+		return
+	}
+
+	if update {
+		if stack.DisableRollback {
+			c.setStackStatus(stack, "UPDATE_FAILED", failure)
+			return
+		}
+		// Rolling back deletes the resources the update added. Replaced resources stay replaced.
+		c.setStackStatus(stack, "UPDATE_ROLLBACK_IN_PROGRESS", failure)
+		slices.Reverse(added)
+		c.deleteStackResources(stack, added, nil)
+		for _, logicalId := range added {
+			c.removeResource(stack, logicalId)
+		}
+		c.setStackStatus(stack, "UPDATE_ROLLBACK_COMPLETE", "")
+		return
+	}
+
+	switch {
+	case stack.DisableRollback || stack.OnFailure == "DO_NOTHING":
+		c.setStackStatus(stack, "CREATE_FAILED", failure)
+	case stack.OnFailure == "DELETE":
+		c.setStackStatus(stack, "DELETE_IN_PROGRESS", failure+"Delete requested by user.")
+		c.deleteStack(stack, nil)
+	default:
+		reason := failure + "Rollback requested by user."
+		c.setStackStatus(stack, "ROLLBACK_IN_PROGRESS", reason)
+		if failed := c.deleteStackResources(stack, c.reverseResourceOrder(stack), nil); len(failed) > 0 {
+			c.setStackStatus(stack, "ROLLBACK_FAILED", fmt.Sprintf("The following resource(s) failed to delete: [%s]. ", strings.Join(failed, ", ")))
+			return
+		}
+		// The stack keeps the reason it was rolled back.
+		c.setStackStatus(stack, "ROLLBACK_COMPLETE", reason)
+	}
+}
+
+func (c *CloudFormation) reverseResourceOrder(stack *Stack) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	order := slices.Clone(stack.resourceOrder)
+	slices.Reverse(order)
+	return order
+}
+
+// deleteStackResources deletes the given resources in order, and returns the logical IDs of those
+// which failed to delete.
+func (c *CloudFormation) deleteStackResources(stack *Stack, logicalIds []string, retain []string) []string {
+	var failed []string
+	for _, logicalId := range logicalIds {
+		c.mu.Lock()
+		resource, ok := stack.Resources[logicalId]
+		c.mu.Unlock()
+		if !ok || resource.Status == "DELETE_COMPLETE" || resource.Status == "DELETE_SKIPPED" {
+			continue
+		}
+		// Resources which failed to create have nothing to delete.
+		if resource.PhysicalId == "" {
+			c.setResourceStatus(stack, resource, "DELETE_COMPLETE", "", "")
+			continue
+		}
+		if resource.DeletionPolicy == "Retain" || slices.Contains(retain, resource.LogicalId) {
+			c.setResourceStatus(stack, resource, "DELETE_SKIPPED", "", "")
+			continue
+		}
+
+		c.setResourceStatus(stack, resource, "DELETE_IN_PROGRESS", "", "")
+		if err := resourceProviders[resource.Type].delete(c, resource); err != nil {
+			c.logger.Error("Deleting stack resource", "stack", stack.Name, "resource", resource.LogicalId, "err", err)
+			c.setResourceStatus(stack, resource, "DELETE_FAILED", err.Error(), "")
+			failed = append(failed, resource.LogicalId)
+			continue
+		}
+		c.setResourceStatus(stack, resource, "DELETE_COMPLETE", "", "")
+	}
+	return failed
+}
+
+// deleteStack deletes the stack's resources, except those which are retained, and then the stack.
+func (c *CloudFormation) deleteStack(stack *Stack, retain []string) {
+	if failed := c.deleteStackResources(stack, c.reverseResourceOrder(stack), retain); len(failed) > 0 {
+		c.setStackStatus(stack, "DELETE_FAILED", fmt.Sprintf("The following resource(s) failed to delete: [%s]. ", strings.Join(failed, ", ")))
+		return
+	}
+	c.mu.Lock()
+	stack.outputs = nil
+	stack.imports = nil
+	c.mu.Unlock()
+	c.setStackStatus(stack, "DELETE_COMPLETE", "")
+}
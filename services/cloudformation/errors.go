@@ -0,0 +1,19 @@
+package cloudformation
+
+import "aws-in-a-box/awserrors"
+
+func ValidationError(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationError", message)
+}
+
+func AlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("AlreadyExistsException", message)
+}
+
+func ChangeSetNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ChangeSetNotFoundException", message)
+}
+
+func InvalidChangeSetStatusException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidChangeSetStatusException", message)
+}
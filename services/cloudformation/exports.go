@@ -0,0 +1,151 @@
+package cloudformation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+// lockedExports returns the values of the exports of every stack except the given one, by name.
+func (c *CloudFormation) lockedExports(except *Stack) map[string]string {
+	exports := make(map[string]string)
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		if stack == except {
+			continue
+		}
+		for _, output := range stack.outputs {
+			if output.ExportName != "" {
+				exports[output.ExportName] = output.OutputValue
+			}
+		}
+	}
+	return exports
+}
+
+// lockedImporters returns the names of the stacks, other than the given one, which import an export.
+func (c *CloudFormation) lockedImporters(exportName string, except *Stack) []string {
+	var importers []string
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		if stack != except && stack.imports[exportName] {
+			importers = append(importers, stack.Name)
+		}
+	}
+	return importers
+}
+
+// evaluateOutputs resolves the deployment's outputs once its resources exist, and checks that its
+// exports don't conflict with other stacks' and that exports it removes aren't still imported.
+func (c *CloudFormation) evaluateOutputs(stack *Stack, d *deployment) ([]APIOutput, error) {
+	keys := make([]string, 0, len(d.template.Outputs))
+	for key := range d.template.Outputs {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var outputs []APIOutput
+	for _, key := range keys {
+		definition := d.template.Outputs[key]
+		if definition.Condition != "" {
+			active, err := d.evaluator.condition(definition.Condition)
+			if err != nil {
+				return nil, err
+			}
+			if !active {
+				continue
+			}
+		}
+		value, err := d.evaluator.resolve(definition.Value)
+		if err != nil {
+			return nil, err
+		}
+		output := APIOutput{
+			Description: definition.Description,
+			OutputKey:   key,
+			OutputValue: formatScalar(value),
+		}
+		if list, ok := value.([]any); ok {
+			parts := make([]string, len(list))
+			for i, item := range list {
+				parts[i] = formatScalar(item)
+			}
+			output.OutputValue = strings.Join(parts, ",")
+		}
+		if definition.Export != nil {
+			if output.ExportName, err = d.evaluator.resolveString(definition.Export.Name, "Export"); err != nil {
+				return nil, err
+			}
+		}
+		outputs = append(outputs, output)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, output := range outputs {
+		if output.ExportName == "" {
+			continue
+		}
+		for _, id := range c.stackIds {
+			other := c.stacksById[id]
+			if other == stack {
+				continue
+			}
+			for _, existing := range other.outputs {
+				if existing.ExportName == output.ExportName {
+					return nil, fmt.Errorf("Export with name %s is already exported by stack %s", output.ExportName, other.Name)
+				}
+			}
+		}
+	}
+	for _, existing := range stack.outputs {
+		if existing.ExportName == "" {
+			continue
+		}
+		kept := slices.ContainsFunc(outputs, func(output APIOutput) bool {
+			return output.ExportName == existing.ExportName && output.OutputValue == existing.OutputValue
+		})
+		if importers := c.lockedImporters(existing.ExportName, stack); !kept && len(importers) > 0 {
+			return nil, fmt.Errorf("Cannot update export %s as it is in use by %s", existing.ExportName, importers[0])
+		}
+	}
+	return outputs, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_ListExports.html
+func (c *CloudFormation) ListExports(input ListExportsInput) (*ListExportsOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &ListExportsOutput{}
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		for _, stackOutput := range stack.outputs {
+			if stackOutput.ExportName != "" {
+				output.Exports = append(output.Exports, APIExport{
+					ExportingStackId: stack.Id,
+					Name:             stackOutput.ExportName,
+					Value:            stackOutput.OutputValue,
+				})
+			}
+		}
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_ListImports.html
+func (c *CloudFormation) ListImports(input ListImportsInput) (*ListImportsOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	importers := c.lockedImporters(input.ExportName, nil)
+	if len(importers) == 0 {
+		return nil, ValidationError(fmt.Sprintf("Export '%s' is not imported by any stack.", input.ExportName))
+	}
+	return &ListImportsOutput{
+		Imports: importers,
+	}, nil
+}
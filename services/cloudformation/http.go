@@ -0,0 +1,38 @@
+package cloudformation
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/query"
+	"aws-in-a-box/region"
+)
+
+// CloudFormation only supports the Query protocol.
+var queryProtocol = query.Protocol{
+	Version:      "2010-05-15",
+	XMLNamespace: "http://cloudformation.amazonaws.com/doc/2010-05-15/",
+}
+
+func NewHandler(logger *slog.Logger, c *CloudFormation, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := query.NewRegistry(queryProtocol)
+	query.RegisterWithRequest(logger, registry, "CreateChangeSet", region.Method(c.regions, (*CloudFormation).CreateChangeSet))
+	query.RegisterWithRequest(logger, registry, "CreateStack", region.Method(c.regions, (*CloudFormation).CreateStack))
+	query.RegisterWithRequest(logger, registry, "DeleteChangeSet", region.Method(c.regions, (*CloudFormation).DeleteChangeSet))
+	query.RegisterWithRequest(logger, registry, "DeleteStack", region.Method(c.regions, (*CloudFormation).DeleteStack))
+	query.RegisterWithRequest(logger, registry, "DescribeChangeSet", region.Method(c.regions, (*CloudFormation).DescribeChangeSet))
+	query.RegisterWithRequest(logger, registry, "DescribeStackEvents", region.Method(c.regions, (*CloudFormation).DescribeStackEvents))
+	query.RegisterWithRequest(logger, registry, "DescribeStackResources", region.Method(c.regions, (*CloudFormation).DescribeStackResources))
+	query.RegisterWithRequest(logger, registry, "DescribeStacks", region.Method(c.regions, (*CloudFormation).DescribeStacks))
+	query.RegisterWithRequest(logger, registry, "ExecuteChangeSet", region.Method(c.regions, (*CloudFormation).ExecuteChangeSet))
+	query.RegisterWithRequest(logger, registry, "GetTemplate", region.Method(c.regions, (*CloudFormation).GetTemplate))
+	query.RegisterWithRequest(logger, registry, "ListChangeSets", region.Method(c.regions, (*CloudFormation).ListChangeSets))
+	query.RegisterWithRequest(logger, registry, "ListExports", region.Method(c.regions, (*CloudFormation).ListExports))
+	query.RegisterWithRequest(logger, registry, "ListImports", region.Method(c.regions, (*CloudFormation).ListImports))
+	query.RegisterWithRequest(logger, registry, "ListStackResources", region.Method(c.regions, (*CloudFormation).ListStackResources))
+	query.RegisterWithRequest(logger, registry, "ListStacks", region.Method(c.regions, (*CloudFormation).ListStacks))
+	query.RegisterWithRequest(logger, registry, "UpdateStack", region.Method(c.regions, (*CloudFormation).UpdateStack))
+	capabilityRegistry.Add("cloudformation", registry.Operations()...)
+	return query.NewHandler(registry)
+}
@@ -0,0 +1,482 @@
+package cloudformation
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/intrinsic-function-reference.html
+
+// noValue is the value of Ref AWS::NoValue, which removes the property it's assigned to.
+type noValue struct{}
+
+type evaluator struct {
+	template *template
+	// Parameter and pseudo parameter values, which are strings or lists of strings.
+	parameters map[string]any
+	// The stack's created resources, keyed by logical ID.
+	resources map[string]*StackResource
+	// Evaluated conditions.
+	conditions map[string]bool
+	// The values exported by other stacks, keyed by their export names, and the names of those
+	// which have been imported.
+	exports map[string]string
+	imports map[string]bool
+}
+
+func (e *evaluator) resolve(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			for name, argument := range v {
+				if name == "Ref" || name == "Condition" || strings.HasPrefix(name, "Fn::") {
+					return e.intrinsic(name, argument)
+				}
+			}
+		}
+		resolved := make(map[string]any, len(v))
+		for key, item := range v {
+			r, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := r.(noValue); !ok {
+				resolved[key] = r
+			}
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, 0, len(v))
+		for _, item := range v {
+			r, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := r.(noValue); !ok {
+				resolved = append(resolved, r)
+			}
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
+
+// resolveString resolves a value which must be a string, number or boolean.
+func (e *evaluator) resolveString(value any, function string) (string, error) {
+	resolved, err := e.resolve(value)
+	if err != nil {
+		return "", err
+	}
+	switch resolved.(type) {
+	case string, float64, bool:
+		return formatScalar(resolved), nil
+	}
+	return "", fmt.Errorf("Template error: %s requires a string, not %v", function, resolved)
+}
+
+func (e *evaluator) resolveList(value any, function string) ([]any, error) {
+	resolved, err := e.resolve(value)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := resolved.([]any)
+	if !ok {
+		return nil, fmt.Errorf("Template error: %s requires a list, not %v", function, resolved)
+	}
+	return list, nil
+}
+
+// arguments returns a function's list of arguments, which must have count items.
+func arguments(function string, argument any, count int) ([]any, error) {
+	list, ok := argument.([]any)
+	if !ok || len(list) != count {
+		return nil, fmt.Errorf("Template error: %s requires a list of %d arguments", function, count)
+	}
+	return list, nil
+}
+
+func (e *evaluator) intrinsic(function string, argument any) (any, error) {
+	switch function {
+	case "Ref":
+		name, ok := argument.(string)
+		if !ok {
+			return nil, errors.New("Template error: Ref requires a string")
+		}
+		return e.ref(name)
+
+	case "Fn::GetAtt":
+		var parts []any
+		switch a := argument.(type) {
+		case string:
+			resource, attribute, _ := strings.Cut(a, ".")
+			parts = []any{resource, attribute}
+		case []any:
+			parts = a
+		}
+		if len(parts) != 2 {
+			return nil, errors.New("Template error: Fn::GetAtt requires a resource and an attribute")
+		}
+		resource, ok := parts[0].(string)
+		if !ok {
+			return nil, errors.New("Template error: Fn::GetAtt requires a resource name")
+		}
+		attribute, err := e.resolveString(parts[1], function)
+		if err != nil {
+			return nil, err
+		}
+		return e.getAtt(resource, attribute)
+
+	case "Fn::Join":
+		args, err := arguments(function, argument, 2)
+		if err != nil {
+			return nil, err
+		}
+		delimiter, err := e.resolveString(args[0], function)
+		if err != nil {
+			return nil, err
+		}
+		list, err := e.resolveList(args[1], function)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(list))
+		for i, item := range list {
+			if parts[i], err = e.resolveString(item, function); err != nil {
+				return nil, err
+			}
+		}
+		return strings.Join(parts, delimiter), nil
+
+	case "Fn::Sub":
+		return e.sub(argument)
+
+	case "Fn::Select":
+		args, err := arguments(function, argument, 2)
+		if err != nil {
+			return nil, err
+		}
+		indexString, err := e.resolveString(args[0], function)
+		if err != nil {
+			return nil, err
+		}
+		index, err := strconv.Atoi(indexString)
+		if err != nil {
+			return nil, fmt.Errorf("Template error: Fn::Select index %q must be an integer", indexString)
+		}
+		list, err := e.resolveList(args[1], function)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(list) {
+			return nil, fmt.Errorf("Template error: Fn::Select cannot select nonexistent value at index %d", index)
+		}
+		return list[index], nil
+
+	case "Fn::Split":
+		args, err := arguments(function, argument, 2)
+		if err != nil {
+			return nil, err
+		}
+		delimiter, err := e.resolveString(args[0], function)
+		if err != nil {
+			return nil, err
+		}
+		s, err := e.resolveString(args[1], function)
+		if err != nil {
+			return nil, err
+		}
+		var list []any
+		for _, part := range strings.Split(s, delimiter) {
+			list = append(list, part)
+		}
+		return list, nil
+
+	case "Fn::If":
+		args, err := arguments(function, argument, 3)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := args[0].(string)
+		if !ok {
+			return nil, errors.New("Template error: Fn::If requires a condition name")
+		}
+		condition, err := e.condition(name)
+		if err != nil {
+			return nil, err
+		}
+		if condition {
+			return e.resolve(args[1])
+		}
+		return e.resolve(args[2])
+
+	case "Condition":
+		name, ok := argument.(string)
+		if !ok {
+			return nil, errors.New("Template error: Condition requires a condition name")
+		}
+		return e.condition(name)
+
+	case "Fn::Equals":
+		args, err := arguments(function, argument, 2)
+		if err != nil {
+			return nil, err
+		}
+		a, err := e.resolveString(args[0], function)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.resolveString(args[1], function)
+		if err != nil {
+			return nil, err
+		}
+		return a == b, nil
+
+	case "Fn::And", "Fn::Or", "Fn::Not":
+		list, ok := argument.([]any)
+		if !ok || len(list) == 0 || (function == "Fn::Not" && len(list) != 1) {
+			return nil, fmt.Errorf("Template error: %s requires a list of conditions", function)
+		}
+		values := make([]bool, len(list))
+		for i, item := range list {
+			resolved, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			b, ok := resolved.(bool)
+			if !ok {
+				return nil, fmt.Errorf("Template error: %s requires conditions, not %v", function, resolved)
+			}
+			values[i] = b
+		}
+		switch function {
+		case "Fn::And":
+			return !slices.Contains(values, false), nil
+		case "Fn::Or":
+			return slices.Contains(values, true), nil
+		}
+		return !values[0], nil
+
+	case "Fn::Base64":
+		s, err := e.resolveString(argument, function)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(s)), nil
+
+	case "Fn::GetAZs":
+		region, err := e.resolveString(argument, function)
+		if err != nil {
+			return nil, err
+		}
+		if region == "" {
+			region = e.parameters["AWS::Region"].(string)
+		}
+		return []any{region + "a", region + "b", region + "c"}, nil
+
+	case "Fn::FindInMap":
+		args, err := arguments(function, argument, 3)
+		if err != nil {
+			return nil, err
+		}
+		var keys [3]string
+		for i, arg := range args {
+			if keys[i], err = e.resolveString(arg, function); err != nil {
+				return nil, err
+			}
+		}
+		mapping, _ := e.template.Mappings[keys[0]].(map[string]any)
+		top, _ := mapping[keys[1]].(map[string]any)
+		value, ok := top[keys[2]]
+		if !ok {
+			return nil, fmt.Errorf("Template error: Unable to get mapping for %s::%s::%s", keys[0], keys[1], keys[2])
+		}
+		return value, nil
+
+	case "Fn::ImportValue":
+		name, err := e.resolveString(argument, function)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := e.exports[name]
+		if !ok {
+			return nil, fmt.Errorf("No export named %s found.", name)
+		}
+		e.imports[name] = true
+		return value, nil
+	}
+	return nil, fmt.Errorf("Template error: %s is not supported", function)
+}
+
+func (e *evaluator) ref(name string) (any, error) {
+	if name == "AWS::NoValue" {
+		return noValue{}, nil
+	}
+	if value, ok := e.parameters[name]; ok {
+		return value, nil
+	}
+	if resource, ok := e.resources[name]; ok && resource.PhysicalId != "" {
+		return resource.PhysicalId, nil
+	}
+	return nil, fmt.Errorf("Template format error: Unresolved resource dependencies [%s] in the Resources block of the template", name)
+}
+
+func (e *evaluator) getAtt(name string, attribute string) (any, error) {
+	resource, ok := e.resources[name]
+	if !ok || resource.PhysicalId == "" {
+		return nil, fmt.Errorf("Template format error: Unresolved resource dependencies [%s] in the Resources block of the template", name)
+	}
+	value, ok := resource.attributes[attribute]
+	if !ok {
+		return nil, fmt.Errorf("Template error: resource %s does not support attribute type %s in Fn::GetAtt", name, attribute)
+	}
+	return value, nil
+}
+
+var subRegex = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+func (e *evaluator) sub(argument any) (any, error) {
+	template := argument
+	variables := map[string]any{}
+	if list, ok := argument.([]any); ok {
+		if len(list) != 2 {
+			return nil, errors.New("Template error: Fn::Sub requires a string and a map of variables")
+		}
+		template = list[0]
+		resolved, err := e.resolve(list[1])
+		if err != nil {
+			return nil, err
+		}
+		variables, ok = resolved.(map[string]any)
+		if !ok {
+			return nil, errors.New("Template error: Fn::Sub variables must be a map")
+		}
+	}
+	s, ok := template.(string)
+	if !ok {
+		return nil, errors.New("Template error: Fn::Sub requires a string")
+	}
+
+	var err error
+	result := subRegex.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[2 : len(match)-1]
+		// ${!Literal} is written as ${Literal}.
+		if strings.HasPrefix(name, "!") {
+			return "${" + name[1:] + "}"
+		}
+		var value any
+		if v, ok := variables[name]; ok {
+			value = v
+		} else if resource, attribute, found := strings.Cut(name, "."); found && !strings.HasPrefix(name, "AWS::") {
+			value, err = e.getAtt(resource, attribute)
+		} else {
+			value, err = e.ref(name)
+		}
+		if err != nil {
+			return ""
+		}
+		return formatScalar(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *evaluator) condition(name string) (bool, error) {
+	if value, ok := e.conditions[name]; ok {
+		return value, nil
+	}
+	definition, ok := e.template.Conditions[name]
+	if !ok {
+		return false, fmt.Errorf("Template format error: Unresolved condition dependency %s", name)
+	}
+	resolved, err := e.resolve(definition)
+	if err != nil {
+		return false, err
+	}
+	value, ok := resolved.(bool)
+	if !ok {
+		return false, fmt.Errorf("Template format error: condition %s must be a condition function", name)
+	}
+	e.conditions[name] = value
+	return value, nil
+}
+
+// resolveParameters returns the values of the template's parameters, which are strings or, for
+// list types, lists of strings.
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/parameters-section-structure.html
+func resolveParameters(t *template, inputs []APIParameter) (map[string]any, error) {
+	given := make(map[string]string)
+	var unknown []string
+	for _, input := range inputs {
+		if _, ok := t.Parameters[input.ParameterKey]; !ok {
+			unknown = append(unknown, input.ParameterKey)
+			continue
+		}
+		given[input.ParameterKey] = input.ParameterValue
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("Parameters: [%s] do not exist in the template", strings.Join(unknown, ", "))
+	}
+
+	values := make(map[string]any)
+	var missing []string
+	for name, parameter := range t.Parameters {
+		value, ok := given[name]
+		if !ok {
+			if parameter.Default == nil {
+				missing = append(missing, name)
+				continue
+			}
+			if list, isList := parameter.Default.([]any); isList {
+				parts := make([]string, len(list))
+				for i, item := range list {
+					parts[i] = formatScalar(item)
+				}
+				value = strings.Join(parts, ",")
+			} else {
+				value = formatScalar(parameter.Default)
+			}
+		}
+
+		if len(parameter.AllowedValues) > 0 && !slices.ContainsFunc(parameter.AllowedValues, func(allowed any) bool {
+			return formatScalar(allowed) == value
+		}) {
+			return nil, fmt.Errorf("Parameter '%s' must be one of AllowedValues", name)
+		}
+		if parameter.AllowedPattern != "" {
+			pattern, err := regexp.Compile("^(?:" + parameter.AllowedPattern + ")$")
+			if err != nil || !pattern.MatchString(value) {
+				return nil, fmt.Errorf("Parameter '%s' must match pattern %s", name, parameter.AllowedPattern)
+			}
+		}
+
+		switch {
+		case parameter.Type == "Number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("Parameter '%s' must be a number.", name)
+			}
+			values[name] = value
+		case parameter.Type == "CommaDelimitedList" || strings.HasPrefix(parameter.Type, "List<"):
+			var list []any
+			for _, item := range strings.Split(value, ",") {
+				list = append(list, strings.TrimSpace(item))
+			}
+			values[name] = list
+		case strings.HasPrefix(parameter.Type, "AWS::SSM::Parameter::"):
+			return nil, fmt.Errorf("Parameter '%s' has unsupported type %s", name, parameter.Type)
+		default:
+			values[name] = value
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return nil, fmt.Errorf("Parameters: [%s] must have values", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
@@ -0,0 +1,660 @@
+package cloudformation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/ecr"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/services/ssm"
+)
+
+// resourceProvider creates and deletes one type of resource through the local services.
+// Properties which the local services don't model are accepted and ignored.
+type resourceProvider struct {
+	// create returns the resource's physical ID, which is also the value of Ref, and its attributes
+	// for Fn::GetAtt.
+	create func(c *CloudFormation, r *resourceRequest) (string, map[string]string, error)
+	delete func(c *CloudFormation, resource *StackResource) error
+}
+
+type resourceRequest struct {
+	stackName  string
+	logicalId  string
+	properties map[string]any
+}
+
+var resourceProviders map[string]resourceProvider
+
+func init() {
+	noop := resourceProvider{
+		create: func(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+			return physicalName(r.stackName, r.logicalId, 128), nil, nil
+		},
+		delete: func(c *CloudFormation, resource *StackResource) error {
+			return nil
+		},
+	}
+	// There's no local IAM, so roles and policies are only given the identifiers templates refer
+	// to them by.
+	noopIAM := func(create func(c *CloudFormation, r *resourceRequest) (string, map[string]string, error)) resourceProvider {
+		return resourceProvider{create: create, delete: noop.delete}
+	}
+	resourceProviders = map[string]resourceProvider{
+		"AWS::CDK::Metadata":                       noop,
+		"AWS::CloudFormation::WaitConditionHandle": noop,
+		"AWS::DynamoDB::Table":                     {createTable, deleteTable},
+		"AWS::ECR::Repository":                     {createRepository, deleteRepository},
+		"AWS::IAM::ManagedPolicy":                  noopIAM(createManagedPolicy),
+		"AWS::IAM::Policy":                         noop,
+		"AWS::IAM::Role":                           noopIAM(createRole),
+		"AWS::Kinesis::Stream":                     {createStream, deleteStream},
+		"AWS::KMS::Alias":                          {createAlias, deleteAlias},
+		"AWS::KMS::Key":                            {createKey, deleteKey},
+		"AWS::S3::Bucket":                          {createBucket, deleteBucket},
+		"AWS::S3::BucketPolicy":                    noop,
+		"AWS::SQS::Queue":                          {createQueue, deleteQueue},
+		"AWS::SQS::QueuePolicy":                    noop,
+		"AWS::SSM::Parameter":                      {createParameter, deleteParameter},
+	}
+}
+
+// physicalName generates a name for a resource which doesn't specify one, like
+// MyStack-MyQueue-1A2B3C4D5E6F7, of at most maxLength characters.
+func physicalName(stackName string, logicalId string, maxLength int) string {
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", ""))[:13]
+	prefix := stackName + "-" + logicalId
+	if len(prefix) > maxLength-len(suffix)-1 {
+		prefix = prefix[:maxLength-len(suffix)-1]
+	}
+	return prefix + "-" + suffix
+}
+
+func serviceError(awserr *awserrors.Error) error {
+	return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+}
+
+func notEnabled(service string) error {
+	return fmt.Errorf("%s is not enabled", service)
+}
+
+func (r *resourceRequest) string(name string) (string, error) {
+	value, ok := r.properties[name]
+	if !ok {
+		return "", nil
+	}
+	switch value.(type) {
+	case string, float64, bool:
+		return formatScalar(value), nil
+	}
+	return "", fmt.Errorf("Property %s must be a string", name)
+}
+
+// int64 returns an integer property, which may be given as a number or a string.
+func (r *resourceRequest) int64(name string, defaultValue int64) (int64, error) {
+	s, err := r.string(name)
+	if err != nil || s == "" {
+		return defaultValue, err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Property %s must be an integer", name)
+	}
+	return n, nil
+}
+
+func (r *resourceRequest) bool(name string) (bool, error) {
+	s, err := r.string(name)
+	if err != nil || s == "" {
+		return false, err
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("Property %s must be a boolean", name)
+	}
+	return b, nil
+}
+
+func (r *resourceRequest) tags() (map[string]string, error) {
+	var tags []struct {
+		Key   string
+		Value string
+	}
+	if value, ok := r.properties["Tags"]; ok {
+		if err := decodeSection(value, &tags); err != nil {
+			return nil, fmt.Errorf("Property Tags: %v", err)
+		}
+	}
+	result := make(map[string]string)
+	for _, tag := range tags {
+		result[tag.Key] = tag.Value
+	}
+	return result, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-s3-bucket.html
+func createBucket(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.buckets == nil {
+		return "", nil, notEnabled("S3")
+	}
+	name, err := r.string("BucketName")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = strings.ToLower(physicalName(r.stackName, r.logicalId, 63))
+	}
+	_, awserr := c.buckets.CreateBucket(s3.CreateBucketInput{Bucket: name})
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	region := c.arnGenerator.Region
+	return name, map[string]string{
+		"Arn":                 "arn:aws:s3:::" + name,
+		"DomainName":          name + ".s3.amazonaws.com",
+		"DualStackDomainName": name + ".s3.dualstack." + region + ".amazonaws.com",
+		"RegionalDomainName":  name + ".s3." + region + ".amazonaws.com",
+		"WebsiteURL":          "http://" + name + ".s3-website-" + region + ".amazonaws.com",
+	}, nil
+}
+
+func deleteBucket(c *CloudFormation, resource *StackResource) error {
+	if c.buckets == nil {
+		return notEnabled("S3")
+	}
+	_, awserr := c.buckets.DeleteBucket(s3.DeleteBucketInput{Bucket: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-kinesis-stream.html
+func createStream(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.streams == nil {
+		return "", nil, notEnabled("Kinesis")
+	}
+	name, err := r.string("Name")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = physicalName(r.stackName, r.logicalId, 128)
+	}
+	var mode struct {
+		StreamMode string
+	}
+	if value, ok := r.properties["StreamModeDetails"]; ok {
+		if err := decodeSection(value, &mode); err != nil {
+			return "", nil, fmt.Errorf("Property StreamModeDetails: %v", err)
+		}
+	}
+	// On-demand streams start with 4 shards.
+	defaultShardCount := int64(1)
+	if mode.StreamMode == "ON_DEMAND" {
+		defaultShardCount = 4
+	}
+	shardCount, err := r.int64("ShardCount", defaultShardCount)
+	if err != nil {
+		return "", nil, err
+	}
+	tags, err := r.tags()
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, awserr := c.streams.CreateStream(kinesis.CreateStreamInput{
+		StreamName: name,
+		ShardCount: shardCount,
+		Tags:       tags,
+	})
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+
+	// The resource isn't complete until the stream is active.
+	for {
+		output, awserr := c.streams.DescribeStreamSummary(kinesis.DescribeStreamSummaryInput{StreamName: name})
+		if awserr != nil {
+			return "", nil, serviceError(awserr)
+		}
+		summary := output.StreamDescriptionSummary
+		if summary.StreamStatus == string(kinesis.StatusActive) {
+			return name, map[string]string{"Arn": summary.StreamARN}, nil
+		}
+		time.Sleep(c.pollInterval)
+	}
+}
+
+func deleteStream(c *CloudFormation, resource *StackResource) error {
+	if c.streams == nil {
+		return notEnabled("Kinesis")
+	}
+	_, awserr := c.streams.DeleteStream(kinesis.DeleteStreamInput{StreamName: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-kms-key.html
+func createKey(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.keys == nil {
+		return "", nil, notEnabled("KMS")
+	}
+	input := kms.CreateKeyInput{}
+	var err error
+	if input.Description, err = r.string("Description"); err != nil {
+		return "", nil, err
+	}
+	if input.KeySpec, err = r.string("KeySpec"); err != nil {
+		return "", nil, err
+	}
+	if input.KeyUsage, err = r.string("KeyUsage"); err != nil {
+		return "", nil, err
+	}
+	tags, err := r.tags()
+	if err != nil {
+		return "", nil, err
+	}
+	for key, value := range tags {
+		input.Tags = append(input.Tags, kms.APITag{TagKey: key, TagValue: value})
+	}
+	slices.SortFunc(input.Tags, func(a, b kms.APITag) int {
+		return strings.Compare(a.TagKey, b.TagKey)
+	})
+	enabled := true
+	if _, ok := r.properties["Enabled"]; ok {
+		if enabled, err = r.bool("Enabled"); err != nil {
+			return "", nil, err
+		}
+	}
+
+	output, awserr := c.keys.CreateKey(input)
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	keyId := output.KeyMetadata.KeyId
+	if !enabled {
+		if _, awserr := c.keys.DisableKey(kms.DisableKeyInput{KeyId: keyId}); awserr != nil {
+			return "", nil, serviceError(awserr)
+		}
+	}
+	return keyId, map[string]string{
+		"Arn":   output.KeyMetadata.Arn,
+		"KeyId": keyId,
+	}, nil
+}
+
+// Keys can't be deleted locally, so deleting the resource disables the key instead.
+func deleteKey(c *CloudFormation, resource *StackResource) error {
+	if c.keys == nil {
+		return notEnabled("KMS")
+	}
+	_, awserr := c.keys.DisableKey(kms.DisableKeyInput{KeyId: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-kms-alias.html
+func createAlias(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.keys == nil {
+		return "", nil, notEnabled("KMS")
+	}
+	name, err := r.string("AliasName")
+	if err != nil {
+		return "", nil, err
+	}
+	targetKeyId, err := r.string("TargetKeyId")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" || targetKeyId == "" {
+		return "", nil, errors.New("Properties validation failed: AliasName and TargetKeyId are required")
+	}
+	_, awserr := c.keys.CreateAlias(kms.CreateAliasInput{AliasName: name, TargetKeyId: targetKeyId})
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	return name, nil, nil
+}
+
+func deleteAlias(c *CloudFormation, resource *StackResource) error {
+	if c.keys == nil {
+		return notEnabled("KMS")
+	}
+	_, awserr := c.keys.DeleteAlias(kms.DeleteAliasInput{AliasName: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// The queue properties which are queue attributes with the same name.
+var queueAttributeProperties = []string{
+	"ContentBasedDeduplication",
+	"DeduplicationScope",
+	"DelaySeconds",
+	"FifoQueue",
+	"FifoThroughputLimit",
+	"KmsDataKeyReusePeriodSeconds",
+	"KmsMasterKeyId",
+	"MaximumMessageSize",
+	"MessageRetentionPeriod",
+	"ReceiveMessageWaitTimeSeconds",
+	"SqsManagedSseEnabled",
+	"VisibilityTimeout",
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-sqs-queue.html
+func createQueue(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.queues == nil {
+		return "", nil, notEnabled("SQS")
+	}
+	attributes := make(map[string]string)
+	for _, name := range queueAttributeProperties {
+		value, err := r.string(name)
+		if err != nil {
+			return "", nil, err
+		}
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	// Redrive policies are objects in templates, but JSON strings in attributes.
+	for _, name := range []string{"RedrivePolicy", "RedriveAllowPolicy"} {
+		value, ok := r.properties[name]
+		if !ok {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			attributes[name] = s
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("Property %s: %v", name, err)
+		}
+		attributes[name] = string(encoded)
+	}
+
+	name, err := r.string("QueueName")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		if attributes["FifoQueue"] == "true" {
+			name = physicalName(r.stackName, r.logicalId, 75) + ".fifo"
+		} else {
+			name = physicalName(r.stackName, r.logicalId, 80)
+		}
+	}
+	tags, err := r.tags()
+	if err != nil {
+		return "", nil, err
+	}
+
+	output, awserr := c.queues.CreateQueue(sqs.CreateQueueInput{
+		Attributes: attributes,
+		QueueName:  name,
+		Tags:       tags,
+	})
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	queueAttributes, awserr := c.queues.GetQueueAttributes(sqs.GetQueueAttributesInput{
+		AttributeNames: []string{"QueueArn"},
+		QueueUrl:       output.QueueUrl,
+	})
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	return output.QueueUrl, map[string]string{
+		"Arn":       queueAttributes.Attributes["QueueArn"],
+		"QueueName": name,
+		"QueueUrl":  output.QueueUrl,
+	}, nil
+}
+
+func deleteQueue(c *CloudFormation, resource *StackResource) error {
+	if c.queues == nil {
+		return notEnabled("SQS")
+	}
+	_, awserr := c.queues.DeleteQueue(sqs.DeleteQueueInput{QueueUrl: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-dynamodb-table.html
+func createTable(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.tables == nil {
+		return "", nil, notEnabled("DynamoDB")
+	}
+	// The table's properties mostly match CreateTable's input.
+	var input dynamodb.CreateTableInput
+	if err := decodeSection(r.properties, &input); err != nil {
+		return "", nil, fmt.Errorf("Properties validation failed: %v", err)
+	}
+	if input.TableName == "" {
+		input.TableName = physicalName(r.stackName, r.logicalId, 255)
+	}
+	// Templates only specify the view type.
+	if input.StreamSpecification != nil && input.StreamSpecification.StreamViewType != "" {
+		input.StreamSpecification.StreamEnabled = true
+	}
+
+	output, awserr := c.tables.CreateTable(input)
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	attributes := map[string]string{
+		"Arn": output.TableDescription.TableARN,
+	}
+	if output.TableDescription.LatestStreamArn != "" {
+		attributes["StreamArn"] = output.TableDescription.LatestStreamArn
+	}
+	return input.TableName, attributes, nil
+}
+
+func deleteTable(c *CloudFormation, resource *StackResource) error {
+	if c.tables == nil {
+		return notEnabled("DynamoDB")
+	}
+	_, awserr := c.tables.DeleteTable(dynamodb.DeleteTableInput{TableName: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-ecr-repository.html
+func createRepository(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.repositories == nil {
+		return "", nil, notEnabled("ECR")
+	}
+	name, err := r.string("RepositoryName")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = strings.ToLower(physicalName(r.stackName, r.logicalId, 256))
+	}
+	input := ecr.CreateRepositoryInput{RepositoryName: name}
+	if input.ImageTagMutability, err = r.string("ImageTagMutability"); err != nil {
+		return "", nil, err
+	}
+	if value, ok := r.properties["ImageScanningConfiguration"]; ok {
+		var scanning struct {
+			ScanOnPush bool
+		}
+		if err := decodeSection(value, &scanning); err != nil {
+			return "", nil, fmt.Errorf("Property ImageScanningConfiguration: %v", err)
+		}
+		input.ImageScanningConfiguration = &ecr.APIImageScanningConfiguration{ScanOnPush: scanning.ScanOnPush}
+	}
+	tags, err := r.tags()
+	if err != nil {
+		return "", nil, err
+	}
+	for key, value := range tags {
+		input.Tags = append(input.Tags, ecr.APITag{Key: key, Value: value})
+	}
+
+	output, awserr := c.repositories.CreateRepository(input)
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	if policy, ok := r.properties["RepositoryPolicyText"]; ok {
+		policyText, ok := policy.(string)
+		if !ok {
+			policyText = encodeProperties(policy)
+		}
+		_, awserr := c.repositories.SetRepositoryPolicy(ecr.SetRepositoryPolicyInput{RepositoryName: name, PolicyText: policyText})
+		if awserr != nil {
+			c.repositories.DeleteRepository(ecr.DeleteRepositoryInput{RepositoryName: name, Force: true})
+			return "", nil, serviceError(awserr)
+		}
+	}
+	attributes := map[string]string{
+		"Arn":           output.Repository.RepositoryArn,
+		"RepositoryUri": output.Repository.RepositoryUri,
+	}
+	return name, attributes, nil
+}
+
+func deleteRepository(c *CloudFormation, resource *StackResource) error {
+	if c.repositories == nil {
+		return notEnabled("ECR")
+	}
+	// Unlike AWS, repositories which have images are deleted along with them, rather than failing
+	// to delete unless EmptyOnDelete is set, so that tearing down a stack doesn't need cleanup.
+	_, awserr := c.repositories.DeleteRepository(ecr.DeleteRepositoryInput{RepositoryName: resource.PhysicalId, Force: true})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-ssm-parameter.html
+func createParameter(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	if c.parameters == nil {
+		return "", nil, notEnabled("SSM")
+	}
+	input := ssm.PutParameterInput{}
+	var err error
+	for name, target := range map[string]*string{
+		"AllowedPattern": &input.AllowedPattern,
+		"DataType":       &input.DataType,
+		"Description":    &input.Description,
+		"Name":           &input.Name,
+		"Policies":       &input.Policies,
+		"Tier":           &input.Tier,
+		"Type":           &input.Type,
+		"Value":          &input.Value,
+	} {
+		if *target, err = r.string(name); err != nil {
+			return "", nil, err
+		}
+	}
+	if input.Type == "" || input.Value == "" {
+		return "", nil, errors.New("Properties validation failed: Type and Value are required")
+	}
+	if input.Name == "" {
+		input.Name = physicalName("CFN", r.logicalId, 2048)
+	}
+	// Unlike other resources, the parameter's tags are a map.
+	if value, ok := r.properties["Tags"]; ok {
+		var tags map[string]string
+		if err := decodeSection(value, &tags); err != nil {
+			return "", nil, fmt.Errorf("Property Tags: %v", err)
+		}
+		for key, value := range tags {
+			input.Tags = append(input.Tags, ssm.APITag{Key: key, Value: value})
+		}
+		slices.SortFunc(input.Tags, func(a, b ssm.APITag) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+	}
+
+	_, awserr := c.parameters.PutParameter(input)
+	if awserr != nil {
+		return "", nil, serviceError(awserr)
+	}
+	return input.Name, map[string]string{
+		"Type":  input.Type,
+		"Value": input.Value,
+	}, nil
+}
+
+func deleteParameter(c *CloudFormation, resource *StackResource) error {
+	if c.parameters == nil {
+		return notEnabled("SSM")
+	}
+	_, awserr := c.parameters.DeleteParameter(ssm.DeleteParameterInput{Name: resource.PhysicalId})
+	if awserr != nil {
+		return serviceError(awserr)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-iam-role.html
+func createRole(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	name, err := r.string("RoleName")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = physicalName(r.stackName, r.logicalId, 64)
+	}
+	path, err := r.string("Path")
+	if err != nil {
+		return "", nil, err
+	}
+	if path == "" {
+		path = "/"
+	}
+	return name, map[string]string{
+		"Arn":    fmt.Sprintf("arn:aws:iam::%s:role%s%s", c.arnGenerator.AwsAccountId, path, name),
+		"RoleId": "AROA" + strings.ToUpper(strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", ""))[:17],
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-iam-managedpolicy.html
+func createManagedPolicy(c *CloudFormation, r *resourceRequest) (string, map[string]string, error) {
+	name, err := r.string("ManagedPolicyName")
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = physicalName(r.stackName, r.logicalId, 128)
+	}
+	path, err := r.string("Path")
+	if err != nil {
+		return "", nil, err
+	}
+	if path == "" {
+		path = "/"
+	}
+	// Ref returns the policy's ARN.
+	policyArn := fmt.Sprintf("arn:aws:iam::%s:policy%s%s", c.arnGenerator.AwsAccountId, path, name)
+	return policyArn, map[string]string{
+		"PolicyArn": policyArn,
+		"PolicyId":  "ANPA" + strings.ToUpper(strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", ""))[:17],
+	}, nil
+}
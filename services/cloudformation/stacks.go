@@ -0,0 +1,513 @@
+package cloudformation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+const timestampFormat = "2006-01-02T15:04:05.000Z"
+
+var stackNameRegex = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]{0,127}$`)
+
+// The statuses stacks can be updated in.
+var updatableStatuses = []string{"CREATE_COMPLETE", "UPDATE_COMPLETE", "UPDATE_ROLLBACK_COMPLETE"}
+
+type Stack struct {
+	Id               string
+	Name             string
+	Description      string
+	Capabilities     []string
+	Tags             []APITag
+	NotificationARNs []string
+	DisableRollback  bool
+	OnFailure        string
+	Status           string
+	StatusReason     string
+	CreationTime     time.Time
+	LastUpdatedTime  time.Time
+	DeletionTime     time.Time
+	// Keyed by logical ID.
+	Resources map[string]*StackResource
+
+	// Nil until a change set which creates the stack is executed.
+	deployment *deployment
+	// Logical IDs in the order the resources were created.
+	resourceOrder []string
+	outputs       []APIOutput
+	// The names of the exports the stack imports.
+	imports map[string]bool
+	// In the order they were created.
+	changeSets []*ChangeSet
+	// Oldest first.
+	events []APIStackEvent
+	// Closed when the stack's current operation finishes.
+	done chan struct{}
+}
+
+type StackResource struct {
+	LogicalId      string
+	PhysicalId     string
+	Type           string
+	Status         string
+	StatusReason   string
+	LastUpdated    time.Time
+	DeletionPolicy string
+
+	attributes map[string]string
+	// The properties the resource was created with, as JSON, to tell whether updates change it.
+	properties string
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timestampFormat)
+}
+
+func (c *CloudFormation) newStackId(stackName string) string {
+	return c.arnGenerator.Generate("cloudformation", "stack", stackName+"/"+uuid.Must(uuid.NewV4()).String())
+}
+
+// lockedNewStack adds a stack with no resources.
+func (c *CloudFormation) lockedNewStack(id string, name string, status string) *Stack {
+	done := make(chan struct{})
+	close(done)
+	stack := &Stack{
+		Id:           id,
+		Name:         name,
+		Status:       status,
+		CreationTime: c.clock(),
+		Resources:    make(map[string]*StackResource),
+		done:         done,
+	}
+	c.stacksById[id] = stack
+	c.stackIds = append(c.stackIds, id)
+	return stack
+}
+
+// lockedGetStack returns a stack by its ID, or by its name if it hasn't been deleted.
+func (c *CloudFormation) lockedGetStack(nameOrId string) (*Stack, *awserrors.Error) {
+	if stack, ok := c.stacksById[nameOrId]; ok {
+		return stack, nil
+	}
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		if stack.Name == nameOrId && stack.Status != "DELETE_COMPLETE" {
+			return stack, nil
+		}
+	}
+	return nil, ValidationError(fmt.Sprintf("Stack with id %s does not exist", nameOrId))
+}
+
+// lockedRun runs an operation on the stack in the background, once any operation in progress finishes.
+func (c *CloudFormation) lockedRun(stack *Stack, operation func()) {
+	previous := stack.done
+	done := make(chan struct{})
+	stack.done = done
+	go func() {
+		defer close(done)
+		<-previous
+		operation()
+	}()
+}
+
+// lockedAddEvent records an event for the stack, or for one of its resources if it's given.
+func (c *CloudFormation) lockedAddEvent(stack *Stack, resource *StackResource, properties string) {
+	event := APIStackEvent{
+		EventId:            uuid.Must(uuid.NewV4()).String(),
+		LogicalResourceId:  stack.Name,
+		PhysicalResourceId: stack.Id,
+		ResourceStatus:     stack.Status,
+		ResourceType:       "AWS::CloudFormation::Stack",
+		StackId:            stack.Id,
+		StackName:          stack.Name,
+		Timestamp:          formatTime(c.clock()),
+	}
+	if resource != nil {
+		event.EventId = resource.LogicalId + "-" + event.EventId
+		event.LogicalResourceId = resource.LogicalId
+		event.PhysicalResourceId = resource.PhysicalId
+		event.ResourceProperties = properties
+		event.ResourceStatus = resource.Status
+		event.ResourceStatusReason = resource.StatusReason
+		event.ResourceType = resource.Type
+	} else {
+		event.ResourceStatusReason = stack.StatusReason
+	}
+	stack.events = append(stack.events, event)
+}
+
+func (c *CloudFormation) lockedSetStackStatus(stack *Stack, status string, reason string) {
+	stack.Status = status
+	stack.StatusReason = reason
+	if status == "DELETE_COMPLETE" {
+		stack.DeletionTime = c.clock()
+	}
+	c.lockedAddEvent(stack, nil, "")
+}
+
+func (c *CloudFormation) setStackStatus(stack *Stack, status string, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lockedSetStackStatus(stack, status, reason)
+}
+
+func (c *CloudFormation) setResourceStatus(stack *Stack, resource *StackResource, status string, reason string, properties string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resource.Status = status
+	resource.StatusReason = reason
+	resource.LastUpdated = c.clock()
+	c.lockedAddEvent(stack, resource, properties)
+}
+
+func validateStackName(name string) *awserrors.Error {
+	if !stackNameRegex.MatchString(name) {
+		return ValidationError(fmt.Sprintf(
+			"1 validation error detected: Value '%s' at 'stackName' failed to satisfy constraint: Member must satisfy regular expression pattern: [a-zA-Z][-a-zA-Z0-9]*", name))
+	}
+	return nil
+}
+
+func validateOnFailure(onFailure string, disableRollback bool) *awserrors.Error {
+	switch onFailure {
+	case "", "DO_NOTHING", "ROLLBACK", "DELETE":
+	default:
+		return ValidationError("OnFailure must be one of DO_NOTHING, ROLLBACK or DELETE")
+	}
+	if onFailure != "" && disableRollback {
+		return ValidationError("You can specify either DisableRollback or OnFailure, but not both.")
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_CreateStack.html
+func (c *CloudFormation) CreateStack(input CreateStackInput) (*CreateStackOutput, *awserrors.Error) {
+	if awserr := validateStackName(input.StackName); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateOnFailure(input.OnFailure, input.DisableRollback); awserr != nil {
+		return nil, awserr
+	}
+
+	identity := stackIdentity{
+		id:               c.newStackId(input.StackName),
+		name:             input.StackName,
+		notificationARNs: input.NotificationARNs,
+	}
+	d, awserr := c.newDeployment(identity, templateSource{body: input.TemplateBody, url: input.TemplateURL}, input.Parameters, nil)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, awserr := c.lockedGetStack(input.StackName); awserr == nil {
+		return nil, AlreadyExistsException(fmt.Sprintf("Stack [%s] already exists", input.StackName))
+	}
+
+	stack := c.lockedNewStack(identity.id, input.StackName, "CREATE_IN_PROGRESS")
+	stack.Description = d.template.Description
+	stack.Capabilities = input.Capabilities
+	stack.Tags = input.Tags
+	stack.NotificationARNs = input.NotificationARNs
+	stack.DisableRollback = input.DisableRollback
+	stack.OnFailure = input.OnFailure
+	stack.StatusReason = "User Initiated"
+	c.lockedAddEvent(stack, nil, "")
+	c.lockedRun(stack, func() {
+		c.deploy(stack, d, false)
+	})
+
+	return &CreateStackOutput{
+		StackId: stack.Id,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_UpdateStack.html
+func (c *CloudFormation) UpdateStack(input UpdateStackInput) (*UpdateStackOutput, *awserrors.Error) {
+	c.mu.Lock()
+	stack, awserr := c.lockedGetStack(input.StackName)
+	if awserr != nil {
+		c.mu.Unlock()
+		return nil, awserr
+	}
+	identity := stackIdentity{id: stack.Id, name: stack.Name, notificationARNs: stack.NotificationARNs}
+	if input.NotificationARNs != nil {
+		identity.notificationARNs = input.NotificationARNs
+	}
+	previous := stack.deployment
+	c.mu.Unlock()
+
+	source := templateSource{body: input.TemplateBody, url: input.TemplateURL, usePrevious: input.UsePreviousTemplate}
+	d, awserr := c.newDeployment(identity, source, input.Parameters, previous)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !slices.Contains(updatableStatuses, stack.Status) {
+		return nil, ValidationError(fmt.Sprintf("Stack:%s is in %s state and can not be updated.", stack.Id, stack.Status))
+	}
+	d.evaluator.resources = stack.Resources
+	d.evaluator.exports = c.lockedExports(stack)
+	if len(c.lockedChanges(stack, d)) == 0 && d.templateBody == stack.deployment.templateBody &&
+		slices.Equal(d.parameters, stack.deployment.parameters) {
+		return nil, ValidationError("No updates are to be performed.")
+	}
+
+	if input.Capabilities != nil {
+		stack.Capabilities = input.Capabilities
+	}
+	if input.Tags != nil {
+		stack.Tags = input.Tags
+	}
+	stack.NotificationARNs = identity.notificationARNs
+	stack.DisableRollback = input.DisableRollback
+	c.lockedExpireChangeSets(stack, nil)
+	c.lockedSetStackStatus(stack, "UPDATE_IN_PROGRESS", "User Initiated")
+	c.lockedRun(stack, func() {
+		c.deploy(stack, d, true)
+	})
+
+	return &UpdateStackOutput{
+		StackId: stack.Id,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_DeleteStack.html
+func (c *CloudFormation) DeleteStack(input DeleteStackInput) (*DeleteStackOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Deleting a stack which doesn't exist succeeds.
+	stack, awserr := c.lockedGetStack(input.StackName)
+	if awserr != nil || stack.Status == "DELETE_COMPLETE" || stack.Status == "DELETE_IN_PROGRESS" {
+		return &DeleteStackOutput{}, nil
+	}
+	for _, output := range stack.outputs {
+		if output.ExportName == "" {
+			continue
+		}
+		if importers := c.lockedImporters(output.ExportName, stack); len(importers) > 0 {
+			return nil, ValidationError(fmt.Sprintf("Export %s cannot be deleted as it is in use by %s", output.ExportName, importers[0]))
+		}
+	}
+
+	stack.changeSets = nil
+	c.lockedRun(stack, func() {
+		c.setStackStatus(stack, "DELETE_IN_PROGRESS", "User Initiated")
+		c.deleteStack(stack, input.RetainResources)
+	})
+
+	return &DeleteStackOutput{}, nil
+}
+
+func (c *CloudFormation) lockedDescribeStack(stack *Stack) APIStack {
+	description := APIStack{
+		Capabilities:      stack.Capabilities,
+		CreationTime:      formatTime(stack.CreationTime),
+		DeletionTime:      formatTime(stack.DeletionTime),
+		Description:       stack.Description,
+		DisableRollback:   stack.DisableRollback,
+		DriftInformation:  APIStackDriftInformation{StackDriftStatus: "NOT_CHECKED"},
+		LastUpdatedTime:   formatTime(stack.LastUpdatedTime),
+		NotificationARNs:  stack.NotificationARNs,
+		Outputs:           stack.outputs,
+		StackId:           stack.Id,
+		StackName:         stack.Name,
+		StackStatus:       stack.Status,
+		StackStatusReason: stack.StatusReason,
+		Tags:              stack.Tags,
+	}
+	if stack.deployment != nil {
+		description.Parameters = stack.deployment.parameters
+	}
+	return description
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_DescribeStacks.html
+func (c *CloudFormation) DescribeStacks(input DescribeStacksInput) (*DescribeStacksOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &DescribeStacksOutput{}
+	if input.StackName != "" {
+		stack, awserr := c.lockedGetStack(input.StackName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		output.Stacks = append(output.Stacks, c.lockedDescribeStack(stack))
+		return output, nil
+	}
+	// Deleted stacks are only described by their ID.
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		if stack.Status != "DELETE_COMPLETE" {
+			output.Stacks = append(output.Stacks, c.lockedDescribeStack(stack))
+		}
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_DescribeStackEvents.html
+func (c *CloudFormation) DescribeStackEvents(input DescribeStackEventsInput) (*DescribeStackEventsOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stack, awserr := c.lockedGetStack(input.StackName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Events are returned newest first.
+	events := slices.Clone(stack.events)
+	slices.Reverse(events)
+	return &DescribeStackEventsOutput{
+		StackEvents: events,
+	}, nil
+}
+
+// lockedSortedResources returns the stack's resources in the order they were created.
+func (c *CloudFormation) lockedSortedResources(stack *Stack) []*StackResource {
+	resources := make([]*StackResource, len(stack.resourceOrder))
+	for i, logicalId := range stack.resourceOrder {
+		resources[i] = stack.Resources[logicalId]
+	}
+	return resources
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_DescribeStackResources.html
+func (c *CloudFormation) DescribeStackResources(input DescribeStackResourcesInput) (*DescribeStackResourcesOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stack *Stack
+	switch {
+	case input.StackName != "":
+		var awserr *awserrors.Error
+		stack, awserr = c.lockedGetStack(input.StackName)
+		if awserr != nil {
+			return nil, awserr
+		}
+	case input.PhysicalResourceId != "":
+		for _, id := range c.stackIds {
+			for _, resource := range c.stacksById[id].Resources {
+				if resource.PhysicalId == input.PhysicalResourceId {
+					stack = c.stacksById[id]
+				}
+			}
+		}
+		if stack == nil {
+			return nil, ValidationError(fmt.Sprintf("Stack for %s does not exist", input.PhysicalResourceId))
+		}
+	default:
+		return nil, ValidationError("Either StackName or PhysicalResourceId must be specified.")
+	}
+
+	output := &DescribeStackResourcesOutput{}
+	for _, resource := range c.lockedSortedResources(stack) {
+		if input.LogicalResourceId != "" && resource.LogicalId != input.LogicalResourceId {
+			continue
+		}
+		output.StackResources = append(output.StackResources, APIStackResource{
+			LogicalResourceId:    resource.LogicalId,
+			PhysicalResourceId:   resource.PhysicalId,
+			ResourceStatus:       resource.Status,
+			ResourceStatusReason: resource.StatusReason,
+			ResourceType:         resource.Type,
+			StackId:              stack.Id,
+			StackName:            stack.Name,
+			Timestamp:            formatTime(resource.LastUpdated),
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_ListStackResources.html
+func (c *CloudFormation) ListStackResources(input ListStackResourcesInput) (*ListStackResourcesOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stack, awserr := c.lockedGetStack(input.StackName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListStackResourcesOutput{}
+	for _, resource := range c.lockedSortedResources(stack) {
+		output.StackResourceSummaries = append(output.StackResourceSummaries, APIStackResourceSummary{
+			LastUpdatedTimestamp: formatTime(resource.LastUpdated),
+			LogicalResourceId:    resource.LogicalId,
+			PhysicalResourceId:   resource.PhysicalId,
+			ResourceStatus:       resource.Status,
+			ResourceStatusReason: resource.StatusReason,
+			ResourceType:         resource.Type,
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_ListStacks.html
+func (c *CloudFormation) ListStacks(input ListStacksInput) (*ListStacksOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &ListStacksOutput{}
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		if len(input.StackStatusFilter) > 0 && !slices.Contains(input.StackStatusFilter, stack.Status) {
+			continue
+		}
+		output.StackSummaries = append(output.StackSummaries, APIStackSummary{
+			CreationTime:        formatTime(stack.CreationTime),
+			DeletionTime:        formatTime(stack.DeletionTime),
+			DriftInformation:    APIStackDriftInformation{StackDriftStatus: "NOT_CHECKED"},
+			LastUpdatedTime:     formatTime(stack.LastUpdatedTime),
+			StackId:             stack.Id,
+			StackName:           stack.Name,
+			StackStatus:         stack.Status,
+			StackStatusReason:   stack.StatusReason,
+			TemplateDescription: stack.Description,
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/APIReference/API_GetTemplate.html
+func (c *CloudFormation) GetTemplate(input GetTemplateInput) (*GetTemplateOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var d *deployment
+	if input.ChangeSetName != "" {
+		changeSet, awserr := c.lockedGetChangeSet(input.StackName, input.ChangeSetName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		d = changeSet.deployment
+	} else {
+		stack, awserr := c.lockedGetStack(input.StackName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		d = stack.deployment
+	}
+	output := &GetTemplateOutput{
+		StagesAvailable: []string{"Original", "Processed"},
+	}
+	if d != nil {
+		output.TemplateBody = d.templateBody
+	}
+	return output, nil
+}
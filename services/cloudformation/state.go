@@ -0,0 +1,202 @@
+package cloudformation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type resourceState struct {
+	*StackResource
+	Attributes map[string]string `json:",omitempty"`
+	Properties string            `json:",omitempty"`
+}
+
+type changeSetState struct {
+	*ChangeSet
+	// The change set's template and the parameters as they were given, from which its deployment
+	// is created again.
+	TemplateBody string
+	Parameters   []APIParameter
+}
+
+type stackState struct {
+	*Stack
+	// Empty until a change set which creates the stack is executed.
+	TemplateBody string         `json:",omitempty"`
+	Parameters   []APIParameter `json:",omitempty"`
+	// In the order they were created.
+	Resources []resourceState
+	Outputs   []APIOutput `json:",omitempty"`
+	// Sorted.
+	Imports []string `json:",omitempty"`
+	// In the order they were created.
+	ChangeSets []changeSetState `json:",omitempty"`
+	// Oldest first.
+	Events []APIStackEvent
+}
+
+type stacksState struct {
+	// In the order they were created.
+	Stacks []stackState
+}
+
+// TryPause pauses changes to each region's stacks while a snapshot copies them.
+func (c *CloudFormation) TryPause() (func(), bool) {
+	return c.regions.TryPause(func(c *CloudFormation) *sync.Mutex { return &c.mu })
+}
+
+// ExportState writes each region's stacks, including deleted ones, with their templates, resources,
+// change sets and events to the archive. The resources themselves are exported by their services.
+func (c *CloudFormation) ExportState(w *state.Writer) error {
+	return c.regions.ExportState(w, (*CloudFormation).exportState)
+}
+
+func (c *CloudFormation) exportState(w *state.Writer) error {
+	unlock := w.Lock(&c.mu)
+	defer unlock()
+
+	var exported stacksState
+	for _, id := range c.stackIds {
+		stack := c.stacksById[id]
+		s := stackState{Stack: stack, Outputs: stack.outputs, Events: stack.events}
+		if stack.deployment != nil {
+			s.TemplateBody = stack.deployment.templateBody
+			s.Parameters = stack.deployment.inputs
+		}
+		for _, logicalId := range stack.resourceOrder {
+			resource := stack.Resources[logicalId]
+			s.Resources = append(s.Resources, resourceState{
+				StackResource: resource,
+				Attributes:    resource.attributes,
+				Properties:    resource.properties,
+			})
+		}
+		for name := range stack.imports {
+			s.Imports = append(s.Imports, name)
+		}
+		slices.Sort(s.Imports)
+		for _, changeSet := range stack.changeSets {
+			s.ChangeSets = append(s.ChangeSets, changeSetState{
+				ChangeSet:    changeSet,
+				TemplateBody: changeSet.deployment.templateBody,
+				Parameters:   changeSet.deployment.inputs,
+			})
+		}
+		exported.Stacks = append(exported.Stacks, s)
+	}
+	return w.WriteJSON("stacks.json", exported)
+}
+
+// interruptedStatus is the status of a stack, resource or change set whose operation was
+// interrupted by a state import.
+func interruptedStatus(status string) string {
+	switch {
+	case status == "REVIEW_IN_PROGRESS":
+		// The stack is waiting for a change set to be executed.
+		return status
+	case strings.HasSuffix(status, "_COMPLETE_CLEANUP_IN_PROGRESS"):
+		// The update or rollback had finished, but some old resources may not have been deleted.
+		return strings.TrimSuffix(status, "_CLEANUP_IN_PROGRESS")
+	case strings.HasSuffix(status, "_IN_PROGRESS"):
+		return strings.TrimSuffix(status, "_IN_PROGRESS") + "_FAILED"
+	}
+	return status
+}
+
+// ImportState replaces each region's stacks with the archive's. Templates are parsed again, and
+// operations which were in progress aren't resumed: they fail once they're imported.
+func (c *CloudFormation) ImportState(r *state.Reader) (func() error, error) {
+	return c.regions.ImportState(r, (*CloudFormation).importState, (*CloudFormation).exportState)
+}
+
+func (c *CloudFormation) importState(r *state.Reader) (func() error, error) {
+	var imported stacksState
+	if err := r.ReadJSON("stacks.json", &imported); err != nil {
+		return nil, err
+	}
+
+	stacksById := make(map[string]*Stack)
+	var stackIds []string
+	for _, s := range imported.Stacks {
+		stack := s.Stack
+		if stack == nil || !stackNameRegex.MatchString(stack.Name) || stack.Id == "" {
+			return nil, fmt.Errorf("invalid stack")
+		}
+		identity := stackIdentity{id: stack.Id, name: stack.Name, notificationARNs: stack.NotificationARNs}
+		stack.deployment = nil
+		if s.TemplateBody != "" {
+			d, awserr := c.newDeployment(identity, templateSource{body: s.TemplateBody}, s.Parameters, nil)
+			if awserr != nil {
+				return nil, fmt.Errorf("stack %s: %s", stack.Id, awserr.Body.Message)
+			}
+			stack.deployment = d
+		}
+		stack.Resources = make(map[string]*StackResource)
+		stack.resourceOrder = nil
+		for _, rs := range s.Resources {
+			resource := rs.StackResource
+			if resource == nil || resource.LogicalId == "" {
+				return nil, fmt.Errorf("stack %s: invalid resource", stack.Id)
+			}
+			if _, ok := resourceProviders[resource.Type]; !ok {
+				return nil, fmt.Errorf("stack %s: resource %s has unsupported type %s", stack.Id, resource.LogicalId, resource.Type)
+			}
+			resource.attributes = rs.Attributes
+			resource.properties = rs.Properties
+			if status := interruptedStatus(resource.Status); status != resource.Status {
+				resource.Status = status
+				resource.StatusReason = "The operation was interrupted by a state import."
+			}
+			stack.Resources[resource.LogicalId] = resource
+			stack.resourceOrder = append(stack.resourceOrder, resource.LogicalId)
+		}
+		stack.outputs = s.Outputs
+		stack.imports = make(map[string]bool)
+		for _, name := range s.Imports {
+			stack.imports[name] = true
+		}
+		stack.changeSets = nil
+		for _, cs := range s.ChangeSets {
+			changeSet := cs.ChangeSet
+			if changeSet == nil || changeSet.Id == "" {
+				return nil, fmt.Errorf("stack %s: invalid change set", stack.Id)
+			}
+			changeSetIdentity := identity
+			changeSetIdentity.notificationARNs = changeSet.NotificationARNs
+			d, awserr := c.newDeployment(changeSetIdentity, templateSource{body: cs.TemplateBody}, cs.Parameters, nil)
+			if awserr != nil {
+				return nil, fmt.Errorf("change set %s: %s", changeSet.Id, awserr.Body.Message)
+			}
+			d.evaluator.resources = stack.Resources
+			changeSet.deployment = d
+			changeSet.stack = stack
+			if changeSet.ExecutionStatus == "EXECUTE_IN_PROGRESS" {
+				changeSet.ExecutionStatus = "EXECUTE_FAILED"
+			}
+			stack.changeSets = append(stack.changeSets, changeSet)
+		}
+		stack.events = s.Events
+		if status := interruptedStatus(stack.Status); status != stack.Status {
+			stack.Status = status
+			stack.StatusReason = "The operation was interrupted by a state import."
+		}
+		done := make(chan struct{})
+		close(done)
+		stack.done = done
+		stacksById[stack.Id] = stack
+		stackIds = append(stackIds, stack.Id)
+	}
+
+	return func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// Operations in progress on the previous stacks carry on, but only change those stacks.
+		c.stacksById = stacksById
+		c.stackIds = stackIds
+		return nil
+	}, nil
+}
@@ -0,0 +1,328 @@
+package cloudformation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/template-anatomy.html
+
+type templateParameter struct {
+	Type           string
+	Default        any
+	AllowedValues  []any
+	AllowedPattern string
+	NoEcho         bool
+	Description    string
+}
+
+type templateResource struct {
+	Type           string
+	Properties     map[string]any
+	DependsOn      []string
+	Condition      string
+	DeletionPolicy string
+}
+
+type templateOutput struct {
+	Value       any
+	Description string
+	Export      *struct {
+		Name any
+	}
+	Condition string
+}
+
+type template struct {
+	Description string
+	Parameters  map[string]templateParameter
+	Mappings    map[string]any
+	Conditions  map[string]any
+	Resources   map[string]templateResource
+	Outputs     map[string]templateOutput
+	// The logical IDs of the resources in an order they can be created in.
+	order []string
+}
+
+// parseTemplate parses a JSON or YAML template and checks that its resources are supported and
+// their dependencies can be created.
+func parseTemplate(body string) (*template, error) {
+	var document any
+	if strings.HasPrefix(strings.TrimSpace(body), "{") {
+		if err := json.Unmarshal([]byte(body), &document); err != nil {
+			return nil, fmt.Errorf("Template format error: JSON not well-formed. (%v)", err)
+		}
+	} else {
+		var err error
+		document, err = parseYAML(body)
+		if err != nil {
+			return nil, fmt.Errorf("Template format error: YAML not well-formed. (%v)", err)
+		}
+	}
+	sections, ok := document.(map[string]any)
+	if !ok {
+		return nil, errors.New("Template format error: template must be a JSON or YAML object")
+	}
+
+	t := &template{
+		Parameters: make(map[string]templateParameter),
+		Mappings:   make(map[string]any),
+		Conditions: make(map[string]any),
+		Resources:  make(map[string]templateResource),
+		Outputs:    make(map[string]templateOutput),
+	}
+	for name, section := range sections {
+		var err error
+		switch name {
+		case "AWSTemplateFormatVersion", "Metadata", "Rules":
+		case "Description":
+			t.Description, _ = section.(string)
+		case "Transform":
+			err = errors.New("Transforms are not supported")
+		case "Parameters":
+			err = decodeSection(section, &t.Parameters)
+		case "Mappings":
+			err = decodeSection(section, &t.Mappings)
+		case "Conditions":
+			err = decodeSection(section, &t.Conditions)
+		case "Resources":
+			err = decodeSection(section, &t.Resources)
+		case "Outputs":
+			err = decodeSection(section, &t.Outputs)
+		default:
+			err = fmt.Errorf("Invalid template property or properties [%s]", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Template format error: %v", err)
+		}
+	}
+	if len(t.Resources) == 0 {
+		return nil, errors.New("Template format error: At least one Resources member must be defined.")
+	}
+
+	var unsupported []string
+	for _, resource := range t.Resources {
+		if _, ok := resourceProviders[resource.Type]; !ok && !slices.Contains(unsupported, resource.Type) {
+			unsupported = append(unsupported, resource.Type)
+		}
+	}
+	if len(unsupported) > 0 {
+		slices.Sort(unsupported)
+		return nil, fmt.Errorf("Template format error: Unrecognized resource types: [%s]", strings.Join(unsupported, ", "))
+	}
+	for name := range t.Parameters {
+		if _, ok := t.Resources[name]; ok {
+			return nil, fmt.Errorf("Template format error: %s is both a parameter and a resource", name)
+		}
+	}
+
+	order, err := t.creationOrder()
+	if err != nil {
+		return nil, err
+	}
+	t.order = order
+
+	for name, output := range t.Outputs {
+		references := findReferences(output.Value)
+		if output.Export != nil {
+			references = append(references, findReferences(output.Export.Name)...)
+		}
+		for _, reference := range references {
+			_, isParameter := t.Parameters[reference]
+			_, isResource := t.Resources[reference]
+			if !isParameter && !isResource && !strings.HasPrefix(reference, "AWS::") {
+				return nil, fmt.Errorf("Template format error: Unresolved resource dependencies [%s] in the Outputs block of the template", reference)
+			}
+		}
+		if output.Value == nil {
+			return nil, fmt.Errorf("Template format error: Every Outputs member must contain a Value object (%s)", name)
+		}
+	}
+	return t, nil
+}
+
+// decodeSection decodes a section into its type. Sections are decoded through JSON, since values
+// from YAML templates are the same types as from JSON ones.
+func decodeSection(section any, v any) error {
+	encoded, err := json.Marshal(section)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(encoded, v); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return fmt.Errorf("%s must be a %s", typeError.Field, typeError.Type)
+		}
+		return err
+	}
+	return nil
+}
+
+// UnmarshalJSON allows DependsOn to be a string or a list.
+func (r *templateResource) UnmarshalJSON(data []byte) error {
+	var resource struct {
+		Type           string
+		Properties     map[string]any
+		DependsOn      any
+		Condition      string
+		DeletionPolicy string
+	}
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return err
+	}
+	*r = templateResource{
+		Type:           resource.Type,
+		Properties:     resource.Properties,
+		Condition:      resource.Condition,
+		DeletionPolicy: resource.DeletionPolicy,
+	}
+	switch dependsOn := resource.DependsOn.(type) {
+	case string:
+		r.DependsOn = []string{dependsOn}
+	case []any:
+		for _, d := range dependsOn {
+			s, ok := d.(string)
+			if !ok {
+				return errors.New("DependsOn must be a string or list of strings")
+			}
+			r.DependsOn = append(r.DependsOn, s)
+		}
+	}
+	return nil
+}
+
+// creationOrder returns the resources in an order where each resource is after the resources it
+// refers to, or an error if there's a cycle or a reference to a resource which doesn't exist.
+func (t *template) creationOrder() ([]string, error) {
+	dependencies := make(map[string][]string)
+	for name, resource := range t.Resources {
+		references := slices.Clone(resource.DependsOn)
+		for _, reference := range findReferences(resource.Properties) {
+			if _, ok := t.Parameters[reference]; ok || strings.HasPrefix(reference, "AWS::") {
+				continue
+			}
+			references = append(references, reference)
+		}
+		for _, reference := range references {
+			if _, ok := t.Resources[reference]; !ok {
+				return nil, fmt.Errorf("Template format error: Unresolved resource dependencies [%s] in the Resources block of the template", reference)
+			}
+		}
+		dependencies[name] = references
+	}
+
+	names := make([]string, 0, len(t.Resources))
+	for name := range t.Resources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var order []string
+	// 0 is unvisited, 1 is being visited and 2 is done.
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("Circular dependency between resources: [%s]", strings.Join(append(path, name), ", "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		dependsOn := slices.Clone(dependencies[name])
+		slices.Sort(dependsOn)
+		for _, dependency := range dependsOn {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+var subVariableRegex = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// findReferences returns the names referred to by Ref, Fn::GetAtt and Fn::Sub in a value.
+func findReferences(value any) []string {
+	var references []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			if len(v) == 1 {
+				switch {
+				case v["Ref"] != nil:
+					if name, ok := v["Ref"].(string); ok {
+						references = append(references, name)
+					}
+				case v["Fn::GetAtt"] != nil:
+					switch getAtt := v["Fn::GetAtt"].(type) {
+					case []any:
+						if len(getAtt) > 0 {
+							if name, ok := getAtt[0].(string); ok {
+								references = append(references, name)
+							}
+						}
+					case string:
+						name, _, _ := strings.Cut(getAtt, ".")
+						references = append(references, name)
+					}
+				case v["Fn::Sub"] != nil:
+					template := v["Fn::Sub"]
+					variables := map[string]any{}
+					if list, ok := template.([]any); ok && len(list) == 2 {
+						template = list[0]
+						variables, _ = list[1].(map[string]any)
+						walk(list[1])
+					}
+					if s, ok := template.(string); ok {
+						for _, match := range subVariableRegex.FindAllStringSubmatch(s, -1) {
+							name, _, _ := strings.Cut(match[1], ".")
+							if _, ok := variables[name]; !ok {
+								references = append(references, name)
+							}
+						}
+					}
+					return
+				}
+			}
+			for _, item := range v {
+				walk(item)
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(value)
+	return references
+}
+
+// formatScalar formats a string, number or boolean as a string.
+func formatScalar(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
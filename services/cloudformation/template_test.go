@@ -0,0 +1,201 @@
+package cloudformation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	document, err := parseYAML(`
+# A comment
+Description: "Queues: and tables"
+Parameters:
+  Env:
+    Type: String
+    AllowedValues: [dev, prod]
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: !Sub "${AWS::StackName}-queue"
+      DelaySeconds: 5
+      Enabled: true
+      Arn: !GetAtt Other.Arn
+      Joined: !Join
+        - ","
+        - - a
+          - !Ref Env
+      Script: |
+        line one
+        line two
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"Description": "Queues: and tables",
+		"Parameters": map[string]any{
+			"Env": map[string]any{
+				"Type":          "String",
+				"AllowedValues": []any{"dev", "prod"},
+			},
+		},
+		"Resources": map[string]any{
+			"Queue": map[string]any{
+				"Type": "AWS::SQS::Queue",
+				"Properties": map[string]any{
+					"QueueName":    map[string]any{"Fn::Sub": "${AWS::StackName}-queue"},
+					"DelaySeconds": float64(5),
+					"Enabled":      true,
+					"Arn":          map[string]any{"Fn::GetAtt": []any{"Other", "Arn"}},
+					"Joined":       map[string]any{"Fn::Join": []any{",", []any{"a", map[string]any{"Ref": "Env"}}}},
+					"Script":       "line one\nline two\n",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(document, want) {
+		t.Errorf("got %#v, want %#v", document, want)
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		body string
+		err  string
+	}{
+		{"no resources", `{"Resources": {}}`, "At least one Resources member must be defined."},
+		{"unsupported type", `{"Resources": {"F": {"Type": "AWS::Lambda::Function"}}}`, "Unrecognized resource types: [AWS::Lambda::Function]"},
+		{"unresolved reference", `{"Resources": {"Q": {"Type": "AWS::SQS::Queue", "Properties": {"QueueName": {"Ref": "Missing"}}}}}`, "Unresolved resource dependencies [Missing]"},
+		{"circular", `
+Resources:
+  A:
+    Type: AWS::SQS::Queue
+    DependsOn: B
+  B:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: !GetAtt A.QueueName
+`, "Circular dependency between resources: [A, B, A]"},
+		{"bad section", `{"Resources": {"Q": {"Type": "AWS::SQS::Queue"}}, "Extra": {}}`, "Invalid template property or properties [Extra]"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTemplate(test.body)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestIntrinsics(t *testing.T) {
+	template, err := parseTemplate(`
+Parameters:
+  Env:
+    Type: String
+    Default: dev
+  Zones:
+    Type: CommaDelimitedList
+    Default: "a, b"
+Mappings:
+  Sizes:
+    dev:
+      Shards: 1
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+  NotProd: !Not [!Condition IsProd]
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters, err := resolveParameters(template, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters["AWS::Region"] = "us-east-1"
+	parameters["AWS::StackName"] = "stack"
+	e := &evaluator{
+		template:   template,
+		parameters: parameters,
+		resources: map[string]*StackResource{
+			"Queue": {PhysicalId: "https://queue", attributes: map[string]string{"Arn": "arn:queue"}},
+		},
+		conditions: make(map[string]bool),
+	}
+
+	for _, test := range []struct {
+		value any
+		want  any
+	}{
+		{map[string]any{"Ref": "Env"}, "dev"},
+		{map[string]any{"Ref": "Queue"}, "https://queue"},
+		{map[string]any{"Fn::GetAtt": []any{"Queue", "Arn"}}, "arn:queue"},
+		{map[string]any{"Fn::Sub": "${AWS::StackName}-${Env}-${Queue.Arn}-${!Literal}"}, "stack-dev-arn:queue-${Literal}"},
+		{map[string]any{"Fn::Sub": []any{"${A}-${Env}", map[string]any{"A": "x"}}}, "x-dev"},
+		{map[string]any{"Fn::Join": []any{"-", []any{"a", map[string]any{"Ref": "Env"}, 1.0}}}, "a-dev-1"},
+		{map[string]any{"Fn::Select": []any{"1", map[string]any{"Ref": "Zones"}}}, "b"},
+		{map[string]any{"Fn::Split": []any{",", "x,y"}}, []any{"x", "y"}},
+		{map[string]any{"Fn::If": []any{"IsProd", "big", "small"}}, "small"},
+		{map[string]any{"Fn::If": []any{"NotProd", "small", map[string]any{"Ref": "AWS::NoValue"}}}, "small"},
+		{map[string]any{"Fn::FindInMap": []any{"Sizes", map[string]any{"Ref": "Env"}, "Shards"}}, 1.0},
+		{map[string]any{"Fn::Base64": "hi"}, "aGk="},
+		{map[string]any{"Fn::GetAZs": ""}, []any{"us-east-1a", "us-east-1b", "us-east-1c"}},
+		{map[string]any{"A": map[string]any{"Ref": "AWS::NoValue"}, "B": "b"}, map[string]any{"B": "b"}},
+	} {
+		got, err := e.resolve(test.value)
+		if err != nil {
+			t.Errorf("%v: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %#v, want %#v", test.value, got, test.want)
+		}
+	}
+
+	if _, err := e.resolve(map[string]any{"Fn::GetAtt": []any{"Queue", "Missing"}}); err == nil {
+		t.Error("expected an error for an unknown attribute")
+	}
+	if _, err := e.resolve(map[string]any{"Fn::ImportValue": "export"}); err == nil {
+		t.Error("expected an error for Fn::ImportValue")
+	}
+}
+
+func TestResolveParameters(t *testing.T) {
+	template, err := parseTemplate(`{
+		"Parameters": {
+			"Name": {"Type": "String", "AllowedPattern": "[a-z]+"},
+			"Count": {"Type": "Number", "Default": 1}
+		},
+		"Resources": {"Queue": {"Type": "AWS::SQS::Queue"}}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		inputs []APIParameter
+		err    string
+	}{
+		{nil, "Parameters: [Name] must have values"},
+		{[]APIParameter{{ParameterKey: "Name", ParameterValue: "ABC"}}, "Parameter 'Name' must match pattern [a-z]+"},
+		{[]APIParameter{{ParameterKey: "Name", ParameterValue: "abc"}, {ParameterKey: "Count", ParameterValue: "x"}}, "Parameter 'Count' must be a number."},
+		{[]APIParameter{{ParameterKey: "Other", ParameterValue: "abc"}}, "Parameters: [Other] do not exist in the template"},
+	} {
+		_, err := resolveParameters(template, test.inputs)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%v: got error %v, want %q", test.inputs, err, test.err)
+		}
+	}
+
+	values, err := resolveParameters(template, []APIParameter{{ParameterKey: "Name", ParameterValue: "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["Name"] != "abc" || values["Count"] != "1" {
+		t.Errorf("got %v", values)
+	}
+}
@@ -0,0 +1,319 @@
+package cloudformation
+
+type APIParameter struct {
+	ParameterKey     string `length:",255" error:"ValidationError"`
+	ParameterValue   string
+	UsePreviousValue bool
+}
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128" error:"ValidationError"`
+	Value string `required:"true" length:"1,256" error:"ValidationError"`
+}
+
+type CreateStackInput struct {
+	Capabilities                []string `enum:"CAPABILITY_IAM|CAPABILITY_NAMED_IAM|CAPABILITY_AUTO_EXPAND" error:"ValidationError"`
+	ClientRequestToken          string   `length:"1,128" pattern:"[a-zA-Z0-9][-a-zA-Z0-9]*" error:"ValidationError"`
+	DisableRollback             bool
+	EnableTerminationProtection bool
+	NotificationARNs            []string `length:",5" error:"ValidationError"`
+	OnFailure                   string   `enum:"DO_NOTHING|ROLLBACK|DELETE" error:"ValidationError"`
+	Parameters                  []APIParameter
+	RoleARN                     string   `length:"20,2048" error:"ValidationError"`
+	StackName                   string   `required:"true" length:"1,128" pattern:"[a-zA-Z][-a-zA-Z0-9]*" error:"ValidationError"`
+	Tags                        []APITag `length:",50" error:"ValidationError"`
+	TemplateBody                string   `length:"1," error:"ValidationError"`
+	TemplateURL                 string   `length:"1,1024" error:"ValidationError"`
+	TimeoutInMinutes            int      `range:"1," error:"ValidationError"`
+}
+
+type CreateStackOutput struct {
+	StackId string
+}
+
+type UpdateStackInput struct {
+	Capabilities        []string `enum:"CAPABILITY_IAM|CAPABILITY_NAMED_IAM|CAPABILITY_AUTO_EXPAND" error:"ValidationError"`
+	ClientRequestToken  string   `length:"1,128" pattern:"[a-zA-Z0-9][-a-zA-Z0-9]*" error:"ValidationError"`
+	DisableRollback     bool
+	NotificationARNs    []string `length:",5" error:"ValidationError"`
+	Parameters          []APIParameter
+	RoleARN             string   `length:"20,2048" error:"ValidationError"`
+	StackName           string   `required:"true" length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+	Tags                []APITag `length:",50" error:"ValidationError"`
+	TemplateBody        string   `length:"1," error:"ValidationError"`
+	TemplateURL         string   `length:"1,1024" error:"ValidationError"`
+	UsePreviousTemplate bool
+}
+
+type UpdateStackOutput struct {
+	StackId string
+}
+
+type DeleteStackInput struct {
+	ClientRequestToken string `length:"1,128" pattern:"[a-zA-Z0-9][-a-zA-Z0-9]*" error:"ValidationError"`
+	RetainResources    []string
+	RoleARN            string `length:"20,2048" error:"ValidationError"`
+	StackName          string `required:"true" length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+}
+
+type DeleteStackOutput struct{}
+
+type DescribeStacksInput struct {
+	NextToken string `length:"1,1024" error:"ValidationError"`
+	StackName string
+}
+
+type DescribeStacksOutput struct {
+	NextToken string
+	Stacks    []APIStack
+}
+
+type APIOutput struct {
+	Description string
+	ExportName  string
+	OutputKey   string
+	OutputValue string
+}
+
+type APIStackDriftInformation struct {
+	StackDriftStatus string
+}
+
+type APIStack struct {
+	Capabilities                []string
+	CreationTime                string
+	DeletionTime                string
+	Description                 string
+	DisableRollback             bool
+	DriftInformation            APIStackDriftInformation
+	EnableTerminationProtection bool
+	LastUpdatedTime             string
+	NotificationARNs            []string
+	Outputs                     []APIOutput
+	Parameters                  []APIParameter
+	StackId                     string
+	StackName                   string
+	StackStatus                 string
+	StackStatusReason           string
+	Tags                        []APITag
+}
+
+type DescribeStackEventsInput struct {
+	NextToken string `length:"1,1024" error:"ValidationError"`
+	StackName string
+}
+
+type DescribeStackEventsOutput struct {
+	NextToken   string
+	StackEvents []APIStackEvent
+}
+
+type APIStackEvent struct {
+	ClientRequestToken   string
+	EventId              string
+	LogicalResourceId    string
+	PhysicalResourceId   string
+	ResourceProperties   string
+	ResourceStatus       string
+	ResourceStatusReason string
+	ResourceType         string
+	StackId              string
+	StackName            string
+	Timestamp            string
+}
+
+type DescribeStackResourcesInput struct {
+	LogicalResourceId  string
+	PhysicalResourceId string
+	StackName          string
+}
+
+type DescribeStackResourcesOutput struct {
+	StackResources []APIStackResource
+}
+
+type APIStackResource struct {
+	LogicalResourceId    string
+	PhysicalResourceId   string
+	ResourceStatus       string
+	ResourceStatusReason string
+	ResourceType         string
+	StackId              string
+	StackName            string
+	Timestamp            string
+}
+
+type ListStackResourcesInput struct {
+	NextToken string `length:"1,1024" error:"ValidationError"`
+	StackName string `required:"true" length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+}
+
+type ListStackResourcesOutput struct {
+	NextToken              string
+	StackResourceSummaries []APIStackResourceSummary
+}
+
+type APIStackResourceSummary struct {
+	LastUpdatedTimestamp string
+	LogicalResourceId    string
+	PhysicalResourceId   string
+	ResourceStatus       string
+	ResourceStatusReason string
+	ResourceType         string
+}
+
+type ListStacksInput struct {
+	NextToken         string `length:"1,1024" error:"ValidationError"`
+	StackStatusFilter []string
+}
+
+type ListStacksOutput struct {
+	NextToken      string
+	StackSummaries []APIStackSummary
+}
+
+type APIStackSummary struct {
+	CreationTime        string
+	DeletionTime        string
+	DriftInformation    APIStackDriftInformation
+	LastUpdatedTime     string
+	StackId             string
+	StackName           string
+	StackStatus         string
+	StackStatusReason   string
+	TemplateDescription string
+}
+
+type GetTemplateInput struct {
+	ChangeSetName string `length:"1,1600" pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/]*" error:"ValidationError"`
+	StackName     string
+	TemplateStage string `enum:"Original|Processed" error:"ValidationError"`
+}
+
+type GetTemplateOutput struct {
+	StagesAvailable []string
+	TemplateBody    string
+}
+
+type APIResourceChange struct {
+	Action             string
+	LogicalResourceId  string
+	PhysicalResourceId string
+	Replacement        string
+	ResourceType       string
+}
+
+type APIChange struct {
+	ResourceChange APIResourceChange
+	Type           string
+}
+
+type CreateChangeSetInput struct {
+	Capabilities        []string `enum:"CAPABILITY_IAM|CAPABILITY_NAMED_IAM|CAPABILITY_AUTO_EXPAND" error:"ValidationError"`
+	ChangeSetName       string   `required:"true" length:"1,128" pattern:"[a-zA-Z][-a-zA-Z0-9]*" error:"ValidationError"`
+	ChangeSetType       string   `enum:"CREATE|UPDATE|IMPORT" error:"ValidationError"`
+	ClientToken         string   `length:"1,128" error:"ValidationError"`
+	Description         string   `length:"1,1024" error:"ValidationError"`
+	NotificationARNs    []string `length:",5" error:"ValidationError"`
+	OnStackFailure      string   `enum:"DO_NOTHING|ROLLBACK|DELETE" error:"ValidationError"`
+	Parameters          []APIParameter
+	RoleARN             string   `length:"20,2048" error:"ValidationError"`
+	StackName           string   `required:"true" length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+	Tags                []APITag `length:",50" error:"ValidationError"`
+	TemplateBody        string   `length:"1," error:"ValidationError"`
+	TemplateURL         string   `length:"1,1024" error:"ValidationError"`
+	UsePreviousTemplate bool
+}
+
+type CreateChangeSetOutput struct {
+	Id      string
+	StackId string
+}
+
+type DescribeChangeSetInput struct {
+	ChangeSetName string `required:"true" length:"1,1600" pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/]*" error:"ValidationError"`
+	NextToken     string `length:"1,1024" error:"ValidationError"`
+	StackName     string `length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+}
+
+type DescribeChangeSetOutput struct {
+	Capabilities     []string
+	ChangeSetId      string
+	ChangeSetName    string
+	Changes          []APIChange
+	CreationTime     string
+	Description      string
+	ExecutionStatus  string
+	NextToken        string
+	NotificationARNs []string
+	OnStackFailure   string
+	Parameters       []APIParameter
+	StackId          string
+	StackName        string
+	Status           string
+	StatusReason     string
+	Tags             []APITag
+}
+
+type ExecuteChangeSetInput struct {
+	ChangeSetName      string `required:"true" length:"1,1600" pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/]*" error:"ValidationError"`
+	ClientRequestToken string `length:"1,128" pattern:"[a-zA-Z0-9][-a-zA-Z0-9]*" error:"ValidationError"`
+	DisableRollback    bool
+	StackName          string `length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+}
+
+type ExecuteChangeSetOutput struct{}
+
+type DeleteChangeSetInput struct {
+	ChangeSetName string `required:"true" length:"1,1600" pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/]*" error:"ValidationError"`
+	StackName     string `length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+}
+
+type DeleteChangeSetOutput struct{}
+
+type ListChangeSetsInput struct {
+	NextToken string `length:"1,1024" error:"ValidationError"`
+	StackName string `required:"true" length:"1," pattern:"[a-zA-Z][-a-zA-Z0-9]*|arn:[-a-zA-Z0-9:/._+]*" error:"ValidationError"`
+}
+
+type ListChangeSetsOutput struct {
+	NextToken string
+	Summaries []APIChangeSetSummary
+}
+
+type APIChangeSetSummary struct {
+	ChangeSetId     string
+	ChangeSetName   string
+	CreationTime    string
+	Description     string
+	ExecutionStatus string
+	StackId         string
+	StackName       string
+	Status          string
+	StatusReason    string
+}
+
+type ListExportsInput struct {
+	NextToken string `length:"1,1024" error:"ValidationError"`
+}
+
+type ListExportsOutput struct {
+	Exports   []APIExport
+	NextToken string
+}
+
+type APIExport struct {
+	ExportingStackId string
+	Name             string
+	Value            string
+}
+
+type ListImportsInput struct {
+	ExportName string `required:"true" error:"ValidationError"`
+	NextToken  string `length:"1,1024" error:"ValidationError"`
+}
+
+type ListImportsOutput struct {
+	Imports   []string
+	NextToken string
+}
@@ -0,0 +1,39 @@
+package cloudformation
+
+import (
+	"fmt"
+	"strings"
+
+	"aws-in-a-box/yaml"
+)
+
+// parseYAML parses a YAML template. The short forms of intrinsic functions, such as !Ref, are
+// converted to their JSON forms.
+// See https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/template-formats.html
+func parseYAML(body string) (any, error) {
+	return yaml.Parse(body, applyTag)
+}
+
+// applyTag converts the short form of an intrinsic function to its full form.
+// See https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/intrinsic-function-reference.html
+func applyTag(tag string, value any) (any, error) {
+	name := strings.TrimPrefix(tag, "!")
+	switch name {
+	case "Ref", "Condition":
+		return map[string]any{name: value}, nil
+	case "GetAtt":
+		// The short form of GetAtt is a string, such as Resource.Attribute.
+		if s, ok := value.(string); ok {
+			resource, attribute, found := strings.Cut(s, ".")
+			if !found {
+				return nil, fmt.Errorf("invalid !GetAtt %q", s)
+			}
+			value = []any{resource, attribute}
+		}
+		return map[string]any{"Fn::GetAtt": value}, nil
+	case "Base64", "Cidr", "FindInMap", "GetAZs", "ImportValue", "Join", "Select", "Split", "Sub",
+		"Transform", "And", "Equals", "If", "Not", "Or", "ToJsonString", "Length":
+		return map[string]any{"Fn::" + name: value}, nil
+	}
+	return nil, fmt.Errorf("unsupported tag %s", tag)
+}
@@ -0,0 +1,393 @@
+package cloudwatch
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+)
+
+const (
+	maxMetricData = 1000
+	maxDimensions = 30
+	maxValues     = 150
+	// Datapoints can be put up to two weeks in the past and two hours in the future.
+	maxDatapointAge  = 14 * 24 * time.Hour
+	maxDatapointSkew = 2 * time.Hour
+
+	listMetricsPageSize = 500
+)
+
+var validUnits = []string{
+	"Seconds", "Microseconds", "Milliseconds",
+	"Bytes", "Kilobytes", "Megabytes", "Gigabytes", "Terabytes",
+	"Bits", "Kilobits", "Megabits", "Gigabits", "Terabits",
+	"Percent", "Count",
+	"Bytes/Second", "Kilobytes/Second", "Megabytes/Second", "Gigabytes/Second", "Terabytes/Second",
+	"Bits/Second", "Kilobits/Second", "Megabits/Second", "Gigabits/Second", "Terabits/Second",
+	"Count/Second", "None",
+}
+
+type statisticSet struct {
+	sampleCount float64
+	sum         float64
+	minimum     float64
+	maximum     float64
+}
+
+func (s *statisticSet) add(other statisticSet) {
+	if s.sampleCount == 0 {
+		*s = other
+		return
+	}
+	s.sampleCount += other.sampleCount
+	s.sum += other.sum
+	s.minimum = min(s.minimum, other.minimum)
+	s.maximum = max(s.maximum, other.maximum)
+}
+
+type Metric struct {
+	Namespace string
+	Name      string
+	// Sorted by name.
+	Dimensions []APIDimension
+	Unit       string
+	// Aggregated by minute, or by second for high resolution data, keyed by the Unix time the minute or second starts.
+	datapoints  map[int64]*statisticSet
+	lastUpdated time.Time
+}
+
+// unit returns the unit of the metric's latest data, or None.
+func (m *Metric) unit() string {
+	if m.Unit == "" {
+		return "None"
+	}
+	return m.Unit
+}
+
+func (m *Metric) toAPI() APIMetric {
+	return APIMetric{
+		Namespace:  m.Namespace,
+		MetricName: m.Name,
+		Dimensions: m.Dimensions,
+	}
+}
+
+// metricKey identifies the metric with the namespace, name and dimensions, which must be sorted.
+func metricKey(namespace string, name string, dimensions []APIDimension) string {
+	var b strings.Builder
+	b.WriteString(namespace)
+	b.WriteString("\n")
+	b.WriteString(name)
+	for _, dimension := range dimensions {
+		b.WriteString("\n")
+		b.WriteString(dimension.Name)
+		b.WriteString("=")
+		b.WriteString(dimension.Value)
+	}
+	return b.String()
+}
+
+func sortedDimensions(dimensions []APIDimension) []APIDimension {
+	sorted := slices.Clone(dimensions)
+	slices.SortFunc(sorted, func(a, b APIDimension) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return sorted
+}
+
+type CloudWatch struct {
+	logger *slog.Logger
+	// Overridden in tests.
+	clock func() time.Time
+	// Shared by the instances of all regions, which each have their own metrics.
+	regions *region.Regions[*CloudWatch]
+
+	mu           sync.Mutex
+	metricsByKey map[string]*Metric
+}
+
+type Options struct {
+	Logger *slog.Logger
+	// The region requests which don't say which region they're for are for.
+	Region string
+	// The regions other than Region which requests can be for, or region.Known if empty. Requests
+	// for others fail.
+	Regions []string
+}
+
+func New(options Options) *CloudWatch {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	c := newRegion(options)
+	c.regions = region.New(options.Region, options.Regions, c, func(name string) (*CloudWatch, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		r := newRegion(regionOptions)
+		r.regions = c.regions
+		return r, nil
+	})
+	return c
+}
+
+// newRegion returns the instance for a region.
+func newRegion(options Options) *CloudWatch {
+	return &CloudWatch{
+		logger:       options.Logger,
+		clock:        clock.Now,
+		metricsByKey: make(map[string]*Metric),
+	}
+}
+
+// InRegion returns the metrics of the region, for services which publish metrics about their
+// resources there. It fails if the region isn't one of Options.Regions.
+func (c *CloudWatch) InRegion(name string) (*CloudWatch, error) {
+	return c.regions.Get(name)
+}
+
+// lockedAddDatapoint adds the statistics to the metric, creating it if needed.
+func (c *CloudWatch) lockedAddDatapoint(namespace string, name string, dimensions []APIDimension, unit string,
+	timestamp time.Time, resolution int64, statistics statisticSet) {
+	dimensions = sortedDimensions(dimensions)
+	key := metricKey(namespace, name, dimensions)
+	metric, ok := c.metricsByKey[key]
+	if !ok {
+		metric = &Metric{
+			Namespace:  namespace,
+			Name:       name,
+			Dimensions: dimensions,
+			datapoints: make(map[int64]*statisticSet),
+		}
+		c.metricsByKey[key] = metric
+	}
+	if unit != "" && unit != "None" {
+		metric.Unit = unit
+	}
+	bucket := timestamp.Unix() - timestamp.Unix()%resolution
+	if metric.datapoints[bucket] == nil {
+		metric.datapoints[bucket] = &statisticSet{}
+	}
+	metric.datapoints[bucket].add(statistics)
+	metric.lastUpdated = c.clock()
+}
+
+// PutMetric records a value for the metric now. It's for services which publish their own metrics,
+// like Lambda's invocation counts, so unlike PutMetricData it allows the reserved AWS/ namespaces.
+func (c *CloudWatch) PutMetric(namespace string, metricName string, dimensions map[string]string, unit string, value float64) {
+	var apiDimensions []APIDimension
+	for name, value := range dimensions {
+		apiDimensions = append(apiDimensions, APIDimension{Name: name, Value: value})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lockedAddDatapoint(namespace, metricName, apiDimensions, unit, c.clock(), 60, statisticSet{
+		sampleCount: 1,
+		sum:         value,
+		minimum:     value,
+		maximum:     value,
+	})
+}
+
+// datumStatistics validates the datum and returns its value, values or statistic set as a statistic set.
+func datumStatistics(datum APIMetricDatum, prefix string) (statisticSet, *awserrors.Error) {
+	switch {
+	case datum.Value != nil && (datum.StatisticValues != nil || len(datum.Values) > 0):
+		return statisticSet{}, InvalidParameterCombinationException("The parameters " + prefix + ".Value, " +
+			prefix + ".StatisticValues and " + prefix + ".Values are mutually exclusive and you have specified more than one.")
+	case datum.StatisticValues != nil && len(datum.Values) > 0:
+		return statisticSet{}, InvalidParameterCombinationException("The parameters " + prefix + ".StatisticValues and " +
+			prefix + ".Values are mutually exclusive and you have specified both.")
+	case datum.Value != nil:
+		return statisticSet{sampleCount: 1, sum: *datum.Value, minimum: *datum.Value, maximum: *datum.Value}, nil
+	case datum.StatisticValues != nil:
+		s := datum.StatisticValues
+		if s.SampleCount <= 0 || s.Minimum > s.Maximum {
+			return statisticSet{}, InvalidParameterValueException("The values for parameter " + prefix + ".StatisticValues are invalid.")
+		}
+		return statisticSet{sampleCount: s.SampleCount, sum: s.Sum, minimum: s.Minimum, maximum: s.Maximum}, nil
+	case len(datum.Values) > 0:
+		if len(datum.Values) > maxValues {
+			return statisticSet{}, InvalidParameterValueException("The collection " + prefix + ".Values must not have a size greater than 150.")
+		}
+		if len(datum.Counts) > 0 && len(datum.Counts) != len(datum.Values) {
+			return statisticSet{}, InvalidParameterValueException("The collections " + prefix + ".Values and " + prefix + ".Counts must be of the same size.")
+		}
+		statistics := statisticSet{minimum: math.Inf(1), maximum: math.Inf(-1)}
+		for i, value := range datum.Values {
+			count := 1.0
+			if len(datum.Counts) > 0 {
+				count = datum.Counts[i]
+			}
+			if count <= 0 {
+				continue
+			}
+			statistics.sampleCount += count
+			statistics.sum += value * count
+			statistics.minimum = min(statistics.minimum, value)
+			statistics.maximum = max(statistics.maximum, value)
+		}
+		if statistics.sampleCount == 0 {
+			return statisticSet{}, InvalidParameterValueException("The values for parameter " + prefix + ".Counts are invalid.")
+		}
+		return statistics, nil
+	default:
+		return statisticSet{}, MissingRequiredParameterException("The parameter " + prefix + ".Value is required.")
+	}
+}
+
+// validateDimensions checks the dimensions in the named parameter.
+func validateDimensions(dimensions []APIDimension, name string) *awserrors.Error {
+	if len(dimensions) > maxDimensions {
+		return InvalidParameterValueException("The collection " + name + " must not have a size greater than 30.")
+	}
+	seen := make(map[string]bool)
+	for _, dimension := range dimensions {
+		if len(dimension.Name) < 1 || len(dimension.Name) > 255 || len(dimension.Value) < 1 || len(dimension.Value) > 1024 {
+			return InvalidParameterValueException("The dimension names and values in " + name + " are invalid.")
+		}
+		if seen[dimension.Name] {
+			return InvalidParameterValueException("The collection " + name + " must not contain duplicate dimension names.")
+		}
+		seen[dimension.Name] = true
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_PutMetricData.html
+func (c *CloudWatch) PutMetricData(input PutMetricDataInput) (*PutMetricDataOutput, *awserrors.Error) {
+	if input.Namespace == "" {
+		return nil, MissingRequiredParameterException("The parameter Namespace is required.")
+	}
+	if strings.HasPrefix(input.Namespace, "AWS/") {
+		return nil, InvalidParameterValueException("The value " + input.Namespace + " for parameter Namespace is invalid.")
+	}
+	if len(input.MetricData) == 0 {
+		return nil, MissingRequiredParameterException("The parameter MetricData is required.")
+	}
+	if len(input.MetricData) > maxMetricData {
+		return nil, InvalidParameterValueException("The collection MetricData must not have a size greater than 1000.")
+	}
+
+	now := c.clock()
+	type datapoint struct {
+		timestamp  time.Time
+		resolution int64
+		statistics statisticSet
+	}
+	datapoints := make([]datapoint, len(input.MetricData))
+	for i, datum := range input.MetricData {
+		prefix := "MetricData.member." + strconv.Itoa(i+1)
+		if len(datum.MetricName) < 1 || len(datum.MetricName) > 255 {
+			return nil, InvalidParameterValueException("The parameter " + prefix + ".MetricName must be between 1 and 255 characters.")
+		}
+		if awserr := validateDimensions(datum.Dimensions, prefix+".Dimensions"); awserr != nil {
+			return nil, awserr
+		}
+		if datum.Unit != "" && !slices.Contains(validUnits, datum.Unit) {
+			return nil, InvalidParameterValueException("The value " + datum.Unit + " for parameter " + prefix + ".Unit is invalid.")
+		}
+		statistics, awserr := datumStatistics(datum, prefix)
+		if awserr != nil {
+			return nil, awserr
+		}
+
+		resolution := int64(60)
+		switch datum.StorageResolution {
+		case 0, 60:
+		case 1:
+			resolution = 1
+		default:
+			return nil, InvalidParameterValueException("The value " + strconv.Itoa(int(datum.StorageResolution)) +
+				" for parameter " + prefix + ".StorageResolution is invalid. Valid values are 1 and 60.")
+		}
+
+		timestamp := now
+		if datum.Timestamp != 0 {
+			timestamp = time.UnixMilli(int64(datum.Timestamp * 1000))
+			if timestamp.Before(now.Add(-maxDatapointAge)) || timestamp.After(now.Add(maxDatapointSkew)) {
+				return nil, InvalidParameterValueException("The parameter " + prefix + ".Timestamp must specify a time no more than two weeks in the past and two hours in the future.")
+			}
+		}
+		datapoints[i] = datapoint{timestamp, resolution, statistics}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, datum := range input.MetricData {
+		c.lockedAddDatapoint(input.Namespace, datum.MetricName, datum.Dimensions, datum.Unit,
+			datapoints[i].timestamp, datapoints[i].resolution, datapoints[i].statistics)
+	}
+	return &PutMetricDataOutput{}, nil
+}
+
+// matchesDimensions returns whether the metric has all of the filters' dimensions, and their values if given.
+func (m *Metric) matchesDimensions(filters []APIDimensionFilter) bool {
+	for _, filter := range filters {
+		i := slices.IndexFunc(m.Dimensions, func(d APIDimension) bool { return d.Name == filter.Name })
+		if i == -1 || (filter.Value != "" && m.Dimensions[i].Value != filter.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_ListMetrics.html
+func (c *CloudWatch) ListMetrics(input ListMetricsInput) (*ListMetricsOutput, *awserrors.Error) {
+	if len(input.Dimensions) > 10 {
+		return nil, InvalidParameterValueException("The collection Dimensions must not have a size greater than 10.")
+	}
+	if input.RecentlyActive != "" && input.RecentlyActive != "PT3H" {
+		return nil, InvalidParameterValueException("The value " + input.RecentlyActive + " for parameter RecentlyActive is invalid.")
+	}
+	// Pages are always the same size, since there's no MaxResults.
+	limit, start, awserr := pagination.Parse(0, listMetricsPageSize, listMetricsPageSize, input.NextToken,
+		nil, InvalidNextToken("The service couldn't process your request because the NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock()
+	var keys []string
+	for key, metric := range c.metricsByKey {
+		if input.Namespace != "" && metric.Namespace != input.Namespace {
+			continue
+		}
+		if input.MetricName != "" && metric.Name != input.MetricName {
+			continue
+		}
+		if !metric.matchesDimensions(input.Dimensions) {
+			continue
+		}
+		if input.RecentlyActive != "" && metric.lastUpdated.Before(now.Add(-3*time.Hour)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	page, nextToken := pagination.Page(keys, limit, start)
+	output := &ListMetricsOutput{
+		Metrics:   []APIMetric{},
+		NextToken: nextToken,
+	}
+	for _, key := range page {
+		output.Metrics = append(output.Metrics, c.metricsByKey[key].toAPI())
+	}
+	return output, nil
+}
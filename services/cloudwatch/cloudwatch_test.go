@@ -0,0 +1,111 @@
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+)
+
+func newCloudWatch() *CloudWatch {
+	c := New(Options{})
+	now := time.Unix(1700000000, 0)
+	c.clock = func() time.Time { return now }
+	return c
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func putMetricData(t *testing.T, c *CloudWatch, namespace string, data ...APIMetricDatum) {
+	_, awserr := c.PutMetricData(PutMetricDataInput{Namespace: namespace, MetricData: data})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestPutMetricData(t *testing.T) {
+	c := newCloudWatch()
+	now := float64(c.clock().Unix())
+
+	for _, tc := range []struct {
+		name      string
+		namespace string
+		datum     APIMetricDatum
+		errorType string
+	}{
+		{"reserved namespace", "AWS/Lambda", APIMetricDatum{MetricName: "m", Value: ptr(1.0)}, "InvalidParameterValueException"},
+		{"missing value", "App", APIMetricDatum{MetricName: "m"}, "MissingRequiredParameterException"},
+		{"value and values", "App", APIMetricDatum{MetricName: "m", Value: ptr(1.0), Values: []float64{1}}, "InvalidParameterCombinationException"},
+		{"counts mismatch", "App", APIMetricDatum{MetricName: "m", Values: []float64{1, 2}, Counts: []float64{1}}, "InvalidParameterValueException"},
+		{"invalid unit", "App", APIMetricDatum{MetricName: "m", Value: ptr(1.0), Unit: "Furlongs"}, "InvalidParameterValueException"},
+		{"invalid resolution", "App", APIMetricDatum{MetricName: "m", Value: ptr(1.0), StorageResolution: 30}, "InvalidParameterValueException"},
+		{"too old", "App", APIMetricDatum{MetricName: "m", Value: ptr(1.0), Timestamp: now - 15*24*3600}, "InvalidParameterValueException"},
+		{"duplicate dimensions", "App", APIMetricDatum{MetricName: "m", Value: ptr(1.0), Dimensions: []APIDimension{
+			{Name: "a", Value: "1"}, {Name: "a", Value: "2"},
+		}}, "InvalidParameterValueException"},
+	} {
+		_, awserr := c.PutMetricData(PutMetricDataInput{Namespace: tc.namespace, MetricData: []APIMetricDatum{tc.datum}})
+		if awserr == nil || awserr.Body.Type != tc.errorType {
+			t.Error(tc.name, "expected", tc.errorType, awserr)
+		}
+	}
+}
+
+func TestListMetrics(t *testing.T) {
+	c := newCloudWatch()
+	putMetricData(t, c, "App",
+		APIMetricDatum{MetricName: "Latency", Value: ptr(1.0), Dimensions: []APIDimension{
+			{Name: "Service", Value: "api"}, {Name: "Host", Value: "a"},
+		}},
+		APIMetricDatum{MetricName: "Latency", Value: ptr(2.0), Dimensions: []APIDimension{
+			{Name: "Host", Value: "b"}, {Name: "Service", Value: "api"},
+		}},
+		APIMetricDatum{MetricName: "Requests", Value: ptr(3.0)},
+	)
+	c.PutMetric("AWS/Lambda", "Invocations", map[string]string{"FunctionName": "fn"}, "Count", 1)
+
+	output, awserr := c.ListMetrics(ListMetricsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Metrics) != 4 {
+		t.Fatal("Unexpected metrics", output.Metrics)
+	}
+
+	output, awserr = c.ListMetrics(ListMetricsInput{
+		Namespace:  "App",
+		MetricName: "Latency",
+		Dimensions: []APIDimensionFilter{{Name: "Host", Value: "b"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Metrics) != 1 {
+		t.Fatal("Unexpected metrics", output.Metrics)
+	}
+	// Dimensions are sorted by name.
+	dimensions := output.Metrics[0].Dimensions
+	if len(dimensions) != 2 || dimensions[0] != (APIDimension{Name: "Host", Value: "b"}) || dimensions[1] != (APIDimension{Name: "Service", Value: "api"}) {
+		t.Fatal("Unexpected dimensions", dimensions)
+	}
+
+	output, awserr = c.ListMetrics(ListMetricsInput{Dimensions: []APIDimensionFilter{{Name: "Service"}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Metrics) != 2 {
+		t.Fatal("Unexpected metrics", output.Metrics)
+	}
+
+	// Metrics without data in the last three hours aren't recently active.
+	later := c.clock().Add(4 * time.Hour)
+	c.clock = func() time.Time { return later }
+	c.PutMetric("AWS/Lambda", "Invocations", map[string]string{"FunctionName": "fn"}, "Count", 1)
+	output, awserr = c.ListMetrics(ListMetricsInput{RecentlyActive: "PT3H"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Metrics) != 1 || output.Metrics[0].Namespace != "AWS/Lambda" {
+		t.Fatal("Unexpected metrics", output.Metrics)
+	}
+}
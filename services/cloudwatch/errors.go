@@ -0,0 +1,19 @@
+package cloudwatch
+
+import "aws-in-a-box/awserrors"
+
+func InvalidNextToken(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidNextToken", message)
+}
+
+func InvalidParameterCombinationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterCombinationException", message)
+}
+
+func InvalidParameterValueException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterValueException", message)
+}
+
+func MissingRequiredParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("MissingRequiredParameterException", message)
+}
@@ -0,0 +1,18 @@
+package cloudwatch
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+// CloudWatch also supports the Query and RPCv2 CBOR protocols, but only the JSON protocol is emulated.
+const service = "GraniteServiceVersion20100801"
+
+func (c *CloudWatch) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetMetricData", region.Method(c.regions, (*CloudWatch).GetMetricData))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetMetricStatistics", region.Method(c.regions, (*CloudWatch).GetMetricStatistics))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListMetrics", region.Method(c.regions, (*CloudWatch).ListMetrics))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutMetricData", region.Method(c.regions, (*CloudWatch).PutMetricData))
+}
@@ -0,0 +1,120 @@
+package cloudwatch
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type datapointState struct {
+	// The Unix time the minute or second starts.
+	Timestamp   int64
+	SampleCount float64
+	Sum         float64
+	Minimum     float64
+	Maximum     float64
+}
+
+type metricState struct {
+	Namespace   string
+	Name        string
+	Dimensions  []APIDimension
+	Unit        string
+	LastUpdated time.Time
+	// Sorted by timestamp.
+	Datapoints []datapointState
+}
+
+type metricsState struct {
+	Metrics []metricState
+}
+
+// TryPause pauses changes to each region's metrics while a snapshot copies them.
+func (c *CloudWatch) TryPause() (func(), bool) {
+	return c.regions.TryPause(func(c *CloudWatch) *sync.Mutex { return &c.mu })
+}
+
+// ExportState writes each region's metrics, with their aggregated datapoints, to the archive.
+func (c *CloudWatch) ExportState(w *state.Writer) error {
+	return c.regions.ExportState(w, (*CloudWatch).exportState)
+}
+
+func (c *CloudWatch) exportState(w *state.Writer) error {
+	unlock := w.Lock(&c.mu)
+	defer unlock()
+
+	var exported metricsState
+	for _, metric := range c.metricsByKey {
+		m := metricState{
+			Namespace:   metric.Namespace,
+			Name:        metric.Name,
+			Dimensions:  metric.Dimensions,
+			Unit:        metric.Unit,
+			LastUpdated: metric.lastUpdated,
+		}
+		for timestamp, statistics := range metric.datapoints {
+			m.Datapoints = append(m.Datapoints, datapointState{
+				Timestamp:   timestamp,
+				SampleCount: statistics.sampleCount,
+				Sum:         statistics.sum,
+				Minimum:     statistics.minimum,
+				Maximum:     statistics.maximum,
+			})
+		}
+		slices.SortFunc(m.Datapoints, func(a, b datapointState) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		exported.Metrics = append(exported.Metrics, m)
+	}
+	slices.SortFunc(exported.Metrics, func(a, b metricState) int {
+		return strings.Compare(metricKey(a.Namespace, a.Name, a.Dimensions), metricKey(b.Namespace, b.Name, b.Dimensions))
+	})
+	return w.WriteJSON("metrics.json", exported)
+}
+
+// ImportState replaces each region's metrics with the archive's.
+func (c *CloudWatch) ImportState(r *state.Reader) (func() error, error) {
+	return c.regions.ImportState(r, (*CloudWatch).importState, (*CloudWatch).exportState)
+}
+
+func (c *CloudWatch) importState(r *state.Reader) (func() error, error) {
+	var imported metricsState
+	if err := r.ReadJSON("metrics.json", &imported); err != nil {
+		return nil, err
+	}
+	metricsByKey := make(map[string]*Metric)
+	for _, m := range imported.Metrics {
+		if m.Namespace == "" || m.Name == "" {
+			return nil, fmt.Errorf("metric %q in namespace %q has no name or namespace", m.Name, m.Namespace)
+		}
+		metric := &Metric{
+			Namespace:   m.Namespace,
+			Name:        m.Name,
+			Dimensions:  sortedDimensions(m.Dimensions),
+			Unit:        m.Unit,
+			datapoints:  make(map[int64]*statisticSet),
+			lastUpdated: m.LastUpdated,
+		}
+		for _, datapoint := range m.Datapoints {
+			metric.datapoints[datapoint.Timestamp] = &statisticSet{
+				sampleCount: datapoint.SampleCount,
+				sum:         datapoint.Sum,
+				minimum:     datapoint.Minimum,
+				maximum:     datapoint.Maximum,
+			}
+		}
+		metricsByKey[metricKey(metric.Namespace, metric.Name, metric.Dimensions)] = metric
+	}
+
+	return func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.metricsByKey = metricsByKey
+		return nil
+	}, nil
+}
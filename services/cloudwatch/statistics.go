@@ -0,0 +1,244 @@
+package cloudwatch
+
+import (
+	"cmp"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"aws-in-a-box/awserrors"
+)
+
+const (
+	maxStatisticsDatapoints = 1440
+	maxMetricDataQueries    = 500
+)
+
+var validStatistics = []string{"SampleCount", "Average", "Sum", "Minimum", "Maximum"}
+
+var metricDataQueryId = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+
+func (s *statisticSet) statistic(name string) float64 {
+	switch name {
+	case "SampleCount":
+		return s.sampleCount
+	case "Average":
+		return s.sum / s.sampleCount
+	case "Sum":
+		return s.sum
+	case "Minimum":
+		return s.minimum
+	case "Maximum":
+		return s.maximum
+	default:
+		panic("unknown statistic " + name)
+	}
+}
+
+type periodStatistics struct {
+	// Unix seconds.
+	timestamp  int64
+	statistics statisticSet
+}
+
+// aggregate returns the metric's statistics for each period in [start, end) with data, in timestamp order.
+// Periods are aligned to the start time.
+func (m *Metric) aggregate(start int64, end int64, period int64) []periodStatistics {
+	byPeriod := make(map[int64]*statisticSet)
+	for timestamp, statistics := range m.datapoints {
+		if timestamp < start || timestamp >= end {
+			continue
+		}
+		periodStart := start + (timestamp-start)/period*period
+		if byPeriod[periodStart] == nil {
+			byPeriod[periodStart] = &statisticSet{}
+		}
+		byPeriod[periodStart].add(*statistics)
+	}
+	periods := make([]periodStatistics, 0, len(byPeriod))
+	for timestamp, statistics := range byPeriod {
+		periods = append(periods, periodStatistics{timestamp, *statistics})
+	}
+	slices.SortFunc(periods, func(a, b periodStatistics) int {
+		return cmp.Compare(a.timestamp, b.timestamp)
+	})
+	return periods
+}
+
+// validatePeriod checks the period is 1, 5, 10, 30 or a multiple of 60 seconds.
+func validatePeriod(period int32, name string) *awserrors.Error {
+	if period < 1 || (period%60 != 0 && !slices.Contains([]int32{1, 5, 10, 30}, period)) {
+		return InvalidParameterValueException("The parameter " + name + " must be 1, 5, 10, 30 or a multiple of 60.")
+	}
+	return nil
+}
+
+// timeRange converts the epoch second start and end times to whole seconds.
+func timeRange(startTime float64, endTime float64) (int64, int64, *awserrors.Error) {
+	if startTime == 0 {
+		return 0, 0, MissingRequiredParameterException("The parameter StartTime is required.")
+	}
+	if endTime == 0 {
+		return 0, 0, MissingRequiredParameterException("The parameter EndTime is required.")
+	}
+	start, end := int64(math.Floor(startTime)), int64(math.Ceil(endTime))
+	if start >= end {
+		return 0, 0, InvalidParameterValueException("The parameter StartTime must be less than the parameter EndTime.")
+	}
+	return start, end, nil
+}
+
+// lockedGetMetric returns the metric, or nil if there's no data for it.
+func (c *CloudWatch) lockedGetMetric(namespace string, name string, dimensions []APIDimension) *Metric {
+	return c.metricsByKey[metricKey(namespace, name, sortedDimensions(dimensions))]
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricStatistics.html
+func (c *CloudWatch) GetMetricStatistics(input GetMetricStatisticsInput) (*GetMetricStatisticsOutput, *awserrors.Error) {
+	if input.Namespace == "" {
+		return nil, MissingRequiredParameterException("The parameter Namespace is required.")
+	}
+	if input.MetricName == "" {
+		return nil, MissingRequiredParameterException("The parameter MetricName is required.")
+	}
+	if awserr := validateDimensions(input.Dimensions, "Dimensions"); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validatePeriod(input.Period, "Period"); awserr != nil {
+		return nil, awserr
+	}
+	start, end, awserr := timeRange(input.StartTime, input.EndTime)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(input.ExtendedStatistics) > 0 {
+		return nil, InvalidParameterValueException("Extended statistics aren't supported.")
+	}
+	if len(input.Statistics) == 0 {
+		return nil, MissingRequiredParameterException("Must specify either Statistics or ExtendedStatistics.")
+	}
+	for _, statistic := range input.Statistics {
+		if !slices.Contains(validStatistics, statistic) {
+			return nil, InvalidParameterValueException("The value " + statistic + " for parameter Statistics is invalid.")
+		}
+	}
+	period := int64(input.Period)
+	if datapoints := (end - start + period - 1) / period; datapoints > maxStatisticsDatapoints {
+		return nil, InvalidParameterCombinationException("You have requested up to " + strconv.FormatInt(datapoints, 10) +
+			" datapoints, which exceeds the limit of 1,440. You may reduce the datapoints requested by increasing Period, or decreasing the time range.")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &GetMetricStatisticsOutput{
+		Label:      input.MetricName,
+		Datapoints: []APIDatapoint{},
+	}
+	metric := c.lockedGetMetric(input.Namespace, input.MetricName, input.Dimensions)
+	if metric == nil || (input.Unit != "" && input.Unit != metric.unit()) {
+		return output, nil
+	}
+	for _, period := range metric.aggregate(start, end, period) {
+		datapoint := APIDatapoint{
+			Timestamp: float64(period.timestamp),
+			Unit:      metric.unit(),
+		}
+		for _, statistic := range input.Statistics {
+			value := period.statistics.statistic(statistic)
+			switch statistic {
+			case "SampleCount":
+				datapoint.SampleCount = &value
+			case "Average":
+				datapoint.Average = &value
+			case "Sum":
+				datapoint.Sum = &value
+			case "Minimum":
+				datapoint.Minimum = &value
+			case "Maximum":
+				datapoint.Maximum = &value
+			}
+		}
+		output.Datapoints = append(output.Datapoints, datapoint)
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricData.html
+func (c *CloudWatch) GetMetricData(input GetMetricDataInput) (*GetMetricDataOutput, *awserrors.Error) {
+	if len(input.MetricDataQueries) == 0 {
+		return nil, MissingRequiredParameterException("The parameter MetricDataQueries is required.")
+	}
+	if len(input.MetricDataQueries) > maxMetricDataQueries {
+		return nil, InvalidParameterValueException("The collection MetricDataQueries must not have a size greater than 500.")
+	}
+	start, end, awserr := timeRange(input.StartTime, input.EndTime)
+	if awserr != nil {
+		return nil, awserr
+	}
+	switch input.ScanBy {
+	case "", "TimestampDescending", "TimestampAscending":
+	default:
+		return nil, InvalidParameterValueException("The value " + input.ScanBy + " for parameter ScanBy is invalid.")
+	}
+	ids := make(map[string]bool)
+	for i, query := range input.MetricDataQueries {
+		prefix := "MetricDataQueries.member." + strconv.Itoa(i+1)
+		if !metricDataQueryId.MatchString(query.Id) {
+			return nil, InvalidParameterValueException("The value " + query.Id + " for parameter " + prefix + ".Id is invalid. Ids must start with a lowercase letter.")
+		}
+		if ids[query.Id] {
+			return nil, InvalidParameterValueException("The value " + query.Id + " for parameter " + prefix + ".Id is duplicated.")
+		}
+		ids[query.Id] = true
+		if query.Expression != "" {
+			return nil, InvalidParameterValueException("Metric math expressions and Metrics Insights queries aren't supported.")
+		}
+		if query.MetricStat == nil {
+			return nil, MissingRequiredParameterException("The parameter " + prefix + ".MetricStat is required.")
+		}
+		if awserr := validatePeriod(query.MetricStat.Period, prefix+".MetricStat.Period"); awserr != nil {
+			return nil, awserr
+		}
+		if !slices.Contains(validStatistics, query.MetricStat.Stat) {
+			return nil, InvalidParameterValueException("The value " + query.MetricStat.Stat + " for parameter " + prefix + ".MetricStat.Stat is invalid.")
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &GetMetricDataOutput{
+		MetricDataResults: []APIMetricDataResult{},
+	}
+	for _, query := range input.MetricDataQueries {
+		if query.ReturnData != nil && !*query.ReturnData {
+			continue
+		}
+		stat := query.MetricStat
+		result := APIMetricDataResult{
+			Id:         query.Id,
+			Label:      query.Label,
+			Timestamps: []float64{},
+			Values:     []float64{},
+			StatusCode: "Complete",
+		}
+		if result.Label == "" {
+			result.Label = stat.Metric.MetricName
+		}
+		metric := c.lockedGetMetric(stat.Metric.Namespace, stat.Metric.MetricName, stat.Metric.Dimensions)
+		if metric != nil && (stat.Unit == "" || stat.Unit == metric.unit()) {
+			periods := metric.aggregate(start, end, int64(stat.Period))
+			if input.ScanBy != "TimestampAscending" {
+				slices.Reverse(periods)
+			}
+			for _, period := range periods {
+				result.Timestamps = append(result.Timestamps, float64(period.timestamp))
+				result.Values = append(result.Values, period.statistics.statistic(stat.Stat))
+			}
+		}
+		output.MetricDataResults = append(output.MetricDataResults, result)
+	}
+	return output, nil
+}
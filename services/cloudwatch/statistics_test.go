@@ -0,0 +1,147 @@
+package cloudwatch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetMetricStatistics(t *testing.T) {
+	c := newCloudWatch()
+	// 1700000000 is 22:13:20, so the minutes start at ...40 and ...00.
+	start := float64(1699999980)
+	dimensions := []APIDimension{{Name: "Host", Value: "a"}}
+	putMetricData(t, c, "App",
+		APIMetricDatum{MetricName: "Latency", Dimensions: dimensions, Unit: "Milliseconds", Timestamp: start, Value: ptr(10.0)},
+		APIMetricDatum{MetricName: "Latency", Dimensions: dimensions, Unit: "Milliseconds", Timestamp: start + 30, Values: []float64{20, 30}, Counts: []float64{1, 2}},
+		APIMetricDatum{MetricName: "Latency", Dimensions: dimensions, Unit: "Milliseconds", Timestamp: start + 60, StatisticValues: &APIStatisticSet{
+			SampleCount: 2, Sum: 100, Minimum: 40, Maximum: 60,
+		}},
+		// Other dimensions are a different metric.
+		APIMetricDatum{MetricName: "Latency", Dimensions: []APIDimension{{Name: "Host", Value: "b"}}, Timestamp: start, Value: ptr(1000.0)},
+	)
+
+	input := GetMetricStatisticsInput{
+		Namespace:  "App",
+		MetricName: "Latency",
+		Dimensions: dimensions,
+		StartTime:  start,
+		EndTime:    start + 120,
+		Period:     60,
+		Statistics: []string{"SampleCount", "Average", "Sum", "Minimum", "Maximum"},
+	}
+	output, awserr := c.GetMetricStatistics(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.Label != "Latency" || len(output.Datapoints) != 2 {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	first, second := output.Datapoints[0], output.Datapoints[1]
+	if first.Timestamp != start || *first.SampleCount != 4 || *first.Sum != 90 || *first.Average != 22.5 ||
+		*first.Minimum != 10 || *first.Maximum != 30 || first.Unit != "Milliseconds" {
+		t.Fatalf("Unexpected first datapoint: %+v", first)
+	}
+	if second.Timestamp != start+60 || *second.SampleCount != 2 || *second.Average != 50 {
+		t.Fatalf("Unexpected second datapoint: %+v", second)
+	}
+
+	// Larger periods aggregate more data.
+	input.Period = 120
+	input.Statistics = []string{"Maximum"}
+	output, awserr = c.GetMetricStatistics(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Datapoints) != 1 || *output.Datapoints[0].Maximum != 60 || output.Datapoints[0].Sum != nil {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	// Data with a different unit doesn't match.
+	input.Unit = "Seconds"
+	output, awserr = c.GetMetricStatistics(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Datapoints) != 0 {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	input.Unit = ""
+	input.Period = 1
+	input.EndTime = start + 3600
+	_, awserr = c.GetMetricStatistics(input)
+	if awserr == nil || awserr.Body.Type != "InvalidParameterCombinationException" {
+		t.Fatal("Expected too many datapoints", awserr)
+	}
+	input.Period = 45
+	_, awserr = c.GetMetricStatistics(input)
+	if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+		t.Fatal("Expected invalid period", awserr)
+	}
+}
+
+func TestGetMetricData(t *testing.T) {
+	c := newCloudWatch()
+	start := float64(1699999980)
+	for i := 0; i < 3; i++ {
+		putMetricData(t, c, "App", APIMetricDatum{MetricName: "Requests", Timestamp: start + float64(60*i), Value: ptr(float64(i + 1))})
+	}
+
+	metric := APIMetric{Namespace: "App", MetricName: "Requests"}
+	output, awserr := c.GetMetricData(GetMetricDataInput{
+		StartTime: start,
+		EndTime:   start + 180,
+		MetricDataQueries: []APIMetricDataQuery{
+			{Id: "requests", MetricStat: &APIMetricStat{Metric: metric, Period: 60, Stat: "Sum"}},
+			{Id: "hidden", MetricStat: &APIMetricStat{Metric: metric, Period: 60, Stat: "Sum"}, ReturnData: ptr(false)},
+			{Id: "missing", Label: "Missing", MetricStat: &APIMetricStat{Metric: APIMetric{Namespace: "App", MetricName: "Other"}, Period: 60, Stat: "Sum"}},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.MetricDataResults) != 2 {
+		t.Fatalf("Unexpected results: %+v", output.MetricDataResults)
+	}
+	// The latest data comes first by default.
+	result := output.MetricDataResults[0]
+	if result.Id != "requests" || result.Label != "Requests" || result.StatusCode != "Complete" ||
+		!slices.Equal(result.Values, []float64{3, 2, 1}) ||
+		!slices.Equal(result.Timestamps, []float64{start + 120, start + 60, start}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	result = output.MetricDataResults[1]
+	if result.Id != "missing" || result.Label != "Missing" || len(result.Values) != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	output, awserr = c.GetMetricData(GetMetricDataInput{
+		StartTime: start,
+		EndTime:   start + 180,
+		ScanBy:    "TimestampAscending",
+		MetricDataQueries: []APIMetricDataQuery{
+			{Id: "total", MetricStat: &APIMetricStat{Metric: metric, Period: 180, Stat: "Sum"}},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(output.MetricDataResults[0].Values, []float64{6}) {
+		t.Fatalf("Unexpected result: %+v", output.MetricDataResults[0])
+	}
+
+	for _, query := range []APIMetricDataQuery{
+		{Id: "Upper", MetricStat: &APIMetricStat{Metric: metric, Period: 60, Stat: "Sum"}},
+		{Id: "expression", Expression: "SUM(METRICS())"},
+		{Id: "percentile", MetricStat: &APIMetricStat{Metric: metric, Period: 60, Stat: "p99"}},
+	} {
+		_, awserr := c.GetMetricData(GetMetricDataInput{
+			StartTime:         start,
+			EndTime:           start + 180,
+			MetricDataQueries: []APIMetricDataQuery{query},
+		})
+		if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+			t.Error("Expected invalid parameter", query.Id, awserr)
+		}
+	}
+}
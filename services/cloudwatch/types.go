@@ -0,0 +1,133 @@
+package cloudwatch
+
+type APIDimension struct {
+	Name  string `required:"true" length:"1,255" error:"InvalidParameterValueException"`
+	Value string `required:"true" length:"1,1024" error:"InvalidParameterValueException"`
+}
+
+type APIDimensionFilter struct {
+	Name  string `required:"true" length:"1,255" error:"InvalidParameterValueException"`
+	Value string `json:",omitempty" length:"1,1024" error:"InvalidParameterValueException"`
+}
+
+type APIStatisticSet struct {
+	SampleCount float64
+	Sum         float64
+	Minimum     float64
+	Maximum     float64
+}
+
+type APIMetricDatum struct {
+	MetricName string         `required:"true" error:"MissingRequiredParameterException"`
+	Dimensions []APIDimension `length:",30" error:"InvalidParameterValueException"`
+	// Epoch seconds.
+	Timestamp         float64
+	Value             *float64
+	StatisticValues   *APIStatisticSet
+	Values            []float64
+	Counts            []float64
+	Unit              string `enum:"Seconds|Microseconds|Milliseconds|Bytes|Kilobytes|Megabytes|Gigabytes|Terabytes|Bits|Kilobits|Megabits|Gigabits|Terabits|Percent|Count|Bytes/Second|Kilobytes/Second|Megabytes/Second|Gigabytes/Second|Terabytes/Second|Bits/Second|Kilobits/Second|Megabits/Second|Gigabits/Second|Terabits/Second|Count/Second|None" error:"InvalidParameterValueException"`
+	StorageResolution int32  `range:"1," error:"InvalidParameterValueException"`
+}
+
+type PutMetricDataInput struct {
+	Namespace              string           `required:"true" error:"MissingRequiredParameterException"`
+	MetricData             []APIMetricDatum `required:"true" error:"MissingRequiredParameterException"`
+	EntityMetricData       []any
+	StrictEntityValidation *bool
+}
+
+type PutMetricDataOutput struct{}
+
+type APIMetric struct {
+	Namespace  string         `length:"1,255" error:"InvalidParameterValueException"`
+	MetricName string         `length:"1,255" error:"InvalidParameterValueException"`
+	Dimensions []APIDimension `length:",30" error:"InvalidParameterValueException"`
+}
+
+type ListMetricsInput struct {
+	Namespace             string               `length:"1,255" error:"InvalidParameterValueException"`
+	MetricName            string               `length:"1,255" error:"InvalidParameterValueException"`
+	Dimensions            []APIDimensionFilter `length:",10" error:"InvalidParameterValueException"`
+	NextToken             string
+	RecentlyActive        string
+	IncludeLinkedAccounts bool
+	OwningAccount         string
+}
+
+type ListMetricsOutput struct {
+	Metrics   []APIMetric
+	NextToken string `json:",omitempty"`
+}
+
+type APIDatapoint struct {
+	// Epoch seconds.
+	Timestamp   float64
+	SampleCount *float64 `json:",omitempty"`
+	Average     *float64 `json:",omitempty"`
+	Sum         *float64 `json:",omitempty"`
+	Minimum     *float64 `json:",omitempty"`
+	Maximum     *float64 `json:",omitempty"`
+	Unit        string
+}
+
+type GetMetricStatisticsInput struct {
+	Namespace          string         `required:"true" error:"MissingRequiredParameterException"`
+	MetricName         string         `required:"true" error:"MissingRequiredParameterException"`
+	Dimensions         []APIDimension `length:",30" error:"InvalidParameterValueException"`
+	StartTime          float64
+	EndTime            float64
+	Period             int32    `required:"true" range:"1," error:"InvalidParameterValueException"`
+	Statistics         []string `length:"1,5" enum:"SampleCount|Average|Sum|Minimum|Maximum" error:"InvalidParameterValueException"`
+	ExtendedStatistics []string `length:"1,10" error:"InvalidParameterValueException"`
+	Unit               string   `enum:"Seconds|Microseconds|Milliseconds|Bytes|Kilobytes|Megabytes|Gigabytes|Terabytes|Bits|Kilobits|Megabits|Gigabits|Terabits|Percent|Count|Bytes/Second|Kilobytes/Second|Megabytes/Second|Gigabytes/Second|Terabytes/Second|Bits/Second|Kilobits/Second|Megabits/Second|Gigabits/Second|Terabits/Second|Count/Second|None" error:"InvalidParameterValueException"`
+}
+
+type GetMetricStatisticsOutput struct {
+	Label      string
+	Datapoints []APIDatapoint
+}
+
+type APIMetricStat struct {
+	Metric APIMetric `required:"true" error:"MissingRequiredParameterException"`
+	Period int32     `required:"true" range:"1," error:"InvalidParameterValueException"`
+	Stat   string    `required:"true" error:"MissingRequiredParameterException"`
+	Unit   string    `enum:"Seconds|Microseconds|Milliseconds|Bytes|Kilobytes|Megabytes|Gigabytes|Terabytes|Bits|Kilobits|Megabits|Gigabits|Terabits|Percent|Count|Bytes/Second|Kilobytes/Second|Megabytes/Second|Gigabytes/Second|Terabytes/Second|Bits/Second|Kilobits/Second|Megabits/Second|Gigabits/Second|Terabits/Second|Count/Second|None" error:"InvalidParameterValueException"`
+}
+
+type APIMetricDataQuery struct {
+	Id         string `required:"true" length:"1,255" error:"InvalidParameterValueException"`
+	MetricStat *APIMetricStat
+	Expression string `length:"1,2048" error:"InvalidParameterValueException"`
+	Label      string
+	ReturnData *bool
+	Period     int32  `range:"1," error:"InvalidParameterValueException"`
+	AccountId  string `length:"1,255" error:"InvalidParameterValueException"`
+}
+
+type APILabelOptions struct {
+	Timezone string
+}
+
+type GetMetricDataInput struct {
+	MetricDataQueries []APIMetricDataQuery `required:"true" error:"MissingRequiredParameterException"`
+	StartTime         float64
+	EndTime           float64
+	NextToken         string
+	ScanBy            string `enum:"TimestampDescending|TimestampAscending" error:"InvalidParameterValueException"`
+	MaxDatapoints     int32
+	LabelOptions      *APILabelOptions
+}
+
+type APIMetricDataResult struct {
+	Id         string
+	Label      string
+	Timestamps []float64
+	Values     []float64
+	StatusCode string
+}
+
+type GetMetricDataOutput struct {
+	MetricDataResults []APIMetricDataResult
+	NextToken         string `json:",omitempty"`
+}
@@ -0,0 +1,444 @@
+package cloudwatchlogs
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/kinesis"
+)
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/cloudwatch_limits_cwl.html
+
+const (
+	maxTags           = 50
+	defaultMaxResults = 50
+	maxMaxResults     = 50
+)
+
+var (
+	logGroupNameRegex  = regexp.MustCompile(`^[.\-_/#A-Za-z0-9]{1,512}$`)
+	logStreamNameRegex = regexp.MustCompile(`^[^:*]{1,512}$`)
+	logGroupClasses    = []string{"STANDARD", "INFREQUENT_ACCESS"}
+)
+
+type LogEvent struct {
+	// Unique across all streams, and increasing in the order events are put.
+	Id int64
+	// Milliseconds since the epoch.
+	Timestamp     int64
+	Message       string
+	IngestionTime int64
+}
+
+type LogStream struct {
+	Name         string
+	ARN          string
+	CreationTime time.Time
+	// Sorted by timestamp, and then in the order they were put.
+	Events            []LogEvent
+	LastIngestionTime int64
+	// Incremented by each PutLogEvents. Zero until events are first put.
+	sequenceNumber int64
+	// How many events have expired or been evicted, which offsets GetLogEvents tokens.
+	removedEvents int
+}
+
+// sequenceToken is the token the next PutLogEvents may pass, which is empty for new streams.
+func (s *LogStream) sequenceToken() string {
+	if s.sequenceNumber == 0 {
+		return ""
+	}
+	return strconv.FormatInt(s.sequenceNumber, 10)
+}
+
+func (s *LogStream) toAPI() APILogStream {
+	apiStream := APILogStream{
+		LogStreamName:       s.Name,
+		Arn:                 s.ARN,
+		CreationTime:        s.CreationTime.UnixMilli(),
+		LastIngestionTime:   s.LastIngestionTime,
+		UploadSequenceToken: s.sequenceToken(),
+	}
+	if len(s.Events) > 0 {
+		apiStream.FirstEventTimestamp = s.Events[0].Timestamp
+		apiStream.LastEventTimestamp = s.Events[len(s.Events)-1].Timestamp
+	}
+	return apiStream
+}
+
+type LogGroup struct {
+	Name          string
+	ARN           string
+	CreationTime  time.Time
+	KmsKeyId      string
+	LogGroupClass string
+	// Zero if events never expire.
+	RetentionInDays int32
+	Tags            map[string]string
+	Streams         map[string]*LogStream
+	// The total size of the messages in the group's streams.
+	StoredBytes         int64
+	SubscriptionFilters []*SubscriptionFilter
+}
+
+func (g *LogGroup) toAPI() APILogGroup {
+	return APILogGroup{
+		LogGroupName: g.Name,
+		// The ARN matches all of the group's streams.
+		Arn:             g.ARN + ":*",
+		LogGroupArn:     g.ARN,
+		CreationTime:    g.CreationTime.UnixMilli(),
+		StoredBytes:     g.StoredBytes,
+		RetentionInDays: g.RetentionInDays,
+		KmsKeyId:        g.KmsKeyId,
+		LogGroupClass:   g.LogGroupClass,
+	}
+}
+
+type CloudWatchLogs struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+	// Subscription filter destinations, which may be nil.
+	kinesis  *kinesis.Kinesis
+	firehose DeliveryStreams
+	lambda   LambdaInvoker
+	// Zero for no limit.
+	maxStoredBytes int64
+
+	mu              sync.Mutex
+	logGroupsByName map[string]*LogGroup
+	nextEventId     int64
+	queriesById     map[string]*Query
+	// Shared by the instances of all regions, which each have their own log groups and queries.
+	regions *region.Regions[*CloudWatchLogs]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	Kinesis      *kinesis.Kinesis
+	Firehose     DeliveryStreams
+	// How often to delete expired events. Zero to never delete them.
+	RetentionSweepInterval time.Duration
+	// When more than this many bytes of a region's messages are stored, the sweeper evicts the
+	// region's oldest events. Zero for no limit.
+	MaxStoredBytes int64
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *CloudWatchLogs {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Subscription filters' destinations are found by their ARNs, which say their regions, so every
+	// region delivers through the same services.
+	c := newRegion(options)
+	c.regions = region.New(options.ArnGenerator.Region, options.Regions, c, func(name string) (*CloudWatchLogs, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = c.regions
+		c.mu.Lock()
+		r.lambda = c.lambda
+		c.mu.Unlock()
+		return r, nil
+	})
+	if options.RetentionSweepInterval > 0 {
+		go func() {
+			for {
+				time.Sleep(options.RetentionSweepInterval)
+				for _, r := range c.regions.All() {
+					r.expireEvents()
+				}
+			}
+		}()
+	}
+	return c
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *CloudWatchLogs {
+	return &CloudWatchLogs{
+		logger:          options.Logger,
+		arnGenerator:    options.ArnGenerator,
+		clock:           clock.Now,
+		kinesis:         options.Kinesis,
+		firehose:        options.Firehose,
+		maxStoredBytes:  options.MaxStoredBytes,
+		logGroupsByName: make(map[string]*LogGroup),
+		queriesById:     make(map[string]*Query),
+	}
+}
+
+// InRegion returns the log groups and queries of the region, for services which write logs, like
+// Lambda's functions. It fails if the region isn't one of Options.Regions.
+func (c *CloudWatchLogs) InRegion(name string) (*CloudWatchLogs, error) {
+	return c.regions.Get(name)
+}
+
+// SetLambda enables subscription filters with Lambda function destinations in every region. It's set
+// after construction because Lambda itself depends on CloudWatch Logs to write functions' output.
+func (c *CloudWatchLogs) SetLambda(lambda LambdaInvoker) {
+	for _, r := range c.regions.All() {
+		r.mu.Lock()
+		r.lambda = lambda
+		r.mu.Unlock()
+	}
+}
+
+// lockedGetLogGroup returns the group by name or identifier, which may be the group's ARN.
+func (c *CloudWatchLogs) lockedGetLogGroup(name string, identifier string) (*LogGroup, *awserrors.Error) {
+	if name != "" && identifier != "" {
+		return nil, InvalidParameterException("LogGroup name and LogGroup ARN are mutually exclusive parameters.")
+	}
+	if identifier != "" {
+		name = identifier
+		if _, id, ok := strings.Cut(strings.TrimSuffix(identifier, ":*"), ":log-group:"); ok {
+			name = id
+		}
+	}
+	group, ok := c.logGroupsByName[name]
+	if !ok {
+		return nil, ResourceNotFoundException("The specified log group does not exist.")
+	}
+	return group, nil
+}
+
+func (c *CloudWatchLogs) lockedGetLogStream(groupName string, groupIdentifier string, streamName string) (*LogGroup, *LogStream, *awserrors.Error) {
+	group, awserr := c.lockedGetLogGroup(groupName, groupIdentifier)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	stream, ok := group.Streams[streamName]
+	if !ok {
+		return nil, nil, ResourceNotFoundException("The specified log stream does not exist.")
+	}
+	return group, stream, nil
+}
+
+func (c *CloudWatchLogs) lockedCreateLogGroup(input CreateLogGroupInput) (*LogGroup, *awserrors.Error) {
+	if !logGroupNameRegex.MatchString(input.LogGroupName) {
+		return nil, InvalidParameterException("Log group names must be between 1 and 512 characters, and only contain letters, numbers and ._-/#")
+	}
+	if _, ok := c.logGroupsByName[input.LogGroupName]; ok {
+		return nil, ResourceAlreadyExistsException("The specified log group already exists")
+	}
+	if input.LogGroupClass == "" {
+		input.LogGroupClass = "STANDARD"
+	}
+	if !slices.Contains(logGroupClasses, input.LogGroupClass) {
+		return nil, InvalidParameterException("Invalid logGroupClass: " + input.LogGroupClass)
+	}
+	if len(input.Tags) > maxTags {
+		return nil, InvalidParameterException("A log group can have at most 50 tags.")
+	}
+	for key := range input.Tags {
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return nil, InvalidParameterException("Tag keys can't start with aws:, which is reserved for AWS use.")
+		}
+	}
+
+	group := &LogGroup{
+		Name:          input.LogGroupName,
+		ARN:           c.arnGenerator.GenerateWithoutType("logs", "log-group:"+input.LogGroupName),
+		CreationTime:  c.clock(),
+		KmsKeyId:      input.KmsKeyId,
+		LogGroupClass: input.LogGroupClass,
+		Tags:          input.Tags,
+		Streams:       make(map[string]*LogStream),
+	}
+	c.logGroupsByName[group.Name] = group
+	return group, nil
+}
+
+func (c *CloudWatchLogs) lockedCreateLogStream(group *LogGroup, name string) (*LogStream, *awserrors.Error) {
+	if !logStreamNameRegex.MatchString(name) {
+		return nil, InvalidParameterException("Log stream names must be between 1 and 512 characters, and can't contain : or *")
+	}
+	if _, ok := group.Streams[name]; ok {
+		return nil, ResourceAlreadyExistsException("The specified log stream already exists")
+	}
+	stream := &LogStream{
+		Name:         name,
+		ARN:          group.ARN + ":log-stream:" + name,
+		CreationTime: c.clock(),
+	}
+	group.Streams[name] = stream
+	return stream, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_CreateLogGroup.html
+func (c *CloudWatchLogs) CreateLogGroup(input CreateLogGroupInput) (*CreateLogGroupOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, awserr := c.lockedCreateLogGroup(input); awserr != nil {
+		return nil, awserr
+	}
+	return &CreateLogGroupOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DeleteLogGroup.html
+func (c *CloudWatchLogs) DeleteLogGroup(input DeleteLogGroupInput) (*DeleteLogGroupOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(c.logGroupsByName, group.Name)
+	return &DeleteLogGroupOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DescribeLogGroups.html
+func (c *CloudWatchLogs) DescribeLogGroups(input DescribeLogGroupsInput) (*DescribeLogGroupsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, defaultMaxResults, maxMaxResults, input.NextToken,
+		InvalidParameterException("limit must be between 1 and 50."),
+		InvalidParameterException("The specified nextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.LogGroupNamePrefix != "" && input.LogGroupNamePattern != "" {
+		return nil, InvalidParameterException("LogGroup name prefix and LogGroup name pattern are mutually exclusive parameters.")
+	}
+	pattern := strings.ToLower(input.LogGroupNamePattern)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var groups []*LogGroup
+	for _, group := range c.logGroupsByName {
+		if !strings.HasPrefix(group.Name, input.LogGroupNamePrefix) ||
+			!strings.Contains(strings.ToLower(group.Name), pattern) {
+			continue
+		}
+		if input.LogGroupClass != "" && group.LogGroupClass != input.LogGroupClass {
+			continue
+		}
+		if len(input.LogGroupIdentifiers) > 0 &&
+			!slices.Contains(input.LogGroupIdentifiers, group.Name) && !slices.Contains(input.LogGroupIdentifiers, group.ARN) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	slices.SortFunc(groups, func(a, b *LogGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	output := &DescribeLogGroupsOutput{
+		LogGroups: []APILogGroup{},
+	}
+	for i := start; i < len(groups); i++ {
+		if len(output.LogGroups) == limit {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		output.LogGroups = append(output.LogGroups, groups[i].toAPI())
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_CreateLogStream.html
+func (c *CloudWatchLogs) CreateLogStream(input CreateLogStreamInput) (*CreateLogStreamOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, awserr := c.lockedCreateLogStream(group, input.LogStreamName); awserr != nil {
+		return nil, awserr
+	}
+	return &CreateLogStreamOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DeleteLogStream.html
+func (c *CloudWatchLogs) DeleteLogStream(input DeleteLogStreamInput) (*DeleteLogStreamOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, stream, awserr := c.lockedGetLogStream(input.LogGroupName, "", input.LogStreamName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, event := range stream.Events {
+		group.StoredBytes -= int64(len(event.Message))
+	}
+	delete(group.Streams, stream.Name)
+	return &DeleteLogStreamOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DescribeLogStreams.html
+func (c *CloudWatchLogs) DescribeLogStreams(input DescribeLogStreamsInput) (*DescribeLogStreamsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, defaultMaxResults, maxMaxResults, input.NextToken,
+		InvalidParameterException("limit must be between 1 and 50."),
+		InvalidParameterException("The specified nextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+	switch input.OrderBy {
+	case "", "LogStreamName":
+	case "LastEventTime":
+		if input.LogStreamNamePrefix != "" {
+			return nil, InvalidParameterException("Cannot order by LastEventTime with a logStreamNamePrefix.")
+		}
+	default:
+		return nil, InvalidParameterException("Invalid orderBy: " + input.OrderBy)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, input.LogGroupIdentifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var streams []APILogStream
+	for _, stream := range group.Streams {
+		if strings.HasPrefix(stream.Name, input.LogStreamNamePrefix) {
+			streams = append(streams, stream.toAPI())
+		}
+	}
+	slices.SortFunc(streams, func(a, b APILogStream) int {
+		if input.OrderBy == "LastEventTime" && a.LastEventTimestamp != b.LastEventTimestamp {
+			if a.LastEventTimestamp < b.LastEventTimestamp {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.LogStreamName, b.LogStreamName)
+	})
+	if input.Descending {
+		slices.Reverse(streams)
+	}
+
+	output := &DescribeLogStreamsOutput{
+		LogStreams: []APILogStream{},
+	}
+	for i := start; i < len(streams); i++ {
+		if len(output.LogStreams) == limit {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		output.LogStreams = append(output.LogStreams, streams[i])
+	}
+	return output, nil
+}
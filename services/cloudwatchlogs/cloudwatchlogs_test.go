@@ -0,0 +1,153 @@
+package cloudwatchlogs
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+func newCloudWatchLogs() *CloudWatchLogs {
+	c := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+	})
+	now := time.Unix(1700000000, 0)
+	c.clock = func() time.Time { return now }
+	return c
+}
+
+func createLogStream(t *testing.T, c *CloudWatchLogs, group string, stream string) {
+	if _, awserr := c.CreateLogGroup(CreateLogGroupInput{LogGroupName: group}); awserr != nil && awserr.Body.Type != "ResourceAlreadyExistsException" {
+		t.Fatal(awserr)
+	}
+	if _, awserr := c.CreateLogStream(CreateLogStreamInput{LogGroupName: group, LogStreamName: stream}); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestLogGroups(t *testing.T) {
+	c := newCloudWatchLogs()
+	for _, name := range []string{"/app/web", "/app/worker", "/aws/lambda/fn"} {
+		if _, awserr := c.CreateLogGroup(CreateLogGroupInput{LogGroupName: name}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	_, awserr := c.CreateLogGroup(CreateLogGroupInput{LogGroupName: "/app/web"})
+	if awserr == nil || awserr.Body.Type != "ResourceAlreadyExistsException" {
+		t.Fatal("Expected already exists", awserr)
+	}
+	_, awserr = c.CreateLogGroup(CreateLogGroupInput{LogGroupName: "bad name"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+
+	output, awserr := c.DescribeLogGroups(DescribeLogGroupsInput{LogGroupNamePrefix: "/app/", Limit: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.LogGroups) != 1 || output.LogGroups[0].LogGroupName != "/app/web" || output.NextToken == "" ||
+		output.LogGroups[0].Arn != "arn:aws:logs:us-east-1:123456789012:log-group:/app/web:*" ||
+		output.LogGroups[0].LogGroupClass != "STANDARD" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	output, awserr = c.DescribeLogGroups(DescribeLogGroupsInput{LogGroupNamePrefix: "/app/", NextToken: output.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.LogGroups) != 1 || output.LogGroups[0].LogGroupName != "/app/worker" || output.NextToken != "" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	output, awserr = c.DescribeLogGroups(DescribeLogGroupsInput{LogGroupNamePattern: "LAMBDA"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.LogGroups) != 1 || output.LogGroups[0].LogGroupName != "/aws/lambda/fn" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	if _, awserr := c.DeleteLogGroup(DeleteLogGroupInput{LogGroupName: "/app/web"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.DeleteLogGroup(DeleteLogGroupInput{LogGroupName: "/app/web"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestLogStreams(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "b")
+	createLogStream(t, c, "group", "a")
+	createLogStream(t, c, "group", "c")
+	_, awserr := c.CreateLogStream(CreateLogStreamInput{LogGroupName: "group", LogStreamName: "a"})
+	if awserr == nil || awserr.Body.Type != "ResourceAlreadyExistsException" {
+		t.Fatal("Expected already exists", awserr)
+	}
+	_, awserr = c.CreateLogStream(CreateLogStreamInput{LogGroupName: "group", LogStreamName: "a:b"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+	_, awserr = c.CreateLogStream(CreateLogStreamInput{LogGroupName: "missing", LogStreamName: "a"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+
+	now := c.clock().UnixMilli()
+	for _, stream := range []string{"c", "a"} {
+		_, awserr := c.PutLogEvents(PutLogEventsInput{
+			LogGroupName:  "group",
+			LogStreamName: stream,
+			LogEvents:     []APIInputLogEvent{{Timestamp: now, Message: "hello"}},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		now++
+	}
+
+	cases := []struct {
+		input    DescribeLogStreamsInput
+		expected []string
+	}{
+		{DescribeLogStreamsInput{LogGroupName: "group"}, []string{"a", "b", "c"}},
+		{DescribeLogStreamsInput{LogGroupName: "group", Descending: true}, []string{"c", "b", "a"}},
+		{DescribeLogStreamsInput{LogGroupName: "group", LogStreamNamePrefix: "b"}, []string{"b"}},
+		// Streams without events come first.
+		{DescribeLogStreamsInput{LogGroupName: "group", OrderBy: "LastEventTime"}, []string{"b", "c", "a"}},
+		{DescribeLogStreamsInput{LogGroupIdentifier: "arn:aws:logs:us-east-1:123456789012:log-group:group", Limit: 2}, []string{"a", "b"}},
+	}
+	for _, tc := range cases {
+		output, awserr := c.DescribeLogStreams(tc.input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		var names []string
+		for _, stream := range output.LogStreams {
+			names = append(names, stream.LogStreamName)
+		}
+		if !slices.Equal(names, tc.expected) {
+			t.Fatalf("Expected %v for %+v, got %v", tc.expected, tc.input, names)
+		}
+	}
+
+	_, awserr = c.DescribeLogStreams(DescribeLogStreamsInput{LogGroupName: "group", LogStreamNamePrefix: "a", OrderBy: "LastEventTime"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+
+	if _, awserr := c.DeleteLogStream(DeleteLogStreamInput{LogGroupName: "group", LogStreamName: "a"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	groups, _ := c.DescribeLogGroups(DescribeLogGroupsInput{})
+	if groups.LogGroups[0].StoredBytes != int64(len("hello")) {
+		t.Fatal("Unexpected stored bytes", groups.LogGroups[0].StoredBytes)
+	}
+	usage := c.MemoryUsage()
+	if len(usage) != 1 || usage[0].Name != "group" || usage[0].Bytes != int64(len("hello")) || usage[0].Count != 1 {
+		t.Fatalf("Unexpected memory usage %+v", usage)
+	}
+}
@@ -0,0 +1,36 @@
+package cloudwatchlogs
+
+import "aws-in-a-box/awserrors"
+
+func InvalidParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterException", message)
+}
+
+// InvalidSequenceTokenException is returned when PutLogEvents is called with an old sequence token.
+// Clients retry with the expected one.
+func InvalidSequenceTokenException(expectedSequenceToken string) *awserrors.Error {
+	expected := expectedSequenceToken
+	if expected == "" {
+		expected = "null"
+	}
+	awserr := awserrors.Generate400Exception("InvalidSequenceTokenException",
+		"The given sequenceToken is invalid. The next expected sequenceToken is: "+expected)
+	awserr.Body.ExpectedSequenceToken = expectedSequenceToken
+	return awserr
+}
+
+func LimitExceededException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("LimitExceededException", message)
+}
+
+func MalformedQueryException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("MalformedQueryException", message)
+}
+
+func ResourceAlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceAlreadyExistsException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
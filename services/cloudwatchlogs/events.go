@@ -0,0 +1,244 @@
+package cloudwatchlogs
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutLogEvents.html
+
+const (
+	maxBatchEvents = 10000
+	maxBatchSize   = 1024 * 1024
+	maxEventSize   = 256 * 1024
+	// Each event counts as its message's length plus this towards the batch size.
+	eventOverhead = 26
+	maxBatchSpan  = 24 * time.Hour
+	maxEventAge   = 14 * 24 * time.Hour
+	maxEventSkew  = 2 * time.Hour
+
+	maxGetLogEvents = 10000
+)
+
+func compareEvents(a, b LogEvent) int {
+	return cmp.Compare(a.Timestamp, b.Timestamp)
+}
+
+// lockedAddEvents adds the events, which must be sorted, to the stream.
+func (c *CloudWatchLogs) lockedAddEvents(group *LogGroup, stream *LogStream, events []APIInputLogEvent, now time.Time) {
+	if len(events) == 0 {
+		return
+	}
+	sorted := len(stream.Events) == 0 || stream.Events[len(stream.Events)-1].Timestamp <= events[0].Timestamp
+	added := make([]LogEvent, 0, len(events))
+	for _, event := range events {
+		c.nextEventId++
+		added = append(added, LogEvent{
+			Id:            c.nextEventId,
+			Timestamp:     event.Timestamp,
+			Message:       event.Message,
+			IngestionTime: now.UnixMilli(),
+		})
+		group.StoredBytes += int64(len(event.Message))
+	}
+	stream.Events = append(stream.Events, added...)
+	if !sorted {
+		slices.SortStableFunc(stream.Events, compareEvents)
+	}
+	stream.LastIngestionTime = now.UnixMilli()
+	c.lockedDeliverToSubscriptions(group, stream, added)
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutLogEvents.html
+func (c *CloudWatchLogs) PutLogEvents(input PutLogEventsInput) (*PutLogEventsOutput, *awserrors.Error) {
+	if len(input.LogEvents) < 1 || len(input.LogEvents) > maxBatchEvents {
+		return nil, InvalidParameterException("A batch must have between 1 and 10000 log events.")
+	}
+	batchSize := 0
+	for i, event := range input.LogEvents {
+		size := len(event.Message) + eventOverhead
+		if size > maxEventSize {
+			return nil, InvalidParameterException("Log event too large: " + strconv.Itoa(size) + " bytes exceeds limit of 262144")
+		}
+		batchSize += size
+		if i > 0 && event.Timestamp < input.LogEvents[i-1].Timestamp {
+			return nil, InvalidParameterException("Log events in a single PutLogEvents request must be in chronological order.")
+		}
+	}
+	if batchSize > maxBatchSize {
+		return nil, InvalidParameterException("Upload too large: " + strconv.Itoa(batchSize) + " bytes exceeds limit of 1048576")
+	}
+	first := input.LogEvents[0].Timestamp
+	last := input.LogEvents[len(input.LogEvents)-1].Timestamp
+	if time.Duration(last-first)*time.Millisecond > maxBatchSpan {
+		return nil, InvalidParameterException("The batch of log events in a single PutLogEvents request cannot span more than 24 hours.")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, stream, awserr := c.lockedGetLogStream(input.LogGroupName, "", input.LogStreamName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Sequence tokens are optional, but if one is passed it must be the latest.
+	if input.SequenceToken != "" && input.SequenceToken != stream.sequenceToken() {
+		return nil, InvalidSequenceTokenException(stream.sequenceToken())
+	}
+
+	// Events too far in the past or future are rejected, but the rest are accepted.
+	// Since the events are sorted, the old ones come first and the new ones last.
+	now := c.clock()
+	start := 0
+	for start < len(input.LogEvents) && input.LogEvents[start].Timestamp < now.Add(-maxEventAge).UnixMilli() {
+		start++
+	}
+	tooOld := start
+	// Events older than the group's retention period are also rejected.
+	if cutoff, ok := group.expiryCutoff(now); ok {
+		for start < len(input.LogEvents) && input.LogEvents[start].Timestamp < cutoff {
+			start++
+		}
+	}
+	end := len(input.LogEvents)
+	for end > start && input.LogEvents[end-1].Timestamp > now.Add(maxEventSkew).UnixMilli() {
+		end--
+	}
+
+	c.lockedAddEvents(group, stream, input.LogEvents[start:end], now)
+	stream.sequenceNumber++
+
+	output := &PutLogEventsOutput{
+		NextSequenceToken: stream.sequenceToken(),
+	}
+	if start > 0 || end < len(input.LogEvents) {
+		output.RejectedLogEventsInfo = &APIRejectedLogEventsInfo{}
+		if tooOld > 0 {
+			tooOldEnd := tooOld - 1
+			output.RejectedLogEventsInfo.TooOldLogEventEndIndex = &tooOldEnd
+		}
+		if start > tooOld {
+			expiredEnd := start - 1
+			output.RejectedLogEventsInfo.ExpiredLogEventEndIndex = &expiredEnd
+		}
+		if end < len(input.LogEvents) {
+			output.RejectedLogEventsInfo.TooNewLogEventStartIndex = &end
+		}
+	}
+	return output, nil
+}
+
+// WriteLogs writes messages to the stream as if they were logged now, creating the group and stream
+// if they don't exist. It's for services like Lambda which write logs automatically.
+func (c *CloudWatchLogs) WriteLogs(logGroupName string, logStreamName string, messages []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, ok := c.logGroupsByName[logGroupName]
+	if !ok {
+		var awserr *awserrors.Error
+		group, awserr = c.lockedCreateLogGroup(CreateLogGroupInput{LogGroupName: logGroupName})
+		if awserr != nil {
+			return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+		}
+	}
+	stream, ok := group.Streams[logStreamName]
+	if !ok {
+		var awserr *awserrors.Error
+		stream, awserr = c.lockedCreateLogStream(group, logStreamName)
+		if awserr != nil {
+			return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+		}
+	}
+
+	now := c.clock()
+	events := make([]APIInputLogEvent, 0, len(messages))
+	for _, message := range messages {
+		events = append(events, APIInputLogEvent{Timestamp: now.UnixMilli(), Message: message})
+	}
+	c.lockedAddEvents(group, stream, events, now)
+	return nil
+}
+
+// parseEventsToken parses GetLogEvents tokens, which are f/index to read forwards from the index,
+// or b/index to read backwards from it.
+func parseEventsToken(token string) (bool, int, bool) {
+	direction, index, ok := strings.Cut(token, "/")
+	if !ok || (direction != "f" && direction != "b") {
+		return false, 0, false
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 {
+		return false, 0, false
+	}
+	return direction == "f", i, true
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_GetLogEvents.html
+func (c *CloudWatchLogs) GetLogEvents(input GetLogEventsInput) (*GetLogEventsOutput, *awserrors.Error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = maxGetLogEvents
+	}
+	if limit < 1 || limit > maxGetLogEvents {
+		return nil, InvalidParameterException("limit must be between 1 and 10000.")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, stream, awserr := c.lockedGetLogStream(input.LogGroupName, input.LogGroupIdentifier, input.LogStreamName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Only events in [lo, hi) are in the time range.
+	lo, hi := 0, len(stream.Events)
+	if input.StartTime != nil {
+		lo, _ = slices.BinarySearchFunc(stream.Events, LogEvent{Timestamp: *input.StartTime}, compareEvents)
+	}
+	if input.EndTime != nil {
+		hi, _ = slices.BinarySearchFunc(stream.Events, LogEvent{Timestamp: *input.EndTime}, compareEvents)
+		hi = max(hi, lo)
+	}
+
+	forward, index := input.StartFromHead, hi
+	if forward {
+		index = lo
+	}
+	if input.NextToken != "" {
+		var ok bool
+		forward, index, ok = parseEventsToken(input.NextToken)
+		if !ok {
+			return nil, InvalidParameterException("The specified nextToken is invalid.")
+		}
+		// Tokens count expired events too, so they stay valid as events expire.
+		index = min(max(index-stream.removedEvents, lo), hi)
+	}
+
+	var start, end int
+	if forward {
+		start, end = index, min(index+limit, hi)
+	} else {
+		start, end = max(index-limit, lo), index
+	}
+	output := &GetLogEventsOutput{
+		Events:            []APIOutputLogEvent{},
+		NextForwardToken:  "f/" + strconv.Itoa(stream.removedEvents+end),
+		NextBackwardToken: "b/" + strconv.Itoa(stream.removedEvents+start),
+	}
+	for _, event := range stream.Events[start:end] {
+		output.Events = append(output.Events, APIOutputLogEvent{
+			Timestamp:     event.Timestamp,
+			Message:       event.Message,
+			IngestionTime: event.IngestionTime,
+		})
+	}
+	return output, nil
+}
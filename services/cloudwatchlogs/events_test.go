@@ -0,0 +1,165 @@
+package cloudwatchlogs
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func messages(output *GetLogEventsOutput) []string {
+	var messages []string
+	for _, event := range output.Events {
+		messages = append(messages, event.Message)
+	}
+	return messages
+}
+
+func TestPutLogEvents(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "stream")
+	now := c.clock().UnixMilli()
+
+	output, awserr := c.PutLogEvents(PutLogEventsInput{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		LogEvents:     []APIInputLogEvent{{Timestamp: now, Message: "first"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.NextSequenceToken == "" || output.RejectedLogEventsInfo != nil {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	// Old sequence tokens are rejected, with the expected token.
+	_, awserr = c.PutLogEvents(PutLogEventsInput{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		LogEvents:     []APIInputLogEvent{{Timestamp: now, Message: "second"}},
+		SequenceToken: "0",
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidSequenceTokenException" || awserr.Body.ExpectedSequenceToken != output.NextSequenceToken {
+		t.Fatal("Expected invalid sequence token", awserr)
+	}
+
+	// Events too old or too new are rejected, but the rest are accepted.
+	output, awserr = c.PutLogEvents(PutLogEventsInput{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		LogEvents: []APIInputLogEvent{
+			{Timestamp: now - (15 * 24 * time.Hour).Milliseconds(), Message: "too old"},
+			{Timestamp: now - 1000, Message: "earlier"},
+			{Timestamp: now + (3 * time.Hour).Milliseconds(), Message: "too new"},
+		},
+		SequenceToken: output.NextSequenceToken,
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		// The batch spans more than 24 hours.
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+	output, awserr = c.PutLogEvents(PutLogEventsInput{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		LogEvents: []APIInputLogEvent{
+			{Timestamp: now - 1000, Message: "earlier"},
+			{Timestamp: now + (3 * time.Hour).Milliseconds(), Message: "too new"},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.RejectedLogEventsInfo == nil || output.RejectedLogEventsInfo.TooNewLogEventStartIndex == nil ||
+		*output.RejectedLogEventsInfo.TooNewLogEventStartIndex != 1 || output.RejectedLogEventsInfo.TooOldLogEventEndIndex != nil {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	// Events are returned in timestamp order.
+	events, awserr := c.GetLogEvents(GetLogEventsInput{LogGroupName: "group", LogStreamName: "stream"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(messages(events), []string{"earlier", "first"}) {
+		t.Fatal("Unexpected events", messages(events))
+	}
+
+	invalid := [][]APIInputLogEvent{
+		nil,
+		{{Timestamp: now, Message: "b"}, {Timestamp: now - 1, Message: "a"}},
+		{{Timestamp: now, Message: string(make([]byte, maxEventSize))}},
+	}
+	for _, logEvents := range invalid {
+		_, awserr := c.PutLogEvents(PutLogEventsInput{LogGroupName: "group", LogStreamName: "stream", LogEvents: logEvents})
+		if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+			t.Fatal("Expected invalid parameter", awserr)
+		}
+	}
+	_, awserr = c.PutLogEvents(PutLogEventsInput{
+		LogGroupName:  "group",
+		LogStreamName: "missing",
+		LogEvents:     []APIInputLogEvent{{Timestamp: now, Message: "m"}},
+	})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestGetLogEvents(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "stream")
+	now := c.clock().UnixMilli()
+	var logEvents []APIInputLogEvent
+	for i := 0; i < 5; i++ {
+		logEvents = append(logEvents, APIInputLogEvent{Timestamp: now + int64(i), Message: strconv.Itoa(i)})
+	}
+	_, awserr := c.PutLogEvents(PutLogEventsInput{LogGroupName: "group", LogStreamName: "stream", LogEvents: logEvents})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	get := func(input GetLogEventsInput) *GetLogEventsOutput {
+		input.LogGroupName = "group"
+		input.LogStreamName = "stream"
+		output, awserr := c.GetLogEvents(input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		return output
+	}
+
+	// Without a token, the latest events are returned.
+	latest := get(GetLogEventsInput{Limit: 2})
+	if !slices.Equal(messages(latest), []string{"3", "4"}) {
+		t.Fatal("Unexpected events", messages(latest))
+	}
+	earlier := get(GetLogEventsInput{Limit: 2, NextToken: latest.NextBackwardToken})
+	if !slices.Equal(messages(earlier), []string{"1", "2"}) {
+		t.Fatal("Unexpected events", messages(earlier))
+	}
+
+	// Reading forwards, the same token is returned at the end of the stream.
+	output := get(GetLogEventsInput{Limit: 3, StartFromHead: true})
+	if !slices.Equal(messages(output), []string{"0", "1", "2"}) {
+		t.Fatal("Unexpected events", messages(output))
+	}
+	output = get(GetLogEventsInput{Limit: 3, NextToken: output.NextForwardToken})
+	if !slices.Equal(messages(output), []string{"3", "4"}) {
+		t.Fatal("Unexpected events", messages(output))
+	}
+	end := get(GetLogEventsInput{Limit: 3, NextToken: output.NextForwardToken})
+	if len(end.Events) != 0 || end.NextForwardToken != output.NextForwardToken {
+		t.Fatalf("Unexpected output: %+v", end)
+	}
+
+	// The start time is inclusive and the end time exclusive.
+	startTime, endTime := now+1, now+3
+	output = get(GetLogEventsInput{StartTime: &startTime, EndTime: &endTime, StartFromHead: true})
+	if !slices.Equal(messages(output), []string{"1", "2"}) {
+		t.Fatal("Unexpected events", messages(output))
+	}
+
+	_, awserr = c.GetLogEvents(GetLogEventsInput{LogGroupName: "group", LogStreamName: "stream", NextToken: "x/1"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+}
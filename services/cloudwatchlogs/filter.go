@@ -0,0 +1,108 @@
+package cloudwatchlogs
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+)
+
+const maxFilterLogEvents = 10000
+
+type streamEvent struct {
+	stream string
+	event  LogEvent
+}
+
+// lockedFilterEvents returns the events in the streams which are in the time range and match the pattern,
+// sorted by timestamp.
+func lockedFilterEvents(streams []*LogStream, startTime *int64, endTime *int64, pattern filterPattern) []streamEvent {
+	var events []streamEvent
+	for _, stream := range streams {
+		for _, event := range stream.Events {
+			if startTime != nil && event.Timestamp < *startTime {
+				continue
+			}
+			if endTime != nil && event.Timestamp >= *endTime {
+				continue
+			}
+			if pattern.matches(event.Message) {
+				events = append(events, streamEvent{stream: stream.Name, event: event})
+			}
+		}
+	}
+	slices.SortFunc(events, func(a, b streamEvent) int {
+		if a.event.Timestamp != b.event.Timestamp {
+			return cmp.Compare(a.event.Timestamp, b.event.Timestamp)
+		}
+		return cmp.Compare(a.event.Id, b.event.Id)
+	})
+	return events
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_FilterLogEvents.html
+func (c *CloudWatchLogs) FilterLogEvents(input FilterLogEventsInput) (*FilterLogEventsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxFilterLogEvents, maxFilterLogEvents, input.NextToken,
+		InvalidParameterException("limit must be between 1 and 10000."),
+		InvalidParameterException("The specified nextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(input.LogStreamNames) > 0 && input.LogStreamNamePrefix != "" {
+		return nil, InvalidParameterException("logStreamNames and logStreamNamePrefix are mutually exclusive parameters.")
+	}
+	pattern, err := parseFilterPattern(input.FilterPattern)
+	if err != nil {
+		return nil, InvalidParameterException("Invalid filter pattern: " + err.Error())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, input.LogGroupIdentifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var streams []*LogStream
+	for _, stream := range group.Streams {
+		if len(input.LogStreamNames) > 0 && !slices.Contains(input.LogStreamNames, stream.Name) {
+			continue
+		}
+		if strings.HasPrefix(stream.Name, input.LogStreamNamePrefix) {
+			streams = append(streams, stream)
+		}
+	}
+	slices.SortFunc(streams, func(a, b *LogStream) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	output := &FilterLogEventsOutput{
+		Events:             []APIFilteredLogEvent{},
+		SearchedLogStreams: []APISearchedLogStream{},
+	}
+	for _, stream := range streams {
+		output.SearchedLogStreams = append(output.SearchedLogStreams, APISearchedLogStream{
+			LogStreamName:      stream.Name,
+			SearchedCompletely: true,
+		})
+	}
+	// The token is the index of the next matching event.
+	events := lockedFilterEvents(streams, input.StartTime, input.EndTime, pattern)
+	for i := start; i < len(events); i++ {
+		if len(output.Events) == limit {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		output.Events = append(output.Events, APIFilteredLogEvent{
+			LogStreamName: events[i].stream,
+			Timestamp:     events[i].event.Timestamp,
+			Message:       events[i].event.Message,
+			IngestionTime: events[i].event.IngestionTime,
+			EventId:       strconv.FormatInt(events[i].event.Id, 10),
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,116 @@
+package cloudwatchlogs
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterPatterns(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		message  string
+		expected bool
+	}{
+		{"", "anything", true},
+		{"ERROR", "[ERROR] failed", true},
+		// Terms are case-sensitive.
+		{"ERROR", "error", false},
+		{"ERROR timeout", "ERROR: connection timeout", true},
+		{"ERROR timeout", "ERROR: connection refused", false},
+		{`"connection refused"`, "ERROR: connection refused", true},
+		{"?ERROR ?WARN", "WARN: disk almost full", true},
+		{"?ERROR ?WARN", "INFO: started", false},
+		{"ERROR -retrying", "ERROR: failed, retrying", false},
+
+		{`{ $.level = "error" }`, `{"level": "error"}`, true},
+		{`{ $.level = "error" }`, `{"level": "info"}`, false},
+		{`{ $.level = "error" }`, `not json`, false},
+		{`{ $.level = err* }`, `{"level": "error"}`, true},
+		{`{ $.latency > 100 && $.status != 200 }`, `{"latency": 150, "status": 500}`, true},
+		{`{ $.latency > 100 && $.status != 200 }`, `{"latency": 150, "status": 200}`, false},
+		{`{ ($.a = 1 || $.b = 2) && $.c = 3 }`, `{"b": 2, "c": 3}`, true},
+		{`{ $.user.roles[1] = "admin" }`, `{"user": {"roles": ["reader", "admin"]}}`, true},
+		{`{ $.missing NOT EXISTS }`, `{"present": 1}`, true},
+		{`{ $.value IS NULL }`, `{"value": null}`, true},
+		{`{ $.enabled IS TRUE }`, `{"enabled": true}`, true},
+		{`{ $.enabled IS FALSE }`, `{"enabled": true}`, false},
+
+		{"[ip, user, status, size]", "10.0.0.1 bob 200 512", true},
+		{"[ip, user, status, size]", "10.0.0.1 bob 200", false},
+		{"[ip, ..., status=404, size]", "10.0.0.1 - bob [10/Oct/2000:13:55:36 -0700] 404 512", true},
+		{"[ip, ..., status=4*, size>1000]", "10.0.0.1 bob 404 512", false},
+		{"[ip, request, status=4* || status=5*, ...]", `10.0.0.1 "GET /index.html" 503 12`, true},
+		{`[ip, request="GET *", ...]`, `10.0.0.1 "GET /index.html" 503 12`, true},
+	}
+	for _, c := range cases {
+		pattern, err := parseFilterPattern(c.pattern)
+		if err != nil {
+			t.Fatalf("Parsing %s: %v", c.pattern, err)
+		}
+		if pattern.matches(c.message) != c.expected {
+			t.Fatalf("Expected %s matching %s to be %v", c.pattern, c.message, c.expected)
+		}
+	}
+
+	for _, invalid := range []string{`{ $.a = }`, `{ level = "error" }`, `{ $.a = 1`, `[a, b=]`, `"unterminated`} {
+		if _, err := parseFilterPattern(invalid); err == nil {
+			t.Fatal("Expected error for", invalid)
+		}
+	}
+}
+
+func TestFilterLogEvents(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "a")
+	createLogStream(t, c, "group", "b")
+	now := c.clock().UnixMilli()
+	put := func(stream string, events ...APIInputLogEvent) {
+		_, awserr := c.PutLogEvents(PutLogEventsInput{LogGroupName: "group", LogStreamName: stream, LogEvents: events})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	put("a", APIInputLogEvent{Timestamp: now, Message: "ERROR one"}, APIInputLogEvent{Timestamp: now + 2, Message: "INFO two"})
+	put("b", APIInputLogEvent{Timestamp: now + 1, Message: "ERROR three"}, APIInputLogEvent{Timestamp: now + 3, Message: "ERROR four"})
+
+	filter := func(input FilterLogEventsInput) []string {
+		input.LogGroupName = "group"
+		output, awserr := c.FilterLogEvents(input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		var messages []string
+		for _, event := range output.Events {
+			messages = append(messages, event.Message)
+		}
+		return messages
+	}
+
+	// Events from all streams are interleaved by timestamp.
+	if messages := filter(FilterLogEventsInput{FilterPattern: "ERROR"}); !slices.Equal(messages, []string{"ERROR one", "ERROR three", "ERROR four"}) {
+		t.Fatal("Unexpected events", messages)
+	}
+	if messages := filter(FilterLogEventsInput{LogStreamNames: []string{"b"}}); !slices.Equal(messages, []string{"ERROR three", "ERROR four"}) {
+		t.Fatal("Unexpected events", messages)
+	}
+	startTime, endTime := now+1, now+3
+	if messages := filter(FilterLogEventsInput{StartTime: &startTime, EndTime: &endTime}); !slices.Equal(messages, []string{"ERROR three", "INFO two"}) {
+		t.Fatal("Unexpected events", messages)
+	}
+
+	output, awserr := c.FilterLogEvents(FilterLogEventsInput{LogGroupName: "group", Limit: 3})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Events) != 3 || output.NextToken == "" || output.Events[0].LogStreamName != "a" || output.Events[0].EventId == "" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	if messages := filter(FilterLogEventsInput{NextToken: output.NextToken}); !slices.Equal(messages, []string{"ERROR four"}) {
+		t.Fatal("Unexpected events", messages)
+	}
+
+	_, awserr = c.FilterLogEvents(FilterLogEventsInput{LogGroupName: "group", FilterPattern: "{ $.a = "})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+}
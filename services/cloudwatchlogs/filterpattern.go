@@ -0,0 +1,591 @@
+package cloudwatchlogs
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/FilterAndPatternSyntax.html
+
+// filterPattern matches log events' messages.
+type filterPattern interface {
+	matches(message string) bool
+}
+
+// parseFilterPattern parses term patterns like `ERROR -DEBUG "exact phrase"`,
+// JSON patterns like `{ $.level = "error" && $.latency > 100 }` and
+// space-delimited patterns like `[ip, user, ..., status=5*, bytes>1000]`.
+func parseFilterPattern(pattern string) (filterPattern, error) {
+	pattern = strings.TrimSpace(pattern)
+	switch {
+	case strings.HasPrefix(pattern, "{"):
+		return parseJSONPattern(pattern)
+	case strings.HasPrefix(pattern, "["):
+		return parseSpaceDelimitedPattern(pattern)
+	default:
+		return parseTermsPattern(pattern)
+	}
+}
+
+// tokenize splits the pattern on whitespace, keeping quoted strings together.
+// Quoted strings keep their quotes, so they can be told apart from other tokens.
+func tokenize(pattern string, punctuation string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			end := strings.IndexByte(pattern[i+1:], '"')
+			if end == -1 {
+				return nil, errors.New("unterminated quoted string")
+			}
+			tokens = append(tokens, pattern[i:i+end+2])
+			i += end + 2
+		case strings.IndexByte(punctuation, c) != -1:
+			// Two character operators, like && and <=.
+			if i+1 < len(pattern) && slices.Contains(twoCharacterOperators, pattern[i:i+2]) {
+				tokens = append(tokens, pattern[i:i+2])
+				i += 2
+			} else {
+				tokens = append(tokens, pattern[i:i+1])
+				i++
+			}
+		default:
+			start := i
+			for i < len(pattern) && !strings.ContainsRune(" \t\n\"", rune(pattern[i])) && strings.IndexByte(punctuation, pattern[i]) == -1 {
+				i++
+			}
+			tokens = append(tokens, pattern[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+func unquote(token string) (string, bool) {
+	if len(token) >= 2 && token[0] == '"' && token[len(token)-1] == '"' {
+		return token[1 : len(token)-1], true
+	}
+	return token, false
+}
+
+var twoCharacterOperators = []string{"&&", "||", "!=", "<=", ">="}
+
+type matchAll struct{}
+
+func (matchAll) matches(message string) bool {
+	return true
+}
+
+// termsPattern matches messages which contain all of the required terms, none of the excluded ones,
+// and at least one of the optional ones, if there are any. Terms are case-sensitive.
+type termsPattern struct {
+	required []string
+	excluded []string
+	optional []string
+}
+
+func parseTermsPattern(pattern string) (filterPattern, error) {
+	tokens, err := tokenize(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return matchAll{}, nil
+	}
+	p := &termsPattern{}
+	for _, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, "?"):
+			term, _ := unquote(token[1:])
+			p.optional = append(p.optional, term)
+		case strings.HasPrefix(token, "-") && len(token) > 1:
+			term, _ := unquote(token[1:])
+			p.excluded = append(p.excluded, term)
+		default:
+			term, _ := unquote(token)
+			p.required = append(p.required, term)
+		}
+	}
+	return p, nil
+}
+
+func (p *termsPattern) matches(message string) bool {
+	for _, term := range p.required {
+		if !strings.Contains(message, term) {
+			return false
+		}
+	}
+	for _, term := range p.excluded {
+		if strings.Contains(message, term) {
+			return false
+		}
+	}
+	if len(p.optional) == 0 {
+		return true
+	}
+	for _, term := range p.optional {
+		if strings.Contains(message, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// comparison compares a value from the message with a literal from the pattern.
+// Numbers are compared numerically, and strings may have * wildcards.
+type comparison struct {
+	op      string
+	literal string
+	quoted  bool
+}
+
+func (c comparison) matchesString(value string) bool {
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !c.quoted {
+		if literal, err := strconv.ParseFloat(c.literal, 64); err == nil {
+			return c.matchesNumber(number, literal)
+		}
+	}
+	matched := matchesWildcard(value, c.literal)
+	switch c.op {
+	case "=":
+		return matched
+	case "!=":
+		return !matched
+	}
+	return false
+}
+
+// matchesWildcard returns whether the value matches the pattern, in which * matches any characters.
+func matchesWildcard(value string, pattern string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return value == pattern
+	}
+	rest, ok := strings.CutPrefix(value, parts[0])
+	if !ok {
+		return false
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i == -1 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return strings.HasSuffix(rest, parts[len(parts)-1])
+}
+
+func (c comparison) matchesNumber(number float64, literal float64) bool {
+	switch c.op {
+	case "=":
+		return number == literal
+	case "!=":
+		return number != literal
+	case "<":
+		return number < literal
+	case ">":
+		return number > literal
+	case "<=":
+		return number <= literal
+	case ">=":
+		return number >= literal
+	}
+	return false
+}
+
+// jsonPattern matches messages which are JSON objects.
+type jsonPattern struct {
+	expression jsonExpression
+}
+
+type jsonExpression interface {
+	matches(document any) bool
+}
+
+type jsonAnd []jsonExpression
+
+func (a jsonAnd) matches(document any) bool {
+	for _, e := range a {
+		if !e.matches(document) {
+			return false
+		}
+	}
+	return true
+}
+
+type jsonOr []jsonExpression
+
+func (o jsonOr) matches(document any) bool {
+	for _, e := range o {
+		if e.matches(document) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonCondition tests the value a selector like $.user.roles[0] refers to.
+type jsonCondition struct {
+	selector []string
+	// IS NULL, IS TRUE, IS FALSE, NOT EXISTS, or empty to compare.
+	test       string
+	comparison comparison
+}
+
+// selectJSON returns the value the selector refers to, and whether it exists.
+func selectJSON(document any, selector []string) (any, bool) {
+	value := document
+	for _, key := range selector {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func (c *jsonCondition) matches(document any) bool {
+	value, exists := selectJSON(document, c.selector)
+	switch c.test {
+	case "NOT EXISTS":
+		return !exists
+	case "IS NULL":
+		return exists && value == nil
+	case "IS TRUE":
+		return value == true
+	case "IS FALSE":
+		return value == false
+	}
+	switch v := value.(type) {
+	case string:
+		return c.comparison.matchesString(v)
+	case float64:
+		literal, err := strconv.ParseFloat(c.comparison.literal, 64)
+		if err != nil || c.comparison.quoted {
+			return false
+		}
+		return c.comparison.matchesNumber(v, literal)
+	case bool:
+		return c.comparison.matchesString(strconv.FormatBool(v))
+	}
+	return false
+}
+
+func parseSelector(token string) ([]string, error) {
+	rest, ok := strings.CutPrefix(token, "$")
+	if !ok {
+		return nil, errors.New("expected a selector like $.field, got " + token)
+	}
+	var selector []string
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			selector = append(selector, rest[1:end+1])
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, errors.New("invalid selector " + token)
+			}
+			selector = append(selector, rest[1:end])
+			rest = rest[end+1:]
+		default:
+			return nil, errors.New("invalid selector " + token)
+		}
+	}
+	return selector, nil
+}
+
+type jsonParser struct {
+	tokens []string
+	i      int
+}
+
+func (p *jsonParser) peek() string {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i]
+	}
+	return ""
+}
+
+func (p *jsonParser) next() string {
+	token := p.peek()
+	p.i++
+	return token
+}
+
+func (p *jsonParser) parseOr() (jsonExpression, error) {
+	var or jsonOr
+	for {
+		and, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, and)
+		if p.peek() != "||" {
+			break
+		}
+		p.next()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *jsonParser) parseAnd() (jsonExpression, error) {
+	var and jsonAnd
+	for {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+		if p.peek() != "&&" {
+			break
+		}
+		p.next()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *jsonParser) parseUnary() (jsonExpression, error) {
+	if p.peek() == "(" {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("expected )")
+		}
+		return e, nil
+	}
+
+	selector, err := parseSelector(p.next())
+	if err != nil {
+		return nil, err
+	}
+	condition := &jsonCondition{selector: selector}
+	op := p.next()
+	switch {
+	case op == "IS" || op == "NOT":
+		test := op + " " + p.next()
+		switch test {
+		case "IS NULL", "IS TRUE", "IS FALSE", "NOT EXISTS":
+			condition.test = test
+		default:
+			return nil, errors.New("invalid test " + test)
+		}
+	case strings.Contains(" = != < > <= >= ", " "+op+" "):
+		literal := p.next()
+		if literal == "" || literal == ")" || literal == "&&" || literal == "||" {
+			return nil, errors.New("expected a value after " + op)
+		}
+		value, quoted := unquote(literal)
+		condition.comparison = comparison{op: op, literal: value, quoted: quoted}
+	default:
+		return nil, errors.New("expected an operator, got " + op)
+	}
+	return condition, nil
+}
+
+func parseJSONPattern(pattern string) (filterPattern, error) {
+	if !strings.HasSuffix(pattern, "}") {
+		return nil, errors.New("JSON patterns must end with }")
+	}
+	tokens, err := tokenize(pattern[1:len(pattern)-1], "()&|=!<>")
+	if err != nil {
+		return nil, err
+	}
+	p := &jsonParser{tokens: tokens}
+	expression, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.i != len(tokens) {
+		return nil, errors.New("unexpected " + p.peek())
+	}
+	return &jsonPattern{expression: expression}, nil
+}
+
+func (p *jsonPattern) matches(message string) bool {
+	var document any
+	if err := json.Unmarshal([]byte(message), &document); err != nil {
+		return false
+	}
+	return p.expression.matches(document)
+}
+
+// spaceDelimitedField matches one field of the message, or any number of them for `...`.
+type spaceDelimitedField struct {
+	ellipsis bool
+	// The conditions are ORed, and each of them is the ANDed comparisons.
+	conditions [][]comparison
+}
+
+func (f *spaceDelimitedField) matches(value string) bool {
+	if len(f.conditions) == 0 {
+		return true
+	}
+	for _, and := range f.conditions {
+		matched := true
+		for _, c := range and {
+			matched = matched && c.matchesString(value)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+type spaceDelimitedPattern struct {
+	fields []*spaceDelimitedField
+}
+
+func parseSpaceDelimitedPattern(pattern string) (filterPattern, error) {
+	if !strings.HasSuffix(pattern, "]") {
+		return nil, errors.New("space-delimited patterns must end with ]")
+	}
+	p := &spaceDelimitedPattern{}
+	for _, spec := range strings.Split(pattern[1:len(pattern)-1], ",") {
+		tokens, err := tokenize(spec, "&|=!<>")
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			return nil, errors.New("empty field in " + pattern)
+		}
+		field := &spaceDelimitedField{ellipsis: tokens[0] == "..."}
+		if field.ellipsis {
+			if len(tokens) > 1 {
+				return nil, errors.New("... can't have conditions")
+			}
+			p.fields = append(p.fields, field)
+			continue
+		}
+		name := tokens[0]
+		if !isIdentifier(name) {
+			return nil, errors.New("invalid field name " + name)
+		}
+		// Conditions look like name=value, and can be combined with && and ||.
+		var and []comparison
+		for i := 1; i < len(tokens); {
+			if i > 1 {
+				switch tokens[i] {
+				case "&&":
+				case "||":
+					field.conditions = append(field.conditions, and)
+					and = nil
+				default:
+					return nil, errors.New("expected && or || in " + spec)
+				}
+				i++
+				if i < len(tokens) && tokens[i] == name {
+					i++
+				}
+			}
+			if i+1 >= len(tokens) || !strings.Contains(" = != < > <= >= ", " "+tokens[i]+" ") {
+				return nil, errors.New("invalid condition in " + spec)
+			}
+			value, quoted := unquote(tokens[i+1])
+			and = append(and, comparison{op: tokens[i], literal: value, quoted: quoted})
+			i += 2
+		}
+		if and != nil {
+			field.conditions = append(field.conditions, and)
+		}
+		p.fields = append(p.fields, field)
+	}
+	return p, nil
+}
+
+func isIdentifier(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitFields splits the message on spaces, but text in quotes or brackets is one field.
+// Quotes are removed, but brackets are kept.
+func splitFields(message string) []string {
+	var fields []string
+	for i := 0; i < len(message); {
+		switch message[i] {
+		case ' ', '\t':
+			i++
+		case '"':
+			end := strings.IndexByte(message[i+1:], '"')
+			if end == -1 {
+				end = len(message) - i - 1
+			}
+			fields = append(fields, message[i+1:i+1+end])
+			i += end + 2
+		case '[':
+			end := strings.IndexByte(message[i:], ']')
+			if end == -1 {
+				end = len(message) - i - 1
+			}
+			fields = append(fields, message[i:i+end+1])
+			i += end + 1
+		default:
+			end := strings.IndexAny(message[i:], " \t")
+			if end == -1 {
+				end = len(message) - i
+			}
+			fields = append(fields, message[i:i+end])
+			i += end
+		}
+	}
+	return fields
+}
+
+func (p *spaceDelimitedPattern) matches(message string) bool {
+	return matchFields(p.fields, splitFields(message))
+}
+
+func matchFields(patterns []*spaceDelimitedField, fields []string) bool {
+	if len(patterns) == 0 {
+		return len(fields) == 0
+	}
+	if patterns[0].ellipsis {
+		for skip := 0; skip <= len(fields); skip++ {
+			if matchFields(patterns[1:], fields[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(fields) == 0 || !patterns[0].matches(fields[0]) {
+		return false
+	}
+	return matchFields(patterns[1:], fields[1:])
+}
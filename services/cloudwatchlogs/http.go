@@ -0,0 +1,29 @@
+package cloudwatchlogs
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "Logs_20140328"
+
+func (c *CloudWatchLogs) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateLogGroup", region.Method(c.regions, (*CloudWatchLogs).CreateLogGroup))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateLogStream", region.Method(c.regions, (*CloudWatchLogs).CreateLogStream))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteLogGroup", region.Method(c.regions, (*CloudWatchLogs).DeleteLogGroup))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteLogStream", region.Method(c.regions, (*CloudWatchLogs).DeleteLogStream))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteRetentionPolicy", region.Method(c.regions, (*CloudWatchLogs).DeleteRetentionPolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteSubscriptionFilter", region.Method(c.regions, (*CloudWatchLogs).DeleteSubscriptionFilter))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeLogGroups", region.Method(c.regions, (*CloudWatchLogs).DescribeLogGroups))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeLogStreams", region.Method(c.regions, (*CloudWatchLogs).DescribeLogStreams))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeSubscriptionFilters", region.Method(c.regions, (*CloudWatchLogs).DescribeSubscriptionFilters))
+	http.RegisterWithRequest(logger, methodRegistry, service, "FilterLogEvents", region.Method(c.regions, (*CloudWatchLogs).FilterLogEvents))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetLogEvents", region.Method(c.regions, (*CloudWatchLogs).GetLogEvents))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetQueryResults", region.Method(c.regions, (*CloudWatchLogs).GetQueryResults))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutLogEvents", region.Method(c.regions, (*CloudWatchLogs).PutLogEvents))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutRetentionPolicy", region.Method(c.regions, (*CloudWatchLogs).PutRetentionPolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutSubscriptionFilter", region.Method(c.regions, (*CloudWatchLogs).PutSubscriptionFilter))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StartQuery", region.Method(c.regions, (*CloudWatchLogs).StartQuery))
+}
@@ -0,0 +1,861 @@
+package cloudwatchlogs
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A subset of the Logs Insights query language: the fields, display, filter, stats, sort and limit commands.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/CWL_QuerySyntax.html
+
+const insightsTimeFormat = "2006-01-02 15:04:05.000"
+
+// record is a log event, or a row of stats, with its fields.
+type record struct {
+	// Milliseconds since the epoch, for bin().
+	timestamp int64
+	fields    map[string]string
+}
+
+// newRecord returns the record for the event, with the fields Insights discovers.
+// Fields of JSON messages are flattened, so {"a":{"b":1}} has the field a.b.
+func newRecord(accountId string, group string, stream string, event LogEvent) record {
+	r := record{
+		timestamp: event.Timestamp,
+		fields: map[string]string{
+			"@timestamp":     time.UnixMilli(event.Timestamp).UTC().Format(insightsTimeFormat),
+			"@ingestionTime": time.UnixMilli(event.IngestionTime).UTC().Format(insightsTimeFormat),
+			"@message":       event.Message,
+			"@logStream":     stream,
+			"@log":           accountId + ":" + group,
+			"@ptr":           strconv.FormatInt(event.Id, 10),
+		},
+	}
+	var document map[string]any
+	if json.Unmarshal([]byte(event.Message), &document) == nil {
+		flatten(r.fields, "", document)
+	}
+	return r
+}
+
+func flatten(fields map[string]string, prefix string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flatten(fields, prefix+key+".", child)
+		}
+	case []any:
+		for i, child := range v {
+			flatten(fields, prefix+strconv.Itoa(i)+".", child)
+		}
+	case string:
+		fields[strings.TrimSuffix(prefix, ".")] = v
+	case nil:
+	default:
+		encoded, _ := json.Marshal(v)
+		fields[strings.TrimSuffix(prefix, ".")] = string(encoded)
+	}
+}
+
+// compareValues compares numerically if both values are numbers, and otherwise as strings.
+func compareValues(a string, b string) int {
+	x, errX := strconv.ParseFloat(a, 64)
+	y, errY := strconv.ParseFloat(b, 64)
+	if errX == nil && errY == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
+
+type tokenKind int
+
+const (
+	identifierToken tokenKind = iota
+	stringToken
+	numberToken
+	regexToken
+	punctuationToken
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+}
+
+// lexQuery splits a command into tokens. Identifiers may be quoted with backticks.
+func lexQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'' || c == '`' || c == '/':
+			end := closingQuote(s, i)
+			if end == -1 {
+				return nil, errors.New("unterminated " + string(c))
+			}
+			token := queryToken{kind: stringToken, text: s[i+1 : end]}
+			switch c {
+			case '`':
+				token.kind = identifierToken
+			case '/':
+				// Escapes are left for the regular expression to interpret.
+				token.kind = regexToken
+			}
+			if token.kind != regexToken {
+				token.text = strings.ReplaceAll(token.text, `\`+string(c), string(c))
+			}
+			tokens = append(tokens, token)
+			i = end + 1
+		case strings.ContainsRune("=!<>", c):
+			if i+1 < len(s) && (s[i+1] == '=' || (c == '=' && s[i+1] == '~')) {
+				tokens = append(tokens, queryToken{kind: punctuationToken, text: s[i : i+2]})
+				i += 2
+			} else {
+				tokens = append(tokens, queryToken{kind: punctuationToken, text: s[i : i+1]})
+				i++
+			}
+		case strings.ContainsRune("(),[]*", c):
+			tokens = append(tokens, queryToken{kind: punctuationToken, text: s[i : i+1]})
+			i++
+		case unicode.IsDigit(c) || c == '-':
+			start := i
+			i++
+			for i < len(s) && (unicode.IsDigit(rune(s[i])) || s[i] == '.') {
+				i++
+			}
+			// Durations, like 5m in bin(5m), are identifiers.
+			for i < len(s) && unicode.IsLetter(rune(s[i])) {
+				i++
+			}
+			kind := numberToken
+			if _, err := strconv.ParseFloat(s[start:i], 64); err != nil {
+				kind = identifierToken
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: s[start:i]})
+		case unicode.IsLetter(c) || c == '@' || c == '_':
+			start := i
+			for i < len(s) && (unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i])) || strings.ContainsRune("@_.-", rune(s[i]))) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: identifierToken, text: s[start:i]})
+		default:
+			return nil, errors.New("unexpected character " + string(c))
+		}
+	}
+	return tokens, nil
+}
+
+// closingQuote returns the index of the quote which closes the one at start, skipping escaped quotes.
+func closingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case s[start]:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitCommands splits the query on pipes which aren't in quotes or regular expressions.
+func splitCommands(query string) []string {
+	var commands []string
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '"', '\'', '`', '/':
+			if end := closingQuote(query, i); end != -1 {
+				i = end
+			}
+		case '|':
+			commands = append(commands, query[start:i])
+			start = i + 1
+		}
+	}
+	return append(commands, query[start:])
+}
+
+// splitTopLevel splits the tokens on commas which aren't in parentheses or brackets.
+func splitTopLevel(tokens []queryToken) [][]queryToken {
+	var parts [][]queryToken
+	depth, start := 0, 0
+	for i, token := range tokens {
+		if token.kind != punctuationToken {
+			continue
+		}
+		switch token.text {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+		case ",":
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// operand is a field or a literal in a filter.
+type operand struct {
+	field   string
+	literal string
+}
+
+func (o operand) value(r record) (string, bool) {
+	if o.field == "" {
+		return o.literal, true
+	}
+	value, ok := r.fields[o.field]
+	return value, ok
+}
+
+type condition interface {
+	matches(r record) bool
+}
+
+type andCondition []condition
+
+func (a andCondition) matches(r record) bool {
+	for _, c := range a {
+		if !c.matches(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type orCondition []condition
+
+func (o orCondition) matches(r record) bool {
+	for _, c := range o {
+		if c.matches(r) {
+			return true
+		}
+	}
+	return false
+}
+
+type notCondition struct {
+	condition condition
+}
+
+func (n notCondition) matches(r record) bool {
+	return !n.condition.matches(r)
+}
+
+type compareCondition struct {
+	left  operand
+	op    string
+	right operand
+}
+
+func (c compareCondition) matches(r record) bool {
+	left, ok := c.left.value(r)
+	if !ok {
+		return false
+	}
+	right, ok := c.right.value(r)
+	if !ok {
+		return false
+	}
+	result := compareValues(left, right)
+	switch c.op {
+	case "=":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case ">":
+		return result > 0
+	case "<=":
+		return result <= 0
+	case ">=":
+		return result >= 0
+	}
+	return false
+}
+
+type likeCondition struct {
+	left  operand
+	regex *regexp.Regexp
+}
+
+func (l likeCondition) matches(r record) bool {
+	value, ok := l.left.value(r)
+	return ok && l.regex.MatchString(value)
+}
+
+type inCondition struct {
+	left   operand
+	values []string
+}
+
+func (c inCondition) matches(r record) bool {
+	value, ok := c.left.value(r)
+	return ok && slices.ContainsFunc(c.values, func(v string) bool { return compareValues(value, v) == 0 })
+}
+
+type isPresentCondition struct {
+	field string
+}
+
+func (c isPresentCondition) matches(r record) bool {
+	_, ok := r.fields[c.field]
+	return ok
+}
+
+type conditionParser struct {
+	tokens []queryToken
+	i      int
+}
+
+func (p *conditionParser) peek() queryToken {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i]
+	}
+	return queryToken{kind: punctuationToken}
+}
+
+func (p *conditionParser) next() queryToken {
+	token := p.peek()
+	p.i++
+	return token
+}
+
+// keyword returns whether the next token is the keyword, and consumes it if so.
+func (p *conditionParser) keyword(keyword string) bool {
+	token := p.peek()
+	if token.kind == identifierToken && strings.EqualFold(token.text, keyword) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (condition, error) {
+	var or orCondition
+	for {
+		c, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, c)
+		if !p.keyword("or") {
+			break
+		}
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *conditionParser) parseAnd() (condition, error) {
+	var and andCondition
+	for {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, c)
+		if !p.keyword("and") {
+			break
+		}
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *conditionParser) parseNot() (condition, error) {
+	if p.keyword("not") {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{c}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parseOperand() (operand, error) {
+	token := p.next()
+	switch token.kind {
+	case identifierToken:
+		return operand{field: token.text}, nil
+	case stringToken, numberToken:
+		return operand{literal: token.text}, nil
+	}
+	return operand{}, errors.New("expected a field or value, got " + token.text)
+}
+
+func (p *conditionParser) parsePrimary() (condition, error) {
+	if token := p.peek(); token.kind == punctuationToken && token.text == "(" {
+		p.next()
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().text != ")" {
+			return nil, errors.New("expected )")
+		}
+		return c, nil
+	}
+	if token := p.peek(); token.kind == identifierToken && strings.EqualFold(token.text, "ispresent") {
+		p.next()
+		if p.next().text != "(" {
+			return nil, errors.New("expected ( after ispresent")
+		}
+		field := p.next()
+		if field.kind != identifierToken || p.next().text != ")" {
+			return nil, errors.New("ispresent takes a field")
+		}
+		return isPresentCondition{field: field.text}, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	negated := p.keyword("not")
+	var c condition
+	switch {
+	case p.keyword("like"):
+		c, err = p.parseLike(left)
+	case p.keyword("in"):
+		c, err = p.parseIn(left)
+	case negated:
+		return nil, errors.New("expected like or in after not")
+	case p.peek().kind == punctuationToken && p.peek().text == "=~":
+		p.next()
+		c, err = p.parseLike(left)
+	case p.peek().kind == punctuationToken && slices.Contains([]string{"=", "!=", "<", ">", "<=", ">="}, p.peek().text):
+		op := p.next().text
+		var right operand
+		right, err = p.parseOperand()
+		c = compareCondition{left: left, op: op, right: right}
+	default:
+		return nil, errors.New("expected a comparison after " + left.field + left.literal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if negated {
+		return notCondition{c}, nil
+	}
+	return c, nil
+}
+
+// parseLike parses the pattern of like and =~, which is a regular expression or a substring.
+func (p *conditionParser) parseLike(left operand) (condition, error) {
+	token := p.next()
+	var pattern string
+	switch token.kind {
+	case regexToken:
+		pattern = token.text
+	case stringToken:
+		pattern = regexp.QuoteMeta(token.text)
+	default:
+		return nil, errors.New("expected a regular expression or string, got " + token.text)
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return likeCondition{left: left, regex: regex}, nil
+}
+
+func (p *conditionParser) parseIn(left operand) (condition, error) {
+	if p.next().text != "[" {
+		return nil, errors.New("expected [ after in")
+	}
+	c := inCondition{left: left}
+	for {
+		token := p.next()
+		if token.kind != stringToken && token.kind != numberToken {
+			return nil, errors.New("expected a value, got " + token.text)
+		}
+		c.values = append(c.values, token.text)
+		switch p.next().text {
+		case ",":
+		case "]":
+			return c, nil
+		default:
+			return nil, errors.New("expected , or ]")
+		}
+	}
+}
+
+// aggregation is a statistic in a stats command, like count(*) or avg(latency).
+type aggregation struct {
+	function string
+	field    string
+	name     string
+}
+
+// grouping is a field in a stats command's by clause, or bin(period).
+type grouping struct {
+	field string
+	bin   time.Duration
+	name  string
+}
+
+func (g grouping) value(r record) string {
+	if g.bin == 0 {
+		return r.fields[g.field]
+	}
+	bin := g.bin.Milliseconds()
+	return time.UnixMilli(r.timestamp - r.timestamp%bin).UTC().Format(insightsTimeFormat)
+}
+
+var aggregationFunctions = []string{"count", "count_distinct", "sum", "avg", "min", "max"}
+
+func tokensText(tokens []queryToken) string {
+	var text []string
+	for _, token := range tokens {
+		text = append(text, token.text)
+	}
+	return strings.Join(text, "")
+}
+
+// parseNamed parses `expression [as name]`, returning the expression's tokens and name.
+func parseNamed(tokens []queryToken) ([]queryToken, string) {
+	if n := len(tokens); n >= 3 && tokens[n-2].kind == identifierToken && strings.EqualFold(tokens[n-2].text, "as") {
+		return tokens[:n-2], tokens[n-1].text
+	}
+	return tokens, tokensText(tokens)
+}
+
+// parseDuration parses bin() periods like 30s, 5m, 1h and 1d.
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(s)
+}
+
+func parseAggregation(tokens []queryToken) (aggregation, error) {
+	tokens, name := parseNamed(tokens)
+	if len(tokens) != 4 || tokens[1].text != "(" || tokens[3].text != ")" ||
+		!slices.Contains(aggregationFunctions, strings.ToLower(tokens[0].text)) {
+		return aggregation{}, errors.New("unsupported statistic " + tokensText(tokens))
+	}
+	a := aggregation{function: strings.ToLower(tokens[0].text), field: tokens[2].text, name: name}
+	if a.field == "*" && a.function != "count" {
+		return aggregation{}, errors.New("only count can be used with *")
+	}
+	return a, nil
+}
+
+func parseGrouping(tokens []queryToken) (grouping, error) {
+	tokens, name := parseNamed(tokens)
+	if len(tokens) == 1 && tokens[0].kind == identifierToken {
+		return grouping{field: tokens[0].text, name: name}, nil
+	}
+	if len(tokens) == 4 && strings.EqualFold(tokens[0].text, "bin") && tokens[1].text == "(" && tokens[3].text == ")" {
+		period, err := parseDuration(tokens[2].text)
+		if err != nil || period <= 0 {
+			return grouping{}, errors.New("invalid bin period " + tokens[2].text)
+		}
+		return grouping{bin: period, name: name}, nil
+	}
+	return grouping{}, errors.New("unsupported grouping " + tokensText(tokens))
+}
+
+// aggregate computes the statistic over the records in a group.
+func (a aggregation) aggregate(records []record) string {
+	if a.function == "count" && a.field == "*" {
+		return strconv.Itoa(len(records))
+	}
+	var values []string
+	for _, r := range records {
+		if value, ok := r.fields[a.field]; ok {
+			values = append(values, value)
+		}
+	}
+	switch a.function {
+	case "count":
+		return strconv.Itoa(len(values))
+	case "count_distinct":
+		slices.Sort(values)
+		return strconv.Itoa(len(slices.Compact(values)))
+	}
+
+	var numbers []float64
+	for _, value := range values {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	if len(numbers) == 0 {
+		return ""
+	}
+	var result float64
+	switch a.function {
+	case "sum", "avg":
+		for _, number := range numbers {
+			result += number
+		}
+		if a.function == "avg" {
+			result /= float64(len(numbers))
+		}
+	case "min":
+		result = slices.Min(numbers)
+	case "max":
+		result = slices.Max(numbers)
+	}
+	return strconv.FormatFloat(result, 'f', -1, 64)
+}
+
+// resultSet is the state of a query as its commands are applied.
+type resultSet struct {
+	records []record
+	// The fields to return, in order, or nil to return the defaults.
+	columns []string
+	// Whether the records are stats, rather than log events.
+	aggregated bool
+}
+
+type queryCommand func(r *resultSet)
+
+func fieldsCommand(tokens []queryToken, replace bool) (queryCommand, error) {
+	type field struct{ from, to string }
+	var fields []field
+	for _, part := range splitTopLevel(tokens) {
+		expression, name := parseNamed(part)
+		if len(expression) != 1 || expression[0].kind != identifierToken {
+			return nil, errors.New("unsupported field " + tokensText(part))
+		}
+		fields = append(fields, field{from: expression[0].text, to: name})
+	}
+	return func(r *resultSet) {
+		if replace {
+			r.columns = nil
+		}
+		for _, f := range fields {
+			if f.from != f.to {
+				for _, record := range r.records {
+					if value, ok := record.fields[f.from]; ok {
+						record.fields[f.to] = value
+					}
+				}
+			}
+			if !slices.Contains(r.columns, f.to) {
+				r.columns = append(r.columns, f.to)
+			}
+		}
+	}, nil
+}
+
+func filterCommand(tokens []queryToken) (queryCommand, error) {
+	p := &conditionParser{tokens: tokens}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.i != len(tokens) {
+		return nil, errors.New("unexpected " + p.peek().text)
+	}
+	return func(r *resultSet) {
+		r.records = slices.DeleteFunc(r.records, func(record record) bool {
+			return !c.matches(record)
+		})
+	}, nil
+}
+
+func statsCommand(tokens []queryToken) (queryCommand, error) {
+	var aggregationTokens, groupingTokens []queryToken = tokens, nil
+	for i, token := range tokens {
+		if token.kind == identifierToken && strings.EqualFold(token.text, "by") {
+			aggregationTokens, groupingTokens = tokens[:i], tokens[i+1:]
+			break
+		}
+	}
+	var aggregations []aggregation
+	for _, part := range splitTopLevel(aggregationTokens) {
+		a, err := parseAggregation(part)
+		if err != nil {
+			return nil, err
+		}
+		aggregations = append(aggregations, a)
+	}
+	var groupings []grouping
+	if groupingTokens != nil {
+		for _, part := range splitTopLevel(groupingTokens) {
+			g, err := parseGrouping(part)
+			if err != nil {
+				return nil, err
+			}
+			groupings = append(groupings, g)
+		}
+	}
+
+	return func(r *resultSet) {
+		// Groups are in the order they're first seen.
+		var keys []string
+		groups := make(map[string][]record)
+		for _, record := range r.records {
+			var values []string
+			for _, g := range groupings {
+				values = append(values, g.value(record))
+			}
+			key := strings.Join(values, "\x00")
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], record)
+		}
+		// Without groupings, there's always one row.
+		if len(groupings) == 0 && len(keys) == 0 {
+			keys = append(keys, "")
+		}
+
+		r.columns = nil
+		for _, g := range groupings {
+			r.columns = append(r.columns, g.name)
+		}
+		for _, a := range aggregations {
+			r.columns = append(r.columns, a.name)
+		}
+		var records []record
+		for _, key := range keys {
+			group := groups[key]
+			stats := record{fields: make(map[string]string)}
+			for i, g := range groupings {
+				stats.fields[g.name] = g.value(group[0])
+				if i == 0 {
+					stats.timestamp = group[0].timestamp
+				}
+			}
+			for _, a := range aggregations {
+				if value := a.aggregate(group); value != "" {
+					stats.fields[a.name] = value
+				}
+			}
+			records = append(records, stats)
+		}
+		r.records = records
+		r.aggregated = true
+	}, nil
+}
+
+func sortCommand(tokens []queryToken) (queryCommand, error) {
+	type key struct {
+		field      string
+		descending bool
+	}
+	var keys []key
+	for _, part := range splitTopLevel(tokens) {
+		if len(part) == 0 || len(part) > 2 || part[0].kind != identifierToken {
+			return nil, errors.New("invalid sort " + tokensText(part))
+		}
+		k := key{field: part[0].text}
+		if len(part) == 2 {
+			switch strings.ToLower(part[1].text) {
+			case "asc":
+			case "desc":
+				k.descending = true
+			default:
+				return nil, errors.New("invalid sort order " + part[1].text)
+			}
+		}
+		keys = append(keys, k)
+	}
+	return func(r *resultSet) {
+		slices.SortStableFunc(r.records, func(a, b record) int {
+			for _, k := range keys {
+				x, okX := a.fields[k.field]
+				y, okY := b.fields[k.field]
+				// Records without the field come last.
+				var result int
+				switch {
+				case !okX && !okY:
+					continue
+				case !okX:
+					return 1
+				case !okY:
+					return -1
+				default:
+					result = compareValues(x, y)
+				}
+				if k.descending {
+					result = -result
+				}
+				if result != 0 {
+					return result
+				}
+			}
+			return 0
+		})
+	}, nil
+}
+
+func limitCommand(tokens []queryToken) (queryCommand, error) {
+	if len(tokens) != 1 || tokens[0].kind != numberToken {
+		return nil, errors.New("limit takes a number")
+	}
+	n, err := strconv.Atoi(tokens[0].text)
+	if err != nil || n < 1 || n > maxQueryResults {
+		return nil, errors.New("limit must be between 1 and 10000")
+	}
+	return func(r *resultSet) {
+		if len(r.records) > n {
+			r.records = r.records[:n]
+		}
+	}, nil
+}
+
+// parseQuery parses the query into commands, which are applied in order.
+func parseQuery(query string) ([]queryCommand, error) {
+	var commands []queryCommand
+	for _, text := range splitCommands(query) {
+		tokens, err := lexQuery(text)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		var command queryCommand
+		switch name, args := strings.ToLower(tokens[0].text), tokens[1:]; name {
+		case "fields":
+			command, err = fieldsCommand(args, false)
+		case "display":
+			command, err = fieldsCommand(args, true)
+		case "filter":
+			command, err = filterCommand(args)
+		case "stats":
+			command, err = statsCommand(args)
+		case "sort":
+			command, err = sortCommand(args)
+		case "limit":
+			command, err = limitCommand(args)
+		default:
+			return nil, errors.New("unsupported command " + tokens[0].text)
+		}
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+	if len(commands) == 0 {
+		return nil, errors.New("the query is empty")
+	}
+	return commands, nil
+}
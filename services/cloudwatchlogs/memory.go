@@ -0,0 +1,25 @@
+package cloudwatchlogs
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each region's log groups' messages.
+func (c *CloudWatchLogs) MemoryUsage() []memory.Resource {
+	return c.regions.MemoryUsage((*CloudWatchLogs).memoryUsage)
+}
+
+func (c *CloudWatchLogs) memoryUsage() []memory.Resource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var resources []memory.Resource
+	for name, group := range c.logGroupsByName {
+		resource := memory.Resource{Name: name, Bytes: group.StoredBytes}
+		for _, stream := range group.Streams {
+			resource.Count += len(stream.Events)
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
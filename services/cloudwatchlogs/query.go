@@ -0,0 +1,172 @@
+package cloudwatchlogs
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/AnalyzingLogData.html
+
+const (
+	defaultQueryResults = 1000
+	maxQueryResults     = 10000
+	maxQueryLogGroups   = 50
+	maxQueryLength      = 10000
+	// Query results are deleted after this long.
+	queryRetention = 7 * 24 * time.Hour
+)
+
+// defaultColumns are returned by queries without fields or stats commands.
+var defaultColumns = []string{"@timestamp", "@message"}
+
+// Query is a completed Logs Insights query.
+type Query struct {
+	Id         string
+	CreateTime time.Time
+	Results    [][]APIResultField
+	Statistics APIQueryStatistics
+}
+
+// lockedRunQuery runs the query over the groups' events in the time range, which is in milliseconds.
+func (c *CloudWatchLogs) lockedRunQuery(groups []*LogGroup, start int64, end int64, commands []queryCommand, limit int) *Query {
+	query := &Query{}
+	r := &resultSet{}
+	for _, group := range groups {
+		for _, stream := range group.Streams {
+			for _, event := range stream.Events {
+				if event.Timestamp < start || event.Timestamp >= end {
+					continue
+				}
+				query.Statistics.RecordsScanned++
+				query.Statistics.BytesScanned += float64(len(event.Message))
+				r.records = append(r.records, newRecord(c.arnGenerator.AwsAccountId, group.Name, stream.Name, event))
+			}
+		}
+	}
+	// Without a sort command, the latest events come first.
+	slices.SortFunc(r.records, func(a, b record) int {
+		if a.timestamp != b.timestamp {
+			return cmp.Compare(b.timestamp, a.timestamp)
+		}
+		return compareValues(b.fields["@ptr"], a.fields["@ptr"])
+	})
+
+	for _, command := range commands {
+		command(r)
+		if !r.aggregated {
+			query.Statistics.RecordsMatched = float64(len(r.records))
+		}
+	}
+	columns := r.columns
+	if columns == nil {
+		columns = defaultColumns
+	}
+	query.Results = [][]APIResultField{}
+	for i, record := range r.records {
+		if i == limit {
+			break
+		}
+		row := []APIResultField{}
+		for _, column := range columns {
+			if value, ok := record.fields[column]; ok {
+				row = append(row, APIResultField{Field: column, Value: value})
+			}
+		}
+		// Log events can be looked up by their pointer.
+		if !r.aggregated {
+			row = append(row, APIResultField{Field: "@ptr", Value: record.fields["@ptr"]})
+		}
+		query.Results = append(query.Results, row)
+	}
+	return query
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_StartQuery.html
+func (c *CloudWatchLogs) StartQuery(input StartQueryInput) (*StartQueryOutput, *awserrors.Error) {
+	if input.QueryLanguage != "" && input.QueryLanguage != "CWLI" {
+		return nil, InvalidParameterException("Only the CWLI query language is supported.")
+	}
+	if len(input.QueryString) < 1 || len(input.QueryString) > maxQueryLength {
+		return nil, InvalidParameterException("queryString must be between 1 and 10000 characters.")
+	}
+	if input.EndTime < input.StartTime {
+		return nil, InvalidParameterException("endTime must be after startTime.")
+	}
+	limit := input.Limit
+	if limit == 0 {
+		limit = defaultQueryResults
+	}
+	if limit < 1 || limit > maxQueryResults {
+		return nil, InvalidParameterException("limit must be between 1 and 10000.")
+	}
+	var names, identifiers []string
+	switch {
+	case input.LogGroupName != "" && len(input.LogGroupNames) == 0 && len(input.LogGroupIdentifiers) == 0:
+		names = []string{input.LogGroupName}
+	case input.LogGroupName == "" && len(input.LogGroupNames) > 0 && len(input.LogGroupIdentifiers) == 0:
+		names = input.LogGroupNames
+	case input.LogGroupName == "" && len(input.LogGroupNames) == 0 && len(input.LogGroupIdentifiers) > 0:
+		identifiers = input.LogGroupIdentifiers
+	default:
+		return nil, InvalidParameterException("Exactly one of logGroupName, logGroupNames and logGroupIdentifiers must be specified.")
+	}
+	if len(names)+len(identifiers) > maxQueryLogGroups {
+		return nil, InvalidParameterException("A query can search at most 50 log groups.")
+	}
+	commands, err := parseQuery(input.QueryString)
+	if err != nil {
+		return nil, MalformedQueryException(err.Error())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var groups []*LogGroup
+	for _, name := range names {
+		group, awserr := c.lockedGetLogGroup(name, "")
+		if awserr != nil {
+			return nil, awserr
+		}
+		groups = append(groups, group)
+	}
+	for _, identifier := range identifiers {
+		group, awserr := c.lockedGetLogGroup("", identifier)
+		if awserr != nil {
+			return nil, awserr
+		}
+		groups = append(groups, group)
+	}
+
+	query := c.lockedRunQuery(groups, input.StartTime*1000, (input.EndTime+1)*1000, commands, limit)
+	query.Id = uuid.Must(uuid.NewV4()).String()
+	query.CreateTime = c.clock()
+	for id, q := range c.queriesById {
+		if query.CreateTime.Sub(q.CreateTime) > queryRetention {
+			delete(c.queriesById, id)
+		}
+	}
+	c.queriesById[query.Id] = query
+	return &StartQueryOutput{QueryId: query.Id}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_GetQueryResults.html
+func (c *CloudWatchLogs) GetQueryResults(input GetQueryResultsInput) (*GetQueryResultsOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	query, ok := c.queriesById[input.QueryId]
+	if !ok {
+		return nil, ResourceNotFoundException("The specified query does not exist.")
+	}
+	return &GetQueryResultsOutput{
+		QueryLanguage: "CWLI",
+		Results:       query.Results,
+		Statistics:    query.Statistics,
+		Status:        "Complete",
+	}, nil
+}
@@ -0,0 +1,128 @@
+package cloudwatchlogs
+
+import (
+	"strings"
+	"testing"
+)
+
+func runQuery(t *testing.T, c *CloudWatchLogs, query string) *GetQueryResultsOutput {
+	now := c.clock().Unix()
+	started, awserr := c.StartQuery(StartQueryInput{
+		LogGroupName: "group",
+		StartTime:    now - 3600,
+		EndTime:      now + 3600,
+		QueryString:  query,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	output, awserr := c.GetQueryResults(GetQueryResultsInput{QueryId: started.QueryId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+// rows returns the values of the results' fields, without @ptr, joined with commas.
+func rows(output *GetQueryResultsOutput) []string {
+	var rows []string
+	for _, result := range output.Results {
+		var values []string
+		for _, field := range result {
+			if field.Field != "@ptr" {
+				values = append(values, field.Field+"="+field.Value)
+			}
+		}
+		rows = append(rows, strings.Join(values, ","))
+	}
+	return rows
+}
+
+func TestQuery(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "stream")
+	now := c.clock().UnixMilli()
+	messages := []string{
+		`{"level":"info","path":"/a","latency":10}`,
+		`{"level":"error","path":"/b","latency":250}`,
+		`{"level":"info","path":"/a","latency":30}`,
+		`plain text ERROR`,
+		`{"level":"error","path":"/a","latency":100}`,
+	}
+	var events []APIInputLogEvent
+	for i, message := range messages {
+		// Two minutes apart, for bin().
+		events = append(events, APIInputLogEvent{Timestamp: now + int64(i)*120000, Message: message})
+	}
+	_, awserr := c.PutLogEvents(PutLogEventsInput{LogGroupName: "group", LogStreamName: "stream", LogEvents: events})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	cases := []struct {
+		query    string
+		expected []string
+	}{
+		{
+			`fields path, latency | filter level = "error" | sort latency desc`,
+			[]string{"path=/b,latency=250", "path=/a,latency=100"},
+		},
+		{
+			`fields path, latency | filter level = "error" | sort latency asc | limit 1`,
+			[]string{"path=/a,latency=100"},
+		},
+		{
+			`filter @message like /ERROR/ | fields @message`,
+			[]string{"@message=plain text ERROR"},
+		},
+		{
+			`filter latency >= 30 and not path = "/b" | fields latency | sort latency`,
+			[]string{"latency=30", "latency=100"},
+		},
+		{
+			`filter level in ["info", "debug"] or latency > 200 | stats count(*) as requests`,
+			[]string{"requests=3"},
+		},
+		{
+			`filter ispresent(level) | stats count(*) as n, avg(latency), max(latency) by path | sort path`,
+			[]string{"path=/a,n=3,avg(latency)=46.666666666666664,max(latency)=100", "path=/b,n=1,avg(latency)=250,max(latency)=250"},
+		},
+		{
+			`stats sum(latency) as total by bin(5m) | sort total`,
+			[]string{"bin(5m)=2023-11-14 22:10:00.000,total=10", "bin(5m)=2023-11-14 22:20:00.000,total=100", "bin(5m)=2023-11-14 22:15:00.000,total=280"},
+		},
+		{
+			`display level | sort @timestamp desc | limit 2`,
+			[]string{"level=error", ""},
+		},
+	}
+	for _, tc := range cases {
+		output := runQuery(t, c, tc.query)
+		got := rows(output)
+		if strings.Join(got, "|") != strings.Join(tc.expected, "|") {
+			t.Fatalf("Expected %q for %s, got %q", tc.expected, tc.query, got)
+		}
+	}
+
+	// Without fields, the timestamp and message are returned, latest first.
+	output := runQuery(t, c, `filter level = "info"`)
+	if len(output.Results) != 2 || output.Results[0][0].Field != "@timestamp" || output.Results[0][1].Value != messages[2] ||
+		output.Results[0][2].Field != "@ptr" || output.Statistics.RecordsScanned != 5 || output.Statistics.RecordsMatched != 2 {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	for _, query := range []string{"", "parse @message 'a*b' as c", "filter level ==", "stats median(latency)", "sort a b c"} {
+		_, awserr := c.StartQuery(StartQueryInput{LogGroupName: "group", EndTime: 1, QueryString: query})
+		if awserr == nil {
+			t.Fatal("Expected error for", query)
+		}
+	}
+	_, awserr = c.StartQuery(StartQueryInput{LogGroupName: "missing", EndTime: 1, QueryString: "fields @message"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+	_, awserr = c.GetQueryResults(GetQueryResultsInput{QueryId: "missing"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
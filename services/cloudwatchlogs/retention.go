@@ -0,0 +1,147 @@
+package cloudwatchlogs
+
+import (
+	"container/heap"
+	"slices"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+var validRetentionDays = []int32{
+	1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653,
+}
+
+// expiryCutoff returns the timestamp events must be at or after to be retained, if the group has a retention policy.
+func (g *LogGroup) expiryCutoff(now time.Time) (int64, bool) {
+	if g.RetentionInDays == 0 {
+		return 0, false
+	}
+	return now.Add(-time.Duration(g.RetentionInDays) * 24 * time.Hour).UnixMilli(), true
+}
+
+// lockedRemoveOldestEvents removes the stream's first n events.
+func (c *CloudWatchLogs) lockedRemoveOldestEvents(group *LogGroup, stream *LogStream, n int) {
+	for _, event := range stream.Events[:n] {
+		group.StoredBytes -= int64(len(event.Message))
+	}
+	// Delete zeroes the removed events, so their messages can be garbage collected.
+	stream.Events = slices.Delete(stream.Events, 0, n)
+	stream.removedEvents += n
+}
+
+// streamHeap orders streams by their oldest event, for evicting the oldest events across all groups.
+type streamHeap []streamInGroup
+
+type streamInGroup struct {
+	group  *LogGroup
+	stream *LogStream
+}
+
+func (h streamHeap) Len() int { return len(h) }
+func (h streamHeap) Less(i, j int) bool {
+	return h[i].stream.Events[0].Timestamp < h[j].stream.Events[0].Timestamp
+}
+func (h streamHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *streamHeap) Push(x any)   { *h = append(*h, x.(streamInGroup)) }
+func (h *streamHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// expireEvents deletes events older than their group's retention period, and then the oldest events
+// if more than maxStoredBytes are stored.
+func (c *CloudWatchLogs) expireEvents() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock()
+	var storedBytes int64
+	for _, group := range c.logGroupsByName {
+		if cutoff, ok := group.expiryCutoff(now); ok {
+			for _, stream := range group.Streams {
+				n, _ := slices.BinarySearchFunc(stream.Events, LogEvent{Timestamp: cutoff}, compareEvents)
+				if n > 0 {
+					c.lockedRemoveOldestEvents(group, stream, n)
+				}
+			}
+		}
+		storedBytes += group.StoredBytes
+	}
+
+	if c.maxStoredBytes <= 0 || storedBytes <= c.maxStoredBytes {
+		return
+	}
+	var h streamHeap
+	for _, group := range c.logGroupsByName {
+		for _, stream := range group.Streams {
+			if len(stream.Events) > 0 {
+				h = append(h, streamInGroup{group, stream})
+			}
+		}
+	}
+	heap.Init(&h)
+	evicted := 0
+	for storedBytes > c.maxStoredBytes && h.Len() > 0 {
+		oldest := h[0]
+		// Evict the stream's events until they're no longer the oldest, or enough have been evicted.
+		n := 0
+		for n < len(oldest.stream.Events) && storedBytes > c.maxStoredBytes &&
+			(h.Len() == 1 || oldest.stream.Events[n].Timestamp <= h.oldestAfterTop()) {
+			storedBytes -= int64(len(oldest.stream.Events[n].Message))
+			n++
+		}
+		c.lockedRemoveOldestEvents(oldest.group, oldest.stream, n)
+		evicted += n
+		if len(oldest.stream.Events) == 0 {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	c.logger.Warn("Evicted the oldest log events to stay within the storage limit",
+		"events", evicted, "maxStoredBytes", c.maxStoredBytes)
+}
+
+// oldestAfterTop returns the oldest event timestamp of the streams other than the heap's top.
+// The heap must have at least two streams.
+func (h streamHeap) oldestAfterTop() int64 {
+	// The second smallest element of a heap is one of the top's children.
+	oldest := h[1].stream.Events[0].Timestamp
+	if len(h) > 2 {
+		oldest = min(oldest, h[2].stream.Events[0].Timestamp)
+	}
+	return oldest
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutRetentionPolicy.html
+func (c *CloudWatchLogs) PutRetentionPolicy(input PutRetentionPolicyInput) (*PutRetentionPolicyOutput, *awserrors.Error) {
+	if !slices.Contains(validRetentionDays, input.RetentionInDays) {
+		return nil, InvalidParameterException("1 validation error detected: Value at 'retentionInDays' failed to satisfy constraint: Member must satisfy enum value set")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	group.RetentionInDays = input.RetentionInDays
+	return &PutRetentionPolicyOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DeleteRetentionPolicy.html
+func (c *CloudWatchLogs) DeleteRetentionPolicy(input DeleteRetentionPolicyInput) (*DeleteRetentionPolicyOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	group.RetentionInDays = 0
+	return &DeleteRetentionPolicyOutput{}, nil
+}
@@ -0,0 +1,142 @@
+package cloudwatchlogs
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func putEvents(t *testing.T, c *CloudWatchLogs, group string, stream string, events ...APIInputLogEvent) *PutLogEventsOutput {
+	output, awserr := c.PutLogEvents(PutLogEventsInput{
+		LogGroupName:  group,
+		LogStreamName: stream,
+		LogEvents:     events,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "stream")
+	start := c.clock()
+	day := (24 * time.Hour).Milliseconds()
+
+	_, awserr := c.PutRetentionPolicy(PutRetentionPolicyInput{LogGroupName: "group", RetentionInDays: 2})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+	_, awserr = c.PutRetentionPolicy(PutRetentionPolicyInput{LogGroupName: "missing", RetentionInDays: 1})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+	if _, awserr := c.PutRetentionPolicy(PutRetentionPolicyInput{LogGroupName: "group", RetentionInDays: 3}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	groups, awserr := c.DescribeLogGroups(DescribeLogGroupsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if groups.LogGroups[0].RetentionInDays != 3 {
+		t.Fatal("Unexpected retention", groups.LogGroups[0].RetentionInDays)
+	}
+
+	// Events older than the retention period are rejected as expired.
+	now := start.UnixMilli()
+	hour := time.Hour.Milliseconds()
+	output := putEvents(t, c, "group", "stream",
+		APIInputLogEvent{Timestamp: now - 3*day - hour, Message: "expired"},
+		APIInputLogEvent{Timestamp: now - 2*day - 2*hour, Message: "first"},
+	)
+	if output.RejectedLogEventsInfo == nil || output.RejectedLogEventsInfo.ExpiredLogEventEndIndex == nil ||
+		*output.RejectedLogEventsInfo.ExpiredLogEventEndIndex != 0 || output.RejectedLogEventsInfo.TooOldLogEventEndIndex != nil {
+		t.Fatalf("Unexpected rejected events: %+v", output.RejectedLogEventsInfo)
+	}
+	putEvents(t, c, "group", "stream",
+		APIInputLogEvent{Timestamp: now - day, Message: "second"},
+		APIInputLogEvent{Timestamp: now, Message: "third"},
+	)
+
+	page, awserr := c.GetLogEvents(GetLogEventsInput{LogGroupName: "group", LogStreamName: "stream", StartFromHead: true, Limit: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(messages(page), []string{"first", "second"}) {
+		t.Fatal("Unexpected events", messages(page))
+	}
+
+	// A day and a half later, the first event expires.
+	c.clock = func() time.Time { return start.Add(36 * time.Hour) }
+	c.expireEvents()
+	// Tokens from before events expired are still valid.
+	page, awserr = c.GetLogEvents(GetLogEventsInput{LogGroupName: "group", LogStreamName: "stream", NextToken: page.NextForwardToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(messages(page), []string{"third"}) {
+		t.Fatal("Unexpected events", messages(page))
+	}
+	page, awserr = c.GetLogEvents(GetLogEventsInput{LogGroupName: "group", LogStreamName: "stream", StartFromHead: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(messages(page), []string{"second", "third"}) {
+		t.Fatal("Unexpected events", messages(page))
+	}
+	groups, awserr = c.DescribeLogGroups(DescribeLogGroupsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if groups.LogGroups[0].StoredBytes != int64(len("second")+len("third")) {
+		t.Fatal("Unexpected stored bytes", groups.LogGroups[0].StoredBytes)
+	}
+
+	// Without a retention policy, events never expire.
+	if _, awserr := c.DeleteRetentionPolicy(DeleteRetentionPolicyInput{LogGroupName: "group"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	c.clock = func() time.Time { return start.Add(365 * 24 * time.Hour) }
+	c.expireEvents()
+	page, awserr = c.GetLogEvents(GetLogEventsInput{LogGroupName: "group", LogStreamName: "stream", StartFromHead: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(messages(page), []string{"second", "third"}) {
+		t.Fatal("Unexpected events", messages(page))
+	}
+}
+
+func TestMaxStoredBytes(t *testing.T) {
+	c := newCloudWatchLogs()
+	c.maxStoredBytes = 10
+	createLogStream(t, c, "group1", "stream")
+	createLogStream(t, c, "group2", "stream")
+	now := c.clock().UnixMilli()
+
+	putEvents(t, c, "group1", "stream",
+		APIInputLogEvent{Timestamp: now - 5, Message: "aaa"},
+		APIInputLogEvent{Timestamp: now - 3, Message: "ccc"},
+		APIInputLogEvent{Timestamp: now - 1, Message: "eee"},
+	)
+	putEvents(t, c, "group2", "stream",
+		APIInputLogEvent{Timestamp: now - 4, Message: "bbb"},
+		APIInputLogEvent{Timestamp: now - 2, Message: "ddd"},
+	)
+	c.expireEvents()
+
+	// The oldest events across both groups are evicted until at most 10 bytes are stored.
+	for group, expected := range map[string][]string{
+		"group1": {"ccc", "eee"},
+		"group2": {"ddd"},
+	} {
+		page, awserr := c.GetLogEvents(GetLogEventsInput{LogGroupName: group, LogStreamName: "stream", StartFromHead: true})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if !slices.Equal(messages(page), expected) {
+			t.Fatal("Unexpected events", group, messages(page))
+		}
+	}
+}
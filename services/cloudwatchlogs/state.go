@@ -0,0 +1,143 @@
+package cloudwatchlogs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type streamState struct {
+	*LogStream
+	SequenceNumber int64 `json:",omitempty"`
+	RemovedEvents  int   `json:",omitempty"`
+}
+
+type groupState struct {
+	Name                string
+	ARN                 string
+	CreationTime        time.Time
+	KmsKeyId            string `json:",omitempty"`
+	LogGroupClass       string
+	RetentionInDays     int32 `json:",omitempty"`
+	Tags                map[string]string
+	StoredBytes         int64
+	SubscriptionFilters []*SubscriptionFilter
+	// Sorted by name.
+	Streams []streamState
+}
+
+type groupsState struct {
+	LogGroups   []groupState
+	NextEventId int64
+}
+
+// TryPause pauses changes to each region's log groups while a snapshot copies them.
+func (c *CloudWatchLogs) TryPause() (func(), bool) {
+	return c.regions.TryPause(func(c *CloudWatchLogs) *sync.Mutex { return &c.mu })
+}
+
+// ExportState writes each region's log groups, their streams and events, and their subscription
+// filters to the archive. Queries' results aren't exported.
+func (c *CloudWatchLogs) ExportState(w *state.Writer) error {
+	return c.regions.ExportState(w, (*CloudWatchLogs).exportState)
+}
+
+func (c *CloudWatchLogs) exportState(w *state.Writer) error {
+	unlock := w.Lock(&c.mu)
+	defer unlock()
+
+	exported := groupsState{NextEventId: c.nextEventId}
+	for _, group := range c.logGroupsByName {
+		g := groupState{
+			Name:                group.Name,
+			ARN:                 group.ARN,
+			CreationTime:        group.CreationTime,
+			KmsKeyId:            group.KmsKeyId,
+			LogGroupClass:       group.LogGroupClass,
+			RetentionInDays:     group.RetentionInDays,
+			Tags:                group.Tags,
+			StoredBytes:         group.StoredBytes,
+			SubscriptionFilters: group.SubscriptionFilters,
+		}
+		for _, stream := range group.Streams {
+			g.Streams = append(g.Streams, streamState{
+				LogStream:      stream,
+				SequenceNumber: stream.sequenceNumber,
+				RemovedEvents:  stream.removedEvents,
+			})
+		}
+		slices.SortFunc(g.Streams, func(a, b streamState) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.LogGroups = append(exported.LogGroups, g)
+	}
+	slices.SortFunc(exported.LogGroups, func(a, b groupState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("loggroups.json", exported)
+}
+
+// ImportState replaces each region's log groups with the archive's, and discards queries' results.
+// Subscription filters' patterns are parsed again.
+func (c *CloudWatchLogs) ImportState(r *state.Reader) (func() error, error) {
+	return c.regions.ImportState(r, (*CloudWatchLogs).importState, (*CloudWatchLogs).exportState)
+}
+
+func (c *CloudWatchLogs) importState(r *state.Reader) (func() error, error) {
+	var imported groupsState
+	if err := r.ReadJSON("loggroups.json", &imported); err != nil {
+		return nil, err
+	}
+	logGroupsByName := make(map[string]*LogGroup)
+	for _, g := range imported.LogGroups {
+		if !logGroupNameRegex.MatchString(g.Name) || g.ARN == "" {
+			return nil, fmt.Errorf("invalid log group %q", g.Name)
+		}
+		group := &LogGroup{
+			Name:            g.Name,
+			ARN:             g.ARN,
+			CreationTime:    g.CreationTime,
+			KmsKeyId:        g.KmsKeyId,
+			LogGroupClass:   g.LogGroupClass,
+			RetentionInDays: g.RetentionInDays,
+			Tags:            g.Tags,
+			Streams:         make(map[string]*LogStream),
+			StoredBytes:     g.StoredBytes,
+		}
+		if group.Tags == nil {
+			group.Tags = make(map[string]string)
+		}
+		for _, filter := range g.SubscriptionFilters {
+			pattern, err := parseFilterPattern(filter.FilterPattern)
+			if err != nil {
+				return nil, fmt.Errorf("log group %s: subscription filter %s: %v", g.Name, filter.Name, err)
+			}
+			filter.pattern = pattern
+			group.SubscriptionFilters = append(group.SubscriptionFilters, filter)
+		}
+		for _, s := range g.Streams {
+			stream := s.LogStream
+			if stream == nil || !logStreamNameRegex.MatchString(stream.Name) {
+				return nil, fmt.Errorf("log group %s: invalid log stream", g.Name)
+			}
+			stream.sequenceNumber = s.SequenceNumber
+			stream.removedEvents = s.RemovedEvents
+			group.Streams[stream.Name] = stream
+		}
+		logGroupsByName[group.Name] = group
+	}
+
+	return func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.logGroupsByName = logGroupsByName
+		// New events' IDs must follow the imported ones.
+		c.nextEventId = max(c.nextEventId, imported.NextEventId)
+		c.queriesById = make(map[string]*Query)
+		return nil
+	}, nil
+}
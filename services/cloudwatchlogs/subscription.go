@@ -0,0 +1,330 @@
+package cloudwatchlogs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/services/firehose"
+	"aws-in-a-box/services/kinesis"
+)
+
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/SubscriptionFilters.html
+
+const maxSubscriptionFilters = 2
+
+// LambdaInvoker asynchronously invokes the Lambda functions subscription filters deliver to.
+type LambdaInvoker interface {
+	InvokeAsync(functionArn string, payload []byte) error
+}
+
+// DeliveryStreams finds the Firehose delivery streams subscription filters deliver to.
+type DeliveryStreams interface {
+	// ForARN returns the delivery streams of the region the delivery stream ARN is in.
+	ForARN(deliveryStreamArn string) (*firehose.Firehose, error)
+}
+
+type SubscriptionFilter struct {
+	Name           string
+	FilterPattern  string
+	DestinationArn string
+	RoleArn        string
+	Distribution   string
+	CreationTime   time.Time
+	pattern        filterPattern
+}
+
+func (f *SubscriptionFilter) toAPI(logGroupName string) APISubscriptionFilter {
+	return APISubscriptionFilter{
+		FilterName:     f.Name,
+		LogGroupName:   logGroupName,
+		FilterPattern:  f.FilterPattern,
+		DestinationArn: f.DestinationArn,
+		RoleArn:        f.RoleArn,
+		Distribution:   f.Distribution,
+		CreationTime:   f.CreationTime.UnixMilli(),
+	}
+}
+
+// subscriptionMessage is delivered to destinations, gzipped. Kinesis and Firehose records have the gzipped message as
+// their data, and Lambda functions receive it base64 encoded in a subscriptionEvent.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/SubscriptionFilters.html#DestinationKinesisExample
+type subscriptionMessage struct {
+	MessageType         string                 `json:"messageType"`
+	Owner               string                 `json:"owner"`
+	LogGroup            string                 `json:"logGroup"`
+	LogStream           string                 `json:"logStream"`
+	SubscriptionFilters []string               `json:"subscriptionFilters"`
+	LogEvents           []subscriptionLogEvent `json:"logEvents"`
+}
+
+type subscriptionLogEvent struct {
+	Id        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// subscriptionEvent is the payload subscribed Lambda functions are invoked with.
+// https://docs.aws.amazon.com/lambda/latest/dg/services-cloudwatchlogs.html
+type subscriptionEvent struct {
+	Awslogs struct {
+		Data string `json:"data"`
+	} `json:"awslogs"`
+}
+
+func gzipMessage(message subscriptionMessage) []byte {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		panic(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(encoded)
+	w.Close()
+	return buf.Bytes()
+}
+
+// deliver sends the gzipped message to the filter's destination.
+func (c *CloudWatchLogs) deliver(destinationArn string, partitionKey string, data []byte) error {
+	a, err := arn.Parse(destinationArn)
+	if err != nil {
+		return err
+	}
+	switch service := a.Service; service {
+	case "kinesis":
+		if c.kinesis == nil {
+			return fmt.Errorf("Kinesis is not enabled")
+		}
+		streams, err := c.kinesis.ForARN(destinationArn)
+		if err != nil {
+			return err
+		}
+		_, awserr := streams.PutRecord(kinesis.PutRecordInput{
+			StreamARN:    destinationArn,
+			PartitionKey: partitionKey,
+			Data:         base64.StdEncoding.EncodeToString(data),
+		})
+		if awserr != nil {
+			return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+		}
+		return nil
+	case "firehose":
+		if c.firehose == nil {
+			return fmt.Errorf("Firehose is not enabled")
+		}
+		streams, err := c.firehose.ForARN(destinationArn)
+		if err != nil {
+			return err
+		}
+		_, awserr := streams.PutRecord(firehose.PutRecordInput{
+			DeliveryStreamName: a.ResourceId(),
+			Record:             firehose.APIRecord{Data: data},
+		})
+		if awserr != nil {
+			return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+		}
+		return nil
+	case "lambda":
+		if c.lambda == nil {
+			return fmt.Errorf("Lambda is not enabled")
+		}
+		var event subscriptionEvent
+		event.Awslogs.Data = base64.StdEncoding.EncodeToString(data)
+		payload, _ := json.Marshal(event)
+		return c.lambda.InvokeAsync(destinationArn, payload)
+	default:
+		return fmt.Errorf("unsupported destination %s", destinationArn)
+	}
+}
+
+// lockedDeliverToSubscriptions asynchronously delivers the events added to the stream
+// to the group's subscription filters which match them.
+func (c *CloudWatchLogs) lockedDeliverToSubscriptions(group *LogGroup, stream *LogStream, events []LogEvent) {
+	for _, filter := range group.SubscriptionFilters {
+		message := subscriptionMessage{
+			MessageType:         "DATA_MESSAGE",
+			Owner:               c.arnGenerator.AwsAccountId,
+			LogGroup:            group.Name,
+			LogStream:           stream.Name,
+			SubscriptionFilters: []string{filter.Name},
+			LogEvents:           []subscriptionLogEvent{},
+		}
+		for _, event := range events {
+			if filter.pattern.matches(event.Message) {
+				message.LogEvents = append(message.LogEvents, subscriptionLogEvent{
+					Id:        strconv.FormatInt(event.Id, 10),
+					Timestamp: event.Timestamp,
+					Message:   event.Message,
+				})
+			}
+		}
+		if len(message.LogEvents) == 0 {
+			continue
+		}
+
+		// Events from a stream go to the same shard, unless they're distributed randomly.
+		partitionKey := group.Name + ":" + stream.Name
+		if filter.Distribution == "Random" {
+			partitionKey = uuid.Must(uuid.NewV4()).String()
+		}
+		destinationArn := filter.DestinationArn
+		data := gzipMessage(message)
+		go func() {
+			if err := c.deliver(destinationArn, partitionKey, data); err != nil {
+				c.logger.Warn("Failed to deliver log events to subscription filter",
+					"logGroup", message.LogGroup, "filter", message.SubscriptionFilters[0], "destination", destinationArn, "error", err)
+			}
+		}()
+	}
+}
+
+// validateDestination checks the destination can be delivered to. Like AWS, it sends Kinesis streams and Firehose
+// delivery streams a control message.
+func (c *CloudWatchLogs) validateDestination(group *LogGroup, destinationArn string) *awserrors.Error {
+	a, err := arn.Parse(destinationArn)
+	if err != nil {
+		return InvalidParameterException("Invalid destinationArn: " + destinationArn)
+	}
+	switch a.Service {
+	case "kinesis", "firehose":
+		destination := "Kinesis stream"
+		if a.Service == "firehose" {
+			destination = "Firehose"
+		}
+		control := subscriptionMessage{
+			MessageType:         "CONTROL_MESSAGE",
+			Owner:               "CloudwatchLogs",
+			LogGroup:            "",
+			LogStream:           "",
+			SubscriptionFilters: []string{},
+			LogEvents: []subscriptionLogEvent{{
+				Id:        "",
+				Timestamp: c.clock().UnixMilli(),
+				Message:   "CWL CONTROL MESSAGE: Checking health of destination " + destination + ".",
+			}},
+		}
+		if err := c.deliver(destinationArn, group.Name, gzipMessage(control)); err != nil {
+			return InvalidParameterException("Could not deliver test message to specified destination. Check if the destination is valid.")
+		}
+	case "lambda":
+		if c.lambda == nil {
+			return InvalidParameterException("Could not execute the lambda function. Make sure you have given CloudWatch Logs permission to execute your function.")
+		}
+	default:
+		// Cross-account destinations aren't emulated.
+		return InvalidParameterException("Only Kinesis stream, Firehose delivery stream and Lambda function destinations are supported: " + destinationArn)
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutSubscriptionFilter.html
+func (c *CloudWatchLogs) PutSubscriptionFilter(input PutSubscriptionFilterInput) (*PutSubscriptionFilterOutput, *awserrors.Error) {
+	if len(input.FilterName) < 1 || len(input.FilterName) > 512 || strings.ContainsAny(input.FilterName, ":*") {
+		return nil, InvalidParameterException("Filter names must be between 1 and 512 characters, and can't contain : or *")
+	}
+	switch input.Distribution {
+	case "":
+		input.Distribution = "ByLogStream"
+	case "ByLogStream", "Random":
+	default:
+		return nil, InvalidParameterException("Invalid distribution: " + input.Distribution)
+	}
+	pattern, err := parseFilterPattern(input.FilterPattern)
+	if err != nil {
+		return nil, InvalidParameterException("Invalid filter pattern: " + err.Error())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	i := slices.IndexFunc(group.SubscriptionFilters, func(f *SubscriptionFilter) bool { return f.Name == input.FilterName })
+	if i == -1 && len(group.SubscriptionFilters) >= maxSubscriptionFilters {
+		return nil, LimitExceededException("Resource limit exceeded.")
+	}
+	if awserr := c.validateDestination(group, input.DestinationArn); awserr != nil {
+		return nil, awserr
+	}
+
+	filter := &SubscriptionFilter{
+		Name:           input.FilterName,
+		FilterPattern:  input.FilterPattern,
+		DestinationArn: input.DestinationArn,
+		RoleArn:        input.RoleArn,
+		Distribution:   input.Distribution,
+		CreationTime:   c.clock(),
+		pattern:        pattern,
+	}
+	if i == -1 {
+		group.SubscriptionFilters = append(group.SubscriptionFilters, filter)
+	} else {
+		group.SubscriptionFilters[i] = filter
+	}
+	return &PutSubscriptionFilterOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DescribeSubscriptionFilters.html
+func (c *CloudWatchLogs) DescribeSubscriptionFilters(input DescribeSubscriptionFiltersInput) (*DescribeSubscriptionFiltersOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, defaultMaxResults, maxMaxResults, input.NextToken,
+		InvalidParameterException("limit must be between 1 and 50."),
+		InvalidParameterException("The specified nextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	var filters []APISubscriptionFilter
+	for _, filter := range group.SubscriptionFilters {
+		if strings.HasPrefix(filter.Name, input.FilterNamePrefix) {
+			filters = append(filters, filter.toAPI(group.Name))
+		}
+	}
+	output := &DescribeSubscriptionFiltersOutput{
+		SubscriptionFilters: []APISubscriptionFilter{},
+	}
+	for i := start; i < len(filters); i++ {
+		if len(output.SubscriptionFilters) == limit {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		output.SubscriptionFilters = append(output.SubscriptionFilters, filters[i])
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_DeleteSubscriptionFilter.html
+func (c *CloudWatchLogs) DeleteSubscriptionFilter(input DeleteSubscriptionFilterInput) (*DeleteSubscriptionFilterOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, awserr := c.lockedGetLogGroup(input.LogGroupName, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	i := slices.IndexFunc(group.SubscriptionFilters, func(f *SubscriptionFilter) bool { return f.Name == input.FilterName })
+	if i == -1 {
+		return nil, ResourceNotFoundException("The specified subscription filter does not exist.")
+	}
+	group.SubscriptionFilters = slices.Delete(slices.Clone(group.SubscriptionFilters), i, i+1)
+	return &DeleteSubscriptionFilterOutput{}, nil
+}
@@ -0,0 +1,353 @@
+package cloudwatchlogs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/services/firehose"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/s3"
+)
+
+type fakeLambdaInvoker struct {
+	mu       sync.Mutex
+	payloads map[string][][]byte
+}
+
+func (f *fakeLambdaInvoker) InvokeAsync(functionArn string, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payloads[functionArn] = append(f.payloads[functionArn], payload)
+	return nil
+}
+
+func (f *fakeLambdaInvoker) get(functionArn string) [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.payloads[functionArn]
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for condition")
+}
+
+func decodeMessage(t *testing.T, data string) subscriptionMessage {
+	compressed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message subscriptionMessage
+	if err := json.Unmarshal(decompressed, &message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestSubscriptionFilterLambda(t *testing.T) {
+	c := newCloudWatchLogs()
+	createLogStream(t, c, "group", "stream")
+	functionArn := "arn:aws:lambda:us-east-1:123456789012:function:forwarder"
+
+	input := PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "errors",
+		FilterPattern:  "ERROR",
+		DestinationArn: functionArn,
+	}
+	_, awserr := c.PutSubscriptionFilter(input)
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter without Lambda", awserr)
+	}
+
+	invoker := &fakeLambdaInvoker{payloads: map[string][][]byte{}}
+	c.SetLambda(invoker)
+	if _, awserr := c.PutSubscriptionFilter(input); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	if err := c.WriteLogs("group", "stream", []string{"INFO started", "ERROR failed", "ERROR again"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(invoker.get(functionArn)) == 1 })
+
+	var event subscriptionEvent
+	if err := json.Unmarshal(invoker.get(functionArn)[0], &event); err != nil {
+		t.Fatal(err)
+	}
+	message := decodeMessage(t, event.Awslogs.Data)
+	if message.MessageType != "DATA_MESSAGE" || message.Owner != "123456789012" ||
+		message.LogGroup != "group" || message.LogStream != "stream" ||
+		len(message.SubscriptionFilters) != 1 || message.SubscriptionFilters[0] != "errors" {
+		t.Fatal("Unexpected message", message)
+	}
+	if len(message.LogEvents) != 2 || message.LogEvents[0].Message != "ERROR failed" || message.LogEvents[1].Message != "ERROR again" {
+		t.Fatal("Unexpected events", message.LogEvents)
+	}
+
+	// Events which don't match aren't delivered.
+	if err := c.WriteLogs("group", "stream", []string{"INFO done"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(invoker.get(functionArn)) != 1 {
+		t.Fatal("Unexpected delivery", len(invoker.get(functionArn)))
+	}
+}
+
+func TestSubscriptionFilterKinesis(t *testing.T) {
+	k := kinesis.New(kinesis.Options{ArnGenerator: newCloudWatchLogs().arnGenerator})
+	if _, awserr := k.CreateStream(kinesis.CreateStreamInput{StreamName: "logs", ShardCount: 1}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	c := New(Options{ArnGenerator: newCloudWatchLogs().arnGenerator, Kinesis: k})
+	createLogStream(t, c, "group", "stream")
+
+	streamArn := "arn:aws:kinesis:us-east-1:123456789012:stream/logs"
+	_, awserr := c.PutSubscriptionFilter(PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "missing",
+		DestinationArn: "arn:aws:kinesis:us-east-1:123456789012:stream/missing",
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter for missing stream", awserr)
+	}
+	if _, awserr := c.PutSubscriptionFilter(PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "all",
+		DestinationArn: streamArn,
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	if err := c.WriteLogs("group", "stream", []string{"hello", "world"}); err != nil {
+		t.Fatal(err)
+	}
+
+	shards, awserr := k.ListShards(kinesis.ListShardsInput{StreamName: "logs"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	iterator, awserr := k.GetShardIterator(kinesis.GetShardIteratorInput{
+		StreamName:        "logs",
+		ShardId:           shards.Shards[0].ShardId,
+		ShardIteratorType: "TRIM_HORIZON",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var records []kinesis.APIRecord
+	waitFor(t, func() bool {
+		output, awserr := k.GetRecords(kinesis.GetRecordsInput{ShardIterator: iterator.ShardIterator})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		records = output.Records
+		return len(records) == 2
+	})
+
+	control := decodeMessage(t, records[0].Data)
+	if control.MessageType != "CONTROL_MESSAGE" {
+		t.Fatal("Expected control message", control)
+	}
+	message := decodeMessage(t, records[1].Data)
+	if message.MessageType != "DATA_MESSAGE" || len(message.LogEvents) != 2 ||
+		message.LogEvents[0].Message != "hello" || message.LogEvents[1].Message != "world" {
+		t.Fatal("Unexpected message", message)
+	}
+	if records[1].PartitionKey != "group:stream" {
+		t.Fatal("Unexpected partition key", records[1].PartitionKey)
+	}
+}
+
+func TestSubscriptionFilterFirehose(t *testing.T) {
+	s, err := s3.New(s3.Options{PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := s.CreateBucket(s3.CreateBucketInput{Bucket: "logs"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	f := firehose.New(firehose.Options{ArnGenerator: newCloudWatchLogs().arnGenerator, S3: s})
+	interval := 0
+	if _, awserr := f.CreateDeliveryStream(firehose.CreateDeliveryStreamInput{
+		DeliveryStreamName: "logs",
+		S3DestinationConfiguration: &firehose.APIS3DestinationConfiguration{
+			BucketARN:      "arn:aws:s3:::logs",
+			RoleARN:        "arn:aws:iam::123456789012:role/firehose",
+			BufferingHints: &firehose.APIBufferingHints{IntervalInSeconds: &interval},
+		},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	c := New(Options{ArnGenerator: newCloudWatchLogs().arnGenerator, Firehose: f})
+	createLogStream(t, c, "group", "stream")
+
+	_, awserr := c.PutSubscriptionFilter(PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "missing",
+		DestinationArn: "arn:aws:firehose:us-east-1:123456789012:deliverystream/missing",
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter for missing delivery stream", awserr)
+	}
+	if _, awserr := c.PutSubscriptionFilter(PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "all",
+		DestinationArn: "arn:aws:firehose:us-east-1:123456789012:deliverystream/logs",
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if err := c.WriteLogs("group", "stream", []string{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each record is delivered to its own object, since the buffering interval is zero.
+	var objects []s3.ListObjectsV2Object
+	waitFor(t, func() bool {
+		output, awserr := s.ListObjectsV2(s3.ListObjectsV2Input{Bucket: "logs"})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		objects = output.Contents
+		return len(objects) == 2
+	})
+	var messages []subscriptionMessage
+	for _, object := range objects {
+		output, awserr := s.GetObject(s3.GetObjectInput{Bucket: "logs", Key: object.Key})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		data, err := io.ReadAll(output.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, decodeMessage(t, base64.StdEncoding.EncodeToString(data)))
+	}
+	types := []string{messages[0].MessageType, messages[1].MessageType}
+	slices.Sort(types)
+	if types[0] != "CONTROL_MESSAGE" || types[1] != "DATA_MESSAGE" {
+		t.Fatal("Unexpected messages", messages)
+	}
+}
+
+func TestSubscriptionFilters(t *testing.T) {
+	c := newCloudWatchLogs()
+	c.SetLambda(&fakeLambdaInvoker{payloads: map[string][][]byte{}})
+	createLogStream(t, c, "group", "stream")
+
+	for _, name := range []string{"one", "two", "three"} {
+		_, awserr := c.PutSubscriptionFilter(PutSubscriptionFilterInput{
+			LogGroupName:   "group",
+			FilterName:     name,
+			DestinationArn: "arn:aws:lambda:us-east-1:123456789012:function:" + name,
+		})
+		if name == "three" {
+			if awserr == nil || awserr.Body.Type != "LimitExceededException" {
+				t.Fatal("Expected limit exceeded", awserr)
+			}
+		} else if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	// Replacing an existing filter doesn't count towards the limit.
+	if _, awserr := c.PutSubscriptionFilter(PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "two",
+		FilterPattern:  "ERROR",
+		DestinationArn: "arn:aws:lambda:us-east-1:123456789012:function:two",
+		Distribution:   "Random",
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	output, awserr := c.DescribeSubscriptionFilters(DescribeSubscriptionFiltersInput{LogGroupName: "group", FilterNamePrefix: "t"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.SubscriptionFilters) != 1 {
+		t.Fatal("Unexpected filters", output.SubscriptionFilters)
+	}
+	filter := output.SubscriptionFilters[0]
+	if filter.FilterName != "two" || filter.FilterPattern != "ERROR" || filter.Distribution != "Random" || filter.LogGroupName != "group" {
+		t.Fatal("Unexpected filter", filter)
+	}
+
+	if _, awserr := c.DeleteSubscriptionFilter(DeleteSubscriptionFilterInput{LogGroupName: "group", FilterName: "one"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.DeleteSubscriptionFilter(DeleteSubscriptionFilterInput{LogGroupName: "group", FilterName: "one"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+	output, awserr = c.DescribeSubscriptionFilters(DescribeSubscriptionFiltersInput{LogGroupName: "group"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.SubscriptionFilters) != 1 || output.SubscriptionFilters[0].FilterName != "two" {
+		t.Fatal("Unexpected filters", output.SubscriptionFilters)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	c := newCloudWatchLogs()
+	if _, err := c.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected an unknown region to fail")
+	}
+	invoker := &fakeLambdaInvoker{payloads: map[string][][]byte{}}
+	c.SetLambda(invoker)
+
+	// Regions first used after SetLambda can still deliver to functions.
+	europe, err := c.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createLogStream(t, europe, "group", "stream")
+	output, awserr := c.DescribeLogGroups(DescribeLogGroupsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.LogGroups) != 0 {
+		t.Fatal("Expected another region's group to be hidden", output.LogGroups)
+	}
+	output, awserr = europe.DescribeLogGroups(DescribeLogGroupsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.LogGroups) != 1 || output.LogGroups[0].Arn != "arn:aws:logs:eu-west-1:123456789012:log-group:group:*" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	functionArn := "arn:aws:lambda:eu-west-1:123456789012:function:fn"
+	if _, awserr := europe.PutSubscriptionFilter(PutSubscriptionFilterInput{
+		LogGroupName:   "group",
+		FilterName:     "all",
+		DestinationArn: functionArn,
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if err := europe.WriteLogs("group", "stream", []string{"started"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(invoker.get(functionArn)) == 1 })
+}
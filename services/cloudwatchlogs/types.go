@@ -0,0 +1,278 @@
+package cloudwatchlogs
+
+// CloudWatch Logs' JSON fields are camelCase, unlike most services.
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_LogGroup.html
+type APILogGroup struct {
+	LogGroupName    string `json:"logGroupName"`
+	Arn             string `json:"arn"`
+	LogGroupArn     string `json:"logGroupArn"`
+	CreationTime    int64  `json:"creationTime"`
+	RetentionInDays int32  `json:"retentionInDays,omitempty"`
+	StoredBytes     int64  `json:"storedBytes"`
+	KmsKeyId        string `json:"kmsKeyId,omitempty"`
+	LogGroupClass   string `json:"logGroupClass"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_LogStream.html
+type APILogStream struct {
+	LogStreamName       string `json:"logStreamName"`
+	Arn                 string `json:"arn"`
+	CreationTime        int64  `json:"creationTime"`
+	FirstEventTimestamp int64  `json:"firstEventTimestamp,omitempty"`
+	LastEventTimestamp  int64  `json:"lastEventTimestamp,omitempty"`
+	LastIngestionTime   int64  `json:"lastIngestionTime,omitempty"`
+	UploadSequenceToken string `json:"uploadSequenceToken,omitempty"`
+	StoredBytes         int64  `json:"storedBytes"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_InputLogEvent.html
+type APIInputLogEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message" required:"true" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_OutputLogEvent.html
+type APIOutputLogEvent struct {
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+	IngestionTime int64  `json:"ingestionTime"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_RejectedLogEventsInfo.html
+type APIRejectedLogEventsInfo struct {
+	TooNewLogEventStartIndex *int `json:"tooNewLogEventStartIndex,omitempty"`
+	TooOldLogEventEndIndex   *int `json:"tooOldLogEventEndIndex,omitempty"`
+	ExpiredLogEventEndIndex  *int `json:"expiredLogEventEndIndex,omitempty"`
+}
+
+type CreateLogGroupInput struct {
+	LogGroupName  string            `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	KmsKeyId      string            `json:"kmsKeyId" length:",256" error:"InvalidParameterException"`
+	Tags          map[string]string `json:"tags" length:"1,50" error:"InvalidParameterException"`
+	LogGroupClass string            `json:"logGroupClass" enum:"STANDARD|INFREQUENT_ACCESS|DELIVERY" error:"InvalidParameterException"`
+}
+
+type CreateLogGroupOutput struct{}
+
+type DeleteLogGroupInput struct {
+	LogGroupName string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+}
+
+type DeleteLogGroupOutput struct{}
+
+type DescribeLogGroupsInput struct {
+	AccountIdentifiers    []string `json:"accountIdentifiers" length:",20" error:"InvalidParameterException"`
+	LogGroupNamePrefix    string   `json:"logGroupNamePrefix" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogGroupNamePattern   string   `json:"logGroupNamePattern" length:",512" pattern:"[\\.\\-_/#A-Za-z0-9]*" error:"InvalidParameterException"`
+	IncludeLinkedAccounts bool     `json:"includeLinkedAccounts"`
+	LogGroupClass         string   `json:"logGroupClass" enum:"STANDARD|INFREQUENT_ACCESS|DELIVERY" error:"InvalidParameterException"`
+	LogGroupIdentifiers   []string `json:"logGroupIdentifiers" length:"1,50" error:"InvalidParameterException"`
+	Limit                 int      `json:"limit" range:"1,50" error:"InvalidParameterException"`
+	NextToken             string   `json:"nextToken" length:"1," error:"InvalidParameterException"`
+}
+
+type DescribeLogGroupsOutput struct {
+	LogGroups []APILogGroup `json:"logGroups"`
+	NextToken string        `json:"nextToken,omitempty"`
+}
+
+type CreateLogStreamInput struct {
+	LogGroupName  string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogStreamName string `json:"logStreamName" required:"true" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+}
+
+type CreateLogStreamOutput struct{}
+
+type DeleteLogStreamInput struct {
+	LogGroupName  string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogStreamName string `json:"logStreamName" required:"true" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+}
+
+type DeleteLogStreamOutput struct{}
+
+type DescribeLogStreamsInput struct {
+	LogGroupName        string `json:"logGroupName" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogGroupIdentifier  string `json:"logGroupIdentifier" length:"1,2048" pattern:"[\\w#+=/:,.@-]*" error:"InvalidParameterException"`
+	LogStreamNamePrefix string `json:"logStreamNamePrefix" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+	// LogStreamName or LastEventTime.
+	OrderBy    string `json:"orderBy" enum:"LogStreamName|LastEventTime" error:"InvalidParameterException"`
+	Descending bool   `json:"descending"`
+	Limit      int    `json:"limit" range:"1,50" error:"InvalidParameterException"`
+	NextToken  string `json:"nextToken" length:"1," error:"InvalidParameterException"`
+}
+
+type DescribeLogStreamsOutput struct {
+	LogStreams []APILogStream `json:"logStreams"`
+	NextToken  string         `json:"nextToken,omitempty"`
+}
+
+type PutLogEventsInput struct {
+	LogGroupName  string             `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogStreamName string             `json:"logStreamName" required:"true" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+	LogEvents     []APIInputLogEvent `json:"logEvents" required:"true" length:"1,10000" error:"InvalidParameterException"`
+	SequenceToken string             `json:"sequenceToken" length:"1," error:"InvalidParameterException"`
+	Entity        any                `json:"entity"`
+}
+
+type PutLogEventsOutput struct {
+	NextSequenceToken     string                    `json:"nextSequenceToken"`
+	RejectedLogEventsInfo *APIRejectedLogEventsInfo `json:"rejectedLogEventsInfo,omitempty"`
+}
+
+type GetLogEventsInput struct {
+	LogGroupName       string `json:"logGroupName" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogGroupIdentifier string `json:"logGroupIdentifier" length:"1,2048" pattern:"[\\w#+=/:,.@-]*" error:"InvalidParameterException"`
+	LogStreamName      string `json:"logStreamName" required:"true" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+	// Milliseconds since the epoch. The start is inclusive and the end exclusive.
+	StartTime *int64 `json:"startTime"`
+	EndTime   *int64 `json:"endTime"`
+	// Defaults to false, which returns the latest events.
+	StartFromHead bool   `json:"startFromHead"`
+	Unmask        bool   `json:"unmask"`
+	Limit         int    `json:"limit" range:"1,10000" error:"InvalidParameterException"`
+	NextToken     string `json:"nextToken" length:"1," error:"InvalidParameterException"`
+}
+
+type GetLogEventsOutput struct {
+	Events            []APIOutputLogEvent `json:"events"`
+	NextForwardToken  string              `json:"nextForwardToken"`
+	NextBackwardToken string              `json:"nextBackwardToken"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_FilteredLogEvent.html
+type APIFilteredLogEvent struct {
+	LogStreamName string `json:"logStreamName"`
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+	IngestionTime int64  `json:"ingestionTime"`
+	EventId       string `json:"eventId"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_SearchedLogStream.html
+type APISearchedLogStream struct {
+	LogStreamName      string `json:"logStreamName"`
+	SearchedCompletely bool   `json:"searchedCompletely"`
+}
+
+type FilterLogEventsInput struct {
+	LogGroupName        string   `json:"logGroupName" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogGroupIdentifier  string   `json:"logGroupIdentifier" length:"1,2048" pattern:"[\\w#+=/:,.@-]*" error:"InvalidParameterException"`
+	LogStreamNames      []string `json:"logStreamNames" length:"1,100" error:"InvalidParameterException"`
+	LogStreamNamePrefix string   `json:"logStreamNamePrefix" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+	// Milliseconds since the epoch. The start is inclusive and the end exclusive.
+	StartTime     *int64 `json:"startTime"`
+	EndTime       *int64 `json:"endTime"`
+	FilterPattern string `json:"filterPattern" length:",1024" error:"InvalidParameterException"`
+	// Deprecated, and ignored since events are always interleaved.
+	Interleaved bool   `json:"interleaved"`
+	Unmask      bool   `json:"unmask"`
+	Limit       int    `json:"limit" range:"1,10000" error:"InvalidParameterException"`
+	NextToken   string `json:"nextToken" length:"1," error:"InvalidParameterException"`
+}
+
+type FilterLogEventsOutput struct {
+	Events             []APIFilteredLogEvent  `json:"events"`
+	SearchedLogStreams []APISearchedLogStream `json:"searchedLogStreams"`
+	NextToken          string                 `json:"nextToken,omitempty"`
+}
+
+type StartQueryInput struct {
+	LogGroupName        string   `json:"logGroupName" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	LogGroupNames       []string `json:"logGroupNames"`
+	LogGroupIdentifiers []string `json:"logGroupIdentifiers" length:",50" error:"InvalidParameterException"`
+	// Seconds since the epoch. Both are inclusive.
+	StartTime     int64  `json:"startTime"`
+	EndTime       int64  `json:"endTime"`
+	QueryString   string `json:"queryString" required:"true" length:",10000" error:"InvalidParameterException"`
+	QueryLanguage string `json:"queryLanguage" enum:"CWLI|SQL|PPL" error:"InvalidParameterException"`
+	Limit         int    `json:"limit" range:"1,10000" error:"InvalidParameterException"`
+}
+
+type StartQueryOutput struct {
+	QueryId string `json:"queryId"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_ResultField.html
+type APIResultField struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_QueryStatistics.html
+type APIQueryStatistics struct {
+	RecordsMatched float64 `json:"recordsMatched"`
+	RecordsScanned float64 `json:"recordsScanned"`
+	BytesScanned   float64 `json:"bytesScanned"`
+}
+
+type GetQueryResultsInput struct {
+	QueryId string `json:"queryId" required:"true" length:",256" error:"InvalidParameterException"`
+}
+
+type GetQueryResultsOutput struct {
+	QueryLanguage string             `json:"queryLanguage"`
+	Results       [][]APIResultField `json:"results"`
+	Statistics    APIQueryStatistics `json:"statistics"`
+	// Queries run when they're started, so they're always Complete.
+	Status string `json:"status"`
+}
+
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_SubscriptionFilter.html
+type APISubscriptionFilter struct {
+	FilterName             string `json:"filterName"`
+	LogGroupName           string `json:"logGroupName"`
+	FilterPattern          string `json:"filterPattern"`
+	DestinationArn         string `json:"destinationArn"`
+	RoleArn                string `json:"roleArn,omitempty"`
+	Distribution           string `json:"distribution"`
+	ApplyOnTransformedLogs bool   `json:"applyOnTransformedLogs"`
+	CreationTime           int64  `json:"creationTime"`
+}
+
+type PutSubscriptionFilterInput struct {
+	LogGroupName   string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	FilterName     string `json:"filterName" required:"true" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+	FilterPattern  string `json:"filterPattern" length:",1024" error:"InvalidParameterException"`
+	DestinationArn string `json:"destinationArn" required:"true" length:"1," error:"InvalidParameterException"`
+	RoleArn        string `json:"roleArn" length:"1," error:"InvalidParameterException"`
+	// ByLogStream or Random.
+	Distribution           string   `json:"distribution" enum:"Random|ByLogStream" error:"InvalidParameterException"`
+	ApplyOnTransformedLogs bool     `json:"applyOnTransformedLogs"`
+	FieldSelectionCriteria string   `json:"fieldSelectionCriteria" length:",2000" error:"InvalidParameterException"`
+	EmitSystemFields       []string `json:"emitSystemFields"`
+}
+
+type PutSubscriptionFilterOutput struct{}
+
+type DescribeSubscriptionFiltersInput struct {
+	LogGroupName     string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	FilterNamePrefix string `json:"filterNamePrefix" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+	Limit            int    `json:"limit" range:"1,50" error:"InvalidParameterException"`
+	NextToken        string `json:"nextToken" length:"1," error:"InvalidParameterException"`
+}
+
+type DescribeSubscriptionFiltersOutput struct {
+	SubscriptionFilters []APISubscriptionFilter `json:"subscriptionFilters"`
+	NextToken           string                  `json:"nextToken,omitempty"`
+}
+
+type DeleteSubscriptionFilterInput struct {
+	LogGroupName string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	FilterName   string `json:"filterName" required:"true" length:"1,512" pattern:"[^:*]*" error:"InvalidParameterException"`
+}
+
+type DeleteSubscriptionFilterOutput struct{}
+
+type PutRetentionPolicyInput struct {
+	LogGroupName    string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+	RetentionInDays int32  `json:"retentionInDays" required:"true" range:"1,3653" error:"InvalidParameterException"`
+}
+
+type PutRetentionPolicyOutput struct{}
+
+type DeleteRetentionPolicyInput struct {
+	LogGroupName string `json:"logGroupName" required:"true" length:"1,512" pattern:"[\\.\\-_/#A-Za-z0-9]+" error:"InvalidParameterException"`
+}
+
+type DeleteRetentionPolicyOutput struct{}
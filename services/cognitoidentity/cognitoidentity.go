@@ -0,0 +1,285 @@
+package cognitoidentity
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+)
+
+const maxListIdentityPoolsResults = 60
+
+var (
+	identityPoolNameRegex = regexp.MustCompile(`^[\w\s+=,.@-]{1,128}$`)
+	providerNameRegex     = regexp.MustCompile(`^cognito-idp\.[\w-]+\.amazonaws\.com/([\w-]+_[0-9a-zA-Z]+)$`)
+	roleArnRegex          = regexp.MustCompile(`^arn:aws:iam::\d{12}:role/.+$`)
+)
+
+// UserPools verifies the ID tokens of Cognito user pools, which identities log in with.
+type UserPools interface {
+	VerifyIdToken(userPoolId string, token string) (map[string]any, error)
+}
+
+type IdentityPool struct {
+	Config APIIdentityPool
+	// authenticated and unauthenticated.
+	Roles map[string]string
+	// By provider name.
+	RoleMappings map[string]APIRoleMapping
+	CreatedAt    time.Time
+
+	// Identities by each of their logins' provider name and subject.
+	identityIdsByLogin map[string]string
+}
+
+type Identity struct {
+	Id             string
+	IdentityPoolId string
+	// The subject of each provider's token the identity logged in with, by provider name.
+	// Empty for unauthenticated identities.
+	Logins         map[string]string
+	CreatedAt      time.Time
+	LastModifiedAt time.Time
+}
+
+type CognitoIdentity struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Nil if Cognito user pools are not enabled.
+	userPools UserPools
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu             sync.Mutex
+	poolsById      map[string]*IdentityPool
+	identitiesById map[string]*Identity
+	// Shared by the instances of all regions, which each have their own identity pools and identities.
+	regions *region.Regions[*CognitoIdentity]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// Tokens from Cognito user pools are verified with this.
+	UserPools UserPools
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *CognitoIdentity {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// User pools' IDs say their regions, so every region verifies tokens with the same service.
+	c := newRegion(options)
+	c.regions = region.New(options.ArnGenerator.Region, options.Regions, c, func(name string) (*CognitoIdentity, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = c.regions
+		return r, nil
+	})
+	return c
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *CognitoIdentity {
+	return &CognitoIdentity{
+		logger:         options.Logger,
+		arnGenerator:   options.ArnGenerator,
+		userPools:      options.UserPools,
+		clock:          clock.Now,
+		poolsById:      make(map[string]*IdentityPool),
+		identitiesById: make(map[string]*Identity),
+	}
+}
+
+// InRegion returns the identity pools and identities of the region. It fails if the region isn't
+// one of Options.Regions.
+func (c *CognitoIdentity) InRegion(name string) (*CognitoIdentity, error) {
+	return c.regions.Get(name)
+}
+
+func (c *CognitoIdentity) lockedGetIdentityPool(id string) (*IdentityPool, *awserrors.Error) {
+	pool, ok := c.poolsById[id]
+	if !ok {
+		return nil, ResourceNotFoundException(fmt.Sprintf("IdentityPool '%s' not found.", id))
+	}
+	return pool, nil
+}
+
+func (p *IdentityPool) provider(providerName string) (APICognitoIdentityProvider, bool) {
+	for _, provider := range p.Config.CognitoIdentityProviders {
+		if provider.ProviderName == providerName {
+			return provider, true
+		}
+	}
+	return APICognitoIdentityProvider{}, false
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_CreateIdentityPool.html
+func (c *CognitoIdentity) CreateIdentityPool(input CreateIdentityPoolInput) (*APIIdentityPool, *awserrors.Error) {
+	if !identityPoolNameRegex.MatchString(input.IdentityPoolName) {
+		return nil, InvalidParameterException("1 validation error detected: Value at 'identityPoolName' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\w\\s+=,.@-]+")
+	}
+	for _, provider := range input.CognitoIdentityProviders {
+		if !providerNameRegex.MatchString(provider.ProviderName) {
+			return nil, InvalidParameterException("Invalid Cognito Identity Provider: " + provider.ProviderName)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.arnGenerator.Region + ":" + uuid.Must(uuid.NewV4()).String()
+	pool := &IdentityPool{
+		Config: APIIdentityPool{
+			IdentityPoolId:                 id,
+			IdentityPoolName:               input.IdentityPoolName,
+			AllowUnauthenticatedIdentities: input.AllowUnauthenticatedIdentities,
+			AllowClassicFlow:               input.AllowClassicFlow,
+			SupportedLoginProviders:        input.SupportedLoginProviders,
+			DeveloperProviderName:          input.DeveloperProviderName,
+			OpenIdConnectProviderARNs:      input.OpenIdConnectProviderARNs,
+			CognitoIdentityProviders:       input.CognitoIdentityProviders,
+			SamlProviderARNs:               input.SamlProviderARNs,
+			IdentityPoolTags:               input.IdentityPoolTags,
+		},
+		Roles:              make(map[string]string),
+		CreatedAt:          c.clock(),
+		identityIdsByLogin: make(map[string]string),
+	}
+	c.poolsById[id] = pool
+	return &pool.Config, nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_DescribeIdentityPool.html
+func (c *CognitoIdentity) DescribeIdentityPool(input DescribeIdentityPoolInput) (*APIIdentityPool, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetIdentityPool(input.IdentityPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	config := pool.Config
+	return &config, nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_ListIdentityPools.html
+func (c *CognitoIdentity) ListIdentityPools(input ListIdentityPoolsInput) (*ListIdentityPoolsOutput, *awserrors.Error) {
+	// MaxResults is required, so it's only 0 for calls from other services.
+	maxResults, start, awserr := pagination.Parse(input.MaxResults, maxListIdentityPoolsResults, maxListIdentityPoolsResults, input.NextToken,
+		InvalidParameterException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: Member must have value between 1 and 60"),
+		InvalidParameterException("Invalid NextToken"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var pools []*IdentityPool
+	for _, pool := range c.poolsById {
+		pools = append(pools, pool)
+	}
+	slices.SortFunc(pools, func(a, b *IdentityPool) int {
+		if cmp := a.CreatedAt.Compare(b.CreatedAt); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a.Config.IdentityPoolId, b.Config.IdentityPoolId)
+	})
+
+	page, nextToken := pagination.Page(pools, maxResults, start)
+	output := &ListIdentityPoolsOutput{IdentityPools: []APIIdentityPoolShortDescription{}, NextToken: nextToken}
+	for _, pool := range page {
+		output.IdentityPools = append(output.IdentityPools, APIIdentityPoolShortDescription{
+			IdentityPoolId:   pool.Config.IdentityPoolId,
+			IdentityPoolName: pool.Config.IdentityPoolName,
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_DeleteIdentityPool.html
+func (c *CognitoIdentity) DeleteIdentityPool(input DeleteIdentityPoolInput) (*DeleteIdentityPoolOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetIdentityPool(input.IdentityPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for id, identity := range c.identitiesById {
+		if identity.IdentityPoolId == pool.Config.IdentityPoolId {
+			delete(c.identitiesById, id)
+		}
+	}
+	delete(c.poolsById, pool.Config.IdentityPoolId)
+	return &DeleteIdentityPoolOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_SetIdentityPoolRoles.html
+func (c *CognitoIdentity) SetIdentityPoolRoles(input SetIdentityPoolRolesInput) (*SetIdentityPoolRolesOutput, *awserrors.Error) {
+	for kind, roleArn := range input.Roles {
+		if kind != "authenticated" && kind != "unauthenticated" {
+			return nil, InvalidParameterException("Roles can only be authenticated and unauthenticated.")
+		}
+		if !roleArnRegex.MatchString(roleArn) {
+			return nil, InvalidParameterException("Invalid role ARN: " + roleArn)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetIdentityPool(input.IdentityPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for providerName, mapping := range input.RoleMappings {
+		if _, ok := pool.provider(providerName); !ok {
+			return nil, InvalidParameterException(
+				fmt.Sprintf("The provider %s is not configured for the identity pool.", providerName))
+		}
+		if awserr := validateRoleMapping(mapping); awserr != nil {
+			return nil, awserr
+		}
+	}
+	pool.Roles = input.Roles
+	if pool.Roles == nil {
+		pool.Roles = make(map[string]string)
+	}
+	pool.RoleMappings = input.RoleMappings
+	return &SetIdentityPoolRolesOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_GetIdentityPoolRoles.html
+func (c *CognitoIdentity) GetIdentityPoolRoles(input GetIdentityPoolRolesInput) (*GetIdentityPoolRolesOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetIdentityPool(input.IdentityPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetIdentityPoolRolesOutput{
+		IdentityPoolId: pool.Config.IdentityPoolId,
+		Roles:          pool.Roles,
+		RoleMappings:   pool.RoleMappings,
+	}, nil
+}
@@ -0,0 +1,328 @@
+package cognitoidentity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/cognitoidp"
+)
+
+const (
+	userPoolId   = "us-east-1_abcdefghi"
+	providerName = "cognito-idp.us-east-1.amazonaws.com/" + userPoolId
+	clientId     = "client"
+)
+
+// fakeUserPools accepts tokens which are the subject of their user.
+type fakeUserPools struct{}
+
+func (fakeUserPools) VerifyIdToken(poolId string, token string) (map[string]any, error) {
+	if poolId != userPoolId || token == "" || token == "expired" {
+		return nil, errors.New("token expired")
+	}
+	return map[string]any{"sub": token, "aud": clientId, "custom:tier": "gold"}, nil
+}
+
+func newCognitoIdentity(t *testing.T, userPools UserPools) *CognitoIdentity {
+	c := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+		UserPools: userPools,
+	})
+	now := time.Unix(1700000000, 0)
+	c.clock = func() time.Time { return now }
+	return c
+}
+
+func createIdentityPool(t *testing.T, c *CognitoIdentity, allowUnauthenticated bool) string {
+	output, awserr := c.CreateIdentityPool(CreateIdentityPoolInput{
+		IdentityPoolName:               "identities",
+		AllowUnauthenticatedIdentities: allowUnauthenticated,
+		CognitoIdentityProviders: []APICognitoIdentityProvider{
+			{ProviderName: providerName, ClientId: clientId},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.SetIdentityPoolRoles(SetIdentityPoolRolesInput{
+		IdentityPoolId: output.IdentityPoolId,
+		Roles: map[string]string{
+			"authenticated":   "arn:aws:iam::123456789012:role/authenticated",
+			"unauthenticated": "arn:aws:iam::123456789012:role/unauthenticated",
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.IdentityPoolId
+}
+
+func TestIdentityPools(t *testing.T) {
+	c := newCognitoIdentity(t, fakeUserPools{})
+	id := createIdentityPool(t, c, false)
+	if !strings.HasPrefix(id, "us-east-1:") {
+		t.Fatal("Unexpected ID", id)
+	}
+
+	described, awserr := c.DescribeIdentityPool(DescribeIdentityPoolInput{IdentityPoolId: id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if described.IdentityPoolName != "identities" || len(described.CognitoIdentityProviders) != 1 {
+		t.Fatalf("Unexpected pool: %+v", described)
+	}
+	listed, awserr := c.ListIdentityPools(ListIdentityPoolsInput{MaxResults: 10})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.IdentityPools) != 1 || listed.IdentityPools[0].IdentityPoolId != id {
+		t.Fatalf("Unexpected pools: %+v", listed)
+	}
+
+	_, awserr = c.SetIdentityPoolRoles(SetIdentityPoolRolesInput{
+		IdentityPoolId: id,
+		Roles:          map[string]string{"authenticated": "not-an-arn"},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected InvalidParameterException", awserr)
+	}
+
+	if _, awserr := c.DeleteIdentityPool(DeleteIdentityPoolInput{IdentityPoolId: id}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.DescribeIdentityPool(DescribeIdentityPoolInput{IdentityPoolId: id})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected ResourceNotFoundException", awserr)
+	}
+}
+
+func TestAuthenticatedIdentity(t *testing.T) {
+	c := newCognitoIdentity(t, fakeUserPools{})
+	poolId := createIdentityPool(t, c, false)
+
+	_, awserr := c.GetId(GetIdInput{IdentityPoolId: poolId})
+	if awserr == nil || awserr.Body.Message != unauthenticatedMessage {
+		t.Fatal("Expected unauthenticated identities to be rejected", awserr)
+	}
+	_, awserr = c.GetId(GetIdInput{IdentityPoolId: poolId, Logins: map[string]string{providerName: "expired"}})
+	if awserr == nil || awserr.Body.Message != "Invalid login token. token expired" {
+		t.Fatal("Expected the token to be rejected", awserr)
+	}
+	_, awserr = c.GetId(GetIdInput{IdentityPoolId: poolId, Logins: map[string]string{"accounts.google.com": "alice"}})
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatal("Expected unsupported providers to be rejected", awserr)
+	}
+
+	logins := map[string]string{providerName: "alice"}
+	output, awserr := c.GetId(GetIdInput{IdentityPoolId: poolId, Logins: logins})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	again, awserr := c.GetId(GetIdInput{IdentityPoolId: poolId, Logins: logins})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if again.IdentityId != output.IdentityId {
+		t.Fatal("Expected the same identity for the same user", output.IdentityId, again.IdentityId)
+	}
+
+	credentials, awserr := c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{IdentityId: output.IdentityId, Logins: logins})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(credentials.Credentials.AccessKeyId, "ASIA") || credentials.Credentials.SessionToken == "" ||
+		credentials.Credentials.Expiration != 1700003600 {
+		t.Fatalf("Unexpected credentials: %+v", credentials)
+	}
+
+	_, awserr = c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{IdentityId: output.IdentityId})
+	if awserr == nil || awserr.Body.Message != loginsMismatchMessage {
+		t.Fatal("Expected logins to be required", awserr)
+	}
+	_, awserr = c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{
+		IdentityId: output.IdentityId,
+		Logins:     map[string]string{providerName: "bob"},
+	})
+	if awserr == nil || awserr.Body.Message != loginsMismatchMessage {
+		t.Fatal("Expected another user's login to be rejected", awserr)
+	}
+
+	described, awserr := c.DescribeIdentity(DescribeIdentityInput{IdentityId: output.IdentityId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(described.Logins) != 1 || described.Logins[0] != providerName {
+		t.Fatalf("Unexpected identity: %+v", described)
+	}
+}
+
+func TestUnauthenticatedIdentity(t *testing.T) {
+	c := newCognitoIdentity(t, fakeUserPools{})
+	poolId := createIdentityPool(t, c, true)
+
+	output, awserr := c.GetId(GetIdInput{IdentityPoolId: poolId, AccountId: "123456789012"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{IdentityId: output.IdentityId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	_, awserr = c.SetIdentityPoolRoles(SetIdentityPoolRolesInput{IdentityPoolId: poolId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{IdentityId: output.IdentityId})
+	if awserr == nil || awserr.Body.Type != "InvalidIdentityPoolConfigurationException" {
+		t.Fatal("Expected credentials to need a role", awserr)
+	}
+}
+
+func TestRoleMappings(t *testing.T) {
+	c := newCognitoIdentity(t, fakeUserPools{})
+	poolId := createIdentityPool(t, c, false)
+	roles, awserr := c.GetIdentityPoolRoles(GetIdentityPoolRolesInput{IdentityPoolId: poolId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	for _, tc := range []struct {
+		rule     APIMappingRule
+		resolve  string
+		expected string
+	}{
+		{APIMappingRule{Claim: "custom:tier", MatchType: "Equals", Value: "gold", RoleARN: "arn:aws:iam::123456789012:role/gold"},
+			"Deny", "arn:aws:iam::123456789012:role/gold"},
+		{APIMappingRule{Claim: "custom:tier", MatchType: "StartsWith", Value: "sil", RoleARN: "arn:aws:iam::123456789012:role/silver"},
+			"AuthenticatedRole", "arn:aws:iam::123456789012:role/authenticated"},
+		{APIMappingRule{Claim: "custom:tier", MatchType: "NotEqual", Value: "gold", RoleARN: "arn:aws:iam::123456789012:role/other"},
+			"Deny", ""},
+	} {
+		_, awserr := c.SetIdentityPoolRoles(SetIdentityPoolRolesInput{
+			IdentityPoolId: poolId,
+			Roles:          roles.Roles,
+			RoleMappings: map[string]APIRoleMapping{providerName: {
+				Type:                    "Rules",
+				AmbiguousRoleResolution: tc.resolve,
+				RulesConfiguration:      &APIRulesConfiguration{Rules: []APIMappingRule{tc.rule}},
+			}},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		pool := c.poolsById[poolId]
+		role, awserr := mappedRole(pool, map[string]map[string]any{providerName: {"custom:tier": "gold"}}, "")
+		if tc.expected == "" {
+			if awserr == nil || awserr.Body.Message != ambiguousRoleMessage {
+				t.Fatal("Expected access to be denied", tc.rule, awserr)
+			}
+		} else if awserr != nil || role != tc.expected {
+			t.Fatal("Unexpected role", tc.rule, role, awserr)
+		}
+	}
+}
+
+func TestCredentialsForUserPoolToken(t *testing.T) {
+	userPools := cognitoidp.New(cognitoidp.Options{
+		ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		Addr:         "localhost:4569",
+	})
+	pool, awserr := userPools.CreateUserPool(cognitoidp.CreateUserPoolInput{PoolName: "users"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	client, awserr := userPools.CreateUserPoolClient(cognitoidp.CreateUserPoolClientInput{
+		UserPoolId:        pool.UserPool.Id,
+		ClientName:        "app",
+		ExplicitAuthFlows: []string{"ALLOW_USER_PASSWORD_AUTH"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := userPools.AdminCreateUser(cognitoidp.AdminCreateUserInput{
+		UserPoolId: pool.UserPool.Id, Username: "alice", MessageAction: "SUPPRESS",
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := userPools.AdminSetUserPassword(cognitoidp.AdminSetUserPasswordInput{
+		UserPoolId: pool.UserPool.Id, Username: "alice", Password: "Password1!", Permanent: true,
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	auth, awserr := userPools.InitiateAuth(cognitoidp.InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       client.UserPoolClient.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice", "PASSWORD": "Password1!"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	c := New(Options{
+		ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		UserPools:    userPools,
+	})
+	provider := "cognito-idp.us-east-1.amazonaws.com/" + pool.UserPool.Id
+	identityPool, awserr := c.CreateIdentityPool(CreateIdentityPoolInput{
+		IdentityPoolName: "identities",
+		CognitoIdentityProviders: []APICognitoIdentityProvider{
+			{ProviderName: provider, ClientId: client.UserPoolClient.ClientId},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := c.SetIdentityPoolRoles(SetIdentityPoolRolesInput{
+		IdentityPoolId: identityPool.IdentityPoolId,
+		Roles:          map[string]string{"authenticated": "arn:aws:iam::123456789012:role/authenticated"},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	logins := map[string]string{provider: auth.AuthenticationResult.IdToken}
+	identity, awserr := c.GetId(GetIdInput{IdentityPoolId: identityPool.IdentityPoolId, Logins: logins})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{IdentityId: identity.IdentityId, Logins: logins}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	logins[provider] = auth.AuthenticationResult.AccessToken
+	_, awserr = c.GetCredentialsForIdentity(GetCredentialsForIdentityInput{IdentityId: identity.IdentityId, Logins: logins})
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatal("Expected access tokens to be rejected", awserr)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	c := newCognitoIdentity(t, fakeUserPools{})
+	if _, err := c.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := c.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Identity pools in eu-west-1 can still have us-east-1's user pools as providers.
+	id := createIdentityPool(t, europe, false)
+	if !strings.HasPrefix(id, "eu-west-1:") {
+		t.Fatal("Unexpected ID", id)
+	}
+	if _, awserr := c.DescribeIdentityPool(DescribeIdentityPoolInput{IdentityPoolId: id}); awserr == nil {
+		t.Fatal("Expected the pool not to be in us-east-1")
+	}
+	identity, awserr := europe.GetId(GetIdInput{IdentityPoolId: id, Logins: map[string]string{providerName: "alice"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(identity.IdentityId, "eu-west-1:") {
+		t.Fatal("Unexpected identity", identity.IdentityId)
+	}
+}
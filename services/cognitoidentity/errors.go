@@ -0,0 +1,19 @@
+package cognitoidentity
+
+import "aws-in-a-box/awserrors"
+
+func InvalidIdentityPoolConfigurationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidIdentityPoolConfigurationException", message)
+}
+
+func InvalidParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterException", message)
+}
+
+func NotAuthorizedException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("NotAuthorizedException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
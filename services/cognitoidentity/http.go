@@ -0,0 +1,22 @@
+package cognitoidentity
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AWSCognitoIdentityService"
+
+func (c *CognitoIdentity) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateIdentityPool", region.Method(c.regions, (*CognitoIdentity).CreateIdentityPool))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteIdentityPool", region.Method(c.regions, (*CognitoIdentity).DeleteIdentityPool))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeIdentity", region.Method(c.regions, (*CognitoIdentity).DescribeIdentity))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeIdentityPool", region.Method(c.regions, (*CognitoIdentity).DescribeIdentityPool))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetCredentialsForIdentity", region.Method(c.regions, (*CognitoIdentity).GetCredentialsForIdentity))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetId", region.Method(c.regions, (*CognitoIdentity).GetId))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetIdentityPoolRoles", region.Method(c.regions, (*CognitoIdentity).GetIdentityPoolRoles))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListIdentityPools", region.Method(c.regions, (*CognitoIdentity).ListIdentityPools))
+	http.RegisterWithRequest(logger, methodRegistry, service, "SetIdentityPoolRoles", region.Method(c.regions, (*CognitoIdentity).SetIdentityPoolRoles))
+}
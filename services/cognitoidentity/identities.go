@@ -0,0 +1,273 @@
+package cognitoidentity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/random"
+	"aws-in-a-box/timestamp"
+)
+
+const credentialsValidity = time.Hour
+
+const (
+	ambiguousRoleMessage   = "Ambiguous role mapping and the identity pool is configured to deny access."
+	loginsMismatchMessage  = "Logins don't match. Please include at least one valid login for this identity or identity pool."
+	unauthenticatedMessage = "Unauthenticated access is not supported for this identity pool."
+)
+
+func validateRoleMapping(mapping APIRoleMapping) *awserrors.Error {
+	if mapping.Type != "Token" && mapping.Type != "Rules" {
+		return InvalidParameterException("RoleMapping Type must be Token or Rules.")
+	}
+	if mapping.AmbiguousRoleResolution != "AuthenticatedRole" && mapping.AmbiguousRoleResolution != "Deny" {
+		return InvalidParameterException("AmbiguousRoleResolution must be AuthenticatedRole or Deny.")
+	}
+	if mapping.Type == "Rules" {
+		if mapping.RulesConfiguration == nil || len(mapping.RulesConfiguration.Rules) == 0 {
+			return InvalidParameterException("RulesConfiguration is required for Rules role mappings.")
+		}
+		for _, rule := range mapping.RulesConfiguration.Rules {
+			switch rule.MatchType {
+			case "Equals", "Contains", "StartsWith", "NotEqual":
+			default:
+				return InvalidParameterException("Invalid MatchType: " + rule.MatchType)
+			}
+			if !roleArnRegex.MatchString(rule.RoleARN) {
+				return InvalidParameterException("Invalid role ARN: " + rule.RoleARN)
+			}
+		}
+	}
+	return nil
+}
+
+// ruleMatches compares the rule's claim like Cognito does: claims which are lists, such as
+// cognito:groups, match if any of their values do.
+func ruleMatches(rule APIMappingRule, claims map[string]any) bool {
+	var values []string
+	switch claim := claims[rule.Claim].(type) {
+	case string:
+		values = []string{claim}
+	case bool, float64:
+		values = []string{fmt.Sprint(claim)}
+	case []any:
+		for _, value := range claim {
+			values = append(values, fmt.Sprint(value))
+		}
+	}
+	if rule.MatchType == "NotEqual" {
+		return !slices.Contains(values, rule.Value)
+	}
+	return slices.ContainsFunc(values, func(value string) bool {
+		switch rule.MatchType {
+		case "Equals":
+			return value == rule.Value
+		case "Contains":
+			return strings.Contains(value, rule.Value)
+		default:
+			return strings.HasPrefix(value, rule.Value)
+		}
+	})
+}
+
+// lockedVerifyLogins checks the logins' tokens, and returns their claims by provider name.
+func (c *CognitoIdentity) lockedVerifyLogins(pool *IdentityPool, logins map[string]string) (map[string]map[string]any, *awserrors.Error) {
+	claimsByProvider := make(map[string]map[string]any)
+	for providerName, token := range logins {
+		provider, ok := pool.provider(providerName)
+		if !ok || c.userPools == nil {
+			return nil, NotAuthorizedException("Token is not from a supported provider of this identity pool.")
+		}
+		userPoolId := providerNameRegex.FindStringSubmatch(providerName)[1]
+		claims, err := c.userPools.VerifyIdToken(userPoolId, token)
+		if err != nil {
+			return nil, NotAuthorizedException("Invalid login token. " + err.Error())
+		}
+		if provider.ClientId != "" && claims["aud"] != provider.ClientId {
+			return nil, NotAuthorizedException("Invalid login token. Incorrect token audience.")
+		}
+		claimsByProvider[providerName] = claims
+	}
+	return claimsByProvider, nil
+}
+
+func loginKey(providerName string, sub string) string {
+	return providerName + "/" + sub
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_GetId.html
+func (c *CognitoIdentity) GetId(input GetIdInput) (*GetIdOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetIdentityPool(input.IdentityPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.AccountId != "" && input.AccountId != c.arnGenerator.AwsAccountId {
+		return nil, NotAuthorizedException("Invalid AccountId.")
+	}
+	if len(input.Logins) == 0 && !pool.Config.AllowUnauthenticatedIdentities {
+		return nil, NotAuthorizedException(unauthenticatedMessage)
+	}
+	claimsByProvider, awserr := c.lockedVerifyLogins(pool, input.Logins)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Logging in with any of an identity's logins returns the same identity.
+	var identity *Identity
+	for providerName, claims := range claimsByProvider {
+		sub, _ := claims["sub"].(string)
+		if id, ok := pool.identityIdsByLogin[loginKey(providerName, sub)]; ok {
+			identity = c.identitiesById[id]
+			break
+		}
+	}
+	now := c.clock()
+	if identity == nil {
+		identity = &Identity{
+			Id:             c.arnGenerator.Region + ":" + uuid.Must(uuid.NewV4()).String(),
+			IdentityPoolId: pool.Config.IdentityPoolId,
+			Logins:         make(map[string]string),
+			CreatedAt:      now,
+			LastModifiedAt: now,
+		}
+		c.identitiesById[identity.Id] = identity
+	}
+	for providerName, claims := range claimsByProvider {
+		sub, _ := claims["sub"].(string)
+		if identity.Logins[providerName] != sub {
+			identity.Logins[providerName] = sub
+			identity.LastModifiedAt = now
+			pool.identityIdsByLogin[loginKey(providerName, sub)] = identity.Id
+		}
+	}
+	return &GetIdOutput{IdentityId: identity.Id}, nil
+}
+
+// mappedRole returns the role an authenticated identity assumes, from the role mapping of the
+// provider it logged in with, if there is one.
+func mappedRole(pool *IdentityPool, claimsByProvider map[string]map[string]any, customRoleArn string) (string, *awserrors.Error) {
+	for providerName, claims := range claimsByProvider {
+		mapping, ok := pool.RoleMappings[providerName]
+		if !ok {
+			continue
+		}
+		if mapping.Type == "Rules" {
+			for _, rule := range mapping.RulesConfiguration.Rules {
+				if ruleMatches(rule, claims) {
+					return rule.RoleARN, nil
+				}
+			}
+		} else {
+			// Tokens from user pools list the roles of the user's groups.
+			var roles []string
+			if claimed, ok := claims["cognito:roles"].([]any); ok {
+				for _, role := range claimed {
+					roles = append(roles, fmt.Sprint(role))
+				}
+			}
+			switch preferred, _ := claims["cognito:preferred_role"].(string); {
+			case customRoleArn != "" && slices.Contains(roles, customRoleArn):
+				return customRoleArn, nil
+			case preferred != "":
+				return preferred, nil
+			case len(roles) == 1:
+				return roles[0], nil
+			}
+		}
+		if mapping.AmbiguousRoleResolution == "Deny" {
+			return "", NotAuthorizedException(ambiguousRoleMessage)
+		}
+	}
+	return pool.Roles["authenticated"], nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_GetCredentialsForIdentity.html
+func (c *CognitoIdentity) GetCredentialsForIdentity(input GetCredentialsForIdentityInput) (*GetCredentialsForIdentityOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	identity, ok := c.identitiesById[input.IdentityId]
+	if !ok {
+		return nil, ResourceNotFoundException(fmt.Sprintf("Identity '%s' not found.", input.IdentityId))
+	}
+	pool, awserr := c.lockedGetIdentityPool(identity.IdentityPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	var role string
+	if len(input.Logins) == 0 {
+		if len(identity.Logins) > 0 {
+			return nil, NotAuthorizedException(loginsMismatchMessage)
+		}
+		if !pool.Config.AllowUnauthenticatedIdentities {
+			return nil, NotAuthorizedException(unauthenticatedMessage)
+		}
+		role = pool.Roles["unauthenticated"]
+	} else {
+		claimsByProvider, awserr := c.lockedVerifyLogins(pool, input.Logins)
+		if awserr != nil {
+			return nil, awserr
+		}
+		// The identity has to be one of the logins'.
+		matched := false
+		for providerName, claims := range claimsByProvider {
+			if sub, ok := identity.Logins[providerName]; ok && sub == claims["sub"] {
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, NotAuthorizedException(loginsMismatchMessage)
+		}
+		role, awserr = mappedRole(pool, claimsByProvider, input.CustomRoleArn)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+	if role == "" {
+		return nil, InvalidIdentityPoolConfigurationException("Invalid identity pool configuration. Check assigned IAM roles for this pool.")
+	}
+
+	// Requests' signatures aren't verified, so these work like any other credentials.
+	credentials := APICredentials{
+		AccessKeyId:  "ASIA" + random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", 16),
+		SecretKey:    random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/", 40),
+		SessionToken: random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/", 256),
+		Expiration:   timestamp.EpochSeconds(c.clock().Add(credentialsValidity)),
+	}
+	c.logger.Debug("Issued credentials", "identityId", identity.Id, "role", role, "accessKeyId", credentials.AccessKeyId)
+	return &GetCredentialsForIdentityOutput{
+		IdentityId:  identity.Id,
+		Credentials: credentials,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_DescribeIdentity.html
+func (c *CognitoIdentity) DescribeIdentity(input DescribeIdentityInput) (*DescribeIdentityOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	identity, ok := c.identitiesById[input.IdentityId]
+	if !ok {
+		return nil, ResourceNotFoundException(fmt.Sprintf("Identity '%s' not found.", input.IdentityId))
+	}
+	logins := []string{}
+	for providerName := range identity.Logins {
+		logins = append(logins, providerName)
+	}
+	slices.Sort(logins)
+	return &DescribeIdentityOutput{
+		IdentityId:       identity.Id,
+		Logins:           logins,
+		CreationDate:     timestamp.EpochSeconds(identity.CreatedAt),
+		LastModifiedDate: timestamp.EpochSeconds(identity.LastModifiedAt),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package cognitoidentity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type poolsState struct {
+	IdentityPools []*IdentityPool
+	Identities    []*Identity
+}
+
+// TryPause pauses changes to each region's identity pools while a snapshot copies them.
+func (c *CognitoIdentity) TryPause() (func(), bool) {
+	return c.regions.TryPause(func(c *CognitoIdentity) *sync.Mutex { return &c.mu })
+}
+
+// ExportState writes each region's identity pools and their identities to the archive.
+func (c *CognitoIdentity) ExportState(w *state.Writer) error {
+	return c.regions.ExportState(w, (*CognitoIdentity).exportState)
+}
+
+func (c *CognitoIdentity) exportState(w *state.Writer) error {
+	unlock := w.Lock(&c.mu)
+	defer unlock()
+
+	var exported poolsState
+	for _, pool := range c.poolsById {
+		exported.IdentityPools = append(exported.IdentityPools, pool)
+	}
+	slices.SortFunc(exported.IdentityPools, func(a, b *IdentityPool) int {
+		return strings.Compare(a.Config.IdentityPoolId, b.Config.IdentityPoolId)
+	})
+	for _, identity := range c.identitiesById {
+		exported.Identities = append(exported.Identities, identity)
+	}
+	slices.SortFunc(exported.Identities, func(a, b *Identity) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return w.WriteJSON("identitypools.json", exported)
+}
+
+// ImportState replaces each region's identity pools and their identities with the archive's.
+func (c *CognitoIdentity) ImportState(r *state.Reader) (func() error, error) {
+	return c.regions.ImportState(r, (*CognitoIdentity).importState, (*CognitoIdentity).exportState)
+}
+
+func (c *CognitoIdentity) importState(r *state.Reader) (func() error, error) {
+	var imported poolsState
+	if err := r.ReadJSON("identitypools.json", &imported); err != nil {
+		return nil, err
+	}
+
+	poolsById := make(map[string]*IdentityPool)
+	for _, pool := range imported.IdentityPools {
+		if pool == nil || pool.Config.IdentityPoolId == "" {
+			return nil, fmt.Errorf("invalid identity pool")
+		}
+		if pool.Roles == nil {
+			pool.Roles = make(map[string]string)
+		}
+		if pool.RoleMappings == nil {
+			pool.RoleMappings = make(map[string]APIRoleMapping)
+		}
+		pool.identityIdsByLogin = make(map[string]string)
+		poolsById[pool.Config.IdentityPoolId] = pool
+	}
+	identitiesById := make(map[string]*Identity)
+	for _, identity := range imported.Identities {
+		if identity == nil || identity.Id == "" {
+			return nil, fmt.Errorf("invalid identity")
+		}
+		pool, ok := poolsById[identity.IdentityPoolId]
+		if !ok {
+			return nil, fmt.Errorf("identity %s: identity pool %s doesn't exist", identity.Id, identity.IdentityPoolId)
+		}
+		for providerName, sub := range identity.Logins {
+			pool.identityIdsByLogin[loginKey(providerName, sub)] = identity.Id
+		}
+		identitiesById[identity.Id] = identity
+	}
+
+	return func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.poolsById = poolsById
+		c.identitiesById = identitiesById
+		return nil
+	}, nil
+}
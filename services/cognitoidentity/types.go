@@ -0,0 +1,144 @@
+package cognitoidentity
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_CognitoIdentityProvider.html
+type APICognitoIdentityProvider struct {
+	// cognito-idp.<region>.amazonaws.com/<userPoolId>
+	ProviderName         string `length:"1,128" pattern:"[\\w._:/-]+" error:"InvalidParameterException"`
+	ClientId             string `json:",omitempty" length:"1,128" pattern:"[\\w_]+" error:"InvalidParameterException"`
+	ServerSideTokenCheck bool
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_CreateIdentityPool.html#API_CreateIdentityPool_ResponseSyntax
+type APIIdentityPool struct {
+	IdentityPoolId                 string
+	IdentityPoolName               string
+	AllowUnauthenticatedIdentities bool
+	AllowClassicFlow               bool
+	SupportedLoginProviders        map[string]string            `json:",omitempty"`
+	DeveloperProviderName          string                       `json:",omitempty"`
+	OpenIdConnectProviderARNs      []string                     `json:",omitempty"`
+	CognitoIdentityProviders       []APICognitoIdentityProvider `json:",omitempty"`
+	SamlProviderARNs               []string                     `json:",omitempty"`
+	IdentityPoolTags               map[string]string            `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_IdentityPoolShortDescription.html
+type APIIdentityPoolShortDescription struct {
+	IdentityPoolId   string
+	IdentityPoolName string
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_MappingRule.html
+type APIMappingRule struct {
+	Claim string `required:"true" length:"1,64" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	// Equals, Contains, StartsWith or NotEqual.
+	MatchType string `required:"true" enum:"Equals|Contains|StartsWith|NotEqual" error:"InvalidParameterException"`
+	Value     string `required:"true" length:"1,128" error:"InvalidParameterException"`
+	RoleARN   string `required:"true" length:"20,2048" error:"InvalidParameterException"`
+}
+
+type APIRulesConfiguration struct {
+	Rules []APIMappingRule `required:"true" length:"1,25" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_RoleMapping.html
+type APIRoleMapping struct {
+	// Token or Rules.
+	Type string `required:"true" enum:"Token|Rules" error:"InvalidParameterException"`
+	// AuthenticatedRole or Deny.
+	AmbiguousRoleResolution string                 `json:",omitempty" enum:"AuthenticatedRole|Deny" error:"InvalidParameterException"`
+	RulesConfiguration      *APIRulesConfiguration `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognitoidentity/latest/APIReference/API_Credentials.html
+type APICredentials struct {
+	AccessKeyId  string
+	SecretKey    string
+	SessionToken string
+	Expiration   float64
+}
+
+type CreateIdentityPoolInput struct {
+	IdentityPoolName               string `required:"true" length:"1,128" pattern:"[\\w\\s+=,.@-]+" error:"InvalidParameterException"`
+	AllowUnauthenticatedIdentities bool
+	AllowClassicFlow               bool
+	SupportedLoginProviders        map[string]string `length:",10" error:"InvalidParameterException"`
+	DeveloperProviderName          string            `length:"1,128" pattern:"[\\w._-]+" error:"InvalidParameterException"`
+	OpenIdConnectProviderARNs      []string
+	CognitoIdentityProviders       []APICognitoIdentityProvider
+	SamlProviderARNs               []string
+	IdentityPoolTags               map[string]string `length:",50" error:"InvalidParameterException"`
+}
+
+type DescribeIdentityPoolInput struct {
+	IdentityPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+}
+
+type ListIdentityPoolsInput struct {
+	MaxResults int    `required:"true" range:"1,60" error:"InvalidParameterException"`
+	NextToken  string `length:"1," error:"InvalidParameterException"`
+}
+
+type ListIdentityPoolsOutput struct {
+	IdentityPools []APIIdentityPoolShortDescription
+	NextToken     string `json:",omitempty"`
+}
+
+type DeleteIdentityPoolInput struct {
+	IdentityPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+}
+
+type DeleteIdentityPoolOutput struct{}
+
+type SetIdentityPoolRolesInput struct {
+	IdentityPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+	// authenticated and unauthenticated.
+	Roles map[string]string `required:"true" length:",2" error:"InvalidParameterException"`
+	// By provider name.
+	RoleMappings map[string]APIRoleMapping `length:",10" error:"InvalidParameterException"`
+}
+
+type SetIdentityPoolRolesOutput struct{}
+
+type GetIdentityPoolRolesInput struct {
+	IdentityPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+}
+
+type GetIdentityPoolRolesOutput struct {
+	IdentityPoolId string
+	Roles          map[string]string
+	RoleMappings   map[string]APIRoleMapping `json:",omitempty"`
+}
+
+type GetIdInput struct {
+	AccountId      string `length:"1,15" pattern:"\\d+" error:"InvalidParameterException"`
+	IdentityPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+	// Tokens by provider name.
+	Logins map[string]string `length:",10" error:"InvalidParameterException"`
+}
+
+type GetIdOutput struct {
+	IdentityId string
+}
+
+type GetCredentialsForIdentityInput struct {
+	IdentityId    string            `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+	Logins        map[string]string `length:",10" error:"InvalidParameterException"`
+	CustomRoleArn string            `length:"20,2048" error:"InvalidParameterException"`
+}
+
+type GetCredentialsForIdentityOutput struct {
+	IdentityId  string
+	Credentials APICredentials
+}
+
+type DescribeIdentityInput struct {
+	IdentityId string `required:"true" length:"1,55" pattern:"[\\w-]+:[0-9a-f-]+" error:"InvalidParameterException"`
+}
+
+type DescribeIdentityOutput struct {
+	IdentityId       string
+	Logins           []string
+	CreationDate     float64
+	LastModifiedDate float64
+}
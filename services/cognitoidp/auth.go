@@ -0,0 +1,531 @@
+package cognitoidp
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/random"
+)
+
+// The scope of access tokens from the user pool API, which lets users manage themselves.
+const accessTokenScope = "aws.cognito.signin.user.admin"
+
+// authSession is a challenge which hasn't been responded to yet.
+type authSession struct {
+	poolId        string
+	clientId      string
+	username      string
+	challengeName string
+	expiresAt     time.Time
+
+	// For PASSWORD_VERIFIER challenges.
+	srpA     *big.Int
+	srpB     *big.Int
+	srpb     *big.Int
+	verifier *big.Int
+
+	// For CUSTOM_CHALLENGE challenges: the previous challenges, and what CreateAuthChallenge returned
+	// for this one.
+	challengeHistory           []challengeResult
+	challengeMetadata          string
+	privateChallengeParameters map[string]string
+}
+
+// refreshSession is what a refresh token refreshes: the sign in which issued it.
+type refreshSession struct {
+	poolId    string
+	clientId  string
+	username  string
+	originJti string
+	authTime  time.Time
+	expiresAt time.Time
+}
+
+// clientAllowsFlow checks the client's explicit auth flows allow the flow. Clients which
+// only list legacy flows can always use SRP and refresh tokens.
+func clientAllowsFlow(client *UserPoolClient, flow string) bool {
+	flows := client.Config.ExplicitAuthFlows
+	if slices.ContainsFunc(flows, func(f string) bool { return strings.HasPrefix(f, "ALLOW_") }) {
+		return slices.Contains(flows, "ALLOW_"+flow)
+	}
+	return flow != "USER_PASSWORD_AUTH" || slices.Contains(flows, "USER_PASSWORD_AUTH")
+}
+
+// userNotFound hides whether the user exists if the client prevents user existence errors.
+func userNotFound(client *UserPoolClient) *awserrors.Error {
+	if client.Config.PreventUserExistenceErrors == "ENABLED" {
+		return NotAuthorizedException("Incorrect username or password.")
+	}
+	return UserNotFoundException("User does not exist.")
+}
+
+// lockedNewSession starts a challenge, which is responded to by the session's ID.
+func (c *CognitoIDP) lockedNewSession(id string, client *UserPoolClient, user *User, challengeName string) *authSession {
+	session := &authSession{
+		poolId:        client.Config.UserPoolId,
+		clientId:      client.Config.ClientId,
+		username:      user.Username,
+		challengeName: challengeName,
+		expiresAt:     c.clock().Add(time.Duration(client.Config.AuthSessionValidity) * time.Minute),
+	}
+	c.authSessions[id] = session
+	return session
+}
+
+// lockedGetSession returns the unexpired session for the challenge, which can only be responded to once.
+func (c *CognitoIDP) lockedGetSession(client *UserPoolClient, id string, challengeName string) (*authSession, *awserrors.Error) {
+	session, ok := c.authSessions[id]
+	if !ok || session.clientId != client.Config.ClientId || session.challengeName != challengeName {
+		return nil, NotAuthorizedException("Invalid session for the user.")
+	}
+	delete(c.authSessions, id)
+	if !c.clock().Before(session.expiresAt) {
+		return nil, NotAuthorizedException("Invalid session for the user, session is expired.")
+	}
+	return session, nil
+}
+
+// lockedSignIn finishes signing in the user who was authenticated. They either get tokens,
+// or have to change their temporary password first.
+func (c *CognitoIDP) lockedSignIn(pool *UserPool, client *UserPoolClient, user *User, triggerSource string, clientMetadata map[string]string) (*InitiateAuthOutput, *awserrors.Error) {
+	if !user.Enabled {
+		return nil, NotAuthorizedException("User is disabled.")
+	}
+	switch user.Status {
+	case "UNCONFIRMED":
+		return nil, UserNotConfirmedException("User is not confirmed.")
+	case "FORCE_CHANGE_PASSWORD":
+		if !c.clock().Before(user.TemporaryPasswordExpiresAt) {
+			return nil, NotAuthorizedException("Temporary password has expired and must be reset by an administrator.")
+		}
+		attributes := make(map[string]string)
+		for name, value := range user.Attributes {
+			if name != "sub" {
+				attributes[name] = value
+			}
+		}
+		attributesJSON, err := json.Marshal(attributes)
+		if err != nil {
+			panic(err)
+		}
+		id := random.String(alphanumeric, 128)
+		c.lockedNewSession(id, client, user, "NEW_PASSWORD_REQUIRED")
+		return &InitiateAuthOutput{
+			ChallengeName: "NEW_PASSWORD_REQUIRED",
+			ChallengeParameters: map[string]string{
+				"USER_ID_FOR_SRP":    user.Username,
+				"requiredAttributes": "[]",
+				"userAttributes":     string(attributesJSON),
+			},
+			Session: id,
+		}, nil
+	}
+
+	now := c.clock()
+	refresh := &refreshSession{
+		poolId:    pool.Id,
+		clientId:  client.Config.ClientId,
+		username:  user.Username,
+		originJti: uuid.Must(uuid.NewV4()).String(),
+		authTime:  now,
+		expiresAt: now.Add(client.refreshTokenValidity),
+	}
+	result, awserr := c.lockedIssueTokens(pool, client, user, refresh, triggerSource, clientMetadata)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for token, session := range c.refreshTokens {
+		if !now.Before(session.expiresAt) {
+			delete(c.refreshTokens, token)
+		}
+	}
+	result.RefreshToken = random.String(alphanumeric, 256)
+	c.refreshTokens[result.RefreshToken] = refresh
+	return &InitiateAuthOutput{AuthenticationResult: result}, nil
+}
+
+// lockedIssueTokens returns a new ID token and access token for the sign in, with any changes the
+// pool's PreTokenGeneration trigger makes.
+// https://docs.aws.amazon.com/cognito/latest/developerguide/amazon-cognito-user-pools-using-the-id-token.html
+// https://docs.aws.amazon.com/cognito/latest/developerguide/amazon-cognito-user-pools-using-the-access-token.html
+func (c *CognitoIDP) lockedIssueTokens(pool *UserPool, client *UserPoolClient, user *User, refresh *refreshSession, triggerSource string, clientMetadata map[string]string) (*APIAuthenticationResult, *awserrors.Error) {
+	overrides, awserr := c.lockedPreTokenGeneration(pool, client, user, triggerSource, clientMetadata)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	now := c.clock()
+	eventId := uuid.Must(uuid.NewV4()).String()
+
+	idClaims := map[string]any{}
+	for name, value := range user.Attributes {
+		if strings.HasSuffix(name, "_verified") {
+			idClaims[name] = value == "true"
+		} else {
+			idClaims[name] = value
+		}
+	}
+	for name, value := range map[string]any{
+		"sub":              user.sub(),
+		"aud":              client.Config.ClientId,
+		"token_use":        "id",
+		"auth_time":        refresh.authTime.Unix(),
+		"iss":              c.issuer(pool.Id),
+		"cognito:username": user.Username,
+		"exp":              now.Add(client.idTokenValidity).Unix(),
+		"iat":              now.Unix(),
+		"jti":              uuid.Must(uuid.NewV4()).String(),
+		"origin_jti":       refresh.originJti,
+		"event_id":         eventId,
+	} {
+		idClaims[name] = value
+	}
+
+	accessClaims := map[string]any{
+		"sub":        user.sub(),
+		"iss":        c.issuer(pool.Id),
+		"client_id":  client.Config.ClientId,
+		"origin_jti": refresh.originJti,
+		"event_id":   eventId,
+		"token_use":  "access",
+		"scope":      accessTokenScope,
+		"auth_time":  refresh.authTime.Unix(),
+		"exp":        now.Add(client.accessTokenValidity).Unix(),
+		"iat":        now.Unix(),
+		"jti":        uuid.Must(uuid.NewV4()).String(),
+		"username":   user.Username,
+	}
+
+	applyClaimsOverride(idClaims, overrides.ClaimsOverrideDetails)
+	if details := overrides.ClaimsAndScopeOverrideDetails; details != nil {
+		applyClaimsOverride(idClaims, details.IdTokenGeneration)
+		applyClaimsOverride(accessClaims, details.AccessTokenGeneration)
+		if details.AccessTokenGeneration != nil {
+			scopes := []string{accessTokenScope}
+			for _, scope := range details.AccessTokenGeneration.ScopesToAdd {
+				if !slices.Contains(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+			scopes = slices.DeleteFunc(scopes, func(scope string) bool {
+				return slices.Contains(details.AccessTokenGeneration.ScopesToSuppress, scope)
+			})
+			accessClaims["scope"] = strings.Join(scopes, " ")
+		}
+	}
+
+	return &APIAuthenticationResult{
+		AccessToken: pool.signToken(accessClaims),
+		ExpiresIn:   int(client.accessTokenValidity / time.Second),
+		IdToken:     pool.signToken(idClaims),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// lockedVerifyAccessToken returns the pool which issued the unexpired access token, and its user.
+func (c *CognitoIDP) lockedVerifyAccessToken(token string) (*UserPool, *User, *awserrors.Error) {
+	claims, err := parseTokenClaims(token)
+	if err != nil {
+		return nil, nil, NotAuthorizedException("Invalid Access Token")
+	}
+	issuer, _ := claims["iss"].(string)
+	_, poolId, _ := cutLast(issuer, "/")
+	pool, ok := c.poolsById[poolId]
+	if !ok {
+		return nil, nil, NotAuthorizedException("Invalid Access Token")
+	}
+	claims, err = pool.verifyToken(token)
+	if err != nil || claims["token_use"] != "access" {
+		return nil, nil, NotAuthorizedException("Invalid Access Token")
+	}
+	if exp, _ := claims["exp"].(float64); !c.clock().Before(time.Unix(int64(exp), 0)) {
+		return nil, nil, NotAuthorizedException("Access Token has expired")
+	}
+	username, _ := claims["username"].(string)
+	user, ok := pool.usersByName[username]
+	if !ok {
+		return nil, nil, NotAuthorizedException("User does not exist.")
+	}
+	return pool, user, nil
+}
+
+// VerifyIdToken checks the ID token was issued by the user pool and hasn't expired, and returns its claims.
+// Cognito identity pools exchange ID tokens for credentials with it.
+func (c *CognitoIDP) VerifyIdToken(userPoolId string, token string) (map[string]any, error) {
+	claims, err := c.VerifyToken(userPoolId, token)
+	if err != nil {
+		return nil, err
+	}
+	if claims["token_use"] != "id" {
+		return nil, errors.New("not an ID token issued by the user pool")
+	}
+	return claims, nil
+}
+
+// VerifyToken checks the ID or access token was issued by the user pool and hasn't expired, and returns
+// its claims. API Gateway's JWT authorizers verify tokens with it. The pool is looked for in the region
+// its ID says, whichever region's instance this is.
+func (c *CognitoIDP) VerifyToken(userPoolId string, token string) (map[string]any, error) {
+	pools, err := c.forPool(userPoolId)
+	if err != nil {
+		return nil, err
+	}
+	return pools.verifyToken(userPoolId, token)
+}
+
+func (c *CognitoIDP) verifyToken(userPoolId string, token string) (map[string]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, ok := c.poolsById[userPoolId]
+	if !ok {
+		return nil, fmt.Errorf("user pool %s does not exist", userPoolId)
+	}
+	claims, err := pool.verifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims["iss"] != c.issuer(pool.Id) {
+		return nil, errors.New("not a token issued by the user pool")
+	}
+	if exp, _ := claims["exp"].(float64); !c.clock().Before(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("token expired at %s", time.Unix(int64(exp), 0).UTC().Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+func requireParameters(parameters map[string]string, names ...string) *awserrors.Error {
+	for _, name := range names {
+		if parameters[name] == "" {
+			return InvalidParameterException("Missing required parameter " + name)
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_InitiateAuth.html
+func (c *CognitoIDP) InitiateAuth(input InitiateAuthInput) (*InitiateAuthOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, pool, awserr := c.lockedGetClient(input.ClientId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	flow := input.AuthFlow
+	if flow == "REFRESH_TOKEN" {
+		flow = "REFRESH_TOKEN_AUTH"
+	}
+	switch flow {
+	case "USER_PASSWORD_AUTH", "USER_SRP_AUTH", "REFRESH_TOKEN_AUTH", "CUSTOM_AUTH":
+	default:
+		return nil, InvalidParameterException("Unsupported auth flow " + input.AuthFlow)
+	}
+	if !clientAllowsFlow(client, flow) {
+		return nil, InvalidParameterException(flow + " flow not enabled for this client")
+	}
+	parameters := input.AuthParameters
+
+	if flow == "REFRESH_TOKEN_AUTH" {
+		if awserr := requireParameters(parameters, "REFRESH_TOKEN"); awserr != nil {
+			return nil, awserr
+		}
+		refresh, ok := c.refreshTokens[parameters["REFRESH_TOKEN"]]
+		if !ok || refresh.clientId != client.Config.ClientId {
+			return nil, NotAuthorizedException("Invalid Refresh Token")
+		}
+		if !c.clock().Before(refresh.expiresAt) {
+			return nil, NotAuthorizedException("Refresh Token has expired")
+		}
+		user, ok := pool.usersByName[refresh.username]
+		if !ok {
+			return nil, NotAuthorizedException("Invalid Refresh Token")
+		}
+		if awserr := checkSecretHash(client, user.Username, parameters["SECRET_HASH"]); awserr != nil {
+			return nil, awserr
+		}
+		result, awserr := c.lockedIssueTokens(pool, client, user, refresh, "TokenGeneration_RefreshTokens", input.ClientMetadata)
+		if awserr != nil {
+			return nil, awserr
+		}
+		return &InitiateAuthOutput{AuthenticationResult: result}, nil
+	}
+
+	if awserr := requireParameters(parameters, "USERNAME"); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := checkSecretHash(client, parameters["USERNAME"], parameters["SECRET_HASH"]); awserr != nil {
+		return nil, awserr
+	}
+
+	if flow == "USER_PASSWORD_AUTH" {
+		if awserr := requireParameters(parameters, "PASSWORD"); awserr != nil {
+			return nil, awserr
+		}
+		user := pool.lockedFindUser(parameters["USERNAME"])
+		if user == nil {
+			return nil, userNotFound(client)
+		}
+		if !user.checkPassword(pool.Id, parameters["PASSWORD"]) {
+			return nil, NotAuthorizedException("Incorrect username or password.")
+		}
+		return c.lockedSignIn(pool, client, user, "TokenGeneration_Authentication", input.ClientMetadata)
+	}
+
+	if flow == "CUSTOM_AUTH" {
+		if pool.LambdaConfig.DefineAuthChallenge == "" {
+			return nil, InvalidParameterException("Custom auth lambda trigger is not configured for the user pool.")
+		}
+		user := pool.lockedFindUser(parameters["USERNAME"])
+		if user == nil {
+			return nil, userNotFound(client)
+		}
+		return c.lockedNextCustomChallenge(pool, client, user, []challengeResult{}, input.ClientMetadata)
+	}
+
+	if awserr := requireParameters(parameters, "SRP_A"); awserr != nil {
+		return nil, awserr
+	}
+	A, ok := new(big.Int).SetString(parameters["SRP_A"], 16)
+	if !ok || new(big.Int).Mod(A, srpN).Sign() == 0 {
+		return nil, InvalidParameterException("Invalid SRP_A")
+	}
+	user := pool.lockedFindUser(parameters["USERNAME"])
+	if user == nil {
+		return nil, userNotFound(client)
+	}
+	b, B := srpServerValues(user.verifier)
+	secretBlock := random.String(alphanumeric, 128)
+	// The secret block identifies the session, since clients don't send a session back.
+	session := c.lockedNewSession(secretBlock, client, user, "PASSWORD_VERIFIER")
+	session.srpA, session.srpB, session.srpb, session.verifier = A, B, b, user.verifier
+	return &InitiateAuthOutput{
+		ChallengeName: "PASSWORD_VERIFIER",
+		ChallengeParameters: map[string]string{
+			"SALT":            user.salt.Text(16),
+			"SECRET_BLOCK":    secretBlock,
+			"SRP_B":           B.Text(16),
+			"USERNAME":        user.Username,
+			"USER_ID_FOR_SRP": user.Username,
+		},
+	}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_RespondToAuthChallenge.html
+func (c *CognitoIDP) RespondToAuthChallenge(input RespondToAuthChallengeInput) (*RespondToAuthChallengeOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, pool, awserr := c.lockedGetClient(input.ClientId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	responses := input.ChallengeResponses
+	if awserr := requireParameters(responses, "USERNAME"); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := checkSecretHash(client, responses["USERNAME"], responses["SECRET_HASH"]); awserr != nil {
+		return nil, awserr
+	}
+
+	var output *InitiateAuthOutput
+	switch input.ChallengeName {
+	case "PASSWORD_VERIFIER":
+		if awserr := requireParameters(responses,
+			"PASSWORD_CLAIM_SECRET_BLOCK", "PASSWORD_CLAIM_SIGNATURE", "TIMESTAMP"); awserr != nil {
+			return nil, awserr
+		}
+		session, awserr := c.lockedGetSession(client, responses["PASSWORD_CLAIM_SECRET_BLOCK"], input.ChallengeName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		user, ok := pool.usersByName[session.username]
+		if !ok {
+			return nil, userNotFound(client)
+		}
+		key := srpSessionKey(session.srpA, session.srpB, session.srpb, session.verifier)
+		secretBlock, err := base64.StdEncoding.DecodeString(responses["PASSWORD_CLAIM_SECRET_BLOCK"])
+		if err != nil {
+			return nil, NotAuthorizedException("Incorrect username or password.")
+		}
+		expected := srpClaimSignature(key, pool.Id, user.Username, secretBlock, responses["TIMESTAMP"])
+		signature, err := base64.StdEncoding.DecodeString(responses["PASSWORD_CLAIM_SIGNATURE"])
+		if err != nil || !hmac.Equal(signature, expected) {
+			return nil, NotAuthorizedException("Incorrect username or password.")
+		}
+		output, awserr = c.lockedSignIn(pool, client, user, "TokenGeneration_Authentication", input.ClientMetadata)
+		if awserr != nil {
+			return nil, awserr
+		}
+	case "NEW_PASSWORD_REQUIRED":
+		if awserr := requireParameters(responses, "NEW_PASSWORD"); awserr != nil {
+			return nil, awserr
+		}
+		session, awserr := c.lockedGetSession(client, input.Session, input.ChallengeName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		user, ok := pool.usersByName[session.username]
+		if !ok {
+			return nil, userNotFound(client)
+		}
+		if awserr := validatePassword(pool.PasswordPolicy, responses["NEW_PASSWORD"]); awserr != nil {
+			return nil, awserr
+		}
+		var attributes []APIAttribute
+		for name, value := range responses {
+			if name, ok := strings.CutPrefix(name, "userAttributes."); ok {
+				attributes = append(attributes, APIAttribute{Name: name, Value: value})
+			}
+		}
+		validated, awserr := pool.lockedValidateAttributes(attributes)
+		if awserr != nil {
+			return nil, awserr
+		}
+		for name, value := range user.Attributes {
+			if _, ok := validated[name]; !ok {
+				validated[name] = value
+			}
+		}
+		if awserr := pool.lockedCheckRequiredAttributes(validated); awserr != nil {
+			return nil, awserr
+		}
+		user.Attributes = validated
+		user.setPassword(pool.Id, responses["NEW_PASSWORD"])
+		user.Status = "CONFIRMED"
+		user.LastModifiedAt = c.clock()
+		output, awserr = c.lockedSignIn(pool, client, user, "TokenGeneration_NewPasswordChallenge", input.ClientMetadata)
+		if awserr != nil {
+			return nil, awserr
+		}
+	case "CUSTOM_CHALLENGE":
+		if awserr := requireParameters(responses, "ANSWER"); awserr != nil {
+			return nil, awserr
+		}
+		session, awserr := c.lockedGetSession(client, input.Session, input.ChallengeName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		user, ok := pool.usersByName[session.username]
+		if !ok {
+			return nil, userNotFound(client)
+		}
+		output, awserr = c.lockedVerifyCustomChallenge(pool, client, user, session, responses["ANSWER"], input.ClientMetadata)
+		if awserr != nil {
+			return nil, awserr
+		}
+	default:
+		return nil, InvalidParameterException("Unsupported challenge " + input.ChallengeName)
+	}
+	return (*RespondToAuthChallengeOutput)(output), nil
+}
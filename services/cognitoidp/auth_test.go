@@ -0,0 +1,649 @@
+package cognitoidp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/admin"
+)
+
+const password = "Password1!"
+
+// newClientPool creates a pool which auto verifies emails, and a client which can use every flow.
+func newClientPool(t *testing.T, c *CognitoIDP) (APIUserPool, APIUserPoolClient) {
+	pool := createUserPool(t, c, CreateUserPoolInput{AutoVerifiedAttributes: []string{"email"}})
+	client := createClient(t, c, CreateUserPoolClientInput{
+		UserPoolId: pool.Id,
+		ExplicitAuthFlows: []string{
+			"ALLOW_USER_PASSWORD_AUTH", "ALLOW_USER_SRP_AUTH", "ALLOW_REFRESH_TOKEN_AUTH",
+		},
+	})
+	return pool, client
+}
+
+func signUp(t *testing.T, c *CognitoIDP, client APIUserPoolClient, username string) {
+	_, awserr := c.SignUp(SignUpInput{
+		ClientId:       client.ClientId,
+		Username:       username,
+		Password:       password,
+		UserAttributes: []APIAttribute{{Name: "email", Value: username + "@example.com"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	codes := c.CapturedCodes()
+	_, awserr = c.ConfirmSignUp(ConfirmSignUpInput{
+		ClientId:         client.ClientId,
+		Username:         username,
+		ConfirmationCode: codes[len(codes)-1].Code,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func passwordAuth(t *testing.T, c *CognitoIDP, client APIUserPoolClient, username string, password string) *InitiateAuthOutput {
+	output, awserr := c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": username, "PASSWORD": password},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestSignUp(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+
+	output, awserr := c.SignUp(SignUpInput{
+		ClientId:       client.ClientId,
+		Username:       "alice",
+		Password:       password,
+		UserAttributes: []APIAttribute{{Name: "email", Value: "alice@example.com"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.UserConfirmed || output.CodeDeliveryDetails == nil || output.CodeDeliveryDetails.Destination != "a***@e***" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	_, awserr = c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice", "PASSWORD": password},
+	})
+	if awserr == nil || awserr.Body.Type != "UserNotConfirmedException" {
+		t.Fatal("Expected UserNotConfirmedException", awserr)
+	}
+
+	_, awserr = c.ConfirmSignUp(ConfirmSignUpInput{ClientId: client.ClientId, Username: "alice", ConfirmationCode: "wrong"})
+	if awserr == nil || awserr.Body.Type != "CodeMismatchException" {
+		t.Fatal("Expected CodeMismatchException", awserr)
+	}
+	codes := c.CapturedCodes()
+	if len(codes) != 1 || codes[0].Destination != "alice@example.com" || len(codes[0].Code) != 6 {
+		t.Fatalf("Unexpected codes: %+v", codes)
+	}
+	_, awserr = c.ConfirmSignUp(ConfirmSignUpInput{ClientId: client.ClientId, Username: "alice", ConfirmationCode: codes[0].Code})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	user, awserr := c.AdminGetUser(AdminGetUserInput{UserPoolId: pool.Id, Username: "alice"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if user.UserStatus != "CONFIRMED" || !strings.Contains(toJSON(t, user.UserAttributes), `{"Name":"email_verified","Value":"true"}`) {
+		t.Fatalf("Unexpected user: %+v", user)
+	}
+
+	_, awserr = c.SignUp(SignUpInput{ClientId: client.ClientId, Username: "alice", Password: password})
+	if awserr == nil || awserr.Body.Type != "UsernameExistsException" {
+		t.Fatal("Expected UsernameExistsException", awserr)
+	}
+	_, awserr = c.SignUp(SignUpInput{ClientId: client.ClientId, Username: "bob", Password: "password"})
+	if awserr == nil || awserr.Body.Type != "InvalidPasswordException" {
+		t.Fatal("Expected InvalidPasswordException", awserr)
+	}
+}
+
+func TestSignUpWithEmailUsername(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool := createUserPool(t, c, CreateUserPoolInput{UsernameAttributes: []string{"email"}})
+	client := createClient(t, c, CreateUserPoolClientInput{
+		UserPoolId:        pool.Id,
+		ExplicitAuthFlows: []string{"ALLOW_USER_PASSWORD_AUTH"},
+	})
+
+	output, awserr := c.SignUp(SignUpInput{ClientId: client.ClientId, Username: "alice@example.com", Password: password})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := c.AdminConfirmSignUp(AdminConfirmSignUpInput{UserPoolId: pool.Id, Username: "alice@example.com"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	result := passwordAuth(t, c, client, "alice@example.com", password).AuthenticationResult
+	claims, err := parseTokenClaims(result.IdToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["cognito:username"] != output.UserSub || claims["email"] != "alice@example.com" {
+		t.Fatal("Unexpected claims", claims)
+	}
+
+	_, awserr = c.SignUp(SignUpInput{ClientId: client.ClientId, Username: "alice", Password: password})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected the username to have to be an email", awserr)
+	}
+}
+
+func TestUserPasswordAuth(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+	signUp(t, c, client, "alice")
+
+	result := passwordAuth(t, c, client, "alice", password).AuthenticationResult
+	if result.TokenType != "Bearer" || result.ExpiresIn != 3600 || result.RefreshToken == "" {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	idClaims, err := c.poolsById[pool.Id].verifyToken(result.IdToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idClaims["iss"] != "http://localhost:4569/"+pool.Id || idClaims["aud"] != client.ClientId ||
+		idClaims["token_use"] != "id" || idClaims["email_verified"] != true || idClaims["exp"] != float64(1700003600) {
+		t.Fatal("Unexpected ID token claims", idClaims)
+	}
+	accessClaims, err := c.poolsById[pool.Id].verifyToken(result.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessClaims["client_id"] != client.ClientId || accessClaims["username"] != "alice" ||
+		accessClaims["sub"] != idClaims["sub"] || accessClaims["origin_jti"] != idClaims["origin_jti"] {
+		t.Fatal("Unexpected access token claims", accessClaims)
+	}
+
+	user, awserr := c.GetUser(GetUserInput{AccessToken: result.AccessToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if user.Username != "alice" {
+		t.Fatal("Unexpected user", user)
+	}
+	_, awserr = c.GetUser(GetUserInput{AccessToken: result.IdToken})
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatal("Expected ID tokens to be rejected", awserr)
+	}
+
+	_, awserr = c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice", "PASSWORD": "Wrong1!"},
+	})
+	if awserr == nil || awserr.Body.Message != "Incorrect username or password." {
+		t.Fatal("Expected NotAuthorizedException", awserr)
+	}
+	_, awserr = c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "bob", "PASSWORD": password},
+	})
+	if awserr == nil || awserr.Body.Type != "UserNotFoundException" {
+		t.Fatal("Expected UserNotFoundException", awserr)
+	}
+
+	srpOnly := createClient(t, c, CreateUserPoolClientInput{UserPoolId: pool.Id})
+	_, awserr = c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       srpOnly.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice", "PASSWORD": password},
+	})
+	if awserr == nil || awserr.Body.Message != "USER_PASSWORD_AUTH flow not enabled for this client" {
+		t.Fatal("Expected the flow to be disabled", awserr)
+	}
+}
+
+func TestVerifyIdToken(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+	signUp(t, c, client, "alice")
+	result := passwordAuth(t, c, client, "alice", password).AuthenticationResult
+
+	claims, err := c.VerifyIdToken(pool.Id, result.IdToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["cognito:username"] != "alice" {
+		t.Fatal("Unexpected claims", claims)
+	}
+	if _, err := c.VerifyIdToken(pool.Id, result.AccessToken); err == nil {
+		t.Fatal("Expected access tokens to be rejected")
+	}
+	other := createUserPool(t, c, CreateUserPoolInput{})
+	if _, err := c.VerifyIdToken(other.Id, result.IdToken); err == nil {
+		t.Fatal("Expected tokens from another pool to be rejected")
+	}
+
+	now := c.clock().Add(2 * time.Hour)
+	c.clock = func() time.Time { return now }
+	if _, err := c.VerifyIdToken(pool.Id, result.IdToken); err == nil {
+		t.Fatal("Expected expired tokens to be rejected")
+	}
+}
+
+func TestRefreshTokenAuth(t *testing.T) {
+	c := newCognitoIDP(t)
+	_, client := newClientPool(t, c)
+	signUp(t, c, client, "alice")
+	result := passwordAuth(t, c, client, "alice", password).AuthenticationResult
+
+	now := c.clock().Add(2 * time.Hour)
+	c.clock = func() time.Time { return now }
+	if _, awserr := c.GetUser(GetUserInput{AccessToken: result.AccessToken}); awserr == nil || awserr.Body.Message != "Access Token has expired" {
+		t.Fatal("Expected the access token to expire", awserr)
+	}
+
+	output, awserr := c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "REFRESH_TOKEN_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"REFRESH_TOKEN": result.RefreshToken},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.AuthenticationResult.RefreshToken != "" {
+		t.Fatal("Refreshing shouldn't issue a new refresh token")
+	}
+	if _, awserr := c.GetUser(GetUserInput{AccessToken: output.AuthenticationResult.AccessToken}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	_, awserr = c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "REFRESH_TOKEN_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"REFRESH_TOKEN": "invalid"},
+	})
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatal("Expected NotAuthorizedException", awserr)
+	}
+}
+
+func TestNewPasswordRequired(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+	created, awserr := c.AdminCreateUser(AdminCreateUserInput{
+		UserPoolId:     pool.Id,
+		Username:       "alice",
+		UserAttributes: []APIAttribute{{Name: "email", Value: "alice@example.com"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.User.UserStatus != "FORCE_CHANGE_PASSWORD" {
+		t.Fatalf("Unexpected user: %+v", created.User)
+	}
+	codes := c.CapturedCodes()
+	if len(codes) != 1 || codes[0].Reason != "AdminCreateUser" {
+		t.Fatalf("Unexpected codes: %+v", codes)
+	}
+	if awserr := validatePassword(defaultPasswordPolicy, codes[0].Code); awserr != nil {
+		t.Fatal("The temporary password should satisfy the policy", awserr)
+	}
+
+	challenge := passwordAuth(t, c, client, "alice", codes[0].Code)
+	if challenge.ChallengeName != "NEW_PASSWORD_REQUIRED" || challenge.Session == "" ||
+		challenge.ChallengeParameters["userAttributes"] != `{"email":"alice@example.com"}` {
+		t.Fatalf("Unexpected challenge: %+v", challenge)
+	}
+
+	_, awserr = c.RespondToAuthChallenge(RespondToAuthChallengeInput{
+		ClientId:           client.ClientId,
+		ChallengeName:      "NEW_PASSWORD_REQUIRED",
+		ChallengeResponses: map[string]string{"USERNAME": "alice", "NEW_PASSWORD": "short"},
+		Session:            challenge.Session,
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidPasswordException" {
+		t.Fatal("Expected InvalidPasswordException", awserr)
+	}
+
+	challenge = passwordAuth(t, c, client, "alice", codes[0].Code)
+	output, awserr := c.RespondToAuthChallenge(RespondToAuthChallengeInput{
+		ClientId:      client.ClientId,
+		ChallengeName: "NEW_PASSWORD_REQUIRED",
+		ChallengeResponses: map[string]string{
+			"USERNAME":             "alice",
+			"NEW_PASSWORD":         password,
+			"userAttributes.name":  "Alice",
+			"userAttributes.email": "alice@example.com",
+		},
+		Session: challenge.Session,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	claims, err := parseTokenClaims(output.AuthenticationResult.IdToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["name"] != "Alice" {
+		t.Fatal("Unexpected claims", claims)
+	}
+	passwordAuth(t, c, client, "alice", password)
+}
+
+func TestTemporaryPasswordExpires(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+	_, awserr := c.AdminCreateUser(AdminCreateUserInput{
+		UserPoolId:        pool.Id,
+		Username:          "alice",
+		TemporaryPassword: password,
+		MessageAction:     "SUPPRESS",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(c.CapturedCodes()) != 0 {
+		t.Fatal("Expected the message to be suppressed")
+	}
+
+	now := c.clock().Add(8 * 24 * time.Hour)
+	c.clock = func() time.Time { return now }
+	_, awserr = c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_PASSWORD_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice", "PASSWORD": password},
+	})
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatal("Expected the temporary password to expire", awserr)
+	}
+
+	if _, awserr := c.AdminSetUserPassword(AdminSetUserPasswordInput{
+		UserPoolId: pool.Id, Username: "alice", Password: "Password2!", Permanent: true,
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if passwordAuth(t, c, client, "alice", "Password2!").AuthenticationResult == nil {
+		t.Fatal("Expected tokens")
+	}
+}
+
+// srpClient is the client's half of USER_SRP_AUTH, like amazon-cognito-identity-js's AuthenticationHelper.
+type srpClient struct {
+	a *big.Int
+	A *big.Int
+}
+
+func newSRPClient() srpClient {
+	a := randomInt(128)
+	return srpClient{a: a, A: new(big.Int).Exp(srpG, a, srpN)}
+}
+
+func (s srpClient) respond(poolId string, parameters map[string]string, password string, timestamp string) map[string]string {
+	B := mustParseHex(parameters["SRP_B"])
+	salt := mustParseHex(parameters["SALT"])
+	userId := parameters["USER_ID_FOR_SRP"]
+
+	u := hexHashInt(padHex(s.A) + padHex(B))
+	x := srpPrivateKey(poolId, userId, password, salt)
+	// S = (B - k * g^x) ^ (a + u * x)
+	base := new(big.Int).Mul(srpK, new(big.Int).Exp(srpG, x, srpN))
+	base.Sub(B, base).Mod(base, srpN)
+	exponent := new(big.Int).Mul(u, x)
+	exponent.Add(exponent, s.a)
+	S := new(big.Int).Exp(base, exponent, srpN)
+	key := srpHKDF(padHex(S), padHex(u))
+
+	secretBlock, err := base64.StdEncoding.DecodeString(parameters["SECRET_BLOCK"])
+	if err != nil {
+		panic(err)
+	}
+	signature := srpClaimSignature(key, poolId, userId, secretBlock, timestamp)
+	return map[string]string{
+		"USERNAME":                    userId,
+		"PASSWORD_CLAIM_SECRET_BLOCK": parameters["SECRET_BLOCK"],
+		"PASSWORD_CLAIM_SIGNATURE":    base64.StdEncoding.EncodeToString(signature),
+		"TIMESTAMP":                   timestamp,
+	}
+}
+
+func TestUserSRPAuth(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+	signUp(t, c, client, "alice")
+	timestamp := c.clock().UTC().Format("Mon Jan 2 15:04:05 UTC 2006")
+
+	for _, tc := range []struct {
+		password string
+		success  bool
+	}{
+		{password, true},
+		{"Wrong1!", false},
+	} {
+		srp := newSRPClient()
+		challenge, awserr := c.InitiateAuth(InitiateAuthInput{
+			AuthFlow:       "USER_SRP_AUTH",
+			ClientId:       client.ClientId,
+			AuthParameters: map[string]string{"USERNAME": "alice", "SRP_A": srp.A.Text(16)},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if challenge.ChallengeName != "PASSWORD_VERIFIER" {
+			t.Fatalf("Unexpected challenge: %+v", challenge)
+		}
+
+		output, awserr := c.RespondToAuthChallenge(RespondToAuthChallengeInput{
+			ClientId:           client.ClientId,
+			ChallengeName:      "PASSWORD_VERIFIER",
+			ChallengeResponses: srp.respond(pool.Id, challenge.ChallengeParameters, tc.password, timestamp),
+		})
+		if tc.success {
+			if awserr != nil {
+				t.Fatal(awserr)
+			}
+			if output.AuthenticationResult == nil || output.AuthenticationResult.AccessToken == "" {
+				t.Fatalf("Unexpected output: %+v", output)
+			}
+		} else if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+			t.Fatal("Expected NotAuthorizedException", awserr)
+		}
+	}
+
+	_, awserr := c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "USER_SRP_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice", "SRP_A": srpN.Text(16)},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected A mod N == 0 to be rejected", awserr)
+	}
+}
+
+func TestSecretHash(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool := createUserPool(t, c, CreateUserPoolInput{})
+	client := createClient(t, c, CreateUserPoolClientInput{
+		UserPoolId:        pool.Id,
+		GenerateSecret:    true,
+		ExplicitAuthFlows: []string{"ALLOW_USER_PASSWORD_AUTH"},
+	})
+	if _, awserr := c.AdminCreateUser(AdminCreateUserInput{UserPoolId: pool.Id, Username: "alice", MessageAction: "SUPPRESS"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := c.AdminSetUserPassword(AdminSetUserPasswordInput{UserPoolId: pool.Id, Username: "alice", Password: password, Permanent: true}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	for _, tc := range []struct {
+		secretHash string
+		success    bool
+	}{
+		{"", false},
+		{secretHash("wrong", "alice", client.ClientId), false},
+		{secretHash(client.ClientSecret, "alice", client.ClientId), true},
+	} {
+		_, awserr := c.InitiateAuth(InitiateAuthInput{
+			AuthFlow:       "USER_PASSWORD_AUTH",
+			ClientId:       client.ClientId,
+			AuthParameters: map[string]string{"USERNAME": "alice", "PASSWORD": password, "SECRET_HASH": tc.secretHash},
+		})
+		if tc.success != (awserr == nil) {
+			t.Fatal("Unexpected result for secret hash", tc.secretHash, awserr)
+		}
+	}
+}
+
+func TestJSONWebKeySet(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool, client := newClientPool(t, c)
+	signUp(t, c, client, "alice")
+	token := passwordAuth(t, c, client, "alice", password).AuthenticationResult.IdToken
+	handler := NewHandler(c.logger, c)
+
+	response := httptest.NewRecorder()
+	if !handler(response, httptest.NewRequest(http.MethodGet, "/"+pool.Id+"/.well-known/openid-configuration", nil)) {
+		t.Fatal("Expected the request to be handled")
+	}
+	var configuration openIDConfiguration
+	if err := json.Unmarshal(response.Body.Bytes(), &configuration); err != nil {
+		t.Fatal(err)
+	}
+	if configuration.JwksUri != "http://localhost:4569/"+pool.Id+"/.well-known/jwks.json" {
+		t.Fatal("Unexpected configuration", configuration)
+	}
+
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/"+pool.Id+"/.well-known/jwks.json", nil))
+	var keySet struct{ Keys []jsonWebKey }
+	if err := json.Unmarshal(response.Body.Bytes(), &keySet); err != nil {
+		t.Fatal(err)
+	}
+	if len(keySet.Keys) != 1 {
+		t.Fatal("Expected one key", keySet)
+	}
+
+	// Verify the token with only the served key, like an API's authentication middleware would.
+	key := keySet.Keys[0]
+	n, _ := base64.RawURLEncoding.DecodeString(key.N)
+	e, _ := base64.RawURLEncoding.DecodeString(key.E)
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	signingInput, signature, _ := cutLast(token, ".")
+	decodedSignature, _ := base64.RawURLEncoding.DecodeString(signature)
+	hash := sha256.Sum256([]byte(signingInput))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], decodedSignature); err != nil {
+		t.Fatal("Token wasn't signed by the served key", err)
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if !strings.Contains(string(header), `"kid":"`+key.Kid+`"`) {
+		t.Fatal("Unexpected header", string(header))
+	}
+
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/us-east-1_missing/.well-known/jwks.json", nil))
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 for an unknown pool", response.Code)
+	}
+	if handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bucket/key", nil)) {
+		t.Fatal("Expected other requests to be passed on")
+	}
+}
+
+func TestCapturedCodesAdminHandler(t *testing.T) {
+	c := newCognitoIDP(t)
+	_, client := newClientPool(t, c)
+	registry := admin.Registry{}
+	c.RegisterAdminHandlers(registry)
+	handler := admin.NewHandler(registry)
+
+	for _, username := range []string{"alice", "bob"} {
+		_, awserr := c.SignUp(SignUpInput{
+			ClientId:       client.ClientId,
+			Username:       username,
+			Password:       password,
+			UserAttributes: []APIAttribute{{Name: "email", Value: username + "@example.com"}},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/_admin/cognito/codes?username=bob", nil))
+	var listed struct{ Codes []CapturedCode }
+	if err := json.Unmarshal(response.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Codes) != 1 || listed.Codes[0].Destination != "bob@example.com" {
+		t.Fatalf("Unexpected codes: %+v", listed)
+	}
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/_admin/cognito/codes", nil))
+	if len(c.CapturedCodes()) != 0 {
+		t.Fatal("Expected the codes to be cleared")
+	}
+}
+
+func toJSON(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRegions(t *testing.T) {
+	c := newCognitoIDP(t)
+	if _, err := c.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := c.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	europe.clock = c.clock
+	pool, client := newClientPool(t, europe)
+	if !strings.HasPrefix(pool.Id, "eu-west-1_") || pool.Arn != "arn:aws:cognito-idp:eu-west-1:123456789012:userpool/"+pool.Id {
+		t.Fatal("Unexpected pool", pool.Id, pool.Arn)
+	}
+	if _, awserr := c.DescribeUserPool(DescribeUserPoolInput{UserPoolId: pool.Id}); awserr == nil {
+		t.Fatal("Expected the pool not to be in us-east-1")
+	}
+	signUp(t, europe, client, "alice")
+	if codes := c.CapturedCodes(); len(codes) != 1 || codes[0].UserPoolId != pool.Id {
+		t.Fatal("Expected every region's codes to be captured together", codes)
+	}
+
+	// Pools' IDs say their regions, so their tokens are verified and their keys served whichever
+	// region is asked.
+	token := passwordAuth(t, europe, client, "alice", password).AuthenticationResult.IdToken
+	if _, err := c.VerifyIdToken(pool.Id, token); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(c.logger, c)
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/"+pool.Id+"/.well-known/jwks.json", nil))
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected the pool's keys to be served", response.Code)
+	}
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/xx-nowhere-1_missing/.well-known/jwks.json", nil))
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 for a region which isn't enabled", response.Code)
+	}
+}
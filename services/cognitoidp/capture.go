@@ -0,0 +1,82 @@
+package cognitoidp
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"aws-in-a-box/admin"
+)
+
+// CapturedCode is a confirmation code or temporary password which would have been sent to a user.
+// Instead, it is kept so tests can inspect it through the admin API.
+type CapturedCode struct {
+	UserPoolId string
+	Username   string
+	// SignUp or AdminCreateUser.
+	Reason string
+	// EMAIL or SMS.
+	DeliveryMedium string
+	// The email address or phone number.
+	Destination string
+	// The confirmation code, or the temporary password.
+	Code      string
+	Timestamp time.Time
+}
+
+func (c *CognitoIDP) lockedCapture(code CapturedCode) {
+	code.Timestamp = c.clock()
+	c.logger.Info("Captured Cognito code",
+		"userPoolId", code.UserPoolId, "username", code.Username, "reason", code.Reason, "code", code.Code)
+	c.capturedCodes = append(c.capturedCodes, code)
+}
+
+// CapturedCodes returns every region's captured confirmation codes and temporary passwords, oldest
+// first.
+func (c *CognitoIDP) CapturedCodes() []CapturedCode {
+	codes := []CapturedCode{}
+	for _, r := range c.regions.All() {
+		r.mu.Lock()
+		codes = append(codes, r.capturedCodes...)
+		r.mu.Unlock()
+	}
+	slices.SortStableFunc(codes, func(a, b CapturedCode) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return codes
+}
+
+// ClearCapturedCodes forgets every region's captured codes, for example between tests.
+func (c *CognitoIDP) ClearCapturedCodes() {
+	for _, r := range c.regions.All() {
+		r.mu.Lock()
+		r.capturedCodes = nil
+		r.mu.Unlock()
+	}
+}
+
+// RegisterAdminHandlers adds the captured codes to the admin API.
+// GET cognito/codes lists them, optionally filtered by the userPoolId and username query parameters,
+// and DELETE cognito/codes clears them.
+func (c *CognitoIDP) RegisterAdminHandlers(registry admin.Registry) {
+	registry["cognito/codes"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			userPoolId := r.URL.Query().Get("userPoolId")
+			username := r.URL.Query().Get("username")
+			codes := []CapturedCode{}
+			for _, code := range c.CapturedCodes() {
+				if (userPoolId == "" || code.UserPoolId == userPoolId) &&
+					(username == "" || code.Username == username || code.Destination == username) {
+					codes = append(codes, code)
+				}
+			}
+			admin.WriteJSON(w, struct{ Codes []CapturedCode }{codes})
+		case http.MethodDelete:
+			c.ClearCapturedCodes()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,552 @@
+package cognitoidp
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/random"
+	"aws-in-a-box/region"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultAccessTokenValidity  = time.Hour
+	defaultIdTokenValidity      = time.Hour
+	defaultRefreshTokenValidity = 30 * 24 * time.Hour
+	defaultAuthSessionValidity  = 3 * time.Minute
+
+	maxListUserPoolsResults = 60
+)
+
+var (
+	userPoolNameRegex = regexp.MustCompile(`^[\w\s+=,.@-]{1,128}$`)
+	clientNameRegex   = regexp.MustCompile(`^[\w\s+=,.@-]{1,128}$`)
+
+	validityUnits = map[string]time.Duration{
+		"seconds": time.Second,
+		"minutes": time.Minute,
+		"hours":   time.Hour,
+		"days":    24 * time.Hour,
+	}
+
+	// Clients created through the API can use these flows unless they list others.
+	defaultExplicitAuthFlows = []string{"ALLOW_CUSTOM_AUTH", "ALLOW_REFRESH_TOKEN_AUTH", "ALLOW_USER_SRP_AUTH"}
+	validExplicitAuthFlows   = []string{
+		"ADMIN_NO_SRP_AUTH", "CUSTOM_AUTH_FLOW_ONLY", "USER_PASSWORD_AUTH",
+		"ALLOW_ADMIN_USER_PASSWORD_AUTH", "ALLOW_CUSTOM_AUTH", "ALLOW_USER_PASSWORD_AUTH",
+		"ALLOW_USER_SRP_AUTH", "ALLOW_REFRESH_TOKEN_AUTH",
+	}
+)
+
+type UserPool struct {
+	Id                     string
+	Name                   string
+	Arn                    string
+	PasswordPolicy         APIPasswordPolicy
+	UsernameAttributes     []string
+	AutoVerifiedAttributes []string
+	// Custom attributes, with their custom: prefix, and standard attributes whose defaults were changed.
+	Schema                   []APISchemaAttribute
+	AllowAdminCreateUserOnly bool
+	// The Lambda functions invoked during the pool's flows.
+	LambdaConfig       APILambdaConfig
+	DeletionProtection string
+	Tags               map[string]string
+	CreatedAt          time.Time
+	LastModifiedAt     time.Time
+
+	// Signs the pool's tokens.
+	key   *rsa.PrivateKey
+	keyId string
+
+	clientsById map[string]*UserPoolClient
+	usersByName map[string]*User
+}
+
+type UserPoolClient struct {
+	Config APIUserPoolClient
+
+	accessTokenValidity  time.Duration
+	idTokenValidity      time.Duration
+	refreshTokenValidity time.Duration
+}
+
+type CognitoIDP struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Where the pools' JSON Web Key Sets are served, which is part of their tokens' issuer.
+	addr string
+	// Nil if Lambda is not enabled.
+	lambda LambdaInvoker
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu          sync.Mutex
+	poolsById   map[string]*UserPool
+	clientsById map[string]*UserPoolClient
+	// Challenges which haven't been responded to, by their session.
+	authSessions map[string]*authSession
+	// Unexpired refresh tokens, which are opaque to clients.
+	refreshTokens map[string]*refreshSession
+	// Oldest first.
+	capturedCodes []CapturedCode
+	// Shared by the instances of all regions, which each have their own user pools, sessions and
+	// captured codes.
+	regions *region.Regions[*CognitoIDP]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The address the server is listening on, which tokens' issuer uses.
+	Addr string
+	// Pools' triggers are invoked with this.
+	Lambda LambdaInvoker
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *CognitoIDP {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Triggers are found by their functions' ARNs, which say their regions, so every region invokes
+	// them through the same Lambda service.
+	c := newRegion(options)
+	c.regions = region.New(options.ArnGenerator.Region, options.Regions, c, func(name string) (*CognitoIDP, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = c.regions
+		return r, nil
+	})
+	return c
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *CognitoIDP {
+	return &CognitoIDP{
+		logger:        options.Logger,
+		arnGenerator:  options.ArnGenerator,
+		addr:          options.Addr,
+		lambda:        options.Lambda,
+		clock:         clock.Now,
+		poolsById:     make(map[string]*UserPool),
+		clientsById:   make(map[string]*UserPoolClient),
+		authSessions:  make(map[string]*authSession),
+		refreshTokens: make(map[string]*refreshSession),
+	}
+}
+
+// InRegion returns the user pools, sessions and captured codes of the region. It fails if the
+// region isn't one of Options.Regions.
+func (c *CognitoIDP) InRegion(name string) (*CognitoIDP, error) {
+	return c.regions.Get(name)
+}
+
+// forPool returns the instance of the region the user pool is in, which its ID starts with.
+func (c *CognitoIDP) forPool(poolId string) (*CognitoIDP, error) {
+	name, _, ok := strings.Cut(poolId, "_")
+	if !ok {
+		return c.regions.Default(), nil
+	}
+	return c.regions.Get(name)
+}
+
+const (
+	lowercaseAlphanumeric = "abcdefghijklmnopqrstuvwxyz0123456789"
+	alphanumeric          = "ABCDEFGHIJKLMNOPQRSTUVWXYZ" + lowercaseAlphanumeric
+)
+
+// issuer is the iss claim of the pool's tokens, which JWT libraries find the JSON Web Key Set from.
+func (c *CognitoIDP) issuer(poolId string) string {
+	return "http://" + c.addr + "/" + poolId
+}
+
+// https://docs.aws.amazon.com/cognito/latest/developerguide/user-pool-settings-policies.html
+var defaultPasswordPolicy = APIPasswordPolicy{
+	MinimumLength:                 8,
+	RequireUppercase:              true,
+	RequireLowercase:              true,
+	RequireNumbers:                true,
+	RequireSymbols:                true,
+	TemporaryPasswordValidityDays: 7,
+}
+
+func (p *UserPool) toAPI() APIUserPool {
+	policy := p.PasswordPolicy
+	deletionProtection := p.DeletionProtection
+	if deletionProtection == "" {
+		deletionProtection = "INACTIVE"
+	}
+	return APIUserPool{
+		Id:                     p.Id,
+		Name:                   p.Name,
+		Arn:                    p.Arn,
+		Policies:               APIUserPoolPolicy{PasswordPolicy: &policy},
+		UsernameAttributes:     p.UsernameAttributes,
+		AutoVerifiedAttributes: p.AutoVerifiedAttributes,
+		SchemaAttributes:       p.Schema,
+		MfaConfiguration:       "OFF",
+		AdminCreateUserConfig:  APIAdminCreateUserConfig{AllowAdminCreateUserOnly: p.AllowAdminCreateUserOnly},
+		LambdaConfig:           p.LambdaConfig,
+		DeletionProtection:     deletionProtection,
+		EstimatedNumberOfUsers: len(p.usersByName),
+		UserPoolTags:           p.Tags,
+		CreationDate:           timestamp.EpochSeconds(p.CreatedAt),
+		LastModifiedDate:       timestamp.EpochSeconds(p.LastModifiedAt),
+	}
+}
+
+func (c *CognitoIDP) lockedGetUserPool(id string) (*UserPool, *awserrors.Error) {
+	pool, ok := c.poolsById[id]
+	if !ok {
+		return nil, ResourceNotFoundException(fmt.Sprintf("User pool %s does not exist.", id))
+	}
+	return pool, nil
+}
+
+// lockedGetClient returns the app client, and the pool it belongs to.
+func (c *CognitoIDP) lockedGetClient(clientId string) (*UserPoolClient, *UserPool, *awserrors.Error) {
+	client, ok := c.clientsById[clientId]
+	if !ok {
+		return nil, nil, ResourceNotFoundException(fmt.Sprintf("User pool client %s does not exist.", clientId))
+	}
+	return client, c.poolsById[client.Config.UserPoolId], nil
+}
+
+func validateSchema(schema []APISchemaAttribute) ([]APISchemaAttribute, *awserrors.Error) {
+	var validated []APISchemaAttribute
+	for _, attribute := range schema {
+		name, custom := strings.CutPrefix(attribute.Name, "custom:")
+		if !custom && slices.Contains(standardAttributes, name) {
+			validated = append(validated, attribute)
+			continue
+		}
+		if name == "" || len(name) > 20 {
+			return nil, InvalidParameterException("Invalid attribute name: " + attribute.Name)
+		}
+		if attribute.Required {
+			return nil, InvalidParameterException("Required custom attributes are not supported currently.")
+		}
+		switch attribute.AttributeDataType {
+		case "String", "Number", "DateTime", "Boolean":
+		default:
+			return nil, InvalidParameterException("Invalid AttributeDataType input, consider using the provided AttributeDataType enum.")
+		}
+		attribute.Name = "custom:" + name
+		validated = append(validated, attribute)
+	}
+	return validated, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_CreateUserPool.html
+func (c *CognitoIDP) CreateUserPool(input CreateUserPoolInput) (*CreateUserPoolOutput, *awserrors.Error) {
+	if !userPoolNameRegex.MatchString(input.PoolName) {
+		return nil, InvalidParameterException("1 validation error detected: Value at 'poolName' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\w\\s+=,.@-]+")
+	}
+	policy := defaultPasswordPolicy
+	if input.Policies.PasswordPolicy != nil {
+		policy = *input.Policies.PasswordPolicy
+		if policy.MinimumLength == 0 {
+			policy.MinimumLength = defaultPasswordPolicy.MinimumLength
+		}
+		if policy.TemporaryPasswordValidityDays == 0 {
+			policy.TemporaryPasswordValidityDays = defaultPasswordPolicy.TemporaryPasswordValidityDays
+		}
+		if policy.MinimumLength < 6 || policy.MinimumLength > 99 {
+			return nil, InvalidParameterException("Password policy MinimumLength must be between 6 and 99.")
+		}
+	}
+	for _, attribute := range input.UsernameAttributes {
+		if attribute != "email" && attribute != "phone_number" {
+			return nil, InvalidParameterException("UsernameAttributes can only contain email and phone_number.")
+		}
+	}
+	if len(input.UsernameAttributes) > 0 && len(input.AliasAttributes) > 0 {
+		return nil, InvalidParameterException("Only one of the aliasAttributes or usernameAttributes can be set in a User Pool.")
+	}
+	for _, attribute := range input.AutoVerifiedAttributes {
+		if attribute != "email" && attribute != "phone_number" {
+			return nil, InvalidParameterException("AutoVerifiedAttributes can only contain email and phone_number.")
+		}
+	}
+	if input.MfaConfiguration != "" && input.MfaConfiguration != "OFF" {
+		return nil, InvalidParameterException("MFA is not supported.")
+	}
+	if input.DeletionProtection != "" && input.DeletionProtection != "ACTIVE" && input.DeletionProtection != "INACTIVE" {
+		return nil, InvalidParameterException("DeletionProtection must be ACTIVE or INACTIVE.")
+	}
+	schema, awserr := validateSchema(input.Schema)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateLambdaConfig(input.LambdaConfig); awserr != nil {
+		return nil, awserr
+	}
+
+	// Generating a key is slow, so it's done before locking.
+	key, keyId := generateSigningKey()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.arnGenerator.Region + "_" + random.String(alphanumeric, 9)
+	now := c.clock()
+	pool := &UserPool{
+		Id:                       id,
+		Name:                     input.PoolName,
+		Arn:                      c.arnGenerator.Generate("cognito-idp", "userpool", id),
+		PasswordPolicy:           policy,
+		UsernameAttributes:       input.UsernameAttributes,
+		AutoVerifiedAttributes:   input.AutoVerifiedAttributes,
+		Schema:                   schema,
+		AllowAdminCreateUserOnly: input.AdminCreateUserConfig.AllowAdminCreateUserOnly,
+		LambdaConfig:             input.LambdaConfig,
+		DeletionProtection:       input.DeletionProtection,
+		Tags:                     input.UserPoolTags,
+		CreatedAt:                now,
+		LastModifiedAt:           now,
+		key:                      key,
+		keyId:                    keyId,
+		clientsById:              make(map[string]*UserPoolClient),
+		usersByName:              make(map[string]*User),
+	}
+	c.poolsById[id] = pool
+	return &CreateUserPoolOutput{UserPool: pool.toAPI()}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_DescribeUserPool.html
+func (c *CognitoIDP) DescribeUserPool(input DescribeUserPoolInput) (*DescribeUserPoolOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeUserPoolOutput{UserPool: pool.toAPI()}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_ListUserPools.html
+func (c *CognitoIDP) ListUserPools(input ListUserPoolsInput) (*ListUserPoolsOutput, *awserrors.Error) {
+	// MaxResults is required, so it's only 0 for calls from other services.
+	maxResults, start, awserr := pagination.Parse(input.MaxResults, maxListUserPoolsResults, maxListUserPoolsResults, input.NextToken,
+		InvalidParameterException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: Member must have value between 1 and 60"),
+		InvalidParameterException("Invalid NextToken"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var pools []*UserPool
+	for _, pool := range c.poolsById {
+		pools = append(pools, pool)
+	}
+	slices.SortFunc(pools, func(a, b *UserPool) int {
+		if cmp := a.CreatedAt.Compare(b.CreatedAt); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	page, nextToken := pagination.Page(pools, maxResults, start)
+	output := &ListUserPoolsOutput{UserPools: []APIUserPoolDescription{}, NextToken: nextToken}
+	for _, pool := range page {
+		output.UserPools = append(output.UserPools, APIUserPoolDescription{
+			Id:               pool.Id,
+			Name:             pool.Name,
+			CreationDate:     timestamp.EpochSeconds(pool.CreatedAt),
+			LastModifiedDate: timestamp.EpochSeconds(pool.LastModifiedAt),
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_DeleteUserPool.html
+func (c *CognitoIDP) DeleteUserPool(input DeleteUserPoolInput) (*DeleteUserPoolOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if pool.DeletionProtection == "ACTIVE" {
+		return nil, InvalidParameterException(
+			"The user pool cannot be deleted because deletion protection is activated. Deletion protection must be inactivated first.")
+	}
+	for clientId := range pool.clientsById {
+		delete(c.clientsById, clientId)
+	}
+	delete(c.poolsById, pool.Id)
+	return &DeleteUserPoolOutput{}, nil
+}
+
+// tokenValidity converts a client's token validity to a duration, checking it's within the allowed range.
+func tokenValidity(name string, validity int, units string, defaultValidity time.Duration, min time.Duration, max time.Duration) (time.Duration, *awserrors.Error) {
+	unit, ok := validityUnits[units]
+	if !ok {
+		return 0, InvalidParameterException("Invalid TokenValidityUnits for " + name)
+	}
+	if validity == 0 {
+		return defaultValidity, nil
+	}
+	duration := time.Duration(validity) * unit
+	if duration < min || duration > max {
+		return 0, InvalidParameterException(fmt.Sprintf("%s must be between %v and %v.", name, min, max))
+	}
+	return duration, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_CreateUserPoolClient.html
+func (c *CognitoIDP) CreateUserPoolClient(input CreateUserPoolClientInput) (*CreateUserPoolClientOutput, *awserrors.Error) {
+	if !clientNameRegex.MatchString(input.ClientName) {
+		return nil, InvalidParameterException("1 validation error detected: Value at 'clientName' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\w\\s+=,.@-]+")
+	}
+	explicitAuthFlows := input.ExplicitAuthFlows
+	if len(explicitAuthFlows) == 0 {
+		explicitAuthFlows = defaultExplicitAuthFlows
+	}
+	for _, flow := range explicitAuthFlows {
+		if !slices.Contains(validExplicitAuthFlows, flow) {
+			return nil, InvalidParameterException("Invalid explicit auth flow: " + flow)
+		}
+	}
+	preventUserExistenceErrors := input.PreventUserExistenceErrors
+	if preventUserExistenceErrors == "" {
+		preventUserExistenceErrors = "LEGACY"
+	}
+	if preventUserExistenceErrors != "LEGACY" && preventUserExistenceErrors != "ENABLED" {
+		return nil, InvalidParameterException("PreventUserExistenceErrors must be LEGACY or ENABLED.")
+	}
+	units := input.TokenValidityUnits
+	if units.AccessToken == "" {
+		units.AccessToken = "hours"
+	}
+	if units.IdToken == "" {
+		units.IdToken = "hours"
+	}
+	if units.RefreshToken == "" {
+		units.RefreshToken = "days"
+	}
+	accessTokenValidity, awserr := tokenValidity("AccessTokenValidity", input.AccessTokenValidity, units.AccessToken,
+		defaultAccessTokenValidity, 5*time.Minute, 24*time.Hour)
+	if awserr != nil {
+		return nil, awserr
+	}
+	idTokenValidity, awserr := tokenValidity("IdTokenValidity", input.IdTokenValidity, units.IdToken,
+		defaultIdTokenValidity, 5*time.Minute, 24*time.Hour)
+	if awserr != nil {
+		return nil, awserr
+	}
+	refreshTokenValidity, awserr := tokenValidity("RefreshTokenValidity", input.RefreshTokenValidity, units.RefreshToken,
+		defaultRefreshTokenValidity, time.Hour, 3650*24*time.Hour)
+	if awserr != nil {
+		return nil, awserr
+	}
+	authSessionValidity := input.AuthSessionValidity
+	if authSessionValidity == 0 {
+		authSessionValidity = int(defaultAuthSessionValidity / time.Minute)
+	}
+	if authSessionValidity < 3 || authSessionValidity > 15 {
+		return nil, InvalidParameterException("AuthSessionValidity must be between 3 and 15 minutes.")
+	}
+	enableTokenRevocation := input.EnableTokenRevocation == nil || *input.EnableTokenRevocation
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	now := c.clock()
+	client := &UserPoolClient{
+		Config: APIUserPoolClient{
+			UserPoolId:                      pool.Id,
+			ClientName:                      input.ClientName,
+			ClientId:                        random.String(lowercaseAlphanumeric, 26),
+			ExplicitAuthFlows:               explicitAuthFlows,
+			RefreshTokenValidity:            int(refreshTokenValidity / validityUnits[units.RefreshToken]),
+			AccessTokenValidity:             int(accessTokenValidity / validityUnits[units.AccessToken]),
+			IdTokenValidity:                 int(idTokenValidity / validityUnits[units.IdToken]),
+			TokenValidityUnits:              units,
+			ReadAttributes:                  input.ReadAttributes,
+			WriteAttributes:                 input.WriteAttributes,
+			SupportedIdentityProviders:      input.SupportedIdentityProviders,
+			CallbackURLs:                    input.CallbackURLs,
+			LogoutURLs:                      input.LogoutURLs,
+			AllowedOAuthFlows:               input.AllowedOAuthFlows,
+			AllowedOAuthScopes:              input.AllowedOAuthScopes,
+			AllowedOAuthFlowsUserPoolClient: input.AllowedOAuthFlowsUserPoolClient,
+			PreventUserExistenceErrors:      preventUserExistenceErrors,
+			EnableTokenRevocation:           enableTokenRevocation,
+			AuthSessionValidity:             authSessionValidity,
+			CreationDate:                    timestamp.EpochSeconds(now),
+			LastModifiedDate:                timestamp.EpochSeconds(now),
+		},
+		accessTokenValidity:  accessTokenValidity,
+		idTokenValidity:      idTokenValidity,
+		refreshTokenValidity: refreshTokenValidity,
+	}
+	if input.GenerateSecret {
+		client.Config.ClientSecret = random.String(lowercaseAlphanumeric, 51)
+	}
+	pool.clientsById[client.Config.ClientId] = client
+	c.clientsById[client.Config.ClientId] = client
+	return &CreateUserPoolClientOutput{UserPoolClient: client.Config}, nil
+}
+
+func (c *CognitoIDP) lockedGetPoolClient(poolId string, clientId string) (*UserPoolClient, *awserrors.Error) {
+	pool, awserr := c.lockedGetUserPool(poolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	client, ok := pool.clientsById[clientId]
+	if !ok {
+		return nil, ResourceNotFoundException(fmt.Sprintf("User pool client %s does not exist.", clientId))
+	}
+	return client, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_DescribeUserPoolClient.html
+func (c *CognitoIDP) DescribeUserPoolClient(input DescribeUserPoolClientInput) (*DescribeUserPoolClientOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, awserr := c.lockedGetPoolClient(input.UserPoolId, input.ClientId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeUserPoolClientOutput{UserPoolClient: client.Config}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_DeleteUserPoolClient.html
+func (c *CognitoIDP) DeleteUserPoolClient(input DeleteUserPoolClientInput) (*DeleteUserPoolClientOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, awserr := c.lockedGetPoolClient(input.UserPoolId, input.ClientId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(c.poolsById[input.UserPoolId].clientsById, client.Config.ClientId)
+	delete(c.clientsById, client.Config.ClientId)
+	return &DeleteUserPoolClientOutput{}, nil
+}
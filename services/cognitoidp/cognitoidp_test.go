@@ -0,0 +1,175 @@
+package cognitoidp
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+func newCognitoIDP(t *testing.T) *CognitoIDP {
+	c := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+		Addr: "localhost:4569",
+	})
+	now := time.Unix(1700000000, 0)
+	c.clock = func() time.Time { return now }
+	return c
+}
+
+func createUserPool(t *testing.T, c *CognitoIDP, input CreateUserPoolInput) APIUserPool {
+	if input.PoolName == "" {
+		input.PoolName = "users"
+	}
+	output, awserr := c.CreateUserPool(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.UserPool
+}
+
+func createClient(t *testing.T, c *CognitoIDP, input CreateUserPoolClientInput) APIUserPoolClient {
+	if input.ClientName == "" {
+		input.ClientName = "app"
+	}
+	output, awserr := c.CreateUserPoolClient(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.UserPoolClient
+}
+
+func TestCreateUserPool(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool := createUserPool(t, c, CreateUserPoolInput{
+		PoolName: "users",
+		Schema: []APISchemaAttribute{
+			{Name: "email", Required: true},
+			{Name: "team", AttributeDataType: "String"},
+		},
+	})
+
+	if !regexp.MustCompile(`^us-east-1_[0-9a-zA-Z]{9}$`).MatchString(pool.Id) {
+		t.Fatal("Unexpected ID", pool.Id)
+	}
+	if pool.Arn != "arn:aws:cognito-idp:us-east-1:123456789012:userpool/"+pool.Id {
+		t.Fatal("Unexpected ARN", pool.Arn)
+	}
+	if *pool.Policies.PasswordPolicy != defaultPasswordPolicy {
+		t.Fatal("Unexpected password policy", *pool.Policies.PasswordPolicy)
+	}
+	if len(pool.SchemaAttributes) != 2 || pool.SchemaAttributes[1].Name != "custom:team" {
+		t.Fatal("Unexpected schema", pool.SchemaAttributes)
+	}
+
+	described, awserr := c.DescribeUserPool(DescribeUserPoolInput{UserPoolId: pool.Id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if described.UserPool.Name != "users" {
+		t.Fatal("Unexpected pool", described.UserPool)
+	}
+
+	_, awserr = c.CreateUserPool(CreateUserPoolInput{PoolName: "users", UsernameAttributes: []string{"preferred_username"}})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected InvalidParameterException", awserr)
+	}
+}
+
+func TestListUserPools(t *testing.T) {
+	c := newCognitoIDP(t)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, createUserPool(t, c, CreateUserPoolInput{}).Id)
+	}
+
+	var listed []string
+	input := ListUserPoolsInput{MaxResults: 2}
+	for {
+		output, awserr := c.ListUserPools(input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		for _, pool := range output.UserPools {
+			listed = append(listed, pool.Id)
+		}
+		if output.NextToken == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	if len(listed) != 3 {
+		t.Fatal("Expected three pools", listed, ids)
+	}
+}
+
+func TestDeleteUserPool(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool := createUserPool(t, c, CreateUserPoolInput{DeletionProtection: "ACTIVE"})
+	client := createClient(t, c, CreateUserPoolClientInput{UserPoolId: pool.Id})
+
+	_, awserr := c.DeleteUserPool(DeleteUserPoolInput{UserPoolId: pool.Id})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected deletion protection to prevent deleting the pool", awserr)
+	}
+
+	c.poolsById[pool.Id].DeletionProtection = "INACTIVE"
+	if _, awserr := c.DeleteUserPool(DeleteUserPoolInput{UserPoolId: pool.Id}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.InitiateAuth(InitiateAuthInput{ClientId: client.ClientId, AuthFlow: "USER_SRP_AUTH"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected the pool's client to be deleted", awserr)
+	}
+}
+
+func TestCreateUserPoolClient(t *testing.T) {
+	c := newCognitoIDP(t)
+	pool := createUserPool(t, c, CreateUserPoolInput{})
+	client := createClient(t, c, CreateUserPoolClientInput{
+		UserPoolId:          pool.Id,
+		GenerateSecret:      true,
+		AccessTokenValidity: 30,
+		TokenValidityUnits:  APITokenValidityUnits{AccessToken: "minutes"},
+	})
+
+	if !regexp.MustCompile(`^[a-z0-9]{26}$`).MatchString(client.ClientId) || len(client.ClientSecret) != 51 {
+		t.Fatal("Unexpected client credentials", client.ClientId, client.ClientSecret)
+	}
+	if client.AccessTokenValidity != 30 || client.IdTokenValidity != 1 || client.RefreshTokenValidity != 30 {
+		t.Fatal("Unexpected token validities", client)
+	}
+	if client.PreventUserExistenceErrors != "LEGACY" || client.AuthSessionValidity != 3 {
+		t.Fatal("Unexpected defaults", client)
+	}
+
+	described, awserr := c.DescribeUserPoolClient(DescribeUserPoolClientInput{UserPoolId: pool.Id, ClientId: client.ClientId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if described.UserPoolClient.ClientSecret != client.ClientSecret {
+		t.Fatal("Unexpected client", described.UserPoolClient)
+	}
+
+	_, awserr = c.CreateUserPoolClient(CreateUserPoolClientInput{
+		UserPoolId:          pool.Id,
+		ClientName:          "app",
+		AccessTokenValidity: 2,
+		TokenValidityUnits:  APITokenValidityUnits{AccessToken: "days"},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected access tokens to be limited to a day", awserr)
+	}
+
+	if _, awserr := c.DeleteUserPoolClient(DeleteUserPoolClientInput{UserPoolId: pool.Id, ClientId: client.ClientId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.DescribeUserPoolClient(DescribeUserPoolClientInput{UserPoolId: pool.Id, ClientId: client.ClientId})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected ResourceNotFoundException", awserr)
+	}
+}
@@ -0,0 +1,55 @@
+package cognitoidp
+
+import "aws-in-a-box/awserrors"
+
+func CodeMismatchException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("CodeMismatchException", message)
+}
+
+func ExpiredCodeException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ExpiredCodeException", message)
+}
+
+func InvalidLambdaResponseException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidLambdaResponseException", message)
+}
+
+func InvalidParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterException", message)
+}
+
+func InvalidPasswordException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidPasswordException", message)
+}
+
+func NotAuthorizedException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("NotAuthorizedException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
+
+func UnexpectedLambdaException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UnexpectedLambdaException", message)
+}
+
+func UnsupportedUserStateException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UnsupportedUserStateException", message)
+}
+
+func UsernameExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UsernameExistsException", message)
+}
+
+func UserLambdaValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UserLambdaValidationException", message)
+}
+
+func UserNotConfirmedException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UserNotConfirmedException", message)
+}
+
+func UserNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UserNotFoundException", message)
+}
@@ -0,0 +1,30 @@
+package cognitoidp
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AWSCognitoIdentityProviderService"
+
+func (c *CognitoIDP) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "AdminConfirmSignUp", region.Method(c.regions, (*CognitoIDP).AdminConfirmSignUp))
+	http.RegisterWithRequest(logger, methodRegistry, service, "AdminCreateUser", region.Method(c.regions, (*CognitoIDP).AdminCreateUser))
+	http.RegisterWithRequest(logger, methodRegistry, service, "AdminDeleteUser", region.Method(c.regions, (*CognitoIDP).AdminDeleteUser))
+	http.RegisterWithRequest(logger, methodRegistry, service, "AdminGetUser", region.Method(c.regions, (*CognitoIDP).AdminGetUser))
+	http.RegisterWithRequest(logger, methodRegistry, service, "AdminSetUserPassword", region.Method(c.regions, (*CognitoIDP).AdminSetUserPassword))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ConfirmSignUp", region.Method(c.regions, (*CognitoIDP).ConfirmSignUp))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateUserPool", region.Method(c.regions, (*CognitoIDP).CreateUserPool))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateUserPoolClient", region.Method(c.regions, (*CognitoIDP).CreateUserPoolClient))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteUserPool", region.Method(c.regions, (*CognitoIDP).DeleteUserPool))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteUserPoolClient", region.Method(c.regions, (*CognitoIDP).DeleteUserPoolClient))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeUserPool", region.Method(c.regions, (*CognitoIDP).DescribeUserPool))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeUserPoolClient", region.Method(c.regions, (*CognitoIDP).DescribeUserPoolClient))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetUser", region.Method(c.regions, (*CognitoIDP).GetUser))
+	http.RegisterWithRequest(logger, methodRegistry, service, "InitiateAuth", region.Method(c.regions, (*CognitoIDP).InitiateAuth))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListUserPools", region.Method(c.regions, (*CognitoIDP).ListUserPools))
+	http.RegisterWithRequest(logger, methodRegistry, service, "RespondToAuthChallenge", region.Method(c.regions, (*CognitoIDP).RespondToAuthChallenge))
+	http.RegisterWithRequest(logger, methodRegistry, service, "SignUp", region.Method(c.regions, (*CognitoIDP).SignUp))
+}
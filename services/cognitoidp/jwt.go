@@ -0,0 +1,129 @@
+package cognitoidp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Tokens are JSON Web Tokens, signed with RS256 by the user pool's key.
+// https://docs.aws.amazon.com/cognito/latest/developerguide/amazon-cognito-user-pools-using-tokens-verifying-a-jwt.html
+
+// https://datatracker.ietf.org/doc/html/rfc7517#section-4
+type jsonWebKey struct {
+	Alg string `json:"alg"`
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	Use string `json:"use"`
+}
+
+type jwtHeader struct {
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+}
+
+// generateSigningKey returns a new key, and its ID in JSON Web Key Sets and tokens' headers.
+func generateSigningKey() (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(der)
+	return key, base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func (p *UserPool) jsonWebKey() jsonWebKey {
+	return jsonWebKey{
+		Alg: "RS256",
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.PublicKey.E)).Bytes()),
+		Kid: p.keyId,
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(p.key.PublicKey.N.Bytes()),
+		Use: "sig",
+	}
+}
+
+func (p *UserPool) signToken(claims map[string]any) string {
+	header, err := json.Marshal(jwtHeader{Kid: p.keyId, Alg: "RS256"})
+	if err != nil {
+		panic(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, hash[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// parseTokenClaims returns the token's claims without verifying it, so its issuer can be found.
+func parseTokenClaims(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token doesn't have three parts")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifyToken checks the token was signed with the pool's key, and returns its claims.
+func (p *UserPool) verifyToken(token string) (map[string]any, error) {
+	signingInput, encodedSignature, ok := cutLast(token, ".")
+	if !ok {
+		return nil, errors.New("token doesn't have a signature")
+	}
+	header, _, _ := strings.Cut(signingInput, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return nil, err
+	}
+	var parsedHeader jwtHeader
+	if err := json.Unmarshal(headerJSON, &parsedHeader); err != nil {
+		return nil, err
+	}
+	if parsedHeader.Alg != "RS256" || parsedHeader.Kid != p.keyId {
+		return nil, errors.New("token wasn't signed by the user pool")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(signingInput))
+	if err := rsa.VerifyPKCS1v15(&p.key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		return nil, err
+	}
+	return parseTokenClaims(token)
+}
+
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,130 @@
+package cognitoidp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// USER_SRP_AUTH uses SRP-6a, so passwords are never sent, with the parameters and key derivation
+// of AWS's clients, such as amazon-cognito-identity-js's AuthenticationHelper.
+// Users' passwords are only stored as SRP verifiers, which USER_PASSWORD_AUTH checks too.
+
+var (
+	// The 3072-bit group from RFC 5054.
+	srpN = mustParseHex("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F" +
+		"83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA0510" +
+		"15728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7" +
+		"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864D87602733EC86A64521F2B18177B200C" +
+		"BBE117577A615D6C770988C0BAD946E208E24FA074E5AB3143DB5BFCE0FD108E4B82D120A93AD2CAFFFFFFFFFFFFFFFF")
+	srpG = big.NewInt(2)
+	srpK = hexHashInt(padHex(srpN) + padHex(srpG))
+)
+
+func mustParseHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex: " + s)
+	}
+	return n
+}
+
+// padHex encodes a positive number like the clients do before hashing it: as whole bytes,
+// with a leading zero byte if the high bit is set.
+func padHex(n *big.Int) string {
+	s := n.Text(16)
+	if len(s)%2 == 1 {
+		return "0" + s
+	}
+	if strings.ContainsRune("89abcdef", rune(s[0])) {
+		return "00" + s
+	}
+	return s
+}
+
+// hexHashInt hashes the bytes the hex string encodes.
+func hexHashInt(hexString string) *big.Int {
+	data, err := hex.DecodeString(hexString)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(data)
+	return new(big.Int).SetBytes(hash[:])
+}
+
+func randomInt(bytes int) *big.Int {
+	data := make([]byte, bytes)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return new(big.Int).SetBytes(data)
+}
+
+// srpPoolName is the part of the user pool's ID which is hashed with the password.
+func srpPoolName(poolId string) string {
+	_, name, _ := strings.Cut(poolId, "_")
+	return name
+}
+
+// srpPrivateKey is x, which is derived from the user's password.
+func srpPrivateKey(poolId string, userId string, password string, salt *big.Int) *big.Int {
+	hash := sha256.Sum256([]byte(srpPoolName(poolId) + userId + ":" + password))
+	return hexHashInt(padHex(salt) + hex.EncodeToString(hash[:]))
+}
+
+// srpVerifier returns a random salt, and the verifier of the password with it.
+func srpVerifier(poolId string, userId string, password string) (*big.Int, *big.Int) {
+	salt := randomInt(16)
+	x := srpPrivateKey(poolId, userId, password, salt)
+	return salt, new(big.Int).Exp(srpG, x, srpN)
+}
+
+// srpServerValues returns the server's secret b and public B for a login with the verifier.
+func srpServerValues(verifier *big.Int) (*big.Int, *big.Int) {
+	for {
+		b := randomInt(128)
+		B := new(big.Int).Mul(srpK, verifier)
+		B.Add(B, new(big.Int).Exp(srpG, b, srpN))
+		B.Mod(B, srpN)
+		if B.Sign() != 0 {
+			return b, B
+		}
+	}
+}
+
+// srpSessionKey returns the key both sides derive, which the client signs its claim with.
+func srpSessionKey(A *big.Int, B *big.Int, b *big.Int, verifier *big.Int) []byte {
+	u := hexHashInt(padHex(A) + padHex(B))
+	// S = (A * v^u) ^ b
+	S := new(big.Int).Exp(verifier, u, srpN)
+	S.Mul(S, A)
+	S.Exp(S, b, srpN)
+	return srpHKDF(padHex(S), padHex(u))
+}
+
+// srpHKDF derives the 16 byte key from S, salted with u.
+func srpHKDF(ikmHex string, saltHex string) []byte {
+	ikm, _ := hex.DecodeString(ikmHex)
+	salt, _ := hex.DecodeString(saltHex)
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte("Caldera Derived Key\x01"))
+	return expand.Sum(nil)[:16]
+}
+
+// srpClaimSignature is PASSWORD_CLAIM_SIGNATURE, before it's base64 encoded.
+func srpClaimSignature(key []byte, poolId string, userId string, secretBlock []byte, timestamp string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(srpPoolName(poolId)))
+	mac.Write([]byte(userId))
+	mac.Write(secretBlock)
+	mac.Write([]byte(timestamp))
+	return mac.Sum(nil)
+}
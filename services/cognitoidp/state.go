@@ -0,0 +1,203 @@
+package cognitoidp
+
+import (
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type userState struct {
+	*User
+	// The password's SRP salt and verifier.
+	Salt                      *big.Int
+	Verifier                  *big.Int
+	ConfirmationCode          string    `json:",omitempty"`
+	ConfirmationCodeExpiresAt time.Time `json:",omitempty"`
+}
+
+type poolState struct {
+	*UserPool
+	// The PKCS #1 encoded key which signs the pool's tokens.
+	Key   []byte
+	KeyId string
+	// Sorted by ID.
+	Clients []APIUserPoolClient
+	// Sorted by username.
+	Users []userState
+}
+
+type refreshTokenState struct {
+	Token     string
+	PoolId    string
+	ClientId  string
+	Username  string
+	OriginJti string
+	AuthTime  time.Time
+	ExpiresAt time.Time
+}
+
+type poolsState struct {
+	UserPools []poolState
+	// Sorted by token.
+	RefreshTokens []refreshTokenState
+	// Oldest first.
+	CapturedCodes []CapturedCode
+}
+
+// TryPause pauses changes to each region's user pools while a snapshot copies them.
+func (c *CognitoIDP) TryPause() (func(), bool) {
+	return c.regions.TryPause(func(c *CognitoIDP) *sync.Mutex { return &c.mu })
+}
+
+// ExportState writes each region's user pools with their signing keys, clients and users, its
+// unexpired refresh tokens, and its captured codes to the archive. Challenges which haven't been responded to
+// aren't exported.
+func (c *CognitoIDP) ExportState(w *state.Writer) error {
+	return c.regions.ExportState(w, (*CognitoIDP).exportState)
+}
+
+func (c *CognitoIDP) exportState(w *state.Writer) error {
+	unlock := w.Lock(&c.mu)
+	defer unlock()
+
+	exported := poolsState{CapturedCodes: c.capturedCodes}
+	for _, pool := range c.poolsById {
+		p := poolState{
+			UserPool: pool,
+			Key:      x509.MarshalPKCS1PrivateKey(pool.key),
+			KeyId:    pool.keyId,
+		}
+		for _, client := range pool.clientsById {
+			p.Clients = append(p.Clients, client.Config)
+		}
+		slices.SortFunc(p.Clients, func(a, b APIUserPoolClient) int {
+			return strings.Compare(a.ClientId, b.ClientId)
+		})
+		for _, user := range pool.usersByName {
+			p.Users = append(p.Users, userState{
+				User:                      user,
+				Salt:                      user.salt,
+				Verifier:                  user.verifier,
+				ConfirmationCode:          user.confirmationCode,
+				ConfirmationCodeExpiresAt: user.confirmationCodeExpiresAt,
+			})
+		}
+		slices.SortFunc(p.Users, func(a, b userState) int {
+			return strings.Compare(a.Username, b.Username)
+		})
+		exported.UserPools = append(exported.UserPools, p)
+	}
+	slices.SortFunc(exported.UserPools, func(a, b poolState) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	for token, refresh := range c.refreshTokens {
+		exported.RefreshTokens = append(exported.RefreshTokens, refreshTokenState{
+			Token:     token,
+			PoolId:    refresh.poolId,
+			ClientId:  refresh.clientId,
+			Username:  refresh.username,
+			OriginJti: refresh.originJti,
+			AuthTime:  refresh.authTime,
+			ExpiresAt: refresh.expiresAt,
+		})
+	}
+	slices.SortFunc(exported.RefreshTokens, func(a, b refreshTokenState) int {
+		return strings.Compare(a.Token, b.Token)
+	})
+	return w.WriteJSON("userpools.json", exported)
+}
+
+// ImportState replaces each region's user pools, refresh tokens and captured codes with the
+// archive's. Tokens issued before the state was exported stay valid, since the pools keep their
+// signing keys. Challenges in progress have to be started again.
+func (c *CognitoIDP) ImportState(r *state.Reader) (func() error, error) {
+	return c.regions.ImportState(r, (*CognitoIDP).importState, (*CognitoIDP).exportState)
+}
+
+func (c *CognitoIDP) importState(r *state.Reader) (func() error, error) {
+	var imported poolsState
+	if err := r.ReadJSON("userpools.json", &imported); err != nil {
+		return nil, err
+	}
+
+	poolsById := make(map[string]*UserPool)
+	clientsById := make(map[string]*UserPoolClient)
+	for _, p := range imported.UserPools {
+		pool := p.UserPool
+		if pool == nil || pool.Id == "" || !userPoolNameRegex.MatchString(pool.Name) {
+			return nil, fmt.Errorf("invalid user pool")
+		}
+		key, err := x509.ParsePKCS1PrivateKey(p.Key)
+		if err != nil {
+			return nil, fmt.Errorf("user pool %s: %v", pool.Id, err)
+		}
+		pool.key = key
+		pool.keyId = p.KeyId
+		if pool.Tags == nil {
+			pool.Tags = make(map[string]string)
+		}
+		pool.clientsById = make(map[string]*UserPoolClient)
+		for _, config := range p.Clients {
+			units := config.TokenValidityUnits
+			if validityUnits[units.AccessToken] == 0 || validityUnits[units.IdToken] == 0 || validityUnits[units.RefreshToken] == 0 {
+				return nil, fmt.Errorf("client %s: invalid token validity units", config.ClientId)
+			}
+			client := &UserPoolClient{
+				Config:               config,
+				accessTokenValidity:  time.Duration(config.AccessTokenValidity) * validityUnits[units.AccessToken],
+				idTokenValidity:      time.Duration(config.IdTokenValidity) * validityUnits[units.IdToken],
+				refreshTokenValidity: time.Duration(config.RefreshTokenValidity) * validityUnits[units.RefreshToken],
+			}
+			pool.clientsById[config.ClientId] = client
+			clientsById[config.ClientId] = client
+		}
+		pool.usersByName = make(map[string]*User)
+		for _, u := range p.Users {
+			user := u.User
+			if user == nil || user.Username == "" || u.Salt == nil || u.Verifier == nil {
+				return nil, fmt.Errorf("user pool %s: invalid user", pool.Id)
+			}
+			user.salt = u.Salt
+			user.verifier = u.Verifier
+			user.confirmationCode = u.ConfirmationCode
+			user.confirmationCodeExpiresAt = u.ConfirmationCodeExpiresAt
+			if user.Attributes == nil {
+				user.Attributes = make(map[string]string)
+			}
+			pool.usersByName[user.Username] = user
+		}
+		poolsById[pool.Id] = pool
+	}
+
+	refreshTokens := make(map[string]*refreshSession)
+	for _, refresh := range imported.RefreshTokens {
+		if _, ok := poolsById[refresh.PoolId]; !ok {
+			return nil, fmt.Errorf("refresh token for user pool %s, which doesn't exist", refresh.PoolId)
+		}
+		refreshTokens[refresh.Token] = &refreshSession{
+			poolId:    refresh.PoolId,
+			clientId:  refresh.ClientId,
+			username:  refresh.Username,
+			originJti: refresh.OriginJti,
+			authTime:  refresh.AuthTime,
+			expiresAt: refresh.ExpiresAt,
+		}
+	}
+
+	return func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.poolsById = poolsById
+		c.clientsById = clientsById
+		c.authSessions = make(map[string]*authSession)
+		c.refreshTokens = refreshTokens
+		c.capturedCodes = imported.CapturedCodes
+		return nil
+	}, nil
+}
@@ -0,0 +1,458 @@
+package cognitoidp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/random"
+	"aws-in-a-box/services/lambda"
+)
+
+// User pools invoke Lambda functions at points in their flows, with these events.
+// https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-identity-pools-working-with-aws-lambda-triggers.html
+
+type LambdaInvoker interface {
+	Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error)
+}
+
+var lambdaArnRegex = regexp.MustCompile(`^arn:aws:lambda:[\w-]+:\d{12}:function:[\w-]+(:[\w$-]+)?$`)
+
+// Claims which PreTokenGeneration can't add, override or suppress.
+var protectedClaims = []string{
+	"acr", "amr", "at_hash", "aud", "auth_time", "azp", "client_id", "cognito:username", "event_id", "exp", "iat",
+	"identities", "iss", "jti", "nbf", "nonce", "origin_jti", "scope", "sub", "token_use", "username",
+}
+
+func validateLambdaConfig(config APILambdaConfig) *awserrors.Error {
+	arns := []string{
+		config.PreSignUp, config.CustomMessage, config.PostConfirmation, config.PreAuthentication,
+		config.PostAuthentication, config.DefineAuthChallenge, config.CreateAuthChallenge,
+		config.VerifyAuthChallengeResponse, config.PreTokenGeneration, config.UserMigration,
+	}
+	if config.PreTokenGenerationConfig != nil {
+		switch config.PreTokenGenerationConfig.LambdaVersion {
+		case "V1_0", "V2_0":
+		default:
+			return InvalidParameterException("PreTokenGenerationConfig LambdaVersion must be V1_0 or V2_0.")
+		}
+		if config.PreTokenGeneration != "" && config.PreTokenGeneration != config.PreTokenGenerationConfig.LambdaArn {
+			return InvalidParameterException("PreTokenGeneration and PreTokenGenerationConfig LambdaArn must be the same.")
+		}
+		arns = append(arns, config.PreTokenGenerationConfig.LambdaArn)
+	}
+	for _, arn := range arns {
+		if arn != "" && !lambdaArnRegex.MatchString(arn) {
+			return InvalidParameterException("Invalid Lambda function ARN: " + arn)
+		}
+	}
+	return nil
+}
+
+// preTokenGeneration returns the PreTokenGeneration function, and the version of the event it's invoked with.
+func (p *UserPool) preTokenGeneration() (string, string) {
+	if config := p.LambdaConfig.PreTokenGenerationConfig; config != nil {
+		if config.LambdaVersion == "V2_0" {
+			return config.LambdaArn, "2"
+		}
+		return config.LambdaArn, "1"
+	}
+	return p.LambdaConfig.PreTokenGeneration, "1"
+}
+
+type triggerCallerContext struct {
+	AwsSdkVersion string `json:"awsSdkVersion"`
+	ClientId      string `json:"clientId,omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-identity-pools-working-with-aws-lambda-triggers.html#cognito-user-pools-lambda-trigger-syntax-shared
+type triggerEvent struct {
+	Version       string               `json:"version"`
+	TriggerSource string               `json:"triggerSource"`
+	Region        string               `json:"region"`
+	UserPoolId    string               `json:"userPoolId"`
+	UserName      string               `json:"userName"`
+	CallerContext triggerCallerContext `json:"callerContext"`
+	Request       any                  `json:"request"`
+	Response      any                  `json:"response"`
+}
+
+type preSignUpRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	ValidationData map[string]string `json:"validationData"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+}
+
+type preSignUpResponse struct {
+	AutoConfirmUser bool `json:"autoConfirmUser"`
+	AutoVerifyEmail bool `json:"autoVerifyEmail"`
+	AutoVerifyPhone bool `json:"autoVerifyPhone"`
+}
+
+type postConfirmationRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+}
+
+type groupConfiguration struct {
+	GroupsToOverride   []string `json:"groupsToOverride"`
+	IamRolesToOverride []string `json:"iamRolesToOverride"`
+	PreferredRole      *string  `json:"preferredRole"`
+}
+
+type preTokenGenerationRequest struct {
+	UserAttributes     map[string]string  `json:"userAttributes"`
+	GroupConfiguration groupConfiguration `json:"groupConfiguration"`
+	// Only in version 2 events.
+	Scopes         []string          `json:"scopes,omitempty"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+}
+
+type claimsOverride struct {
+	ClaimsToAddOrOverride map[string]any `json:"claimsToAddOrOverride"`
+	ClaimsToSuppress      []string       `json:"claimsToSuppress"`
+	// Only for access tokens.
+	ScopesToAdd      []string `json:"scopesToAdd"`
+	ScopesToSuppress []string `json:"scopesToSuppress"`
+}
+
+type preTokenGenerationResponse struct {
+	// Version 1 events can only change ID tokens.
+	ClaimsOverrideDetails *claimsOverride `json:"claimsOverrideDetails"`
+	// Version 2 events can change both tokens.
+	ClaimsAndScopeOverrideDetails *struct {
+		IdTokenGeneration     *claimsOverride `json:"idTokenGeneration"`
+		AccessTokenGeneration *claimsOverride `json:"accessTokenGeneration"`
+	} `json:"claimsAndScopeOverrideDetails"`
+}
+
+// challengeResult is one of a custom auth flow's challenges, and whether it was answered correctly.
+type challengeResult struct {
+	ChallengeName     string `json:"challengeName"`
+	ChallengeResult   bool   `json:"challengeResult"`
+	ChallengeMetadata string `json:"challengeMetadata,omitempty"`
+}
+
+type defineAuthChallengeRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	Session        []challengeResult `json:"session"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+	UserNotFound   bool              `json:"userNotFound"`
+}
+
+type defineAuthChallengeResponse struct {
+	ChallengeName      string `json:"challengeName"`
+	IssueTokens        bool   `json:"issueTokens"`
+	FailAuthentication bool   `json:"failAuthentication"`
+}
+
+type createAuthChallengeRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	ChallengeName  string            `json:"challengeName"`
+	Session        []challengeResult `json:"session"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+	UserNotFound   bool              `json:"userNotFound"`
+}
+
+type createAuthChallengeResponse struct {
+	PublicChallengeParameters  map[string]string `json:"publicChallengeParameters"`
+	PrivateChallengeParameters map[string]string `json:"privateChallengeParameters"`
+	ChallengeMetadata          string            `json:"challengeMetadata"`
+}
+
+type verifyAuthChallengeResponseRequest struct {
+	UserAttributes             map[string]string `json:"userAttributes"`
+	PrivateChallengeParameters map[string]string `json:"privateChallengeParameters"`
+	ChallengeAnswer            string            `json:"challengeAnswer"`
+	ClientMetadata             map[string]string `json:"clientMetadata,omitempty"`
+	UserNotFound               bool              `json:"userNotFound"`
+}
+
+type verifyAuthChallengeResponseResponse struct {
+	AnswerCorrect bool `json:"answerCorrect"`
+}
+
+// triggerUserAttributes are the user's attributes as triggers see them, with their status.
+func triggerUserAttributes(user *User) map[string]string {
+	attributes := map[string]string{"cognito:user_status": user.Status}
+	for name, value := range user.Attributes {
+		attributes[name] = value
+	}
+	return attributes
+}
+
+// lockedInvokeTrigger invokes the trigger's function with the event, and decodes its response into the
+// event's response. The lock is released while the function runs, since it may call back into Cognito,
+// so callers must look up anything they need again afterwards.
+func (c *CognitoIDP) lockedInvokeTrigger(name string, functionArn string, pool *UserPool, event triggerEvent) *awserrors.Error {
+	event.Region = c.arnGenerator.Region
+	event.UserPoolId = pool.Id
+	event.CallerContext.AwsSdkVersion = "aws-sdk-unknown-unknown"
+	if event.Version == "" {
+		event.Version = "1"
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		panic(err)
+	}
+
+	c.mu.Unlock()
+	defer c.mu.Lock()
+
+	if c.lambda == nil {
+		return UnexpectedLambdaException(name + " invocation failed because Lambda is not enabled.")
+	}
+	c.logger.Debug("Invoking trigger", "trigger", name, "triggerSource", event.TriggerSource, "function", functionArn)
+	output, awserr := c.lambda.Invoke(lambda.InvokeInput{
+		FunctionName: functionArn,
+		Payload:      payload,
+	})
+	if awserr != nil {
+		return UnexpectedLambdaException(fmt.Sprintf("%s invocation failed due to error %s.", name, awserr.Body.Type))
+	}
+	if output.FunctionError != "" {
+		var functionError struct {
+			ErrorMessage string `json:"errorMessage"`
+		}
+		_ = json.Unmarshal(output.Payload, &functionError)
+		return UserLambdaValidationException(fmt.Sprintf("%s failed with error %s.", name, functionError.ErrorMessage))
+	}
+	var response struct {
+		Response json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(output.Payload, &response); err != nil {
+		return InvalidLambdaResponseException("Unrecognizable lambda output")
+	}
+	if len(response.Response) > 0 && string(response.Response) != "null" {
+		if err := json.Unmarshal(response.Response, event.Response); err != nil {
+			return InvalidLambdaResponseException("Unrecognizable lambda output")
+		}
+	}
+	return nil
+}
+
+// applyClaimsOverride changes the token's claims like PreTokenGeneration asked, except for protected claims.
+func applyClaimsOverride(claims map[string]any, override *claimsOverride) {
+	if override == nil {
+		return
+	}
+	for name, value := range override.ClaimsToAddOrOverride {
+		if !slices.Contains(protectedClaims, name) {
+			claims[name] = value
+		}
+	}
+	for _, name := range override.ClaimsToSuppress {
+		if !slices.Contains(protectedClaims, name) {
+			delete(claims, name)
+		}
+	}
+}
+
+// lockedCheckUserExists checks the user, and their pool, weren't deleted while a trigger ran.
+func (c *CognitoIDP) lockedCheckUserExists(pool *UserPool, user *User) *awserrors.Error {
+	if c.poolsById[pool.Id] != pool || pool.usersByName[user.Username] != user {
+		return UserNotFoundException("User does not exist.")
+	}
+	return nil
+}
+
+// lockedPreSignUp invokes the pool's PreSignUp trigger, if it has one, for the user who is signing up
+// but isn't in the pool yet. It returns the pool, which is looked up again afterwards.
+func (c *CognitoIDP) lockedPreSignUp(pool *UserPool, user *User, triggerSource string, clientId string, validationData []APIAttribute, clientMetadata map[string]string) (*UserPool, *preSignUpResponse, *awserrors.Error) {
+	response := &preSignUpResponse{}
+	if pool.LambdaConfig.PreSignUp == "" {
+		return pool, response, nil
+	}
+	attributes := make(map[string]string)
+	for name, value := range user.Attributes {
+		if name != "sub" {
+			attributes[name] = value
+		}
+	}
+	data := make(map[string]string)
+	for _, attribute := range validationData {
+		data[attribute.Name] = attribute.Value
+	}
+	awserr := c.lockedInvokeTrigger("PreSignUp", pool.LambdaConfig.PreSignUp, pool, triggerEvent{
+		TriggerSource: triggerSource,
+		UserName:      user.Username,
+		CallerContext: triggerCallerContext{ClientId: clientId},
+		Request: preSignUpRequest{
+			UserAttributes: attributes,
+			ValidationData: data,
+			ClientMetadata: clientMetadata,
+		},
+		Response: response,
+	})
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	// Someone else may have signed up with the same username meanwhile.
+	pool, awserr = c.lockedGetUserPool(pool.Id)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	if awserr := pool.lockedCheckUsernameAvailable(user); awserr != nil {
+		return nil, nil, awserr
+	}
+	return pool, response, nil
+}
+
+// lockedPostConfirmation invokes the pool's PostConfirmation trigger, if it has one, for the user who was confirmed.
+func (c *CognitoIDP) lockedPostConfirmation(pool *UserPool, user *User, clientId string, clientMetadata map[string]string) *awserrors.Error {
+	if pool.LambdaConfig.PostConfirmation == "" {
+		return nil
+	}
+	return c.lockedInvokeTrigger("PostConfirmation", pool.LambdaConfig.PostConfirmation, pool, triggerEvent{
+		TriggerSource: "PostConfirmation_ConfirmSignUp",
+		UserName:      user.Username,
+		CallerContext: triggerCallerContext{ClientId: clientId},
+		Request: postConfirmationRequest{
+			UserAttributes: triggerUserAttributes(user),
+			ClientMetadata: clientMetadata,
+		},
+		Response: &struct{}{},
+	})
+}
+
+// lockedPreTokenGeneration invokes the pool's PreTokenGeneration trigger, if it has one, before tokens
+// are issued to the user.
+func (c *CognitoIDP) lockedPreTokenGeneration(pool *UserPool, client *UserPoolClient, user *User, triggerSource string, clientMetadata map[string]string) (*preTokenGenerationResponse, *awserrors.Error) {
+	response := &preTokenGenerationResponse{}
+	functionArn, version := pool.preTokenGeneration()
+	if functionArn == "" {
+		return response, nil
+	}
+	request := preTokenGenerationRequest{
+		UserAttributes: triggerUserAttributes(user),
+		GroupConfiguration: groupConfiguration{
+			GroupsToOverride:   []string{},
+			IamRolesToOverride: []string{},
+		},
+		ClientMetadata: clientMetadata,
+	}
+	if version == "2" {
+		request.Scopes = []string{accessTokenScope}
+	}
+	awserr := c.lockedInvokeTrigger("PreTokenGeneration", functionArn, pool, triggerEvent{
+		Version:       version,
+		TriggerSource: triggerSource,
+		UserName:      user.Username,
+		CallerContext: triggerCallerContext{ClientId: client.Config.ClientId},
+		Request:       request,
+		Response:      response,
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := c.lockedCheckUserExists(pool, user); awserr != nil {
+		return nil, awserr
+	}
+	return response, nil
+}
+
+// lockedNextCustomChallenge asks the pool's DefineAuthChallenge trigger what comes next in a custom auth
+// flow, given the challenges so far: tokens, failure, or another challenge, which the CreateAuthChallenge
+// trigger creates.
+// https://docs.aws.amazon.com/cognito/latest/developerguide/user-pool-lambda-challenge.html
+func (c *CognitoIDP) lockedNextCustomChallenge(pool *UserPool, client *UserPoolClient, user *User, history []challengeResult, clientMetadata map[string]string) (*InitiateAuthOutput, *awserrors.Error) {
+	define := &defineAuthChallengeResponse{}
+	awserr := c.lockedInvokeTrigger("DefineAuthChallenge", pool.LambdaConfig.DefineAuthChallenge, pool, triggerEvent{
+		TriggerSource: "DefineAuthChallenge_Authentication",
+		UserName:      user.Username,
+		CallerContext: triggerCallerContext{ClientId: client.Config.ClientId},
+		Request: defineAuthChallengeRequest{
+			UserAttributes: triggerUserAttributes(user),
+			Session:        history,
+			ClientMetadata: clientMetadata,
+		},
+		Response: define,
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := c.lockedCheckUserExists(pool, user); awserr != nil {
+		return nil, awserr
+	}
+	switch {
+	case define.FailAuthentication:
+		return nil, NotAuthorizedException("Incorrect username or password.")
+	case define.IssueTokens:
+		return c.lockedSignIn(pool, client, user, "TokenGeneration_Authentication", clientMetadata)
+	case define.ChallengeName != "CUSTOM_CHALLENGE":
+		return nil, InvalidLambdaResponseException("Unsupported challenge name " + define.ChallengeName)
+	case pool.LambdaConfig.CreateAuthChallenge == "":
+		return nil, InvalidLambdaResponseException("Create auth challenge lambda trigger is not configured for the user pool.")
+	}
+
+	create := &createAuthChallengeResponse{}
+	awserr = c.lockedInvokeTrigger("CreateAuthChallenge", pool.LambdaConfig.CreateAuthChallenge, pool, triggerEvent{
+		TriggerSource: "CreateAuthChallenge_Authentication",
+		UserName:      user.Username,
+		CallerContext: triggerCallerContext{ClientId: client.Config.ClientId},
+		Request: createAuthChallengeRequest{
+			UserAttributes: triggerUserAttributes(user),
+			ChallengeName:  define.ChallengeName,
+			Session:        history,
+			ClientMetadata: clientMetadata,
+		},
+		Response: create,
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := c.lockedCheckUserExists(pool, user); awserr != nil {
+		return nil, awserr
+	}
+
+	id := random.String(alphanumeric, 128)
+	session := c.lockedNewSession(id, client, user, "CUSTOM_CHALLENGE")
+	session.challengeHistory = history
+	session.challengeMetadata = create.ChallengeMetadata
+	session.privateChallengeParameters = create.PrivateChallengeParameters
+	parameters := map[string]string{}
+	for name, value := range create.PublicChallengeParameters {
+		parameters[name] = value
+	}
+	parameters["USERNAME"] = user.Username
+	return &InitiateAuthOutput{
+		ChallengeName:       "CUSTOM_CHALLENGE",
+		ChallengeParameters: parameters,
+		Session:             id,
+	}, nil
+}
+
+// lockedVerifyCustomChallenge asks the pool's VerifyAuthChallengeResponse trigger whether the answer to
+// the session's custom challenge is correct, and continues the custom auth flow.
+func (c *CognitoIDP) lockedVerifyCustomChallenge(pool *UserPool, client *UserPoolClient, user *User, session *authSession, answer string, clientMetadata map[string]string) (*InitiateAuthOutput, *awserrors.Error) {
+	if pool.LambdaConfig.VerifyAuthChallengeResponse == "" {
+		return nil, InvalidLambdaResponseException("Verify auth challenge response lambda trigger is not configured for the user pool.")
+	}
+	verify := &verifyAuthChallengeResponseResponse{}
+	awserr := c.lockedInvokeTrigger("VerifyAuthChallengeResponse", pool.LambdaConfig.VerifyAuthChallengeResponse, pool, triggerEvent{
+		TriggerSource: "VerifyAuthChallengeResponse_Authentication",
+		UserName:      user.Username,
+		CallerContext: triggerCallerContext{ClientId: client.Config.ClientId},
+		Request: verifyAuthChallengeResponseRequest{
+			UserAttributes:             triggerUserAttributes(user),
+			PrivateChallengeParameters: session.privateChallengeParameters,
+			ChallengeAnswer:            answer,
+			ClientMetadata:             clientMetadata,
+		},
+		Response: verify,
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := c.lockedCheckUserExists(pool, user); awserr != nil {
+		return nil, awserr
+	}
+	history := append(slices.Clone(session.challengeHistory), challengeResult{
+		ChallengeName:     "CUSTOM_CHALLENGE",
+		ChallengeResult:   verify.AnswerCorrect,
+		ChallengeMetadata: session.challengeMetadata,
+	})
+	return c.lockedNextCustomChallenge(pool, client, user, history, clientMetadata)
+}
@@ -0,0 +1,402 @@
+package cognitoidp
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/lambda"
+)
+
+// fakeLambdaInvoker runs the trigger functions, by their ARN, and records the events they're invoked with.
+type fakeLambdaInvoker struct {
+	// Return the trigger's response to the event, or its error.
+	functions map[string]func(event map[string]any) (any, error)
+
+	events []map[string]any
+}
+
+func (f *fakeLambdaInvoker) Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error) {
+	function, ok := f.functions[input.FunctionName]
+	if !ok {
+		return nil, lambda.ResourceNotFoundException("Function not found: " + input.FunctionName)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(input.Payload, &event); err != nil {
+		panic(err)
+	}
+	f.events = append(f.events, event)
+
+	output := &lambda.InvokeOutput{StatusCode: 200}
+	response, err := function(event)
+	if err != nil {
+		output.FunctionError = "Unhandled"
+		output.Payload, _ = json.Marshal(map[string]string{"errorType": "Error", "errorMessage": err.Error()})
+		return output, nil
+	}
+	event["response"] = response
+	output.Payload, _ = json.Marshal(event)
+	return output, nil
+}
+
+func (f *fakeLambdaInvoker) lastEvent(t *testing.T) map[string]any {
+	if len(f.events) == 0 {
+		t.Fatal("No triggers were invoked")
+	}
+	return f.events[len(f.events)-1]
+}
+
+const (
+	preSignUpArn          = "arn:aws:lambda:us-east-1:123456789012:function:pre-sign-up"
+	postConfirmationArn   = "arn:aws:lambda:us-east-1:123456789012:function:post-confirmation"
+	preTokenGenerationArn = "arn:aws:lambda:us-east-1:123456789012:function:pre-token-generation"
+	defineAuthArn         = "arn:aws:lambda:us-east-1:123456789012:function:define-auth"
+	createAuthArn         = "arn:aws:lambda:us-east-1:123456789012:function:create-auth"
+	verifyAuthArn         = "arn:aws:lambda:us-east-1:123456789012:function:verify-auth"
+)
+
+func newTriggerPool(t *testing.T, config APILambdaConfig) (*CognitoIDP, *fakeLambdaInvoker, APIUserPool, APIUserPoolClient) {
+	c := newCognitoIDP(t)
+	invoker := &fakeLambdaInvoker{functions: make(map[string]func(event map[string]any) (any, error))}
+	c.lambda = invoker
+	pool := createUserPool(t, c, CreateUserPoolInput{
+		AutoVerifiedAttributes: []string{"email"},
+		LambdaConfig:           config,
+	})
+	client := createClient(t, c, CreateUserPoolClientInput{
+		UserPoolId: pool.Id,
+		ExplicitAuthFlows: []string{
+			"ALLOW_USER_PASSWORD_AUTH", "ALLOW_CUSTOM_AUTH", "ALLOW_REFRESH_TOKEN_AUTH",
+		},
+	})
+	return c, invoker, pool, client
+}
+
+func TestInvalidLambdaConfig(t *testing.T) {
+	c := newCognitoIDP(t)
+	for _, config := range []APILambdaConfig{
+		{PreSignUp: "pre-sign-up"},
+		{PreTokenGenerationConfig: &APILambdaVersionConfig{LambdaArn: preTokenGenerationArn, LambdaVersion: "V3_0"}},
+	} {
+		_, awserr := c.CreateUserPool(CreateUserPoolInput{PoolName: "users", LambdaConfig: config})
+		if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+			t.Fatalf("Expected InvalidParameterException for %+v, got %v", config, awserr)
+		}
+	}
+}
+
+func TestPreSignUpTrigger(t *testing.T) {
+	c, invoker, pool, client := newTriggerPool(t, APILambdaConfig{PreSignUp: preSignUpArn})
+	if pool.LambdaConfig.PreSignUp != preSignUpArn {
+		t.Fatalf("Unexpected LambdaConfig: %+v", pool.LambdaConfig)
+	}
+	invoker.functions[preSignUpArn] = func(event map[string]any) (any, error) {
+		request := event["request"].(map[string]any)
+		if request["validationData"].(map[string]any)["invite"] != "secret" {
+			return nil, errors.New("Invalid invite")
+		}
+		return map[string]any{"autoConfirmUser": true, "autoVerifyEmail": true}, nil
+	}
+
+	output, awserr := c.SignUp(SignUpInput{
+		ClientId:       client.ClientId,
+		Username:       "alice",
+		Password:       password,
+		UserAttributes: []APIAttribute{{Name: "email", Value: "alice@example.com"}},
+		ValidationData: []APIAttribute{{Name: "invite", Value: "secret"}},
+		ClientMetadata: map[string]string{"source": "test"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !output.UserConfirmed || output.CodeDeliveryDetails != nil {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	event := invoker.lastEvent(t)
+	if event["triggerSource"] != "PreSignUp_SignUp" || event["userPoolId"] != pool.Id || event["userName"] != "alice" ||
+		event["region"] != "us-east-1" || event["callerContext"].(map[string]any)["clientId"] != client.ClientId {
+		t.Fatalf("Unexpected event: %s", toJSON(t, event))
+	}
+	request := event["request"].(map[string]any)
+	if request["userAttributes"].(map[string]any)["email"] != "alice@example.com" ||
+		request["clientMetadata"].(map[string]any)["source"] != "test" {
+		t.Fatalf("Unexpected request: %s", toJSON(t, request))
+	}
+
+	user, awserr := c.AdminGetUser(AdminGetUserInput{UserPoolId: pool.Id, Username: "alice"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if user.UserStatus != "CONFIRMED" || !hasAttribute(user.UserAttributes, "email_verified", "true") {
+		t.Fatalf("Unexpected user: %+v", user)
+	}
+	if codes := c.CapturedCodes(); len(codes) != 0 {
+		t.Fatalf("Unexpected codes: %+v", codes)
+	}
+
+	// Functions' errors reject the sign up.
+	_, awserr = c.SignUp(SignUpInput{
+		ClientId: client.ClientId,
+		Username: "bob",
+		Password: password,
+	})
+	if awserr == nil || awserr.Body.Type != "UserLambdaValidationException" ||
+		awserr.Body.Message != "PreSignUp failed with error Invalid invite." {
+		t.Fatalf("Expected UserLambdaValidationException, got %v", awserr)
+	}
+	if _, awserr := c.AdminGetUser(AdminGetUserInput{UserPoolId: pool.Id, Username: "bob"}); awserr == nil {
+		t.Fatal("Expected rejected user not to exist")
+	}
+
+	_, awserr = c.AdminCreateUser(AdminCreateUserInput{
+		UserPoolId:     pool.Id,
+		Username:       "carol",
+		ValidationData: []APIAttribute{{Name: "invite", Value: "secret"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if event := invoker.lastEvent(t); event["triggerSource"] != "PreSignUp_AdminCreateUser" {
+		t.Fatalf("Unexpected event: %s", toJSON(t, event))
+	}
+}
+
+func hasAttribute(attributes []APIAttribute, name string, value string) bool {
+	for _, attribute := range attributes {
+		if attribute.Name == name && attribute.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPostConfirmationTrigger(t *testing.T) {
+	c, invoker, pool, client := newTriggerPool(t, APILambdaConfig{PostConfirmation: postConfirmationArn})
+	invoker.functions[postConfirmationArn] = func(event map[string]any) (any, error) {
+		// Functions can call back into Cognito.
+		user, awserr := c.AdminGetUser(AdminGetUserInput{UserPoolId: pool.Id, Username: event["userName"].(string)})
+		if awserr != nil {
+			return nil, errors.New(awserr.Body.Message)
+		}
+		if user.UserStatus != "CONFIRMED" {
+			return nil, errors.New("User is " + user.UserStatus)
+		}
+		return map[string]any{}, nil
+	}
+
+	signUp(t, c, client, "alice")
+	event := invoker.lastEvent(t)
+	attributes := event["request"].(map[string]any)["userAttributes"].(map[string]any)
+	if event["triggerSource"] != "PostConfirmation_ConfirmSignUp" || attributes["cognito:user_status"] != "CONFIRMED" ||
+		attributes["email_verified"] != "true" || attributes["sub"] == nil {
+		t.Fatalf("Unexpected event: %s", toJSON(t, event))
+	}
+
+	// Failures are returned, but the user stays confirmed.
+	invoker.functions[postConfirmationArn] = func(event map[string]any) (any, error) {
+		return nil, errors.New("Database is down.")
+	}
+	_, awserr := c.SignUp(SignUpInput{ClientId: client.ClientId, Username: "bob", Password: password})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = c.AdminConfirmSignUp(AdminConfirmSignUpInput{UserPoolId: pool.Id, Username: "bob"})
+	if awserr == nil || awserr.Body.Type != "UserLambdaValidationException" {
+		t.Fatalf("Expected UserLambdaValidationException, got %v", awserr)
+	}
+	user, awserr := c.AdminGetUser(AdminGetUserInput{UserPoolId: pool.Id, Username: "bob"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if user.UserStatus != "CONFIRMED" {
+		t.Fatalf("Unexpected status: %s", user.UserStatus)
+	}
+}
+
+func TestPreTokenGenerationTrigger(t *testing.T) {
+	t.Run("V1", func(t *testing.T) {
+		c, invoker, _, client := newTriggerPool(t, APILambdaConfig{PreTokenGeneration: preTokenGenerationArn})
+		invoker.functions[preTokenGenerationArn] = func(event map[string]any) (any, error) {
+			return map[string]any{
+				"claimsOverrideDetails": map[string]any{
+					"claimsToAddOrOverride": map[string]any{"department": "engineering", "sub": "someone-else"},
+					"claimsToSuppress":      []string{"email"},
+				},
+			}, nil
+		}
+		signUp(t, c, client, "alice")
+
+		output := passwordAuth(t, c, client, "alice", password)
+		event := invoker.lastEvent(t)
+		if event["version"] != "1" || event["triggerSource"] != "TokenGeneration_Authentication" {
+			t.Fatalf("Unexpected event: %s", toJSON(t, event))
+		}
+		claims, err := parseTokenClaims(output.AuthenticationResult.IdToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims["department"] != "engineering" || claims["email"] != nil || claims["sub"] == "someone-else" {
+			t.Fatalf("Unexpected claims: %s", toJSON(t, claims))
+		}
+
+		_, awserr := c.InitiateAuth(InitiateAuthInput{
+			AuthFlow:       "REFRESH_TOKEN_AUTH",
+			ClientId:       client.ClientId,
+			AuthParameters: map[string]string{"REFRESH_TOKEN": output.AuthenticationResult.RefreshToken},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if event := invoker.lastEvent(t); event["triggerSource"] != "TokenGeneration_RefreshTokens" {
+			t.Fatalf("Unexpected event: %s", toJSON(t, event))
+		}
+	})
+
+	t.Run("V2", func(t *testing.T) {
+		c, invoker, _, client := newTriggerPool(t, APILambdaConfig{
+			PreTokenGenerationConfig: &APILambdaVersionConfig{LambdaArn: preTokenGenerationArn, LambdaVersion: "V2_0"},
+		})
+		invoker.functions[preTokenGenerationArn] = func(event map[string]any) (any, error) {
+			return map[string]any{
+				"claimsAndScopeOverrideDetails": map[string]any{
+					"idTokenGeneration": map[string]any{
+						"claimsToAddOrOverride": map[string]any{"tier": "gold"},
+					},
+					"accessTokenGeneration": map[string]any{
+						"claimsToAddOrOverride": map[string]any{"tenant": "acme"},
+						"scopesToAdd":           []string{"orders/read"},
+					},
+				},
+			}, nil
+		}
+		signUp(t, c, client, "alice")
+
+		output := passwordAuth(t, c, client, "alice", password)
+		event := invoker.lastEvent(t)
+		if event["version"] != "2" || event["request"].(map[string]any)["scopes"] == nil {
+			t.Fatalf("Unexpected event: %s", toJSON(t, event))
+		}
+		idClaims, err := parseTokenClaims(output.AuthenticationResult.IdToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accessClaims, err := parseTokenClaims(output.AuthenticationResult.AccessToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if idClaims["tier"] != "gold" || accessClaims["tenant"] != "acme" ||
+			accessClaims["scope"] != "aws.cognito.signin.user.admin orders/read" {
+			t.Fatalf("Unexpected claims: %s %s", toJSON(t, idClaims), toJSON(t, accessClaims))
+		}
+	})
+}
+
+func TestCustomAuth(t *testing.T) {
+	c, invoker, _, client := newTriggerPool(t, APILambdaConfig{
+		DefineAuthChallenge:         defineAuthArn,
+		CreateAuthChallenge:         createAuthArn,
+		VerifyAuthChallengeResponse: verifyAuthArn,
+	})
+	// Users get two tries to answer the question.
+	invoker.functions[defineAuthArn] = func(event map[string]any) (any, error) {
+		session := event["request"].(map[string]any)["session"].([]any)
+		switch {
+		case len(session) > 0 && session[len(session)-1].(map[string]any)["challengeResult"] == true:
+			return map[string]any{"issueTokens": true}, nil
+		case len(session) >= 2:
+			return map[string]any{"failAuthentication": true}, nil
+		}
+		return map[string]any{"challengeName": "CUSTOM_CHALLENGE"}, nil
+	}
+	invoker.functions[createAuthArn] = func(event map[string]any) (any, error) {
+		return map[string]any{
+			"publicChallengeParameters":  map[string]string{"question": "2 + 2"},
+			"privateChallengeParameters": map[string]string{"answer": "4"},
+			"challengeMetadata":          "QUESTION",
+		}, nil
+	}
+	invoker.functions[verifyAuthArn] = func(event map[string]any) (any, error) {
+		request := event["request"].(map[string]any)
+		answer := request["privateChallengeParameters"].(map[string]any)["answer"]
+		return map[string]any{"answerCorrect": request["challengeAnswer"] == answer}, nil
+	}
+	signUp(t, c, client, "alice")
+
+	initiate := func() *InitiateAuthOutput {
+		output, awserr := c.InitiateAuth(InitiateAuthInput{
+			AuthFlow:       "CUSTOM_AUTH",
+			ClientId:       client.ClientId,
+			AuthParameters: map[string]string{"USERNAME": "alice"},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if output.ChallengeName != "CUSTOM_CHALLENGE" || output.ChallengeParameters["question"] != "2 + 2" ||
+			output.ChallengeParameters["answer"] != "" || output.Session == "" {
+			t.Fatalf("Unexpected output: %+v", output)
+		}
+		return output
+	}
+	respond := func(session string, answer string) (*RespondToAuthChallengeOutput, *awserrors.Error) {
+		return c.RespondToAuthChallenge(RespondToAuthChallengeInput{
+			ClientId:           client.ClientId,
+			ChallengeName:      "CUSTOM_CHALLENGE",
+			Session:            session,
+			ChallengeResponses: map[string]string{"USERNAME": "alice", "ANSWER": answer},
+		})
+	}
+
+	// A wrong answer gets another try, and then the right one gets tokens.
+	challenge := initiate()
+	output, awserr := respond(challenge.Session, "5")
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.ChallengeName != "CUSTOM_CHALLENGE" || output.AuthenticationResult != nil {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	output, awserr = respond(output.Session, "4")
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.AuthenticationResult == nil || output.AuthenticationResult.IdToken == "" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	event := invoker.lastEvent(t)
+	session := event["request"].(map[string]any)["session"].([]any)
+	if event["triggerSource"] != "DefineAuthChallenge_Authentication" || len(session) != 2 ||
+		session[0].(map[string]any)["challengeResult"] != false ||
+		session[1].(map[string]any)["challengeMetadata"] != "QUESTION" {
+		t.Fatalf("Unexpected event: %s", toJSON(t, event))
+	}
+
+	// Two wrong answers fail.
+	challenge = initiate()
+	output, awserr = respond(challenge.Session, "5")
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = respond(output.Session, "6")
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatalf("Expected NotAuthorizedException, got %v", awserr)
+	}
+	// Sessions can only be responded to once.
+	_, awserr = respond(output.Session, "4")
+	if awserr == nil || awserr.Body.Type != "NotAuthorizedException" {
+		t.Fatalf("Expected NotAuthorizedException, got %v", awserr)
+	}
+}
+
+func TestCustomAuthWithoutTrigger(t *testing.T) {
+	c, _, _, client := newTriggerPool(t, APILambdaConfig{})
+	signUp(t, c, client, "alice")
+	_, awserr := c.InitiateAuth(InitiateAuthInput{
+		AuthFlow:       "CUSTOM_AUTH",
+		ClientId:       client.ClientId,
+		AuthParameters: map[string]string{"USERNAME": "alice"},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatalf("Expected InvalidParameterException, got %v", awserr)
+	}
+}
@@ -0,0 +1,390 @@
+package cognitoidp
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_PasswordPolicyType.html
+type APIPasswordPolicy struct {
+	MinimumLength                 int `range:"6,99" error:"InvalidParameterException"`
+	RequireUppercase              bool
+	RequireLowercase              bool
+	RequireNumbers                bool
+	RequireSymbols                bool
+	TemporaryPasswordValidityDays int `range:",365" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_UserPoolPolicyType.html
+type APIUserPoolPolicy struct {
+	PasswordPolicy *APIPasswordPolicy `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_StringAttributeConstraintsType.html
+type APIStringAttributeConstraints struct {
+	MinLength string `json:",omitempty"`
+	MaxLength string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_NumberAttributeConstraintsType.html
+type APINumberAttributeConstraints struct {
+	MinValue string `json:",omitempty"`
+	MaxValue string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_SchemaAttributeType.html
+type APISchemaAttribute struct {
+	Name string `length:"1,20" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	// String, Number, DateTime or Boolean.
+	AttributeDataType          string                         `json:",omitempty" enum:"String|Number|DateTime|Boolean" error:"InvalidParameterException"`
+	DeveloperOnlyAttribute     bool                           `json:",omitempty"`
+	Mutable                    *bool                          `json:",omitempty"`
+	Required                   bool                           `json:",omitempty"`
+	StringAttributeConstraints *APIStringAttributeConstraints `json:",omitempty"`
+	NumberAttributeConstraints *APINumberAttributeConstraints `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AdminCreateUserConfigType.html
+type APIAdminCreateUserConfig struct {
+	AllowAdminCreateUserOnly  bool
+	UnusedAccountValidityDays int `json:",omitempty" range:",365" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_LambdaConfigType.html
+type APILambdaConfig struct {
+	PreSignUp                   string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	CustomMessage               string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	PostConfirmation            string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	PreAuthentication           string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	PostAuthentication          string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	DefineAuthChallenge         string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	CreateAuthChallenge         string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	VerifyAuthChallengeResponse string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	PreTokenGeneration          string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	UserMigration               string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+	PreTokenGenerationConfig    *APILambdaVersionConfig `json:",omitempty"`
+	CustomSMSSender             *APILambdaVersionConfig `json:",omitempty"`
+	CustomEmailSender           *APILambdaVersionConfig `json:",omitempty"`
+	KMSKeyID                    string                  `json:",omitempty" length:"20,2048" error:"InvalidParameterException"`
+}
+
+type APILambdaVersionConfig struct {
+	LambdaArn string `required:"true" length:"20,2048" error:"InvalidParameterException"`
+	// V1_0 or V2_0 for PreTokenGenerationConfig.
+	LambdaVersion string `required:"true" enum:"V1_0|V2_0|V3_0" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_UserPoolType.html
+type APIUserPool struct {
+	Id                     string
+	Name                   string
+	Arn                    string
+	Policies               APIUserPoolPolicy
+	UsernameAttributes     []string `json:",omitempty"`
+	AutoVerifiedAttributes []string `json:",omitempty"`
+	SchemaAttributes       []APISchemaAttribute
+	MfaConfiguration       string
+	AdminCreateUserConfig  APIAdminCreateUserConfig
+	LambdaConfig           APILambdaConfig
+	DeletionProtection     string
+	EstimatedNumberOfUsers int
+	UserPoolTags           map[string]string `json:",omitempty"`
+	CreationDate           float64
+	LastModifiedDate       float64
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_UserPoolDescriptionType.html
+type APIUserPoolDescription struct {
+	Id               string
+	Name             string
+	CreationDate     float64
+	LastModifiedDate float64
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_TokenValidityUnitsType.html
+type APITokenValidityUnits struct {
+	// seconds, minutes, hours or days.
+	AccessToken  string `json:",omitempty" enum:"seconds|minutes|hours|days" error:"InvalidParameterException"`
+	IdToken      string `json:",omitempty" enum:"seconds|minutes|hours|days" error:"InvalidParameterException"`
+	RefreshToken string `json:",omitempty" enum:"seconds|minutes|hours|days" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_UserPoolClientType.html
+type APIUserPoolClient struct {
+	UserPoolId                      string
+	ClientName                      string
+	ClientId                        string
+	ClientSecret                    string `json:",omitempty"`
+	ExplicitAuthFlows               []string
+	RefreshTokenValidity            int
+	AccessTokenValidity             int
+	IdTokenValidity                 int
+	TokenValidityUnits              APITokenValidityUnits
+	ReadAttributes                  []string `json:",omitempty"`
+	WriteAttributes                 []string `json:",omitempty"`
+	SupportedIdentityProviders      []string `json:",omitempty"`
+	CallbackURLs                    []string `json:",omitempty"`
+	LogoutURLs                      []string `json:",omitempty"`
+	AllowedOAuthFlows               []string `json:",omitempty"`
+	AllowedOAuthScopes              []string `json:",omitempty"`
+	AllowedOAuthFlowsUserPoolClient bool
+	PreventUserExistenceErrors      string
+	EnableTokenRevocation           bool
+	AuthSessionValidity             int
+	CreationDate                    float64
+	LastModifiedDate                float64
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AttributeType.html
+type APIAttribute struct {
+	Name  string `required:"true" length:"1,32" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	Value string `length:",2048" error:"InvalidParameterException"`
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_UserType.html
+type APIUser struct {
+	Username             string
+	Attributes           []APIAttribute
+	UserCreateDate       float64
+	UserLastModifiedDate float64
+	Enabled              bool
+	// UNCONFIRMED, CONFIRMED or FORCE_CHANGE_PASSWORD.
+	UserStatus string
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_CodeDeliveryDetailsType.html
+type APICodeDeliveryDetails struct {
+	Destination    string
+	DeliveryMedium string
+	AttributeName  string
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AuthenticationResultType.html
+type APIAuthenticationResult struct {
+	AccessToken  string
+	ExpiresIn    int
+	IdToken      string
+	RefreshToken string `json:",omitempty"`
+	TokenType    string
+}
+
+// Sent by the SDKs' auth flows, for advanced security features, which aren't supported.
+type APIAnalyticsMetadata struct {
+	AnalyticsEndpointId string
+}
+
+type APIUserContextData struct {
+	EncodedData string `json:",omitempty"`
+	IpAddress   string `json:",omitempty"`
+}
+
+type CreateUserPoolInput struct {
+	PoolName               string `required:"true" length:"1,128" pattern:"[\\w\\s+=,.@-]+" error:"InvalidParameterException"`
+	Policies               APIUserPoolPolicy
+	UsernameAttributes     []string             `enum:"phone_number|email" error:"InvalidParameterException"`
+	AliasAttributes        []string             `enum:"phone_number|email|preferred_username" error:"InvalidParameterException"`
+	AutoVerifiedAttributes []string             `enum:"phone_number|email" error:"InvalidParameterException"`
+	Schema                 []APISchemaAttribute `length:"1,50" error:"InvalidParameterException"`
+	// Only OFF is supported.
+	MfaConfiguration      string `enum:"OFF|ON|OPTIONAL" error:"InvalidParameterException"`
+	AdminCreateUserConfig APIAdminCreateUserConfig
+	LambdaConfig          APILambdaConfig
+	DeletionProtection    string            `enum:"ACTIVE|INACTIVE" error:"InvalidParameterException"`
+	UserPoolTags          map[string]string `length:",50" error:"InvalidParameterException"`
+}
+
+type CreateUserPoolOutput struct {
+	UserPool APIUserPool
+}
+
+type DescribeUserPoolInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+}
+
+type DescribeUserPoolOutput struct {
+	UserPool APIUserPool
+}
+
+type ListUserPoolsInput struct {
+	MaxResults int    `required:"true" range:"1,60" error:"InvalidParameterException"`
+	NextToken  string `length:"1," error:"InvalidParameterException"`
+}
+
+type ListUserPoolsOutput struct {
+	UserPools []APIUserPoolDescription
+	NextToken string `json:",omitempty"`
+}
+
+type DeleteUserPoolInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+}
+
+type DeleteUserPoolOutput struct{}
+
+type CreateUserPoolClientInput struct {
+	UserPoolId                      string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	ClientName                      string `required:"true" length:"1,128" pattern:"[\\w\\s+=,.@-]+" error:"InvalidParameterException"`
+	GenerateSecret                  bool
+	ExplicitAuthFlows               []string `enum:"ADMIN_NO_SRP_AUTH|CUSTOM_AUTH_FLOW_ONLY|USER_PASSWORD_AUTH|ALLOW_ADMIN_USER_PASSWORD_AUTH|ALLOW_CUSTOM_AUTH|ALLOW_USER_PASSWORD_AUTH|ALLOW_USER_SRP_AUTH|ALLOW_REFRESH_TOKEN_AUTH|ALLOW_USER_AUTH" error:"InvalidParameterException"`
+	RefreshTokenValidity            int      `range:",315360000" error:"InvalidParameterException"`
+	AccessTokenValidity             int      `range:"1,86400" error:"InvalidParameterException"`
+	IdTokenValidity                 int      `range:"1,86400" error:"InvalidParameterException"`
+	TokenValidityUnits              APITokenValidityUnits
+	ReadAttributes                  []string
+	WriteAttributes                 []string
+	SupportedIdentityProviders      []string
+	CallbackURLs                    []string `length:",100" error:"InvalidParameterException"`
+	LogoutURLs                      []string `length:",100" error:"InvalidParameterException"`
+	DefaultRedirectURI              string   `length:"1,1024" error:"InvalidParameterException"`
+	AllowedOAuthFlows               []string `length:",3" enum:"code|implicit|client_credentials" error:"InvalidParameterException"`
+	AllowedOAuthScopes              []string `length:",50" error:"InvalidParameterException"`
+	AllowedOAuthFlowsUserPoolClient bool
+	PreventUserExistenceErrors      string `enum:"LEGACY|ENABLED" error:"InvalidParameterException"`
+	EnableTokenRevocation           *bool
+	AuthSessionValidity             int `range:"3,15" error:"InvalidParameterException"`
+}
+
+type CreateUserPoolClientOutput struct {
+	UserPoolClient APIUserPoolClient
+}
+
+type DescribeUserPoolClientInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	ClientId   string `required:"true" length:"1,128" pattern:"[\\w+]+" error:"InvalidParameterException"`
+}
+
+type DescribeUserPoolClientOutput struct {
+	UserPoolClient APIUserPoolClient
+}
+
+type DeleteUserPoolClientInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	ClientId   string `required:"true" length:"1,128" pattern:"[\\w+]+" error:"InvalidParameterException"`
+}
+
+type DeleteUserPoolClientOutput struct{}
+
+type SignUpInput struct {
+	ClientId          string `required:"true" length:"1,128" pattern:"[\\w+]+" error:"InvalidParameterException"`
+	SecretHash        string `length:"1,128" error:"InvalidParameterException"`
+	Username          string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	Password          string `length:",256" error:"InvalidParameterException"`
+	UserAttributes    []APIAttribute
+	ValidationData    []APIAttribute
+	ClientMetadata    map[string]string
+	AnalyticsMetadata *APIAnalyticsMetadata
+	UserContextData   *APIUserContextData
+}
+
+type SignUpOutput struct {
+	UserConfirmed       bool
+	UserSub             string
+	CodeDeliveryDetails *APICodeDeliveryDetails `json:",omitempty"`
+}
+
+type ConfirmSignUpInput struct {
+	ClientId           string `required:"true" length:"1,128" pattern:"[\\w+]+" error:"InvalidParameterException"`
+	SecretHash         string `length:"1,128" error:"InvalidParameterException"`
+	Username           string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	ConfirmationCode   string `required:"true" length:"1,2048" pattern:"[\\S]+" error:"InvalidParameterException"`
+	ForceAliasCreation bool
+	ClientMetadata     map[string]string
+	AnalyticsMetadata  *APIAnalyticsMetadata
+	UserContextData    *APIUserContextData
+}
+
+type ConfirmSignUpOutput struct{}
+
+type AdminConfirmSignUpInput struct {
+	UserPoolId     string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	Username       string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	ClientMetadata map[string]string
+}
+
+type AdminConfirmSignUpOutput struct{}
+
+type AdminCreateUserInput struct {
+	UserPoolId        string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	Username          string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	TemporaryPassword string `length:",256" error:"InvalidParameterException"`
+	UserAttributes    []APIAttribute
+	ValidationData    []APIAttribute
+	// RESEND or SUPPRESS.
+	MessageAction          string   `enum:"RESEND|SUPPRESS" error:"InvalidParameterException"`
+	DesiredDeliveryMediums []string `enum:"SMS|EMAIL" error:"InvalidParameterException"`
+	ForceAliasCreation     bool
+	ClientMetadata         map[string]string
+}
+
+type AdminCreateUserOutput struct {
+	User APIUser
+}
+
+type AdminGetUserInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	Username   string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+}
+
+type AdminGetUserOutput struct {
+	Username             string
+	UserAttributes       []APIAttribute
+	UserCreateDate       float64
+	UserLastModifiedDate float64
+	Enabled              bool
+	UserStatus           string
+}
+
+type AdminDeleteUserInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	Username   string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+}
+
+type AdminDeleteUserOutput struct{}
+
+type AdminSetUserPasswordInput struct {
+	UserPoolId string `required:"true" length:"1,55" pattern:"[\\w-]+_[0-9a-zA-Z]+" error:"InvalidParameterException"`
+	Username   string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{M}\\p{S}\\p{N}\\p{P}]+" error:"InvalidParameterException"`
+	Password   string `required:"true" length:",256" error:"InvalidParameterException"`
+	Permanent  bool
+}
+
+type AdminSetUserPasswordOutput struct{}
+
+type GetUserInput struct {
+	AccessToken string `required:"true" error:"InvalidParameterException"`
+}
+
+type GetUserOutput struct {
+	Username       string
+	UserAttributes []APIAttribute
+}
+
+type InitiateAuthInput struct {
+	// USER_PASSWORD_AUTH, USER_SRP_AUTH, CUSTOM_AUTH, REFRESH_TOKEN_AUTH or REFRESH_TOKEN.
+	AuthFlow          string `required:"true" enum:"USER_SRP_AUTH|REFRESH_TOKEN_AUTH|REFRESH_TOKEN|CUSTOM_AUTH|ADMIN_NO_SRP_AUTH|USER_PASSWORD_AUTH|ADMIN_USER_PASSWORD_AUTH|USER_AUTH" error:"InvalidParameterException"`
+	AuthParameters    map[string]string
+	ClientId          string `required:"true" length:"1,128" pattern:"[\\w+]+" error:"InvalidParameterException"`
+	ClientMetadata    map[string]string
+	AnalyticsMetadata *APIAnalyticsMetadata
+	UserContextData   *APIUserContextData
+}
+
+type InitiateAuthOutput struct {
+	AuthenticationResult *APIAuthenticationResult `json:",omitempty"`
+	ChallengeName        string                   `json:",omitempty"`
+	ChallengeParameters  map[string]string        `json:",omitempty"`
+	Session              string                   `json:",omitempty"`
+}
+
+type RespondToAuthChallengeInput struct {
+	ClientId string `required:"true" length:"1,128" pattern:"[\\w+]+" error:"InvalidParameterException"`
+	// PASSWORD_VERIFIER, NEW_PASSWORD_REQUIRED or CUSTOM_CHALLENGE.
+	ChallengeName      string `required:"true" enum:"SMS_MFA|EMAIL_OTP|SOFTWARE_TOKEN_MFA|SELECT_MFA_TYPE|MFA_SETUP|PASSWORD_VERIFIER|CUSTOM_CHALLENGE|SELECT_CHALLENGE|DEVICE_SRP_AUTH|DEVICE_PASSWORD_VERIFIER|ADMIN_NO_SRP_AUTH|NEW_PASSWORD_REQUIRED|SMS_OTP|PASSWORD|WEB_AUTHN|PASSWORD_SRP" error:"InvalidParameterException"`
+	ChallengeResponses map[string]string
+	Session            string `length:"20,2048" error:"InvalidParameterException"`
+	ClientMetadata     map[string]string
+	AnalyticsMetadata  *APIAnalyticsMetadata
+	UserContextData    *APIUserContextData
+}
+
+type RespondToAuthChallengeOutput struct {
+	AuthenticationResult *APIAuthenticationResult `json:",omitempty"`
+	ChallengeName        string                   `json:",omitempty"`
+	ChallengeParameters  map[string]string        `json:",omitempty"`
+	Session              string                   `json:",omitempty"`
+}
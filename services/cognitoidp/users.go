@@ -0,0 +1,548 @@
+package cognitoidp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/random"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	confirmationCodeValidity = 24 * time.Hour
+	passwordSymbols          = "^$*.[]{}()?\"!@#%&/\\,><':;|_~`=+- "
+)
+
+// https://docs.aws.amazon.com/cognito/latest/developerguide/user-pool-settings-attributes.html#cognito-user-pools-standard-attributes
+var standardAttributes = []string{
+	"address", "birthdate", "email", "email_verified", "family_name", "gender", "given_name", "locale",
+	"middle_name", "name", "nickname", "phone_number", "phone_number_verified", "picture",
+	"preferred_username", "profile", "sub", "updated_at", "website", "zoneinfo",
+}
+
+type User struct {
+	Username string
+	// Including sub, which is the user's immutable ID.
+	Attributes map[string]string
+	// UNCONFIRMED, CONFIRMED or FORCE_CHANGE_PASSWORD.
+	Status         string
+	Enabled        bool
+	CreatedAt      time.Time
+	LastModifiedAt time.Time
+	// When the user's temporary password expires, if they have to change it.
+	TemporaryPasswordExpiresAt time.Time
+
+	// The password is only kept as an SRP verifier.
+	salt     *big.Int
+	verifier *big.Int
+	// The code sent when the user signed up, until they're confirmed.
+	confirmationCode          string
+	confirmationCodeExpiresAt time.Time
+}
+
+func (u *User) sub() string {
+	return u.Attributes["sub"]
+}
+
+func (u *User) attributesToAPI() []APIAttribute {
+	attributes := []APIAttribute{}
+	for name, value := range u.Attributes {
+		attributes = append(attributes, APIAttribute{Name: name, Value: value})
+	}
+	slices.SortFunc(attributes, func(a, b APIAttribute) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return attributes
+}
+
+func (u *User) toAPI() APIUser {
+	return APIUser{
+		Username:             u.Username,
+		Attributes:           u.attributesToAPI(),
+		UserCreateDate:       timestamp.EpochSeconds(u.CreatedAt),
+		UserLastModifiedDate: timestamp.EpochSeconds(u.LastModifiedAt),
+		Enabled:              u.Enabled,
+		UserStatus:           u.Status,
+	}
+}
+
+func (u *User) setPassword(poolId string, password string) {
+	u.salt, u.verifier = srpVerifier(poolId, u.Username, password)
+}
+
+func (u *User) checkPassword(poolId string, password string) bool {
+	x := srpPrivateKey(poolId, u.Username, password, u.salt)
+	return new(big.Int).Exp(srpG, x, srpN).Cmp(u.verifier) == 0
+}
+
+// lockedFindUser finds a user by their username, or by their email or phone number if the pool
+// uses them as usernames.
+func (p *UserPool) lockedFindUser(username string) *User {
+	if user, ok := p.usersByName[username]; ok {
+		return user
+	}
+	for _, attribute := range p.UsernameAttributes {
+		for _, user := range p.usersByName {
+			if user.Attributes[attribute] == username {
+				return user
+			}
+		}
+	}
+	return nil
+}
+
+func (p *UserPool) lockedGetUser(username string) (*User, *awserrors.Error) {
+	user := p.lockedFindUser(username)
+	if user == nil {
+		return nil, UserNotFoundException("User does not exist.")
+	}
+	return user, nil
+}
+
+func validatePassword(policy APIPasswordPolicy, password string) *awserrors.Error {
+	var problem string
+	switch {
+	case len(password) < policy.MinimumLength:
+		problem = "Password not long enough"
+	case policy.RequireLowercase && !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz"):
+		problem = "Password must have lowercase characters"
+	case policy.RequireUppercase && !strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"):
+		problem = "Password must have uppercase characters"
+	case policy.RequireNumbers && !strings.ContainsAny(password, "0123456789"):
+		problem = "Password must have numeric characters"
+	case policy.RequireSymbols && !strings.ContainsAny(password, passwordSymbols):
+		problem = "Password must have symbol characters"
+	default:
+		return nil
+	}
+	return InvalidPasswordException("Password did not conform with policy: " + problem)
+}
+
+// temporaryPassword returns a random password which satisfies any password policy.
+func temporaryPassword() string {
+	return random.String("ABCDEFGHJKLMNPQRSTUVWXYZ", 2) + random.String("abcdefghijkmnopqrstuvwxyz", 4) +
+		random.String("23456789", 2) + random.String("!#%&*+-=?@^_", 2)
+}
+
+// lockedValidateAttributes checks the attributes are standard attributes or the pool's custom attributes.
+func (p *UserPool) lockedValidateAttributes(attributes []APIAttribute) (map[string]string, *awserrors.Error) {
+	validated := make(map[string]string)
+	for _, attribute := range attributes {
+		if attribute.Name == "sub" {
+			return nil, InvalidParameterException("Cannot modify the non-mutable attribute sub")
+		}
+		standard := slices.Contains(standardAttributes, attribute.Name)
+		custom := slices.ContainsFunc(p.Schema, func(schema APISchemaAttribute) bool {
+			return schema.Name == attribute.Name
+		})
+		if !standard && !custom {
+			return nil, InvalidParameterException(
+				fmt.Sprintf("Attributes did not conform to the schema: %s: Attribute does not exist in the schema.\n", attribute.Name))
+		}
+		validated[attribute.Name] = attribute.Value
+	}
+	return validated, nil
+}
+
+func (p *UserPool) lockedCheckRequiredAttributes(attributes map[string]string) *awserrors.Error {
+	for _, schema := range p.Schema {
+		if _, ok := attributes[schema.Name]; schema.Required && !ok {
+			return InvalidParameterException(
+				fmt.Sprintf("Attributes did not conform to the schema: %s: The attribute is required\n", schema.Name))
+		}
+	}
+	return nil
+}
+
+// lockedNewUser creates a user with the username, which is the user's email or phone number
+// if the pool uses those as usernames.
+func (c *CognitoIDP) lockedNewUser(pool *UserPool, username string, attributes []APIAttribute) (*User, *awserrors.Error) {
+	if username == "" || strings.ContainsAny(username, " \t\n") || len(username) > 128 {
+		return nil, InvalidParameterException("Username should be a valid username.")
+	}
+	validated, awserr := pool.lockedValidateAttributes(attributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	sub := uuid.Must(uuid.NewV4()).String()
+	if len(pool.UsernameAttributes) > 0 {
+		switch {
+		case slices.Contains(pool.UsernameAttributes, "email") && strings.Contains(username, "@"):
+			validated["email"] = username
+		case slices.Contains(pool.UsernameAttributes, "phone_number") && strings.HasPrefix(username, "+"):
+			validated["phone_number"] = username
+		default:
+			return nil, InvalidParameterException("Username should be either an email or a phone number.")
+		}
+		// The username is the user's sub, and they sign in with their email or phone number.
+		username = sub
+	}
+	if awserr := pool.lockedCheckRequiredAttributes(validated); awserr != nil {
+		return nil, awserr
+	}
+	validated["sub"] = sub
+
+	now := c.clock()
+	user := &User{
+		Username:       username,
+		Attributes:     validated,
+		Enabled:        true,
+		CreatedAt:      now,
+		LastModifiedAt: now,
+	}
+	if awserr := pool.lockedCheckUsernameAvailable(user); awserr != nil {
+		return nil, awserr
+	}
+	return user, nil
+}
+
+// lockedCheckUsernameAvailable checks no other user has the new user's username, or the email or
+// phone number they'd sign in with.
+func (p *UserPool) lockedCheckUsernameAvailable(user *User) *awserrors.Error {
+	if p.lockedFindUser(user.Username) != nil {
+		return UsernameExistsException("User already exists")
+	}
+	for _, attribute := range p.UsernameAttributes {
+		if value, ok := user.Attributes[attribute]; ok && p.lockedFindUser(value) != nil {
+			return UsernameExistsException(fmt.Sprintf("An account with the given %s already exists.", attribute))
+		}
+	}
+	return nil
+}
+
+// maskDestination hides most of the email address or phone number a code was sent to, like Cognito does.
+func maskDestination(destination string) string {
+	if local, domain, ok := strings.Cut(destination, "@"); ok && local != "" && domain != "" {
+		return local[:1] + "***@" + domain[:1] + "***"
+	}
+	if len(destination) > 4 {
+		return "+" + strings.Repeat("*", len(destination)-5) + destination[len(destination)-4:]
+	}
+	return destination
+}
+
+// lockedSendConfirmationCode sends a code to verify the first of the pool's auto verified attributes
+// the user has, if any.
+func (c *CognitoIDP) lockedSendConfirmationCode(pool *UserPool, user *User) *APICodeDeliveryDetails {
+	for _, attribute := range pool.AutoVerifiedAttributes {
+		destination, ok := user.Attributes[attribute]
+		if !ok {
+			continue
+		}
+		medium := "EMAIL"
+		if attribute == "phone_number" {
+			medium = "SMS"
+		}
+		user.confirmationCode = random.String("0123456789", 6)
+		user.confirmationCodeExpiresAt = c.clock().Add(confirmationCodeValidity)
+		c.lockedCapture(CapturedCode{
+			UserPoolId:     pool.Id,
+			Username:       user.Username,
+			Reason:         "SignUp",
+			DeliveryMedium: medium,
+			Destination:    destination,
+			Code:           user.confirmationCode,
+		})
+		return &APICodeDeliveryDetails{
+			Destination:    maskDestination(destination),
+			DeliveryMedium: medium,
+			AttributeName:  attribute,
+		}
+	}
+	return nil
+}
+
+// lockedConfirm marks the user as confirmed, and their auto verified attributes as verified.
+func (c *CognitoIDP) lockedConfirm(pool *UserPool, user *User) {
+	user.Status = "CONFIRMED"
+	user.confirmationCode = ""
+	user.LastModifiedAt = c.clock()
+	for _, attribute := range pool.AutoVerifiedAttributes {
+		if _, ok := user.Attributes[attribute]; ok {
+			user.Attributes[attribute+"_verified"] = "true"
+		}
+	}
+}
+
+// secretHash is the SECRET_HASH clients with secrets send, which proves they know the secret.
+func secretHash(clientSecret string, username string, clientId string) string {
+	mac := hmac.New(sha256.New, []byte(clientSecret))
+	mac.Write([]byte(username + clientId))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func checkSecretHash(client *UserPoolClient, username string, hash string) *awserrors.Error {
+	if client.Config.ClientSecret == "" {
+		return nil
+	}
+	if hash == "" {
+		return NotAuthorizedException(
+			fmt.Sprintf("Client %s is configured for secret but secret was not received", client.Config.ClientId))
+	}
+	if !hmac.Equal([]byte(hash), []byte(secretHash(client.Config.ClientSecret, username, client.Config.ClientId))) {
+		return NotAuthorizedException(fmt.Sprintf("Unable to verify secret hash for client %s", client.Config.ClientId))
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_SignUp.html
+func (c *CognitoIDP) SignUp(input SignUpInput) (*SignUpOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, pool, awserr := c.lockedGetClient(input.ClientId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := checkSecretHash(client, input.Username, input.SecretHash); awserr != nil {
+		return nil, awserr
+	}
+	if pool.AllowAdminCreateUserOnly {
+		return nil, NotAuthorizedException("SignUp is not permitted for this user pool")
+	}
+	if awserr := validatePassword(pool.PasswordPolicy, input.Password); awserr != nil {
+		return nil, awserr
+	}
+	user, awserr := c.lockedNewUser(pool, input.Username, input.UserAttributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+	pool, preSignUp, awserr := c.lockedPreSignUp(pool, user, "PreSignUp_SignUp", client.Config.ClientId,
+		input.ValidationData, input.ClientMetadata)
+	if awserr != nil {
+		return nil, awserr
+	}
+	user.Status = "UNCONFIRMED"
+	user.setPassword(pool.Id, input.Password)
+	if preSignUp.AutoVerifyEmail && user.Attributes["email"] != "" {
+		user.Attributes["email_verified"] = "true"
+	}
+	if preSignUp.AutoVerifyPhone && user.Attributes["phone_number"] != "" {
+		user.Attributes["phone_number_verified"] = "true"
+	}
+	pool.usersByName[user.Username] = user
+
+	// Users the trigger confirmed don't need a code.
+	if preSignUp.AutoConfirmUser {
+		user.Status = "CONFIRMED"
+		return &SignUpOutput{UserConfirmed: true, UserSub: user.sub()}, nil
+	}
+	return &SignUpOutput{
+		UserConfirmed:       false,
+		UserSub:             user.sub(),
+		CodeDeliveryDetails: c.lockedSendConfirmationCode(pool, user),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_ConfirmSignUp.html
+func (c *CognitoIDP) ConfirmSignUp(input ConfirmSignUpInput) (*ConfirmSignUpOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, pool, awserr := c.lockedGetClient(input.ClientId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := checkSecretHash(client, input.Username, input.SecretHash); awserr != nil {
+		return nil, awserr
+	}
+	user, awserr := pool.lockedGetUser(input.Username)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if user.Status != "UNCONFIRMED" {
+		return nil, NotAuthorizedException("User cannot be confirmed. Current status is " + user.Status)
+	}
+	if user.confirmationCode == "" || !hmac.Equal([]byte(input.ConfirmationCode), []byte(user.confirmationCode)) {
+		return nil, CodeMismatchException("Invalid verification code provided, please try again.")
+	}
+	if !c.clock().Before(user.confirmationCodeExpiresAt) {
+		return nil, ExpiredCodeException("Invalid code provided, please request a code again.")
+	}
+	c.lockedConfirm(pool, user)
+	// The user stays confirmed even if the trigger fails.
+	if awserr := c.lockedPostConfirmation(pool, user, client.Config.ClientId, input.ClientMetadata); awserr != nil {
+		return nil, awserr
+	}
+	return &ConfirmSignUpOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AdminConfirmSignUp.html
+func (c *CognitoIDP) AdminConfirmSignUp(input AdminConfirmSignUpInput) (*AdminConfirmSignUpOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	user, awserr := pool.lockedGetUser(input.Username)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if user.Status != "UNCONFIRMED" {
+		return nil, NotAuthorizedException("User cannot be confirmed. Current status is " + user.Status)
+	}
+	c.lockedConfirm(pool, user)
+	if awserr := c.lockedPostConfirmation(pool, user, "", input.ClientMetadata); awserr != nil {
+		return nil, awserr
+	}
+	return &AdminConfirmSignUpOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AdminCreateUser.html
+func (c *CognitoIDP) AdminCreateUser(input AdminCreateUserInput) (*AdminCreateUserOutput, *awserrors.Error) {
+	if input.MessageAction != "" && input.MessageAction != "RESEND" && input.MessageAction != "SUPPRESS" {
+		return nil, InvalidParameterException("MessageAction must be RESEND or SUPPRESS.")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	password := input.TemporaryPassword
+	if password == "" {
+		password = temporaryPassword()
+	} else if awserr := validatePassword(pool.PasswordPolicy, password); awserr != nil {
+		return nil, awserr
+	}
+
+	var user *User
+	if input.MessageAction == "RESEND" {
+		user, awserr = pool.lockedGetUser(input.Username)
+		if awserr != nil {
+			return nil, awserr
+		}
+		if user.Status != "FORCE_CHANGE_PASSWORD" {
+			return nil, UnsupportedUserStateException("User does not exist or has already been confirmed.")
+		}
+	} else {
+		user, awserr = c.lockedNewUser(pool, input.Username, input.UserAttributes)
+		if awserr != nil {
+			return nil, awserr
+		}
+		// Admins confirm the users they create, so only the trigger's validation matters.
+		pool, _, awserr = c.lockedPreSignUp(pool, user, "PreSignUp_AdminCreateUser", "",
+			input.ValidationData, input.ClientMetadata)
+		if awserr != nil {
+			return nil, awserr
+		}
+		pool.usersByName[user.Username] = user
+	}
+	user.Status = "FORCE_CHANGE_PASSWORD"
+	user.TemporaryPasswordExpiresAt = c.clock().Add(time.Duration(pool.PasswordPolicy.TemporaryPasswordValidityDays) * 24 * time.Hour)
+	user.setPassword(pool.Id, password)
+
+	if input.MessageAction != "SUPPRESS" {
+		medium, destination := "EMAIL", user.Attributes["email"]
+		if slices.Equal(input.DesiredDeliveryMediums, []string{"SMS"}) || destination == "" {
+			medium, destination = "SMS", user.Attributes["phone_number"]
+		}
+		if destination != "" {
+			c.lockedCapture(CapturedCode{
+				UserPoolId:     pool.Id,
+				Username:       user.Username,
+				Reason:         "AdminCreateUser",
+				DeliveryMedium: medium,
+				Destination:    destination,
+				Code:           password,
+			})
+		}
+	}
+	return &AdminCreateUserOutput{User: user.toAPI()}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AdminGetUser.html
+func (c *CognitoIDP) AdminGetUser(input AdminGetUserInput) (*AdminGetUserOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	user, awserr := pool.lockedGetUser(input.Username)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &AdminGetUserOutput{
+		Username:             user.Username,
+		UserAttributes:       user.attributesToAPI(),
+		UserCreateDate:       timestamp.EpochSeconds(user.CreatedAt),
+		UserLastModifiedDate: timestamp.EpochSeconds(user.LastModifiedAt),
+		Enabled:              user.Enabled,
+		UserStatus:           user.Status,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AdminDeleteUser.html
+func (c *CognitoIDP) AdminDeleteUser(input AdminDeleteUserInput) (*AdminDeleteUserOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	user, awserr := pool.lockedGetUser(input.Username)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(pool.usersByName, user.Username)
+	return &AdminDeleteUserOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_AdminSetUserPassword.html
+func (c *CognitoIDP) AdminSetUserPassword(input AdminSetUserPasswordInput) (*AdminSetUserPasswordOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pool, awserr := c.lockedGetUserPool(input.UserPoolId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	user, awserr := pool.lockedGetUser(input.Username)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validatePassword(pool.PasswordPolicy, input.Password); awserr != nil {
+		return nil, awserr
+	}
+	user.setPassword(pool.Id, input.Password)
+	user.LastModifiedAt = c.clock()
+	if input.Permanent {
+		user.Status = "CONFIRMED"
+	} else {
+		user.Status = "FORCE_CHANGE_PASSWORD"
+		user.TemporaryPasswordExpiresAt = c.clock().Add(time.Duration(pool.PasswordPolicy.TemporaryPasswordValidityDays) * 24 * time.Hour)
+	}
+	return &AdminSetUserPasswordOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cognito-user-identity-pools/latest/APIReference/API_GetUser.html
+func (c *CognitoIDP) GetUser(input GetUserInput) (*GetUserOutput, *awserrors.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, user, awserr := c.lockedVerifyAccessToken(input.AccessToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetUserOutput{
+		Username:       user.Username,
+		UserAttributes: user.attributesToAPI(),
+	}, nil
+}
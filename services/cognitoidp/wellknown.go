@@ -0,0 +1,69 @@
+package cognitoidp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+)
+
+var wellKnownPathRegex = regexp.MustCompile(`^/([\w-]+_[0-9a-zA-Z]+)/\.well-known/(jwks\.json|openid-configuration)$`)
+
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type openIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JwksUri                          string   `json:"jwks_uri"`
+	IdTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+}
+
+// NewHandler serves each user pool's JSON Web Key Set and OpenID configuration under its issuer,
+// so JWT libraries can verify the pool's tokens like they do real ones. Issuers have the pools'
+// IDs, which say their regions, so the requests' regions don't matter.
+func NewHandler(logger *slog.Logger, c *CognitoIDP) func(w http.ResponseWriter, r *http.Request) bool {
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodGet {
+			return false
+		}
+		match := wellKnownPathRegex.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			return false
+		}
+		logger.Debug("Handling Cognito well-known request", "url", r.URL)
+
+		pools, err := c.forPool(match[1])
+		if err != nil {
+			http.NotFound(w, r)
+			return true
+		}
+		pools.mu.Lock()
+		pool, ok := pools.poolsById[match[1]]
+		pools.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return true
+		}
+
+		var body any
+		if match[2] == "jwks.json" {
+			body = struct {
+				Keys []jsonWebKey `json:"keys"`
+			}{[]jsonWebKey{pool.jsonWebKey()}}
+		} else {
+			issuer := c.issuer(pool.Id)
+			body = openIDConfiguration{
+				Issuer:                           issuer,
+				JwksUri:                          issuer + "/.well-known/jwks.json",
+				IdTokenSigningAlgValuesSupported: []string{"RS256"},
+				ResponseTypesSupported:           []string{"code", "token"},
+				SubjectTypesSupported:            []string{"public"},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			logger.Error("Writing well-known response", "err", err)
+		}
+		return true
+	}
+}
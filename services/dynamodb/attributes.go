@@ -0,0 +1,318 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MarshalJSON emits exactly one member of the union, since the SDKs reject
+// attribute values with more than one type set.
+func (v APIAttributeValue) MarshalJSON() ([]byte, error) {
+	var out any
+	switch {
+	case v.NULL:
+		out = map[string]bool{"NULL": true}
+	case v.BOOL != nil:
+		out = map[string]bool{"BOOL": *v.BOOL}
+	case v.N != "":
+		out = map[string]string{"N": v.N}
+	case v.B != "":
+		out = map[string]string{"B": v.B}
+	case v.SS != nil:
+		out = map[string][]string{"SS": v.SS}
+	case v.NS != nil:
+		out = map[string][]string{"NS": v.NS}
+	case v.BS != nil:
+		out = map[string][]string{"BS": v.BS}
+	case v.L != nil:
+		out = map[string][]APIAttributeValue{"L": v.L}
+	case v.M != nil:
+		out = map[string]APIItem{"M": v.M}
+	default:
+		out = map[string]string{"S": v.S}
+	}
+	return json.Marshal(out)
+}
+
+// Type returns the DynamoDB type descriptor of the value, e.g. "S" or "NS".
+func (v APIAttributeValue) Type() string {
+	switch {
+	case v.NULL:
+		return "NULL"
+	case v.BOOL != nil:
+		return "BOOL"
+	case v.N != "":
+		return "N"
+	case v.B != "":
+		return "B"
+	case v.SS != nil:
+		return "SS"
+	case v.NS != nil:
+		return "NS"
+	case v.BS != nil:
+		return "BS"
+	case v.L != nil:
+		return "L"
+	case v.M != nil:
+		return "M"
+	default:
+		return "S"
+	}
+}
+
+func stringValue(s string) APIAttributeValue {
+	return APIAttributeValue{S: s}
+}
+
+func numberValue(r *big.Rat) APIAttributeValue {
+	return APIAttributeValue{N: formatNumber(r)}
+}
+
+func boolValue(b bool) APIAttributeValue {
+	return APIAttributeValue{BOOL: &b}
+}
+
+// parseNumber parses a DynamoDB number. Numbers are decimal strings, so we use
+// rationals to keep arithmetic and comparisons exact.
+func parseNumber(n string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(n))
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// Anything parsed from a decimal string has a terminating expansion,
+	// so this always finishes.
+	for precision := 1; ; precision++ {
+		s := r.FloatString(precision)
+		back, _ := new(big.Rat).SetString(s)
+		if back.Cmp(r) == 0 {
+			return s
+		}
+	}
+}
+
+func decodeBinary(b string) []byte {
+	data, err := base64.StdEncoding.DecodeString(b)
+	if err != nil {
+		// Be lenient and compare the raw encoding instead.
+		return []byte(b)
+	}
+	return data
+}
+
+// keyString returns a canonical string for a scalar key attribute so that
+// equal keys always map to the same string.
+func keyString(v APIAttributeValue) string {
+	switch v.Type() {
+	case "N":
+		if r, ok := parseNumber(v.N); ok {
+			return r.RatString()
+		}
+		return v.N
+	case "B":
+		return string(decodeBinary(v.B))
+	default:
+		return v.S
+	}
+}
+
+// compareValues orders two scalar values of the same type.
+// The boolean result is false if the values are not comparable.
+func compareValues(a, b APIAttributeValue) (int, bool) {
+	if a.Type() != b.Type() {
+		return 0, false
+	}
+	switch a.Type() {
+	case "S":
+		return strings.Compare(a.S, b.S), true
+	case "N":
+		ra, ok1 := parseNumber(a.N)
+		rb, ok2 := parseNumber(b.N)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return ra.Cmp(rb), true
+	case "B":
+		return bytes.Compare(decodeBinary(a.B), decodeBinary(b.B)), true
+	}
+	return 0, false
+}
+
+func valuesEqual(a, b APIAttributeValue) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case "NULL":
+		return true
+	case "BOOL":
+		return *a.BOOL == *b.BOOL
+	case "S", "N", "B":
+		c, ok := compareValues(a, b)
+		return ok && c == 0
+	case "SS":
+		return setsEqual(a.SS, b.SS, stringValue)
+	case "NS":
+		return setsEqual(a.NS, b.NS, func(n string) APIAttributeValue { return APIAttributeValue{N: n} })
+	case "BS":
+		return setsEqual(a.BS, b.BS, func(b string) APIAttributeValue { return APIAttributeValue{B: b} })
+	case "L":
+		return slices.EqualFunc(a.L, b.L, valuesEqual)
+	case "M":
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for k, av := range a.M {
+			bv, ok := b.M[k]
+			if !ok || !valuesEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func setsEqual(a, b []string, wrap func(string) APIAttributeValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, elem := range a {
+		if !setContains(b, wrap(elem), wrap) {
+			return false
+		}
+	}
+	return true
+}
+
+func setContains(set []string, elem APIAttributeValue, wrap func(string) APIAttributeValue) bool {
+	for _, member := range set {
+		if valuesEqual(wrap(member), elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// setMembers returns the members of a set value as individual attribute values.
+func setMembers(v APIAttributeValue) []APIAttributeValue {
+	var members []APIAttributeValue
+	switch v.Type() {
+	case "SS":
+		for _, s := range v.SS {
+			members = append(members, APIAttributeValue{S: s})
+		}
+	case "NS":
+		for _, n := range v.NS {
+			members = append(members, APIAttributeValue{N: n})
+		}
+	case "BS":
+		for _, b := range v.BS {
+			members = append(members, APIAttributeValue{B: b})
+		}
+	}
+	return members
+}
+
+// itemSize approximates the size DynamoDB counts against its item size limits.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/CapacityUnitCalculations.html
+func itemSize(item APIItem) int {
+	size := 0
+	for name, v := range item {
+		size += len(name) + valueSize(v)
+	}
+	return size
+}
+
+func valueSize(v APIAttributeValue) int {
+	switch v.Type() {
+	case "NULL", "BOOL":
+		return 1
+	case "N":
+		return numberSize(v.N)
+	case "B":
+		return len(decodeBinary(v.B))
+	case "SS":
+		size := 0
+		for _, s := range v.SS {
+			size += len(s)
+		}
+		return size
+	case "NS":
+		size := 0
+		for _, n := range v.NS {
+			size += numberSize(n)
+		}
+		return size
+	case "BS":
+		size := 0
+		for _, b := range v.BS {
+			size += len(decodeBinary(b))
+		}
+		return size
+	case "L":
+		size := 3
+		for _, elem := range v.L {
+			size += 1 + valueSize(elem)
+		}
+		return size
+	case "M":
+		return 3 + len(v.M) + itemSize(v.M)
+	default:
+		return len(v.S)
+	}
+}
+
+// numberSize is roughly one byte per two significant digits, plus one.
+func numberSize(n string) int {
+	digits := strings.TrimSpace(n)
+	if i := strings.IndexAny(digits, "eE"); i != -1 {
+		digits = digits[:i]
+	}
+	digits = strings.Trim(strings.NewReplacer("-", "", "+", "", ".", "").Replace(digits), "0")
+	return (len(digits)+1)/2 + 1
+}
+
+func cloneValue(v APIAttributeValue) APIAttributeValue {
+	v.SS = slices.Clone(v.SS)
+	v.NS = slices.Clone(v.NS)
+	v.BS = slices.Clone(v.BS)
+	if v.L != nil {
+		l := make([]APIAttributeValue, len(v.L))
+		for i, elem := range v.L {
+			l[i] = cloneValue(elem)
+		}
+		v.L = l
+	}
+	if v.M != nil {
+		v.M = cloneItem(v.M)
+	}
+	return v
+}
+
+func cloneItem(item APIItem) APIItem {
+	if item == nil {
+		return nil
+	}
+	clone := make(APIItem, len(item))
+	for k, v := range item {
+		clone[k] = cloneValue(v)
+	}
+	return clone
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
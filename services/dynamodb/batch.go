@@ -0,0 +1,230 @@
+package dynamodb
+
+import (
+	"fmt"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ServiceQuotas.html
+const (
+	maxBatchGetKeys       = 100
+	maxBatchWriteRequests = 25
+	maxBatchSize          = 16 * 1024 * 1024
+)
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_BatchGetItem.html
+func (d *DynamoDB) BatchGetItem(input BatchGetItemInput) (*BatchGetItemOutput, *awserrors.Error) {
+	if len(input.RequestItems) == 0 {
+		return nil, ValidationException("The requestItems parameter is required for BatchGetItem")
+	}
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+
+	keyCount := 0
+	projections := make(map[string]projection)
+	for tableName, request := range input.RequestItems {
+		if len(request.Keys) == 0 {
+			return nil, ValidationException(fmt.Sprintf(
+				"1 validation error detected: Value at 'requestItems.%s.member.keys' failed to satisfy constraint: Member must have length greater than or equal to 1",
+				tableName))
+		}
+		keyCount += len(request.Keys)
+
+		if request.ProjectionExpression != "" {
+			p, err := parseProjection(request.ProjectionExpression, request.ExpressionAttributeNames)
+			if err != nil {
+				return nil, ValidationException("Invalid ProjectionExpression: " + err.Error())
+			}
+			projections[tableName] = p
+		}
+	}
+	if keyCount > maxBatchGetKeys {
+		return nil, ValidationException("Too many items requested for the BatchGetItem call")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Validate every key before reading anything.
+	tables := make(map[string]*Table)
+	for tableName, request := range input.RequestItems {
+		t, awserr := d.lockedGetTable(tableName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		tables[tableName] = t
+
+		seen := make(map[string]bool)
+		for _, key := range request.Keys {
+			if awserr := t.keySchema.validateKey(key, t.AttributeDefinitions); awserr != nil {
+				return nil, awserr
+			}
+			id := t.keySchema.keyID(key)
+			if seen[id] {
+				return nil, ValidationException("Provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+		}
+	}
+
+	output := &BatchGetItemOutput{
+		Responses:       make(map[string][]APIItem),
+		UnprocessedKeys: make(map[string]APIKeysAndAttributes),
+	}
+	// Once the response reaches the size limit, the remaining keys are left for the caller to retry,
+	// as are the keys of throttled tables.
+	size := 0
+	var throttleErr *awserrors.Error
+	for _, tableName := range sortedKeys(input.RequestItems) {
+		request := input.RequestItems[tableName]
+		t := tables[tableName]
+
+		if awserr := d.lockedCheckThroughput(t, true); awserr != nil {
+			output.UnprocessedKeys[tableName] = request
+			throttleErr = awserr
+			continue
+		}
+
+		items := []APIItem{}
+		capacity := newReadCapacity()
+		for i, key := range request.Keys {
+			if size >= maxBatchSize {
+				unprocessed := request
+				unprocessed.Keys = request.Keys[i:]
+				output.UnprocessedKeys[tableName] = unprocessed
+				break
+			}
+
+			// Each item is rounded up separately, and reading a missing item still has a cost.
+			item, ok, err := t.items.get(key)
+			if !request.ConsistentRead {
+				item, ok, err = t.eventualItem(key)
+			}
+			if err != nil {
+				return nil, InternalServerError(err.Error())
+			}
+			capacity.addRead(nil, itemSize(item), request.ConsistentRead)
+			if !ok {
+				continue
+			}
+			size += itemSize(item)
+			if p, ok := projections[tableName]; ok {
+				items = append(items, p.apply(item))
+			} else {
+				items = append(items, cloneItem(item))
+			}
+		}
+		output.Responses[tableName] = items
+		d.lockedConsume(t, capacity)
+		if c := capacity.toAPI(t, input.ReturnConsumedCapacity); c != nil {
+			output.ConsumedCapacity = append(output.ConsumedCapacity, *c)
+		}
+	}
+	if len(output.Responses) == 0 && throttleErr != nil {
+		return nil, throttleErr
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_BatchWriteItem.html
+func (d *DynamoDB) BatchWriteItem(input BatchWriteItemInput) (*BatchWriteItemOutput, *awserrors.Error) {
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+	requestCount := 0
+	for _, requests := range input.RequestItems {
+		requestCount += len(requests)
+		for _, request := range requests {
+			if (request.PutRequest == nil) == (request.DeleteRequest == nil) {
+				return nil, ValidationException("A WriteRequest must contain exactly one of PutRequest or DeleteRequest")
+			}
+		}
+	}
+	if len(input.RequestItems) == 0 || requestCount == 0 || requestCount > maxBatchWriteRequests {
+		return nil, ValidationException(fmt.Sprintf(
+			"1 validation error detected: Value at 'requestItems' failed to satisfy constraint: Map value must satisfy constraint: [Member must have length less than or equal to %d, Member must have length greater than or equal to 1]",
+			maxBatchWriteRequests))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Validate every request before writing anything.
+	tables := make(map[string]*Table)
+	size := 0
+	for tableName, requests := range input.RequestItems {
+		t, awserr := d.lockedGetTable(tableName)
+		if awserr != nil {
+			return nil, awserr
+		}
+		tables[tableName] = t
+
+		seen := make(map[string]bool)
+		for _, request := range requests {
+			var key APIItem
+			if request.PutRequest != nil {
+				if awserr := t.validateItem(request.PutRequest.Item); awserr != nil {
+					return nil, awserr
+				}
+				key = request.PutRequest.Item
+				size += itemSize(request.PutRequest.Item)
+			} else {
+				if awserr := t.keySchema.validateKey(request.DeleteRequest.Key, t.AttributeDefinitions); awserr != nil {
+					return nil, awserr
+				}
+				key = request.DeleteRequest.Key
+				size += itemSize(request.DeleteRequest.Key)
+			}
+
+			id := t.keySchema.keyID(key)
+			if seen[id] {
+				return nil, ValidationException("Provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+		}
+	}
+	if size > maxBatchSize {
+		return nil, ValidationException("Item size to update has exceeded the maximum allowed size")
+	}
+
+	output := &BatchWriteItemOutput{
+		UnprocessedItems: make(map[string][]APIWriteRequest),
+	}
+	// The requests for throttled tables are left for the caller to retry.
+	var throttleErr *awserrors.Error
+	for _, tableName := range sortedKeys(input.RequestItems) {
+		t := tables[tableName]
+		if awserr := d.lockedCheckThroughput(t, false); awserr != nil {
+			output.UnprocessedItems[tableName] = input.RequestItems[tableName]
+			throttleErr = awserr
+			continue
+		}
+
+		capacity := newWriteCapacity()
+		for _, request := range input.RequestItems[tableName] {
+			var err error
+			if request.PutRequest != nil {
+				var existingItem APIItem
+				existingItem, _, err = t.putItem(cloneItem(request.PutRequest.Item))
+				capacity.addWrite(t, existingItem, request.PutRequest.Item)
+			} else {
+				var existingItem APIItem
+				existingItem, _, err = t.deleteItem(request.DeleteRequest.Key)
+				capacity.addWrite(t, existingItem, nil)
+			}
+			if err != nil {
+				return nil, InternalServerError(err.Error())
+			}
+		}
+		d.lockedConsume(t, capacity)
+		if c := capacity.toAPI(t, input.ReturnConsumedCapacity); c != nil {
+			output.ConsumedCapacity = append(output.ConsumedCapacity, *c)
+		}
+	}
+	if len(output.UnprocessedItems) == len(input.RequestItems) && throttleErr != nil {
+		return nil, throttleErr
+	}
+	return output, nil
+}
@@ -0,0 +1,162 @@
+package dynamodb
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBatchWriteItem(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	put := func(item APIItem) APIWriteRequest {
+		var request APIWriteRequest
+		request.PutRequest = &struct{ Item APIItem }{Item: item}
+		return request
+	}
+	del := func(key APIItem) APIWriteRequest {
+		var request APIWriteRequest
+		request.DeleteRequest = &struct{ Key APIItem }{Key: key}
+		return request
+	}
+
+	output, err := d.BatchWriteItem(BatchWriteItemInput{
+		RequestItems: map[string][]APIWriteRequest{
+			tableName: {
+				put(APIItem{"pk": {S: "c"}, "sk": {N: "0"}}),
+				put(APIItem{"pk": {S: "c"}, "sk": {N: "1"}}),
+				del(APIItem{"pk": {S: "a"}, "sk": {N: "0"}}),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.UnprocessedItems == nil || len(output.UnprocessedItems) != 0 {
+		t.Fatal("Expected empty UnprocessedItems", output.UnprocessedItems)
+	}
+
+	scan, err := d.Scan(ScanInput{TableName: tableName, Select: "COUNT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Count != 21 {
+		t.Fatal("Wrong item count", scan.Count)
+	}
+
+	var tooMany []APIWriteRequest
+	for i := 0; i < 26; i++ {
+		tooMany = append(tooMany, put(APIItem{"pk": {S: "d"}, "sk": {N: strconv.Itoa(i)}}))
+	}
+	for _, requests := range [][]APIWriteRequest{
+		tooMany,
+		{put(APIItem{"pk": {S: "d"}, "sk": {N: "0"}}), del(APIItem{"pk": {S: "d"}, "sk": {N: "0.0"}})},
+		{put(APIItem{"pk": {S: "d"}, "sk": {N: "0"}}), put(APIItem{"pk": {S: "d"}})},
+		{{}},
+	} {
+		_, err := d.BatchWriteItem(BatchWriteItemInput{
+			RequestItems: map[string][]APIWriteRequest{tableName: requests},
+		})
+		if err == nil {
+			t.Fatal("Expected error for", requests)
+		}
+	}
+
+	// Failed batches must not be partially applied.
+	scan, err = d.Scan(ScanInput{TableName: tableName, Select: "COUNT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Count != 21 {
+		t.Fatal("Wrong item count", scan.Count)
+	}
+}
+
+func TestBatchGetItem(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	output, err := d.BatchGetItem(BatchGetItemInput{
+		RequestItems: map[string]APIKeysAndAttributes{
+			tableName: {
+				Keys: []APIItem{
+					{"pk": {S: "a"}, "sk": {N: "1"}},
+					{"pk": {S: "b"}, "sk": {N: "2"}},
+					{"pk": {S: "missing"}, "sk": {N: "1"}},
+				},
+				ProjectionExpression: "#v",
+				ExpressionAttributeNames: map[string]string{
+					"#v": "value",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := output.Responses[tableName]
+	if len(items) != 2 || len(items[0]) != 1 || items[0]["value"].S != "a1" || items[1]["value"].S != "b2" {
+		t.Fatal("Wrong items", items)
+	}
+
+	_, err = d.BatchGetItem(BatchGetItemInput{
+		RequestItems: map[string]APIKeysAndAttributes{
+			tableName: {
+				Keys: []APIItem{
+					{"pk": {S: "a"}, "sk": {N: "1"}},
+					{"pk": {S: "a"}, "sk": {N: "1.0"}},
+				},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected duplicate key error")
+	}
+}
+
+func TestBatchGetItemSizeLimit(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	large := strings.Repeat("x", 300*1024)
+	var keys []APIItem
+	for i := 0; i < 100; i++ {
+		key := APIItem{"pk": {S: "large"}, "sk": {N: strconv.Itoa(i)}}
+		item := cloneItem(key)
+		item["data"] = stringValue(large)
+		_, err := d.PutItem(PutItemInput{TableName: tableName, Item: item})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	_, err := d.PutItem(PutItemInput{
+		TableName: tableName,
+		Item:      APIItem{"pk": {S: "large"}, "sk": {N: "-1"}, "data": stringValue(large + large)},
+	})
+	if err == nil {
+		t.Fatal("Expected item size error")
+	}
+
+	// Retrying the unprocessed keys should eventually return everything.
+	request := APIKeysAndAttributes{Keys: keys}
+	retrieved := 0
+	for attempt := 0; len(request.Keys) > 0; attempt++ {
+		if attempt == 10 {
+			t.Fatal("Too many attempts")
+		}
+		output, err := d.BatchGetItem(BatchGetItemInput{
+			RequestItems: map[string]APIKeysAndAttributes{tableName: request},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempt == 0 && len(output.UnprocessedKeys) == 0 {
+			t.Fatal("Expected unprocessed keys")
+		}
+		retrieved += len(output.Responses[tableName])
+		request = output.UnprocessedKeys[tableName]
+	}
+	if retrieved != 100 {
+		t.Fatal("Wrong item count", retrieved)
+	}
+}
@@ -0,0 +1,244 @@
+package dynamodb
+
+import (
+	"math"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// This file implements billing modes, consumed capacity and throttling.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/HowItWorks.ReadWriteCapacityMode.html
+
+const (
+	readUnitSize  = 4 * 1024
+	writeUnitSize = 1024
+)
+
+// newBillingMode validates the billing mode and throughput of a table or GSI.
+// Tables created without either use on-demand capacity.
+func newBillingMode(
+	billingMode string,
+	throughput *APIProvisionedThroughput,
+) (string, *APIProvisionedThroughput, *awserrors.Error) {
+	if billingMode == "" {
+		billingMode = "PAY_PER_REQUEST"
+		if throughput != nil {
+			billingMode = "PROVISIONED"
+		}
+	}
+
+	switch billingMode {
+	case "PAY_PER_REQUEST":
+		if throughput != nil {
+			return "", nil, ValidationException(
+				"One or more parameter values were invalid: Neither ReadCapacityUnits nor WriteCapacityUnits can be specified when BillingMode is PAY_PER_REQUEST")
+		}
+	case "PROVISIONED":
+		if awserr := validateThroughput(throughput); awserr != nil {
+			return "", nil, awserr
+		}
+	default:
+		return "", nil, ValidationException("Invalid BillingMode: " + billingMode)
+	}
+	return billingMode, throughput, nil
+}
+
+func validateThroughput(throughput *APIProvisionedThroughput) *awserrors.Error {
+	if throughput == nil {
+		return ValidationException(
+			"One or more parameter values were invalid: ReadCapacityUnits and WriteCapacityUnits must both be specified when BillingMode is PROVISIONED")
+	}
+	if throughput.ReadCapacityUnits < 1 || throughput.WriteCapacityUnits < 1 {
+		return ValidationException(
+			"One or more parameter values were invalid: Provisioned throughput units must be greater than or equal to 1")
+	}
+	return nil
+}
+
+func throughputDescription(throughput *APIProvisionedThroughput) APIProvisionedThroughputDescription {
+	if throughput == nil {
+		return APIProvisionedThroughputDescription{}
+	}
+	return APIProvisionedThroughputDescription{
+		ReadCapacityUnits:  throughput.ReadCapacityUnits,
+		WriteCapacityUnits: throughput.WriteCapacityUnits,
+	}
+}
+
+// capacityBucket is a token bucket refilled at the provisioned rate. Unused capacity
+// accumulates for up to burstDuration, like DynamoDB's burst capacity.
+type capacityBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+const burstDuration = 5 * time.Minute
+
+func (b *capacityBucket) refill(now time.Time, rate int64) {
+	if b.lastRefill.IsZero() {
+		// A new bucket hasn't accumulated any burst capacity yet.
+		b.tokens = float64(rate)
+	} else {
+		elapsed := now.Sub(b.lastRefill).Seconds()
+		b.tokens = math.Min(b.tokens+elapsed*float64(rate), float64(rate)*burstDuration.Seconds())
+	}
+	b.lastRefill = now
+}
+
+// lockedCheckThroughput returns ProvisionedThroughputExceededException if the table has used up its
+// provisioned capacity. Requests are allowed while any capacity remains, and may overdraw it.
+func (d *DynamoDB) lockedCheckThroughput(t *Table, read bool) *awserrors.Error {
+	if !d.throttle || t.BillingMode != "PROVISIONED" {
+		return nil
+	}
+
+	bucket, rate := &t.writeBucket, t.ProvisionedThroughput.WriteCapacityUnits
+	if read {
+		bucket, rate = &t.readBucket, t.ProvisionedThroughput.ReadCapacityUnits
+	}
+	bucket.refill(d.clock(), rate)
+	if bucket.tokens <= 0 {
+		return ProvisionedThroughputExceededException(
+			"The level of configured provisioned throughput for the table was exceeded. Consider increasing your provisioning level with the UpdateTable API.")
+	}
+	return nil
+}
+
+// consumedCapacity accumulates the capacity units used by a request.
+type consumedCapacity struct {
+	read    bool
+	table   float64
+	indexes map[*secondaryIndex]float64
+}
+
+func newReadCapacity() *consumedCapacity {
+	return &consumedCapacity{read: true, indexes: make(map[*secondaryIndex]float64)}
+}
+
+func newWriteCapacity() *consumedCapacity {
+	return &consumedCapacity{indexes: make(map[*secondaryIndex]float64)}
+}
+
+// readUnits returns the capacity units to read items of the given total size.
+// Eventually consistent reads cost half as much.
+func readUnits(size int, consistent bool) float64 {
+	units := math.Max(math.Ceil(float64(size)/readUnitSize), 1)
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+func writeUnits(size int) float64 {
+	return math.Max(math.Ceil(float64(size)/writeUnitSize), 1)
+}
+
+// addRead adds the cost of reading items from the table, or index if not nil.
+func (c *consumedCapacity) addRead(index *secondaryIndex, size int, consistent bool) {
+	if index == nil {
+		c.table += readUnits(size, consistent)
+	} else {
+		c.indexes[index] += readUnits(size, consistent)
+	}
+}
+
+// addWrite adds the cost of changing an item from oldItem to newItem, either of which may be nil,
+// including the writes needed to keep the indexes up to date.
+func (c *consumedCapacity) addWrite(t *Table, oldItem, newItem APIItem) {
+	c.table += writeUnits(max(itemSize(oldItem), itemSize(newItem)))
+
+	for _, index := range t.indexes {
+		inOld := oldItem != nil && index.items.hasKey(oldItem)
+		inNew := newItem != nil && index.items.hasKey(newItem)
+		switch {
+		case inOld && inNew:
+			oldKey := index.items.extractKey(oldItem)
+			newKey := index.items.extractKey(newItem)
+			if index.keySchema.keyID(oldKey) != index.keySchema.keyID(newKey) {
+				// Moving the item within the index takes a delete and a put.
+				c.indexes[index] += writeUnits(itemSize(index.project(oldItem)))
+			}
+			c.indexes[index] += writeUnits(itemSize(index.project(newItem)))
+		case inOld:
+			c.indexes[index] += writeUnits(itemSize(index.project(oldItem)))
+		case inNew:
+			c.indexes[index] += writeUnits(itemSize(index.project(newItem)))
+		}
+	}
+}
+
+func (c *consumedCapacity) total() float64 {
+	total := c.table
+	for _, units := range c.indexes {
+		total += units
+	}
+	return total
+}
+
+// lockedConsume charges the capacity to the table's provisioned throughput. Local indexes
+// share the throughput of the table, but global indexes aren't throttled.
+func (d *DynamoDB) lockedConsume(t *Table, c *consumedCapacity) {
+	if !d.throttle || t.BillingMode != "PROVISIONED" {
+		return
+	}
+	units := c.table
+	for index, indexUnits := range c.indexes {
+		if !index.Global {
+			units += indexUnits
+		}
+	}
+	if c.read {
+		t.readBucket.tokens -= units
+	} else {
+		t.writeBucket.tokens -= units
+	}
+}
+
+func validateReturnConsumedCapacity(returnConsumedCapacity string) *awserrors.Error {
+	switch returnConsumedCapacity {
+	case "", "NONE", "TOTAL", "INDEXES":
+		return nil
+	}
+	return ValidationException("Invalid ReturnConsumedCapacity: " + returnConsumedCapacity)
+}
+
+// toAPI returns the ConsumedCapacity to include in the response, or nil if it wasn't requested.
+func (c *consumedCapacity) toAPI(t *Table, returnConsumedCapacity string) *APIConsumedCapacity {
+	if returnConsumedCapacity != "TOTAL" && returnConsumedCapacity != "INDEXES" {
+		return nil
+	}
+
+	capacity := func(units float64) APICapacity {
+		if c.read {
+			return APICapacity{CapacityUnits: units, ReadCapacityUnits: units}
+		}
+		return APICapacity{CapacityUnits: units, WriteCapacityUnits: units}
+	}
+
+	total := capacity(c.total())
+	output := &APIConsumedCapacity{
+		CapacityUnits:      total.CapacityUnits,
+		ReadCapacityUnits:  total.ReadCapacityUnits,
+		TableName:          t.Name,
+		WriteCapacityUnits: total.WriteCapacityUnits,
+	}
+	if returnConsumedCapacity == "INDEXES" {
+		table := capacity(c.table)
+		output.Table = &table
+		for index, units := range c.indexes {
+			if index.Global {
+				if output.GlobalSecondaryIndexes == nil {
+					output.GlobalSecondaryIndexes = make(map[string]APICapacity)
+				}
+				output.GlobalSecondaryIndexes[index.Name] = capacity(units)
+			} else {
+				if output.LocalSecondaryIndexes == nil {
+					output.LocalSecondaryIndexes = make(map[string]APICapacity)
+				}
+				output.LocalSecondaryIndexes[index.Name] = capacity(units)
+			}
+		}
+	}
+	return output
+}
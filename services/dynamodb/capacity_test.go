@@ -0,0 +1,175 @@
+package dynamodb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+func TestConsumedCapacity(t *testing.T) {
+	d := newDynamoDBWithIndexes(t)
+
+	put, err := d.PutItem(PutItemInput{
+		TableName:              tableName,
+		Item:                   APIItem{"pk": {S: "c"}, "sk": {N: "1"}, "group": {S: "x"}, "value": {S: strings.Repeat("v", 1500)}},
+		ReturnConsumedCapacity: "INDEXES",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := put.ConsumedCapacity
+	if c == nil || c.TableName != tableName || c.Table.CapacityUnits != 2 || c.GlobalSecondaryIndexes["byGroup"].CapacityUnits != 2 {
+		t.Fatal("Wrong consumed capacity", c)
+	}
+	if _, ok := c.LocalSecondaryIndexes["byRank"]; ok || c.CapacityUnits != 4 {
+		t.Fatal("Wrong consumed capacity", c)
+	}
+
+	// Eventually consistent reads cost half as much.
+	query, err := d.Query(QueryInput{
+		TableName:              tableName,
+		KeyConditionExpression: "pk = :a",
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":a": {S: "a"},
+		},
+		ReturnConsumedCapacity: "TOTAL",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.ConsumedCapacity.CapacityUnits != 0.5 || query.ConsumedCapacity.Table != nil {
+		t.Fatal("Wrong consumed capacity", query.ConsumedCapacity)
+	}
+
+	scan, err := d.Scan(ScanInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.ConsumedCapacity != nil {
+		t.Fatal("Unexpected consumed capacity", scan.ConsumedCapacity)
+	}
+
+	_, err = d.Scan(ScanInput{TableName: tableName, ReturnConsumedCapacity: "SOME"})
+	if err == nil {
+		t.Fatal("Expected error for invalid ReturnConsumedCapacity")
+	}
+}
+
+func TestBillingMode(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	describe, err := d.DescribeTable(DescribeTableInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if describe.Table.BillingModeSummary.BillingMode != "PAY_PER_REQUEST" {
+		t.Fatal("Wrong billing mode", describe.Table.BillingModeSummary)
+	}
+
+	_, err = d.UpdateTable(UpdateTableInput{
+		TableName:   tableName,
+		BillingMode: "PROVISIONED",
+	})
+	if err == nil {
+		t.Fatal("Expected error for PROVISIONED without throughput")
+	}
+
+	update, err := d.UpdateTable(UpdateTableInput{
+		TableName:             tableName,
+		BillingMode:           "PROVISIONED",
+		ProvisionedThroughput: &APIProvisionedThroughput{ReadCapacityUnits: 5, WriteCapacityUnits: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	throughput := update.TableDescription.ProvisionedThroughput
+	if update.TableDescription.BillingModeSummary.BillingMode != "PROVISIONED" ||
+		throughput.ReadCapacityUnits != 5 || throughput.WriteCapacityUnits != 2 {
+		t.Fatal("Wrong table description", update.TableDescription)
+	}
+
+	_, err = d.CreateTable(CreateTableInput{
+		TableName:   "other",
+		BillingMode: "PAY_PER_REQUEST",
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+		},
+		KeySchema: []APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+		},
+		ProvisionedThroughput: &APIProvisionedThroughput{ReadCapacityUnits: 1, WriteCapacityUnits: 1},
+	})
+	if err == nil {
+		t.Fatal("Expected error for PAY_PER_REQUEST with throughput")
+	}
+}
+
+func TestThrottling(t *testing.T) {
+	d := newDynamoDB(Options{ArnGenerator: generator, ThrottleProvisionedThroughput: true})
+	now := time.Unix(1_700_000_000, 0)
+	d.clock = func() time.Time { return now }
+
+	_, err := d.CreateTable(CreateTableInput{
+		TableName: tableName,
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+		},
+		KeySchema: []APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+		},
+		ProvisionedThroughput: &APIProvisionedThroughput{ReadCapacityUnits: 1, WriteCapacityUnits: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(pk string) *awserrors.Error {
+		_, err := d.PutItem(PutItemInput{TableName: tableName, Item: APIItem{"pk": {S: pk}}})
+		return err
+	}
+	for _, pk := range []string{"a", "b"} {
+		if err := put(pk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := put("c"); err == nil || err.Body.Type != "ProvisionedThroughputExceededException" {
+		t.Fatal("Expected throttling error", err)
+	}
+
+	// Batches fail if every table is throttled.
+	_, err = d.BatchWriteItem(BatchWriteItemInput{
+		RequestItems: map[string][]APIWriteRequest{
+			tableName: {{PutRequest: &struct{ Item APIItem }{Item: APIItem{"pk": {S: "c"}}}}},
+		},
+	})
+	if err == nil || err.Body.Type != "ProvisionedThroughputExceededException" {
+		t.Fatal("Expected throttling error", err)
+	}
+
+	// Capacity is replenished over time.
+	now = now.Add(time.Second)
+	if err := put("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Strongly consistent reads use up the read capacity faster.
+	read := func() *QueryOutput {
+		output, _ := d.Query(QueryInput{
+			TableName:              tableName,
+			ConsistentRead:         true,
+			KeyConditionExpression: "pk = :a",
+			ExpressionAttributeValues: map[string]APIAttributeValue{
+				":a": {S: "a"},
+			},
+		})
+		return output
+	}
+	if read() == nil {
+		t.Fatal("Expected first read to succeed")
+	}
+	if read() != nil {
+		t.Fatal("Expected second read to be throttled")
+	}
+}
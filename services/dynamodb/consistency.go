@@ -0,0 +1,118 @@
+package dynamodb
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// eventualConsistency keeps what items were before their latest writes, until the writes have had
+// time to reach every replica, so eventually consistent reads can miss them like they can in AWS.
+type eventualConsistency struct {
+	delay time.Duration
+	clock func() time.Time
+	// Keyed by the items' key IDs.
+	pending map[string]*pendingWrite
+}
+
+// pendingWrite is a write which eventually consistent reads don't see yet.
+type pendingWrite struct {
+	key APIItem
+	// nil if the item didn't exist.
+	previous  APIItem
+	visibleAt time.Time
+}
+
+func newEventualConsistency(delay time.Duration, clock func() time.Time) *eventualConsistency {
+	return &eventualConsistency{
+		delay:   delay,
+		clock:   clock,
+		pending: make(map[string]*pendingWrite),
+	}
+}
+
+// recordWrite remembers the item from before a write. Reads keep seeing it until the latest of
+// the writes to its key since then is visible.
+func (c *eventualConsistency) recordWrite(schema keySchema, key APIItem, previous APIItem) {
+	now := c.clock()
+	id := schema.keyID(key)
+	p, ok := c.pending[id]
+	if !ok || !now.Before(p.visibleAt) {
+		p = &pendingWrite{key: schema.extractKey(key), previous: previous}
+		c.pending[id] = p
+	}
+	p.visibleAt = now.Add(c.delay)
+}
+
+// lockedPendingWrites returns the writes which reads don't see yet, and forgets the rest.
+func (c *eventualConsistency) lockedPendingWrites() map[string]*pendingWrite {
+	now := c.clock()
+	maps.DeleteFunc(c.pending, func(_ string, p *pendingWrite) bool {
+		return !now.Before(p.visibleAt)
+	})
+	return c.pending
+}
+
+// eventualItem returns the item with the key as an eventually consistent read sees it.
+func (t *Table) eventualItem(key APIItem) (APIItem, bool, error) {
+	if t.consistency != nil {
+		if p, ok := t.consistency.lockedPendingWrites()[t.keySchema.keyID(key)]; ok {
+			return p.previous, p.previous != nil, nil
+		}
+	}
+	return t.items.get(key)
+}
+
+// eventualView returns the items of the table, or of the index if there is one, as eventually
+// consistent reads see them. It's a copy if any writes are pending, so it's only used for reading.
+func (t *Table) eventualView(index *secondaryIndex) (*itemCollection, error) {
+	source := t.items
+	if index != nil {
+		source = index.items
+	}
+	if t.consistency == nil {
+		return source, nil
+	}
+	pending := t.consistency.lockedPendingWrites()
+	if len(pending) == 0 {
+		return source, nil
+	}
+
+	view := source.clone()
+	for _, p := range pending {
+		current, ok, err := t.items.get(p.key)
+		if err != nil {
+			return nil, err
+		}
+		if ok && view.hasKey(current) {
+			if _, _, err := view.delete(current); err != nil {
+				return nil, err
+			}
+		}
+		if p.previous == nil || !view.hasKey(p.previous) {
+			continue
+		}
+		previous := p.previous
+		if index != nil {
+			previous = index.project(p.previous)
+		}
+		if _, _, err := view.put(previous); err != nil {
+			return nil, err
+		}
+	}
+	return view, nil
+}
+
+// clone copies the collection's partitions, but not its items, which are replaced rather than
+// modified by writes. Writes to a clone of a collection in storage only change the clone.
+func (c *itemCollection) clone() *itemCollection {
+	clone := *c
+	clone.partitions = make(map[string][]APIItem, len(c.partitions))
+	for partitionKey, partition := range c.partitions {
+		clone.partitions[partitionKey] = slices.Clone(partition)
+	}
+	if c.items != nil {
+		clone.overlay = make(map[string]APIItem)
+	}
+	return &clone
+}
@@ -0,0 +1,104 @@
+package dynamodb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventualConsistency(t *testing.T) {
+	d := newDynamoDB(Options{ArnGenerator: generator, EventualConsistencyDelay: time.Second})
+	now := time.Unix(1_700_000_000, 0)
+	d.clock = func() time.Time { return now }
+	_, err := d.CreateTable(CreateTableInput{
+		TableName: tableName,
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+			{AttributeName: "group", AttributeType: "S"},
+		},
+		KeySchema: []APIKeySchemaElement{{AttributeName: "pk", KeyType: "HASH"}},
+		GlobalSecondaryIndexes: []APIGlobalSecondaryIndex{{
+			IndexName:  "byGroup",
+			KeySchema:  []APIKeySchemaElement{{AttributeName: "group", KeyType: "HASH"}},
+			Projection: APIProjection{ProjectionType: "ALL"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(pk, group string) {
+		_, err := d.PutItem(PutItemInput{TableName: tableName, Item: APIItem{"pk": {S: pk}, "group": {S: group}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(pk string, consistent bool) string {
+		output, err := d.BatchGetItem(BatchGetItemInput{RequestItems: map[string]APIKeysAndAttributes{
+			tableName: {Keys: []APIItem{{"pk": {S: pk}}}, ConsistentRead: consistent},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items := output.Responses[tableName]; len(items) == 1 {
+			return items[0]["group"].S
+		}
+		return ""
+	}
+	scan := func(consistent bool) int {
+		output, err := d.Scan(ScanInput{TableName: tableName, ConsistentRead: consistent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output.Count
+	}
+	queryGroup := func(group string) int {
+		output, err := d.Query(QueryInput{
+			TableName:                 tableName,
+			IndexName:                 "byGroup",
+			KeyConditionExpression:    "#g = :g",
+			ExpressionAttributeNames:  map[string]string{"#g": "group"},
+			ExpressionAttributeValues: map[string]APIAttributeValue{":g": {S: group}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output.Count
+	}
+
+	put("a", "red")
+	if get("a", false) != "" || scan(false) != 0 || queryGroup("red") != 0 {
+		t.Fatal("Expected the new item not to be visible yet")
+	}
+	if get("a", true) != "red" || scan(true) != 1 {
+		t.Fatal("Expected consistent reads to see the new item")
+	}
+
+	now = now.Add(time.Second)
+	if get("a", false) != "red" || scan(false) != 1 || queryGroup("red") != 1 {
+		t.Fatal("Expected the item to be visible")
+	}
+
+	// Reads see the item from before the latest writes, until the last of them is visible.
+	put("a", "blue")
+	now = now.Add(500 * time.Millisecond)
+	put("a", "green")
+	now = now.Add(700 * time.Millisecond)
+	if get("a", false) != "red" || queryGroup("red") != 1 || queryGroup("green") != 0 {
+		t.Fatal("Expected the old item to still be visible")
+	}
+	now = now.Add(300 * time.Millisecond)
+	if get("a", false) != "green" || queryGroup("red") != 0 || queryGroup("green") != 1 {
+		t.Fatal("Expected the latest item to be visible")
+	}
+
+	if _, err := d.DeleteItem(DeleteItemInput{TableName: tableName, Key: APIItem{"pk": {S: "a"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if get("a", false) != "green" || scan(false) != 1 || scan(true) != 0 {
+		t.Fatal("Expected the deleted item to still be visible")
+	}
+	now = now.Add(time.Second)
+	if get("a", false) != "" || scan(false) != 0 {
+		t.Fatal("Expected the deletion to be visible")
+	}
+}
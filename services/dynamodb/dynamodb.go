@@ -1,56 +1,185 @@
 package dynamodb
 
 import (
+	"fmt"
 	"log/slog"
-	"reflect"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"aws-in-a-box/arn"
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/storage"
 )
 
 type Table struct {
-	Name                 string
-	ARN                  string
-	BillingMode          string
-	AttributeDefinitions []APIAttributeDefinition
-	KeySchema            []APIKeySchemaElement
+	Name                  string
+	ARN                   string
+	BillingMode           string
+	AttributeDefinitions  []APIAttributeDefinition
+	KeySchema             []APIKeySchemaElement
+	ProvisionedThroughput *APIProvisionedThroughput
 
-	PrimaryKeyAttributeName string
-	ItemsByPrimaryKey       map[string][]APIItem
-	ItemCount               int
+	keySchema keySchema
+	items     *itemCollection
+	indexes   []*secondaryIndex
+	// The latest stream, which may be disabled.
+	stream *tableStream
+	// Empty if time to live is disabled.
+	timeToLiveAttribute string
+	// Only used for throttling tables in PROVISIONED billing mode.
+	readBucket  capacityBucket
+	writeBucket capacityBucket
+	// nil if eventually consistent reads see every write.
+	consistency *eventualConsistency
 }
 
 func (t *Table) toAPI() APITableDescription {
-	return APITableDescription{
+	description := APITableDescription{
 		AttributeDefinitions: t.AttributeDefinitions,
-		ItemCount:            t.ItemCount,
-		KeySchema:            t.KeySchema,
+		BillingModeSummary: APIBillingModeSummary{
+			BillingMode: t.BillingMode,
+		},
+		ItemCount:             t.items.count,
+		KeySchema:             t.KeySchema,
+		ProvisionedThroughput: throughputDescription(t.ProvisionedThroughput),
 		// TODO: delayed creation
 		TableARN:    t.ARN,
 		TableStatus: "ACTIVE",
 	}
+	if t.stream != nil {
+		description.LatestStreamArn = t.stream.ARN
+		description.LatestStreamLabel = t.stream.Label
+		if t.stream.Enabled {
+			description.StreamSpecification = &APIStreamSpecification{
+				StreamEnabled:  true,
+				StreamViewType: t.stream.ViewType,
+			}
+		}
+	}
+	for _, index := range t.indexes {
+		if index.Global {
+			description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes, index.toGlobalAPI())
+		} else {
+			description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes, index.toLocalAPI())
+		}
+	}
+	return description
 }
 
 type DynamoDB struct {
 	logger       *slog.Logger
 	arnGenerator arn.Generator
+	// Overridden in tests to control time to live expiry and throttling.
+	clock func() time.Time
+	// Whether to throttle requests beyond the provisioned throughput of PROVISIONED tables.
+	throttle bool
+	// How long eventually consistent reads don't see writes for.
+	eventualConsistencyDelay time.Duration
+	// Shared by the instances of all regions, which each have their own tables.
+	regions *region.Regions[*DynamoDB]
+	// Where the tables' items are kept.
+	storage storage.Backend
 
 	mu           sync.Mutex
 	tablesByName map[string]*Table
+	streamsByARN map[string]*tableStream
+	// The region's tables as they're kept in storage, so they can be restored, by name.
+	storedTables *storage.Map[storedTable]
 }
 
-func New(logger *slog.Logger, generator arn.Generator) *DynamoDB {
-	if logger == nil {
-		logger = slog.Default()
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// How often to delete items whose time to live has expired. Expiry is disabled if zero.
+	TimeToLiveSweepInterval time.Duration
+	// Whether to reject requests beyond the provisioned throughput of PROVISIONED tables
+	// with ProvisionedThroughputExceededException.
+	ThrottleProvisionedThroughput bool
+	// Eventually consistent reads don't see writes until this long after they're made, so code
+	// which relies on reading its own writes without ConsistentRead fails. 0 makes every read see
+	// every write.
+	EventualConsistencyDelay time.Duration
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+	// Where to keep the tables' items, or in memory if nil. Tables kept in a file are restored
+	// from it.
+	Storage storage.Backend
+}
+
+func New(options Options) (*DynamoDB, error) {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.Storage == nil {
+		options.Storage = storage.NewMemory()
 	}
 
-	d := &DynamoDB{
-		logger:       logger,
-		arnGenerator: generator,
-		tablesByName: make(map[string]*Table),
+	d, err := newRegion(options)
+	if err != nil {
+		return nil, err
+	}
+	d.regions = region.New(options.ArnGenerator.Region, options.Regions, d, func(name string) (*DynamoDB, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r, err := newRegion(regionOptions)
+		if err != nil {
+			return nil, err
+		}
+		r.regions = d.regions
+		return r, nil
+	})
+	if err := d.restore(); err != nil {
+		return nil, err
+	}
+	if options.TimeToLiveSweepInterval > 0 {
+		go func() {
+			for {
+				time.Sleep(options.TimeToLiveSweepInterval)
+				for _, r := range d.regions.All() {
+					r.deleteExpiredItems()
+				}
+			}
+		}()
+	}
+	return d, nil
+}
+
+// newRegion returns the instance for the options' region, without any tables.
+func newRegion(options Options) (*DynamoDB, error) {
+	storedTables, err := storage.OpenMap[storedTable](options.Storage, storedTablesPrefix+options.ArnGenerator.Region)
+	if err != nil {
+		return nil, err
 	}
-	return d
+	return &DynamoDB{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		clock:        clock.Now,
+		throttle:     options.ThrottleProvisionedThroughput,
+		storage:      options.Storage,
+		tablesByName: make(map[string]*Table),
+		streamsByARN: make(map[string]*tableStream),
+		storedTables: storedTables,
+
+		eventualConsistencyDelay: options.EventualConsistencyDelay,
+	}, nil
+}
+
+// InRegion returns the tables of the region, for services which use tables by name. It fails if
+// the region isn't one of Options.Regions.
+func (d *DynamoDB) InRegion(name string) (*DynamoDB, error) {
+	return d.regions.Get(name)
+}
+
+// ForARN returns the tables of the region the table or stream ARN is in, for services which read
+// streams by ARN, like Lambda's event sources.
+func (d *DynamoDB) ForARN(resourceARN string) (*DynamoDB, error) {
+	return d.regions.ForARN(resourceARN)
 }
 
 // https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_CreateTable.html
@@ -58,45 +187,131 @@ func (d *DynamoDB) CreateTable(input CreateTableInput) (*CreateTableOutput, *aws
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	t, awserr := d.lockedCreateTable(input, nil)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := d.lockedSaveTable(t); awserr != nil {
+		delete(d.tablesByName, t.Name)
+		t.drop()
+		return nil, awserr
+	}
+	return &CreateTableOutput{
+		TableDescription: t.toAPI(),
+	}, nil
+}
+
+// lockedCreateTable creates the table, with the stored table's items if it's being restored. It
+// doesn't keep the table in storage, which is up to the caller.
+func (d *DynamoDB) lockedCreateTable(input CreateTableInput, stored *storedTable) (*Table, *awserrors.Error) {
 	if _, ok := d.tablesByName[input.TableName]; ok {
 		return nil, awserrors.ResourceInUseException("Table already exists")
 	}
 
-	primaryKeyAttributeName := ""
-	for _, keySchemaElement := range input.KeySchema {
-		if keySchemaElement.KeyType == "HASH" {
-			primaryKeyAttributeName = keySchemaElement.AttributeName
-			break
-		}
+	schema, err := newKeySchema(input.KeySchema, input.AttributeDefinitions)
+	if err != nil {
+		return nil, err
 	}
-	if primaryKeyAttributeName == "" {
-		return nil, awserrors.InvalidArgumentException("KeySchema must have a HASH key")
+	billingMode, throughput, err := newBillingMode(input.BillingMode, input.ProvisionedThroughput)
+	if err != nil {
+		return nil, err
 	}
 
 	t := &Table{
-		Name:                    input.TableName,
-		ARN:                     d.arnGenerator.Generate("dynamodb", "table", input.TableName),
-		BillingMode:             input.BillingMode,
-		AttributeDefinitions:    input.AttributeDefinitions,
-		KeySchema:               input.KeySchema,
-		PrimaryKeyAttributeName: primaryKeyAttributeName,
-		ItemsByPrimaryKey:       make(map[string][]APIItem),
+		Name:                  input.TableName,
+		ARN:                   d.arnGenerator.Generate("dynamodb", "table", input.TableName),
+		BillingMode:           billingMode,
+		AttributeDefinitions:  input.AttributeDefinitions,
+		KeySchema:             input.KeySchema,
+		ProvisionedThroughput: throughput,
+		keySchema:             schema,
+		items:                 newItemCollection(schema),
+	}
+	if d.eventualConsistencyDelay > 0 {
+		t.consistency = newEventualConsistency(d.eventualConsistencyDelay, func() time.Time { return d.clock() })
+	}
+
+	if len(input.GlobalSecondaryIndexes) > maxGlobalSecondaryIndexes {
+		return nil, awserrors.LimitExceededException(fmt.Sprintf(
+			"One or more parameter values were invalid: GlobalSecondaryIndex count exceeds the per-table limit of %d", maxGlobalSecondaryIndexes))
 	}
+	if len(input.LocalSecondaryIndexes) > maxLocalSecondaryIndexes {
+		return nil, ValidationException(fmt.Sprintf(
+			"One or more parameter values were invalid: LocalSecondaryIndex count exceeds the per-table limit of %d", maxLocalSecondaryIndexes))
+	}
+	for _, definition := range input.GlobalSecondaryIndexes {
+		index, err := newSecondaryIndex(
+			t, definition.IndexName, definition.KeySchema, definition.Projection, definition.ProvisionedThroughput, true)
+		if err != nil {
+			return nil, err
+		}
+		// The table doesn't have any items yet, so there's nothing to backfill.
+		t.indexes = append(t.indexes, index)
+	}
+	for _, definition := range input.LocalSecondaryIndexes {
+		index, err := newSecondaryIndex(t, definition.IndexName, definition.KeySchema, definition.Projection, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		// The table doesn't have any items yet, so there's nothing to backfill.
+		t.indexes = append(t.indexes, index)
+	}
+
+	// The items are only opened in storage once the table is known to be valid.
+	if err := d.openStorage(t, stored); err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	if input.StreamSpecification != nil && input.StreamSpecification.StreamEnabled {
+		if awserr := d.lockedEnableStream(t, input.StreamSpecification.StreamViewType); awserr != nil {
+			if stored == nil {
+				t.drop()
+			}
+			return nil, awserr
+		}
+	}
+
 	d.tablesByName[input.TableName] = t
+	return t, nil
+}
 
-	return &CreateTableOutput{
-		TableDescription: t.toAPI(),
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_DeleteTable.html
+func (d *DynamoDB) DeleteTable(input DeleteTableInput) (*DeleteTableOutput, *awserrors.Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, ok := d.tablesByName[input.TableName]
+	if !ok {
+		return nil, awserrors.ResourceNotFoundException("Requested resource not found")
+	}
+
+	if err := d.storedTables.Delete(t.Name); err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	// The table's stream stays readable, as it does in AWS for 24 hours after deletion.
+	if t.stream != nil && t.stream.Enabled {
+		t.lockedDisableStream()
+	}
+	delete(d.tablesByName, input.TableName)
+	// Items left behind are deleted when the emulator next starts.
+	if err := t.drop(); err != nil {
+		d.logger.Error("Deleting table items from storage", "table", t.Name, "err", err)
+	}
+
+	description := t.toAPI()
+	description.TableStatus = "DELETING"
+	return &DeleteTableOutput{
+		TableDescription: description,
 	}, nil
 }
 
-// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_CreateTable.html
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_DescribeTable.html
 func (d *DynamoDB) DescribeTable(input DescribeTableInput) (*DescribeTableOutput, *awserrors.Error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	t, ok := d.tablesByName[input.TableName]
 	if !ok {
-		return nil, awserrors.InvalidArgumentException("Table does not exist")
+		return nil, awserrors.ResourceNotFoundException("Requested resource not found")
 	}
 
 	return &DescribeTableOutput{
@@ -104,114 +319,884 @@ func (d *DynamoDB) DescribeTable(input DescribeTableInput) (*DescribeTableOutput
 	}, nil
 }
 
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_UpdateTable.html
+func (d *DynamoDB) UpdateTable(input UpdateTableInput) (*UpdateTableOutput, *awserrors.Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Validate all the changes before applying any of them.
+	definitions := slices.Clone(t.AttributeDefinitions)
+	for _, definition := range input.AttributeDefinitions {
+		i := slices.IndexFunc(definitions, func(existing APIAttributeDefinition) bool {
+			return existing.AttributeName == definition.AttributeName
+		})
+		if i == -1 {
+			definitions = append(definitions, definition)
+		} else if definitions[i].AttributeType != definition.AttributeType {
+			return nil, ValidationException(
+				"Cannot change the type of existing attribute definition: " + definition.AttributeName)
+		}
+	}
+
+	billingMode := t.BillingMode
+	throughput := t.ProvisionedThroughput
+	if input.BillingMode != "" || input.ProvisionedThroughput != nil {
+		if input.BillingMode != "" {
+			billingMode = input.BillingMode
+		}
+		if input.ProvisionedThroughput != nil || billingMode != t.BillingMode {
+			throughput = input.ProvisionedThroughput
+		}
+		var awserr *awserrors.Error
+		billingMode, throughput, awserr = newBillingMode(billingMode, throughput)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	// Index validation needs the new settings, so work on a copy of the table.
+	updated := *t
+	updated.AttributeDefinitions = definitions
+	updated.BillingMode = billingMode
+	updated.ProvisionedThroughput = throughput
+	updated.indexes = slices.Clone(t.indexes)
+	throughputUpdates := make(map[*secondaryIndex]*APIProvisionedThroughput)
+
+	var created []*secondaryIndex
+	for _, update := range input.GlobalSecondaryIndexUpdates {
+		switch {
+		case update.Create != nil:
+			index, awserr := newSecondaryIndex(
+				&updated, update.Create.IndexName, update.Create.KeySchema, update.Create.Projection,
+				update.Create.ProvisionedThroughput, true)
+			if awserr != nil {
+				return nil, awserr
+			}
+			updated.indexes = append(updated.indexes, index)
+			created = append(created, index)
+		case update.Delete != nil:
+			index := updated.lockedGetIndex(update.Delete.IndexName)
+			if index == nil || !index.Global {
+				return nil, awserrors.ResourceNotFoundException("Requested resource not found: Index: " + update.Delete.IndexName)
+			}
+			updated.lockedDeleteIndex(update.Delete.IndexName)
+		case update.Update != nil:
+			index := updated.lockedGetIndex(update.Update.IndexName)
+			if index == nil || !index.Global {
+				return nil, awserrors.ResourceNotFoundException("Requested resource not found: Index: " + update.Update.IndexName)
+			}
+			if updated.BillingMode == "PROVISIONED" {
+				if awserr := validateThroughput(update.Update.ProvisionedThroughput); awserr != nil {
+					return nil, awserr
+				}
+				throughputUpdates[index] = update.Update.ProvisionedThroughput
+			}
+		default:
+			return nil, ValidationException("One of Create, Update or Delete must be set in a GlobalSecondaryIndexUpdate")
+		}
+	}
+
+	globalIndexCount := 0
+	for _, index := range updated.indexes {
+		if index.Global {
+			globalIndexCount++
+		}
+	}
+	if globalIndexCount > maxGlobalSecondaryIndexes {
+		return nil, awserrors.LimitExceededException(fmt.Sprintf(
+			"One or more parameter values were invalid: GlobalSecondaryIndex count exceeds the per-table limit of %d", maxGlobalSecondaryIndexes))
+	}
+
+	// dropCreated deletes the created indexes' items from storage if the update fails.
+	dropCreated := func() {
+		for _, index := range created {
+			index.items.drop()
+		}
+	}
+	for _, index := range created {
+		items, err := d.openItems("")
+		if err != nil {
+			dropCreated()
+			return nil, InternalServerError(err.Error())
+		}
+		index.items.items = items
+	}
+
+	// This is the last change which can fail, short of storage failing, so it's applied first.
+	if spec := input.StreamSpecification; spec != nil {
+		var awserr *awserrors.Error
+		if spec.StreamEnabled {
+			awserr = d.lockedEnableStream(t, spec.StreamViewType)
+		} else {
+			awserr = t.lockedDisableStream()
+		}
+		if awserr != nil {
+			dropCreated()
+			return nil, awserr
+		}
+	}
+
+	deleted := slices.DeleteFunc(slices.Clone(t.indexes), func(index *secondaryIndex) bool {
+		return slices.Contains(updated.indexes, index)
+	})
+	t.AttributeDefinitions = definitions
+	t.indexes = slices.DeleteFunc(updated.indexes, func(index *secondaryIndex) bool {
+		return slices.Contains(created, index)
+	})
+	for _, index := range created {
+		if err := t.lockedAddIndex(index); err != nil {
+			dropCreated()
+			return nil, InternalServerError(err.Error())
+		}
+	}
+	for index, throughput := range throughputUpdates {
+		index.ProvisionedThroughput = throughput
+	}
+	t.BillingMode = billingMode
+	t.ProvisionedThroughput = throughput
+	if awserr := d.lockedSaveTable(t); awserr != nil {
+		return nil, awserr
+	}
+	for _, index := range deleted {
+		if err := index.items.drop(); err != nil {
+			d.logger.Error("Deleting index items from storage", "table", t.Name, "index", index.Name, "err", err)
+		}
+	}
+
+	return &UpdateTableOutput{
+		TableDescription: t.toAPI(),
+	}, nil
+}
+
+func (d *DynamoDB) lockedGetTable(tableName string) (*Table, *awserrors.Error) {
+	t, ok := d.tablesByName[tableName]
+	if !ok {
+		return nil, awserrors.ResourceNotFoundException("Requested resource not found")
+	}
+	return t, nil
+}
+
+// readOptions holds the parsed parameters shared by Query and Scan.
+type readOptions struct {
+	filter     condition
+	projection projection
+	countOnly  bool
+	limit      int
+}
+
+func parseReadOptions(
+	indexName string,
+	filterExpression string,
+	projectionExpression string,
+	selectValue string,
+	limit int,
+	names map[string]string,
+	values map[string]APIAttributeValue,
+) (readOptions, *awserrors.Error) {
+	var options readOptions
+
+	if limit < 0 {
+		return options, ValidationException("Limit must be greater than or equal to 1")
+	}
+	options.limit = limit
+
+	switch selectValue {
+	case "", "ALL_ATTRIBUTES", "COUNT", "SPECIFIC_ATTRIBUTES":
+	case "ALL_PROJECTED_ATTRIBUTES":
+		if indexName == "" {
+			return options, ValidationException("ALL_PROJECTED_ATTRIBUTES can be used only when Querying using an IndexName")
+		}
+	default:
+		return options, ValidationException("Invalid Select value: " + selectValue)
+	}
+
+	if projectionExpression != "" {
+		if selectValue != "" && selectValue != "SPECIFIC_ATTRIBUTES" {
+			return options, ValidationException(
+				"Cannot specify the ProjectionExpression when choosing to get " + selectValue)
+		}
+		var err error
+		options.projection, err = parseProjection(projectionExpression, names)
+		if err != nil {
+			return options, ValidationException("Invalid ProjectionExpression: " + err.Error())
+		}
+	} else if selectValue == "SPECIFIC_ATTRIBUTES" {
+		return options, ValidationException(
+			"SPECIFIC_ATTRIBUTES requires a ProjectionExpression to be specified")
+	}
+	options.countOnly = selectValue == "COUNT"
+
+	if filterExpression != "" {
+		var err error
+		options.filter, err = parseCondition(filterExpression, names, values)
+		if err != nil {
+			return options, ValidationException("Invalid FilterExpression: " + err.Error())
+		}
+	}
+
+	return options, nil
+}
+
+// lockedReadSource returns the items read by a Query or Scan: either the table's or the index's.
+func (t *Table) lockedReadSource(
+	indexName string,
+	consistentRead bool,
+	selectValue string,
+) (*itemCollection, *secondaryIndex, *awserrors.Error) {
+	if indexName == "" {
+		return t.items, nil, nil
+	}
+
+	index := t.lockedGetIndex(indexName)
+	if index == nil {
+		return nil, nil, ValidationException("The table does not have the specified index: " + indexName)
+	}
+	if index.Global && consistentRead {
+		return nil, nil, ValidationException("Consistent reads are not supported on global secondary indexes")
+	}
+	if index.Global && selectValue == "ALL_ATTRIBUTES" && !index.hasAllAttributes() {
+		return nil, nil, ValidationException(fmt.Sprintf(
+			"One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index %s because its projection type is not ALL",
+			indexName))
+	}
+	return index.items, index, nil
+}
+
+// lockedLoader returns how a Query or Scan reads the item with each of the source's keys. Local
+// secondary indexes read the full items from the table when attributes which aren't projected may
+// be needed.
+func (t *Table) lockedLoader(
+	source *itemCollection,
+	index *secondaryIndex,
+	options readOptions,
+	selectValue string,
+	consistentRead bool,
+) func(key APIItem) (APIItem, error) {
+	if index == nil || index.Global || index.hasAllAttributes() {
+		return source.load
+	}
+	if selectValue != "ALL_ATTRIBUTES" && options.projection == nil && options.filter == nil {
+		return source.load
+	}
+	return func(key APIItem) (APIItem, error) {
+		var item APIItem
+		var err error
+		if consistentRead {
+			item, _, err = t.items.get(key)
+		} else {
+			item, _, err = t.eventualItem(key)
+		}
+		return item, err
+	}
+}
+
+// validateStartKey checks that the ExclusiveStartKey identifies an item in the collection.
+func (t *Table) validateStartKey(c *itemCollection, key APIItem) *awserrors.Error {
+	awserr := c.schema.validateItem(key, t.AttributeDefinitions)
+	if awserr == nil {
+		awserr = t.keySchema.validateItem(key, t.AttributeDefinitions)
+	}
+	if awserr == nil && len(key) != len(c.keyNames()) {
+		awserr = ValidationException("The provided key element does not match the schema")
+	}
+	if awserr != nil {
+		return ValidationException("The provided starting key is invalid: " + awserr.Body.Message)
+	}
+	return nil
+}
+
+// page is the result of a Query or Scan.
+type page struct {
+	items        []APIItem
+	count        int
+	scannedCount int
+	// The total size of the evaluated items, which is rounded up once for the read's capacity.
+	scannedSize      int
+	lastEvaluatedKey APIItem
+}
+
+// readPage loads and evaluates up to limit candidates, which are keys, in order. It returns the
+// matching items (projected and copied, so they are safe to use without the lock), and the key to
+// resume from if there are more candidates left.
+func (c *itemCollection) readPage(
+	candidates []APIItem,
+	load func(key APIItem) (APIItem, error),
+	options readOptions,
+) (page, error) {
+	p := page{items: []APIItem{}}
+	for i, key := range candidates {
+		if options.limit > 0 && p.scannedCount == options.limit {
+			p.lastEvaluatedKey = c.extractKey(candidates[i-1])
+			break
+		}
+		item, err := load(key)
+		if err != nil {
+			return p, err
+		}
+		p.scannedCount++
+		p.scannedSize += itemSize(item)
+
+		if options.filter != nil && !options.filter.evaluate(item) {
+			continue
+		}
+		p.count++
+
+		if options.countOnly {
+			continue
+		}
+		if options.projection != nil {
+			item = options.projection.apply(item)
+		} else {
+			item = cloneItem(item)
+		}
+		p.items = append(p.items, item)
+	}
+
+	if options.countOnly {
+		p.items = nil
+	}
+	return p, nil
+}
+
 // https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_Scan.html
 func (d *DynamoDB) Scan(input ScanInput) (*ScanOutput, *awserrors.Error) {
+	options, awserr := parseReadOptions(
+		input.IndexName, input.FilterExpression, input.ProjectionExpression, input.Select, input.Limit,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+
+	segment, totalSegments := 0, 1
+	if (input.Segment == nil) != (input.TotalSegments == nil) {
+		return nil, ValidationException("The Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	}
+	if input.TotalSegments != nil {
+		segment, totalSegments = *input.Segment, *input.TotalSegments
+		if totalSegments < 1 || totalSegments > 1_000_000 {
+			return nil, ValidationException("TotalSegments must be between 1 and 1000000")
+		}
+		if segment < 0 || segment >= totalSegments {
+			return nil, ValidationException("The Segment parameter is zero-based and must be less than parameter TotalSegments")
+		}
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	t, ok := d.tablesByName[input.TableName]
-	if !ok {
-		return nil, awserrors.InvalidArgumentException("Table does not exist")
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	var allItems []APIItem
-	for _, items := range t.ItemsByPrimaryKey {
-		allItems = append(allItems, items...)
+	source, index, awserr := t.lockedReadSource(input.IndexName, input.ConsistentRead, input.Select)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if !input.ConsistentRead {
+		var err error
+		if source, err = t.eventualView(index); err != nil {
+			return nil, InternalServerError(err.Error())
+		}
+	}
+	if awserr := d.lockedCheckThroughput(t, true); awserr != nil {
+		return nil, awserr
 	}
 
+	candidates := source.scan(segment, totalSegments)
+	if input.ExclusiveStartKey != nil {
+		if awserr := t.validateStartKey(source, input.ExclusiveStartKey); awserr != nil {
+			return nil, awserr
+		}
+		start := sort.Search(len(candidates), func(i int) bool {
+			return source.compareKeys(candidates[i], input.ExclusiveStartKey) > 0
+		})
+		candidates = candidates[start:]
+	}
+	load := t.lockedLoader(source, index, options, input.Select, input.ConsistentRead)
+	p, err := source.readPage(candidates, load, options)
+	if err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	capacity := newReadCapacity()
+	capacity.addRead(index, p.scannedSize, input.ConsistentRead)
+	d.lockedConsume(t, capacity)
 	return &ScanOutput{
-		Count: len(allItems),
-		Items: allItems,
+		ConsumedCapacity: capacity.toAPI(t, input.ReturnConsumedCapacity),
+		Count:            p.count,
+		Items:            p.items,
+		LastEvaluatedKey: p.lastEvaluatedKey,
+		ScannedCount:     p.scannedCount,
 	}, nil
 }
 
+// parseKeyCondition validates the KeyConditionExpression against the key schema of the table or index.
+// It returns the partition key value to look up, and the condition to apply within the partition.
+func (t *Table) parseKeyCondition(
+	schema keySchema,
+	expression string,
+	names map[string]string,
+	values map[string]APIAttributeValue,
+) (APIAttributeValue, condition, *awserrors.Error) {
+	var partitionKeyValue APIAttributeValue
+	if expression == "" {
+		return partitionKeyValue, nil, ValidationException(
+			"Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+
+	cond, err := parseCondition(expression, names, values)
+	if err != nil {
+		return partitionKeyValue, nil, ValidationException("Invalid KeyConditionExpression: " + err.Error())
+	}
+
+	parts := []condition{cond}
+	if and, ok := cond.(andCondition); ok {
+		parts = []condition{and.left, and.right}
+	}
+
+	invalid := ValidationException("Query key condition not supported")
+	foundPartitionKey, foundSortKey := false, false
+	checkSortKey := func(o operand, values ...operand) bool {
+		path, ok := o.(pathOperand)
+		if !ok || schema.SortKey == "" || !path.path.isTopLevel(schema.SortKey) || foundSortKey {
+			return false
+		}
+		for _, v := range values {
+			if _, ok := v.(literalOperand); !ok {
+				return false
+			}
+		}
+		foundSortKey = true
+		return true
+	}
+
+	for _, part := range parts {
+		switch c := part.(type) {
+		case comparisonCondition:
+			value, ok := c.right.(literalOperand)
+			if !ok {
+				return partitionKeyValue, nil, invalid
+			}
+			path, ok := c.left.(pathOperand)
+			if ok && c.operator == "=" && path.path.isTopLevel(schema.PartitionKey) && !foundPartitionKey {
+				foundPartitionKey = true
+				partitionKeyValue = value.value
+			} else if c.operator == "<>" || !checkSortKey(c.left, c.right) {
+				return partitionKeyValue, nil, invalid
+			}
+		case betweenCondition:
+			if !checkSortKey(c.operand, c.low, c.high) {
+				return partitionKeyValue, nil, invalid
+			}
+		case functionCondition:
+			if c.name != "begins_with" || !checkSortKey(c.args[0], c.args[1]) {
+				return partitionKeyValue, nil, invalid
+			}
+		default:
+			return partitionKeyValue, nil, invalid
+		}
+	}
+
+	if !foundPartitionKey {
+		return partitionKeyValue, nil, ValidationException(
+			"Query condition missed key schema element: " + schema.PartitionKey)
+	}
+	for _, definition := range t.AttributeDefinitions {
+		if definition.AttributeName == schema.PartitionKey && definition.AttributeType != partitionKeyValue.Type() {
+			return partitionKeyValue, nil, ValidationException(
+				"One or more parameter values were invalid: Condition parameter type does not match schema type")
+		}
+	}
+
+	return partitionKeyValue, cond, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_Query.html
+func (d *DynamoDB) Query(input QueryInput) (*QueryOutput, *awserrors.Error) {
+	options, awserr := parseReadOptions(
+		input.IndexName, input.FilterExpression, input.ProjectionExpression, input.Select, input.Limit,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+
+	forward := input.ScanIndexForward == nil || *input.ScanIndexForward
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	source, index, awserr := t.lockedReadSource(input.IndexName, input.ConsistentRead, input.Select)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if !input.ConsistentRead {
+		var err error
+		if source, err = t.eventualView(index); err != nil {
+			return nil, InternalServerError(err.Error())
+		}
+	}
+	if awserr := d.lockedCheckThroughput(t, true); awserr != nil {
+		return nil, awserr
+	}
+
+	partitionKeyValue, keyCondition, awserr := t.parseKeyCondition(
+		source.schema, input.KeyConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// The key condition only needs the keys, which are what the partition has.
+	var candidates []APIItem
+	for _, key := range source.partition(partitionKeyValue) {
+		if keyCondition.evaluate(key) {
+			candidates = append(candidates, key)
+		}
+	}
+	if !forward {
+		slices.Reverse(candidates)
+	}
+
+	if input.ExclusiveStartKey != nil {
+		if awserr := t.validateStartKey(source, input.ExclusiveStartKey); awserr != nil {
+			return nil, awserr
+		}
+		start := sort.Search(len(candidates), func(i int) bool {
+			cmp := source.compareSortKeys(candidates[i], input.ExclusiveStartKey)
+			if forward {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+		candidates = candidates[start:]
+	}
+
+	load := t.lockedLoader(source, index, options, input.Select, input.ConsistentRead)
+	p, err := source.readPage(candidates, load, options)
+	if err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	capacity := newReadCapacity()
+	capacity.addRead(index, p.scannedSize, input.ConsistentRead)
+	d.lockedConsume(t, capacity)
+	return &QueryOutput{
+		ConsumedCapacity: capacity.toAPI(t, input.ReturnConsumedCapacity),
+		Count:            p.count,
+		Items:            p.items,
+		LastEvaluatedKey: p.lastEvaluatedKey,
+		ScannedCount:     p.scannedCount,
+	}, nil
+}
+
+// writeCondition is the parsed ConditionExpression of a write request.
+type writeCondition struct {
+	condition             condition
+	returnValuesOnFailure string
+}
+
+func parseWriteCondition(
+	expression string,
+	names map[string]string,
+	values map[string]APIAttributeValue,
+	returnValuesOnFailure string,
+) (writeCondition, *awserrors.Error) {
+	wc := writeCondition{returnValuesOnFailure: returnValuesOnFailure}
+	switch returnValuesOnFailure {
+	case "", "NONE", "ALL_OLD":
+	default:
+		return wc, ValidationException("Invalid ReturnValuesOnConditionCheckFailure: " + returnValuesOnFailure)
+	}
+
+	if expression != "" {
+		var err error
+		wc.condition, err = parseCondition(expression, names, values)
+		if err != nil {
+			return wc, ValidationException("Invalid ConditionExpression: " + err.Error())
+		}
+	}
+	return wc, nil
+}
+
+// check evaluates the condition against the existing item, which is nil if there is none.
+func (wc writeCondition) check(existingItem APIItem) *awserrors.Error {
+	if wc.condition == nil || wc.condition.evaluate(existingItem) {
+		return nil
+	}
+	awserr := ConditionalCheckFailedException("The conditional request failed")
+	if wc.returnValuesOnFailure == "ALL_OLD" && existingItem != nil {
+		awserr.Body.Item = cloneItem(existingItem)
+	}
+	return awserr
+}
+
+func validateReturnValues(returnValues string) *awserrors.Error {
+	switch returnValues {
+	case "", "NONE", "ALL_OLD":
+		return nil
+	}
+	return ValidationException("Return values set to invalid value: " + returnValues)
+}
+
 // https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_PutItem.html
 func (d *DynamoDB) PutItem(input PutItemInput) (*PutItemOutput, *awserrors.Error) {
+	if awserr := validateReturnValues(input.ReturnValues); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+	wc, awserr := parseWriteCondition(
+		input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues,
+		input.ReturnValuesOnConditionCheckFailure)
+	if awserr != nil {
+		return nil, awserr
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	t, ok := d.tablesByName[input.TableName]
-	if !ok {
-		return nil, awserrors.InvalidArgumentException("Table does not exist")
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	if awserr := t.validateItem(input.Item); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := d.lockedCheckThroughput(t, false); awserr != nil {
+		return nil, awserr
+	}
+
+	existingItem, _, err := t.items.get(input.Item)
+	if err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	if awserr := wc.check(existingItem); awserr != nil {
+		return nil, awserr
+	}
+	capacity := newWriteCapacity()
+	capacity.addWrite(t, existingItem, input.Item)
+	d.lockedConsume(t, capacity)
+	if _, _, err := t.putItem(cloneItem(input.Item)); err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+
+	output := &PutItemOutput{
+		ConsumedCapacity: capacity.toAPI(t, input.ReturnConsumedCapacity),
+	}
+	if input.ReturnValues == "ALL_OLD" && existingItem != nil {
+		output.Attributes = cloneItem(existingItem)
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_DeleteItem.html
+func (d *DynamoDB) DeleteItem(input DeleteItemInput) (*DeleteItemOutput, *awserrors.Error) {
+	if awserr := validateReturnValues(input.ReturnValues); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+	wc, awserr := parseWriteCondition(
+		input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues,
+		input.ReturnValuesOnConditionCheckFailure)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	if awserr := t.keySchema.validateKey(input.Key, t.AttributeDefinitions); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := d.lockedCheckThroughput(t, false); awserr != nil {
+		return nil, awserr
 	}
-	key := input.Item[t.PrimaryKeyAttributeName].S
-	if key == "" {
-		return nil, awserrors.InvalidArgumentException("PrimaryKey must be provided (and string)")
+
+	existingItem, _, err := t.items.get(input.Key)
+	if err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	if awserr := wc.check(existingItem); awserr != nil {
+		return nil, awserr
+	}
+	capacity := newWriteCapacity()
+	capacity.addWrite(t, existingItem, nil)
+	d.lockedConsume(t, capacity)
+	if _, _, err := t.deleteItem(input.Key); err != nil {
+		return nil, InternalServerError(err.Error())
 	}
-	t.ItemsByPrimaryKey[key] = append(t.ItemsByPrimaryKey[key], input.Item)
-	t.ItemCount += 1
 
-	return &PutItemOutput{}, nil
+	output := &DeleteItemOutput{
+		ConsumedCapacity: capacity.toAPI(t, input.ReturnConsumedCapacity),
+	}
+	if input.ReturnValues == "ALL_OLD" {
+		// The item has been removed from the table so no copy is needed.
+		output.Attributes = existingItem
+	}
+	return output, nil
 }
 
 // https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_UpdateItem.html
 func (d *DynamoDB) UpdateItem(input UpdateItemInput) (*UpdateItemOutput, *awserrors.Error) {
+	switch input.ReturnValues {
+	case "", "NONE", "ALL_OLD", "UPDATED_OLD", "ALL_NEW", "UPDATED_NEW":
+	default:
+		return nil, ValidationException("Invalid ReturnValues: " + input.ReturnValues)
+	}
+	if awserr := validateReturnConsumedCapacity(input.ReturnConsumedCapacity); awserr != nil {
+		return nil, awserr
+	}
+
+	if (input.UpdateExpression != "" || input.ConditionExpression != "") &&
+		(input.AttributeUpdates != nil || input.Expected != nil) {
+		return nil, ValidationException(
+			"Can not use both expression and non-expression parameters in the same request: " +
+				"Non-expression parameters: {AttributeUpdates, Expected} " +
+				"Expression parameters: {UpdateExpression, ConditionExpression}")
+	}
+	wc, awserr := parseWriteCondition(
+		input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues,
+		input.ReturnValuesOnConditionCheckFailure)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	var update *updateExpression
+	if input.UpdateExpression != "" {
+		var err error
+		update, err = parseUpdate(input.UpdateExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+		if err != nil {
+			return nil, ValidationException("Invalid UpdateExpression: " + err.Error())
+		}
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	t, ok := d.tablesByName[input.TableName]
-	if !ok {
-		return nil, awserrors.InvalidArgumentException("Table does not exist")
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	if awserr := t.keySchema.validateKey(input.Key, t.AttributeDefinitions); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := d.lockedCheckThroughput(t, false); awserr != nil {
+		return nil, awserr
 	}
 
-	// TODO: composite keys
-	key := input.Key[t.PrimaryKeyAttributeName].S
-	if key == "" {
-		return nil, awserrors.InvalidArgumentException("PrimaryKey must be provided (and string)")
+	// Work on a copy so a failed update leaves the stored item untouched.
+	oldItem, exists, err := t.items.get(input.Key)
+	if err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+	if awserr := wc.check(oldItem); awserr != nil {
+		return nil, awserr
 	}
-	items := t.ItemsByPrimaryKey[key]
 
-	itemCountIncrease := 0
-	var existingItem map[string]APIAttributeValue
-	if len(items) == 0 {
-		existingItem = make(map[string]APIAttributeValue)
-		itemCountIncrease = 1
-	} else if len(items) == 1 {
-		existingItem = items[0]
-		t.ItemsByPrimaryKey[key] = items[:0]
+	var existingItem APIItem
+	if exists {
+		existingItem = cloneItem(oldItem)
 	} else {
-		return nil, awserrors.XXX_TODO("Multiple items with same primary key")
+		existingItem = cloneItem(input.Key)
 	}
 
-	// Check preconditions
-	for attribute, expectation := range input.Expected {
-		attr, exists := existingItem[attribute]
-		if expectation.Exists != nil {
-			if *expectation.Exists != exists {
-				return nil, awserrors.XXX_TODO("Attribute exists mismatch")
-			}
+	var updatedPaths projection
+	if update != nil {
+		if err := update.apply(existingItem, t.keySchema); err != nil {
+			return nil, ValidationException(err.Error())
 		}
-		switch expectation.ComparisonOperator {
-		case "":
-		case "EQ":
-			if !reflect.DeepEqual(attr, expectation.Value) {
-				return nil, awserrors.XXX_TODO("Attribute EQ mismatch")
+		updatedPaths = update.paths()
+	} else {
+		// Check preconditions
+		for attribute, expectation := range input.Expected {
+			attr, exists := existingItem[attribute]
+			if expectation.Exists != nil {
+				if *expectation.Exists != exists {
+					return nil, ConditionalCheckFailedException("The conditional request failed")
+				}
 			}
-		case "NEQ":
-			if reflect.DeepEqual(attr, expectation.Value) {
-				return nil, awserrors.XXX_TODO("Attribute NEQ mismatch")
+			switch expectation.ComparisonOperator {
+			case "":
+			case "EQ":
+				if !exists || !valuesEqual(attr, expectation.Value) {
+					return nil, ConditionalCheckFailedException("The conditional request failed")
+				}
+			case "NEQ":
+				if exists && valuesEqual(attr, expectation.Value) {
+					return nil, ConditionalCheckFailedException("The conditional request failed")
+				}
+			default:
+				return nil, awserrors.InvalidArgumentException("Invalid expectation comparison operator: " + expectation.ComparisonOperator)
 			}
-		default:
-			return nil, awserrors.InvalidArgumentException("Invalid expectation comparison operator: " + expectation.ComparisonOperator)
 		}
-	}
 
-	// Perform update
-	for attribute, update := range input.AttributeUpdates {
-		switch update.Action {
-		case "PUT":
-			existingItem[attribute] = update.Value
-		case "DELETE":
-			delete(existingItem, attribute)
-		case "ADD":
-			// TODO
-			// fallthrough
-		default:
-			return nil, awserrors.InvalidArgumentException("Invalid update action: " + update.Action)
+		// Perform update
+		for attribute, update := range input.AttributeUpdates {
+			switch update.Action {
+			case "PUT":
+				existingItem[attribute] = update.Value
+			case "DELETE":
+				delete(existingItem, attribute)
+			case "ADD":
+				// TODO
+				// fallthrough
+			default:
+				return nil, awserrors.InvalidArgumentException("Invalid update action: " + update.Action)
+			}
+			updatedPaths = append(updatedPaths, attributePath{{name: attribute}})
 		}
 	}
 
-	t.ItemCount += itemCountIncrease
-	t.ItemsByPrimaryKey[key] = []APIItem{existingItem}
-	return &UpdateItemOutput{}, nil
+	if awserr := t.validateItem(existingItem); awserr != nil {
+		return nil, awserr
+	}
+	capacity := newWriteCapacity()
+	capacity.addWrite(t, oldItem, existingItem)
+	d.lockedConsume(t, capacity)
+	if _, _, err := t.putItem(existingItem); err != nil {
+		return nil, InternalServerError(err.Error())
+	}
+
+	output := &UpdateItemOutput{
+		ConsumedCapacity: capacity.toAPI(t, input.ReturnConsumedCapacity),
+	}
+	switch input.ReturnValues {
+	case "ALL_OLD":
+		if exists {
+			output.Attributes = cloneItem(oldItem)
+		}
+	case "UPDATED_OLD":
+		if exists {
+			output.Attributes = updatedPaths.apply(oldItem)
+		}
+	case "ALL_NEW":
+		output.Attributes = cloneItem(existingItem)
+	case "UPDATED_NEW":
+		output.Attributes = updatedPaths.apply(existingItem)
+	}
+	return output, nil
 }
@@ -0,0 +1,478 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"aws-in-a-box/arn"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+const tableName = "table"
+
+// newDynamoDB creates the service, which only fails if its storage does.
+func newDynamoDB(options Options) *DynamoDB {
+	d, err := New(options)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// newDynamoDBWithTable creates a table with a (pk, sk) primary key holding
+// items for partitions "a" and "b", with sort keys 0-9.
+func newDynamoDBWithTable() *DynamoDB {
+	d := newDynamoDB(Options{ArnGenerator: generator})
+	_, err := d.CreateTable(CreateTableInput{
+		TableName: tableName,
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+			{AttributeName: "sk", AttributeType: "N"},
+		},
+		KeySchema: []APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+			{AttributeName: "sk", KeyType: "RANGE"},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, pk := range []string{"a", "b"} {
+		for i := 0; i < 10; i++ {
+			_, err := d.PutItem(PutItemInput{
+				TableName: tableName,
+				Item: APIItem{
+					"pk":    {S: pk},
+					"sk":    {N: strconv.Itoa(i)},
+					"even":  boolValue(i%2 == 0),
+					"value": {S: pk + strconv.Itoa(i)},
+				},
+			})
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	return d
+}
+
+func sortKeys(items []APIItem) []string {
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, item["sk"].N)
+	}
+	return keys
+}
+
+func TestPutItemReplaces(t *testing.T) {
+	d := newDynamoDBWithTable()
+	_, err := d.PutItem(PutItemInput{
+		TableName: tableName,
+		Item:      APIItem{"pk": {S: "a"}, "sk": {N: "3.0"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := d.DescribeTable(DescribeTableInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Table.ItemCount != 20 {
+		t.Fatal("Wrong item count", output.Table.ItemCount)
+	}
+
+	_, err = d.PutItem(PutItemInput{
+		TableName: tableName,
+		Item:      APIItem{"pk": {S: "a"}},
+	})
+	if err == nil {
+		t.Fatal("Expected missing key error")
+	}
+}
+
+func TestDeleteTable(t *testing.T) {
+	d := newDynamoDBWithTable()
+	output, err := d.DeleteTable(DeleteTableInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.TableDescription.TableStatus != "DELETING" || output.TableDescription.ItemCount != 20 {
+		t.Fatal("Unexpected description", output.TableDescription)
+	}
+
+	_, err = d.DescribeTable(DescribeTableInput{TableName: tableName})
+	if err == nil || err.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected the table to be deleted", err)
+	}
+	_, err = d.DeleteTable(DeleteTableInput{TableName: tableName})
+	if err == nil || err.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected the table to not be found", err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	output, err := d.Query(QueryInput{
+		TableName:              tableName,
+		KeyConditionExpression: "pk = :pk AND sk BETWEEN :low AND :high",
+		FilterExpression:       "#even = :true",
+		ExpressionAttributeNames: map[string]string{
+			"#even": "even",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":pk":   {S: "b"},
+			":low":  {N: "3"},
+			":high": {N: "8"},
+			":true": boolValue(true),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.ScannedCount != 6 || output.Count != 3 {
+		t.Fatal("Wrong counts", output.ScannedCount, output.Count)
+	}
+	if keys := sortKeys(output.Items); keys[0] != "4" || keys[1] != "6" || keys[2] != "8" {
+		t.Fatal("Wrong items", keys)
+	}
+	if output.Items[0]["value"].S != "b4" {
+		t.Fatal("Wrong partition")
+	}
+}
+
+func TestQueryPaging(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	backwards := false
+	input := QueryInput{
+		TableName:              tableName,
+		KeyConditionExpression: "pk = :pk AND sk < :high",
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":pk":   {S: "a"},
+			":high": {N: "7"},
+		},
+		ProjectionExpression: "sk",
+		ScanIndexForward:     &backwards,
+		Limit:                3,
+	}
+
+	var pages [][]string
+	for {
+		output, err := d.Query(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, sortKeys(output.Items))
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	if len(pages) != 3 {
+		t.Fatal("Wrong page count", pages)
+	}
+	if pages[0][0] != "6" || pages[1][0] != "3" || pages[2][0] != "0" || len(pages[2]) != 1 {
+		t.Fatal("Wrong pages", pages)
+	}
+}
+
+func TestQueryValidation(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	values := map[string]APIAttributeValue{
+		":pk": {S: "a"},
+		":sk": {N: "1"},
+	}
+	for _, expression := range []string{
+		"",
+		"sk = :sk",
+		"pk < :pk",
+		"pk = :pk OR sk = :sk",
+		"pk = :pk AND sk <> :sk",
+		"pk = :pk AND value = :sk",
+		"pk = :sk",
+	} {
+		_, err := d.Query(QueryInput{
+			TableName:                 tableName,
+			KeyConditionExpression:    expression,
+			ExpressionAttributeValues: values,
+		})
+		if err == nil {
+			t.Fatal("Expected error for", expression)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	output, err := d.Scan(ScanInput{
+		TableName: tableName,
+		Select:    "COUNT",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Count != 20 || output.Items != nil {
+		t.Fatal("Wrong count output")
+	}
+
+	// Parallel segments should partition the table.
+	seen := make(map[string]bool)
+	for segment := 0; segment < 3; segment++ {
+		input := ScanInput{
+			TableName:     tableName,
+			Segment:       &segment,
+			TotalSegments: intPtr(3),
+			Limit:         4,
+		}
+		for {
+			output, err := d.Scan(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, item := range output.Items {
+				if seen[item["value"].S] {
+					t.Fatal("Duplicate item", item["value"].S)
+				}
+				seen[item["value"].S] = true
+			}
+			if output.LastEvaluatedKey == nil {
+				break
+			}
+			input.ExclusiveStartKey = output.LastEvaluatedKey
+		}
+	}
+	if len(seen) != 20 {
+		t.Fatal("Missing items", len(seen))
+	}
+
+	_, err = d.Scan(ScanInput{
+		TableName:     tableName,
+		TotalSegments: intPtr(3),
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing segment")
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestAttributeValueJSON(t *testing.T) {
+	item := APIItem{
+		"bool":  boolValue(false),
+		"empty": {S: ""},
+		"list":  {L: []APIAttributeValue{}},
+		"null":  {NULL: true},
+		"num":   {N: "1.5"},
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"bool":{"BOOL":false},"empty":{"S":""},"list":{"L":[]},"null":{"NULL":true},"num":{"N":"1.5"}}`
+	if string(data) != expected {
+		t.Fatal("Wrong JSON", string(data))
+	}
+
+	var roundTripped APIItem
+	err = json.Unmarshal(data, &roundTripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(item, roundTripped) {
+		t.Fatal("Round trip mismatch", roundTripped)
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	input := UpdateItemInput{
+		TableName:        tableName,
+		Key:              APIItem{"pk": {S: "c"}, "sk": {N: "0"}},
+		UpdateExpression: "ADD counter :one SET #v = :v",
+		ExpressionAttributeNames: map[string]string{
+			"#v": "value",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":one": {N: "1"},
+			":v":   {S: "c0"},
+		},
+		ReturnValues: "UPDATED_NEW",
+	}
+	output, err := d.UpdateItem(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := APIItem{"counter": {N: "1"}, "value": {S: "c0"}}
+	if !reflect.DeepEqual(output.Attributes, expected) {
+		t.Fatal("Wrong attributes", output.Attributes)
+	}
+
+	input.ReturnValues = "ALL_OLD"
+	output, err = d.UpdateItem(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = APIItem{"pk": {S: "c"}, "sk": {N: "0"}, "counter": {N: "1"}, "value": {S: "c0"}}
+	if !reflect.DeepEqual(output.Attributes, expected) {
+		t.Fatal("Wrong attributes", output.Attributes)
+	}
+
+	scan, err := d.Scan(ScanInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Count != 21 {
+		t.Fatal("Wrong item count", scan.Count)
+	}
+
+	// A failed update must leave the item untouched.
+	input.UpdateExpression = "SET counter = :one, other = missing"
+	_, err = d.UpdateItem(input)
+	if err == nil {
+		t.Fatal("Expected error for missing attribute")
+	}
+	input.UpdateExpression = "REMOVE other"
+	input.ReturnValues = "ALL_NEW"
+	output, err = d.UpdateItem(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Attributes["counter"].N != "2" {
+		t.Fatal("Wrong counter", output.Attributes)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	d := newDynamoDBWithTable()
+
+	// Optimistic locking: only update if the version matches.
+	update := UpdateItemInput{
+		TableName:           tableName,
+		Key:                 APIItem{"pk": {S: "a"}, "sk": {N: "1"}},
+		UpdateExpression:    "SET version = :new",
+		ConditionExpression: "attribute_not_exists(version) OR version = :old",
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":old": {N: "0"},
+			":new": {N: "1"},
+		},
+		ReturnValuesOnConditionCheckFailure: "ALL_OLD",
+	}
+	_, err := d.UpdateItem(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.UpdateItem(update)
+	if err == nil || err.Body.Type != "ConditionalCheckFailedException" {
+		t.Fatal("Expected conditional check failure", err)
+	}
+	if item, ok := err.Body.Item.(APIItem); !ok || item["version"].N != "1" {
+		t.Fatal("Wrong item in error", err.Body.Item)
+	}
+
+	// Conditional creates.
+	put := PutItemInput{
+		TableName:           tableName,
+		Item:                APIItem{"pk": {S: "a"}, "sk": {N: "1"}},
+		ConditionExpression: "attribute_not_exists(pk)",
+	}
+	_, err = d.PutItem(put)
+	if err == nil || err.Body.Item != nil {
+		t.Fatal("Expected conditional check failure without item", err)
+	}
+	put.Item = APIItem{"pk": {S: "a"}, "sk": {N: "100"}}
+	_, err = d.PutItem(put)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	del := DeleteItemInput{
+		TableName:           tableName,
+		Key:                 APIItem{"pk": {S: "a"}, "sk": {N: "2"}},
+		ConditionExpression: "size(#v) > :len AND even = :even",
+		ExpressionAttributeNames: map[string]string{
+			"#v": "value",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":len":  {N: "1"},
+			":even": boolValue(false),
+		},
+		ReturnValues: "ALL_OLD",
+	}
+	_, err = d.DeleteItem(del)
+	if err == nil {
+		t.Fatal("Expected conditional check failure")
+	}
+	del.ExpressionAttributeValues[":even"] = boolValue(true)
+	output, err := d.DeleteItem(del)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Attributes["value"].S != "a2" {
+		t.Fatal("Wrong old item", output.Attributes)
+	}
+
+	scan, err := d.Scan(ScanInput{TableName: tableName, Select: "COUNT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Count != 20 {
+		t.Fatal("Wrong item count", scan.Count)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	d := newDynamoDBWithTable()
+	if _, err := d.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := d.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.DescribeTable(DescribeTableInput{TableName: tableName}); awserr == nil {
+		t.Fatal("Expected the table not to be in eu-west-1")
+	}
+
+	// A table of the same name in another region is another table, with that region's ARNs.
+	output, awserr := europe.CreateTable(CreateTableInput{
+		TableName:            tableName,
+		AttributeDefinitions: []APIAttributeDefinition{{AttributeName: "pk", AttributeType: "S"}},
+		KeySchema:            []APIKeySchemaElement{{AttributeName: "pk", KeyType: "HASH"}},
+		StreamSpecification:  &APIStreamSpecification{StreamEnabled: true, StreamViewType: "KEYS_ONLY"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	description := output.TableDescription
+	if description.TableARN != "arn:aws:dynamodb:eu-west-1:123456789012:table/"+tableName {
+		t.Fatal("Unexpected table ARN", description.TableARN)
+	}
+	if r, _ := d.ForARN(description.LatestStreamArn); r != europe {
+		t.Fatal("Expected eu-west-1's stream ARN to choose its tables", description.LatestStreamArn)
+	}
+	if _, awserr := d.DescribeStream(DescribeStreamInput{StreamArn: description.LatestStreamArn}); awserr == nil {
+		t.Fatal("Expected eu-west-1's stream not to be in us-east-1")
+	}
+	scan, awserr := d.Scan(ScanInput{TableName: tableName})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(scan.Items) != 20 {
+		t.Fatal("Expected the default region's table to be left as it was", len(scan.Items))
+	}
+}
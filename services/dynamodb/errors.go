@@ -0,0 +1,26 @@
+package dynamodb
+
+import "aws-in-a-box/awserrors"
+
+func ValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationException", message)
+}
+
+func ConditionalCheckFailedException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ConditionalCheckFailedException", message)
+}
+
+func ProvisionedThroughputExceededException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ProvisionedThroughputExceededException", message)
+}
+
+// InternalServerError is returned when reading or writing the items in storage fails.
+func InternalServerError(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 500,
+		Body: awserrors.ErrorBody{
+			Type:    "InternalServerError",
+			Message: message,
+		},
+	}
+}
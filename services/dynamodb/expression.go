@@ -0,0 +1,830 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This file implements the expression language shared by KeyConditionExpression,
+// ConditionExpression, FilterExpression and ProjectionExpression.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.html
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// Attribute names, keywords and function names
+	tokenIdentifier
+	// #name placeholders
+	tokenName
+	// :value placeholders
+	tokenValue
+	// List indexes
+	tokenNumber
+	tokenPunctuation
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' || r == ':':
+			start := i
+			i++
+			for i < len(runes) && isIdentifierRune(runes[i]) {
+				i++
+			}
+			if i == start+1 {
+				return nil, fmt.Errorf("Syntax error; token: \"%c\", near: \"%s\"", r, string(runes[start:]))
+			}
+			kind := tokenName
+			if r == ':' {
+				kind = tokenValue
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[start:i])})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i])})
+		case isIdentifierRune(r):
+			start := i
+			for i < len(runes) && isIdentifierRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: string(runes[start:i])})
+		case r == '<' || r == '>':
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				tokens = append(tokens, token{kind: tokenPunctuation, text: string(runes[i : i+2])})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenPunctuation, text: string(r)})
+				i++
+			}
+		case strings.ContainsRune("()[],.=+-", r):
+			tokens = append(tokens, token{kind: tokenPunctuation, text: string(r)})
+			i++
+		default:
+			return nil, fmt.Errorf("Invalid character encountered; character: \"%c\"", r)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type pathElement struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+type attributePath []pathElement
+
+func (p attributePath) String() string {
+	var sb strings.Builder
+	for i, elem := range p {
+		if elem.isIndex {
+			sb.WriteString("[" + strconv.Itoa(elem.index) + "]")
+		} else {
+			if i > 0 {
+				sb.WriteRune('.')
+			}
+			sb.WriteString(elem.name)
+		}
+	}
+	return sb.String()
+}
+
+// isTopLevel reports whether the path refers to a top-level attribute called name.
+func (p attributePath) isTopLevel(name string) bool {
+	return len(p) == 1 && !p[0].isIndex && p[0].name == name
+}
+
+func (p attributePath) resolve(item APIItem) (APIAttributeValue, bool) {
+	current := APIAttributeValue{M: item}
+	for _, elem := range p {
+		if elem.isIndex {
+			if current.Type() != "L" || elem.index >= len(current.L) {
+				return APIAttributeValue{}, false
+			}
+			current = current.L[elem.index]
+		} else {
+			if current.Type() != "M" {
+				return APIAttributeValue{}, false
+			}
+			v, ok := current.M[elem.name]
+			if !ok {
+				return APIAttributeValue{}, false
+			}
+			current = v
+		}
+	}
+	return current, true
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+
+	names  map[string]string
+	values map[string]APIAttributeValue
+}
+
+func newParser(
+	expression string,
+	names map[string]string,
+	values map[string]APIAttributeValue,
+) (*parser, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, names: names, values: values}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return token{kind: tokenEOF}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenIdentifier && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) peekPunctuation(text string) bool {
+	t := p.peek()
+	return t.kind == tokenPunctuation && t.text == text
+}
+
+func (p *parser) syntaxError(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("Syntax error; token: <EOF>, near: end of expression")
+	}
+	return fmt.Errorf("Syntax error; token: \"%s\"", t.text)
+}
+
+func (p *parser) expectPunctuation(text string) error {
+	t := p.next()
+	if t.kind != tokenPunctuation || t.text != text {
+		return p.syntaxError(t)
+	}
+	return nil
+}
+
+func (p *parser) expectEOF() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return p.syntaxError(t)
+	}
+	return nil
+}
+
+func (p *parser) resolveName(t token) (string, error) {
+	switch t.kind {
+	case tokenIdentifier:
+		return t.text, nil
+	case tokenName:
+		name, ok := p.names[t.text]
+		if !ok {
+			return "", fmt.Errorf("An expression attribute name used in the document path is not defined; attribute name: %s", t.text)
+		}
+		return name, nil
+	}
+	return "", p.syntaxError(t)
+}
+
+func (p *parser) resolveValue(t token) (APIAttributeValue, error) {
+	v, ok := p.values[t.text]
+	if !ok {
+		return APIAttributeValue{}, fmt.Errorf("An expression attribute value used in expression is not defined; attribute value: %s", t.text)
+	}
+	return v, nil
+}
+
+func (p *parser) parsePath() (attributePath, error) {
+	name, err := p.resolveName(p.next())
+	if err != nil {
+		return nil, err
+	}
+	path := attributePath{{name: name}}
+	for {
+		if p.peekPunctuation(".") {
+			p.next()
+			name, err := p.resolveName(p.next())
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, pathElement{name: name})
+		} else if p.peekPunctuation("[") {
+			p.next()
+			t := p.next()
+			if t.kind != tokenNumber {
+				return nil, p.syntaxError(t)
+			}
+			index, err := strconv.Atoi(t.text)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunctuation("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, pathElement{index: index, isIndex: true})
+		} else {
+			return path, nil
+		}
+	}
+}
+
+// Operands
+
+type operand interface {
+	evaluate(item APIItem) (APIAttributeValue, bool)
+}
+
+type literalOperand struct {
+	value APIAttributeValue
+}
+
+func (o literalOperand) evaluate(item APIItem) (APIAttributeValue, bool) {
+	return o.value, true
+}
+
+type pathOperand struct {
+	path attributePath
+}
+
+func (o pathOperand) evaluate(item APIItem) (APIAttributeValue, bool) {
+	return o.path.resolve(item)
+}
+
+type sizeOperand struct {
+	path attributePath
+}
+
+func (o sizeOperand) evaluate(item APIItem) (APIAttributeValue, bool) {
+	v, ok := o.path.resolve(item)
+	if !ok {
+		return APIAttributeValue{}, false
+	}
+	var size int
+	switch v.Type() {
+	case "S":
+		size = len([]rune(v.S))
+	case "B":
+		size = len(decodeBinary(v.B))
+	case "SS", "NS", "BS":
+		size = len(setMembers(v))
+	case "L":
+		size = len(v.L)
+	case "M":
+		size = len(v.M)
+	default:
+		return APIAttributeValue{}, false
+	}
+	return APIAttributeValue{N: strconv.Itoa(size)}, true
+}
+
+func (p *parser) isFunctionCall(name string) bool {
+	return p.peekKeyword(name) && p.peekAt(1).kind == tokenPunctuation && p.peekAt(1).text == "("
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenValue:
+		p.next()
+		v, err := p.resolveValue(t)
+		if err != nil {
+			return nil, err
+		}
+		return literalOperand{value: v}, nil
+	case p.isFunctionCall("size"):
+		p.next()
+		p.next()
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunctuation(")"); err != nil {
+			return nil, err
+		}
+		return sizeOperand{path: path}, nil
+	case t.kind == tokenIdentifier || t.kind == tokenName:
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{path: path}, nil
+	}
+	return nil, p.syntaxError(t)
+}
+
+// Conditions
+
+type condition interface {
+	evaluate(item APIItem) bool
+}
+
+type comparisonCondition struct {
+	operator    string
+	left, right operand
+}
+
+func (c comparisonCondition) evaluate(item APIItem) bool {
+	left, leftOk := c.left.evaluate(item)
+	right, rightOk := c.right.evaluate(item)
+	if !leftOk || !rightOk {
+		// A missing attribute is never equal to anything.
+		return c.operator == "<>"
+	}
+
+	switch c.operator {
+	case "=":
+		return valuesEqual(left, right)
+	case "<>":
+		return !valuesEqual(left, right)
+	}
+
+	cmp, ok := compareValues(left, right)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+type betweenCondition struct {
+	operand   operand
+	low, high operand
+}
+
+func (c betweenCondition) evaluate(item APIItem) bool {
+	v, ok1 := c.operand.evaluate(item)
+	low, ok2 := c.low.evaluate(item)
+	high, ok3 := c.high.evaluate(item)
+	if !ok1 || !ok2 || !ok3 {
+		return false
+	}
+	cmpLow, ok1 := compareValues(v, low)
+	cmpHigh, ok2 := compareValues(v, high)
+	return ok1 && ok2 && cmpLow >= 0 && cmpHigh <= 0
+}
+
+type inCondition struct {
+	operand    operand
+	candidates []operand
+}
+
+func (c inCondition) evaluate(item APIItem) bool {
+	v, ok := c.operand.evaluate(item)
+	if !ok {
+		return false
+	}
+	for _, candidate := range c.candidates {
+		cv, ok := candidate.evaluate(item)
+		if ok && valuesEqual(v, cv) {
+			return true
+		}
+	}
+	return false
+}
+
+type functionCondition struct {
+	name string
+	args []operand
+}
+
+func (c functionCondition) evaluate(item APIItem) bool {
+	first, firstOk := c.args[0].evaluate(item)
+	switch c.name {
+	case "attribute_exists":
+		return firstOk
+	case "attribute_not_exists":
+		return !firstOk
+	}
+
+	second, secondOk := c.args[1].evaluate(item)
+	if !firstOk || !secondOk {
+		return false
+	}
+	switch c.name {
+	case "attribute_type":
+		return second.Type() == "S" && first.Type() == second.S
+	case "begins_with":
+		switch {
+		case first.Type() == "S" && second.Type() == "S":
+			return strings.HasPrefix(first.S, second.S)
+		case first.Type() == "B" && second.Type() == "B":
+			return strings.HasPrefix(string(decodeBinary(first.B)), string(decodeBinary(second.B)))
+		}
+	case "contains":
+		switch first.Type() {
+		case "S":
+			return second.Type() == "S" && strings.Contains(first.S, second.S)
+		case "B":
+			return second.Type() == "B" && strings.Contains(string(decodeBinary(first.B)), string(decodeBinary(second.B)))
+		case "SS", "NS", "BS":
+			for _, member := range setMembers(first) {
+				if valuesEqual(member, second) {
+					return true
+				}
+			}
+		case "L":
+			for _, elem := range first.L {
+				if valuesEqual(elem, second) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+type andCondition struct {
+	left, right condition
+}
+
+func (c andCondition) evaluate(item APIItem) bool {
+	return c.left.evaluate(item) && c.right.evaluate(item)
+}
+
+type orCondition struct {
+	left, right condition
+}
+
+func (c orCondition) evaluate(item APIItem) bool {
+	return c.left.evaluate(item) || c.right.evaluate(item)
+}
+
+type notCondition struct {
+	condition condition
+}
+
+func (c notCondition) evaluate(item APIItem) bool {
+	return !c.condition.evaluate(item)
+}
+
+var comparators = map[string]bool{
+	"=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+var validAttributeTypes = map[string]bool{
+	"S": true, "SS": true, "N": true, "NS": true, "B": true, "BS": true,
+	"BOOL": true, "NULL": true, "L": true, "M": true,
+}
+
+// The number of arguments each condition function takes.
+var conditionFunctions = map[string]int{
+	"attribute_exists":     1,
+	"attribute_not_exists": 1,
+	"attribute_type":       2,
+	"begins_with":          2,
+	"contains":             2,
+}
+
+func parseCondition(
+	expression string,
+	names map[string]string,
+	values map[string]APIAttributeValue,
+) (condition, error) {
+	p, err := newParser(expression, names, values)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEOF(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.peekKeyword("NOT") {
+		p.next()
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{condition: c}, nil
+	}
+	return p.parsePrimaryCondition()
+}
+
+func (p *parser) parsePrimaryCondition() (condition, error) {
+	if p.peekPunctuation("(") {
+		p.next()
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunctuation(")"); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	for name, argCount := range conditionFunctions {
+		if p.isFunctionCall(name) {
+			return p.parseFunctionCondition(name, argCount)
+		}
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokenPunctuation && comparators[t.text]:
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonCondition{operator: t.text, left: left, right: right}, nil
+	case p.peekKeyword("BETWEEN"):
+		p.next()
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekKeyword("AND") {
+			return nil, p.syntaxError(p.peek())
+		}
+		p.next()
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{operand: left, low: low, high: high}, nil
+	case p.peekKeyword("IN"):
+		p.next()
+		if err := p.expectPunctuation("("); err != nil {
+			return nil, err
+		}
+		var candidates []operand
+		for {
+			candidate, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, candidate)
+			if !p.peekPunctuation(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expectPunctuation(")"); err != nil {
+			return nil, err
+		}
+		if len(candidates) > 100 {
+			return nil, fmt.Errorf("The IN operator is provided with too many operands; number of operands: %d", len(candidates))
+		}
+		return inCondition{operand: left, candidates: candidates}, nil
+	}
+	return nil, p.syntaxError(t)
+}
+
+func (p *parser) parseFunctionCondition(name string, argCount int) (condition, error) {
+	p.next()
+	p.next()
+
+	var args []operand
+	for {
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.peekPunctuation(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectPunctuation(")"); err != nil {
+		return nil, err
+	}
+
+	if len(args) != argCount {
+		return nil, fmt.Errorf("Incorrect number of operands for operator or function; operator or function: %s, number of operands: %d", name, len(args))
+	}
+	if _, ok := args[0].(pathOperand); !ok && name != "begins_with" && name != "contains" {
+		return nil, fmt.Errorf("Operator or function requires a document path; operator or function: %s", name)
+	}
+	if name == "attribute_type" {
+		typ, ok := args[1].(literalOperand)
+		if !ok || typ.value.Type() != "S" || !validAttributeTypes[typ.value.S] {
+			return nil, fmt.Errorf("Invalid attribute type name found; type: %v, valid types: { B, NULL, SS, BOOL, L, BS, N, NS, S, M }", typ.value.S)
+		}
+	}
+
+	return functionCondition{name: name, args: args}, nil
+}
+
+// Projections
+
+type projection []attributePath
+
+var errOverlappingPaths = errors.New("Two document paths overlap with each other; must remove or rewrite one of these paths")
+
+func parseProjection(expression string, names map[string]string) (projection, error) {
+	p, err := newParser(expression, names, nil)
+	if err != nil {
+		return nil, err
+	}
+	var paths projection
+	for {
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		if !p.peekPunctuation(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectEOF(); err != nil {
+		return nil, err
+	}
+
+	// Validate that no two paths overlap.
+	if _, err := paths.tree(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+type projectionNode struct {
+	whole   bool
+	fields  map[string]*projectionNode
+	indexes map[int]*projectionNode
+}
+
+func (p projection) tree() (*projectionNode, error) {
+	root := &projectionNode{}
+	for _, path := range p {
+		node := root
+		for _, elem := range path {
+			if node.whole {
+				return nil, errOverlappingPaths
+			}
+			var child *projectionNode
+			if elem.isIndex {
+				if node.indexes == nil {
+					node.indexes = make(map[int]*projectionNode)
+				}
+				child = node.indexes[elem.index]
+				if child == nil {
+					child = &projectionNode{}
+					node.indexes[elem.index] = child
+				}
+			} else {
+				if node.fields == nil {
+					node.fields = make(map[string]*projectionNode)
+				}
+				child = node.fields[elem.name]
+				if child == nil {
+					child = &projectionNode{}
+					node.fields[elem.name] = child
+				}
+			}
+			node = child
+		}
+		if node.whole || len(node.fields) > 0 || len(node.indexes) > 0 {
+			return nil, errOverlappingPaths
+		}
+		node.whole = true
+	}
+	return root, nil
+}
+
+func (p projection) apply(item APIItem) APIItem {
+	root, err := p.tree()
+	if err != nil {
+		// Validated at parse time.
+		panic(err)
+	}
+	projected, ok := root.apply(APIAttributeValue{M: item})
+	if !ok {
+		return APIItem{}
+	}
+	return projected.M
+}
+
+func (n *projectionNode) apply(v APIAttributeValue) (APIAttributeValue, bool) {
+	if n.whole {
+		return cloneValue(v), true
+	}
+
+	switch v.Type() {
+	case "M":
+		if len(n.fields) == 0 {
+			return APIAttributeValue{}, false
+		}
+		m := make(APIItem)
+		for name, child := range n.fields {
+			if elem, ok := v.M[name]; ok {
+				if projected, ok := child.apply(elem); ok {
+					m[name] = projected
+				}
+			}
+		}
+		if len(m) == 0 {
+			return APIAttributeValue{}, false
+		}
+		return APIAttributeValue{M: m}, true
+	case "L":
+		if len(n.indexes) == 0 {
+			return APIAttributeValue{}, false
+		}
+		var l []APIAttributeValue
+		for i, elem := range v.L {
+			if child, ok := n.indexes[i]; ok {
+				if projected, ok := child.apply(elem); ok {
+					l = append(l, projected)
+				}
+			}
+		}
+		if len(l) == 0 {
+			return APIAttributeValue{}, false
+		}
+		return APIAttributeValue{L: l}, true
+	}
+	return APIAttributeValue{}, false
+}
@@ -0,0 +1,98 @@
+package dynamodb
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testItem = APIItem{
+	"pk":     {S: "partition"},
+	"count":  {N: "10"},
+	"name":   {S: "Gerald"},
+	"tags":   {SS: []string{"a", "b"}},
+	"nested": {M: map[string]APIAttributeValue{"list": {L: []APIAttributeValue{{N: "1"}, {S: "two"}}}}},
+}
+
+func TestConditionEvaluation(t *testing.T) {
+	names := map[string]string{"#n": "name", "#c": "count"}
+	values := map[string]APIAttributeValue{
+		":ten":    {N: "10.0"},
+		":five":   {N: "5"},
+		":prefix": {S: "Ger"},
+		":a":      {S: "a"},
+		":two":    {S: "two"},
+		":type":   {S: "SS"},
+	}
+
+	cases := map[string]bool{
+		"#c = :ten":                              true,
+		"#c <> :ten":                             false,
+		"#c > :five":                             true,
+		"#c BETWEEN :five AND :ten":              true,
+		"#c IN (:five, :ten)":                    true,
+		"begins_with(#n, :prefix)":               true,
+		"contains(tags, :a)":                     true,
+		"contains(nested.list, :two)":            true,
+		"nested.list[1] = :two":                  true,
+		"attribute_exists(nested.list[0])":       true,
+		"attribute_not_exists(missing)":          true,
+		"attribute_type(tags, :type)":            true,
+		"size(tags) < :five":                     true,
+		"missing <> :five":                       true,
+		"missing = :five":                        false,
+		"NOT #c = :ten OR #c = :five":            false,
+		"NOT (#c = :ten AND #c = :five)":         true,
+		"#c > :five and begins_with(#n, :a)":     false,
+		"(#c > :five) AND (begins_with(#n, :a))": false,
+	}
+
+	for expression, expected := range cases {
+		c, err := parseCondition(expression, names, values)
+		if err != nil {
+			t.Fatal(expression, err)
+		}
+		if c.evaluate(testItem) != expected {
+			t.Fatal("Wrong result for", expression)
+		}
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	values := map[string]APIAttributeValue{":v": {S: "v"}}
+	for _, expression := range []string{
+		"a = :missing",
+		"#missing = :v",
+		"a = ",
+		"a BETWEEN :v",
+		"attribute_exists(a, :v)",
+		"attribute_type(a, :v)",
+		"a = :v b = :v",
+		"a ! :v",
+	} {
+		_, err := parseCondition(expression, nil, values)
+		if err == nil {
+			t.Fatal("Expected error for", expression)
+		}
+	}
+}
+
+func TestProjection(t *testing.T) {
+	p, err := parseProjection("pk, #n, nested.list[1], missing", map[string]string{"#n": "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := APIItem{
+		"pk":     {S: "partition"},
+		"name":   {S: "Gerald"},
+		"nested": {M: map[string]APIAttributeValue{"list": {L: []APIAttributeValue{{S: "two"}}}}},
+	}
+	if !reflect.DeepEqual(p.apply(testItem), expected) {
+		t.Fatal("Wrong projection", p.apply(testItem))
+	}
+
+	_, err = parseProjection("nested, nested.list", nil)
+	if err == nil {
+		t.Fatal("Expected overlapping paths error")
+	}
+}
@@ -4,14 +4,31 @@ import (
 	"log/slog"
 
 	"aws-in-a-box/http"
+	"aws-in-a-box/region"
 )
 
-const service = "DynamoDB_20120810"
+const (
+	service        = "DynamoDB_20120810"
+	streamsService = "DynamoDBStreams_20120810"
+)
 
 func (d *DynamoDB) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
-	http.Register(logger, methodRegistry, service, "CreateTable", d.CreateTable)
-	http.Register(logger, methodRegistry, service, "DescribeTable", d.DescribeTable)
-	http.Register(logger, methodRegistry, service, "PutItem", d.PutItem)
-	http.Register(logger, methodRegistry, service, "Scan", d.Scan)
-	http.Register(logger, methodRegistry, service, "UpdateItem", d.UpdateItem)
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchGetItem", region.Method(d.regions, (*DynamoDB).BatchGetItem))
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchWriteItem", region.Method(d.regions, (*DynamoDB).BatchWriteItem))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateTable", region.Method(d.regions, (*DynamoDB).CreateTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteItem", region.Method(d.regions, (*DynamoDB).DeleteItem))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteTable", region.Method(d.regions, (*DynamoDB).DeleteTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeTable", region.Method(d.regions, (*DynamoDB).DescribeTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeTimeToLive", region.Method(d.regions, (*DynamoDB).DescribeTimeToLive))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutItem", region.Method(d.regions, (*DynamoDB).PutItem))
+	http.RegisterWithRequest(logger, methodRegistry, service, "Query", region.Method(d.regions, (*DynamoDB).Query))
+	http.RegisterWithRequest(logger, methodRegistry, service, "Scan", region.Method(d.regions, (*DynamoDB).Scan))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateItem", region.Method(d.regions, (*DynamoDB).UpdateItem))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateTable", region.Method(d.regions, (*DynamoDB).UpdateTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateTimeToLive", region.Method(d.regions, (*DynamoDB).UpdateTimeToLive))
+
+	http.RegisterWithRequest(logger, methodRegistry, streamsService, "DescribeStream", region.Method(d.regions, (*DynamoDB).DescribeStream))
+	http.RegisterWithRequest(logger, methodRegistry, streamsService, "GetRecords", region.Method(d.regions, (*DynamoDB).GetRecords))
+	http.RegisterWithRequest(logger, methodRegistry, streamsService, "GetShardIterator", region.Method(d.regions, (*DynamoDB).GetShardIterator))
+	http.RegisterWithRequest(logger, methodRegistry, streamsService, "ListStreams", region.Method(d.regions, (*DynamoDB).ListStreams))
 }
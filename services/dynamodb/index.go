@@ -0,0 +1,217 @@
+package dynamodb
+
+import (
+	"fmt"
+	"slices"
+
+	"aws-in-a-box/awserrors"
+)
+
+const (
+	maxGlobalSecondaryIndexes = 20
+	maxLocalSecondaryIndexes  = 5
+	maxNonKeyAttributes       = 100
+)
+
+type secondaryIndex struct {
+	Name       string
+	ARN        string
+	Global     bool
+	KeySchema  []APIKeySchemaElement
+	Projection APIProjection
+	// Only set for global indexes of PROVISIONED tables.
+	ProvisionedThroughput *APIProvisionedThroughput
+
+	keySchema keySchema
+	items     *itemCollection
+}
+
+func newSecondaryIndex(
+	t *Table,
+	name string,
+	elements []APIKeySchemaElement,
+	projection APIProjection,
+	throughput *APIProvisionedThroughput,
+	global bool,
+) (*secondaryIndex, *awserrors.Error) {
+	if len(name) < 3 || len(name) > 255 {
+		return nil, ValidationException("IndexName must be between 3 and 255 characters long")
+	}
+	if t.lockedGetIndex(name) != nil {
+		return nil, ValidationException("One or more parameter values were invalid: Duplicate index name: " + name)
+	}
+
+	schema, awserr := newKeySchema(elements, t.AttributeDefinitions)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if global {
+		if t.BillingMode == "PROVISIONED" {
+			if awserr := validateThroughput(throughput); awserr != nil {
+				return nil, awserr
+			}
+		} else if throughput != nil {
+			return nil, ValidationException(
+				"One or more parameter values were invalid: ProvisionedThroughput should not be specified for index: " + name + " when BillingMode is PAY_PER_REQUEST")
+		}
+	} else {
+		if t.keySchema.SortKey == "" {
+			return nil, ValidationException(
+				"One or more parameter values were invalid: Table KeySchema does not have a range key, which is required when specifying a LocalSecondaryIndex")
+		}
+		if schema.PartitionKey != t.keySchema.PartitionKey || schema.SortKey == "" {
+			return nil, ValidationException(
+				"One or more parameter values were invalid: Index KeySchema does not have the same leading hash key as table KeySchema for index: " + name)
+		}
+	}
+
+	switch projection.ProjectionType {
+	case "ALL", "KEYS_ONLY":
+		if len(projection.NonKeyAttributes) > 0 {
+			return nil, ValidationException(
+				"One or more parameter values were invalid: ProjectionType is " + projection.ProjectionType +
+					", but NonKeyAttributes is specified")
+		}
+	case "INCLUDE":
+		if len(projection.NonKeyAttributes) == 0 {
+			return nil, ValidationException(
+				"One or more parameter values were invalid: NonKeyAttributes must not be empty when ProjectionType is INCLUDE")
+		}
+		if len(projection.NonKeyAttributes) > maxNonKeyAttributes {
+			return nil, ValidationException(fmt.Sprintf(
+				"One or more parameter values were invalid: Too many NonKeyAttributes, the limit is %d", maxNonKeyAttributes))
+		}
+	default:
+		return nil, ValidationException("Unknown ProjectionType: " + projection.ProjectionType)
+	}
+
+	return &secondaryIndex{
+		Name:                  name,
+		ARN:                   t.ARN + "/index/" + name,
+		Global:                global,
+		KeySchema:             elements,
+		Projection:            projection,
+		ProvisionedThroughput: throughput,
+		keySchema:             schema,
+		items:                 newIndexItemCollection(schema, t.keySchema),
+	}, nil
+}
+
+// project returns the copy of the item stored in the index.
+func (i *secondaryIndex) project(item APIItem) APIItem {
+	if i.Projection.ProjectionType == "ALL" {
+		return cloneItem(item)
+	}
+
+	projected := make(APIItem)
+	for _, name := range i.items.keyNames() {
+		projected[name] = cloneValue(item[name])
+	}
+	for _, name := range i.Projection.NonKeyAttributes {
+		if v, ok := item[name]; ok {
+			projected[name] = cloneValue(v)
+		}
+	}
+	return projected
+}
+
+// hasAllAttributes returns whether the index stores every attribute of the table items.
+func (i *secondaryIndex) hasAllAttributes() bool {
+	return i.Projection.ProjectionType == "ALL"
+}
+
+func (i *secondaryIndex) add(item APIItem) error {
+	if !i.items.hasKey(item) {
+		return nil
+	}
+	_, _, err := i.items.put(i.project(item))
+	return err
+}
+
+func (i *secondaryIndex) remove(item APIItem) error {
+	if !i.items.hasKey(item) {
+		return nil
+	}
+	_, _, err := i.items.delete(item)
+	return err
+}
+
+// validateItem checks the types of the index key attributes of an item being written.
+func (i *secondaryIndex) validateItem(item APIItem, definitions []APIAttributeDefinition) *awserrors.Error {
+	for _, name := range i.keySchema.names() {
+		v, ok := item[name]
+		if !ok {
+			continue
+		}
+		for _, definition := range definitions {
+			if definition.AttributeName == name && definition.AttributeType != v.Type() {
+				return ValidationException(fmt.Sprintf(
+					"One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s IndexName: %s",
+					name, definition.AttributeType, v.Type(), i.Name))
+			}
+		}
+		if v.Type() != "N" && keyString(v) == "" {
+			return ValidationException(fmt.Sprintf(
+				"One or more parameter values are not valid. A value specified for a secondary index key is not supported. The AttributeValue for a key attribute cannot contain an empty string value. IndexName: %s, IndexKey: %s",
+				i.Name, name))
+		}
+	}
+	return nil
+}
+
+func (i *secondaryIndex) toGlobalAPI() APIGlobalSecondaryIndexDescription {
+	return APIGlobalSecondaryIndexDescription{
+		IndexArn:              i.ARN,
+		IndexName:             i.Name,
+		IndexStatus:           "ACTIVE",
+		ItemCount:             i.items.count,
+		KeySchema:             i.KeySchema,
+		Projection:            i.Projection,
+		ProvisionedThroughput: throughputDescription(i.ProvisionedThroughput),
+	}
+}
+
+func (i *secondaryIndex) toLocalAPI() APILocalSecondaryIndexDescription {
+	return APILocalSecondaryIndexDescription{
+		IndexArn:   i.ARN,
+		IndexName:  i.Name,
+		ItemCount:  i.items.count,
+		KeySchema:  i.KeySchema,
+		Projection: i.Projection,
+	}
+}
+
+func (t *Table) lockedGetIndex(name string) *secondaryIndex {
+	for _, index := range t.indexes {
+		if index.Name == name {
+			return index
+		}
+	}
+	return nil
+}
+
+// lockedAddIndex creates the index, and backfills it from the items in the table.
+func (t *Table) lockedAddIndex(index *secondaryIndex) error {
+	err := t.items.each(func(item APIItem) error {
+		if index.validateItem(item, t.AttributeDefinitions) != nil {
+			return nil
+		}
+		return index.add(item)
+	})
+	if err != nil {
+		return err
+	}
+	t.indexes = append(t.indexes, index)
+	return nil
+}
+
+func (t *Table) lockedDeleteIndex(name string) bool {
+	i := slices.IndexFunc(t.indexes, func(index *secondaryIndex) bool {
+		return index.Name == name
+	})
+	if i == -1 {
+		return false
+	}
+	t.indexes = slices.Delete(t.indexes, i, i+1)
+	return true
+}
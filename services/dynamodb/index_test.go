@@ -0,0 +1,280 @@
+package dynamodb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newDynamoDBWithIndexes creates a table with a GSI on "group" and an LSI on "rank".
+// Some items are missing the index keys, so they only appear in the table.
+func newDynamoDBWithIndexes(t *testing.T) *DynamoDB {
+	d := newDynamoDB(Options{ArnGenerator: generator})
+	createTableWithIndexes(t, d)
+	return d
+}
+
+// createTableWithIndexes creates newDynamoDBWithIndexes's table in d.
+func createTableWithIndexes(t *testing.T, d *DynamoDB) {
+	_, err := d.CreateTable(CreateTableInput{
+		TableName: tableName,
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+			{AttributeName: "sk", AttributeType: "N"},
+			{AttributeName: "group", AttributeType: "S"},
+			{AttributeName: "rank", AttributeType: "N"},
+		},
+		KeySchema: []APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+			{AttributeName: "sk", KeyType: "RANGE"},
+		},
+		GlobalSecondaryIndexes: []APIGlobalSecondaryIndex{{
+			IndexName: "byGroup",
+			KeySchema: []APIKeySchemaElement{
+				{AttributeName: "group", KeyType: "HASH"},
+			},
+			Projection: APIProjection{ProjectionType: "INCLUDE", NonKeyAttributes: []string{"value"}},
+		}},
+		LocalSecondaryIndexes: []APILocalSecondaryIndex{{
+			IndexName: "byRank",
+			KeySchema: []APIKeySchemaElement{
+				{AttributeName: "pk", KeyType: "HASH"},
+				{AttributeName: "rank", KeyType: "RANGE"},
+			},
+			Projection: APIProjection{ProjectionType: "KEYS_ONLY"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, item := range []APIItem{
+		{"pk": {S: "a"}, "sk": {N: "1"}, "group": {S: "x"}, "rank": {N: "3"}, "value": {S: "a1"}, "other": {S: "o"}},
+		{"pk": {S: "a"}, "sk": {N: "2"}, "group": {S: "x"}, "rank": {N: "1"}, "value": {S: "a2"}},
+		{"pk": {S: "a"}, "sk": {N: "3"}, "value": {S: "a3"}},
+		{"pk": {S: "b"}, "sk": {N: "1"}, "group": {S: "x"}, "rank": {N: "2"}, "value": {S: "b1"}},
+		{"pk": {S: "b"}, "sk": {N: "2"}, "group": {S: "y"}, "value": {S: "b2"}},
+	} {
+		_, err := d.PutItem(PutItemInput{TableName: tableName, Item: item})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+	}
+}
+
+func valueAttributes(items []APIItem) []string {
+	var values []string
+	for _, item := range items {
+		values = append(values, item["value"].S)
+	}
+	return values
+}
+
+func TestGlobalSecondaryIndex(t *testing.T) {
+	d := newDynamoDBWithIndexes(t)
+
+	input := QueryInput{
+		TableName:              tableName,
+		IndexName:              "byGroup",
+		KeyConditionExpression: "#g = :x",
+		ExpressionAttributeNames: map[string]string{
+			"#g": "group",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":x": {S: "x"},
+		},
+		Limit: 2,
+	}
+	output, err := d.Query(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(valueAttributes(output.Items), []string{"a1", "a2"}) {
+		t.Fatal("Wrong items", output.Items)
+	}
+	if _, ok := output.Items[0]["other"]; ok {
+		t.Fatal("Unprojected attribute returned")
+	}
+	expectedKey := APIItem{"group": {S: "x"}, "pk": {S: "a"}, "sk": {N: "2"}}
+	if !reflect.DeepEqual(output.LastEvaluatedKey, expectedKey) {
+		t.Fatal("Wrong LastEvaluatedKey", output.LastEvaluatedKey)
+	}
+
+	input.ExclusiveStartKey = output.LastEvaluatedKey
+	output, err = d.Query(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(valueAttributes(output.Items), []string{"b1"}) {
+		t.Fatal("Wrong items", output.Items)
+	}
+
+	// Moving an item to another group updates the index.
+	_, err = d.UpdateItem(UpdateItemInput{
+		TableName:        tableName,
+		Key:              APIItem{"pk": {S: "a"}, "sk": {N: "1"}},
+		UpdateExpression: "SET #g = :y",
+		ExpressionAttributeNames: map[string]string{
+			"#g": "group",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":y": {S: "y"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.DeleteItem(DeleteItemInput{
+		TableName: tableName,
+		Key:       APIItem{"pk": {S: "b"}, "sk": {N: "2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan, err := d.Scan(ScanInput{TableName: tableName, IndexName: "byGroup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(valueAttributes(scan.Items), []string{"a2", "b1", "a1"}) {
+		t.Fatal("Wrong items", scan.Items)
+	}
+
+	for _, input := range []QueryInput{
+		{IndexName: "missing"},
+		{IndexName: "byGroup", ConsistentRead: true},
+		{IndexName: "byGroup", Select: "ALL_ATTRIBUTES"},
+	} {
+		input.TableName = tableName
+		input.KeyConditionExpression = "#g = :x"
+		input.ExpressionAttributeNames = map[string]string{"#g": "group"}
+		input.ExpressionAttributeValues = map[string]APIAttributeValue{":x": {S: "x"}}
+		_, err := d.Query(input)
+		if err == nil {
+			t.Fatal("Expected error for", input)
+		}
+	}
+
+	// Index key attributes must have the right type.
+	_, err = d.PutItem(PutItemInput{
+		TableName: tableName,
+		Item:      APIItem{"pk": {S: "c"}, "sk": {N: "1"}, "group": {N: "1"}},
+	})
+	if err == nil {
+		t.Fatal("Expected index key type error")
+	}
+}
+
+func TestLocalSecondaryIndex(t *testing.T) {
+	d := newDynamoDBWithIndexes(t)
+
+	input := QueryInput{
+		TableName:              tableName,
+		IndexName:              "byRank",
+		KeyConditionExpression: "pk = :a AND #r > :zero",
+		ExpressionAttributeNames: map[string]string{
+			"#r": "rank",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":a":    {S: "a"},
+			":zero": {N: "0"},
+		},
+	}
+	output, err := d.Query(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []APIItem{
+		{"pk": {S: "a"}, "sk": {N: "2"}, "rank": {N: "1"}},
+		{"pk": {S: "a"}, "sk": {N: "1"}, "rank": {N: "3"}},
+	}
+	if !reflect.DeepEqual(output.Items, expected) {
+		t.Fatal("Wrong items", output.Items)
+	}
+
+	// Local indexes fetch unprojected attributes from the table.
+	input.Select = "ALL_ATTRIBUTES"
+	output, err = d.Query(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(valueAttributes(output.Items), []string{"a2", "a1"}) {
+		t.Fatal("Wrong items", output.Items)
+	}
+
+	_, err = d.CreateTable(CreateTableInput{
+		TableName: "simple",
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+			{AttributeName: "rank", AttributeType: "N"},
+		},
+		KeySchema: []APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+		},
+		LocalSecondaryIndexes: []APILocalSecondaryIndex{{
+			IndexName: "byRank",
+			KeySchema: []APIKeySchemaElement{
+				{AttributeName: "pk", KeyType: "HASH"},
+				{AttributeName: "rank", KeyType: "RANGE"},
+			},
+			Projection: APIProjection{ProjectionType: "ALL"},
+		}},
+	})
+	if err == nil {
+		t.Fatal("Expected error for LSI on table without sort key")
+	}
+}
+
+func TestUpdateTableIndexes(t *testing.T) {
+	d := newDynamoDBWithIndexes(t)
+
+	output, err := d.UpdateTable(UpdateTableInput{
+		TableName: tableName,
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "value", AttributeType: "S"},
+		},
+		GlobalSecondaryIndexUpdates: []APIGlobalSecondaryIndexUpdate{
+			{Create: &APIGlobalSecondaryIndex{
+				IndexName: "byValue",
+				KeySchema: []APIKeySchemaElement{
+					{AttributeName: "value", KeyType: "HASH"},
+				},
+				Projection: APIProjection{ProjectionType: "ALL"},
+			}},
+			{Delete: &struct{ IndexName string }{IndexName: "byGroup"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := output.TableDescription.GlobalSecondaryIndexes
+	if len(indexes) != 1 || indexes[0].IndexName != "byValue" || indexes[0].ItemCount != 5 {
+		t.Fatal("Wrong indexes", indexes)
+	}
+
+	query, err := d.Query(QueryInput{
+		TableName:              tableName,
+		IndexName:              "byValue",
+		KeyConditionExpression: "#v = :v",
+		ExpressionAttributeNames: map[string]string{
+			"#v": "value",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":v": {S: "a1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Count != 1 || query.Items[0]["other"].S != "o" {
+		t.Fatal("Wrong items", query.Items)
+	}
+
+	_, err = d.UpdateTable(UpdateTableInput{
+		TableName: tableName,
+		GlobalSecondaryIndexUpdates: []APIGlobalSecondaryIndexUpdate{
+			{Delete: &struct{ IndexName string }{IndexName: "byRank"}},
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected error deleting a local index")
+	}
+}
@@ -0,0 +1,38 @@
+package dynamodb
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each table's items, including the copies projected into its
+// secondary indexes. Tables whose items are kept in a file only count the items' keys, which are
+// all that's kept in memory.
+func (d *DynamoDB) MemoryUsage() []memory.Resource {
+	return d.regions.MemoryUsage((*DynamoDB).memoryUsage)
+}
+
+func (d *DynamoDB) memoryUsage() []memory.Resource {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var resources []memory.Resource
+	for name, table := range d.tablesByName {
+		resource := memory.Resource{Name: name, Count: table.items.count}
+		resource.Bytes += table.items.size()
+		for _, index := range table.indexes {
+			resource.Bytes += index.items.size()
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func (c *itemCollection) size() int64 {
+	var size int64
+	for _, partition := range c.partitions {
+		for _, item := range partition {
+			size += int64(itemSize(item))
+		}
+	}
+	return size
+}
@@ -0,0 +1,179 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/region"
+	"aws-in-a-box/state"
+)
+
+type tableState struct {
+	// The table's definition, as it would be created now.
+	Definition CreateTableInput
+	// Empty if time to live is disabled.
+	TimeToLiveAttribute string `json:",omitempty"`
+	// In scan order.
+	Items []APIItem
+}
+
+type tablesState struct {
+	Tables []tableState
+}
+
+// StateVersion is 2 since other regions' tables were added to archives, under regions/. Version 1
+// archives only have the default region's, in the same place.
+func (d *DynamoDB) StateVersion() int {
+	return 2
+}
+
+func (d *DynamoDB) StateMigrations() []state.Migration {
+	return []state.Migration{
+		func(e *state.Entries) error { return nil },
+	}
+}
+
+// TryPause pauses changes to each region's tables while a snapshot copies them.
+func (d *DynamoDB) TryPause() (func(), bool) {
+	return d.regions.TryPause(func(d *DynamoDB) *sync.Mutex { return &d.mu })
+}
+
+// ExportState writes each region's tables and their items to the archive. Streams' records aren't
+// exported.
+func (d *DynamoDB) ExportState(w *state.Writer) error {
+	return d.regions.ExportState(w, (*DynamoDB).exportState)
+}
+
+func (d *DynamoDB) exportState(w *state.Writer) error {
+	unlock := w.Lock(&d.mu)
+	defer unlock()
+
+	var exported tablesState
+	for _, t := range d.tablesByName {
+		table := tableState{
+			Definition:          t.definition(),
+			TimeToLiveAttribute: t.timeToLiveAttribute,
+			Items:               []APIItem{},
+		}
+		err := t.items.each(func(item APIItem) error {
+			table.Items = append(table.Items, item)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		exported.Tables = append(exported.Tables, table)
+	}
+	slices.SortFunc(exported.Tables, func(a, b tableState) int {
+		return strings.Compare(a.Definition.TableName, b.Definition.TableName)
+	})
+	return w.WriteJSON("tables.json", exported)
+}
+
+// ImportState replaces each region's tables and their items with the archive's. Tables with
+// streams get new streams, without records for the imported items.
+func (d *DynamoDB) ImportState(r *state.Reader) (func() error, error) {
+	return d.regions.ImportState(r, (*DynamoDB).importState, (*DynamoDB).exportState)
+}
+
+func (d *DynamoDB) importState(r *state.Reader) (func() error, error) {
+	var imported tablesState
+	if err := r.ReadJSON("tables.json", &imported); err != nil {
+		return nil, err
+	}
+
+	// The tables are created with the same code as CreateTable, into new maps which are swapped back
+	// out until they replace the service's. Their items are kept in new maps in storage, which are
+	// deleted if the archive is invalid, or when the emulator next starts if another service's is.
+	d.mu.Lock()
+	previousTables, previousStreams := d.tablesByName, d.streamsByARN
+	d.tablesByName = make(map[string]*Table)
+	d.streamsByARN = make(map[string]*tableStream)
+	err := d.lockedImportTables(imported.Tables)
+	tablesByName, streamsByARN := d.tablesByName, d.streamsByARN
+	d.tablesByName, d.streamsByARN = previousTables, previousStreams
+	d.mu.Unlock()
+	if err != nil {
+		for _, t := range tablesByName {
+			t.drop()
+		}
+		return nil, err
+	}
+	return func() error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for _, t := range tablesByName {
+			if awserr := d.lockedSaveTable(t); awserr != nil {
+				return errors.New(awserr.Body.Message)
+			}
+		}
+		for name, t := range d.tablesByName {
+			if _, ok := tablesByName[name]; !ok {
+				if err := d.storedTables.Delete(name); err != nil {
+					return err
+				}
+			}
+			if err := t.drop(); err != nil {
+				d.logger.Error("Deleting table items from storage", "table", name, "err", err)
+			}
+		}
+		d.tablesByName, d.streamsByARN = tablesByName, streamsByARN
+		return nil
+	}, nil
+}
+
+func (d *DynamoDB) lockedImportTables(tables []tableState) error {
+	for _, table := range tables {
+		t, awserr := d.lockedCreateTable(table.Definition, nil)
+		if awserr != nil {
+			return fmt.Errorf("table %s: %s", table.Definition.TableName, awserr.Body.Message)
+		}
+		t.timeToLiveAttribute = table.TimeToLiveAttribute
+		for _, item := range table.Items {
+			if awserr := t.validateItem(item); awserr != nil {
+				return fmt.Errorf("table %s: %s", t.Name, awserr.Body.Message)
+			}
+			// Not putItem, so the stream doesn't record the items.
+			if _, _, err := t.items.put(item); err != nil {
+				return err
+			}
+			for _, index := range t.indexes {
+				if err := index.add(item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// StateResources lists the tables and items of each region in the archive, for diffing archives.
+// Items are named by their keys, as JSON.
+var StateResources = region.StateResources(func(r *state.Reader) (map[string]any, error) {
+	var exported tablesState
+	if err := r.ReadJSON("tables.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, table := range exported.Tables {
+		name := table.Definition.TableName
+		schema, awserr := newKeySchema(table.Definition.KeySchema, table.Definition.AttributeDefinitions)
+		if awserr != nil {
+			return nil, fmt.Errorf("table %s: %s", name, awserr.Body.Message)
+		}
+		for _, item := range table.Items {
+			key, err := json.Marshal(schema.extractKey(item))
+			if err != nil {
+				return nil, err
+			}
+			resources["item/"+name+"/"+string(key)] = item
+		}
+		table.Items = nil
+		resources["table/"+name] = table
+	}
+	return resources, nil
+})
@@ -0,0 +1,93 @@
+package dynamodb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"aws-in-a-box/state"
+)
+
+func TestExportImportState(t *testing.T) {
+	d := newDynamoDBWithIndexes(t)
+	if _, err := d.UpdateTimeToLive(UpdateTimeToLiveInput{
+		TableName:               tableName,
+		TimeToLiveSpecification: APITimeToLiveSpecification{AttributeName: "expires", Enabled: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"dynamodb": d}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := newDynamoDB(Options{ArnGenerator: generator})
+	if _, _, err := state.Import(&buf, state.Registry{"dynamodb": imported}); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := d.Scan(ScanInput{TableName: tableName})
+	got, awserr := imported.Scan(ScanInput{TableName: tableName})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !reflect.DeepEqual(got.Items, want.Items) {
+		t.Fatalf("Wrong items %v, want %v", got.Items, want.Items)
+	}
+
+	output, awserr := imported.Query(QueryInput{
+		TableName:                 tableName,
+		IndexName:                 "byGroup",
+		KeyConditionExpression:    "#g = :x",
+		ExpressionAttributeNames:  map[string]string{"#g": "group"},
+		ExpressionAttributeValues: map[string]APIAttributeValue{":x": {S: "x"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !reflect.DeepEqual(valueAttributes(output.Items), []string{"a1", "a2", "b1"}) {
+		t.Fatal("Wrong index items", output.Items)
+	}
+
+	ttl, awserr := imported.DescribeTimeToLive(DescribeTimeToLiveInput{TableName: tableName})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if ttl.TimeToLiveDescription.AttributeName != "expires" {
+		t.Fatal("Wrong time to live", ttl.TimeToLiveDescription)
+	}
+}
+
+func TestExportImportStateRegions(t *testing.T) {
+	d := newDynamoDB(Options{ArnGenerator: generator})
+	europe, err := d.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.CreateTable(CreateTableInput{
+		TableName:            tableName,
+		AttributeDefinitions: []APIAttributeDefinition{{AttributeName: "pk", AttributeType: "S"}},
+		KeySchema:            []APIKeySchemaElement{{AttributeName: "pk", KeyType: "HASH"}},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"dynamodb": d}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := newDynamoDB(Options{ArnGenerator: generator})
+	if _, _, err := state.Import(&buf, state.Registry{"dynamodb": imported}); err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := imported.DescribeTable(DescribeTableInput{TableName: tableName}); awserr == nil {
+		t.Fatal("Expected eu-west-1's table not to be imported into the default region")
+	}
+	importedEurope, err := imported.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := importedEurope.DescribeTable(DescribeTableInput{TableName: tableName}); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
@@ -0,0 +1,201 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/storage"
+)
+
+const (
+	// Each region's tables are kept in the map named after it with this prefix.
+	storedTablesPrefix = "dynamodb/tables/"
+	// Each table's items, and each index's, are kept in a map with this prefix and a random ID, so
+	// a table which replaces one with the same name doesn't see its items.
+	itemsPrefix = "dynamodb/items/"
+)
+
+// storedTable is what's kept in storage about a table besides its items, so it can be restored
+// when the emulator restarts.
+type storedTable struct {
+	Definition          CreateTableInput
+	TimeToLiveAttribute string `json:",omitempty"`
+	// The names of the maps with the table's items, and each index's, by index name.
+	Items   string
+	Indexes map[string]string
+}
+
+// definition returns the table's definition, as it would be created now.
+func (t *Table) definition() CreateTableInput {
+	definition := CreateTableInput{
+		AttributeDefinitions:  t.AttributeDefinitions,
+		TableName:             t.Name,
+		BillingMode:           t.BillingMode,
+		KeySchema:             t.KeySchema,
+		ProvisionedThroughput: t.ProvisionedThroughput,
+	}
+	for _, index := range t.indexes {
+		if index.Global {
+			definition.GlobalSecondaryIndexes = append(definition.GlobalSecondaryIndexes, APIGlobalSecondaryIndex{
+				IndexName:             index.Name,
+				KeySchema:             index.KeySchema,
+				Projection:            index.Projection,
+				ProvisionedThroughput: index.ProvisionedThroughput,
+			})
+		} else {
+			definition.LocalSecondaryIndexes = append(definition.LocalSecondaryIndexes, APILocalSecondaryIndex{
+				IndexName:  index.Name,
+				KeySchema:  index.KeySchema,
+				Projection: index.Projection,
+			})
+		}
+	}
+	if t.stream != nil && t.stream.Enabled {
+		definition.StreamSpecification = &APIStreamSpecification{
+			StreamEnabled:  true,
+			StreamViewType: t.stream.ViewType,
+		}
+	}
+	return definition
+}
+
+// openItems opens the map of a collection's items in storage: the named one, or a new one if the
+// name is empty. It's nil if storage is in memory, so the collection keeps its items itself.
+func (d *DynamoDB) openItems(name string) (*storage.Map[APIItem], error) {
+	if name == "" {
+		name = itemsPrefix + uuid.Must(uuid.NewV4()).String()
+	}
+	items, err := storage.OpenMap[APIItem](d.storage, name)
+	if err != nil || items.InMemory() {
+		return nil, err
+	}
+	return items, nil
+}
+
+// openStorage opens the maps of the table's items and its indexes', which are the stored table's
+// if it's being restored, in which case the table's collections are filled with the stored items'
+// keys. The table and its indexes don't have any items yet.
+func (d *DynamoDB) openStorage(t *Table, stored *storedTable) error {
+	if stored == nil {
+		stored = &storedTable{}
+	}
+	collections := map[*itemCollection]string{t.items: stored.Items}
+	for _, index := range t.indexes {
+		collections[index.items] = stored.Indexes[index.Name]
+	}
+	for c, name := range collections {
+		items, err := d.openItems(name)
+		if err != nil {
+			return errors.Join(err, t.drop())
+		}
+		c.items = items
+		if err := c.restore(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drop deletes the items of the table and its indexes from storage, once it's been deleted or
+// replaced.
+func (t *Table) drop() error {
+	errs := []error{t.items.drop()}
+	for _, index := range t.indexes {
+		errs = append(errs, index.items.drop())
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Table) stored() storedTable {
+	stored := storedTable{
+		Definition:          t.definition(),
+		TimeToLiveAttribute: t.timeToLiveAttribute,
+		Indexes:             make(map[string]string),
+	}
+	if t.items.items != nil {
+		stored.Items = t.items.items.Name()
+	}
+	for _, index := range t.indexes {
+		if index.items.items != nil {
+			stored.Indexes[index.Name] = index.items.items.Name()
+		}
+	}
+	return stored
+}
+
+// lockedSaveTable keeps the table's definition in storage, after it's created or changed.
+func (d *DynamoDB) lockedSaveTable(t *Table) *awserrors.Error {
+	if err := d.storedTables.Put(t.Name, t.stored()); err != nil {
+		return InternalServerError(err.Error())
+	}
+	return nil
+}
+
+// restore recreates the tables kept in storage, in each region they were in, and deletes any items
+// in storage which don't belong to any of them, such as those of a table which was being deleted
+// when the emulator stopped.
+func (d *DynamoDB) restore() error {
+	regions, err := d.storage.Tables(storedTablesPrefix)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, name := range regions {
+		r, err := d.regions.Get(strings.TrimPrefix(name, storedTablesPrefix))
+		if err != nil {
+			return err
+		}
+		err = r.storedTables.Scan("", func(_ string, stored storedTable) bool {
+			used[stored.Items] = true
+			for _, name := range stored.Indexes {
+				used[name] = true
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if err := r.restoreTables(); err != nil {
+			return err
+		}
+	}
+
+	stored, err := d.storage.Tables(itemsPrefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range stored {
+		if !used[name] {
+			if err := d.storage.DropTable(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *DynamoDB) restoreTables() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var tables []storedTable
+	err := d.storedTables.Scan("", func(_ string, stored storedTable) bool {
+		tables = append(tables, stored)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, stored := range tables {
+		t, awserr := d.lockedCreateTable(stored.Definition, &stored)
+		if awserr != nil {
+			return fmt.Errorf("restoring table %s: %s", stored.Definition.TableName, awserr.Body.Message)
+		}
+		t.timeToLiveAttribute = stored.TimeToLiveAttribute
+	}
+	return nil
+}
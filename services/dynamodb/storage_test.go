@@ -0,0 +1,169 @@
+package dynamodb
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"aws-in-a-box/storage"
+)
+
+// readTable reads the table's items every way newDynamoDBWithIndexes's table is read, so tables
+// kept in storage can be compared with those in memory.
+func readTable(t *testing.T, d *DynamoDB) []any {
+	var outputs []any
+	scan := ScanInput{TableName: tableName, Limit: 2}
+	for {
+		output, awserr := d.Scan(scan)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		outputs = append(outputs, output.Items, output.LastEvaluatedKey)
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		scan.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+	for _, input := range []QueryInput{{
+		TableName:                 tableName,
+		IndexName:                 "byGroup",
+		KeyConditionExpression:    "#g = :x",
+		FilterExpression:          "#v <> :a2",
+		ExpressionAttributeNames:  map[string]string{"#g": "group", "#v": "value"},
+		ExpressionAttributeValues: map[string]APIAttributeValue{":x": {S: "x"}, ":a2": {S: "a2"}},
+	}, {
+		// Reads the items' unprojected attributes from the table.
+		TableName:                 tableName,
+		IndexName:                 "byRank",
+		KeyConditionExpression:    "pk = :a",
+		Select:                    "ALL_ATTRIBUTES",
+		ExpressionAttributeValues: map[string]APIAttributeValue{":a": {S: "a"}},
+	}, {
+		TableName:                 tableName,
+		KeyConditionExpression:    "pk = :b AND sk > :one",
+		ExpressionAttributeValues: map[string]APIAttributeValue{":b": {S: "b"}, ":one": {N: "1"}},
+	}} {
+		output, awserr := d.Query(input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		outputs = append(outputs, output.Items, output.Count, output.ScannedCount)
+	}
+	return outputs
+}
+
+func TestStorage(t *testing.T) {
+	want := readTable(t, newDynamoDBWithIndexes(t))
+
+	for _, kind := range []string{"bolt", "sqlite"} {
+		t.Run(kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage."+kind)
+			backend, err := storage.Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := newDynamoDB(Options{ArnGenerator: generator, Storage: backend})
+			createTableWithIndexes(t, d)
+			if got := readTable(t, d); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Got %v, want %v", got, want)
+			}
+			if _, awserr := d.UpdateTimeToLive(UpdateTimeToLiveInput{
+				TableName:               tableName,
+				TimeToLiveSpecification: APITimeToLiveSpecification{AttributeName: "expires", Enabled: true},
+			}); awserr != nil {
+				t.Fatal(awserr)
+			}
+			europe, err := d.InRegion("eu-west-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			createTableWithIndexes(t, europe)
+			// Deleted tables' items are deleted from storage.
+			if _, awserr := europe.DeleteTable(DeleteTableInput{TableName: tableName}); awserr != nil {
+				t.Fatal(awserr)
+			}
+			createTableWithIndexes(t, europe)
+			if err := backend.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			backend, err = storage.Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer backend.Close()
+			restored := newDynamoDB(Options{ArnGenerator: generator, Storage: backend})
+			if got := readTable(t, restored); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Got %v after restarting, want %v", got, want)
+			}
+			ttl, awserr := restored.DescribeTimeToLive(DescribeTimeToLiveInput{TableName: tableName})
+			if awserr != nil {
+				t.Fatal(awserr)
+			}
+			if ttl.TimeToLiveDescription.AttributeName != "expires" {
+				t.Fatal("Wrong time to live", ttl.TimeToLiveDescription)
+			}
+			europe, err = restored.InRegion("eu-west-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readTable(t, europe); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Got %v in eu-west-1 after restarting, want %v", got, want)
+			}
+			// Each region's table, and its two indexes.
+			if items, err := backend.Tables(itemsPrefix); err != nil || len(items) != 6 {
+				t.Fatal("Wrong items in storage", items, err)
+			}
+		})
+	}
+}
+
+func TestStorageEventualConsistency(t *testing.T) {
+	backend, err := storage.Open("bolt", filepath.Join(t.TempDir(), "storage.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	d := newDynamoDB(Options{ArnGenerator: generator, EventualConsistencyDelay: time.Second, Storage: backend})
+	now := time.Unix(1_700_000_000, 0)
+	d.clock = func() time.Time { return now }
+	createTableWithIndexes(t, d)
+	now = now.Add(time.Second)
+
+	if _, awserr := d.PutItem(PutItemInput{
+		TableName: tableName,
+		Item:      APIItem{"pk": {S: "a"}, "sk": {N: "1"}, "group": {S: "x"}, "value": {S: "new"}},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	output, awserr := d.Query(QueryInput{
+		TableName:                 tableName,
+		IndexName:                 "byGroup",
+		KeyConditionExpression:    "#g = :x",
+		ExpressionAttributeNames:  map[string]string{"#g": "group"},
+		ExpressionAttributeValues: map[string]APIAttributeValue{":x": {S: "x"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !reflect.DeepEqual(valueAttributes(output.Items), []string{"a1", "a2", "b1"}) {
+		t.Fatal("Eventually consistent read saw the write", output.Items)
+	}
+
+	// Reading the view doesn't write the items it shows to storage.
+	now = now.Add(time.Second)
+	output, awserr = d.Query(QueryInput{
+		TableName:                 tableName,
+		IndexName:                 "byGroup",
+		KeyConditionExpression:    "#g = :x",
+		ExpressionAttributeNames:  map[string]string{"#g": "group"},
+		ExpressionAttributeValues: map[string]APIAttributeValue{":x": {S: "x"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !reflect.DeepEqual(valueAttributes(output.Items), []string{"new", "a2", "b1"}) {
+		t.Fatal("Wrong items once the write is visible", output.Items)
+	}
+}
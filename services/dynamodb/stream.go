@@ -0,0 +1,316 @@
+package dynamodb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+// Each stream has a single shard, which is never split.
+const streamShardId = "shardId-00000000000000000001-00000000"
+
+const maxGetRecordsLimit = 1000
+
+// ttlIdentity is the UserIdentity of records for items deleted by time to live.
+var ttlIdentity = &APIIdentity{
+	PrincipalId: "dynamodb.amazonaws.com",
+	Type:        "Service",
+}
+
+type tableStream struct {
+	ARN          string
+	Label        string
+	TableName    string
+	ViewType     string
+	KeySchema    []APIKeySchemaElement
+	CreationTime time.Time
+	// Disabled streams can still be read, but receive no new records.
+	Enabled bool
+
+	region  string
+	clock   func() time.Time
+	records []APIRecord
+}
+
+func (d *DynamoDB) lockedEnableStream(t *Table, viewType string) *awserrors.Error {
+	switch viewType {
+	case "KEYS_ONLY", "NEW_IMAGE", "OLD_IMAGE", "NEW_AND_OLD_IMAGES":
+	default:
+		return ValidationException("Invalid StreamViewType: " + viewType)
+	}
+	if t.stream != nil && t.stream.Enabled {
+		return ValidationException("Table already has an enabled stream: " + t.stream.ARN)
+	}
+
+	now := d.clock()
+	label := now.UTC().Format("2006-01-02T15:04:05.000")
+	arn := t.ARN + "/stream/" + label
+	for d.streamsByARN[arn] != nil {
+		now = now.Add(time.Millisecond)
+		label = now.UTC().Format("2006-01-02T15:04:05.000")
+		arn = t.ARN + "/stream/" + label
+	}
+
+	s := &tableStream{
+		ARN:          arn,
+		Label:        label,
+		TableName:    t.Name,
+		ViewType:     viewType,
+		KeySchema:    t.KeySchema,
+		CreationTime: now,
+		Enabled:      true,
+		region:       d.arnGenerator.Region,
+		clock:        d.clock,
+	}
+	d.streamsByARN[arn] = s
+	t.stream = s
+	return nil
+}
+
+func (t *Table) lockedDisableStream() *awserrors.Error {
+	if t.stream == nil || !t.stream.Enabled {
+		return ValidationException("Table does not have an enabled stream")
+	}
+	t.stream.Enabled = false
+	return nil
+}
+
+// recordChange appends a record for the change to the item, if the table has an enabled stream.
+// Either of oldItem or newItem may be nil.
+func (t *Table) recordChange(oldItem, newItem APIItem, identity *APIIdentity) {
+	s := t.stream
+	if s == nil || !s.Enabled {
+		return
+	}
+
+	eventName := "MODIFY"
+	keySource := newItem
+	switch {
+	case oldItem == nil && newItem == nil:
+		return
+	case oldItem == nil:
+		eventName = "INSERT"
+	case newItem == nil:
+		eventName = "REMOVE"
+		keySource = oldItem
+	case valuesEqual(APIAttributeValue{M: oldItem}, APIAttributeValue{M: newItem}):
+		// Writes which don't change the item aren't recorded.
+		return
+	}
+
+	record := APIStreamRecord{
+		ApproximateCreationDateTime: s.clock().Unix(),
+		Keys:                        cloneItem(t.keySchema.extractKey(keySource)),
+		SequenceNumber:              formatSequenceNumber(len(s.records) + 1),
+		StreamViewType:              s.ViewType,
+	}
+	if s.ViewType == "NEW_IMAGE" || s.ViewType == "NEW_AND_OLD_IMAGES" {
+		record.NewImage = cloneItem(newItem)
+	}
+	if s.ViewType == "OLD_IMAGE" || s.ViewType == "NEW_AND_OLD_IMAGES" {
+		record.OldImage = cloneItem(oldItem)
+	}
+	record.SizeBytes = itemSize(record.Keys) + itemSize(record.NewImage) + itemSize(record.OldImage)
+
+	s.records = append(s.records, APIRecord{
+		AwsRegion:    s.region,
+		Dynamodb:     record,
+		EventID:      uuid.Must(uuid.NewV4()).String(),
+		EventName:    eventName,
+		EventSource:  "aws:dynamodb",
+		EventVersion: "1.1",
+		UserIdentity: identity,
+	})
+}
+
+func formatSequenceNumber(n int) string {
+	return fmt.Sprintf("%021d", n)
+}
+
+func (s *tableStream) toAPI() APIStreamDescription {
+	status := "ENABLED"
+	if !s.Enabled {
+		status = "DISABLED"
+	}
+	shard := APIShard{
+		SequenceNumberRange: APISequenceNumberRange{
+			StartingSequenceNumber: formatSequenceNumber(1),
+		},
+		ShardId: streamShardId,
+	}
+	if !s.Enabled {
+		shard.SequenceNumberRange.EndingSequenceNumber = formatSequenceNumber(len(s.records))
+	}
+	return APIStreamDescription{
+		CreationRequestDateTime: s.CreationTime.Unix(),
+		KeySchema:               s.KeySchema,
+		Shards:                  []APIShard{shard},
+		StreamArn:               s.ARN,
+		StreamLabel:             s.Label,
+		StreamStatus:            status,
+		StreamViewType:          s.ViewType,
+		TableName:               s.TableName,
+	}
+}
+
+func (d *DynamoDB) lockedGetStream(streamArn string) (*tableStream, *awserrors.Error) {
+	s, ok := d.streamsByARN[streamArn]
+	if !ok {
+		return nil, awserrors.ResourceNotFoundException(
+			fmt.Sprintf("Requested resource not found: Stream: %s not found", streamArn))
+	}
+	return s, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_streams_ListStreams.html
+func (d *DynamoDB) ListStreams(input ListStreamsInput) (*ListStreamsOutput, *awserrors.Error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = 100
+	}
+	if limit < 1 || limit > 100 {
+		return nil, ValidationException("Limit must be between 1 and 100")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	output := &ListStreamsOutput{Streams: []APIStream{}}
+	for _, arn := range sortedKeys(d.streamsByARN) {
+		s := d.streamsByARN[arn]
+		if input.TableName != "" && s.TableName != input.TableName {
+			continue
+		}
+		if input.ExclusiveStartStreamArn != "" && arn <= input.ExclusiveStartStreamArn {
+			continue
+		}
+		if len(output.Streams) == limit {
+			output.LastEvaluatedStreamArn = output.Streams[limit-1].StreamArn
+			break
+		}
+		output.Streams = append(output.Streams, APIStream{
+			StreamArn:   s.ARN,
+			StreamLabel: s.Label,
+			TableName:   s.TableName,
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_streams_DescribeStream.html
+func (d *DynamoDB) DescribeStream(input DescribeStreamInput) (*DescribeStreamOutput, *awserrors.Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, awserr := d.lockedGetStream(input.StreamArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	description := s.toAPI()
+	if input.ExclusiveStartShardId != "" {
+		// There is only one shard.
+		description.Shards = []APIShard{}
+	}
+	return &DescribeStreamOutput{
+		StreamDescription: description,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_streams_GetShardIterator.html
+func (d *DynamoDB) GetShardIterator(input GetShardIteratorInput) (*GetShardIteratorOutput, *awserrors.Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, awserr := d.lockedGetStream(input.StreamArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.ShardId != streamShardId {
+		return nil, awserrors.ResourceNotFoundException(
+			fmt.Sprintf("Requested resource not found: Shard does not exist: %s", input.ShardId))
+	}
+
+	var index int
+	switch input.ShardIteratorType {
+	case "TRIM_HORIZON":
+		index = 0
+	case "LATEST":
+		index = len(s.records)
+	case "AT_SEQUENCE_NUMBER", "AFTER_SEQUENCE_NUMBER":
+		sequenceNumber, err := strconv.Atoi(input.SequenceNumber)
+		if err != nil || sequenceNumber < 1 || sequenceNumber > len(s.records) {
+			return nil, ValidationException("Invalid SequenceNumber: " + input.SequenceNumber)
+		}
+		index = sequenceNumber - 1
+		if input.ShardIteratorType == "AFTER_SEQUENCE_NUMBER" {
+			index++
+		}
+	default:
+		return nil, ValidationException("Invalid ShardIteratorType: " + input.ShardIteratorType)
+	}
+
+	return &GetShardIteratorOutput{
+		ShardIterator: encodeStreamIterator(s.ARN, index),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_streams_GetRecords.html
+func (d *DynamoDB) GetRecords(input GetRecordsInput) (*GetRecordsOutput, *awserrors.Error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = maxGetRecordsLimit
+	}
+	if limit < 1 || limit > maxGetRecordsLimit {
+		return nil, ValidationException(fmt.Sprintf("Limit must be between 1 and %d", maxGetRecordsLimit))
+	}
+
+	streamArn, start, ok := decodeStreamIterator(input.ShardIterator)
+	if !ok {
+		return nil, ValidationException("Invalid ShardIterator")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, awserr := d.lockedGetStream(streamArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if start > len(s.records) {
+		return nil, ValidationException("Invalid ShardIterator")
+	}
+
+	end := min(start+limit, len(s.records))
+	output := &GetRecordsOutput{
+		// Records are never modified once written, so they can be shared.
+		Records: s.records[start:end:end],
+	}
+	// The shard of a disabled stream is closed once all its records have been read.
+	if s.Enabled || end < len(s.records) {
+		output.NextShardIterator = encodeStreamIterator(s.ARN, end)
+	}
+	return output, nil
+}
+
+// Stream ARNs contain slashes, so use a separator which can't appear in them.
+func encodeStreamIterator(streamArn string, index int) string {
+	return fmt.Sprintf("%s|%d", streamArn, index)
+}
+
+func decodeStreamIterator(iterator string) (string, int, bool) {
+	i := strings.LastIndex(iterator, "|")
+	if i == -1 {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(iterator[i+1:])
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+	return iterator[:i], index, true
+}
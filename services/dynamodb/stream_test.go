@@ -0,0 +1,117 @@
+package dynamodb
+
+import (
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	d := newDynamoDB(Options{ArnGenerator: generator})
+	_, err := d.CreateTable(CreateTableInput{
+		TableName: tableName,
+		AttributeDefinitions: []APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+		},
+		KeySchema: []APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+		},
+		StreamSpecification: &APIStreamSpecification{
+			StreamEnabled:  true,
+			StreamViewType: "NEW_AND_OLD_IMAGES",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := APIItem{"pk": {S: "a"}, "value": {N: "1"}}
+	_, err = d.PutItem(PutItemInput{TableName: tableName, Item: item})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged items aren't recorded.
+	_, err = d.PutItem(PutItemInput{TableName: tableName, Item: item})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.UpdateItem(UpdateItemInput{
+		TableName:        tableName,
+		Key:              APIItem{"pk": {S: "a"}},
+		UpdateExpression: "ADD #v :one",
+		ExpressionAttributeNames: map[string]string{
+			"#v": "value",
+		},
+		ExpressionAttributeValues: map[string]APIAttributeValue{
+			":one": {N: "1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.DeleteItem(DeleteItemInput{TableName: tableName, Key: APIItem{"pk": {S: "a"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := d.DescribeTable(DescribeTableInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamArn := table.Table.LatestStreamArn
+	if streamArn == "" || table.Table.StreamSpecification == nil {
+		t.Fatal("Missing stream", table.Table)
+	}
+
+	iterator, err := d.GetShardIterator(GetShardIteratorInput{
+		StreamArn:         streamArn,
+		ShardId:           streamShardId,
+		ShardIteratorType: "TRIM_HORIZON",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []APIRecord
+	next := iterator.ShardIterator
+	for i := 0; i < 3; i++ {
+		output, err := d.GetRecords(GetRecordsInput{ShardIterator: next, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, output.Records...)
+		next = output.NextShardIterator
+	}
+	if len(records) != 3 {
+		t.Fatal("Wrong record count", len(records))
+	}
+	if records[0].EventName != "INSERT" || records[0].Dynamodb.OldImage != nil {
+		t.Fatal("Wrong insert record", records[0])
+	}
+	if records[1].EventName != "MODIFY" || records[1].Dynamodb.OldImage["value"].N != "1" || records[1].Dynamodb.NewImage["value"].N != "2" {
+		t.Fatal("Wrong modify record", records[1])
+	}
+	if records[2].EventName != "REMOVE" || records[2].Dynamodb.NewImage != nil || records[2].UserIdentity != nil {
+		t.Fatal("Wrong remove record", records[2])
+	}
+
+	// Once disabled, the shard is closed after the remaining records are read.
+	_, err = d.UpdateTable(UpdateTableInput{
+		TableName:           tableName,
+		StreamSpecification: &APIStreamSpecification{StreamEnabled: false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := d.GetRecords(GetRecordsInput{ShardIterator: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Records) != 0 || output.NextShardIterator != "" {
+		t.Fatal("Expected closed shard", output)
+	}
+	stream, err := d.DescribeStream(DescribeStreamInput{StreamArn: streamArn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.StreamDescription.StreamStatus != "DISABLED" {
+		t.Fatal("Wrong status", stream.StreamDescription)
+	}
+}
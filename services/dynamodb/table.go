@@ -0,0 +1,468 @@
+package dynamodb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/storage"
+)
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ServiceQuotas.html
+const maxItemSize = 400 * 1024
+
+type keySchema struct {
+	PartitionKey string
+	// Empty if the table has a simple primary key
+	SortKey string
+}
+
+func newKeySchema(
+	elements []APIKeySchemaElement,
+	definitions []APIAttributeDefinition,
+) (keySchema, *awserrors.Error) {
+	var schema keySchema
+	for _, element := range elements {
+		switch element.KeyType {
+		case "HASH":
+			if schema.PartitionKey != "" {
+				return schema, ValidationException("Too many hash keys in KeySchema")
+			}
+			schema.PartitionKey = element.AttributeName
+		case "RANGE":
+			if schema.SortKey != "" {
+				return schema, ValidationException("Too many range keys in KeySchema")
+			}
+			schema.SortKey = element.AttributeName
+		default:
+			return schema, ValidationException("Invalid KeyType: " + element.KeyType)
+		}
+	}
+	if schema.PartitionKey == "" {
+		return schema, ValidationException("KeySchema must have a HASH key")
+	}
+
+	for _, name := range schema.names() {
+		found := false
+		for _, definition := range definitions {
+			if definition.AttributeName != name {
+				continue
+			}
+			switch definition.AttributeType {
+			case "S", "N", "B":
+			default:
+				return schema, ValidationException(fmt.Sprintf(
+					"Member must satisfy enum value set: [B, N, S]; got %s for %s", definition.AttributeType, name))
+			}
+			found = true
+		}
+		if !found {
+			return schema, ValidationException(
+				"One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions")
+		}
+	}
+	return schema, nil
+}
+
+func (s keySchema) names() []string {
+	if s.SortKey == "" {
+		return []string{s.PartitionKey}
+	}
+	return []string{s.PartitionKey, s.SortKey}
+}
+
+// extractKey returns just the key attributes of the item.
+func (s keySchema) extractKey(item APIItem) APIItem {
+	key := make(APIItem)
+	for _, name := range s.names() {
+		if v, ok := item[name]; ok {
+			key[name] = v
+		}
+	}
+	return key
+}
+
+// keyID returns a string uniquely identifying the key.
+func (s keySchema) keyID(key APIItem) string {
+	id := keyString(key[s.PartitionKey])
+	if s.SortKey != "" {
+		id += "\x00" + keyString(key[s.SortKey])
+	}
+	return id
+}
+
+// validateKey checks that the key contains exactly the key attributes, with the right types.
+func (s keySchema) validateKey(key APIItem, definitions []APIAttributeDefinition) *awserrors.Error {
+	if len(key) != len(s.names()) {
+		return ValidationException("The provided key element does not match the schema")
+	}
+	return s.validateItem(key, definitions)
+}
+
+// validateItem checks that the item contains all the key attributes, with the right types.
+func (s keySchema) validateItem(item APIItem, definitions []APIAttributeDefinition) *awserrors.Error {
+	for _, name := range s.names() {
+		v, ok := item[name]
+		if !ok {
+			return ValidationException(fmt.Sprintf(
+				"One or more parameter values were invalid: Missing the key %s in the item", name))
+		}
+		for _, definition := range definitions {
+			if definition.AttributeName == name && definition.AttributeType != v.Type() {
+				return ValidationException(fmt.Sprintf(
+					"One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s",
+					name, definition.AttributeType, v.Type()))
+			}
+		}
+		if v.Type() != "N" && keyString(v) == "" {
+			return ValidationException(fmt.Sprintf(
+				"One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name))
+		}
+	}
+	return nil
+}
+
+// validateItem checks that the item can be written to the table and all its indexes.
+func (t *Table) validateItem(item APIItem) *awserrors.Error {
+	if itemSize(item) > maxItemSize {
+		return ValidationException("Item size has exceeded the maximum allowed size")
+	}
+	if awserr := t.keySchema.validateItem(item, t.AttributeDefinitions); awserr != nil {
+		return awserr
+	}
+	for _, index := range t.indexes {
+		if awserr := index.validateItem(item, t.AttributeDefinitions); awserr != nil {
+			return awserr
+		}
+	}
+	return nil
+}
+
+// putItem inserts or replaces the item, keeping the indexes and stream up to date.
+// It returns the previous item if any.
+func (t *Table) putItem(item APIItem) (APIItem, bool, error) {
+	old, existed, err := t.items.put(item)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, index := range t.indexes {
+		if existed {
+			if err := index.remove(old); err != nil {
+				return nil, false, err
+			}
+		}
+		if err := index.add(item); err != nil {
+			return nil, false, err
+		}
+	}
+	if t.consistency != nil {
+		t.consistency.recordWrite(t.keySchema, item, old)
+	}
+	t.recordChange(old, item, nil)
+	return old, existed, nil
+}
+
+// deleteItem removes the item with the given key, keeping the indexes and stream up to date.
+// It returns the deleted item if it existed.
+func (t *Table) deleteItem(key APIItem) (APIItem, bool, error) {
+	return t.deleteItemAs(key, nil)
+}
+
+// deleteItemAs is deleteItem, with the identity to record in the stream.
+func (t *Table) deleteItemAs(key APIItem, identity *APIIdentity) (APIItem, bool, error) {
+	old, existed, err := t.items.delete(key)
+	if err != nil || !existed {
+		return nil, false, err
+	}
+	for _, index := range t.indexes {
+		if err := index.remove(old); err != nil {
+			return nil, false, err
+		}
+	}
+	if t.consistency != nil {
+		t.consistency.recordWrite(t.keySchema, old, old)
+	}
+	t.recordChange(old, nil, identity)
+	return old, true, nil
+}
+
+// itemCollection stores items grouped into partitions by partition key,
+// sorted by sort key within each partition.
+//
+// If the items are kept in storage, the partitions only have their keys, so the collection
+// doesn't need to fit in memory, and the items are read from storage as they're needed.
+// Otherwise the partitions have the items themselves.
+type itemCollection struct {
+	schema keySchema
+	// For secondary indexes, the key schema of the table. Index keys aren't unique,
+	// so items with the same index key are ordered by their table key.
+	tableSchema *keySchema
+	partitions  map[string][]APIItem
+	count       int
+	// The items by key ID, or nil if the partitions have them.
+	items *storage.Map[APIItem]
+	// For views, the items which reads see instead of the stored ones, by key ID. Views are only
+	// used for reading, so writes to them never reach storage.
+	overlay map[string]APIItem
+}
+
+func newItemCollection(schema keySchema) *itemCollection {
+	return &itemCollection{
+		schema:     schema,
+		partitions: make(map[string][]APIItem),
+	}
+}
+
+func newIndexItemCollection(schema keySchema, tableSchema keySchema) *itemCollection {
+	c := newItemCollection(schema)
+	c.tableSchema = &tableSchema
+	return c
+}
+
+// restore fills the partitions with the keys of the items already in storage, such as when the
+// emulator restarts.
+func (c *itemCollection) restore() error {
+	if c.items == nil {
+		return nil
+	}
+	err := c.items.Scan("", func(_ string, item APIItem) bool {
+		partitionKey := keyString(item[c.schema.PartitionKey])
+		c.partitions[partitionKey] = append(c.partitions[partitionKey], c.extractKey(item))
+		c.count++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	// Storage is in key ID order, which doesn't order numbers by value.
+	for _, partition := range c.partitions {
+		slices.SortFunc(partition, c.compareSortKeys)
+	}
+	return nil
+}
+
+// drop deletes the collection's items from storage, once it's no longer used.
+func (c *itemCollection) drop() error {
+	if c.items == nil {
+		return nil
+	}
+	return c.items.Drop()
+}
+
+// keyNames returns the attributes which uniquely identify an item in the collection.
+func (c *itemCollection) keyNames() []string {
+	names := c.schema.names()
+	if c.tableSchema != nil {
+		for _, name := range c.tableSchema.names() {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func (c *itemCollection) extractKey(item APIItem) APIItem {
+	key := make(APIItem)
+	for _, name := range c.keyNames() {
+		if v, ok := item[name]; ok {
+			key[name] = v
+		}
+	}
+	return key
+}
+
+// keyID returns a string uniquely identifying the item in the collection, which it's stored by.
+func (c *itemCollection) keyID(item APIItem) string {
+	var id strings.Builder
+	for i, name := range c.keyNames() {
+		if i > 0 {
+			id.WriteByte(0)
+		}
+		id.WriteString(keyString(item[name]))
+	}
+	return id.String()
+}
+
+// hasKey returns whether the item has all the key attributes of the collection.
+// Items missing an index key attribute are not included in the index.
+func (c *itemCollection) hasKey(item APIItem) bool {
+	for _, name := range c.schema.names() {
+		if _, ok := item[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *itemCollection) compareSortKeys(a, b APIItem) int {
+	cmp := 0
+	if c.schema.SortKey != "" {
+		cmp, _ = compareValues(a[c.schema.SortKey], b[c.schema.SortKey])
+	}
+	if cmp != 0 || c.tableSchema == nil {
+		return cmp
+	}
+
+	partitionA := keyString(a[c.tableSchema.PartitionKey])
+	partitionB := keyString(b[c.tableSchema.PartitionKey])
+	if partitionA != partitionB {
+		return strings.Compare(partitionA, partitionB)
+	}
+	if c.tableSchema.SortKey != "" {
+		cmp, _ = compareValues(a[c.tableSchema.SortKey], b[c.tableSchema.SortKey])
+	}
+	return cmp
+}
+
+// compareKeys orders items the same way a Scan visits them.
+func (c *itemCollection) compareKeys(a, b APIItem) int {
+	partitionA := keyString(a[c.schema.PartitionKey])
+	partitionB := keyString(b[c.schema.PartitionKey])
+	if partitionA < partitionB {
+		return -1
+	}
+	if partitionA > partitionB {
+		return 1
+	}
+	return c.compareSortKeys(a, b)
+}
+
+// find returns the partition containing the key, and the index at which the
+// item with this key is (or would be inserted).
+func (c *itemCollection) find(key APIItem) (string, int, bool) {
+	partitionKey := keyString(key[c.schema.PartitionKey])
+	partition := c.partitions[partitionKey]
+	i := sort.Search(len(partition), func(i int) bool {
+		return c.compareSortKeys(partition[i], key) >= 0
+	})
+	found := i < len(partition) && c.compareSortKeys(partition[i], key) == 0
+	return partitionKey, i, found
+}
+
+// load returns the item with the key, which is one of the partitions' keys.
+func (c *itemCollection) load(key APIItem) (APIItem, error) {
+	if c.items == nil {
+		return key, nil
+	}
+	id := c.keyID(key)
+	if item, ok := c.overlay[id]; ok {
+		return item, nil
+	}
+	item, ok, err := c.items.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("item %q is missing from storage", id)
+	}
+	return item, nil
+}
+
+func (c *itemCollection) get(key APIItem) (APIItem, bool, error) {
+	partitionKey, i, found := c.find(key)
+	if !found {
+		return nil, false, nil
+	}
+	item, err := c.load(c.partitions[partitionKey][i])
+	return item, err == nil, err
+}
+
+// put inserts or replaces the item, returning the previous item if any.
+func (c *itemCollection) put(item APIItem) (APIItem, bool, error) {
+	partitionKey, i, found := c.find(item)
+	partition := c.partitions[partitionKey]
+	var old APIItem
+	if found {
+		var err error
+		if old, err = c.load(partition[i]); err != nil {
+			return nil, false, err
+		}
+	}
+
+	key := item
+	if c.items != nil {
+		key = c.extractKey(item)
+		if c.overlay != nil {
+			c.overlay[c.keyID(item)] = item
+		} else if err := c.items.Put(c.keyID(item), item); err != nil {
+			return nil, false, err
+		}
+	}
+	if found {
+		partition[i] = key
+		return old, true, nil
+	}
+	c.partitions[partitionKey] = slices.Insert(partition, i, key)
+	c.count += 1
+	return nil, false, nil
+}
+
+// delete removes the item with the given key, returning it if it existed.
+func (c *itemCollection) delete(key APIItem) (APIItem, bool, error) {
+	partitionKey, i, found := c.find(key)
+	if !found {
+		return nil, false, nil
+	}
+	partition := c.partitions[partitionKey]
+	old, err := c.load(partition[i])
+	if err != nil {
+		return nil, false, err
+	}
+	if c.items != nil {
+		if c.overlay != nil {
+			delete(c.overlay, c.keyID(key))
+		} else if err := c.items.Delete(c.keyID(key)); err != nil {
+			return nil, false, err
+		}
+	}
+
+	partition = slices.Delete(partition, i, i+1)
+	if len(partition) == 0 {
+		delete(c.partitions, partitionKey)
+	} else {
+		c.partitions[partitionKey] = partition
+	}
+	c.count -= 1
+	return old, true, nil
+}
+
+// partition returns the keys of the items sharing the partition key, in sort key order.
+func (c *itemCollection) partition(partitionKeyValue APIAttributeValue) []APIItem {
+	return c.partitions[keyString(partitionKeyValue)]
+}
+
+// scan returns the keys of the items belonging to the segment, in scan order.
+func (c *itemCollection) scan(segment, totalSegments int) []APIItem {
+	var keys []APIItem
+	for _, partitionKey := range sortedKeys(c.partitions) {
+		if totalSegments > 1 && segmentFor(partitionKey, totalSegments) != segment {
+			continue
+		}
+		keys = append(keys, c.partitions[partitionKey]...)
+	}
+	return keys
+}
+
+// each calls f with every item, in scan order, until it returns an error.
+func (c *itemCollection) each(f func(item APIItem) error) error {
+	for _, key := range c.scan(0, 1) {
+		item, err := c.load(key)
+		if err != nil {
+			return err
+		}
+		if err := f(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func segmentFor(partitionKey string, totalSegments int) int {
+	h := fnv.New32a()
+	h.Write([]byte(partitionKey))
+	return int(h.Sum32() % uint32(totalSegments))
+}
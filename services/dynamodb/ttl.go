@@ -0,0 +1,114 @@
+package dynamodb
+
+import (
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// Items which expired more than this long ago are never deleted.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/time-to-live-ttl-before-you-start.html
+const maxTimeToLiveAge = 5 * 365 * 24 * time.Hour
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_UpdateTimeToLive.html
+func (d *DynamoDB) UpdateTimeToLive(input UpdateTimeToLiveInput) (*UpdateTimeToLiveOutput, *awserrors.Error) {
+	spec := input.TimeToLiveSpecification
+	if spec.AttributeName == "" {
+		return nil, ValidationException("TimeToLiveSpecification.AttributeName must not be empty")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	if spec.Enabled {
+		if t.timeToLiveAttribute != "" {
+			return nil, ValidationException("TimeToLive is already enabled")
+		}
+		t.timeToLiveAttribute = spec.AttributeName
+	} else {
+		if t.timeToLiveAttribute == "" {
+			return nil, ValidationException("TimeToLive is already disabled")
+		}
+		t.timeToLiveAttribute = ""
+	}
+	if awserr := d.lockedSaveTable(t); awserr != nil {
+		return nil, awserr
+	}
+
+	return &UpdateTimeToLiveOutput{
+		TimeToLiveSpecification: spec,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_DescribeTimeToLive.html
+func (d *DynamoDB) DescribeTimeToLive(input DescribeTimeToLiveInput) (*DescribeTimeToLiveOutput, *awserrors.Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, awserr := d.lockedGetTable(input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	description := APITimeToLiveDescription{TimeToLiveStatus: "DISABLED"}
+	if t.timeToLiveAttribute != "" {
+		description = APITimeToLiveDescription{
+			AttributeName:    t.timeToLiveAttribute,
+			TimeToLiveStatus: "ENABLED",
+		}
+	}
+	return &DescribeTimeToLiveOutput{
+		TimeToLiveDescription: description,
+	}, nil
+}
+
+// deleteExpiredItems removes the items whose time to live attribute is in the past.
+// Deletions are recorded in the table's stream as made by the DynamoDB service.
+func (d *DynamoDB) deleteExpiredItems() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock()
+	oldest := now.Add(-maxTimeToLiveAge).Unix()
+	for _, tableName := range sortedKeys(d.tablesByName) {
+		t := d.tablesByName[tableName]
+		if t.timeToLiveAttribute == "" {
+			continue
+		}
+
+		var expired []APIItem
+		err := t.items.each(func(item APIItem) error {
+			v, ok := item[t.timeToLiveAttribute]
+			if !ok || v.Type() != "N" {
+				return nil
+			}
+			expiry, ok := parseNumber(v.N)
+			if !ok || !expiry.IsInt() {
+				// Only whole numbers of seconds are valid.
+				return nil
+			}
+			if seconds := expiry.Num(); seconds.IsInt64() && seconds.Int64() <= now.Unix() && seconds.Int64() >= oldest {
+				expired = append(expired, t.keySchema.extractKey(item))
+			}
+			return nil
+		})
+		for _, key := range expired {
+			if err != nil {
+				break
+			}
+			_, _, err = t.deleteItemAs(key, ttlIdentity)
+		}
+		if err != nil {
+			d.logger.Error("Deleting expired items", "table", tableName, "err", err)
+			continue
+		}
+		if len(expired) > 0 {
+			d.logger.Debug("Deleted expired items", "table", tableName, "count", len(expired))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package dynamodb
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTimeToLive(t *testing.T) {
+	d := newDynamoDBWithTable()
+	now := time.Unix(1_700_000_000, 0)
+	d.clock = func() time.Time { return now }
+
+	_, err := d.UpdateTable(UpdateTableInput{
+		TableName: tableName,
+		StreamSpecification: &APIStreamSpecification{
+			StreamEnabled:  true,
+			StreamViewType: "OLD_IMAGE",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.UpdateTimeToLive(UpdateTimeToLiveInput{
+		TableName: tableName,
+		TimeToLiveSpecification: APITimeToLiveSpecification{
+			AttributeName: "expiry",
+			Enabled:       true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	describe, err := d.DescribeTimeToLive(DescribeTimeToLiveInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if describe.TimeToLiveDescription.TimeToLiveStatus != "ENABLED" {
+		t.Fatal("Wrong status", describe.TimeToLiveDescription)
+	}
+
+	expiries := map[string]APIAttributeValue{
+		"0": {N: strconv.FormatInt(now.Unix()-1, 10)},
+		"1": {N: strconv.FormatInt(now.Unix()+1, 10)},
+		// More than five years in the past is ignored.
+		"2": {N: "1"},
+		"3": {S: strconv.FormatInt(now.Unix()-1, 10)},
+		"4": {N: strconv.FormatInt(now.Unix(), 10)},
+	}
+	for sk, expiry := range expiries {
+		_, err := d.UpdateItem(UpdateItemInput{
+			TableName:        tableName,
+			Key:              APIItem{"pk": {S: "a"}, "sk": {N: sk}},
+			UpdateExpression: "SET expiry = :e",
+			ExpressionAttributeValues: map[string]APIAttributeValue{
+				":e": expiry,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.deleteExpiredItems()
+
+	scan, err := d.Scan(ScanInput{TableName: tableName, Select: "COUNT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Count != 18 {
+		t.Fatal("Wrong item count", scan.Count)
+	}
+
+	streams, err := d.ListStreams(ListStreamsInput{TableName: tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterator, err := d.GetShardIterator(GetShardIteratorInput{
+		StreamArn:         streams.Streams[0].StreamArn,
+		ShardId:           streamShardId,
+		ShardIteratorType: "TRIM_HORIZON",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := d.GetRecords(GetRecordsInput{ShardIterator: iterator.ShardIterator})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var removed []string
+	for _, record := range records.Records {
+		if record.EventName != "REMOVE" {
+			continue
+		}
+		if record.UserIdentity == nil || record.UserIdentity.PrincipalId != "dynamodb.amazonaws.com" {
+			t.Fatal("Missing TTL identity", record)
+		}
+		if record.Dynamodb.OldImage["expiry"].N == "" {
+			t.Fatal("Missing old image", record)
+		}
+		removed = append(removed, record.Dynamodb.Keys["sk"].N)
+	}
+	if len(removed) != 2 || removed[0] != "0" || removed[1] != "4" {
+		t.Fatal("Wrong removals", removed)
+	}
+
+	_, err = d.UpdateTimeToLive(UpdateTimeToLiveInput{
+		TableName: tableName,
+		TimeToLiveSpecification: APITimeToLiveSpecification{
+			AttributeName: "expiry",
+			Enabled:       true,
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected error enabling TTL twice")
+	}
+}
@@ -1,10 +1,14 @@
 package dynamodb
 
 type CreateTableInput struct {
-	AttributeDefinitions []APIAttributeDefinition
-	TableName            string
-	BillingMode          string
-	KeySchema            []APIKeySchemaElement
+	AttributeDefinitions   []APIAttributeDefinition  `required:"true"`
+	TableName              string                    `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	BillingMode            string                    `enum:"PROVISIONED|PAY_PER_REQUEST"`
+	GlobalSecondaryIndexes []APIGlobalSecondaryIndex `length:",20"`
+	KeySchema              []APIKeySchemaElement     `required:"true" length:"1,2"`
+	LocalSecondaryIndexes  []APILocalSecondaryIndex  `length:",5"`
+	ProvisionedThroughput  *APIProvisionedThroughput
+	StreamSpecification    *APIStreamSpecification
 }
 
 type CreateTableOutput struct {
@@ -12,48 +16,195 @@ type CreateTableOutput struct {
 }
 
 type APITableDescription struct {
-	AttributeDefinitions []APIAttributeDefinition
-	ItemCount            int
-	KeySchema            []APIKeySchemaElement
-	TableARN             string
-	TableStatus          string
+	AttributeDefinitions   []APIAttributeDefinition
+	BillingModeSummary     APIBillingModeSummary
+	GlobalSecondaryIndexes []APIGlobalSecondaryIndexDescription `json:",omitempty"`
+	ItemCount              int
+	KeySchema              []APIKeySchemaElement
+	LatestStreamArn        string                              `json:",omitempty"`
+	LatestStreamLabel      string                              `json:",omitempty"`
+	LocalSecondaryIndexes  []APILocalSecondaryIndexDescription `json:",omitempty"`
+	ProvisionedThroughput  APIProvisionedThroughputDescription
+	StreamSpecification    *APIStreamSpecification `json:",omitempty"`
+	TableARN               string
+	TableStatus            string
+}
+
+type APIStreamSpecification struct {
+	StreamEnabled  bool
+	StreamViewType string `json:",omitempty" enum:"NEW_IMAGE|OLD_IMAGE|NEW_AND_OLD_IMAGES|KEYS_ONLY"`
+}
+
+type APIProvisionedThroughput struct {
+	ReadCapacityUnits  int64 `range:"1,"`
+	WriteCapacityUnits int64 `range:"1,"`
+}
+
+type APIProvisionedThroughputDescription struct {
+	NumberOfDecreasesToday int64
+	ReadCapacityUnits      int64
+	WriteCapacityUnits     int64
+}
+
+type APIBillingModeSummary struct {
+	BillingMode string
+}
+
+type APICapacity struct {
+	CapacityUnits      float64
+	ReadCapacityUnits  float64 `json:",omitempty"`
+	WriteCapacityUnits float64 `json:",omitempty"`
+}
+
+type APIConsumedCapacity struct {
+	CapacityUnits          float64
+	GlobalSecondaryIndexes map[string]APICapacity `json:",omitempty"`
+	LocalSecondaryIndexes  map[string]APICapacity `json:",omitempty"`
+	ReadCapacityUnits      float64                `json:",omitempty"`
+	Table                  *APICapacity           `json:",omitempty"`
+	TableName              string
+	WriteCapacityUnits     float64 `json:",omitempty"`
+}
+
+type APIProjection struct {
+	NonKeyAttributes []string `json:",omitempty" length:"1,20"`
+	ProjectionType   string   `enum:"ALL|KEYS_ONLY|INCLUDE"`
+}
+
+type APIGlobalSecondaryIndex struct {
+	IndexName             string                `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	KeySchema             []APIKeySchemaElement `required:"true" length:"1,2"`
+	Projection            APIProjection
+	ProvisionedThroughput *APIProvisionedThroughput
+}
+
+type APILocalSecondaryIndex struct {
+	IndexName  string                `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	KeySchema  []APIKeySchemaElement `required:"true" length:"1,2"`
+	Projection APIProjection
+}
+
+type APIGlobalSecondaryIndexDescription struct {
+	IndexArn              string
+	IndexName             string
+	IndexStatus           string
+	ItemCount             int
+	KeySchema             []APIKeySchemaElement
+	Projection            APIProjection
+	ProvisionedThroughput APIProvisionedThroughputDescription
+}
+
+type APILocalSecondaryIndexDescription struct {
+	IndexArn   string
+	IndexName  string
+	ItemCount  int
+	KeySchema  []APIKeySchemaElement
+	Projection APIProjection
 }
 
 type APIAttributeDefinition struct {
-	AttributeName string
-	AttributeType string
+	AttributeName string `required:"true" length:"1,255"`
+	AttributeType string `required:"true" enum:"S|N|B"`
 }
 
 type APIKeySchemaElement struct {
-	AttributeName string
-	KeyType       string
+	AttributeName string `required:"true" length:"1,255"`
+	KeyType       string `required:"true" enum:"HASH|RANGE"`
+}
+
+type DeleteTableInput struct {
+	TableName string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+}
+
+type DeleteTableOutput struct {
+	TableDescription APITableDescription
 }
 
 type DescribeTableInput struct {
-	TableName string
+	TableName string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
 }
 
 type DescribeTableOutput struct {
 	Table APITableDescription
 }
 
+type UpdateTableInput struct {
+	AttributeDefinitions        []APIAttributeDefinition
+	BillingMode                 string `enum:"PROVISIONED|PAY_PER_REQUEST"`
+	GlobalSecondaryIndexUpdates []APIGlobalSecondaryIndexUpdate
+	ProvisionedThroughput       *APIProvisionedThroughput
+	StreamSpecification         *APIStreamSpecification
+	TableName                   string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+}
+
+// Exactly one of the fields should be set.
+type APIGlobalSecondaryIndexUpdate struct {
+	Create *APIGlobalSecondaryIndex
+	Delete *struct {
+		IndexName string
+	}
+	Update *struct {
+		IndexName             string
+		ProvisionedThroughput *APIProvisionedThroughput
+	}
+}
+
+type UpdateTableOutput struct {
+	TableDescription APITableDescription
+}
+
 type ScanInput struct {
-	FilterExpression     string
-	Limit                int
-	ProjectionExpression string
-	Select               string
-	TableName            string
+	ConsistentRead            bool
+	ExclusiveStartKey         APIItem
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]APIAttributeValue
+	FilterExpression          string `length:",4096"`
+	IndexName                 string `length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	Limit                     int    `range:"1,"`
+	ProjectionExpression      string `length:",4096"`
+	ReturnConsumedCapacity    string `enum:"INDEXES|TOTAL|NONE"`
+	Segment                   *int   `range:"0,999999"`
+	Select                    string `enum:"ALL_ATTRIBUTES|ALL_PROJECTED_ATTRIBUTES|SPECIFIC_ATTRIBUTES|COUNT"`
+	TableName                 string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	TotalSegments             *int   `range:"1,1000000"`
 }
 
 type ScanOutput struct {
-	Count        int
-	Items        []APIItem
-	ScannedCount int
+	ConsumedCapacity *APIConsumedCapacity `json:",omitempty"`
+	Count            int
+	Items            []APIItem
+	LastEvaluatedKey APIItem
+	ScannedCount     int
+}
+
+type QueryInput struct {
+	ConsistentRead            bool
+	ExclusiveStartKey         APIItem
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]APIAttributeValue
+	FilterExpression          string `length:",4096"`
+	IndexName                 string `length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	KeyConditionExpression    string `length:",4096"`
+	Limit                     int    `range:"1,"`
+	ProjectionExpression      string `length:",4096"`
+	ReturnConsumedCapacity    string `enum:"INDEXES|TOTAL|NONE"`
+	ScanIndexForward          *bool
+	Select                    string `enum:"ALL_ATTRIBUTES|ALL_PROJECTED_ATTRIBUTES|SPECIFIC_ATTRIBUTES|COUNT"`
+	TableName                 string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
 }
 
+type QueryOutput struct {
+	ConsumedCapacity *APIConsumedCapacity `json:",omitempty"`
+	Count            int
+	Items            []APIItem
+	LastEvaluatedKey APIItem
+	ScannedCount     int
+}
+
+// Exactly one of the fields should be set. See MarshalJSON.
 type APIAttributeValue struct {
 	B    string // base64 encoded binary
-	BOOL bool
+	BOOL *bool
 	BS   []string                     // base64 encoded binary set
 	L    []APIAttributeValue          // list
 	M    map[string]APIAttributeValue // map
@@ -65,35 +216,229 @@ type APIAttributeValue struct {
 }
 
 type PutItemInput struct {
-	Expected map[string]struct {
+	ConditionExpression string `length:",4096"`
+	Expected            map[string]struct {
 		AttributeValueList []APIAttributeValue
 		ComparisonOperator string
 		Exists             bool
 		Value              APIAttributeValue
 	}
-	Item      APIItem
-	TableName string
+	ExpressionAttributeNames            map[string]string
+	ExpressionAttributeValues           map[string]APIAttributeValue
+	Item                                APIItem `required:"true"`
+	ReturnConsumedCapacity              string  `enum:"INDEXES|TOTAL|NONE"`
+	ReturnValues                        string  `enum:"NONE|ALL_OLD|UPDATED_OLD|ALL_NEW|UPDATED_NEW"`
+	ReturnValuesOnConditionCheckFailure string  `enum:"ALL_OLD|NONE"`
+	TableName                           string  `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
 }
 
 type APIItem map[string]APIAttributeValue
 
-type PutItemOutput struct{}
+type PutItemOutput struct {
+	Attributes       APIItem
+	ConsumedCapacity *APIConsumedCapacity `json:",omitempty"`
+}
+
+type DeleteItemInput struct {
+	ConditionExpression                 string `length:",4096"`
+	ExpressionAttributeNames            map[string]string
+	ExpressionAttributeValues           map[string]APIAttributeValue
+	Key                                 APIItem `required:"true"`
+	ReturnConsumedCapacity              string  `enum:"INDEXES|TOTAL|NONE"`
+	ReturnValues                        string  `enum:"NONE|ALL_OLD|UPDATED_OLD|ALL_NEW|UPDATED_NEW"`
+	ReturnValuesOnConditionCheckFailure string  `enum:"ALL_OLD|NONE"`
+	TableName                           string  `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+}
+
+type DeleteItemOutput struct {
+	Attributes       APIItem
+	ConsumedCapacity *APIConsumedCapacity `json:",omitempty"`
+}
 
 type UpdateItemInput struct {
 	AttributeUpdates map[string]struct {
 		Action string
 		Value  APIAttributeValue
 	}
-	Expected map[string]struct {
+	ConditionExpression string `length:",4096"`
+	Expected            map[string]struct {
 		AttributeValueList []APIAttributeValue
 		ComparisonOperator string
 		Exists             *bool // Support explicit false
 		Value              APIAttributeValue
 	}
-	Key       map[string]APIAttributeValue
-	TableName string
+	ExpressionAttributeNames            map[string]string
+	ExpressionAttributeValues           map[string]APIAttributeValue
+	Key                                 map[string]APIAttributeValue `required:"true"`
+	ReturnConsumedCapacity              string                       `enum:"INDEXES|TOTAL|NONE"`
+	ReturnValues                        string                       `enum:"NONE|ALL_OLD|UPDATED_OLD|ALL_NEW|UPDATED_NEW"`
+	ReturnValuesOnConditionCheckFailure string                       `enum:"ALL_OLD|NONE"`
+	TableName                           string                       `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	UpdateExpression                    string                       `length:",4096"`
 }
 
 type UpdateItemOutput struct {
-	Attributes APIItem
+	Attributes       APIItem
+	ConsumedCapacity *APIConsumedCapacity `json:",omitempty"`
+}
+
+type APIKeysAndAttributes struct {
+	ConsistentRead           bool
+	ExpressionAttributeNames map[string]string `json:",omitempty"`
+	Keys                     []APIItem         `required:"true" length:"1,100"`
+	ProjectionExpression     string            `json:",omitempty" length:",4096"`
+}
+
+type BatchGetItemInput struct {
+	RequestItems           map[string]APIKeysAndAttributes `required:"true" length:"1,100"`
+	ReturnConsumedCapacity string                          `enum:"INDEXES|TOTAL|NONE"`
+}
+
+type BatchGetItemOutput struct {
+	ConsumedCapacity []APIConsumedCapacity `json:",omitempty"`
+	Responses        map[string][]APIItem
+	UnprocessedKeys  map[string]APIKeysAndAttributes
+}
+
+// Exactly one of the fields should be set.
+type APIWriteRequest struct {
+	DeleteRequest *struct {
+		Key APIItem
+	} `json:",omitempty"`
+	PutRequest *struct {
+		Item APIItem
+	} `json:",omitempty"`
+}
+
+type BatchWriteItemInput struct {
+	RequestItems           map[string][]APIWriteRequest `required:"true" length:"1,25"`
+	ReturnConsumedCapacity string                       `enum:"INDEXES|TOTAL|NONE"`
+}
+
+type BatchWriteItemOutput struct {
+	ConsumedCapacity []APIConsumedCapacity `json:",omitempty"`
+	UnprocessedItems map[string][]APIWriteRequest
+}
+
+type APITimeToLiveSpecification struct {
+	AttributeName string `required:"true" length:"1,255"`
+	Enabled       bool
+}
+
+type UpdateTimeToLiveInput struct {
+	TableName               string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+	TimeToLiveSpecification APITimeToLiveSpecification
+}
+
+type UpdateTimeToLiveOutput struct {
+	TimeToLiveSpecification APITimeToLiveSpecification
+}
+
+type DescribeTimeToLiveInput struct {
+	TableName string `required:"true" length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+}
+
+type APITimeToLiveDescription struct {
+	AttributeName    string `json:",omitempty"`
+	TimeToLiveStatus string
+}
+
+type DescribeTimeToLiveOutput struct {
+	TimeToLiveDescription APITimeToLiveDescription
+}
+
+// DynamoDB Streams
+
+type APIStream struct {
+	StreamArn   string
+	StreamLabel string
+	TableName   string
+}
+
+type ListStreamsInput struct {
+	ExclusiveStartStreamArn string `length:"37,1024"`
+	Limit                   int    `range:"1,100"`
+	TableName               string `length:"3,255" pattern:"[a-zA-Z0-9_.-]+"`
+}
+
+type ListStreamsOutput struct {
+	LastEvaluatedStreamArn string `json:",omitempty"`
+	Streams                []APIStream
+}
+
+type DescribeStreamInput struct {
+	ExclusiveStartShardId string `length:"28,65"`
+	Limit                 int    `range:"1,100"`
+	StreamArn             string `required:"true" length:"37,1024"`
+}
+
+type APISequenceNumberRange struct {
+	EndingSequenceNumber   string `json:",omitempty"`
+	StartingSequenceNumber string
+}
+
+type APIShard struct {
+	SequenceNumberRange APISequenceNumberRange
+	ShardId             string
+}
+
+type APIStreamDescription struct {
+	CreationRequestDateTime int64
+	KeySchema               []APIKeySchemaElement
+	Shards                  []APIShard
+	StreamArn               string
+	StreamLabel             string
+	StreamStatus            string
+	StreamViewType          string
+	TableName               string
+}
+
+type DescribeStreamOutput struct {
+	StreamDescription APIStreamDescription
+}
+
+type GetShardIteratorInput struct {
+	SequenceNumber    string `length:"21,40"`
+	ShardId           string `required:"true" length:"28,65"`
+	ShardIteratorType string `required:"true" enum:"TRIM_HORIZON|LATEST|AT_SEQUENCE_NUMBER|AFTER_SEQUENCE_NUMBER"`
+	StreamArn         string `required:"true" length:"37,1024"`
+}
+
+type GetShardIteratorOutput struct {
+	ShardIterator string
+}
+
+type GetRecordsInput struct {
+	Limit         int    `range:"1,1000"`
+	ShardIterator string `required:"true" length:"1,2048"`
+}
+
+type APIIdentity struct {
+	PrincipalId string
+	Type        string
+}
+
+type APIStreamRecord struct {
+	ApproximateCreationDateTime int64
+	Keys                        APIItem
+	NewImage                    APIItem `json:",omitempty"`
+	OldImage                    APIItem `json:",omitempty"`
+	SequenceNumber              string
+	SizeBytes                   int
+	StreamViewType              string
+}
+
+type APIRecord struct {
+	AwsRegion    string
+	Dynamodb     APIStreamRecord
+	EventID      string
+	EventName    string
+	EventSource  string
+	EventVersion string
+	UserIdentity *APIIdentity `json:",omitempty"`
+}
+
+type GetRecordsOutput struct {
+	NextShardIterator string `json:",omitempty"`
+	Records           []APIRecord
 }
@@ -0,0 +1,500 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// This file implements UpdateExpression.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.UpdateExpressions.html
+
+var (
+	errIncorrectOperandType = errors.New("An operand in the update expression has an incorrect data type")
+	errMissingAttribute     = errors.New("The provided expression refers to an attribute that does not exist in the item")
+	errInvalidPath          = errors.New("The document path provided in the update expression is invalid for update")
+)
+
+// updateValue is the right hand side of a SET action.
+type updateValue interface {
+	evaluateUpdate(item APIItem) (APIAttributeValue, error)
+}
+
+type operandValue struct {
+	operand operand
+}
+
+func (v operandValue) evaluateUpdate(item APIItem) (APIAttributeValue, error) {
+	value, ok := v.operand.evaluate(item)
+	if !ok {
+		return value, errMissingAttribute
+	}
+	return value, nil
+}
+
+type ifNotExistsValue struct {
+	path     attributePath
+	fallback updateValue
+}
+
+func (v ifNotExistsValue) evaluateUpdate(item APIItem) (APIAttributeValue, error) {
+	if value, ok := v.path.resolve(item); ok {
+		return value, nil
+	}
+	return v.fallback.evaluateUpdate(item)
+}
+
+type listAppendValue struct {
+	first, second updateValue
+}
+
+func (v listAppendValue) evaluateUpdate(item APIItem) (APIAttributeValue, error) {
+	first, err := v.first.evaluateUpdate(item)
+	if err != nil {
+		return first, err
+	}
+	second, err := v.second.evaluateUpdate(item)
+	if err != nil {
+		return second, err
+	}
+	if first.Type() != "L" || second.Type() != "L" {
+		return APIAttributeValue{}, errIncorrectOperandType
+	}
+	l := append(slices.Clone(first.L), second.L...)
+	return APIAttributeValue{L: l}, nil
+}
+
+type arithmeticValue struct {
+	operator    string
+	left, right updateValue
+}
+
+func (v arithmeticValue) evaluateUpdate(item APIItem) (APIAttributeValue, error) {
+	left, err := v.left.evaluateUpdate(item)
+	if err != nil {
+		return left, err
+	}
+	right, err := v.right.evaluateUpdate(item)
+	if err != nil {
+		return right, err
+	}
+	if left.Type() != "N" || right.Type() != "N" {
+		return APIAttributeValue{}, errIncorrectOperandType
+	}
+	l, ok1 := parseNumber(left.N)
+	r, ok2 := parseNumber(right.N)
+	if !ok1 || !ok2 {
+		return APIAttributeValue{}, errIncorrectOperandType
+	}
+	if v.operator == "+" {
+		return numberValue(l.Add(l, r)), nil
+	}
+	return numberValue(l.Sub(l, r)), nil
+}
+
+type setAction struct {
+	path  attributePath
+	value updateValue
+}
+
+// addAction is used for both ADD and DELETE.
+type addAction struct {
+	path  attributePath
+	value APIAttributeValue
+}
+
+type updateExpression struct {
+	set    []setAction
+	remove []attributePath
+	add    []addAction
+	delete []addAction
+}
+
+func parseUpdate(
+	expression string,
+	names map[string]string,
+	values map[string]APIAttributeValue,
+) (*updateExpression, error) {
+	p, err := newParser(expression, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &updateExpression{}
+	seenClauses := make(map[string]bool)
+	for p.peek().kind != tokenEOF {
+		t := p.next()
+		if t.kind != tokenIdentifier {
+			return nil, p.syntaxError(t)
+		}
+		clause := strings.ToUpper(t.text)
+		if seenClauses[clause] {
+			return nil, fmt.Errorf("The \"%s\" section can only be used once in an update expression", clause)
+		}
+		seenClauses[clause] = true
+
+		for {
+			switch clause {
+			case "SET":
+				path, err := p.parsePath()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunctuation("="); err != nil {
+					return nil, err
+				}
+				value, err := p.parseUpdateValue()
+				if err != nil {
+					return nil, err
+				}
+				update.set = append(update.set, setAction{path: path, value: value})
+			case "REMOVE":
+				path, err := p.parsePath()
+				if err != nil {
+					return nil, err
+				}
+				update.remove = append(update.remove, path)
+			case "ADD", "DELETE":
+				path, err := p.parsePath()
+				if err != nil {
+					return nil, err
+				}
+				t := p.next()
+				if t.kind != tokenValue {
+					return nil, p.syntaxError(t)
+				}
+				value, err := p.resolveValue(t)
+				if err != nil {
+					return nil, err
+				}
+				if clause == "ADD" {
+					update.add = append(update.add, addAction{path: path, value: value})
+				} else {
+					update.delete = append(update.delete, addAction{path: path, value: value})
+				}
+			default:
+				return nil, p.syntaxError(t)
+			}
+
+			if !p.peekPunctuation(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if len(seenClauses) == 0 {
+		return nil, errors.New("The expression can not be empty")
+	}
+
+	// Validate that no two actions touch overlapping paths.
+	if _, err := update.paths().tree(); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+func (p *parser) parseUpdateValue() (updateValue, error) {
+	left, err := p.parseUpdateOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.peekPunctuation("+") || p.peekPunctuation("-") {
+		operator := p.next().text
+		right, err := p.parseUpdateOperand()
+		if err != nil {
+			return nil, err
+		}
+		return arithmeticValue{operator: operator, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseUpdateOperand() (updateValue, error) {
+	switch {
+	case p.isFunctionCall("if_not_exists"):
+		p.next()
+		p.next()
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunctuation(","); err != nil {
+			return nil, err
+		}
+		fallback, err := p.parseUpdateOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunctuation(")"); err != nil {
+			return nil, err
+		}
+		return ifNotExistsValue{path: path, fallback: fallback}, nil
+	case p.isFunctionCall("list_append"):
+		p.next()
+		p.next()
+		first, err := p.parseUpdateOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunctuation(","); err != nil {
+			return nil, err
+		}
+		second, err := p.parseUpdateOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunctuation(")"); err != nil {
+			return nil, err
+		}
+		return listAppendValue{first: first, second: second}, nil
+	case p.isFunctionCall("size"):
+		return nil, errors.New("Invalid UpdateExpression: The function is not allowed in an update expression; function: size")
+	}
+
+	o, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return operandValue{operand: o}, nil
+}
+
+// paths returns every document path modified by the update.
+func (u *updateExpression) paths() projection {
+	var paths projection
+	for _, action := range u.set {
+		paths = append(paths, action.path)
+	}
+	paths = append(paths, u.remove...)
+	for _, action := range u.add {
+		paths = append(paths, action.path)
+	}
+	for _, action := range u.delete {
+		paths = append(paths, action.path)
+	}
+	return paths
+}
+
+// apply performs the update on item in place.
+// The right hand side of every action is evaluated against the original item.
+func (u *updateExpression) apply(item APIItem, schema keySchema) error {
+	for _, path := range u.paths() {
+		for _, keyName := range schema.names() {
+			if path[0].name == keyName {
+				return fmt.Errorf("Cannot update attribute %s. This attribute is part of the key", keyName)
+			}
+		}
+	}
+
+	original := cloneItem(item)
+
+	setValues := make([]APIAttributeValue, len(u.set))
+	for i, action := range u.set {
+		value, err := action.value.evaluateUpdate(original)
+		if err != nil {
+			return err
+		}
+		setValues[i] = value
+	}
+
+	for i, action := range u.set {
+		if err := setPath(item, action.path, cloneValue(setValues[i])); err != nil {
+			return err
+		}
+	}
+
+	for _, action := range u.add {
+		existing, ok := action.path.resolve(original)
+		var updated APIAttributeValue
+		switch action.value.Type() {
+		case "N":
+			if !ok {
+				updated = action.value
+				break
+			}
+			if existing.Type() != "N" {
+				return errIncorrectOperandType
+			}
+			l, ok1 := parseNumber(existing.N)
+			r, ok2 := parseNumber(action.value.N)
+			if !ok1 || !ok2 {
+				return errIncorrectOperandType
+			}
+			updated = numberValue(l.Add(l, r))
+		case "SS", "NS", "BS":
+			if !ok {
+				updated = cloneValue(action.value)
+				break
+			}
+			if existing.Type() != action.value.Type() {
+				return errIncorrectOperandType
+			}
+			updated = cloneValue(existing)
+			for _, member := range setMembers(action.value) {
+				if !setHasMember(updated, member) {
+					updated = setAppend(updated, member)
+				}
+			}
+		default:
+			return errIncorrectOperandType
+		}
+		if err := setPath(item, action.path, updated); err != nil {
+			return err
+		}
+	}
+
+	for _, action := range u.delete {
+		switch action.value.Type() {
+		case "SS", "NS", "BS":
+		default:
+			return errIncorrectOperandType
+		}
+		existing, ok := action.path.resolve(original)
+		if !ok {
+			continue
+		}
+		if existing.Type() != action.value.Type() {
+			return errIncorrectOperandType
+		}
+		var remaining APIAttributeValue
+		for _, member := range setMembers(existing) {
+			if !setHasMember(action.value, member) {
+				remaining = setAppend(remaining, member)
+			}
+		}
+		if len(setMembers(remaining)) == 0 {
+			removePath(item, action.path)
+		} else if err := setPath(item, action.path, remaining); err != nil {
+			return err
+		}
+	}
+
+	// Remove list elements from the highest index down so earlier removals
+	// don't shift the later ones.
+	removals := slices.Clone(u.remove)
+	slices.SortStableFunc(removals, func(a, b attributePath) int {
+		last := func(p attributePath) int {
+			if p[len(p)-1].isIndex {
+				return p[len(p)-1].index
+			}
+			return -1
+		}
+		return last(b) - last(a)
+	})
+	for _, path := range removals {
+		removePath(item, path)
+	}
+
+	return nil
+}
+
+func setHasMember(set APIAttributeValue, member APIAttributeValue) bool {
+	for _, m := range setMembers(set) {
+		if valuesEqual(m, member) {
+			return true
+		}
+	}
+	return false
+}
+
+func setAppend(set APIAttributeValue, member APIAttributeValue) APIAttributeValue {
+	switch member.Type() {
+	case "S":
+		set.SS = append(set.SS, member.S)
+	case "N":
+		set.NS = append(set.NS, member.N)
+	case "B":
+		set.BS = append(set.BS, member.B)
+	}
+	return set
+}
+
+func setPath(item APIItem, path attributePath, value APIAttributeValue) error {
+	return modifyPath(APIAttributeValue{M: item}, path, func(container APIAttributeValue, last pathElement) (APIAttributeValue, error) {
+		if last.isIndex {
+			if container.Type() != "L" {
+				return container, errInvalidPath
+			}
+			if last.index >= len(container.L) {
+				container.L = append(container.L, value)
+			} else {
+				container.L[last.index] = value
+			}
+			return container, nil
+		}
+		if container.Type() != "M" {
+			return container, errInvalidPath
+		}
+		container.M[last.name] = value
+		return container, nil
+	})
+}
+
+func removePath(item APIItem, path attributePath) {
+	modifyPath(APIAttributeValue{M: item}, path, func(container APIAttributeValue, last pathElement) (APIAttributeValue, error) {
+		if last.isIndex {
+			if container.Type() == "L" && last.index < len(container.L) {
+				container.L = slices.Delete(container.L, last.index, last.index+1)
+				if container.L == nil {
+					container.L = []APIAttributeValue{}
+				}
+			}
+		} else if container.Type() == "M" {
+			delete(container.M, last.name)
+		}
+		return container, nil
+	})
+}
+
+// modifyPath walks down to the container of the last path element, applies modify to it,
+// and writes the modified containers back up the path.
+func modifyPath(
+	container APIAttributeValue,
+	path attributePath,
+	modify func(container APIAttributeValue, last pathElement) (APIAttributeValue, error),
+) error {
+	if len(path) == 1 {
+		_, err := modify(container, path[0])
+		return err
+	}
+	_, err := modifyChild(container, path, modify)
+	return err
+}
+
+func modifyChild(
+	container APIAttributeValue,
+	path attributePath,
+	modify func(container APIAttributeValue, last pathElement) (APIAttributeValue, error),
+) (APIAttributeValue, error) {
+	if len(path) == 1 {
+		return modify(container, path[0])
+	}
+
+	elem := path[0]
+	if elem.isIndex {
+		if container.Type() != "L" || elem.index >= len(container.L) {
+			return container, errInvalidPath
+		}
+		child, err := modifyChild(container.L[elem.index], path[1:], modify)
+		if err != nil {
+			return container, err
+		}
+		container.L[elem.index] = child
+		return container, nil
+	}
+
+	if container.Type() != "M" {
+		return container, errInvalidPath
+	}
+	child, ok := container.M[elem.name]
+	if !ok {
+		return container, errInvalidPath
+	}
+	child, err := modifyChild(child, path[1:], modify)
+	if err != nil {
+		return container, err
+	}
+	container.M[elem.name] = child
+	return container, nil
+}
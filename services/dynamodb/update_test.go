@@ -0,0 +1,115 @@
+package dynamodb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateExpression(t *testing.T) {
+	names := map[string]string{"#c": "count"}
+	values := map[string]APIAttributeValue{
+		":one":   {N: "1"},
+		":half":  {N: "0.5"},
+		":name":  {S: "Fred"},
+		":list":  {L: []APIAttributeValue{{S: "three"}}},
+		":tags":  {SS: []string{"b", "c"}},
+		":untag": {SS: []string{"a"}},
+	}
+
+	cases := []struct {
+		expression string
+		path       string
+		expected   *APIAttributeValue
+	}{
+		{"SET #c = #c + :one", "count", &APIAttributeValue{N: "11"}},
+		{"SET #c = #c - :half", "count", &APIAttributeValue{N: "9.5"}},
+		{"SET new = if_not_exists(new, :one)", "new", &APIAttributeValue{N: "1"}},
+		{"SET #c = if_not_exists(#c, :one)", "count", &APIAttributeValue{N: "10"}},
+		{"SET nested.list = list_append(nested.list, :list)", "nested.list[2]", &APIAttributeValue{S: "three"}},
+		{"SET nested.list[1] = :name", "nested.list[1]", &APIAttributeValue{S: "Fred"}},
+		{"SET nested.list[9] = :name", "nested.list[2]", &APIAttributeValue{S: "Fred"}},
+		{"SET nested.other = :name", "nested.other", &APIAttributeValue{S: "Fred"}},
+		{"SET copy = #c", "copy", &APIAttributeValue{N: "10"}},
+		{"REMOVE name, nested.list[0]", "name", nil},
+		{"REMOVE nested.list[0]", "nested.list[0]", &APIAttributeValue{S: "two"}},
+		{"ADD #c :one", "count", &APIAttributeValue{N: "11"}},
+		{"ADD new :one", "new", &APIAttributeValue{N: "1"}},
+		{"ADD tags :tags", "tags", &APIAttributeValue{SS: []string{"a", "b", "c"}}},
+		{"DELETE tags :untag", "tags", &APIAttributeValue{SS: []string{"b"}}},
+		{"DELETE tags :tags", "tags", &APIAttributeValue{SS: []string{"a"}}},
+		{"set name = :name remove #c", "count", nil},
+	}
+
+	for _, c := range cases {
+		u, err := parseUpdate(c.expression, names, values)
+		if err != nil {
+			t.Fatal(c.expression, err)
+		}
+		item := cloneItem(testItem)
+		err = u.apply(item, keySchema{PartitionKey: "pk"})
+		if err != nil {
+			t.Fatal(c.expression, err)
+		}
+
+		p, err := parseProjection(c.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, ok := p[0].resolve(item)
+		if c.expected == nil {
+			if ok {
+				t.Fatal("Expected removal for", c.expression, actual)
+			}
+		} else if !ok || !reflect.DeepEqual(actual, *c.expected) {
+			t.Fatal("Wrong result for", c.expression, actual)
+		}
+	}
+
+	// The original item must be unaffected.
+	if testItem["count"].N != "10" || len(testItem["nested"].M["list"].L) != 2 {
+		t.Fatal("Test item was modified")
+	}
+}
+
+func TestUpdateExpressionErrors(t *testing.T) {
+	values := map[string]APIAttributeValue{
+		":one":  {N: "1"},
+		":name": {S: "Fred"},
+	}
+	for _, expression := range []string{
+		"",
+		"SET",
+		"SET a = :one SET b = :one",
+		"SET a = :one, a = :name",
+		"SET nested = :one REMOVE nested.list",
+		"SET a = size(name)",
+		"ADD a :missing",
+		"UPSERT a = :one",
+	} {
+		_, err := parseUpdate(expression, nil, values)
+		if err == nil {
+			t.Fatal("Expected parse error for", expression)
+		}
+	}
+
+	for _, expression := range []string{
+		"SET pk = :name",
+		"SET a = name + :one",
+		"SET a = missing",
+		"SET a = list_append(name, :one)",
+		"SET missing.field = :one",
+		"SET name[0] = :one",
+		"ADD name :one",
+		"ADD tags :name",
+		"DELETE tags :one",
+	} {
+		u, err := parseUpdate(expression, nil, values)
+		if err != nil {
+			t.Fatal(expression, err)
+		}
+		err = u.apply(cloneItem(testItem), keySchema{PartitionKey: "pk"})
+		if err == nil {
+			t.Fatal("Expected apply error for", expression)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package ecr
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/state"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	mutable   = "MUTABLE"
+	immutable = "IMMUTABLE"
+
+	defaultMaxResults = 100
+	maxListResults    = 1000
+
+	maxTags           = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+
+	// The version of the layout of the blobs in -persistDir. Bump it, and add a state.DirMigration
+	// from the previous version, whenever the layout changes.
+	persistVersion = 1
+)
+
+var repositoryNameRegex = regexp.MustCompile(`^(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+type Image struct {
+	Digest            string
+	Manifest          string
+	MediaType         string
+	ArtifactMediaType string
+	// The total size of the image's layers.
+	Size     int64
+	Tags     []string
+	PushedAt time.Time
+	// Zero if the image has never been pulled.
+	LastPulledAt time.Time
+
+	// The digests of the config and layers, or of an index's manifests, which Docker pushes first.
+	blobDigests     []string
+	manifestDigests []string
+}
+
+type Repository struct {
+	Name               string
+	ARN                string
+	CreatedAt          time.Time
+	ImageTagMutability string
+	ScanOnPush         bool
+	Encryption         APIEncryptionConfiguration
+	Tags               map[string]string
+	// Empty if the repository has no policy.
+	PolicyText string
+
+	// Nil if the repository has no lifecycle policy.
+	LifecyclePolicy *lifecyclePolicy
+	LastEvaluatedAt time.Time
+	// The result of the last StartLifecyclePolicyPreview, or nil.
+	Preview *lifecyclePreview
+
+	// In the order they were pushed.
+	Images []*Image
+	// The sizes of the layers and configs pushed to the repository, by digest.
+	Blobs map[string]int64
+}
+
+type ECR struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// The address the Docker registry is served on, which repositories' URIs use.
+	addr string
+	// Where blobs are stored, by their hex digest.
+	blobDir string
+	// Overridden in tests to control lifecycle policy expiry and token expiry.
+	clock func() time.Time
+
+	mu                 sync.Mutex
+	repositoriesByName map[string]*Repository
+	uploadsById        map[string]*blobUpload
+	// When the passwords of authorization tokens expire.
+	passwords map[string]time.Time
+	// Shared by the instances of all regions, which each have their own repositories.
+	regions *region.Regions[*ECR]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The address the Docker registry is served on. If empty, repositories' URIs use ECR's hostname.
+	Addr string
+	// Blobs are stored in a temporary directory if empty.
+	PersistDir string
+	// How often to expire images with repositories' lifecycle policies. Images are never expired if zero.
+	LifecyclePolicySweepInterval time.Duration
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) (*ECR, error) {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	var blobDir string
+	if options.PersistDir == "" {
+		var err error
+		blobDir, err = os.MkdirTemp("", "aws-in-a-box-ecr")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		dir := filepath.Join(options.PersistDir, "ecr")
+		blobDir = filepath.Join(dir, "blobs")
+		if err := os.MkdirAll(blobDir, 0700); err != nil {
+			return nil, err
+		}
+		if err := state.MigrateDir(dir, persistVersion, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// Blobs are named by their digests, so every region stores them in the same directory.
+	e := newRegion(options, blobDir)
+	e.regions = region.New(options.ArnGenerator.Region, options.Regions, e, func(name string) (*ECR, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions, blobDir)
+		r.regions = e.regions
+		return r, nil
+	})
+	if options.LifecyclePolicySweepInterval > 0 {
+		go func() {
+			for {
+				time.Sleep(options.LifecyclePolicySweepInterval)
+				for _, r := range e.regions.All() {
+					r.applyLifecyclePolicies()
+				}
+			}
+		}()
+	}
+	return e, nil
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options, blobDir string) *ECR {
+	return &ECR{
+		logger:             options.Logger,
+		arnGenerator:       options.ArnGenerator,
+		addr:               options.Addr,
+		blobDir:            blobDir,
+		clock:              clock.Now,
+		repositoriesByName: make(map[string]*Repository),
+		uploadsById:        make(map[string]*blobUpload),
+		passwords:          make(map[string]time.Time),
+	}
+}
+
+// InRegion returns the repositories of the region, for services which run their images, like
+// Lambda's functions. It fails if the region isn't one of Options.Regions.
+func (e *ECR) InRegion(name string) (*ECR, error) {
+	return e.regions.Get(name)
+}
+
+func (e *ECR) registryId() string {
+	return e.arnGenerator.AwsAccountId
+}
+
+// ecrHostname is the hostname of the registry in AWS, which image URIs may use instead of the registry's address.
+func (e *ECR) ecrHostname() string {
+	return e.registryId() + ".dkr.ecr." + e.arnGenerator.Region + ".amazonaws.com"
+}
+
+// registryHost is where Docker pushes and pulls images.
+func (e *ECR) registryHost() string {
+	if e.addr == "" {
+		return e.ecrHostname()
+	}
+	return e.addr
+}
+
+func (e *ECR) repositoryUri(name string) string {
+	return e.registryHost() + "/" + name
+}
+
+func (e *ECR) toAPI(repository *Repository) APIRepository {
+	return APIRepository{
+		RepositoryArn:      repository.ARN,
+		RegistryId:         e.registryId(),
+		RepositoryName:     repository.Name,
+		RepositoryUri:      e.repositoryUri(repository.Name),
+		CreatedAt:          timestamp.EpochSeconds(repository.CreatedAt),
+		ImageTagMutability: repository.ImageTagMutability,
+		ImageScanningConfiguration: APIImageScanningConfiguration{
+			ScanOnPush: repository.ScanOnPush,
+		},
+		EncryptionConfiguration: repository.Encryption,
+	}
+}
+
+func validateRepositoryName(name string) *awserrors.Error {
+	if len(name) < 2 || len(name) > 256 || !repositoryNameRegex.MatchString(name) {
+		return ValidationException("1 validation error detected: Value '" + name + "' at 'repositoryName' failed to satisfy constraint: " +
+			"Member must satisfy regular expression pattern: " + repositoryNameRegex.String())
+	}
+	return nil
+}
+
+func validateImageTagMutability(mutability string) *awserrors.Error {
+	if mutability != mutable && mutability != immutable {
+		return ValidationException("1 validation error detected: Value '" + mutability + "' at 'imageTagMutability' failed to satisfy constraint: " +
+			"Member must satisfy enum value set: [MUTABLE, IMMUTABLE]")
+	}
+	return nil
+}
+
+func validateTags(tags []APITag) *awserrors.Error {
+	for _, tag := range tags {
+		if len(tag.Key) < 1 || len(tag.Key) > maxTagKeyLength {
+			return InvalidTagParameterException("Tag keys must be between 1 and 128 characters.")
+		}
+		if len(tag.Value) > maxTagValueLength {
+			return InvalidTagParameterException("Tag values must be at most 256 characters.")
+		}
+		if strings.HasPrefix(strings.ToLower(tag.Key), "aws:") {
+			return InvalidTagParameterException("Tag keys can't start with aws:, which is reserved for AWS use.")
+		}
+	}
+	return nil
+}
+
+// lockedGetRepository returns the repository, which must be in this emulator's registry.
+func (e *ECR) lockedGetRepository(registryId string, name string) (*Repository, *awserrors.Error) {
+	if registryId == "" {
+		registryId = e.registryId()
+	}
+	repository, ok := e.repositoriesByName[name]
+	if !ok || registryId != e.registryId() {
+		return nil, RepositoryNotFoundException("The repository with name '" + name +
+			"' does not exist in the registry with id '" + registryId + "'")
+	}
+	return repository, nil
+}
+
+func (e *ECR) lockedGetRepositoryByArn(resourceArn string) (*Repository, *awserrors.Error) {
+	if !strings.HasPrefix(resourceArn, "arn:aws:ecr:") || !strings.Contains(resourceArn, ":repository/") {
+		return nil, InvalidParameterException("Invalid parameter at 'resourceArn' failed to satisfy constraint: 'Invalid ARN'")
+	}
+	for _, repository := range e.repositoriesByName {
+		if repository.ARN == resourceArn {
+			return repository, nil
+		}
+	}
+	_, name, _ := strings.Cut(resourceArn, ":repository/")
+	return nil, RepositoryNotFoundException("The repository with name '" + name +
+		"' does not exist in the registry with id '" + e.registryId() + "'")
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_CreateRepository.html
+func (e *ECR) CreateRepository(input CreateRepositoryInput) (*CreateRepositoryOutput, *awserrors.Error) {
+	if awserr := validateRepositoryName(input.RepositoryName); awserr != nil {
+		return nil, awserr
+	}
+	if input.ImageTagMutability == "" {
+		input.ImageTagMutability = mutable
+	}
+	if awserr := validateImageTagMutability(input.ImageTagMutability); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateTags(input.Tags); awserr != nil {
+		return nil, awserr
+	}
+	tags := make(map[string]string, len(input.Tags))
+	for _, tag := range input.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	if len(tags) > maxTags {
+		return nil, TooManyTagsException("A repository can have at most 50 tags.")
+	}
+	encryption := APIEncryptionConfiguration{EncryptionType: "AES256"}
+	if input.EncryptionConfiguration != nil {
+		encryption = *input.EncryptionConfiguration
+		if encryption.EncryptionType != "AES256" && encryption.EncryptionType != "KMS" {
+			return nil, ValidationException("1 validation error detected: Value '" + encryption.EncryptionType +
+				"' at 'encryptionConfiguration.encryptionType' failed to satisfy constraint: Member must satisfy enum value set: [AES256, KMS]")
+		}
+		if encryption.EncryptionType == "AES256" && encryption.KmsKey != "" {
+			return nil, InvalidParameterException("Invalid parameter at 'encryptionConfiguration' failed to satisfy constraint: " +
+				"'kmsKey can only be specified when encryptionType is KMS'")
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if input.RegistryId != "" && input.RegistryId != e.registryId() {
+		return nil, InvalidParameterException("Invalid parameter at 'registryId' failed to satisfy constraint: " +
+			"'Repositories can only be created in the registry with id '" + e.registryId() + "''")
+	}
+	if _, ok := e.repositoriesByName[input.RepositoryName]; ok {
+		return nil, RepositoryAlreadyExistsException("The repository with name '" + input.RepositoryName +
+			"' already exists in the registry with id '" + e.registryId() + "'")
+	}
+	repository := &Repository{
+		Name:               input.RepositoryName,
+		ARN:                e.arnGenerator.Generate("ecr", "repository", input.RepositoryName),
+		CreatedAt:          e.clock(),
+		ImageTagMutability: input.ImageTagMutability,
+		Encryption:         encryption,
+		Tags:               tags,
+		Blobs:              make(map[string]int64),
+	}
+	if input.ImageScanningConfiguration != nil {
+		repository.ScanOnPush = input.ImageScanningConfiguration.ScanOnPush
+	}
+	e.repositoriesByName[repository.Name] = repository
+	return &CreateRepositoryOutput{Repository: e.toAPI(repository)}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_DescribeRepositories.html
+func (e *ECR) DescribeRepositories(input DescribeRepositoriesInput) (*DescribeRepositoriesOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(input.RepositoryNames) > 0 {
+		if input.MaxResults != 0 || input.NextToken != "" {
+			return nil, InvalidParameterException("Invalid parameter at 'maxResults' failed to satisfy constraint: " +
+				"'maxResults and nextToken can't be used with repositoryNames'")
+		}
+		output := &DescribeRepositoriesOutput{}
+		for _, name := range input.RepositoryNames {
+			repository, awserr := e.lockedGetRepository(input.RegistryId, name)
+			if awserr != nil {
+				return nil, awserr
+			}
+			output.Repositories = append(output.Repositories, e.toAPI(repository))
+		}
+		return output, nil
+	}
+
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		InvalidParameterException("Invalid parameter at 'nextToken' failed to satisfy constraint: 'Invalid token'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.RegistryId != "" && input.RegistryId != e.registryId() {
+		return &DescribeRepositoriesOutput{Repositories: []APIRepository{}}, nil
+	}
+	repositories := make([]APIRepository, 0, len(e.repositoriesByName))
+	for _, repository := range e.repositoriesByName {
+		repositories = append(repositories, e.toAPI(repository))
+	}
+	slices.SortFunc(repositories, func(a, b APIRepository) int {
+		return strings.Compare(a.RepositoryName, b.RepositoryName)
+	})
+	output := &DescribeRepositoriesOutput{}
+	output.Repositories, output.NextToken = pagination.Page(repositories, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_DeleteRepository.html
+func (e *ECR) DeleteRepository(input DeleteRepositoryInput) (*DeleteRepositoryOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(repository.Images) > 0 && !input.Force {
+		return nil, RepositoryNotEmptyException("The repository with name '" + repository.Name + "' in registry with id '" +
+			e.registryId() + "' cannot be deleted because it still contains images")
+	}
+	delete(e.repositoriesByName, repository.Name)
+	return &DeleteRepositoryOutput{Repository: e.toAPI(repository)}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_PutImageTagMutability.html
+func (e *ECR) PutImageTagMutability(input PutImageTagMutabilityInput) (*PutImageTagMutabilityOutput, *awserrors.Error) {
+	if awserr := validateImageTagMutability(input.ImageTagMutability); awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	repository.ImageTagMutability = input.ImageTagMutability
+	return &PutImageTagMutabilityOutput{
+		RegistryId:         e.registryId(),
+		RepositoryName:     repository.Name,
+		ImageTagMutability: repository.ImageTagMutability,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_PutImageScanningConfiguration.html
+// Images aren't scanned, so this is only recorded.
+func (e *ECR) PutImageScanningConfiguration(input PutImageScanningConfigurationInput) (*PutImageScanningConfigurationOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	repository.ScanOnPush = input.ImageScanningConfiguration.ScanOnPush
+	return &PutImageScanningConfigurationOutput{
+		RegistryId:                 e.registryId(),
+		RepositoryName:             repository.Name,
+		ImageScanningConfiguration: input.ImageScanningConfiguration,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_SetRepositoryPolicy.html
+// Policies are stored, but not enforced.
+func (e *ECR) SetRepositoryPolicy(input SetRepositoryPolicyInput) (*SetRepositoryPolicyOutput, *awserrors.Error) {
+	if input.PolicyText == "" {
+		return nil, InvalidParameterException("Invalid parameter at 'PolicyText' failed to satisfy constraint: 'Invalid repository policy provided'")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	repository.PolicyText = input.PolicyText
+	return &SetRepositoryPolicyOutput{
+		RegistryId:     e.registryId(),
+		RepositoryName: repository.Name,
+		PolicyText:     repository.PolicyText,
+	}, nil
+}
+
+func (e *ECR) lockedGetRepositoryPolicy(registryId string, name string) (*Repository, *awserrors.Error) {
+	repository, awserr := e.lockedGetRepository(registryId, name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if repository.PolicyText == "" {
+		return nil, RepositoryPolicyNotFoundException("Repository policy does not exist for the repository with name '" +
+			repository.Name + "' in the registry with id '" + e.registryId() + "'")
+	}
+	return repository, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_GetRepositoryPolicy.html
+func (e *ECR) GetRepositoryPolicy(input GetRepositoryPolicyInput) (*GetRepositoryPolicyOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepositoryPolicy(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetRepositoryPolicyOutput{
+		RegistryId:     e.registryId(),
+		RepositoryName: repository.Name,
+		PolicyText:     repository.PolicyText,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_DeleteRepositoryPolicy.html
+func (e *ECR) DeleteRepositoryPolicy(input DeleteRepositoryPolicyInput) (*DeleteRepositoryPolicyOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepositoryPolicy(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &DeleteRepositoryPolicyOutput{
+		RegistryId:     e.registryId(),
+		RepositoryName: repository.Name,
+		PolicyText:     repository.PolicyText,
+	}
+	repository.PolicyText = ""
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_TagResource.html
+func (e *ECR) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	if awserr := validateTags(input.Tags); awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepositoryByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	count := len(repository.Tags)
+	for _, tag := range input.Tags {
+		if _, ok := repository.Tags[tag.Key]; !ok {
+			count++
+		}
+	}
+	if count > maxTags {
+		return nil, TooManyTagsException("A repository can have at most 50 tags.")
+	}
+	for _, tag := range input.Tags {
+		repository.Tags[tag.Key] = tag.Value
+	}
+	return &TagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_UntagResource.html
+func (e *ECR) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepositoryByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, key := range input.TagKeys {
+		delete(repository.Tags, key)
+	}
+	return &UntagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ListTagsForResource.html
+func (e *ECR) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepositoryByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	tags := make([]APITag, 0, len(repository.Tags))
+	for key, value := range repository.Tags {
+		tags = append(tags, APITag{Key: key, Value: value})
+	}
+	slices.SortFunc(tags, func(a, b APITag) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return &ListTagsForResourceOutput{Tags: tags}, nil
+}
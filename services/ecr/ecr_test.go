@@ -0,0 +1,201 @@
+package ecr
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+func newECR(t *testing.T) *ECR {
+	e, err := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+		PersistDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	e.clock = func() time.Time { return now }
+	return e
+}
+
+func createRepository(t *testing.T, e *ECR, name string) APIRepository {
+	output, awserr := e.CreateRepository(CreateRepositoryInput{RepositoryName: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Repository
+}
+
+func TestCreateRepository(t *testing.T) {
+	e := newECR(t)
+	repository := createRepository(t, e, "team/app")
+	expected := APIRepository{
+		RepositoryArn:      "arn:aws:ecr:us-east-1:123456789012:repository/team/app",
+		RegistryId:         "123456789012",
+		RepositoryName:     "team/app",
+		RepositoryUri:      "123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app",
+		CreatedAt:          1700000000,
+		ImageTagMutability: "MUTABLE",
+		EncryptionConfiguration: APIEncryptionConfiguration{
+			EncryptionType: "AES256",
+		},
+	}
+	if repository != expected {
+		t.Fatalf("Unexpected repository: %+v", repository)
+	}
+
+	_, awserr := e.CreateRepository(CreateRepositoryInput{RepositoryName: "team/app"})
+	if awserr == nil || awserr.Body.Type != "RepositoryAlreadyExistsException" {
+		t.Fatal("Expected RepositoryAlreadyExistsException", awserr)
+	}
+	for _, name := range []string{"a", "Upper", "trailing/", "double//slash", "dash-"} {
+		if _, awserr := e.CreateRepository(CreateRepositoryInput{RepositoryName: name}); awserr == nil {
+			t.Error("Expected an error for", name)
+		}
+	}
+	_, awserr = e.CreateRepository(CreateRepositoryInput{RepositoryName: "other", ImageTagMutability: "SOMETIMES"})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected ValidationException", awserr)
+	}
+}
+
+func TestDescribeRepositories(t *testing.T) {
+	e := newECR(t)
+	for _, name := range []string{"c", "a", "b"} {
+		createRepository(t, e, name+"-repo")
+	}
+
+	output, awserr := e.DescribeRepositories(DescribeRepositoriesInput{MaxResults: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Repositories) != 2 || output.Repositories[0].RepositoryName != "a-repo" || output.NextToken == "" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	output, awserr = e.DescribeRepositories(DescribeRepositoriesInput{NextToken: output.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Repositories) != 1 || output.Repositories[0].RepositoryName != "c-repo" || output.NextToken != "" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	output, awserr = e.DescribeRepositories(DescribeRepositoriesInput{RepositoryNames: []string{"b-repo", "a-repo"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Repositories) != 2 || output.Repositories[0].RepositoryName != "b-repo" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	_, awserr = e.DescribeRepositories(DescribeRepositoriesInput{RepositoryNames: []string{"a-repo", "missing"}})
+	if awserr == nil || awserr.Body.Type != "RepositoryNotFoundException" ||
+		awserr.Body.Message != "The repository with name 'missing' does not exist in the registry with id '123456789012'" {
+		t.Fatal("Expected RepositoryNotFoundException", awserr)
+	}
+	_, awserr = e.DescribeRepositories(DescribeRepositoriesInput{RegistryId: "111111111111", RepositoryNames: []string{"a-repo"}})
+	if awserr == nil || awserr.Body.Type != "RepositoryNotFoundException" {
+		t.Fatal("Expected RepositoryNotFoundException", awserr)
+	}
+}
+
+func TestDeleteRepository(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+	putImage(t, e, "app", "latest", testManifest(1))
+
+	_, awserr := e.DeleteRepository(DeleteRepositoryInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "RepositoryNotEmptyException" {
+		t.Fatal("Expected RepositoryNotEmptyException", awserr)
+	}
+	output, awserr := e.DeleteRepository(DeleteRepositoryInput{RepositoryName: "app", Force: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.Repository.RepositoryName != "app" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	_, awserr = e.DeleteRepository(DeleteRepositoryInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "RepositoryNotFoundException" {
+		t.Fatal("Expected RepositoryNotFoundException", awserr)
+	}
+}
+
+func TestRepositoryTags(t *testing.T) {
+	e := newECR(t)
+	output, awserr := e.CreateRepository(CreateRepositoryInput{
+		RepositoryName: "app",
+		Tags:           []APITag{{Key: "team", Value: "a"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	repositoryArn := output.Repository.RepositoryArn
+
+	_, awserr = e.TagResource(TagResourceInput{
+		ResourceArn: repositoryArn,
+		Tags:        []APITag{{Key: "team", Value: "b"}, {Key: "env", Value: "dev"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.UntagResource(UntagResourceInput{ResourceArn: repositoryArn, TagKeys: []string{"env", "missing"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := e.ListTagsForResource(ListTagsForResourceInput{ResourceArn: repositoryArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(tags.Tags, []APITag{{Key: "team", Value: "b"}}) {
+		t.Fatal("Unexpected tags", tags.Tags)
+	}
+
+	_, awserr = e.TagResource(TagResourceInput{ResourceArn: repositoryArn, Tags: []APITag{{Key: "aws:reserved"}}})
+	if awserr == nil || awserr.Body.Type != "InvalidTagParameterException" {
+		t.Fatal("Expected InvalidTagParameterException", awserr)
+	}
+	_, awserr = e.ListTagsForResource(ListTagsForResourceInput{ResourceArn: "arn:aws:ecr:us-east-1:123456789012:repository/missing"})
+	if awserr == nil || awserr.Body.Type != "RepositoryNotFoundException" {
+		t.Fatal("Expected RepositoryNotFoundException", awserr)
+	}
+	_, awserr = e.ListTagsForResource(ListTagsForResourceInput{ResourceArn: "arn:aws:sqs:us-east-1:123456789012:queue"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected InvalidParameterException", awserr)
+	}
+}
+
+func TestRepositoryPolicy(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+
+	_, awserr := e.GetRepositoryPolicy(GetRepositoryPolicyInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "RepositoryPolicyNotFoundException" {
+		t.Fatal("Expected RepositoryPolicyNotFoundException", awserr)
+	}
+	policy := `{"Version":"2012-10-17","Statement":[]}`
+	_, awserr = e.SetRepositoryPolicy(SetRepositoryPolicyInput{RepositoryName: "app", PolicyText: policy})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	output, awserr := e.GetRepositoryPolicy(GetRepositoryPolicyInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.PolicyText != policy {
+		t.Fatal("Unexpected policy", output.PolicyText)
+	}
+	_, awserr = e.DeleteRepositoryPolicy(DeleteRepositoryPolicyInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.DeleteRepositoryPolicy(DeleteRepositoryPolicyInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "RepositoryPolicyNotFoundException" {
+		t.Fatal("Expected RepositoryPolicyNotFoundException", awserr)
+	}
+}
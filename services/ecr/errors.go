@@ -0,0 +1,59 @@
+package ecr
+
+import "aws-in-a-box/awserrors"
+
+func ImageAlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ImageAlreadyExistsException", message)
+}
+
+func ImageDigestDoesNotMatchException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ImageDigestDoesNotMatchException", message)
+}
+
+func ImageNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ImageNotFoundException", message)
+}
+
+func ImageTagAlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ImageTagAlreadyExistsException", message)
+}
+
+func InvalidParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterException", message)
+}
+
+func InvalidTagParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidTagParameterException", message)
+}
+
+func LifecyclePolicyNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("LifecyclePolicyNotFoundException", message)
+}
+
+func LifecyclePolicyPreviewNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("LifecyclePolicyPreviewNotFoundException", message)
+}
+
+func RepositoryAlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("RepositoryAlreadyExistsException", message)
+}
+
+func RepositoryNotEmptyException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("RepositoryNotEmptyException", message)
+}
+
+func RepositoryNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("RepositoryNotFoundException", message)
+}
+
+func RepositoryPolicyNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("RepositoryPolicyNotFoundException", message)
+}
+
+func TooManyTagsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("TooManyTagsException", message)
+}
+
+func ValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationException", message)
+}
@@ -0,0 +1,35 @@
+package ecr
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AmazonEC2ContainerRegistry_V20150921"
+
+func (e *ECR) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchDeleteImage", region.Method(e.regions, (*ECR).BatchDeleteImage))
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchGetImage", region.Method(e.regions, (*ECR).BatchGetImage))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateRepository", region.Method(e.regions, (*ECR).CreateRepository))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteLifecyclePolicy", region.Method(e.regions, (*ECR).DeleteLifecyclePolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteRepository", region.Method(e.regions, (*ECR).DeleteRepository))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteRepositoryPolicy", region.Method(e.regions, (*ECR).DeleteRepositoryPolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeImages", region.Method(e.regions, (*ECR).DescribeImages))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeRepositories", region.Method(e.regions, (*ECR).DescribeRepositories))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetAuthorizationToken", region.Method(e.regions, (*ECR).GetAuthorizationToken))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetLifecyclePolicy", region.Method(e.regions, (*ECR).GetLifecyclePolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetLifecyclePolicyPreview", region.Method(e.regions, (*ECR).GetLifecyclePolicyPreview))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetRepositoryPolicy", region.Method(e.regions, (*ECR).GetRepositoryPolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListImages", region.Method(e.regions, (*ECR).ListImages))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListTagsForResource", region.Method(e.regions, (*ECR).ListTagsForResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutImage", region.Method(e.regions, (*ECR).PutImage))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutImageScanningConfiguration", region.Method(e.regions, (*ECR).PutImageScanningConfiguration))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutImageTagMutability", region.Method(e.regions, (*ECR).PutImageTagMutability))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutLifecyclePolicy", region.Method(e.regions, (*ECR).PutLifecyclePolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "SetRepositoryPolicy", region.Method(e.regions, (*ECR).SetRepositoryPolicy))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StartLifecyclePolicyPreview", region.Method(e.regions, (*ECR).StartLifecyclePolicyPreview))
+	http.RegisterWithRequest(logger, methodRegistry, service, "TagResource", region.Method(e.regions, (*ECR).TagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UntagResource", region.Method(e.regions, (*ECR).UntagResource))
+}
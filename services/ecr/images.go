@@ -0,0 +1,445 @@
+package ecr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/AmazonECR/latest/userguide/image-manifest-formats.html
+
+const (
+	mediaTypeDockerManifestV1 = "application/vnd.docker.distribution.manifest.v1+json"
+	mediaTypeOCIManifest      = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex         = "application/vnd.oci.image.index.v1+json"
+
+	maxImageIds = 100
+)
+
+var (
+	imageTagRegex    = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,299}$`)
+	imageDigestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// manifest is the part of a Docker or OCI image manifest, or an image index, which ECR reads.
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        *descriptor  `json:"config"`
+	Layers        []descriptor `json:"layers"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// https://github.com/opencontainers/image-spec/blob/main/descriptor.md
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+func manifestDigest(imageManifest string) string {
+	sum := sha256.Sum256([]byte(imageManifest))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// parseManifest returns the image for the manifest, without tags.
+// The media type is read from the manifest if it isn't given.
+func parseManifest(imageManifest string, mediaType string) (*Image, *awserrors.Error) {
+	var parsed manifest
+	if err := json.Unmarshal([]byte(imageManifest), &parsed); err != nil {
+		return nil, InvalidParameterException("Invalid parameter at 'ImageManifest' failed to satisfy constraint: 'Invalid JSON syntax'")
+	}
+	if mediaType != "" && parsed.MediaType != "" && mediaType != parsed.MediaType {
+		return nil, InvalidParameterException("Invalid parameter at 'imageManifestMediaType' failed to satisfy constraint: " +
+			"'Manifest media type does not match the mediaType field of the manifest'")
+	}
+	if mediaType == "" {
+		mediaType = parsed.MediaType
+	}
+	if mediaType == "" {
+		switch {
+		case parsed.SchemaVersion == 1:
+			mediaType = mediaTypeDockerManifestV1
+		case parsed.Manifests != nil:
+			mediaType = mediaTypeOCIIndex
+		default:
+			mediaType = mediaTypeOCIManifest
+		}
+	}
+	image := &Image{
+		Digest:    manifestDigest(imageManifest),
+		Manifest:  imageManifest,
+		MediaType: mediaType,
+	}
+	if parsed.Config != nil {
+		image.ArtifactMediaType = parsed.Config.MediaType
+		image.blobDigests = append(image.blobDigests, parsed.Config.Digest)
+	}
+	for _, layer := range parsed.Layers {
+		image.Size += layer.Size
+		image.blobDigests = append(image.blobDigests, layer.Digest)
+	}
+	for _, child := range parsed.Manifests {
+		image.manifestDigests = append(image.manifestDigests, child.Digest)
+	}
+	return image, nil
+}
+
+func validateImageTag(tag string) *awserrors.Error {
+	if !imageTagRegex.MatchString(tag) {
+		return InvalidParameterException("Invalid parameter at 'imageTag' failed to satisfy constraint: " +
+			"'must satisfy regular expression '" + imageTagRegex.String() + "''")
+	}
+	return nil
+}
+
+func validateTagStatus(tagStatus string) *awserrors.Error {
+	switch tagStatus {
+	case "", "TAGGED", "UNTAGGED", "ANY":
+		return nil
+	}
+	return ValidationException("1 validation error detected: Value '" + tagStatus + "' at 'filter.tagStatus' failed to satisfy constraint: " +
+		"Member must satisfy enum value set: [TAGGED, UNTAGGED, ANY]")
+}
+
+func matchesTagStatus(image *Image, tagStatus string) bool {
+	switch tagStatus {
+	case "TAGGED":
+		return len(image.Tags) > 0
+	case "UNTAGGED":
+		return len(image.Tags) == 0
+	}
+	return true
+}
+
+func (r *Repository) imageWithDigest(digest string) *Image {
+	for _, image := range r.Images {
+		if image.Digest == digest {
+			return image
+		}
+	}
+	return nil
+}
+
+func (r *Repository) imageWithTag(tag string) *Image {
+	for _, image := range r.Images {
+		if slices.Contains(image.Tags, tag) {
+			return image
+		}
+	}
+	return nil
+}
+
+func (r *Repository) deleteImage(image *Image) {
+	r.Images = slices.DeleteFunc(r.Images, func(other *Image) bool { return other == image })
+}
+
+// findImage returns the image with the identifier's digest and tag, or why it can't be found.
+func (r *Repository) findImage(id APIImageIdentifier) (*Image, *APIImageFailure) {
+	failure := func(code string, reason string) *APIImageFailure {
+		return &APIImageFailure{ImageId: id, FailureCode: code, FailureReason: reason}
+	}
+	if id.ImageDigest == "" && id.ImageTag == "" {
+		return nil, failure("MissingDigestAndTag", "Invalid request parameters: both tag and digest cannot be null")
+	}
+	var image *Image
+	if id.ImageDigest != "" {
+		if !imageDigestRegex.MatchString(id.ImageDigest) {
+			return nil, failure("InvalidImageDigest", "Invalid request parameters: image digest should satisfy the regex '"+
+				imageDigestRegex.String()+"'")
+		}
+		image = r.imageWithDigest(id.ImageDigest)
+		if image != nil && id.ImageTag != "" && !slices.Contains(image.Tags, id.ImageTag) {
+			return nil, failure("ImageTagDoesNotMatchDigest", "Invalid request parameters: image tag does not match digest")
+		}
+	} else {
+		if validateImageTag(id.ImageTag) != nil {
+			return nil, failure("InvalidImageTag", "Invalid request parameters: invalid image tag")
+		}
+		image = r.imageWithTag(id.ImageTag)
+	}
+	if image == nil {
+		return nil, failure("ImageNotFound", "Requested image not found")
+	}
+	return image, nil
+}
+
+func (e *ECR) imageToAPI(repository *Repository, image *Image, tag string) APIImage {
+	return APIImage{
+		RegistryId:     e.registryId(),
+		RepositoryName: repository.Name,
+		ImageId: APIImageIdentifier{
+			ImageDigest: image.Digest,
+			ImageTag:    tag,
+		},
+		ImageManifest:          image.Manifest,
+		ImageManifestMediaType: image.MediaType,
+	}
+}
+
+func (e *ECR) imageDetail(repository *Repository, image *Image) APIImageDetail {
+	return APIImageDetail{
+		RegistryId:             e.registryId(),
+		RepositoryName:         repository.Name,
+		ImageDigest:            image.Digest,
+		ImageTags:              slices.Clone(image.Tags),
+		ImageSizeInBytes:       image.Size,
+		ImagePushedAt:          timestamp.EpochSeconds(image.PushedAt),
+		ImageManifestMediaType: image.MediaType,
+		ArtifactMediaType:      image.ArtifactMediaType,
+		LastRecordedPullTime:   timestamp.EpochSeconds(image.LastPulledAt),
+	}
+}
+
+func validateImageIds(imageIds []APIImageIdentifier) *awserrors.Error {
+	if len(imageIds) < 1 || len(imageIds) > maxImageIds {
+		return ValidationException("1 validation error detected: Value at 'imageIds' failed to satisfy constraint: " +
+			"Member must have length less than or equal to 100 and greater than or equal to 1")
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_PutImage.html
+func (e *ECR) PutImage(input PutImageInput) (*PutImageOutput, *awserrors.Error) {
+	if input.ImageTag != "" {
+		if awserr := validateImageTag(input.ImageTag); awserr != nil {
+			return nil, awserr
+		}
+	}
+	image, awserr := parseManifest(input.ImageManifest, input.ImageManifestMediaType)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.ImageDigest != "" && input.ImageDigest != image.Digest {
+		return nil, ImageDigestDoesNotMatchException("Provided digest does not match the digest of the provided image manifest")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	image, awserr = e.lockedPutImage(repository, image, input.ImageTag)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &PutImageOutput{Image: e.imageToAPI(repository, image, input.ImageTag)}, nil
+}
+
+// lockedPutImage adds the image to the repository, or tags the existing image with the same digest.
+func (e *ECR) lockedPutImage(repository *Repository, image *Image, tag string) (*Image, *awserrors.Error) {
+	existing := repository.imageWithDigest(image.Digest)
+	var tagged *Image
+	if tag != "" {
+		tagged = repository.imageWithTag(tag)
+	}
+	if existing != nil && (tag == "" || tagged == existing) {
+		message := fmt.Sprintf("Image with digest '%s' and tag '%s' already exists in the repository with name '%s' in registry with id '%s'",
+			image.Digest, tag, repository.Name, e.registryId())
+		if tag == "" {
+			message = fmt.Sprintf("Image with digest '%s' already exists in the repository with name '%s' in registry with id '%s'",
+				image.Digest, repository.Name, e.registryId())
+		}
+		return nil, ImageAlreadyExistsException(message)
+	}
+	if tagged != nil {
+		if repository.ImageTagMutability == immutable {
+			return nil, ImageTagAlreadyExistsException(fmt.Sprintf(
+				"The image tag '%s' already exists in the '%s' repository and cannot be overwritten because the repository is immutable.",
+				tag, repository.Name))
+		}
+		// The tag moves to the new image, which may leave the old one untagged.
+		tagged.Tags = slices.DeleteFunc(tagged.Tags, func(other string) bool { return other == tag })
+	}
+
+	if existing == nil {
+		image.PushedAt = e.clock()
+		repository.Images = append(repository.Images, image)
+	} else {
+		image = existing
+	}
+	if tag != "" {
+		image.Tags = append(image.Tags, tag)
+	}
+	return image, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_BatchGetImage.html
+func (e *ECR) BatchGetImage(input BatchGetImageInput) (*BatchGetImageOutput, *awserrors.Error) {
+	if awserr := validateImageIds(input.ImageIds); awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &BatchGetImageOutput{
+		Images:   []APIImage{},
+		Failures: []APIImageFailure{},
+	}
+	now := e.clock()
+	for _, id := range input.ImageIds {
+		image, failure := repository.findImage(id)
+		if failure != nil {
+			output.Failures = append(output.Failures, *failure)
+			continue
+		}
+		image.LastPulledAt = now
+		output.Images = append(output.Images, e.imageToAPI(repository, image, id.ImageTag))
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_BatchDeleteImage.html
+func (e *ECR) BatchDeleteImage(input BatchDeleteImageInput) (*BatchDeleteImageOutput, *awserrors.Error) {
+	if awserr := validateImageIds(input.ImageIds); awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &BatchDeleteImageOutput{
+		ImageIds: []APIImageIdentifier{},
+		Failures: []APIImageFailure{},
+	}
+	for _, id := range input.ImageIds {
+		image, failure := repository.findImage(id)
+		if failure != nil {
+			output.Failures = append(output.Failures, *failure)
+			continue
+		}
+		if id.ImageTag != "" {
+			// Deleting a tag only deletes the image once it has no tags left.
+			image.Tags = slices.DeleteFunc(image.Tags, func(tag string) bool { return tag == id.ImageTag })
+			output.ImageIds = append(output.ImageIds, APIImageIdentifier{ImageDigest: image.Digest, ImageTag: id.ImageTag})
+			if len(image.Tags) == 0 {
+				repository.deleteImage(image)
+			}
+			continue
+		}
+		if len(image.Tags) == 0 {
+			output.ImageIds = append(output.ImageIds, APIImageIdentifier{ImageDigest: image.Digest})
+		}
+		for _, tag := range image.Tags {
+			output.ImageIds = append(output.ImageIds, APIImageIdentifier{ImageDigest: image.Digest, ImageTag: tag})
+		}
+		repository.deleteImage(image)
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ListImages.html
+func (e *ECR) ListImages(input ListImagesInput) (*ListImagesOutput, *awserrors.Error) {
+	if awserr := validateTagStatus(input.Filter.TagStatus); awserr != nil {
+		return nil, awserr
+	}
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		InvalidParameterException("Invalid parameter at 'nextToken' failed to satisfy constraint: 'Invalid token'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Tagged images are listed once for each tag.
+	var imageIds []APIImageIdentifier
+	for _, image := range repository.Images {
+		if !matchesTagStatus(image, input.Filter.TagStatus) {
+			continue
+		}
+		if len(image.Tags) == 0 {
+			imageIds = append(imageIds, APIImageIdentifier{ImageDigest: image.Digest})
+		}
+		for _, tag := range image.Tags {
+			imageIds = append(imageIds, APIImageIdentifier{ImageDigest: image.Digest, ImageTag: tag})
+		}
+	}
+	output := &ListImagesOutput{}
+	output.ImageIds, output.NextToken = pagination.Page(imageIds, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_DescribeImages.html
+func (e *ECR) DescribeImages(input DescribeImagesInput) (*DescribeImagesOutput, *awserrors.Error) {
+	if awserr := validateTagStatus(input.Filter.TagStatus); awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	if len(input.ImageIds) > 0 {
+		if input.MaxResults != 0 || input.NextToken != "" {
+			return nil, InvalidParameterException("Invalid parameter at 'maxResults' failed to satisfy constraint: " +
+				"'maxResults and nextToken can't be used with imageIds'")
+		}
+		output := &DescribeImagesOutput{}
+		for _, id := range input.ImageIds {
+			image, failure := repository.findImage(id)
+			if failure != nil {
+				if failure.FailureCode != "ImageNotFound" {
+					return nil, InvalidParameterException(failure.FailureReason)
+				}
+				return nil, ImageNotFoundException(fmt.Sprintf(
+					"The image with imageId {imageDigest:'%s', imageTag:'%s'} does not exist within the repository with name '%s' in the registry with id '%s'",
+					nullIfEmpty(id.ImageDigest), nullIfEmpty(id.ImageTag), repository.Name, e.registryId()))
+			}
+			output.ImageDetails = append(output.ImageDetails, e.imageDetail(repository, image))
+		}
+		return output, nil
+	}
+
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		InvalidParameterException("Invalid parameter at 'nextToken' failed to satisfy constraint: 'Invalid token'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+	var details []APIImageDetail
+	for _, image := range repository.Images {
+		if matchesTagStatus(image, input.Filter.TagStatus) {
+			details = append(details, e.imageDetail(repository, image))
+		}
+	}
+	output := &DescribeImagesOutput{}
+	output.ImageDetails, output.NextToken = pagination.Page(details, limit, start)
+	return output, nil
+}
+
+func nullIfEmpty(s string) string {
+	if s == "" {
+		return "null"
+	}
+	return s
+}
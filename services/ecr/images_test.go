@@ -0,0 +1,216 @@
+package ecr
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// testManifest returns a distinct Docker image manifest for each n.
+func testManifest(n int) string {
+	return fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+  "config": {"mediaType": "application/vnd.docker.container.image.v1+json", "size": 100, "digest": "sha256:%064x"},
+  "layers": [
+    {"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip", "size": 1000, "digest": "sha256:%064x"},
+    {"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip", "size": 234, "digest": "sha256:%064x"}
+  ]
+}`, n, n+1, n+2)
+}
+
+func putImage(t *testing.T, e *ECR, repositoryName string, tag string, manifest string) APIImage {
+	t.Helper()
+	output, awserr := e.PutImage(PutImageInput{RepositoryName: repositoryName, ImageTag: tag, ImageManifest: manifest})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Image
+}
+
+func listImages(t *testing.T, e *ECR, tagStatus string) []APIImageIdentifier {
+	t.Helper()
+	output, awserr := e.ListImages(ListImagesInput{RepositoryName: "app", Filter: APIImageFilter{TagStatus: tagStatus}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.ImageIds
+}
+
+func TestPutImage(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+
+	image := putImage(t, e, "app", "v1", testManifest(1))
+	digest := manifestDigest(testManifest(1))
+	if image.ImageId != (APIImageIdentifier{ImageDigest: digest, ImageTag: "v1"}) ||
+		image.ImageManifestMediaType != "application/vnd.docker.distribution.manifest.v2+json" {
+		t.Fatalf("Unexpected image: %+v", image)
+	}
+	// The same manifest can be tagged again.
+	putImage(t, e, "app", "latest", testManifest(1))
+	_, awserr := e.PutImage(PutImageInput{RepositoryName: "app", ImageTag: "latest", ImageManifest: testManifest(1)})
+	if awserr == nil || awserr.Body.Type != "ImageAlreadyExistsException" {
+		t.Fatal("Expected ImageAlreadyExistsException", awserr)
+	}
+
+	// Moving a tag leaves the image with its other tags.
+	putImage(t, e, "app", "latest", testManifest(2))
+	// Moving the only tag leaves the image untagged.
+	putImage(t, e, "app", "v1", testManifest(3))
+	expected := []APIImageIdentifier{
+		{ImageDigest: manifestDigest(testManifest(2)), ImageTag: "latest"},
+		{ImageDigest: manifestDigest(testManifest(3)), ImageTag: "v1"},
+	}
+	if ids := listImages(t, e, "TAGGED"); !slices.Equal(ids, expected) {
+		t.Fatal("Unexpected tagged images", ids)
+	}
+	if ids := listImages(t, e, "UNTAGGED"); !slices.Equal(ids, []APIImageIdentifier{{ImageDigest: digest}}) {
+		t.Fatal("Unexpected untagged images", ids)
+	}
+	if ids := listImages(t, e, ""); len(ids) != 3 {
+		t.Fatal("Unexpected images", ids)
+	}
+
+	details, awserr := e.DescribeImages(DescribeImagesInput{RepositoryName: "app", ImageIds: []APIImageIdentifier{{ImageTag: "latest"}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	expectedDetail := APIImageDetail{
+		RegistryId:             "123456789012",
+		RepositoryName:         "app",
+		ImageDigest:            manifestDigest(testManifest(2)),
+		ImageTags:              []string{"latest"},
+		ImageSizeInBytes:       1234,
+		ImagePushedAt:          1700000000,
+		ImageManifestMediaType: "application/vnd.docker.distribution.manifest.v2+json",
+		ArtifactMediaType:      "application/vnd.docker.container.image.v1+json",
+	}
+	if len(details.ImageDetails) != 1 || fmt.Sprint(details.ImageDetails[0]) != fmt.Sprint(expectedDetail) {
+		t.Fatalf("Unexpected details: %+v", details.ImageDetails)
+	}
+	_, awserr = e.DescribeImages(DescribeImagesInput{RepositoryName: "app", ImageIds: []APIImageIdentifier{{ImageTag: "missing"}}})
+	if awserr == nil || awserr.Body.Type != "ImageNotFoundException" {
+		t.Fatal("Expected ImageNotFoundException", awserr)
+	}
+}
+
+func TestPutImageErrors(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+
+	for _, input := range []PutImageInput{
+		{RepositoryName: "app", ImageManifest: "not json"},
+		{RepositoryName: "app", ImageManifest: testManifest(1), ImageTag: "-invalid"},
+		{RepositoryName: "app", ImageManifest: testManifest(1), ImageManifestMediaType: "application/vnd.oci.image.manifest.v1+json"},
+	} {
+		_, awserr := e.PutImage(input)
+		if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+			t.Errorf("Expected InvalidParameterException for %+v: %v", input, awserr)
+		}
+	}
+	_, awserr := e.PutImage(PutImageInput{
+		RepositoryName: "app",
+		ImageManifest:  testManifest(1),
+		ImageDigest:    manifestDigest(testManifest(2)),
+	})
+	if awserr == nil || awserr.Body.Type != "ImageDigestDoesNotMatchException" {
+		t.Fatal("Expected ImageDigestDoesNotMatchException", awserr)
+	}
+
+	// OCI manifests don't need a mediaType.
+	image := putImage(t, e, "app", "", `{"schemaVersion": 2, "config": {"mediaType": "application/vnd.oci.image.config.v1+json"}, "layers": []}`)
+	if image.ImageManifestMediaType != "application/vnd.oci.image.manifest.v1+json" {
+		t.Fatalf("Unexpected image: %+v", image)
+	}
+
+	_, awserr = e.PutImageTagMutability(PutImageTagMutabilityInput{RepositoryName: "app", ImageTagMutability: "IMMUTABLE"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	putImage(t, e, "app", "v1", testManifest(1))
+	_, awserr = e.PutImage(PutImageInput{RepositoryName: "app", ImageTag: "v1", ImageManifest: testManifest(2)})
+	if awserr == nil || awserr.Body.Type != "ImageTagAlreadyExistsException" {
+		t.Fatal("Expected ImageTagAlreadyExistsException", awserr)
+	}
+}
+
+func TestBatchGetImage(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+	image := putImage(t, e, "app", "v1", testManifest(1))
+
+	output, awserr := e.BatchGetImage(BatchGetImageInput{
+		RepositoryName: "app",
+		ImageIds: []APIImageIdentifier{
+			{ImageTag: "v1"},
+			{ImageDigest: image.ImageId.ImageDigest},
+			{ImageTag: "missing"},
+			{ImageDigest: image.ImageId.ImageDigest, ImageTag: "v2"},
+			{ImageDigest: "sha256:bad"},
+			{},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Images) != 2 || output.Images[0].ImageManifest != testManifest(1) ||
+		output.Images[0].ImageId != image.ImageId || output.Images[1].ImageId.ImageTag != "" {
+		t.Fatalf("Unexpected images: %+v", output.Images)
+	}
+	var codes []string
+	for _, failure := range output.Failures {
+		codes = append(codes, failure.FailureCode)
+	}
+	if !slices.Equal(codes, []string{"ImageNotFound", "ImageTagDoesNotMatchDigest", "InvalidImageDigest", "MissingDigestAndTag"}) {
+		t.Fatalf("Unexpected failures: %+v", output.Failures)
+	}
+
+	details, awserr := e.DescribeImages(DescribeImagesInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if details.ImageDetails[0].LastRecordedPullTime != 1700000000 {
+		t.Fatalf("Expected the pull to be recorded: %+v", details.ImageDetails)
+	}
+}
+
+func TestBatchDeleteImage(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+	putImage(t, e, "app", "v1", testManifest(1))
+	putImage(t, e, "app", "latest", testManifest(1))
+	second := putImage(t, e, "app", "v2", testManifest(2))
+
+	// Deleting one of the image's tags only untags it.
+	output, awserr := e.BatchDeleteImage(BatchDeleteImageInput{RepositoryName: "app", ImageIds: []APIImageIdentifier{{ImageTag: "v1"}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.ImageIds) != 1 || len(listImages(t, e, "")) != 2 {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	// Deleting its last tag deletes it.
+	_, awserr = e.BatchDeleteImage(BatchDeleteImageInput{RepositoryName: "app", ImageIds: []APIImageIdentifier{{ImageTag: "latest"}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if ids := listImages(t, e, ""); !slices.Equal(ids, []APIImageIdentifier{second.ImageId}) {
+		t.Fatal("Unexpected images", ids)
+	}
+
+	output, awserr = e.BatchDeleteImage(BatchDeleteImageInput{
+		RepositoryName: "app",
+		ImageIds:       []APIImageIdentifier{{ImageDigest: second.ImageId.ImageDigest}, {ImageTag: "v1"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(output.ImageIds, []APIImageIdentifier{second.ImageId}) ||
+		len(output.Failures) != 1 || output.Failures[0].FailureCode != "ImageNotFound" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	if ids := listImages(t, e, ""); len(ids) != 0 {
+		t.Fatal("Unexpected images", ids)
+	}
+}
@@ -0,0 +1,403 @@
+package ecr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/AmazonECR/latest/userguide/LifecyclePolicies.html
+//
+// Each image is expired by at most one rule. Rules are evaluated from the lowest priority number, and an image
+// which matches the tags of a rule can't be expired by later rules, even if that rule doesn't expire it.
+
+const (
+	tagStatusTagged   = "tagged"
+	tagStatusUntagged = "untagged"
+	tagStatusAny      = "any"
+
+	countTypeImageCountMoreThan = "imageCountMoreThan"
+	countTypeSinceImagePushed   = "sinceImagePushed"
+
+	maxWildcardsPerPattern = 4
+)
+
+type lifecycleRule struct {
+	RulePriority int    `json:"rulePriority"`
+	Description  string `json:"description"`
+	Selection    struct {
+		TagStatus      string   `json:"tagStatus"`
+		TagPrefixList  []string `json:"tagPrefixList"`
+		TagPatternList []string `json:"tagPatternList"`
+		CountType      string   `json:"countType"`
+		CountUnit      string   `json:"countUnit"`
+		CountNumber    int      `json:"countNumber"`
+	} `json:"selection"`
+	Action struct {
+		Type string `json:"type"`
+	} `json:"action"`
+
+	patterns []*regexp.Regexp
+}
+
+// matches returns whether the image's tags are selected by the rule.
+// Every prefix or pattern in the rule must match one of the image's tags.
+func (r *lifecycleRule) matches(image *Image) bool {
+	switch r.Selection.TagStatus {
+	case tagStatusUntagged:
+		return len(image.Tags) == 0
+	case tagStatusAny:
+		return true
+	}
+	if len(image.Tags) == 0 {
+		return false
+	}
+	for _, prefix := range r.Selection.TagPrefixList {
+		if !slices.ContainsFunc(image.Tags, func(tag string) bool { return strings.HasPrefix(tag, prefix) }) {
+			return false
+		}
+	}
+	for _, pattern := range r.patterns {
+		if !slices.ContainsFunc(image.Tags, pattern.MatchString) {
+			return false
+		}
+	}
+	return true
+}
+
+type lifecyclePolicy struct {
+	Text string
+	// Sorted by priority.
+	Rules []*lifecycleRule
+}
+
+func lifecyclePolicyError(message string) *awserrors.Error {
+	return InvalidParameterException("Invalid parameter at 'LifecyclePolicyText' failed to satisfy constraint: " +
+		"'Lifecycle policy validation failure: " + message + "'")
+}
+
+// patternRegex converts a tag pattern, in which * matches any characters, to a regex.
+func patternRegex(pattern string) *regexp.Regexp {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+func parseLifecyclePolicy(text string) (*lifecyclePolicy, *awserrors.Error) {
+	var document struct {
+		Rules []*lifecycleRule `json:"rules"`
+	}
+	decoder := json.NewDecoder(bytes.NewBufferString(text))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, lifecyclePolicyError(err.Error())
+	}
+	if len(document.Rules) == 0 {
+		return nil, lifecyclePolicyError("object has missing required properties ([\"rules\"])")
+	}
+
+	priorities := make(map[int]bool)
+	anyPriority := 0
+	for _, rule := range document.Rules {
+		selection := &rule.Selection
+		if rule.RulePriority < 1 {
+			return nil, lifecyclePolicyError("rulePriority must be a positive integer")
+		}
+		if priorities[rule.RulePriority] {
+			return nil, lifecyclePolicyError(fmt.Sprintf("rulePriority %d is used by more than one rule", rule.RulePriority))
+		}
+		priorities[rule.RulePriority] = true
+
+		switch selection.TagStatus {
+		case tagStatusTagged:
+			if (len(selection.TagPrefixList) == 0) == (len(selection.TagPatternList) == 0) {
+				return nil, lifecyclePolicyError("rules with tagStatus tagged must have exactly one of tagPrefixList and tagPatternList")
+			}
+			for _, pattern := range selection.TagPatternList {
+				if pattern == "" || strings.Count(pattern, "*") > maxWildcardsPerPattern {
+					return nil, lifecyclePolicyError("tag patterns must be non-empty and have at most 4 wildcards")
+				}
+				rule.patterns = append(rule.patterns, patternRegex(pattern))
+			}
+		case tagStatusUntagged, tagStatusAny:
+			if len(selection.TagPrefixList) > 0 || len(selection.TagPatternList) > 0 {
+				return nil, lifecyclePolicyError("tagPrefixList and tagPatternList can only be used with tagStatus tagged")
+			}
+			if selection.TagStatus == tagStatusAny {
+				if anyPriority != 0 {
+					return nil, lifecyclePolicyError("only one rule can have tagStatus any")
+				}
+				anyPriority = rule.RulePriority
+			}
+		default:
+			return nil, lifecyclePolicyError("tagStatus must be one of tagged, untagged or any")
+		}
+
+		switch selection.CountType {
+		case countTypeImageCountMoreThan:
+			if selection.CountUnit != "" {
+				return nil, lifecyclePolicyError("countUnit can't be used with countType imageCountMoreThan")
+			}
+		case countTypeSinceImagePushed:
+			if selection.CountUnit != "days" {
+				return nil, lifecyclePolicyError("countUnit must be days for countType sinceImagePushed")
+			}
+		default:
+			return nil, lifecyclePolicyError("countType must be one of imageCountMoreThan or sinceImagePushed")
+		}
+		if selection.CountNumber < 1 {
+			return nil, lifecyclePolicyError("countNumber must be a positive integer")
+		}
+		if rule.Action.Type != "expire" {
+			return nil, lifecyclePolicyError("action type must be expire")
+		}
+	}
+	for priority := range priorities {
+		if anyPriority != 0 && priority > anyPriority {
+			return nil, lifecyclePolicyError("the rule with tagStatus any must have the highest rulePriority")
+		}
+	}
+
+	slices.SortFunc(document.Rules, func(a, b *lifecycleRule) int {
+		return a.RulePriority - b.RulePriority
+	})
+	return &lifecyclePolicy{Text: text, Rules: document.Rules}, nil
+}
+
+type expiration struct {
+	image        *Image
+	rulePriority int
+}
+
+// evaluate returns the images which the policy expires, in the order they were pushed.
+func (p *lifecyclePolicy) evaluate(images []*Image, now time.Time) []expiration {
+	selected := make(map[*Image]bool)
+	expiringRule := make(map[*Image]int)
+	for _, rule := range p.Rules {
+		// Newest first, so the images beyond the count are the oldest.
+		var candidates []*Image
+		for i := len(images) - 1; i >= 0; i-- {
+			image := images[i]
+			if !selected[image] && rule.matches(image) {
+				selected[image] = true
+				candidates = append(candidates, image)
+			}
+		}
+		for i, image := range candidates {
+			switch rule.Selection.CountType {
+			case countTypeImageCountMoreThan:
+				if i >= rule.Selection.CountNumber {
+					expiringRule[image] = rule.RulePriority
+				}
+			case countTypeSinceImagePushed:
+				if now.Sub(image.PushedAt) > time.Duration(rule.Selection.CountNumber)*24*time.Hour {
+					expiringRule[image] = rule.RulePriority
+				}
+			}
+		}
+	}
+
+	var expirations []expiration
+	for _, image := range images {
+		if priority, ok := expiringRule[image]; ok {
+			expirations = append(expirations, expiration{image: image, rulePriority: priority})
+		}
+	}
+	return expirations
+}
+
+// applyLifecyclePolicies deletes the images expired by repositories' lifecycle policies.
+func (e *ECR) applyLifecyclePolicies() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.clock()
+	for _, repository := range e.repositoriesByName {
+		if repository.LifecyclePolicy == nil {
+			continue
+		}
+		for _, expired := range repository.LifecyclePolicy.evaluate(repository.Images, now) {
+			e.logger.Info("Expiring image with lifecycle policy",
+				"repository", repository.Name, "digest", expired.image.Digest, "rulePriority", expired.rulePriority)
+			repository.deleteImage(expired.image)
+		}
+		repository.LastEvaluatedAt = now
+	}
+}
+
+type lifecyclePreview struct {
+	Text    string
+	Results []APILifecyclePolicyPreviewResult
+}
+
+func (e *ECR) lockedGetLifecyclePolicy(registryId string, name string) (*Repository, *awserrors.Error) {
+	repository, awserr := e.lockedGetRepository(registryId, name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if repository.LifecyclePolicy == nil {
+		return nil, LifecyclePolicyNotFoundException("Lifecycle policy does not exist for the repository with name '" +
+			repository.Name + "' in the registry with id '" + e.registryId() + "'")
+	}
+	return repository, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_PutLifecyclePolicy.html
+func (e *ECR) PutLifecyclePolicy(input PutLifecyclePolicyInput) (*PutLifecyclePolicyOutput, *awserrors.Error) {
+	policy, awserr := parseLifecyclePolicy(input.LifecyclePolicyText)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	repository.LifecyclePolicy = policy
+	return &PutLifecyclePolicyOutput{
+		RegistryId:          e.registryId(),
+		RepositoryName:      repository.Name,
+		LifecyclePolicyText: policy.Text,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_GetLifecyclePolicy.html
+func (e *ECR) GetLifecyclePolicy(input GetLifecyclePolicyInput) (*GetLifecyclePolicyOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetLifecyclePolicy(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetLifecyclePolicyOutput{
+		RegistryId:          e.registryId(),
+		RepositoryName:      repository.Name,
+		LifecyclePolicyText: repository.LifecyclePolicy.Text,
+		LastEvaluatedAt:     timestamp.EpochSeconds(repository.LastEvaluatedAt),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_DeleteLifecyclePolicy.html
+func (e *ECR) DeleteLifecyclePolicy(input DeleteLifecyclePolicyInput) (*DeleteLifecyclePolicyOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetLifecyclePolicy(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &DeleteLifecyclePolicyOutput{
+		RegistryId:          e.registryId(),
+		RepositoryName:      repository.Name,
+		LifecyclePolicyText: repository.LifecyclePolicy.Text,
+		LastEvaluatedAt:     timestamp.EpochSeconds(repository.LastEvaluatedAt),
+	}
+	repository.LifecyclePolicy = nil
+	repository.LastEvaluatedAt = time.Time{}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_StartLifecyclePolicyPreview.html
+// Previews are evaluated immediately, so they're COMPLETE once started.
+func (e *ECR) StartLifecyclePolicyPreview(input StartLifecyclePolicyPreviewInput) (*StartLifecyclePolicyPreviewOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var policy *lifecyclePolicy
+	if input.LifecyclePolicyText == "" {
+		if _, awserr := e.lockedGetLifecyclePolicy(input.RegistryId, input.RepositoryName); awserr != nil {
+			return nil, awserr
+		}
+		policy = repository.LifecyclePolicy
+	} else if policy, awserr = parseLifecyclePolicy(input.LifecyclePolicyText); awserr != nil {
+		return nil, awserr
+	}
+
+	preview := &lifecyclePreview{
+		Text:    policy.Text,
+		Results: []APILifecyclePolicyPreviewResult{},
+	}
+	for _, expired := range policy.evaluate(repository.Images, e.clock()) {
+		preview.Results = append(preview.Results, APILifecyclePolicyPreviewResult{
+			ImageTags:           slices.Clone(expired.image.Tags),
+			ImageDigest:         expired.image.Digest,
+			ImagePushedAt:       timestamp.EpochSeconds(expired.image.PushedAt),
+			Action:              APILifecyclePolicyRuleAction{Type: "EXPIRE"},
+			AppliedRulePriority: expired.rulePriority,
+		})
+	}
+	repository.Preview = preview
+	return &StartLifecyclePolicyPreviewOutput{
+		RegistryId:          e.registryId(),
+		RepositoryName:      repository.Name,
+		LifecyclePolicyText: preview.Text,
+		Status:              "IN_PROGRESS",
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_GetLifecyclePolicyPreview.html
+func (e *ECR) GetLifecyclePolicyPreview(input GetLifecyclePolicyPreviewInput) (*GetLifecyclePolicyPreviewOutput, *awserrors.Error) {
+	if awserr := validateTagStatus(input.Filter.TagStatus); awserr != nil {
+		return nil, awserr
+	}
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		InvalidParameterException("Invalid parameter at 'nextToken' failed to satisfy constraint: 'Invalid token'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository(input.RegistryId, input.RepositoryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	preview := repository.Preview
+	if preview == nil {
+		return nil, LifecyclePolicyPreviewNotFoundException("There is no dry run for the repository with name '" +
+			repository.Name + "' in the registry with id '" + e.registryId() + "'")
+	}
+
+	var results []APILifecyclePolicyPreviewResult
+	for _, result := range preview.Results {
+		if !matchesTagStatus(&Image{Tags: result.ImageTags}, input.Filter.TagStatus) {
+			continue
+		}
+		if len(input.ImageIds) > 0 && !slices.ContainsFunc(input.ImageIds, func(id APIImageIdentifier) bool {
+			return (id.ImageDigest == "" || id.ImageDigest == result.ImageDigest) &&
+				(id.ImageTag == "" || slices.Contains(result.ImageTags, id.ImageTag))
+		}) {
+			continue
+		}
+		results = append(results, result)
+	}
+	output := &GetLifecyclePolicyPreviewOutput{
+		RegistryId:          e.registryId(),
+		RepositoryName:      repository.Name,
+		LifecyclePolicyText: preview.Text,
+		Status:              "COMPLETE",
+		Summary: APILifecyclePolicyPreviewSummary{
+			ExpiringImageTotalCount: len(preview.Results),
+		},
+	}
+	output.PreviewResults, output.NextToken = pagination.Page(results, limit, start)
+	return output, nil
+}
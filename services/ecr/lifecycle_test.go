@@ -0,0 +1,154 @@
+package ecr
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLifecyclePolicy(t *testing.T) {
+	for _, policy := range []string{
+		`{}`,
+		`{"rules": []}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "any", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}, "extra": 1}]}`,
+		`{"rules": [{"rulePriority": 0, "selection": {"tagStatus": "any", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}}]}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "tagged", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}}]}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "untagged", "tagPrefixList": ["v"], "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}}]}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "untagged", "countType": "sinceImagePushed", "countNumber": 1}, "action": {"type": "expire"}}]}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "untagged", "countType": "imageCountMoreThan", "countNumber": 0}, "action": {"type": "expire"}}]}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "untagged", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "delete"}}]}`,
+		`{"rules": [{"rulePriority": 1, "selection": {"tagStatus": "tagged", "tagPatternList": ["*a*b*c*d*e"], "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}}]}`,
+		`{"rules": [
+			{"rulePriority": 1, "selection": {"tagStatus": "any", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}},
+			{"rulePriority": 2, "selection": {"tagStatus": "untagged", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}}
+		]}`,
+		`{"rules": [
+			{"rulePriority": 1, "selection": {"tagStatus": "untagged", "countType": "imageCountMoreThan", "countNumber": 1}, "action": {"type": "expire"}},
+			{"rulePriority": 1, "selection": {"tagStatus": "untagged", "countType": "imageCountMoreThan", "countNumber": 2}, "action": {"type": "expire"}}
+		]}`,
+	} {
+		if _, awserr := parseLifecyclePolicy(policy); awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+			t.Errorf("%s: expected InvalidParameterException, got %v", policy, awserr)
+		}
+	}
+}
+
+func TestLifecyclePolicy(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+	start := time.Unix(1700000000, 0)
+	push := func(n int, tag string, age time.Duration) {
+		now := start.Add(-age)
+		e.clock = func() time.Time { return now }
+		putImage(t, e, "app", tag, testManifest(n))
+	}
+	push(1, "", 20*24*time.Hour)
+	push(2, "", 2*24*time.Hour)
+	push(3, "prod-1", 5*24*time.Hour)
+	push(4, "prod-2", 4*24*time.Hour)
+	push(5, "prod-3", 3*24*time.Hour)
+	push(6, "release-1.0", 30*24*time.Hour)
+	push(7, "dev", 30*24*time.Hour)
+	e.clock = func() time.Time { return start }
+
+	// Rule 2 selects the release image, so rule 3 can't expire it.
+	policy := `{"rules": [
+		{"rulePriority": 1, "description": "Keep the last two prod images",
+		 "selection": {"tagStatus": "tagged", "tagPrefixList": ["prod"], "countType": "imageCountMoreThan", "countNumber": 2},
+		 "action": {"type": "expire"}},
+		{"rulePriority": 2,
+		 "selection": {"tagStatus": "tagged", "tagPatternList": ["release-*"], "countType": "imageCountMoreThan", "countNumber": 5},
+		 "action": {"type": "expire"}},
+		{"rulePriority": 3, "selection": {"tagStatus": "untagged", "countType": "sinceImagePushed", "countUnit": "days", "countNumber": 7},
+		 "action": {"type": "expire"}},
+		{"rulePriority": 10, "selection": {"tagStatus": "any", "countType": "sinceImagePushed", "countUnit": "days", "countNumber": 14},
+		 "action": {"type": "expire"}}
+	]}`
+	_, awserr := e.PutLifecyclePolicy(PutLifecyclePolicyInput{RepositoryName: "app", LifecyclePolicyText: policy})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	expectedExpired := []string{
+		manifestDigest(testManifest(1)),
+		manifestDigest(testManifest(3)),
+		manifestDigest(testManifest(7)),
+	}
+
+	_, awserr = e.StartLifecyclePolicyPreview(StartLifecyclePolicyPreviewInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	preview, awserr := e.GetLifecyclePolicyPreview(GetLifecyclePolicyPreviewInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var previewed []string
+	var priorities []int
+	for _, result := range preview.PreviewResults {
+		previewed = append(previewed, result.ImageDigest)
+		priorities = append(priorities, result.AppliedRulePriority)
+	}
+	if preview.Status != "COMPLETE" || preview.Summary.ExpiringImageTotalCount != 3 ||
+		!slices.Equal(previewed, expectedExpired) || !slices.Equal(priorities, []int{3, 1, 10}) {
+		t.Fatalf("Unexpected preview: %+v", preview)
+	}
+	preview, awserr = e.GetLifecyclePolicyPreview(GetLifecyclePolicyPreviewInput{
+		RepositoryName: "app",
+		Filter:         APIImageFilter{TagStatus: "TAGGED"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(preview.PreviewResults) != 2 || preview.Summary.ExpiringImageTotalCount != 3 {
+		t.Fatalf("Unexpected preview: %+v", preview)
+	}
+	// Previewing doesn't expire anything.
+	if ids := listImages(t, e, ""); len(ids) != 7 {
+		t.Fatal("Unexpected images", ids)
+	}
+
+	e.applyLifecyclePolicies()
+	details, awserr := e.DescribeImages(DescribeImagesInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var remaining []string
+	for _, detail := range details.ImageDetails {
+		remaining = append(remaining, detail.ImageDigest)
+		if slices.Contains(expectedExpired, detail.ImageDigest) {
+			t.Error("Expected image to be expired", detail)
+		}
+	}
+	if len(remaining) != 4 {
+		t.Fatal("Unexpected images", remaining)
+	}
+	output, awserr := e.GetLifecyclePolicy(GetLifecyclePolicyInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.LifecyclePolicyText != policy || output.LastEvaluatedAt != 1700000000 {
+		t.Fatalf("Unexpected policy: %+v", output)
+	}
+
+	_, awserr = e.DeleteLifecyclePolicy(DeleteLifecyclePolicyInput{RepositoryName: "app"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.GetLifecyclePolicy(GetLifecyclePolicyInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "LifecyclePolicyNotFoundException" {
+		t.Fatal("Expected LifecyclePolicyNotFoundException", awserr)
+	}
+	_, awserr = e.StartLifecyclePolicyPreview(StartLifecyclePolicyPreviewInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "LifecyclePolicyNotFoundException" {
+		t.Fatal("Expected LifecyclePolicyNotFoundException", awserr)
+	}
+}
+
+func TestLifecyclePolicyPreviewNotFound(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "app")
+	_, awserr := e.GetLifecyclePolicyPreview(GetLifecyclePolicyPreviewInput{RepositoryName: "app"})
+	if awserr == nil || awserr.Body.Type != "LifecyclePolicyPreviewNotFoundException" {
+		t.Fatal("Expected LifecyclePolicyPreviewNotFoundException", awserr)
+	}
+}
@@ -0,0 +1,529 @@
+package ecr
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/timestamp"
+)
+
+// The Docker Registry HTTP API, which docker push and pull use once logged in with an authorization token.
+// https://distribution.github.io/distribution/spec/api/
+
+const (
+	authorizationTokenLifetime = 12 * time.Hour
+	maxRegistryManifestSize    = 4 * 1024 * 1024
+)
+
+var registryPathRegex = regexp.MustCompile(`^/v2/(.+?)/(manifests/[^/]+|blobs/uploads/[^/]*|blobs/[^/]+|tags/list)$`)
+
+type blobUpload struct {
+	repositoryName string
+	path           string
+
+	// Guards the file, which chunks are appended to.
+	mu sync.Mutex
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_GetAuthorizationToken.html
+func (e *ECR) GetAuthorizationToken(input GetAuthorizationTokenInput) (*GetAuthorizationTokenOutput, *awserrors.Error) {
+	password := base64.RawURLEncoding.EncodeToString(uuid.Must(uuid.NewV4()).Bytes())
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.clock()
+	for other, expiresAt := range e.passwords {
+		if !now.Before(expiresAt) {
+			delete(e.passwords, other)
+		}
+	}
+	expiresAt := now.Add(authorizationTokenLifetime)
+	e.passwords[password] = expiresAt
+
+	return &GetAuthorizationTokenOutput{
+		AuthorizationData: []APIAuthorizationData{{
+			AuthorizationToken: base64.StdEncoding.EncodeToString([]byte("AWS:" + password)),
+			ExpiresAt:          timestamp.EpochSeconds(expiresAt),
+			// The registry is served over plain HTTP, which Docker allows for localhost.
+			ProxyEndpoint: "http://" + e.registryHost(),
+		}},
+	}, nil
+}
+
+// authorized returns the region which issued the request's authorization token, or nil if none did.
+// The registry is served on the same address for every region, so the token says which region's
+// repositories Docker pushes to and pulls from.
+func (e *ECR) authorized(r *http.Request) *ECR {
+	username, password, ok := r.BasicAuth()
+	if !ok || username != "AWS" {
+		return nil
+	}
+	for _, registry := range e.regions.All() {
+		if registry.issued(password) {
+			return registry
+		}
+	}
+	return nil
+}
+
+func (e *ECR) issued(password string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	expiresAt, ok := e.passwords[password]
+	return ok && e.clock().Before(expiresAt)
+}
+
+// ResolveImageUri pins an image URI to the digest of the image its tag refers to.
+// ok is false if the URI isn't in this registry.
+func (e *ECR) ResolveImageUri(imageUri string) (resolved string, ok bool, awserr *awserrors.Error) {
+	host, path, found := strings.Cut(imageUri, "/")
+	if !found || (host != e.registryHost() && host != e.ecrHostname()) {
+		return "", false, nil
+	}
+	name, id := path, APIImageIdentifier{ImageTag: "latest"}
+	if i := strings.LastIndex(path, "@"); i >= 0 {
+		name, id = path[:i], APIImageIdentifier{ImageDigest: path[i+1:]}
+	} else if i := strings.LastIndex(path, ":"); i >= 0 {
+		name, id = path[:i], APIImageIdentifier{ImageTag: path[i+1:]}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repository, awserr := e.lockedGetRepository("", name)
+	if awserr != nil {
+		return "", true, awserr
+	}
+	image, failure := repository.findImage(id)
+	if failure != nil {
+		return "", true, ImageNotFoundException(fmt.Sprintf(
+			"The image with imageId {imageDigest:'%s', imageTag:'%s'} does not exist within the repository with name '%s' in the registry with id '%s'",
+			nullIfEmpty(id.ImageDigest), nullIfEmpty(id.ImageTag), repository.Name, e.registryId()))
+	}
+	return e.registryHost() + "/" + repository.Name + "@" + image.Digest, true, nil
+}
+
+type registryError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeRegistryError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(map[string][]registryError{
+		"errors": {{Code: code, Message: message}},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewHandler(logger *slog.Logger, e *ECR) func(w http.ResponseWriter, r *http.Request) bool {
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		var match []string
+		if r.URL.Path != "/v2/" && r.URL.Path != "/v2" {
+			match = registryPathRegex.FindStringSubmatch(r.URL.Path)
+			if match == nil {
+				return false
+			}
+		}
+		logger.Info("Handling registry request", "method", r.Method, "url", r.URL)
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+
+		registry := e.authorized(r)
+		if registry == nil {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="http://%s",service="ecr.amazonaws.com"`, e.registryHost()))
+			writeRegistryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+			return true
+		}
+		// Docker checks it can log in with the base path.
+		if match == nil {
+			w.WriteHeader(http.StatusOK)
+			return true
+		}
+
+		name, route := match[1], match[2]
+		switch {
+		case route == "tags/list":
+			registry.handleTags(w, r, name)
+		case strings.HasPrefix(route, "manifests/"):
+			registry.handleManifest(w, r, name, strings.TrimPrefix(route, "manifests/"))
+		case strings.HasPrefix(route, "blobs/uploads/"):
+			registry.handleUpload(w, r, name, strings.TrimPrefix(route, "blobs/uploads/"))
+		default:
+			registry.handleBlob(w, r, name, strings.TrimPrefix(route, "blobs/"))
+		}
+		return true
+	}
+}
+
+func writeRepositoryUnknown(w http.ResponseWriter, name string) {
+	writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", fmt.Sprintf("repository '%s' not found", name))
+}
+
+func (e *ECR) blobPath(digest string) string {
+	return filepath.Join(e.blobDir, strings.TrimPrefix(digest, "sha256:"))
+}
+
+func (e *ECR) handleManifest(w http.ResponseWriter, r *http.Request, name string, reference string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		e.mu.Lock()
+		repository, awserr := e.lockedGetRepository("", name)
+		if awserr != nil {
+			e.mu.Unlock()
+			writeRepositoryUnknown(w, name)
+			return
+		}
+		var image *Image
+		if imageDigestRegex.MatchString(reference) {
+			image = repository.imageWithDigest(reference)
+		} else {
+			image = repository.imageWithTag(reference)
+		}
+		if image == nil {
+			e.mu.Unlock()
+			writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("manifest '%s' not found", reference))
+			return
+		}
+		if r.Method == http.MethodGet {
+			image.LastPulledAt = e.clock()
+		}
+		imageManifest, mediaType, digest := image.Manifest, image.MediaType, image.Digest
+		e.mu.Unlock()
+
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("Content-Length", strconv.Itoa(len(imageManifest)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			io.WriteString(w, imageManifest)
+		}
+
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRegistryManifestSize+1))
+		if err != nil {
+			panic(err)
+		}
+		if len(body) > maxRegistryManifestSize {
+			writeRegistryError(w, http.StatusRequestEntityTooLarge, "SIZE_INVALID", "manifest is too large")
+			return
+		}
+		image, awserr := parseManifest(string(body), r.Header.Get("Content-Type"))
+		if awserr != nil {
+			writeRegistryError(w, http.StatusBadRequest, "MANIFEST_INVALID", awserr.Body.Message)
+			return
+		}
+		tag := ""
+		if strings.HasPrefix(reference, "sha256:") {
+			if reference != image.Digest {
+				writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID",
+					fmt.Sprintf("manifest digest '%s' doesn't match '%s'", image.Digest, reference))
+				return
+			}
+		} else {
+			if validateImageTag(reference) != nil {
+				writeRegistryError(w, http.StatusBadRequest, "TAG_INVALID", fmt.Sprintf("invalid tag '%s'", reference))
+				return
+			}
+			tag = reference
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		repository, awserr := e.lockedGetRepository("", name)
+		if awserr != nil {
+			writeRepositoryUnknown(w, name)
+			return
+		}
+		for _, digest := range image.blobDigests {
+			if _, ok := repository.Blobs[digest]; !ok {
+				writeRegistryError(w, http.StatusBadRequest, "MANIFEST_BLOB_UNKNOWN", fmt.Sprintf("blob '%s' not found", digest))
+				return
+			}
+		}
+		for _, digest := range image.manifestDigests {
+			if repository.imageWithDigest(digest) == nil {
+				writeRegistryError(w, http.StatusBadRequest, "MANIFEST_BLOB_UNKNOWN", fmt.Sprintf("manifest '%s' not found", digest))
+				return
+			}
+		}
+		// Docker pushes every tag of an image, so pushing one that's already there isn't an error.
+		_, awserr = e.lockedPutImage(repository, image, tag)
+		if awserr != nil && awserr.Body.Type != "ImageAlreadyExistsException" {
+			writeRegistryError(w, http.StatusBadRequest, "TAG_INVALID", awserr.Body.Message)
+			return
+		}
+		w.Header().Set("Location", "/v2/"+name+"/manifests/"+image.Digest)
+		w.Header().Set("Docker-Content-Digest", image.Digest)
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "images can be deleted with BatchDeleteImage")
+	}
+}
+
+func (e *ECR) handleBlob(w http.ResponseWriter, r *http.Request, name string, digest string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "blobs can't be deleted")
+		return
+	}
+
+	e.mu.Lock()
+	repository, awserr := e.lockedGetRepository("", name)
+	if awserr != nil {
+		e.mu.Unlock()
+		writeRepositoryUnknown(w, name)
+		return
+	}
+	_, ok := repository.Blobs[digest]
+	e.mu.Unlock()
+	if !ok {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UNKNOWN", fmt.Sprintf("blob '%s' not found", digest))
+		return
+	}
+
+	f, err := os.Open(e.blobPath(digest))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
+
+func (e *ECR) handleUpload(w http.ResponseWriter, r *http.Request, name string, id string) {
+	if id == "" {
+		if r.Method != http.MethodPost {
+			writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "uploads are started with POST")
+			return
+		}
+		e.startUpload(w, r, name)
+		return
+	}
+
+	e.mu.Lock()
+	upload, ok := e.uploadsById[id]
+	e.mu.Unlock()
+	if !ok || upload.repositoryName != name {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", fmt.Sprintf("upload '%s' not found", id))
+		return
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		writeUploadProgress(w, name, id, upload.size())
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		upload.append(r.Body)
+		writeUploadProgress(w, name, id, upload.size())
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		upload.append(r.Body)
+		e.completeUpload(w, r.URL.Query().Get("digest"), upload, id)
+	case http.MethodDelete:
+		e.removeUpload(upload, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "unsupported method")
+	}
+}
+
+func (e *ECR) startUpload(w http.ResponseWriter, r *http.Request, name string) {
+	query := r.URL.Query()
+
+	e.mu.Lock()
+	repository, awserr := e.lockedGetRepository("", name)
+	if awserr != nil {
+		e.mu.Unlock()
+		writeRepositoryUnknown(w, name)
+		return
+	}
+	// Layers shared with another repository don't need to be pushed again.
+	if digest := query.Get("mount"); digest != "" {
+		if source, ok := e.repositoriesByName[query.Get("from")]; ok {
+			if size, ok := source.Blobs[digest]; ok {
+				repository.Blobs[digest] = size
+				e.mu.Unlock()
+				w.Header().Set("Location", "/v2/"+name+"/blobs/"+digest)
+				w.Header().Set("Docker-Content-Digest", digest)
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+		}
+	}
+	id := uuid.Must(uuid.NewV4()).String()
+	upload := &blobUpload{
+		repositoryName: name,
+		path:           filepath.Join(e.blobDir, "upload-"+id),
+	}
+	e.uploadsById[id] = upload
+	e.mu.Unlock()
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	upload.append(r.Body)
+	// The whole blob may be sent at once.
+	if query.Has("digest") {
+		e.completeUpload(w, query.Get("digest"), upload, id)
+		return
+	}
+	writeUploadProgress(w, name, id, upload.size())
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeUploadProgress(w http.ResponseWriter, name string, id string, size int64) {
+	w.Header().Set("Location", "/v2/"+name+"/blobs/uploads/"+id)
+	w.Header().Set("Docker-Upload-UUID", id)
+	w.Header().Set("Range", fmt.Sprintf("0-%d", max(size-1, 0)))
+	w.Header().Set("Content-Length", "0")
+}
+
+func (u *blobUpload) append(body io.Reader) {
+	f, err := os.OpenFile(u.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	_, err = io.Copy(f, body)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (u *blobUpload) size() int64 {
+	info, err := os.Stat(u.path)
+	if err != nil {
+		panic(err)
+	}
+	return info.Size()
+}
+
+// completeUpload moves the upload into the blob store if it has the expected digest.
+// The upload's lock must be held.
+func (e *ECR) completeUpload(w http.ResponseWriter, digest string, upload *blobUpload, id string) {
+	f, err := os.Open(upload.path)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	f.Close()
+	if err != nil {
+		panic(err)
+	}
+	actual := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if digest != actual {
+		e.removeUpload(upload, id)
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", fmt.Sprintf("blob digest '%s' doesn't match '%s'", actual, digest))
+		return
+	}
+	err = os.Rename(upload.path, e.blobPath(digest))
+	if err != nil {
+		panic(err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.uploadsById, id)
+	repository, ok := e.repositoriesByName[upload.repositoryName]
+	if !ok {
+		writeRepositoryUnknown(w, upload.repositoryName)
+		return
+	}
+	repository.Blobs[digest] = size
+	w.Header().Set("Location", "/v2/"+upload.repositoryName+"/blobs/"+digest)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// The upload's lock must be held.
+func (e *ECR) removeUpload(upload *blobUpload, id string) {
+	err := os.Remove(upload.path)
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.uploadsById, id)
+}
+
+func (e *ECR) handleTags(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "tags are listed with GET")
+		return
+	}
+	query := r.URL.Query()
+	n := 0
+	if query.Has("n") {
+		var err error
+		n, err = strconv.Atoi(query.Get("n"))
+		if err != nil || n < 0 {
+			writeRegistryError(w, http.StatusBadRequest, "PAGINATION_NUMBER_INVALID", "invalid number of results requested")
+			return
+		}
+	}
+
+	e.mu.Lock()
+	repository, awserr := e.lockedGetRepository("", name)
+	if awserr != nil {
+		e.mu.Unlock()
+		writeRepositoryUnknown(w, name)
+		return
+	}
+	tags := []string{}
+	for _, image := range repository.Images {
+		tags = append(tags, image.Tags...)
+	}
+	e.mu.Unlock()
+
+	slices.Sort(tags)
+	if last := query.Get("last"); last != "" {
+		start, found := slices.BinarySearch(tags, last)
+		if found {
+			start++
+		}
+		tags = tags[start:]
+	}
+	if n > 0 && len(tags) > n {
+		tags = tags[:n]
+		w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=%d&last=%s>; rel="next"`, name, n, tags[n-1]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"name": name,
+		"tags": tags,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,323 @@
+package ecr
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const dockerManifestV2 = "application/vnd.docker.distribution.manifest.v2+json"
+
+func newRegistry(t *testing.T) (*ECR, *httptest.Server, string) {
+	e := newECR(t)
+	handler := NewHandler(slog.Default(), e)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !handler(w, r) {
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	output, awserr := e.GetAuthorizationToken(GetAuthorizationTokenInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	token, err := base64.StdEncoding.DecodeString(output.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, password, _ := strings.Cut(string(token), ":")
+	return e, server, password
+}
+
+func registryRequest(t *testing.T, server *httptest.Server, password string, method string, path string, body string, contentType string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password != "" {
+		req.SetBasicAuth("AWS", password)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func blobDigest(blob string) string {
+	hash := sha256.Sum256([]byte(blob))
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+func TestRegistryAuthorization(t *testing.T) {
+	e, server, password := newRegistry(t)
+
+	resp := registryRequest(t, server, "", http.MethodGet, "/v2/", "", "")
+	if resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Basic ") {
+		t.Fatal("Expected a challenge", resp.Status, resp.Header)
+	}
+	resp = registryRequest(t, server, "wrong", http.MethodGet, "/v2/", "", "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("Expected the password to be rejected", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/", "", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Docker-Distribution-API-Version") != "registry/2.0" {
+		t.Fatal("Expected to be logged in", resp.Status, resp.Header)
+	}
+
+	// Other paths are left to other services.
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/email/templates", "", "")
+	if resp.StatusCode != http.StatusTeapot {
+		t.Fatal("Expected the request to be unhandled", resp.Status)
+	}
+
+	e.clock = func() time.Time { return time.Unix(1700000000, 0).Add(authorizationTokenLifetime) }
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/", "", "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("Expected the token to have expired", resp.Status)
+	}
+}
+
+func TestRegistryPushAndPull(t *testing.T) {
+	e, server, password := newRegistry(t)
+	createRepository(t, e, "team/app")
+	createRepository(t, e, "other")
+
+	config := `{"architecture": "amd64", "os": "linux"}`
+	layer := "layer contents"
+	imageManifest := fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+  "config": {"mediaType": "application/vnd.docker.container.image.v1+json", "size": %d, "digest": "%s"},
+  "layers": [{"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip", "size": %d, "digest": "%s"}]
+}`, len(config), blobDigest(config), len(layer), blobDigest(layer))
+
+	// The manifest can't be pushed before its blobs.
+	resp := registryRequest(t, server, password, http.MethodPut, "/v2/team/app/manifests/v1", imageManifest, dockerManifestV2)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("Expected MANIFEST_BLOB_UNKNOWN", resp.Status)
+	}
+
+	// The config is pushed in one request.
+	resp = registryRequest(t, server, password, http.MethodPost, "/v2/team/app/blobs/uploads/?digest="+blobDigest(config), config, "")
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Docker-Content-Digest") != blobDigest(config) {
+		t.Fatal("Unexpected response", resp.Status, resp.Header)
+	}
+	// The layer is pushed in chunks.
+	resp = registryRequest(t, server, password, http.MethodPost, "/v2/team/app/blobs/uploads/", "", "")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatal("Unexpected response", resp.Status)
+	}
+	location := resp.Header.Get("Location")
+	resp = registryRequest(t, server, password, http.MethodPatch, location, layer[:5], "")
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Range") != "0-4" {
+		t.Fatal("Unexpected response", resp.Status, resp.Header)
+	}
+	resp = registryRequest(t, server, password, http.MethodPut, location+"?digest="+blobDigest("wrong"), layer[5:], "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("Expected DIGEST_INVALID", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodPatch, location, layer, "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("Expected the failed upload to be removed", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodPost, "/v2/team/app/blobs/uploads/", "", "")
+	location = resp.Header.Get("Location")
+	resp = registryRequest(t, server, password, http.MethodPatch, location, layer[:5], "")
+	resp = registryRequest(t, server, password, http.MethodPut, location+"?digest="+blobDigest(layer), layer[5:], "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatal("Unexpected response", resp.Status)
+	}
+
+	resp = registryRequest(t, server, password, http.MethodPut, "/v2/team/app/manifests/v1", imageManifest, dockerManifestV2)
+	digest := manifestDigest(imageManifest)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Docker-Content-Digest") != digest {
+		t.Fatal("Unexpected response", resp.Status, resp.Header)
+	}
+	// Pushing the same tag again is fine.
+	resp = registryRequest(t, server, password, http.MethodPut, "/v2/team/app/manifests/v1", imageManifest, dockerManifestV2)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatal("Unexpected response", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodPut, "/v2/team/app/manifests/"+blobDigest("wrong"), imageManifest, dockerManifestV2)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("Expected DIGEST_INVALID", resp.Status)
+	}
+	if ids := listImagesIn(t, e, "team/app"); !slices.Equal(ids, []APIImageIdentifier{{ImageDigest: digest, ImageTag: "v1"}}) {
+		t.Fatal("Unexpected images", ids)
+	}
+
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/team/app/manifests/v1", "", "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != imageManifest ||
+		resp.Header.Get("Content-Type") != dockerManifestV2 || resp.Header.Get("Docker-Content-Digest") != digest {
+		t.Fatal("Unexpected response", resp.Status, resp.Header, string(body))
+	}
+	resp = registryRequest(t, server, password, http.MethodHead, "/v2/team/app/manifests/"+digest, "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Unexpected response", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/team/app/manifests/v2", "", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("Expected MANIFEST_UNKNOWN", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/team/app/blobs/"+blobDigest(layer), "", "")
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != layer {
+		t.Fatal("Unexpected response", resp.Status, string(body))
+	}
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/other/blobs/"+blobDigest(layer), "", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("Expected BLOB_UNKNOWN", resp.Status)
+	}
+
+	// Blobs can be mounted from another repository.
+	resp = registryRequest(t, server, password, http.MethodPost, "/v2/other/blobs/uploads/?mount="+blobDigest(layer)+"&from=team/app", "", "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatal("Unexpected response", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodHead, "/v2/other/blobs/"+blobDigest(layer), "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Unexpected response", resp.Status)
+	}
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/missing/manifests/v1", "", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("Expected NAME_UNKNOWN", resp.Status)
+	}
+}
+
+func listImagesIn(t *testing.T, e *ECR, repositoryName string) []APIImageIdentifier {
+	t.Helper()
+	output, awserr := e.ListImages(ListImagesInput{RepositoryName: repositoryName})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.ImageIds
+}
+
+func TestRegistryTags(t *testing.T) {
+	e, server, password := newRegistry(t)
+	createRepository(t, e, "app")
+	putImage(t, e, "app", "v2", testManifest(1))
+	putImage(t, e, "app", "v1", testManifest(1))
+	putImage(t, e, "app", "latest", testManifest(2))
+
+	var tags struct {
+		Name string
+		Tags []string
+	}
+	resp := registryRequest(t, server, password, http.MethodGet, "/v2/app/tags/list?n=2", "", "")
+	err := json.NewDecoder(resp.Body).Decode(&tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags.Name != "app" || !slices.Equal(tags.Tags, []string{"latest", "v1"}) ||
+		resp.Header.Get("Link") != `</v2/app/tags/list?n=2&last=v1>; rel="next"` {
+		t.Fatal("Unexpected tags", tags, resp.Header)
+	}
+	resp = registryRequest(t, server, password, http.MethodGet, "/v2/app/tags/list?n=2&last=v1", "", "")
+	err = json.NewDecoder(resp.Body).Decode(&tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tags.Tags, []string{"v2"}) || resp.Header.Get("Link") != "" {
+		t.Fatal("Unexpected tags", tags, resp.Header)
+	}
+}
+
+func TestResolveImageUri(t *testing.T) {
+	e := newECR(t)
+	createRepository(t, e, "team/app")
+	image := putImage(t, e, "team/app", "latest", testManifest(1))
+	expected := "123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app@" + image.ImageId.ImageDigest
+
+	for _, imageUri := range []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app:latest",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app@" + image.ImageId.ImageDigest,
+	} {
+		resolved, ok, awserr := e.ResolveImageUri(imageUri)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if !ok || resolved != expected {
+			t.Errorf("%s: unexpected resolution %s", imageUri, resolved)
+		}
+	}
+
+	_, ok, awserr := e.ResolveImageUri("docker.io/library/alpine:3")
+	if ok || awserr != nil {
+		t.Fatal("Expected other registries to be ignored", awserr)
+	}
+	_, ok, awserr = e.ResolveImageUri("123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app:v1")
+	if !ok || awserr == nil || awserr.Body.Type != "ImageNotFoundException" {
+		t.Fatal("Expected ImageNotFoundException", awserr)
+	}
+	_, ok, awserr = e.ResolveImageUri("123456789012.dkr.ecr.us-east-1.amazonaws.com/missing")
+	if !ok || awserr == nil || awserr.Body.Type != "RepositoryNotFoundException" {
+		t.Fatal("Expected RepositoryNotFoundException", awserr)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	e, server, password := newRegistry(t)
+	if _, err := e.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected an unknown region to fail")
+	}
+	europe, err := e.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := createRepository(t, europe, "app")
+	if repository.RepositoryArn != "arn:aws:ecr:eu-west-1:123456789012:repository/app" ||
+		repository.RepositoryUri != "123456789012.dkr.ecr.eu-west-1.amazonaws.com/app" {
+		t.Fatalf("Unexpected repository: %+v", repository)
+	}
+	image := putImage(t, europe, "app", "latest", testManifest(1))
+	if _, awserr := e.DescribeRepositories(DescribeRepositoriesInput{RepositoryNames: []string{"app"}}); awserr == nil {
+		t.Fatal("Expected another region's repository to be hidden")
+	}
+
+	// Docker reaches the repositories of the region which issued its token.
+	resp := registryRequest(t, server, password, http.MethodGet, "/v2/app/tags/list", "", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("Expected another region's repository to be unknown", resp.Status)
+	}
+	output, awserr := europe.GetAuthorizationToken(GetAuthorizationTokenInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	token, err := base64.StdEncoding.DecodeString(output.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, europePassword, _ := strings.Cut(string(token), ":")
+	resp = registryRequest(t, server, europePassword, http.MethodGet, "/v2/app/tags/list", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Expected the region's repository", resp.Status)
+	}
+
+	resolved, ok, awserr := europe.ResolveImageUri(repository.RepositoryUri)
+	if !ok || awserr != nil || resolved != repository.RepositoryUri+"@"+image.ImageId.ImageDigest {
+		t.Fatal("Unexpected resolution", resolved, awserr)
+	}
+	if _, ok, _ := e.ResolveImageUri(repository.RepositoryUri); ok {
+		t.Fatal("Expected another region's registry to be ignored")
+	}
+}
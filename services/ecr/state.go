@@ -0,0 +1,181 @@
+package ecr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type repositoryState struct {
+	*Repository
+	// The lifecycle policy's text, which is parsed again on import. Empty if there's no policy.
+	LifecyclePolicy string `json:",omitempty"`
+}
+
+type repositoriesState struct {
+	Repositories []repositoryState
+}
+
+func blobName(digest string) string {
+	return "blobs/" + strings.TrimPrefix(digest, "sha256:")
+}
+
+// TryPause pauses changes to each region's repositories while a snapshot copies them.
+func (e *ECR) TryPause() (func(), bool) {
+	return e.regions.TryPause(func(e *ECR) *sync.Mutex { return &e.mu })
+}
+
+// ExportState writes each region's repositories, their images and the blobs they were pushed with
+// to the archive. Each region's blobs are written once, named by their digests. Uploads which
+// haven't completed and authorization tokens aren't exported.
+func (e *ECR) ExportState(w *state.Writer) error {
+	return e.regions.ExportState(w, (*ECR).exportState)
+}
+
+func (e *ECR) exportState(w *state.Writer) error {
+	var exported repositoriesState
+	unlock := w.Lock(&e.mu)
+	for _, repository := range e.repositoriesByName {
+		r := repositoryState{Repository: repository}
+		if repository.LifecyclePolicy != nil {
+			r.LifecyclePolicy = repository.LifecyclePolicy.Text
+		}
+		exported.Repositories = append(exported.Repositories, r)
+	}
+	slices.SortFunc(exported.Repositories, func(a, b repositoryState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	// Marshal while holding the lock, since images are modified in place.
+	err := w.WriteJSON("repositories.json", exported)
+	blobs := make(map[string]int64)
+	for _, repository := range exported.Repositories {
+		for digest, size := range repository.Blobs {
+			blobs[digest] = size
+		}
+	}
+	unlock()
+	if err != nil {
+		return err
+	}
+
+	// Blobs are never modified or deleted, so they can be read without holding the lock.
+	digests := make([]string, 0, len(blobs))
+	for digest := range blobs {
+		digests = append(digests, digest)
+	}
+	slices.Sort(digests)
+	for _, digest := range digests {
+		path := e.blobPath(digest)
+		err := w.WriteBlob(blobName(digest), blobs[digest], func() (io.ReadCloser, error) {
+			return os.Open(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportState replaces each region's repositories and their images with the archive's. Manifests
+// and lifecycle policies are parsed again. The blobs are streamed into the blob store as they're
+// checked, which doesn't change any repository even if the import then fails.
+func (e *ECR) ImportState(r *state.Reader) (func() error, error) {
+	return e.regions.ImportState(r, (*ECR).importState, (*ECR).exportState)
+}
+
+func (e *ECR) importState(r *state.Reader) (func() error, error) {
+	var imported repositoriesState
+	if err := r.ReadJSON("repositories.json", &imported); err != nil {
+		return nil, err
+	}
+
+	repositoriesByName := make(map[string]*Repository)
+	imports := make(map[string]bool)
+	for _, rs := range imported.Repositories {
+		repository := rs.Repository
+		if repository == nil || !repositoryNameRegex.MatchString(repository.Name) || repository.ARN == "" {
+			return nil, fmt.Errorf("invalid repository")
+		}
+		repository.LifecyclePolicy = nil
+		if rs.LifecyclePolicy != "" {
+			policy, awserr := parseLifecyclePolicy(rs.LifecyclePolicy)
+			if awserr != nil {
+				return nil, fmt.Errorf("repository %s: %s", repository.Name, awserr.Body.Message)
+			}
+			repository.LifecyclePolicy = policy
+		}
+		for i, image := range repository.Images {
+			if image == nil {
+				return nil, fmt.Errorf("repository %s: invalid image", repository.Name)
+			}
+			parsed, awserr := parseManifest(image.Manifest, image.MediaType)
+			if awserr != nil || parsed.Digest != image.Digest {
+				return nil, fmt.Errorf("repository %s: image %s has an invalid manifest", repository.Name, image.Digest)
+			}
+			parsed.Tags = image.Tags
+			parsed.PushedAt = image.PushedAt
+			parsed.LastPulledAt = image.LastPulledAt
+			repository.Images[i] = parsed
+		}
+		if repository.Tags == nil {
+			repository.Tags = make(map[string]string)
+		}
+		if repository.Blobs == nil {
+			repository.Blobs = make(map[string]int64)
+		}
+		for digest, size := range repository.Blobs {
+			if imports[digest] {
+				continue
+			}
+			imports[digest] = true
+			if err := e.importBlob(r, digest, size); err != nil {
+				return nil, fmt.Errorf("blob %s: %w", digest, err)
+			}
+		}
+		repositoriesByName[repository.Name] = repository
+	}
+
+	return func() error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		// Uploads in progress complete into the imported repository with the same name, if any.
+		e.repositoriesByName = repositoriesByName
+		return nil
+	}, nil
+}
+
+// importBlob copies the blob into the blob store if it has the expected digest and size.
+func (e *ECR) importBlob(r *state.Reader, digest string, size int64) error {
+	if !imageDigestRegex.MatchString(digest) {
+		return fmt.Errorf("invalid digest")
+	}
+	src, err := r.Open(blobName(digest))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.CreateTemp(e.blobDir, "import-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dst, hash), src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if "sha256:"+hex.EncodeToString(hash.Sum(nil)) != digest || n != size {
+		return fmt.Errorf("doesn't match its digest")
+	}
+	return os.Rename(dst.Name(), e.blobPath(digest))
+}
@@ -0,0 +1,360 @@
+package ecr
+
+// ECR's JSON fields are camelCase, except for tags.
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_Tag.html
+type APITag struct {
+	Key   string `required:"true" length:"1,128"`
+	Value string `length:",256"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ImageScanningConfiguration.html
+type APIImageScanningConfiguration struct {
+	ScanOnPush bool `json:"scanOnPush"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_EncryptionConfiguration.html
+type APIEncryptionConfiguration struct {
+	// AES256 or KMS.
+	EncryptionType string `json:"encryptionType" required:"true" enum:"AES256|KMS|KMS_DSSE"`
+	KmsKey         string `json:"kmsKey,omitempty" length:"1,2048"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_Repository.html
+type APIRepository struct {
+	RepositoryArn              string                        `json:"repositoryArn"`
+	RegistryId                 string                        `json:"registryId"`
+	RepositoryName             string                        `json:"repositoryName"`
+	RepositoryUri              string                        `json:"repositoryUri"`
+	CreatedAt                  float64                       `json:"createdAt"`
+	ImageTagMutability         string                        `json:"imageTagMutability"`
+	ImageScanningConfiguration APIImageScanningConfiguration `json:"imageScanningConfiguration"`
+	EncryptionConfiguration    APIEncryptionConfiguration    `json:"encryptionConfiguration"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ImageIdentifier.html
+type APIImageIdentifier struct {
+	ImageDigest string `json:"imageDigest,omitempty" length:",1024"`
+	ImageTag    string `json:"imageTag,omitempty" length:"1,300"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_Image.html
+type APIImage struct {
+	RegistryId             string             `json:"registryId"`
+	RepositoryName         string             `json:"repositoryName"`
+	ImageId                APIImageIdentifier `json:"imageId"`
+	ImageManifest          string             `json:"imageManifest"`
+	ImageManifestMediaType string             `json:"imageManifestMediaType"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ImageDetail.html
+type APIImageDetail struct {
+	RegistryId             string   `json:"registryId"`
+	RepositoryName         string   `json:"repositoryName"`
+	ImageDigest            string   `json:"imageDigest"`
+	ImageTags              []string `json:"imageTags,omitempty"`
+	ImageSizeInBytes       int64    `json:"imageSizeInBytes"`
+	ImagePushedAt          float64  `json:"imagePushedAt"`
+	ImageManifestMediaType string   `json:"imageManifestMediaType"`
+	ArtifactMediaType      string   `json:"artifactMediaType,omitempty"`
+	LastRecordedPullTime   float64  `json:"lastRecordedPullTime,omitempty"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ImageFailure.html
+type APIImageFailure struct {
+	ImageId       APIImageIdentifier `json:"imageId"`
+	FailureCode   string             `json:"failureCode"`
+	FailureReason string             `json:"failureReason"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_ListImagesFilter.html
+type APIImageFilter struct {
+	// TAGGED, UNTAGGED or ANY.
+	TagStatus string `json:"tagStatus,omitempty" enum:"TAGGED|UNTAGGED|ANY"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_LifecyclePolicyPreviewResult.html
+type APILifecyclePolicyPreviewResult struct {
+	ImageTags           []string                     `json:"imageTags,omitempty"`
+	ImageDigest         string                       `json:"imageDigest"`
+	ImagePushedAt       float64                      `json:"imagePushedAt"`
+	Action              APILifecyclePolicyRuleAction `json:"action"`
+	AppliedRulePriority int                          `json:"appliedRulePriority"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_LifecyclePolicyRuleAction.html
+type APILifecyclePolicyRuleAction struct {
+	Type string `json:"type"`
+}
+
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_LifecyclePolicyPreviewSummary.html
+type APILifecyclePolicyPreviewSummary struct {
+	ExpiringImageTotalCount int `json:"expiringImageTotalCount"`
+}
+
+type CreateRepositoryInput struct {
+	RegistryId                 string                         `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName             string                         `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	Tags                       []APITag                       `json:"tags" length:",200"`
+	ImageTagMutability         string                         `json:"imageTagMutability" enum:"MUTABLE|IMMUTABLE"`
+	ImageScanningConfiguration *APIImageScanningConfiguration `json:"imageScanningConfiguration"`
+	EncryptionConfiguration    *APIEncryptionConfiguration    `json:"encryptionConfiguration"`
+}
+
+type CreateRepositoryOutput struct {
+	Repository APIRepository `json:"repository"`
+}
+
+type DescribeRepositoriesInput struct {
+	RegistryId      string   `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryNames []string `json:"repositoryNames" length:"1,100"`
+	MaxResults      int      `json:"maxResults" range:"1,1000"`
+	NextToken       string   `json:"nextToken"`
+}
+
+type DescribeRepositoriesOutput struct {
+	Repositories []APIRepository `json:"repositories"`
+	NextToken    string          `json:"nextToken,omitempty"`
+}
+
+type DeleteRepositoryInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	// Whether to delete the repository's images too.
+	Force bool `json:"force"`
+}
+
+type DeleteRepositoryOutput struct {
+	Repository APIRepository `json:"repository"`
+}
+
+type PutImageTagMutabilityInput struct {
+	RegistryId         string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName     string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageTagMutability string `json:"imageTagMutability" required:"true" enum:"MUTABLE|IMMUTABLE"`
+}
+
+type PutImageTagMutabilityOutput struct {
+	RegistryId         string `json:"registryId"`
+	RepositoryName     string `json:"repositoryName"`
+	ImageTagMutability string `json:"imageTagMutability"`
+}
+
+type PutImageScanningConfigurationInput struct {
+	RegistryId                 string                        `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName             string                        `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageScanningConfiguration APIImageScanningConfiguration `json:"imageScanningConfiguration"`
+}
+
+type PutImageScanningConfigurationOutput struct {
+	RegistryId                 string                        `json:"registryId"`
+	RepositoryName             string                        `json:"repositoryName"`
+	ImageScanningConfiguration APIImageScanningConfiguration `json:"imageScanningConfiguration"`
+}
+
+type SetRepositoryPolicyInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	PolicyText     string `json:"policyText" required:"true" length:",10240"`
+	// The policy isn't checked for lockouts, so this has no effect.
+	Force bool `json:"force"`
+}
+
+type SetRepositoryPolicyOutput struct {
+	RegistryId     string `json:"registryId"`
+	RepositoryName string `json:"repositoryName"`
+	PolicyText     string `json:"policyText"`
+}
+
+type GetRepositoryPolicyInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+}
+
+type GetRepositoryPolicyOutput struct {
+	RegistryId     string `json:"registryId"`
+	RepositoryName string `json:"repositoryName"`
+	PolicyText     string `json:"policyText"`
+}
+
+type DeleteRepositoryPolicyInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+}
+
+type DeleteRepositoryPolicyOutput struct {
+	RegistryId     string `json:"registryId"`
+	RepositoryName string `json:"repositoryName"`
+	PolicyText     string `json:"policyText"`
+}
+
+type TagResourceInput struct {
+	ResourceArn string   `json:"resourceArn" required:"true"`
+	Tags        []APITag `json:"tags" required:"true" length:",200"`
+}
+
+type TagResourceOutput struct{}
+
+type UntagResourceInput struct {
+	ResourceArn string   `json:"resourceArn" required:"true"`
+	TagKeys     []string `json:"tagKeys" required:"true" length:",200"`
+}
+
+type UntagResourceOutput struct{}
+
+type ListTagsForResourceInput struct {
+	ResourceArn string `json:"resourceArn" required:"true"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags []APITag `json:"tags"`
+}
+
+type PutImageInput struct {
+	RegistryId             string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName         string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageManifest          string `json:"imageManifest" required:"true" length:"1,4194304"`
+	ImageManifestMediaType string `json:"imageManifestMediaType" length:",255"`
+	ImageTag               string `json:"imageTag" length:"1,300"`
+	ImageDigest            string `json:"imageDigest" length:",1024"`
+}
+
+type PutImageOutput struct {
+	Image APIImage `json:"image"`
+}
+
+type BatchGetImageInput struct {
+	RegistryId     string               `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string               `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageIds       []APIImageIdentifier `json:"imageIds" required:"true" length:"1,100"`
+	// Manifests aren't converted, so this is ignored.
+	AcceptedMediaTypes []string `json:"acceptedMediaTypes" length:"1,100"`
+}
+
+type BatchGetImageOutput struct {
+	Images   []APIImage        `json:"images"`
+	Failures []APIImageFailure `json:"failures"`
+}
+
+type BatchDeleteImageInput struct {
+	RegistryId     string               `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string               `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageIds       []APIImageIdentifier `json:"imageIds" required:"true" length:"1,100"`
+}
+
+type BatchDeleteImageOutput struct {
+	ImageIds []APIImageIdentifier `json:"imageIds"`
+	Failures []APIImageFailure    `json:"failures"`
+}
+
+type ListImagesInput struct {
+	RegistryId     string         `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string         `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	Filter         APIImageFilter `json:"filter"`
+	MaxResults     int            `json:"maxResults" range:"1,1000"`
+	NextToken      string         `json:"nextToken"`
+}
+
+type ListImagesOutput struct {
+	ImageIds  []APIImageIdentifier `json:"imageIds"`
+	NextToken string               `json:"nextToken,omitempty"`
+}
+
+type DescribeImagesInput struct {
+	RegistryId     string               `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string               `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageIds       []APIImageIdentifier `json:"imageIds" length:"1,100"`
+	Filter         APIImageFilter       `json:"filter"`
+	MaxResults     int                  `json:"maxResults" range:"1,1000"`
+	NextToken      string               `json:"nextToken"`
+}
+
+type DescribeImagesOutput struct {
+	ImageDetails []APIImageDetail `json:"imageDetails"`
+	NextToken    string           `json:"nextToken,omitempty"`
+}
+
+type PutLifecyclePolicyInput struct {
+	RegistryId          string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName      string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	LifecyclePolicyText string `json:"lifecyclePolicyText" required:"true" length:"100,30720"`
+}
+
+type PutLifecyclePolicyOutput struct {
+	RegistryId          string `json:"registryId"`
+	RepositoryName      string `json:"repositoryName"`
+	LifecyclePolicyText string `json:"lifecyclePolicyText"`
+}
+
+type GetLifecyclePolicyInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+}
+
+type GetLifecyclePolicyOutput struct {
+	RegistryId          string  `json:"registryId"`
+	RepositoryName      string  `json:"repositoryName"`
+	LifecyclePolicyText string  `json:"lifecyclePolicyText"`
+	LastEvaluatedAt     float64 `json:"lastEvaluatedAt,omitempty"`
+}
+
+type DeleteLifecyclePolicyInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+}
+
+type DeleteLifecyclePolicyOutput struct {
+	RegistryId          string  `json:"registryId"`
+	RepositoryName      string  `json:"repositoryName"`
+	LifecyclePolicyText string  `json:"lifecyclePolicyText"`
+	LastEvaluatedAt     float64 `json:"lastEvaluatedAt,omitempty"`
+}
+
+type StartLifecyclePolicyPreviewInput struct {
+	RegistryId     string `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	// Defaults to the repository's lifecycle policy.
+	LifecyclePolicyText string `json:"lifecyclePolicyText" length:"100,30720"`
+}
+
+type StartLifecyclePolicyPreviewOutput struct {
+	RegistryId          string `json:"registryId"`
+	RepositoryName      string `json:"repositoryName"`
+	LifecyclePolicyText string `json:"lifecyclePolicyText"`
+	Status              string `json:"status"`
+}
+
+type GetLifecyclePolicyPreviewInput struct {
+	RegistryId     string               `json:"registryId" pattern:"[0-9]{12}"`
+	RepositoryName string               `json:"repositoryName" required:"true" length:"2,256" pattern:"(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*"`
+	ImageIds       []APIImageIdentifier `json:"imageIds" length:"1,100"`
+	Filter         APIImageFilter       `json:"filter"`
+	MaxResults     int                  `json:"maxResults" range:"1,100"`
+	NextToken      string               `json:"nextToken"`
+}
+
+type GetLifecyclePolicyPreviewOutput struct {
+	RegistryId          string                            `json:"registryId"`
+	RepositoryName      string                            `json:"repositoryName"`
+	LifecyclePolicyText string                            `json:"lifecyclePolicyText"`
+	Status              string                            `json:"status"`
+	PreviewResults      []APILifecyclePolicyPreviewResult `json:"previewResults"`
+	Summary             APILifecyclePolicyPreviewSummary  `json:"summary"`
+	NextToken           string                            `json:"nextToken,omitempty"`
+}
+
+type APIAuthorizationData struct {
+	// Base64 of "AWS:<password>", for docker login.
+	AuthorizationToken string  `json:"authorizationToken"`
+	ExpiresAt          float64 `json:"expiresAt"`
+	ProxyEndpoint      string  `json:"proxyEndpoint"`
+}
+
+type GetAuthorizationTokenInput struct {
+	// Deprecated, and ignored.
+	RegistryIds []string `json:"registryIds" length:"1,10" pattern:"[0-9]{12}"`
+}
+
+type GetAuthorizationTokenOutput struct {
+	AuthorizationData []APIAuthorizationData `json:"authorizationData"`
+}
@@ -0,0 +1,159 @@
+package ecscredentials
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/services/sts"
+)
+
+// This package emulates the container credentials endpoint of ECS and Fargate tasks, which the SDKs
+// get credentials from if AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or AWS_CONTAINER_CREDENTIALS_FULL_URI is set.
+// See https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-iam-roles.html
+//
+// Credentials are served at /v2/credentials/<roleName> for the role with that name, so each
+// container can be given its own role by its URI.
+
+const (
+	credentialsPath = "/v2/credentials/"
+	sessionName     = "ecs-task"
+)
+
+var roleNameRegex = regexp.MustCompile(`^[\w+=,.@-]{1,64}$`)
+
+// Credentials is the local STS service, which issues the credentials of the roles.
+type Credentials interface {
+	AssumeRole(input sts.AssumeRoleInput) (*sts.AssumeRoleOutput, *awserrors.Error)
+}
+
+type ECSCredentials struct {
+	logger             *slog.Logger
+	arnGenerator       arn.Generator
+	sts                Credentials
+	authorizationToken string
+	duration           time.Duration
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu sync.Mutex
+	// Keyed by role name.
+	credentials map[string]sts.APICredentials
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	STS          Credentials
+	// If set, requests must have it as their Authorization header, like the value of
+	// AWS_CONTAINER_AUTHORIZATION_TOKEN which the SDKs send with full URIs.
+	AuthorizationToken string
+	// How long credentials are valid for. They're rotated once three quarters of it have passed,
+	// so clients refresh them before they expire.
+	Duration time.Duration
+}
+
+func New(options Options) *ECSCredentials {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.Duration == 0 {
+		options.Duration = time.Hour
+	}
+
+	return &ECSCredentials{
+		logger:             options.Logger,
+		arnGenerator:       options.ArnGenerator,
+		sts:                options.STS,
+		authorizationToken: options.AuthorizationToken,
+		duration:           options.Duration,
+		clock:              clock.Now,
+		credentials:        make(map[string]sts.APICredentials),
+	}
+}
+
+// Serve serves credentials requests on the listener until it's closed.
+func (e *ECSCredentials) Serve(listener net.Listener) error {
+	err := http.Serve(listener, e)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// The response is the same as the ECS agent's.
+type credentialsResponse struct {
+	AccessKeyId     string
+	Expiration      string
+	RoleArn         string
+	SecretAccessKey string
+	Token           string
+}
+
+func (e *ECSCredentials) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if e.authorizationToken != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(e.authorizationToken)) != 1 {
+		e.logger.Debug("Rejected request without the authorization token", "path", r.URL.Path)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	roleName, ok := strings.CutPrefix(r.URL.Path, credentialsPath)
+	if !ok || !roleNameRegex.MatchString(roleName) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	roleArn := fmt.Sprintf("arn:aws:iam::%s:role/%s", e.arnGenerator.AwsAccountId, roleName)
+	credentials, err := e.roleCredentials(roleName, roleArn)
+	if err != nil {
+		e.logger.Error("Issuing credentials", "role", roleName, "err", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentialsResponse{
+		AccessKeyId:     credentials.AccessKeyId,
+		Expiration:      credentials.Expiration.UTC().Format(time.RFC3339),
+		RoleArn:         roleArn,
+		SecretAccessKey: credentials.SecretAccessKey,
+		Token:           credentials.SessionToken,
+	})
+}
+
+// roleCredentials returns the role's current credentials, assuming a new session of the role
+// once three quarters of their duration have passed.
+func (e *ECSCredentials) roleCredentials(roleName string, roleArn string) (sts.APICredentials, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if credentials, ok := e.credentials[roleName]; ok && e.clock().Add(e.duration/4).Before(credentials.Expiration) {
+		return credentials, nil
+	}
+	output, awserr := e.sts.AssumeRole(sts.AssumeRoleInput{
+		RoleArn:         roleArn,
+		RoleSessionName: sessionName,
+		DurationSeconds: int32(e.duration / time.Second),
+	})
+	if awserr != nil {
+		return sts.APICredentials{}, fmt.Errorf("assuming role %s: %s", roleName, awserr.Body.Message)
+	}
+	e.logger.Info("Issued container credentials", "role", roleName, "accessKeyId", output.Credentials.AccessKeyId,
+		"expiration", output.Credentials.Expiration)
+	e.credentials[roleName] = output.Credentials
+	return output.Credentials, nil
+}
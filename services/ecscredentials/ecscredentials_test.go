@@ -0,0 +1,89 @@
+package ecscredentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/sts"
+)
+
+// fakeSTS issues numbered credentials which expire relative to the test's clock.
+type fakeSTS struct {
+	now    *time.Time
+	issued int
+}
+
+func (f *fakeSTS) AssumeRole(input sts.AssumeRoleInput) (*sts.AssumeRoleOutput, *awserrors.Error) {
+	f.issued++
+	return &sts.AssumeRoleOutput{
+		Credentials: sts.APICredentials{
+			AccessKeyId:     fmt.Sprintf("ASIA%d", f.issued),
+			SecretAccessKey: "secret",
+			SessionToken:    "token",
+			Expiration:      f.now.Add(time.Duration(input.DurationSeconds) * time.Second),
+		},
+	}, nil
+}
+
+func get(e *ECSCredentials, path string, authorization string) (*httptest.ResponseRecorder, credentialsResponse) {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	var credentials credentialsResponse
+	json.Unmarshal(response.Body.Bytes(), &credentials)
+	return response, credentials
+}
+
+func TestRotation(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := New(Options{
+		ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		STS:          &fakeSTS{now: &now},
+		Duration:     20 * time.Minute,
+	})
+	e.clock = func() time.Time { return now }
+
+	response, first := get(e, "/v2/credentials/app", "")
+	if response.Code != http.StatusOK || first.AccessKeyId != "ASIA1" ||
+		first.RoleArn != "arn:aws:iam::123456789012:role/app" || first.Expiration != "2024-01-02T03:24:05Z" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	// Credentials are reused until three quarters of their duration have passed.
+	now = now.Add(10 * time.Minute)
+	if _, again := get(e, "/v2/credentials/app", ""); again.AccessKeyId != "ASIA1" {
+		t.Fatal("Expected the same credentials, got", again.AccessKeyId)
+	}
+	now = now.Add(6 * time.Minute)
+	if _, rotated := get(e, "/v2/credentials/app", ""); rotated.AccessKeyId != "ASIA2" {
+		t.Fatal("Expected rotated credentials, got", rotated.AccessKeyId)
+	}
+
+	// Roles have their own credentials.
+	if _, other := get(e, "/v2/credentials/other", ""); other.AccessKeyId != "ASIA3" {
+		t.Fatal("Expected new credentials, got", other.AccessKeyId)
+	}
+	if response, _ := get(e, "/v1/credentials", ""); response.Code != http.StatusNotFound {
+		t.Fatal("Expected a 404, got", response.Code)
+	}
+}
+
+func TestAuthorizationToken(t *testing.T) {
+	now := time.Now()
+	e := New(Options{STS: &fakeSTS{now: &now}, AuthorizationToken: "Bearer secret"})
+	if response, _ := get(e, "/v2/credentials/app", ""); response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected a 401, got", response.Code)
+	}
+	if response, _ := get(e, "/v2/credentials/app", "Bearer secret"); response.Code != http.StatusOK {
+		t.Fatal("Expected a 200, got", response.Code)
+	}
+}
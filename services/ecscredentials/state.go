@@ -0,0 +1,48 @@
+package ecscredentials
+
+import (
+	"fmt"
+
+	"aws-in-a-box/services/sts"
+	"aws-in-a-box/state"
+)
+
+type credentialsState struct {
+	// Keyed by role name.
+	Credentials map[string]sts.APICredentials
+}
+
+// TryPause pauses changes to the credentials while a snapshot copies them.
+func (e *ECSCredentials) TryPause() (func(), bool) {
+	return state.TryLock(&e.mu)
+}
+
+// ExportState writes the roles' cached credentials to the archive, so containers keep being served
+// the same credentials until they're refreshed.
+func (e *ECSCredentials) ExportState(w *state.Writer) error {
+	unlock := w.Lock(&e.mu)
+	defer unlock()
+	return w.WriteJSON("credentials.json", credentialsState{Credentials: e.credentials})
+}
+
+// ImportState replaces the roles' cached credentials with the archive's.
+func (e *ECSCredentials) ImportState(r *state.Reader) (func() error, error) {
+	var imported credentialsState
+	if err := r.ReadJSON("credentials.json", &imported); err != nil {
+		return nil, err
+	}
+	credentials := make(map[string]sts.APICredentials)
+	for roleName, c := range imported.Credentials {
+		if roleName == "" || c.AccessKeyId == "" {
+			return nil, fmt.Errorf("invalid credentials for role %q", roleName)
+		}
+		credentials[roleName] = c
+	}
+
+	return func() error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.credentials = credentials
+		return nil
+	}, nil
+}
@@ -0,0 +1,250 @@
+package eventbridge
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/eventpattern"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-archive.html
+
+var archiveNameRegex = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,48}$`)
+
+type Archive struct {
+	Name string
+	Arn  string
+	// The ARN of the event bus whose events are archived.
+	EventSourceArn string
+	Description    string
+	EventPattern   string
+	// 0 to keep events forever.
+	RetentionDays int32
+	CreationTime  time.Time
+	// Nil to archive every event.
+	pattern *eventpattern.Pattern
+	// In the order they were archived.
+	events    []*deliveredEvent
+	sizeBytes int64
+}
+
+func (a *Archive) toAPI() APIArchive {
+	return APIArchive{
+		ArchiveName:    a.Name,
+		EventSourceArn: a.EventSourceArn,
+		State:          "ENABLED",
+		RetentionDays:  a.RetentionDays,
+		SizeBytes:      a.sizeBytes,
+		EventCount:     int64(len(a.events)),
+		CreationTime:   timestamp.EpochSeconds(a.CreationTime),
+	}
+}
+
+// lockedExpireEvents removes events older than the archive's retention period.
+func (a *Archive) lockedExpireEvents(now time.Time) {
+	if a.RetentionDays == 0 {
+		return
+	}
+	cutoff := now.AddDate(0, 0, -int(a.RetentionDays))
+	a.events = slices.DeleteFunc(a.events, func(ev *deliveredEvent) bool {
+		if ev.time.Before(cutoff) {
+			a.sizeBytes -= int64(len(ev.encoded))
+			return true
+		}
+		return false
+	})
+}
+
+// lockedArchiveEvent adds the event to the archives of the bus whose patterns match it.
+// Replayed events aren't archived again.
+func (e *EventBridge) lockedArchiveEvent(bus *EventBus, ev *deliveredEvent) {
+	if ev.event.ReplayName != "" {
+		return
+	}
+	for _, archive := range e.archives {
+		if archive.EventSourceArn != bus.Arn || (archive.pattern != nil && !archive.pattern.Matches(ev.decoded)) {
+			continue
+		}
+		archive.lockedExpireEvents(e.clock())
+		archive.events = append(archive.events, ev)
+		archive.sizeBytes += int64(len(ev.encoded))
+	}
+}
+
+func (e *EventBridge) lockedGetArchive(name string) (*Archive, *awserrors.Error) {
+	archive, ok := e.archives[name]
+	if !ok {
+		return nil, ResourceNotFoundException("Archive " + name + " does not exist.")
+	}
+	return archive, nil
+}
+
+func validateRetentionDays(retentionDays int32) *awserrors.Error {
+	if retentionDays < 0 {
+		return ValidationException("1 validation error detected: Value at 'retentionDays' failed to satisfy constraint: Member must have value greater than or equal to 0")
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_CreateArchive.html
+func (e *EventBridge) CreateArchive(input CreateArchiveInput) (*CreateArchiveOutput, *awserrors.Error) {
+	if !archiveNameRegex.MatchString(input.ArchiveName) {
+		return nil, ValidationException("1 validation error detected: Value '" + input.ArchiveName +
+			"' at 'archiveName' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\.\\-_A-Za-z0-9]+")
+	}
+	if awserr := validateRetentionDays(input.RetentionDays); awserr != nil {
+		return nil, awserr
+	}
+	var pattern *eventpattern.Pattern
+	if input.EventPattern != "" {
+		var awserr *awserrors.Error
+		pattern, awserr = parseEventPattern(input.EventPattern)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.archives[input.ArchiveName]; ok {
+		return nil, ResourceAlreadyExistsException("Archive " + input.ArchiveName + " already exists.")
+	}
+	bus, awserr := e.lockedGetEventBus(input.EventSourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	archive := &Archive{
+		Name:           input.ArchiveName,
+		Arn:            e.arnGenerator.Generate("events", "archive", input.ArchiveName),
+		EventSourceArn: bus.Arn,
+		Description:    input.Description,
+		EventPattern:   input.EventPattern,
+		RetentionDays:  input.RetentionDays,
+		CreationTime:   e.clock(),
+		pattern:        pattern,
+	}
+	e.archives[archive.Name] = archive
+	return &CreateArchiveOutput{
+		ArchiveArn:   archive.Arn,
+		State:        "ENABLED",
+		CreationTime: timestamp.EpochSeconds(archive.CreationTime),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DescribeArchive.html
+func (e *EventBridge) DescribeArchive(input DescribeArchiveInput) (*DescribeArchiveOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	archive, awserr := e.lockedGetArchive(input.ArchiveName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	archive.lockedExpireEvents(e.clock())
+	api := archive.toAPI()
+	return &DescribeArchiveOutput{
+		ArchiveArn:     archive.Arn,
+		ArchiveName:    api.ArchiveName,
+		EventSourceArn: api.EventSourceArn,
+		Description:    archive.Description,
+		EventPattern:   archive.EventPattern,
+		State:          api.State,
+		RetentionDays:  api.RetentionDays,
+		SizeBytes:      api.SizeBytes,
+		EventCount:     api.EventCount,
+		CreationTime:   api.CreationTime,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_ListArchives.html
+func (e *EventBridge) ListArchives(input ListArchivesInput) (*ListArchivesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		ValidationException("Limit must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var archives []APIArchive
+	for name, archive := range e.archives {
+		if !strings.HasPrefix(name, input.NamePrefix) {
+			continue
+		}
+		if input.EventSourceArn != "" && input.EventSourceArn != archive.EventSourceArn {
+			continue
+		}
+		// Archives are always enabled.
+		if input.State != "" && input.State != "ENABLED" {
+			continue
+		}
+		archive.lockedExpireEvents(e.clock())
+		archives = append(archives, archive.toAPI())
+	}
+	slices.SortFunc(archives, func(a, b APIArchive) int {
+		return strings.Compare(a.ArchiveName, b.ArchiveName)
+	})
+	output := &ListArchivesOutput{}
+	output.Archives, output.NextToken = pagination.Page(archives, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_UpdateArchive.html
+func (e *EventBridge) UpdateArchive(input UpdateArchiveInput) (*UpdateArchiveOutput, *awserrors.Error) {
+	if input.RetentionDays != nil {
+		if awserr := validateRetentionDays(*input.RetentionDays); awserr != nil {
+			return nil, awserr
+		}
+	}
+	var pattern *eventpattern.Pattern
+	if input.EventPattern != nil && *input.EventPattern != "" {
+		var awserr *awserrors.Error
+		pattern, awserr = parseEventPattern(*input.EventPattern)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	archive, awserr := e.lockedGetArchive(input.ArchiveName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Description != nil {
+		archive.Description = *input.Description
+	}
+	if input.EventPattern != nil {
+		archive.EventPattern = *input.EventPattern
+		archive.pattern = pattern
+	}
+	if input.RetentionDays != nil {
+		archive.RetentionDays = *input.RetentionDays
+	}
+	return &UpdateArchiveOutput{
+		ArchiveArn:   archive.Arn,
+		State:        "ENABLED",
+		CreationTime: timestamp.EpochSeconds(archive.CreationTime),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DeleteArchive.html
+func (e *EventBridge) DeleteArchive(input DeleteArchiveInput) (*DeleteArchiveOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, awserr := e.lockedGetArchive(input.ArchiveName); awserr != nil {
+		return nil, awserr
+	}
+	delete(e.archives, input.ArchiveName)
+	return &DeleteArchiveOutput{}, nil
+}
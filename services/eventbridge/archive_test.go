@@ -0,0 +1,202 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func waitForReplay(t *testing.T, e *EventBridge, name string) *DescribeReplayOutput {
+	for i := 0; i < 100; i++ {
+		output, awserr := e.DescribeReplay(DescribeReplayInput{ReplayName: name})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if output.State != "STARTING" && output.State != "RUNNING" {
+			return output
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for replay", name)
+	return nil
+}
+
+func TestArchiveAndReplay(t *testing.T) {
+	lambda := &fakeLambdaInvoker{}
+	e := newEventBridge(Options{Lambda: lambda})
+	now := e.clock()
+	function := "arn:aws:lambda:us-east-1:123456789012:function:"
+
+	archive, awserr := e.CreateArchive(CreateArchiveInput{
+		ArchiveName:    "shop",
+		EventSourceArn: "arn:aws:events:us-east-1:123456789012:event-bus/default",
+		EventPattern:   `{"source": ["shop"]}`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if archive.ArchiveArn != "arn:aws:events:us-east-1:123456789012:archive/shop" || archive.State != "ENABLED" {
+		t.Fatalf("Unexpected archive: %+v", archive)
+	}
+
+	putRule(t, e, PutRuleInput{Name: "orders", EventPattern: `{"source": ["shop", "other"]}`})
+	putTargets(t, e, "orders", "", APITarget{Id: "fn", Arn: function + "orders"})
+	replaysArn := putRule(t, e, PutRuleInput{Name: "replays", EventPattern: `{"replay-name": [{"exists": true}]}`})
+	putTargets(t, e, "replays", "", APITarget{Id: "fn", Arn: function + "replays"})
+
+	start := float64(now.Unix())
+	putEvents(t, e,
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{"n": 1}`, Time: start - 3*3600},
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{"n": 2}`, Time: start - 2*3600},
+		APIPutEventsRequestEntry{Source: "other", DetailType: "Order", Detail: `{"n": 3}`, Time: start - 2*3600},
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{"n": 4}`, Time: start - 3600},
+	)
+	if len(lambda.invocations(function+"orders")) != 4 || len(lambda.invocations(function+"replays")) != 0 {
+		t.Fatal("Unexpected invocations", lambda.payloads)
+	}
+	description, awserr := e.DescribeArchive(DescribeArchiveInput{ArchiveName: "shop"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if description.EventCount != 3 || description.SizeBytes == 0 {
+		t.Fatalf("Unexpected archive: %+v", description)
+	}
+
+	replay, awserr := e.StartReplay(StartReplayInput{
+		ReplayName:     "recovery",
+		EventSourceArn: archive.ArchiveArn,
+		EventStartTime: start - 2.5*3600,
+		EventEndTime:   start,
+		Destination:    APIReplayDestination{Arn: "arn:aws:events:us-east-1:123456789012:event-bus/default"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if replay.ReplayArn != "arn:aws:events:us-east-1:123456789012:replay/recovery" {
+		t.Fatal("Unexpected ARN", replay.ReplayArn)
+	}
+	finished := waitForReplay(t, e, "recovery")
+	if finished.State != "COMPLETED" || finished.EventLastReplayedTime != start-3600 || finished.EventSourceArn != archive.ArchiveArn {
+		t.Fatalf("Unexpected replay: %+v", finished)
+	}
+
+	// Replayed events are delivered in time order, with the replay's name.
+	replayed := lambda.invocations(function + "replays")
+	if len(replayed) != 2 || len(lambda.invocations(function+"orders")) != 6 {
+		t.Fatal("Unexpected invocations", lambda.payloads)
+	}
+	for i, n := range []int{2, 4} {
+		var ev struct {
+			event
+			Detail struct{ N int } `json:"detail"`
+		}
+		if err := json.Unmarshal([]byte(replayed[i]), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.ReplayName != "recovery" || ev.Detail.N != n {
+			t.Fatalf("Unexpected replayed event: %+v", ev)
+		}
+	}
+	// Replayed events aren't archived again.
+	description, awserr = e.DescribeArchive(DescribeArchiveInput{ArchiveName: "shop"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if description.EventCount != 3 {
+		t.Fatalf("Unexpected archive: %+v", description)
+	}
+
+	// Replays can be limited to some rules.
+	if _, awserr := e.StartReplay(StartReplayInput{
+		ReplayName:     "filtered",
+		EventSourceArn: archive.ArchiveArn,
+		EventStartTime: start - 4*3600,
+		EventEndTime:   start,
+		Destination: APIReplayDestination{
+			Arn:        "arn:aws:events:us-east-1:123456789012:event-bus/default",
+			FilterArns: []string{replaysArn},
+		},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	waitForReplay(t, e, "filtered")
+	if len(lambda.invocations(function+"replays")) != 5 || len(lambda.invocations(function+"orders")) != 6 {
+		t.Fatal("Unexpected invocations", lambda.payloads)
+	}
+
+	_, awserr = e.CancelReplay(CancelReplayInput{ReplayName: "filtered"})
+	if awserr == nil || awserr.Body.Type != "IllegalStatusException" {
+		t.Fatal("Expected finished replay not to be cancellable", awserr)
+	}
+	replays, awserr := e.ListReplays(ListReplaysInput{State: "COMPLETED"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(replays.Replays) != 2 || replays.Replays[0].ReplayName != "filtered" {
+		t.Fatalf("Unexpected replays: %+v", replays)
+	}
+
+	// Events older than the retention period are deleted.
+	retentionDays := int32(1)
+	if _, awserr := e.UpdateArchive(UpdateArchiveInput{ArchiveName: "shop", RetentionDays: &retentionDays}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	later := now.Add(22*time.Hour + 30*time.Minute)
+	e.clock = func() time.Time { return later }
+	archives, awserr := e.ListArchives(ListArchivesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(archives.Archives) != 1 || archives.Archives[0].EventCount != 1 || archives.Archives[0].RetentionDays != 1 {
+		t.Fatalf("Unexpected archives: %+v", archives)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	e := newEventBridge(Options{})
+	start := float64(e.clock().Unix())
+	if _, awserr := e.CreateEventBus(CreateEventBusInput{Name: "orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	archive, awserr := e.CreateArchive(CreateArchiveInput{ArchiveName: "all", EventSourceArn: "default"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.CreateArchive(CreateArchiveInput{ArchiveName: "all", EventSourceArn: "default"})
+	if awserr == nil || awserr.Body.Type != "ResourceAlreadyExistsException" {
+		t.Fatal("Expected archive to already exist", awserr)
+	}
+	_, awserr = e.CreateArchive(CreateArchiveInput{ArchiveName: "missing", EventSourceArn: "missing"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected missing bus", awserr)
+	}
+
+	defaultBus := "arn:aws:events:us-east-1:123456789012:event-bus/default"
+	for _, tc := range []struct {
+		name      string
+		input     StartReplayInput
+		errorType string
+	}{
+		{"missing archive", StartReplayInput{ReplayName: "r", EventSourceArn: "arn:aws:events:us-east-1:123456789012:archive/missing",
+			EventStartTime: start - 60, EventEndTime: start, Destination: APIReplayDestination{Arn: defaultBus}}, "ResourceNotFoundException"},
+		{"other bus", StartReplayInput{ReplayName: "r", EventSourceArn: archive.ArchiveArn,
+			EventStartTime: start - 60, EventEndTime: start, Destination: APIReplayDestination{Arn: "arn:aws:events:us-east-1:123456789012:event-bus/orders"}}, "ValidationException"},
+		{"start after end", StartReplayInput{ReplayName: "r", EventSourceArn: archive.ArchiveArn,
+			EventStartTime: start, EventEndTime: start - 60, Destination: APIReplayDestination{Arn: defaultBus}}, "ValidationException"},
+		{"invalid name", StartReplayInput{ReplayName: "a replay", EventSourceArn: archive.ArchiveArn,
+			EventStartTime: start - 60, EventEndTime: start, Destination: APIReplayDestination{Arn: defaultBus}}, "ValidationException"},
+	} {
+		_, awserr := e.StartReplay(tc.input)
+		if awserr == nil || awserr.Body.Type != tc.errorType {
+			t.Error(tc.name, "expected", tc.errorType, awserr)
+		}
+	}
+
+	if _, awserr := e.DeleteArchive(DeleteArchiveInput{ArchiveName: "all"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.DescribeArchive(DescribeArchiveInput{ArchiveName: "all"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected deleted archive not to exist", awserr)
+	}
+}
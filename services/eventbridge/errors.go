@@ -0,0 +1,27 @@
+package eventbridge
+
+import "aws-in-a-box/awserrors"
+
+func IllegalStatusException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("IllegalStatusException", message)
+}
+
+func InvalidEventPatternException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidEventPatternException", message)
+}
+
+func LimitExceededException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("LimitExceededException", message)
+}
+
+func ResourceAlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceAlreadyExistsException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
+
+func ValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationException", message)
+}
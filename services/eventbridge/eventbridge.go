@@ -0,0 +1,485 @@
+package eventbridge
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/eventpattern"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultEventBusName = "default"
+	maxListLimit        = 100
+)
+
+var (
+	eventBusNameRegex = regexp.MustCompile(`^[/\.\-_A-Za-z0-9]{1,256}$`)
+	ruleNameRegex     = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,64}$`)
+)
+
+type EventBus struct {
+	Name             string
+	Arn              string
+	Description      string
+	CreationTime     time.Time
+	LastModifiedTime time.Time
+	// Keyed by name.
+	rules map[string]*Rule
+}
+
+func (b *EventBus) toAPI() APIEventBus {
+	return APIEventBus{
+		Name:             b.Name,
+		Arn:              b.Arn,
+		Description:      b.Description,
+		CreationTime:     timestamp.EpochSeconds(b.CreationTime),
+		LastModifiedTime: timestamp.EpochSeconds(b.LastModifiedTime),
+	}
+}
+
+type Rule struct {
+	Name               string
+	Arn                string
+	EventBusName       string
+	EventPattern       string
+	ScheduleExpression string
+	// ENABLED or DISABLED.
+	State       string
+	Description string
+	RoleArn     string
+	// In the order they were first put.
+	Targets []APITarget
+	// Nil if the rule doesn't have an event pattern or a schedule.
+	pattern  *eventpattern.Pattern
+	schedule schedule
+	// When the scheduled rule fires next.
+	nextFire time.Time
+}
+
+func (r *Rule) toAPI() APIRule {
+	return APIRule{
+		Name:               r.Name,
+		Arn:                r.Arn,
+		EventBusName:       r.EventBusName,
+		EventPattern:       r.EventPattern,
+		ScheduleExpression: r.ScheduleExpression,
+		State:              r.State,
+		Description:        r.Description,
+		RoleArn:            r.RoleArn,
+	}
+}
+
+// LambdaInvoker asynchronously invokes the Lambda functions rules target.
+type LambdaInvoker interface {
+	InvokeAsync(functionArn string, payload []byte) error
+}
+
+type EventBridge struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+	// Targets of these services are delivered to if they're enabled.
+	sqs     *sqs.SQS
+	sns     *sns.SNS
+	lambda  LambdaInvoker
+	kinesis *kinesis.Kinesis
+
+	mu sync.Mutex
+	// Keyed by name.
+	buses    map[string]*EventBus
+	archives map[string]*Archive
+	replays  map[string]*Replay
+	// Shared by the instances of all regions, which each have their own event buses.
+	regions *region.Regions[*EventBridge]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	SQS          *sqs.SQS
+	SNS          *sns.SNS
+	Lambda       LambdaInvoker
+	Kinesis      *kinesis.Kinesis
+	// How often to check for scheduled rules which are due. If 0, scheduled rules never fire.
+	ScheduleInterval time.Duration
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *EventBridge {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Targets are found by their ARNs, which say their regions, so every region delivers through
+	// the same services.
+	e := newRegion(options)
+	e.regions = region.New(options.ArnGenerator.Region, options.Regions, e, func(name string) (*EventBridge, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = e.regions
+		return r, nil
+	})
+	if options.ScheduleInterval > 0 {
+		go func() {
+			for {
+				time.Sleep(options.ScheduleInterval)
+				for _, r := range e.regions.All() {
+					r.fireScheduledRules()
+				}
+			}
+		}()
+	}
+	return e
+}
+
+// newRegion returns the instance for the options' region, with its default event bus.
+func newRegion(options Options) *EventBridge {
+	e := &EventBridge{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		clock:        clock.Now,
+		sqs:          options.SQS,
+		sns:          options.SNS,
+		lambda:       options.Lambda,
+		kinesis:      options.Kinesis,
+		buses:        make(map[string]*EventBus),
+		archives:     make(map[string]*Archive),
+		replays:      make(map[string]*Replay),
+	}
+	e.lockedCreateEventBus(defaultEventBusName, "")
+	return e
+}
+
+// InRegion returns the event buses of the region. It fails if the region isn't one of
+// Options.Regions.
+func (e *EventBridge) InRegion(name string) (*EventBridge, error) {
+	return e.regions.Get(name)
+}
+
+// ForARN returns the event buses of the region the event bus ARN is in, for services which put
+// events on buses by ARN.
+func (e *EventBridge) ForARN(busArn string) (*EventBridge, error) {
+	return e.regions.ForARN(busArn)
+}
+
+// busName returns the name of the event bus given by name or ARN, or the default event bus if it's empty.
+func busName(nameOrArn string) string {
+	if nameOrArn == "" {
+		return defaultEventBusName
+	}
+	if strings.HasPrefix(nameOrArn, "arn:") {
+		_, name, _ := strings.Cut(nameOrArn, ":event-bus/")
+		return name
+	}
+	return nameOrArn
+}
+
+func (e *EventBridge) lockedCreateEventBus(name string, description string) *EventBus {
+	now := e.clock()
+	bus := &EventBus{
+		Name:             name,
+		Arn:              e.arnGenerator.Generate("events", "event-bus", name),
+		Description:      description,
+		CreationTime:     now,
+		LastModifiedTime: now,
+		rules:            make(map[string]*Rule),
+	}
+	e.buses[name] = bus
+	return bus
+}
+
+func (e *EventBridge) lockedGetEventBus(nameOrArn string) (*EventBus, *awserrors.Error) {
+	name := busName(nameOrArn)
+	bus, ok := e.buses[name]
+	if !ok {
+		return nil, ResourceNotFoundException("Event bus " + name + " does not exist.")
+	}
+	return bus, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_CreateEventBus.html
+func (e *EventBridge) CreateEventBus(input CreateEventBusInput) (*CreateEventBusOutput, *awserrors.Error) {
+	if !eventBusNameRegex.MatchString(input.Name) {
+		return nil, ValidationException("1 validation error detected: Value '" + input.Name +
+			"' at 'name' failed to satisfy constraint: Member must satisfy regular expression pattern: [/\\.\\-_A-Za-z0-9]+")
+	}
+	if input.EventSourceName != "" || strings.HasPrefix(input.Name, "aws.") {
+		return nil, ValidationException("Partner event buses aren't supported.")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.buses[input.Name]; ok {
+		return nil, ResourceAlreadyExistsException("Event bus " + input.Name + " already exists.")
+	}
+	bus := e.lockedCreateEventBus(input.Name, input.Description)
+	return &CreateEventBusOutput{
+		EventBusArn: bus.Arn,
+		Description: bus.Description,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DescribeEventBus.html
+func (e *EventBridge) DescribeEventBus(input DescribeEventBusInput) (*DescribeEventBusOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bus, awserr := e.lockedGetEventBus(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := bus.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_ListEventBuses.html
+func (e *EventBridge) ListEventBuses(input ListEventBusesInput) (*ListEventBusesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		ValidationException("Limit must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var buses []APIEventBus
+	for name, bus := range e.buses {
+		if strings.HasPrefix(name, input.NamePrefix) {
+			buses = append(buses, bus.toAPI())
+		}
+	}
+	slices.SortFunc(buses, func(a, b APIEventBus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &ListEventBusesOutput{}
+	output.EventBuses, output.NextToken = pagination.Page(buses, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DeleteEventBus.html
+func (e *EventBridge) DeleteEventBus(input DeleteEventBusInput) (*DeleteEventBusOutput, *awserrors.Error) {
+	if input.Name == defaultEventBusName {
+		return nil, ValidationException("Cannot delete event bus default.")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Deleting a bus which doesn't exist succeeds.
+	if bus, ok := e.buses[input.Name]; ok {
+		if len(bus.rules) > 0 {
+			return nil, ValidationException("Cannot delete event bus " + input.Name + " because it has rules.")
+		}
+		delete(e.buses, input.Name)
+	}
+	return &DeleteEventBusOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_PutRule.html
+func (e *EventBridge) PutRule(input PutRuleInput) (*PutRuleOutput, *awserrors.Error) {
+	if !ruleNameRegex.MatchString(input.Name) {
+		return nil, ValidationException("1 validation error detected: Value '" + input.Name +
+			"' at 'name' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\.\\-_A-Za-z0-9]+")
+	}
+	if input.EventPattern == "" && input.ScheduleExpression == "" {
+		return nil, ValidationException("Parameter(s) EventPattern or ScheduleExpression must be specified.")
+	}
+	switch input.State {
+	case "":
+		input.State = "ENABLED"
+	case "ENABLED", "DISABLED":
+	default:
+		return nil, ValidationException("1 validation error detected: Value '" + input.State +
+			"' at 'state' failed to satisfy constraint: Member must satisfy enum value set: [ENABLED, DISABLED]")
+	}
+	var pattern *eventpattern.Pattern
+	if input.EventPattern != "" {
+		var awserr *awserrors.Error
+		pattern, awserr = parseEventPattern(input.EventPattern)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+	var schedule schedule
+	if input.ScheduleExpression != "" {
+		if busName(input.EventBusName) != defaultEventBusName {
+			return nil, ValidationException("ScheduleExpression is supported only on the default event bus.")
+		}
+		var awserr *awserrors.Error
+		schedule, awserr = parseSchedule(input.ScheduleExpression)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bus, awserr := e.lockedGetEventBus(input.EventBusName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	rule, ok := bus.rules[input.Name]
+	if !ok {
+		rule = &Rule{
+			Name:         input.Name,
+			Arn:          e.ruleArn(bus.Name, input.Name),
+			EventBusName: bus.Name,
+		}
+		bus.rules[input.Name] = rule
+	}
+	// Updating a rule replaces everything but its targets.
+	rule.EventPattern = input.EventPattern
+	rule.ScheduleExpression = input.ScheduleExpression
+	rule.State = input.State
+	rule.Description = input.Description
+	rule.RoleArn = input.RoleArn
+	rule.pattern = pattern
+	rule.schedule = schedule
+	rule.lockedScheduleNext(e.clock())
+	return &PutRuleOutput{
+		RuleArn: rule.Arn,
+	}, nil
+}
+
+// lockedScheduleNext sets when the rule fires next, if it's scheduled.
+func (r *Rule) lockedScheduleNext(now time.Time) {
+	r.nextFire = time.Time{}
+	if r.schedule != nil {
+		r.nextFire = r.schedule.next(now)
+	}
+}
+
+// ruleArn returns the rule's ARN, which only includes the event bus if it's not the default.
+func (e *EventBridge) ruleArn(busName string, name string) string {
+	if busName == defaultEventBusName {
+		return e.arnGenerator.Generate("events", "rule", name)
+	}
+	return e.arnGenerator.Generate("events", "rule", busName+"/"+name)
+}
+
+func (e *EventBridge) lockedGetRule(busNameOrArn string, name string) (*EventBus, *Rule, *awserrors.Error) {
+	bus, awserr := e.lockedGetEventBus(busNameOrArn)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	rule, ok := bus.rules[name]
+	if !ok {
+		return nil, nil, ResourceNotFoundException("Rule " + name + " does not exist on EventBus " + bus.Name + ".")
+	}
+	return bus, rule, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DescribeRule.html
+func (e *EventBridge) DescribeRule(input DescribeRuleInput) (*DescribeRuleOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, rule, awserr := e.lockedGetRule(input.EventBusName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeRuleOutput{
+		APIRule:   rule.toAPI(),
+		CreatedBy: e.arnGenerator.AwsAccountId,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_ListRules.html
+func (e *EventBridge) ListRules(input ListRulesInput) (*ListRulesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		ValidationException("Limit must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bus, awserr := e.lockedGetEventBus(input.EventBusName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var rules []APIRule
+	for name, rule := range bus.rules {
+		if strings.HasPrefix(name, input.NamePrefix) {
+			rules = append(rules, rule.toAPI())
+		}
+	}
+	slices.SortFunc(rules, func(a, b APIRule) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &ListRulesOutput{}
+	output.Rules, output.NextToken = pagination.Page(rules, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DeleteRule.html
+func (e *EventBridge) DeleteRule(input DeleteRuleInput) (*DeleteRuleOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bus, awserr := e.lockedGetEventBus(input.EventBusName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Deleting a rule which doesn't exist succeeds.
+	if rule, ok := bus.rules[input.Name]; ok {
+		if len(rule.Targets) > 0 {
+			return nil, ValidationException("Rule can't be deleted since it has targets.")
+		}
+		delete(bus.rules, input.Name)
+	}
+	return &DeleteRuleOutput{}, nil
+}
+
+func (e *EventBridge) setRuleState(busNameOrArn string, name string, state string) *awserrors.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, rule, awserr := e.lockedGetRule(busNameOrArn, name)
+	if awserr != nil {
+		return awserr
+	}
+	rule.State = state
+	rule.lockedScheduleNext(e.clock())
+	return nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_EnableRule.html
+func (e *EventBridge) EnableRule(input EnableRuleInput) (*EnableRuleOutput, *awserrors.Error) {
+	if awserr := e.setRuleState(input.EventBusName, input.Name, "ENABLED"); awserr != nil {
+		return nil, awserr
+	}
+	return &EnableRuleOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DisableRule.html
+func (e *EventBridge) DisableRule(input DisableRuleInput) (*DisableRuleOutput, *awserrors.Error) {
+	if awserr := e.setRuleState(input.EventBusName, input.Name, "DISABLED"); awserr != nil {
+		return nil, awserr
+	}
+	return &DisableRuleOutput{}, nil
+}
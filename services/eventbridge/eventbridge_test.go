@@ -0,0 +1,225 @@
+package eventbridge
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+func newEventBridge(options Options) *EventBridge {
+	options.ArnGenerator = generator
+	e := New(options)
+	now := time.Unix(1700000000, 0)
+	e.clock = func() time.Time { return now }
+	return e
+}
+
+func putRule(t *testing.T, e *EventBridge, input PutRuleInput) string {
+	output, awserr := e.PutRule(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.RuleArn
+}
+
+func putTargets(t *testing.T, e *EventBridge, rule string, busName string, targets ...APITarget) {
+	output, awserr := e.PutTargets(PutTargetsInput{Rule: rule, EventBusName: busName, Targets: targets})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.FailedEntryCount != 0 {
+		t.Fatal("Failed to put targets", output.FailedEntries)
+	}
+}
+
+func TestEventBuses(t *testing.T) {
+	e := newEventBridge(Options{})
+
+	output, awserr := e.CreateEventBus(CreateEventBusInput{Name: "orders", Description: "Order events"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.EventBusArn != "arn:aws:events:us-east-1:123456789012:event-bus/orders" {
+		t.Fatal("Unexpected ARN", output.EventBusArn)
+	}
+	for _, name := range []string{"orders", "default"} {
+		_, awserr = e.CreateEventBus(CreateEventBusInput{Name: name})
+		if awserr == nil || awserr.Body.Type != "ResourceAlreadyExistsException" {
+			t.Fatal("Expected already exists", name, awserr)
+		}
+	}
+
+	// Buses can be described by ARN.
+	bus, awserr := e.DescribeEventBus(DescribeEventBusInput{Name: output.EventBusArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if bus.Name != "orders" || bus.Description != "Order events" || bus.CreationTime != 1700000000 {
+		t.Fatalf("Unexpected bus: %+v", bus)
+	}
+
+	list, awserr := e.ListEventBuses(ListEventBusesInput{Limit: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.EventBuses) != 1 || list.EventBuses[0].Name != "default" || list.NextToken == "" {
+		t.Fatalf("Unexpected buses: %+v", list)
+	}
+	list, awserr = e.ListEventBuses(ListEventBusesInput{Limit: 1, NextToken: list.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.EventBuses) != 1 || list.EventBuses[0].Name != "orders" || list.NextToken != "" {
+		t.Fatalf("Unexpected buses: %+v", list)
+	}
+
+	putRule(t, e, PutRuleInput{Name: "rule", EventBusName: "orders", EventPattern: `{"source": ["shop"]}`})
+	_, awserr = e.DeleteEventBus(DeleteEventBusInput{Name: "orders"})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected bus with rules not to be deleted", awserr)
+	}
+	if _, awserr := e.DeleteRule(DeleteRuleInput{Name: "rule", EventBusName: "orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := e.DeleteEventBus(DeleteEventBusInput{Name: "orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.DescribeEventBus(DescribeEventBusInput{Name: "orders"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected deleted bus not to exist", awserr)
+	}
+	_, awserr = e.DeleteEventBus(DeleteEventBusInput{Name: "default"})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected default bus not to be deleted", awserr)
+	}
+}
+
+func TestRules(t *testing.T) {
+	e := newEventBridge(Options{})
+	if _, awserr := e.CreateEventBus(CreateEventBusInput{Name: "orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	ruleArn := putRule(t, e, PutRuleInput{Name: "shop", EventPattern: `{"source": ["shop"]}`})
+	if ruleArn != "arn:aws:events:us-east-1:123456789012:rule/shop" {
+		t.Fatal("Unexpected ARN", ruleArn)
+	}
+	ruleArn = putRule(t, e, PutRuleInput{Name: "shop", EventBusName: "orders", EventPattern: `{"source": ["shop"]}`})
+	if ruleArn != "arn:aws:events:us-east-1:123456789012:rule/orders/shop" {
+		t.Fatal("Unexpected ARN", ruleArn)
+	}
+	putRule(t, e, PutRuleInput{Name: "other", EventPattern: `{"detail": {"state": ["on", "off"]}}`, State: "DISABLED"})
+
+	for _, tc := range []struct {
+		name      string
+		input     PutRuleInput
+		errorType string
+	}{
+		{"invalid name", PutRuleInput{Name: "a rule", EventPattern: `{"source": ["shop"]}`}, "ValidationException"},
+		{"no pattern", PutRuleInput{Name: "rule"}, "ValidationException"},
+		{"invalid JSON", PutRuleInput{Name: "rule", EventPattern: `{"source"`}, "InvalidEventPatternException"},
+		{"not an array", PutRuleInput{Name: "rule", EventPattern: `{"source": "shop"}`}, "InvalidEventPatternException"},
+		{"empty array", PutRuleInput{Name: "rule", EventPattern: `{"source": []}`}, "InvalidEventPatternException"},
+		{"missing bus", PutRuleInput{Name: "rule", EventBusName: "missing", EventPattern: `{"source": ["shop"]}`}, "ResourceNotFoundException"},
+		{"invalid state", PutRuleInput{Name: "rule", EventPattern: `{"source": ["shop"]}`, State: "PAUSED"}, "ValidationException"},
+	} {
+		_, awserr := e.PutRule(tc.input)
+		if awserr == nil || awserr.Body.Type != tc.errorType {
+			t.Error(tc.name, "expected", tc.errorType, awserr)
+		}
+	}
+
+	rules, awserr := e.ListRules(ListRulesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(rules.Rules) != 2 || rules.Rules[0].Name != "other" || rules.Rules[1].Name != "shop" || rules.Rules[0].State != "DISABLED" {
+		t.Fatalf("Unexpected rules: %+v", rules.Rules)
+	}
+	rules, awserr = e.ListRules(ListRulesInput{EventBusName: "orders", NamePrefix: "sh"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(rules.Rules) != 1 || rules.Rules[0].EventBusName != "orders" {
+		t.Fatalf("Unexpected rules: %+v", rules.Rules)
+	}
+
+	if _, awserr := e.EnableRule(EnableRuleInput{Name: "other"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	rule, awserr := e.DescribeRule(DescribeRuleInput{Name: "other"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if rule.State != "ENABLED" || rule.EventBusName != "default" {
+		t.Fatalf("Unexpected rule: %+v", rule)
+	}
+
+	// Rules with targets can't be deleted.
+	putTargets(t, e, "other", "", APITarget{Id: "queue", Arn: "arn:aws:sqs:us-east-1:123456789012:queue"})
+	_, awserr = e.DeleteRule(DeleteRuleInput{Name: "other"})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected rule with targets not to be deleted", awserr)
+	}
+	if _, awserr := e.RemoveTargets(RemoveTargetsInput{Rule: "other", Ids: []string{"queue"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := e.DeleteRule(DeleteRuleInput{Name: "other"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.DescribeRule(DescribeRuleInput{Name: "other"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected deleted rule not to exist", awserr)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	e := newEventBridge(Options{})
+	putRule(t, e, PutRuleInput{Name: "rule", EventPattern: `{"source": ["shop"]}`})
+
+	putTargets(t, e, "rule", "",
+		APITarget{Id: "queue", Arn: "arn:aws:sqs:us-east-1:123456789012:queue"},
+		APITarget{Id: "function", Arn: "arn:aws:lambda:us-east-1:123456789012:function:fn"},
+	)
+	// Putting a target with the same ID replaces it.
+	putTargets(t, e, "rule", "", APITarget{Id: "queue", Arn: "arn:aws:sqs:us-east-1:123456789012:other", Input: `{"a": 1}`})
+
+	output, awserr := e.PutTargets(PutTargetsInput{Rule: "rule", Targets: []APITarget{
+		{Id: "invalid id!", Arn: "arn:aws:sqs:us-east-1:123456789012:queue"},
+		{Id: "inputs", Arn: "arn:aws:sqs:us-east-1:123456789012:queue", Input: "{}", InputPath: "$.detail"},
+		{Id: "path", Arn: "arn:aws:sqs:us-east-1:123456789012:queue", InputPath: "detail"},
+	}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.FailedEntryCount != 3 || output.FailedEntries[0].TargetId != "invalid id!" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	targets, awserr := e.ListTargetsByRule(ListTargetsByRuleInput{Rule: "rule"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(targets.Targets) != 2 || targets.Targets[0].Arn != "arn:aws:sqs:us-east-1:123456789012:other" || targets.Targets[1].Id != "function" {
+		t.Fatalf("Unexpected targets: %+v", targets.Targets)
+	}
+
+	var many []APITarget
+	for _, id := range []string{"a", "b", "c", "d"} {
+		many = append(many, APITarget{Id: id, Arn: "arn:aws:sqs:us-east-1:123456789012:" + id})
+	}
+	_, awserr = e.PutTargets(PutTargetsInput{Rule: "rule", Targets: many})
+	if awserr == nil || awserr.Body.Type != "LimitExceededException" {
+		t.Fatal("Expected too many targets", awserr)
+	}
+	_, awserr = e.PutTargets(PutTargetsInput{Rule: "missing", Targets: many})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected missing rule", awserr)
+	}
+}
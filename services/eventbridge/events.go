@@ -0,0 +1,306 @@
+package eventbridge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/eventpattern"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+)
+
+const maxPutEventsEntries = 10
+
+// event is the envelope targets receive events in.
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-events-structure.html
+type event struct {
+	Version    string          `json:"version"`
+	Id         string          `json:"id"`
+	DetailType string          `json:"detail-type"`
+	Source     string          `json:"source"`
+	Account    string          `json:"account"`
+	Time       string          `json:"time"`
+	Region     string          `json:"region"`
+	Resources  []string        `json:"resources"`
+	Detail     json.RawMessage `json:"detail"`
+	// Set on events which are being replayed from an archive.
+	ReplayName string `json:"replay-name,omitempty"`
+}
+
+// deliveredEvent is an event with the encodings rules and targets need.
+type deliveredEvent struct {
+	event event
+	// The event's time, which archives and replays use.
+	time    time.Time
+	encoded []byte
+	// Decoded with eventpattern.Decode, for matching patterns and looking up paths.
+	decoded map[string]any
+}
+
+// delivery is an event to deliver to a rule's target.
+type delivery struct {
+	rule   *Rule
+	target APITarget
+}
+
+func (e *EventBridge) newEvent(source string, detailType string, resources []string, detail []byte, t time.Time) *deliveredEvent {
+	if resources == nil {
+		resources = []string{}
+	}
+	ev := event{
+		Version:    "0",
+		Id:         uuid.Must(uuid.NewV4()).String(),
+		DetailType: detailType,
+		Source:     source,
+		Account:    e.arnGenerator.AwsAccountId,
+		Time:       t.UTC().Format(time.RFC3339),
+		Region:     e.arnGenerator.Region,
+		Resources:  resources,
+		Detail:     detail,
+	}
+	return encodeEvent(ev, t)
+}
+
+func encodeEvent(ev event, t time.Time) *deliveredEvent {
+	encoded, err := json.Marshal(ev)
+	if err != nil {
+		panic(err)
+	}
+	var decoded map[string]any
+	if err := eventpattern.Decode(string(encoded), &decoded); err != nil {
+		panic(err)
+	}
+	return &deliveredEvent{
+		event:   ev,
+		time:    t,
+		encoded: encoded,
+		decoded: decoded,
+	}
+}
+
+// lockedMatchRules returns the targets of the bus's enabled rules which match the event.
+// If ruleArns isn't nil, only those rules are matched.
+func (e *EventBridge) lockedMatchRules(bus *EventBus, ev *deliveredEvent, ruleArns []string) []delivery {
+	var deliveries []delivery
+	for _, rule := range bus.rules {
+		if rule.State != "ENABLED" || rule.pattern == nil || !rule.pattern.Matches(ev.decoded) {
+			continue
+		}
+		if ruleArns != nil && !slices.Contains(ruleArns, rule.Arn) {
+			continue
+		}
+		for _, target := range rule.Targets {
+			deliveries = append(deliveries, delivery{rule: rule, target: target})
+		}
+	}
+	return deliveries
+}
+
+// route archives the event and delivers it to the targets of the bus's matching rules.
+// It must be called without holding the lock, since targets may call back into EventBridge.
+func (e *EventBridge) route(busName string, ev *deliveredEvent, forwarded bool) {
+	e.mu.Lock()
+	bus, ok := e.buses[busName]
+	var deliveries []delivery
+	if ok {
+		e.lockedArchiveEvent(bus, ev)
+		deliveries = e.lockedMatchRules(bus, ev, nil)
+	}
+	e.mu.Unlock()
+
+	e.deliverAll(deliveries, ev, forwarded)
+}
+
+func (e *EventBridge) deliverAll(deliveries []delivery, ev *deliveredEvent, forwarded bool) {
+	for _, d := range deliveries {
+		if err := e.deliver(d, ev, forwarded); err != nil {
+			e.logger.Warn("Failed to deliver event to target",
+				"rule", d.rule.Arn, "target", d.target.Arn, "event", ev.event.Id, "error", err)
+		}
+	}
+}
+
+// deliver sends the event to the target.
+func (e *EventBridge) deliver(d delivery, ev *deliveredEvent, forwarded bool) error {
+	payload := targetInput(d.target, d.rule, ev)
+	target, err := arn.Parse(d.target.Arn)
+	if err != nil {
+		return err
+	}
+	switch target.Service {
+	case "sqs":
+		if e.sqs == nil {
+			return fmt.Errorf("SQS is not enabled")
+		}
+		input := sqs.SendMessageInput{
+			MessageBody: payload,
+		}
+		if d.target.SqsParameters != nil && d.target.SqsParameters.MessageGroupId != "" {
+			input.MessageGroupId = d.target.SqsParameters.MessageGroupId
+			input.MessageDeduplicationId = ev.event.Id
+		}
+		_, awserr := e.sqs.SendMessageToQueueArn(d.target.Arn, input)
+		return errorFromAWS(awserr)
+	case "sns":
+		if e.sns == nil {
+			return fmt.Errorf("SNS is not enabled")
+		}
+		topics, err := e.sns.ForARN(d.target.Arn)
+		if err != nil {
+			return err
+		}
+		_, awserr := topics.Publish(sns.PublishInput{
+			TopicArn: d.target.Arn,
+			Message:  payload,
+		})
+		return errorFromAWS(awserr)
+	case "lambda":
+		if e.lambda == nil {
+			return fmt.Errorf("Lambda is not enabled")
+		}
+		return e.lambda.InvokeAsync(d.target.Arn, []byte(payload))
+	case "kinesis":
+		if e.kinesis == nil {
+			return fmt.Errorf("Kinesis is not enabled")
+		}
+		partitionKey := ev.event.Id
+		if d.target.KinesisParameters != nil {
+			value, ok := lookupJSONPath(ev.decoded, d.target.KinesisParameters.PartitionKeyPath)
+			if s, isString := value.(string); ok && isString && s != "" {
+				partitionKey = s
+			}
+		}
+		streams, err := e.kinesis.ForARN(d.target.Arn)
+		if err != nil {
+			return err
+		}
+		_, awserr := streams.PutRecord(kinesis.PutRecordInput{
+			StreamARN:    d.target.Arn,
+			PartitionKey: partitionKey,
+			Data:         base64.StdEncoding.EncodeToString([]byte(payload)),
+		})
+		return errorFromAWS(awserr)
+	case "events":
+		// Events are only forwarded once, so that rules can't send events around in a loop.
+		if forwarded {
+			return fmt.Errorf("events sent to an event bus by a rule aren't forwarded again")
+		}
+		// The bus can be in another region.
+		buses, err := e.regions.ForARN(d.target.Arn)
+		if err != nil {
+			return err
+		}
+		name, err := buses.arnGenerator.Resolve(d.target.Arn, "events", "event-bus")
+		if err != nil {
+			return fmt.Errorf("unsupported target %s: %w", d.target.Arn, err)
+		}
+		buses.route(name, ev, true)
+		return nil
+	default:
+		e.logger.Debug("Delivery is not supported for target", "target", d.target.Arn, "rule", d.rule.Arn)
+		return nil
+	}
+}
+
+func errorFromAWS(awserr *awserrors.Error) error {
+	if awserr != nil {
+		return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+	}
+	return nil
+}
+
+// validateEntry returns the error code and message if the entry can't be put.
+func validateEntry(entry APIPutEventsRequestEntry) (string, string) {
+	if entry.Source == "" {
+		return "InvalidArgument", "Parameter Source is not valid. Reason: Source is a required argument."
+	}
+	if strings.HasPrefix(entry.Source, "aws.") {
+		return "NotAuthorizedForSourceException", "Not authorized for the source."
+	}
+	if entry.DetailType == "" {
+		return "InvalidArgument", "Parameter DetailType is not valid. Reason: DetailType is a required argument."
+	}
+	if entry.Detail == "" {
+		return "InvalidArgument", "Parameter Detail is not valid. Reason: Detail is a required argument."
+	}
+	var detail map[string]any
+	if err := json.Unmarshal([]byte(entry.Detail), &detail); err != nil {
+		return "MalformedDetail", "Detail is malformed."
+	}
+	return "", ""
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_PutEvents.html
+func (e *EventBridge) PutEvents(input PutEventsInput) (*PutEventsOutput, *awserrors.Error) {
+	if len(input.Entries) == 0 || len(input.Entries) > maxPutEventsEntries {
+		return nil, ValidationException(fmt.Sprintf("1 validation error detected: Value at 'entries' failed to satisfy constraint: "+
+			"Member must have length less than or equal to %d and greater than or equal to 1", maxPutEventsEntries))
+	}
+	if input.EndpointId != "" {
+		return nil, ValidationException("Global endpoints aren't supported.")
+	}
+
+	output := &PutEventsOutput{
+		Entries: make([]APIPutEventsResultEntry, 0, len(input.Entries)),
+	}
+	type routedEvent struct {
+		busName string
+		event   *deliveredEvent
+	}
+	var routed []routedEvent
+
+	e.mu.Lock()
+	for _, entry := range input.Entries {
+		code, message := validateEntry(entry)
+		name := busName(entry.EventBusName)
+		if _, ok := e.buses[name]; code == "" && !ok {
+			code, message = "ResourceNotFoundException", "Event bus "+name+" does not exist."
+		}
+		if code != "" {
+			output.FailedEntryCount++
+			output.Entries = append(output.Entries, APIPutEventsResultEntry{
+				ErrorCode:    code,
+				ErrorMessage: message,
+			})
+			continue
+		}
+
+		t := e.clock()
+		if entry.Time != 0 {
+			t = time.UnixMilli(int64(entry.Time * 1000))
+		}
+		ev := e.newEvent(entry.Source, entry.DetailType, entry.Resources, []byte(entry.Detail), t)
+		routed = append(routed, routedEvent{name, ev})
+		output.Entries = append(output.Entries, APIPutEventsResultEntry{
+			EventId: ev.event.Id,
+		})
+	}
+	e.mu.Unlock()
+
+	for _, r := range routed {
+		e.route(r.busName, r.event, false)
+	}
+	return output, nil
+}
+
+// PutEvent puts an event on the region's default event bus, for AWS services which publish events.
+func (e *EventBridge) PutEvent(region string, source string, detailType string, resources []string, detail []byte) error {
+	if !json.Valid(detail) {
+		return fmt.Errorf("detail is not valid JSON")
+	}
+	buses, err := e.regions.Get(region)
+	if err != nil {
+		return err
+	}
+	buses.route(defaultEventBusName, buses.newEvent(source, detailType, resources, detail, buses.clock()), false)
+	return nil
+}
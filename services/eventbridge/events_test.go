@@ -0,0 +1,222 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"aws-in-a-box/services/sqs"
+)
+
+type fakeLambdaInvoker struct {
+	mu       sync.Mutex
+	payloads map[string][]string
+}
+
+func (f *fakeLambdaInvoker) InvokeAsync(functionArn string, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.payloads == nil {
+		f.payloads = make(map[string][]string)
+	}
+	f.payloads[functionArn] = append(f.payloads[functionArn], string(payload))
+	return nil
+}
+
+func (f *fakeLambdaInvoker) invocations(functionArn string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.payloads[functionArn]
+}
+
+func createQueue(t *testing.T, s *sqs.SQS, name string) (string, string) {
+	output, awserr := s.CreateQueue(sqs.CreateQueueInput{QueueName: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.QueueUrl, generator.GenerateWithoutType("sqs", name)
+}
+
+func receiveMessages(t *testing.T, s *sqs.SQS, queueUrl string) []string {
+	output, awserr := s.ReceiveMessage(sqs.ReceiveMessageInput{QueueUrl: queueUrl, MaxNumberOfMessages: 10})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var bodies []string
+	for _, message := range output.Messages {
+		bodies = append(bodies, message.Body)
+	}
+	return bodies
+}
+
+func putEvents(t *testing.T, e *EventBridge, entries ...APIPutEventsRequestEntry) *PutEventsOutput {
+	output, awserr := e.PutEvents(PutEventsInput{Entries: entries})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestPutEvents(t *testing.T) {
+	s := sqs.New(sqs.Options{ArnGenerator: generator})
+	queueUrl, queueArn := createQueue(t, s, "orders")
+	e := newEventBridge(Options{SQS: s})
+
+	putRule(t, e, PutRuleInput{Name: "placed", EventPattern: `{"source": ["shop"], "detail": {"state": ["placed"]}}`})
+	putTargets(t, e, "placed", "", APITarget{Id: "queue", Arn: queueArn})
+	putRule(t, e, PutRuleInput{Name: "disabled", EventPattern: `{"source": ["shop"]}`, State: "DISABLED"})
+	putTargets(t, e, "disabled", "", APITarget{Id: "queue", Arn: queueArn})
+
+	output := putEvents(t, e,
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{"state": "placed", "id": 1}`, Resources: []string{"order/1"}},
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{"state": "shipped", "id": 1}`},
+		APIPutEventsRequestEntry{Source: "other", DetailType: "Order", Detail: `{"state": "placed", "id": 2}`},
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `not json`},
+		APIPutEventsRequestEntry{Source: "aws.shop", DetailType: "Order", Detail: `{}`},
+		APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{}`, EventBusName: "missing"},
+	)
+	if output.FailedEntryCount != 3 || len(output.Entries) != 6 || output.Entries[0].EventId == "" ||
+		output.Entries[3].ErrorCode != "MalformedDetail" || output.Entries[4].ErrorCode != "NotAuthorizedForSourceException" ||
+		output.Entries[5].ErrorCode != "ResourceNotFoundException" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	bodies := receiveMessages(t, s, queueUrl)
+	if len(bodies) != 1 {
+		t.Fatal("Unexpected messages", bodies)
+	}
+	var ev struct {
+		event
+		Detail struct {
+			State string
+			Id    int
+		} `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Id != output.Entries[0].EventId || ev.Version != "0" || ev.Source != "shop" || ev.DetailType != "Order" ||
+		ev.Account != "123456789012" || ev.Region != "us-east-1" || ev.Time != "2023-11-14T22:13:20Z" ||
+		len(ev.Resources) != 1 || ev.Detail.State != "placed" || ev.Detail.Id != 1 {
+		t.Fatalf("Unexpected event: %+v", ev)
+	}
+
+	_, awserr := e.PutEvents(PutEventsInput{})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected no entries to be invalid", awserr)
+	}
+}
+
+func TestTargetInput(t *testing.T) {
+	lambda := &fakeLambdaInvoker{}
+	e := newEventBridge(Options{Lambda: lambda})
+	ruleArn := putRule(t, e, PutRuleInput{Name: "rule", EventPattern: `{"detail-type": ["Order"]}`})
+	function := "arn:aws:lambda:us-east-1:123456789012:function:"
+	putTargets(t, e, "rule", "",
+		APITarget{Id: "input", Arn: function + "input", Input: `{"constant": true}`},
+		APITarget{Id: "path", Arn: function + "path", InputPath: "$.detail.items"},
+		APITarget{Id: "transformer", Arn: function + "transformer", InputTransformer: &APIInputTransformer{
+			InputPathsMap: map[string]string{"state": "$.detail.state", "items": "$.detail.items", "missing": "$.detail.missing"},
+			InputTemplate: `{"message": "Order is <state>", "items": <items>, "rule": "<aws.events.rule-arn>", "missing": "<missing>"}`,
+		}},
+	)
+
+	putEvents(t, e, APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{"state": "placed", "items": [1, 2]}`})
+
+	for target, expected := range map[string]string{
+		"input":       `{"constant": true}`,
+		"path":        `[1,2]`,
+		"transformer": `{"message": "Order is placed", "items": [1,2], "rule": "` + ruleArn + `", "missing": ""}`,
+	} {
+		payloads := lambda.payloads[function+target]
+		if len(payloads) != 1 || payloads[0] != expected {
+			t.Error("Unexpected payload for", target, payloads)
+		}
+	}
+}
+
+func TestEventBusTarget(t *testing.T) {
+	s := sqs.New(sqs.Options{ArnGenerator: generator})
+	queueUrl, queueArn := createQueue(t, s, "orders")
+	e := newEventBridge(Options{SQS: s})
+	output, awserr := e.CreateEventBus(CreateEventBusInput{Name: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	putRule(t, e, PutRuleInput{Name: "forward", EventPattern: `{"source": ["shop"]}`})
+	putTargets(t, e, "forward", "", APITarget{Id: "bus", Arn: output.EventBusArn})
+	putRule(t, e, PutRuleInput{Name: "queue", EventBusName: "orders", EventPattern: `{"source": ["shop"]}`})
+	putTargets(t, e, "queue", "orders", APITarget{Id: "queue", Arn: queueArn})
+	// Forwarded events aren't forwarded again.
+	putRule(t, e, PutRuleInput{Name: "back", EventBusName: "orders", EventPattern: `{"source": ["shop"]}`})
+	putTargets(t, e, "back", "orders", APITarget{Id: "bus", Arn: "arn:aws:events:us-east-1:123456789012:event-bus/default"})
+
+	putEvents(t, e, APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{}`})
+	if bodies := receiveMessages(t, s, queueUrl); len(bodies) != 1 {
+		t.Fatal("Unexpected messages", bodies)
+	}
+}
+
+func TestPutEvent(t *testing.T) {
+	s := sqs.New(sqs.Options{ArnGenerator: generator})
+	queueUrl, queueArn := createQueue(t, s, "parameters")
+	e := newEventBridge(Options{SQS: s})
+	putRule(t, e, PutRuleInput{Name: "ssm", EventPattern: `{"source": ["aws.ssm"], "detail": {"operation": ["Create"]}}`})
+	putTargets(t, e, "ssm", "", APITarget{Id: "queue", Arn: queueArn, InputPath: "$.resources"})
+
+	resources := []string{"arn:aws:ssm:us-east-1:123456789012:parameter/app"}
+	if err := e.PutEvent("us-east-1", "aws.ssm", "Parameter Store Change", resources, []byte(`{"operation": "Create"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.PutEvent("us-east-1", "aws.ssm", "Parameter Store Change", resources, []byte(`{"operation": "Delete"}`)); err != nil {
+		t.Fatal(err)
+	}
+	bodies := receiveMessages(t, s, queueUrl)
+	if len(bodies) != 1 || bodies[0] != `["arn:aws:ssm:us-east-1:123456789012:parameter/app"]` {
+		t.Fatal("Unexpected messages", bodies)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	s := sqs.New(sqs.Options{ArnGenerator: generator})
+	europeQueues, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queueUrl, _ := createQueue(t, europeQueues, "europe")
+	e := newEventBridge(Options{SQS: s})
+	europe, err := e.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+
+	// Each region has its own default event bus.
+	bus, awserr := europe.DescribeEventBus(DescribeEventBusInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if bus.Arn != "arn:aws:events:eu-west-1:123456789012:event-bus/default" {
+		t.Fatal("Unexpected event bus", bus.Arn)
+	}
+	putRule(t, europe, PutRuleInput{Name: "queue", EventPattern: `{"source": ["shop", "aws.ssm"]}`})
+	putTargets(t, europe, "queue", "", APITarget{Id: "queue", Arn: "arn:aws:sqs:eu-west-1:123456789012:europe", InputPath: "$.region"})
+
+	// Events can be forwarded to buses in other regions, where they keep their region.
+	putRule(t, e, PutRuleInput{Name: "forward", EventPattern: `{"source": ["shop"]}`})
+	putTargets(t, e, "forward", "", APITarget{Id: "bus", Arn: bus.Arn})
+	putEvents(t, e, APIPutEventsRequestEntry{Source: "shop", DetailType: "Order", Detail: `{}`})
+	if err := e.PutEvent("eu-west-1", "aws.ssm", "Parameter Store Change", nil, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	bodies := receiveMessages(t, europeQueues, queueUrl)
+	if len(bodies) != 2 || bodies[0] != `"us-east-1"` || bodies[1] != `"eu-west-1"` {
+		t.Fatal("Unexpected messages", bodies)
+	}
+	if err := e.PutEvent("xx-nowhere-1", "aws.ssm", "Parameter Store Change", nil, []byte(`{}`)); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+}
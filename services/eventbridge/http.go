@@ -0,0 +1,37 @@
+package eventbridge
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AWSEvents"
+
+func (e *EventBridge) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CancelReplay", region.Method(e.regions, (*EventBridge).CancelReplay))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateArchive", region.Method(e.regions, (*EventBridge).CreateArchive))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateEventBus", region.Method(e.regions, (*EventBridge).CreateEventBus))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteArchive", region.Method(e.regions, (*EventBridge).DeleteArchive))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteEventBus", region.Method(e.regions, (*EventBridge).DeleteEventBus))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteRule", region.Method(e.regions, (*EventBridge).DeleteRule))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeArchive", region.Method(e.regions, (*EventBridge).DescribeArchive))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeEventBus", region.Method(e.regions, (*EventBridge).DescribeEventBus))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeReplay", region.Method(e.regions, (*EventBridge).DescribeReplay))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeRule", region.Method(e.regions, (*EventBridge).DescribeRule))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DisableRule", region.Method(e.regions, (*EventBridge).DisableRule))
+	http.RegisterWithRequest(logger, methodRegistry, service, "EnableRule", region.Method(e.regions, (*EventBridge).EnableRule))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListArchives", region.Method(e.regions, (*EventBridge).ListArchives))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListEventBuses", region.Method(e.regions, (*EventBridge).ListEventBuses))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListReplays", region.Method(e.regions, (*EventBridge).ListReplays))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListRules", region.Method(e.regions, (*EventBridge).ListRules))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListTargetsByRule", region.Method(e.regions, (*EventBridge).ListTargetsByRule))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutEvents", region.Method(e.regions, (*EventBridge).PutEvents))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutRule", region.Method(e.regions, (*EventBridge).PutRule))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutTargets", region.Method(e.regions, (*EventBridge).PutTargets))
+	http.RegisterWithRequest(logger, methodRegistry, service, "RemoveTargets", region.Method(e.regions, (*EventBridge).RemoveTargets))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StartReplay", region.Method(e.regions, (*EventBridge).StartReplay))
+	http.RegisterWithRequest(logger, methodRegistry, service, "TestEventPattern", region.Method(e.regions, (*EventBridge).TestEventPattern))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateArchive", region.Method(e.regions, (*EventBridge).UpdateArchive))
+}
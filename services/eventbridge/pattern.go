@@ -0,0 +1,64 @@
+package eventbridge
+
+import (
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/eventpattern"
+)
+
+func invalidEventPattern(reason string) *awserrors.Error {
+	return InvalidEventPatternException("Event pattern is not valid. Reason: " + reason)
+}
+
+func parseEventPattern(value string) (*eventpattern.Pattern, *awserrors.Error) {
+	var object map[string]any
+	if err := eventpattern.Decode(value, &object); err != nil {
+		return nil, invalidEventPattern("Filter is not an object")
+	}
+	if len(object) == 0 {
+		return nil, invalidEventPattern("Empty objects are not allowed")
+	}
+	pattern, err := eventpattern.New(object, eventpattern.Options{})
+	if err != nil {
+		return nil, invalidEventPattern(err.Error())
+	}
+	return pattern, nil
+}
+
+// ParseEventPattern parses an event pattern, for other services which filter events with them, like EventBridge Pipes.
+// It returns the function which reports whether a JSON event matches the pattern.
+func ParseEventPattern(value string) (func(event []byte) bool, *awserrors.Error) {
+	pattern, awserr := parseEventPattern(value)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return func(event []byte) bool {
+		var decoded map[string]any
+		if err := eventpattern.Decode(string(event), &decoded); err != nil {
+			return false
+		}
+		return pattern.Matches(decoded)
+	}, nil
+}
+
+// The fields events must have to be tested against patterns.
+var requiredEventFields = []string{"id", "account", "source", "time", "region", "resources", "detail-type"}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_TestEventPattern.html
+func (e *EventBridge) TestEventPattern(input TestEventPatternInput) (*TestEventPatternOutput, *awserrors.Error) {
+	pattern, awserr := parseEventPattern(input.EventPattern)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var event map[string]any
+	if err := eventpattern.Decode(input.Event, &event); err != nil {
+		return nil, ValidationException("Parameter Event is not valid.")
+	}
+	for _, field := range requiredEventFields {
+		if _, ok := event[field]; !ok {
+			return nil, ValidationException("Parameter Event is not valid. Reason: Provided event is missing required field " + field + ".")
+		}
+	}
+	return &TestEventPatternOutput{
+		Result: pattern.Matches(event),
+	}, nil
+}
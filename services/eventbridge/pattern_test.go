@@ -0,0 +1,120 @@
+package eventbridge
+
+import (
+	"testing"
+)
+
+const testEvent = `{
+	"version": "0",
+	"id": "6a7e8feb-b491-4cf7-a9f1-bf3703467718",
+	"detail-type": "EC2 Instance State-change Notification",
+	"source": "aws.ec2",
+	"account": "111122223333",
+	"time": "2017-12-22T18:43:48Z",
+	"region": "us-west-1",
+	"resources": ["arn:aws:ec2:us-west-1:123456789012:instance/i-1234567890abcdef0"],
+	"detail": {
+		"instance-id": "i-1234567890abcdef0",
+		"state": "terminated",
+		"c-count": 5,
+		"d-count": 3.018e2,
+		"x-limit": 301.8,
+		"source-ip": "10.0.0.123",
+		"tags": ["prod", "web"],
+		"location": {"country": "US", "city": null},
+		"empty": ""
+	}
+}`
+
+func TestEventPatternMatching(t *testing.T) {
+	cases := map[string]bool{
+		`{"source": ["aws.ec2"]}`:                                                        true,
+		`{"source": ["aws.s3"]}`:                                                         false,
+		`{"source": ["aws.s3", "aws.ec2"], "region": ["us-west-1"]}`:                     true,
+		`{"detail": {"state": ["terminated"]}}`:                                          true,
+		`{"detail": {"state": ["running"]}}`:                                             false,
+		`{"detail": {"c-count": [5]}}`:                                                   true,
+		`{"detail": {"c-count": ["5"]}}`:                                                 false,
+		`{"detail": {"d-count": [301.8]}}`:                                               true,
+		`{"detail": {"tags": ["web"]}}`:                                                  true,
+		`{"detail": {"location": {"country": ["US"], "city": [null]}}}`:                  true,
+		`{"detail": {"empty": [""]}}`:                                                    true,
+		`{"detail": {"missing": [null]}}`:                                                false,
+		`{"source": [{"prefix": "aws."}]}`:                                               true,
+		`{"source": [{"prefix": {"equals-ignore-case": "AWS.E"}}]}`:                      true,
+		`{"source": [{"suffix": ".ec2"}]}`:                                               true,
+		`{"source": [{"suffix": {"equals-ignore-case": ".EC2"}}]}`:                       true,
+		`{"source": [{"suffix": ".s3"}]}`:                                                false,
+		`{"source": [{"equals-ignore-case": "AWS.EC2"}]}`:                                true,
+		`{"source": [{"wildcard": "aws.*"}]}`:                                            true,
+		`{"detail": {"instance-id": [{"wildcard": "i-*cdef*"}]}}`:                        true,
+		`{"detail": {"instance-id": [{"wildcard": "i-*cdef"}]}}`:                         false,
+		`{"detail": {"instance-id": [{"wildcard": "i-1234*890*0"}]}}`:                    true,
+		`{"detail": {"state": [{"wildcard": "term\\*"}]}}`:                               false,
+		`{"detail": {"state": [{"anything-but": "running"}]}}`:                           true,
+		`{"detail": {"state": [{"anything-but": ["running", "terminated"]}]}}`:           false,
+		`{"detail": {"state": [{"anything-but": {"prefix": "term"}}]}}`:                  false,
+		`{"detail": {"state": [{"anything-but": {"suffix": "ing"}}]}}`:                   true,
+		`{"detail": {"state": [{"anything-but": {"equals-ignore-case": ["RUNNING"]}}]}}`: true,
+		`{"detail": {"state": [{"anything-but": {"wildcard": "*nated"}}]}}`:              false,
+		`{"detail": {"c-count": [{"anything-but": [1, 2]}]}}`:                            true,
+		`{"detail": {"missing": [{"anything-but": "value"}]}}`:                           false,
+		`{"detail": {"c-count": [{"numeric": [">", 0, "<=", 5]}]}}`:                      true,
+		`{"detail": {"c-count": [{"numeric": ["<", 5]}]}}`:                               false,
+		`{"detail": {"x-limit": [{"numeric": ["=", 3.018e2]}]}}`:                         true,
+		`{"detail": {"source-ip": [{"cidr": "10.0.0.0/24"}]}}`:                           true,
+		`{"detail": {"source-ip": [{"cidr": "10.0.1.0/24"}]}}`:                           false,
+		`{"detail": {"state": [{"exists": true}]}}`:                                      true,
+		`{"detail": {"missing": [{"exists": false}]}}`:                                   true,
+		`{"detail": {"state": [{"exists": false}]}}`:                                     false,
+		`{"detail": {"location": [{"exists": true}]}}`:                                   false,
+		`{"other": {"missing": [{"exists": false}]}}`:                                    true,
+		`{"detail": {"state": ["running", {"prefix": "term"}]}}`:                         true,
+		`{"$or": [{"source": ["aws.s3"]}, {"detail": {"c-count": [5]}}]}`:                true,
+		`{"$or": [{"source": ["aws.s3"]}, {"detail": {"state": ["running"]}}]}`:          false,
+	}
+
+	e := New(Options{})
+	for pattern, expected := range cases {
+		output, awserr := e.TestEventPattern(TestEventPatternInput{EventPattern: pattern, Event: testEvent})
+		if awserr != nil {
+			t.Fatal(pattern, awserr)
+		}
+		if output.Result != expected {
+			t.Error("Wrong result for", pattern)
+		}
+	}
+}
+
+func TestEventPatternErrors(t *testing.T) {
+	e := New(Options{})
+	for _, pattern := range []string{
+		`[]`,
+		`{}`,
+		`{"source": "aws.ec2"}`,
+		`{"source": []}`,
+		`{"source": [["aws.ec2"]]}`,
+		`{"source": [{"prefix": 1}]}`,
+		`{"source": [{"prefix": "a", "suffix": "b"}]}`,
+		`{"source": [{"wildcard": "a**b"}]}`,
+		`{"source": [{"exists": "yes"}]}`,
+		`{"source": [{"anything-but": []}]}`,
+		`{"source": [{"anything-but": {"numeric": [">", 1]}}]}`,
+		`{"source": [{"numeric": [">", "a"]}]}`,
+		`{"source": [{"numeric": [">", 5, "<", 1]}]}`,
+		`{"source": [{"numeric": ["=", 1, "<", 5]}]}`,
+		`{"source": [{"cidr": "10.0.0.1"}]}`,
+		`{"source": [{"regex": "a.*"}]}`,
+		`{"$or": [{"source": ["a"]}]}`,
+	} {
+		_, awserr := e.TestEventPattern(TestEventPatternInput{EventPattern: pattern, Event: testEvent})
+		if awserr == nil || awserr.Body.Type != "InvalidEventPatternException" {
+			t.Error("Expected invalid pattern", pattern, awserr)
+		}
+	}
+
+	_, awserr := e.TestEventPattern(TestEventPatternInput{EventPattern: `{"source": ["a"]}`, Event: `{"source": "a"}`})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Error("Expected invalid event", awserr)
+	}
+}
@@ -0,0 +1,235 @@
+package eventbridge
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-replay-archived-event.html
+
+var replayNameRegex = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,64}$`)
+
+type Replay struct {
+	Name        string
+	Arn         string
+	Description string
+	// The ARN of the archive events are replayed from.
+	EventSourceArn string
+	Destination    APIReplayDestination
+	EventStartTime time.Time
+	EventEndTime   time.Time
+	// STARTING, RUNNING, CANCELLING, CANCELLED, COMPLETED or FAILED.
+	State                 string
+	StateReason           string
+	EventLastReplayedTime time.Time
+	ReplayStartTime       time.Time
+	ReplayEndTime         time.Time
+}
+
+func (r *Replay) toAPI() APIReplay {
+	return APIReplay{
+		ReplayName:            r.Name,
+		EventSourceArn:        r.EventSourceArn,
+		State:                 r.State,
+		StateReason:           r.StateReason,
+		EventStartTime:        timestamp.EpochSeconds(r.EventStartTime),
+		EventEndTime:          timestamp.EpochSeconds(r.EventEndTime),
+		EventLastReplayedTime: timestamp.EpochSeconds(r.EventLastReplayedTime),
+		ReplayStartTime:       timestamp.EpochSeconds(r.ReplayStartTime),
+		ReplayEndTime:         timestamp.EpochSeconds(r.ReplayEndTime),
+	}
+}
+
+// lockedFinish ends the replay in the state.
+func (r *Replay) lockedFinish(state string, reason string, now time.Time) {
+	r.State = state
+	r.StateReason = reason
+	r.ReplayEndTime = now
+}
+
+func (e *EventBridge) lockedGetReplay(name string) (*Replay, *awserrors.Error) {
+	replay, ok := e.replays[name]
+	if !ok {
+		return nil, ResourceNotFoundException("Replay " + name + " does not exist.")
+	}
+	return replay, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_StartReplay.html
+func (e *EventBridge) StartReplay(input StartReplayInput) (*StartReplayOutput, *awserrors.Error) {
+	if !replayNameRegex.MatchString(input.ReplayName) {
+		return nil, ValidationException("1 validation error detected: Value '" + input.ReplayName +
+			"' at 'replayName' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\.\\-_A-Za-z0-9]+")
+	}
+	if input.EventStartTime == 0 || input.EventEndTime == 0 {
+		return nil, ValidationException("Parameters EventStartTime and EventEndTime are required.")
+	}
+	if input.EventStartTime >= input.EventEndTime {
+		return nil, ValidationException("Parameter EventStartTime is not valid. Reason: EventStartTime must be before EventEndTime.")
+	}
+	start := time.UnixMilli(int64(input.EventStartTime * 1000))
+	end := time.UnixMilli(int64(input.EventEndTime * 1000))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.replays[input.ReplayName]; ok {
+		return nil, ResourceAlreadyExistsException("Replay " + input.ReplayName + " already exists.")
+	}
+	_, archiveName, _ := strings.Cut(input.EventSourceArn, ":archive/")
+	archive, awserr := e.lockedGetArchive(archiveName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Destination.Arn != archive.EventSourceArn {
+		return nil, ValidationException("Parameter Destination.Arn is not valid. Reason: Cross event bus replay is not permitted.")
+	}
+
+	now := e.clock()
+	archive.lockedExpireEvents(now)
+	var events []*deliveredEvent
+	for _, ev := range archive.events {
+		if !ev.time.Before(start) && ev.time.Before(end) {
+			events = append(events, ev)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b *deliveredEvent) int {
+		return a.time.Compare(b.time)
+	})
+
+	replay := &Replay{
+		Name:            input.ReplayName,
+		Arn:             e.arnGenerator.Generate("events", "replay", input.ReplayName),
+		Description:     input.Description,
+		EventSourceArn:  archive.Arn,
+		Destination:     input.Destination,
+		EventStartTime:  start,
+		EventEndTime:    end,
+		State:           "STARTING",
+		ReplayStartTime: now,
+	}
+	e.replays[replay.Name] = replay
+	go e.runReplay(replay, busName(archive.EventSourceArn), events)
+
+	return &StartReplayOutput{
+		ReplayArn:       replay.Arn,
+		State:           replay.State,
+		ReplayStartTime: timestamp.EpochSeconds(replay.ReplayStartTime),
+	}, nil
+}
+
+// runReplay delivers the events, in time order, to the matching rules of the bus.
+// Replayed events have a replay-name field, so that rules can tell them apart.
+func (e *EventBridge) runReplay(replay *Replay, busName string, events []*deliveredEvent) {
+	var ruleArns []string
+	if len(replay.Destination.FilterArns) > 0 {
+		ruleArns = replay.Destination.FilterArns
+	}
+
+	for _, ev := range events {
+		e.mu.Lock()
+		if replay.State == "CANCELLING" {
+			replay.lockedFinish("CANCELLED", "", e.clock())
+			e.mu.Unlock()
+			return
+		}
+		replay.State = "RUNNING"
+		bus, ok := e.buses[busName]
+		if !ok {
+			replay.lockedFinish("FAILED", "Event bus "+busName+" does not exist.", e.clock())
+			e.mu.Unlock()
+			return
+		}
+		replayed := ev.event
+		replayed.ReplayName = replay.Name
+		replayedEvent := encodeEvent(replayed, ev.time)
+		deliveries := e.lockedMatchRules(bus, replayedEvent, ruleArns)
+		replay.EventLastReplayedTime = ev.time
+		e.mu.Unlock()
+
+		e.deliverAll(deliveries, replayedEvent, false)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if replay.State == "CANCELLING" {
+		replay.lockedFinish("CANCELLED", "", e.clock())
+	} else {
+		replay.lockedFinish("COMPLETED", "", e.clock())
+	}
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DescribeReplay.html
+func (e *EventBridge) DescribeReplay(input DescribeReplayInput) (*DescribeReplayOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	replay, awserr := e.lockedGetReplay(input.ReplayName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeReplayOutput{
+		APIReplay:   replay.toAPI(),
+		ReplayArn:   replay.Arn,
+		Description: replay.Description,
+		Destination: replay.Destination,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_ListReplays.html
+func (e *EventBridge) ListReplays(input ListReplaysInput) (*ListReplaysOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		ValidationException("Limit must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var replays []APIReplay
+	for name, replay := range e.replays {
+		if !strings.HasPrefix(name, input.NamePrefix) {
+			continue
+		}
+		if input.EventSourceArn != "" && input.EventSourceArn != replay.EventSourceArn {
+			continue
+		}
+		if input.State != "" && input.State != replay.State {
+			continue
+		}
+		replays = append(replays, replay.toAPI())
+	}
+	slices.SortFunc(replays, func(a, b APIReplay) int {
+		return strings.Compare(a.ReplayName, b.ReplayName)
+	})
+	output := &ListReplaysOutput{}
+	output.Replays, output.NextToken = pagination.Page(replays, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_CancelReplay.html
+func (e *EventBridge) CancelReplay(input CancelReplayInput) (*CancelReplayOutput, *awserrors.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	replay, awserr := e.lockedGetReplay(input.ReplayName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if replay.State != "STARTING" && replay.State != "RUNNING" {
+		return nil, IllegalStatusException("Replay " + replay.Name + " is not in a state that can be cancelled.")
+	}
+	replay.State = "CANCELLING"
+	return &CancelReplayOutput{
+		ReplayArn: replay.Arn,
+		State:     replay.State,
+	}, nil
+}
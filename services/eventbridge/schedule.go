@@ -0,0 +1,254 @@
+package eventbridge
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-scheduled-rule-pattern.html
+
+// How far ahead to look for the next time a cron expression matches.
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+var (
+	rateRegex = regexp.MustCompile(`^rate\((\d+) (minute|minutes|hour|hours|day|days)\)$`)
+	cronRegex = regexp.MustCompile(`^cron\((.*)\)$`)
+)
+
+type schedule interface {
+	// next returns the first time the schedule fires after the given time, or the zero time if it never does.
+	next(after time.Time) time.Time
+}
+
+type rateSchedule struct {
+	period time.Duration
+}
+
+func (r rateSchedule) next(after time.Time) time.Time {
+	return after.Add(r.period)
+}
+
+// cronField is the set of values a field of a cron expression matches.
+type cronField map[int]bool
+
+type cronSchedule struct {
+	// The time zone the fields are in.
+	location *time.Location
+	minutes  cronField
+	hours    cronField
+	// Nil if the field is ?.
+	daysOfMonth cronField
+	months      cronField
+	// 1 is Sunday. Nil if the field is ?.
+	daysOfWeek cronField
+	years      cronField
+}
+
+func (c cronSchedule) matchesDay(t time.Time) bool {
+	if !c.years[t.Year()] || !c.months[int(t.Month())] {
+		return false
+	}
+	if c.daysOfMonth != nil {
+		return c.daysOfMonth[t.Day()]
+	}
+	return c.daysOfWeek[int(t.Weekday())+1]
+}
+
+func (c cronSchedule) next(after time.Time) time.Time {
+	// Cron expressions fire at the start of the minute.
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.location)
+	for ; day.Sub(t) < maxCronSearch; day = day.AddDate(0, 0, 1) {
+		if !c.matchesDay(day) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !c.hours[hour] {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				candidate := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, c.location)
+				if c.minutes[minute] && !candidate.Before(t) {
+					return candidate
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func invalidSchedule() *awserrors.Error {
+	return ValidationException("Parameter ScheduleExpression is not valid.")
+}
+
+// ParseSchedule parses a rate or cron expression, for other services with schedules, and returns the function
+// which gives the next time it fires. Cron expressions are in the time zone.
+func ParseSchedule(expression string, location *time.Location) (func(after time.Time) time.Time, bool) {
+	s, awserr := parseScheduleIn(expression, location)
+	if awserr != nil {
+		return nil, false
+	}
+	return s.next, true
+}
+
+func parseSchedule(expression string) (schedule, *awserrors.Error) {
+	return parseScheduleIn(expression, time.UTC)
+}
+
+func parseScheduleIn(expression string, location *time.Location) (schedule, *awserrors.Error) {
+	if match := rateRegex.FindStringSubmatch(expression); match != nil {
+		value, err := strconv.Atoi(match[1])
+		if err != nil || value < 1 {
+			return nil, invalidSchedule()
+		}
+		// The unit must be singular for 1, and plural otherwise.
+		if (value == 1) == strings.HasSuffix(match[2], "s") {
+			return nil, invalidSchedule()
+		}
+		unit := map[string]time.Duration{"minute": time.Minute, "hour": time.Hour, "day": 24 * time.Hour}[strings.TrimSuffix(match[2], "s")]
+		return rateSchedule{period: time.Duration(value) * unit}, nil
+	}
+	if match := cronRegex.FindStringSubmatch(expression); match != nil {
+		return parseCron(match[1], location)
+	}
+	return nil, invalidSchedule()
+}
+
+var (
+	monthNames = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	dayNames = map[string]int{"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7}
+)
+
+// parseCron parses the six fields of a cron expression: minutes, hours, day of month, month, day of week and year.
+// Exactly one of the day of month and day of week must be ?.
+func parseCron(expression string, location *time.Location) (schedule, *awserrors.Error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 6 {
+		return nil, invalidSchedule()
+	}
+	if (fields[2] == "?") == (fields[4] == "?") {
+		return nil, invalidSchedule()
+	}
+	c := cronSchedule{location: location}
+	var ok bool
+	if c.minutes, ok = parseCronField(fields[0], 0, 59, nil); !ok {
+		return nil, invalidSchedule()
+	}
+	if c.hours, ok = parseCronField(fields[1], 0, 23, nil); !ok {
+		return nil, invalidSchedule()
+	}
+	if fields[2] != "?" {
+		if c.daysOfMonth, ok = parseCronField(fields[2], 1, 31, nil); !ok {
+			return nil, invalidSchedule()
+		}
+	}
+	if c.months, ok = parseCronField(fields[3], 1, 12, monthNames); !ok {
+		return nil, invalidSchedule()
+	}
+	if fields[4] != "?" {
+		if c.daysOfWeek, ok = parseCronField(fields[4], 1, 7, dayNames); !ok {
+			return nil, invalidSchedule()
+		}
+	}
+	if c.years, ok = parseCronField(fields[5], 1970, 2199, nil); !ok {
+		return nil, invalidSchedule()
+	}
+	return c, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges like 1-5, and increments like 0/15 or */5.
+// The L, W and # wildcards aren't supported.
+func parseCronField(field string, minimum int, maximum int, names map[string]int) (cronField, bool) {
+	parseValue := func(s string) (int, bool) {
+		if n, ok := names[s]; ok {
+			return n, true
+		}
+		n, err := strconv.Atoi(s)
+		return n, err == nil && n >= minimum && n <= maximum
+	}
+
+	values := make(cronField)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return nil, false
+			}
+		}
+
+		var start, end int
+		if rangePart == "*" {
+			start, end = minimum, maximum
+		} else if low, high, isRange := strings.Cut(rangePart, "-"); isRange {
+			var lowOk, highOk bool
+			start, lowOk = parseValue(low)
+			end, highOk = parseValue(high)
+			if !lowOk || !highOk || start > end {
+				return nil, false
+			}
+		} else {
+			var ok bool
+			if start, ok = parseValue(rangePart); !ok {
+				return nil, false
+			}
+			end = start
+			// An increment from a single value continues to the end of the range, like 0/15.
+			if hasStep {
+				end = maximum
+			}
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, true
+}
+
+// fireScheduledRules sends scheduled events to the targets of the enabled scheduled rules which are due.
+// If a rule's schedule was missed several times, for example because the clock jumped forward, it only fires once.
+func (e *EventBridge) fireScheduledRules() {
+	e.mu.Lock()
+	now := e.clock()
+	type scheduledEvent struct {
+		event      *deliveredEvent
+		deliveries []delivery
+	}
+	var due []scheduledEvent
+	for _, rule := range e.buses[defaultEventBusName].rules {
+		if rule.schedule == nil || rule.State != "ENABLED" || rule.nextFire.IsZero() || rule.nextFire.After(now) {
+			continue
+		}
+		fireTime := rule.nextFire
+		for next := rule.schedule.next(fireTime); !next.IsZero() && !next.After(now); next = rule.schedule.next(next) {
+			fireTime = next
+		}
+		rule.nextFire = rule.schedule.next(fireTime)
+
+		ev := e.newEvent("aws.events", "Scheduled Event", []string{rule.Arn}, []byte("{}"), fireTime)
+		var deliveries []delivery
+		for _, target := range rule.Targets {
+			deliveries = append(deliveries, delivery{rule: rule, target: target})
+		}
+		due = append(due, scheduledEvent{ev, deliveries})
+	}
+	e.mu.Unlock()
+
+	for _, scheduled := range due {
+		for _, d := range scheduled.deliveries {
+			if err := e.deliver(d, scheduled.event, false); err != nil {
+				e.logger.Warn("Failed to deliver scheduled event to target",
+					"rule", d.rule.Arn, "target", d.target.Arn, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, time.February, 28, 10, 17, 30, 0, time.UTC)
+	for expression, expected := range map[string]time.Time{
+		"rate(1 minute)":            from.Add(time.Minute),
+		"rate(5 minutes)":           from.Add(5 * time.Minute),
+		"rate(2 hours)":             from.Add(2 * time.Hour),
+		"rate(1 day)":               from.Add(24 * time.Hour),
+		"cron(* * * * ? *)":         time.Date(2024, time.February, 28, 10, 18, 0, 0, time.UTC),
+		"cron(0/15 * * * ? *)":      time.Date(2024, time.February, 28, 10, 30, 0, 0, time.UTC),
+		"cron(0 12 * * ? *)":        time.Date(2024, time.February, 28, 12, 0, 0, 0, time.UTC),
+		"cron(0 8 * * ? *)":         time.Date(2024, time.February, 29, 8, 0, 0, 0, time.UTC),
+		"cron(0 8 1 * ? *)":         time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC),
+		"cron(0 9 ? * MON-FRI *)":   time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC),
+		"cron(0 9 ? * SAT,SUN *)":   time.Date(2024, time.March, 2, 9, 0, 0, 0, time.UTC),
+		"cron(0 9 ? * 1 *)":         time.Date(2024, time.March, 3, 9, 0, 0, 0, time.UTC),
+		"cron(30 6 1 JAN ? 2025)":   time.Date(2025, time.January, 1, 6, 30, 0, 0, time.UTC),
+		"cron(0 0 31 2 ? *)":        {},
+		"cron(10-20/5 10 * * ? *)":  time.Date(2024, time.February, 28, 10, 20, 0, 0, time.UTC),
+		"cron(*/20 9-17 * * ? *)":   time.Date(2024, time.February, 28, 10, 20, 0, 0, time.UTC),
+		"cron(0 0 1 1 ? 2020-2023)": {},
+	} {
+		s, awserr := parseSchedule(expression)
+		if awserr != nil {
+			t.Fatal(expression, awserr)
+		}
+		if next := s.next(from); !next.Equal(expected) {
+			t.Error("Unexpected next time for", expression, next)
+		}
+	}
+
+	// Cron expressions can be in other time zones.
+	next, ok := ParseSchedule("cron(0 9 * * ? *)", time.FixedZone("UTC-5", -5*60*60))
+	if !ok {
+		t.Fatal("Expected valid schedule")
+	}
+	if expected := time.Date(2024, time.February, 28, 14, 0, 0, 0, time.UTC); !next(from).Equal(expected) {
+		t.Error("Unexpected next time in time zone", next(from))
+	}
+
+	for _, expression := range []string{
+		"rate(0 minutes)",
+		"rate(1 minutes)",
+		"rate(5 minute)",
+		"rate(5 seconds)",
+		"every 5 minutes",
+		"cron(* * * * *)",
+		"cron(* * * * * *)",
+		"cron(* * ? * ? *)",
+		"cron(60 * * * ? *)",
+		"cron(0 24 * * ? *)",
+		"cron(0 0 L * ? *)",
+		"cron(0 0 ? * 6#3 *)",
+		"cron(0 0 * FOO ? *)",
+		"cron(5-1 * * * ? *)",
+	} {
+		if _, awserr := parseSchedule(expression); awserr == nil {
+			t.Error("Expected invalid schedule", expression)
+		}
+	}
+}
+
+func TestScheduledRules(t *testing.T) {
+	lambda := &fakeLambdaInvoker{}
+	e := newEventBridge(Options{Lambda: lambda})
+	now := e.clock()
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		e.clock = func() time.Time { return now }
+		e.fireScheduledRules()
+	}
+	function := "arn:aws:lambda:us-east-1:123456789012:function:fn"
+	ruleArn := putRule(t, e, PutRuleInput{Name: "every-five", ScheduleExpression: "rate(5 minutes)"})
+	putTargets(t, e, "every-five", "", APITarget{Id: "fn", Arn: function})
+
+	advance(4 * time.Minute)
+	if len(lambda.payloads[function]) != 0 {
+		t.Fatal("Expected no invocations", lambda.payloads)
+	}
+	advance(time.Minute)
+	if len(lambda.payloads[function]) != 1 {
+		t.Fatal("Expected one invocation", lambda.payloads)
+	}
+	var ev event
+	if err := json.Unmarshal([]byte(lambda.payloads[function][0]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Source != "aws.events" || ev.DetailType != "Scheduled Event" || len(ev.Resources) != 1 || ev.Resources[0] != ruleArn ||
+		string(ev.Detail) != "{}" || ev.Time != now.UTC().Format(time.RFC3339) {
+		t.Fatalf("Unexpected event: %+v", ev)
+	}
+
+	// Missed schedules only fire once.
+	advance(time.Hour)
+	if len(lambda.payloads[function]) != 2 {
+		t.Fatal("Expected two invocations", lambda.payloads)
+	}
+
+	// Disabled rules don't fire, and restart their schedule when they're enabled.
+	if _, awserr := e.DisableRule(DisableRuleInput{Name: "every-five"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	advance(10 * time.Minute)
+	if _, awserr := e.EnableRule(EnableRuleInput{Name: "every-five"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	advance(4 * time.Minute)
+	if len(lambda.payloads[function]) != 2 {
+		t.Fatal("Expected two invocations", lambda.payloads)
+	}
+	advance(time.Minute)
+	if len(lambda.payloads[function]) != 3 {
+		t.Fatal("Expected three invocations", lambda.payloads)
+	}
+
+	_, awserr := e.CreateEventBus(CreateEventBusInput{Name: "custom"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = e.PutRule(PutRuleInput{Name: "scheduled", EventBusName: "custom", ScheduleExpression: "rate(1 hour)"})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected schedules on custom buses to be invalid", awserr)
+	}
+}
@@ -0,0 +1,175 @@
+package eventbridge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type busState struct {
+	*EventBus
+	// Sorted by name.
+	Rules []*Rule
+}
+
+type archivedEventState struct {
+	Event event
+	Time  time.Time
+}
+
+type archiveState struct {
+	*Archive
+	// In the order they were archived.
+	Events []archivedEventState
+}
+
+type busesState struct {
+	EventBuses []busState
+	Archives   []archiveState
+	Replays    []*Replay
+}
+
+// TryPause pauses changes to each region's event buses while a snapshot copies them.
+func (e *EventBridge) TryPause() (func(), bool) {
+	return e.regions.TryPause(func(e *EventBridge) *sync.Mutex { return &e.mu })
+}
+
+// ExportState writes each region's event buses and their rules, archives and their events, and
+// replays to the archive.
+func (e *EventBridge) ExportState(w *state.Writer) error {
+	return e.regions.ExportState(w, (*EventBridge).exportState)
+}
+
+func (e *EventBridge) exportState(w *state.Writer) error {
+	unlock := w.Lock(&e.mu)
+	defer unlock()
+
+	var exported busesState
+	for _, bus := range e.buses {
+		b := busState{EventBus: bus}
+		for _, rule := range bus.rules {
+			b.Rules = append(b.Rules, rule)
+		}
+		slices.SortFunc(b.Rules, func(a, b *Rule) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.EventBuses = append(exported.EventBuses, b)
+	}
+	slices.SortFunc(exported.EventBuses, func(a, b busState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, archive := range e.archives {
+		a := archiveState{Archive: archive}
+		for _, ev := range archive.events {
+			a.Events = append(a.Events, archivedEventState{Event: ev.event, Time: ev.time})
+		}
+		exported.Archives = append(exported.Archives, a)
+	}
+	slices.SortFunc(exported.Archives, func(a, b archiveState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, replay := range e.replays {
+		exported.Replays = append(exported.Replays, replay)
+	}
+	slices.SortFunc(exported.Replays, func(a, b *Replay) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("eventbuses.json", exported)
+}
+
+// ImportState replaces each region's event buses, archives and replays with the archive's. Rules' and
+// archives' patterns and schedules are parsed again, and scheduled rules next fire after the
+// import. Replays which hadn't finished aren't resumed, and are imported as failed.
+func (e *EventBridge) ImportState(r *state.Reader) (func() error, error) {
+	return e.regions.ImportState(r, (*EventBridge).importState, (*EventBridge).exportState)
+}
+
+func (e *EventBridge) importState(r *state.Reader) (func() error, error) {
+	var imported busesState
+	if err := r.ReadJSON("eventbuses.json", &imported); err != nil {
+		return nil, err
+	}
+	now := e.clock()
+
+	buses := make(map[string]*EventBus)
+	for _, b := range imported.EventBuses {
+		bus := b.EventBus
+		if bus == nil || !eventBusNameRegex.MatchString(bus.Name) || bus.Arn == "" {
+			return nil, fmt.Errorf("invalid event bus")
+		}
+		bus.rules = make(map[string]*Rule)
+		for _, rule := range b.Rules {
+			if !ruleNameRegex.MatchString(rule.Name) || rule.Arn == "" {
+				return nil, fmt.Errorf("event bus %s: invalid rule %q", bus.Name, rule.Name)
+			}
+			if rule.EventPattern != "" {
+				pattern, awserr := parseEventPattern(rule.EventPattern)
+				if awserr != nil {
+					return nil, fmt.Errorf("rule %s: %s", rule.Arn, awserr.Body.Message)
+				}
+				rule.pattern = pattern
+			}
+			if rule.ScheduleExpression != "" {
+				schedule, awserr := parseSchedule(rule.ScheduleExpression)
+				if awserr != nil {
+					return nil, fmt.Errorf("rule %s: %s", rule.Arn, awserr.Body.Message)
+				}
+				rule.schedule = schedule
+			}
+			rule.EventBusName = bus.Name
+			rule.lockedScheduleNext(now)
+			bus.rules[rule.Name] = rule
+		}
+		buses[bus.Name] = bus
+	}
+	if _, ok := buses[defaultEventBusName]; !ok {
+		return nil, fmt.Errorf("there's no %s event bus", defaultEventBusName)
+	}
+
+	archives := make(map[string]*Archive)
+	for _, a := range imported.Archives {
+		archive := a.Archive
+		if archive == nil || archive.Name == "" || archive.Arn == "" {
+			return nil, fmt.Errorf("invalid archive")
+		}
+		if archive.EventPattern != "" {
+			pattern, awserr := parseEventPattern(archive.EventPattern)
+			if awserr != nil {
+				return nil, fmt.Errorf("archive %s: %s", archive.Name, awserr.Body.Message)
+			}
+			archive.pattern = pattern
+		}
+		for _, ev := range a.Events {
+			encoded := encodeEvent(ev.Event, ev.Time)
+			archive.events = append(archive.events, encoded)
+			archive.sizeBytes += int64(len(encoded.encoded))
+		}
+		archives[archive.Name] = archive
+	}
+
+	replays := make(map[string]*Replay)
+	for _, replay := range imported.Replays {
+		if replay.Name == "" || replay.Arn == "" {
+			return nil, fmt.Errorf("invalid replay")
+		}
+		switch replay.State {
+		case "COMPLETED", "CANCELLED", "FAILED":
+		default:
+			replay.lockedFinish("FAILED", "The replay was interrupted by a state import.", now)
+		}
+		replays[replay.Name] = replay
+	}
+
+	return func() error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.buses = buses
+		e.archives = archives
+		e.replays = replays
+		return nil
+	}, nil
+}
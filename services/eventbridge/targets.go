@@ -0,0 +1,242 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+)
+
+const maxTargets = 5
+
+var (
+	targetIdRegex = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,64}$`)
+	// Placeholders in input templates, like <detail-type>.
+	placeholderRegex = regexp.MustCompile(`<([A-Za-z0-9_\-\.]+)>`)
+)
+
+// validateTarget returns the error code and message if the target is invalid.
+func validateTarget(target APITarget) (string, string) {
+	if !targetIdRegex.MatchString(target.Id) {
+		return "ValidationException", "Target Id must be 1 to 64 characters of letters, numbers, '.', '-' and '_'."
+	}
+	if !strings.HasPrefix(target.Arn, "arn:") {
+		return "ValidationException", "Parameter " + target.Arn + " is not valid. Reason: Provided Arn is not in correct format."
+	}
+	inputs := 0
+	if target.Input != "" {
+		inputs++
+		if !json.Valid([]byte(target.Input)) {
+			return "ValidationException", "Input is not valid JSON."
+		}
+	}
+	if target.InputPath != "" {
+		inputs++
+		if _, ok := parseJSONPath(target.InputPath); !ok {
+			return "ValidationException", "InputPath " + target.InputPath + " is not a valid JSON path."
+		}
+	}
+	if target.InputTransformer != nil {
+		inputs++
+		for name, path := range target.InputTransformer.InputPathsMap {
+			if _, ok := parseJSONPath(path); !ok {
+				return "ValidationException", "InputPathsMap " + name + " has an invalid JSON path " + path + "."
+			}
+		}
+	}
+	if inputs > 1 {
+		return "ValidationException", "Only one of Input, InputPath, or InputTransformer can be specified."
+	}
+	if target.KinesisParameters != nil {
+		if _, ok := parseJSONPath(target.KinesisParameters.PartitionKeyPath); !ok {
+			return "ValidationException", "PartitionKeyPath " + target.KinesisParameters.PartitionKeyPath + " is not a valid JSON path."
+		}
+	}
+	return "", ""
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_PutTargets.html
+func (e *EventBridge) PutTargets(input PutTargetsInput) (*PutTargetsOutput, *awserrors.Error) {
+	if len(input.Targets) == 0 {
+		return nil, ValidationException("Targets must not be empty.")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, rule, awserr := e.lockedGetRule(input.EventBusName, input.Rule)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	newTargets := 0
+	for _, target := range input.Targets {
+		if !slices.ContainsFunc(rule.Targets, func(t APITarget) bool { return t.Id == target.Id }) {
+			newTargets++
+		}
+	}
+	if len(rule.Targets)+newTargets > maxTargets {
+		return nil, LimitExceededException(fmt.Sprintf("The requested resource exceeds the maximum number allowed. A rule can have at most %d targets.", maxTargets))
+	}
+
+	output := &PutTargetsOutput{
+		FailedEntries: []APIFailedTarget{},
+	}
+	for _, target := range input.Targets {
+		if code, message := validateTarget(target); code != "" {
+			output.FailedEntries = append(output.FailedEntries, APIFailedTarget{
+				TargetId:     target.Id,
+				ErrorCode:    code,
+				ErrorMessage: message,
+			})
+			continue
+		}
+		i := slices.IndexFunc(rule.Targets, func(t APITarget) bool { return t.Id == target.Id })
+		if i >= 0 {
+			rule.Targets[i] = target
+		} else {
+			rule.Targets = append(rule.Targets, target)
+		}
+	}
+	output.FailedEntryCount = len(output.FailedEntries)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_RemoveTargets.html
+func (e *EventBridge) RemoveTargets(input RemoveTargetsInput) (*RemoveTargetsOutput, *awserrors.Error) {
+	if len(input.Ids) == 0 {
+		return nil, ValidationException("Ids must not be empty.")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, rule, awserr := e.lockedGetRule(input.EventBusName, input.Rule)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Removing targets which don't exist succeeds.
+	rule.Targets = slices.DeleteFunc(rule.Targets, func(t APITarget) bool {
+		return slices.Contains(input.Ids, t.Id)
+	})
+	return &RemoveTargetsOutput{
+		FailedEntries: []APIFailedTarget{},
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_ListTargetsByRule.html
+func (e *EventBridge) ListTargetsByRule(input ListTargetsByRuleInput) (*ListTargetsByRuleOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		ValidationException("Limit must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, rule, awserr := e.lockedGetRule(input.EventBusName, input.Rule)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListTargetsByRuleOutput{}
+	output.Targets, output.NextToken = pagination.Page(rule.Targets, limit, start)
+	return output, nil
+}
+
+// parseJSONPath parses the simple JSON paths EventBridge supports, like $.detail.name, into their keys.
+func parseJSONPath(path string) ([]string, bool) {
+	if path == "$" {
+		return nil, true
+	}
+	rest, ok := strings.CutPrefix(path, "$.")
+	if !ok || rest == "" {
+		return nil, false
+	}
+	keys := strings.Split(rest, ".")
+	if slices.Contains(keys, "") {
+		return nil, false
+	}
+	return keys, true
+}
+
+// lookupJSONPath returns the value at the path in the decoded event, if it exists.
+func lookupJSONPath(data map[string]any, path string) (any, bool) {
+	keys, ok := parseJSONPath(path)
+	if !ok {
+		return nil, false
+	}
+	var value any = data
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func marshalJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return string(encoded)
+}
+
+// targetInput returns what the target receives for the event, which is the event unless the target customizes it.
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-transform-target-input.html
+func targetInput(target APITarget, rule *Rule, event *deliveredEvent) string {
+	switch {
+	case target.Input != "":
+		return target.Input
+	case target.InputPath != "":
+		value, ok := lookupJSONPath(event.decoded, target.InputPath)
+		if !ok {
+			return ""
+		}
+		return marshalJSON(value)
+	case target.InputTransformer != nil:
+		values := make(map[string]any)
+		for name, path := range target.InputTransformer.InputPathsMap {
+			if value, ok := lookupJSONPath(event.decoded, path); ok {
+				values[name] = value
+			}
+		}
+		return placeholderRegex.ReplaceAllStringFunc(target.InputTransformer.InputTemplate, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+			switch name {
+			case "aws.events.rule-arn":
+				return rule.Arn
+			case "aws.events.rule-name":
+				return rule.Name
+			case "aws.events.event", "aws.events.event.json":
+				return string(event.encoded)
+			}
+			value, ok := values[name]
+			if !ok {
+				if _, defined := target.InputTransformer.InputPathsMap[name]; defined {
+					// Missing values are replaced with nothing.
+					return ""
+				}
+				return placeholder
+			}
+			// Strings are inserted as is, so templates quote them if they should be JSON strings.
+			if s, ok := value.(string); ok {
+				return s
+			}
+			return marshalJSON(value)
+		})
+	default:
+		return string(event.encoded)
+	}
+}
@@ -0,0 +1,386 @@
+package eventbridge
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128"`
+	Value string `required:"true" length:",256"`
+}
+
+type APIEventBus struct {
+	Name             string
+	Arn              string
+	Description      string  `json:",omitempty"`
+	Policy           string  `json:",omitempty"`
+	CreationTime     float64 `json:",omitempty"`
+	LastModifiedTime float64 `json:",omitempty"`
+}
+
+type CreateEventBusInput struct {
+	Name             string `required:"true" length:"1,256" pattern:"[/\\.\\-_A-Za-z0-9]+"`
+	Description      string `length:",512"`
+	EventSourceName  string `length:"1,256" pattern:"aws\\.partner(/[\\.\\-_A-Za-z0-9]+){2,}"`
+	KmsKeyIdentifier string `length:",2048"`
+	DeadLetterConfig any
+	Tags             []APITag
+}
+
+type CreateEventBusOutput struct {
+	EventBusArn string
+	Description string `json:",omitempty"`
+}
+
+type DescribeEventBusInput struct {
+	// The name or ARN of the event bus.
+	Name string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+}
+
+type DescribeEventBusOutput = APIEventBus
+
+type ListEventBusesInput struct {
+	NamePrefix string `length:"1,256" pattern:"[/\\.\\-_A-Za-z0-9]+"`
+	Limit      int    `range:"1,100"`
+	NextToken  string `length:"1,2048"`
+}
+
+type ListEventBusesOutput struct {
+	EventBuses []APIEventBus
+	NextToken  string `json:",omitempty"`
+}
+
+type DeleteEventBusInput struct {
+	Name string `required:"true" length:"1,256" pattern:"[/\\.\\-_A-Za-z0-9]+"`
+}
+
+type DeleteEventBusOutput struct{}
+
+type APIRule struct {
+	Name               string
+	Arn                string
+	EventBusName       string
+	EventPattern       string `json:",omitempty"`
+	ScheduleExpression string `json:",omitempty"`
+	State              string
+	Description        string `json:",omitempty"`
+	RoleArn            string `json:",omitempty"`
+	ManagedBy          string `json:",omitempty"`
+}
+
+type PutRuleInput struct {
+	Name string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	// The name or ARN of the event bus. Defaults to the default event bus.
+	EventBusName       string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	EventPattern       string `length:",4096"`
+	ScheduleExpression string `length:",256"`
+	State              string `enum:"ENABLED|DISABLED|ENABLED_WITH_ALL_CLOUDTRAIL_MANAGEMENT_EVENTS"`
+	Description        string `length:",512"`
+	RoleArn            string `length:"1,1600"`
+	Tags               []APITag
+}
+
+type PutRuleOutput struct {
+	RuleArn string
+}
+
+type DescribeRuleInput struct {
+	Name         string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+}
+
+type DescribeRuleOutput struct {
+	APIRule
+	CreatedBy string
+}
+
+type ListRulesInput struct {
+	NamePrefix   string `length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	Limit        int    `range:"1,100"`
+	NextToken    string `length:"1,2048"`
+}
+
+type ListRulesOutput struct {
+	Rules     []APIRule
+	NextToken string `json:",omitempty"`
+}
+
+type DeleteRuleInput struct {
+	Name         string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	Force        bool
+}
+
+type DeleteRuleOutput struct{}
+
+type EnableRuleInput struct {
+	Name         string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+}
+
+type EnableRuleOutput struct{}
+
+type DisableRuleInput struct {
+	Name         string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+}
+
+type DisableRuleOutput struct{}
+
+type APIInputTransformer struct {
+	InputPathsMap map[string]string `json:",omitempty" length:",100"`
+	InputTemplate string            `required:"true" length:"1,8192"`
+}
+
+type APIKinesisParameters struct {
+	PartitionKeyPath string `required:"true" length:",256"`
+}
+
+type APISqsParameters struct {
+	MessageGroupId string `json:",omitempty"`
+}
+
+// APITarget has every field the SDKs send, but only those for supported targets are used.
+type APITarget struct {
+	Id                          string                `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	Arn                         string                `required:"true" length:"1,1600"`
+	RoleArn                     string                `json:",omitempty" length:"1,1600"`
+	Input                       string                `json:",omitempty" length:",8192"`
+	InputPath                   string                `json:",omitempty" length:",256"`
+	InputTransformer            *APIInputTransformer  `json:",omitempty"`
+	KinesisParameters           *APIKinesisParameters `json:",omitempty"`
+	SqsParameters               *APISqsParameters     `json:",omitempty"`
+	AppSyncParameters           any                   `json:",omitempty"`
+	BatchParameters             any                   `json:",omitempty"`
+	DeadLetterConfig            any                   `json:",omitempty"`
+	EcsParameters               any                   `json:",omitempty"`
+	HttpParameters              any                   `json:",omitempty"`
+	RedshiftDataParameters      any                   `json:",omitempty"`
+	RetryPolicy                 any                   `json:",omitempty"`
+	RunCommandParameters        any                   `json:",omitempty"`
+	SageMakerPipelineParameters any                   `json:",omitempty"`
+}
+
+type APIFailedTarget struct {
+	TargetId     string
+	ErrorCode    string
+	ErrorMessage string
+}
+
+type PutTargetsInput struct {
+	Rule         string      `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string      `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	Targets      []APITarget `required:"true" length:"1,100"`
+}
+
+type PutTargetsOutput struct {
+	FailedEntryCount int
+	FailedEntries    []APIFailedTarget
+}
+
+type RemoveTargetsInput struct {
+	Rule         string   `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string   `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	Ids          []string `required:"true" length:"1,100"`
+	Force        bool
+}
+
+type RemoveTargetsOutput struct {
+	FailedEntryCount int
+	FailedEntries    []APIFailedTarget
+}
+
+type ListTargetsByRuleInput struct {
+	Rule         string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	Limit        int    `range:"1,100"`
+	NextToken    string `length:"1,2048"`
+}
+
+type ListTargetsByRuleOutput struct {
+	Targets   []APITarget
+	NextToken string `json:",omitempty"`
+}
+
+type APIPutEventsRequestEntry struct {
+	// Epoch seconds. Defaults to the time the event is put.
+	Time       float64
+	Source     string
+	Resources  []string
+	DetailType string
+	Detail     string
+	// The name or ARN of the event bus. Defaults to the default event bus.
+	EventBusName string `length:"1,1600" pattern:"(arn:aws[\\w-]*:events:[a-z0-9-]+:\\d{12}:event-bus/)?[/\\.\\-_A-Za-z0-9]+"`
+	TraceHeader  string `length:"1,500"`
+}
+
+type APIPutEventsResultEntry struct {
+	EventId      string `json:",omitempty"`
+	ErrorCode    string `json:",omitempty"`
+	ErrorMessage string `json:",omitempty"`
+}
+
+type PutEventsInput struct {
+	Entries    []APIPutEventsRequestEntry `required:"true" length:"1,10"`
+	EndpointId string                     `length:"1,50" pattern:"[A-Za-z0-9\\-]+[\\.][A-Za-z0-9\\-]+"`
+}
+
+type PutEventsOutput struct {
+	FailedEntryCount int
+	Entries          []APIPutEventsResultEntry
+}
+
+type TestEventPatternInput struct {
+	EventPattern string `required:"true" length:",4096"`
+	Event        string `required:"true"`
+}
+
+type TestEventPatternOutput struct {
+	Result bool
+}
+
+type APIArchive struct {
+	ArchiveName    string
+	EventSourceArn string
+	State          string
+	StateReason    string `json:",omitempty"`
+	RetentionDays  int32
+	SizeBytes      int64
+	EventCount     int64
+	CreationTime   float64
+}
+
+type CreateArchiveInput struct {
+	ArchiveName      string `required:"true" length:"1,48" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventSourceArn   string `required:"true" length:"1,1600"`
+	Description      string `length:",512"`
+	EventPattern     string `length:",4096"`
+	RetentionDays    int32  `range:"0,"`
+	KmsKeyIdentifier string `length:",2048"`
+}
+
+type CreateArchiveOutput struct {
+	ArchiveArn   string
+	State        string
+	StateReason  string `json:",omitempty"`
+	CreationTime float64
+}
+
+type DescribeArchiveInput struct {
+	ArchiveName string `required:"true" length:"1,48" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type DescribeArchiveOutput struct {
+	ArchiveArn     string
+	ArchiveName    string
+	EventSourceArn string
+	Description    string `json:",omitempty"`
+	EventPattern   string `json:",omitempty"`
+	State          string
+	StateReason    string `json:",omitempty"`
+	RetentionDays  int32
+	SizeBytes      int64
+	EventCount     int64
+	CreationTime   float64
+}
+
+type ListArchivesInput struct {
+	NamePrefix     string `length:"1,48" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventSourceArn string `length:"1,1600"`
+	State          string `enum:"ENABLED|DISABLED|CREATING|UPDATING|CREATE_FAILED|UPDATE_FAILED"`
+	Limit          int    `range:"1,100"`
+	NextToken      string `length:"1,2048"`
+}
+
+type ListArchivesOutput struct {
+	Archives  []APIArchive
+	NextToken string `json:",omitempty"`
+}
+
+type UpdateArchiveInput struct {
+	ArchiveName      string  `required:"true" length:"1,48" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	Description      *string `length:",512"`
+	EventPattern     *string `length:",4096"`
+	RetentionDays    *int32  `range:"0,"`
+	KmsKeyIdentifier string  `length:",2048"`
+}
+
+type UpdateArchiveOutput struct {
+	ArchiveArn   string
+	State        string
+	StateReason  string `json:",omitempty"`
+	CreationTime float64
+}
+
+type DeleteArchiveInput struct {
+	ArchiveName string `required:"true" length:"1,48" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type DeleteArchiveOutput struct{}
+
+type APIReplayDestination struct {
+	// The ARN of the event bus to replay events to, which must be the archive's event bus.
+	Arn string `required:"true" length:"1,1600"`
+	// If not empty, only these rules receive the replayed events.
+	FilterArns []string `json:",omitempty" length:"1,"`
+}
+
+type APIReplay struct {
+	ReplayName            string
+	EventSourceArn        string
+	State                 string
+	StateReason           string `json:",omitempty"`
+	EventStartTime        float64
+	EventEndTime          float64
+	EventLastReplayedTime float64 `json:",omitempty"`
+	ReplayStartTime       float64
+	ReplayEndTime         float64 `json:",omitempty"`
+}
+
+type StartReplayInput struct {
+	ReplayName string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	// The ARN of the archive to replay events from.
+	EventSourceArn string  `required:"true" length:"1,1600"`
+	Description    string  `length:",512"`
+	EventStartTime float64 `required:"true"`
+	EventEndTime   float64 `required:"true"`
+	Destination    APIReplayDestination
+}
+
+type StartReplayOutput struct {
+	ReplayArn       string
+	State           string
+	StateReason     string `json:",omitempty"`
+	ReplayStartTime float64
+}
+
+type DescribeReplayInput struct {
+	ReplayName string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type DescribeReplayOutput struct {
+	APIReplay
+	ReplayArn   string
+	Description string `json:",omitempty"`
+	Destination APIReplayDestination
+}
+
+type ListReplaysInput struct {
+	NamePrefix     string `length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	EventSourceArn string `length:"1,1600"`
+	State          string `enum:"STARTING|RUNNING|CANCELLING|COMPLETED|CANCELLED|FAILED"`
+	Limit          int    `range:"1,100"`
+	NextToken      string `length:"1,2048"`
+}
+
+type ListReplaysOutput struct {
+	Replays   []APIReplay
+	NextToken string `json:",omitempty"`
+}
+
+type CancelReplayInput struct {
+	ReplayName string `required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type CancelReplayOutput struct {
+	ReplayArn   string
+	State       string
+	StateReason string `json:",omitempty"`
+}
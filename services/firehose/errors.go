@@ -0,0 +1,19 @@
+package firehose
+
+import "aws-in-a-box/awserrors"
+
+func InvalidArgumentException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidArgumentException", message)
+}
+
+func LimitExceededException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("LimitExceededException", message)
+}
+
+func ResourceInUseException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceInUseException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
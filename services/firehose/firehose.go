@@ -0,0 +1,433 @@
+package firehose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/firehose/latest/dev/limits.html
+
+const (
+	maxDeliveryStreams       = 5000
+	defaultMaxResults        = 10
+	defaultBufferSizeInMBs   = 5
+	defaultBufferingInterval = 300
+	// Delivery streams only have the destination they were created with.
+	destinationId = "destinationId-000000000001"
+)
+
+// Objects is the local S3 service, which records are delivered to.
+type Objects interface {
+	PutObject(input s3.PutObjectInput) (*s3.PutObjectOutput, *awserrors.Error)
+}
+
+type DeliveryStream struct {
+	Name    string
+	ARN     string
+	Type    string
+	Created time.Time
+	// ExtendedS3DestinationConfiguration, if the stream was created with one, otherwise its S3DestinationConfiguration.
+	Destination APIS3DestinationConfiguration
+	Extended    bool
+	Tags        map[string]string
+
+	// Records waiting to be delivered, until the buffer is full or its interval has passed.
+	buffer        [][]byte
+	bufferedBytes int
+	// Delivers the buffer once its interval has passed. Nil when the buffer is empty.
+	timer *time.Timer
+}
+
+func (s *DeliveryStream) bufferSize() int {
+	return s.Destination.BufferingHints.SizeInMBs << 20
+}
+
+func (s *DeliveryStream) bufferingInterval() time.Duration {
+	return time.Duration(*s.Destination.BufferingHints.IntervalInSeconds) * time.Second
+}
+
+func (s *DeliveryStream) toAPI() APIDeliveryStreamDescription {
+	destination := &APIS3DestinationDescription{
+		BucketARN:         s.Destination.BucketARN,
+		RoleARN:           s.Destination.RoleARN,
+		Prefix:            s.Destination.Prefix,
+		ErrorOutputPrefix: s.Destination.ErrorOutputPrefix,
+		BufferingHints:    *s.Destination.BufferingHints,
+		CompressionFormat: s.Destination.CompressionFormat,
+		EncryptionConfiguration: APIEncryptionConfiguration{
+			NoEncryptionConfig: "NoEncryption",
+		},
+	}
+	description := APIDestinationDescription{DestinationId: destinationId}
+	if s.Extended {
+		description.ExtendedS3DestinationDescription = destination
+	}
+	// Streams with an extended S3 destination describe it as an S3 destination too.
+	description.S3DestinationDescription = destination
+	return APIDeliveryStreamDescription{
+		DeliveryStreamName:   s.Name,
+		DeliveryStreamARN:    s.ARN,
+		DeliveryStreamStatus: "ACTIVE",
+		DeliveryStreamType:   s.Type,
+		VersionId:            "1",
+		CreateTimestamp:      timestamp.EpochSeconds(s.Created),
+		Destinations:         []APIDestinationDescription{description},
+	}
+}
+
+// batch is the records taken from a stream's buffer to be delivered in one object.
+type batch struct {
+	stream      string
+	destination APIS3DestinationConfiguration
+	records     [][]byte
+	time        time.Time
+}
+
+type Firehose struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	s3           Objects
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu              sync.Mutex
+	deliveryStreams map[string]*DeliveryStream
+	// Shared by the instances of all regions, which each have their own delivery streams.
+	regions *region.Regions[*Firehose]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// Optional, but records can't be delivered without it.
+	S3 Objects
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Firehose {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// S3 is global, so every region delivers to the same buckets.
+	f := newRegion(options)
+	f.regions = region.New(options.ArnGenerator.Region, options.Regions, f, func(name string) (*Firehose, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = f.regions
+		return r, nil
+	})
+	return f
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *Firehose {
+	return &Firehose{
+		logger:          options.Logger,
+		arnGenerator:    options.ArnGenerator,
+		s3:              options.S3,
+		clock:           clock.Now,
+		deliveryStreams: make(map[string]*DeliveryStream),
+	}
+}
+
+// InRegion returns the delivery streams of the region. It fails if the region isn't one of
+// Options.Regions.
+func (f *Firehose) InRegion(name string) (*Firehose, error) {
+	return f.regions.Get(name)
+}
+
+// ForARN returns the delivery streams of the region the delivery stream ARN is in, for services
+// which put records to streams by ARN, like CloudWatch Logs' subscription filters.
+func (f *Firehose) ForARN(deliveryStreamArn string) (*Firehose, error) {
+	return f.regions.ForARN(deliveryStreamArn)
+}
+
+func (f *Firehose) lockedGetDeliveryStream(name string) (*DeliveryStream, *awserrors.Error) {
+	stream, ok := f.deliveryStreams[name]
+	if !ok {
+		return nil, ResourceNotFoundException(fmt.Sprintf("Firehose %s under account %s not found.", name, f.arnGenerator.AwsAccountId))
+	}
+	return stream, nil
+}
+
+// https://docs.aws.amazon.com/firehose/latest/APIReference/API_CreateDeliveryStream.html
+func (f *Firehose) CreateDeliveryStream(input CreateDeliveryStreamInput) (*CreateDeliveryStreamOutput, *awserrors.Error) {
+	if input.DeliveryStreamType == "" {
+		input.DeliveryStreamType = "DirectPut"
+	}
+	if input.DeliveryStreamType != "DirectPut" {
+		return nil, InvalidArgumentException("Only DirectPut delivery streams are supported.")
+	}
+	destination := input.ExtendedS3DestinationConfiguration
+	extended := destination != nil
+	if destination == nil {
+		destination = input.S3DestinationConfiguration
+	} else if input.S3DestinationConfiguration != nil {
+		return nil, InvalidArgumentException("Only one destination configuration can be specified.")
+	}
+	if destination == nil {
+		return nil, InvalidArgumentException("Only S3 destinations are supported, with S3DestinationConfiguration or ExtendedS3DestinationConfiguration.")
+	}
+	configuration := *destination
+	hints := APIBufferingHints{SizeInMBs: defaultBufferSizeInMBs}
+	if configuration.BufferingHints != nil {
+		hints = *configuration.BufferingHints
+		if hints.SizeInMBs == 0 {
+			hints.SizeInMBs = defaultBufferSizeInMBs
+		}
+	}
+	if hints.IntervalInSeconds == nil {
+		interval := defaultBufferingInterval
+		hints.IntervalInSeconds = &interval
+	}
+	configuration.BufferingHints = &hints
+	switch configuration.CompressionFormat {
+	case "":
+		configuration.CompressionFormat = "UNCOMPRESSED"
+	case "UNCOMPRESSED", "GZIP":
+	default:
+		return nil, InvalidArgumentException("Only UNCOMPRESSED and GZIP compression are supported.")
+	}
+	if _, err := parseBucketARN(configuration.BucketARN); err != nil {
+		return nil, InvalidArgumentException(err.Error())
+	}
+	tags := make(map[string]string)
+	for _, tag := range input.Tags {
+		tags[tag.Key] = tag.Value
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.deliveryStreams[input.DeliveryStreamName]; ok {
+		return nil, ResourceInUseException(fmt.Sprintf("Firehose %s under accountId %s already exists", input.DeliveryStreamName, f.arnGenerator.AwsAccountId))
+	}
+	if len(f.deliveryStreams) >= maxDeliveryStreams {
+		return nil, LimitExceededException(fmt.Sprintf("You have already consumed your firehose quota of %d hoses.", maxDeliveryStreams))
+	}
+	stream := &DeliveryStream{
+		Name:        input.DeliveryStreamName,
+		ARN:         f.arnGenerator.Generate("firehose", "deliverystream", input.DeliveryStreamName),
+		Type:        input.DeliveryStreamType,
+		Created:     f.clock(),
+		Destination: configuration,
+		Extended:    extended,
+		Tags:        tags,
+	}
+	f.deliveryStreams[stream.Name] = stream
+	return &CreateDeliveryStreamOutput{DeliveryStreamARN: stream.ARN}, nil
+}
+
+// https://docs.aws.amazon.com/firehose/latest/APIReference/API_DeleteDeliveryStream.html
+func (f *Firehose) DeleteDeliveryStream(input DeleteDeliveryStreamInput) (*DeleteDeliveryStreamOutput, *awserrors.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stream, awserr := f.lockedGetDeliveryStream(input.DeliveryStreamName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Buffered records which haven't been delivered are lost, like they are in AWS.
+	f.lockedTakeBuffer(stream)
+	delete(f.deliveryStreams, stream.Name)
+	return &DeleteDeliveryStreamOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/firehose/latest/APIReference/API_DescribeDeliveryStream.html
+func (f *Firehose) DescribeDeliveryStream(input DescribeDeliveryStreamInput) (*DescribeDeliveryStreamOutput, *awserrors.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stream, awserr := f.lockedGetDeliveryStream(input.DeliveryStreamName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	description := stream.toAPI()
+	// There's only one destination, so none come after it.
+	if input.ExclusiveStartDestinationId != "" {
+		description.Destinations = []APIDestinationDescription{}
+	}
+	return &DescribeDeliveryStreamOutput{DeliveryStreamDescription: description}, nil
+}
+
+// https://docs.aws.amazon.com/firehose/latest/APIReference/API_ListDeliveryStreams.html
+func (f *Firehose) ListDeliveryStreams(input ListDeliveryStreamsInput) (*ListDeliveryStreamsOutput, *awserrors.Error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = defaultMaxResults
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for name, stream := range f.deliveryStreams {
+		if input.DeliveryStreamType != "" && stream.Type != input.DeliveryStreamType {
+			continue
+		}
+		if name > input.ExclusiveStartDeliveryStreamName {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	output := &ListDeliveryStreamsOutput{DeliveryStreamNames: []string{}}
+	if len(names) > limit {
+		names = names[:limit]
+		output.HasMoreDeliveryStreams = true
+	}
+	output.DeliveryStreamNames = append(output.DeliveryStreamNames, names...)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/firehose/latest/APIReference/API_PutRecord.html
+func (f *Firehose) PutRecord(input PutRecordInput) (*PutRecordOutput, *awserrors.Error) {
+	recordIds, awserr := f.put(input.DeliveryStreamName, []APIRecord{input.Record})
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &PutRecordOutput{RecordId: recordIds[0]}, nil
+}
+
+// https://docs.aws.amazon.com/firehose/latest/APIReference/API_PutRecordBatch.html
+func (f *Firehose) PutRecordBatch(input PutRecordBatchInput) (*PutRecordBatchOutput, *awserrors.Error) {
+	recordIds, awserr := f.put(input.DeliveryStreamName, input.Records)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &PutRecordBatchOutput{}
+	for _, recordId := range recordIds {
+		output.RequestResponses = append(output.RequestResponses, APIPutRecordBatchResponseEntry{RecordId: recordId})
+	}
+	return output, nil
+}
+
+// put buffers the records, and delivers the buffer if it's full, or straight away if the stream's
+// buffering interval is zero.
+func (f *Firehose) put(name string, records []APIRecord) ([]string, *awserrors.Error) {
+	f.mu.Lock()
+	stream, awserr := f.lockedGetDeliveryStream(name)
+	if awserr != nil {
+		f.mu.Unlock()
+		return nil, awserr
+	}
+	var recordIds []string
+	for _, record := range records {
+		stream.buffer = append(stream.buffer, record.Data)
+		stream.bufferedBytes += len(record.Data)
+		recordIds = append(recordIds, uuid.Must(uuid.NewV4()).String())
+	}
+	var full *batch
+	if stream.bufferedBytes >= stream.bufferSize() || stream.bufferingInterval() == 0 {
+		full = f.lockedTakeBuffer(stream)
+	} else if stream.timer == nil {
+		f.lockedScheduleDelivery(stream)
+	}
+	f.mu.Unlock()
+
+	// Records are delivered without holding the lock, so puts to other streams don't wait for S3.
+	if full != nil {
+		f.deliver(full)
+	}
+	return recordIds, nil
+}
+
+// lockedScheduleDelivery delivers the stream's buffer once its buffering interval has passed.
+func (f *Firehose) lockedScheduleDelivery(stream *DeliveryStream) {
+	stream.timer = time.AfterFunc(stream.bufferingInterval(), func() {
+		f.mu.Lock()
+		b := f.lockedTakeBuffer(stream)
+		f.mu.Unlock()
+		if b != nil {
+			f.deliver(b)
+		}
+	})
+}
+
+// lockedTakeBuffer empties the stream's buffer, returning the batch of its records, or nil if it was empty.
+func (f *Firehose) lockedTakeBuffer(stream *DeliveryStream) *batch {
+	if stream.timer != nil {
+		stream.timer.Stop()
+		stream.timer = nil
+	}
+	if len(stream.buffer) == 0 {
+		return nil
+	}
+	b := &batch{
+		stream:      stream.Name,
+		destination: stream.Destination,
+		records:     stream.buffer,
+		time:        f.clock(),
+	}
+	stream.buffer = nil
+	stream.bufferedBytes = 0
+	return b
+}
+
+// deliver writes the batch's records, concatenated, to an object in the destination bucket.
+// Like AWS, its key is the prefix, then the time as YYYY/MM/DD/HH/ in UTC, and then
+// <stream>-<version>-YYYY-MM-DD-HH-MM-SS-<uuid>.
+// https://docs.aws.amazon.com/firehose/latest/dev/basic-deliver.html#s3-object-name
+func (f *Firehose) deliver(b *batch) {
+	logger := f.logger.With("deliveryStream", b.stream)
+	if f.s3 == nil {
+		logger.Warn("Can't deliver records, since S3 is not enabled", "records", len(b.records))
+		return
+	}
+	bucket, err := parseBucketARN(b.destination.BucketARN)
+	if err != nil {
+		logger.Warn("Can't deliver records", "err", err)
+		return
+	}
+	t := b.time.UTC()
+	key := b.destination.Prefix + t.Format("2006/01/02/15/") +
+		fmt.Sprintf("%s-1-%s-%s", b.stream, t.Format("2006-01-02-15-04-05"), uuid.Must(uuid.NewV4()).String())
+
+	var content bytes.Buffer
+	if b.destination.CompressionFormat == "GZIP" {
+		key += ".gz"
+		w := gzip.NewWriter(&content)
+		for _, record := range b.records {
+			w.Write(record)
+		}
+		w.Close()
+	} else {
+		for _, record := range b.records {
+			content.Write(record)
+		}
+	}
+
+	if _, awserr := f.s3.PutObject(s3.PutObjectInput{Bucket: bucket, Key: key, Data: &content}); awserr != nil {
+		logger.Warn("Failed to deliver records", "bucket", bucket, "key", key, "error", awserr.Body.Message)
+		return
+	}
+	logger.Debug("Delivered records", "bucket", bucket, "key", key, "records", len(b.records))
+}
+
+// parseBucketARN returns the name of the bucket, like arn:aws:s3:::bucket.
+func parseBucketARN(bucketARN string) (string, error) {
+	a, err := arn.Parse(bucketARN)
+	if err != nil || a.Service != "s3" || a.Resource == "" || strings.Contains(a.Resource, "/") {
+		return "", fmt.Errorf("Invalid BucketARN: %s", bucketARN)
+	}
+	return a.Resource, nil
+}
@@ -0,0 +1,230 @@
+package firehose
+
+import (
+	"compress/gzip"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/s3"
+)
+
+func newFirehose(t *testing.T) (*Firehose, *s3.S3) {
+	s3Service, err := s3.New(s3.Options{PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := s3Service.CreateBucket(s3.CreateBucketInput{Bucket: "logs"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	f := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+		S3: s3Service,
+	})
+	f.clock = func() time.Time {
+		return time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	}
+	return f, s3Service
+}
+
+func createDeliveryStream(t *testing.T, f *Firehose, name string, hints *APIBufferingHints, compression string) {
+	_, awserr := f.CreateDeliveryStream(CreateDeliveryStreamInput{
+		DeliveryStreamName: name,
+		ExtendedS3DestinationConfiguration: &APIS3DestinationConfiguration{
+			BucketARN:         "arn:aws:s3:::logs",
+			RoleARN:           "arn:aws:iam::123456789012:role/firehose",
+			Prefix:            "delivered/",
+			BufferingHints:    hints,
+			CompressionFormat: compression,
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+// getObjects returns the contents of the objects in the bucket, by key.
+func getObjects(t *testing.T, s *s3.S3) map[string]string {
+	list, awserr := s.ListObjectsV2(s3.ListObjectsV2Input{Bucket: "logs"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	objects := make(map[string]string)
+	for _, object := range list.Contents {
+		output, awserr := s.GetObject(s3.GetObjectInput{Bucket: "logs", Key: object.Key})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		content, err := io.ReadAll(output.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects[object.Key] = string(content)
+	}
+	return objects
+}
+
+func TestDeliveryStreams(t *testing.T) {
+	f, _ := newFirehose(t)
+	createDeliveryStream(t, f, "b", nil, "")
+	createDeliveryStream(t, f, "a", nil, "")
+
+	_, awserr := f.CreateDeliveryStream(CreateDeliveryStreamInput{
+		DeliveryStreamName: "a",
+		S3DestinationConfiguration: &APIS3DestinationConfiguration{
+			BucketARN: "arn:aws:s3:::logs",
+			RoleARN:   "arn:aws:iam::123456789012:role/firehose",
+		},
+	})
+	if awserr == nil || awserr.Body.Type != "ResourceInUseException" {
+		t.Fatal("Expected the stream to exist", awserr)
+	}
+
+	described, awserr := f.DescribeDeliveryStream(DescribeDeliveryStreamInput{DeliveryStreamName: "a"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	description := described.DeliveryStreamDescription
+	if description.DeliveryStreamARN != "arn:aws:firehose:us-east-1:123456789012:deliverystream/a" ||
+		description.DeliveryStreamStatus != "ACTIVE" || description.DeliveryStreamType != "DirectPut" {
+		t.Fatal("Unexpected description", description)
+	}
+	destination := description.Destinations[0].ExtendedS3DestinationDescription
+	if destination == nil || destination.BufferingHints.SizeInMBs != 5 || *destination.BufferingHints.IntervalInSeconds != 300 ||
+		destination.CompressionFormat != "UNCOMPRESSED" {
+		t.Fatal("Unexpected destination", destination)
+	}
+
+	list, awserr := f.ListDeliveryStreams(ListDeliveryStreamsInput{Limit: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.DeliveryStreamNames) != 1 || list.DeliveryStreamNames[0] != "a" || !list.HasMoreDeliveryStreams {
+		t.Fatal("Unexpected list", list)
+	}
+	list, awserr = f.ListDeliveryStreams(ListDeliveryStreamsInput{ExclusiveStartDeliveryStreamName: "a"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.DeliveryStreamNames) != 1 || list.DeliveryStreamNames[0] != "b" || list.HasMoreDeliveryStreams {
+		t.Fatal("Unexpected list", list)
+	}
+
+	if _, awserr := f.DeleteDeliveryStream(DeleteDeliveryStreamInput{DeliveryStreamName: "a"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := f.DescribeDeliveryStream(DescribeDeliveryStreamInput{DeliveryStreamName: "a"}); awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected the stream to be deleted", awserr)
+	}
+	if _, awserr := f.PutRecord(PutRecordInput{DeliveryStreamName: "a", Record: APIRecord{Data: []byte("x")}}); awserr == nil {
+		t.Fatal("Expected putting to a deleted stream to fail")
+	}
+}
+
+func TestDeliverWhenBufferIsFull(t *testing.T) {
+	f, s := newFirehose(t)
+	interval := 900
+	createDeliveryStream(t, f, "events", &APIBufferingHints{SizeInMBs: 1, IntervalInSeconds: &interval}, "")
+
+	if _, awserr := f.PutRecord(PutRecordInput{DeliveryStreamName: "events", Record: APIRecord{Data: []byte("first\n")}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if objects := getObjects(t, s); len(objects) != 0 {
+		t.Fatal("Expected records to be buffered", objects)
+	}
+
+	large := make([]byte, 1<<20)
+	output, awserr := f.PutRecordBatch(PutRecordBatchInput{DeliveryStreamName: "events", Records: []APIRecord{{Data: large}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.FailedPutCount != 0 || len(output.RequestResponses) != 1 || output.RequestResponses[0].RecordId == "" {
+		t.Fatal("Unexpected output", output)
+	}
+	objects := getObjects(t, s)
+	if len(objects) != 1 {
+		t.Fatal("Expected the full buffer to be delivered", len(objects))
+	}
+	keyRegex := regexp.MustCompile(`^delivered/2023/11/14/22/events-1-2023-11-14-22-13-20-[0-9a-f-]{36}$`)
+	for key, content := range objects {
+		if !keyRegex.MatchString(key) || content != "first\n"+string(large) {
+			t.Fatal("Unexpected object", key, len(content))
+		}
+	}
+}
+
+func TestDeliverGzipped(t *testing.T) {
+	f, s := newFirehose(t)
+	interval := 0
+	createDeliveryStream(t, f, "events", &APIBufferingHints{IntervalInSeconds: &interval}, "GZIP")
+
+	if _, awserr := f.PutRecordBatch(PutRecordBatchInput{DeliveryStreamName: "events", Records: []APIRecord{
+		{Data: []byte("a\n")},
+		{Data: []byte("b\n")},
+	}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	objects := getObjects(t, s)
+	if len(objects) != 1 {
+		t.Fatal("Expected records to be delivered straight away", objects)
+	}
+	for key, content := range objects {
+		if !strings.HasSuffix(key, ".gz") {
+			t.Fatal("Expected a .gz key", key)
+		}
+		r, err := gzip.NewReader(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decompressed, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decompressed) != "a\nb\n" {
+			t.Fatal("Unexpected content", string(decompressed))
+		}
+	}
+}
+
+func TestRegions(t *testing.T) {
+	f, s := newFirehose(t)
+	if _, err := f.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := f.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	interval := 900
+	createDeliveryStream(t, europe, "events", &APIBufferingHints{SizeInMBs: 1, IntervalInSeconds: &interval}, "")
+	if _, awserr := f.DescribeDeliveryStream(DescribeDeliveryStreamInput{DeliveryStreamName: "events"}); awserr == nil {
+		t.Fatal("Expected the delivery stream not to be in us-east-1")
+	}
+	description, awserr := europe.DescribeDeliveryStream(DescribeDeliveryStreamInput{DeliveryStreamName: "events"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	streamArn := description.DeliveryStreamDescription.DeliveryStreamARN
+	if streamArn != "arn:aws:firehose:eu-west-1:123456789012:deliverystream/events" {
+		t.Fatal("Unexpected ARN", streamArn)
+	}
+
+	// The stream's region delivers to the bucket, which is in every region.
+	streams, err := f.ForARN(streamArn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := make([]byte, 1<<20)
+	if _, awserr := streams.PutRecord(PutRecordInput{DeliveryStreamName: "events", Record: APIRecord{Data: large}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if objects := getObjects(t, s); len(objects) != 1 {
+		t.Fatal("Expected the full buffer to be delivered", len(objects))
+	}
+}
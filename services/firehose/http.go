@@ -0,0 +1,19 @@
+package firehose
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "Firehose_20150804"
+
+func (f *Firehose) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateDeliveryStream", region.Method(f.regions, (*Firehose).CreateDeliveryStream))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteDeliveryStream", region.Method(f.regions, (*Firehose).DeleteDeliveryStream))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeDeliveryStream", region.Method(f.regions, (*Firehose).DescribeDeliveryStream))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListDeliveryStreams", region.Method(f.regions, (*Firehose).ListDeliveryStreams))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutRecord", region.Method(f.regions, (*Firehose).PutRecord))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutRecordBatch", region.Method(f.regions, (*Firehose).PutRecordBatch))
+}
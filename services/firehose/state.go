@@ -0,0 +1,100 @@
+package firehose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type streamState struct {
+	*DeliveryStream
+	// Records which haven't been delivered yet, oldest first.
+	Buffer [][]byte `json:",omitempty"`
+}
+
+type streamsState struct {
+	DeliveryStreams []streamState
+}
+
+// TryPause pauses changes to each region's delivery streams while a snapshot copies them.
+func (f *Firehose) TryPause() (func(), bool) {
+	return f.regions.TryPause(func(f *Firehose) *sync.Mutex { return &f.mu })
+}
+
+// ExportState writes each region's delivery streams, with the records they haven't delivered yet,
+// to the archive.
+func (f *Firehose) ExportState(w *state.Writer) error {
+	return f.regions.ExportState(w, (*Firehose).exportState)
+}
+
+func (f *Firehose) exportState(w *state.Writer) error {
+	unlock := w.Lock(&f.mu)
+	defer unlock()
+
+	var exported streamsState
+	for _, stream := range f.deliveryStreams {
+		exported.DeliveryStreams = append(exported.DeliveryStreams, streamState{
+			DeliveryStream: stream,
+			Buffer:         stream.buffer,
+		})
+	}
+	slices.SortFunc(exported.DeliveryStreams, func(a, b streamState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("deliverystreams.json", exported)
+}
+
+// ImportState replaces each region's delivery streams with the archive's. Records which the
+// previous streams hadn't delivered are lost, and the imported streams' records are delivered once
+// their buffering intervals have passed.
+func (f *Firehose) ImportState(r *state.Reader) (func() error, error) {
+	return f.regions.ImportState(r, (*Firehose).importState, (*Firehose).exportState)
+}
+
+func (f *Firehose) importState(r *state.Reader) (func() error, error) {
+	var imported streamsState
+	if err := r.ReadJSON("deliverystreams.json", &imported); err != nil {
+		return nil, err
+	}
+	deliveryStreams := make(map[string]*DeliveryStream)
+	for _, s := range imported.DeliveryStreams {
+		stream := s.DeliveryStream
+		if stream == nil || stream.Name == "" || stream.ARN == "" {
+			return nil, fmt.Errorf("invalid delivery stream")
+		}
+		hints := stream.Destination.BufferingHints
+		if hints == nil || hints.IntervalInSeconds == nil || hints.SizeInMBs <= 0 {
+			return nil, fmt.Errorf("delivery stream %s has no buffering hints", stream.Name)
+		}
+		if _, err := parseBucketARN(stream.Destination.BucketARN); err != nil {
+			return nil, fmt.Errorf("delivery stream %s: %v", stream.Name, err)
+		}
+		if stream.Tags == nil {
+			stream.Tags = make(map[string]string)
+		}
+		stream.buffer = s.Buffer
+		stream.bufferedBytes = 0
+		for _, record := range s.Buffer {
+			stream.bufferedBytes += len(record)
+		}
+		deliveryStreams[stream.Name] = stream
+	}
+
+	return func() error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, stream := range f.deliveryStreams {
+			f.lockedTakeBuffer(stream)
+		}
+		f.deliveryStreams = deliveryStreams
+		for _, stream := range deliveryStreams {
+			if len(stream.buffer) > 0 {
+				f.lockedScheduleDelivery(stream)
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,122 @@
+package firehose
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128"`
+	Value string `length:",256"`
+}
+
+type APIBufferingHints struct {
+	SizeInMBs         int  `range:"1,128"`
+	IntervalInSeconds *int `range:"0,900"`
+}
+
+type APIEncryptionConfiguration struct {
+	NoEncryptionConfig string `json:",omitempty"`
+}
+
+type APIS3DestinationConfiguration struct {
+	BucketARN         string             `required:"true" length:"1,2048" pattern:"arn:.*:s3:::[\\w\\.\\-]{1,255}"`
+	RoleARN           string             `required:"true" length:"1,512" pattern:"arn:.*:iam::\\d{12}:role/[a-zA-Z_0-9+=,.@\\-_/]+"`
+	Prefix            string             `length:",1024"`
+	ErrorOutputPrefix string             `length:",1024"`
+	BufferingHints    *APIBufferingHints `json:",omitempty"`
+	CompressionFormat string             `enum:"UNCOMPRESSED|GZIP|ZIP|Snappy|HADOOP_SNAPPY"`
+}
+
+type APIS3DestinationDescription struct {
+	BucketARN               string
+	RoleARN                 string
+	Prefix                  string `json:",omitempty"`
+	ErrorOutputPrefix       string `json:",omitempty"`
+	BufferingHints          APIBufferingHints
+	CompressionFormat       string
+	EncryptionConfiguration APIEncryptionConfiguration
+}
+
+type APIDestinationDescription struct {
+	DestinationId                    string
+	S3DestinationDescription         *APIS3DestinationDescription `json:",omitempty"`
+	ExtendedS3DestinationDescription *APIS3DestinationDescription `json:",omitempty"`
+}
+
+type APIDeliveryStreamDescription struct {
+	DeliveryStreamName   string
+	DeliveryStreamARN    string
+	DeliveryStreamStatus string
+	DeliveryStreamType   string
+	VersionId            string
+	CreateTimestamp      float64
+	Destinations         []APIDestinationDescription
+	HasMoreDestinations  bool
+}
+
+type APIRecord struct {
+	Data []byte `required:"true" length:",1024000"`
+}
+
+type APIPutRecordBatchResponseEntry struct {
+	RecordId     string `json:",omitempty"`
+	ErrorCode    string `json:",omitempty"`
+	ErrorMessage string `json:",omitempty"`
+}
+
+type CreateDeliveryStreamInput struct {
+	DeliveryStreamName                 string `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_.-]+"`
+	DeliveryStreamType                 string `enum:"DirectPut|KinesisStreamAsSource|MSKAsSource|DatabaseAsSource"`
+	S3DestinationConfiguration         *APIS3DestinationConfiguration
+	ExtendedS3DestinationConfiguration *APIS3DestinationConfiguration
+	Tags                               []APITag `length:",50"`
+}
+
+type CreateDeliveryStreamOutput struct {
+	DeliveryStreamARN string
+}
+
+type DeleteDeliveryStreamInput struct {
+	DeliveryStreamName string `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_.-]+"`
+	AllowForceDelete   bool
+}
+
+type DeleteDeliveryStreamOutput struct{}
+
+type DescribeDeliveryStreamInput struct {
+	DeliveryStreamName          string `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_.-]+"`
+	Limit                       int    `range:"1,10000"`
+	ExclusiveStartDestinationId string `length:"1,100"`
+}
+
+type DescribeDeliveryStreamOutput struct {
+	DeliveryStreamDescription APIDeliveryStreamDescription
+}
+
+type ListDeliveryStreamsInput struct {
+	Limit                            int    `range:"1,10000"`
+	DeliveryStreamType               string `enum:"DirectPut|KinesisStreamAsSource|MSKAsSource|DatabaseAsSource"`
+	ExclusiveStartDeliveryStreamName string `length:"1,64" pattern:"[a-zA-Z0-9_.-]+"`
+}
+
+type ListDeliveryStreamsOutput struct {
+	DeliveryStreamNames    []string
+	HasMoreDeliveryStreams bool
+}
+
+type PutRecordInput struct {
+	DeliveryStreamName string    `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_.-]+"`
+	Record             APIRecord `required:"true"`
+}
+
+type PutRecordOutput struct {
+	RecordId  string
+	Encrypted bool
+}
+
+type PutRecordBatchInput struct {
+	DeliveryStreamName string      `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_.-]+"`
+	Records            []APIRecord `required:"true" length:"1,500"`
+}
+
+type PutRecordBatchOutput struct {
+	FailedPutCount   int
+	Encrypted        bool
+	RequestResponses []APIPutRecordBatchResponseEntry
+}
@@ -0,0 +1,359 @@
+package glue
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/services/s3"
+	"aws-in-a-box/timestamp"
+)
+
+type Database struct {
+	APIDatabaseInput
+	Tags    map[string]string
+	Created time.Time
+	// Keyed by name.
+	tables map[string]*Table
+}
+
+type Table struct {
+	APITableInput
+	DatabaseName string
+	Created      time.Time
+	Updated      time.Time
+	// Incremented by each update.
+	Version int
+	// Keyed by partitionKey of their values.
+	partitions map[string]*Partition
+}
+
+type Partition struct {
+	APIPartitionInput
+	Created time.Time
+}
+
+func (d *Database) output(catalogId string) APIDatabase {
+	return APIDatabase{
+		Name:                          d.Name,
+		Description:                   d.Description,
+		LocationUri:                   d.LocationUri,
+		Parameters:                    d.Parameters,
+		CreateTime:                    timestamp.EpochSeconds(d.Created),
+		CreateTableDefaultPermissions: d.CreateTableDefaultPermissions,
+		TargetDatabase:                d.TargetDatabase,
+		CatalogId:                     catalogId,
+		FederatedDatabase:             d.FederatedDatabase,
+	}
+}
+
+func (t *Table) output(catalogId string) APITable {
+	partitionKeys := t.PartitionKeys
+	if partitionKeys == nil {
+		partitionKeys = []APIColumn{}
+	}
+	return APITable{
+		Name:              t.Name,
+		DatabaseName:      t.DatabaseName,
+		Description:       t.Description,
+		Owner:             t.Owner,
+		CreateTime:        timestamp.EpochSeconds(t.Created),
+		UpdateTime:        timestamp.EpochSeconds(t.Updated),
+		LastAccessTime:    t.LastAccessTime,
+		LastAnalyzedTime:  t.LastAnalyzedTime,
+		Retention:         t.Retention,
+		StorageDescriptor: t.StorageDescriptor,
+		PartitionKeys:     partitionKeys,
+		ViewOriginalText:  t.ViewOriginalText,
+		ViewExpandedText:  t.ViewExpandedText,
+		TableType:         t.TableType,
+		Parameters:        t.Parameters,
+		TargetTable:       t.TargetTable,
+		CatalogId:         catalogId,
+		VersionId:         strconv.Itoa(t.Version),
+		ViewDefinition:    t.ViewDefinition,
+	}
+}
+
+// Database and table names are case insensitive, and stored in lower case.
+func validateCatalogName(kind string, name string) (string, *awserrors.Error) {
+	if len(name) < 1 || len(name) > 255 {
+		return "", InvalidInputException(kind + " name must be between 1 and 255 characters.")
+	}
+	if strings.ContainsAny(name, ":/") {
+		return "", InvalidInputException(kind + " name cannot contain : or /.")
+	}
+	return strings.ToLower(name), nil
+}
+
+// checkLocation logs when a location is in a bucket which doesn't exist in the local S3 service,
+// since nothing will be able to read its data. AWS doesn't check locations, so neither does this.
+func (g *Glue) checkLocation(storageDescriptor *APIStorageDescriptor) {
+	if g.s3 == nil || storageDescriptor == nil {
+		return
+	}
+	bucket, ok := strings.CutPrefix(storageDescriptor.Location, "s3://")
+	if !ok {
+		return
+	}
+	bucket, _, _ = strings.Cut(bucket, "/")
+	if _, awserr := g.s3.HeadBucket(s3.HeadBucketInput{Bucket: bucket}); awserr != nil {
+		g.logger.Warn("Location is in a bucket which doesn't exist", "location", storageDescriptor.Location)
+	}
+}
+
+func (g *Glue) lockedGetDatabase(name string) (*Database, *awserrors.Error) {
+	database, ok := g.databases[strings.ToLower(name)]
+	if !ok {
+		return nil, EntityNotFoundException(fmt.Sprintf("Database %s not found.", name))
+	}
+	return database, nil
+}
+
+func (g *Glue) lockedGetTable(databaseName string, name string) (*Table, *awserrors.Error) {
+	database, awserr := g.lockedGetDatabase(databaseName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	table, ok := database.tables[strings.ToLower(name)]
+	if !ok {
+		return nil, EntityNotFoundException(fmt.Sprintf("Table %s not found.", name))
+	}
+	return table, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_CreateDatabase.html
+func (g *Glue) CreateDatabase(input CreateDatabaseInput) (*CreateDatabaseOutput, *awserrors.Error) {
+	name, awserr := validateCatalogName("Database", input.DatabaseInput.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	input.DatabaseInput.Name = name
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.databases[name]; ok {
+		return nil, AlreadyExistsException("Database already exists.")
+	}
+	g.databases[name] = &Database{
+		APIDatabaseInput: input.DatabaseInput,
+		Tags:             copyTags(input.Tags),
+		Created:          g.clock(),
+		tables:           make(map[string]*Table),
+	}
+	return &CreateDatabaseOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetDatabase.html
+func (g *Glue) GetDatabase(input GetDatabaseInput) (*GetDatabaseOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	database, awserr := g.lockedGetDatabase(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetDatabaseOutput{Database: database.output(g.arnGenerator.AwsAccountId)}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetDatabases.html
+func (g *Glue) GetDatabases(input GetDatabasesInput) (*GetDatabasesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, 100, 100, input.NextToken,
+		InvalidInputException("MaxResults must be between 1 and 100."),
+		InvalidInputException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	databases := make([]APIDatabase, 0, len(g.databases))
+	for _, database := range g.databases {
+		databases = append(databases, database.output(g.arnGenerator.AwsAccountId))
+	}
+	slices.SortFunc(databases, func(a, b APIDatabase) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &GetDatabasesOutput{}
+	output.DatabaseList, output.NextToken = pagination.Page(databases, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_UpdateDatabase.html
+func (g *Glue) UpdateDatabase(input UpdateDatabaseInput) (*UpdateDatabaseOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	database, awserr := g.lockedGetDatabase(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if !strings.EqualFold(input.DatabaseInput.Name, database.Name) {
+		return nil, InvalidInputException("Database cannot be renamed.")
+	}
+	input.DatabaseInput.Name = database.Name
+	database.APIDatabaseInput = input.DatabaseInput
+	return &UpdateDatabaseOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_DeleteDatabase.html
+func (g *Glue) DeleteDatabase(input DeleteDatabaseInput) (*DeleteDatabaseOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	database, awserr := g.lockedGetDatabase(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Its tables and their partitions are deleted with it.
+	delete(g.databases, database.Name)
+	return &DeleteDatabaseOutput{}, nil
+}
+
+func validateTableInput(input *APITableInput) *awserrors.Error {
+	name, awserr := validateCatalogName("Table", input.Name)
+	if awserr != nil {
+		return awserr
+	}
+	input.Name = name
+	seen := make(map[string]bool)
+	for _, key := range input.PartitionKeys {
+		if key.Name == "" {
+			return InvalidInputException("Partition key name must not be empty.")
+		}
+		if seen[strings.ToLower(key.Name)] {
+			return InvalidInputException("Duplicate partition key " + key.Name + ".")
+		}
+		seen[strings.ToLower(key.Name)] = true
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_CreateTable.html
+func (g *Glue) CreateTable(input CreateTableInput) (*CreateTableOutput, *awserrors.Error) {
+	if awserr := validateTableInput(&input.TableInput); awserr != nil {
+		return nil, awserr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	database, awserr := g.lockedGetDatabase(input.DatabaseName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, ok := database.tables[input.TableInput.Name]; ok {
+		return nil, AlreadyExistsException("Table already exists.")
+	}
+	g.checkLocation(input.TableInput.StorageDescriptor)
+
+	now := g.clock()
+	database.tables[input.TableInput.Name] = &Table{
+		APITableInput: input.TableInput,
+		DatabaseName:  database.Name,
+		Created:       now,
+		Updated:       now,
+		partitions:    make(map[string]*Partition),
+	}
+	return &CreateTableOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetTable.html
+func (g *Glue) GetTable(input GetTableInput) (*GetTableOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetTableOutput{Table: table.output(g.arnGenerator.AwsAccountId)}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetTables.html
+func (g *Glue) GetTables(input GetTablesInput) (*GetTablesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, 100, 100, input.NextToken,
+		InvalidInputException("MaxResults must be between 1 and 100."),
+		InvalidInputException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, awserr
+	}
+	var pattern *regexp.Regexp
+	if input.Expression != "" {
+		var err error
+		pattern, err = regexp.Compile("^(?:" + input.Expression + ")$")
+		if err != nil {
+			return nil, InvalidInputException("Invalid Expression: " + err.Error())
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	database, awserr := g.lockedGetDatabase(input.DatabaseName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	tables := make([]APITable, 0, len(database.tables))
+	for _, table := range database.tables {
+		if pattern != nil && !pattern.MatchString(table.Name) {
+			continue
+		}
+		tables = append(tables, table.output(g.arnGenerator.AwsAccountId))
+	}
+	slices.SortFunc(tables, func(a, b APITable) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &GetTablesOutput{}
+	output.TableList, output.NextToken = pagination.Page(tables, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_UpdateTable.html
+func (g *Glue) UpdateTable(input UpdateTableInput) (*UpdateTableOutput, *awserrors.Error) {
+	if awserr := validateTableInput(&input.TableInput); awserr != nil {
+		return nil, awserr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableInput.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.VersionId != "" && input.VersionId != strconv.Itoa(table.Version) {
+		return nil, ConcurrentModificationException("Table version " + input.VersionId + " is not the latest.")
+	}
+	if len(table.partitions) > 0 && !slices.EqualFunc(table.PartitionKeys, input.TableInput.PartitionKeys, func(a, b APIColumn) bool {
+		return strings.EqualFold(a.Name, b.Name)
+	}) {
+		return nil, InvalidInputException("Partition keys cannot be changed while the table has partitions.")
+	}
+	g.checkLocation(input.TableInput.StorageDescriptor)
+
+	table.APITableInput = input.TableInput
+	table.Updated = g.clock()
+	table.Version++
+	return &UpdateTableOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_DeleteTable.html
+func (g *Glue) DeleteTable(input DeleteTableInput) (*DeleteTableOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(g.databases[table.DatabaseName].tables, table.Name)
+	return &DeleteTableOutput{}, nil
+}
@@ -0,0 +1,205 @@
+package glue
+
+import (
+	"slices"
+	"testing"
+)
+
+func createTable(t *testing.T, g *Glue) {
+	_, awserr := g.CreateDatabase(CreateDatabaseInput{DatabaseInput: APIDatabaseInput{Name: "Sales"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = g.CreateTable(CreateTableInput{
+		DatabaseName: "sales",
+		TableInput: APITableInput{
+			Name: "Orders",
+			StorageDescriptor: &APIStorageDescriptor{
+				Columns:  []APIColumn{{Name: "id", Type: "string"}},
+				Location: "s3://data/orders/",
+			},
+			PartitionKeys: []APIColumn{{Name: "year", Type: "int"}, {Name: "region", Type: "string"}},
+			TableType:     "EXTERNAL_TABLE",
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestDatabasesAndTables(t *testing.T) {
+	g := newGlue()
+	createTable(t, g)
+
+	_, awserr := g.CreateDatabase(CreateDatabaseInput{DatabaseInput: APIDatabaseInput{Name: "sales"}})
+	if awserr == nil || awserr.Body.Type != "AlreadyExistsException" {
+		t.Fatal("Expected already exists", awserr)
+	}
+	databases, awserr := g.GetDatabases(GetDatabasesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(databases.DatabaseList) != 1 || databases.DatabaseList[0].Name != "sales" || databases.DatabaseList[0].CatalogId != "123456789012" {
+		t.Fatal("Unexpected databases", databases.DatabaseList)
+	}
+
+	got, awserr := g.GetTable(GetTableInput{DatabaseName: "Sales", Name: "ORDERS"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.Table.Name != "orders" || got.Table.DatabaseName != "sales" || got.Table.VersionId != "0" ||
+		got.Table.CreateTime != 1700000000 || got.Table.StorageDescriptor.Location != "s3://data/orders/" {
+		t.Fatal("Unexpected table", got.Table)
+	}
+
+	input := APITableInput{Name: "orders", PartitionKeys: got.Table.PartitionKeys, Description: "Orders"}
+	_, awserr = g.UpdateTable(UpdateTableInput{DatabaseName: "sales", TableInput: input, VersionId: "0"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = g.UpdateTable(UpdateTableInput{DatabaseName: "sales", TableInput: input, VersionId: "0"})
+	if awserr == nil || awserr.Body.Type != "ConcurrentModificationException" {
+		t.Fatal("Expected concurrent modification", awserr)
+	}
+
+	tables, awserr := g.GetTables(GetTablesInput{DatabaseName: "sales", Expression: "ord.*"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tables.TableList) != 1 || tables.TableList[0].Description != "Orders" || tables.TableList[0].VersionId != "1" {
+		t.Fatal("Unexpected tables", tables.TableList)
+	}
+	tables, awserr = g.GetTables(GetTablesInput{DatabaseName: "sales", Expression: "customers"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tables.TableList) != 0 {
+		t.Fatal("Unexpected tables", tables.TableList)
+	}
+
+	_, awserr = g.DeleteDatabase(DeleteDatabaseInput{Name: "sales"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = g.GetTable(GetTableInput{DatabaseName: "sales", Name: "orders"})
+	if awserr == nil || awserr.Body.Type != "EntityNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	g := newGlue()
+	createTable(t, g)
+
+	var inputs []APIPartitionInput
+	for _, values := range [][]string{{"2022", "eu"}, {"2023", "eu"}, {"2023", "us"}, {"2024", "us"}} {
+		inputs = append(inputs, APIPartitionInput{
+			Values:            values,
+			StorageDescriptor: &APIStorageDescriptor{Location: "s3://data/orders/year=" + values[0] + "/region=" + values[1] + "/"},
+		})
+	}
+	inputs = append(inputs, APIPartitionInput{Values: []string{"2023"}}, APIPartitionInput{Values: []string{"2022", "eu"}})
+	created, awserr := g.BatchCreatePartition(BatchCreatePartitionInput{DatabaseName: "sales", TableName: "orders", PartitionInputList: inputs})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(created.Errors) != 2 || created.Errors[0].ErrorDetail.ErrorCode != "InvalidInputException" ||
+		created.Errors[1].ErrorDetail.ErrorCode != "AlreadyExistsException" {
+		t.Fatal("Unexpected errors", created.Errors)
+	}
+
+	partition, awserr := g.GetPartition(GetPartitionInput{DatabaseName: "sales", TableName: "orders", PartitionValues: []string{"2023", "us"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if partition.Partition.StorageDescriptor.Location != "s3://data/orders/year=2023/region=us/" || partition.Partition.TableName != "orders" {
+		t.Fatal("Unexpected partition", partition.Partition)
+	}
+
+	for _, test := range []struct {
+		expression string
+		expected   [][]string
+	}{
+		{"", [][]string{{"2022", "eu"}, {"2023", "eu"}, {"2023", "us"}, {"2024", "us"}}},
+		{"year = 2023", [][]string{{"2023", "eu"}, {"2023", "us"}}},
+		{"year > '2022' AND region = 'us'", [][]string{{"2023", "us"}, {"2024", "us"}}},
+		{"year BETWEEN 2022 AND 2023 AND NOT region IN ('us')", [][]string{{"2022", "eu"}, {"2023", "eu"}}},
+		{"(year = 2022 OR year = 2024) and region like 'u%'", [][]string{{"2024", "us"}}},
+		{"region <> 'eu'", [][]string{{"2023", "us"}, {"2024", "us"}}},
+	} {
+		partitions, awserr := g.GetPartitions(GetPartitionsInput{DatabaseName: "sales", TableName: "orders", Expression: test.expression})
+		if awserr != nil {
+			t.Fatal(test.expression, awserr)
+		}
+		var values [][]string
+		for _, partition := range partitions.Partitions {
+			values = append(values, partition.Values)
+		}
+		if !slices.EqualFunc(values, test.expected, slices.Equal) {
+			t.Fatal("Unexpected partitions", test.expression, values)
+		}
+	}
+
+	_, awserr = g.GetPartitions(GetPartitionsInput{DatabaseName: "sales", TableName: "orders", Expression: "month = 1"})
+	if awserr == nil || awserr.Body.Type != "InvalidInputException" {
+		t.Fatal("Expected invalid input", awserr)
+	}
+
+	paged, awserr := g.GetPartitions(GetPartitionsInput{DatabaseName: "sales", TableName: "orders", MaxResults: 3})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(paged.Partitions) != 3 || paged.NextToken == "" {
+		t.Fatal("Unexpected page", paged)
+	}
+
+	deleted, awserr := g.BatchDeletePartition(BatchDeletePartitionInput{
+		DatabaseName:       "sales",
+		TableName:          "orders",
+		PartitionsToDelete: []APIPartitionValueList{{Values: []string{"2022", "eu"}}, {Values: []string{"2021", "eu"}}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(deleted.Errors) != 1 || deleted.Errors[0].ErrorDetail.ErrorCode != "EntityNotFoundException" {
+		t.Fatal("Unexpected errors", deleted.Errors)
+	}
+	got, awserr := g.BatchGetPartition(BatchGetPartitionInput{
+		DatabaseName:    "sales",
+		TableName:       "orders",
+		PartitionsToGet: []APIPartitionValueList{{Values: []string{"2022", "eu"}}, {Values: []string{"2024", "us"}}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(got.Partitions) != 1 || !slices.Equal(got.Partitions[0].Values, []string{"2024", "us"}) {
+		t.Fatal("Unexpected partitions", got.Partitions)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	g := newGlue()
+	createTable(t, g)
+	if _, err := g.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := g.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.GetDatabase(GetDatabaseInput{Name: "sales"}); awserr == nil || awserr.Body.Type != "EntityNotFoundException" {
+		t.Fatal("Expected the database not to be in eu-west-1", awserr)
+	}
+	createTable(t, europe)
+
+	registry, awserr := europe.CreateRegistry(CreateRegistryInput{RegistryName: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if registry.RegistryArn != "arn:aws:glue:eu-west-1:123456789012:registry/orders" {
+		t.Fatal("Unexpected ARN", registry.RegistryArn)
+	}
+	if _, awserr := g.GetRegistry(GetRegistryInput{RegistryId: APIRegistryId{RegistryName: "orders"}}); awserr == nil {
+		t.Fatal("Expected the registry not to be in us-east-1")
+	}
+}
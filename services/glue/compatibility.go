@@ -0,0 +1,479 @@
+package glue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Protobuf definitions aren't parsed, but must define a message or an enum.
+var protobufDefinitionRegex = regexp.MustCompile(`(^|\s)(message|enum)\s+\w+\s*\{`)
+
+// validateDefinition checks that the definition is a schema in the data format.
+func validateDefinition(dataFormat string, definition string) error {
+	switch dataFormat {
+	case "AVRO":
+		_, err := parseAvro(definition)
+		return err
+	case "JSON":
+		_, err := parseJSONSchema(definition)
+		return err
+	case "PROTOBUF":
+		if !protobufDefinitionRegex.MatchString(definition) {
+			return errors.New("no message or enum definition found")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported data format %s", dataFormat)
+}
+
+// normalizeDefinition returns the definition without insignificant whitespace, so equivalent
+// definitions can be compared.
+func normalizeDefinition(dataFormat string, definition string) string {
+	if dataFormat == "PROTOBUF" {
+		return strings.Join(strings.Fields(definition), " ")
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(definition)); err != nil {
+		return definition
+	}
+	return compacted.String()
+}
+
+// checkCompatibility checks that a new version with the definition is compatible with the schema's
+// versions, according to its compatibility mode. Protobuf compatibility isn't checked.
+// See https://docs.aws.amazon.com/glue/latest/dg/schema-registry.html#schema-registry-compatibility
+func checkCompatibility(schema *Schema, definition string) error {
+	var previous []*SchemaVersion
+	switch schema.Compatibility {
+	case "BACKWARD", "FORWARD", "FULL":
+		if latest := schema.latest(); latest != nil {
+			previous = append(previous, latest)
+		}
+	case "BACKWARD_ALL", "FORWARD_ALL", "FULL_ALL":
+		for _, version := range schema.versions {
+			if version.Status == "AVAILABLE" && version.Number >= schema.checkpoint {
+				previous = append(previous, version)
+			}
+		}
+	default:
+		return nil
+	}
+
+	backward := !strings.HasPrefix(schema.Compatibility, "FORWARD")
+	forward := !strings.HasPrefix(schema.Compatibility, "BACKWARD")
+	for _, version := range previous {
+		// Backward compatibility means consumers using the new version can read data written with
+		// the previous one, and forward compatibility means the reverse.
+		if backward {
+			if err := canRead(schema.DataFormat, definition, version.Definition); err != nil {
+				return fmt.Errorf("not backward compatible with version %d: %v", version.Number, err)
+			}
+		}
+		if forward {
+			if err := canRead(schema.DataFormat, version.Definition, definition); err != nil {
+				return fmt.Errorf("not forward compatible with version %d: %v", version.Number, err)
+			}
+		}
+	}
+	return nil
+}
+
+// canRead returns whether data written with the writer's definition can be read with the reader's.
+func canRead(dataFormat string, reader string, writer string) error {
+	switch dataFormat {
+	case "AVRO":
+		readerSchema, err := parseAvro(reader)
+		if err != nil {
+			return err
+		}
+		writerSchema, err := parseAvro(writer)
+		if err != nil {
+			return err
+		}
+		return avroCanRead(readerSchema, writerSchema, make(map[[2]*avroSchema]bool))
+	case "JSON":
+		readerSchema, err := parseJSONSchema(reader)
+		if err != nil {
+			return err
+		}
+		writerSchema, err := parseJSONSchema(writer)
+		if err != nil {
+			return err
+		}
+		return jsonSchemaCanRead(readerSchema, writerSchema, "$")
+	}
+	return nil
+}
+
+// avroSchema is a parsed Avro schema. Named types referenced by name are the same *avroSchema,
+// so recursive types are cyclic.
+type avroSchema struct {
+	// A primitive type, or record, enum, array, map, fixed or union.
+	Type string
+	// The full name of records, enums and fixed types.
+	Name     string
+	Fields   []avroField
+	Symbols  []string
+	Default  bool
+	Items    *avroSchema
+	Values   *avroSchema
+	Size     int
+	Branches []*avroSchema
+}
+
+type avroField struct {
+	Name       string
+	Type       *avroSchema
+	HasDefault bool
+}
+
+var avroPrimitives = []string{"null", "boolean", "int", "long", "float", "double", "bytes", "string"}
+
+// parseAvro parses an Avro schema. See https://avro.apache.org/docs/1.11.1/specification/
+func parseAvro(definition string) (*avroSchema, error) {
+	var raw any
+	if err := json.Unmarshal([]byte(definition), &raw); err != nil {
+		return nil, err
+	}
+	return (&avroParser{named: make(map[string]*avroSchema)}).parse(raw, "")
+}
+
+type avroParser struct {
+	// Keyed by full name.
+	named map[string]*avroSchema
+}
+
+func (p *avroParser) fullName(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *avroParser) parse(raw any, namespace string) (*avroSchema, error) {
+	switch raw := raw.(type) {
+	case string:
+		if slices.Contains(avroPrimitives, raw) {
+			return &avroSchema{Type: raw}, nil
+		}
+		if named, ok := p.named[p.fullName(raw, namespace)]; ok {
+			return named, nil
+		}
+		if named, ok := p.named[raw]; ok {
+			return named, nil
+		}
+		return nil, fmt.Errorf("undefined name: %q", raw)
+	case []any:
+		union := &avroSchema{Type: "union"}
+		for _, branch := range raw {
+			parsed, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if parsed.Type == "union" {
+				return nil, errors.New("unions may not immediately contain other unions")
+			}
+			union.Branches = append(union.Branches, parsed)
+		}
+		return union, nil
+	case map[string]any:
+		return p.parseComplex(raw, namespace)
+	}
+	return nil, fmt.Errorf("invalid schema: %v", raw)
+}
+
+func (p *avroParser) parseComplex(raw map[string]any, namespace string) (*avroSchema, error) {
+	typeName, _ := raw["type"].(string)
+	switch typeName {
+	case "record", "error", "enum", "fixed":
+		name, _ := raw["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s has no name", typeName)
+		}
+		if ns, ok := raw["namespace"].(string); ok {
+			namespace = ns
+		}
+		schema := &avroSchema{Type: typeName, Name: p.fullName(name, namespace)}
+		if typeName == "error" {
+			schema.Type = "record"
+		}
+		if _, ok := p.named[schema.Name]; ok {
+			return nil, fmt.Errorf("can't redefine: %s", schema.Name)
+		}
+		// Names are defined before their fields are parsed, so records can refer to themselves.
+		p.named[schema.Name] = schema
+		if i := strings.LastIndex(schema.Name, "."); i >= 0 {
+			namespace = schema.Name[:i]
+		}
+		return schema, p.parseNamed(schema, raw, namespace)
+	case "array":
+		items, err := p.parse(raw["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{Type: "array", Items: items}, nil
+	case "map":
+		values, err := p.parse(raw["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{Type: "map", Values: values}, nil
+	}
+	// Primitive types may also be objects, such as {"type": "long", "logicalType": "timestamp-millis"}.
+	if slices.Contains(avroPrimitives, typeName) {
+		return &avroSchema{Type: typeName}, nil
+	}
+	return nil, fmt.Errorf("unknown type: %v", raw["type"])
+}
+
+func (p *avroParser) parseNamed(schema *avroSchema, raw map[string]any, namespace string) error {
+	switch schema.Type {
+	case "record":
+		fields, ok := raw["fields"].([]any)
+		if !ok {
+			return fmt.Errorf("record %s has no fields", schema.Name)
+		}
+		for _, f := range fields {
+			field, ok := f.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid field in %s", schema.Name)
+			}
+			name, _ := field["name"].(string)
+			if name == "" {
+				return fmt.Errorf("field in %s has no name", schema.Name)
+			}
+			fieldType, err := p.parse(field["type"], namespace)
+			if err != nil {
+				return err
+			}
+			_, hasDefault := field["default"]
+			schema.Fields = append(schema.Fields, avroField{Name: name, Type: fieldType, HasDefault: hasDefault})
+		}
+	case "enum":
+		symbols, ok := raw["symbols"].([]any)
+		if !ok {
+			return fmt.Errorf("enum %s has no symbols", schema.Name)
+		}
+		for _, symbol := range symbols {
+			s, ok := symbol.(string)
+			if !ok {
+				return fmt.Errorf("invalid symbol in %s", schema.Name)
+			}
+			schema.Symbols = append(schema.Symbols, s)
+		}
+		_, schema.Default = raw["default"]
+	case "fixed":
+		size, ok := raw["size"].(float64)
+		if !ok {
+			return fmt.Errorf("fixed %s has no size", schema.Name)
+		}
+		schema.Size = int(size)
+	}
+	return nil
+}
+
+// avroPromotions are the writer types each reader type can read, besides its own.
+var avroPromotions = map[string][]string{
+	"long":   {"int"},
+	"float":  {"int", "long"},
+	"double": {"int", "long", "float"},
+	"string": {"bytes"},
+	"bytes":  {"string"},
+}
+
+// avroCanRead implements Avro's schema resolution rules. Pairs which are being checked
+// are assumed to be compatible, so recursive types terminate.
+func avroCanRead(reader *avroSchema, writer *avroSchema, checking map[[2]*avroSchema]bool) error {
+	pair := [2]*avroSchema{reader, writer}
+	if checking[pair] {
+		return nil
+	}
+	checking[pair] = true
+
+	if writer.Type == "union" {
+		for _, branch := range writer.Branches {
+			if err := avroCanRead(reader, branch, checking); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if reader.Type == "union" {
+		for _, branch := range reader.Branches {
+			if avroCanRead(branch, writer, checking) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("no branch of the reader's union can read %s", writer.describe())
+	}
+
+	if reader.Type != writer.Type {
+		if slices.Contains(avroPromotions[reader.Type], writer.Type) {
+			return nil
+		}
+		return fmt.Errorf("%s can't be read as %s", writer.describe(), reader.describe())
+	}
+	if (reader.Type == "record" || reader.Type == "enum" || reader.Type == "fixed") && reader.Name != writer.Name {
+		return fmt.Errorf("%s can't be read as %s", writer.describe(), reader.describe())
+	}
+
+	switch reader.Type {
+	case "record":
+		for _, field := range reader.Fields {
+			i := slices.IndexFunc(writer.Fields, func(f avroField) bool { return f.Name == field.Name })
+			if i < 0 {
+				if !field.HasDefault {
+					return fmt.Errorf("field %s.%s has no default value", reader.Name, field.Name)
+				}
+				continue
+			}
+			if err := avroCanRead(field.Type, writer.Fields[i].Type, checking); err != nil {
+				return fmt.Errorf("field %s.%s: %v", reader.Name, field.Name, err)
+			}
+		}
+	case "enum":
+		if reader.Default {
+			return nil
+		}
+		for _, symbol := range writer.Symbols {
+			if !slices.Contains(reader.Symbols, symbol) {
+				return fmt.Errorf("enum %s is missing symbol %s", reader.Name, symbol)
+			}
+		}
+	case "fixed":
+		if reader.Size != writer.Size {
+			return fmt.Errorf("fixed %s changed size", reader.Name)
+		}
+	case "array":
+		return avroCanRead(reader.Items, writer.Items, checking)
+	case "map":
+		return avroCanRead(reader.Values, writer.Values, checking)
+	}
+	return nil
+}
+
+func (s *avroSchema) describe() string {
+	if s.Name != "" {
+		return s.Type + " " + s.Name
+	}
+	return s.Type
+}
+
+// parseJSONSchema parses a JSON Schema document, which is an object or a boolean.
+func parseJSONSchema(definition string) (any, error) {
+	var schema any
+	if err := json.Unmarshal([]byte(definition), &schema); err != nil {
+		return nil, err
+	}
+	switch schema.(type) {
+	case map[string]any, bool:
+		return schema, nil
+	}
+	return nil, errors.New("a JSON schema must be an object or a boolean")
+}
+
+// jsonSchemaCanRead approximates whether every document valid against the writer's schema is valid
+// against the reader's. It compares types, required properties, closed objects, enums and array items.
+func jsonSchemaCanRead(reader any, writer any, path string) error {
+	readerObject, ok := reader.(map[string]any)
+	if !ok {
+		// true (or {}) accepts anything. false accepts nothing, which only false can be read as.
+		if reader == false && writer != false {
+			return fmt.Errorf("%s: no documents are allowed", path)
+		}
+		return nil
+	}
+	writerObject, ok := writer.(map[string]any)
+	if !ok {
+		if writer == false {
+			return nil
+		}
+		writerObject = map[string]any{}
+	}
+
+	readerTypes, writerTypes := jsonTypes(readerObject), jsonTypes(writerObject)
+	if len(readerTypes) > 0 {
+		if len(writerTypes) == 0 {
+			return fmt.Errorf("%s: type is restricted to %v", path, readerTypes)
+		}
+		for _, t := range writerTypes {
+			if !slices.Contains(readerTypes, t) && !(t == "integer" && slices.Contains(readerTypes, "number")) {
+				return fmt.Errorf("%s: type %s is no longer allowed", path, t)
+			}
+		}
+	}
+
+	if readerEnum, ok := readerObject["enum"].([]any); ok {
+		writerEnum, ok := writerObject["enum"].([]any)
+		if !ok {
+			return fmt.Errorf("%s: values are restricted to an enum", path)
+		}
+		for _, value := range writerEnum {
+			if !slices.ContainsFunc(readerEnum, func(v any) bool { return fmt.Sprint(v) == fmt.Sprint(value) }) {
+				return fmt.Errorf("%s: enum value %v is no longer allowed", path, value)
+			}
+		}
+	}
+
+	writerRequired := jsonStrings(writerObject["required"])
+	for _, name := range jsonStrings(readerObject["required"]) {
+		if !slices.Contains(writerRequired, name) {
+			return fmt.Errorf("%s: property %s is required", path, name)
+		}
+	}
+	readerProperties, _ := readerObject["properties"].(map[string]any)
+	writerProperties, _ := writerObject["properties"].(map[string]any)
+	for name, writerProperty := range writerProperties {
+		readerProperty, ok := readerProperties[name]
+		if !ok {
+			if readerObject["additionalProperties"] == false {
+				return fmt.Errorf("%s: property %s is no longer allowed", path, name)
+			}
+			continue
+		}
+		if err := jsonSchemaCanRead(readerProperty, writerProperty, path+"."+name); err != nil {
+			return err
+		}
+	}
+	for name, readerProperty := range readerProperties {
+		if _, ok := writerProperties[name]; !ok && writerObject["additionalProperties"] != false {
+			// The writer allowed any value for the property.
+			if err := jsonSchemaCanRead(readerProperty, true, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if readerItems, ok := readerObject["items"]; ok {
+		writerItems, ok := writerObject["items"]
+		if !ok {
+			writerItems = true
+		}
+		return jsonSchemaCanRead(readerItems, writerItems, path+"[]")
+	}
+	return nil
+}
+
+// jsonTypes returns the schema's types, which may be a string or a list.
+func jsonTypes(schema map[string]any) []string {
+	if t, ok := schema["type"].(string); ok {
+		return []string{t}
+	}
+	return jsonStrings(schema["type"])
+}
+
+func jsonStrings(v any) []string {
+	list, _ := v.([]any)
+	var strs []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
@@ -0,0 +1,96 @@
+package glue
+
+import "testing"
+
+func TestAvroCanRead(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		reader string
+		writer string
+		ok     bool
+	}{
+		{"promotion", `"long"`, `"int"`, true},
+		{"narrowing", `"int"`, `"long"`, false},
+		{"string to bytes", `"bytes"`, `"string"`, true},
+		{"union reader", `["null","string"]`, `"string"`, true},
+		{"union writer", `"string"`, `["null","string"]`, false},
+		{
+			"removed field",
+			`{"type":"record","name":"R","fields":[]}`,
+			`{"type":"record","name":"R","fields":[{"name":"a","type":"int"}]}`,
+			true,
+		},
+		{
+			"new field without default",
+			`{"type":"record","name":"R","fields":[{"name":"a","type":"int"}]}`,
+			`{"type":"record","name":"R","fields":[]}`,
+			false,
+		},
+		{
+			"new enum symbol",
+			`{"type":"enum","name":"E","symbols":["A"]}`,
+			`{"type":"enum","name":"E","symbols":["A","B"]}`,
+			false,
+		},
+		{
+			"new enum symbol with default",
+			`{"type":"enum","name":"E","symbols":["A"],"default":"A"}`,
+			`{"type":"enum","name":"E","symbols":["A","B"]}`,
+			true,
+		},
+		{
+			"recursive",
+			`{"type":"record","name":"Node","fields":[{"name":"next","type":["null","Node"]}]}`,
+			`{"type":"record","name":"Node","fields":[{"name":"next","type":["null","Node"]}]}`,
+			true,
+		},
+		{"arrays", `{"type":"array","items":"long"}`, `{"type":"array","items":"int"}`, true},
+		{"fixed size", `{"type":"fixed","name":"F","size":4}`, `{"type":"fixed","name":"F","size":8}`, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := canRead("AVRO", test.reader, test.writer)
+			if (err == nil) != test.ok {
+				t.Fatal("Unexpected result", err)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaCanRead(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		reader string
+		writer string
+		ok     bool
+	}{
+		{"same", `{"type":"string"}`, `{"type":"string"}`, true},
+		{"widened", `{"type":["string","null"]}`, `{"type":"string"}`, true},
+		{"narrowed", `{"type":"string"}`, `{"type":["string","null"]}`, false},
+		{"integer as number", `{"type":"number"}`, `{"type":"integer"}`, true},
+		{
+			"new required property",
+			`{"type":"object","properties":{"a":{"type":"string"}},"required":["a"]}`,
+			`{"type":"object","properties":{"a":{"type":"string"}}}`,
+			false,
+		},
+		{
+			"removed required property",
+			`{"type":"object","properties":{"a":{"type":"string"}}}`,
+			`{"type":"object","properties":{"a":{"type":"string"}},"required":["a"]}`,
+			true,
+		},
+		{
+			"closed content model",
+			`{"type":"object","properties":{},"additionalProperties":false}`,
+			`{"type":"object","properties":{"a":{"type":"string"}}}`,
+			false,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := canRead("JSON", test.reader, test.writer)
+			if (err == nil) != test.ok {
+				t.Fatal("Unexpected result", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package glue
+
+import "aws-in-a-box/awserrors"
+
+func AlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("AlreadyExistsException", message)
+}
+
+func ConcurrentModificationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ConcurrentModificationException", message)
+}
+
+func EntityNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("EntityNotFoundException", message)
+}
+
+func InvalidInputException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidInputException", message)
+}
@@ -0,0 +1,384 @@
+package glue
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/sqllike"
+)
+
+// GetPartitions filters partitions with a SQL-like expression over the partition keys, such as
+// year = '2023' AND month BETWEEN 1 AND 6.
+// See https://docs.aws.amazon.com/glue/latest/webapi/API_GetPartitions.html#Glue-GetPartitions-request-Expression
+
+// condition is a parsed expression, which matches partitions' values keyed by their lower case names.
+type condition interface {
+	matches(values map[string]string) bool
+}
+
+type andCondition []condition
+
+func (c andCondition) matches(values map[string]string) bool {
+	for _, operand := range c {
+		if !operand.matches(values) {
+			return false
+		}
+	}
+	return true
+}
+
+type orCondition []condition
+
+func (c orCondition) matches(values map[string]string) bool {
+	for _, operand := range c {
+		if operand.matches(values) {
+			return true
+		}
+	}
+	return false
+}
+
+type notCondition struct {
+	condition condition
+}
+
+func (c notCondition) matches(values map[string]string) bool {
+	return !c.condition.matches(values)
+}
+
+// operand is either a partition key or a literal.
+type operand struct {
+	column  string
+	literal string
+}
+
+func (o operand) value(values map[string]string) string {
+	if o.column != "" {
+		return values[o.column]
+	}
+	return o.literal
+}
+
+type comparison struct {
+	left     operand
+	operator string
+	right    operand
+	// Whether the partition key is numeric, so values are compared as numbers.
+	numeric bool
+}
+
+func (c comparison) matches(values map[string]string) bool {
+	cmp, ok := compareValues(c.left.value(values), c.right.value(values), c.numeric)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareValues(a string, b string, numeric bool) (int, bool) {
+	if numeric {
+		x, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return 0, false
+		}
+		y, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	return strings.Compare(a, b), true
+}
+
+type likeCondition struct {
+	operand operand
+	pattern *regexp.Regexp
+}
+
+func (c likeCondition) matches(values map[string]string) bool {
+	return c.pattern.MatchString(c.operand.value(values))
+}
+
+type nullCondition struct {
+	operand operand
+}
+
+func (c nullCondition) matches(values map[string]string) bool {
+	// Partitions have a value for every key, so only a missing key is null.
+	return c.operand.column != "" && values[c.operand.column] == ""
+}
+
+var numericTypes = []string{"tinyint", "smallint", "int", "integer", "bigint", "float", "double", "decimal"}
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+	// The types of the table's partition keys, keyed by their lower case names.
+	keyTypes map[string]string
+}
+
+func parsePartitionExpression(expression string, partitionKeys []APIColumn) (condition, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &expressionParser{tokens: tokens, keyTypes: make(map[string]string)}
+	for _, key := range partitionKeys {
+		p.keyTypes[strings.ToLower(key.Name)] = strings.ToLower(key.Type)
+	}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return c, nil
+}
+
+var expressionTokenRegex = regexp.MustCompile(`^(\s+|'(?:[^']|'')*'|"(?:[^"]|"")*"|` + "`[^`]+`" + `|-?\d+(?:\.\d+)?|[A-Za-z_][A-Za-z0-9_]*|<=|>=|<>|!=|[=<>(),])`)
+
+func tokenizeExpression(expression string) ([]string, error) {
+	var tokens []string
+	for expression != "" {
+		token := expressionTokenRegex.FindString(expression)
+		if token == "" {
+			return nil, fmt.Errorf("unexpected %q", expression)
+		}
+		expression = expression[len(token):]
+		if strings.TrimSpace(token) != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *expressionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// accept consumes the next token if it's the keyword or symbol.
+func (p *expressionParser) accept(token string) bool {
+	if strings.EqualFold(p.peek(), token) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) expect(token string) error {
+	if !p.accept(token) {
+		if p.peek() == "" {
+			return fmt.Errorf("expected %s at the end", token)
+		}
+		return fmt.Errorf("expected %s but got %q", token, p.peek())
+	}
+	return nil
+}
+
+func (p *expressionParser) parseOr() (condition, error) {
+	c, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	conditions := orCondition{c}
+	for p.accept("OR") {
+		c, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return conditions, nil
+}
+
+func (p *expressionParser) parseAnd() (condition, error) {
+	c, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	conditions := andCondition{c}
+	for p.accept("AND") {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return conditions, nil
+}
+
+func (p *expressionParser) parseNot() (condition, error) {
+	if p.accept("NOT") {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{c}, nil
+	}
+	if p.accept("(") {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+	return p.parsePredicate()
+}
+
+func (p *expressionParser) parsePredicate() (condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.accept("IS") {
+		negated := p.accept("NOT")
+		if err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return negate(nullCondition{left}, negated), nil
+	}
+
+	negated := p.accept("NOT")
+	switch {
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var conditions orCondition
+		for {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, p.comparison(left, "=", right))
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return negate(conditions, negated), nil
+	case p.accept("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return negate(andCondition{p.comparison(left, ">=", low), p.comparison(left, "<=", high)}, negated), nil
+	case p.accept("LIKE"):
+		pattern, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if pattern.column != "" {
+			return nil, errors.New("LIKE patterns must be literals")
+		}
+		return negate(likeCondition{left, sqllike.Regexp(pattern.literal)}, negated), nil
+	}
+	if negated {
+		return nil, fmt.Errorf("expected IN, BETWEEN or LIKE after NOT but got %q", p.peek())
+	}
+
+	operator := p.peek()
+	if !slices.Contains([]string{"=", "!=", "<>", "<", "<=", ">", ">="}, operator) {
+		return nil, fmt.Errorf("expected an operator but got %q", operator)
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return p.comparison(left, operator, right), nil
+}
+
+func negate(c condition, negated bool) condition {
+	if negated {
+		return notCondition{c}
+	}
+	return c
+}
+
+func (p *expressionParser) comparison(left operand, operator string, right operand) comparison {
+	numeric := slices.Contains(numericTypes, p.baseType(left.column)) ||
+		slices.Contains(numericTypes, p.baseType(right.column))
+	return comparison{left: left, operator: operator, right: right, numeric: numeric}
+}
+
+// baseType returns the key's type without parameters, such as decimal for decimal(10,2).
+func (p *expressionParser) baseType(column string) string {
+	typ, _, _ := strings.Cut(p.keyTypes[column], "(")
+	return typ
+}
+
+func (p *expressionParser) parseOperand() (operand, error) {
+	token := p.peek()
+	if token == "" {
+		return operand{}, errors.New("unexpected end of expression")
+	}
+	p.pos++
+	switch token[0] {
+	case '\'':
+		return operand{literal: strings.ReplaceAll(token[1:len(token)-1], "''", "'")}, nil
+	case '"':
+		return operand{literal: strings.ReplaceAll(token[1:len(token)-1], `""`, `"`)}, nil
+	case '`':
+		return p.column(token[1 : len(token)-1])
+	}
+	if token[0] == '-' || (token[0] >= '0' && token[0] <= '9') {
+		return operand{literal: token}, nil
+	}
+	if token == "(" || token == ")" || token == "," || strings.ContainsAny(token[:1], "=<>!") {
+		return operand{}, fmt.Errorf("unexpected %q", token)
+	}
+	return p.column(token)
+}
+
+func (p *expressionParser) column(name string) (operand, error) {
+	name = strings.ToLower(name)
+	if _, ok := p.keyTypes[name]; !ok {
+		return operand{}, fmt.Errorf("%s is not a partition key", name)
+	}
+	return operand{column: name}, nil
+}
@@ -0,0 +1,92 @@
+package glue
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/s3"
+)
+
+// Buckets is the local S3 service, which tables' data is expected to be in.
+type Buckets interface {
+	HeadBucket(input s3.HeadBucketInput) (*s3.HeadBucketOutput, *awserrors.Error)
+}
+
+type Glue struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	s3           Buckets
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu sync.Mutex
+	// Schema registries, keyed by name.
+	registries map[string]*Registry
+	// Keyed by ID, across all schemas.
+	schemaVersions map[string]*SchemaVersion
+	// Data Catalog databases, keyed by name.
+	databases map[string]*Database
+	// Shared by the instances of all regions, which each have their own schema registries and Data
+	// Catalog.
+	regions *region.Regions[*Glue]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// Optional. If set, table and partition locations in buckets which don't exist are logged.
+	S3 Buckets
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Glue {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// S3 is global, so every region checks locations in the same buckets.
+	g := newRegion(options)
+	g.regions = region.New(options.ArnGenerator.Region, options.Regions, g, func(name string) (*Glue, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = g.regions
+		return r, nil
+	})
+	return g
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *Glue {
+	return &Glue{
+		logger:         options.Logger,
+		arnGenerator:   options.ArnGenerator,
+		s3:             options.S3,
+		clock:          clock.Now,
+		registries:     make(map[string]*Registry),
+		schemaVersions: make(map[string]*SchemaVersion),
+		databases:      make(map[string]*Database),
+	}
+}
+
+// InRegion returns the schema registries and Data Catalog of the region, for services which query
+// the catalog. It fails if the region isn't one of Options.Regions.
+func (g *Glue) InRegion(name string) (*Glue, error) {
+	return g.regions.Get(name)
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,45 @@
+package glue
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AWSGlue"
+
+func (g *Glue) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchCreatePartition", region.Method(g.regions, (*Glue).BatchCreatePartition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchDeletePartition", region.Method(g.regions, (*Glue).BatchDeletePartition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "BatchGetPartition", region.Method(g.regions, (*Glue).BatchGetPartition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CheckSchemaVersionValidity", region.Method(g.regions, (*Glue).CheckSchemaVersionValidity))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateDatabase", region.Method(g.regions, (*Glue).CreateDatabase))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreatePartition", region.Method(g.regions, (*Glue).CreatePartition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateRegistry", region.Method(g.regions, (*Glue).CreateRegistry))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateSchema", region.Method(g.regions, (*Glue).CreateSchema))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateTable", region.Method(g.regions, (*Glue).CreateTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteDatabase", region.Method(g.regions, (*Glue).DeleteDatabase))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeletePartition", region.Method(g.regions, (*Glue).DeletePartition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteRegistry", region.Method(g.regions, (*Glue).DeleteRegistry))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteSchema", region.Method(g.regions, (*Glue).DeleteSchema))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteTable", region.Method(g.regions, (*Glue).DeleteTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetDatabase", region.Method(g.regions, (*Glue).GetDatabase))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetDatabases", region.Method(g.regions, (*Glue).GetDatabases))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetPartition", region.Method(g.regions, (*Glue).GetPartition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetPartitions", region.Method(g.regions, (*Glue).GetPartitions))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetRegistry", region.Method(g.regions, (*Glue).GetRegistry))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetSchema", region.Method(g.regions, (*Glue).GetSchema))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetSchemaByDefinition", region.Method(g.regions, (*Glue).GetSchemaByDefinition))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetSchemaVersion", region.Method(g.regions, (*Glue).GetSchemaVersion))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetTable", region.Method(g.regions, (*Glue).GetTable))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetTables", region.Method(g.regions, (*Glue).GetTables))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListRegistries", region.Method(g.regions, (*Glue).ListRegistries))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListSchemaVersions", region.Method(g.regions, (*Glue).ListSchemaVersions))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListSchemas", region.Method(g.regions, (*Glue).ListSchemas))
+	http.RegisterWithRequest(logger, methodRegistry, service, "RegisterSchemaVersion", region.Method(g.regions, (*Glue).RegisterSchemaVersion))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateDatabase", region.Method(g.regions, (*Glue).UpdateDatabase))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateRegistry", region.Method(g.regions, (*Glue).UpdateRegistry))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateSchema", region.Method(g.regions, (*Glue).UpdateSchema))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateTable", region.Method(g.regions, (*Glue).UpdateTable))
+}
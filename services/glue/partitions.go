@@ -0,0 +1,252 @@
+package glue
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+const maxBatchPartitions = 100
+
+// partitionKey identifies a partition by its values.
+func partitionKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+func (t *Table) partitionOutput(partition *Partition, catalogId string) APIPartition {
+	return APIPartition{
+		Values:            partition.Values,
+		DatabaseName:      t.DatabaseName,
+		TableName:         t.Name,
+		CreationTime:      timestamp.EpochSeconds(partition.Created),
+		LastAccessTime:    partition.LastAccessTime,
+		StorageDescriptor: partition.StorageDescriptor,
+		Parameters:        partition.Parameters,
+		LastAnalyzedTime:  partition.LastAnalyzedTime,
+		CatalogId:         catalogId,
+	}
+}
+
+func (t *Table) getPartition(values []string) (*Partition, *awserrors.Error) {
+	partition, ok := t.partitions[partitionKey(values)]
+	if !ok {
+		return nil, EntityNotFoundException("Cannot find partition.")
+	}
+	return partition, nil
+}
+
+func (g *Glue) lockedCreatePartition(table *Table, input APIPartitionInput) *awserrors.Error {
+	if len(input.Values) != len(table.PartitionKeys) {
+		return InvalidInputException("The number of partition keys do not match the number of partition values.")
+	}
+	key := partitionKey(input.Values)
+	if _, ok := table.partitions[key]; ok {
+		return AlreadyExistsException("Partition already exists.")
+	}
+	g.checkLocation(input.StorageDescriptor)
+	table.partitions[key] = &Partition{
+		APIPartitionInput: input,
+		Created:           g.clock(),
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_CreatePartition.html
+func (g *Glue) CreatePartition(input CreatePartitionInput) (*CreatePartitionOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := g.lockedCreatePartition(table, input.PartitionInput); awserr != nil {
+		return nil, awserr
+	}
+	return &CreatePartitionOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_BatchCreatePartition.html
+func (g *Glue) BatchCreatePartition(input BatchCreatePartitionInput) (*BatchCreatePartitionOutput, *awserrors.Error) {
+	if len(input.PartitionInputList) > maxBatchPartitions {
+		return nil, InvalidInputException(fmt.Sprintf("PartitionInputList can have at most %d partitions.", maxBatchPartitions))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Each partition succeeds or fails on its own.
+	output := &BatchCreatePartitionOutput{}
+	for _, partitionInput := range input.PartitionInputList {
+		if awserr := g.lockedCreatePartition(table, partitionInput); awserr != nil {
+			output.Errors = append(output.Errors, APIPartitionError{
+				PartitionValues: partitionInput.Values,
+				ErrorDetail:     APIErrorDetail{ErrorCode: awserr.Body.Type, ErrorMessage: awserr.Body.Message},
+			})
+		}
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetPartition.html
+func (g *Glue) GetPartition(input GetPartitionInput) (*GetPartitionOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	partition, awserr := table.getPartition(input.PartitionValues)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetPartitionOutput{Partition: table.partitionOutput(partition, g.arnGenerator.AwsAccountId)}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetPartitions.html
+func (g *Glue) GetPartitions(input GetPartitionsInput) (*GetPartitionsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, 100, 100, input.NextToken,
+		InvalidInputException("MaxResults must be between 1 and 100."),
+		InvalidInputException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Segment != nil {
+		if input.Segment.TotalSegments < 1 || input.Segment.TotalSegments > 10 {
+			return nil, InvalidInputException("TotalSegments must be between 1 and 10.")
+		}
+		if input.Segment.SegmentNumber < 0 || input.Segment.SegmentNumber >= input.Segment.TotalSegments {
+			return nil, InvalidInputException("SegmentNumber must be between 0 and TotalSegments - 1.")
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var filter condition
+	if input.Expression != "" {
+		var err error
+		filter, err = parsePartitionExpression(input.Expression, table.PartitionKeys)
+		if err != nil {
+			return nil, InvalidInputException("Unsupported expression: " + err.Error())
+		}
+	}
+
+	partitions := make([]*Partition, 0, len(table.partitions))
+	for _, partition := range table.partitions {
+		partitions = append(partitions, partition)
+	}
+	slices.SortFunc(partitions, func(a, b *Partition) int {
+		return slices.Compare(a.Values, b.Values)
+	})
+
+	var matching []APIPartition
+	for i, partition := range partitions {
+		if input.Segment != nil && i%input.Segment.TotalSegments != input.Segment.SegmentNumber {
+			continue
+		}
+		if filter != nil && !filter.matches(table.partitionValues(partition)) {
+			continue
+		}
+		output := table.partitionOutput(partition, g.arnGenerator.AwsAccountId)
+		if input.ExcludeColumnSchema && output.StorageDescriptor != nil {
+			storageDescriptor := *output.StorageDescriptor
+			storageDescriptor.Columns = nil
+			output.StorageDescriptor = &storageDescriptor
+		}
+		matching = append(matching, output)
+	}
+	output := &GetPartitionsOutput{}
+	output.Partitions, output.NextToken = pagination.Page(matching, limit, start)
+	return output, nil
+}
+
+// partitionValues returns the partition's values keyed by the lower case names of the table's partition keys.
+func (t *Table) partitionValues(partition *Partition) map[string]string {
+	values := make(map[string]string, len(t.PartitionKeys))
+	for i, key := range t.PartitionKeys {
+		if i < len(partition.Values) {
+			values[strings.ToLower(key.Name)] = partition.Values[i]
+		}
+	}
+	return values
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_BatchGetPartition.html
+func (g *Glue) BatchGetPartition(input BatchGetPartitionInput) (*BatchGetPartitionOutput, *awserrors.Error) {
+	if len(input.PartitionsToGet) > 1000 {
+		return nil, InvalidInputException("PartitionsToGet can have at most 1000 partitions.")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &BatchGetPartitionOutput{Partitions: []APIPartition{}}
+	for _, values := range input.PartitionsToGet {
+		// Missing partitions are left out, rather than being unprocessed.
+		if partition, awserr := table.getPartition(values.Values); awserr == nil {
+			output.Partitions = append(output.Partitions, table.partitionOutput(partition, g.arnGenerator.AwsAccountId))
+		}
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_DeletePartition.html
+func (g *Glue) DeletePartition(input DeletePartitionInput) (*DeletePartitionOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, awserr := table.getPartition(input.PartitionValues); awserr != nil {
+		return nil, awserr
+	}
+	delete(table.partitions, partitionKey(input.PartitionValues))
+	return &DeletePartitionOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_BatchDeletePartition.html
+func (g *Glue) BatchDeletePartition(input BatchDeletePartitionInput) (*BatchDeletePartitionOutput, *awserrors.Error) {
+	if len(input.PartitionsToDelete) > 25 {
+		return nil, InvalidInputException("PartitionsToDelete can have at most 25 partitions.")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	table, awserr := g.lockedGetTable(input.DatabaseName, input.TableName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &BatchDeletePartitionOutput{}
+	for _, values := range input.PartitionsToDelete {
+		if _, awserr := table.getPartition(values.Values); awserr != nil {
+			output.Errors = append(output.Errors, APIPartitionError{
+				PartitionValues: values.Values,
+				ErrorDetail:     APIErrorDetail{ErrorCode: awserr.Body.Type, ErrorMessage: awserr.Body.Message},
+			})
+			continue
+		}
+		delete(table.partitions, partitionKey(values.Values))
+	}
+	return output, nil
+}
@@ -0,0 +1,624 @@
+package glue
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	// Schemas created without a registry are added to this one, which is created when it's first used.
+	defaultRegistryName = "default-registry"
+	maxDefinitionLength = 170000
+	maxSchemaVersions   = 1000
+)
+
+var (
+	registryNameRegex  = regexp.MustCompile(`^[a-zA-Z0-9-_$#.]{1,255}$`)
+	dataFormats        = []string{"AVRO", "JSON", "PROTOBUF"}
+	compatibilityModes = []string{"NONE", "DISABLED", "BACKWARD", "BACKWARD_ALL", "FORWARD", "FORWARD_ALL", "FULL", "FULL_ALL"}
+)
+
+type Registry struct {
+	Name        string
+	Arn         string
+	Description string
+	Tags        map[string]string
+	Created     time.Time
+	Updated     time.Time
+	// Keyed by name.
+	schemas map[string]*Schema
+}
+
+type Schema struct {
+	Name          string
+	Arn           string
+	Description   string
+	DataFormat    string
+	Compatibility string
+	Tags          map[string]string
+	Created       time.Time
+	Updated       time.Time
+	registry      *Registry
+	// The version compatibility is checked from, for the *_ALL modes.
+	checkpoint int64
+	// In order of their version numbers, starting at 1.
+	versions []*SchemaVersion
+}
+
+type SchemaVersion struct {
+	Id         string
+	Number     int64
+	Definition string
+	// AVAILABLE, or FAILURE if the definition wasn't compatible with the previous versions.
+	Status  string
+	Created time.Time
+	schema  *Schema
+}
+
+// latest returns the latest available version, if there is one.
+func (s *Schema) latest() *SchemaVersion {
+	for i := len(s.versions) - 1; i >= 0; i-- {
+		if s.versions[i].Status == "AVAILABLE" {
+			return s.versions[i]
+		}
+	}
+	return nil
+}
+
+func (s *Schema) latestNumber() int64 {
+	if latest := s.latest(); latest != nil {
+		return latest.Number
+	}
+	return 0
+}
+
+func (s *Schema) output() *GetSchemaOutput {
+	return &GetSchemaOutput{
+		RegistryArn:         s.registry.Arn,
+		RegistryName:        s.registry.Name,
+		SchemaArn:           s.Arn,
+		SchemaName:          s.Name,
+		Description:         s.Description,
+		DataFormat:          s.DataFormat,
+		Compatibility:       s.Compatibility,
+		SchemaCheckpoint:    s.checkpoint,
+		LatestSchemaVersion: s.latestNumber(),
+		NextSchemaVersion:   int64(len(s.versions)) + 1,
+		SchemaStatus:        "AVAILABLE",
+		CreatedTime:         timestamp.ISO8601(s.Created),
+		UpdatedTime:         timestamp.ISO8601(s.Updated),
+	}
+}
+
+func (v *SchemaVersion) output() *GetSchemaVersionOutput {
+	return &GetSchemaVersionOutput{
+		SchemaVersionId:  v.Id,
+		SchemaDefinition: v.Definition,
+		DataFormat:       v.schema.DataFormat,
+		SchemaArn:        v.schema.Arn,
+		VersionNumber:    v.Number,
+		Status:           v.Status,
+		CreatedTime:      timestamp.ISO8601(v.Created),
+	}
+}
+
+// lockedGetRegistry returns the registry with the ID, or the default registry if the ID is nil.
+func (g *Glue) lockedGetRegistry(id *APIRegistryId) (*Registry, *awserrors.Error) {
+	name := defaultRegistryName
+	if id != nil && id.RegistryArn != "" {
+		var err error
+		name, err = g.arnGenerator.Resolve(id.RegistryArn, "glue", "registry")
+		if err != nil {
+			return nil, InvalidInputException(err.Error())
+		}
+	} else if id != nil && id.RegistryName != "" {
+		name = id.RegistryName
+	}
+	registry, ok := g.registries[name]
+	if !ok && name == defaultRegistryName {
+		registry = g.lockedCreateRegistry(name, "", nil)
+	} else if !ok {
+		return nil, EntityNotFoundException(fmt.Sprintf("Registry is not found. RegistryName: %s", name))
+	}
+	return registry, nil
+}
+
+func (g *Glue) lockedCreateRegistry(name string, description string, tags map[string]string) *Registry {
+	now := g.clock()
+	registry := &Registry{
+		Name:        name,
+		Arn:         g.arnGenerator.Generate("glue", "registry", name),
+		Description: description,
+		Tags:        copyTags(tags),
+		Created:     now,
+		Updated:     now,
+		schemas:     make(map[string]*Schema),
+	}
+	g.registries[name] = registry
+	return registry
+}
+
+func (g *Glue) lockedGetSchema(id APISchemaId) (*Schema, *awserrors.Error) {
+	var registryName, schemaName string
+	if id.SchemaArn != "" {
+		// Schema ARNs are arn:aws:glue:<region>:<account>:schema/<registry>/<schema>.
+		path, err := g.arnGenerator.Resolve(id.SchemaArn, "glue", "schema")
+		if err != nil {
+			return nil, InvalidInputException(err.Error())
+		}
+		registryName, schemaName, _ = strings.Cut(path, "/")
+	} else if id.SchemaName != "" && id.RegistryName != "" {
+		registryName, schemaName = id.RegistryName, id.SchemaName
+	} else {
+		return nil, InvalidInputException("SchemaId must contain either a SchemaArn, or a SchemaName and a RegistryName.")
+	}
+	registry, awserr := g.lockedGetRegistry(&APIRegistryId{RegistryName: registryName})
+	if awserr != nil {
+		return nil, awserr
+	}
+	schema, ok := registry.schemas[schemaName]
+	if !ok {
+		return nil, EntityNotFoundException(fmt.Sprintf("Schema is not found. RegistryName: %s, SchemaName: %s", registryName, schemaName))
+	}
+	return schema, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_CreateRegistry.html
+func (g *Glue) CreateRegistry(input CreateRegistryInput) (*CreateRegistryOutput, *awserrors.Error) {
+	if !registryNameRegex.MatchString(input.RegistryName) {
+		return nil, InvalidInputException("RegistryName must match [a-zA-Z0-9-_$#.]{1,255}.")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.registries[input.RegistryName]; ok {
+		return nil, AlreadyExistsException("Registry already exists. RegistryName: " + input.RegistryName)
+	}
+	registry := g.lockedCreateRegistry(input.RegistryName, input.Description, input.Tags)
+	return &CreateRegistryOutput{
+		RegistryArn:  registry.Arn,
+		RegistryName: registry.Name,
+		Description:  registry.Description,
+		Tags:         copyTags(registry.Tags),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetRegistry.html
+func (g *Glue) GetRegistry(input GetRegistryInput) (*GetRegistryOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	registry, awserr := g.lockedGetRegistry(&input.RegistryId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetRegistryOutput{
+		RegistryArn:  registry.Arn,
+		RegistryName: registry.Name,
+		Description:  registry.Description,
+		Status:       "AVAILABLE",
+		CreatedTime:  timestamp.ISO8601(registry.Created),
+		UpdatedTime:  timestamp.ISO8601(registry.Updated),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_ListRegistries.html
+func (g *Glue) ListRegistries(input ListRegistriesInput) (*ListRegistriesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, 25, 100, input.NextToken,
+		InvalidInputException("MaxResults must be between 1 and 100."),
+		InvalidInputException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var registries []APIRegistryListItem
+	for _, registry := range g.registries {
+		registries = append(registries, APIRegistryListItem{
+			RegistryArn:  registry.Arn,
+			RegistryName: registry.Name,
+			Description:  registry.Description,
+			Status:       "AVAILABLE",
+			CreatedTime:  timestamp.ISO8601(registry.Created),
+			UpdatedTime:  timestamp.ISO8601(registry.Updated),
+		})
+	}
+	slices.SortFunc(registries, func(a, b APIRegistryListItem) int {
+		return strings.Compare(a.RegistryName, b.RegistryName)
+	})
+	output := &ListRegistriesOutput{}
+	output.Registries, output.NextToken = pagination.Page(registries, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_UpdateRegistry.html
+func (g *Glue) UpdateRegistry(input UpdateRegistryInput) (*UpdateRegistryOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	registry, awserr := g.lockedGetRegistry(&input.RegistryId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	registry.Description = input.Description
+	registry.Updated = g.clock()
+	return &UpdateRegistryOutput{RegistryArn: registry.Arn, RegistryName: registry.Name}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_DeleteRegistry.html
+func (g *Glue) DeleteRegistry(input DeleteRegistryInput) (*DeleteRegistryOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	registry, awserr := g.lockedGetRegistry(&input.RegistryId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Deleting a registry deletes its schemas, rather than failing.
+	for _, schema := range registry.schemas {
+		g.lockedDeleteSchema(schema)
+	}
+	delete(g.registries, registry.Name)
+	return &DeleteRegistryOutput{RegistryArn: registry.Arn, RegistryName: registry.Name, Status: "DELETING"}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_CreateSchema.html
+func (g *Glue) CreateSchema(input CreateSchemaInput) (*CreateSchemaOutput, *awserrors.Error) {
+	if !registryNameRegex.MatchString(input.SchemaName) {
+		return nil, InvalidInputException("SchemaName must match [a-zA-Z0-9-_$#.]{1,255}.")
+	}
+	if !slices.Contains(dataFormats, input.DataFormat) {
+		return nil, InvalidInputException("DataFormat must be one of AVRO, JSON or PROTOBUF.")
+	}
+	if input.Compatibility == "" {
+		input.Compatibility = "BACKWARD"
+	}
+	if !slices.Contains(compatibilityModes, input.Compatibility) {
+		return nil, InvalidInputException("Compatibility must be one of " + strings.Join(compatibilityModes, ", ") + ".")
+	}
+	if input.SchemaDefinition != "" {
+		if err := validateDefinition(input.DataFormat, input.SchemaDefinition); err != nil {
+			return nil, InvalidInputException("Schema definition of " + input.DataFormat + " data format is invalid: " + err.Error())
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	registry, awserr := g.lockedGetRegistry(input.RegistryId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, ok := registry.schemas[input.SchemaName]; ok {
+		return nil, AlreadyExistsException(fmt.Sprintf("Schema already exists. SchemaName: %s, RegistryName: %s", input.SchemaName, registry.Name))
+	}
+
+	now := g.clock()
+	schema := &Schema{
+		Name:          input.SchemaName,
+		Arn:           g.arnGenerator.Generate("glue", "schema", registry.Name+"/"+input.SchemaName),
+		Description:   input.Description,
+		DataFormat:    input.DataFormat,
+		Compatibility: input.Compatibility,
+		Tags:          copyTags(input.Tags),
+		Created:       now,
+		Updated:       now,
+		registry:      registry,
+	}
+	registry.schemas[schema.Name] = schema
+
+	output := schema.output()
+	createOutput := &CreateSchemaOutput{
+		RegistryArn:   output.RegistryArn,
+		RegistryName:  output.RegistryName,
+		SchemaArn:     output.SchemaArn,
+		SchemaName:    output.SchemaName,
+		Description:   output.Description,
+		DataFormat:    output.DataFormat,
+		Compatibility: output.Compatibility,
+		SchemaStatus:  output.SchemaStatus,
+		Tags:          copyTags(schema.Tags),
+	}
+	if input.SchemaDefinition != "" {
+		version := g.lockedAddVersion(schema, input.SchemaDefinition, "AVAILABLE")
+		schema.checkpoint = version.Number
+		createOutput.SchemaVersionId = version.Id
+		createOutput.SchemaVersionStatus = version.Status
+	}
+	createOutput.SchemaCheckpoint = schema.checkpoint
+	createOutput.LatestSchemaVersion = schema.latestNumber()
+	createOutput.NextSchemaVersion = int64(len(schema.versions)) + 1
+	return createOutput, nil
+}
+
+func (g *Glue) lockedAddVersion(schema *Schema, definition string, status string) *SchemaVersion {
+	version := &SchemaVersion{
+		Id:         uuid.Must(uuid.NewV4()).String(),
+		Number:     int64(len(schema.versions)) + 1,
+		Definition: definition,
+		Status:     status,
+		Created:    g.clock(),
+		schema:     schema,
+	}
+	schema.versions = append(schema.versions, version)
+	schema.Updated = version.Created
+	g.schemaVersions[version.Id] = version
+	return version
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetSchema.html
+func (g *Glue) GetSchema(input GetSchemaInput) (*GetSchemaOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	schema, awserr := g.lockedGetSchema(input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return schema.output(), nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_ListSchemas.html
+func (g *Glue) ListSchemas(input ListSchemasInput) (*ListSchemasOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, 25, 100, input.NextToken,
+		InvalidInputException("MaxResults must be between 1 and 100."),
+		InvalidInputException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var registries []*Registry
+	if input.RegistryId != nil {
+		registry, awserr := g.lockedGetRegistry(input.RegistryId)
+		if awserr != nil {
+			return nil, awserr
+		}
+		registries = append(registries, registry)
+	} else {
+		for _, registry := range g.registries {
+			registries = append(registries, registry)
+		}
+	}
+
+	var schemas []APISchemaListItem
+	for _, registry := range registries {
+		for _, schema := range registry.schemas {
+			schemas = append(schemas, APISchemaListItem{
+				RegistryName: registry.Name,
+				SchemaArn:    schema.Arn,
+				SchemaName:   schema.Name,
+				Description:  schema.Description,
+				SchemaStatus: "AVAILABLE",
+				CreatedTime:  timestamp.ISO8601(schema.Created),
+				UpdatedTime:  timestamp.ISO8601(schema.Updated),
+			})
+		}
+	}
+	slices.SortFunc(schemas, func(a, b APISchemaListItem) int {
+		return strings.Compare(a.SchemaArn, b.SchemaArn)
+	})
+	output := &ListSchemasOutput{}
+	output.Schemas, output.NextToken = pagination.Page(schemas, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_UpdateSchema.html
+func (g *Glue) UpdateSchema(input UpdateSchemaInput) (*UpdateSchemaOutput, *awserrors.Error) {
+	if input.Compatibility != "" && !slices.Contains(compatibilityModes, input.Compatibility) {
+		return nil, InvalidInputException("Compatibility must be one of " + strings.Join(compatibilityModes, ", ") + ".")
+	}
+	if input.Compatibility == "" && input.Description == nil {
+		return nil, InvalidInputException("Compatibility or Description must be specified.")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	schema, awserr := g.lockedGetSchema(input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Compatibility != "" {
+		if input.SchemaVersionNumber == nil {
+			return nil, InvalidInputException("SchemaVersionNumber must be specified to update the compatibility.")
+		}
+		version, awserr := schema.version(*input.SchemaVersionNumber)
+		if awserr != nil {
+			return nil, awserr
+		}
+		schema.Compatibility = input.Compatibility
+		schema.checkpoint = version.Number
+	}
+	if input.Description != nil {
+		schema.Description = *input.Description
+	}
+	schema.Updated = g.clock()
+	return &UpdateSchemaOutput{RegistryName: schema.registry.Name, SchemaArn: schema.Arn, SchemaName: schema.Name}, nil
+}
+
+func (s *Schema) version(number APISchemaVersionNumber) (*SchemaVersion, *awserrors.Error) {
+	if number.LatestVersion {
+		if latest := s.latest(); latest != nil {
+			return latest, nil
+		}
+	} else if number.VersionNumber >= 1 && number.VersionNumber <= int64(len(s.versions)) {
+		return s.versions[number.VersionNumber-1], nil
+	}
+	return nil, EntityNotFoundException(fmt.Sprintf("Schema version is not found. SchemaArn: %s", s.Arn))
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_DeleteSchema.html
+func (g *Glue) DeleteSchema(input DeleteSchemaInput) (*DeleteSchemaOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	schema, awserr := g.lockedGetSchema(input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	g.lockedDeleteSchema(schema)
+	return &DeleteSchemaOutput{SchemaArn: schema.Arn, SchemaName: schema.Name, Status: "DELETING"}, nil
+}
+
+func (g *Glue) lockedDeleteSchema(schema *Schema) {
+	for _, version := range schema.versions {
+		delete(g.schemaVersions, version.Id)
+	}
+	delete(schema.registry.schemas, schema.Name)
+}
+
+// findVersion returns the schema's version with the same definition, if there is one.
+func findVersion(schema *Schema, definition string) *SchemaVersion {
+	normalized := normalizeDefinition(schema.DataFormat, definition)
+	for _, version := range schema.versions {
+		if normalizeDefinition(schema.DataFormat, version.Definition) == normalized {
+			return version
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_RegisterSchemaVersion.html
+func (g *Glue) RegisterSchemaVersion(input RegisterSchemaVersionInput) (*RegisterSchemaVersionOutput, *awserrors.Error) {
+	if input.SchemaDefinition == "" || len(input.SchemaDefinition) > maxDefinitionLength {
+		return nil, InvalidInputException(fmt.Sprintf("SchemaDefinition must be between 1 and %d characters.", maxDefinitionLength))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	schema, awserr := g.lockedGetSchema(input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if err := validateDefinition(schema.DataFormat, input.SchemaDefinition); err != nil {
+		return nil, InvalidInputException("Schema definition of " + schema.DataFormat + " data format is invalid: " + err.Error())
+	}
+	// Registering an existing definition returns its version, which is how serializers look up schemas.
+	if version := findVersion(schema, input.SchemaDefinition); version != nil {
+		return &RegisterSchemaVersionOutput{SchemaVersionId: version.Id, VersionNumber: version.Number, Status: version.Status}, nil
+	}
+	if schema.Compatibility == "DISABLED" {
+		return nil, InvalidInputException("Compatibility DISABLED does not allow versioning. SchemaId: SchemaId(schemaArn=" + schema.Arn + ")")
+	}
+	if len(schema.versions) >= maxSchemaVersions {
+		return nil, InvalidInputException(fmt.Sprintf("A schema can have at most %d versions.", maxSchemaVersions))
+	}
+
+	status := "AVAILABLE"
+	if err := checkCompatibility(schema, input.SchemaDefinition); err != nil {
+		// Incompatible versions are still created, but can't be used.
+		g.logger.Info("Schema version is incompatible", "schema", schema.Arn, "error", err)
+		status = "FAILURE"
+	}
+	version := g.lockedAddVersion(schema, input.SchemaDefinition, status)
+	if schema.checkpoint == 0 && status == "AVAILABLE" {
+		schema.checkpoint = version.Number
+	}
+	return &RegisterSchemaVersionOutput{SchemaVersionId: version.Id, VersionNumber: version.Number, Status: version.Status}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetSchemaVersion.html
+func (g *Glue) GetSchemaVersion(input GetSchemaVersionInput) (*GetSchemaVersionOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if input.SchemaVersionId != "" {
+		version, ok := g.schemaVersions[input.SchemaVersionId]
+		if !ok {
+			return nil, EntityNotFoundException("Schema version is not found. SchemaVersionId: " + input.SchemaVersionId)
+		}
+		return version.output(), nil
+	}
+	if input.SchemaId == nil || input.SchemaVersionNumber == nil {
+		return nil, InvalidInputException("SchemaVersionId, or SchemaId and SchemaVersionNumber, must be specified.")
+	}
+	schema, awserr := g.lockedGetSchema(*input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	version, awserr := schema.version(*input.SchemaVersionNumber)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return version.output(), nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_GetSchemaByDefinition.html
+func (g *Glue) GetSchemaByDefinition(input GetSchemaByDefinitionInput) (*GetSchemaByDefinitionOutput, *awserrors.Error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	schema, awserr := g.lockedGetSchema(input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	version := findVersion(schema, input.SchemaDefinition)
+	if version == nil {
+		return nil, EntityNotFoundException("Schema is not found.")
+	}
+	return &GetSchemaByDefinitionOutput{
+		SchemaVersionId: version.Id,
+		SchemaArn:       schema.Arn,
+		DataFormat:      schema.DataFormat,
+		Status:          version.Status,
+		CreatedTime:     timestamp.ISO8601(version.Created),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_ListSchemaVersions.html
+func (g *Glue) ListSchemaVersions(input ListSchemaVersionsInput) (*ListSchemaVersionsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, 25, 100, input.NextToken,
+		InvalidInputException("MaxResults must be between 1 and 100."),
+		InvalidInputException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	schema, awserr := g.lockedGetSchema(input.SchemaId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	versions := make([]APISchemaVersionListItem, 0, len(schema.versions))
+	for _, version := range schema.versions {
+		versions = append(versions, APISchemaVersionListItem{
+			SchemaArn:       schema.Arn,
+			SchemaVersionId: version.Id,
+			VersionNumber:   version.Number,
+			Status:          version.Status,
+			CreatedTime:     timestamp.ISO8601(version.Created),
+		})
+	}
+	output := &ListSchemaVersionsOutput{}
+	output.Schemas, output.NextToken = pagination.Page(versions, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/glue/latest/webapi/API_CheckSchemaVersionValidity.html
+func (g *Glue) CheckSchemaVersionValidity(input CheckSchemaVersionValidityInput) (*CheckSchemaVersionValidityOutput, *awserrors.Error) {
+	if !slices.Contains(dataFormats, input.DataFormat) {
+		return nil, InvalidInputException("DataFormat must be one of AVRO, JSON or PROTOBUF.")
+	}
+	if err := validateDefinition(input.DataFormat, input.SchemaDefinition); err != nil {
+		return &CheckSchemaVersionValidityOutput{Valid: false, Error: err.Error()}, nil
+	}
+	return &CheckSchemaVersionValidityOutput{Valid: true}, nil
+}
@@ -0,0 +1,249 @@
+package glue
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+func newGlue() *Glue {
+	g := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+	})
+	g.clock = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	return g
+}
+
+const userV1 = `{"type":"record","name":"User","fields":[{"name":"id","type":"string"}]}`
+
+func createSchema(t *testing.T, g *Glue, input CreateSchemaInput) *CreateSchemaOutput {
+	output, awserr := g.CreateSchema(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func registerVersion(t *testing.T, g *Glue, schemaName string, definition string) *RegisterSchemaVersionOutput {
+	output, awserr := g.RegisterSchemaVersion(RegisterSchemaVersionInput{
+		SchemaId:         APISchemaId{SchemaName: schemaName, RegistryName: defaultRegistryName},
+		SchemaDefinition: definition,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestRegistries(t *testing.T) {
+	g := newGlue()
+	created, awserr := g.CreateRegistry(CreateRegistryInput{RegistryName: "events", Description: "Events"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.RegistryArn != "arn:aws:glue:us-east-1:123456789012:registry/events" {
+		t.Fatal("Unexpected ARN", created.RegistryArn)
+	}
+	_, awserr = g.CreateRegistry(CreateRegistryInput{RegistryName: "events"})
+	if awserr == nil || awserr.Body.Type != "AlreadyExistsException" {
+		t.Fatal("Expected already exists", awserr)
+	}
+
+	_, awserr = g.UpdateRegistry(UpdateRegistryInput{RegistryId: APIRegistryId{RegistryArn: created.RegistryArn}, Description: "Updated"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	got, awserr := g.GetRegistry(GetRegistryInput{RegistryId: APIRegistryId{RegistryName: "events"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.Description != "Updated" || got.Status != "AVAILABLE" || got.CreatedTime != "2023-11-14T22:13:20Z" {
+		t.Fatal("Unexpected registry", got)
+	}
+
+	createSchema(t, g, CreateSchemaInput{
+		RegistryId:       &APIRegistryId{RegistryName: "events"},
+		SchemaName:       "user",
+		DataFormat:       "AVRO",
+		SchemaDefinition: userV1,
+	})
+	_, awserr = g.DeleteRegistry(DeleteRegistryInput{RegistryId: APIRegistryId{RegistryName: "events"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = g.GetSchema(GetSchemaInput{SchemaId: APISchemaId{RegistryName: "events", SchemaName: "user"}})
+	if awserr == nil || awserr.Body.Type != "EntityNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+	if len(g.schemaVersions) != 0 {
+		t.Fatal("Expected versions to be deleted", g.schemaVersions)
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	g := newGlue()
+	created := createSchema(t, g, CreateSchemaInput{
+		SchemaName:       "user",
+		DataFormat:       "AVRO",
+		SchemaDefinition: userV1,
+	})
+	if created.RegistryName != defaultRegistryName || created.Compatibility != "BACKWARD" ||
+		created.LatestSchemaVersion != 1 || created.NextSchemaVersion != 2 || created.SchemaVersionStatus != "AVAILABLE" {
+		t.Fatal("Unexpected schema", created)
+	}
+
+	_, awserr := g.CreateSchema(CreateSchemaInput{SchemaName: "invalid", DataFormat: "AVRO", SchemaDefinition: `{"type":"record"}`})
+	if awserr == nil || awserr.Body.Type != "InvalidInputException" {
+		t.Fatal("Expected invalid input", awserr)
+	}
+
+	listed, awserr := g.ListSchemas(ListSchemasInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.Schemas) != 1 || listed.Schemas[0].SchemaArn != created.SchemaArn {
+		t.Fatal("Unexpected schemas", listed.Schemas)
+	}
+
+	_, awserr = g.UpdateSchema(UpdateSchemaInput{SchemaId: APISchemaId{SchemaArn: created.SchemaArn}, Compatibility: "NONE"})
+	if awserr == nil || awserr.Body.Type != "InvalidInputException" {
+		t.Fatal("Expected a version number to be required", awserr)
+	}
+	_, awserr = g.UpdateSchema(UpdateSchemaInput{
+		SchemaId:            APISchemaId{SchemaArn: created.SchemaArn},
+		SchemaVersionNumber: &APISchemaVersionNumber{LatestVersion: true},
+		Compatibility:       "NONE",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	got, awserr := g.GetSchema(GetSchemaInput{SchemaId: APISchemaId{SchemaArn: created.SchemaArn}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.Compatibility != "NONE" {
+		t.Fatal("Unexpected compatibility", got.Compatibility)
+	}
+
+	_, awserr = g.DeleteSchema(DeleteSchemaInput{SchemaId: APISchemaId{SchemaArn: created.SchemaArn}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = g.GetSchemaVersion(GetSchemaVersionInput{SchemaVersionId: created.SchemaVersionId})
+	if awserr == nil || awserr.Body.Type != "EntityNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestRegisterSchemaVersion(t *testing.T) {
+	g := newGlue()
+	created := createSchema(t, g, CreateSchemaInput{SchemaName: "user", DataFormat: "AVRO", SchemaDefinition: userV1})
+
+	// Registering the same definition, formatted differently, returns the existing version.
+	existing := registerVersion(t, g, "user", `{"type": "record", "name": "User", "fields": [{"name": "id", "type": "string"}]}`)
+	if existing.SchemaVersionId != created.SchemaVersionId || existing.VersionNumber != 1 {
+		t.Fatal("Expected the existing version", existing)
+	}
+
+	// Adding a field with a default is backward compatible.
+	v2 := registerVersion(t, g, "user", `{"type":"record","name":"User","fields":[
+		{"name":"id","type":"string"},
+		{"name":"email","type":["null","string"],"default":null}]}`)
+	if v2.VersionNumber != 2 || v2.Status != "AVAILABLE" {
+		t.Fatal("Unexpected version", v2)
+	}
+
+	// Adding a field without a default isn't.
+	v3 := registerVersion(t, g, "user", `{"type":"record","name":"User","fields":[
+		{"name":"id","type":"string"},
+		{"name":"age","type":"int"}]}`)
+	if v3.VersionNumber != 3 || v3.Status != "FAILURE" {
+		t.Fatal("Unexpected version", v3)
+	}
+
+	got, awserr := g.GetSchemaVersion(GetSchemaVersionInput{
+		SchemaId:            &APISchemaId{SchemaArn: created.SchemaArn},
+		SchemaVersionNumber: &APISchemaVersionNumber{LatestVersion: true},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.SchemaVersionId != v2.SchemaVersionId {
+		t.Fatal("Expected the latest available version", got)
+	}
+
+	byDefinition, awserr := g.GetSchemaByDefinition(GetSchemaByDefinitionInput{
+		SchemaId:         APISchemaId{SchemaArn: created.SchemaArn},
+		SchemaDefinition: userV1,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if byDefinition.SchemaVersionId != created.SchemaVersionId {
+		t.Fatal("Unexpected version", byDefinition)
+	}
+
+	listed, awserr := g.ListSchemaVersions(ListSchemaVersionsInput{SchemaId: APISchemaId{SchemaArn: created.SchemaArn}, MaxResults: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.Schemas) != 2 || listed.NextToken == "" {
+		t.Fatal("Unexpected page", listed)
+	}
+	listed, awserr = g.ListSchemaVersions(ListSchemaVersionsInput{SchemaId: APISchemaId{SchemaArn: created.SchemaArn}, NextToken: listed.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.Schemas) != 1 || listed.Schemas[0].Status != "FAILURE" || listed.NextToken != "" {
+		t.Fatal("Unexpected page", listed)
+	}
+
+	_, awserr = g.UpdateSchema(UpdateSchemaInput{
+		SchemaId:            APISchemaId{SchemaArn: created.SchemaArn},
+		SchemaVersionNumber: &APISchemaVersionNumber{LatestVersion: true},
+		Compatibility:       "DISABLED",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = g.RegisterSchemaVersion(RegisterSchemaVersionInput{
+		SchemaId:         APISchemaId{SchemaArn: created.SchemaArn},
+		SchemaDefinition: `{"type":"record","name":"Other","fields":[]}`,
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidInputException" {
+		t.Fatal("Expected versioning to be disabled", awserr)
+	}
+}
+
+func TestCheckSchemaVersionValidity(t *testing.T) {
+	g := newGlue()
+	for _, test := range []struct {
+		dataFormat string
+		definition string
+		valid      bool
+	}{
+		{"AVRO", userV1, true},
+		{"AVRO", `{"type":"record","name":"User","fields":[{"name":"id","type":"Missing"}]}`, false},
+		{"JSON", `{"type":"object","properties":{"id":{"type":"string"}}}`, true},
+		{"JSON", `{"type":`, false},
+		{"PROTOBUF", `syntax = "proto3"; message User { string id = 1; }`, true},
+		{"PROTOBUF", `syntax = "proto3";`, false},
+	} {
+		output, awserr := g.CheckSchemaVersionValidity(CheckSchemaVersionValidityInput{
+			DataFormat:       test.dataFormat,
+			SchemaDefinition: test.definition,
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if output.Valid != test.valid {
+			t.Fatal("Unexpected validity", test.definition, output)
+		}
+	}
+}
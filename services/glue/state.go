@@ -0,0 +1,180 @@
+package glue
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type schemaState struct {
+	*Schema
+	Checkpoint int64
+	// In order of their version numbers.
+	Versions []*SchemaVersion
+}
+
+type registryState struct {
+	*Registry
+	// Sorted by name.
+	Schemas []schemaState
+}
+
+type tableState struct {
+	*Table
+	// Sorted by their values.
+	Partitions []*Partition
+}
+
+type databaseState struct {
+	*Database
+	// Sorted by name.
+	Tables []tableState
+}
+
+type gluesState struct {
+	Registries []registryState
+	Databases  []databaseState
+}
+
+// TryPause pauses changes to each region's registries and Data Catalog while a snapshot copies them.
+func (g *Glue) TryPause() (func(), bool) {
+	return g.regions.TryPause(func(g *Glue) *sync.Mutex { return &g.mu })
+}
+
+// ExportState writes each region's schema registries with their schemas and versions, and its Data
+// Catalog's databases with their tables and partitions, to the archive.
+func (g *Glue) ExportState(w *state.Writer) error {
+	return g.regions.ExportState(w, (*Glue).exportState)
+}
+
+func (g *Glue) exportState(w *state.Writer) error {
+	unlock := w.Lock(&g.mu)
+	defer unlock()
+
+	var exported gluesState
+	for _, registry := range g.registries {
+		r := registryState{Registry: registry}
+		for _, schema := range registry.schemas {
+			r.Schemas = append(r.Schemas, schemaState{
+				Schema:     schema,
+				Checkpoint: schema.checkpoint,
+				Versions:   schema.versions,
+			})
+		}
+		slices.SortFunc(r.Schemas, func(a, b schemaState) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.Registries = append(exported.Registries, r)
+	}
+	slices.SortFunc(exported.Registries, func(a, b registryState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, database := range g.databases {
+		d := databaseState{Database: database}
+		for _, table := range database.tables {
+			t := tableState{Table: table}
+			for _, partition := range table.partitions {
+				t.Partitions = append(t.Partitions, partition)
+			}
+			slices.SortFunc(t.Partitions, func(a, b *Partition) int {
+				return strings.Compare(partitionKey(a.Values), partitionKey(b.Values))
+			})
+			d.Tables = append(d.Tables, t)
+		}
+		slices.SortFunc(d.Tables, func(a, b tableState) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.Databases = append(exported.Databases, d)
+	}
+	slices.SortFunc(exported.Databases, func(a, b databaseState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("glue.json", exported)
+}
+
+// ImportState replaces each region's schema registries and Data Catalog with the archive's.
+func (g *Glue) ImportState(r *state.Reader) (func() error, error) {
+	return g.regions.ImportState(r, (*Glue).importState, (*Glue).exportState)
+}
+
+func (g *Glue) importState(r *state.Reader) (func() error, error) {
+	var imported gluesState
+	if err := r.ReadJSON("glue.json", &imported); err != nil {
+		return nil, err
+	}
+
+	registries := make(map[string]*Registry)
+	schemaVersions := make(map[string]*SchemaVersion)
+	for _, rs := range imported.Registries {
+		registry := rs.Registry
+		if registry == nil || !registryNameRegex.MatchString(registry.Name) || registry.Arn == "" {
+			return nil, fmt.Errorf("invalid registry")
+		}
+		if registry.Tags == nil {
+			registry.Tags = make(map[string]string)
+		}
+		registry.schemas = make(map[string]*Schema)
+		for _, s := range rs.Schemas {
+			schema := s.Schema
+			if schema == nil || schema.Name == "" || schema.Arn == "" {
+				return nil, fmt.Errorf("registry %s: invalid schema", registry.Name)
+			}
+			if schema.Tags == nil {
+				schema.Tags = make(map[string]string)
+			}
+			schema.registry = registry
+			schema.checkpoint = s.Checkpoint
+			schema.versions = nil
+			for i, version := range s.Versions {
+				if version == nil || version.Id == "" || version.Number != int64(i+1) {
+					return nil, fmt.Errorf("schema %s: versions aren't numbered in order", schema.Arn)
+				}
+				version.schema = schema
+				schema.versions = append(schema.versions, version)
+				schemaVersions[version.Id] = version
+			}
+			registry.schemas[schema.Name] = schema
+		}
+		registries[registry.Name] = registry
+	}
+
+	databases := make(map[string]*Database)
+	for _, ds := range imported.Databases {
+		database := ds.Database
+		if database == nil || database.Name == "" {
+			return nil, fmt.Errorf("invalid database")
+		}
+		if database.Tags == nil {
+			database.Tags = make(map[string]string)
+		}
+		database.tables = make(map[string]*Table)
+		for _, ts := range ds.Tables {
+			table := ts.Table
+			if table == nil || table.Name == "" {
+				return nil, fmt.Errorf("database %s: invalid table", database.Name)
+			}
+			table.DatabaseName = database.Name
+			table.partitions = make(map[string]*Partition)
+			for _, partition := range ts.Partitions {
+				if partition == nil {
+					return nil, fmt.Errorf("table %s.%s: invalid partition", database.Name, table.Name)
+				}
+				table.partitions[partitionKey(partition.Values)] = partition
+			}
+			database.tables[table.Name] = table
+		}
+		databases[database.Name] = database
+	}
+
+	return func() error {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.registries = registries
+		g.schemaVersions = schemaVersions
+		g.databases = databases
+		return nil
+	}, nil
+}
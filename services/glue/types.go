@@ -0,0 +1,619 @@
+package glue
+
+import "encoding/json"
+
+type APIRegistryId struct {
+	RegistryArn  string `json:",omitempty" length:"1,10240" error:"InvalidInputException"`
+	RegistryName string `json:",omitempty" length:"1,255" pattern:"[a-zA-Z0-9-_$#.]+" error:"InvalidInputException"`
+}
+
+type APISchemaId struct {
+	RegistryName string `json:",omitempty" length:"1,255" pattern:"[a-zA-Z0-9-_$#.]+" error:"InvalidInputException"`
+	SchemaArn    string `json:",omitempty" length:"1,10240" error:"InvalidInputException"`
+	SchemaName   string `json:",omitempty" length:"1,255" pattern:"[a-zA-Z0-9-_$#.]+" error:"InvalidInputException"`
+}
+
+type APISchemaVersionNumber struct {
+	LatestVersion bool  `json:",omitempty"`
+	VersionNumber int64 `json:",omitempty" range:"1,100000" error:"InvalidInputException"`
+}
+
+type CreateRegistryInput struct {
+	RegistryName string            `required:"true" length:"1,255" pattern:"[a-zA-Z0-9-_$#.]+" error:"InvalidInputException"`
+	Description  string            `length:",2048" error:"InvalidInputException"`
+	Tags         map[string]string `length:",50" error:"InvalidInputException"`
+}
+
+type CreateRegistryOutput struct {
+	RegistryArn  string
+	RegistryName string
+	Description  string            `json:",omitempty"`
+	Tags         map[string]string `json:",omitempty"`
+}
+
+type GetRegistryInput struct {
+	RegistryId APIRegistryId
+}
+
+type GetRegistryOutput struct {
+	RegistryArn  string
+	RegistryName string
+	Description  string `json:",omitempty"`
+	Status       string
+	CreatedTime  string
+	UpdatedTime  string
+}
+
+type ListRegistriesInput struct {
+	MaxResults int `range:"1,100" error:"InvalidInputException"`
+	NextToken  string
+}
+
+type ListRegistriesOutput struct {
+	Registries []APIRegistryListItem
+	NextToken  string `json:",omitempty"`
+}
+
+type APIRegistryListItem struct {
+	RegistryArn  string
+	RegistryName string
+	Description  string `json:",omitempty"`
+	Status       string
+	CreatedTime  string
+	UpdatedTime  string
+}
+
+type UpdateRegistryInput struct {
+	RegistryId  APIRegistryId
+	Description string `required:"true" length:",2048" error:"InvalidInputException"`
+}
+
+type UpdateRegistryOutput struct {
+	RegistryArn  string
+	RegistryName string
+}
+
+type DeleteRegistryInput struct {
+	RegistryId APIRegistryId
+}
+
+type DeleteRegistryOutput struct {
+	RegistryArn  string
+	RegistryName string
+	Status       string
+}
+
+type CreateSchemaInput struct {
+	RegistryId       *APIRegistryId
+	SchemaName       string            `required:"true" length:"1,255" pattern:"[a-zA-Z0-9-_$#.]+" error:"InvalidInputException"`
+	DataFormat       string            `required:"true" enum:"AVRO|JSON|PROTOBUF" error:"InvalidInputException"`
+	Compatibility    string            `enum:"NONE|DISABLED|BACKWARD|BACKWARD_ALL|FORWARD|FORWARD_ALL|FULL|FULL_ALL" error:"InvalidInputException"`
+	Description      string            `length:",2048" error:"InvalidInputException"`
+	Tags             map[string]string `length:",50" error:"InvalidInputException"`
+	SchemaDefinition string            `length:"1,170000" error:"InvalidInputException"`
+}
+
+type CreateSchemaOutput struct {
+	RegistryArn         string
+	RegistryName        string
+	SchemaArn           string
+	SchemaName          string
+	Description         string `json:",omitempty"`
+	DataFormat          string
+	Compatibility       string
+	SchemaCheckpoint    int64
+	LatestSchemaVersion int64
+	NextSchemaVersion   int64
+	SchemaStatus        string
+	Tags                map[string]string `json:",omitempty"`
+	SchemaVersionId     string            `json:",omitempty"`
+	SchemaVersionStatus string            `json:",omitempty"`
+}
+
+type GetSchemaInput struct {
+	SchemaId APISchemaId
+}
+
+type GetSchemaOutput struct {
+	RegistryArn         string
+	RegistryName        string
+	SchemaArn           string
+	SchemaName          string
+	Description         string `json:",omitempty"`
+	DataFormat          string
+	Compatibility       string
+	SchemaCheckpoint    int64
+	LatestSchemaVersion int64
+	NextSchemaVersion   int64
+	SchemaStatus        string
+	CreatedTime         string
+	UpdatedTime         string
+}
+
+type ListSchemasInput struct {
+	RegistryId *APIRegistryId
+	MaxResults int `range:"1,100" error:"InvalidInputException"`
+	NextToken  string
+}
+
+type ListSchemasOutput struct {
+	Schemas   []APISchemaListItem
+	NextToken string `json:",omitempty"`
+}
+
+type APISchemaListItem struct {
+	RegistryName string
+	SchemaArn    string
+	SchemaName   string
+	Description  string `json:",omitempty"`
+	SchemaStatus string
+	CreatedTime  string
+	UpdatedTime  string
+}
+
+type UpdateSchemaInput struct {
+	SchemaId            APISchemaId
+	SchemaVersionNumber *APISchemaVersionNumber
+	Compatibility       string  `enum:"NONE|DISABLED|BACKWARD|BACKWARD_ALL|FORWARD|FORWARD_ALL|FULL|FULL_ALL" error:"InvalidInputException"`
+	Description         *string `length:",2048" error:"InvalidInputException"`
+}
+
+type UpdateSchemaOutput struct {
+	RegistryName string
+	SchemaArn    string
+	SchemaName   string
+}
+
+type DeleteSchemaInput struct {
+	SchemaId APISchemaId
+}
+
+type DeleteSchemaOutput struct {
+	SchemaArn  string
+	SchemaName string
+	Status     string
+}
+
+type RegisterSchemaVersionInput struct {
+	SchemaId         APISchemaId
+	SchemaDefinition string `required:"true" length:"1,170000" error:"InvalidInputException"`
+}
+
+type RegisterSchemaVersionOutput struct {
+	SchemaVersionId string
+	VersionNumber   int64
+	Status          string
+}
+
+type GetSchemaVersionInput struct {
+	SchemaId            *APISchemaId
+	SchemaVersionId     string `length:"36,36" pattern:"[a-z0-9]{8}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{12}" error:"InvalidInputException"`
+	SchemaVersionNumber *APISchemaVersionNumber
+}
+
+type GetSchemaVersionOutput struct {
+	SchemaVersionId  string
+	SchemaDefinition string
+	DataFormat       string
+	SchemaArn        string
+	VersionNumber    int64
+	Status           string
+	CreatedTime      string
+}
+
+type GetSchemaByDefinitionInput struct {
+	SchemaId         APISchemaId
+	SchemaDefinition string `required:"true" length:"1,170000" error:"InvalidInputException"`
+}
+
+type GetSchemaByDefinitionOutput struct {
+	SchemaVersionId string
+	SchemaArn       string
+	DataFormat      string
+	Status          string
+	CreatedTime     string
+}
+
+type ListSchemaVersionsInput struct {
+	SchemaId   APISchemaId
+	MaxResults int `range:"1,100" error:"InvalidInputException"`
+	NextToken  string
+}
+
+type ListSchemaVersionsOutput struct {
+	Schemas   []APISchemaVersionListItem
+	NextToken string `json:",omitempty"`
+}
+
+type APISchemaVersionListItem struct {
+	SchemaArn       string
+	SchemaVersionId string
+	VersionNumber   int64
+	Status          string
+	CreatedTime     string
+}
+
+type CheckSchemaVersionValidityInput struct {
+	DataFormat       string `required:"true" enum:"AVRO|JSON|PROTOBUF" error:"InvalidInputException"`
+	SchemaDefinition string `required:"true" length:"1,170000" error:"InvalidInputException"`
+}
+
+type CheckSchemaVersionValidityOutput struct {
+	Valid bool
+	Error string `json:",omitempty"`
+}
+
+type APIColumn struct {
+	Name       string            `required:"true" length:"1,255" error:"InvalidInputException"`
+	Type       string            `json:",omitempty" length:",131072" error:"InvalidInputException"`
+	Comment    string            `json:",omitempty" length:",255" error:"InvalidInputException"`
+	Parameters map[string]string `json:",omitempty"`
+}
+
+type APISerDeInfo struct {
+	Name                 string            `json:",omitempty"`
+	SerializationLibrary string            `json:",omitempty"`
+	Parameters           map[string]string `json:",omitempty"`
+}
+
+type APIOrder struct {
+	Column    string `required:"true" length:"1,255" error:"InvalidInputException"`
+	SortOrder int    `range:"0,1" error:"InvalidInputException"`
+}
+
+type APISkewedInfo struct {
+	SkewedColumnNames             []string          `json:",omitempty"`
+	SkewedColumnValues            []string          `json:",omitempty"`
+	SkewedColumnValueLocationMaps map[string]string `json:",omitempty"`
+}
+
+type APISchemaReference struct {
+	SchemaId            *APISchemaId `json:",omitempty"`
+	SchemaVersionId     string       `json:",omitempty" length:"36,36" pattern:"[a-z0-9]{8}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{12}" error:"InvalidInputException"`
+	SchemaVersionNumber int64        `json:",omitempty" range:"1,100000" error:"InvalidInputException"`
+}
+
+type APIStorageDescriptor struct {
+	Columns                []APIColumn         `json:",omitempty"`
+	Location               string              `json:",omitempty" length:",2056" error:"InvalidInputException"`
+	AdditionalLocations    []string            `json:",omitempty"`
+	InputFormat            string              `json:",omitempty" length:",128" error:"InvalidInputException"`
+	OutputFormat           string              `json:",omitempty" length:",128" error:"InvalidInputException"`
+	Compressed             bool                `json:",omitempty"`
+	NumberOfBuckets        int                 `json:",omitempty"`
+	SerdeInfo              *APISerDeInfo       `json:",omitempty"`
+	BucketColumns          []string            `json:",omitempty"`
+	SortColumns            []APIOrder          `json:",omitempty"`
+	Parameters             map[string]string   `json:",omitempty"`
+	SkewedInfo             *APISkewedInfo      `json:",omitempty"`
+	StoredAsSubDirectories bool                `json:",omitempty"`
+	SchemaReference        *APISchemaReference `json:",omitempty"`
+}
+
+type APIDataLakePrincipal struct {
+	DataLakePrincipalIdentifier string `json:",omitempty"`
+}
+
+type APIPrincipalPermissions struct {
+	Principal   *APIDataLakePrincipal `json:",omitempty"`
+	Permissions []string              `json:",omitempty"`
+}
+
+type APIDatabaseIdentifier struct {
+	CatalogId    string `json:",omitempty"`
+	DatabaseName string `json:",omitempty"`
+	Region       string `json:",omitempty"`
+}
+
+type APIFederatedDatabase struct {
+	Identifier     string `json:",omitempty"`
+	ConnectionName string `json:",omitempty"`
+}
+
+type APIDatabaseInput struct {
+	Name                          string                    `required:"true" length:"1,255" error:"InvalidInputException"`
+	Description                   string                    `json:",omitempty" length:",2048" error:"InvalidInputException"`
+	LocationUri                   string                    `json:",omitempty" length:"1,1024" error:"InvalidInputException"`
+	Parameters                    map[string]string         `json:",omitempty"`
+	CreateTableDefaultPermissions []APIPrincipalPermissions `json:",omitempty"`
+	TargetDatabase                *APIDatabaseIdentifier    `json:",omitempty"`
+	FederatedDatabase             *APIFederatedDatabase     `json:",omitempty"`
+}
+
+type APIDatabase struct {
+	Name                          string
+	Description                   string                    `json:",omitempty"`
+	LocationUri                   string                    `json:",omitempty"`
+	Parameters                    map[string]string         `json:",omitempty"`
+	CreateTime                    float64                   `json:",omitempty"`
+	CreateTableDefaultPermissions []APIPrincipalPermissions `json:",omitempty"`
+	TargetDatabase                *APIDatabaseIdentifier    `json:",omitempty"`
+	CatalogId                     string
+	FederatedDatabase             *APIFederatedDatabase `json:",omitempty"`
+}
+
+type APITableIdentifier struct {
+	CatalogId    string `json:",omitempty"`
+	DatabaseName string `json:",omitempty"`
+	Name         string `json:",omitempty"`
+	Region       string `json:",omitempty"`
+}
+
+type APITableInput struct {
+	Name              string                `required:"true" length:"1,255" error:"InvalidInputException"`
+	Description       string                `json:",omitempty" length:",2048" error:"InvalidInputException"`
+	Owner             string                `json:",omitempty" length:"1,255" error:"InvalidInputException"`
+	LastAccessTime    float64               `json:",omitempty"`
+	LastAnalyzedTime  float64               `json:",omitempty"`
+	Retention         int                   `json:",omitempty" range:"0," error:"InvalidInputException"`
+	StorageDescriptor *APIStorageDescriptor `json:",omitempty"`
+	PartitionKeys     []APIColumn           `json:",omitempty"`
+	ViewOriginalText  string                `json:",omitempty" length:",409600" error:"InvalidInputException"`
+	ViewExpandedText  string                `json:",omitempty" length:",409600" error:"InvalidInputException"`
+	TableType         string                `json:",omitempty" length:",255" error:"InvalidInputException"`
+	Parameters        map[string]string     `json:",omitempty"`
+	TargetTable       *APITableIdentifier   `json:",omitempty"`
+	// Not interpreted, so it's kept as is.
+	ViewDefinition json.RawMessage `json:",omitempty"`
+}
+
+type APITable struct {
+	Name                          string
+	DatabaseName                  string
+	Description                   string                `json:",omitempty"`
+	Owner                         string                `json:",omitempty"`
+	CreateTime                    float64               `json:",omitempty"`
+	UpdateTime                    float64               `json:",omitempty"`
+	LastAccessTime                float64               `json:",omitempty"`
+	LastAnalyzedTime              float64               `json:",omitempty"`
+	Retention                     int                   `json:",omitempty"`
+	StorageDescriptor             *APIStorageDescriptor `json:",omitempty"`
+	PartitionKeys                 []APIColumn
+	ViewOriginalText              string              `json:",omitempty"`
+	ViewExpandedText              string              `json:",omitempty"`
+	TableType                     string              `json:",omitempty"`
+	Parameters                    map[string]string   `json:",omitempty"`
+	CreatedBy                     string              `json:",omitempty"`
+	IsRegisteredWithLakeFormation bool                `json:",omitempty"`
+	TargetTable                   *APITableIdentifier `json:",omitempty"`
+	CatalogId                     string
+	VersionId                     string
+	ViewDefinition                json.RawMessage `json:",omitempty"`
+}
+
+type APIPartitionIndex struct {
+	Keys      []string `required:"true" length:"1," error:"InvalidInputException"`
+	IndexName string   `required:"true" length:"1,255" error:"InvalidInputException"`
+}
+
+type APIIcebergInput struct {
+	MetadataOperation string `required:"true" enum:"CREATE" error:"InvalidInputException"`
+	Version           string `json:",omitempty"`
+}
+
+type APIOpenTableFormatInput struct {
+	IcebergInput *APIIcebergInput `json:",omitempty"`
+}
+
+type APIPartitionInput struct {
+	Values            []string
+	LastAccessTime    float64               `json:",omitempty"`
+	StorageDescriptor *APIStorageDescriptor `json:",omitempty"`
+	Parameters        map[string]string     `json:",omitempty"`
+	LastAnalyzedTime  float64               `json:",omitempty"`
+}
+
+type APIPartition struct {
+	Values            []string
+	DatabaseName      string
+	TableName         string
+	CreationTime      float64
+	LastAccessTime    float64               `json:",omitempty"`
+	StorageDescriptor *APIStorageDescriptor `json:",omitempty"`
+	Parameters        map[string]string     `json:",omitempty"`
+	LastAnalyzedTime  float64               `json:",omitempty"`
+	CatalogId         string
+}
+
+type APIPartitionValueList struct {
+	Values []string `required:"true" error:"InvalidInputException"`
+}
+
+type APIErrorDetail struct {
+	ErrorCode    string
+	ErrorMessage string
+}
+
+type APIPartitionError struct {
+	PartitionValues []string
+	ErrorDetail     APIErrorDetail
+}
+
+type APISegment struct {
+	SegmentNumber int `range:"0," error:"InvalidInputException"`
+	TotalSegments int `required:"true" range:"1,10" error:"InvalidInputException"`
+}
+
+type CreateDatabaseInput struct {
+	CatalogId     string `length:"1,255" error:"InvalidInputException"`
+	DatabaseInput APIDatabaseInput
+	Tags          map[string]string `length:",50" error:"InvalidInputException"`
+}
+
+type CreateDatabaseOutput struct{}
+
+type GetDatabaseInput struct {
+	CatalogId string `length:"1,255" error:"InvalidInputException"`
+	Name      string `required:"true" length:"1,255" error:"InvalidInputException"`
+}
+
+type GetDatabaseOutput struct {
+	Database APIDatabase
+}
+
+type GetDatabasesInput struct {
+	CatalogId         string `length:"1,255" error:"InvalidInputException"`
+	NextToken         string
+	MaxResults        int      `range:"1,100" error:"InvalidInputException"`
+	ResourceShareType string   `enum:"FOREIGN|ALL|FEDERATED" error:"InvalidInputException"`
+	AttributesToGet   []string `enum:"NAME|TABLE_NAME" error:"InvalidInputException"`
+}
+
+type GetDatabasesOutput struct {
+	DatabaseList []APIDatabase
+	NextToken    string `json:",omitempty"`
+}
+
+type UpdateDatabaseInput struct {
+	CatalogId     string `length:"1,255" error:"InvalidInputException"`
+	Name          string `required:"true" length:"1,255" error:"InvalidInputException"`
+	DatabaseInput APIDatabaseInput
+}
+
+type UpdateDatabaseOutput struct{}
+
+type DeleteDatabaseInput struct {
+	CatalogId string `length:"1,255" error:"InvalidInputException"`
+	Name      string `required:"true" length:"1,255" error:"InvalidInputException"`
+}
+
+type DeleteDatabaseOutput struct{}
+
+type CreateTableInput struct {
+	CatalogId            string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName         string `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableInput           APITableInput
+	PartitionIndexes     []APIPartitionIndex `length:",3" error:"InvalidInputException"`
+	TransactionId        string              `length:"1,255" error:"InvalidInputException"`
+	OpenTableFormatInput *APIOpenTableFormatInput
+}
+
+type CreateTableOutput struct{}
+
+type GetTableInput struct {
+	CatalogId     string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName  string `required:"true" length:"1,255" error:"InvalidInputException"`
+	Name          string `required:"true" length:"1,255" error:"InvalidInputException"`
+	TransactionId string `length:"1,255" error:"InvalidInputException"`
+	QueryAsOfTime float64
+}
+
+type GetTableOutput struct {
+	Table APITable
+}
+
+type GetTablesInput struct {
+	CatalogId     string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName  string `required:"true" length:"1,255" error:"InvalidInputException"`
+	Expression    string `length:",2048" error:"InvalidInputException"`
+	NextToken     string
+	MaxResults    int    `range:"1,100" error:"InvalidInputException"`
+	TransactionId string `length:"1,255" error:"InvalidInputException"`
+	QueryAsOfTime float64
+}
+
+type GetTablesOutput struct {
+	TableList []APITable
+	NextToken string `json:",omitempty"`
+}
+
+type UpdateTableInput struct {
+	CatalogId     string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName  string `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableInput    APITableInput
+	SkipArchive   bool
+	TransactionId string `length:"1,255" error:"InvalidInputException"`
+	VersionId     string `length:"1,255" error:"InvalidInputException"`
+}
+
+type UpdateTableOutput struct{}
+
+type DeleteTableInput struct {
+	CatalogId     string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName  string `required:"true" length:"1,255" error:"InvalidInputException"`
+	Name          string `required:"true" length:"1,255" error:"InvalidInputException"`
+	TransactionId string `length:"1,255" error:"InvalidInputException"`
+}
+
+type DeleteTableOutput struct{}
+
+type CreatePartitionInput struct {
+	CatalogId      string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName   string `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName      string `required:"true" length:"1,255" error:"InvalidInputException"`
+	PartitionInput APIPartitionInput
+}
+
+type CreatePartitionOutput struct{}
+
+type BatchCreatePartitionInput struct {
+	CatalogId          string              `length:"1,255" error:"InvalidInputException"`
+	DatabaseName       string              `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName          string              `required:"true" length:"1,255" error:"InvalidInputException"`
+	PartitionInputList []APIPartitionInput `required:"true" length:",100" error:"InvalidInputException"`
+}
+
+type BatchCreatePartitionOutput struct {
+	Errors []APIPartitionError `json:",omitempty"`
+}
+
+type GetPartitionInput struct {
+	CatalogId       string   `length:"1,255" error:"InvalidInputException"`
+	DatabaseName    string   `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName       string   `required:"true" length:"1,255" error:"InvalidInputException"`
+	PartitionValues []string `required:"true" length:",100" error:"InvalidInputException"`
+}
+
+type GetPartitionOutput struct {
+	Partition APIPartition
+}
+
+type GetPartitionsInput struct {
+	CatalogId           string `length:"1,255" error:"InvalidInputException"`
+	DatabaseName        string `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName           string `required:"true" length:"1,255" error:"InvalidInputException"`
+	Expression          string `length:",2048" error:"InvalidInputException"`
+	NextToken           string
+	Segment             *APISegment
+	MaxResults          int `range:"1,1000" error:"InvalidInputException"`
+	ExcludeColumnSchema bool
+	TransactionId       string `length:"1,255" error:"InvalidInputException"`
+	QueryAsOfTime       float64
+}
+
+type GetPartitionsOutput struct {
+	Partitions []APIPartition
+	NextToken  string `json:",omitempty"`
+}
+
+type BatchGetPartitionInput struct {
+	CatalogId       string                  `length:"1,255" error:"InvalidInputException"`
+	DatabaseName    string                  `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName       string                  `required:"true" length:"1,255" error:"InvalidInputException"`
+	PartitionsToGet []APIPartitionValueList `required:"true" length:",1000" error:"InvalidInputException"`
+}
+
+type BatchGetPartitionOutput struct {
+	Partitions      []APIPartition
+	UnprocessedKeys []APIPartitionValueList `json:",omitempty"`
+}
+
+type DeletePartitionInput struct {
+	CatalogId       string   `length:"1,255" error:"InvalidInputException"`
+	DatabaseName    string   `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName       string   `required:"true" length:"1,255" error:"InvalidInputException"`
+	PartitionValues []string `required:"true" length:",100" error:"InvalidInputException"`
+}
+
+type DeletePartitionOutput struct{}
+
+type BatchDeletePartitionInput struct {
+	CatalogId          string                  `length:"1,255" error:"InvalidInputException"`
+	DatabaseName       string                  `required:"true" length:"1,255" error:"InvalidInputException"`
+	TableName          string                  `required:"true" length:"1,255" error:"InvalidInputException"`
+	PartitionsToDelete []APIPartitionValueList `required:"true" length:",25" error:"InvalidInputException"`
+}
+
+type BatchDeletePartitionOutput struct {
+	Errors []APIPartitionError `json:",omitempty"`
+}
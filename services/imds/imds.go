@@ -0,0 +1,322 @@
+package imds
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/services/sts"
+)
+
+// This package emulates the EC2 instance metadata service (IMDS), so applications which get their
+// region or credentials from it, like the SDKs' default credential chains, work outside of EC2.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instancedata-data-retrieval.html
+
+const (
+	tokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	tokenHeader    = "X-aws-ec2-metadata-token"
+	maxTokenTTL    = 6 * time.Hour
+	// Credentials are refreshed this long before they expire, since clients refresh them
+	// shortly before their expiration too.
+	credentialsRefreshWindow = 15 * time.Minute
+	instanceType             = "t3.micro"
+	imageId                  = "ami-0123456789abcdef0"
+	privateIP                = "10.0.0.1"
+)
+
+// Credentials is the local STS service, which issues the credentials of the instance's role.
+type Credentials interface {
+	AssumeRole(input sts.AssumeRoleInput) (*sts.AssumeRoleOutput, *awserrors.Error)
+}
+
+type IMDS struct {
+	logger        *slog.Logger
+	arnGenerator  arn.Generator
+	sts           Credentials
+	roleName      string
+	requireTokens bool
+	instanceId    string
+	// The ID of the instance profile holding the role.
+	instanceProfileId string
+	launchTime        time.Time
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu sync.Mutex
+	// Session tokens, mapped to when they expire.
+	tokens      map[string]time.Time
+	credentials *sts.APICredentials
+	// When the credentials were issued.
+	lastUpdated time.Time
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// If nil, the instance has no role, so there are no credentials.
+	STS      Credentials
+	RoleName string
+	// If set, only IMDSv2 requests, which have a session token, are served.
+	RequireTokens bool
+}
+
+func New(options Options) *IMDS {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	return &IMDS{
+		logger:            options.Logger,
+		arnGenerator:      options.ArnGenerator,
+		sts:               options.STS,
+		roleName:          options.RoleName,
+		requireTokens:     options.RequireTokens,
+		instanceId:        "i-" + randomHex(17),
+		instanceProfileId: "AIPA" + strings.ToUpper(randomHex(17)),
+		launchTime:        time.Now().UTC().Truncate(time.Second),
+		clock:             clock.Now,
+		tokens:            make(map[string]time.Time),
+	}
+}
+
+func randomHex(length int) string {
+	b := make([]byte, (length+1)/2)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)[:length]
+}
+
+// Serve serves IMDS requests on the listener until it's closed.
+func (m *IMDS) Serve(listener net.Listener) error {
+	err := http.Serve(listener, m)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (m *IMDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/latest/api/token" {
+		m.handleToken(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !m.checkToken(r) {
+		m.logger.Debug("Rejected request without a valid token", "path", r.URL.Path)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entries := m.entries()
+	path := strings.TrimPrefix(r.URL.Path, "/latest/")
+	if entry, ok := entries[path]; ok {
+		value, err := entry()
+		if err != nil {
+			m.logger.Error("Reading metadata", "path", path, "err", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		m.logger.Debug("Served metadata", "path", path)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(value))
+		return
+	}
+
+	// Directories list their entries, with a trailing slash for subdirectories.
+	dir := path
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	var children []string
+	for name := range entries {
+		rest, ok := strings.CutPrefix(name, dir)
+		if !ok {
+			continue
+		}
+		child, _, isDir := strings.Cut(rest, "/")
+		if isDir {
+			child += "/"
+		}
+		if !slices.Contains(children, child) {
+			children = append(children, child)
+		}
+	}
+	if len(children) == 0 {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	slices.Sort(children)
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(strings.Join(children, "\n")))
+}
+
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/configuring-instance-metadata-service.html
+func (m *IMDS) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Requests through proxies are rejected, so tokens can't leak out of the instance.
+	if r.Header.Get("X-Forwarded-For") != "" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	seconds, err := strconv.Atoi(r.Header.Get(tokenTTLHeader))
+	ttl := time.Duration(seconds) * time.Second
+	if err != nil || ttl < time.Second || ttl > maxTokenTTL {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	token := randomHex(56)
+	now := m.clock()
+	m.mu.Lock()
+	for t, expiration := range m.tokens {
+		if !now.Before(expiration) {
+			delete(m.tokens, t)
+		}
+	}
+	m.tokens[token] = now.Add(ttl)
+	m.mu.Unlock()
+
+	w.Header().Set(tokenTTLHeader, strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(token))
+}
+
+// checkToken checks the request's token is valid. Requests without one are IMDSv1 requests,
+// which are allowed unless tokens are required.
+func (m *IMDS) checkToken(r *http.Request) bool {
+	token := r.Header.Get(tokenHeader)
+	if token == "" {
+		return !m.requireTokens
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiration, ok := m.tokens[token]
+	return ok && m.clock().Before(expiration)
+}
+
+// entries returns the instance's metadata, keyed by path under /latest/.
+func (m *IMDS) entries() map[string]func() (string, error) {
+	region := m.arnGenerator.Region
+	availabilityZone := region + "a"
+	hostname := fmt.Sprintf("ip-%s.%s.compute.internal", strings.ReplaceAll(privateIP, ".", "-"), region)
+	static := func(value string) func() (string, error) {
+		return func() (string, error) { return value, nil }
+	}
+
+	entries := map[string]func() (string, error){
+		"meta-data/ami-id":                      static(imageId),
+		"meta-data/hostname":                    static(hostname),
+		"meta-data/instance-id":                 static(m.instanceId),
+		"meta-data/instance-type":               static(instanceType),
+		"meta-data/local-hostname":              static(hostname),
+		"meta-data/local-ipv4":                  static(privateIP),
+		"meta-data/placement/availability-zone": static(availabilityZone),
+		"meta-data/placement/region":            static(region),
+		"meta-data/services/domain":             static("amazonaws.com"),
+		"meta-data/services/partition":          static("aws"),
+		"dynamic/instance-identity/document":    m.identityDocument,
+	}
+	// Only instances with a role have IAM entries.
+	if m.sts != nil {
+		entries["meta-data/iam/info"] = m.iamInfo
+		entries["meta-data/iam/security-credentials/"+m.roleName] = m.securityCredentials
+	}
+	return entries
+}
+
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-identity-documents.html
+func (m *IMDS) identityDocument() (string, error) {
+	document := map[string]any{
+		"accountId":               m.arnGenerator.AwsAccountId,
+		"architecture":            "x86_64",
+		"availabilityZone":        m.arnGenerator.Region + "a",
+		"billingProducts":         nil,
+		"devpayProductCodes":      nil,
+		"imageId":                 imageId,
+		"instanceId":              m.instanceId,
+		"instanceType":            instanceType,
+		"kernelId":                nil,
+		"marketplaceProductCodes": nil,
+		"pendingTime":             m.launchTime.Format(time.RFC3339),
+		"privateIp":               privateIP,
+		"ramdiskId":               nil,
+		"region":                  m.arnGenerator.Region,
+		"version":                 "2017-09-30",
+	}
+	data, err := json.MarshalIndent(document, "", "  ")
+	return string(data), err
+}
+
+func (m *IMDS) iamInfo() (string, error) {
+	_, lastUpdated, err := m.roleCredentials()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(map[string]string{
+		"Code":               "Success",
+		"LastUpdated":        lastUpdated.Format(time.RFC3339),
+		"InstanceProfileArn": fmt.Sprintf("arn:aws:iam::%s:instance-profile/%s", m.arnGenerator.AwsAccountId, m.roleName),
+		"InstanceProfileId":  m.instanceProfileId,
+	}, "", "  ")
+	return string(data), err
+}
+
+func (m *IMDS) securityCredentials() (string, error) {
+	credentials, lastUpdated, err := m.roleCredentials()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(map[string]string{
+		"Code":            "Success",
+		"LastUpdated":     lastUpdated.Format(time.RFC3339),
+		"Type":            "AWS-HMAC",
+		"AccessKeyId":     credentials.AccessKeyId,
+		"SecretAccessKey": credentials.SecretAccessKey,
+		"Token":           credentials.SessionToken,
+		"Expiration":      credentials.Expiration.UTC().Format(time.RFC3339),
+	}, "", "  ")
+	return string(data), err
+}
+
+// roleCredentials returns the role's credentials, and when they were issued. They're cached,
+// and a new session of the role is assumed when they're close to expiring.
+func (m *IMDS) roleCredentials() (sts.APICredentials, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+	if m.credentials != nil && now.Add(credentialsRefreshWindow).Before(m.credentials.Expiration) {
+		return *m.credentials, m.lastUpdated, nil
+	}
+	output, awserr := m.sts.AssumeRole(sts.AssumeRoleInput{
+		RoleArn:         fmt.Sprintf("arn:aws:iam::%s:role/%s", m.arnGenerator.AwsAccountId, m.roleName),
+		RoleSessionName: m.instanceId,
+	})
+	if awserr != nil {
+		return sts.APICredentials{}, time.Time{}, fmt.Errorf("assuming role %s: %s", m.roleName, awserr.Body.Message)
+	}
+	m.logger.Info("Issued instance role credentials", "role", m.roleName, "accessKeyId", output.Credentials.AccessKeyId)
+	m.credentials = &output.Credentials
+	m.lastUpdated = now.UTC().Truncate(time.Second)
+	return *m.credentials, m.lastUpdated, nil
+}
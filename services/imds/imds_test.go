@@ -0,0 +1,113 @@
+package imds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/sts"
+)
+
+var arnGenerator = arn.Generator{AwsAccountId: "123456789012", Region: "eu-west-1"}
+
+func get(m *IMDS, path string, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		request.Header.Set(tokenHeader, token)
+	}
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response
+}
+
+func getToken(t *testing.T, m *IMDS, ttl string) string {
+	request := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+	request.Header.Set(tokenTTLHeader, ttl)
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	return response.Body.String()
+}
+
+func TestTokens(t *testing.T) {
+	now := time.Now()
+	m := New(Options{ArnGenerator: arnGenerator, RequireTokens: true})
+	m.clock = func() time.Time { return now }
+
+	if response := get(m, "/latest/meta-data/placement/region", ""); response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected requests without a token to be rejected, got", response.Code)
+	}
+	token := getToken(t, m, "60")
+	if response := get(m, "/latest/meta-data/placement/region", token); response.Body.String() != "eu-west-1" {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	now = now.Add(time.Minute)
+	if response := get(m, "/latest/meta-data/placement/region", token); response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected expired tokens to be rejected, got", response.Code)
+	}
+
+	request := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+	request.Header.Set(tokenTTLHeader, "21601")
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Expected a TTL over 6 hours to be rejected, got", response.Code)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	m := New(Options{
+		ArnGenerator: arnGenerator,
+		STS:          sts.New(sts.Options{ArnGenerator: arnGenerator}),
+		RoleName:     "app",
+	})
+
+	if response := get(m, "/latest/meta-data/", ""); response.Body.String() !=
+		"ami-id\nhostname\niam/\ninstance-id\ninstance-type\nlocal-hostname\nlocal-ipv4\nplacement/\nservices/" {
+		t.Fatal("Unexpected listing", response.Body.String())
+	}
+	if response := get(m, "/latest/meta-data/iam/security-credentials/", ""); response.Body.String() != "app" {
+		t.Fatal("Unexpected roles", response.Body.String())
+	}
+
+	var credentials map[string]string
+	response := get(m, "/latest/meta-data/iam/security-credentials/app", "")
+	if err := json.Unmarshal(response.Body.Bytes(), &credentials); err != nil {
+		t.Fatal(err)
+	}
+	if credentials["Code"] != "Success" || credentials["AccessKeyId"] == "" || credentials["Token"] == "" {
+		t.Fatalf("Unexpected credentials %v", credentials)
+	}
+	// Credentials are reused until they're close to expiring.
+	var again map[string]string
+	json.Unmarshal(get(m, "/latest/meta-data/iam/security-credentials/app", "").Body.Bytes(), &again)
+	if again["AccessKeyId"] != credentials["AccessKeyId"] {
+		t.Fatal("Expected the same credentials")
+	}
+
+	var document map[string]any
+	response = get(m, "/latest/dynamic/instance-identity/document", "")
+	if err := json.Unmarshal(response.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document["region"] != "eu-west-1" || document["accountId"] != "123456789012" || document["instanceId"] != m.instanceId {
+		t.Fatalf("Unexpected document %v", document)
+	}
+
+	if response := get(m, "/latest/meta-data/missing", ""); response.Code != http.StatusNotFound {
+		t.Fatal("Expected a 404, got", response.Code)
+	}
+}
+
+func TestNoRole(t *testing.T) {
+	m := New(Options{ArnGenerator: arnGenerator})
+	if response := get(m, "/latest/meta-data/iam/security-credentials/", ""); response.Code != http.StatusNotFound {
+		t.Fatal("Expected a 404, got", response.Code)
+	}
+}
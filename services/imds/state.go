@@ -0,0 +1,72 @@
+package imds
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/services/sts"
+	"aws-in-a-box/state"
+)
+
+type tokenState struct {
+	Token      string
+	Expiration time.Time
+}
+
+type imdsState struct {
+	// Sorted by token.
+	Tokens []tokenState
+	// The role's cached credentials, if any.
+	Credentials *sts.APICredentials `json:",omitempty"`
+	LastUpdated time.Time           `json:",omitempty"`
+}
+
+// TryPause pauses changes to the credentials while a snapshot copies them.
+func (m *IMDS) TryPause() (func(), bool) {
+	return state.TryLock(&m.mu)
+}
+
+// ExportState writes the unexpired session tokens and the role's cached credentials to the archive.
+// The instance's ID and launch time are chosen when the emulator starts, and aren't exported.
+func (m *IMDS) ExportState(w *state.Writer) error {
+	unlock := w.Lock(&m.mu)
+	defer unlock()
+
+	exported := imdsState{Credentials: m.credentials, LastUpdated: m.lastUpdated}
+	now := m.clock()
+	for token, expiration := range m.tokens {
+		if now.Before(expiration) {
+			exported.Tokens = append(exported.Tokens, tokenState{Token: token, Expiration: expiration})
+		}
+	}
+	slices.SortFunc(exported.Tokens, func(a, b tokenState) int {
+		return strings.Compare(a.Token, b.Token)
+	})
+	return w.WriteJSON("imds.json", exported)
+}
+
+// ImportState replaces the session tokens and the role's cached credentials with the archive's.
+func (m *IMDS) ImportState(r *state.Reader) (func() error, error) {
+	var imported imdsState
+	if err := r.ReadJSON("imds.json", &imported); err != nil {
+		return nil, err
+	}
+	tokens := make(map[string]time.Time)
+	for _, token := range imported.Tokens {
+		if token.Token == "" {
+			return nil, fmt.Errorf("invalid session token")
+		}
+		tokens[token.Token] = token.Expiration
+	}
+
+	return func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.tokens = tokens
+		m.credentials = imported.Credentials
+		m.lastUpdated = imported.LastUpdated
+		return nil
+	}, nil
+}
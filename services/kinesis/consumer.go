@@ -6,8 +6,8 @@ import (
 	"slices"
 	"time"
 
-	"aws-in-a-box/arn"
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
 )
 
 var (
@@ -24,7 +24,10 @@ func (k *Kinesis) RegisterStreamConsumer(input RegisterStreamConsumerInput) (*Re
 		return nil, awserrors.InvalidArgumentException("Invalid length")
 	}
 
-	_, streamName := arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName("", input.StreamARN)
+	if err != nil {
+		return nil, err
+	}
 
 	k.mu.Lock()
 	defer k.mu.Unlock()
@@ -42,7 +45,7 @@ func (k *Kinesis) RegisterStreamConsumer(input RegisterStreamConsumerInput) (*Re
 		return nil, XXXTodoException("Consumer already exists")
 	}
 
-	now := time.Now().UnixNano()
+	now := clock.Now().UnixNano()
 
 	// See https://docs.aws.amazon.com/kinesis/latest/APIReference/API_Consumer.html#Streams-Type-Consumer-ConsumerARN
 	arn := k.arnGenerator.Generate("kinesis", "stream",
@@ -79,7 +82,10 @@ func (k *Kinesis) lockedGetConsumer(
 	}
 
 	if streamARN != "" && consumerName != "" {
-		_, streamName := arn.ExtractId(streamARN)
+		streamName, err := k.streamName("", streamARN)
+		if err != nil {
+			return nil, err
+		}
 		stream, ok := k.streams[streamName]
 		if !ok {
 			return nil, awserrors.ResourceNotFoundException("No such stream")
@@ -168,7 +174,7 @@ func (k *Kinesis) SubscribeToShard(input SubscribeToShardInput) (chan *APISubscr
 		return nil, err
 	}
 
-	now := time.Now()
+	now := clock.Now()
 	subscription, ok := c.SubscriptionsByShardId[input.ShardId]
 	if ok {
 		if subscription.CreationTime.Sub(now) < 5*time.Second {
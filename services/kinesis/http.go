@@ -2,27 +2,37 @@ package kinesis
 
 import (
 	"log/slog"
+	"net/http"
 
-	"aws-in-a-box/http"
+	"aws-in-a-box/awserrors"
+	awshttp "aws-in-a-box/http"
+	"aws-in-a-box/region"
 )
 
 const service = "Kinesis_20131202"
 
-func (k *Kinesis) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
-	http.Register(logger, methodRegistry, service, "AddTagsToStream", k.AddTagsToStream)
-	http.Register(logger, methodRegistry, service, "CreateStream", k.CreateStream)
-	http.Register(logger, methodRegistry, service, "DecreaseStreamRetentionPeriod", k.DecreaseStreamRetentionPeriod)
-	http.Register(logger, methodRegistry, service, "DeleteStream", k.DeleteStream)
-	http.Register(logger, methodRegistry, service, "DescribeStreamConsumer", k.DescribeStreamConsumer)
-	http.Register(logger, methodRegistry, service, "DescribeStreamSummary", k.DescribeStreamSummary)
-	http.Register(logger, methodRegistry, service, "DeregisterStreamConsumer", k.DeregisterStreamConsumer)
-	http.Register(logger, methodRegistry, service, "GetRecords", k.GetRecords)
-	http.Register(logger, methodRegistry, service, "GetShardIterator", k.GetShardIterator)
-	http.Register(logger, methodRegistry, service, "IncreaseStreamRetentionPeriod", k.IncreaseStreamRetentionPeriod)
-	http.Register(logger, methodRegistry, service, "ListShards", k.ListShards)
-	http.Register(logger, methodRegistry, service, "ListTagsForStream", k.ListTagsForStream)
-	http.Register(logger, methodRegistry, service, "PutRecord", k.PutRecord)
-	http.Register(logger, methodRegistry, service, "RegisterStreamConsumer", k.RegisterStreamConsumer)
-	http.Register(logger, methodRegistry, service, "RemoveTagsFromStream", k.RemoveTagsFromStream)
-	http.RegisterOutputStream(logger, methodRegistry, service, "SubscribeToShard", k.SubscribeToShard)
+func (k *Kinesis) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry awshttp.Registry) {
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "AddTagsToStream", region.Method(k.regions, (*Kinesis).AddTagsToStream))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "CreateStream", region.Method(k.regions, (*Kinesis).CreateStream))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DecreaseStreamRetentionPeriod", region.Method(k.regions, (*Kinesis).DecreaseStreamRetentionPeriod))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DeleteStream", region.Method(k.regions, (*Kinesis).DeleteStream))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DescribeStreamConsumer", region.Method(k.regions, (*Kinesis).DescribeStreamConsumer))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DescribeStreamSummary", region.Method(k.regions, (*Kinesis).DescribeStreamSummary))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DeregisterStreamConsumer", region.Method(k.regions, (*Kinesis).DeregisterStreamConsumer))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "GetRecords", region.Method(k.regions, (*Kinesis).GetRecords))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "GetShardIterator", region.Method(k.regions, (*Kinesis).GetShardIterator))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "IncreaseStreamRetentionPeriod", region.Method(k.regions, (*Kinesis).IncreaseStreamRetentionPeriod))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ListShards", region.Method(k.regions, (*Kinesis).ListShards))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ListTagsForStream", region.Method(k.regions, (*Kinesis).ListTagsForStream))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "PutRecord", region.Method(k.regions, (*Kinesis).PutRecord))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "RegisterStreamConsumer", region.Method(k.regions, (*Kinesis).RegisterStreamConsumer))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "RemoveTagsFromStream", region.Method(k.regions, (*Kinesis).RemoveTagsFromStream))
+	awshttp.RegisterOutputStreamWithRequest(logger, methodRegistry, service, "SubscribeToShard",
+		func(r *http.Request, input SubscribeToShardInput) (chan *APISubscribeToShardEvent, *awserrors.Error) {
+			k, awserr := k.regions.ForRequest(r)
+			if awserr != nil {
+				return nil, awserr
+			}
+			return k.SubscribeToShard(input)
+		})
 }
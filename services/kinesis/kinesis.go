@@ -1,6 +1,7 @@
 package kinesis
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"log/slog"
@@ -13,6 +14,9 @@ import (
 
 	"aws-in-a-box/arn"
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/cloudwatch"
 
 	"golang.org/x/exp/maps"
 )
@@ -37,6 +41,8 @@ type Shard struct {
 	Records []APIRecord
 
 	ConsumerChans map[chan *APISubscribeToShardEvent]struct{}
+	// Closed, and replaced, when records are added, to wake up long-polling GetRecords.
+	recordsAdded chan struct{}
 }
 
 type Consumer struct {
@@ -82,6 +88,10 @@ type Kinesis struct {
 	defaultRetention     time.Duration
 	streamCreateDuration time.Duration
 	streamDeleteDuration time.Duration
+	getRecordsWaitTime   time.Duration
+	metrics              *cloudwatch.CloudWatch
+	// Shared by the instances of all regions, which each have their own streams.
+	regions *region.Regions[*Kinesis]
 
 	mu               sync.Mutex
 	streams          map[string]*Stream
@@ -95,38 +105,94 @@ type Options struct {
 	DefaultRetention     time.Duration
 	StreamCreateDuration time.Duration
 	StreamDeleteDuration time.Duration
+	// How long GetRecords waits for new records, rather than returning none straight away. This
+	// isn't something AWS does, but saves local consumers from busy-polling.
+	GetRecordsWaitTime time.Duration
+	// Streams' IncomingRecords and IncomingBytes metrics are published to this CloudWatch, if any,
+	// in the stream's region.
+	Metrics *cloudwatch.CloudWatch
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+	// Enforcing retention stops when this is done, such as when the server is.
+	Context context.Context
 }
 
 func New(options Options) *Kinesis {
 	if options.Logger == nil {
 		options.Logger = slog.Default()
 	}
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
 
-	k := &Kinesis{
+	k := newRegion(options)
+	k.regions = region.New(options.ArnGenerator.Region, options.Regions, k, func(name string) (*Kinesis, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.Metrics != nil {
+			metrics, err := options.Metrics.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.Metrics = metrics
+		}
+		r := newRegion(regionOptions)
+		r.regions = k.regions
+		return r, nil
+	})
+	if options.DefaultRetention > 0 {
+		go func() {
+			ticker := time.NewTicker(options.DefaultRetention / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					for _, r := range k.regions.All() {
+						r.enforceDuration()
+					}
+				case <-options.Context.Done():
+					return
+				}
+			}
+		}()
+	}
+	return k
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *Kinesis {
+	return &Kinesis{
 		logger:               options.Logger,
 		arnGenerator:         options.ArnGenerator,
 		defaultRetention:     options.DefaultRetention,
 		streamCreateDuration: options.StreamCreateDuration,
 		streamDeleteDuration: options.StreamDeleteDuration,
+		getRecordsWaitTime:   options.GetRecordsWaitTime,
+		metrics:              options.Metrics,
 		streams:              map[string]*Stream{},
 		consumersByARN:       map[string]*Consumer{},
 	}
-	if options.DefaultRetention > 0 {
-		go func() {
-			for {
-				time.Sleep(options.DefaultRetention / 2)
-				k.enforceDuration()
-			}
-		}()
-	}
-	return k
+}
+
+// InRegion returns the streams of the region, which are created the first time it's used, or of
+// the default region if it's empty. It fails if the region isn't one of Options.Regions.
+func (k *Kinesis) InRegion(name string) (*Kinesis, error) {
+	return k.regions.Get(name)
+}
+
+// ForARN returns the streams of the region the stream ARN is in, for services which write to or
+// read from streams by ARN.
+func (k *Kinesis) ForARN(streamARN string) (*Kinesis, error) {
+	return k.regions.ForARN(streamARN)
 }
 
 func (k *Kinesis) enforceDuration() {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	now := time.Now()
+	now := clock.Now()
 	for _, stream := range k.streams {
 		cutoff := now.Add(-stream.Retention).UnixNano()
 		for _, shard := range stream.Shards {
@@ -170,7 +236,7 @@ func (k *Kinesis) CreateStream(input CreateStreamInput) (*CreateStreamOutput, *a
 		Status:            initialStatus,
 		Name:              input.StreamName,
 		Retention:         k.defaultRetention,
-		CreationTimestamp: time.Now().UnixNano(),
+		CreationTimestamp: clock.Now().UnixNano(),
 		consumersByName:   make(map[string]*Consumer),
 		Tags:              make(map[string]string),
 	}
@@ -179,7 +245,7 @@ func (k *Kinesis) CreateStream(input CreateStreamInput) (*CreateStreamOutput, *a
 		stream.Tags[tagName] = tagValue
 	}
 
-	sequenceNumber := time.Now().UnixNano()
+	sequenceNumber := clock.Now().UnixNano()
 
 	step := big.NewInt(0).Div(uint128Max, big.NewInt(input.ShardCount))
 	for i := int64(0); i < input.ShardCount; i++ {
@@ -199,6 +265,7 @@ func (k *Kinesis) CreateStream(input CreateStreamInput) (*CreateStreamOutput, *a
 			StartingSequenceNumber: sequenceNumber,
 			EndingSequenceNumber:   sequenceNumber,
 			ConsumerChans:          make(map[chan *APISubscribeToShardEvent]struct{}),
+			recordsAdded:           make(chan struct{}),
 		})
 	}
 
@@ -218,9 +285,9 @@ func (k *Kinesis) CreateStream(input CreateStreamInput) (*CreateStreamOutput, *a
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_DeleteStream.html
 func (k *Kinesis) DeleteStream(input DeleteStreamInput) (*DeleteStreamOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -247,9 +314,9 @@ func (k *Kinesis) DeleteStream(input DeleteStreamInput) (*DeleteStreamOutput, *a
 }
 
 func (k *Kinesis) lockedGetNextSequenceNumber() string {
-	timestamp := time.Now().UnixNano()
-	if timestamp == k.highestTimestamp {
-		timestamp += 1
+	timestamp := clock.Now().UnixNano()
+	if timestamp <= k.highestTimestamp {
+		timestamp = k.highestTimestamp + 1
 	}
 	k.highestTimestamp = timestamp
 	return i64toA(timestamp)
@@ -257,9 +324,9 @@ func (k *Kinesis) lockedGetNextSequenceNumber() string {
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_PutRecord.html
 func (k *Kinesis) PutRecord(input PutRecordInput) (*PutRecordOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	var hashKey big.Int
@@ -282,12 +349,14 @@ func (k *Kinesis) PutRecord(input PutRecordInput) (*PutRecordOutput, *awserrors.
 		if hashKey.Cmp(&shard.EndingHashKey) <= 0 && hashKey.Cmp(&shard.StartingHashKey) >= 0 {
 			sequenceNumber := k.lockedGetNextSequenceNumber()
 			record := APIRecord{
-				ApproximateArrivalTimestamp: time.Now().Unix(),
+				ApproximateArrivalTimestamp: clock.Now().Unix(),
 				Data:                        input.Data,
 				PartitionKey:                input.PartitionKey,
 				SequenceNumber:              sequenceNumber,
 			}
 			shard.Records = append(shard.Records, record)
+			close(shard.recordsAdded)
+			shard.recordsAdded = make(chan struct{})
 
 			for ch := range shard.ConsumerChans {
 				ch <- &APISubscribeToShardEvent{
@@ -295,6 +364,11 @@ func (k *Kinesis) PutRecord(input PutRecordInput) (*PutRecordOutput, *awserrors.
 					ContinuationSequenceNumber: sequenceNumber,
 				}
 			}
+			if k.metrics != nil {
+				dimensions := map[string]string{"StreamName": streamName}
+				k.metrics.PutMetric("AWS/Kinesis", "IncomingRecords", dimensions, "Count", 1)
+				k.metrics.PutMetric("AWS/Kinesis", "IncomingBytes", dimensions, "Bytes", float64(decodedLen(input.Data)))
+			}
 
 			return &PutRecordOutput{
 				ShardId:        shard.Id,
@@ -306,6 +380,11 @@ func (k *Kinesis) PutRecord(input PutRecordInput) (*PutRecordOutput, *awserrors.
 	panic("Could not find shard for record?")
 }
 
+// decodedLen returns the length of the base64 encoded data.
+func decodedLen(data string) int {
+	return len(data)/4*3 - (len(data) - len(strings.TrimRight(data, "=")))
+}
+
 func (k *Kinesis) lockedGetShard(streamName, shardId string) (*Shard, *awserrors.Error) {
 	stream, ok := k.streams[streamName]
 	if !ok {
@@ -336,6 +415,38 @@ func (k *Kinesis) GetRecords(input GetRecordsInput) (*GetRecordsOutput, *awserro
 		return nil, awserr
 	}
 
+	output := lockedGetRecords(shard, streamName, shardId, start)
+	if len(output.Records) > 0 || k.getRecordsWaitTime == 0 {
+		return output, nil
+	}
+
+	// Long polling: wait until a record is put or the wait time lapses.
+	timer := time.NewTimer(k.getRecordsWaitTime)
+	defer timer.Stop()
+	for {
+		recordsAdded := shard.recordsAdded
+		k.mu.Unlock()
+		timedOut := false
+		select {
+		case <-recordsAdded:
+		case <-timer.C:
+			timedOut = true
+		}
+		k.mu.Lock()
+
+		// The stream may have been deleted while we were waiting.
+		shard, awserr = k.lockedGetShard(streamName, shardId)
+		if awserr != nil {
+			return nil, awserr
+		}
+		output = lockedGetRecords(shard, streamName, shardId, start)
+		if len(output.Records) > 0 || timedOut {
+			return output, nil
+		}
+	}
+}
+
+func lockedGetRecords(shard *Shard, streamName string, shardId string, start int) *GetRecordsOutput {
 	output := &GetRecordsOutput{}
 	var currIndex int
 	for currIndex = start; currIndex < len(shard.Records); currIndex++ {
@@ -347,16 +458,19 @@ func (k *Kinesis) GetRecords(input GetRecordsInput) (*GetRecordsOutput, *awserro
 	}
 
 	output.NextShardIterator = encodeShardIterator(streamName, shardId, currIndex)
-	return output, nil
+	return output
 }
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_GetShardIterator.html
 func (k *Kinesis) GetShardIterator(input GetShardIteratorInput) (*GetShardIteratorOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
 	output := &GetShardIteratorOutput{}
 	switch input.ShardIteratorType {
 	case "TRIM_HORIZON":
@@ -389,9 +503,9 @@ func (k *Kinesis) GetShardIterator(input GetShardIteratorInput) (*GetShardIterat
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_ListShards.html
 func (k *Kinesis) ListShards(input ListShardsInput) (*ListShardsOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -450,9 +564,9 @@ func (k *Kinesis) ListStreams(input ListStreamsInput) (*ListStreamsOutput, *awse
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_AddTagsToStream.html
 func (k *Kinesis) AddTagsToStream(input AddTagsToStreamInput) (*AddTagsToStreamOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -472,9 +586,9 @@ func (k *Kinesis) AddTagsToStream(input AddTagsToStreamInput) (*AddTagsToStreamO
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_RemoveTagsFromStream.html
 func (k *Kinesis) RemoveTagsFromStream(input RemoveTagsFromStreamInput) (*RemoveTagsFromStreamOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -494,9 +608,9 @@ func (k *Kinesis) RemoveTagsFromStream(input RemoveTagsFromStreamInput) (*Remove
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_ListTagsForStream.html
 func (k *Kinesis) ListTagsForStream(input ListTagsForStreamInput) (*ListTagsForStreamOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -520,9 +634,9 @@ func (k *Kinesis) ListTagsForStream(input ListTagsForStreamInput) (*ListTagsForS
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_IncreaseStreamRetentionPeriod.html
 func (k *Kinesis) IncreaseStreamRetentionPeriod(input IncreaseStreamRetentionPeriodInput) (*IncreaseStreamRetentionPeriodOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -540,9 +654,9 @@ func (k *Kinesis) IncreaseStreamRetentionPeriod(input IncreaseStreamRetentionPer
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_IncreaseStreamRetentionPeriod.html
 func (k *Kinesis) DecreaseStreamRetentionPeriod(input DecreaseStreamRetentionPeriodInput) (*DecreaseStreamRetentionPeriodOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -560,9 +674,9 @@ func (k *Kinesis) DecreaseStreamRetentionPeriod(input DecreaseStreamRetentionPer
 
 // https://docs.aws.amazon.com/kinesis/latest/APIReference/API_DescribeStreamSummary.html
 func (k *Kinesis) DescribeStreamSummary(input DescribeStreamSummaryInput) (*DescribeStreamSummaryOutput, *awserrors.Error) {
-	streamName := input.StreamName
-	if streamName == "" {
-		_, streamName = arn.ExtractId(input.StreamARN)
+	streamName, err := k.streamName(input.StreamName, input.StreamARN)
+	if err != nil {
+		return nil, err
 	}
 
 	k.mu.Lock()
@@ -591,6 +705,28 @@ func (k *Kinesis) arnForStream(streamName string) string {
 	return k.arnGenerator.Generate("kinesis", "stream", streamName)
 }
 
+// streamName returns the name of the stream a call is for, which it names either directly or by
+// its ARN. If it does both, they must be the same stream.
+func (k *Kinesis) streamName(name string, streamARN string) (string, *awserrors.Error) {
+	if streamARN == "" {
+		if name == "" {
+			return "", awserrors.InvalidArgumentException("Either StreamName or StreamARN must be specified")
+		}
+		return name, nil
+	}
+	if _, err := arn.Parse(streamARN); err != nil {
+		return "", awserrors.InvalidArgumentException(err.Error())
+	}
+	arnName, err := k.arnGenerator.Resolve(streamARN, "kinesis", "stream")
+	if err != nil {
+		return "", awserrors.ResourceNotFoundException(err.Error())
+	}
+	if name != "" && name != arnName {
+		return "", awserrors.InvalidArgumentException("StreamName and StreamARN are for different streams")
+	}
+	return arnName, nil
+}
+
 // These are complete HAX, they probably need to be more legit
 func encodeShardIterator(streamName string, shardId string, index int) string {
 	return fmt.Sprintf("%s/%s/%d", streamName, shardId, index)
@@ -1,12 +1,18 @@
 package kinesis
 
 import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"slices"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"aws-in-a-box/arn"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/cloudwatch"
 )
 
 var generator = arn.Generator{
@@ -310,3 +316,228 @@ func TestListStreams(t *testing.T) {
 		t.Fatal("Bad Streams", output.StreamNames)
 	}
 }
+
+func TestStreamARN(t *testing.T) {
+	k, streamName := newKinesisWithStream()
+	streamARN := k.arnForStream(streamName)
+
+	_, err := k.DescribeStreamSummary(DescribeStreamSummaryInput{StreamARN: streamARN})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = k.DescribeStreamSummary(DescribeStreamSummaryInput{StreamName: streamName, StreamARN: streamARN})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		input DescribeStreamSummaryInput
+		code  string
+	}{
+		{DescribeStreamSummaryInput{}, "InvalidArgumentException"},
+		{DescribeStreamSummaryInput{StreamARN: streamName}, "InvalidArgumentException"},
+		{DescribeStreamSummaryInput{StreamName: "other", StreamARN: streamARN}, "InvalidArgumentException"},
+		{DescribeStreamSummaryInput{StreamARN: "arn:aws:kinesis:eu-west-1:123456789012:stream/" + streamName}, "ResourceNotFoundException"},
+		{DescribeStreamSummaryInput{StreamARN: "arn:aws:sqs:us-east-1:123456789012:" + streamName}, "ResourceNotFoundException"},
+	} {
+		_, err := k.DescribeStreamSummary(tc.input)
+		if err == nil || err.Body.Type != tc.code {
+			t.Errorf("Expected %s for %+v, got %v", tc.code, tc.input, err)
+		}
+	}
+}
+
+func TestIncomingMetrics(t *testing.T) {
+	metrics := cloudwatch.New(cloudwatch.Options{})
+	k := New(Options{ArnGenerator: generator, Metrics: metrics})
+	if _, awserr := k.CreateStream(CreateStreamInput{StreamName: "stream", ShardCount: 1}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	// "hello" and "hi!"
+	for _, data := range []string{"aGVsbG8=", "aGkh"} {
+		if _, awserr := k.PutRecord(PutRecordInput{StreamName: "stream", PartitionKey: "key", Data: data}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	now := float64(time.Now().Unix())
+	for metricName, expected := range map[string]float64{"IncomingRecords": 2, "IncomingBytes": 8} {
+		output, awserr := metrics.GetMetricStatistics(cloudwatch.GetMetricStatisticsInput{
+			Namespace:  "AWS/Kinesis",
+			MetricName: metricName,
+			Dimensions: []cloudwatch.APIDimension{{Name: "StreamName", Value: "stream"}},
+			StartTime:  now - 150,
+			EndTime:    now + 150,
+			Period:     300,
+			Statistics: []string{"Sum"},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if len(output.Datapoints) != 1 || *output.Datapoints[0].Sum != expected {
+			t.Fatalf("Unexpected %s datapoints: %+v", metricName, output.Datapoints)
+		}
+	}
+
+	// A stream's metrics are published to its region's CloudWatch.
+	europe := inRegion(t, k, "eu-west-1")
+	if _, awserr := europe.CreateStream(CreateStreamInput{StreamName: "europe", ShardCount: 1}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := europe.PutRecord(PutRecordInput{StreamName: "europe", PartitionKey: "key", Data: "aGkh"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	europeMetrics, err := metrics.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c, expected := range map[*cloudwatch.CloudWatch]int{metrics: 0, europeMetrics: 1} {
+		output, awserr := c.ListMetrics(cloudwatch.ListMetricsInput{
+			Namespace:  "AWS/Kinesis",
+			Dimensions: []cloudwatch.APIDimensionFilter{{Name: "StreamName", Value: "europe"}},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if len(output.Metrics) != 2*expected {
+			t.Fatalf("Unexpected metrics %+v", output.Metrics)
+		}
+	}
+}
+
+func TestGetRecordsWaitTime(t *testing.T) {
+	streamName := "stream"
+	k := New(Options{ArnGenerator: generator, GetRecordsWaitTime: 5 * time.Second})
+	_, err := k.CreateStream(CreateStreamInput{
+		StreamName: streamName,
+		ShardCount: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardsOutput, err := k.ListShards(ListShardsInput{
+		StreamName: streamName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardIteratorOutput, err := k.GetShardIterator(GetShardIteratorInput{
+		StreamName:        streamName,
+		ShardId:           shardsOutput.Shards[0].ShardId,
+		ShardIteratorType: "LATEST",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		k.PutRecord(PutRecordInput{
+			StreamName:   streamName,
+			PartitionKey: "key",
+			Data:         "record",
+		})
+	}()
+
+	start := time.Now()
+	recordsOutput, err := k.GetRecords(GetRecordsInput{
+		ShardIterator: shardIteratorOutput.ShardIterator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordsOutput.Records) != 1 || recordsOutput.Records[0].Data != "record" {
+		t.Fatalf("Expected the record put while waiting, got %+v", recordsOutput.Records)
+	}
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Fatalf("Waited %v, rather than returning once the record was put", elapsed)
+	}
+
+	// With no new records, it waits out the wait time.
+	k.getRecordsWaitTime = 100 * time.Millisecond
+	start = time.Now()
+	recordsOutput, err = k.GetRecords(GetRecordsInput{
+		ShardIterator: recordsOutput.NextShardIterator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordsOutput.Records) != 0 || time.Since(start) < 100*time.Millisecond {
+		t.Fatalf("Expected no records after waiting, got %+v", recordsOutput.Records)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	k, streamName := newKinesisWithStream()
+	methodRegistry := make(map[string]http.HandlerFunc)
+	k.RegisterHTTPHandlers(slog.Default(), methodRegistry)
+	call := func(method string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		response := httptest.NewRecorder()
+		methodRegistry[service+"."+method](response, request)
+		return response
+	}
+	signedFor := func(region string) map[string]string {
+		return map[string]string{"Authorization": "AWS4-HMAC-SHA256 Credential=AKIA1/20240102/" + region + "/kinesis/aws4_request"}
+	}
+	describe := `{"StreamName":"` + streamName + `"}`
+
+	if response := call("DescribeStreamSummary", describe, nil); response.Code != http.StatusOK {
+		t.Fatal("Expected the stream to be in the default region", response.Body.String())
+	}
+	if response := call("DescribeStreamSummary", describe, signedFor("us-east-1")); response.Code != http.StatusOK {
+		t.Fatal("Expected the stream to be in us-east-1", response.Body.String())
+	}
+	if response := call("DescribeStreamSummary", describe, signedFor("eu-west-1")); response.Code != http.StatusBadRequest ||
+		!strings.Contains(response.Body.String(), "ResourceNotFoundException") {
+		t.Fatal("Expected the stream not to be in eu-west-1", response.Code, response.Body.String())
+	}
+	if response := call("DescribeStreamSummary", describe, map[string]string{region.Header: "eu-west-1"}); response.Code != http.StatusBadRequest {
+		t.Fatal("Expected the header to choose the region", response.Code, response.Body.String())
+	}
+
+	// A stream of the same name in another region is another stream, with that region's ARN.
+	if response := call("CreateStream", `{"StreamName":"`+streamName+`","ShardCount":2}`, signedFor("eu-west-1")); response.Code != http.StatusOK {
+		t.Fatal("Unexpected response", response.Body.String())
+	}
+	europe := inRegion(t, k, "eu-west-1")
+	summary, awserr := europe.DescribeStreamSummary(DescribeStreamSummaryInput{StreamName: streamName})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if summary.StreamDescriptionSummary.OpenShardCount != 2 ||
+		summary.StreamDescriptionSummary.StreamARN != "arn:aws:kinesis:eu-west-1:123456789012:stream/"+streamName {
+		t.Fatalf("Unexpected stream %+v", summary.StreamDescriptionSummary)
+	}
+	if _, awserr := k.DescribeStreamSummary(DescribeStreamSummaryInput{StreamARN: summary.StreamDescriptionSummary.StreamARN}); awserr == nil {
+		t.Fatal("Expected eu-west-1's stream ARN not to be found in us-east-1")
+	}
+	if r, _ := k.ForARN(summary.StreamDescriptionSummary.StreamARN); r != europe {
+		t.Fatal("Expected eu-west-1's ARN to choose its streams")
+	}
+	if r, _ := k.ForARN(k.arnForStream(streamName)); r != k {
+		t.Fatal("Expected us-east-1's ARN to choose its streams")
+	}
+
+	// Requests for regions which aren't enabled fail, rather than reaching the default region's streams.
+	if response := call("DescribeStreamSummary", describe, signedFor("xx-nowhere-1")); response.Code != http.StatusBadRequest ||
+		!strings.Contains(response.Body.String(), "InvalidRegionException") {
+		t.Fatal("Expected an unknown region to fail", response.Code, response.Body.String())
+	}
+	if _, err := k.ForARN("arn:aws:kinesis:xx-nowhere-1:123456789012:stream/" + streamName); err == nil {
+		t.Fatal("Expected an ARN in an unknown region to fail")
+	}
+}
+
+func inRegion(t *testing.T, k *Kinesis, name string) *Kinesis {
+	t.Helper()
+	r, err := k.InRegion(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
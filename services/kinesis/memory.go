@@ -0,0 +1,29 @@
+package kinesis
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each stream's records. Streams outside the default region are
+// prefixed with their region.
+func (k *Kinesis) MemoryUsage() []memory.Resource {
+	return k.regions.MemoryUsage((*Kinesis).memoryUsage)
+}
+
+func (k *Kinesis) memoryUsage() []memory.Resource {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var resources []memory.Resource
+	for name, stream := range k.streams {
+		resource := memory.Resource{Name: name}
+		for _, shard := range stream.Shards {
+			resource.Count += len(shard.Records)
+			for _, record := range shard.Records {
+				resource.Bytes += int64(len(record.Data) + len(record.PartitionKey) + len(record.SequenceNumber))
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
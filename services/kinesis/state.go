@@ -0,0 +1,209 @@
+package kinesis
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/region"
+	"aws-in-a-box/state"
+)
+
+type shardState struct {
+	Id string
+	// Decimal, like the API's hash key ranges.
+	StartingHashKey        string
+	EndingHashKey          string
+	StartingSequenceNumber int64
+	EndingSequenceNumber   int64
+	Records                []APIRecord
+}
+
+type consumerState struct {
+	ARN               string
+	Name              string
+	CreationTimestamp int64
+}
+
+type streamState struct {
+	Name              string
+	CreationTimestamp int64
+	Retention         time.Duration
+	Tags              map[string]string
+	Shards            []shardState
+	Consumers         []consumerState
+}
+
+type streamsState struct {
+	Streams []streamState
+}
+
+// StateVersion is 2 since other regions' streams were added, under regions/<region>/. Version 1
+// only has the default region's, which are where they were.
+func (k *Kinesis) StateVersion() int {
+	return 2
+}
+
+func (k *Kinesis) StateMigrations() []state.Migration {
+	return []state.Migration{
+		func(e *state.Entries) error { return nil },
+	}
+}
+
+// TryPause pauses changes to each region's streams while a snapshot copies them.
+func (k *Kinesis) TryPause() (func(), bool) {
+	return k.regions.TryPause(func(k *Kinesis) *sync.Mutex { return &k.mu })
+}
+
+// ExportState writes the streams of each region, with their shards' records and their consumers,
+// to the archive.
+func (k *Kinesis) ExportState(w *state.Writer) error {
+	return k.regions.ExportState(w, (*Kinesis).exportState)
+}
+
+func (k *Kinesis) exportState(w *state.Writer) error {
+	return w.WriteJSON("streams.json", k.exportStreams(w))
+}
+
+func (k *Kinesis) exportStreams(w *state.Writer) streamsState {
+	unlock := w.Lock(&k.mu)
+	defer unlock()
+
+	var exported streamsState
+	for _, stream := range k.streams {
+		if stream.Status == StatusDeleting {
+			continue
+		}
+		s := streamState{
+			Name:              stream.Name,
+			CreationTimestamp: stream.CreationTimestamp,
+			Retention:         stream.Retention,
+			Tags:              stream.Tags,
+		}
+		for _, shard := range stream.Shards {
+			s.Shards = append(s.Shards, shardState{
+				Id:                     shard.Id,
+				StartingHashKey:        shard.StartingHashKey.String(),
+				EndingHashKey:          shard.EndingHashKey.String(),
+				StartingSequenceNumber: shard.StartingSequenceNumber,
+				EndingSequenceNumber:   shard.EndingSequenceNumber,
+				Records:                shard.Records,
+			})
+		}
+		for _, consumer := range stream.consumersByName {
+			s.Consumers = append(s.Consumers, consumerState{
+				ARN:               consumer.ARN,
+				Name:              consumer.Name,
+				CreationTimestamp: consumer.CreationTimestamp,
+			})
+		}
+		slices.SortFunc(s.Consumers, func(a, b consumerState) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.Streams = append(exported.Streams, s)
+	}
+	slices.SortFunc(exported.Streams, func(a, b streamState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return exported
+}
+
+// ImportState replaces the streams of each region with the archive's, and removes those of regions
+// the archive has none in. Subscriptions to the previous streams' shards stop receiving records.
+func (k *Kinesis) ImportState(r *state.Reader) (func() error, error) {
+	return k.regions.ImportState(r, (*Kinesis).importState, (*Kinesis).exportState)
+}
+
+// importState checks the region's imported streams, and returns a function which replaces the
+// region's streams with them.
+func (k *Kinesis) importState(r *state.Reader) (func() error, error) {
+	var imported streamsState
+	if err := r.ReadJSON("streams.json", &imported); err != nil {
+		return nil, err
+	}
+	streams := make(map[string]*Stream)
+	consumersByARN := make(map[string]*Consumer)
+	var highestTimestamp int64
+	for _, s := range imported.Streams {
+		if len(s.Shards) == 0 {
+			return nil, fmt.Errorf("stream %q has no shards", s.Name)
+		}
+		stream := &Stream{
+			Name:              s.Name,
+			CreationTimestamp: s.CreationTimestamp,
+			Status:            StatusActive,
+			Retention:         s.Retention,
+			Tags:              s.Tags,
+			consumersByName:   make(map[string]*Consumer),
+		}
+		if stream.Tags == nil {
+			stream.Tags = make(map[string]string)
+		}
+		for _, sh := range s.Shards {
+			shard := &Shard{
+				Id:                     sh.Id,
+				StartingSequenceNumber: sh.StartingSequenceNumber,
+				EndingSequenceNumber:   sh.EndingSequenceNumber,
+				Records:                sh.Records,
+				ConsumerChans:          make(map[chan *APISubscribeToShardEvent]struct{}),
+				recordsAdded:           make(chan struct{}),
+			}
+			if _, ok := shard.StartingHashKey.SetString(sh.StartingHashKey, 10); !ok {
+				return nil, fmt.Errorf("shard %s: invalid StartingHashKey", sh.Id)
+			}
+			if _, ok := shard.EndingHashKey.SetString(sh.EndingHashKey, 10); !ok {
+				return nil, fmt.Errorf("shard %s: invalid EndingHashKey", sh.Id)
+			}
+			if shard.StartingHashKey.Cmp(&shard.EndingHashKey) > 0 || shard.EndingHashKey.Cmp(uint128Max) > 0 ||
+				shard.StartingHashKey.Cmp(big.NewInt(0)) < 0 {
+				return nil, fmt.Errorf("shard %s: invalid hash key range", sh.Id)
+			}
+			for _, record := range sh.Records {
+				sequenceNumber, _ := strconv.ParseInt(record.SequenceNumber, 10, 64)
+				highestTimestamp = max(highestTimestamp, sequenceNumber)
+			}
+			stream.Shards = append(stream.Shards, shard)
+		}
+		for _, c := range s.Consumers {
+			consumer := &Consumer{
+				ARN:                    c.ARN,
+				Name:                   c.Name,
+				StreamName:             stream.Name,
+				CreationTimestamp:      c.CreationTimestamp,
+				SubscriptionsByShardId: make(map[string]consumerSubscription),
+			}
+			stream.consumersByName[consumer.Name] = consumer
+			consumersByARN[consumer.ARN] = consumer
+		}
+		streams[stream.Name] = stream
+	}
+
+	return func() error {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		k.streams = streams
+		k.consumersByARN = consumersByARN
+		// New records' sequence numbers must follow the imported ones.
+		k.highestTimestamp = max(k.highestTimestamp, highestTimestamp)
+		return nil
+	}, nil
+}
+
+// StateResources lists the streams of each region in the archive, for diffing archives. A stream is
+// modified if any of its records are. Streams outside the default region are prefixed with their
+// region.
+var StateResources = region.StateResources(func(r *state.Reader) (map[string]any, error) {
+	var exported streamsState
+	if err := r.ReadJSON("streams.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, stream := range exported.Streams {
+		resources["stream/"+stream.Name] = stream
+	}
+	return resources, nil
+})
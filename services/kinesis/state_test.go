@@ -0,0 +1,47 @@
+package kinesis
+
+import (
+	"bytes"
+	"testing"
+
+	"aws-in-a-box/state"
+)
+
+func TestExportImportStateRegions(t *testing.T) {
+	k, streamName := newKinesisWithStream()
+	if _, err := inRegion(t, k, "eu-west-1").CreateStream(CreateStreamInput{StreamName: "europe", ShardCount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// Regions without streams are exported without any.
+	inRegion(t, k, "ap-south-1")
+
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"kinesis": k}, ""); err != nil {
+		t.Fatal(err)
+	}
+	imported := New(Options{ArnGenerator: generator})
+	// Streams of regions the archive doesn't have are removed.
+	if _, err := inRegion(t, imported, "us-west-2").CreateStream(CreateStreamInput{StreamName: "removed", ShardCount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := state.Import(&buf, state.Registry{"kinesis": imported}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := imported.DescribeStreamSummary(DescribeStreamSummaryInput{StreamName: streamName}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imported.DescribeStreamSummary(DescribeStreamSummaryInput{StreamName: "europe"}); err == nil {
+		t.Fatal("Expected eu-west-1's stream not to be in the default region")
+	}
+	if _, err := inRegion(t, imported, "eu-west-1").DescribeStreamSummary(DescribeStreamSummaryInput{StreamName: "europe"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inRegion(t, imported, "us-west-2").DescribeStreamSummary(DescribeStreamSummaryInput{StreamName: "removed"}); err == nil {
+		t.Fatal("Expected us-west-2's stream to be removed")
+	}
+	resources := imported.MemoryUsage()
+	if len(resources) != 2 || (resources[0].Name != "eu-west-1/europe" && resources[1].Name != "eu-west-1/europe") {
+		t.Fatalf("Unexpected memory usage %+v", resources)
+	}
+}
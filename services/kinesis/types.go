@@ -1,28 +1,28 @@
 package kinesis
 
 type CreateStreamInput struct {
-	StreamName string
-	ShardCount int64
-	Tags       map[string]string
+	StreamName string            `required:"true" length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	ShardCount int64             `range:"1,"`
+	Tags       map[string]string `length:",50"`
 }
 
 type CreateStreamOutput struct{}
 
 type DeleteStreamInput struct {
 	// EnforceConsumerDeletion bool TODO
-	StreamName string
-	StreamARN  string
+	StreamName string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN  string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type DeleteStreamOutput struct{}
 
 type PutRecordInput struct {
-	PartitionKey string
-	StreamName   string
-	StreamARN    string
+	PartitionKey string `required:"true" length:"1,256"`
+	StreamName   string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN    string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 	Data         string
 
-	ExplicitHashKey string
+	ExplicitHashKey string `pattern:"0|([1-9][0-9]{0,38})"`
 }
 
 type PutRecordOutput struct {
@@ -31,11 +31,11 @@ type PutRecordOutput struct {
 }
 
 type GetShardIteratorInput struct {
-	ShardId                string
-	ShardIteratorType      string
-	StreamName             string
-	StreamARN              string
-	StartingSequenceNumber string
+	ShardId                string `required:"true" length:"1,128"`
+	ShardIteratorType      string `required:"true" enum:"AT_SEQUENCE_NUMBER|AFTER_SEQUENCE_NUMBER|TRIM_HORIZON|LATEST|AT_TIMESTAMP"`
+	StreamName             string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN              string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
+	StartingSequenceNumber string `pattern:"0|([1-9][0-9]{0,128})"`
 }
 
 type GetShardIteratorOutput struct {
@@ -43,9 +43,9 @@ type GetShardIteratorOutput struct {
 }
 
 type GetRecordsInput struct {
-	Limit         uint64
-	ShardIterator string
-	StreamARN     string
+	Limit         uint64 `range:"1,10000"`
+	ShardIterator string `required:"true" length:"1,512"`
+	StreamARN     string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type GetRecordsOutput struct {
@@ -55,8 +55,8 @@ type GetRecordsOutput struct {
 }
 
 type ListStreamsInput struct {
-	ExclusiveStartStreamName string
-	Limit                    int
+	ExclusiveStartStreamName string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	Limit                    int    `range:"1,10000"`
 	NextToken                string
 }
 
@@ -77,8 +77,8 @@ type APIStreamSummary struct {
 }
 
 type ListShardsInput struct {
-	StreamName  string
-	StreamARN   string
+	StreamName  string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN   string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 	ShardFilter struct {
 		Type string
 	}
@@ -111,24 +111,24 @@ type APIRecord struct {
 }
 
 type AddTagsToStreamInput struct {
-	StreamName string
-	StreamARN  string
-	Tags       map[string]string
+	StreamName string            `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN  string            `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
+	Tags       map[string]string `required:"true" length:"1,50"`
 }
 
 type AddTagsToStreamOutput struct{}
 
 type RemoveTagsFromStreamInput struct {
-	StreamName string
-	StreamARN  string
-	TagKeys    []string
+	StreamName string   `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN  string   `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
+	TagKeys    []string `required:"true" length:"1,50"`
 }
 
 type RemoveTagsFromStreamOutput struct{}
 
 type ListTagsForStreamInput struct {
-	StreamName string
-	StreamARN  string
+	StreamName string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN  string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type ListTagsForStreamOutput struct {
@@ -141,24 +141,24 @@ type APITag struct {
 }
 
 type IncreaseStreamRetentionPeriodInput struct {
-	StreamName           string
-	StreamARN            string
-	RetentionPeriodHours int32
+	StreamName           string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN            string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
+	RetentionPeriodHours int32  `required:"true"`
 }
 
 type IncreaseStreamRetentionPeriodOutput struct{}
 
 type DecreaseStreamRetentionPeriodInput struct {
-	StreamName           string
-	StreamARN            string
-	RetentionPeriodHours int32
+	StreamName           string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN            string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
+	RetentionPeriodHours int32  `required:"true"`
 }
 
 type DecreaseStreamRetentionPeriodOutput struct{}
 
 type DescribeStreamSummaryInput struct {
-	StreamName string
-	StreamARN  string
+	StreamName string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN  string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type DescribeStreamSummaryOutput struct {
@@ -181,8 +181,8 @@ type APIStreamDescriptionSummary struct {
 }
 
 type RegisterStreamConsumerInput struct {
-	ConsumerName string
-	StreamARN    string
+	ConsumerName string `required:"true" length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN    string `required:"true" length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type RegisterStreamConsumerOutput struct {
@@ -198,16 +198,16 @@ type APIConsumer struct {
 
 type DeregisterStreamConsumerInput struct {
 	ConsumerARN  string
-	ConsumerName string
-	StreamARN    string
+	ConsumerName string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN    string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type DeregisterStreamConsumerOutput struct{}
 
 type DescribeStreamConsumerInput struct {
 	ConsumerARN  string
-	ConsumerName string
-	StreamARN    string
+	ConsumerName string `length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	StreamARN    string `length:"1,2048" pattern:"arn:aws.*:kinesis:.*:[0-9]{12}:stream/.+"`
 }
 
 type DescribeStreamConsumerOutput struct {
@@ -224,13 +224,13 @@ type APIConsumerDescription struct {
 }
 
 type SubscribeToShardInput struct {
-	ConsumerARN      string
-	ShardId          string
-	StartingPosition APIStartingPosition
+	ConsumerARN      string              `required:"true" length:"1,2048"`
+	ShardId          string              `required:"true" length:"1,128"`
+	StartingPosition APIStartingPosition `required:"true"`
 }
 
 type APIStartingPosition struct {
-	Type           string
+	Type           string `required:"true" enum:"AT_SEQUENCE_NUMBER|AFTER_SEQUENCE_NUMBER|TRIM_HORIZON|LATEST|AT_TIMESTAMP"`
 	SequenceNumber string
 	// A time stamp is the Unix epoch date with precision in milliseconds.
 	// need to fix these!
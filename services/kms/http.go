@@ -4,34 +4,35 @@ import (
 	"log/slog"
 
 	"aws-in-a-box/http"
+	"aws-in-a-box/region"
 )
 
 const service = "TrentService"
 
 func (k *KMS) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
-	http.Register(logger, methodRegistry, service, "CreateAlias", k.CreateAlias)
-	http.Register(logger, methodRegistry, service, "CreateKey", k.CreateKey)
-	http.Register(logger, methodRegistry, service, "Decrypt", k.Decrypt)
-	http.Register(logger, methodRegistry, service, "DeleteAlias", k.DeleteAlias)
-	http.Register(logger, methodRegistry, service, "DescribeKey", k.DescribeKey)
-	http.Register(logger, methodRegistry, service, "DisableKey", k.DisableKey)
-	http.Register(logger, methodRegistry, service, "EnableKey", k.EnableKey)
-	http.Register(logger, methodRegistry, service, "Encrypt", k.Encrypt)
-	http.Register(logger, methodRegistry, service, "GenerateDataKey", k.GenerateDataKey)
-	http.Register(logger, methodRegistry, service, "GenerateDataKeyPair", k.GenerateDataKeyPair)
-	http.Register(logger, methodRegistry, service, "GenerateDataKeyWithoutPlaintext", k.GenerateDataKeyWithoutPlaintext)
-	http.Register(logger, methodRegistry, service, "GenerateDataKeyPairWithoutPlaintext", k.GenerateDataKeyPairWithoutPlaintext)
-	http.Register(logger, methodRegistry, service, "GenerateMac", k.GenerateMac)
-	http.Register(logger, methodRegistry, service, "GenerateRandom", k.GenerateRandom)
-	http.Register(logger, methodRegistry, service, "ListAliases", k.ListAliases)
-	http.Register(logger, methodRegistry, service, "ListKeys", k.ListKeys)
-	http.Register(logger, methodRegistry, service, "ListResourceTags", k.ListResourceTags)
-	http.Register(logger, methodRegistry, service, "ReEncrypt", k.ReEncrypt)
-	http.Register(logger, methodRegistry, service, "Sign", k.Sign)
-	http.Register(logger, methodRegistry, service, "TagResource", k.TagResource)
-	http.Register(logger, methodRegistry, service, "UntagResource", k.UntagResource)
-	http.Register(logger, methodRegistry, service, "UpdateAlias", k.UpdateAlias)
-	http.Register(logger, methodRegistry, service, "UpdateKeyDescription", k.UpdateKeyDescription)
-	http.Register(logger, methodRegistry, service, "Verify", k.Verify)
-	http.Register(logger, methodRegistry, service, "VerifyMac", k.VerifyMac)
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateAlias", region.Method(k.regions, (*KMS).CreateAlias))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateKey", region.Method(k.regions, (*KMS).CreateKey))
+	http.RegisterWithRequest(logger, methodRegistry, service, "Decrypt", region.Method(k.regions, (*KMS).Decrypt))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteAlias", region.Method(k.regions, (*KMS).DeleteAlias))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeKey", region.Method(k.regions, (*KMS).DescribeKey))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DisableKey", region.Method(k.regions, (*KMS).DisableKey))
+	http.RegisterWithRequest(logger, methodRegistry, service, "EnableKey", region.Method(k.regions, (*KMS).EnableKey))
+	http.RegisterWithRequest(logger, methodRegistry, service, "Encrypt", region.Method(k.regions, (*KMS).Encrypt))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GenerateDataKey", region.Method(k.regions, (*KMS).GenerateDataKey))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GenerateDataKeyPair", region.Method(k.regions, (*KMS).GenerateDataKeyPair))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GenerateDataKeyWithoutPlaintext", region.Method(k.regions, (*KMS).GenerateDataKeyWithoutPlaintext))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GenerateDataKeyPairWithoutPlaintext", region.Method(k.regions, (*KMS).GenerateDataKeyPairWithoutPlaintext))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GenerateMac", region.Method(k.regions, (*KMS).GenerateMac))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GenerateRandom", region.Method(k.regions, (*KMS).GenerateRandom))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListAliases", region.Method(k.regions, (*KMS).ListAliases))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListKeys", region.Method(k.regions, (*KMS).ListKeys))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListResourceTags", region.Method(k.regions, (*KMS).ListResourceTags))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ReEncrypt", region.Method(k.regions, (*KMS).ReEncrypt))
+	http.RegisterWithRequest(logger, methodRegistry, service, "Sign", region.Method(k.regions, (*KMS).Sign))
+	http.RegisterWithRequest(logger, methodRegistry, service, "TagResource", region.Method(k.regions, (*KMS).TagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UntagResource", region.Method(k.regions, (*KMS).UntagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateAlias", region.Method(k.regions, (*KMS).UpdateAlias))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateKeyDescription", region.Method(k.regions, (*KMS).UpdateKeyDescription))
+	http.RegisterWithRequest(logger, methodRegistry, service, "Verify", region.Method(k.regions, (*KMS).Verify))
+	http.RegisterWithRequest(logger, methodRegistry, service, "VerifyMac", region.Method(k.regions, (*KMS).VerifyMac))
 }
@@ -147,7 +147,7 @@ func (k *Key) persist() error {
 	if k.persistPath == "" {
 		return nil
 	}
-	data, err := k.serialize()
+	data, err := k.Serialize()
 	if err != nil {
 		return err
 	}
@@ -155,7 +155,19 @@ func (k *Key) persist() error {
 	return err
 }
 
+// serializedVersion is the version of the format keys are persisted in. Bump it, and add a
+// migration from the previous version to migrations, whenever serializableKey changes in a way
+// older files can't be read as.
+const serializedVersion = 1
+
+// migrations[v] rewrites a key persisted in version v to version v+1. Files written before
+// keys were versioned have no Version, so are version 0, which is the same format as version 1.
+var migrations = []func(key map[string]json.RawMessage) error{
+	func(key map[string]json.RawMessage) error { return nil },
+}
+
 type serializableKey struct {
+	Version  int
 	Metadata metadata
 
 	AesKeys [][32]byte
@@ -167,8 +179,10 @@ type serializableKey struct {
 	EccKey []byte
 }
 
-func (k *Key) serialize() ([]byte, error) {
+// Serialize returns the key, including its material, in the format it's persisted in.
+func (k *Key) Serialize() ([]byte, error) {
 	key := serializableKey{
+		Version:  serializedVersion,
 		Metadata: k.metadata,
 		AesKeys:  k.aesKey.backingKeys,
 		RsaKey:   k.rsaKey.key,
@@ -323,17 +337,77 @@ func NewFromFile(path string) (*Key, error) {
 	if err != nil {
 		return nil, err
 	}
+	var versioned struct{ Version int }
+	if err := json.Unmarshal(data, &versioned); err != nil {
+		return nil, err
+	}
 	key, err := newFromData(data)
 	if err != nil {
 		return nil, err
 	}
 	key.persistPath = path
+	// Rewrite files in older versions, so they're only migrated once.
+	if versioned.Version != serializedVersion {
+		if err := key.persist(); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// NewFromData returns a key from data returned by Serialize, persisting it to persistPath unless
+// that's empty.
+func NewFromData(data []byte, persistPath string) (*Key, error) {
+	key, err := newFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	key.persistPath = persistPath
+	if err := key.persist(); err != nil {
+		return nil, err
+	}
 	return key, nil
 }
 
+// PersistTo persists the key to path, and persists it there whenever it changes from now on.
+func (k *Key) PersistTo(path string) error {
+	k.persistPath = path
+	return k.persist()
+}
+
+// migrate rewrites a persisted key in an older version of the format to the current one.
+func migrate(data []byte) ([]byte, error) {
+	var versioned struct{ Version int }
+	if err := json.Unmarshal(data, &versioned); err != nil {
+		return nil, err
+	}
+	if versioned.Version > serializedVersion {
+		return nil, fmt.Errorf("key is persisted in version %d, newer than the supported version %d", versioned.Version, serializedVersion)
+	}
+	if versioned.Version == serializedVersion {
+		return data, nil
+	}
+
+	var key map[string]json.RawMessage
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+	for version := versioned.Version; version < serializedVersion; version++ {
+		if err := migrations[version](key); err != nil {
+			return nil, fmt.Errorf("migrating key from version %d: %w", version, err)
+		}
+	}
+	key["Version"] = json.RawMessage(fmt.Sprint(serializedVersion))
+	return json.Marshal(key)
+}
+
 func newFromData(data []byte) (*Key, error) {
+	data, err := migrate(data)
+	if err != nil {
+		return nil, err
+	}
 	var key serializableKey
-	err := json.Unmarshal(data, &key)
+	err = json.Unmarshal(data, &key)
 	if err != nil {
 		return nil, err
 	}
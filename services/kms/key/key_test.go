@@ -1,6 +1,10 @@
 package key
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -31,7 +35,7 @@ func TestSerialization(t *testing.T) {
 
 	for name, key := range tests {
 		t.Run(name, func(t *testing.T) {
-			data, err := key.serialize()
+			data, err := key.Serialize()
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -47,3 +51,56 @@ func TestSerialization(t *testing.T) {
 		})
 	}
 }
+
+func TestVersions(t *testing.T) {
+	key, err := NewAES(Options{Usage: types.EncryptDecrypt, Id: "keyId"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := key.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serialized map[string]any
+	if err := json.Unmarshal(data, &serialized); err != nil {
+		t.Fatal(err)
+	}
+	if serialized["Version"] != float64(serializedVersion) {
+		t.Fatalf("bad version; got %v, want %d", serialized["Version"], serializedVersion)
+	}
+
+	// Keys persisted before they were versioned.
+	delete(serialized, "Version")
+	legacy, err := json.Marshal(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keyId.json")
+	if err := os.WriteFile(path, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated.persistPath = ""
+	if !reflect.DeepEqual(key, migrated) {
+		t.Fatalf("bad key; got %v, want %v", migrated, key)
+	}
+	rewritten, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rewritten, data) {
+		t.Fatalf("legacy file wasn't rewritten; got %s, want %s", rewritten, data)
+	}
+
+	serialized["Version"] = serializedVersion + 1
+	newer, err := json.Marshal(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newFromData(newer); err == nil {
+		t.Fatal("expected an error for a newer version")
+	}
+}
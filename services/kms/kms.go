@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -22,13 +23,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/gofrs/uuid/v5"
 
 	"aws-in-a-box/arn"
 	"aws-in-a-box/atomicfile"
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
 	"aws-in-a-box/services/kms/key"
 	"aws-in-a-box/services/kms/types"
 )
@@ -39,6 +41,8 @@ type KMS struct {
 	logger       *slog.Logger
 	arnGenerator arn.Generator
 	persistDir   string
+	// Shared by the instances of all regions, which each have their own keys.
+	regions *region.Regions[*KMS]
 
 	mu sync.Mutex
 
@@ -50,7 +54,12 @@ type KMS struct {
 type Options struct {
 	Logger       *slog.Logger
 	ArnGenerator arn.Generator
-	PersistDir   string
+	// The default region's keys are persisted in kms under it, and other regions' in
+	// kms/regions/<region>.
+	PersistDir string
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
 }
 
 const aliasesFilename = "aliases.json"
@@ -60,51 +69,103 @@ func New(options Options) (*KMS, error) {
 		options.Logger = slog.Default()
 	}
 
-	keys := make(map[KeyId]*key.Key)
-	aliases := make(map[string]KeyId)
-
+	var regionsDir string
 	if options.PersistDir != "" {
 		options.PersistDir = filepath.Join(options.PersistDir, "kms")
-		err := os.MkdirAll(options.PersistDir, 0700)
-		if err != nil {
-			return nil, err
+		regionsDir = filepath.Join(options.PersistDir, "regions")
+	}
+	k := newRegion(options)
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	k.regions = region.New(options.ArnGenerator.Region, options.Regions, k, func(name string) (*KMS, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if regionsDir != "" {
+			regionOptions.PersistDir = filepath.Join(regionsDir, name)
+			if err := os.MkdirAll(regionOptions.PersistDir, 0700); err != nil {
+				return nil, err
+			}
 		}
+		r := newRegion(regionOptions)
+		r.regions = k.regions
+		return r, nil
+	})
 
-		files, err := os.ReadDir(options.PersistDir)
-		if err != nil {
+	// Every region with persisted keys is loaded, so importing state replaces them too.
+	if regionsDir != "" {
+		dirs, err := os.ReadDir(regionsDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return nil, err
 		}
-		for _, file := range files {
-			name := file.Name()
-			fullPath := filepath.Join(options.PersistDir, name)
-			if name == aliasesFilename {
-				data, err := os.ReadFile(fullPath)
-				if err != nil {
-					return nil, err
-				}
-				err = json.Unmarshal(data, &aliases)
-				if err != nil {
-					return nil, err
-				}
-			} else if strings.HasSuffix(name, ".tmp") {
-				os.Remove(fullPath)
-			} else if strings.HasSuffix(name, ".json") {
-				key, err := key.NewFromFile(fullPath)
-				if err != nil {
-					return nil, err
-				}
-				keys[key.Id()] = key
+		for _, dir := range dirs {
+			r, err := k.regions.Get(dir.Name())
+			if err != nil {
+				options.Logger.Warn("Not loading persisted keys", "dir", filepath.Join(regionsDir, dir.Name()), "err", err)
+				continue
+			}
+			if err := r.load(); err != nil {
+				return nil, err
 			}
 		}
 	}
+	return k, nil
+}
 
+// newRegion returns the instance for the options' region, without the keys persisted for it.
+func newRegion(options Options) *KMS {
 	return &KMS{
 		logger:       options.Logger,
 		arnGenerator: options.ArnGenerator,
 		persistDir:   options.PersistDir,
 		aliases:      make(map[string]KeyId),
-		keys:         keys,
-	}, nil
+		keys:         make(map[KeyId]*key.Key),
+	}
+}
+
+// load reads the keys persisted for the region.
+func (k *KMS) load() error {
+	if k.persistDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(k.persistDir, 0700); err != nil {
+		return err
+	}
+	files, err := os.ReadDir(k.persistDir)
+	if err != nil {
+		return err
+	}
+	aliases := make(map[string]KeyId)
+	for _, file := range files {
+		name := file.Name()
+		fullPath := filepath.Join(k.persistDir, name)
+		if name == aliasesFilename {
+			data, err := os.ReadFile(fullPath)
+			if err != nil {
+				return err
+			}
+			err = json.Unmarshal(data, &aliases)
+			if err != nil {
+				return err
+			}
+		} else if strings.HasSuffix(name, ".tmp") {
+			os.Remove(fullPath)
+		} else if strings.HasSuffix(name, ".json") {
+			key, err := key.NewFromFile(fullPath)
+			if err != nil {
+				return err
+			}
+			k.keys[key.Id()] = key
+		}
+	}
+	return nil
+}
+
+// InRegion returns the keys of the region, for services which encrypt with keys in their own
+// region. It fails if the region isn't one of Options.Regions.
+func (k *KMS) InRegion(name string) (*KMS, error) {
+	return k.regions.Get(name)
 }
 
 func (k *KMS) persistAliases() error {
@@ -123,10 +184,23 @@ func (k *KMS) persistAliases() error {
 
 // https://docs.aws.amazon.com/kms/latest/APIReference/API_CreateKey.html
 func (k *KMS) CreateKey(input CreateKeyInput) (*CreateKeyOutput, *awserrors.Error) {
+	return k.CreateKeyWithId(uuid.Must(uuid.NewV4()).String(), input)
+}
+
+// CreateKeyWithId is like CreateKey, but the key has the given ID rather than a random one, so
+// keys created at startup have the same ID every time. It isn't part of the KMS API.
+func (k *KMS) CreateKeyWithId(keyId string, input CreateKeyInput) (*CreateKeyOutput, *awserrors.Error) {
+	// Key IDs are UUIDs, which are also safe to persist keys under.
+	if _, err := uuid.FromString(keyId); err != nil {
+		return nil, ValidationException(fmt.Sprintf("Invalid keyId %s", keyId))
+	}
+
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	keyId := uuid.Must(uuid.NewV4()).String()
+	if _, ok := k.keys[keyId]; ok {
+		return nil, AlreadyExistsException(fmt.Sprintf("Key %s already exists", keyId))
+	}
 
 	for _, t := range input.Tags {
 		if !isValidTagKey(t.TagKey) || !isValidTagValue(t.TagValue) {
@@ -154,7 +228,7 @@ func (k *KMS) CreateKey(input CreateKeyInput) (*CreateKeyOutput, *awserrors.Erro
 	options := key.Options{
 		PersistPath: persistPath,
 
-		CreationDate: time.Now(),
+		CreationDate: clock.Now(),
 		Id:           keyId,
 		KeySpec:      keySpec,
 		Description:  input.Description,
@@ -268,8 +342,18 @@ func (k *KMS) lockedGetKey(keyId string) *key.Key {
 
 	var isAlias bool
 	if strings.HasPrefix(keyId, "arn:") {
-		var resourceType string
-		resourceType, keyId = arn.ExtractId(keyId)
+		parsed, err := arn.Parse(keyId)
+		if err != nil {
+			return nil
+		}
+		resourceType := parsed.ResourceType()
+		if resourceType != "key" && resourceType != "alias" {
+			return nil
+		}
+		keyId, err = k.arnGenerator.Resolve(keyId, "kms", resourceType)
+		if err != nil {
+			return nil
+		}
 		isAlias = resourceType == "alias"
 	} else if strings.HasPrefix(keyId, "alias/") {
 		_, keyId, isAlias = strings.Cut(keyId, "alias/")
@@ -518,6 +602,40 @@ func (k *KMS) UpdateAlias(input UpdateAliasInput) (*UpdateAliasOutput, *awserror
 	return nil, nil
 }
 
+// AWSManagedKey returns the ARN of the AWS managed key which other services use by default,
+// such as alias/aws/ssm for SSM. The key is created the first time it's used, like in AWS.
+// https://docs.aws.amazon.com/kms/latest/developerguide/concepts.html#aws-managed-cmk
+func (k *KMS) AWSManagedKey(service string) (string, *awserrors.Error) {
+	aliasName := "aws/" + service
+
+	k.mu.Lock()
+	keyId, ok := k.aliases[aliasName]
+	k.mu.Unlock()
+	if ok {
+		return k.arnGenerator.Generate("kms", "key", keyId), nil
+	}
+
+	output, awserr := k.CreateKey(CreateKeyInput{
+		Description: "Default key that protects my " + service + " data when no other key is defined",
+	})
+	if awserr != nil {
+		return "", awserr
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// Another caller may have created the key first.
+	if keyId, ok := k.aliases[aliasName]; ok {
+		return k.arnGenerator.Generate("kms", "key", keyId), nil
+	}
+	k.aliases[aliasName] = output.KeyMetadata.KeyId
+	if err := k.persistAliases(); err != nil {
+		return "", KMSInternalException(err.Error())
+	}
+	return output.KeyMetadata.Arn, nil
+}
+
 // https://docs.aws.amazon.com/kms/latest/APIReference/API_DeleteAlias.html
 func (k *KMS) DeleteAlias(input DeleteAliasInput) (*DeleteAliasOutput, *awserrors.Error) {
 	k.mu.Lock()
@@ -12,7 +12,7 @@ import (
 
 var kmsOptions = Options{
 	ArnGenerator: arn.Generator{
-		AwsAccountId: "12345",
+		AwsAccountId: "123456789012",
 		Region:       "us-east-1",
 	}}
 
@@ -142,7 +142,7 @@ func TestAliasCreateDelete(t *testing.T) {
 		if alias.TargetKeyId != keyId {
 			t.Fatal(alias.TargetKeyId)
 		}
-		if alias.AliasArn != "arn:aws:kms:us-east-1:12345:alias/short" {
+		if alias.AliasArn != "arn:aws:kms:us-east-1:123456789012:alias/short" {
 			t.Fatal(alias.AliasArn)
 		}
 	}
@@ -421,3 +421,93 @@ func TestInvalidCiphertext(t *testing.T) {
 		t.Fatal("bad err", err)
 	}
 }
+
+func TestAWSManagedKey(t *testing.T) {
+	k, err := New(kmsOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arn, awserr := k.AWSManagedKey("ssm")
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	again, awserr := k.AWSManagedKey("ssm")
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if arn != again {
+		t.Fatal("Expected the same key", arn, again)
+	}
+
+	output, awserr := k.DescribeKey(DescribeKeyInput{KeyId: "alias/aws/ssm"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.KeyMetadata.Arn != arn {
+		t.Fatal("Unexpected key", output.KeyMetadata.Arn)
+	}
+}
+
+func TestCreateKeyWithId(t *testing.T) {
+	k, err := New(kmsOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const keyId = "1234abcd-12ab-34cd-56ef-1234567890ab"
+	output, awserr := k.CreateKeyWithId(keyId, CreateKeyInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.KeyMetadata.KeyId != keyId || output.KeyMetadata.Arn != "arn:aws:kms:us-east-1:123456789012:key/"+keyId {
+		t.Fatal("Unexpected metadata", output.KeyMetadata)
+	}
+
+	_, awserr = k.CreateKeyWithId(keyId, CreateKeyInput{})
+	if awserr == nil || awserr.Body.Type != "AlreadyExistsException" {
+		t.Fatal("Expected AlreadyExistsException, got", awserr)
+	}
+	_, awserr = k.CreateKeyWithId("../key", CreateKeyInput{})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected ValidationException, got", awserr)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	options := kmsOptions
+	options.PersistDir = t.TempDir()
+	k, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	europe, err := k.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, awserr := europe.CreateKey(CreateKeyInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(output.KeyMetadata.Arn, "arn:aws:kms:eu-west-1:") {
+		t.Fatal("Expected the key to have eu-west-1's ARN", output.KeyMetadata.Arn)
+	}
+	if _, awserr := k.DescribeKey(DescribeKeyInput{KeyId: output.KeyMetadata.KeyId}); awserr == nil {
+		t.Fatal("Expected eu-west-1's key not to be in us-east-1")
+	}
+
+	// Each region's keys are loaded from where they were persisted.
+	reloaded, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := reloaded.DescribeKey(DescribeKeyInput{KeyId: output.KeyMetadata.KeyId}); awserr == nil {
+		t.Fatal("Expected eu-west-1's key not to be loaded into us-east-1")
+	}
+	reloadedEurope, err := reloaded.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := reloadedEurope.DescribeKey(DescribeKeyInput{KeyId: output.KeyMetadata.KeyId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
@@ -0,0 +1,140 @@
+package kms
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/kms/key"
+	"aws-in-a-box/state"
+)
+
+type keysState struct {
+	// In the format keys are persisted in, including their material.
+	Keys []json.RawMessage
+	// Alias names, without the "alias/" prefix, to key IDs.
+	Aliases map[string]KeyId
+}
+
+// StateVersion is 2 since the keys of regions other than the default were added. Archives of
+// version 1 have the default region's keys where version 2 does.
+func (k *KMS) StateVersion() int {
+	return 2
+}
+
+func (k *KMS) StateMigrations() []state.Migration {
+	return []state.Migration{
+		func(e *state.Entries) error { return nil },
+	}
+}
+
+// TryPause pauses changes to each region's keys while a snapshot copies them.
+func (k *KMS) TryPause() (func(), bool) {
+	return k.regions.TryPause(func(k *KMS) *sync.Mutex { return &k.mu })
+}
+
+// ExportState writes each region's keys, including their material, and aliases to the archive.
+func (k *KMS) ExportState(w *state.Writer) error {
+	return k.regions.ExportState(w, (*KMS).exportState)
+}
+
+func (k *KMS) exportState(w *state.Writer) error {
+	unlock := w.Lock(&k.mu)
+	defer unlock()
+
+	exported := keysState{Aliases: k.aliases}
+	var ids []KeyId
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		data, err := k.keys[id].Serialize()
+		if err != nil {
+			return err
+		}
+		exported.Keys = append(exported.Keys, data)
+	}
+	return w.WriteJSON("keys.json", exported)
+}
+
+// ImportState replaces each region's keys and aliases with the archive's, including the persisted
+// ones.
+func (k *KMS) ImportState(r *state.Reader) (func() error, error) {
+	return k.regions.ImportState(r, (*KMS).importState, (*KMS).exportState)
+}
+
+func (k *KMS) importState(r *state.Reader) (func() error, error) {
+	var imported keysState
+	if err := r.ReadJSON("keys.json", &imported); err != nil {
+		return nil, err
+	}
+
+	// The keys are only persisted once they replace the service's.
+	keys := make(map[KeyId]*key.Key)
+	for _, data := range imported.Keys {
+		newKey, err := key.NewFromData(data, "")
+		if err != nil {
+			return nil, err
+		}
+		keys[newKey.Id()] = newKey
+	}
+	aliases := make(map[string]KeyId)
+	for alias, keyId := range imported.Aliases {
+		if _, ok := keys[keyId]; ok {
+			aliases[alias] = keyId
+		}
+	}
+
+	return func() error {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		if k.persistDir != "" {
+			files, err := os.ReadDir(k.persistDir)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				if strings.HasSuffix(file.Name(), ".json") && file.Name() != aliasesFilename {
+					if err := os.Remove(filepath.Join(k.persistDir, file.Name())); err != nil {
+						return err
+					}
+				}
+			}
+			for id, newKey := range keys {
+				if err := newKey.PersistTo(filepath.Join(k.persistDir, filepath.Base(id)+".json")); err != nil {
+					return err
+				}
+			}
+		}
+		k.keys = keys
+		k.aliases = aliases
+		return k.persistAliases()
+	}, nil
+}
+
+// StateResources lists each region's keys and aliases in the archive, for diffing archives. Those
+// outside the default region are prefixed with their region.
+var StateResources = region.StateResources(func(r *state.Reader) (map[string]any, error) {
+	var exported keysState
+	if err := r.ReadJSON("keys.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, data := range exported.Keys {
+		var identified struct{ Metadata struct{ Id string } }
+		if err := json.Unmarshal(data, &identified); err != nil {
+			return nil, err
+		}
+		resources["key/"+identified.Metadata.Id] = data
+	}
+	for alias, keyId := range exported.Aliases {
+		resources["alias/"+alias] = keyId
+	}
+	return resources, nil
+})
@@ -0,0 +1,94 @@
+package kms
+
+import (
+	"bytes"
+	"testing"
+
+	"aws-in-a-box/state"
+)
+
+func TestExportImportState(t *testing.T) {
+	k, keyId := newKMSWithKey()
+	if _, err := k.CreateAlias(CreateAliasInput{AliasName: "alias/test", TargetKeyId: keyId}); err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("The quick brown fox jumps over the lazy dog")
+	encryptOutput, awserr := k.Encrypt(EncryptInput{KeyId: "alias/test", Plaintext: plaintext})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"kms": k}, ""); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := New(Options{ArnGenerator: kmsOptions.ArnGenerator, PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := state.Import(&buf, state.Registry{"kms": imported}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key material was imported, so the ciphertext can be decrypted.
+	decryptOutput, awserr := imported.Decrypt(DecryptInput{
+		KeyId:          "alias/test",
+		CiphertextBlob: encryptOutput.CiphertextBlob,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !bytes.Equal(decryptOutput.Plaintext, plaintext) {
+		t.Fatal("Wrong plaintext", decryptOutput.Plaintext)
+	}
+}
+
+func TestExportImportStateRegions(t *testing.T) {
+	k, _ := newKMSWithKey()
+	europe, err := k.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	europeKey, awserr := europe.CreateKey(CreateKeyInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"kms": k}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	options := kmsOptions
+	options.PersistDir = t.TempDir()
+	imported, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys of regions the archive has none in are removed, including the persisted ones.
+	asia, err := imported.InRegion("ap-south-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asiaKey, awserr := asia.CreateKey(CreateKeyInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, _, err := state.Import(&buf, state.Registry{"kms": imported}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for region, keyId := range map[string]string{"eu-west-1": europeKey.KeyMetadata.KeyId, "ap-south-1": asiaKey.KeyMetadata.KeyId} {
+		r, err := reloaded.InRegion(region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, awserr := r.DescribeKey(DescribeKeyInput{KeyId: keyId})
+		if found := awserr == nil; found != (region == "eu-west-1") {
+			t.Fatal("Unexpected key in", region, awserr)
+		}
+	}
+}
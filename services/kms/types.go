@@ -3,10 +3,11 @@ package kms
 import "aws-in-a-box/services/kms/types"
 
 type CreateKeyInput struct {
-	Description           string
+	Description string `length:",8192"`
+	// The key specs are checked by CreateKey, which rejects SM2 as unsupported rather than invalid.
 	CustomerMasterKeySpec string
 	KeySpec               string
-	KeyUsage              string
+	KeyUsage              string `enum:"SIGN_VERIFY|ENCRYPT_DECRYPT|GENERATE_VERIFY_MAC|KEY_AGREEMENT"`
 	Tags                  []APITag
 }
 
@@ -15,7 +16,7 @@ type CreateKeyOutput struct {
 }
 
 type DescribeKeyInput struct {
-	KeyId string
+	KeyId string `required:"true" length:"1,2048"`
 }
 
 type DescribeKeyOutput struct {
@@ -23,30 +24,30 @@ type DescribeKeyOutput struct {
 }
 
 type CreateAliasInput struct {
-	AliasName   string
-	TargetKeyId string
+	AliasName   string `required:"true" length:"1,256" pattern:"^[a-zA-Z0-9:/_-]+$"`
+	TargetKeyId string `required:"true" length:"1,2048"`
 }
 
 type CreateAliasOutput struct{}
 
 type DeleteAliasInput struct {
-	AliasName string
+	AliasName string `required:"true" length:"1,256" pattern:"^[a-zA-Z0-9:/_-]+$"`
 }
 
 type DeleteAliasOutput struct{}
 
 type UpdateAliasInput struct {
-	AliasName   string
-	TargetKeyId string
+	AliasName   string `required:"true" length:"1,256" pattern:"^[a-zA-Z0-9:/_-]+$"`
+	TargetKeyId string `required:"true" length:"1,2048"`
 }
 
 type UpdateAliasOutput struct{}
 
 type SignInput struct {
-	KeyId            string
-	Message          []byte
-	SigningAlgorithm types.SigningAlgorithm
-	MessageType      string
+	KeyId            string                 `required:"true" length:"1,2048"`
+	Message          []byte                 `required:"true" length:"1,4096"`
+	SigningAlgorithm types.SigningAlgorithm `required:"true" enum:"RSASSA_PSS_SHA_256|RSASSA_PSS_SHA_384|RSASSA_PSS_SHA_512|RSASSA_PKCS1_V1_5_SHA_256|RSASSA_PKCS1_V1_5_SHA_384|RSASSA_PKCS1_V1_5_SHA_512|ECDSA_SHA_256|ECDSA_SHA_384|ECDSA_SHA_512|SM2DSA|ML_DSA_SHAKE_256"`
+	MessageType      string                 `enum:"RAW|DIGEST|EXTERNAL_MU"`
 }
 
 type SignOutput struct {
@@ -56,11 +57,11 @@ type SignOutput struct {
 }
 
 type VerifyInput struct {
-	KeyId            string
-	Message          []byte
-	MessageType      string
-	Signature        []byte
-	SigningAlgorithm types.SigningAlgorithm
+	KeyId            string                 `required:"true" length:"1,2048"`
+	Message          []byte                 `required:"true" length:"1,4096"`
+	MessageType      string                 `enum:"RAW|DIGEST|EXTERNAL_MU"`
+	Signature        []byte                 `required:"true" length:"1,6144"`
+	SigningAlgorithm types.SigningAlgorithm `required:"true" enum:"RSASSA_PSS_SHA_256|RSASSA_PSS_SHA_384|RSASSA_PSS_SHA_512|RSASSA_PKCS1_V1_5_SHA_256|RSASSA_PKCS1_V1_5_SHA_384|RSASSA_PKCS1_V1_5_SHA_512|ECDSA_SHA_256|ECDSA_SHA_384|ECDSA_SHA_512|SM2DSA|ML_DSA_SHAKE_256"`
 }
 
 type VerifyOutput struct {
@@ -70,7 +71,7 @@ type VerifyOutput struct {
 }
 
 type ListAliasesInput struct {
-	KeyId string
+	KeyId string `length:"1,2048"`
 }
 
 type ListAliasesOutput struct {
@@ -87,9 +88,9 @@ type APIAliasListEntry struct {
 type GenerateDataKeyInput struct {
 	EncryptionContext map[string]string
 
-	KeyId         string
-	KeySpec       string
-	NumberOfBytes int
+	KeyId         string `required:"true" length:"1,2048"`
+	KeySpec       string `enum:"AES_256|AES_128"`
+	NumberOfBytes int    `range:"1,1024"`
 }
 
 type GenerateDataKeyOutput struct {
@@ -108,8 +109,8 @@ type GenerateDataKeyWithoutPlaintextOutput struct {
 type GenerateDataKeyPairInput struct {
 	EncryptionContext map[string]string
 
-	KeyId       string
-	KeyPairSpec string
+	KeyId       string `required:"true" length:"1,2048"`
+	KeyPairSpec string `required:"true" enum:"RSA_2048|RSA_3072|RSA_4096|ECC_NIST_P256|ECC_NIST_P384|ECC_NIST_P521|ECC_SECG_P256K1|SM2"`
 }
 
 type GenerateDataKeyPairOutput struct {
@@ -130,7 +131,7 @@ type GenerateDataKeyPairWithoutPlaintextOutput struct {
 }
 
 type GenerateRandomInput struct {
-	NumberOfBytes int
+	NumberOfBytes int `range:"1,1024"`
 }
 
 type GenerateRandomOutput struct {
@@ -138,10 +139,10 @@ type GenerateRandomOutput struct {
 }
 
 type EncryptInput struct {
-	EncryptionAlgorithm types.EncryptionAlgorithm
+	EncryptionAlgorithm types.EncryptionAlgorithm `enum:"SYMMETRIC_DEFAULT|RSAES_OAEP_SHA_1|RSAES_OAEP_SHA_256|SM2PKE"`
 	EncryptionContext   map[string]string
-	KeyId               string
-	Plaintext           []byte
+	KeyId               string `required:"true" length:"1,2048"`
+	Plaintext           []byte `required:"true" length:"1,4096"`
 }
 
 type EncryptOutput struct {
@@ -151,9 +152,9 @@ type EncryptOutput struct {
 }
 
 type GenerateMacInput struct {
-	KeyId        string
-	MacAlgorithm string
-	Message      []byte
+	KeyId        string `required:"true" length:"1,2048"`
+	MacAlgorithm string `required:"true" enum:"HMAC_SHA_224|HMAC_SHA_256|HMAC_SHA_384|HMAC_SHA_512"`
+	Message      []byte `required:"true" length:"1,4096"`
 }
 
 type GenerateMacOutput struct {
@@ -163,10 +164,10 @@ type GenerateMacOutput struct {
 }
 
 type VerifyMacInput struct {
-	KeyId        string
-	Mac          []byte
-	MacAlgorithm string
-	Message      []byte
+	KeyId        string `required:"true" length:"1,2048"`
+	Mac          []byte `required:"true" length:"1,6144"`
+	MacAlgorithm string `required:"true" enum:"HMAC_SHA_224|HMAC_SHA_256|HMAC_SHA_384|HMAC_SHA_512"`
+	Message      []byte `required:"true" length:"1,4096"`
 }
 
 type VerifyMacOutput struct {
@@ -176,10 +177,10 @@ type VerifyMacOutput struct {
 }
 
 type DecryptInput struct {
-	CiphertextBlob      []byte
-	EncryptionAlgorithm types.EncryptionAlgorithm
+	CiphertextBlob      []byte                    `required:"true" length:"1,6144"`
+	EncryptionAlgorithm types.EncryptionAlgorithm `enum:"SYMMETRIC_DEFAULT|RSAES_OAEP_SHA_1|RSAES_OAEP_SHA_256|SM2PKE"`
 	EncryptionContext   map[string]string
-	KeyId               string
+	KeyId               string `length:"1,2048"`
 }
 
 type DecryptOutput struct {
@@ -209,45 +210,45 @@ type APIKeyMetadata struct {
 }
 
 type UpdateKeyDescriptionInput struct {
-	KeyId       string
-	Description string
+	KeyId       string `required:"true" length:"1,2048"`
+	Description string `length:",8192"`
 }
 
 type UpdateKeyDescriptionOutput struct{}
 
 type DisableKeyInput struct {
-	KeyId string
+	KeyId string `required:"true" length:"1,2048"`
 }
 
 type DisableKeyOutput struct{}
 
 type EnableKeyInput struct {
-	KeyId string
+	KeyId string `required:"true" length:"1,2048"`
 }
 
 type EnableKeyOutput struct{}
 
 type TagResourceInput struct {
-	KeyId string
-	Tags  []APITag
+	KeyId string   `required:"true" length:"1,2048"`
+	Tags  []APITag `required:"true"`
 }
 
 type APITag struct {
-	TagKey   string
-	TagValue string
+	TagKey   string `required:"true" length:"1,128"`
+	TagValue string `length:",256"`
 }
 
 type TagResourceOutput struct{}
 
 type UntagResourceInput struct {
-	KeyId string
-	Tags  []string
+	KeyId string   `required:"true" length:"1,2048"`
+	Tags  []string `required:"true"`
 }
 
 type UntagResourceOutput struct{}
 
 type ListResourceTagsInput struct {
-	KeyId string
+	KeyId string `required:"true" length:"1,2048"`
 }
 
 type ListResourceTagsOutput struct {
@@ -266,12 +267,12 @@ type APIKey struct {
 }
 
 type ReEncryptInput struct {
-	CiphertextBlob                 []byte
-	DestinationEncryptionAlgorithm types.EncryptionAlgorithm
+	CiphertextBlob                 []byte                    `required:"true" length:"1,6144"`
+	DestinationEncryptionAlgorithm types.EncryptionAlgorithm `enum:"SYMMETRIC_DEFAULT|RSAES_OAEP_SHA_1|RSAES_OAEP_SHA_256|SM2PKE"`
 	DestinationEncryptionContext   map[string]string
-	DestinationKeyId               string
-	SourceKeyId                    string
-	SourceEncryptionAlgorithm      types.EncryptionAlgorithm
+	DestinationKeyId               string                    `required:"true" length:"1,2048"`
+	SourceKeyId                    string                    `length:"1,2048"`
+	SourceEncryptionAlgorithm      types.EncryptionAlgorithm `enum:"SYMMETRIC_DEFAULT|RSAES_OAEP_SHA_1|RSAES_OAEP_SHA_256|SM2PKE"`
 	SourceEncryptionContext        map[string]string
 }
 
@@ -0,0 +1,317 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/ecr"
+)
+
+// The AWS base images include the runtime interface emulator, which serves the Invoke API on this port.
+// https://docs.aws.amazon.com/lambda/latest/dg/images-test.html
+const (
+	runtimeInterfacePort = "8080/tcp"
+	runtimeInvokePath    = "/2015-03-31/functions/function/invocations"
+	// How long to wait for a new container to accept invocations.
+	containerStartTimeout = 30 * time.Second
+)
+
+// Matches runtime identifiers like nodejs18.x, python3.11, java8.al2 and go1.x.
+var runtimeRegex = regexp.MustCompile(`^([a-z]+)(\d[a-z0-9.]*?)(\.x)?$`)
+
+// baseImage returns the AWS base image for a runtime identifier.
+// https://gallery.ecr.aws/lambda
+func baseImage(runtime string) (string, error) {
+	if version, ok := strings.CutPrefix(runtime, "provided."); ok {
+		return "public.ecr.aws/lambda/provided:" + version, nil
+	}
+	if runtime == "provided" {
+		return "public.ecr.aws/lambda/provided:alami", nil
+	}
+	match := runtimeRegex.FindStringSubmatch(runtime)
+	if match == nil {
+		return "", fmt.Errorf("unsupported runtime %q", runtime)
+	}
+	return "public.ecr.aws/lambda/" + match[1] + ":" + match[2], nil
+}
+
+// dockerExecutor runs each function version in a Docker container based on the AWS base images,
+// and invokes it through the runtime interface emulator.
+type dockerExecutor struct {
+	logger     *slog.Logger
+	httpClient *http.Client
+	// Nil if ECR is not enabled.
+	ecr *ecr.ECR
+
+	mu sync.Mutex
+	// By version key.
+	containers map[string]*container
+}
+
+type container struct {
+	id          string
+	functionArn string
+	url         string
+
+	// The runtime interface emulator handles one invocation at a time.
+	mu sync.Mutex
+	// How much of the container's logs earlier invocations returned.
+	logOffset int
+}
+
+func newDockerExecutor(logger *slog.Logger, ecr *ecr.ECR) *dockerExecutor {
+	return &dockerExecutor{
+		logger:     logger,
+		httpClient: &http.Client{},
+		ecr:        ecr,
+		containers: make(map[string]*container),
+	}
+}
+
+func docker(ctx context.Context, args ...string) ([]byte, error) {
+	return dockerWithInput(ctx, nil, args...)
+}
+
+func dockerWithInput(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdin = stdin
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+func (d *dockerExecutor) invoke(ctx context.Context, version *FunctionVersion, payload []byte) (*invocationResult, error) {
+	c, err := d.getContainer(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timeout := time.Duration(version.Timeout) * time.Second
+	invokeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, functionError, err := d.post(invokeCtx, c, payload)
+	logs := d.readLogs(c)
+	if err != nil {
+		if !errors.Is(invokeCtx.Err(), context.DeadlineExceeded) {
+			return nil, err
+		}
+		// The function is still running, so replace its container.
+		d.removeContainer(version.key())
+		message := fmt.Sprintf("%s Task timed out after %.2f seconds",
+			time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), timeout.Seconds())
+		return errorResult("Sandbox.Timedout", message, logs), nil
+	}
+
+	if functionError == "" && isErrorPayload(output) {
+		functionError = "Unhandled"
+	}
+	return &invocationResult{
+		payload:       output,
+		functionError: functionError,
+		logs:          logs,
+	}, nil
+}
+
+func (d *dockerExecutor) post(ctx context.Context, c *container, payload []byte) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return output, resp.Header.Get("X-Amz-Function-Error"), nil
+}
+
+// readLogs returns the container's output since the last invocation.
+func (d *dockerExecutor) readLogs(c *container) []byte {
+	logs, err := docker(context.Background(), "logs", c.id)
+	if err != nil {
+		d.logger.Warn("Reading container logs", "container", c.id, "error", err)
+		return nil
+	}
+	if c.logOffset > len(logs) {
+		c.logOffset = 0
+	}
+	logs = logs[c.logOffset:]
+	c.logOffset += len(logs)
+	return logs
+}
+
+func (d *dockerExecutor) getContainer(ctx context.Context, version *FunctionVersion) (*container, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if c, ok := d.containers[version.key()]; ok {
+		return c, nil
+	}
+	c, err := d.startContainer(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	d.containers[version.key()] = c
+	return c, nil
+}
+
+func (d *dockerExecutor) startContainer(ctx context.Context, version *FunctionVersion) (*container, error) {
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + runtimeInterfacePort}
+	for name, value := range version.environmentVariables() {
+		args = append(args, "--env", name+"="+value)
+	}
+	if version.PackageType == "Image" {
+		if version.ResolvedImageUri == "" {
+			args = append(args, version.ImageUri)
+		} else {
+			if err := d.loginToECR(ctx, version); err != nil {
+				return nil, err
+			}
+			args = append(args, version.ResolvedImageUri)
+		}
+	} else {
+		image, err := baseImage(version.Runtime)
+		if err != nil {
+			return nil, err
+		}
+		if version.optDir != "" {
+			args = append(args, "--volume", version.optDir+":/opt:ro")
+		}
+		args = append(args, "--volume", version.codeDir+":/var/task:ro", image, version.Handler)
+	}
+
+	output, err := docker(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	c := &container{
+		id:          strings.TrimSpace(string(output)),
+		functionArn: version.FunctionArn,
+	}
+	d.logger.Info("Started container", "function", version.FunctionArn, "container", c.id)
+
+	output, err = docker(ctx, "port", c.id, runtimeInterfacePort)
+	if err != nil {
+		d.stopContainer(c)
+		return nil, err
+	}
+	// There may be a line for each address family.
+	hostPort, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	c.url = "http://" + hostPort + runtimeInvokePath
+
+	if err := d.waitForContainer(ctx, c); err != nil {
+		d.stopContainer(c)
+		return nil, err
+	}
+	return c, nil
+}
+
+// loginToECR lets Docker pull images from the emulated ECR's registry, like aws ecr get-login-password does.
+// The registry serves the repositories of the region which issued the token, which is the function's.
+func (d *dockerExecutor) loginToECR(ctx context.Context, version *FunctionVersion) error {
+	a, err := arn.Parse(version.FunctionArn)
+	if err != nil {
+		return err
+	}
+	repositories, err := d.ecr.InRegion(a.Region)
+	if err != nil {
+		return err
+	}
+	output, awserr := repositories.GetAuthorizationToken(ecr.GetAuthorizationTokenInput{})
+	if awserr != nil {
+		return fmt.Errorf("getting ECR authorization token: %s", awserr.Body.Message)
+	}
+	authorizationData := output.AuthorizationData[0]
+	token, err := base64.StdEncoding.DecodeString(authorizationData.AuthorizationToken)
+	if err != nil {
+		return err
+	}
+	_, password, _ := strings.Cut(string(token), ":")
+	registry := strings.TrimPrefix(authorizationData.ProxyEndpoint, "http://")
+	_, err = dockerWithInput(ctx, strings.NewReader(password), "login", "--username", "AWS", "--password-stdin", registry)
+	return err
+}
+
+// waitForContainer waits until the runtime interface emulator accepts connections.
+func (d *dockerExecutor) waitForContainer(ctx context.Context, c *container) error {
+	deadline := time.Now().Add(containerStartTimeout)
+	hostPort := strings.TrimPrefix(strings.TrimSuffix(c.url, runtimeInvokePath), "http://")
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+hostPort+"/", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := d.httpClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container %s did not start: %v", c.id, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (d *dockerExecutor) stopContainer(c *container) {
+	_, err := docker(context.Background(), "kill", c.id)
+	if err != nil {
+		d.logger.Warn("Stopping container", "container", c.id, "error", err)
+	}
+}
+
+func (d *dockerExecutor) removeContainer(key string) {
+	d.mu.Lock()
+	c, ok := d.containers[key]
+	delete(d.containers, key)
+	d.mu.Unlock()
+
+	if ok {
+		d.stopContainer(c)
+	}
+}
+
+func (d *dockerExecutor) stop(functionArn string) {
+	d.mu.Lock()
+	var stopped []*container
+	for key, c := range d.containers {
+		if c.functionArn == functionArn {
+			stopped = append(stopped, c)
+			delete(d.containers, key)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, c := range stopped {
+		d.stopContainer(c)
+	}
+}
+
+var _ executor = (*dockerExecutor)(nil)
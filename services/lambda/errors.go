@@ -0,0 +1,81 @@
+package lambda
+
+import "aws-in-a-box/awserrors"
+
+func InvalidParameterValueException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterValueException", message)
+}
+
+func InvalidRequestContentException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidRequestContentException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    "ResourceNotFoundException",
+			Message: message,
+		},
+	}
+}
+
+func ResourceConflictException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 409,
+		Body: awserrors.ErrorBody{
+			Type:    "ResourceConflictException",
+			Message: message,
+		},
+	}
+}
+
+func PreconditionFailedException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 412,
+		Body: awserrors.ErrorBody{
+			Type:    "PreconditionFailedException",
+			Message: message,
+		},
+	}
+}
+
+func RequestTooLargeException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 413,
+		Body: awserrors.ErrorBody{
+			Type:    "RequestTooLargeException",
+			Message: message,
+		},
+	}
+}
+
+func ServiceException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 500,
+		Body: awserrors.ErrorBody{
+			Type:    "ServiceException",
+			Message: message,
+		},
+	}
+}
+
+func KMSDisabledException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 502,
+		Body: awserrors.ErrorBody{
+			Type:    "KMSDisabledException",
+			Message: message,
+		},
+	}
+}
+
+func KMSNotFoundException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 502,
+		Body: awserrors.ErrorBody{
+			Type:    "KMSNotFoundException",
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,450 @@
+package lambda
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/lambda/latest/dg/invocation-eventsourcemapping.html
+
+const (
+	maxBatchingWindowInSeconds = 300
+	// How often pollers check for new records when there were none.
+	eventSourcePollInterval = 100 * time.Millisecond
+	// How long pollers wait before retrying after the function failed.
+	eventSourceRetryInterval = time.Second
+)
+
+type EventSourceMapping struct {
+	UUID                    string
+	EventSourceArn          string
+	FunctionName            string
+	FunctionArn             string
+	BatchSize               int32
+	BatchingWindow          time.Duration
+	StartingPosition        string
+	ReportBatchItemFailures bool
+	LastModified            time.Time
+	// Enabled or Disabled.
+	State                string
+	LastProcessingResult string
+
+	// Closed to stop the poller.
+	stop chan struct{}
+}
+
+func (m *EventSourceMapping) configuration() EventSourceMappingConfiguration {
+	config := EventSourceMappingConfiguration{
+		UUID:                           m.UUID,
+		EventSourceArn:                 m.EventSourceArn,
+		FunctionArn:                    m.FunctionArn,
+		BatchSize:                      m.BatchSize,
+		MaximumBatchingWindowInSeconds: int32(m.BatchingWindow / time.Second),
+		StartingPosition:               m.StartingPosition,
+		LastModified:                   timestamp.EpochSeconds(m.LastModified),
+		LastProcessingResult:           m.LastProcessingResult,
+		State:                          m.State,
+		StateTransitionReason:          "USER_INITIATED",
+	}
+	if m.ReportBatchItemFailures {
+		config.FunctionResponseTypes = []string{"ReportBatchItemFailures"}
+	}
+	return config
+}
+
+// eventRecord is a record from an event source, in the format it is passed to the function.
+type eventRecord struct {
+	// How the function refers to the record when reporting batch item failures:
+	// the message ID for SQS, and the sequence number for streams.
+	id    string
+	event any
+}
+
+// eventSource reads batches of records from a queue or stream.
+type eventSource interface {
+	// poll waits for the next batch of records. It returns no records if the mapping was stopped.
+	poll(stop <-chan struct{}) ([]eventRecord, error)
+	// commit is called once the function has processed the batch, with whether each record succeeded.
+	// Failed records are delivered again.
+	commit(batch []eventRecord, succeeded []bool)
+}
+
+// batchItemFailures is the response of functions which report batch item failures.
+// https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html#services-sqs-batchfailurereporting
+type batchItemFailures struct {
+	BatchItemFailures []struct {
+		ItemIdentifier string `json:"itemIdentifier"`
+	} `json:"batchItemFailures"`
+}
+
+// sleep waits for the duration, returning false if the mapping was stopped first.
+func sleep(stop <-chan struct{}, d time.Duration) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_CreateEventSourceMapping.html
+func (l *Lambda) CreateEventSourceMapping(input CreateEventSourceMappingInput) (*CreateEventSourceMappingOutput, *awserrors.Error) {
+	batchingWindow := int32(0)
+	if input.MaximumBatchingWindowInSeconds != nil {
+		batchingWindow = *input.MaximumBatchingWindowInSeconds
+		if batchingWindow < 0 || batchingWindow > maxBatchingWindowInSeconds {
+			return nil, InvalidParameterValueException("MaximumBatchingWindowInSeconds must be between 0 and 300.")
+		}
+	}
+	reportBatchItemFailures := false
+	for _, responseType := range input.FunctionResponseTypes {
+		if responseType != "ReportBatchItemFailures" {
+			return nil, InvalidParameterValueException("Unsupported FunctionResponseType: " + responseType)
+		}
+		reportBatchItemFailures = true
+	}
+
+	source, batchSize, awserr := newEventSource(l.eventSourceServices(), input.EventSourceArn, input.StartingPosition,
+		input.BatchSize, batchingWindow)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, m := range l.eventSourceMappings {
+		if m.EventSourceArn == input.EventSourceArn && m.FunctionName == function.Name {
+			return nil, ResourceConflictException("The event source arn (" + input.EventSourceArn + ") and function (" +
+				function.Name + ") provided mapping already exists. Please update or delete the existing mapping with UUID " + m.UUID)
+		}
+	}
+
+	m := &EventSourceMapping{
+		UUID:                    uuid.Must(uuid.NewV4()).String(),
+		EventSourceArn:          input.EventSourceArn,
+		FunctionName:            function.Name,
+		FunctionArn:             function.ARN,
+		BatchSize:               batchSize,
+		BatchingWindow:          time.Duration(batchingWindow) * time.Second,
+		StartingPosition:        input.StartingPosition,
+		ReportBatchItemFailures: reportBatchItemFailures,
+		LastModified:            l.clock(),
+		State:                   "Disabled",
+		LastProcessingResult:    "No records processed",
+		stop:                    make(chan struct{}),
+	}
+	if input.Enabled == nil || *input.Enabled {
+		m.State = "Enabled"
+		go l.runEventSourceMapping(m, source)
+	}
+	l.eventSourceMappings = append(l.eventSourceMappings, m)
+
+	return &CreateEventSourceMappingOutput{
+		EventSourceMappingConfiguration: m.configuration(),
+		StatusCode:                      202,
+	}, nil
+}
+
+// EventSourceServices are the services event sources read from. Services which aren't enabled are nil.
+type EventSourceServices struct {
+	SQS      *sqs.SQS
+	Kinesis  *kinesis.Kinesis
+	DynamoDB *dynamodb.DynamoDB
+	Region   string
+}
+
+func (l *Lambda) eventSourceServices() EventSourceServices {
+	return EventSourceServices{
+		SQS:      l.sqs,
+		Kinesis:  l.kinesis,
+		DynamoDB: l.dynamoDB,
+		Region:   l.arnGenerator.Region,
+	}
+}
+
+// newEventSource validates the event source, and returns it with the batch size to use.
+func newEventSource(services EventSourceServices, eventSourceArn string, startingPosition string,
+	inputBatchSize *int32, batchingWindow int32) (eventSource, int32, *awserrors.Error) {
+	a, err := arn.Parse(eventSourceArn)
+	if err != nil {
+		return nil, 0, InvalidParameterValueException("Invalid EventSourceArn: " + eventSourceArn)
+	}
+	service := a.Service
+
+	batchSize := int32(100)
+	maxBatchSize := int32(10000)
+	if service == "sqs" {
+		batchSize = 10
+	}
+	if inputBatchSize != nil {
+		batchSize = *inputBatchSize
+		if batchSize < 1 || batchSize > maxBatchSize {
+			return nil, 0, InvalidParameterValueException("BatchSize must be between 1 and 10000.")
+		}
+	}
+
+	switch service {
+	case "sqs":
+		if startingPosition != "" {
+			return nil, 0, InvalidParameterValueException("StartingPosition is not valid for SQS event sources.")
+		}
+		if batchSize > 10 && batchingWindow == 0 {
+			return nil, 0, InvalidParameterValueException(
+				"Maximum batch window in seconds must be greater than 0 if maximum batch size is greater than 10")
+		}
+		source, awserr := newSQSEventSource(services, eventSourceArn, batchSize, batchingWindow)
+		return source, batchSize, awserr
+	case "kinesis", "dynamodb":
+		if startingPosition != "TRIM_HORIZON" && startingPosition != "LATEST" {
+			return nil, 0, InvalidParameterValueException("StartingPosition must be TRIM_HORIZON or LATEST for stream event sources.")
+		}
+		var shards []streamShard
+		var awserr *awserrors.Error
+		if service == "kinesis" {
+			shards, awserr = kinesisShards(services, eventSourceArn, startingPosition)
+		} else {
+			shards, awserr = dynamoDBShards(services, eventSourceArn, startingPosition)
+		}
+		if awserr != nil {
+			return nil, 0, awserr
+		}
+		return newStreamEventSource(shards, batchSize, batchingWindow), batchSize, nil
+	default:
+		return nil, 0, InvalidParameterValueException("Unsupported event source: " + eventSourceArn)
+	}
+}
+
+// EventSource reads batches of records from an SQS queue, or a Kinesis or DynamoDB stream, in the format functions
+// receive them. Other services which read from the same sources, like EventBridge Pipes, use it.
+type EventSource struct {
+	source eventSource
+	batch  []eventRecord
+}
+
+// NewEventSource validates the event source, with the same defaults and limits as event source mappings.
+func NewEventSource(services EventSourceServices, eventSourceArn string, startingPosition string,
+	batchSize *int32, batchingWindow int32) (*EventSource, *awserrors.Error) {
+	source, _, awserr := newEventSource(services, eventSourceArn, startingPosition, batchSize, batchingWindow)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &EventSource{source: source}, nil
+}
+
+// Poll waits for the next batch of records. It returns no records if stop was closed.
+func (s *EventSource) Poll(stop <-chan struct{}) ([]any, error) {
+	batch, err := s.source.poll(stop)
+	if err != nil {
+		return nil, err
+	}
+	s.batch = batch
+	records := make([]any, len(batch))
+	for i, record := range batch {
+		records[i] = record.event
+	}
+	return records, nil
+}
+
+// Commit is called once the last batch was processed, with whether each record succeeded.
+// Failed records are read again.
+func (s *EventSource) Commit(succeeded []bool) {
+	s.source.commit(s.batch, succeeded)
+	s.batch = nil
+}
+
+func (l *Lambda) runEventSourceMapping(m *EventSourceMapping, source eventSource) {
+	for {
+		batch, err := source.poll(m.stop)
+		if err != nil {
+			l.logger.Warn("Polling event source", "mapping", m.UUID, "source", m.EventSourceArn, "error", err)
+			l.setLastProcessingResult(m, "PROBLEM: "+err.Error())
+			if !sleep(m.stop, eventSourceRetryInterval) {
+				return
+			}
+			continue
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		succeeded := l.processBatch(m, batch)
+		source.commit(batch, succeeded)
+		if slices.Contains(succeeded, false) && !sleep(m.stop, eventSourceRetryInterval) {
+			return
+		}
+	}
+}
+
+// processBatch invokes the function with the batch, and returns whether each record was processed.
+func (l *Lambda) processBatch(m *EventSourceMapping, batch []eventRecord) []bool {
+	events := make([]any, len(batch))
+	for i, record := range batch {
+		events[i] = record.event
+	}
+	payload, _ := json.Marshal(map[string]any{"Records": events})
+
+	succeeded := make([]bool, len(batch))
+	result, err := l.invokeLatest(m.FunctionName, payload)
+	if err != nil || result.functionError != "" {
+		l.logger.Warn("Function failed to process batch", "mapping", m.UUID, "function", m.FunctionArn, "error", err)
+		l.setLastProcessingResult(m, "PROBLEM: Function call failed")
+		return succeeded
+	}
+	l.setLastProcessingResult(m, "OK")
+
+	for i := range succeeded {
+		succeeded[i] = true
+	}
+	if !m.ReportBatchItemFailures {
+		return succeeded
+	}
+
+	var response batchItemFailures
+	// Functions which don't return a response succeeded.
+	if len(result.payload) == 0 || string(result.payload) == "null" {
+		return succeeded
+	}
+	if err := json.Unmarshal(result.payload, &response); err != nil {
+		// An invalid response fails the whole batch.
+		return make([]bool, len(batch))
+	}
+	for _, failure := range response.BatchItemFailures {
+		i := slices.IndexFunc(batch, func(record eventRecord) bool {
+			return record.id == failure.ItemIdentifier
+		})
+		if i == -1 {
+			return make([]bool, len(batch))
+		}
+		succeeded[i] = false
+	}
+	return succeeded
+}
+
+// invokeLatest invokes the latest version of the function, for event source mappings.
+func (l *Lambda) invokeLatest(functionName string, payload []byte) (*invocationResult, error) {
+	l.mu.Lock()
+	version, awserr := l.lockedGetVersion(functionName, "")
+	l.mu.Unlock()
+	if awserr != nil {
+		return nil, errors.New(awserr.Body.Message)
+	}
+	result, awserr := l.invoke(version, payload)
+	if awserr != nil {
+		return nil, errors.New(awserr.Body.Message)
+	}
+	return result, nil
+}
+
+func (l *Lambda) setLastProcessingResult(m *EventSourceMapping, result string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m.LastProcessingResult = result
+}
+
+func (l *Lambda) lockedGetEventSourceMapping(id string) (int, *awserrors.Error) {
+	i := slices.IndexFunc(l.eventSourceMappings, func(m *EventSourceMapping) bool {
+		return m.UUID == id
+	})
+	if i == -1 {
+		return 0, ResourceNotFoundException("The resource you requested does not exist.")
+	}
+	return i, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_GetEventSourceMapping.html
+func (l *Lambda) GetEventSourceMapping(input GetEventSourceMappingInput) (*GetEventSourceMappingOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i, awserr := l.lockedGetEventSourceMapping(input.UUID)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetEventSourceMappingOutput{
+		EventSourceMappingConfiguration: l.eventSourceMappings[i].configuration(),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_DeleteEventSourceMapping.html
+func (l *Lambda) DeleteEventSourceMapping(input DeleteEventSourceMappingInput) (*DeleteEventSourceMappingOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i, awserr := l.lockedGetEventSourceMapping(input.UUID)
+	if awserr != nil {
+		return nil, awserr
+	}
+	m := l.eventSourceMappings[i]
+	l.eventSourceMappings = slices.Delete(l.eventSourceMappings, i, i+1)
+	// A batch being processed still completes.
+	close(m.stop)
+
+	config := m.configuration()
+	config.State = "Deleting"
+	return &DeleteEventSourceMappingOutput{
+		EventSourceMappingConfiguration: config,
+		StatusCode:                      202,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_ListEventSourceMappings.html
+func (l *Lambda) ListEventSourceMappings(input ListEventSourceMappingsInput) (*ListEventSourceMappingsOutput, *awserrors.Error) {
+	maxItems := input.MaxItems
+	if maxItems == 0 {
+		maxItems = 100
+	}
+	if maxItems < 1 || maxItems > 10000 {
+		return nil, InvalidParameterValueException("MaxItems must be between 1 and 10000.")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	functionName := ""
+	if input.FunctionName != "" {
+		var awserr *awserrors.Error
+		functionName, _, awserr = parseFunctionName(input.FunctionName, "")
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	// Mappings are listed in the order they were created, and the marker is the UUID of the next one.
+	started := input.Marker == ""
+	output := &ListEventSourceMappingsOutput{
+		EventSourceMappings: []EventSourceMappingConfiguration{},
+	}
+	for _, m := range l.eventSourceMappings {
+		if m.UUID == input.Marker {
+			started = true
+		}
+		if !started ||
+			(input.EventSourceArn != "" && m.EventSourceArn != input.EventSourceArn) ||
+			(functionName != "" && m.FunctionName != functionName) {
+			continue
+		}
+		if len(output.EventSourceMappings) == maxItems {
+			output.NextMarker = m.UUID
+			break
+		}
+		output.EventSourceMappings = append(output.EventSourceMappings, m.configuration())
+	}
+	return output, nil
+}
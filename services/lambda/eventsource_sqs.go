@@ -0,0 +1,154 @@
+package lambda
+
+import (
+	"errors"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/sqs"
+)
+
+// sqsEventSource receives messages from a queue, and deletes them once they're processed.
+// Messages which fail are received again once their visibility timeout lapses.
+// https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html
+type sqsEventSource struct {
+	sqs            *sqs.SQS
+	queueArn       string
+	queueUrl       string
+	region         string
+	batchSize      int
+	batchingWindow time.Duration
+}
+
+// sqsEvent is a message, in the format functions receive it.
+type sqsEvent struct {
+	MessageId         string                         `json:"messageId"`
+	ReceiptHandle     string                         `json:"receiptHandle"`
+	Body              string                         `json:"body"`
+	Attributes        map[string]string              `json:"attributes"`
+	MessageAttributes map[string]sqsMessageAttribute `json:"messageAttributes"`
+	MD5OfBody         string                         `json:"md5OfBody"`
+	EventSource       string                         `json:"eventSource"`
+	EventSourceARN    string                         `json:"eventSourceARN"`
+	AwsRegion         string                         `json:"awsRegion"`
+}
+
+type sqsMessageAttribute struct {
+	StringValue      *string  `json:"stringValue,omitempty"`
+	BinaryValue      []byte   `json:"binaryValue,omitempty"`
+	StringListValues []string `json:"stringListValues"`
+	BinaryListValues [][]byte `json:"binaryListValues"`
+	DataType         string   `json:"dataType"`
+}
+
+func newSQSEventSource(services EventSourceServices, queueArn string, batchSize int32, batchingWindow int32) (*sqsEventSource, *awserrors.Error) {
+	if services.SQS == nil {
+		return nil, InvalidParameterValueException("SQS is not enabled, so it can't be used as an event source.")
+	}
+	a, err := arn.Parse(queueArn)
+	if err != nil {
+		return nil, InvalidParameterValueException("Invalid EventSourceArn: " + queueArn)
+	}
+	queues, err := services.SQS.ForARN(queueArn)
+	if err != nil {
+		return nil, InvalidParameterValueException(err.Error())
+	}
+	output, awserr := queues.GetQueueUrl(sqs.GetQueueUrlInput{
+		QueueName: a.ResourceId(),
+	})
+	if awserr != nil {
+		return nil, InvalidParameterValueException("Error occurred while ReceiveMessage. SQS Error Code: " +
+			awserr.Body.Type + ". SQS Error Message: " + awserr.Body.Message)
+	}
+	// Messages are from the queue's region, which needn't be the function's.
+	region := services.Region
+	if a.Region != "" {
+		region = a.Region
+	}
+	return &sqsEventSource{
+		sqs:            queues,
+		queueArn:       queueArn,
+		queueUrl:       output.QueueUrl,
+		region:         region,
+		batchSize:      int(batchSize),
+		batchingWindow: time.Duration(batchingWindow) * time.Second,
+	}, nil
+}
+
+func (s *sqsEventSource) poll(stop <-chan struct{}) ([]eventRecord, error) {
+	var batch []eventRecord
+	var deadline time.Time
+	for {
+		select {
+		case <-stop:
+			return nil, nil
+		default:
+		}
+
+		output, awserr := s.sqs.ReceiveMessage(sqs.ReceiveMessageInput{
+			QueueUrl:              s.queueUrl,
+			MaxNumberOfMessages:   min(s.batchSize-len(batch), 10),
+			AttributeNames:        []sqs.AttributeName{sqs.All},
+			MessageAttributeNames: []string{"All"},
+		})
+		if awserr != nil {
+			return nil, errors.New(awserr.Body.Message)
+		}
+		for _, message := range output.Messages {
+			batch = append(batch, eventRecord{
+				id:    message.MessageId,
+				event: s.event(message),
+			})
+		}
+
+		if len(batch) > 0 && deadline.IsZero() {
+			deadline = time.Now().Add(s.batchingWindow)
+		}
+		if len(batch) == s.batchSize || (len(batch) > 0 && !time.Now().Before(deadline)) {
+			return batch, nil
+		}
+		// Keep receiving while there are messages, to fill the batch.
+		if len(output.Messages) == 0 && !sleep(stop, eventSourcePollInterval) {
+			return nil, nil
+		}
+	}
+}
+
+func (s *sqsEventSource) event(message sqs.APIMessage) sqsEvent {
+	attributes := make(map[string]sqsMessageAttribute)
+	for name, attribute := range message.MessageAttributes {
+		a := sqsMessageAttribute{
+			BinaryValue:      attribute.BinaryValue,
+			StringListValues: []string{},
+			BinaryListValues: [][]byte{},
+			DataType:         attribute.DataType,
+		}
+		if attribute.BinaryValue == nil {
+			a.StringValue = &attribute.StringValue
+		}
+		attributes[name] = a
+	}
+	return sqsEvent{
+		MessageId:         message.MessageId,
+		ReceiptHandle:     message.ReceiptHandle,
+		Body:              message.Body,
+		Attributes:        message.Attributes,
+		MessageAttributes: attributes,
+		MD5OfBody:         message.MD5OfBody,
+		EventSource:       "aws:sqs",
+		EventSourceARN:    s.queueArn,
+		AwsRegion:         s.region,
+	}
+}
+
+func (s *sqsEventSource) commit(batch []eventRecord, succeeded []bool) {
+	for i, record := range batch {
+		if succeeded[i] {
+			s.sqs.DeleteMessage(sqs.DeleteMessageInput{
+				QueueUrl:      s.queueUrl,
+				ReceiptHandle: record.event.(sqsEvent).ReceiptHandle,
+			})
+		}
+	}
+}
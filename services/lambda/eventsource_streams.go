@@ -0,0 +1,276 @@
+package lambda
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/kinesis"
+)
+
+// streamShard reads the records of a Kinesis or DynamoDB stream shard, in order.
+type streamShard interface {
+	// read returns the records added since the last read.
+	read() ([]eventRecord, error)
+}
+
+// streamEventSource reads batches of records from each shard of a stream.
+// Like Lambda, a batch which fails is retried until it succeeds, which blocks the shard.
+// If the function reports batch item failures, processing resumes from the first failed record.
+// https://docs.aws.amazon.com/lambda/latest/dg/with-kinesis.html
+type streamEventSource struct {
+	shards         []*shardPoller
+	batchSize      int
+	batchingWindow time.Duration
+	// The shard the last batch came from, which is polled last next time, so each shard gets a turn.
+	last int
+}
+
+type shardPoller struct {
+	shard streamShard
+	// Records which have been read but not processed.
+	pending []eventRecord
+	// When the first pending record was read, to enforce the batching window.
+	pendingSince time.Time
+}
+
+func newStreamEventSource(shards []streamShard, batchSize int32, batchingWindow int32) *streamEventSource {
+	s := &streamEventSource{
+		batchSize:      int(batchSize),
+		batchingWindow: time.Duration(batchingWindow) * time.Second,
+	}
+	for _, shard := range shards {
+		s.shards = append(s.shards, &shardPoller{shard: shard})
+	}
+	return s
+}
+
+func (s *streamEventSource) poll(stop <-chan struct{}) ([]eventRecord, error) {
+	for {
+		select {
+		case <-stop:
+			return nil, nil
+		default:
+		}
+
+		for i := range s.shards {
+			index := (s.last + 1 + i) % len(s.shards)
+			p := s.shards[index]
+			if len(p.pending) < s.batchSize {
+				records, err := p.shard.read()
+				if err != nil {
+					return nil, err
+				}
+				if len(p.pending) == 0 && len(records) > 0 {
+					p.pendingSince = time.Now()
+				}
+				p.pending = append(p.pending, records...)
+			}
+			if len(p.pending) >= s.batchSize ||
+				(len(p.pending) > 0 && !time.Now().Before(p.pendingSince.Add(s.batchingWindow))) {
+				s.last = index
+				return p.pending[:min(len(p.pending), s.batchSize)], nil
+			}
+		}
+
+		if !sleep(stop, eventSourcePollInterval) {
+			return nil, nil
+		}
+	}
+}
+
+func (s *streamEventSource) commit(batch []eventRecord, succeeded []bool) {
+	p := s.shards[s.last]
+	processed := 0
+	for processed < len(batch) && succeeded[processed] {
+		processed++
+	}
+	p.pending = p.pending[processed:]
+	p.pendingSince = time.Now()
+}
+
+// kinesisShard reads a Kinesis shard.
+type kinesisShard struct {
+	kinesis   *kinesis.Kinesis
+	streamArn string
+	shardId   string
+	region    string
+	iterator  string
+}
+
+// kinesisEvent is a Kinesis record, in the format functions receive it.
+type kinesisEvent struct {
+	Kinesis        kinesisEventRecord `json:"kinesis"`
+	EventSource    string             `json:"eventSource"`
+	EventVersion   string             `json:"eventVersion"`
+	EventID        string             `json:"eventID"`
+	EventName      string             `json:"eventName"`
+	AwsRegion      string             `json:"awsRegion"`
+	EventSourceARN string             `json:"eventSourceARN"`
+}
+
+type kinesisEventRecord struct {
+	KinesisSchemaVersion string `json:"kinesisSchemaVersion"`
+	PartitionKey         string `json:"partitionKey"`
+	SequenceNumber       string `json:"sequenceNumber"`
+	// Base64 encoded.
+	Data                        string  `json:"data"`
+	ApproximateArrivalTimestamp float64 `json:"approximateArrivalTimestamp"`
+}
+
+func kinesisShards(services EventSourceServices, streamArn string, startingPosition string) ([]streamShard, *awserrors.Error) {
+	if services.Kinesis == nil {
+		return nil, InvalidParameterValueException("Kinesis is not enabled, so it can't be used as an event source.")
+	}
+	if !strings.Contains(streamArn, ":stream/") {
+		return nil, InvalidParameterValueException("Invalid Kinesis stream ARN: " + streamArn)
+	}
+	streams, err := services.Kinesis.ForARN(streamArn)
+	if err != nil {
+		return nil, InvalidParameterValueException(err.Error())
+	}
+	// Records are from the stream's region, which needn't be the function's.
+	region := services.Region
+	if a, err := arn.Parse(streamArn); err == nil && a.Region != "" {
+		region = a.Region
+	}
+	output, awserr := streams.ListShards(kinesis.ListShardsInput{StreamARN: streamArn})
+	if awserr != nil {
+		return nil, InvalidParameterValueException("Stream not found: " + streamArn)
+	}
+
+	var shards []streamShard
+	for _, shard := range output.Shards {
+		iterator, awserr := streams.GetShardIterator(kinesis.GetShardIteratorInput{
+			StreamARN:         streamArn,
+			ShardId:           shard.ShardId,
+			ShardIteratorType: startingPosition,
+		})
+		if awserr != nil {
+			return nil, awserr
+		}
+		shards = append(shards, &kinesisShard{
+			kinesis:   streams,
+			streamArn: streamArn,
+			shardId:   shard.ShardId,
+			region:    region,
+			iterator:  iterator.ShardIterator,
+		})
+	}
+	return shards, nil
+}
+
+func (s *kinesisShard) read() ([]eventRecord, error) {
+	output, awserr := s.kinesis.GetRecords(kinesis.GetRecordsInput{ShardIterator: s.iterator})
+	if awserr != nil {
+		return nil, errors.New(awserr.Body.Message)
+	}
+	s.iterator = output.NextShardIterator
+
+	var records []eventRecord
+	for _, record := range output.Records {
+		records = append(records, eventRecord{
+			id: record.SequenceNumber,
+			event: kinesisEvent{
+				Kinesis: kinesisEventRecord{
+					KinesisSchemaVersion:        "1.0",
+					PartitionKey:                record.PartitionKey,
+					SequenceNumber:              record.SequenceNumber,
+					Data:                        record.Data,
+					ApproximateArrivalTimestamp: float64(record.ApproximateArrivalTimestamp),
+				},
+				EventSource:    "aws:kinesis",
+				EventVersion:   "1.0",
+				EventID:        s.shardId + ":" + record.SequenceNumber,
+				EventName:      "aws:kinesis:record",
+				AwsRegion:      s.region,
+				EventSourceARN: s.streamArn,
+			},
+		})
+	}
+	return records, nil
+}
+
+// dynamoDBShard reads the shard of a DynamoDB stream.
+type dynamoDBShard struct {
+	dynamoDB  *dynamodb.DynamoDB
+	streamArn string
+	iterator  string
+}
+
+// dynamoDBEvent is a DynamoDB stream record, in the format functions receive it.
+type dynamoDBEvent struct {
+	EventID        string                   `json:"eventID"`
+	EventName      string                   `json:"eventName"`
+	EventVersion   string                   `json:"eventVersion"`
+	EventSource    string                   `json:"eventSource"`
+	AwsRegion      string                   `json:"awsRegion"`
+	Dynamodb       dynamodb.APIStreamRecord `json:"dynamodb"`
+	EventSourceARN string                   `json:"eventSourceARN"`
+	UserIdentity   *dynamodb.APIIdentity    `json:"userIdentity,omitempty"`
+}
+
+func dynamoDBShards(services EventSourceServices, streamArn string, startingPosition string) ([]streamShard, *awserrors.Error) {
+	if services.DynamoDB == nil {
+		return nil, InvalidParameterValueException("DynamoDB is not enabled, so it can't be used as an event source.")
+	}
+	streams, err := services.DynamoDB.ForARN(streamArn)
+	if err != nil {
+		return nil, InvalidParameterValueException(err.Error())
+	}
+	output, awserr := streams.DescribeStream(dynamodb.DescribeStreamInput{StreamArn: streamArn})
+	if awserr != nil {
+		return nil, InvalidParameterValueException("Stream not found: " + streamArn)
+	}
+
+	var shards []streamShard
+	for _, shard := range output.StreamDescription.Shards {
+		iterator, awserr := streams.GetShardIterator(dynamodb.GetShardIteratorInput{
+			StreamArn:         streamArn,
+			ShardId:           shard.ShardId,
+			ShardIteratorType: startingPosition,
+		})
+		if awserr != nil {
+			return nil, awserr
+		}
+		shards = append(shards, &dynamoDBShard{
+			dynamoDB:  streams,
+			streamArn: streamArn,
+			iterator:  iterator.ShardIterator,
+		})
+	}
+	return shards, nil
+}
+
+func (s *dynamoDBShard) read() ([]eventRecord, error) {
+	if s.iterator == "" {
+		// The stream was disabled and all its records were read.
+		return nil, nil
+	}
+	output, awserr := s.dynamoDB.GetRecords(dynamodb.GetRecordsInput{ShardIterator: s.iterator})
+	if awserr != nil {
+		return nil, errors.New(awserr.Body.Message)
+	}
+	s.iterator = output.NextShardIterator
+
+	var records []eventRecord
+	for _, record := range output.Records {
+		records = append(records, eventRecord{
+			id: record.Dynamodb.SequenceNumber,
+			event: dynamoDBEvent{
+				EventID:        record.EventID,
+				EventName:      record.EventName,
+				EventVersion:   record.EventVersion,
+				EventSource:    record.EventSource,
+				AwsRegion:      record.AwsRegion,
+				Dynamodb:       record.Dynamodb,
+				EventSourceARN: s.streamArn,
+				UserIdentity:   record.UserIdentity,
+			},
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,320 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/sqs"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+// recordingExecutor records the batches of records functions are invoked with.
+type recordingExecutor struct {
+	mu      sync.Mutex
+	batches [][]map[string]any
+	// Returns the function's response to a batch.
+	respond func(records []map[string]any) string
+}
+
+func (r *recordingExecutor) invoke(ctx context.Context, version *FunctionVersion, payload []byte) (*invocationResult, error) {
+	var event struct {
+		Records []map[string]any
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.batches = append(r.batches, event.Records)
+	r.mu.Unlock()
+
+	response := "null"
+	if r.respond != nil {
+		response = r.respond(event.Records)
+	}
+	return &invocationResult{payload: []byte(response)}, nil
+}
+
+func (r *recordingExecutor) stop(functionArn string) {}
+
+func (r *recordingExecutor) getBatches() [][]map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.batches
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out")
+}
+
+func newLambdaWithEventSources(options Options, respond func(records []map[string]any) string) (*Lambda, *recordingExecutor) {
+	options.ArnGenerator = generator
+	l := New(options)
+	executor := &recordingExecutor{respond: respond}
+	l.executor = executor
+	return l, executor
+}
+
+func TestSQSEventSourceMapping(t *testing.T) {
+	s := sqs.New(sqs.Options{ArnGenerator: generator})
+	queue, awserr := s.CreateQueue(sqs.CreateQueueInput{
+		QueueName: "queue",
+		// So failed messages are received again immediately.
+		Attributes: map[string]string{"VisibilityTimeout": "0"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	for _, body := range []string{"one", "fail", "two"} {
+		_, awserr := s.SendMessage(sqs.SendMessageInput{QueueUrl: queue.QueueUrl, MessageBody: body})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	l, executor := newLambdaWithEventSources(Options{SQS: s}, func(records []map[string]any) string {
+		var response batchItemFailures
+		for _, record := range records {
+			if record["body"] == "fail" {
+				response.BatchItemFailures = append(response.BatchItemFailures, struct {
+					ItemIdentifier string `json:"itemIdentifier"`
+				}{record["messageId"].(string)})
+			}
+		}
+		data, _ := json.Marshal(response)
+		return string(data)
+	})
+	createFunction(t, l, "function", "")
+
+	queueArn := generator.GenerateWithoutType("sqs", "queue")
+	mapping, awserr := l.CreateEventSourceMapping(CreateEventSourceMappingInput{
+		EventSourceArn:        queueArn,
+		FunctionName:          "function",
+		FunctionResponseTypes: []string{"ReportBatchItemFailures"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if mapping.StatusCode != 202 || mapping.State != "Enabled" || mapping.BatchSize != 10 ||
+		mapping.FunctionArn != "arn:aws:lambda:us-east-1:123456789012:function:function" {
+		t.Fatal("Unexpected mapping", mapping)
+	}
+
+	// The failed message is delivered again on its own, once the others were deleted.
+	waitFor(t, func() bool {
+		batches := executor.getBatches()
+		if len(batches) < 2 {
+			return false
+		}
+		last := batches[len(batches)-1]
+		return len(last) == 1 && last[0]["body"] == "fail"
+	})
+	first := executor.getBatches()[0]
+	if len(first) != 3 || first[0]["eventSource"] != "aws:sqs" || first[0]["eventSourceARN"] != queueArn {
+		t.Fatal("Unexpected batch", first)
+	}
+
+	_, awserr = l.CreateEventSourceMapping(CreateEventSourceMappingInput{
+		EventSourceArn: queueArn,
+		FunctionName:   "function",
+	})
+	if awserr == nil || awserr.Code != 409 {
+		t.Fatal("Expected conflict", awserr)
+	}
+
+	deleted, awserr := l.DeleteEventSourceMapping(DeleteEventSourceMappingInput{UUID: mapping.UUID})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if deleted.State != "Deleting" {
+		t.Fatal("Unexpected state", deleted.State)
+	}
+	_, awserr = l.GetEventSourceMapping(GetEventSourceMappingInput{UUID: mapping.UUID})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestKinesisEventSourceMapping(t *testing.T) {
+	k := kinesis.New(kinesis.Options{ArnGenerator: generator})
+	_, awserr := k.CreateStream(kinesis.CreateStreamInput{StreamName: "stream", ShardCount: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	for _, data := range []string{"MQ==", "Mg==", "Mw=="} {
+		_, awserr := k.PutRecord(kinesis.PutRecordInput{StreamName: "stream", PartitionKey: "key", Data: data})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	l, executor := newLambdaWithEventSources(Options{Kinesis: k}, nil)
+	createFunction(t, l, "function", "")
+
+	streamArn := generator.Generate("kinesis", "stream", "stream")
+	_, awserr = l.CreateEventSourceMapping(CreateEventSourceMappingInput{
+		EventSourceArn: streamArn,
+		FunctionName:   "function",
+		BatchSize:      ptr(int32(2)),
+	})
+	if awserr == nil {
+		t.Fatal("Expected error for missing starting position")
+	}
+	_, awserr = l.CreateEventSourceMapping(CreateEventSourceMappingInput{
+		EventSourceArn:   streamArn,
+		FunctionName:     "function",
+		BatchSize:        ptr(int32(2)),
+		StartingPosition: "TRIM_HORIZON",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	waitFor(t, func() bool {
+		return len(executor.getBatches()) == 2
+	})
+	var data []string
+	for _, batch := range executor.getBatches() {
+		for _, record := range batch {
+			data = append(data, record["kinesis"].(map[string]any)["data"].(string))
+		}
+	}
+	if len(data) != 3 || data[0] != "MQ==" || data[1] != "Mg==" || data[2] != "Mw==" {
+		t.Fatal("Unexpected records", data)
+	}
+}
+
+func TestKinesisEventSourceMappingInOtherRegion(t *testing.T) {
+	k, err := kinesis.New(kinesis.Options{ArnGenerator: generator}).InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, awserr := k.CreateStream(kinesis.CreateStreamInput{StreamName: "stream", ShardCount: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = k.PutRecord(kinesis.PutRecordInput{StreamName: "stream", PartitionKey: "key", Data: "MQ=="})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	l, executor := newLambdaWithEventSources(Options{Kinesis: k}, nil)
+	createFunction(t, l, "function", "")
+	_, awserr = l.CreateEventSourceMapping(CreateEventSourceMappingInput{
+		EventSourceArn:   "arn:aws:kinesis:eu-west-1:" + generator.AwsAccountId + ":stream/stream",
+		FunctionName:     "function",
+		StartingPosition: "TRIM_HORIZON",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	waitFor(t, func() bool {
+		return len(executor.getBatches()) == 1
+	})
+	// The records are from the stream's region, rather than the function's.
+	if region := executor.getBatches()[0][0]["awsRegion"]; region != "eu-west-1" {
+		t.Fatal("Unexpected region", region)
+	}
+}
+
+func TestDynamoDBEventSourceMapping(t *testing.T) {
+	d, err := dynamodb.New(dynamodb.Options{ArnGenerator: generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, awserr := d.CreateTable(dynamodb.CreateTableInput{
+		TableName: "table",
+		AttributeDefinitions: []dynamodb.APIAttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+		},
+		KeySchema: []dynamodb.APIKeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+		},
+		StreamSpecification: &dynamodb.APIStreamSpecification{
+			StreamEnabled:  true,
+			StreamViewType: "NEW_IMAGE",
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	l, executor := newLambdaWithEventSources(Options{DynamoDB: d}, nil)
+	createFunction(t, l, "function", "")
+
+	streamArn := table.TableDescription.LatestStreamArn
+	mapping, awserr := l.CreateEventSourceMapping(CreateEventSourceMappingInput{
+		EventSourceArn:   streamArn,
+		FunctionName:     "function",
+		StartingPosition: "LATEST",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	_, awserr = d.PutItem(dynamodb.PutItemInput{TableName: "table", Item: dynamodb.APIItem{"pk": {S: "a"}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	waitFor(t, func() bool {
+		return len(executor.getBatches()) == 1
+	})
+	record := executor.getBatches()[0][0]
+	if record["eventName"] != "INSERT" || record["eventSourceARN"] != streamArn {
+		t.Fatal("Unexpected record", record)
+	}
+
+	list, awserr := l.ListEventSourceMappings(ListEventSourceMappingsInput{FunctionName: "function"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.EventSourceMappings) != 1 || list.EventSourceMappings[0].UUID != mapping.UUID {
+		t.Fatal("Unexpected mappings", list.EventSourceMappings)
+	}
+	waitFor(t, func() bool {
+		output, _ := l.GetEventSourceMapping(GetEventSourceMappingInput{UUID: mapping.UUID})
+		return output.LastProcessingResult == "OK"
+	})
+}
+
+func TestCreateEventSourceMappingErrors(t *testing.T) {
+	l, _ := newLambdaWithEventSources(Options{}, nil)
+	createFunction(t, l, "function", "")
+
+	cases := []CreateEventSourceMappingInput{
+		{EventSourceArn: "queue", FunctionName: "function"},
+		{EventSourceArn: generator.GenerateWithoutType("sns", "topic"), FunctionName: "function"},
+		// SQS is not enabled.
+		{EventSourceArn: generator.GenerateWithoutType("sqs", "queue"), FunctionName: "function"},
+		{EventSourceArn: generator.GenerateWithoutType("sqs", "queue"), FunctionName: "function", BatchSize: ptr(int32(100))},
+		{EventSourceArn: generator.GenerateWithoutType("sqs", "queue"), FunctionName: "function", MaximumBatchingWindowInSeconds: ptr(int32(301))},
+	}
+	for _, input := range cases {
+		_, awserr := l.CreateEventSourceMapping(input)
+		if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+			t.Fatal("Expected invalid parameter for", input, awserr)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
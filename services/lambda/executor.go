@@ -0,0 +1,103 @@
+package lambda
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// executor runs function code. Each function version gets its own execution environment,
+// which is started on first invocation and reused until the function's code is updated.
+type executor interface {
+	invoke(ctx context.Context, version *FunctionVersion, payload []byte) (*invocationResult, error)
+	// stop shuts down the execution environments of the function's versions.
+	stop(functionArn string)
+}
+
+type invocationResult struct {
+	payload []byte
+	// Unhandled if the function returned an error, otherwise empty.
+	functionError string
+	// The function's output during the invocation.
+	logs []byte
+}
+
+// errorResult is the result of an invocation which failed outside of the function's code,
+// for example because it timed out.
+func errorResult(errorType string, message string, logs []byte) *invocationResult {
+	payload, _ := json.Marshal(map[string]string{
+		"errorType":    errorType,
+		"errorMessage": message,
+	})
+	return &invocationResult{
+		payload:       payload,
+		functionError: "Unhandled",
+		logs:          logs,
+	}
+}
+
+// isErrorPayload reports whether the payload is an error returned by the runtime,
+// which has errorType and errorMessage fields.
+func isErrorPayload(payload []byte) bool {
+	var body struct {
+		ErrorType    *string `json:"errorType"`
+		ErrorMessage *string `json:"errorMessage"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return false
+	}
+	return body.ErrorType != nil && body.ErrorMessage != nil
+}
+
+// extractZip extracts the function's deployment package to dir.
+func extractZip(zipFile []byte, dir string) error {
+	r, err := zip.NewReader(bytes.NewReader(zipFile), int64(len(zipFile)))
+	if err != nil {
+		return err
+	}
+	for _, f := range r.File {
+		path := filepath.Join(dir, f.Name)
+		if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file path %q", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := extractFile(f, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, path string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Keep the executable bit, which custom runtimes' bootstrap files need.
+	mode := f.Mode().Perm() | 0o644
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,418 @@
+package lambda
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/lambda/latest/dg/lambda-urls.html
+
+const (
+	// Function URLs are served at http://<addr>/lambda-url/<id>/, since <id>.lambda-url.<region>.on.aws
+	// can't resolve to this server. Requests for <id>.lambda-url.<region>.<anything> are also routed
+	// by their Host header, for setups with wildcard DNS.
+	functionUrlPathPrefix = "/lambda-url/"
+	functionUrlHostInfix  = ".lambda-url."
+
+	functionUrlEventTimeFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+type FunctionUrl struct {
+	// The URL's subdomain in AWS.
+	Id               string
+	AuthType         string
+	Cors             *Cors
+	InvokeMode       string
+	CreationTime     time.Time
+	LastModifiedTime time.Time
+}
+
+func (l *Lambda) functionUrl(id string) string {
+	return "http://" + l.addr + functionUrlPathPrefix + id + "/"
+}
+
+// lockedGetFunctionForUrl returns the function a function URL API refers to.
+func (l *Lambda) lockedGetFunctionForUrl(identifier string, qualifier string) (*Function, *awserrors.Error) {
+	function, awserr := l.lockedGetFunction(identifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	_, qualifier, awserr = parseFunctionName(identifier, qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// TODO: URLs for aliases.
+	if qualifier != "" {
+		return nil, ResourceNotFoundException("Function not found: " + function.ARN + ":" + qualifier)
+	}
+	return function, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_CreateFunctionUrlConfig.html
+func (l *Lambda) CreateFunctionUrlConfig(input CreateFunctionUrlConfigInput) (*CreateFunctionUrlConfigOutput, *awserrors.Error) {
+	if input.AuthType != "NONE" && input.AuthType != "AWS_IAM" {
+		return nil, InvalidParameterValueException("AuthType must be NONE or AWS_IAM.")
+	}
+	invokeMode := input.InvokeMode
+	if invokeMode == "" {
+		invokeMode = "BUFFERED"
+	}
+	if invokeMode != "BUFFERED" && invokeMode != "RESPONSE_STREAM" {
+		return nil, InvalidParameterValueException("InvokeMode must be BUFFERED or RESPONSE_STREAM.")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunctionForUrl(input.FunctionName, input.Qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if function.Url != nil {
+		return nil, ResourceConflictException("Failed to create function url config for [functionArn = " +
+			function.ARN + "]. Error message:  FunctionUrlConfig exists for this Lambda function")
+	}
+
+	now := l.clock()
+	function.Url = &FunctionUrl{
+		Id:               strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", ""),
+		AuthType:         input.AuthType,
+		Cors:             input.Cors,
+		InvokeMode:       invokeMode,
+		CreationTime:     now,
+		LastModifiedTime: now,
+	}
+	return &CreateFunctionUrlConfigOutput{
+		FunctionUrl:  l.functionUrl(function.Url.Id),
+		FunctionArn:  function.ARN,
+		AuthType:     function.Url.AuthType,
+		Cors:         function.Url.Cors,
+		CreationTime: now.UTC().Format(time.RFC3339Nano),
+		InvokeMode:   invokeMode,
+		StatusCode:   201,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_GetFunctionUrlConfig.html
+func (l *Lambda) GetFunctionUrlConfig(input GetFunctionUrlConfigInput) (*GetFunctionUrlConfigOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunctionForUrl(input.FunctionName, input.Qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if function.Url == nil {
+		return nil, ResourceNotFoundException("The resource you requested does not exist.")
+	}
+	return &GetFunctionUrlConfigOutput{
+		FunctionUrl:      l.functionUrl(function.Url.Id),
+		FunctionArn:      function.ARN,
+		AuthType:         function.Url.AuthType,
+		Cors:             function.Url.Cors,
+		CreationTime:     function.Url.CreationTime.UTC().Format(time.RFC3339Nano),
+		LastModifiedTime: function.Url.LastModifiedTime.UTC().Format(time.RFC3339Nano),
+		InvokeMode:       function.Url.InvokeMode,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_DeleteFunctionUrlConfig.html
+func (l *Lambda) DeleteFunctionUrlConfig(input DeleteFunctionUrlConfigInput) (*DeleteFunctionUrlConfigOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunctionForUrl(input.FunctionName, input.Qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if function.Url == nil {
+		return nil, ResourceNotFoundException("The resource you requested does not exist.")
+	}
+	function.Url = nil
+	return &DeleteFunctionUrlConfigOutput{StatusCode: 204}, nil
+}
+
+// functionUrlEvent is the payload functions are invoked with for requests to their URL,
+// which is the same as API Gateway's HTTP API payload version 2.0.
+// https://docs.aws.amazon.com/lambda/latest/dg/urls-invocation.html#urls-payloads
+type functionUrlEvent struct {
+	Version               string                    `json:"version"`
+	RouteKey              string                    `json:"routeKey"`
+	RawPath               string                    `json:"rawPath"`
+	RawQueryString        string                    `json:"rawQueryString"`
+	Cookies               []string                  `json:"cookies,omitempty"`
+	Headers               map[string]string         `json:"headers"`
+	QueryStringParameters map[string]string         `json:"queryStringParameters,omitempty"`
+	RequestContext        functionUrlRequestContext `json:"requestContext"`
+	Body                  string                    `json:"body,omitempty"`
+	IsBase64Encoded       bool                      `json:"isBase64Encoded"`
+}
+
+type functionUrlRequestContext struct {
+	AccountId    string                 `json:"accountId"`
+	ApiId        string                 `json:"apiId"`
+	DomainName   string                 `json:"domainName"`
+	DomainPrefix string                 `json:"domainPrefix"`
+	Http         functionUrlHttpContext `json:"http"`
+	RequestId    string                 `json:"requestId"`
+	RouteKey     string                 `json:"routeKey"`
+	Stage        string                 `json:"stage"`
+	Time         string                 `json:"time"`
+	TimeEpoch    int64                  `json:"timeEpoch"`
+}
+
+type functionUrlHttpContext struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	SourceIp  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// functionUrlResponse is the response of functions which set the status code.
+// Other responses are returned as JSON with status 200.
+type functionUrlResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers"`
+	Cookies         []string          `json:"cookies"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// parseFunctionUrlRequest returns the ID of the function URL the request is for, and the path
+// within it, or false if the request isn't for a function URL.
+func parseFunctionUrlRequest(r *http.Request) (string, string, bool) {
+	if i := strings.Index(r.Host, functionUrlHostInfix); i > 0 {
+		return r.Host[:i], r.URL.Path, true
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, functionUrlPathPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, path, _ := strings.Cut(rest, "/")
+	return id, "/" + path, true
+}
+
+// serveFunctionUrl invokes the function whose URL the request is for, if any. URLs' IDs are unique
+// across regions, and requests to http://<addr>/lambda-url/<id>/ don't say their regions, so every
+// region's URLs are searched.
+func (l *Lambda) serveFunctionUrl(w http.ResponseWriter, r *http.Request) bool {
+	id, path, ok := parseFunctionUrlRequest(r)
+	if !ok {
+		return false
+	}
+	for _, functions := range l.regions.All() {
+		if url, version := functions.functionForUrl(id); url != nil {
+			functions.invokeFunctionUrl(w, r, id, path, url, version)
+			return true
+		}
+	}
+	http.NotFound(w, r)
+	return true
+}
+
+// functionForUrl returns the URL with the ID and the version it invokes, or nil if no function in
+// the region has the URL.
+func (l *Lambda) functionForUrl(id string) (*FunctionUrl, *FunctionVersion) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, function := range l.functionsByName {
+		if function.Url != nil && function.Url.Id == id {
+			return function.Url, function.Latest
+		}
+	}
+	return nil, nil
+}
+
+// invokeFunctionUrl invokes the version with the request to its function's URL.
+func (l *Lambda) invokeFunctionUrl(w http.ResponseWriter, r *http.Request, id string, path string, url *FunctionUrl, version *FunctionVersion) {
+	requestId := uuid.Must(uuid.NewV4()).String()
+	w.Header().Set("X-Amzn-RequestId", requestId)
+	// AWS_IAM URLs would require signed requests, but credentials aren't checked.
+	if url.Cors != nil && SetCorsHeaders(w, r, url.Cors) {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSyncPayloadSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxSyncPayloadSize {
+		http.Error(w, "Request must be smaller than 6291456 bytes", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	event := l.functionUrlEvent(r, id, path, requestId, body)
+	payload, _ := json.Marshal(event)
+	result, awserr := l.invoke(version, payload)
+	if awserr != nil {
+		http.Error(w, "Internal Server Error", awserr.Code)
+		return
+	}
+	if result.functionError != "" {
+		http.Error(w, "Internal Server Error", http.StatusBadGateway)
+		return
+	}
+	WriteHTTPResponse(w, result.payload)
+}
+
+func (l *Lambda) functionUrlEvent(r *http.Request, id string, path string, requestId string, body []byte) functionUrlEvent {
+	now := l.clock()
+	sourceIp, _, _ := net.SplitHostPort(r.RemoteAddr)
+	event := functionUrlEvent{
+		Version:        "2.0",
+		RouteKey:       "$default",
+		RawPath:        path,
+		RawQueryString: r.URL.RawQuery,
+		Headers:        make(map[string]string),
+		RequestContext: functionUrlRequestContext{
+			AccountId:    "anonymous",
+			ApiId:        id,
+			DomainName:   r.Host,
+			DomainPrefix: id,
+			Http: functionUrlHttpContext{
+				Method:    r.Method,
+				Path:      path,
+				Protocol:  r.Proto,
+				SourceIp:  sourceIp,
+				UserAgent: r.UserAgent(),
+			},
+			RequestId: requestId,
+			RouteKey:  "$default",
+			Stage:     "$default",
+			Time:      now.UTC().Format(functionUrlEventTimeFormat),
+			TimeEpoch: now.UnixMilli(),
+		},
+	}
+
+	// Header names are lowercase, and repeated headers are joined with commas.
+	for name, values := range r.Header {
+		if name == "Cookie" {
+			for _, cookie := range r.Cookies() {
+				event.Cookies = append(event.Cookies, cookie.String())
+			}
+			continue
+		}
+		event.Headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		event.QueryStringParameters = make(map[string]string)
+		for name, values := range query {
+			event.QueryStringParameters[name] = strings.Join(values, ",")
+		}
+	}
+
+	if len(body) > 0 {
+		if IsTextContent(r.Header.Get("Content-Type"), body) {
+			event.Body = string(body)
+		} else {
+			event.Body = base64.StdEncoding.EncodeToString(body)
+			event.IsBase64Encoded = true
+		}
+	}
+	return event
+}
+
+// IsTextContent returns whether the body is passed to the function as text, rather than base64 encoded.
+// API Gateway's HTTP APIs use it too.
+func IsTextContent(contentType string, body []byte) bool {
+	if contentType == "" {
+		return utf8.Valid(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript" ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// WriteHTTPResponse translates the function's response to an HTTP response. Function URLs' responses
+// are the same as API Gateway's HTTP API payload version 2.0, so its HTTP APIs use it too.
+// https://docs.aws.amazon.com/lambda/latest/dg/urls-invocation.html#urls-response-payload
+func WriteHTTPResponse(w http.ResponseWriter, payload []byte) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(payload, &fields) != nil || fields["statusCode"] == nil {
+		// Lambda infers the response format: JSON strings are returned as is, and other JSON as JSON.
+		var s string
+		if json.Unmarshal(payload, &s) == nil {
+			payload = []byte(s)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+		return
+	}
+
+	var response functionUrlResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusBadGateway)
+		return
+	}
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusBadGateway)
+			return
+		}
+	}
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(response.StatusCode)
+	w.Write(body)
+}
+
+// SetCorsHeaders sets the CORS headers for requests from allowed origins,
+// and returns true if the request was a preflight request, which it responds to.
+// https://docs.aws.amazon.com/lambda/latest/dg/urls-configuration.html#urls-cors
+func SetCorsHeaders(w http.ResponseWriter, r *http.Request, cors *Cors) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || (!slices.Contains(cors.AllowOrigins, "*") && !slices.Contains(cors.AllowOrigins, origin)) {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if cors.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(cors.ExposeHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ","))
+	}
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	if len(cors.AllowMethods) > 0 {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.AllowMethods, ","))
+	}
+	if len(cors.AllowHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.AllowHeaders, ","))
+	}
+	if cors.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge)))
+	}
+	w.WriteHeader(http.StatusOK)
+	return true
+}
@@ -0,0 +1,49 @@
+package lambda
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// Lambda only supports the REST-JSON protocol.
+// The handler also serves function URLs.
+func NewHandler(logger *slog.Logger, l *Lambda, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2015-03-31/functions", "CreateFunction", region.Method(l.regions, (*Lambda).CreateFunction))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2015-03-31/functions/{FunctionName}", "GetFunction", region.Method(l.regions, (*Lambda).GetFunction))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2015-03-31/functions/{FunctionName}/invocations", "Invoke", region.Method(l.regions, (*Lambda).invokeFunction))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, "/2015-03-31/functions/{FunctionName}/code", "UpdateFunctionCode", region.Method(l.regions, (*Lambda).UpdateFunctionCode))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, "/2015-03-31/functions/{FunctionName}/configuration", "UpdateFunctionConfiguration", region.Method(l.regions, (*Lambda).UpdateFunctionConfiguration))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2015-03-31/functions/{FunctionName}/versions", "PublishVersion", region.Method(l.regions, (*Lambda).PublishVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2015-03-31/functions/{FunctionName}/versions", "ListVersionsByFunction", region.Method(l.regions, (*Lambda).ListVersionsByFunction))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2015-03-31/functions/{FunctionName}/aliases", "CreateAlias", region.Method(l.regions, (*Lambda).CreateAlias))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2015-03-31/functions/{FunctionName}/aliases", "ListAliases", region.Method(l.regions, (*Lambda).ListAliases))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2015-03-31/functions/{FunctionName}/aliases/{Name}", "GetAlias", region.Method(l.regions, (*Lambda).GetAlias))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, "/2015-03-31/functions/{FunctionName}/aliases/{Name}", "UpdateAlias", region.Method(l.regions, (*Lambda).UpdateAlias))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/2015-03-31/functions/{FunctionName}/aliases/{Name}", "DeleteAlias", region.Method(l.regions, (*Lambda).DeleteAlias))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2015-03-31/event-source-mappings", "CreateEventSourceMapping", region.Method(l.regions, (*Lambda).CreateEventSourceMapping))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2015-03-31/event-source-mappings", "ListEventSourceMappings", region.Method(l.regions, (*Lambda).ListEventSourceMappings))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2015-03-31/event-source-mappings/{UUID}", "GetEventSourceMapping", region.Method(l.regions, (*Lambda).GetEventSourceMapping))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/2015-03-31/event-source-mappings/{UUID}", "DeleteEventSourceMapping", region.Method(l.regions, (*Lambda).DeleteEventSourceMapping))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2018-10-31/layers/{LayerName}/versions", "PublishLayerVersion", region.Method(l.regions, (*Lambda).PublishLayerVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2018-10-31/layers/{LayerName}/versions", "ListLayerVersions", region.Method(l.regions, (*Lambda).ListLayerVersions))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2018-10-31/layers/{LayerName}/versions/{VersionNumber}", "GetLayerVersion", region.Method(l.regions, (*Lambda).GetLayerVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/2018-10-31/layers/{LayerName}/versions/{VersionNumber}", "DeleteLayerVersion", region.Method(l.regions, (*Lambda).DeleteLayerVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/2021-10-31/functions/{FunctionName}/url", "CreateFunctionUrlConfig", region.Method(l.regions, (*Lambda).CreateFunctionUrlConfig))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/2021-10-31/functions/{FunctionName}/url", "GetFunctionUrlConfig", region.Method(l.regions, (*Lambda).GetFunctionUrlConfig))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/2021-10-31/functions/{FunctionName}/url", "DeleteFunctionUrlConfig", region.Method(l.regions, (*Lambda).DeleteFunctionUrlConfig))
+	capabilityRegistry.Add("lambda", registry.Operations()...)
+	handler := restjson.NewHandler(registry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		// Requests to function URLs are plain HTTP requests, rather than API calls.
+		if l.serveFunctionUrl(w, r) {
+			return true
+		}
+		return handler(w, r)
+	}
+}
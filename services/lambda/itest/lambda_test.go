@@ -0,0 +1,412 @@
+package itest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/server"
+	lambdaImpl "aws-in-a-box/services/lambda"
+)
+
+// The test binary doubles as the functions' runtime. When aws-in-a-box runs it as a function,
+// it serves invocations from the runtime API instead of running the tests.
+func TestMain(m *testing.M) {
+	if api := os.Getenv("AWS_LAMBDA_RUNTIME_API"); api != "" {
+		runRuntime(api, os.Getenv("_HANDLER"))
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runRuntime is a minimal custom runtime, whose behavior depends on the function's handler.
+func runRuntime(api string, handler string) {
+	if handler == "initError" {
+		http.Post("http://"+api+"/2018-06-01/runtime/init/error", "application/json",
+			strings.NewReader(`{"errorType":"Runtime.InitError","errorMessage":"init failed"}`))
+		os.Exit(1)
+	}
+
+	for {
+		resp, err := http.Get("http://" + api + "/2018-06-01/runtime/invocation/next")
+		if err != nil {
+			os.Exit(1)
+		}
+		payload, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		requestId := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+		url := "http://" + api + "/2018-06-01/runtime/invocation/" + requestId
+
+		switch handler {
+		case "echo":
+			fmt.Println("echoing", string(payload))
+			http.Post(url+"/response", "application/json", bytes.NewReader(payload))
+		case "error":
+			http.Post(url+"/error", "application/json",
+				strings.NewReader(`{"errorType":"Error","errorMessage":"something went wrong"}`))
+		case "env":
+			response, _ := json.Marshal(map[string]string{
+				"function": os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+				"custom":   os.Getenv("CUSTOM"),
+				"arn":      resp.Header.Get("Lambda-Runtime-Invoked-Function-Arn"),
+			})
+			http.Post(url+"/response", "application/json", bytes.NewReader(response))
+		case "url":
+			// Responds to function URL requests with the request's method and body.
+			var event struct {
+				Body            string
+				IsBase64Encoded bool
+				RequestContext  struct {
+					Http struct {
+						Method string
+					}
+				}
+			}
+			json.Unmarshal(payload, &event)
+			response, _ := json.Marshal(map[string]any{
+				"statusCode":      201,
+				"headers":         map[string]string{"content-type": "text/plain"},
+				"cookies":         []string{"session=1"},
+				"body":            base64.StdEncoding.EncodeToString([]byte(event.RequestContext.Http.Method + " " + event.Body)),
+				"isBase64Encoded": true,
+			})
+			http.Post(url+"/response", "application/json", bytes.NewReader(response))
+		case "sleep":
+			time.Sleep(10 * time.Second)
+		case "exit":
+			os.Exit(2)
+		}
+	}
+}
+
+func makeClientServerPair() (*lambda.Client, *http.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	commands := make(map[string][]string)
+	for _, name := range []string{"echo", "error", "env", "url", "sleep", "exit", "initError"} {
+		commands[name] = []string{os.Args[0]}
+	}
+	impl := lambdaImpl.New(lambdaImpl.Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+		Addr:         listener.Addr().String(),
+		ExecCommands: commands,
+	})
+
+	srv := server.NewWithHandlerChain(
+		lambdaImpl.NewHandler(slog.Default(), impl, nil),
+	)
+	go srv.Serve(listener)
+
+	client := lambda.New(lambda.Options{
+		EndpointResolver: lambda.EndpointResolverFromURL("http://" + listener.Addr().String()),
+		Retryer:          aws.NopRetryer{},
+	})
+
+	return client, srv
+}
+
+func makeZip(files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, contents := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			panic(err)
+		}
+		f.Write([]byte(contents))
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func createFunction(t *testing.T, client *lambda.Client, name string, timeout int32) *lambda.CreateFunctionOutput {
+	output, err := client.CreateFunction(context.Background(), &lambda.CreateFunctionInput{
+		FunctionName: aws.String(name),
+		Runtime:      types.RuntimeProvidedal2,
+		Role:         aws.String("arn:aws:iam::123456789012:role/lambda"),
+		Handler:      aws.String(name),
+		Timeout:      aws.Int32(timeout),
+		Code:         &types.FunctionCode{ZipFile: makeZip(map[string]string{"bootstrap": ""})},
+		Environment: &types.Environment{
+			Variables: map[string]string{"CUSTOM": "value"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestFunctions(t *testing.T) {
+	ctx := context.Background()
+	client, srv := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	created := createFunction(t, client, "echo", 3)
+	if *created.FunctionArn != "arn:aws:lambda:us-east-1:123456789012:function:echo" ||
+		created.PackageType != types.PackageTypeZip ||
+		created.State != types.StateActive {
+		t.Fatal("Unexpected function", created)
+	}
+
+	_, err := client.CreateFunction(ctx, &lambda.CreateFunctionInput{
+		FunctionName: aws.String("echo"),
+		Runtime:      types.RuntimeProvidedal2,
+		Role:         aws.String("arn:aws:iam::123456789012:role/lambda"),
+		Handler:      aws.String("echo"),
+		Code:         &types.FunctionCode{ZipFile: makeZip(map[string]string{"bootstrap": ""})},
+	})
+	var conflict *types.ResourceConflictException
+	if !errors.As(err, &conflict) {
+		t.Fatal("Expected conflict", err)
+	}
+
+	function, err := client.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: created.FunctionArn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *function.Configuration.RevisionId != *created.RevisionId ||
+		function.Configuration.Environment.Variables["CUSTOM"] != "value" {
+		t.Fatal("Unexpected function", function.Configuration)
+	}
+
+	_, err = client.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String("missing"),
+	})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Fatal("Expected not found", err)
+	}
+
+	updated, err := client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
+		FunctionName: aws.String("echo"),
+		ZipFile:      makeZip(map[string]string{"bootstrap": "v2"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updated.CodeSha256 == *created.CodeSha256 {
+		t.Fatal("Code was not updated")
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	ctx := context.Background()
+	client, srv := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	createFunction(t, client, "echo", 3)
+	// The second invocation reuses the process.
+	for i := 0; i < 2; i++ {
+		output, err := client.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName: aws.String("echo"),
+			Payload:      []byte(`{"hello":"world"}`),
+			LogType:      types.LogTypeTail,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output.Payload) != `{"hello":"world"}` || output.FunctionError != nil || *output.ExecutedVersion != "$LATEST" {
+			t.Fatal("Unexpected output", string(output.Payload), output.FunctionError)
+		}
+		logs, _ := base64.StdEncoding.DecodeString(*output.LogResult)
+		if !strings.Contains(string(logs), "START RequestId") ||
+			!strings.Contains(string(logs), `echoing {"hello":"world"}`) ||
+			!strings.Contains(string(logs), "REPORT RequestId") ||
+			strings.Count(string(logs), "START") != 1 {
+			t.Fatalf("Unexpected logs: %s", logs)
+		}
+	}
+
+	createFunction(t, client, "env", 3)
+	output, err := client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName: aws.String("env"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env map[string]string
+	json.Unmarshal(output.Payload, &env)
+	if env["function"] != "env" || env["custom"] != "value" || env["arn"] != "arn:aws:lambda:us-east-1:123456789012:function:env" {
+		t.Fatal("Unexpected environment", env)
+	}
+
+	output, err = client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String("echo"),
+		InvocationType: types.InvocationTypeEvent,
+		Payload:        []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.StatusCode != 202 {
+		t.Fatal("Unexpected status", output.StatusCode)
+	}
+}
+
+func TestInvokeErrors(t *testing.T) {
+	ctx := context.Background()
+	client, srv := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	cases := map[string]string{
+		"error":     "something went wrong",
+		"sleep":     "Task timed out after 1.00 seconds",
+		"exit":      "Runtime exited with error: exit status 2",
+		"initError": "init failed",
+	}
+	for name, message := range cases {
+		createFunction(t, client, name, 1)
+		output, err := client.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName: aws.String(name),
+		})
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if output.FunctionError == nil || *output.FunctionError != "Unhandled" {
+			t.Fatal("Expected function error for", name)
+		}
+		if !strings.Contains(string(output.Payload), message) {
+			t.Fatalf("Unexpected payload for %s: %s", name, output.Payload)
+		}
+	}
+
+	// A new process is started after the last one exited.
+	output, err := client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName: aws.String("exit"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output.Payload), "exit status 2") {
+		t.Fatal("Unexpected payload", string(output.Payload))
+	}
+
+	createFunction(t, client, "echo", 3)
+	_, err = client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName: aws.String("echo"),
+		Payload:      []byte("not json"),
+	})
+	var invalidContent *types.InvalidRequestContentException
+	if !errors.As(err, &invalidContent) {
+		t.Fatal("Expected invalid content", err)
+	}
+}
+
+func TestFunctionUrl(t *testing.T) {
+	ctx := context.Background()
+	client, srv := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	createFunction(t, client, "echo", 3)
+	config, err := client.CreateFunctionUrlConfig(ctx, &lambda.CreateFunctionUrlConfigInput{
+		FunctionName: aws.String("echo"),
+		AuthType:     types.FunctionUrlAuthTypeNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.CreateFunctionUrlConfig(ctx, &lambda.CreateFunctionUrlConfigInput{
+		FunctionName: aws.String("echo"),
+		AuthType:     types.FunctionUrlAuthTypeNone,
+	})
+	var conflict *types.ResourceConflictException
+	if !errors.As(err, &conflict) {
+		t.Fatal("Expected conflict", err)
+	}
+
+	got, err := client.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String("echo"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.FunctionUrl != *config.FunctionUrl {
+		t.Fatal("Unexpected URL", *got.FunctionUrl)
+	}
+
+	// Functions which don't set the status code respond with their payload as JSON,
+	// which for echo is the event.
+	resp, err := http.Post(*config.FunctionUrl+"hooks/github?a=1&a=2", "application/json", strings.NewReader(`{"ref":"main"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var event struct {
+		RawPath               string
+		QueryStringParameters map[string]string
+		Headers               map[string]string
+		Body                  string
+		IsBase64Encoded       bool
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || event.RawPath != "/hooks/github" || event.QueryStringParameters["a"] != "1,2" ||
+		event.Headers["content-type"] != "application/json" || event.Body != `{"ref":"main"}` || event.IsBase64Encoded {
+		t.Fatal("Unexpected event", resp.StatusCode, event)
+	}
+
+	createFunction(t, client, "url", 3)
+	config, err = client.CreateFunctionUrlConfig(ctx, &lambda.CreateFunctionUrlConfigInput{
+		FunctionName: aws.String("url"),
+		AuthType:     types.FunctionUrlAuthTypeNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Functions can set the status code, headers and cookies.
+	resp, err = http.Post(*config.FunctionUrl, "application/x-www-form-urlencoded", strings.NewReader("a=b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 201 || string(body) != "POST a=b" ||
+		resp.Header.Get("Content-Type") != "text/plain" || resp.Header.Get("Set-Cookie") != "session=1" {
+		t.Fatal("Unexpected response", resp.StatusCode, resp.Header, string(body))
+	}
+
+	_, err = client.DeleteFunctionUrlConfig(ctx, &lambda.DeleteFunctionUrlConfigInput{
+		FunctionName: aws.String("url"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Get(*config.FunctionUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Fatal("Expected not found", resp.StatusCode)
+	}
+}
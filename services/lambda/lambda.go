@@ -0,0 +1,965 @@
+package lambda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/cloudwatch"
+	"aws-in-a-box/services/cloudwatchlogs"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/ecr"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/kms"
+	kmstypes "aws-in-a-box/services/kms/types"
+	"aws-in-a-box/services/sqs"
+)
+
+const (
+	latestVersion = "$LATEST"
+
+	defaultTimeout    = 3
+	maxTimeout        = 900
+	defaultMemorySize = 128
+	minMemorySize     = 128
+	maxMemorySize     = 10240
+
+	maxZipFileSize          = 50 * 1024 * 1024
+	maxSyncPayloadSize      = 6 * 1024 * 1024
+	maxAsyncPayloadSize     = 256 * 1024
+	maxLogResultSize        = 4 * 1024
+	maxEnvironmentSize      = 4 * 1024
+	lastModifiedTimeFormat  = "2006-01-02T15:04:05.000-0700"
+	unzipFailedErrorMessage = "Could not unzip uploaded file. Please check your file, then try to upload again."
+)
+
+var (
+	functionNameRegex        = regexp.MustCompile(`^[a-zA-Z0-9-_]{1,64}$`)
+	environmentVariableRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+)
+
+// Environment variables which Lambda sets, so functions can't.
+// https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html#configuration-envvars-runtime
+var reservedEnvironmentVariables = []string{
+	"_HANDLER",
+	"_X_AMZN_TRACE_ID",
+	"AWS_ACCESS_KEY",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_DEFAULT_REGION",
+	"AWS_EXECUTION_ENV",
+	"AWS_LAMBDA_FUNCTION_MEMORY_SIZE",
+	"AWS_LAMBDA_FUNCTION_NAME",
+	"AWS_LAMBDA_FUNCTION_VERSION",
+	"AWS_LAMBDA_INITIALIZATION_TYPE",
+	"AWS_LAMBDA_LOG_GROUP_NAME",
+	"AWS_LAMBDA_LOG_STREAM_NAME",
+	"AWS_LAMBDA_RUNTIME_API",
+	"AWS_REGION",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"LAMBDA_RUNTIME_DIR",
+	"LAMBDA_TASK_ROOT",
+}
+
+type Function struct {
+	Name string
+	ARN  string
+	Tags map[string]string
+
+	Latest *FunctionVersion
+	// Published versions, which are numbered from 1.
+	Versions []*FunctionVersion
+	// The revision of $LATEST which was last published, so it isn't published again.
+	publishedRevisionId string
+	Aliases             map[string]*Alias
+	// Nil if the function doesn't have a URL.
+	Url *FunctionUrl
+}
+
+// FunctionVersion is a snapshot of a function's code and configuration.
+// It is never modified, so it can be used without holding the lock.
+type FunctionVersion struct {
+	FunctionName string
+	FunctionArn  string
+	Version      string
+	RevisionId   string
+	LastModified time.Time
+
+	Runtime     string
+	Role        string
+	Handler     string
+	Description string
+	Timeout     int32
+	MemorySize  int32
+	Environment map[string]string
+	// The customer managed key which encrypts the environment variables, if any.
+	KMSKeyArn     string
+	Architectures []string
+
+	// Zip or Image.
+	PackageType string
+	ImageUri    string
+	// The image URI pinned to the image's digest, if the image is in the emulated ECR.
+	ResolvedImageUri string
+	ZipFile          []byte
+	CodeSha256       string
+	// Where the zip file is extracted to.
+	codeDir string
+	Layers  []Layer
+	// Where the layers are extracted to, which is mounted at /opt. Empty if there are no layers.
+	optDir string
+}
+
+// key identifies the version's code and configuration, for reusing execution environments.
+func (v *FunctionVersion) key() string {
+	return v.FunctionArn + ":" + v.Version + ":" + v.RevisionId
+}
+
+// qualifiedArn is the version's ARN, which is unqualified for $LATEST.
+func (v *FunctionVersion) qualifiedArn() string {
+	if v.Version == latestVersion {
+		return v.FunctionArn
+	}
+	return v.FunctionArn + ":" + v.Version
+}
+
+func (v *FunctionVersion) configuration() FunctionConfiguration {
+	config := FunctionConfiguration{
+		FunctionName:     v.FunctionName,
+		FunctionArn:      v.qualifiedArn(),
+		Runtime:          v.Runtime,
+		Role:             v.Role,
+		Handler:          v.Handler,
+		CodeSize:         int64(len(v.ZipFile)),
+		Description:      v.Description,
+		Timeout:          v.Timeout,
+		MemorySize:       v.MemorySize,
+		LastModified:     v.LastModified.UTC().Format(lastModifiedTimeFormat),
+		CodeSha256:       v.CodeSha256,
+		Version:          v.Version,
+		KMSKeyArn:        v.KMSKeyArn,
+		RevisionId:       v.RevisionId,
+		PackageType:      v.PackageType,
+		Architectures:    v.Architectures,
+		Layers:           v.Layers,
+		State:            "Active",
+		LastUpdateStatus: "Successful",
+	}
+	if len(v.Environment) > 0 {
+		config.Environment = &EnvironmentResponse{Variables: v.Environment}
+	}
+	return config
+}
+
+// environmentVariables returns the variables set in the function's execution environment.
+// https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html#configuration-envvars-runtime
+func (v *FunctionVersion) environmentVariables() map[string]string {
+	variables := map[string]string{
+		"AWS_LAMBDA_FUNCTION_NAME":        v.FunctionName,
+		"AWS_LAMBDA_FUNCTION_VERSION":     v.Version,
+		"AWS_LAMBDA_FUNCTION_MEMORY_SIZE": strconv.Itoa(int(v.MemorySize)),
+		"AWS_LAMBDA_FUNCTION_TIMEOUT":     strconv.Itoa(int(v.Timeout)),
+		"AWS_LAMBDA_LOG_GROUP_NAME":       "/aws/lambda/" + v.FunctionName,
+	}
+	for name, value := range v.Environment {
+		variables[name] = value
+	}
+	return variables
+}
+
+type Lambda struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	addr         string
+	executor     executor
+	// Nil if KMS is not enabled, in which case functions can't have a KMSKeyArn.
+	kms *kms.KMS
+	// Nil if CloudWatch Logs is not enabled, in which case function output is only returned by Invoke.
+	logs *cloudwatchlogs.CloudWatchLogs
+	// Nil if CloudWatch is not enabled, in which case invocation metrics aren't published.
+	metrics *cloudwatch.CloudWatch
+	// Nil if ECR is not enabled, in which case container images are pulled as they are.
+	ecr *ecr.ECR
+	// Overridden in tests.
+	clock func() time.Time
+
+	// Event sources, which are nil if the service is not enabled.
+	sqs      *sqs.SQS
+	kinesis  *kinesis.Kinesis
+	dynamoDB *dynamodb.DynamoDB
+
+	mu              sync.Mutex
+	functionsByName map[string]*Function
+	// Versions by layer name, with nil for deleted versions.
+	layersByName map[string][]*LayerVersion
+	// In the order they were created.
+	eventSourceMappings []*EventSourceMapping
+	// Shared by the instances of all regions, which each have their own functions, layers and event
+	// source mappings.
+	regions *region.Regions[*Lambda]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The address function URLs are served on.
+	Addr string
+	// Commands to run functions as local processes, by function name, instead of in Docker.
+	// The commands must implement the Lambda runtime API, like custom runtimes do.
+	ExecCommands map[string][]string
+	// Functions' environment variables can be encrypted with customer managed keys in this KMS.
+	KMS *kms.KMS
+	// Function output is written to log groups in this CloudWatch Logs.
+	Logs *cloudwatchlogs.CloudWatchLogs
+	// Invocations, Errors and Duration metrics are published to this CloudWatch.
+	Metrics *cloudwatch.CloudWatch
+	// Event source mappings poll these services' queues and streams.
+	SQS      *sqs.SQS
+	Kinesis  *kinesis.Kinesis
+	DynamoDB *dynamodb.DynamoDB
+	// Container images in this ECR are resolved when functions are created, and pulled from its registry.
+	ECR *ecr.ECR
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Lambda {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Execution environments are keyed by functions' ARNs, which say their regions, so every region
+	// runs its functions with the same executor. Event sources are found by their ARNs too, so
+	// every region polls them through the same services.
+	executor := newProcessExecutor(options.Logger, options.ExecCommands, newDockerExecutor(options.Logger, options.ECR))
+	l := newRegion(options, executor)
+	l.regions = region.New(options.ArnGenerator.Region, options.Regions, l, func(name string) (*Lambda, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.KMS != nil {
+			keys, err := options.KMS.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.KMS = keys
+		}
+		if options.Logs != nil {
+			logs, err := options.Logs.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.Logs = logs
+		}
+		if options.Metrics != nil {
+			metrics, err := options.Metrics.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.Metrics = metrics
+		}
+		if options.ECR != nil {
+			repositories, err := options.ECR.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.ECR = repositories
+		}
+		r := newRegion(regionOptions, executor)
+		r.regions = l.regions
+		return r, nil
+	})
+	return l
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options, executor executor) *Lambda {
+	return &Lambda{
+		logger:          options.Logger,
+		arnGenerator:    options.ArnGenerator,
+		addr:            options.Addr,
+		kms:             options.KMS,
+		logs:            options.Logs,
+		metrics:         options.Metrics,
+		executor:        executor,
+		sqs:             options.SQS,
+		kinesis:         options.Kinesis,
+		dynamoDB:        options.DynamoDB,
+		ecr:             options.ECR,
+		clock:           clock.Now,
+		functionsByName: make(map[string]*Function),
+		layersByName:    make(map[string][]*LayerVersion),
+	}
+}
+
+// InRegion returns the functions, layers and event source mappings of the region. It fails if the
+// region isn't one of Options.Regions.
+func (l *Lambda) InRegion(name string) (*Lambda, error) {
+	return l.regions.Get(name)
+}
+
+// forFunction returns the instance for the region of the function's ARN, or this instance if the
+// function is named without its ARN.
+func (l *Lambda) forFunction(identifier string) (*Lambda, *awserrors.Error) {
+	a, err := arn.Parse(identifier)
+	if err != nil || a.Region == "" {
+		return l, nil
+	}
+	functions, err := l.regions.Get(a.Region)
+	if err != nil {
+		return nil, InvalidParameterValueException(err.Error())
+	}
+	return functions, nil
+}
+
+// parseFunctionName accepts a function name, ARN or partial ARN, any of which may include a qualifier,
+// and returns the name and qualifier.
+// https://docs.aws.amazon.com/lambda/latest/api/API_GetFunction.html#API_GetFunction_RequestSyntax
+func parseFunctionName(identifier string, qualifier string) (string, string, *awserrors.Error) {
+	name := identifier
+	var nameQualifier string
+	parts := strings.Split(identifier, ":")
+	switch {
+	case strings.HasPrefix(identifier, "arn:"):
+		// arn:aws:lambda:us-east-1:123456789012:function:name[:qualifier]
+		if len(parts) < 7 || len(parts) > 8 || parts[5] != "function" {
+			return "", "", InvalidParameterValueException("Invalid function ARN: " + identifier)
+		}
+		name = parts[6]
+		if len(parts) == 8 {
+			nameQualifier = parts[7]
+		}
+	case len(parts) >= 3 && parts[1] == "function":
+		// 123456789012:function:name[:qualifier]
+		name = parts[2]
+		if len(parts) == 4 {
+			nameQualifier = parts[3]
+		}
+	case len(parts) == 2:
+		// name:qualifier
+		name, nameQualifier = parts[0], parts[1]
+	}
+
+	if !functionNameRegex.MatchString(name) {
+		return "", "", InvalidParameterValueException("Invalid function name: " + identifier)
+	}
+	if nameQualifier != "" {
+		if qualifier != "" && qualifier != nameQualifier {
+			return "", "", InvalidParameterValueException("The derived qualifier from the function name does not match the specified qualifier.")
+		}
+		qualifier = nameQualifier
+	}
+	return name, qualifier, nil
+}
+
+func (l *Lambda) lockedGetFunction(identifier string) (*Function, *awserrors.Error) {
+	name, _, awserr := parseFunctionName(identifier, "")
+	if awserr != nil {
+		return nil, awserr
+	}
+	if a, err := arn.Parse(identifier); err == nil && a.Region != "" && a.Region != l.arnGenerator.Region {
+		return nil, InvalidParameterValueException("Functions from '" + a.Region + "' are not reachable in this region ('" +
+			l.arnGenerator.Region + "')")
+	}
+	function, ok := l.functionsByName[name]
+	if !ok {
+		return nil, ResourceNotFoundException("Function not found: " + l.functionArn(name))
+	}
+	return function, nil
+}
+
+func (l *Lambda) lockedGetVersion(identifier string, qualifier string) (*FunctionVersion, *awserrors.Error) {
+	_, qualifier, awserr := parseFunctionName(identifier, qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	function, awserr := l.lockedGetFunction(identifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if qualifier == "" || qualifier == latestVersion {
+		return function.Latest, nil
+	}
+	if alias, ok := function.Aliases[qualifier]; ok {
+		qualifier = alias.FunctionVersion
+	}
+	version := function.lockedGetPublishedVersion(qualifier)
+	if version == nil {
+		return nil, ResourceNotFoundException("Function not found: " + function.ARN + ":" + qualifier)
+	}
+	return version, nil
+}
+
+// lockedRouteVersion returns the version to invoke. Unlike lockedGetVersion, invocations of aliases
+// are routed to their additional versions according to the weights.
+func (l *Lambda) lockedRouteVersion(identifier string, qualifier string) (*FunctionVersion, *awserrors.Error) {
+	_, qualifier, awserr := parseFunctionName(identifier, qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	function, awserr := l.lockedGetFunction(identifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if alias, ok := function.Aliases[qualifier]; ok {
+		r := rand.Float64()
+		for version, weight := range alias.AdditionalVersionWeights {
+			if r < weight {
+				return l.lockedGetVersion(function.Name, version)
+			}
+			r -= weight
+		}
+	}
+	return l.lockedGetVersion(function.Name, qualifier)
+}
+
+func (f *Function) lockedGetPublishedVersion(version string) *FunctionVersion {
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 || n > len(f.Versions) {
+		return nil
+	}
+	return f.Versions[n-1]
+}
+
+func (l *Lambda) functionArn(name string) string {
+	return l.arnGenerator.GenerateWithoutType("lambda", "function:"+name)
+}
+
+// setCode validates the deployment package and sets the version's code.
+func (l *Lambda) setCode(version *FunctionVersion, code FunctionCode) *awserrors.Error {
+	if code.S3Bucket != "" || code.S3Key != "" {
+		return InvalidParameterValueException("Uploading code from S3 is not supported. Use ZipFile instead.")
+	}
+
+	switch version.PackageType {
+	case "Zip":
+		if code.ImageUri != "" {
+			return InvalidParameterValueException("Please don't provide ImageUri when updating a function with packageType Zip.")
+		}
+		if len(code.ZipFile) == 0 {
+			return InvalidParameterValueException("Please provide a source for function code.")
+		}
+		if len(code.ZipFile) > maxZipFileSize {
+			return RequestTooLargeException("Request must be smaller than 52428800 bytes for the UpdateFunctionCode operation")
+		}
+		dir, err := os.MkdirTemp("", "lambda-"+version.FunctionName+"-")
+		if err != nil {
+			return ServiceException(err.Error())
+		}
+		if err := extractZip(code.ZipFile, dir); err != nil {
+			os.RemoveAll(dir)
+			return InvalidParameterValueException(unzipFailedErrorMessage)
+		}
+		hash := sha256.Sum256(code.ZipFile)
+		version.ZipFile = code.ZipFile
+		version.CodeSha256 = base64.StdEncoding.EncodeToString(hash[:])
+		version.codeDir = dir
+	case "Image":
+		if len(code.ZipFile) > 0 {
+			return InvalidParameterValueException("Please don't provide ZipFile when updating a function with packageType Image.")
+		}
+		if code.ImageUri == "" {
+			return InvalidParameterValueException("Please provide a valid ImageUri for the function.")
+		}
+		version.ImageUri = code.ImageUri
+		hash := sha256.Sum256([]byte(code.ImageUri))
+		version.CodeSha256 = base64.StdEncoding.EncodeToString(hash[:])
+		if l.ecr != nil {
+			resolved, ok, awserr := l.ecr.ResolveImageUri(code.ImageUri)
+			if awserr != nil {
+				return InvalidParameterValueException("Source image " + code.ImageUri + " does not exist. Provide a valid source image.")
+			}
+			if ok {
+				// Like Lambda, the image's digest is its code's hash.
+				_, digest, _ := strings.Cut(resolved, "@sha256:")
+				version.ResolvedImageUri = resolved
+				version.CodeSha256 = digest
+			}
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_CreateFunction.html
+func (l *Lambda) CreateFunction(input CreateFunctionInput) (*CreateFunctionOutput, *awserrors.Error) {
+	if !functionNameRegex.MatchString(input.FunctionName) {
+		return nil, InvalidParameterValueException("Invalid function name: " + input.FunctionName)
+	}
+	if input.Role == "" {
+		return nil, InvalidParameterValueException("The role defined for the function cannot be assumed by Lambda.")
+	}
+
+	version := &FunctionVersion{
+		FunctionName:  input.FunctionName,
+		FunctionArn:   l.functionArn(input.FunctionName),
+		Version:       latestVersion,
+		RevisionId:    uuid.Must(uuid.NewV4()).String(),
+		LastModified:  l.clock(),
+		Runtime:       input.Runtime,
+		Role:          input.Role,
+		Handler:       input.Handler,
+		Description:   input.Description,
+		Timeout:       defaultTimeout,
+		MemorySize:    defaultMemorySize,
+		Architectures: input.Architectures,
+		PackageType:   input.PackageType,
+	}
+	if version.PackageType == "" {
+		version.PackageType = "Zip"
+	}
+	if len(version.Architectures) == 0 {
+		version.Architectures = []string{"x86_64"}
+	}
+	if input.Timeout != nil {
+		if *input.Timeout < 1 || *input.Timeout > maxTimeout {
+			return nil, InvalidParameterValueException("Timeout must be between 1 and 900 seconds.")
+		}
+		version.Timeout = *input.Timeout
+	}
+	if input.MemorySize != nil {
+		if *input.MemorySize < minMemorySize || *input.MemorySize > maxMemorySize {
+			return nil, InvalidParameterValueException("MemorySize must be between 128 and 10240 MB.")
+		}
+		version.MemorySize = *input.MemorySize
+	}
+	if input.Environment != nil {
+		if awserr := validateEnvironment(input.Environment.Variables); awserr != nil {
+			return nil, awserr
+		}
+		version.Environment = input.Environment.Variables
+	}
+	if awserr := l.validateKMSKey(input.KMSKeyArn); awserr != nil {
+		return nil, awserr
+	}
+	version.KMSKeyArn = input.KMSKeyArn
+
+	switch version.PackageType {
+	case "Zip":
+		if input.Runtime == "" || input.Handler == "" {
+			return nil, InvalidParameterValueException("Runtime and Handler are mandatory parameters for functions created with Zip packages.")
+		}
+		if _, err := baseImage(input.Runtime); err != nil {
+			return nil, InvalidParameterValueException("Value " + input.Runtime + " at 'runtime' failed to satisfy constraint: Member must satisfy enum value set")
+		}
+	case "Image":
+		if input.Runtime != "" || input.Handler != "" {
+			return nil, InvalidParameterValueException("Runtime and Handler are not supported for functions created with Image packages.")
+		}
+	default:
+		return nil, InvalidParameterValueException("Invalid PackageType: " + input.PackageType)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.functionsByName[input.FunctionName]; ok {
+		return nil, ResourceConflictException("Function already exist: " + input.FunctionName)
+	}
+	if awserr := l.setCode(version, input.Code); awserr != nil {
+		return nil, awserr
+	}
+	layerVersions, layers, awserr := l.lockedGetFunctionLayers(input.Layers, version.PackageType)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := setLayers(version, layerVersions, layers); awserr != nil {
+		return nil, awserr
+	}
+
+	tags := input.Tags
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	function := &Function{
+		Name:    input.FunctionName,
+		ARN:     version.FunctionArn,
+		Tags:    tags,
+		Latest:  version,
+		Aliases: make(map[string]*Alias),
+	}
+	l.functionsByName[input.FunctionName] = function
+	if input.Publish {
+		version = l.lockedPublishVersion(function, "")
+	}
+
+	return &CreateFunctionOutput{
+		FunctionConfiguration: version.configuration(),
+		StatusCode:            201,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_GetFunction.html
+func (l *Lambda) GetFunction(input GetFunctionInput) (*GetFunctionOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	version, awserr := l.lockedGetVersion(input.FunctionName, input.Qualifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	function := l.functionsByName[version.FunctionName]
+
+	code := FunctionCodeLocation{
+		RepositoryType: "S3",
+	}
+	if version.PackageType == "Image" {
+		code = FunctionCodeLocation{
+			RepositoryType:   "ECR",
+			ImageUri:         version.ImageUri,
+			ResolvedImageUri: version.ImageUri,
+		}
+		if version.ResolvedImageUri != "" {
+			code.ResolvedImageUri = version.ResolvedImageUri
+		}
+	}
+	return &GetFunctionOutput{
+		Configuration: version.configuration(),
+		Code:          code,
+		Tags:          function.Tags,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_UpdateFunctionCode.html
+func (l *Lambda) UpdateFunctionCode(input UpdateFunctionCodeInput) (*UpdateFunctionCodeOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.RevisionId != "" && input.RevisionId != function.Latest.RevisionId {
+		return nil, PreconditionFailedException(
+			"The Revision Id provided does not match the latest Revision Id. Call the GetFunction/GetAlias API to retrieve the latest Revision Id")
+	}
+
+	version := *function.Latest
+	version.RevisionId = uuid.Must(uuid.NewV4()).String()
+	version.LastModified = l.clock()
+	version.ZipFile = nil
+	version.ImageUri = ""
+	version.ResolvedImageUri = ""
+	version.codeDir = ""
+	if len(input.Architectures) > 0 {
+		version.Architectures = input.Architectures
+	}
+	awserr = l.setCode(&version, FunctionCode{
+		ZipFile:         input.ZipFile,
+		S3Bucket:        input.S3Bucket,
+		S3Key:           input.S3Key,
+		S3ObjectVersion: input.S3ObjectVersion,
+		ImageUri:        input.ImageUri,
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	if input.DryRun {
+		if version.codeDir != "" {
+			os.RemoveAll(version.codeDir)
+		}
+		return &UpdateFunctionCodeOutput{
+			FunctionConfiguration: version.configuration(),
+		}, nil
+	}
+
+	// Execution environments running the old code are shut down.
+	l.executor.stop(function.ARN)
+	function.Latest = &version
+	published := &version
+	if input.Publish {
+		published = l.lockedPublishVersion(function, "")
+	}
+	return &UpdateFunctionCodeOutput{
+		FunctionConfiguration: published.configuration(),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_UpdateFunctionConfiguration.html
+func (l *Lambda) UpdateFunctionConfiguration(input UpdateFunctionConfigurationInput) (*UpdateFunctionConfigurationOutput, *awserrors.Error) {
+	if input.Environment != nil {
+		if awserr := validateEnvironment(input.Environment.Variables); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if input.KMSKeyArn != nil {
+		if awserr := l.validateKMSKey(*input.KMSKeyArn); awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.RevisionId != "" && input.RevisionId != function.Latest.RevisionId {
+		return nil, PreconditionFailedException(
+			"The Revision Id provided does not match the latest Revision Id. Call the GetFunction/GetAlias API to retrieve the latest Revision Id")
+	}
+
+	version := *function.Latest
+	version.RevisionId = uuid.Must(uuid.NewV4()).String()
+	version.LastModified = l.clock()
+	if input.Role != nil {
+		version.Role = *input.Role
+	}
+	if input.Description != nil {
+		version.Description = *input.Description
+	}
+	if input.Environment != nil {
+		version.Environment = input.Environment.Variables
+	}
+	if input.KMSKeyArn != nil {
+		version.KMSKeyArn = *input.KMSKeyArn
+	}
+	if input.Timeout != nil {
+		if *input.Timeout < 1 || *input.Timeout > maxTimeout {
+			return nil, InvalidParameterValueException("Timeout must be between 1 and 900 seconds.")
+		}
+		version.Timeout = *input.Timeout
+	}
+	if input.MemorySize != nil {
+		if *input.MemorySize < minMemorySize || *input.MemorySize > maxMemorySize {
+			return nil, InvalidParameterValueException("MemorySize must be between 128 and 10240 MB.")
+		}
+		version.MemorySize = *input.MemorySize
+	}
+	if input.Runtime != nil || input.Handler != nil {
+		if version.PackageType == "Image" {
+			return nil, InvalidParameterValueException("Runtime and Handler are not supported for functions created with Image packages.")
+		}
+		if input.Runtime != nil {
+			if _, err := baseImage(*input.Runtime); err != nil {
+				return nil, InvalidParameterValueException("Value " + *input.Runtime + " at 'runtime' failed to satisfy constraint: Member must satisfy enum value set")
+			}
+			version.Runtime = *input.Runtime
+		}
+		if input.Handler != nil {
+			version.Handler = *input.Handler
+		}
+	}
+	if input.Layers != nil {
+		layerVersions, layers, awserr := l.lockedGetFunctionLayers(input.Layers, version.PackageType)
+		if awserr != nil {
+			return nil, awserr
+		}
+		if awserr := setLayers(&version, layerVersions, layers); awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	// Execution environments with the old configuration are shut down.
+	l.executor.stop(function.ARN)
+	function.Latest = &version
+	return &UpdateFunctionConfigurationOutput{
+		FunctionConfiguration: version.configuration(),
+	}, nil
+}
+
+// validateEnvironment checks the function's environment variables.
+// https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html#configuration-envvars-config
+func validateEnvironment(variables map[string]string) *awserrors.Error {
+	var reserved []string
+	size := 0
+	for name, value := range variables {
+		if !environmentVariableRegex.MatchString(name) {
+			return InvalidParameterValueException("Invalid environment variable name: " + name)
+		}
+		if slices.Contains(reservedEnvironmentVariables, name) {
+			reserved = append(reserved, name)
+		}
+		size += len(name) + len(value)
+	}
+	if len(reserved) > 0 {
+		slices.Sort(reserved)
+		return InvalidParameterValueException("Lambda was unable to configure your environment variables because the environment variables " +
+			"you have provided contains reserved keys that are currently not supported for modification. Reserved keys used in this request: " +
+			strings.Join(reserved, ", "))
+	}
+	if size > maxEnvironmentSize {
+		return InvalidParameterValueException("Lambda was unable to configure your environment variables because the environment variables " +
+			"you have provided exceeded the 4KB limit. String measured: " + strconv.Itoa(size))
+	}
+	return nil
+}
+
+// validateKMSKey checks the key can encrypt environment variables.
+func (l *Lambda) validateKMSKey(keyArn string) *awserrors.Error {
+	if keyArn == "" {
+		return nil
+	}
+	if l.kms == nil {
+		return InvalidParameterValueException("KMS is not enabled, so environment variables can't be encrypted with " + keyArn)
+	}
+	output, awserr := l.kms.DescribeKey(kms.DescribeKeyInput{KeyId: keyArn})
+	if awserr != nil || output.KeyMetadata.KeyUsage != kmstypes.EncryptDecrypt {
+		return InvalidParameterValueException("Lambda was unable to configure access to your environment variables because the KMS key is invalid for CreateGrant. " +
+			"Please check your KMS key settings. KMS Key: " + keyArn)
+	}
+	return nil
+}
+
+// checkKMSKey checks the function's environment variables can be decrypted, which Lambda does
+// before running the function, so invocations fail if the key was disabled or deleted.
+func (l *Lambda) checkKMSKey(version *FunctionVersion) *awserrors.Error {
+	if version.KMSKeyArn == "" || len(version.Environment) == 0 || l.kms == nil {
+		return nil
+	}
+	output, awserr := l.kms.DescribeKey(kms.DescribeKeyInput{KeyId: version.KMSKeyArn})
+	if awserr != nil {
+		return KMSNotFoundException("Lambda was unable to decrypt the environment variables because the KMS key was not found. " +
+			"Please check the function's KMS key settings.")
+	}
+	if output.KeyMetadata.KeyState != "Enabled" {
+		return KMSDisabledException("Lambda was unable to decrypt the environment variables because the KMS key used is disabled. " +
+			"Please check the function's KMS key settings.")
+	}
+	return nil
+}
+
+// Invoke invokes the function in the region of its ARN, for services which invoke functions, like
+// Step Functions' tasks. Functions named without their ARNs are this instance's region's.
+func (l *Lambda) Invoke(input InvokeInput) (*InvokeOutput, *awserrors.Error) {
+	functions, awserr := l.forFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return functions.invokeFunction(input)
+}
+
+// invokeFunction invokes the function, which must be in this instance's region.
+// https://docs.aws.amazon.com/lambda/latest/api/API_Invoke.html
+func (l *Lambda) invokeFunction(input InvokeInput) (*InvokeOutput, *awserrors.Error) {
+	l.mu.Lock()
+	version, awserr := l.lockedRouteVersion(input.FunctionName, input.Qualifier)
+	l.mu.Unlock()
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	payload := input.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	if !json.Valid(payload) {
+		return nil, InvalidRequestContentException("Could not parse request body into json: Could not parse payload into json")
+	}
+	if input.LogType != "" && input.LogType != "None" && input.LogType != "Tail" {
+		return nil, InvalidParameterValueException("Invalid LogType: " + input.LogType)
+	}
+
+	switch input.InvocationType {
+	case "", "RequestResponse":
+	case "Event":
+		if len(payload) > maxAsyncPayloadSize {
+			return nil, RequestTooLargeException("Request must be smaller than 262144 bytes for the InvokeAsync operation")
+		}
+		go l.invokeAsync(version, payload)
+		return &InvokeOutput{StatusCode: 202}, nil
+	case "DryRun":
+		return &InvokeOutput{StatusCode: 204}, nil
+	default:
+		return nil, InvalidParameterValueException("Invalid InvocationType: " + input.InvocationType)
+	}
+
+	if len(payload) > maxSyncPayloadSize {
+		return nil, RequestTooLargeException("Request must be smaller than 6291456 bytes for the Invoke operation")
+	}
+	result, awserr := l.invoke(version, payload)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	output := &InvokeOutput{
+		FunctionError:   result.functionError,
+		ExecutedVersion: version.Version,
+		Payload:         result.payload,
+	}
+	if input.LogType == "Tail" {
+		logs := result.logs
+		if len(logs) > maxLogResultSize {
+			logs = logs[len(logs)-maxLogResultSize:]
+		}
+		output.LogResult = base64.StdEncoding.EncodeToString(logs)
+	}
+	return output, nil
+}
+
+// InvokeAsync queues an asynchronous invocation of the function, in the region of its ARN, for
+// services like SNS which invoke functions with events. It only returns an error if the function
+// can't be invoked.
+func (l *Lambda) InvokeAsync(functionArn string, payload []byte) error {
+	_, awserr := l.Invoke(InvokeInput{
+		FunctionName:   functionArn,
+		InvocationType: "Event",
+		Payload:        payload,
+	})
+	if awserr != nil {
+		return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+	}
+	return nil
+}
+
+// invoke runs the function version with the payload.
+func (l *Lambda) invoke(version *FunctionVersion, payload []byte) (*invocationResult, *awserrors.Error) {
+	if awserr := l.checkKMSKey(version); awserr != nil {
+		return nil, awserr
+	}
+	start := l.clock()
+	result, err := l.executor.invoke(context.Background(), version, payload)
+	l.publishInvocationMetrics(version, l.clock().Sub(start), err != nil || result.functionError != "")
+	if err != nil {
+		l.logger.Error("Invoking function", "function", version.FunctionArn, "error", err)
+		return nil, ServiceException("Failed to invoke function: " + err.Error())
+	}
+	l.writeLogs(version, result.logs)
+	return result, nil
+}
+
+// publishInvocationMetrics publishes the function's metrics for an invocation to CloudWatch, if it's enabled.
+// https://docs.aws.amazon.com/lambda/latest/dg/monitoring-metrics-types.html
+func (l *Lambda) publishInvocationMetrics(version *FunctionVersion, duration time.Duration, failed bool) {
+	if l.metrics == nil {
+		return
+	}
+	dimensions := map[string]string{"FunctionName": version.FunctionName}
+	errorCount := 0.0
+	if failed {
+		errorCount = 1
+	}
+	l.metrics.PutMetric("AWS/Lambda", "Invocations", dimensions, "Count", 1)
+	l.metrics.PutMetric("AWS/Lambda", "Errors", dimensions, "Count", errorCount)
+	l.metrics.PutMetric("AWS/Lambda", "Duration", dimensions, "Milliseconds", float64(duration.Microseconds())/1000)
+}
+
+func (l *Lambda) invokeAsync(version *FunctionVersion, payload []byte) {
+	result, awserr := l.invoke(version, payload)
+	if awserr != nil {
+		l.logger.Warn("Asynchronous invocation failed", "function", version.FunctionArn, "error", awserr.Body.Message)
+		return
+	}
+	if result.functionError != "" {
+		l.logger.Warn("Asynchronous invocation failed", "function", version.FunctionArn, "error", string(result.payload))
+	}
+}
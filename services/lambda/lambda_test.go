@@ -0,0 +1,463 @@
+package lambda
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/cloudwatch"
+	"aws-in-a-box/services/cloudwatchlogs"
+	"aws-in-a-box/services/ecr"
+)
+
+// fakeExecutor echoes payloads back, instead of running the function's code.
+type fakeExecutor struct {
+	mu          sync.Mutex
+	invocations []string
+	stopped     []string
+}
+
+func (f *fakeExecutor) invoke(ctx context.Context, version *FunctionVersion, payload []byte) (*invocationResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code, err := os.ReadFile(filepath.Join(version.codeDir, "index.js"))
+	if err != nil {
+		return nil, err
+	}
+	f.invocations = append(f.invocations, string(code))
+	if string(payload) == `{"fail":true}` {
+		return errorResult("Error", "failed", []byte("oops\n")), nil
+	}
+	return &invocationResult{
+		payload: payload,
+		logs:    []byte("START\n" + string(code) + "\nEND\n"),
+	}, nil
+}
+
+func (f *fakeExecutor) stop(functionArn string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = append(f.stopped, functionArn)
+}
+
+func newLambda() (*Lambda, *fakeExecutor) {
+	l := New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+	})
+	executor := &fakeExecutor{}
+	l.executor = executor
+	return l, executor
+}
+
+func makeZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, contents := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(contents))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func createFunction(t *testing.T, l *Lambda, name string, code string) *CreateFunctionOutput {
+	output, awserr := l.CreateFunction(CreateFunctionInput{
+		FunctionName: name,
+		Runtime:      "nodejs18.x",
+		Role:         "arn:aws:iam::123456789012:role/lambda",
+		Handler:      "index.handler",
+		Code:         FunctionCode{ZipFile: makeZip(t, map[string]string{"index.js": code})},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestCreateFunction(t *testing.T) {
+	l, _ := newLambda()
+
+	output := createFunction(t, l, "fn", "v1")
+	if output.StatusCode != 201 ||
+		output.FunctionArn != "arn:aws:lambda:us-east-1:123456789012:function:fn" ||
+		output.Version != "$LATEST" ||
+		output.Timeout != 3 ||
+		output.MemorySize != 128 ||
+		output.PackageType != "Zip" ||
+		output.State != "Active" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	_, awserr := l.CreateFunction(CreateFunctionInput{
+		FunctionName: "fn",
+		Runtime:      "nodejs18.x",
+		Role:         "role",
+		Handler:      "index.handler",
+		Code:         FunctionCode{ZipFile: makeZip(t, map[string]string{"index.js": ""})},
+	})
+	if awserr == nil || awserr.Body.Type != "ResourceConflictException" {
+		t.Fatal("Expected conflict", awserr)
+	}
+
+	badInputs := []CreateFunctionInput{
+		{FunctionName: "bad name", Runtime: "nodejs18.x", Role: "role", Handler: "index.handler"},
+		{FunctionName: "fn2", Runtime: "cobol", Role: "role", Handler: "index.handler"},
+		{FunctionName: "fn2", Runtime: "nodejs18.x", Role: "role"},
+		{FunctionName: "fn2", Runtime: "nodejs18.x", Role: "role", Handler: "index.handler",
+			Code: FunctionCode{ZipFile: []byte("not a zip")}},
+		{FunctionName: "fn2", Runtime: "nodejs18.x", Role: "role", Handler: "index.handler",
+			Code: FunctionCode{S3Bucket: "bucket", S3Key: "key"}},
+		{FunctionName: "fn2", PackageType: "Image", Role: "role"},
+	}
+	for _, input := range badInputs {
+		_, awserr := l.CreateFunction(input)
+		if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+			t.Fatalf("Expected error for %+v, got %v", input, awserr)
+		}
+	}
+}
+
+func TestGetFunction(t *testing.T) {
+	l, _ := newLambda()
+	created := createFunction(t, l, "fn", "v1")
+
+	for _, name := range []string{
+		"fn",
+		"fn:$LATEST",
+		"123456789012:function:fn",
+		"arn:aws:lambda:us-east-1:123456789012:function:fn",
+	} {
+		output, awserr := l.GetFunction(GetFunctionInput{FunctionName: name})
+		if awserr != nil {
+			t.Fatal(name, awserr)
+		}
+		if output.Configuration.RevisionId != created.RevisionId {
+			t.Fatal("Wrong function for", name)
+		}
+	}
+
+	_, awserr := l.GetFunction(GetFunctionInput{FunctionName: "missing"})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected not found", awserr)
+	}
+	_, awserr = l.GetFunction(GetFunctionInput{FunctionName: "fn", Qualifier: "1"})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestCreateFunctionWithECRImage(t *testing.T) {
+	l, _ := newLambda()
+	e, err := ecr.New(ecr.Options{ArnGenerator: l.arnGenerator, Addr: "localhost:4569", PersistDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.ecr = e
+	if _, awserr := e.CreateRepository(ecr.CreateRepositoryInput{RepositoryName: "app"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	image, awserr := e.PutImage(ecr.PutImageInput{
+		RepositoryName: "app",
+		ImageTag:       "latest",
+		ImageManifest:  `{"schemaVersion": 2, "config": {"mediaType": "application/vnd.oci.image.config.v1+json"}, "layers": []}`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	digest := image.Image.ImageId.ImageDigest
+
+	output, awserr := l.CreateFunction(CreateFunctionInput{
+		FunctionName: "fn",
+		Role:         "role",
+		PackageType:  "Image",
+		Code:         FunctionCode{ImageUri: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.CodeSha256 != strings.TrimPrefix(digest, "sha256:") {
+		t.Fatal("Unexpected CodeSha256", output.CodeSha256)
+	}
+	function, awserr := l.GetFunction(GetFunctionInput{FunctionName: "fn"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if function.Code.ResolvedImageUri != "localhost:4569/app@"+digest {
+		t.Fatal("Unexpected ResolvedImageUri", function.Code.ResolvedImageUri)
+	}
+
+	_, awserr = l.CreateFunction(CreateFunctionInput{
+		FunctionName: "missing",
+		Role:         "role",
+		PackageType:  "Image",
+		Code:         FunctionCode{ImageUri: "localhost:4569/app:v1"},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+		t.Fatal("Expected InvalidParameterValueException", awserr)
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	l, executor := newLambda()
+	createFunction(t, l, "fn", "v1")
+
+	output, awserr := l.Invoke(InvokeInput{
+		FunctionName: "fn",
+		LogType:      "Tail",
+		Payload:      []byte(`{"hello":"world"}`),
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if string(output.Payload) != `{"hello":"world"}` || output.FunctionError != "" || output.ExecutedVersion != "$LATEST" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	logs, _ := base64.StdEncoding.DecodeString(output.LogResult)
+	if string(logs) != "START\nv1\nEND\n" {
+		t.Fatalf("Unexpected logs: %q", logs)
+	}
+
+	output, awserr = l.Invoke(InvokeInput{
+		FunctionName: "fn",
+		Payload:      []byte(`{"fail":true}`),
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.FunctionError != "Unhandled" || output.LogResult != "" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	output, awserr = l.Invoke(InvokeInput{
+		FunctionName:   "fn",
+		InvocationType: "DryRun",
+	})
+	if awserr != nil || output.StatusCode != 204 {
+		t.Fatal("Unexpected DryRun result", output, awserr)
+	}
+	if len(executor.invocations) != 2 {
+		t.Fatal("DryRun should not invoke the function")
+	}
+
+	_, awserr = l.Invoke(InvokeInput{
+		FunctionName: "fn",
+		Payload:      []byte("not json"),
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestContentException" {
+		t.Fatal("Expected invalid content", awserr)
+	}
+}
+
+func TestUpdateFunctionCode(t *testing.T) {
+	l, executor := newLambda()
+	created := createFunction(t, l, "fn", "v1")
+
+	_, awserr := l.UpdateFunctionCode(UpdateFunctionCodeInput{
+		FunctionName: "fn",
+		ZipFile:      makeZip(t, map[string]string{"index.js": "v2"}),
+		RevisionId:   "stale",
+	})
+	if awserr == nil || awserr.Code != 412 {
+		t.Fatal("Expected precondition failure", awserr)
+	}
+
+	dryRun, awserr := l.UpdateFunctionCode(UpdateFunctionCodeInput{
+		FunctionName: "fn",
+		ZipFile:      makeZip(t, map[string]string{"index.js": "v2"}),
+		DryRun:       true,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if dryRun.CodeSha256 == created.CodeSha256 {
+		t.Fatal("DryRun should return the new code's hash")
+	}
+
+	updated, awserr := l.UpdateFunctionCode(UpdateFunctionCodeInput{
+		FunctionName: "fn",
+		ZipFile:      makeZip(t, map[string]string{"index.js": "v2"}),
+		RevisionId:   created.RevisionId,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.RevisionId == created.RevisionId || updated.CodeSha256 == created.CodeSha256 {
+		t.Fatalf("Unexpected output: %+v", updated)
+	}
+	if len(executor.stopped) != 1 || executor.stopped[0] != created.FunctionArn {
+		t.Fatal("Updating the code should stop the old execution environment")
+	}
+
+	output, awserr := l.Invoke(InvokeInput{FunctionName: "fn", LogType: "Tail"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	logs, _ := base64.StdEncoding.DecodeString(output.LogResult)
+	if string(logs) != "START\nv2\nEND\n" {
+		t.Fatalf("Expected the new code to run, got %q", logs)
+	}
+}
+
+func TestBaseImage(t *testing.T) {
+	cases := map[string]string{
+		"nodejs18.x":      "public.ecr.aws/lambda/nodejs:18",
+		"python3.11":      "public.ecr.aws/lambda/python:3.11",
+		"java8.al2":       "public.ecr.aws/lambda/java:8.al2",
+		"go1.x":           "public.ecr.aws/lambda/go:1",
+		"dotnet6":         "public.ecr.aws/lambda/dotnet:6",
+		"provided.al2023": "public.ecr.aws/lambda/provided:al2023",
+	}
+	for runtime, expected := range cases {
+		image, err := baseImage(runtime)
+		if err != nil {
+			t.Fatal(runtime, err)
+		}
+		if image != expected {
+			t.Fatalf("Wrong image for %s: %s", runtime, image)
+		}
+	}
+}
+
+func TestInvokeWritesLogs(t *testing.T) {
+	l, _ := newLambda()
+	logs := cloudwatchlogs.New(cloudwatchlogs.Options{})
+	l.logs = logs
+	createFunction(t, l, "fn", "hello")
+	if _, awserr := l.Invoke(InvokeInput{FunctionName: "fn"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	streams, awserr := logs.DescribeLogStreams(cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: "/aws/lambda/fn"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(streams.LogStreams) != 1 || !strings.Contains(streams.LogStreams[0].LogStreamName, "/[$LATEST]") {
+		t.Fatalf("Unexpected streams: %+v", streams)
+	}
+	events, awserr := logs.GetLogEvents(cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  "/aws/lambda/fn",
+		LogStreamName: streams.LogStreams[0].LogStreamName,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var messages []string
+	for _, event := range events.Events {
+		messages = append(messages, event.Message)
+	}
+	if !slices.Equal(messages, []string{"START", "hello", "END"}) {
+		t.Fatal("Unexpected messages", messages)
+	}
+}
+
+func TestInvokePublishesMetrics(t *testing.T) {
+	l, _ := newLambda()
+	metrics := cloudwatch.New(cloudwatch.Options{})
+	l.metrics = metrics
+	createFunction(t, l, "fn", "hello")
+	for _, payload := range []string{`{}`, `{"fail":true}`, `{}`} {
+		if _, awserr := l.Invoke(InvokeInput{FunctionName: "fn", Payload: []byte(payload)}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	now := float64(time.Now().Unix())
+	for metricName, expected := range map[string]float64{"Invocations": 3, "Errors": 1} {
+		output, awserr := metrics.GetMetricStatistics(cloudwatch.GetMetricStatisticsInput{
+			Namespace:  "AWS/Lambda",
+			MetricName: metricName,
+			Dimensions: []cloudwatch.APIDimension{{Name: "FunctionName", Value: "fn"}},
+			StartTime:  now - 150,
+			EndTime:    now + 150,
+			Period:     300,
+			Statistics: []string{"Sum", "SampleCount"},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if len(output.Datapoints) != 1 || *output.Datapoints[0].Sum != expected || *output.Datapoints[0].SampleCount != 3 {
+			t.Fatalf("Unexpected %s datapoints: %+v", metricName, output.Datapoints)
+		}
+	}
+}
+
+func TestRegions(t *testing.T) {
+	logs := cloudwatchlogs.New(cloudwatchlogs.Options{
+		ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+	})
+	l := New(Options{
+		ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		Logs:         logs,
+	})
+	executor := &fakeExecutor{}
+	l.executor = executor
+	if _, err := l.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected an unknown region to fail")
+	}
+	europe, err := l.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	europe.executor = executor
+
+	function := createFunction(t, europe, "fn", "hello")
+	if function.FunctionArn != "arn:aws:lambda:eu-west-1:123456789012:function:fn" {
+		t.Fatal("Unexpected ARN", function.FunctionArn)
+	}
+	if _, awserr := l.GetFunction(GetFunctionInput{FunctionName: "fn"}); awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected another region's function to be hidden", awserr)
+	}
+	_, awserr := l.invokeFunction(InvokeInput{FunctionName: function.FunctionArn})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+		t.Fatal("Expected API requests for another region's function to fail", awserr)
+	}
+
+	// Services invoke functions in their ARNs' regions, and the functions log there.
+	if _, awserr := l.Invoke(InvokeInput{FunctionName: function.FunctionArn}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	europeLogs, err := logs.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams, awserr := europeLogs.DescribeLogStreams(cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: "/aws/lambda/fn"})
+	if awserr != nil || len(streams.LogStreams) != 1 {
+		t.Fatalf("Expected the function to log in its region: %+v %v", streams, awserr)
+	}
+
+	// Function URLs don't say their regions, so every region's are served.
+	url, awserr := europe.CreateFunctionUrlConfig(CreateFunctionUrlConfigInput{FunctionName: "fn", AuthType: "NONE"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, id, _ := strings.Cut(url.FunctionUrl, functionUrlPathPrefix)
+	response := httptest.NewRecorder()
+	if !l.serveFunctionUrl(response, httptest.NewRequest(http.MethodGet, functionUrlPathPrefix+id, nil)) ||
+		response.Code != http.StatusOK {
+		t.Fatal("Expected the URL to invoke the function", response.Code)
+	}
+	if len(executor.invocations) != 2 {
+		t.Fatal("Unexpected invocations", executor.invocations)
+	}
+}
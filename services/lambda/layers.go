@@ -0,0 +1,316 @@
+package lambda
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/lambda/latest/dg/chapter-layers.html
+
+const maxLayersPerFunction = 5
+
+var layerNameRegex = regexp.MustCompile(`^[a-zA-Z0-9-_]{1,140}$`)
+
+// LayerVersion is immutable once published, so it can be used without holding the lock.
+type LayerVersion struct {
+	LayerName               string
+	LayerArn                string
+	Version                 int64
+	Description             string
+	CreatedDate             time.Time
+	CompatibleRuntimes      []string
+	CompatibleArchitectures []string
+	LicenseInfo             string
+	ZipFile                 []byte
+	CodeSha256              string
+}
+
+func (v *LayerVersion) arn() string {
+	return v.LayerArn + ":" + strconv.FormatInt(v.Version, 10)
+}
+
+func (v *LayerVersion) listItem() LayerVersionsListItem {
+	return LayerVersionsListItem{
+		LayerVersionArn:         v.arn(),
+		Version:                 v.Version,
+		Description:             v.Description,
+		CreatedDate:             v.CreatedDate.UTC().Format(lastModifiedTimeFormat),
+		CompatibleRuntimes:      v.CompatibleRuntimes,
+		CompatibleArchitectures: v.CompatibleArchitectures,
+		LicenseInfo:             v.LicenseInfo,
+	}
+}
+
+func (v *LayerVersion) content() LayerVersionContentOutput {
+	return LayerVersionContentOutput{
+		CodeSha256: v.CodeSha256,
+		CodeSize:   int64(len(v.ZipFile)),
+	}
+}
+
+func (l *Lambda) layerArn(name string) string {
+	return l.arnGenerator.GenerateWithoutType("lambda", "layer:"+name)
+}
+
+// parseLayerName accepts a layer name or ARN, and returns the name.
+func (l *Lambda) parseLayerName(identifier string) (string, *awserrors.Error) {
+	name := identifier
+	if strings.HasPrefix(identifier, "arn:") {
+		// arn:aws:lambda:us-east-1:123456789012:layer:name
+		parts := strings.Split(identifier, ":")
+		if len(parts) != 7 || parts[5] != "layer" {
+			return "", InvalidParameterValueException("Invalid layer ARN: " + identifier)
+		}
+		name = parts[6]
+		if l.layerArn(name) != identifier {
+			return "", ResourceNotFoundException("Layer not found: " + identifier)
+		}
+	}
+	if !layerNameRegex.MatchString(name) {
+		return "", InvalidParameterValueException("Invalid layer name: " + identifier)
+	}
+	return name, nil
+}
+
+func (l *Lambda) lockedGetLayerVersion(identifier string, version int64) (*LayerVersion, *awserrors.Error) {
+	name, awserr := l.parseLayerName(identifier)
+	if awserr != nil {
+		return nil, awserr
+	}
+	versions := l.layersByName[name]
+	if version < 1 || version > int64(len(versions)) || versions[version-1] == nil {
+		return nil, ResourceNotFoundException("The resource you requested does not exist.")
+	}
+	return versions[version-1], nil
+}
+
+// lockedGetFunctionLayers returns the layers a function uses, from their version ARNs.
+// Layers in other accounts, such as those AWS publishes, can't be downloaded, so they're skipped.
+func (l *Lambda) lockedGetFunctionLayers(arns []string, packageType string) ([]*LayerVersion, []Layer, *awserrors.Error) {
+	if len(arns) > maxLayersPerFunction {
+		return nil, nil, InvalidParameterValueException("Cannot reference more than 5 layers.")
+	}
+	if len(arns) > 0 && packageType == "Image" {
+		return nil, nil, InvalidParameterValueException("Please don't provide Layers when the function has packageType Image.")
+	}
+
+	var versions []*LayerVersion
+	var layers []Layer
+	for _, versionArn := range arns {
+		// arn:aws:lambda:us-east-1:123456789012:layer:name:version
+		a, err := arn.Parse(versionArn)
+		if err != nil || a.Service != "lambda" || a.ResourceType() != "layer" {
+			return nil, nil, InvalidParameterValueException("Invalid layer version ARN: " + versionArn)
+		}
+		name, number, _ := strings.Cut(a.ResourceId(), ":")
+		n, err := strconv.ParseInt(number, 10, 64)
+		if name == "" || err != nil {
+			return nil, nil, InvalidParameterValueException("Invalid layer version ARN: " + versionArn)
+		}
+		if a.AccountId != l.arnGenerator.AwsAccountId || a.Region != l.arnGenerator.Region {
+			l.logger.Warn("Skipping layer from another account or region", "layer", versionArn)
+			layers = append(layers, Layer{Arn: versionArn})
+			continue
+		}
+		a.Resource = "layer:" + name
+		version, awserr := l.lockedGetLayerVersion(a.String(), n)
+		if awserr != nil {
+			return nil, nil, InvalidParameterValueException("Layer version " + versionArn + " does not exist.")
+		}
+		versions = append(versions, version)
+		layers = append(layers, Layer{Arn: versionArn, CodeSize: int64(len(version.ZipFile))})
+	}
+	return versions, layers, nil
+}
+
+// setLayers extracts the layers into the directory mounted at /opt, in order,
+// so later layers overwrite files from earlier ones.
+func setLayers(version *FunctionVersion, layerVersions []*LayerVersion, layers []Layer) *awserrors.Error {
+	version.Layers = layers
+	version.optDir = ""
+	if len(layerVersions) == 0 {
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "lambda-"+version.FunctionName+"-opt-")
+	if err != nil {
+		return ServiceException(err.Error())
+	}
+	for _, layer := range layerVersions {
+		if err := extractZip(layer.ZipFile, dir); err != nil {
+			os.RemoveAll(dir)
+			return ServiceException("Extracting layer " + layer.arn() + ": " + err.Error())
+		}
+	}
+	version.optDir = dir
+	return nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_PublishLayerVersion.html
+func (l *Lambda) PublishLayerVersion(input PublishLayerVersionInput) (*PublishLayerVersionOutput, *awserrors.Error) {
+	name, awserr := l.parseLayerName(input.LayerName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.Content.S3Bucket != "" || input.Content.S3Key != "" {
+		return nil, InvalidParameterValueException("Uploading layers from S3 is not supported. Use ZipFile instead.")
+	}
+	if len(input.Content.ZipFile) == 0 {
+		return nil, InvalidParameterValueException("Please provide a source for layer content.")
+	}
+	if len(input.Content.ZipFile) > maxZipFileSize {
+		return nil, RequestTooLargeException("Request must be smaller than 52428800 bytes for the PublishLayerVersion operation")
+	}
+	// Check the zip file can be extracted, so functions using the layer can start.
+	dir, err := os.MkdirTemp("", "lambda-layer-"+name+"-")
+	if err != nil {
+		return nil, ServiceException(err.Error())
+	}
+	err = extractZip(input.Content.ZipFile, dir)
+	os.RemoveAll(dir)
+	if err != nil {
+		return nil, InvalidParameterValueException(unzipFailedErrorMessage)
+	}
+	for _, runtime := range input.CompatibleRuntimes {
+		if _, err := baseImage(runtime); err != nil {
+			return nil, InvalidParameterValueException("Value " + runtime + " at 'compatibleRuntimes' failed to satisfy constraint: Member must satisfy enum value set")
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hash := sha256.Sum256(input.Content.ZipFile)
+	version := &LayerVersion{
+		LayerName:               name,
+		LayerArn:                l.layerArn(name),
+		Version:                 int64(len(l.layersByName[name]) + 1),
+		Description:             input.Description,
+		CreatedDate:             l.clock(),
+		CompatibleRuntimes:      input.CompatibleRuntimes,
+		CompatibleArchitectures: input.CompatibleArchitectures,
+		LicenseInfo:             input.LicenseInfo,
+		ZipFile:                 input.Content.ZipFile,
+		CodeSha256:              base64.StdEncoding.EncodeToString(hash[:]),
+	}
+	l.layersByName[name] = append(l.layersByName[name], version)
+
+	return &PublishLayerVersionOutput{
+		LayerVersionsListItem: version.listItem(),
+		LayerArn:              version.LayerArn,
+		Content:               version.content(),
+		StatusCode:            201,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_GetLayerVersion.html
+func (l *Lambda) GetLayerVersion(input GetLayerVersionInput) (*GetLayerVersionOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	version, awserr := l.lockedGetLayerVersion(input.LayerName, input.VersionNumber)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetLayerVersionOutput{
+		LayerVersionsListItem: version.listItem(),
+		LayerArn:              version.LayerArn,
+		Content:               version.content(),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_ListLayerVersions.html
+func (l *Lambda) ListLayerVersions(input ListLayerVersionsInput) (*ListLayerVersionsOutput, *awserrors.Error) {
+	maxItems, awserr := parseMaxItems(input.MaxItems)
+	if awserr != nil {
+		return nil, awserr
+	}
+	name, awserr := l.parseLayerName(input.LayerName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Versions are listed newest first, and the marker is the next version number.
+	marker := int64(math.MaxInt64)
+	if input.Marker != "" {
+		n, err := strconv.ParseInt(input.Marker, 10, 64)
+		if err != nil {
+			return nil, InvalidParameterValueException("Invalid Marker: " + input.Marker)
+		}
+		marker = n
+	}
+	output := &ListLayerVersionsOutput{
+		LayerVersions: []LayerVersionsListItem{},
+	}
+	versions := l.layersByName[name]
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		if version == nil || version.Version > marker ||
+			(input.CompatibleRuntime != "" && !slices.Contains(version.CompatibleRuntimes, input.CompatibleRuntime)) ||
+			(input.CompatibleArchitecture != "" && !slices.Contains(version.CompatibleArchitectures, input.CompatibleArchitecture)) {
+			continue
+		}
+		if len(output.LayerVersions) == maxItems {
+			output.NextMarker = strconv.FormatInt(version.Version, 10)
+			break
+		}
+		output.LayerVersions = append(output.LayerVersions, version.listItem())
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_DeleteLayerVersion.html
+func (l *Lambda) DeleteLayerVersion(input DeleteLayerVersionInput) (*DeleteLayerVersionOutput, *awserrors.Error) {
+	name, awserr := l.parseLayerName(input.LayerName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Deleting a version which doesn't exist succeeds. Functions which use it keep their copy.
+	versions := l.layersByName[name]
+	if input.VersionNumber >= 1 && input.VersionNumber <= int64(len(versions)) {
+		versions[input.VersionNumber-1] = nil
+	}
+	return &DeleteLayerVersionOutput{StatusCode: 204}, nil
+}
+
+// layerEnvironmentVariables returns the variables which let processes find the layers' contents,
+// which runtimes find at /opt in containers.
+// https://docs.aws.amazon.com/lambda/latest/dg/packaging-layers.html#packaging-layers-paths
+func layerEnvironmentVariables(version *FunctionVersion) []string {
+	prepend := func(name string, dirs ...string) string {
+		if value := os.Getenv(name); value != "" {
+			dirs = append(dirs, value)
+		}
+		return name + "=" + strings.Join(dirs, string(os.PathListSeparator))
+	}
+	opt := func(path string) string {
+		return filepath.Join(version.optDir, path)
+	}
+	python := []string{opt("python")}
+	if strings.HasPrefix(version.Runtime, "python") {
+		python = append(python, opt(filepath.Join("python", "lib", version.Runtime, "site-packages")))
+	}
+	return []string{
+		prepend("PATH", opt("bin")),
+		prepend("LD_LIBRARY_PATH", opt("lib")),
+		prepend("PYTHONPATH", python...),
+		prepend("NODE_PATH", opt(filepath.Join("nodejs", "node_modules"))),
+	}
+}
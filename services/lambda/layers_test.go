@@ -0,0 +1,140 @@
+package lambda
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func publishLayerVersion(t *testing.T, l *Lambda, name string, files map[string]string) *PublishLayerVersionOutput {
+	output, awserr := l.PublishLayerVersion(PublishLayerVersionInput{
+		LayerName:          name,
+		Content:            LayerVersionContentInput{ZipFile: makeZip(t, files)},
+		CompatibleRuntimes: []string{"nodejs18.x"},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestLayerVersions(t *testing.T) {
+	l, _ := newLambda()
+
+	first := publishLayerVersion(t, l, "layer", map[string]string{"bin/tool": "v1"})
+	if first.StatusCode != 201 || first.Version != 1 ||
+		first.LayerArn != "arn:aws:lambda:us-east-1:123456789012:layer:layer" ||
+		first.LayerVersionArn != first.LayerArn+":1" || first.Content.CodeSize == 0 {
+		t.Fatalf("Unexpected layer version: %+v", first)
+	}
+	second := publishLayerVersion(t, l, "layer", map[string]string{"bin/tool": "v2"})
+	if second.Version != 2 {
+		t.Fatal("Unexpected version", second.Version)
+	}
+
+	// Layers can be identified by ARN.
+	got, awserr := l.GetLayerVersion(GetLayerVersionInput{LayerName: first.LayerArn, VersionNumber: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.LayerVersionArn != first.LayerVersionArn || got.Content.CodeSha256 != first.Content.CodeSha256 {
+		t.Fatalf("Unexpected layer version: %+v", got)
+	}
+
+	list, awserr := l.ListLayerVersions(ListLayerVersionsInput{LayerName: "layer", MaxItems: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.LayerVersions) != 1 || list.LayerVersions[0].Version != 2 || list.NextMarker != "1" {
+		t.Fatalf("Unexpected layer versions: %+v", list)
+	}
+	list, awserr = l.ListLayerVersions(ListLayerVersionsInput{LayerName: "layer", CompatibleRuntime: "python3.12"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.LayerVersions) != 0 {
+		t.Fatalf("Unexpected layer versions: %+v", list)
+	}
+
+	_, awserr = l.PublishLayerVersion(PublishLayerVersionInput{
+		LayerName: "layer",
+		Content:   LayerVersionContentInput{ZipFile: []byte("not a zip")},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+
+	_, awserr = l.DeleteLayerVersion(DeleteLayerVersionInput{LayerName: "layer", VersionNumber: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = l.GetLayerVersion(GetLayerVersionInput{LayerName: "layer", VersionNumber: 1})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected not found", awserr)
+	}
+	// Version numbers aren't reused.
+	if third := publishLayerVersion(t, l, "layer", nil); third.Version != 3 {
+		t.Fatal("Unexpected version", third.Version)
+	}
+}
+
+func TestFunctionLayers(t *testing.T) {
+	l, _ := newLambda()
+	tools := publishLayerVersion(t, l, "tools", map[string]string{"bin/tool": "tools", "lib/shared.so": "tools"})
+	libs := publishLayerVersion(t, l, "libs", map[string]string{"lib/shared.so": "libs"})
+
+	created, awserr := l.CreateFunction(CreateFunctionInput{
+		FunctionName: "fn",
+		Runtime:      "nodejs18.x",
+		Role:         "arn:aws:iam::123456789012:role/lambda",
+		Handler:      "index.handler",
+		Code:         FunctionCode{ZipFile: makeZip(t, map[string]string{"index.js": "v1"})},
+		Layers: []string{
+			tools.LayerVersionArn,
+			libs.LayerVersionArn,
+			// Layers AWS publishes are accepted, but have no contents.
+			"arn:aws:lambda:us-east-1:580247275435:layer:LambdaInsightsExtension:38",
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(created.Layers) != 3 || created.Layers[0].Arn != tools.LayerVersionArn ||
+		created.Layers[0].CodeSize != tools.Content.CodeSize || created.Layers[2].CodeSize != 0 {
+		t.Fatalf("Unexpected layers: %+v", created.Layers)
+	}
+
+	// Later layers overwrite files from earlier ones.
+	optDir := l.functionsByName["fn"].Latest.optDir
+	for path, contents := range map[string]string{"bin/tool": "tools", "lib/shared.so": "libs"} {
+		data, err := os.ReadFile(filepath.Join(optDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != contents {
+			t.Fatalf("Expected %s to contain %s, got %s", path, contents, data)
+		}
+	}
+
+	invalid := []UpdateFunctionConfigurationInput{
+		{Layers: []string{tools.LayerArn + ":5"}},
+		{Layers: []string{"layer"}},
+		{Layers: []string{tools.LayerVersionArn, tools.LayerVersionArn, tools.LayerVersionArn, tools.LayerVersionArn, tools.LayerVersionArn, tools.LayerVersionArn}},
+	}
+	for _, input := range invalid {
+		input.FunctionName = "fn"
+		_, awserr := l.UpdateFunctionConfiguration(input)
+		if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+			t.Fatalf("Expected invalid parameter for %+v, got %v", input, awserr)
+		}
+	}
+
+	// An empty list removes the layers.
+	updated, awserr := l.UpdateFunctionConfiguration(UpdateFunctionConfigurationInput{FunctionName: "fn", Layers: []string{}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(updated.Layers) != 0 || l.functionsByName["fn"].Latest.optDir != "" {
+		t.Fatalf("Unexpected layers: %+v", updated.Layers)
+	}
+}
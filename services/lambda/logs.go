@@ -0,0 +1,28 @@
+package lambda
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// logStreamName returns the name of the stream the version's execution environment logs to,
+// like 2024/01/02/[$LATEST]0123456789abcdef0123456789abcdef.
+// https://docs.aws.amazon.com/lambda/latest/dg/monitoring-cloudwatchlogs.html
+func (l *Lambda) logStreamName(version *FunctionVersion) string {
+	environmentId := sha256.Sum256([]byte(version.key()))
+	return l.clock().UTC().Format("2006/01/02") + "/[" + version.Version + "]" + hex.EncodeToString(environmentId[:16])
+}
+
+// writeLogs writes the function's output during an invocation to CloudWatch Logs, if it's enabled,
+// in the function's /aws/lambda/<name> log group.
+func (l *Lambda) writeLogs(version *FunctionVersion, logs []byte) {
+	if l.logs == nil || len(logs) == 0 {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(string(logs), "\n"), "\n")
+	err := l.logs.WriteLogs("/aws/lambda/"+version.FunctionName, l.logStreamName(version), lines)
+	if err != nil {
+		l.logger.Warn("Writing function logs", "function", version.FunctionArn, "error", err)
+	}
+}
@@ -0,0 +1,41 @@
+package lambda
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each region's functions' and layers' zip files, counting versions
+// which share their code once. Layers are prefixed with layer:. Container images aren't held in
+// memory.
+func (l *Lambda) MemoryUsage() []memory.Resource {
+	return l.regions.MemoryUsage((*Lambda).memoryUsage)
+}
+
+func (l *Lambda) memoryUsage() []memory.Resource {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var resources []memory.Resource
+	for name, function := range l.functionsByName {
+		resource := memory.Resource{Name: name, Count: 1 + len(function.Versions)}
+		seen := make(map[string]bool)
+		for _, version := range append([]*FunctionVersion{function.Latest}, function.Versions...) {
+			if !seen[version.CodeSha256] {
+				seen[version.CodeSha256] = true
+				resource.Bytes += int64(len(version.ZipFile))
+			}
+		}
+		resources = append(resources, resource)
+	}
+	for name, versions := range l.layersByName {
+		resource := memory.Resource{Name: "layer:" + name}
+		for _, version := range versions {
+			if version != nil {
+				resource.Count++
+				resource.Bytes += int64(len(version.ZipFile))
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
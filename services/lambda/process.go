@@ -0,0 +1,390 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// processExecutor runs functions as local processes, which is much faster than starting containers.
+// The process implements a custom runtime: it gets invocations from the Lambda runtime API,
+// which the executor serves at AWS_LAMBDA_RUNTIME_API, so runtime interface clients work unchanged.
+// https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html
+//
+// Functions without a command are run by the fallback executor.
+type processExecutor struct {
+	logger *slog.Logger
+	// By function name.
+	commands map[string][]string
+	fallback executor
+
+	mu sync.Mutex
+	// By version key.
+	processes map[string]*process
+}
+
+type process struct {
+	functionArn string
+	cmd         *exec.Cmd
+	server      *http.Server
+	output      *logBuffer
+
+	// Invocations are handed to the runtime when it asks for the next one.
+	next chan *pendingInvocation
+	// Closed when the process exits.
+	exited chan struct{}
+	// Set before exited is closed.
+	exitErr error
+
+	// The runtime handles one invocation at a time.
+	invokeMu sync.Mutex
+
+	mu        sync.Mutex
+	pending   map[string]*pendingInvocation
+	initError []byte
+}
+
+type pendingInvocation struct {
+	requestId string
+	payload   []byte
+	deadline  time.Time
+	// Buffered, so the runtime API handlers never block.
+	result chan *invocationResult
+}
+
+// logBuffer collects the process's output, which is written concurrently.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+// settle waits briefly for output the process wrote before responding, which is copied from its
+// stdout and stderr asynchronously, so it appears in the right invocation's logs.
+func (b *logBuffer) settle() {
+	length := b.Len()
+	for i := 0; i < 10; i++ {
+		time.Sleep(5 * time.Millisecond)
+		newLength := b.Len()
+		if newLength == length {
+			return
+		}
+		length = newLength
+	}
+}
+
+// Since returns a copy of the output after offset.
+func (b *logBuffer) Since(offset int) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes()[offset:])
+}
+
+func newProcessExecutor(logger *slog.Logger, commands map[string][]string, fallback executor) *processExecutor {
+	return &processExecutor{
+		logger:    logger,
+		commands:  commands,
+		fallback:  fallback,
+		processes: make(map[string]*process),
+	}
+}
+
+// ParseExecCommands parses function commands in the form name=command,name=command.
+// Commands are split into arguments on spaces.
+func ParseExecCommands(s string) (map[string][]string, error) {
+	commands := make(map[string][]string)
+	if s == "" {
+		return commands, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		name, command, ok := strings.Cut(entry, "=")
+		args := strings.Fields(command)
+		if !ok || !functionNameRegex.MatchString(name) || len(args) == 0 {
+			return nil, fmt.Errorf("invalid function command %q", entry)
+		}
+		commands[name] = args
+	}
+	return commands, nil
+}
+
+func (e *processExecutor) invoke(ctx context.Context, version *FunctionVersion, payload []byte) (*invocationResult, error) {
+	if _, ok := e.commands[version.FunctionName]; !ok {
+		return e.fallback.invoke(ctx, version, payload)
+	}
+
+	p, err := e.getProcess(version)
+	if err != nil {
+		return nil, err
+	}
+
+	p.invokeMu.Lock()
+	defer p.invokeMu.Unlock()
+
+	start := time.Now()
+	invocation := &pendingInvocation{
+		requestId: uuid.Must(uuid.NewV4()).String(),
+		payload:   payload,
+		deadline:  start.Add(time.Duration(version.Timeout) * time.Second),
+		result:    make(chan *invocationResult, 1),
+	}
+	p.mu.Lock()
+	p.pending[invocation.requestId] = invocation
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, invocation.requestId)
+		p.mu.Unlock()
+	}()
+
+	logOffset := p.output.Len()
+	fmt.Fprintf(p.output, "START RequestId: %s Version: %s\n", invocation.requestId, version.Version)
+	result := e.await(ctx, p, version, invocation)
+	duration := time.Since(start)
+	p.output.settle()
+	fmt.Fprintf(p.output, "END RequestId: %s\n", invocation.requestId)
+	fmt.Fprintf(p.output, "REPORT RequestId: %s\tDuration: %.2f ms\tBilled Duration: %d ms\tMemory Size: %d MB\n",
+		invocation.requestId, float64(duration.Microseconds())/1000, duration.Milliseconds()+1, version.MemorySize)
+	result.logs = p.output.Since(logOffset)
+	return result, nil
+}
+
+// await hands the invocation to the runtime and waits for its result.
+// If the function times out or the process exits, the process is discarded.
+func (e *processExecutor) await(ctx context.Context, p *process, version *FunctionVersion, invocation *pendingInvocation) *invocationResult {
+	ctx, cancel := context.WithDeadline(ctx, invocation.deadline)
+	defer cancel()
+
+	var result *invocationResult
+	select {
+	case p.next <- invocation:
+		select {
+		case result = <-invocation.result:
+			return result
+		case <-p.exited:
+		case <-ctx.Done():
+		}
+	case <-p.exited:
+	case <-ctx.Done():
+	}
+
+	select {
+	case <-p.exited:
+		e.removeProcess(version.key(), p)
+		p.mu.Lock()
+		initError := p.initError
+		p.mu.Unlock()
+		if initError != nil {
+			return &invocationResult{payload: initError, functionError: "Unhandled"}
+		}
+		return errorResult("Runtime.ExitError",
+			fmt.Sprintf("RequestId: %s Error: Runtime exited with error: %v", invocation.requestId, p.exitErr), nil)
+	default:
+		e.removeProcess(version.key(), p)
+		message := fmt.Sprintf("%s %s Task timed out after %.2f seconds",
+			time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), invocation.requestId, float64(version.Timeout))
+		return errorResult("Sandbox.Timedout", message, nil)
+	}
+}
+
+func (e *processExecutor) getProcess(version *FunctionVersion) (*process, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if p, ok := e.processes[version.key()]; ok {
+		return p, nil
+	}
+	p, err := e.startProcess(version)
+	if err != nil {
+		return nil, err
+	}
+	e.processes[version.key()] = p
+	return p, nil
+}
+
+func (e *processExecutor) startProcess(version *FunctionVersion) (*process, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	args := e.commands[version.FunctionName]
+	p := &process{
+		functionArn: version.FunctionArn,
+		cmd:         exec.Command(args[0], args[1:]...),
+		output:      &logBuffer{},
+		next:        make(chan *pendingInvocation),
+		exited:      make(chan struct{}),
+		pending:     make(map[string]*pendingInvocation),
+	}
+	p.server = &http.Server{Handler: p.runtimeAPI(version)}
+	go p.server.Serve(listener)
+
+	// Unlike in a container, the process inherits the environment, so it can find interpreters.
+	p.cmd.Env = os.Environ()
+	for name, value := range version.environmentVariables() {
+		p.cmd.Env = append(p.cmd.Env, name+"="+value)
+	}
+	p.cmd.Env = append(p.cmd.Env,
+		"AWS_LAMBDA_RUNTIME_API="+listener.Addr().String(),
+		"LAMBDA_TASK_ROOT="+version.codeDir,
+		"_HANDLER="+version.Handler,
+	)
+	if version.optDir != "" {
+		p.cmd.Env = append(p.cmd.Env, layerEnvironmentVariables(version)...)
+	}
+	p.cmd.Stdout = p.output
+	p.cmd.Stderr = p.output
+	// Don't wait for children which inherited the output, such as those started by shell scripts.
+	p.cmd.WaitDelay = time.Second
+
+	if err := p.cmd.Start(); err != nil {
+		p.server.Close()
+		return nil, fmt.Errorf("starting %s: %v", args[0], err)
+	}
+	e.logger.Info("Started process", "function", version.FunctionArn, "pid", p.cmd.Process.Pid)
+	go func() {
+		p.exitErr = p.cmd.Wait()
+		p.server.Close()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// runtimeAPI serves the runtime API to the process.
+func (p *process) runtimeAPI(version *FunctionVersion) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/2018-06-01/runtime/invocation/next", func(w http.ResponseWriter, r *http.Request) {
+		var invocation *pendingInvocation
+		select {
+		case invocation = <-p.next:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Lambda-Runtime-Aws-Request-Id", invocation.requestId)
+		w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(invocation.deadline.UnixMilli(), 10))
+		w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", version.FunctionArn)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(invocation.payload)
+	})
+	mux.HandleFunc("/2018-06-01/runtime/invocation/", func(w http.ResponseWriter, r *http.Request) {
+		// /2018-06-01/runtime/invocation/{AwsRequestId}/response or /error
+		rest := strings.TrimPrefix(r.URL.Path, "/2018-06-01/runtime/invocation/")
+		requestId, action, _ := strings.Cut(rest, "/")
+		if r.Method != http.MethodPost || (action != "response" && action != "error") {
+			http.NotFound(w, r)
+			return
+		}
+		p.mu.Lock()
+		invocation, ok := p.pending[requestId]
+		p.mu.Unlock()
+		if !ok {
+			writeRuntimeAPIError(w, http.StatusBadRequest, "InvalidRequestID", "Invalid request ID")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+
+		result := &invocationResult{payload: body}
+		if action == "error" {
+			result.functionError = "Unhandled"
+		}
+		select {
+		case invocation.result <- result:
+		default:
+			writeRuntimeAPIError(w, http.StatusBadRequest, "InvalidStateTransition", "Response already sent")
+			return
+		}
+		writeRuntimeAPIStatus(w)
+	})
+	mux.HandleFunc("/2018-06-01/runtime/init/error", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		p.initError = body
+		p.mu.Unlock()
+		writeRuntimeAPIStatus(w)
+	})
+	return mux
+}
+
+func writeRuntimeAPIStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"status":"OK"}`))
+}
+
+func writeRuntimeAPIError(w http.ResponseWriter, status int, errorType string, message string) {
+	body, _ := json.Marshal(map[string]string{
+		"errorType":    errorType,
+		"errorMessage": message,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func (p *process) kill() {
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return
+	}
+	<-p.exited
+}
+
+// removeProcess discards the process if it is still the one running the version.
+func (e *processExecutor) removeProcess(key string, p *process) {
+	e.mu.Lock()
+	if e.processes[key] == p {
+		delete(e.processes, key)
+	}
+	e.mu.Unlock()
+
+	p.kill()
+}
+
+func (e *processExecutor) stop(functionArn string) {
+	e.mu.Lock()
+	var stopped []*process
+	for key, p := range e.processes {
+		if p.functionArn == functionArn {
+			stopped = append(stopped, p)
+			delete(e.processes, key)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, p := range stopped {
+		p.kill()
+	}
+	e.fallback.stop(functionArn)
+}
+
+var _ executor = (*processExecutor)(nil)
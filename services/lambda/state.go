@@ -0,0 +1,214 @@
+package lambda
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type functionState struct {
+	*Function
+	PublishedRevisionId string `json:",omitempty"`
+}
+
+type layerState struct {
+	Name string
+	// Numbered from 1, with nil for deleted versions.
+	Versions []*LayerVersion
+}
+
+type functionsState struct {
+	Functions []functionState
+	Layers    []layerState
+	// In the order they were created.
+	EventSourceMappings []*EventSourceMapping
+}
+
+// TryPause pauses changes to each region's functions while a snapshot copies them.
+func (l *Lambda) TryPause() (func(), bool) {
+	return l.regions.TryPause(func(l *Lambda) *sync.Mutex { return &l.mu })
+}
+
+// ExportState writes each region's functions with their versions, aliases and URLs, its layers, and
+// its event source mappings to the archive. Execution environments aren't exported.
+func (l *Lambda) ExportState(w *state.Writer) error {
+	return l.regions.ExportState(w, (*Lambda).exportState)
+}
+
+func (l *Lambda) exportState(w *state.Writer) error {
+	unlock := w.Lock(&l.mu)
+	defer unlock()
+
+	exported := functionsState{EventSourceMappings: l.eventSourceMappings}
+	for _, function := range l.functionsByName {
+		exported.Functions = append(exported.Functions, functionState{
+			Function:            function,
+			PublishedRevisionId: function.publishedRevisionId,
+		})
+	}
+	slices.SortFunc(exported.Functions, func(a, b functionState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for name, versions := range l.layersByName {
+		exported.Layers = append(exported.Layers, layerState{Name: name, Versions: versions})
+	}
+	slices.SortFunc(exported.Layers, func(a, b layerState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("functions.json", exported)
+}
+
+// importVersion extracts the imported version's code and layers.
+func importVersion(version *FunctionVersion, layers map[string]*LayerVersion) error {
+	if version == nil || version.FunctionArn == "" {
+		return fmt.Errorf("invalid function version")
+	}
+	version.codeDir = ""
+	if version.PackageType == "Zip" {
+		dir, err := os.MkdirTemp("", "lambda-"+version.FunctionName+"-")
+		if err != nil {
+			return err
+		}
+		version.codeDir = dir
+		if err := extractZip(version.ZipFile, dir); err != nil {
+			return fmt.Errorf("function %s version %s: %v", version.FunctionName, version.Version, err)
+		}
+	}
+	// Like when the version was created, layers in other accounts are skipped.
+	var layerVersions []*LayerVersion
+	for _, layer := range version.Layers {
+		if layerVersion, ok := layers[layer.Arn]; ok {
+			layerVersions = append(layerVersions, layerVersion)
+		}
+	}
+	if awserr := setLayers(version, layerVersions, version.Layers); awserr != nil {
+		return fmt.Errorf("function %s version %s: %s", version.FunctionName, version.Version, awserr.Body.Message)
+	}
+	return nil
+}
+
+// removeVersionDirs removes the directories the versions' code and layers were extracted to.
+func removeVersionDirs(versions []*FunctionVersion) {
+	for _, version := range versions {
+		if version.codeDir != "" {
+			os.RemoveAll(version.codeDir)
+		}
+		if version.optDir != "" {
+			os.RemoveAll(version.optDir)
+		}
+	}
+}
+
+// ImportState replaces each region's functions, layers and event source mappings with the archive's.
+// The functions' code and layers are extracted again, and enabled event source mappings start
+// reading their sources from their starting positions.
+func (l *Lambda) ImportState(r *state.Reader) (func() error, error) {
+	return l.regions.ImportState(r, (*Lambda).importState, (*Lambda).exportState)
+}
+
+func (l *Lambda) importState(r *state.Reader) (func() error, error) {
+	var imported functionsState
+	if err := r.ReadJSON("functions.json", &imported); err != nil {
+		return nil, err
+	}
+
+	layersByName := make(map[string][]*LayerVersion)
+	layersByArn := make(map[string]*LayerVersion)
+	for _, layer := range imported.Layers {
+		if !layerNameRegex.MatchString(layer.Name) {
+			return nil, fmt.Errorf("invalid layer %q", layer.Name)
+		}
+		for _, version := range layer.Versions {
+			if version != nil {
+				layersByArn[version.arn()] = version
+			}
+		}
+		layersByName[layer.Name] = layer.Versions
+	}
+
+	var versions []*FunctionVersion
+	functionsByName := make(map[string]*Function)
+	for _, f := range imported.Functions {
+		function := f.Function
+		if function == nil || !functionNameRegex.MatchString(function.Name) || function.ARN == "" {
+			removeVersionDirs(versions)
+			return nil, fmt.Errorf("invalid function")
+		}
+		function.publishedRevisionId = f.PublishedRevisionId
+		if function.Tags == nil {
+			function.Tags = make(map[string]string)
+		}
+		if function.Aliases == nil {
+			function.Aliases = make(map[string]*Alias)
+		}
+		for _, version := range append([]*FunctionVersion{function.Latest}, function.Versions...) {
+			err := importVersion(version, layersByArn)
+			if version != nil {
+				versions = append(versions, version)
+			}
+			if err != nil {
+				removeVersionDirs(versions)
+				return nil, err
+			}
+		}
+		functionsByName[function.Name] = function
+	}
+
+	for _, m := range imported.EventSourceMappings {
+		if m == nil || m.UUID == "" {
+			removeVersionDirs(versions)
+			return nil, fmt.Errorf("invalid event source mapping")
+		}
+		if _, ok := functionsByName[m.FunctionName]; !ok {
+			removeVersionDirs(versions)
+			return nil, fmt.Errorf("event source mapping %s: function %s doesn't exist", m.UUID, m.FunctionName)
+		}
+		m.stop = make(chan struct{})
+	}
+
+	return func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		// Execution environments running the old functions are shut down, and batches being
+		// processed by the old event source mappings still complete.
+		for _, function := range l.functionsByName {
+			l.executor.stop(function.ARN)
+		}
+		for _, m := range l.eventSourceMappings {
+			close(m.stop)
+		}
+		l.functionsByName = functionsByName
+		l.layersByName = layersByName
+		l.eventSourceMappings = imported.EventSourceMappings
+
+		for _, m := range l.eventSourceMappings {
+			if m.State == "Enabled" {
+				go l.runImportedEventSourceMapping(m)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// runImportedEventSourceMapping runs the imported event source mapping. Its source may be imported
+// after Lambda, so it's retried until it exists.
+func (l *Lambda) runImportedEventSourceMapping(m *EventSourceMapping) {
+	for {
+		source, _, awserr := newEventSource(l.eventSourceServices(), m.EventSourceArn, m.StartingPosition,
+			&m.BatchSize, int32(m.BatchingWindow/time.Second))
+		if awserr == nil {
+			l.runEventSourceMapping(m, source)
+			return
+		}
+		l.setLastProcessingResult(m, "PROBLEM: "+awserr.Body.Message)
+		if !sleep(m.stop, eventSourceRetryInterval) {
+			return
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package lambda
+
+type Environment struct {
+	Variables map[string]string
+}
+
+type EnvironmentResponse struct {
+	Variables map[string]string `json:",omitempty"`
+}
+
+type FunctionCode struct {
+	// Base64 encoded in JSON.
+	ZipFile         []byte
+	S3Bucket        string
+	S3Key           string
+	S3ObjectVersion string
+	ImageUri        string
+}
+
+type FunctionCodeLocation struct {
+	RepositoryType   string
+	Location         string `json:",omitempty"`
+	ImageUri         string `json:",omitempty"`
+	ResolvedImageUri string `json:",omitempty"`
+}
+
+type FunctionConfiguration struct {
+	FunctionName     string
+	FunctionArn      string
+	Runtime          string `json:",omitempty"`
+	Role             string
+	Handler          string `json:",omitempty"`
+	CodeSize         int64
+	Description      string
+	Timeout          int32
+	MemorySize       int32
+	LastModified     string
+	CodeSha256       string
+	Version          string
+	Environment      *EnvironmentResponse `json:",omitempty"`
+	KMSKeyArn        string               `json:",omitempty"`
+	Layers           []Layer              `json:",omitempty"`
+	RevisionId       string
+	PackageType      string
+	Architectures    []string
+	State            string
+	LastUpdateStatus string
+}
+
+type CreateFunctionInput struct {
+	FunctionName string `required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Runtime      string
+	Role         string `required:"true" pattern:"arn:(aws[a-zA-Z-]*)?:iam::\\d{12}:role/?[a-zA-Z_0-9+=,.@\\-_/]+"`
+	Handler      string `length:",128" pattern:"[^\\s]+"`
+	Code         FunctionCode
+	Description  string `length:",256"`
+	Timeout      *int32 `range:"1,900"`
+	MemorySize   *int32 `range:"128,10240"`
+	Environment  *Environment
+	KMSKeyArn    string
+	// Layer version ARNs, which are extracted to /opt in order.
+	Layers []string `length:",5"`
+	// Zip or Image.
+	PackageType   string `enum:"Zip|Image"`
+	Publish       bool
+	Architectures []string `length:"1,1" enum:"x86_64|arm64"`
+	Tags          map[string]string
+}
+
+type CreateFunctionOutput struct {
+	FunctionConfiguration
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type GetFunctionInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Qualifier    string `json:"-" rest:"query:Qualifier" length:"1,128" pattern:"[a-zA-Z0-9$_-]+"`
+}
+
+type GetFunctionOutput struct {
+	Configuration FunctionConfiguration
+	Code          FunctionCodeLocation
+	Tags          map[string]string `json:",omitempty"`
+}
+
+type InvokeInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Qualifier    string `json:"-" rest:"query:Qualifier" length:"1,128" pattern:"[a-zA-Z0-9$_-]+"`
+	// RequestResponse, Event or DryRun.
+	InvocationType string `json:"-" rest:"header:X-Amz-Invocation-Type" enum:"Event|RequestResponse|DryRun"`
+	// None or Tail.
+	LogType       string `json:"-" rest:"header:X-Amz-Log-Type" enum:"None|Tail"`
+	ClientContext string `json:"-" rest:"header:X-Amz-Client-Context"`
+	Payload       []byte `json:"-" rest:"body"`
+}
+
+type InvokeOutput struct {
+	StatusCode      int    `json:"-" rest:"status"`
+	FunctionError   string `json:"-" rest:"header:X-Amz-Function-Error"`
+	LogResult       string `json:"-" rest:"header:X-Amz-Log-Result"`
+	ExecutedVersion string `json:"-" rest:"header:X-Amz-Executed-Version"`
+	Payload         []byte `json:"-" rest:"body"`
+}
+
+type UpdateFunctionCodeInput struct {
+	FunctionName    string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	ZipFile         []byte
+	S3Bucket        string `length:"3,63" pattern:"[0-9A-Za-z\\.\\-_]*(?:[A-Za-z0-9])"`
+	S3Key           string `length:"1,1024"`
+	S3ObjectVersion string `length:"1,1024"`
+	ImageUri        string
+	Publish         bool
+	DryRun          bool
+	RevisionId      string
+	Architectures   []string `length:"1,1" enum:"x86_64|arm64"`
+}
+
+type UpdateFunctionCodeOutput struct {
+	FunctionConfiguration
+}
+
+type UpdateFunctionConfigurationInput struct {
+	FunctionName string  `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Role         *string `pattern:"arn:(aws[a-zA-Z-]*)?:iam::\\d{12}:role/?[a-zA-Z_0-9+=,.@\\-_/]+"`
+	Handler      *string `length:",128" pattern:"[^\\s]+"`
+	Description  *string `length:",256"`
+	Timeout      *int32  `range:"1,900"`
+	MemorySize   *int32  `range:"128,10240"`
+	Environment  *Environment
+	Runtime      *string
+	KMSKeyArn    *string
+	// Replaces the layers if set.
+	Layers     []string `length:",5"`
+	RevisionId string
+}
+
+type UpdateFunctionConfigurationOutput struct {
+	FunctionConfiguration
+}
+
+type PublishVersionInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	// Only publish if the code has this hash.
+	CodeSha256  string
+	Description string `length:",256"`
+	RevisionId  string
+}
+
+type PublishVersionOutput struct {
+	FunctionConfiguration
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type ListVersionsByFunctionInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Marker       string `json:"-" rest:"query:Marker"`
+	MaxItems     int    `json:"-" rest:"query:MaxItems" range:"1,10000"`
+}
+
+type ListVersionsByFunctionOutput struct {
+	Versions   []FunctionConfiguration
+	NextMarker string `json:",omitempty"`
+}
+
+type AliasRoutingConfiguration struct {
+	// The version, and the fraction of invocations routed to it, between 0 and 1.
+	AdditionalVersionWeights map[string]float64
+}
+
+type AliasConfiguration struct {
+	AliasArn        string
+	Name            string
+	FunctionVersion string
+	Description     string
+	RoutingConfig   *AliasRoutingConfiguration `json:",omitempty"`
+	RevisionId      string
+}
+
+type CreateAliasInput struct {
+	FunctionName    string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Name            string `required:"true" length:"1,128" pattern:"[a-zA-Z0-9-_]+"`
+	FunctionVersion string `required:"true" length:"1,1024" pattern:"\\$LATEST|[0-9]+"`
+	Description     string `length:",256"`
+	RoutingConfig   *AliasRoutingConfiguration
+}
+
+type CreateAliasOutput struct {
+	AliasConfiguration
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type GetAliasInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Name         string `json:"-" rest:"path:Name" required:"true" length:"1,128" pattern:"[a-zA-Z0-9-_]+"`
+}
+
+type GetAliasOutput struct {
+	AliasConfiguration
+}
+
+type UpdateAliasInput struct {
+	FunctionName    string  `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Name            string  `json:"-" rest:"path:Name" required:"true" length:"1,128" pattern:"[a-zA-Z0-9-_]+"`
+	FunctionVersion string  `length:"1,1024" pattern:"\\$LATEST|[0-9]+"`
+	Description     *string `length:",256"`
+	// Replaces the routing configuration if set.
+	RoutingConfig *AliasRoutingConfiguration
+	RevisionId    string
+}
+
+type UpdateAliasOutput struct {
+	AliasConfiguration
+}
+
+type DeleteAliasInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Name         string `json:"-" rest:"path:Name" required:"true" length:"1,128" pattern:"[a-zA-Z0-9-_]+"`
+}
+
+type DeleteAliasOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type ListAliasesInput struct {
+	FunctionName    string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	FunctionVersion string `json:"-" rest:"query:FunctionVersion" length:"1,1024" pattern:"\\$LATEST|[0-9]+"`
+	Marker          string `json:"-" rest:"query:Marker"`
+	MaxItems        int    `json:"-" rest:"query:MaxItems" range:"1,10000"`
+}
+
+type ListAliasesOutput struct {
+	Aliases    []AliasConfiguration
+	NextMarker string `json:",omitempty"`
+}
+
+type EventSourceMappingConfiguration struct {
+	UUID                           string
+	EventSourceArn                 string
+	FunctionArn                    string
+	BatchSize                      int32
+	MaximumBatchingWindowInSeconds int32
+	StartingPosition               string   `json:",omitempty"`
+	FunctionResponseTypes          []string `json:",omitempty"`
+	// Seconds since the epoch.
+	LastModified          float64
+	LastProcessingResult  string
+	State                 string
+	StateTransitionReason string
+}
+
+type CreateEventSourceMappingInput struct {
+	EventSourceArn                 string
+	FunctionName                   string `required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Enabled                        *bool
+	BatchSize                      *int32 `range:"1,10000"`
+	MaximumBatchingWindowInSeconds *int32 `range:"0,300"`
+	// TRIM_HORIZON or LATEST, for streams only.
+	StartingPosition string `enum:"TRIM_HORIZON|LATEST|AT_TIMESTAMP"`
+	// ReportBatchItemFailures, to let functions report which records failed.
+	FunctionResponseTypes []string `length:",1" enum:"ReportBatchItemFailures"`
+}
+
+type CreateEventSourceMappingOutput struct {
+	EventSourceMappingConfiguration
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type GetEventSourceMappingInput struct {
+	UUID string `json:"-" rest:"path:UUID" required:"true"`
+}
+
+type GetEventSourceMappingOutput struct {
+	EventSourceMappingConfiguration
+}
+
+type DeleteEventSourceMappingInput struct {
+	UUID string `json:"-" rest:"path:UUID" required:"true"`
+}
+
+type DeleteEventSourceMappingOutput struct {
+	EventSourceMappingConfiguration
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type ListEventSourceMappingsInput struct {
+	EventSourceArn string `json:"-" rest:"query:EventSourceArn"`
+	FunctionName   string `json:"-" rest:"query:FunctionName" length:"1,140"`
+	Marker         string `json:"-" rest:"query:Marker"`
+	MaxItems       int    `json:"-" rest:"query:MaxItems" range:"1,10000"`
+}
+
+type ListEventSourceMappingsOutput struct {
+	EventSourceMappings []EventSourceMappingConfiguration
+	NextMarker          string `json:",omitempty"`
+}
+
+type Cors struct {
+	AllowCredentials bool     `json:",omitempty"`
+	AllowHeaders     []string `json:",omitempty"`
+	AllowMethods     []string `json:",omitempty"`
+	AllowOrigins     []string `json:",omitempty"`
+	ExposeHeaders    []string `json:",omitempty"`
+	MaxAge           int32    `json:",omitempty"`
+}
+
+type CreateFunctionUrlConfigInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Qualifier    string `json:"-" rest:"query:Qualifier" length:"1,128" pattern:"[a-zA-Z0-9$_-]+"`
+	// NONE or AWS_IAM.
+	AuthType string `required:"true" enum:"NONE|AWS_IAM"`
+	Cors     *Cors
+	// BUFFERED or RESPONSE_STREAM.
+	InvokeMode string `enum:"BUFFERED|RESPONSE_STREAM"`
+}
+
+type CreateFunctionUrlConfigOutput struct {
+	FunctionUrl  string
+	FunctionArn  string
+	AuthType     string
+	Cors         *Cors `json:",omitempty"`
+	CreationTime string
+	InvokeMode   string
+	StatusCode   int `json:"-" rest:"status"`
+}
+
+type GetFunctionUrlConfigInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Qualifier    string `json:"-" rest:"query:Qualifier" length:"1,128" pattern:"[a-zA-Z0-9$_-]+"`
+}
+
+type GetFunctionUrlConfigOutput struct {
+	FunctionUrl      string
+	FunctionArn      string
+	AuthType         string
+	Cors             *Cors `json:",omitempty"`
+	CreationTime     string
+	LastModifiedTime string
+	InvokeMode       string
+}
+
+type DeleteFunctionUrlConfigInput struct {
+	FunctionName string `json:"-" rest:"path:FunctionName" required:"true" length:"1,170" pattern:"(arn:(aws[a-zA-Z-]*)?:lambda:)?([a-z0-9-]+:)?(\\d{12}:)?(function:)?([a-zA-Z0-9-_\\.]+)(:(\\$LATEST|[a-zA-Z0-9-_]+))?"`
+	Qualifier    string `json:"-" rest:"query:Qualifier" length:"1,128" pattern:"[a-zA-Z0-9$_-]+"`
+}
+
+type DeleteFunctionUrlConfigOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type Layer struct {
+	Arn      string
+	CodeSize int64
+}
+
+type LayerVersionContentInput struct {
+	// Base64 encoded in JSON.
+	ZipFile         []byte
+	S3Bucket        string `length:"3,63" pattern:"[0-9A-Za-z\\.\\-_]*(?:[A-Za-z0-9])"`
+	S3Key           string `length:"1,1024"`
+	S3ObjectVersion string `length:"1,1024"`
+}
+
+type LayerVersionContentOutput struct {
+	CodeSha256 string
+	CodeSize   int64
+}
+
+type LayerVersionsListItem struct {
+	LayerVersionArn         string
+	Version                 int64
+	Description             string
+	CreatedDate             string
+	CompatibleRuntimes      []string `json:",omitempty"`
+	CompatibleArchitectures []string `json:",omitempty"`
+	LicenseInfo             string   `json:",omitempty"`
+}
+
+type PublishLayerVersionInput struct {
+	LayerName               string `json:"-" rest:"path:LayerName" required:"true" length:"1,140" pattern:"(arn:[a-zA-Z0-9-]+:lambda:[a-zA-Z0-9-]+:\\d{12}:layer:[a-zA-Z0-9-_]+)|[a-zA-Z0-9-_]+"`
+	Description             string `length:",256"`
+	Content                 LayerVersionContentInput
+	CompatibleRuntimes      []string `length:",15"`
+	CompatibleArchitectures []string `length:",2" enum:"x86_64|arm64"`
+	LicenseInfo             string   `length:",512"`
+}
+
+type PublishLayerVersionOutput struct {
+	LayerVersionsListItem
+	LayerArn   string
+	Content    LayerVersionContentOutput
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type GetLayerVersionInput struct {
+	LayerName     string `json:"-" rest:"path:LayerName" required:"true" length:"1,140" pattern:"(arn:[a-zA-Z0-9-]+:lambda:[a-zA-Z0-9-]+:\\d{12}:layer:[a-zA-Z0-9-_]+)|[a-zA-Z0-9-_]+"`
+	VersionNumber int64  `json:"-" rest:"path:VersionNumber"`
+}
+
+type GetLayerVersionOutput struct {
+	LayerVersionsListItem
+	LayerArn string
+	Content  LayerVersionContentOutput
+}
+
+type ListLayerVersionsInput struct {
+	LayerName              string `json:"-" rest:"path:LayerName" required:"true" length:"1,140" pattern:"(arn:[a-zA-Z0-9-]+:lambda:[a-zA-Z0-9-]+:\\d{12}:layer:[a-zA-Z0-9-_]+)|[a-zA-Z0-9-_]+"`
+	CompatibleRuntime      string `json:"-" rest:"query:CompatibleRuntime"`
+	CompatibleArchitecture string `json:"-" rest:"query:CompatibleArchitecture" enum:"x86_64|arm64"`
+	Marker                 string `json:"-" rest:"query:Marker"`
+	MaxItems               int    `json:"-" rest:"query:MaxItems" range:"1,50"`
+}
+
+type ListLayerVersionsOutput struct {
+	LayerVersions []LayerVersionsListItem
+	NextMarker    string `json:",omitempty"`
+}
+
+type DeleteLayerVersionInput struct {
+	LayerName     string `json:"-" rest:"path:LayerName" required:"true" length:"1,140" pattern:"(arn:[a-zA-Z0-9-]+:lambda:[a-zA-Z0-9-]+:\\d{12}:layer:[a-zA-Z0-9-_]+)|[a-zA-Z0-9-_]+"`
+	VersionNumber int64  `json:"-" rest:"path:VersionNumber"`
+}
+
+type DeleteLayerVersionOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
@@ -0,0 +1,328 @@
+package lambda
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/lambda/latest/dg/configuration-versions.html
+// https://docs.aws.amazon.com/lambda/latest/dg/configuration-aliases.html
+
+const (
+	defaultMaxItems = 50
+	maxMaxItems     = 10000
+)
+
+var (
+	aliasNameRegex = regexp.MustCompile(`^[a-zA-Z0-9-_]{1,128}$`)
+	// Alias names can't be version numbers.
+	versionNumberRegex = regexp.MustCompile(`^[0-9]+$`)
+)
+
+type Alias struct {
+	Name            string
+	ARN             string
+	FunctionVersion string
+	Description     string
+	// Invocations are routed to these versions with these probabilities, and to FunctionVersion otherwise.
+	AdditionalVersionWeights map[string]float64
+	RevisionId               string
+}
+
+func (a *Alias) configuration() AliasConfiguration {
+	config := AliasConfiguration{
+		AliasArn:        a.ARN,
+		Name:            a.Name,
+		FunctionVersion: a.FunctionVersion,
+		Description:     a.Description,
+		RevisionId:      a.RevisionId,
+	}
+	if len(a.AdditionalVersionWeights) > 0 {
+		config.RoutingConfig = &AliasRoutingConfiguration{
+			AdditionalVersionWeights: a.AdditionalVersionWeights,
+		}
+	}
+	return config
+}
+
+// lockedPublishVersion publishes $LATEST as a new version, unless it hasn't changed since
+// the last version was published, in which case that version is returned.
+func (l *Lambda) lockedPublishVersion(function *Function, description string) *FunctionVersion {
+	if len(function.Versions) > 0 && function.publishedRevisionId == function.Latest.RevisionId {
+		return function.Versions[len(function.Versions)-1]
+	}
+
+	version := *function.Latest
+	version.Version = strconv.Itoa(len(function.Versions) + 1)
+	version.RevisionId = uuid.Must(uuid.NewV4()).String()
+	version.LastModified = l.clock()
+	if description != "" {
+		version.Description = description
+	}
+	function.Versions = append(function.Versions, &version)
+	function.publishedRevisionId = function.Latest.RevisionId
+	return &version
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_PublishVersion.html
+func (l *Lambda) PublishVersion(input PublishVersionInput) (*PublishVersionOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.RevisionId != "" && input.RevisionId != function.Latest.RevisionId {
+		return nil, PreconditionFailedException(
+			"The Revision Id provided does not match the latest Revision Id. Call the GetFunction/GetAlias API to retrieve the latest Revision Id")
+	}
+	if input.CodeSha256 != "" && input.CodeSha256 != function.Latest.CodeSha256 {
+		return nil, InvalidParameterValueException("CodeSHA256 (" + input.CodeSha256 +
+			") is different from current CodeSHA256 in $LATEST (" + function.Latest.CodeSha256 + "). Please try again with the CodeSHA256 in $LATEST.")
+	}
+
+	version := l.lockedPublishVersion(function, input.Description)
+	return &PublishVersionOutput{
+		FunctionConfiguration: version.configuration(),
+		StatusCode:            201,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_ListVersionsByFunction.html
+func (l *Lambda) ListVersionsByFunction(input ListVersionsByFunctionInput) (*ListVersionsByFunctionOutput, *awserrors.Error) {
+	maxItems, awserr := parseMaxItems(input.MaxItems)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// $LATEST is listed first, and the marker is the next version.
+	versions := append([]*FunctionVersion{function.Latest}, function.Versions...)
+	start := 0
+	if input.Marker != "" {
+		start = slices.IndexFunc(versions, func(v *FunctionVersion) bool {
+			return v.Version == input.Marker
+		})
+		if start == -1 {
+			return nil, InvalidParameterValueException("Invalid Marker: " + input.Marker)
+		}
+	}
+	output := &ListVersionsByFunctionOutput{
+		Versions: []FunctionConfiguration{},
+	}
+	for _, version := range versions[start:] {
+		if len(output.Versions) == maxItems {
+			output.NextMarker = version.Version
+			break
+		}
+		output.Versions = append(output.Versions, version.configuration())
+	}
+	return output, nil
+}
+
+func parseMaxItems(maxItems int) (int, *awserrors.Error) {
+	if maxItems == 0 {
+		return defaultMaxItems, nil
+	}
+	if maxItems < 1 || maxItems > maxMaxItems {
+		return 0, InvalidParameterValueException("MaxItems must be between 1 and 10000.")
+	}
+	return maxItems, nil
+}
+
+// lockedValidateAliasVersions checks the versions an alias points to, and returns its routing weights.
+func (l *Lambda) lockedValidateAliasVersions(function *Function, primary string, routing *AliasRoutingConfiguration) (map[string]float64, *awserrors.Error) {
+	if primary != latestVersion && function.lockedGetPublishedVersion(primary) == nil {
+		return nil, ResourceNotFoundException("Function not found: " + function.ARN + ":" + primary)
+	}
+	if routing == nil || len(routing.AdditionalVersionWeights) == 0 {
+		return nil, nil
+	}
+
+	weights := routing.AdditionalVersionWeights
+	if len(weights) > 1 {
+		return nil, InvalidParameterValueException("Number of items in AdditionalVersionWeights cannot be greater than 1")
+	}
+	if primary == latestVersion {
+		return nil, InvalidParameterValueException("$LATEST is not supported for an alias pointing to more than 1 version")
+	}
+	for version, weight := range weights {
+		if weight < 0 || weight > 1 {
+			return nil, InvalidParameterValueException("Invalid weight " + strconv.FormatFloat(weight, 'f', -1, 64) +
+				" for version " + version + ". Weights must be between 0.0 and 1.0.")
+		}
+		if version == primary {
+			return nil, InvalidParameterValueException("Invalid function version " + version +
+				". Function version " + version + " is already included in routing configuration.")
+		}
+		if function.lockedGetPublishedVersion(version) == nil {
+			return nil, ResourceNotFoundException("Function not found: " + function.ARN + ":" + version)
+		}
+	}
+	return maps.Clone(weights), nil
+}
+
+func (l *Lambda) lockedGetAlias(functionName string, name string) (*Function, *Alias, *awserrors.Error) {
+	function, awserr := l.lockedGetFunction(functionName)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	alias, ok := function.Aliases[name]
+	if !ok {
+		return nil, nil, ResourceNotFoundException("Alias not found: " + function.ARN + ":" + name)
+	}
+	return function, alias, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_CreateAlias.html
+func (l *Lambda) CreateAlias(input CreateAliasInput) (*CreateAliasOutput, *awserrors.Error) {
+	if !aliasNameRegex.MatchString(input.Name) || versionNumberRegex.MatchString(input.Name) {
+		return nil, InvalidParameterValueException("Invalid alias name: " + input.Name)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, ok := function.Aliases[input.Name]; ok {
+		return nil, ResourceConflictException("Alias already exists: " + function.ARN + ":" + input.Name)
+	}
+	weights, awserr := l.lockedValidateAliasVersions(function, input.FunctionVersion, input.RoutingConfig)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	alias := &Alias{
+		Name:                     input.Name,
+		ARN:                      function.ARN + ":" + input.Name,
+		FunctionVersion:          input.FunctionVersion,
+		Description:              input.Description,
+		AdditionalVersionWeights: weights,
+		RevisionId:               uuid.Must(uuid.NewV4()).String(),
+	}
+	function.Aliases[input.Name] = alias
+	return &CreateAliasOutput{
+		AliasConfiguration: alias.configuration(),
+		StatusCode:         201,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_GetAlias.html
+func (l *Lambda) GetAlias(input GetAliasInput) (*GetAliasOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, alias, awserr := l.lockedGetAlias(input.FunctionName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetAliasOutput{
+		AliasConfiguration: alias.configuration(),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_UpdateAlias.html
+func (l *Lambda) UpdateAlias(input UpdateAliasInput) (*UpdateAliasOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, alias, awserr := l.lockedGetAlias(input.FunctionName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.RevisionId != "" && input.RevisionId != alias.RevisionId {
+		return nil, PreconditionFailedException(
+			"The Revision Id provided does not match the latest Revision Id. Call the GetFunction/GetAlias API to retrieve the latest Revision Id")
+	}
+
+	primary := alias.FunctionVersion
+	if input.FunctionVersion != "" {
+		primary = input.FunctionVersion
+	}
+	routing := input.RoutingConfig
+	if routing == nil {
+		routing = &AliasRoutingConfiguration{AdditionalVersionWeights: alias.AdditionalVersionWeights}
+	}
+	weights, awserr := l.lockedValidateAliasVersions(function, primary, routing)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	alias.FunctionVersion = primary
+	alias.AdditionalVersionWeights = weights
+	if input.Description != nil {
+		alias.Description = *input.Description
+	}
+	alias.RevisionId = uuid.Must(uuid.NewV4()).String()
+	return &UpdateAliasOutput{
+		AliasConfiguration: alias.configuration(),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_DeleteAlias.html
+func (l *Lambda) DeleteAlias(input DeleteAliasInput) (*DeleteAliasOutput, *awserrors.Error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, alias, awserr := l.lockedGetAlias(input.FunctionName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(function.Aliases, alias.Name)
+	return &DeleteAliasOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/lambda/latest/api/API_ListAliases.html
+func (l *Lambda) ListAliases(input ListAliasesInput) (*ListAliasesOutput, *awserrors.Error) {
+	maxItems, awserr := parseMaxItems(input.MaxItems)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	function, awserr := l.lockedGetFunction(input.FunctionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Aliases are listed by name, and the marker is the name of the next one.
+	var names []string
+	for name := range function.Aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	output := &ListAliasesOutput{
+		Aliases: []AliasConfiguration{},
+	}
+	for _, name := range names {
+		alias := function.Aliases[name]
+		if name < input.Marker || (input.FunctionVersion != "" && alias.FunctionVersion != input.FunctionVersion) {
+			continue
+		}
+		if len(output.Aliases) == maxItems {
+			output.NextMarker = name
+			break
+		}
+		output.Aliases = append(output.Aliases, alias.configuration())
+	}
+	return output, nil
+}
@@ -0,0 +1,231 @@
+package lambda
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"aws-in-a-box/services/kms"
+)
+
+// invokedCode invokes the function, and returns the version which ran and its code.
+func invokedCode(t *testing.T, l *Lambda, functionName string) (string, string) {
+	output, awserr := l.Invoke(InvokeInput{FunctionName: functionName, LogType: "Tail"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	logs, _ := base64.StdEncoding.DecodeString(output.LogResult)
+	return output.ExecutedVersion, string(logs[len("START\n") : len(logs)-len("\nEND\n")])
+}
+
+func TestPublishVersion(t *testing.T) {
+	l, _ := newLambda()
+	created := createFunction(t, l, "fn", "v1")
+
+	published, awserr := l.PublishVersion(PublishVersionInput{FunctionName: "fn", Description: "first"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if published.Version != "1" || published.FunctionArn != created.FunctionArn+":1" ||
+		published.Description != "first" || published.StatusCode != 201 {
+		t.Fatalf("Unexpected version: %+v", published)
+	}
+	// Nothing changed, so no version is published.
+	republished, awserr := l.PublishVersion(PublishVersionInput{FunctionName: "fn"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if republished.Version != "1" {
+		t.Fatal("Unexpected version", republished.Version)
+	}
+
+	_, awserr = l.PublishVersion(PublishVersionInput{FunctionName: "fn", CodeSha256: "stale"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+
+	updated, awserr := l.UpdateFunctionCode(UpdateFunctionCodeInput{
+		FunctionName: "fn",
+		ZipFile:      makeZip(t, map[string]string{"index.js": "v2"}),
+		Publish:      true,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.Version != "2" {
+		t.Fatal("Unexpected version", updated.Version)
+	}
+	// Versions which share their code only count it once.
+	usage := l.MemoryUsage()
+	if len(usage) != 1 || usage[0].Name != "fn" || usage[0].Count != 3 || usage[0].Bytes != created.CodeSize+updated.CodeSize {
+		t.Fatalf("Unexpected memory usage %+v", usage)
+	}
+
+	// Published versions keep their code.
+	for qualifier, code := range map[string]string{"fn:1": "v1", "fn:2": "v2", "fn": "v2"} {
+		if _, got := invokedCode(t, l, qualifier); got != code {
+			t.Fatalf("Expected %s to run %s, got %s", qualifier, code, got)
+		}
+	}
+	_, awserr = l.Invoke(InvokeInput{FunctionName: "fn", Qualifier: "3"})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected not found", awserr)
+	}
+
+	list, awserr := l.ListVersionsByFunction(ListVersionsByFunctionInput{FunctionName: "fn", MaxItems: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Versions) != 2 || list.Versions[0].Version != "$LATEST" || list.Versions[1].Version != "1" || list.NextMarker != "2" {
+		t.Fatalf("Unexpected versions: %+v", list)
+	}
+	list, awserr = l.ListVersionsByFunction(ListVersionsByFunctionInput{FunctionName: "fn", Marker: list.NextMarker})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Versions) != 1 || list.Versions[0].Version != "2" || list.NextMarker != "" {
+		t.Fatalf("Unexpected versions: %+v", list)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	l, _ := newLambda()
+	createFunction(t, l, "fn", "v1")
+	l.PublishVersion(PublishVersionInput{FunctionName: "fn"})
+	l.UpdateFunctionCode(UpdateFunctionCodeInput{
+		FunctionName: "fn",
+		ZipFile:      makeZip(t, map[string]string{"index.js": "v2"}),
+		Publish:      true,
+	})
+
+	created, awserr := l.CreateAlias(CreateAliasInput{FunctionName: "fn", Name: "live", FunctionVersion: "1"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.AliasArn != "arn:aws:lambda:us-east-1:123456789012:function:fn:live" || created.StatusCode != 201 {
+		t.Fatalf("Unexpected alias: %+v", created)
+	}
+	if version, code := invokedCode(t, l, "fn:live"); version != "1" || code != "v1" {
+		t.Fatal("Unexpected invocation", version, code)
+	}
+
+	invalid := []UpdateAliasInput{
+		{FunctionVersion: "$LATEST", RoutingConfig: &AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{"2": 0.5}}},
+		{RoutingConfig: &AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{"2": 1.5}}},
+		{RoutingConfig: &AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{"1": 0.5}}},
+		{RoutingConfig: &AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{"3": 0.5}}},
+		{RevisionId: "stale"},
+	}
+	for _, input := range invalid {
+		input.FunctionName = "fn"
+		input.Name = "live"
+		if _, awserr := l.UpdateAlias(input); awserr == nil {
+			t.Fatalf("Expected error for %+v", input)
+		}
+	}
+
+	// All invocations are routed to the additional version.
+	updated, awserr := l.UpdateAlias(UpdateAliasInput{
+		FunctionName:  "fn",
+		Name:          "live",
+		RoutingConfig: &AliasRoutingConfiguration{AdditionalVersionWeights: map[string]float64{"2": 1}},
+		RevisionId:    created.RevisionId,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.FunctionVersion != "1" || updated.RoutingConfig.AdditionalVersionWeights["2"] != 1 {
+		t.Fatalf("Unexpected alias: %+v", updated)
+	}
+	if version, code := invokedCode(t, l, "fn:live"); version != "2" || code != "v2" {
+		t.Fatal("Unexpected invocation", version, code)
+	}
+	// Reading the function through the alias returns its primary version.
+	function, awserr := l.GetFunction(GetFunctionInput{FunctionName: "fn", Qualifier: "live"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if function.Configuration.Version != "1" {
+		t.Fatal("Unexpected version", function.Configuration.Version)
+	}
+
+	_, awserr = l.CreateAlias(CreateAliasInput{FunctionName: "fn", Name: "live", FunctionVersion: "2"})
+	if awserr == nil || awserr.Code != 409 {
+		t.Fatal("Expected conflict", awserr)
+	}
+	_, awserr = l.CreateAlias(CreateAliasInput{FunctionName: "fn", Name: "12", FunctionVersion: "2"})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+		t.Fatal("Expected invalid name", awserr)
+	}
+	l.CreateAlias(CreateAliasInput{FunctionName: "fn", Name: "dev", FunctionVersion: "$LATEST"})
+
+	list, awserr := l.ListAliases(ListAliasesInput{FunctionName: "fn"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Aliases) != 2 || list.Aliases[0].Name != "dev" || list.Aliases[1].Name != "live" {
+		t.Fatalf("Unexpected aliases: %+v", list.Aliases)
+	}
+	list, awserr = l.ListAliases(ListAliasesInput{FunctionName: "fn", FunctionVersion: "1"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Aliases) != 1 || list.Aliases[0].Name != "live" {
+		t.Fatalf("Unexpected aliases: %+v", list.Aliases)
+	}
+
+	_, awserr = l.DeleteAlias(DeleteAliasInput{FunctionName: "fn", Name: "live"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = l.GetAlias(GetAliasInput{FunctionName: "fn", Name: "live"})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestEnvironmentVariables(t *testing.T) {
+	k, err := kms.New(kms.Options{ArnGenerator: generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, awserr := k.CreateKey(kms.CreateKeyInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	l, _ := newLambda()
+	l.kms = k
+	createFunction(t, l, "fn", "v1")
+
+	invalid := []UpdateFunctionConfigurationInput{
+		{Environment: &Environment{Variables: map[string]string{"AWS_REGION": "eu-west-1"}}},
+		{Environment: &Environment{Variables: map[string]string{"1A": "value"}}},
+		{KMSKeyArn: ptr(generator.Generate("kms", "key", "missing"))},
+	}
+	for _, input := range invalid {
+		input.FunctionName = "fn"
+		_, awserr := l.UpdateFunctionConfiguration(input)
+		if awserr == nil || awserr.Body.Type != "InvalidParameterValueException" {
+			t.Fatalf("Expected invalid parameter for %+v, got %v", input, awserr)
+		}
+	}
+
+	updated, awserr := l.UpdateFunctionConfiguration(UpdateFunctionConfigurationInput{
+		FunctionName: "fn",
+		Environment:  &Environment{Variables: map[string]string{"SECRET": "value"}},
+		KMSKeyArn:    &key.KeyMetadata.Arn,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.Environment.Variables["SECRET"] != "value" || updated.KMSKeyArn != key.KeyMetadata.Arn {
+		t.Fatalf("Unexpected configuration: %+v", updated)
+	}
+	invokedCode(t, l, "fn")
+
+	// Lambda can't decrypt the variables with a disabled key.
+	k.DisableKey(kms.DisableKeyInput{KeyId: key.KeyMetadata.KeyId})
+	_, awserr = l.Invoke(InvokeInput{FunctionName: "fn"})
+	if awserr == nil || awserr.Body.Type != "KMSDisabledException" || awserr.Code != 502 {
+		t.Fatal("Expected KMS disabled", awserr)
+	}
+}
@@ -0,0 +1,36 @@
+package pipes
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// EventBridge Pipes only supports the REST-JSON protocol.
+func NewHandler(logger *slog.Logger, p *Pipes, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v1/pipes/{Name}", "CreatePipe", region.Method(p.regions, (*Pipes).CreatePipe))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v1/pipes/{Name}", "DescribePipe", region.Method(p.regions, (*Pipes).DescribePipe))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, "/v1/pipes/{Name}", "UpdatePipe", region.Method(p.regions, (*Pipes).UpdatePipe))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/v1/pipes/{Name}", "DeletePipe", region.Method(p.regions, (*Pipes).DeletePipe))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v1/pipes", "ListPipes", region.Method(p.regions, (*Pipes).ListPipes))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v1/pipes/{Name}/start", "StartPipe", region.Method(p.regions, (*Pipes).StartPipe))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v1/pipes/{Name}/stop", "StopPipe", region.Method(p.regions, (*Pipes).StopPipe))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/tags/{ResourceArn}", "ListTagsForResource", region.Method(p.regions, (*Pipes).ListTagsForResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/tags/{ResourceArn}", "TagResource", region.Method(p.regions, (*Pipes).TagResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/tags/{ResourceArn}", "UntagResource", region.Method(p.regions, (*Pipes).UntagResource))
+	capabilityRegistry.Add("pipes", registry.Operations()...)
+	handler := restjson.NewHandler(registry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		// Other services have the same tagging routes, so only handle the tags of pipes.
+		if strings.HasPrefix(r.URL.Path, "/tags/") && !strings.Contains(r.URL.Path, ":pipes:") {
+			return false
+		}
+		return handler(w, r)
+	}
+}
@@ -0,0 +1,589 @@
+package pipes
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/eventbridge"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/lambda"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	maxListLimit = 100
+	maxFilters   = 5
+)
+
+var (
+	nameRegex    = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,64}$`)
+	roleArnRegex = regexp.MustCompile(`^arn:aws(-[a-z]+)?:iam::\d{12}:role\/[\w+=,.@\/-]+$`)
+)
+
+type Pipe struct {
+	Name        string
+	Arn         string
+	Description string
+	// RUNNING or STOPPED.
+	DesiredState string
+	// RUNNING, STOPPED or START_FAILED.
+	CurrentState         string
+	StateReason          string
+	RoleArn              string
+	Source               string
+	SourceParameters     *APIPipeSourceParameters
+	Enrichment           string
+	EnrichmentParameters *APIPipeEnrichmentParameters
+	Target               string
+	TargetParameters     *APIPipeTargetParameters
+	Tags                 map[string]string
+	LogConfiguration     any
+	KmsKeyIdentifier     string
+	CreationTime         time.Time
+	LastModifiedTime     time.Time
+	// Records which match any of the filters are processed. Nil if there are no filters.
+	filters []func(event []byte) bool
+	// Kept while the pipe is stopped, so that it resumes where it left off.
+	source *lambda.EventSource
+	// Closed to stop the running pipe. Nil if it isn't running.
+	stop chan struct{}
+	// Closed once the last run of the pipe stopped, so that the next run doesn't read the source at the same time.
+	done chan struct{}
+}
+
+func (p *Pipe) toAPI() APIPipe {
+	return APIPipe{
+		Arn:              p.Arn,
+		Name:             p.Name,
+		CurrentState:     p.CurrentState,
+		DesiredState:     p.DesiredState,
+		StateReason:      p.StateReason,
+		Source:           p.Source,
+		Enrichment:       p.Enrichment,
+		Target:           p.Target,
+		CreationTime:     timestamp.EpochSeconds(p.CreationTime),
+		LastModifiedTime: timestamp.EpochSeconds(p.LastModifiedTime),
+	}
+}
+
+func (p *Pipe) state() *APIPipeState {
+	return &APIPipeState{
+		Arn:              p.Arn,
+		Name:             p.Name,
+		CurrentState:     p.CurrentState,
+		DesiredState:     p.DesiredState,
+		CreationTime:     timestamp.EpochSeconds(p.CreationTime),
+		LastModifiedTime: timestamp.EpochSeconds(p.LastModifiedTime),
+	}
+}
+
+// LambdaInvoker invokes the Lambda functions pipes enrich events with and target.
+type LambdaInvoker interface {
+	Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error)
+	InvokeAsync(functionArn string, payload []byte) error
+}
+
+type Pipes struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+	// The services pipes read from.
+	sources lambda.EventSourceServices
+	// Targets of these services are invoked if they're enabled.
+	sqs         *sqs.SQS
+	sns         *sns.SNS
+	lambda      LambdaInvoker
+	kinesis     *kinesis.Kinesis
+	eventBridge *eventbridge.EventBridge
+
+	mu sync.Mutex
+	// Keyed by name.
+	pipes map[string]*Pipe
+	// Shared by the instances of all regions, which each have their own pipes.
+	regions *region.Regions[*Pipes]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	SQS          *sqs.SQS
+	SNS          *sns.SNS
+	Lambda       LambdaInvoker
+	Kinesis      *kinesis.Kinesis
+	DynamoDB     *dynamodb.DynamoDB
+	EventBridge  *eventbridge.EventBridge
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Pipes {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Sources and targets are found by their ARNs, which say their regions, so every region reads
+	// and invokes them through the same services.
+	p := newRegion(options)
+	p.regions = region.New(options.ArnGenerator.Region, options.Regions, p, func(name string) (*Pipes, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = p.regions
+		return r, nil
+	})
+	return p
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *Pipes {
+	return &Pipes{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		clock:        clock.Now,
+		sources: lambda.EventSourceServices{
+			SQS:      options.SQS,
+			Kinesis:  options.Kinesis,
+			DynamoDB: options.DynamoDB,
+			Region:   options.ArnGenerator.Region,
+		},
+		sqs:         options.SQS,
+		sns:         options.SNS,
+		lambda:      options.Lambda,
+		kinesis:     options.Kinesis,
+		eventBridge: options.EventBridge,
+		pipes:       make(map[string]*Pipe),
+	}
+}
+
+// InRegion returns the pipes of the region. It fails if the region isn't one of Options.Regions.
+func (p *Pipes) InRegion(name string) (*Pipes, error) {
+	return p.regions.Get(name)
+}
+
+// arnService returns the service of the ARN, or "" if it isn't an ARN.
+func arnService(resourceArn string) string {
+	a, err := arn.Parse(resourceArn)
+	if err != nil {
+		return ""
+	}
+	return a.Service
+}
+
+func validateDesiredState(desiredState string) (string, *awserrors.Error) {
+	if desiredState == "" {
+		return "RUNNING", nil
+	}
+	if desiredState != "RUNNING" && desiredState != "STOPPED" {
+		return "", ValidationException("Invalid DesiredState " + desiredState + ".")
+	}
+	return desiredState, nil
+}
+
+// parseFilters returns the filters of the filter criteria, or nil if there are none.
+func parseFilters(criteria *APIFilterCriteria) ([]func([]byte) bool, *awserrors.Error) {
+	if criteria == nil {
+		return nil, nil
+	}
+	if len(criteria.Filters) > maxFilters {
+		return nil, ValidationException("FilterCriteria can have at most 5 filters.")
+	}
+	var filters []func([]byte) bool
+	for _, filter := range criteria.Filters {
+		matches, awserr := eventbridge.ParseEventPattern(filter.Pattern)
+		if awserr != nil {
+			return nil, ValidationException("Invalid FilterCriteria pattern: " + awserr.Body.Message)
+		}
+		filters = append(filters, matches)
+	}
+	return filters, nil
+}
+
+// newSource validates the source and its parameters, and returns the source to read and the filters to apply.
+func (p *Pipes) newSource(source string, parameters *APIPipeSourceParameters) (*lambda.EventSource, []func([]byte) bool, *awserrors.Error) {
+	if parameters == nil {
+		parameters = &APIPipeSourceParameters{}
+	}
+
+	filters, awserr := parseFilters(parameters.FilterCriteria)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+
+	var batchSize, batchingWindow *int32
+	startingPosition := ""
+	switch arnService(source) {
+	case "sqs":
+		if parameters.KinesisStreamParameters != nil || parameters.DynamoDBStreamParameters != nil {
+			return nil, nil, ValidationException("Only SqsQueueParameters can be set for SQS sources.")
+		}
+		if parameters.SqsQueueParameters != nil {
+			batchSize = parameters.SqsQueueParameters.BatchSize
+			batchingWindow = parameters.SqsQueueParameters.MaximumBatchingWindowInSeconds
+		}
+	case "kinesis", "dynamodb":
+		streamParameters := parameters.KinesisStreamParameters
+		if arnService(source) == "dynamodb" {
+			streamParameters = parameters.DynamoDBStreamParameters
+		}
+		if streamParameters == nil {
+			return nil, nil, ValidationException("StartingPosition is required for stream sources.")
+		}
+		batchSize = streamParameters.BatchSize
+		batchingWindow = streamParameters.MaximumBatchingWindowInSeconds
+		startingPosition = streamParameters.StartingPosition
+	default:
+		return nil, nil, ValidationException("Unsupported source " + source + ". Sources must be SQS queues, or Kinesis or DynamoDB streams.")
+	}
+	window := int32(0)
+	if batchingWindow != nil {
+		window = *batchingWindow
+	}
+
+	eventSource, awserr := lambda.NewEventSource(p.sources, source, startingPosition, batchSize, window)
+	if awserr != nil {
+		return nil, nil, ValidationException(awserr.Body.Message)
+	}
+	return eventSource, filters, nil
+}
+
+func validateEnrichment(enrichment string) *awserrors.Error {
+	if enrichment != "" && arnService(enrichment) != "lambda" {
+		return ValidationException("Unsupported enrichment " + enrichment + ". Only Lambda functions are supported.")
+	}
+	return nil
+}
+
+func validateTarget(target string, parameters *APIPipeTargetParameters) *awserrors.Error {
+	service := arnService(target)
+	if service == "" {
+		return ValidationException("Invalid Target " + target + ".")
+	}
+	if service == "kinesis" && (parameters == nil || parameters.KinesisStreamParameters == nil ||
+		parameters.KinesisStreamParameters.PartitionKey == "") {
+		return ValidationException("KinesisStreamParameters.PartitionKey is required for Kinesis targets.")
+	}
+	if parameters != nil && parameters.LambdaFunctionParameters != nil {
+		invocationType := parameters.LambdaFunctionParameters.InvocationType
+		if invocationType != "" && invocationType != "REQUEST_RESPONSE" && invocationType != "FIRE_AND_FORGET" {
+			return ValidationException("Invalid InvocationType " + invocationType + ".")
+		}
+	}
+	return nil
+}
+
+func validateRoleArn(roleArn string) *awserrors.Error {
+	// Roles are validated, but pipes can read from and invoke anything.
+	if !roleArnRegex.MatchString(roleArn) {
+		return ValidationException("Invalid RoleArn " + roleArn + ".")
+	}
+	return nil
+}
+
+func (p *Pipes) lockedGetPipe(name string) (*Pipe, *awserrors.Error) {
+	pipe, ok := p.pipes[name]
+	if !ok {
+		return nil, NotFoundException("Pipe " + name + " does not exist.")
+	}
+	return pipe, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_CreatePipe.html
+func (p *Pipes) CreatePipe(input CreatePipeInput) (*CreatePipeOutput, *awserrors.Error) {
+	if !nameRegex.MatchString(input.Name) {
+		return nil, ValidationException("Invalid Name " + input.Name + ".")
+	}
+	desiredState, awserr := validateDesiredState(input.DesiredState)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateRoleArn(input.RoleArn); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateEnrichment(input.Enrichment); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateTarget(input.Target, input.TargetParameters); awserr != nil {
+		return nil, awserr
+	}
+	source, filters, awserr := p.newSource(input.Source, input.SourceParameters)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pipes[input.Name]; ok {
+		return nil, ConflictException("Pipe " + input.Name + " already exists.")
+	}
+	now := p.clock()
+	pipe := &Pipe{
+		Name:                 input.Name,
+		Arn:                  p.arnGenerator.Generate("pipes", "pipe", input.Name),
+		Description:          input.Description,
+		DesiredState:         desiredState,
+		RoleArn:              input.RoleArn,
+		Source:               input.Source,
+		SourceParameters:     input.SourceParameters,
+		Enrichment:           input.Enrichment,
+		EnrichmentParameters: input.EnrichmentParameters,
+		Target:               input.Target,
+		TargetParameters:     input.TargetParameters,
+		Tags:                 make(map[string]string),
+		LogConfiguration:     input.LogConfiguration,
+		KmsKeyIdentifier:     input.KmsKeyIdentifier,
+		CreationTime:         now,
+		LastModifiedTime:     now,
+		filters:              filters,
+		source:               source,
+	}
+	for key, value := range input.Tags {
+		pipe.Tags[key] = value
+	}
+	p.pipes[pipe.Name] = pipe
+	p.lockedApplyDesiredState(pipe)
+	return pipe.state(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_DescribePipe.html
+func (p *Pipes) DescribePipe(input DescribePipeInput) (*DescribePipeOutput, *awserrors.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipe(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &DescribePipeOutput{
+		Arn:                  pipe.Arn,
+		Name:                 pipe.Name,
+		Description:          pipe.Description,
+		CurrentState:         pipe.CurrentState,
+		DesiredState:         pipe.DesiredState,
+		StateReason:          pipe.StateReason,
+		RoleArn:              pipe.RoleArn,
+		Source:               pipe.Source,
+		SourceParameters:     pipe.SourceParameters,
+		Enrichment:           pipe.Enrichment,
+		EnrichmentParameters: pipe.EnrichmentParameters,
+		Target:               pipe.Target,
+		TargetParameters:     pipe.TargetParameters,
+		Tags:                 make(map[string]string),
+		LogConfiguration:     pipe.LogConfiguration,
+		KmsKeyIdentifier:     pipe.KmsKeyIdentifier,
+		CreationTime:         timestamp.EpochSeconds(pipe.CreationTime),
+		LastModifiedTime:     timestamp.EpochSeconds(pipe.LastModifiedTime),
+	}
+	for key, value := range pipe.Tags {
+		output.Tags[key] = value
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_UpdatePipe.html
+func (p *Pipes) UpdatePipe(input UpdatePipeInput) (*UpdatePipeOutput, *awserrors.Error) {
+	desiredState, awserr := validateDesiredState(input.DesiredState)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateRoleArn(input.RoleArn); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateEnrichment(input.Enrichment); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateTarget(input.Target, input.TargetParameters); awserr != nil {
+		return nil, awserr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipe(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// The source is read from where the pipe left off, unless its parameters changed.
+	sourceParameters := input.SourceParameters
+	if sourceParameters == nil {
+		sourceParameters = pipe.SourceParameters
+	}
+	filters := pipe.filters
+	if input.SourceParameters != nil {
+		source, newFilters, awserr := p.newSource(pipe.Source, sourceParameters)
+		if awserr != nil {
+			return nil, awserr
+		}
+		pipe.source = source
+		filters = newFilters
+	}
+
+	p.lockedStop(pipe)
+	pipe.Description = input.Description
+	pipe.DesiredState = desiredState
+	pipe.RoleArn = input.RoleArn
+	pipe.SourceParameters = sourceParameters
+	pipe.Enrichment = input.Enrichment
+	pipe.EnrichmentParameters = input.EnrichmentParameters
+	pipe.Target = input.Target
+	pipe.TargetParameters = input.TargetParameters
+	pipe.LogConfiguration = input.LogConfiguration
+	pipe.KmsKeyIdentifier = input.KmsKeyIdentifier
+	pipe.LastModifiedTime = p.clock()
+	pipe.filters = filters
+	p.lockedApplyDesiredState(pipe)
+	return pipe.state(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_DeletePipe.html
+func (p *Pipes) DeletePipe(input DeletePipeInput) (*DeletePipeOutput, *awserrors.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipe(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	p.lockedStop(pipe)
+	delete(p.pipes, pipe.Name)
+	output := pipe.state()
+	output.CurrentState = "DELETING"
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_StartPipe.html
+func (p *Pipes) StartPipe(input StartPipeInput) (*StartPipeOutput, *awserrors.Error) {
+	return p.setDesiredState(input.Name, "RUNNING")
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_StopPipe.html
+func (p *Pipes) StopPipe(input StopPipeInput) (*StopPipeOutput, *awserrors.Error) {
+	return p.setDesiredState(input.Name, "STOPPED")
+}
+
+func (p *Pipes) setDesiredState(name string, desiredState string) (*APIPipeState, *awserrors.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipe(name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	pipe.DesiredState = desiredState
+	pipe.LastModifiedTime = p.clock()
+	p.lockedApplyDesiredState(pipe)
+	return pipe.state(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_ListPipes.html
+func (p *Pipes) ListPipes(input ListPipesInput) (*ListPipesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		ValidationException("Limit must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var pipes []APIPipe
+	for _, pipe := range p.pipes {
+		if !strings.HasPrefix(pipe.Name, input.NamePrefix) ||
+			!strings.HasPrefix(pipe.Source, input.SourcePrefix) ||
+			!strings.HasPrefix(pipe.Target, input.TargetPrefix) ||
+			(input.CurrentState != "" && input.CurrentState != pipe.CurrentState) ||
+			(input.DesiredState != "" && input.DesiredState != pipe.DesiredState) {
+			continue
+		}
+		pipes = append(pipes, pipe.toAPI())
+	}
+	slices.SortFunc(pipes, func(a, b APIPipe) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	output := &ListPipesOutput{
+		Pipes: []APIPipe{},
+	}
+	if start < len(pipes) {
+		end := min(start+limit, len(pipes))
+		output.Pipes = pipes[start:end]
+		if end < len(pipes) {
+			output.NextToken = strconv.Itoa(end)
+		}
+	}
+	return output, nil
+}
+
+func (p *Pipes) lockedGetPipeByArn(resourceArn string) (*Pipe, *awserrors.Error) {
+	_, name, _ := strings.Cut(resourceArn, ":pipe/")
+	pipe, ok := p.pipes[name]
+	if !ok || pipe.Arn != resourceArn {
+		return nil, NotFoundException("Resource " + resourceArn + " does not exist.")
+	}
+	return pipe, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_TagResource.html
+func (p *Pipes) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipeByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for key, value := range input.Tags {
+		pipe.Tags[key] = value
+	}
+	return &TagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_UntagResource.html
+func (p *Pipes) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipeByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, key := range input.TagKeys {
+		delete(pipe.Tags, key)
+	}
+	return &UntagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/pipes-reference/API_ListTagsForResource.html
+func (p *Pipes) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pipe, awserr := p.lockedGetPipeByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListTagsForResourceOutput{
+		Tags: make(map[string]string),
+	}
+	for key, value := range pipe.Tags {
+		output.Tags[key] = value
+	}
+	return output, nil
+}
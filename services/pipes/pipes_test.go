@@ -0,0 +1,176 @@
+package pipes
+
+import (
+	"testing"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/sqs"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+const roleArn = "arn:aws:iam::123456789012:role/pipes"
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func createQueue(t *testing.T, s *sqs.SQS, name string) (string, string) {
+	output, awserr := s.CreateQueue(sqs.CreateQueueInput{QueueName: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.QueueUrl, generator.GenerateWithoutType("sqs", name)
+}
+
+func createPipe(t *testing.T, p *Pipes, input CreatePipeInput) string {
+	if input.RoleArn == "" {
+		input.RoleArn = roleArn
+	}
+	output, awserr := p.CreatePipe(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	t.Cleanup(func() {
+		p.DeletePipe(DeletePipeInput{Name: input.Name})
+	})
+	return output.Arn
+}
+
+func TestPipes(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	_, sourceArn := createQueue(t, q, "source")
+	_, targetArn := createQueue(t, q, "target")
+	p := New(Options{ArnGenerator: generator, SQS: q})
+
+	pipeArn := createPipe(t, p, CreatePipeInput{
+		Name:         "orders",
+		DesiredState: "STOPPED",
+		Source:       sourceArn,
+		Target:       targetArn,
+		Tags:         map[string]string{"team": "a"},
+	})
+	if pipeArn != "arn:aws:pipes:us-east-1:123456789012:pipe/orders" {
+		t.Fatal("Unexpected ARN", pipeArn)
+	}
+	_, awserr := p.CreatePipe(CreatePipeInput{Name: "orders", RoleArn: roleArn, Source: sourceArn, Target: targetArn})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatal("Expected pipe to already exist", awserr)
+	}
+
+	pipe, awserr := p.DescribePipe(DescribePipeInput{Name: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if pipe.CurrentState != "STOPPED" || pipe.Source != sourceArn || pipe.Target != targetArn || pipe.Tags["team"] != "a" {
+		t.Fatalf("Unexpected pipe: %+v", pipe)
+	}
+
+	state, awserr := p.StartPipe(StartPipeInput{Name: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if state.CurrentState != "RUNNING" || state.DesiredState != "RUNNING" {
+		t.Fatalf("Unexpected state: %+v", state)
+	}
+
+	_, awserr = p.UpdatePipe(UpdatePipeInput{Name: "orders", RoleArn: roleArn, Target: targetArn, Description: "Orders",
+		DesiredState: "STOPPED"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	pipe, awserr = p.DescribePipe(DescribePipeInput{Name: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if pipe.CurrentState != "STOPPED" || pipe.Description != "Orders" || pipe.Source != sourceArn {
+		t.Fatalf("Unexpected pipe: %+v", pipe)
+	}
+
+	createPipe(t, p, CreatePipeInput{Name: "payments", Source: sourceArn, Target: "arn:aws:sns:us-east-1:123456789012:payments"})
+	list, awserr := p.ListPipes(ListPipesInput{Limit: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Pipes) != 1 || list.Pipes[0].Name != "orders" || list.NextToken == "" {
+		t.Fatalf("Unexpected pipes: %+v", list)
+	}
+	list, awserr = p.ListPipes(ListPipesInput{NextToken: list.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Pipes) != 1 || list.Pipes[0].Name != "payments" || list.NextToken != "" {
+		t.Fatalf("Unexpected pipes: %+v", list)
+	}
+	list, awserr = p.ListPipes(ListPipesInput{TargetPrefix: "arn:aws:sns:", CurrentState: "RUNNING"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Pipes) != 1 || list.Pipes[0].Name != "payments" {
+		t.Fatalf("Unexpected pipes: %+v", list)
+	}
+
+	if _, awserr := p.TagResource(TagResourceInput{ResourceArn: pipeArn, Tags: map[string]string{"env": "dev"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := p.UntagResource(UntagResourceInput{ResourceArn: pipeArn, TagKeys: []string{"team"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := p.ListTagsForResource(ListTagsForResourceInput{ResourceArn: pipeArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tags.Tags) != 1 || tags.Tags["env"] != "dev" {
+		t.Fatalf("Unexpected tags: %+v", tags)
+	}
+
+	if _, awserr := p.DeletePipe(DeletePipeInput{Name: "orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = p.DescribePipe(DescribePipeInput{Name: "orders"})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected pipe to be deleted", awserr)
+	}
+}
+
+func TestPipeValidation(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	_, sourceArn := createQueue(t, q, "source")
+	p := New(Options{ArnGenerator: generator, SQS: q})
+	target := "arn:aws:sqs:us-east-1:123456789012:target"
+
+	for name, input := range map[string]CreatePipeInput{
+		"name":           {Name: "not valid", Source: sourceArn, Target: target},
+		"role":           {Name: "pipe", RoleArn: "role", Source: sourceArn, Target: target},
+		"missing queue":  {Name: "pipe", Source: "arn:aws:sqs:us-east-1:123456789012:missing", Target: target},
+		"source service": {Name: "pipe", Source: "arn:aws:mq:us-east-1:123456789012:broker:b-1", Target: target},
+		"kinesis": {Name: "pipe", Source: "arn:aws:kinesis:us-east-1:123456789012:stream/s", Target: target,
+			SourceParameters: &APIPipeSourceParameters{KinesisStreamParameters: &APIStreamSourceParameters{StartingPosition: "LATEST"}}},
+		"starting position": {Name: "pipe", Source: sourceArn, Target: target,
+			SourceParameters: &APIPipeSourceParameters{KinesisStreamParameters: &APIStreamSourceParameters{}}},
+		"filter": {Name: "pipe", Source: sourceArn, Target: target,
+			SourceParameters: &APIPipeSourceParameters{FilterCriteria: &APIFilterCriteria{Filters: []APIFilter{{Pattern: `{"body": "x"}`}}}}},
+		"batch size": {Name: "pipe", Source: sourceArn, Target: target,
+			SourceParameters: &APIPipeSourceParameters{SqsQueueParameters: &APISqsQueueSourceParameters{BatchSize: ptr(int32(20000))}}},
+		"enrichment":    {Name: "pipe", Source: sourceArn, Target: target, Enrichment: "arn:aws:states:us-east-1:123456789012:stateMachine:s"},
+		"target":        {Name: "pipe", Source: sourceArn, Target: "target"},
+		"partition key": {Name: "pipe", Source: sourceArn, Target: "arn:aws:kinesis:us-east-1:123456789012:stream/s"},
+		"desired state": {Name: "pipe", Source: sourceArn, Target: target, DesiredState: "PAUSED"},
+	} {
+		if input.RoleArn == "" {
+			input.RoleArn = roleArn
+		}
+		_, awserr := p.CreatePipe(input)
+		if awserr == nil || awserr.Body.Type != "ValidationException" {
+			t.Error("Expected", name, "to be invalid", awserr)
+		}
+	}
+
+	_, awserr := p.StartPipe(StartPipeInput{Name: "missing"})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected pipe not to exist", awserr)
+	}
+}
@@ -0,0 +1,381 @@
+package pipes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/eventbridge"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/lambda"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+)
+
+// How long a pipe waits before reading a source again after an error.
+const retryInterval = time.Second
+
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-pipes.html
+
+func sleep(stop <-chan struct{}, d time.Duration) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func errorFromAWS(awserr *awserrors.Error) error {
+	if awserr != nil {
+		return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+	}
+	return nil
+}
+
+// lockedApplyDesiredState starts or stops the pipe to match its desired state.
+func (p *Pipes) lockedApplyDesiredState(pipe *Pipe) {
+	if pipe.DesiredState == "STOPPED" {
+		p.lockedStop(pipe)
+		pipe.CurrentState = "STOPPED"
+		return
+	}
+	if pipe.stop == nil {
+		previous := pipe.done
+		pipe.stop = make(chan struct{})
+		pipe.done = make(chan struct{})
+		// The run gets a copy of the pipe, since updates restart it.
+		go p.run(*pipe, pipe.stop, pipe.done, previous)
+	}
+	pipe.CurrentState = "RUNNING"
+}
+
+func (p *Pipes) lockedStop(pipe *Pipe) {
+	if pipe.stop != nil {
+		close(pipe.stop)
+		pipe.stop = nil
+	}
+}
+
+// run reads batches from the pipe's source until it's stopped. Failed batches are read again.
+func (p *Pipes) run(pipe Pipe, stop <-chan struct{}, done chan<- struct{}, previous <-chan struct{}) {
+	defer close(done)
+	if previous != nil {
+		select {
+		case <-previous:
+		case <-stop:
+			return
+		}
+	}
+
+	if pipe.source == nil {
+		source, ok := p.importedSource(pipe, stop)
+		if !ok {
+			return
+		}
+		pipe.source = source
+	}
+
+	for {
+		records, err := pipe.source.Poll(stop)
+		if err != nil {
+			p.logger.Warn("Polling pipe source", "pipe", pipe.Name, "source", pipe.Source, "error", err)
+			if !sleep(stop, retryInterval) {
+				return
+			}
+			continue
+		}
+		if len(records) == 0 {
+			return
+		}
+
+		err = p.processBatch(pipe, records)
+		succeeded := make([]bool, len(records))
+		for i := range succeeded {
+			succeeded[i] = err == nil
+		}
+		pipe.source.Commit(succeeded)
+		if err != nil {
+			p.logger.Warn("Pipe failed to process batch", "pipe", pipe.Name, "error", err)
+			if !sleep(stop, retryInterval) {
+				return
+			}
+		}
+	}
+}
+
+// importedSource creates the source of a pipe which was imported, retrying until the source exists,
+// since the service it reads from may be imported after the pipe. It returns false if the pipe was
+// stopped first.
+func (p *Pipes) importedSource(pipe Pipe, stop <-chan struct{}) (*lambda.EventSource, bool) {
+	for {
+		source, _, awserr := p.newSource(pipe.Source, pipe.SourceParameters)
+		if awserr == nil {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			select {
+			case <-stop:
+				return nil, false
+			default:
+			}
+			// Kept on the pipe, so that it resumes where it left off once it's started again.
+			if current := p.pipes[pipe.Name]; current != nil && current.source == nil {
+				current.source = source
+			}
+			return source, true
+		}
+		p.logger.Warn("Creating imported pipe source", "pipe", pipe.Name, "source", pipe.Source, "error", errorFromAWS(awserr))
+		if !sleep(stop, retryInterval) {
+			return nil, false
+		}
+	}
+}
+
+// processBatch filters, enriches and delivers the records. Records which are filtered out succeed.
+func (p *Pipes) processBatch(pipe Pipe, records []any) error {
+	var events []json.RawMessage
+	for _, record := range records {
+		event := pipeEvent(record)
+		if matchesFilters(pipe.filters, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	if pipe.Enrichment != "" {
+		var err error
+		events, err = p.enrich(pipe, events)
+		if err != nil {
+			return fmt.Errorf("enriching: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+	}
+	return p.deliver(pipe, events)
+}
+
+// pipeEvent encodes the record the way pipes pass it on. Unlike for Lambda event source mappings, the fields of
+// Kinesis records aren't nested in a kinesis field.
+func pipeEvent(record any) json.RawMessage {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		panic(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return encoded
+	}
+	nested, ok := fields["kinesis"]
+	if !ok {
+		return encoded
+	}
+	var kinesisFields map[string]json.RawMessage
+	if err := json.Unmarshal(nested, &kinesisFields); err != nil {
+		return encoded
+	}
+	delete(fields, "kinesis")
+	for key, value := range kinesisFields {
+		fields[key] = value
+	}
+	flattened, _ := json.Marshal(fields)
+	return flattened
+}
+
+// matchesFilters returns whether the event matches any of the filters, or true if there are none.
+// SQS message bodies and Kinesis data which are JSON are filtered on as JSON, so that patterns can match their fields.
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-pipes-event-filtering.html
+func matchesFilters(filters []func([]byte) bool, event json.RawMessage) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(event, &fields); err == nil {
+		var body string
+		if json.Unmarshal(fields["body"], &body) == nil && json.Valid([]byte(body)) {
+			fields["body"] = json.RawMessage(body)
+		}
+		var data string
+		if json.Unmarshal(fields["data"], &data) == nil {
+			if decoded, err := base64.StdEncoding.DecodeString(data); err == nil && json.Valid(decoded) {
+				fields["data"] = decoded
+			}
+		}
+		event, _ = json.Marshal(fields)
+	}
+	for _, matches := range filters {
+		if matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// enrich invokes the enrichment function with the events, and returns the events it responds with.
+// A function which responds with an array replaces the events with its elements, and one which responds with null
+// drops them.
+func (p *Pipes) enrich(pipe Pipe, events []json.RawMessage) ([]json.RawMessage, error) {
+	if p.lambda == nil {
+		return nil, fmt.Errorf("Lambda is not enabled")
+	}
+	payload, _ := json.Marshal(events)
+	output, awserr := p.lambda.Invoke(lambda.InvokeInput{
+		FunctionName: pipe.Enrichment,
+		Payload:      payload,
+	})
+	if awserr != nil {
+		return nil, errorFromAWS(awserr)
+	}
+	if output.FunctionError != "" {
+		return nil, fmt.Errorf("function error: %s", output.Payload)
+	}
+	if len(output.Payload) == 0 || string(output.Payload) == "null" {
+		return nil, nil
+	}
+	var enriched []json.RawMessage
+	if err := json.Unmarshal(output.Payload, &enriched); err != nil {
+		if !json.Valid(output.Payload) {
+			return nil, fmt.Errorf("invalid response: %s", output.Payload)
+		}
+		enriched = []json.RawMessage{output.Payload}
+	}
+	return enriched, nil
+}
+
+// deliver sends the events to the pipe's target. Lambda functions receive the batch, and other targets each event.
+func (p *Pipes) deliver(pipe Pipe, events []json.RawMessage) error {
+	parameters := pipe.TargetParameters
+	if parameters == nil {
+		parameters = &APIPipeTargetParameters{}
+	}
+	switch arnService(pipe.Target) {
+	case "sqs":
+		if p.sqs == nil {
+			return fmt.Errorf("SQS is not enabled")
+		}
+		for _, event := range events {
+			input := sqs.SendMessageInput{
+				MessageBody: string(event),
+			}
+			if parameters.SqsQueueParameters != nil {
+				input.MessageGroupId = parameters.SqsQueueParameters.MessageGroupId
+				input.MessageDeduplicationId = parameters.SqsQueueParameters.MessageDeduplicationId
+			}
+			if _, awserr := p.sqs.SendMessageToQueueArn(pipe.Target, input); awserr != nil {
+				return errorFromAWS(awserr)
+			}
+		}
+	case "sns":
+		if p.sns == nil {
+			return fmt.Errorf("SNS is not enabled")
+		}
+		topics, err := p.sns.ForARN(pipe.Target)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			_, awserr := topics.Publish(sns.PublishInput{
+				TopicArn: pipe.Target,
+				Message:  string(event),
+			})
+			if awserr != nil {
+				return errorFromAWS(awserr)
+			}
+		}
+	case "kinesis":
+		if p.kinesis == nil {
+			return fmt.Errorf("Kinesis is not enabled")
+		}
+		streams, err := p.kinesis.ForARN(pipe.Target)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			_, awserr := streams.PutRecord(kinesis.PutRecordInput{
+				StreamARN:    pipe.Target,
+				PartitionKey: parameters.KinesisStreamParameters.PartitionKey,
+				Data:         base64.StdEncoding.EncodeToString(event),
+			})
+			if awserr != nil {
+				return errorFromAWS(awserr)
+			}
+		}
+	case "lambda":
+		if p.lambda == nil {
+			return fmt.Errorf("Lambda is not enabled")
+		}
+		payload, _ := json.Marshal(events)
+		if parameters.LambdaFunctionParameters != nil && parameters.LambdaFunctionParameters.InvocationType == "FIRE_AND_FORGET" {
+			return p.lambda.InvokeAsync(pipe.Target, payload)
+		}
+		output, awserr := p.lambda.Invoke(lambda.InvokeInput{
+			FunctionName: pipe.Target,
+			Payload:      payload,
+		})
+		if awserr != nil {
+			return errorFromAWS(awserr)
+		}
+		if output.FunctionError != "" {
+			return fmt.Errorf("function error: %s", output.Payload)
+		}
+	case "events":
+		if p.eventBridge == nil {
+			return fmt.Errorf("EventBridge is not enabled")
+		}
+		return p.putEvents(pipe, parameters.EventBridgeEventBusParameters, events)
+	default:
+		p.logger.Debug("Delivery is not supported for target", "target", pipe.Target, "pipe", pipe.Name)
+	}
+	return nil
+}
+
+// putEvents puts the events on the target event bus, as the details of events from the pipe.
+func (p *Pipes) putEvents(pipe Pipe, parameters *APIEventBridgeEventBusTargetParameters, events []json.RawMessage) error {
+	source := "Pipe " + pipe.Name
+	detailType := "Event from aws:" + arnService(pipe.Source)
+	var resources []string
+	if parameters != nil {
+		if parameters.Source != "" {
+			source = parameters.Source
+		}
+		if parameters.DetailType != "" {
+			detailType = parameters.DetailType
+		}
+		resources = parameters.Resources
+	}
+
+	buses, err := p.eventBridge.ForARN(pipe.Target)
+	if err != nil {
+		return err
+	}
+	// PutEvents takes at most 10 entries.
+	for len(events) > 0 {
+		n := min(len(events), 10)
+		var entries []eventbridge.APIPutEventsRequestEntry
+		for _, event := range events[:n] {
+			entries = append(entries, eventbridge.APIPutEventsRequestEntry{
+				Source:       source,
+				DetailType:   detailType,
+				Detail:       string(event),
+				Resources:    resources,
+				EventBusName: pipe.Target,
+			})
+		}
+		events = events[n:]
+
+		output, awserr := buses.PutEvents(eventbridge.PutEventsInput{Entries: entries})
+		if awserr != nil {
+			return errorFromAWS(awserr)
+		}
+		for _, entry := range output.Entries {
+			if entry.ErrorCode != "" {
+				return fmt.Errorf("%s: %s", entry.ErrorCode, entry.ErrorMessage)
+			}
+		}
+	}
+	return nil
+}
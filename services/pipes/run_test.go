@@ -0,0 +1,222 @@
+package pipes
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/lambda"
+	"aws-in-a-box/services/sqs"
+)
+
+type fakeLambdaInvoker struct {
+	// Returns the function's response to the payload.
+	respond func(payload []byte) []byte
+
+	mu       sync.Mutex
+	payloads map[string][]string
+}
+
+func (f *fakeLambdaInvoker) Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error) {
+	f.InvokeAsync(input.FunctionName, input.Payload)
+	output := &lambda.InvokeOutput{StatusCode: 200}
+	if f.respond != nil {
+		output.Payload = f.respond(input.Payload)
+	}
+	return output, nil
+}
+
+func (f *fakeLambdaInvoker) InvokeAsync(functionArn string, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.payloads == nil {
+		f.payloads = make(map[string][]string)
+	}
+	f.payloads[functionArn] = append(f.payloads[functionArn], string(payload))
+	return nil
+}
+
+func (f *fakeLambdaInvoker) invocations(functionArn string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.payloads[functionArn]
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func sendMessages(t *testing.T, s *sqs.SQS, queueUrl string, bodies ...string) {
+	for _, body := range bodies {
+		if _, awserr := s.SendMessage(sqs.SendMessageInput{QueueUrl: queueUrl, MessageBody: body}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+}
+
+// waitForMessages receives messages from the queue until there are count of them.
+func waitForMessages(t *testing.T, s *sqs.SQS, queueUrl string, count int) []string {
+	var bodies []string
+	for deadline := time.Now().Add(5 * time.Second); len(bodies) < count && time.Now().Before(deadline); {
+		output, awserr := s.ReceiveMessage(sqs.ReceiveMessageInput{QueueUrl: queueUrl, MaxNumberOfMessages: 10})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		for _, message := range output.Messages {
+			bodies = append(bodies, message.Body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return bodies
+}
+
+func TestSQSPipeWithFilter(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	sourceUrl, sourceArn := createQueue(t, q, "source")
+	targetUrl, targetArn := createQueue(t, q, "target")
+	p := New(Options{ArnGenerator: generator, SQS: q})
+	createPipe(t, p, CreatePipeInput{
+		Name:   "placed",
+		Source: sourceArn,
+		SourceParameters: &APIPipeSourceParameters{
+			FilterCriteria: &APIFilterCriteria{Filters: []APIFilter{{Pattern: `{"body": {"state": ["placed"]}}`}}},
+		},
+		Target: targetArn,
+	})
+
+	sendMessages(t, q, sourceUrl, `{"state": "placed", "id": 1}`, `{"state": "shipped", "id": 1}`, `not json`)
+	bodies := waitForMessages(t, q, targetUrl, 1)
+	if len(bodies) != 1 {
+		t.Fatal("Unexpected messages", bodies)
+	}
+	var event struct {
+		Body           string `json:"body"`
+		EventSourceARN string `json:"eventSourceARN"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Body != `{"state": "placed", "id": 1}` || event.EventSourceARN != sourceArn {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+
+	// Filtered out messages are deleted from the source.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		attributes, awserr := q.GetQueueAttributes(sqs.GetQueueAttributesInput{QueueUrl: sourceUrl,
+			AttributeNames: []string{"ApproximateNumberOfMessages", "ApproximateNumberOfMessagesNotVisible"}})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		if attributes.Attributes["ApproximateNumberOfMessages"] == "0" &&
+			attributes.Attributes["ApproximateNumberOfMessagesNotVisible"] == "0" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Unexpected source attributes: %+v", attributes.Attributes)
+		}
+	}
+}
+
+func TestLambdaEnrichment(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	sourceUrl, sourceArn := createQueue(t, q, "source")
+	function := "arn:aws:lambda:us-east-1:123456789012:function:"
+	l := &fakeLambdaInvoker{respond: func(payload []byte) []byte {
+		var events []map[string]any
+		json.Unmarshal(payload, &events)
+		enriched := []map[string]any{}
+		for _, event := range events {
+			enriched = append(enriched, map[string]any{"enriched": event["body"]})
+		}
+		return must(json.Marshal(enriched))
+	}}
+	p := New(Options{ArnGenerator: generator, SQS: q, Lambda: l})
+	createPipe(t, p, CreatePipeInput{
+		Name:       "enriched",
+		Source:     sourceArn,
+		Enrichment: function + "enrich",
+		Target:     function + "target",
+		TargetParameters: &APIPipeTargetParameters{
+			LambdaFunctionParameters: &APILambdaFunctionTargetParameters{InvocationType: "FIRE_AND_FORGET"},
+		},
+	})
+
+	sendMessages(t, q, sourceUrl, "order")
+	var invocations []string
+	for deadline := time.Now().Add(5 * time.Second); len(invocations) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		invocations = l.invocations(function + "target")
+	}
+	if len(invocations) != 1 || invocations[0] != `[{"enriched":"order"}]` {
+		t.Fatal("Unexpected invocations", invocations)
+	}
+	if enrichments := l.invocations(function + "enrich"); len(enrichments) != 1 {
+		t.Fatal("Unexpected enrichments", enrichments)
+	}
+}
+
+func TestStoppedPipe(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	sourceUrl, sourceArn := createQueue(t, q, "source")
+	targetUrl, targetArn := createQueue(t, q, "target")
+	p := New(Options{ArnGenerator: generator, SQS: q})
+	createPipe(t, p, CreatePipeInput{Name: "stopped", DesiredState: "STOPPED", Source: sourceArn, Target: targetArn})
+
+	sendMessages(t, q, sourceUrl, "first")
+	time.Sleep(100 * time.Millisecond)
+	if bodies := waitForMessages(t, q, targetUrl, 0); len(bodies) != 0 {
+		t.Fatal("Unexpected messages", bodies)
+	}
+
+	// Starting the pipe processes the messages which were sent while it was stopped.
+	if _, awserr := p.StartPipe(StartPipeInput{Name: "stopped"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if bodies := waitForMessages(t, q, targetUrl, 1); len(bodies) != 1 {
+		t.Fatal("Unexpected messages", bodies)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	europeQueues := must(q.InRegion("eu-west-1"))
+	sourceUrl, _ := createQueue(t, europeQueues, "source")
+	targetUrl, _ := createQueue(t, europeQueues, "target")
+	p := New(Options{ArnGenerator: generator, SQS: q})
+	if _, err := p.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe := must(p.InRegion("eu-west-1"))
+	pipeArn := createPipe(t, europe, CreatePipeInput{
+		Name:   "europe",
+		Source: "arn:aws:sqs:eu-west-1:123456789012:source",
+		Target: "arn:aws:sqs:eu-west-1:123456789012:target",
+	})
+	if pipeArn != "arn:aws:pipes:eu-west-1:123456789012:pipe/europe" {
+		t.Fatal("Unexpected ARN", pipeArn)
+	}
+	if _, awserr := p.DescribePipe(DescribePipeInput{Name: "europe"}); awserr == nil {
+		t.Fatal("Expected eu-west-1's pipe not to be in us-east-1")
+	}
+
+	sendMessages(t, europeQueues, sourceUrl, "hello")
+	bodies := waitForMessages(t, europeQueues, targetUrl, 1)
+	if len(bodies) != 1 {
+		t.Fatal("Unexpected messages", bodies)
+	}
+	var event struct {
+		Body      string `json:"body"`
+		AwsRegion string `json:"awsRegion"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Body != "hello" || event.AwsRegion != "eu-west-1" {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+}
@@ -0,0 +1,91 @@
+package pipes
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type pipesState struct {
+	// Sorted by name.
+	Pipes []*Pipe
+}
+
+// TryPause pauses changes to each region's pipes while a snapshot copies them.
+func (p *Pipes) TryPause() (func(), bool) {
+	return p.regions.TryPause(func(p *Pipes) *sync.Mutex { return &p.mu })
+}
+
+// ExportState writes each region's pipes to the archive. Where the pipes were reading their
+// sources isn't exported.
+func (p *Pipes) ExportState(w *state.Writer) error {
+	return p.regions.ExportState(w, (*Pipes).exportState)
+}
+
+func (p *Pipes) exportState(w *state.Writer) error {
+	unlock := w.Lock(&p.mu)
+	defer unlock()
+
+	var exported pipesState
+	for _, pipe := range p.pipes {
+		exported.Pipes = append(exported.Pipes, pipe)
+	}
+	slices.SortFunc(exported.Pipes, func(a, b *Pipe) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("pipes.json", exported)
+}
+
+// ImportState replaces each region's pipes with the archive's, stopping the previous ones. The
+// imported pipes which are running start reading their sources once the sources exist, from the
+// sources' starting positions.
+func (p *Pipes) ImportState(r *state.Reader) (func() error, error) {
+	return p.regions.ImportState(r, (*Pipes).importState, (*Pipes).exportState)
+}
+
+func (p *Pipes) importState(r *state.Reader) (func() error, error) {
+	var imported pipesState
+	if err := r.ReadJSON("pipes.json", &imported); err != nil {
+		return nil, err
+	}
+
+	pipes := make(map[string]*Pipe)
+	for _, pipe := range imported.Pipes {
+		if pipe == nil || !nameRegex.MatchString(pipe.Name) || pipe.Arn == "" {
+			return nil, fmt.Errorf("invalid pipe")
+		}
+		if _, awserr := validateDesiredState(pipe.DesiredState); awserr != nil {
+			return nil, fmt.Errorf("pipe %s: %s", pipe.Name, awserr.Body.Message)
+		}
+		if pipe.SourceParameters != nil {
+			filters, awserr := parseFilters(pipe.SourceParameters.FilterCriteria)
+			if awserr != nil {
+				return nil, fmt.Errorf("pipe %s: %s", pipe.Name, awserr.Body.Message)
+			}
+			pipe.filters = filters
+		}
+		if pipe.Tags == nil {
+			pipe.Tags = make(map[string]string)
+		}
+		pipe.source = nil
+		pipe.stop = nil
+		pipe.done = nil
+		pipes[pipe.Name] = pipe
+	}
+
+	return func() error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, pipe := range p.pipes {
+			p.lockedStop(pipe)
+		}
+		p.pipes = pipes
+		for _, pipe := range pipes {
+			p.lockedApplyDesiredState(pipe)
+		}
+		return nil
+	}, nil
+}
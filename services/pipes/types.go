@@ -0,0 +1,226 @@
+package pipes
+
+type APIFilter struct {
+	Pattern string `length:",4096"`
+}
+
+type APIFilterCriteria struct {
+	Filters []APIFilter `length:",5"`
+}
+
+type APIDeadLetterConfig struct {
+	Arn string `json:",omitempty" length:"1,1600"`
+}
+
+type APISqsQueueSourceParameters struct {
+	BatchSize                      *int32 `json:",omitempty" range:"1,10000"`
+	MaximumBatchingWindowInSeconds *int32 `json:",omitempty" range:"0,300"`
+}
+
+type APIStreamSourceParameters struct {
+	// TRIM_HORIZON or LATEST.
+	StartingPosition               string `required:"true" enum:"TRIM_HORIZON|LATEST|AT_TIMESTAMP"`
+	BatchSize                      *int32 `json:",omitempty" range:"1,10000"`
+	MaximumBatchingWindowInSeconds *int32 `json:",omitempty" range:"0,300"`
+	// Stored, but failed batches are always retried until they succeed.
+	DeadLetterConfig          *APIDeadLetterConfig `json:",omitempty"`
+	MaximumRecordAgeInSeconds *int32               `json:",omitempty" range:"-1,604800"`
+	MaximumRetryAttempts      *int32               `json:",omitempty" range:"-1,10000"`
+	OnPartialBatchItemFailure string               `json:",omitempty" enum:"AUTOMATIC_BISECT"`
+	ParallelizationFactor     *int32               `json:",omitempty" range:"1,10"`
+	StartingPositionTimestamp float64              `json:",omitempty"`
+}
+
+type APIPipeSourceParameters struct {
+	FilterCriteria           *APIFilterCriteria           `json:",omitempty"`
+	SqsQueueParameters       *APISqsQueueSourceParameters `json:",omitempty"`
+	KinesisStreamParameters  *APIStreamSourceParameters   `json:",omitempty"`
+	DynamoDBStreamParameters *APIStreamSourceParameters   `json:",omitempty"`
+}
+
+type APIPipeEnrichmentParameters struct {
+	// Stored, but not applied.
+	InputTemplate  string `json:",omitempty" length:",8192"`
+	HttpParameters any    `json:",omitempty"`
+}
+
+type APISqsQueueTargetParameters struct {
+	MessageGroupId         string `json:",omitempty" length:",128"`
+	MessageDeduplicationId string `json:",omitempty" length:",128"`
+}
+
+type APIKinesisStreamTargetParameters struct {
+	PartitionKey string `required:"true" length:",256"`
+}
+
+type APILambdaFunctionTargetParameters struct {
+	// REQUEST_RESPONSE or FIRE_AND_FORGET.
+	InvocationType string `json:",omitempty" enum:"REQUEST_RESPONSE|FIRE_AND_FORGET"`
+}
+
+type APIEventBridgeEventBusTargetParameters struct {
+	DetailType string   `json:",omitempty" length:"1,128"`
+	Source     string   `json:",omitempty" length:"1,256"`
+	Resources  []string `json:",omitempty" length:",10"`
+	Time       string   `json:",omitempty"`
+	EndpointId string   `json:",omitempty" length:"1,50"`
+}
+
+type APIPipeTargetParameters struct {
+	// Stored, but not applied.
+	InputTemplate                 string                                  `json:",omitempty" length:",8192"`
+	SqsQueueParameters            *APISqsQueueTargetParameters            `json:",omitempty"`
+	KinesisStreamParameters       *APIKinesisStreamTargetParameters       `json:",omitempty"`
+	LambdaFunctionParameters      *APILambdaFunctionTargetParameters      `json:",omitempty"`
+	EventBridgeEventBusParameters *APIEventBridgeEventBusTargetParameters `json:",omitempty"`
+	// Stored, but these targets aren't invoked.
+	StepFunctionStateMachineParameters any `json:",omitempty"`
+	BatchJobParameters                 any `json:",omitempty"`
+	CloudWatchLogsParameters           any `json:",omitempty"`
+	EcsTaskParameters                  any `json:",omitempty"`
+	HttpParameters                     any `json:",omitempty"`
+	RedshiftDataParameters             any `json:",omitempty"`
+	SageMakerPipelineParameters        any `json:",omitempty"`
+}
+
+// APIPipe is a pipe, as it's listed.
+type APIPipe struct {
+	Arn              string
+	Name             string
+	CurrentState     string
+	DesiredState     string
+	StateReason      string `json:",omitempty"`
+	Source           string
+	Enrichment       string `json:",omitempty"`
+	Target           string
+	CreationTime     float64
+	LastModifiedTime float64
+}
+
+// APIPipeState is the output of the operations which change a pipe.
+type APIPipeState struct {
+	Arn              string
+	Name             string
+	CurrentState     string
+	DesiredState     string
+	CreationTime     float64
+	LastModifiedTime float64
+}
+
+type CreatePipeInput struct {
+	Name string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	// RUNNING or STOPPED.
+	DesiredState         string `enum:"RUNNING|STOPPED"`
+	Description          string `length:",512"`
+	RoleArn              string `required:"true" length:"1,1600"`
+	Source               string `required:"true" length:"1,1600"`
+	SourceParameters     *APIPipeSourceParameters
+	Enrichment           string `length:",1600"`
+	EnrichmentParameters *APIPipeEnrichmentParameters
+	Target               string `required:"true" length:"1,1600"`
+	TargetParameters     *APIPipeTargetParameters
+	Tags                 map[string]string `length:"1,50"`
+	// Stored, but nothing is logged.
+	LogConfiguration any
+	KmsKeyIdentifier string `length:",2048"`
+}
+
+type CreatePipeOutput = APIPipeState
+
+type DescribePipeInput struct {
+	Name string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type DescribePipeOutput struct {
+	Arn                  string
+	Name                 string
+	Description          string `json:",omitempty"`
+	CurrentState         string
+	DesiredState         string
+	StateReason          string `json:",omitempty"`
+	RoleArn              string
+	Source               string
+	SourceParameters     *APIPipeSourceParameters     `json:",omitempty"`
+	Enrichment           string                       `json:",omitempty"`
+	EnrichmentParameters *APIPipeEnrichmentParameters `json:",omitempty"`
+	Target               string
+	TargetParameters     *APIPipeTargetParameters `json:",omitempty"`
+	Tags                 map[string]string
+	LogConfiguration     any    `json:",omitempty"`
+	KmsKeyIdentifier     string `json:",omitempty"`
+	CreationTime         float64
+	LastModifiedTime     float64
+}
+
+// UpdatePipe replaces every field of the pipe except its source and tags.
+type UpdatePipeInput struct {
+	Name                 string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	DesiredState         string `enum:"RUNNING|STOPPED"`
+	Description          string `length:",512"`
+	RoleArn              string `required:"true" length:"1,1600"`
+	SourceParameters     *APIPipeSourceParameters
+	Enrichment           string `length:",1600"`
+	EnrichmentParameters *APIPipeEnrichmentParameters
+	Target               string `length:"1,1600"`
+	TargetParameters     *APIPipeTargetParameters
+	LogConfiguration     any
+	KmsKeyIdentifier     string `length:",2048"`
+}
+
+type UpdatePipeOutput = APIPipeState
+
+type DeletePipeInput struct {
+	Name string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type DeletePipeOutput = APIPipeState
+
+type StartPipeInput struct {
+	Name string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type StartPipeOutput = APIPipeState
+
+type StopPipeInput struct {
+	Name string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+}
+
+type StopPipeOutput = APIPipeState
+
+type ListPipesInput struct {
+	NamePrefix   string `json:"-" rest:"query:NamePrefix" length:"1,64" pattern:"[\\.\\-_A-Za-z0-9]+"`
+	SourcePrefix string `json:"-" rest:"query:SourcePrefix" length:"1,300"`
+	TargetPrefix string `json:"-" rest:"query:TargetPrefix" length:"1,300"`
+	CurrentState string `json:"-" rest:"query:CurrentState" enum:"RUNNING|STOPPED|CREATING|UPDATING|DELETING|STARTING|STOPPING|CREATE_FAILED|UPDATE_FAILED|START_FAILED|STOP_FAILED|DELETE_FAILED|CREATE_ROLLBACK_FAILED|DELETE_ROLLBACK_FAILED|UPDATE_ROLLBACK_FAILED"`
+	DesiredState string `json:"-" rest:"query:DesiredState" enum:"RUNNING|STOPPED"`
+	Limit        int    `json:"-" rest:"query:Limit" range:"1,100"`
+	NextToken    string `json:"-" rest:"query:NextToken" length:",2048"`
+}
+
+type ListPipesOutput struct {
+	Pipes     []APIPipe
+	NextToken string `json:",omitempty"`
+}
+
+// The tagging operations' fields are lower case.
+type TagResourceInput struct {
+	ResourceArn string            `json:"-" rest:"path:ResourceArn" required:"true" length:"1,1600"`
+	Tags        map[string]string `json:"tags" required:"true" length:"1,50"`
+}
+
+type TagResourceOutput struct{}
+
+type UntagResourceInput struct {
+	ResourceArn string   `json:"-" rest:"path:ResourceArn" required:"true" length:"1,1600"`
+	TagKeys     []string `json:"-" rest:"query:tagKeys" required:"true" length:",50"`
+}
+
+type UntagResourceOutput struct{}
+
+type ListTagsForResourceInput struct {
+	ResourceArn string `json:"-" rest:"path:ResourceArn" required:"true" length:"1,1600"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags map[string]string `json:"tags"`
+}
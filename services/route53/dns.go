@@ -0,0 +1,403 @@
+package route53
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// Names under this domain, such as bucket.s3.localhost, resolve to the service IP.
+	serviceDomain = "localhost."
+	serviceTTL    = 60
+	// How many aliases and CNAMEs are followed when answering a query.
+	maxChainLength = 8
+	// Without EDNS, UDP responses are limited to 512 bytes.
+	maxUDPSize = 512
+)
+
+var dnsTypes = map[dnsmessage.Type]string{
+	dnsmessage.TypeA:     "A",
+	dnsmessage.TypeAAAA:  "AAAA",
+	dnsmessage.TypeCNAME: "CNAME",
+	dnsmessage.TypeMX:    "MX",
+	dnsmessage.TypeNS:    "NS",
+	dnsmessage.TypePTR:   "PTR",
+	dnsmessage.TypeSOA:   "SOA",
+	dnsmessage.TypeSRV:   "SRV",
+	dnsmessage.TypeTXT:   "TXT",
+}
+
+// ServeDNS answers DNS queries for the hosted zones' records until the connection is closed.
+// Queries for other names are forwarded to the upstream server, if there is one.
+func (r *Route53) ServeDNS(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			response, err := r.handleDNSQuery(query)
+			if err != nil {
+				r.logger.Debug("Handling DNS query", "error", err)
+				return
+			}
+			conn.WriteTo(response, addr)
+		}()
+	}
+}
+
+func (r *Route53) handleDNSQuery(query []byte) ([]byte, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		return nil, err
+	}
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               msg.Header.ID,
+			Response:         true,
+			OpCode:           msg.Header.OpCode,
+			RecursionDesired: msg.Header.RecursionDesired,
+		},
+		Questions: msg.Questions,
+	}
+	if msg.Header.Response || msg.Header.OpCode != 0 || len(msg.Questions) != 1 {
+		response.Header.RCode = dnsmessage.RCodeFormatError
+		return response.Pack()
+	}
+
+	question := msg.Questions[0]
+	answer, ok := r.resolve(question)
+	if !ok {
+		if r.dnsUpstream != "" {
+			return r.forwardDNSQuery(query)
+		}
+		response.Header.RCode = dnsmessage.RCodeRefused
+		return response.Pack()
+	}
+	r.logger.Debug("Answering DNS query", "name", question.Name.String(), "type", question.Type.String(), "answers", len(answer.resources))
+
+	response.Header.Authoritative = true
+	response.Header.RecursionAvailable = r.dnsUpstream != ""
+	response.Answers = answer.resources
+	if len(answer.resources) == 0 && answer.zone != nil {
+		if !answer.nameExists {
+			response.Header.RCode = dnsmessage.RCodeNameError
+		}
+		// Negative answers include the zone's SOA record, so resolvers can cache them.
+		soa := answer.zone.records[recordKey{answer.zone.Name, "SOA", ""}]
+		response.Authorities = toResources(answer.zone.Name, soa)
+	}
+	packed, err := response.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if len(packed) > maxUDPSize {
+		response.Header.Truncated = true
+		response.Answers, response.Authorities = nil, nil
+		return response.Pack()
+	}
+	return packed, nil
+}
+
+// forwardDNSQuery sends the query to the upstream server and returns its response.
+func (r *Route53) forwardDNSQuery(query []byte) ([]byte, error) {
+	conn, err := net.Dial("udp", r.dnsUpstream)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+type dnsAnswer struct {
+	resources []dnsmessage.Resource
+	// The hosted zone the name is in, if any.
+	zone *HostedZone
+	// Whether the name has records of any type, or names below it do.
+	nameExists bool
+}
+
+// resolve answers the question from the hosted zones or the service domain.
+// It returns false if the name is in neither.
+func (r *Route53) resolve(question dnsmessage.Question) (dnsAnswer, bool) {
+	if question.Class != dnsmessage.ClassINET && question.Class != dnsmessage.ClassANY {
+		return dnsAnswer{}, false
+	}
+	name := strings.ToLower(question.Name.String())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lockedResolve(name, question.Type, maxChainLength)
+}
+
+func (r *Route53) lockedResolve(name string, qtype dnsmessage.Type, chainLength int) (dnsAnswer, bool) {
+	if chainLength == 0 {
+		return dnsAnswer{}, true
+	}
+	zone := r.lockedFindZone(name)
+	if zone == nil {
+		if inZone(name, serviceDomain) {
+			return r.serviceAnswer(name, qtype), true
+		}
+		return dnsAnswer{}, false
+	}
+
+	answer := dnsAnswer{zone: zone}
+	sets := zone.recordSets(name)
+	if len(sets) > 0 || zone.hasDescendants(name) {
+		answer.nameExists = true
+	} else {
+		sets = zone.wildcardRecordSets(name)
+		answer.nameExists = len(sets) > 0
+	}
+
+	recordType, ok := dnsTypes[qtype]
+	if !ok {
+		return answer, true
+	}
+	set := sets[recordType]
+	if set == nil && recordType != "CNAME" && sets["CNAME"] != nil {
+		// The CNAME is followed, like a recursive resolver would.
+		cname := sets["CNAME"]
+		answer.resources = toResources(name, cname)
+		if cname.AliasTarget == nil && len(cname.ResourceRecords) > 0 {
+			target, _ := r.lockedResolve(normalizeName(cname.ResourceRecords[0].Value), qtype, chainLength-1)
+			answer.resources = append(answer.resources, target.resources...)
+		}
+		return answer, true
+	}
+	if set == nil {
+		return answer, true
+	}
+	if set.AliasTarget != nil {
+		target, _ := r.lockedResolve(set.AliasTarget.DNSName, qtype, chainLength-1)
+		for _, resource := range target.resources {
+			resource.Header.Name = mustNewName(name)
+			answer.resources = append(answer.resources, resource)
+		}
+		return answer, true
+	}
+	answer.resources = toResources(name, set)
+	return answer, true
+}
+
+// lockedFindZone returns the hosted zone with the longest name the name is in.
+func (r *Route53) lockedFindZone(name string) *HostedZone {
+	var found *HostedZone
+	for _, zone := range r.zones {
+		if !inZone(name, zone.Name) {
+			continue
+		}
+		// Of zones with the same name, the oldest ID is used, so answers are stable.
+		if found == nil || len(zone.Name) > len(found.Name) || (zone.Name == found.Name && zone.Id < found.Id) {
+			found = zone
+		}
+	}
+	return found
+}
+
+func (r *Route53) serviceAnswer(name string, qtype dnsmessage.Type) dnsAnswer {
+	header := dnsmessage.ResourceHeader{Name: mustNewName(name), Class: dnsmessage.ClassINET, TTL: serviceTTL}
+	var answer dnsAnswer
+	if ip := r.dnsServiceIP.To4(); ip != nil && qtype == dnsmessage.TypeA {
+		header.Type = dnsmessage.TypeA
+		answer.resources = append(answer.resources, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: [4]byte(ip)}})
+	} else if r.dnsServiceIP.To4() == nil && qtype == dnsmessage.TypeAAAA {
+		header.Type = dnsmessage.TypeAAAA
+		answer.resources = append(answer.resources, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: [16]byte(r.dnsServiceIP.To16())}})
+	}
+	return answer
+}
+
+// recordSets returns the name's record sets, keyed by type. Of record sets with routing policies,
+// the first by set identifier is used.
+func (z *HostedZone) recordSets(name string) map[string]*APIResourceRecordSet {
+	sets := make(map[string]*APIResourceRecordSet)
+	for key, set := range z.records {
+		if key.name != name {
+			continue
+		}
+		if existing, ok := sets[key.recordType]; !ok || key.setIdentifier < existing.SetIdentifier {
+			sets[key.recordType] = set
+		}
+	}
+	return sets
+}
+
+func (z *HostedZone) hasDescendants(name string) bool {
+	for key := range z.records {
+		if strings.HasSuffix(key.name, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardRecordSets returns the record sets of the closest wildcard name which matches the name.
+func (z *HostedZone) wildcardRecordSets(name string) map[string]*APIResourceRecordSet {
+	for parent := name; parent != z.Name; {
+		_, parent, _ = strings.Cut(parent, ".")
+		if sets := z.recordSets("*." + parent); len(sets) > 0 {
+			return sets
+		}
+	}
+	return nil
+}
+
+func mustNewName(name string) dnsmessage.Name {
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// toResources returns the record set's records as DNS resources with the name.
+// Records whose values can't be parsed are skipped.
+func toResources(name string, set *APIResourceRecordSet) []dnsmessage.Resource {
+	var resources []dnsmessage.Resource
+	for _, record := range set.ResourceRecords {
+		body, err := resourceBody(set.Type, record.Value)
+		if err != nil {
+			continue
+		}
+		header := dnsmessage.ResourceHeader{Name: mustNewName(name), Class: dnsmessage.ClassINET}
+		if set.TTL != nil {
+			header.TTL = uint32(*set.TTL)
+		}
+		resources = append(resources, dnsmessage.Resource{Header: header, Body: body})
+	}
+	return resources
+}
+
+// resourceBody parses a record's value, in the format Route 53 uses for its type.
+// See https://docs.aws.amazon.com/Route53/latest/DeveloperGuide/ResourceRecordTypes.html
+func resourceBody(recordType string, value string) (dnsmessage.ResourceBody, error) {
+	fields := strings.Fields(value)
+	switch recordType {
+	case "A":
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid A value %q", value)
+		}
+		return &dnsmessage.AResource{A: [4]byte(ip)}, nil
+	case "AAAA":
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid AAAA value %q", value)
+		}
+		return &dnsmessage.AAAAResource{AAAA: [16]byte(ip.To16())}, nil
+	case "CNAME":
+		name, err := dnsmessage.NewName(normalizeName(value))
+		return &dnsmessage.CNAMEResource{CNAME: name}, err
+	case "NS":
+		name, err := dnsmessage.NewName(normalizeName(value))
+		return &dnsmessage.NSResource{NS: name}, err
+	case "PTR":
+		name, err := dnsmessage.NewName(normalizeName(value))
+		return &dnsmessage.PTRResource{PTR: name}, err
+	case "MX":
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid MX value %q", value)
+		}
+		pref, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		name, err := dnsmessage.NewName(normalizeName(fields[1]))
+		return &dnsmessage.MXResource{Pref: uint16(pref), MX: name}, err
+	case "SRV":
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid SRV value %q", value)
+		}
+		var numbers [3]uint16
+		for i := range numbers {
+			n, err := strconv.ParseUint(fields[i], 10, 16)
+			if err != nil {
+				return nil, err
+			}
+			numbers[i] = uint16(n)
+		}
+		name, err := dnsmessage.NewName(normalizeName(fields[3]))
+		return &dnsmessage.SRVResource{Priority: numbers[0], Weight: numbers[1], Port: numbers[2], Target: name}, err
+	case "SOA":
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid SOA value %q", value)
+		}
+		var numbers [5]uint32
+		for i := range numbers {
+			n, err := strconv.ParseUint(fields[i+2], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			numbers[i] = uint32(n)
+		}
+		ns, err := dnsmessage.NewName(normalizeName(fields[0]))
+		if err != nil {
+			return nil, err
+		}
+		mbox, err := dnsmessage.NewName(normalizeName(fields[1]))
+		return &dnsmessage.SOAResource{
+			NS: ns, MBox: mbox, Serial: numbers[0], Refresh: numbers[1], Retry: numbers[2], Expire: numbers[3], MinTTL: numbers[4],
+		}, err
+	case "TXT":
+		txt, err := parseCharacterStrings(value)
+		return &dnsmessage.TXTResource{TXT: txt}, err
+	}
+	return nil, fmt.Errorf("unsupported record type %s", recordType)
+}
+
+// parseCharacterStrings parses a TXT value, which is one or more quoted strings, such as
+// "v=spf1 -all" "second string". Within quotes, \" is a quote and \ddd is an octal byte.
+func parseCharacterStrings(value string) ([]string, error) {
+	var strs []string
+	for value = strings.TrimSpace(value); value != ""; value = strings.TrimSpace(value) {
+		if value[0] != '"' {
+			// Unquoted values are a single string up to the next space.
+			str, rest, _ := strings.Cut(value, " ")
+			strs = append(strs, str)
+			value = rest
+			continue
+		}
+		var sb strings.Builder
+		i := 1
+		for ; i < len(value) && value[i] != '"'; i++ {
+			if value[i] == '\\' && i+1 < len(value) {
+				if n, err := strconv.ParseUint(value[i+1:min(i+4, len(value))], 8, 8); err == nil && i+4 <= len(value) {
+					sb.WriteByte(byte(n))
+					i += 3
+					continue
+				}
+				i++
+			}
+			sb.WriteByte(value[i])
+		}
+		if i == len(value) {
+			return nil, fmt.Errorf("unterminated string in %q", value)
+		}
+		strs = append(strs, sb.String())
+		value = value[i+1:]
+	}
+	return strs, nil
+}
@@ -0,0 +1,149 @@
+package route53
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func serveDNS(t *testing.T, r *Route53) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go r.ServeDNS(conn)
+	return conn.LocalAddr().String()
+}
+
+func query(t *testing.T, addr string, name string, qtype dnsmessage.Type) dnsmessage.Message {
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: mustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(packed); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response dnsmessage.Message
+	if err := response.Unpack(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if response.Header.ID != 42 {
+		t.Fatal("Unexpected ID", response.Header.ID)
+	}
+	return response
+}
+
+// answers returns the answers' values as strings, such as 10.0.0.1 or www.example.com.
+func answers(msg dnsmessage.Message) []string {
+	var values []string
+	for _, answer := range msg.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			values = append(values, net.IP(body.A[:]).String())
+		case *dnsmessage.CNAMEResource:
+			values = append(values, body.CNAME.String())
+		case *dnsmessage.MXResource:
+			values = append(values, body.MX.String())
+		case *dnsmessage.TXTResource:
+			values = append(values, body.TXT...)
+		}
+	}
+	return values
+}
+
+func TestDNS(t *testing.T) {
+	r := New(Options{DNSServiceIP: net.IPv4(172, 18, 0, 2)})
+	id := createZone(t, r, "example.com")
+	change(t, r, id,
+		APIChange{Action: "CREATE", ResourceRecordSet: aRecord("www.example.com", "10.0.0.1", "10.0.0.2")},
+		APIChange{Action: "CREATE", ResourceRecordSet: aRecord(`*.apps.example.com`, "10.0.0.3")},
+		APIChange{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{
+			Name: "api.example.com", Type: "CNAME", TTL: ptr(int64(60)),
+			ResourceRecords: []APIResourceRecord{{Value: "www.example.com"}},
+		}},
+		APIChange{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{
+			Name: "example.com", Type: "A",
+			AliasTarget: &APIAliasTarget{HostedZoneId: id, DNSName: "www.example.com"},
+		}},
+		APIChange{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{
+			Name: "assets.example.com", Type: "A",
+			AliasTarget: &APIAliasTarget{HostedZoneId: "Z3AQBSTGFYJSTF", DNSName: "assets.s3.localhost"},
+		}},
+		APIChange{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{
+			Name: "example.com", Type: "TXT", TTL: ptr(int64(60)),
+			ResourceRecords: []APIResourceRecord{{Value: `"v=spf1 -all" "say \"hi\""`}},
+		}},
+		APIChange{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{
+			Name: "example.com", Type: "MX", TTL: ptr(int64(60)),
+			ResourceRecords: []APIResourceRecord{{Value: "10 mail.example.com"}},
+		}},
+	)
+	addr := serveDNS(t, r)
+
+	for _, test := range []struct {
+		name  string
+		qtype dnsmessage.Type
+		want  []string
+	}{
+		{"www.example.com.", dnsmessage.TypeA, []string{"10.0.0.1", "10.0.0.2"}},
+		{"WWW.Example.com.", dnsmessage.TypeA, []string{"10.0.0.1", "10.0.0.2"}},
+		{"api.example.com.", dnsmessage.TypeA, []string{"www.example.com.", "10.0.0.1", "10.0.0.2"}},
+		{"example.com.", dnsmessage.TypeA, []string{"10.0.0.1", "10.0.0.2"}},
+		{"example.com.", dnsmessage.TypeTXT, []string{"v=spf1 -all", `say "hi"`}},
+		{"example.com.", dnsmessage.TypeMX, []string{"mail.example.com."}},
+		{"web.apps.example.com.", dnsmessage.TypeA, []string{"10.0.0.3"}},
+		{"assets.example.com.", dnsmessage.TypeA, []string{"172.18.0.2"}},
+		{"my-bucket.s3.localhost.", dnsmessage.TypeA, []string{"172.18.0.2"}},
+	} {
+		response := query(t, addr, test.name, test.qtype)
+		if got := answers(response); !slices.Equal(got, test.want) || !response.Header.Authoritative {
+			t.Errorf("Unexpected answers for %s %v: %v", test.name, test.qtype, got)
+		}
+	}
+
+	response := query(t, addr, "www.example.com.", dnsmessage.TypeAAAA)
+	if response.Header.RCode != dnsmessage.RCodeSuccess || len(response.Answers) != 0 || len(response.Authorities) != 1 {
+		t.Fatalf("Expected an empty answer: %+v", response)
+	}
+	response = query(t, addr, "missing.example.com.", dnsmessage.TypeA)
+	if response.Header.RCode != dnsmessage.RCodeNameError || len(response.Authorities) != 1 {
+		t.Fatalf("Expected NXDOMAIN: %+v", response)
+	}
+	// There is no upstream server to forward other names to.
+	response = query(t, addr, "example.org.", dnsmessage.TypeA)
+	if response.Header.RCode != dnsmessage.RCodeRefused {
+		t.Fatalf("Expected REFUSED: %+v", response)
+	}
+}
+
+func TestDNSUpstream(t *testing.T) {
+	upstream := New(Options{})
+	change(t, upstream, createZone(t, upstream, "example.org"),
+		APIChange{Action: "CREATE", ResourceRecordSet: aRecord("www.example.org", "10.0.1.1")})
+	r := New(Options{DNSUpstream: serveDNS(t, upstream)})
+	createZone(t, r, "example.com")
+
+	response := query(t, serveDNS(t, r), "www.example.org.", dnsmessage.TypeA)
+	if got := answers(response); !slices.Equal(got, []string{"10.0.1.1"}) {
+		t.Fatal("Unexpected answers", got)
+	}
+}
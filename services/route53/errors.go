@@ -0,0 +1,47 @@
+package route53
+
+import "aws-in-a-box/awserrors"
+
+func HostedZoneAlreadyExists(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 409,
+		Body: awserrors.ErrorBody{
+			Type:    "HostedZoneAlreadyExists",
+			Message: message,
+		},
+	}
+}
+
+func HostedZoneNotEmpty(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("HostedZoneNotEmpty", message)
+}
+
+func InvalidChangeBatch(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidChangeBatch", message)
+}
+
+func InvalidDomainName(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidDomainName", message)
+}
+
+func InvalidInput(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidInput", message)
+}
+
+func NoSuchChange(message string) *awserrors.Error {
+	return notFound("NoSuchChange", message)
+}
+
+func NoSuchHostedZone(message string) *awserrors.Error {
+	return notFound("NoSuchHostedZone", message)
+}
+
+func notFound(errorType string, message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    errorType,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package route53
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restxml"
+)
+
+// Route 53 only supports the REST-XML protocol.
+var restXMLProtocol = restxml.Protocol{
+	XMLNamespace: "https://route53.amazonaws.com/doc/2013-04-01/",
+}
+
+func NewHandler(logger *slog.Logger, r *Route53, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	const zonePath = "/2013-04-01/hostedzone/{Id}"
+	const tagsPath = "/2013-04-01/tags/{ResourceType}/{ResourceId}"
+
+	registry := restxml.NewRegistry(restXMLProtocol)
+	restxml.Register(logger, registry, http.MethodPost, "/2013-04-01/hostedzone", "CreateHostedZone", r.CreateHostedZone)
+	restxml.Register(logger, registry, http.MethodGet, "/2013-04-01/hostedzone", "ListHostedZones", r.ListHostedZones)
+	restxml.Register(logger, registry, http.MethodGet, "/2013-04-01/hostedzonesbyname", "ListHostedZonesByName", r.ListHostedZonesByName)
+	restxml.Register(logger, registry, http.MethodGet, zonePath, "GetHostedZone", r.GetHostedZone)
+	restxml.Register(logger, registry, http.MethodPost, zonePath, "UpdateHostedZoneComment", r.UpdateHostedZoneComment)
+	restxml.Register(logger, registry, http.MethodDelete, zonePath, "DeleteHostedZone", r.DeleteHostedZone)
+	restxml.Register(logger, registry, http.MethodPost, zonePath+"/rrset", "ChangeResourceRecordSets", r.ChangeResourceRecordSets)
+	restxml.Register(logger, registry, http.MethodGet, zonePath+"/rrset", "ListResourceRecordSets", r.ListResourceRecordSets)
+	restxml.Register(logger, registry, http.MethodGet, "/2013-04-01/change/{Id}", "GetChange", r.GetChange)
+	restxml.Register(logger, registry, http.MethodPost, tagsPath, "ChangeTagsForResource", r.ChangeTagsForResource)
+	restxml.Register(logger, registry, http.MethodGet, tagsPath, "ListTagsForResource", r.ListTagsForResource)
+	capabilityRegistry.Add("route53", registry.Operations()...)
+	return restxml.NewHandler(registry)
+}
@@ -0,0 +1,230 @@
+package route53
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"aws-in-a-box/awserrors"
+)
+
+func (s *APIResourceRecordSet) key() recordKey {
+	return recordKey{s.Name, s.Type, s.SetIdentifier}
+}
+
+func (s *APIResourceRecordSet) output() APIResourceRecordSet {
+	output := *s
+	output.Name = escapeName(s.Name)
+	output.ResourceRecords = slices.Clone(s.ResourceRecords)
+	if s.AliasTarget != nil {
+		target := *s.AliasTarget
+		target.DNSName = escapeName(target.DNSName)
+		output.AliasTarget = &target
+	}
+	return output
+}
+
+// hasRoutingPolicy returns whether the record set is one of several with the same name and type,
+// which are told apart by their set identifiers.
+func (s *APIResourceRecordSet) hasRoutingPolicy() bool {
+	return s.Weight != nil || s.Region != "" || s.Failover != "" || s.MultiValueAnswer != nil
+}
+
+// validateRecordSet returns the record set with its names normalized, if it's valid in the zone.
+func validateRecordSet(zone *HostedZone, set APIResourceRecordSet) (*APIResourceRecordSet, *awserrors.Error) {
+	set.Name = normalizeName(set.Name)
+	if !validateName(set.Name) {
+		return nil, InvalidChangeBatch(fmt.Sprintf("%s is not a valid DNS name.", escapeName(set.Name)))
+	}
+	if !slices.Contains(recordTypes, set.Type) {
+		return nil, InvalidInput(fmt.Sprintf("Invalid request: %s is not a valid record type.", set.Type))
+	}
+	if !inZone(set.Name, zone.Name) {
+		return nil, InvalidChangeBatch(fmt.Sprintf("RRSet with DNS name %s is not permitted in zone %s", escapeName(set.Name), zone.Name))
+	}
+	if (set.SetIdentifier != "") != set.hasRoutingPolicy() {
+		return nil, InvalidInput(fmt.Sprintf("Invalid request: A SetIdentifier must be specified with exactly one routing policy in RRSet %s, type %s.", escapeName(set.Name), set.Type))
+	}
+
+	if set.AliasTarget != nil {
+		if set.TTL != nil || len(set.ResourceRecords) > 0 {
+			return nil, InvalidInput(fmt.Sprintf("Invalid request: An alias RRSet %s, type %s can't have a TTL or resource records.", escapeName(set.Name), set.Type))
+		}
+		target := *set.AliasTarget
+		if target.HostedZoneId == "" || target.DNSName == "" {
+			return nil, InvalidInput("Invalid request: An AliasTarget needs a HostedZoneId and a DNSName.")
+		}
+		target.HostedZoneId = trimZoneId(target.HostedZoneId)
+		target.DNSName = normalizeName(target.DNSName)
+		set.AliasTarget = &target
+	} else if set.TTL == nil || len(set.ResourceRecords) == 0 {
+		return nil, InvalidInput(fmt.Sprintf(
+			"Invalid request: Expected exactly one of [AliasTarget, all of [TTL, and ResourceRecords]], but found none in Change with [Name=%s, Type=%s]",
+			escapeName(set.Name), set.Type))
+	}
+
+	if set.Type == "CNAME" {
+		if set.Name == zone.Name {
+			return nil, InvalidChangeBatch(fmt.Sprintf("RRSet of type CNAME with DNS name %s is not permitted at apex in zone %s", escapeName(set.Name), zone.Name))
+		}
+		if len(set.ResourceRecords) > 1 {
+			return nil, InvalidChangeBatch(fmt.Sprintf("RRSet of type CNAME with DNS name %s has more than one resource record", escapeName(set.Name)))
+		}
+	}
+	return &set, nil
+}
+
+// lockedCheckAliasTarget checks that an alias to an emulated hosted zone targets one of its record sets.
+// Other targets, such as the hosted zones of S3 website endpoints or load balancers, may be any name.
+func (r *Route53) lockedCheckAliasTarget(zone *HostedZone, records map[recordKey]*APIResourceRecordSet, set *APIResourceRecordSet) *awserrors.Error {
+	target := set.AliasTarget
+	if target == nil {
+		return nil
+	}
+	targetZone, ok := r.zones[target.HostedZoneId]
+	if !ok {
+		return nil
+	}
+	if targetZone != zone {
+		records = targetZone.records
+	}
+	if !inZone(target.DNSName, targetZone.Name) {
+		return InvalidChangeBatch(fmt.Sprintf(
+			"Tried to create an alias that targets %s, type %s in zone %s, but the alias target name does not lie within the target zone",
+			escapeName(target.DNSName), set.Type, targetZone.Id))
+	}
+	for key := range records {
+		if key.name == target.DNSName && key.recordType == set.Type {
+			return nil
+		}
+	}
+	return InvalidChangeBatch(fmt.Sprintf(
+		"Tried to create an alias that targets %s, type %s in zone %s, but that target was not found",
+		escapeName(target.DNSName), set.Type, targetZone.Id))
+}
+
+// checkConflicts checks that a CNAME record set is the only record set with its name.
+func checkConflicts(zone *HostedZone, records map[recordKey]*APIResourceRecordSet, set *APIResourceRecordSet) *awserrors.Error {
+	for key := range records {
+		if key.name != set.Name || key.recordType == set.Type {
+			continue
+		}
+		if key.recordType == "CNAME" || set.Type == "CNAME" {
+			return InvalidChangeBatch(fmt.Sprintf(
+				"RRSet of type %s with DNS name %s is not permitted because a conflicting RRSet of type %s with the same DNS name already exists in zone %s",
+				set.Type, escapeName(set.Name), key.recordType, zone.Name))
+		}
+	}
+	return nil
+}
+
+// lockedApplyChange applies the change to the records, which are a copy of the zone's.
+func (r *Route53) lockedApplyChange(zone *HostedZone, records map[recordKey]*APIResourceRecordSet, change APIChange) *awserrors.Error {
+	set, awserr := validateRecordSet(zone, change.ResourceRecordSet)
+	if awserr != nil {
+		return awserr
+	}
+	key := set.key()
+	existing, exists := records[key]
+
+	switch change.Action {
+	case "CREATE", "UPSERT":
+		if exists && change.Action == "CREATE" {
+			return InvalidChangeBatch(fmt.Sprintf("Tried to create resource record set [name='%s', type='%s'] but it already exists", escapeName(set.Name), set.Type))
+		}
+		if awserr := checkConflicts(zone, records, set); awserr != nil {
+			return awserr
+		}
+		if awserr := r.lockedCheckAliasTarget(zone, records, set); awserr != nil {
+			return awserr
+		}
+		records[key] = set
+	case "DELETE":
+		if !exists {
+			return InvalidChangeBatch(fmt.Sprintf("Tried to delete resource record set [name='%s', type='%s'] but it was not found", escapeName(set.Name), set.Type))
+		}
+		if !reflect.DeepEqual(existing, set) {
+			return InvalidChangeBatch(fmt.Sprintf("Tried to delete resource record set [name='%s', type='%s'] but the values provided do not match the current values", escapeName(set.Name), set.Type))
+		}
+		if zone.isRequired(key) {
+			return InvalidChangeBatch(fmt.Sprintf("A HostedZone must contain at least one %s record for the zone itself.", set.Type))
+		}
+		delete(records, key)
+	default:
+		return InvalidInput(fmt.Sprintf("Invalid request: %s is not a valid change action.", change.Action))
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ChangeResourceRecordSets.html
+func (r *Route53) ChangeResourceRecordSets(input ChangeResourceRecordSetsInput) (*ChangeResourceRecordSetsOutput, *awserrors.Error) {
+	if len(input.ChangeBatch.Changes) == 0 {
+		return nil, InvalidInput("Invalid request: A ChangeBatch must contain at least one change.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.HostedZoneId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Changes are applied to a copy, so none of them are applied if any is invalid.
+	records := maps.Clone(zone.records)
+	for _, change := range input.ChangeBatch.Changes {
+		if awserr := r.lockedApplyChange(zone, records, change); awserr != nil {
+			return nil, awserr
+		}
+	}
+	zone.records = records
+	return &ChangeResourceRecordSetsOutput{ChangeInfo: r.lockedAddChange(input.ChangeBatch.Comment)}, nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ListResourceRecordSets.html
+func (r *Route53) ListResourceRecordSets(input ListResourceRecordSetsInput) (*ListResourceRecordSetsOutput, *awserrors.Error) {
+	maxItems, awserr := parseMaxItems(input.MaxItems, defaultMaxRecords)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.StartRecordType != "" && input.StartRecordName == "" {
+		return nil, InvalidInput("The input is not valid: a type must be specified with a name.")
+	}
+	if input.StartRecordIdentifier != "" && input.StartRecordType == "" {
+		return nil, InvalidInput("The input is not valid: an identifier must be specified with a name and type.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.HostedZoneId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	keys := make([]recordKey, 0, len(zone.records))
+	for key := range zone.records {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, compareRecords)
+	start := 0
+	if input.StartRecordName != "" {
+		startKey := recordKey{normalizeName(input.StartRecordName), input.StartRecordType, input.StartRecordIdentifier}
+		start, _ = slices.BinarySearchFunc(keys, startKey, compareRecords)
+	}
+
+	output := &ListResourceRecordSetsOutput{
+		ResourceRecordSets: []APIResourceRecordSet{},
+		MaxItems:           maxItems,
+	}
+	for _, key := range keys[start:] {
+		if len(output.ResourceRecordSets) == maxItems {
+			output.IsTruncated = true
+			output.NextRecordName = escapeName(key.name)
+			output.NextRecordType = key.recordType
+			output.NextRecordIdentifier = key.setIdentifier
+			break
+		}
+		output.ResourceRecordSets = append(output.ResourceRecordSets, zone.records[key].output())
+	}
+	return output, nil
+}
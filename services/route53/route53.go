@@ -0,0 +1,510 @@
+package route53
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/random"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultMaxZones   = 100
+	defaultMaxRecords = 300
+	// The TTLs of the SOA and NS records created with every hosted zone, like AWS's.
+	soaTTL = 900
+	nsTTL  = 172800
+)
+
+var (
+	labelRegex = regexp.MustCompile(`^(\*|[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?)$`)
+	// The name servers in every public hosted zone's delegation set. They don't resolve, but
+	// the embedded DNS server answers for every hosted zone.
+	nameServers = []string{
+		"ns-0.awsdns-00.com.",
+		"ns-512.awsdns-00.net.",
+		"ns-1024.awsdns-00.org.",
+		"ns-1536.awsdns-00.co.uk.",
+	}
+	recordTypes = []string{"A", "AAAA", "CAA", "CNAME", "DS", "MX", "NAPTR", "NS", "PTR", "SOA", "SPF", "SRV", "TXT"}
+)
+
+type HostedZone struct {
+	Id              string
+	Name            string
+	CallerReference string
+	Comment         string
+	VPC             *APIVPC
+	Tags            map[string]string
+	// Keyed by name, type and set identifier.
+	records map[recordKey]*APIResourceRecordSet
+}
+
+type recordKey struct {
+	name          string
+	recordType    string
+	setIdentifier string
+}
+
+type Route53 struct {
+	logger *slog.Logger
+	// Overridden in tests.
+	clock func() time.Time
+	// The DNS server answers for names under localhost with this IP.
+	dnsServiceIP net.IP
+	// The DNS server forwards queries for other names here, if it isn't empty.
+	dnsUpstream string
+
+	mu sync.Mutex
+	// Keyed by ID, without the /hostedzone/ prefix.
+	zones map[string]*HostedZone
+	// Keyed by ID, without the /change/ prefix.
+	changes map[string]APIChangeInfo
+}
+
+type Options struct {
+	Logger *slog.Logger
+	// The IP the DNS server answers with for names under localhost, such as bucket.s3.localhost.
+	// Defaults to 127.0.0.1.
+	DNSServiceIP net.IP
+	// The address of the DNS server to forward queries for names outside of the hosted zones to,
+	// such as 8.8.8.8:53. If empty, they're refused.
+	DNSUpstream string
+}
+
+func New(options Options) *Route53 {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.DNSServiceIP == nil {
+		options.DNSServiceIP = net.IPv4(127, 0, 0, 1)
+	}
+
+	return &Route53{
+		logger:       options.Logger,
+		clock:        clock.Now,
+		dnsServiceIP: options.DNSServiceIP,
+		dnsUpstream:  options.DNSUpstream,
+		zones:        make(map[string]*HostedZone),
+		changes:      make(map[string]APIChangeInfo),
+	}
+}
+
+// randomId returns an ID with the prefix, followed by uppercase letters and digits, like Route 53's.
+func randomId(prefix string) string {
+	return prefix + random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", 20)
+}
+
+// normalizeName returns the domain name in lowercase, with a trailing dot and without
+// octal escapes such as \052 for *.
+func normalizeName(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if n, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(name[i])
+	}
+	name = strings.ToLower(sb.String())
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// escapeName returns the domain name as Route 53 returns it, with * escaped as \052.
+func escapeName(name string) string {
+	return strings.ReplaceAll(name, "*", `\052`)
+}
+
+func validateName(name string) bool {
+	if name == "." || len(name) > 255 {
+		return false
+	}
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, label := range labels {
+		// Only the leftmost label can be a wildcard.
+		if !labelRegex.MatchString(label) || (label == "*" && i > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// inZone returns whether the name is the zone's name or a subdomain of it.
+func inZone(name string, zoneName string) bool {
+	return name == zoneName || strings.HasSuffix(name, "."+zoneName)
+}
+
+// sortKey returns the key Route 53 lists records in, which is their name with its labels reversed.
+func sortKey(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	slices.Reverse(labels)
+	return strings.Join(labels, ".")
+}
+
+func compareRecords(a, b recordKey) int {
+	if c := strings.Compare(sortKey(a.name), sortKey(b.name)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.recordType, b.recordType); c != 0 {
+		return c
+	}
+	return strings.Compare(a.setIdentifier, b.setIdentifier)
+}
+
+// parseMaxItems returns the page size, which is the default if maxItems is empty.
+func parseMaxItems(maxItems string, defaultMax int) (int, *awserrors.Error) {
+	if maxItems == "" {
+		return defaultMax, nil
+	}
+	n, err := strconv.Atoi(maxItems)
+	if err != nil || n < 1 {
+		return 0, InvalidInput("maxitems must be a positive integer.")
+	}
+	return min(n, defaultMax), nil
+}
+
+func trimZoneId(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
+
+func (z *HostedZone) output() APIHostedZone {
+	return APIHostedZone{
+		Id:              "/hostedzone/" + z.Id,
+		Name:            escapeName(z.Name),
+		CallerReference: z.CallerReference,
+		Config: APIHostedZoneConfig{
+			Comment:     z.Comment,
+			PrivateZone: z.VPC != nil,
+		},
+		ResourceRecordSetCount: int64(len(z.records)),
+	}
+}
+
+func (z *HostedZone) delegationSet() *APIDelegationSet {
+	if z.VPC != nil {
+		return nil
+	}
+	return &APIDelegationSet{NameServers: nameServers}
+}
+
+func (r *Route53) lockedGetZone(id string) (*HostedZone, *awserrors.Error) {
+	zone, ok := r.zones[trimZoneId(id)]
+	if !ok {
+		return nil, NoSuchHostedZone("No hosted zone found with ID: " + trimZoneId(id))
+	}
+	return zone, nil
+}
+
+// lockedAddChange records a change which is already in sync, since changes apply immediately.
+func (r *Route53) lockedAddChange(comment string) APIChangeInfo {
+	change := APIChangeInfo{
+		Id:          "/change/" + randomId("C"),
+		Status:      "INSYNC",
+		SubmittedAt: timestamp.ISO8601(r.clock()),
+		Comment:     comment,
+	}
+	r.changes[strings.TrimPrefix(change.Id, "/change/")] = change
+	return change
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_CreateHostedZone.html
+func (r *Route53) CreateHostedZone(input CreateHostedZoneInput) (*CreateHostedZoneOutput, *awserrors.Error) {
+	name := normalizeName(input.Name)
+	if !validateName(name) || strings.HasPrefix(name, "*") {
+		return nil, InvalidDomainName(input.Name + " is reserved by AWS!")
+	}
+	if input.CallerReference == "" {
+		return nil, InvalidInput("1 validation error detected: Value null at 'callerReference' failed to satisfy constraint: Member must not be null")
+	}
+	if input.HostedZoneConfig != nil && input.HostedZoneConfig.PrivateZone && input.VPC == nil {
+		return nil, InvalidInput("When you're creating a private hosted zone (when you specify true for PrivateZone), you must also specify values for VPCId and VPCRegion.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, zone := range r.zones {
+		if zone.CallerReference == input.CallerReference {
+			return nil, HostedZoneAlreadyExists(fmt.Sprintf("A hosted zone has already been created with the specified caller reference: %s.", input.CallerReference))
+		}
+	}
+
+	zone := &HostedZone{
+		Id:              randomId("Z"),
+		Name:            name,
+		CallerReference: input.CallerReference,
+		VPC:             input.VPC,
+		Tags:            make(map[string]string),
+		records:         make(map[recordKey]*APIResourceRecordSet),
+	}
+	if input.HostedZoneConfig != nil {
+		zone.Comment = input.HostedZoneConfig.Comment
+	}
+	soa := fmt.Sprintf("%s awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400", nameServers[0])
+	zone.records[recordKey{name, "SOA", ""}] = &APIResourceRecordSet{
+		Name:            name,
+		Type:            "SOA",
+		TTL:             ptr(int64(soaTTL)),
+		ResourceRecords: []APIResourceRecord{{Value: soa}},
+	}
+	ns := &APIResourceRecordSet{Name: name, Type: "NS", TTL: ptr(int64(nsTTL))}
+	for _, nameServer := range nameServers {
+		ns.ResourceRecords = append(ns.ResourceRecords, APIResourceRecord{Value: nameServer})
+	}
+	zone.records[recordKey{name, "NS", ""}] = ns
+	r.zones[zone.Id] = zone
+
+	output := &CreateHostedZoneOutput{
+		Location:      "https://route53.amazonaws.com/2013-04-01/hostedzone/" + zone.Id,
+		Status:        201,
+		HostedZone:    zone.output(),
+		ChangeInfo:    r.lockedAddChange(""),
+		DelegationSet: zone.delegationSet(),
+		VPC:           zone.VPC,
+	}
+	return output, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_GetHostedZone.html
+func (r *Route53) GetHostedZone(input GetHostedZoneInput) (*GetHostedZoneOutput, *awserrors.Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &GetHostedZoneOutput{
+		HostedZone:    zone.output(),
+		DelegationSet: zone.delegationSet(),
+	}
+	if zone.VPC != nil {
+		output.VPCs = []APIVPC{*zone.VPC}
+	}
+	return output, nil
+}
+
+// lockedSortedZones returns the hosted zones in order of the comparison function.
+func (r *Route53) lockedSortedZones(compare func(a, b *HostedZone) int) []*HostedZone {
+	zones := make([]*HostedZone, 0, len(r.zones))
+	for _, zone := range r.zones {
+		zones = append(zones, zone)
+	}
+	slices.SortFunc(zones, compare)
+	return zones
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ListHostedZones.html
+func (r *Route53) ListHostedZones(input ListHostedZonesInput) (*ListHostedZonesOutput, *awserrors.Error) {
+	maxItems, awserr := parseMaxItems(input.MaxItems, defaultMaxZones)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The marker is the ID of the first hosted zone on the page.
+	zones := r.lockedSortedZones(func(a, b *HostedZone) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	start := 0
+	if input.Marker != "" {
+		start, _ = slices.BinarySearchFunc(zones, trimZoneId(input.Marker), func(zone *HostedZone, id string) int {
+			return strings.Compare(zone.Id, id)
+		})
+	}
+
+	output := &ListHostedZonesOutput{
+		HostedZones: []APIHostedZone{},
+		Marker:      input.Marker,
+		MaxItems:    maxItems,
+	}
+	for _, zone := range zones[start:] {
+		if len(output.HostedZones) == maxItems {
+			output.IsTruncated = true
+			output.NextMarker = zone.Id
+			break
+		}
+		output.HostedZones = append(output.HostedZones, zone.output())
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ListHostedZonesByName.html
+func (r *Route53) ListHostedZonesByName(input ListHostedZonesByNameInput) (*ListHostedZonesByNameOutput, *awserrors.Error) {
+	maxItems, awserr := parseMaxItems(input.MaxItems, defaultMaxZones)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.HostedZoneId != "" && input.DNSName == "" {
+		return nil, InvalidInput("The DNSName must be specified if the HostedZoneId is specified.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zones := r.lockedSortedZones(func(a, b *HostedZone) int {
+		if c := strings.Compare(sortKey(a.Name), sortKey(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+	start := 0
+	if input.DNSName != "" {
+		name, id := sortKey(normalizeName(input.DNSName)), trimZoneId(input.HostedZoneId)
+		start, _ = slices.BinarySearchFunc(zones, name, func(zone *HostedZone, name string) int {
+			if c := strings.Compare(sortKey(zone.Name), name); c != 0 {
+				return c
+			}
+			return strings.Compare(zone.Id, id)
+		})
+	}
+
+	output := &ListHostedZonesByNameOutput{
+		HostedZones:  []APIHostedZone{},
+		DNSName:      input.DNSName,
+		HostedZoneId: input.HostedZoneId,
+		MaxItems:     maxItems,
+	}
+	for _, zone := range zones[start:] {
+		if len(output.HostedZones) == maxItems {
+			output.IsTruncated = true
+			output.NextDNSName = escapeName(zone.Name)
+			output.NextHostedZoneId = zone.Id
+			break
+		}
+		output.HostedZones = append(output.HostedZones, zone.output())
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_UpdateHostedZoneComment.html
+func (r *Route53) UpdateHostedZoneComment(input UpdateHostedZoneCommentInput) (*UpdateHostedZoneCommentOutput, *awserrors.Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	zone.Comment = input.Comment
+	return &UpdateHostedZoneCommentOutput{HostedZone: zone.output()}, nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_DeleteHostedZone.html
+func (r *Route53) DeleteHostedZone(input DeleteHostedZoneInput) (*DeleteHostedZoneOutput, *awserrors.Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for key := range zone.records {
+		if !zone.isRequired(key) {
+			return nil, HostedZoneNotEmpty("The specified hosted zone contains non-required resource record sets and so cannot be deleted.")
+		}
+	}
+	delete(r.zones, zone.Id)
+	return &DeleteHostedZoneOutput{ChangeInfo: r.lockedAddChange("")}, nil
+}
+
+// isRequired returns whether the record is the zone's SOA or NS record, which can't be deleted.
+func (z *HostedZone) isRequired(key recordKey) bool {
+	return key.name == z.Name && (key.recordType == "SOA" || key.recordType == "NS")
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_GetChange.html
+func (r *Route53) GetChange(input GetChangeInput) (*GetChangeOutput, *awserrors.Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := strings.TrimPrefix(input.Id, "/change/")
+	change, ok := r.changes[id]
+	if !ok {
+		return nil, NoSuchChange("A change with the specified change ID does not exist: " + id)
+	}
+	return &GetChangeOutput{ChangeInfo: change}, nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ChangeTagsForResource.html
+func (r *Route53) ChangeTagsForResource(input ChangeTagsForResourceInput) (*ChangeTagsForResourceOutput, *awserrors.Error) {
+	if input.ResourceType != "hostedzone" {
+		return nil, InvalidInput("Only hostedzone resources can be tagged.")
+	}
+	if len(input.AddTags) > 10 || len(input.RemoveTagKeys) > 10 {
+		return nil, InvalidInput("At most 10 tags can be added or removed in a request.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.ResourceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	tags := make(map[string]string, len(zone.Tags))
+	for key, value := range zone.Tags {
+		tags[key] = value
+	}
+	for _, key := range input.RemoveTagKeys {
+		delete(tags, key)
+	}
+	for _, tag := range input.AddTags {
+		tags[tag.Key] = tag.Value
+	}
+	if len(tags) > 50 {
+		return nil, InvalidInput("A resource can have at most 50 tags.")
+	}
+	zone.Tags = tags
+	return &ChangeTagsForResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ListTagsForResource.html
+func (r *Route53) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	if input.ResourceType != "hostedzone" {
+		return nil, InvalidInput("Only hostedzone resources can be tagged.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, awserr := r.lockedGetZone(input.ResourceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListTagsForResourceOutput{
+		ResourceTagSet: APIResourceTagSet{
+			ResourceType: "hostedzone",
+			ResourceId:   zone.Id,
+			Tags:         []APITag{},
+		},
+	}
+	for key, value := range zone.Tags {
+		output.ResourceTagSet.Tags = append(output.ResourceTagSet.Tags, APITag{Key: key, Value: value})
+	}
+	slices.SortFunc(output.ResourceTagSet.Tags, func(a, b APITag) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return output, nil
+}
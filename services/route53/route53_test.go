@@ -0,0 +1,213 @@
+package route53
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRoute53() *Route53 {
+	r := New(Options{})
+	r.clock = func() time.Time { return time.Unix(1700000000, 0) }
+	return r
+}
+
+func createZone(t *testing.T, r *Route53, name string) string {
+	output, awserr := r.CreateHostedZone(CreateHostedZoneInput{Name: name, CallerReference: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.HostedZone.Id
+}
+
+func change(t *testing.T, r *Route53, zoneId string, changes ...APIChange) {
+	_, awserr := r.ChangeResourceRecordSets(ChangeResourceRecordSetsInput{
+		HostedZoneId: zoneId,
+		ChangeBatch:  APIChangeBatch{Changes: changes},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func aRecord(name string, values ...string) APIResourceRecordSet {
+	set := APIResourceRecordSet{Name: name, Type: "A", TTL: ptr(int64(300))}
+	for _, value := range values {
+		set.ResourceRecords = append(set.ResourceRecords, APIResourceRecord{Value: value})
+	}
+	return set
+}
+
+func listRecords(t *testing.T, r *Route53, input ListResourceRecordSetsInput) *ListResourceRecordSetsOutput {
+	output, awserr := r.ListResourceRecordSets(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func TestHostedZones(t *testing.T) {
+	r := newRoute53()
+	id := createZone(t, r, "Example.com")
+	createZone(t, r, "example.org.")
+
+	_, awserr := r.CreateHostedZone(CreateHostedZoneInput{Name: "other.com", CallerReference: "Example.com"})
+	if awserr == nil || awserr.Body.Type != "HostedZoneAlreadyExists" {
+		t.Fatal("Expected HostedZoneAlreadyExists", awserr)
+	}
+	_, awserr = r.CreateHostedZone(CreateHostedZoneInput{Name: "bad_.-name", CallerReference: "bad"})
+	if awserr == nil || awserr.Body.Type != "InvalidDomainName" {
+		t.Fatal("Expected InvalidDomainName", awserr)
+	}
+
+	got, awserr := r.GetHostedZone(GetHostedZoneInput{Id: strings.TrimPrefix(id, "/hostedzone/")})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.HostedZone.Name != "example.com." || got.HostedZone.ResourceRecordSetCount != 2 || len(got.DelegationSet.NameServers) != 4 {
+		t.Fatalf("Unexpected hosted zone: %+v", got)
+	}
+
+	listed, awserr := r.ListHostedZonesByName(ListHostedZonesByNameInput{MaxItems: "1"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.HostedZones) != 1 || listed.HostedZones[0].Name != "example.com." || listed.NextDNSName != "example.org." {
+		t.Fatalf("Unexpected hosted zones: %+v", listed)
+	}
+
+	change(t, r, id, APIChange{Action: "CREATE", ResourceRecordSet: aRecord("www.example.com", "10.0.0.1")})
+	_, awserr = r.DeleteHostedZone(DeleteHostedZoneInput{Id: id})
+	if awserr == nil || awserr.Body.Type != "HostedZoneNotEmpty" {
+		t.Fatal("Expected HostedZoneNotEmpty", awserr)
+	}
+	change(t, r, id, APIChange{Action: "DELETE", ResourceRecordSet: aRecord("www.example.com", "10.0.0.1")})
+	deleted, awserr := r.DeleteHostedZone(DeleteHostedZoneInput{Id: id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	changeOutput, awserr := r.GetChange(GetChangeInput{Id: deleted.ChangeInfo.Id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if changeOutput.ChangeInfo.Status != "INSYNC" || changeOutput.ChangeInfo.SubmittedAt != "2023-11-14T22:13:20Z" {
+		t.Fatalf("Unexpected change: %+v", changeOutput.ChangeInfo)
+	}
+	_, awserr = r.GetHostedZone(GetHostedZoneInput{Id: id})
+	if awserr == nil || awserr.Body.Type != "NoSuchHostedZone" {
+		t.Fatal("Expected NoSuchHostedZone", awserr)
+	}
+}
+
+func TestChangeResourceRecordSets(t *testing.T) {
+	r := newRoute53()
+	id := createZone(t, r, "example.com")
+	change(t, r, id,
+		APIChange{Action: "CREATE", ResourceRecordSet: aRecord("www.example.com", "10.0.0.1")},
+		APIChange{Action: "CREATE", ResourceRecordSet: aRecord(`\052.example.com`, "10.0.0.2")},
+		APIChange{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{
+			Name:        "example.com",
+			Type:        "A",
+			AliasTarget: &APIAliasTarget{HostedZoneId: id, DNSName: "www.example.com"},
+		}},
+	)
+
+	for name, changes := range map[string][]APIChange{
+		"InvalidChangeBatch": {
+			// The first change is valid, but isn't applied because the second isn't.
+			{Action: "CREATE", ResourceRecordSet: aRecord("api.example.com", "10.0.0.3")},
+			{Action: "CREATE", ResourceRecordSet: aRecord("www.example.com", "10.0.0.1")},
+		},
+		"InvalidInput": {{Action: "CREATE", ResourceRecordSet: APIResourceRecordSet{Name: "api.example.com", Type: "A"}}},
+	} {
+		_, awserr := r.ChangeResourceRecordSets(ChangeResourceRecordSetsInput{HostedZoneId: id, ChangeBatch: APIChangeBatch{Changes: changes}})
+		if awserr == nil || awserr.Body.Type != name {
+			t.Error("Expected", name, awserr)
+		}
+	}
+	for _, set := range []APIResourceRecordSet{
+		aRecord("www.example.org", "10.0.0.1"),
+		{Name: "www.example.com", Type: "CNAME", TTL: ptr(int64(60)), ResourceRecords: []APIResourceRecord{{Value: "example.com"}}},
+		{Name: "api.example.com", Type: "A", AliasTarget: &APIAliasTarget{HostedZoneId: id, DNSName: "missing.example.com"}},
+	} {
+		_, awserr := r.ChangeResourceRecordSets(ChangeResourceRecordSetsInput{
+			HostedZoneId: id,
+			ChangeBatch:  APIChangeBatch{Changes: []APIChange{{Action: "UPSERT", ResourceRecordSet: set}}},
+		})
+		if awserr == nil || awserr.Body.Type != "InvalidChangeBatch" {
+			t.Errorf("Expected InvalidChangeBatch for %s %s: %v", set.Name, set.Type, awserr)
+		}
+	}
+	_, awserr := r.ChangeResourceRecordSets(ChangeResourceRecordSetsInput{
+		HostedZoneId: id,
+		ChangeBatch:  APIChangeBatch{Changes: []APIChange{{Action: "DELETE", ResourceRecordSet: aRecord("www.example.com", "10.0.0.9")}}},
+	})
+	if awserr == nil || !strings.Contains(awserr.Body.Message, "do not match") {
+		t.Error("Expected InvalidChangeBatch", awserr)
+	}
+
+	// Records are listed with their names' labels reversed, so the apex comes first.
+	listed := listRecords(t, r, ListResourceRecordSetsInput{HostedZoneId: id})
+	var got []string
+	for _, set := range listed.ResourceRecordSets {
+		got = append(got, set.Name+" "+set.Type)
+	}
+	want := []string{"example.com. A", "example.com. NS", "example.com. SOA", `\052.example.com. A`, "www.example.com. A"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatal("Unexpected records", got)
+	}
+	if alias := listed.ResourceRecordSets[0].AliasTarget; alias == nil || alias.DNSName != "www.example.com." {
+		t.Fatalf("Unexpected alias: %+v", alias)
+	}
+
+	listed = listRecords(t, r, ListResourceRecordSetsInput{HostedZoneId: id, StartRecordName: "example.com", StartRecordType: "SOA", MaxItems: "1"})
+	if len(listed.ResourceRecordSets) != 1 || listed.ResourceRecordSets[0].Type != "SOA" ||
+		!listed.IsTruncated || listed.NextRecordName != `\052.example.com.` || listed.NextRecordType != "A" {
+		t.Fatalf("Unexpected page: %+v", listed)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := newRoute53()
+	handler := NewHandler(slog.Default(), r, nil)
+
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<CreateHostedZoneRequest xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <Name>example.com</Name><CallerReference>ref</CallerReference>
+  <HostedZoneConfig><Comment>test zone</Comment></HostedZoneConfig>
+</CreateHostedZoneRequest>`
+	req := httptest.NewRequest(http.MethodPost, "/2013-04-01/hostedzone", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	if !handler(w, req) {
+		t.Fatal("Request wasn't handled")
+	}
+	if w.Code != http.StatusCreated || !strings.Contains(w.Header().Get("Location"), "/2013-04-01/hostedzone/Z") {
+		t.Fatal("Unexpected response", w.Code, w.Header(), w.Body.String())
+	}
+	var created CreateHostedZoneOutput
+	if err := xml.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.HostedZone.Config.Comment != "test zone" || created.ChangeInfo.Status != "INSYNC" {
+		t.Fatalf("Unexpected output: %+v", created)
+	}
+	if !strings.Contains(w.Body.String(), `<CreateHostedZoneResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">`) {
+		t.Fatal("Missing namespace", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/2013-04-01/hostedzone/ZMISSING/rrset?maxitems=10", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<Code>NoSuchHostedZone</Code>") {
+		t.Fatal("Unexpected response", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/my-bucket/key", nil)
+	if handler(httptest.NewRecorder(), req) {
+		t.Fatal("Expected S3 request to be left for the rest of the chain")
+	}
+}
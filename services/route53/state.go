@@ -0,0 +1,122 @@
+package route53
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/state"
+)
+
+type zoneState struct {
+	Id              string
+	Name            string
+	CallerReference string
+	Comment         string
+	VPC             *APIVPC
+	Tags            map[string]string
+	RecordSets      []*APIResourceRecordSet
+}
+
+type zonesState struct {
+	HostedZones []zoneState
+}
+
+// TryPause pauses changes to the hosted zones while a snapshot copies them.
+func (r *Route53) TryPause() (func(), bool) {
+	return state.TryLock(&r.mu)
+}
+
+// ExportState writes the hosted zones and their record sets to the archive.
+func (r *Route53) ExportState(w *state.Writer) error {
+	unlock := w.Lock(&r.mu)
+	defer unlock()
+
+	var exported zonesState
+	for _, zone := range r.zones {
+		z := zoneState{
+			Id:              zone.Id,
+			Name:            zone.Name,
+			CallerReference: zone.CallerReference,
+			Comment:         zone.Comment,
+			VPC:             zone.VPC,
+			Tags:            zone.Tags,
+		}
+		var keys []recordKey
+		for key := range zone.records {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, compareRecords)
+		for _, key := range keys {
+			z.RecordSets = append(z.RecordSets, zone.records[key])
+		}
+		exported.HostedZones = append(exported.HostedZones, z)
+	}
+	slices.SortFunc(exported.HostedZones, func(a, b zoneState) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return w.WriteJSON("hostedzones.json", exported)
+}
+
+// ImportState replaces the hosted zones with the archive's.
+func (r *Route53) ImportState(reader *state.Reader) (func() error, error) {
+	var imported zonesState
+	if err := reader.ReadJSON("hostedzones.json", &imported); err != nil {
+		return nil, err
+	}
+	zones := make(map[string]*HostedZone)
+	for _, z := range imported.HostedZones {
+		name := normalizeName(z.Name)
+		if z.Id == "" || !validateName(name) {
+			return nil, fmt.Errorf("invalid hosted zone %q", z.Name)
+		}
+		zone := &HostedZone{
+			Id:              z.Id,
+			Name:            name,
+			CallerReference: z.CallerReference,
+			Comment:         z.Comment,
+			VPC:             z.VPC,
+			Tags:            z.Tags,
+			records:         make(map[recordKey]*APIResourceRecordSet),
+		}
+		if zone.Tags == nil {
+			zone.Tags = make(map[string]string)
+		}
+		for _, set := range z.RecordSets {
+			valid, awserr := validateRecordSet(zone, *set)
+			if awserr != nil {
+				return nil, fmt.Errorf("hosted zone %s: %s", z.Id, awserr.Body.Message)
+			}
+			zone.records[valid.key()] = valid
+		}
+		zones[zone.Id] = zone
+	}
+
+	return func() error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.zones = zones
+		return nil
+	}, nil
+}
+
+// StateResources lists the hosted zones and record sets in the archive, for diffing archives.
+func StateResources(reader *state.Reader) (map[string]any, error) {
+	var exported zonesState
+	if err := reader.ReadJSON("hostedzones.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, zone := range exported.HostedZones {
+		for _, set := range zone.RecordSets {
+			name := "recordset/" + zone.Id + "/" + set.Name + "/" + set.Type
+			if set.SetIdentifier != "" {
+				name += "/" + set.SetIdentifier
+			}
+			resources[name] = set
+		}
+		zone.RecordSets = nil
+		resources["hostedzone/"+zone.Id] = zone
+	}
+	return resources, nil
+}
@@ -0,0 +1,218 @@
+package route53
+
+import "encoding/xml"
+
+type APIHostedZone struct {
+	Id                     string
+	Name                   string
+	CallerReference        string
+	Config                 APIHostedZoneConfig
+	ResourceRecordSetCount int64
+}
+
+type APIHostedZoneConfig struct {
+	Comment     string `xml:",omitempty" length:",256" error:"InvalidInput"`
+	PrivateZone bool
+}
+
+type APIVPC struct {
+	VPCId     string `length:",1024" error:"InvalidInput"`
+	VPCRegion string `length:"1,64" error:"InvalidInput"`
+}
+
+type APIChangeInfo struct {
+	Id          string
+	Status      string
+	SubmittedAt string
+	Comment     string `xml:",omitempty"`
+}
+
+type APIDelegationSet struct {
+	NameServers []string `xml:"NameServers>NameServer"`
+}
+
+type APIResourceRecordSet struct {
+	Name             string              `required:"true" length:",1024" error:"InvalidInput"`
+	Type             string              `required:"true" enum:"SOA|A|TXT|NS|CNAME|MX|NAPTR|PTR|SRV|SPF|AAAA|CAA|DS|TLSA|SSHFP|SVCB|HTTPS" error:"InvalidInput"`
+	SetIdentifier    string              `xml:",omitempty" length:"1,128" error:"InvalidInput"`
+	Weight           *int64              `xml:",omitempty" range:"0,255" error:"InvalidInput"`
+	Region           string              `xml:",omitempty" length:"1,64" error:"InvalidInput"`
+	Failover         string              `xml:",omitempty" enum:"PRIMARY|SECONDARY" error:"InvalidInput"`
+	MultiValueAnswer *bool               `xml:",omitempty"`
+	TTL              *int64              `xml:",omitempty" range:"0,2147483647" error:"InvalidInput"`
+	ResourceRecords  []APIResourceRecord `xml:"ResourceRecords>ResourceRecord,omitempty" length:"1," error:"InvalidInput"`
+	AliasTarget      *APIAliasTarget     `xml:",omitempty"`
+	HealthCheckId    string              `xml:",omitempty" length:",64" error:"InvalidInput"`
+}
+
+type APIResourceRecord struct {
+	Value string `required:"true" length:",4000" error:"InvalidInput"`
+}
+
+type APIAliasTarget struct {
+	HostedZoneId         string `required:"true" length:",32" error:"InvalidInput"`
+	DNSName              string `required:"true" length:",1024" error:"InvalidInput"`
+	EvaluateTargetHealth bool
+}
+
+type APIChange struct {
+	Action            string `required:"true" enum:"CREATE|DELETE|UPSERT" error:"InvalidInput"`
+	ResourceRecordSet APIResourceRecordSet
+}
+
+type APIChangeBatch struct {
+	Comment string      `length:",256" error:"InvalidInput"`
+	Changes []APIChange `xml:"Changes>Change" required:"true" length:"1," error:"InvalidInput"`
+}
+
+type APITag struct {
+	Key   string `length:",128" error:"InvalidInput"`
+	Value string `length:",256" error:"InvalidInput"`
+}
+
+type APIResourceTagSet struct {
+	ResourceType string
+	ResourceId   string
+	Tags         []APITag `xml:"Tags>Tag"`
+}
+
+type CreateHostedZoneInput struct {
+	XMLName          xml.Name `xml:"CreateHostedZoneRequest"`
+	Name             string   `required:"true" length:",1024" error:"InvalidInput"`
+	CallerReference  string   `required:"true" length:"1,128" error:"InvalidInput"`
+	HostedZoneConfig *APIHostedZoneConfig
+	VPC              *APIVPC
+	DelegationSetId  string `length:",32" error:"InvalidInput"`
+}
+
+type CreateHostedZoneOutput struct {
+	XMLName       xml.Name `xml:"CreateHostedZoneResponse"`
+	Location      string   `xml:"-" rest:"header:Location"`
+	Status        int      `xml:"-" rest:"status"`
+	HostedZone    APIHostedZone
+	ChangeInfo    APIChangeInfo
+	DelegationSet *APIDelegationSet `xml:",omitempty"`
+	VPC           *APIVPC           `xml:",omitempty"`
+}
+
+type GetHostedZoneInput struct {
+	Id string `xml:"-" rest:"path:Id" required:"true" length:",32" error:"InvalidInput"`
+}
+
+type GetHostedZoneOutput struct {
+	XMLName       xml.Name `xml:"GetHostedZoneResponse"`
+	HostedZone    APIHostedZone
+	DelegationSet *APIDelegationSet `xml:",omitempty"`
+	VPCs          []APIVPC          `xml:"VPCs>VPC,omitempty"`
+}
+
+type ListHostedZonesInput struct {
+	Marker   string `xml:"-" rest:"query:marker"`
+	MaxItems string `xml:"-" rest:"query:maxitems"`
+}
+
+type ListHostedZonesOutput struct {
+	XMLName     xml.Name        `xml:"ListHostedZonesResponse"`
+	HostedZones []APIHostedZone `xml:"HostedZones>HostedZone"`
+	Marker      string          `xml:",omitempty"`
+	IsTruncated bool
+	NextMarker  string `xml:",omitempty"`
+	MaxItems    int
+}
+
+type ListHostedZonesByNameInput struct {
+	DNSName      string `xml:"-" rest:"query:dnsname" length:",1024" error:"InvalidInput"`
+	HostedZoneId string `xml:"-" rest:"query:hostedzoneid" length:",32" error:"InvalidInput"`
+	MaxItems     string `xml:"-" rest:"query:maxitems"`
+}
+
+type ListHostedZonesByNameOutput struct {
+	XMLName          xml.Name        `xml:"ListHostedZonesByNameResponse"`
+	HostedZones      []APIHostedZone `xml:"HostedZones>HostedZone"`
+	DNSName          string          `xml:",omitempty"`
+	HostedZoneId     string          `xml:",omitempty"`
+	IsTruncated      bool
+	NextDNSName      string `xml:",omitempty"`
+	NextHostedZoneId string `xml:",omitempty"`
+	MaxItems         int
+}
+
+type UpdateHostedZoneCommentInput struct {
+	XMLName xml.Name `xml:"UpdateHostedZoneCommentRequest"`
+	Id      string   `xml:"-" rest:"path:Id" required:"true" length:",32" error:"InvalidInput"`
+	Comment string   `length:",256" error:"InvalidInput"`
+}
+
+type UpdateHostedZoneCommentOutput struct {
+	XMLName    xml.Name `xml:"UpdateHostedZoneCommentResponse"`
+	HostedZone APIHostedZone
+}
+
+type DeleteHostedZoneInput struct {
+	Id string `xml:"-" rest:"path:Id" required:"true" length:",32" error:"InvalidInput"`
+}
+
+type DeleteHostedZoneOutput struct {
+	XMLName    xml.Name `xml:"DeleteHostedZoneResponse"`
+	ChangeInfo APIChangeInfo
+}
+
+type ChangeResourceRecordSetsInput struct {
+	XMLName      xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+	HostedZoneId string   `xml:"-" rest:"path:Id" required:"true" length:",32" error:"InvalidInput"`
+	ChangeBatch  APIChangeBatch
+}
+
+type ChangeResourceRecordSetsOutput struct {
+	XMLName    xml.Name `xml:"ChangeResourceRecordSetsResponse"`
+	ChangeInfo APIChangeInfo
+}
+
+type ListResourceRecordSetsInput struct {
+	HostedZoneId          string `xml:"-" rest:"path:Id" required:"true" length:",32" error:"InvalidInput"`
+	StartRecordName       string `xml:"-" rest:"query:name" length:",1024" error:"InvalidInput"`
+	StartRecordType       string `xml:"-" rest:"query:type" enum:"SOA|A|TXT|NS|CNAME|MX|NAPTR|PTR|SRV|SPF|AAAA|CAA|DS|TLSA|SSHFP|SVCB|HTTPS" error:"InvalidInput"`
+	StartRecordIdentifier string `xml:"-" rest:"query:identifier" length:"1,128" error:"InvalidInput"`
+	MaxItems              string `xml:"-" rest:"query:maxitems"`
+}
+
+type ListResourceRecordSetsOutput struct {
+	XMLName              xml.Name               `xml:"ListResourceRecordSetsResponse"`
+	ResourceRecordSets   []APIResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	IsTruncated          bool
+	NextRecordName       string `xml:",omitempty"`
+	NextRecordType       string `xml:",omitempty"`
+	NextRecordIdentifier string `xml:",omitempty"`
+	MaxItems             int
+}
+
+type GetChangeInput struct {
+	Id string `xml:"-" rest:"path:Id" required:"true" length:",6500" error:"InvalidInput"`
+}
+
+type GetChangeOutput struct {
+	XMLName    xml.Name `xml:"GetChangeResponse"`
+	ChangeInfo APIChangeInfo
+}
+
+type ChangeTagsForResourceInput struct {
+	XMLName       xml.Name `xml:"ChangeTagsForResourceRequest"`
+	ResourceType  string   `xml:"-" rest:"path:ResourceType" required:"true" enum:"healthcheck|hostedzone" error:"InvalidInput"`
+	ResourceId    string   `xml:"-" rest:"path:ResourceId" required:"true" length:",64" error:"InvalidInput"`
+	AddTags       []APITag `xml:"AddTags>Tag" length:"1,10" error:"InvalidInput"`
+	RemoveTagKeys []string `xml:"RemoveTagKeys>Key" length:"1,10" error:"InvalidInput"`
+}
+
+type ChangeTagsForResourceOutput struct {
+	XMLName xml.Name `xml:"ChangeTagsForResourceResponse"`
+}
+
+type ListTagsForResourceInput struct {
+	ResourceType string `xml:"-" rest:"path:ResourceType" required:"true" enum:"healthcheck|hostedzone" error:"InvalidInput"`
+	ResourceId   string `xml:"-" rest:"path:ResourceId" required:"true" length:",64" error:"InvalidInput"`
+}
+
+type ListTagsForResourceOutput struct {
+	XMLName        xml.Name `xml:"ListTagsForResourceResponse"`
+	ResourceTagSet APIResourceTagSet
+}
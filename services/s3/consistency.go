@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"aws-in-a-box/clock"
+)
+
+// pendingListing is a write to an object which listings don't show yet.
+type pendingListing struct {
+	// nil if the object didn't exist.
+	previous  *Object
+	visibleAt time.Time
+}
+
+// lockedSetObject writes the object, or deletes it if it's nil. Listings keep showing the object
+// from before the write until the latest of the writes to its key since then is visible.
+func (s *S3) lockedSetObject(b *Bucket, key string, object *Object) error {
+	if s.eventualConsistencyDelay > 0 {
+		now := clock.Now()
+		p, ok := b.pendingListings[key]
+		if !ok || !now.Before(p.visibleAt) {
+			previous, _, err := b.objects.Get(key)
+			if err != nil {
+				return err
+			}
+			if b.pendingListings == nil {
+				b.pendingListings = make(map[string]*pendingListing)
+			}
+			p = &pendingListing{previous: previous}
+			b.pendingListings[key] = p
+		}
+		p.visibleAt = now.Add(s.eventualConsistencyDelay)
+	}
+
+	if object == nil {
+		return b.objects.Delete(key)
+	}
+	return b.objects.Put(key, object)
+}
+
+// lockedScanListedObjects calls f with each of the bucket's objects whose key isn't before start,
+// as listings see them, in key order, until f returns false.
+func (s *S3) lockedScanListedObjects(b *Bucket, start string, f func(key string, object *Object) bool) error {
+	now := clock.Now()
+	maps.DeleteFunc(b.pendingListings, func(_ string, p *pendingListing) bool {
+		return !now.Before(p.visibleAt)
+	})
+	var pending []string
+	for key := range b.pendingListings {
+		if key >= start {
+			pending = append(pending, key)
+		}
+	}
+	slices.Sort(pending)
+
+	// The objects from before pending writes are merged into the stored ones, in place of any
+	// stored with the same key.
+	stopped := false
+	listPending := func(all bool, before string) {
+		for !stopped && len(pending) > 0 && (all || pending[0] < before) {
+			key := pending[0]
+			pending = pending[1:]
+			if previous := b.pendingListings[key].previous; previous != nil {
+				stopped = !f(key, previous)
+			}
+		}
+	}
+	err := b.objects.Scan(start, func(key string, object *Object) bool {
+		listPending(false, key)
+		if _, ok := b.pendingListings[key]; !ok && !stopped {
+			stopped = !f(key, object)
+		}
+		return !stopped
+	})
+	if err != nil {
+		return err
+	}
+	listPending(true, "")
+	return nil
+}
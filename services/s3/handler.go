@@ -1,266 +1,120 @@
 package s3
 
 import (
-	"encoding/xml"
-	"io"
 	"log/slog"
+	"mime"
 	"net/http"
-	"reflect"
-	"strconv"
 	"strings"
 
-	"aws-in-a-box/awserrors"
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/calls"
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/endpoint"
+	"aws-in-a-box/http/restxml"
 )
 
-func NewHandler(logger *slog.Logger, s3 *S3) func(w http.ResponseWriter, r *http.Request) bool {
-	return func(w http.ResponseWriter, r *http.Request) bool {
-		logger.Info("Handling S3 request", "method", r.Method, "url", r.URL)
-		path := strings.Trim(r.URL.Path, "/")
-		parts := strings.SplitN(path, "/", 2)
+// S3 only supports the REST-XML protocol.
+var restXMLProtocol = restxml.Protocol{
+	XMLNamespace:    "http://s3.amazonaws.com/doc/2006-03-01/",
+	NoErrorWrapping: true,
+}
 
-		if len(parts) == 1 {
-			if r.URL.Query().Has("tagging") {
-				switch r.Method {
-				case http.MethodGet:
-					handle(w, r, logger.With("method", "GetBucketTagging"), s3.GetBucketTagging)
-				case http.MethodPut:
-					handle(w, r, logger.With("method", "PutBucketTagging"), s3.PutBucketTagging)
-				case http.MethodDelete:
-					handle(w, r, logger.With("method", "DeleteBucketTagging"), s3.DeleteBucketTagging)
-				default:
-					panic("Unhandled method")
-				}
-				return true
-			} else if r.URL.Query().Has("delete") {
-				switch r.Method {
-				case http.MethodPost:
-					handle(w, r, logger.With("method", "DeleteObjects"), s3.DeleteObjects)
-				default:
-					panic("Unhandled method")
-				}
-				return true
-			} else if r.URL.Query().Get("list-type") == "2" {
-				switch r.Method {
-				case http.MethodGet:
-					handle(w, r, logger.With("method", "ListObjectsV2"), s3.ListObjectsV2)
-				default:
-					panic("Unhandled method")
-				}
-				return true
-			}
-			switch r.Method {
-			case http.MethodPut:
-				handle(w, r, logger.With("method", "CreateBucket"), s3.CreateBucket)
-			case http.MethodDelete:
-				handle(w, r, logger.With("method", "DeleteBucket"), s3.DeleteBucket)
-			case http.MethodHead:
-				handle(w, r, logger.With("method", "HeadBucket"), s3.HeadBucket)
-			// https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
-			case http.MethodPost:
-				err := r.ParseMultipartForm(10 * 1024 * 1024)
-				if err != nil {
-					panic(err)
-				}
-				f, err := r.MultipartForm.File["file"][0].Open()
-				if err != nil {
-					panic(err)
-				}
-				input := PutObjectInput{
-					Bucket:               parts[0],
-					Key:                  r.Form.Get("key"),
-					ServerSideEncryption: r.Form.Get("x-amz-server-side-encryption"),
-					ContentType:          r.Form.Get("Content-Type"),
-					Data:                 f,
-				}
-				logger.Debug("Parsed input", "method", "PutObject", "input", input)
-				output, awserr := s3.PutObject(input)
-				logger.Debug("Got output", "method", "PutObject", "output", output, "error", awserr)
-				marshal(w, output, awserr)
-			default:
-				panic("Unhandled method")
-			}
+func NewHandler(logger *slog.Logger, s3 *S3, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	const bucketPath = "/{Bucket}"
+	const objectPath = "/{Bucket}/{Key+}"
+
+	registry := restxml.NewRegistry(restXMLProtocol)
+	restxml.Register(logger, registry, http.MethodPut, bucketPath, "CreateBucket", s3.CreateBucket)
+	restxml.Register(logger, registry, http.MethodDelete, bucketPath, "DeleteBucket", s3.DeleteBucket)
+	restxml.Register(logger, registry, http.MethodHead, bucketPath, "HeadBucket", s3.HeadBucket)
+	restxml.Register(logger, registry, http.MethodGet, bucketPath+"?tagging", "GetBucketTagging", s3.GetBucketTagging)
+	restxml.Register(logger, registry, http.MethodPut, bucketPath+"?tagging", "PutBucketTagging", s3.PutBucketTagging)
+	restxml.Register(logger, registry, http.MethodDelete, bucketPath+"?tagging", "DeleteBucketTagging", s3.DeleteBucketTagging)
+	restxml.Register(logger, registry, http.MethodPost, bucketPath+"?delete", "DeleteObjects", s3.DeleteObjects)
+	restxml.Register(logger, registry, http.MethodGet, bucketPath+"?list-type=2", "ListObjectsV2", s3.ListObjectsV2)
+	restxml.Register(logger, registry, http.MethodGet, objectPath, "GetObject", s3.GetObject)
+	restxml.Register(logger, registry, http.MethodHead, objectPath, "HeadObject", s3.HeadObject)
+	restxml.Register(logger, registry, http.MethodPut, objectPath, "PutObject", s3.PutObject)
+	restxml.Register(logger, registry, http.MethodDelete, objectPath, "DeleteObject", s3.DeleteObject)
+	restxml.Register(logger, registry, http.MethodGet, objectPath+"?tagging", "GetObjectTagging", s3.GetObjectTagging)
+	restxml.Register(logger, registry, http.MethodPut, objectPath+"?tagging", "PutObjectTagging", s3.PutObjectTagging)
+	restxml.Register(logger, registry, http.MethodDelete, objectPath+"?tagging", "DeleteObjectTagging", s3.DeleteObjectTagging)
+	restxml.Register(logger, registry, http.MethodPost, objectPath+"?uploads", "CreateMultipartUpload", s3.CreateMultipartUpload)
+	restxml.Register(logger, registry, http.MethodPut, objectPath+"?uploadId", "UploadPart", s3.UploadPart)
+	restxml.Register(logger, registry, http.MethodPost, objectPath+"?uploadId", "CompleteMultipartUpload", s3.CompleteMultipartUpload)
+	restxml.Register(logger, registry, http.MethodDelete, objectPath+"?uploadId", "AbortMultipartUpload", s3.AbortMultipartUpload)
+	restxml.Register(logger, registry, http.MethodGet, objectPath+"?uploadId", "ListParts", s3.ListParts)
+	capabilityRegistry.Add("s3", registry.Operations()...)
+	handler := restxml.NewHandler(registry)
+
+	// CopyObject only differs from PutObject by its x-amz-copy-source header.
+	copyRegistry := restxml.NewRegistry(restXMLProtocol)
+	restxml.Register(logger, copyRegistry, http.MethodPut, objectPath, "CopyObject", s3.CopyObject)
+	capabilityRegistry.Add("s3", copyRegistry.Operations()...)
+	capabilityRegistry.Add("s3", "PostObject")
+	copyHandler := restxml.NewHandler(copyRegistry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		r = withVirtualHostBucket(r)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if r.Method == http.MethodPost && mediaType == "multipart/form-data" {
+			postObject(logger.With("method", "PostObject"), s3, w, r)
+			return true
 		}
-		if len(parts) == 2 {
-			if r.URL.Query().Has("tagging") {
-				switch r.Method {
-				case http.MethodGet:
-					handle(w, r, logger.With("method", "GetObjectTagging"), s3.GetObjectTagging)
-				case http.MethodPut:
-					handle(w, r, logger.With("method", "PutObjectTagging"), s3.PutObjectTagging)
-				case http.MethodDelete:
-					handle(w, r, logger.With("method", "DeleteObjectTagging"), s3.DeleteObjectTagging)
-				default:
-					panic("Unhandled method")
-				}
-				return true
-			} else if r.URL.Query().Has("uploads") {
-				switch r.Method {
-				case http.MethodPost:
-					handle(w, r, logger.With("method", "CreateMultipartUpload"), s3.CreateMultipartUpload)
-				default:
-					panic("Unhandled method")
-				}
-				return true
-			} else if r.URL.Query().Has("uploadId") {
-				switch r.Method {
-				case http.MethodPut:
-					handle(w, r, logger.With("method", "UploadPart"), s3.UploadPart)
-				case http.MethodPost:
-					handle(w, r, logger.With("method", "CompleteMultipartUpload"), s3.CompleteMultipartUpload)
-				case http.MethodDelete:
-					handle(w, r, logger.With("method", "AbortMultipartUpload"), s3.AbortMultipartUpload)
-				case http.MethodGet:
-					handle(w, r, logger.With("method", "ListParts"), s3.ListParts)
-				default:
-					panic("Unhandled method")
-				}
-				return true
-			}
-			switch r.Method {
-			case http.MethodGet:
-				handle(w, r, logger.With("method", "GetObject"), s3.GetObject)
-			case http.MethodHead:
-				handle(w, r, logger.With("method", "HeadObject"), s3.HeadObject)
-			case http.MethodPut:
-				if r.Header.Get("x-amz-copy-source") != "" {
-					handle(w, r, logger.With("method", "CopyObject"), s3.CopyObject)
-				} else {
-					handle(w, r, logger.With("method", "PutObject"), s3.PutObject)
-				}
-			case http.MethodDelete:
-				handle(w, r, logger.With("method", "DeleteObject"), s3.DeleteObject)
-			default:
-				panic("Unhandled method")
-			}
+		if r.Header.Get("x-amz-copy-source") != "" && copyHandler(w, r) {
+			return true
 		}
-		return true
+		return handler(w, r)
 	}
 }
 
-func handle[Input any, Output any](
-	w http.ResponseWriter,
-	r *http.Request,
-	logger *slog.Logger,
-	handler func(input Input) (*Output, *awserrors.Error),
-) {
-	var input Input
-	err := unmarshal(r, &input)
-	if err != nil {
-		logger.Error("Unmarshaling input", "err", err)
-		panic(err)
+// withVirtualHostBucket returns the request with the bucket in its path, for virtual-hosted-style
+// requests, which have it in their hostname, such as bucket.s3.us-east-1.amazonaws.com.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/VirtualHosting.html
+func withVirtualHostBucket(r *http.Request) *http.Request {
+	e, ok := endpoint.FromRequest(r)
+	if !ok || e.Bucket == "" {
+		return r
 	}
-	logger.Debug("Parsed input", "input", input)
-
-	output, awserr := handler(input)
-	logger.Debug("Got output", "output", output, "error", awserr)
-
-	marshal(w, output, awserr)
+	u := *r.URL
+	u.Path = "/" + e.Bucket + u.Path
+	if u.RawPath != "" {
+		u.RawPath = "/" + e.Bucket + u.RawPath
+	}
+	r = r.WithContext(r.Context())
+	r.URL = &u
+	return r
 }
 
-func unmarshal(r *http.Request, target any) error {
-	path := strings.Trim(r.URL.Path, "/")
-	parts := strings.SplitN(path, "/", 2)
-
-	v := reflect.ValueOf(target).Elem()
-	ty := v.Type()
-
-	if _, ok := ty.FieldByName("XMLName"); ok {
-		err := xml.NewDecoder(r.Body).Decode(target)
-		if err != nil && err != io.EOF {
-			return err
-		}
+// postObject handles uploads from browsers' HTML forms, which are multipart forms rather than REST-XML.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
+func postObject(logger *slog.Logger, s3 *S3, w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(10 * 1024 * 1024)
+	if err != nil {
+		panic(err)
 	}
-
-	for i := 0; i < ty.NumField(); i++ {
-		field := ty.Field(i)
-		tag := field.Tag.Get("s3")
-		if tag == "" {
-			continue
-		}
-
-		f := v.Field(i)
-		if tag == "bucket" {
-			f.Set(reflect.ValueOf(parts[0]))
-		} else if tag == "key" {
-			f.Set(reflect.ValueOf(parts[1]))
-		} else if tag == "body" {
-			f.Set(reflect.ValueOf(r.Body))
-		} else if q, ok := strings.CutPrefix(tag, "query:"); ok {
-			v := r.URL.Query().Get(q)
-
-			isPointer := false
-			kind := field.Type.Kind()
-			if kind == reflect.Pointer {
-				if v == "" {
-					continue
-				}
-				isPointer = true
-				kind = field.Type.Elem().Kind()
-			}
-
-			if kind == reflect.Int {
-				ival, err := strconv.Atoi(v)
-				if err != nil {
-					panic(err)
-				}
-				if isPointer {
-					f.Set(reflect.ValueOf(&ival))
-				} else {
-					f.Set(reflect.ValueOf(ival))
-				}
-			} else {
-				if isPointer {
-					f.Set(reflect.ValueOf(&v))
-				} else {
-					f.Set(reflect.ValueOf(v))
-				}
-			}
-		} else if h, ok := strings.CutPrefix(tag, "header:"); ok {
-			f.Set(reflect.ValueOf(r.Header.Get(h)))
-		}
+	f, err := r.MultipartForm.File["file"][0].Open()
+	if err != nil {
+		panic(err)
 	}
-	return nil
-}
+	input := PutObjectInput{
+		Bucket:               strings.Trim(r.URL.Path, "/"),
+		Key:                  r.Form.Get("key"),
+		ServerSideEncryption: r.Form.Get("x-amz-server-side-encryption"),
+		ContentType:          r.Form.Get("Content-Type"),
+		Data:                 f,
+	}
+	logger.Debug("Parsed input", "input", input)
+	output, awserr := s3.PutObject(input)
+	logger.Debug("Got output", "output", output, "error", awserr)
+	calls.Record(r, "PostObject", awserr)
 
-func marshal(w http.ResponseWriter, output any, awserr *awserrors.Error) {
-	var body io.Reader
+	requestId := uuid.Must(uuid.NewV4()).String()
+	w.Header().Set("x-amzn-RequestId", requestId)
 	if awserr != nil {
-		w.WriteHeader(awserr.Code)
-		w.Write([]byte(awserr.Body.Message))
+		restXMLProtocol.MarshalError(w, awserr, requestId)
 	} else {
-		v := reflect.ValueOf(output).Elem()
-		ty := v.Type()
-		for i := 0; i < ty.NumField(); i++ {
-			tag := ty.Field(i).Tag.Get("s3")
-			if tag == "body" {
-				reflect.ValueOf(&body).Elem().Set(v.Field(i))
-			} else if h, ok := strings.CutPrefix(tag, "header:"); ok {
-				field := ty.Field(i)
-				switch field.Type.Kind() {
-				case reflect.Int, reflect.Int64:
-					w.Header().Set(h, strconv.Itoa(int(v.Field(i).Int())))
-				default:
-					w.Header().Set(h, v.Field(i).String())
-				}
-			}
-		}
-
-		if output == response204 {
-			w.WriteHeader(http.StatusNoContent)
-		} else {
-			w.WriteHeader(http.StatusOK)
-		}
-
-		if body != nil {
-			_, err := io.Copy(w, body)
-			if err != nil {
-				panic(err)
-			}
-		} else if _, ok := ty.FieldByName("XMLName"); ok {
-			err := xml.NewEncoder(w).Encode(output)
-			if err != nil {
-				panic(err)
-			}
-		}
+		restXMLProtocol.Marshal(w, output)
 	}
 }
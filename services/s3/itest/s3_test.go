@@ -7,33 +7,53 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 
+	"aws-in-a-box/clock"
 	"aws-in-a-box/server"
 	s3Impl "aws-in-a-box/services/s3"
+	"aws-in-a-box/storage"
 )
 
 var bucket = "test-bucket"
 
 func makeClientServerPair() (*s3.Client, *http.Server) {
+	return makeClientServerPairWithOptions(s3Impl.Options{})
+}
+
+func makeClientServerPairWithOptions(options s3Impl.Options) (*s3.Client, *http.Server) {
+	client, srv := makeClientServer(options)
+	_, err := client.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket: &bucket,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return client, srv
+}
+
+// makeClientServer is makeClientServerPairWithOptions without creating the bucket, for servers
+// which restore it from storage.
+func makeClientServer(options s3Impl.Options) (*s3.Client, *http.Server) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		panic(err)
 	}
-	impl, err := s3Impl.New(s3Impl.Options{
-		Addr: listener.Addr().String(),
-	})
+	options.Addr = listener.Addr().String()
+	impl, err := s3Impl.New(options)
 	if err != nil {
 		panic(err)
 	}
-	srv := server.NewWithHandlerChain(s3Impl.NewHandler(slog.Default(), impl))
+	srv := server.NewWithHandlerChain(s3Impl.NewHandler(slog.Default(), impl, nil))
 	go srv.Serve(listener)
 
 	client := s3.New(s3.Options{
@@ -42,13 +62,6 @@ func makeClientServerPair() (*s3.Client, *http.Server) {
 		UsePathStyle: true,
 		Retryer:      aws.NopRetryer{},
 	})
-	_, err = client.CreateBucket(context.Background(), &s3.CreateBucketInput{
-		Bucket: &bucket,
-	})
-	if err != nil {
-		panic(err)
-	}
-
 	return client, srv
 }
 func TestMultipartUpload(t *testing.T) {
@@ -74,7 +87,7 @@ func TestMultipartUpload(t *testing.T) {
 	var parts []types.CompletedPart
 	for i, s := range []string{"hello", " world"} {
 		output, err := client.UploadPart(ctx, &s3.UploadPartInput{
-			PartNumber: int32(i),
+			PartNumber: int32(i + 1),
 			Bucket:     &bucket,
 			Key:        &key,
 			UploadId:   id,
@@ -91,7 +104,7 @@ func TestMultipartUpload(t *testing.T) {
 		}
 		parts = append(parts, types.CompletedPart{
 			ETag:       output.ETag,
-			PartNumber: int32(i),
+			PartNumber: int32(i + 1),
 		})
 	}
 
@@ -146,7 +159,7 @@ func TestMultipartUpload(t *testing.T) {
 	if !reflect.DeepEqual(partsOutput.Parts, []types.Part{{
 		ETag:       parts[0].ETag,
 		Size:       5,
-		PartNumber: 0,
+		PartNumber: 1,
 	}}) {
 		t.Fatal("wrong parts", partsOutput.Parts)
 	}
@@ -169,7 +182,7 @@ func TestMultipartUpload(t *testing.T) {
 	if !reflect.DeepEqual(partsOutput.Parts, []types.Part{{
 		ETag:       parts[1].ETag,
 		Size:       6,
-		PartNumber: 1,
+		PartNumber: 2,
 	}}) {
 		t.Fatal("wrong parts", partsOutput.Parts)
 	}
@@ -199,7 +212,7 @@ func TestRangeQuery(t *testing.T) {
 	var parts []types.CompletedPart
 	for i, s := range []string{"hello", " world ", "hi"} {
 		output, err := client.UploadPart(ctx, &s3.UploadPartInput{
-			PartNumber: int32(i),
+			PartNumber: int32(i + 1),
 			Bucket:     &bucket,
 			Key:        &key,
 			UploadId:   id,
@@ -210,7 +223,7 @@ func TestRangeQuery(t *testing.T) {
 		}
 		parts = append(parts, types.CompletedPart{
 			ETag:       output.ETag,
-			PartNumber: int32(i),
+			PartNumber: int32(i + 1),
 		})
 	}
 
@@ -559,3 +572,146 @@ func TestListObjectsV2(t *testing.T) {
 	}
 
 }
+
+func TestListObjectsV2EventualConsistency(t *testing.T) {
+	ctx := context.Background()
+	client, srv := makeClientServerPairWithOptions(s3Impl.Options{EventualConsistencyDelay: time.Minute})
+	defer srv.Shutdown(ctx)
+
+	listedKeys := func() []string {
+		resp, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, content := range resp.Contents {
+			keys = append(keys, *content.Key)
+		}
+		return keys
+	}
+
+	for _, key := range []string{"a", "b"} {
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: &bucket, Key: aws.String(key), Body: strings.NewReader(key)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys := listedKeys(); len(keys) != 0 {
+		t.Fatal("Expected new objects not to be listed yet", keys)
+	}
+	// Reading the object itself is consistent.
+	if _, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: aws.String("a")}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Minute)
+	if keys := listedKeys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatal("Expected the objects to be listed", keys)
+	}
+
+	if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &bucket, Key: aws.String("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if keys := listedKeys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatal("Expected the deleted object to still be listed", keys)
+	}
+	clock.Advance(time.Minute)
+	if keys := listedKeys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatal("Expected the deletion to be listed", keys)
+	}
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	for _, kind := range []string{"bolt", "sqlite"} {
+		t.Run(kind, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "storage."+kind)
+			backend, err := storage.Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options := s3Impl.Options{PersistDir: dir, Storage: backend}
+			client, srv := makeClientServerPairWithOptions(options)
+			for key, body := range map[string]string{"a/1": "one", "a/2": "two", "b": "three"} {
+				_, err := client.PutObject(ctx, &s3.PutObjectInput{
+					Bucket: &bucket,
+					Key:    aws.String(key),
+					Body:   strings.NewReader(body),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			tag := types.Tag{Key: aws.String("key"), Value: aws.String("value")}
+			_, err = client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+				Bucket:  &bucket,
+				Key:     aws.String("a/1"),
+				Tagging: &types.Tagging{TagSet: []types.Tag{tag}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+				Bucket:  &bucket,
+				Tagging: &types.Tagging{TagSet: []types.Tag{tag}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &bucket, Key: aws.String("b")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.Shutdown(ctx)
+			if err := backend.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			options.Storage, err = storage.Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer options.Storage.Close()
+			client, srv = makeClientServer(options)
+			defer srv.Shutdown(ctx)
+
+			resp, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, content := range resp.Contents {
+				keys = append(keys, *content.Key)
+			}
+			if !reflect.DeepEqual(keys, []string{"a/1", "a/2"}) {
+				t.Fatal("Wrong keys after restarting", keys)
+			}
+			object, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: aws.String("a/2")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(object.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != "two" {
+				t.Fatal("Wrong body after restarting", string(body))
+			}
+			objectTagging, err := client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{Bucket: &bucket, Key: aws.String("a/1")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(objectTagging.TagSet, []types.Tag{tag}) {
+				t.Fatal("Wrong object tags after restarting", objectTagging.TagSet)
+			}
+			bucketTagging, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: &bucket})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(bucketTagging.TagSet, []types.Tag{tag}) {
+				t.Fatal("Wrong bucket tags after restarting", bucketTagging.TagSet)
+			}
+		})
+	}
+}
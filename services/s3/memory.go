@@ -0,0 +1,23 @@
+package s3
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each bucket's objects. Their contents are kept on disk, so only
+// their metadata takes up memory.
+func (s *S3) MemoryUsage() []memory.Resource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resources []memory.Resource
+	for name, bucket := range s.buckets {
+		resource := memory.Resource{Name: name, Count: bucket.objects.Len()}
+		bucket.objects.Scan("", func(_ string, object *Object) bool {
+			resource.Bytes += object.ContentLength
+			return true
+		})
+		resources = append(resources, resource)
+	}
+	return resources
+}
@@ -11,7 +11,6 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,6 +20,10 @@ import (
 
 	"aws-in-a-box/atomicfile"
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/services/cloudwatch"
+	"aws-in-a-box/state"
+	"aws-in-a-box/storage"
 )
 
 type Object struct {
@@ -41,10 +44,17 @@ type Object struct {
 }
 
 type Bucket struct {
-	objects map[string]*Object
+	// Gets return copies if storage isn't in memory, so changed objects are put back.
+	objects *storage.Map[*Object]
 	TagSet  TagSet
+	// Writes which listings don't show yet, by key.
+	pendingListings map[string]*pendingListing
 }
 
+// persistVersion is the version of the layout of the objects' contents in -persistDir. Bump it, and
+// add a state.DirMigration from the previous version, whenever the layout changes.
+const persistVersion = 1
+
 type UploadStatus int
 
 const (
@@ -74,22 +84,42 @@ type S3 struct {
 	// We need the address to generate location URLs.
 	addr       string
 	persistDir string
+	metrics    *cloudwatch.CloudWatch
+	// How long listings don't show writes for.
+	eventualConsistencyDelay time.Duration
+	// Where the buckets' objects are kept.
+	storage storage.Backend
 
 	mu               sync.Mutex
 	buckets          map[string]*Bucket
 	multipartUploads map[string]*multipartUpload
+	// The buckets as they're kept in storage, so they can be restored, by name.
+	storedBuckets *storage.Map[storedBucket]
 }
 
 type Options struct {
 	Logger     *slog.Logger
 	Addr       string
 	PersistDir string
+	// Buckets' storage metrics are published to this CloudWatch, if any, every StorageMetricsInterval.
+	Metrics                *cloudwatch.CloudWatch
+	StorageMetricsInterval time.Duration
+	// New, replaced and deleted objects only show up in ListObjectsV2 this long after they're
+	// written, like before S3 was strongly consistent, so code which lists its own writes straight
+	// away fails. Reads of the objects themselves aren't delayed. 0 makes listings show every write.
+	EventualConsistencyDelay time.Duration
+	// Where to keep the objects, though not their data, which is in PersistDir, or in memory if nil.
+	// Buckets kept in a file are restored from it.
+	Storage storage.Backend
 }
 
 func New(options Options) (*S3, error) {
 	if options.Logger == nil {
 		options.Logger = slog.Default()
 	}
+	if options.Storage == nil {
+		options.Storage = storage.NewMemory()
+	}
 
 	if options.PersistDir == "" {
 		var err error
@@ -98,20 +128,76 @@ func New(options Options) (*S3, error) {
 			return nil, err
 		}
 	} else {
-		options.PersistDir = filepath.Join(options.PersistDir, "s3", "cas")
+		dir := filepath.Join(options.PersistDir, "s3")
+		options.PersistDir = filepath.Join(dir, "cas")
 		err := os.MkdirAll(options.PersistDir, 0700)
 		if err != nil {
 			return nil, err
 		}
+		if err := state.MigrateDir(dir, persistVersion, nil); err != nil {
+			return nil, err
+		}
 	}
 
-	return &S3{
+	storedBuckets, err := storage.OpenMap[storedBucket](options.Storage, storedBucketsName)
+	if err != nil {
+		return nil, err
+	}
+	s := &S3{
 		logger:           options.Logger,
 		addr:             options.Addr,
 		persistDir:       options.PersistDir,
+		metrics:          options.Metrics,
+		storage:          options.Storage,
 		buckets:          make(map[string]*Bucket),
 		multipartUploads: make(map[string]*multipartUpload),
-	}, nil
+		storedBuckets:    storedBuckets,
+
+		eventualConsistencyDelay: options.EventualConsistencyDelay,
+	}
+	if err := s.restore(); err != nil {
+		return nil, err
+	}
+	if options.Metrics != nil && options.StorageMetricsInterval > 0 {
+		go func() {
+			for {
+				time.Sleep(options.StorageMetricsInterval)
+				s.publishStorageMetrics()
+			}
+		}()
+	}
+	return s, nil
+}
+
+// publishStorageMetrics publishes each bucket's size and object count, which AWS publishes daily.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/metrics-dimensions.html
+func (s *S3) publishStorageMetrics() {
+	type bucketStorage struct {
+		size    int64
+		objects int
+	}
+	buckets := make(map[string]bucketStorage)
+	s.mu.Lock()
+	for name, bucket := range s.buckets {
+		var size int64
+		err := bucket.objects.Scan("", func(_ string, object *Object) bool {
+			size += object.ContentLength
+			return true
+		})
+		if err != nil {
+			s.logger.Error("Reading bucket size", "bucket", name, "err", err)
+			continue
+		}
+		buckets[name] = bucketStorage{size, bucket.objects.Len()}
+	}
+	s.mu.Unlock()
+
+	for name, bucket := range buckets {
+		s.metrics.PutMetric("AWS/S3", "BucketSizeBytes",
+			map[string]string{"BucketName": name, "StorageType": "StandardStorage"}, "Bytes", float64(bucket.size))
+		s.metrics.PutMetric("AWS/S3", "NumberOfObjects",
+			map[string]string{"BucketName": name, "StorageType": "AllStorageTypes"}, "Count", float64(bucket.objects))
+	}
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
@@ -124,9 +210,15 @@ func (s *S3) CreateBucket(input CreateBucketInput) (*CreateBucketOutput, *awserr
 		return nil, awserrors.XXX_TODO("bucket already exists")
 	}
 
-	s.buckets[input.Bucket] = &Bucket{
-		objects: make(map[string]*Object),
+	b, err := s.newBucket(TagSet{})
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
+	if awserr := s.lockedSaveBucket(input.Bucket, b); awserr != nil {
+		b.objects.Drop()
+		return nil, awserr
 	}
+	s.buckets[input.Bucket] = b
 
 	return &CreateBucketOutput{
 		Location: "/" + input.Bucket,
@@ -152,15 +244,21 @@ func (s *S3) DeleteBucket(input DeleteBucketInput) (*Response204, *awserrors.Err
 	defer s.mu.Unlock()
 
 	b, ok := s.buckets[input.Bucket]
-	if ok {
-		return nil, awserrors.XXX_TODO("bucket already exists")
+	if !ok {
+		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	if len(b.objects) != 0 {
+	if b.objects.Len() != 0 {
 		return nil, awserrors.XXX_TODO("bucket must be empty")
 	}
 
+	if err := s.storedBuckets.Delete(input.Bucket); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	delete(s.buckets, input.Bucket)
+	if err := b.objects.Drop(); err != nil {
+		s.logger.Error("Deleting bucket from storage", "bucket", input.Bucket, "err", err)
+	}
 	return response204, nil
 }
 
@@ -300,7 +398,10 @@ func (s *S3) getObject(input GetObjectInput, includeBody bool) (*GetObjectOutput
 		return nil, NotFound()
 	}
 
-	object, ok := b.objects[input.Key]
+	object, ok, err := b.objects.Get(input.Key)
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	if !ok {
 		return nil, NotFound()
 	}
@@ -315,7 +416,7 @@ func (s *S3) getObject(input GetObjectInput, includeBody bool) (*GetObjectOutput
 		SSECustomerKey:       object.SSECustomerKey,
 		SSEKMSKeyId:          object.SSEKMSKeyId,
 		// Bafflingly, This format is expected here.
-		LastModified: time.Now().UTC().Format(timeFormat),
+		LastModified: clock.Now().UTC().Format(timeFormat),
 	}
 	if includeBody {
 		var ranges []ByteRange
@@ -398,7 +499,9 @@ func (s *S3) PutObject(input PutObjectInput) (*PutObjectOutput, *awserrors.Error
 		SSECustomerAlgorithm: input.SSECustomerAlgorithm,
 		SSECustomerKey:       input.SSECustomerKey,
 	}
-	b.objects[input.Key] = object
+	if err := s.lockedSetObject(b, input.Key, object); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 
 	return &PutObjectOutput{
 		ETag:                    object.ETag,
@@ -427,11 +530,16 @@ func (s *S3) CopyObject(input CopyObjectInput) (*CopyObjectOutput, *awserrors.Er
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	object, ok := b.objects[sourceKey]
+	source, ok, err := b.objects.Get(sourceKey)
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	if !ok {
 		return nil, awserrors.XXX_TODO("no source item")
 	}
 
+	// The source is left as it is.
+	object := *source
 	if input.MetadataDirective == "REPLACE" {
 		// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/UsingMetadata.html for full list
 		object.ContentType = input.ContentType
@@ -450,10 +558,12 @@ func (s *S3) CopyObject(input CopyObjectInput) (*CopyObjectOutput, *awserrors.Er
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	destBucket.objects[input.Key] = object
+	if err := s.lockedSetObject(destBucket, input.Key, &object); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	return &CopyObjectOutput{
 		// TODO: Complete guess on format
-		LastModified: time.Now().UTC().Format(time.RFC3339Nano),
+		LastModified: clock.Now().UTC().Format(time.RFC3339Nano),
 		ETag:         object.ETag,
 	}, nil
 }
@@ -473,12 +583,17 @@ func (s *S3) DeleteObject(input DeleteObjectInput) (*DeleteObjectOutput, *awserr
 		return nil, NotFound()
 	}
 
-	_, ok = b.objects[input.Key]
+	_, ok, err := b.objects.Get(input.Key)
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	if !ok {
 		return nil, NotFound()
 	}
 
-	delete(b.objects, input.Key)
+	if err := s.lockedSetObject(b, input.Key, nil); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	return &DeleteObjectOutput{}, nil
 }
 
@@ -487,18 +602,19 @@ func (s *S3) DeleteObjects(input DeleteObjectsInput) (*DeleteObjectsOutput, *aws
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	b, ok := s.buckets[input.Bucket]
-	if !ok {
-		// Ensure the rest of the lookups will be misses
-		b = &Bucket{
-			objects: map[string]*Object{},
-		}
-	}
+	// Every lookup misses if there's no bucket.
+	b, bucketExists := s.buckets[input.Bucket]
 
 	output := &DeleteObjectsOutput{}
 	for _, object := range input.Object {
-		_, ok = b.objects[object.Key]
-		if !ok {
+		exists := false
+		if bucketExists {
+			var err error
+			if _, exists, err = b.objects.Get(object.Key); err != nil {
+				return nil, awserrors.XXX_TODO(err.Error())
+			}
+		}
+		if !exists {
 			err := NotFound().Body
 			output.Error = append(output.Error, DeleteObjectsError{
 				Code:    err.Type,
@@ -508,7 +624,9 @@ func (s *S3) DeleteObjects(input DeleteObjectsInput) (*DeleteObjectsOutput, *aws
 			continue
 		}
 
-		delete(b.objects, object.Key)
+		if err := s.lockedSetObject(b, object.Key, nil); err != nil {
+			return nil, awserrors.XXX_TODO(err.Error())
+		}
 		if !input.Quiet {
 			output.Deleted = append(output.Deleted, DeleteObjectsDeleted{
 				Key: object.Key,
@@ -528,7 +646,10 @@ func (s *S3) GetObjectTagging(input GetObjectTaggingInput) (*GetObjectTaggingOut
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	object, ok := b.objects[input.Key]
+	object, ok, err := b.objects.Get(input.Key)
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	if !ok {
 		return nil, awserrors.XXX_TODO("no item")
 	}
@@ -559,7 +680,10 @@ func (s *S3) PutObjectTagging(input PutObjectTaggingInput) (*PutObjectTaggingOut
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	object, ok := b.objects[input.Key]
+	object, ok, err := b.objects.Get(input.Key)
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	if !ok {
 		return nil, awserrors.XXX_TODO("no item")
 	}
@@ -574,6 +698,9 @@ func (s *S3) PutObjectTagging(input PutObjectTaggingInput) (*PutObjectTaggingOut
 		}
 	}
 	object.Tagging = tagging.String()
+	if err := b.objects.Put(input.Key, object); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 
 	return &PutObjectTaggingOutput{}, nil
 }
@@ -588,11 +715,17 @@ func (s *S3) DeleteObjectTagging(input DeleteObjectTaggingInput) (*Response204,
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	object, ok := b.objects[input.Key]
+	object, ok, err := b.objects.Get(input.Key)
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	if !ok {
 		return nil, awserrors.XXX_TODO("no item")
 	}
 	object.Tagging = ""
+	if err := b.objects.Put(input.Key, object); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 
 	return response204, nil
 }
@@ -763,7 +896,13 @@ func (s *S3) CompleteMultipartUpload(input CompleteMultipartUploadInput) (*Compl
 	object.ContentLength = totalContentLength
 	object.ETag = etag(combinedMD5s) + "-" + strconv.Itoa(len(input.Part))
 
-	s.buckets[input.Bucket].objects[input.Key] = &object
+	b, ok := s.buckets[input.Bucket]
+	if !ok {
+		return nil, awserrors.XXX_TODO("no bucket")
+	}
+	if err := s.lockedSetObject(b, input.Key, &object); err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
+	}
 	upload.Status = UploadStatusCompleted
 
 	return &CompleteMultipartUploadOutput{
@@ -825,6 +964,9 @@ func (s *S3) PutBucketTagging(input PutBucketTaggingInput) (*PutBucketTaggingOut
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 	b.TagSet = input.TagSet
+	if awserr := s.lockedSaveBucket(input.Bucket, b); awserr != nil {
+		return nil, awserr
+	}
 
 	return &PutBucketTaggingOutput{}, nil
 }
@@ -840,6 +982,9 @@ func (s *S3) DeleteBucketTagging(input DeleteBucketTaggingInput) (*Response204,
 	}
 
 	b.TagSet = TagSet{}
+	if awserr := s.lockedSaveBucket(input.Bucket, b); awserr != nil {
+		return nil, awserr
+	}
 	return response204, nil
 }
 
@@ -853,13 +998,6 @@ func (s *S3) ListObjectsV2(input ListObjectsV2Input) (*ListObjectsV2Output, *aws
 		return nil, awserrors.XXX_TODO("no bucket")
 	}
 
-	// Gather a list of all keys in bucket, sort them.
-	var keysSorted []string
-	for key := range b.objects {
-		keysSorted = append(keysSorted, key)
-	}
-	sort.Strings(keysSorted)
-
 	var maxKeys int
 	if input.MaxKeys == nil {
 		maxKeys = 1000
@@ -867,43 +1005,39 @@ func (s *S3) ListObjectsV2(input ListObjectsV2Input) (*ListObjectsV2Output, *aws
 		maxKeys = *input.MaxKeys
 	}
 
+	// Listing starts from the latest of StartAfter, ContinuationToken and Prefix, and stops once
+	// keys are past Prefix.
+	start := ""
+	for _, after := range []*string{input.StartAfter, input.ContinuationToken, input.Prefix} {
+		if after != nil && *after > start {
+			start = *after
+		}
+	}
+
 	// Gather up to maxKeys to include
 	isTruncated := false
 	continuationToken := ""
-	var keysToInclude []string
-	for _, key := range keysSorted {
-		if len(keysToInclude) >= maxKeys {
+	var contents []ListObjectsV2Object
+	err := s.lockedScanListedObjects(b, start, func(key string, object *Object) bool {
+		if input.Prefix != nil && !strings.HasPrefix(key, *input.Prefix) {
+			return false
+		}
+		if len(contents) >= maxKeys {
 			isTruncated = true
 			continuationToken = key
-			break
-		}
-
-		if input.StartAfter != nil && key < *input.StartAfter {
-			continue
+			return false
 		}
-
-		if input.Prefix != nil {
-			if !strings.HasPrefix(key, *input.Prefix) {
-				continue
-			}
-		}
-
-		if input.ContinuationToken != nil && key < *input.ContinuationToken {
-			continue
-		}
-		keysToInclude = append(keysToInclude, key)
-	}
-
-	var contents []ListObjectsV2Object
-	for _, keyToInclude := range keysToInclude {
-		object := b.objects[keyToInclude]
 		contents = append(contents, ListObjectsV2Object{
 			ETag: object.ETag,
-			Key:  keyToInclude,
+			Key:  key,
 			Size: int(object.ContentLength),
 			// TODO: Complete guess on format
-			LastModified: time.Now().UTC().Format(time.RFC3339Nano),
+			LastModified: clock.Now().UTC().Format(time.RFC3339Nano),
 		})
+		return true
+	})
+	if err != nil {
+		return nil, awserrors.XXX_TODO(err.Error())
 	}
 
 	response := &ListObjectsV2Output{
@@ -0,0 +1,196 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/state"
+)
+
+type objectState struct {
+	Key    string
+	Object Object
+}
+
+type bucketState struct {
+	Name    string
+	TagSet  TagSet
+	Objects []objectState
+}
+
+type bucketsState struct {
+	Buckets []bucketState
+}
+
+// blobs returns the MD5s and sizes of the content-addressed files the object's data is in.
+func (o *Object) blobs() []Part {
+	if len(o.Parts) == 0 {
+		return []Part{{MD5: o.MD5, Size: o.ContentLength}}
+	}
+	return o.Parts
+}
+
+func blobName(MD5 []byte) string {
+	return "blobs/" + hex.EncodeToString(MD5)
+}
+
+// TryPause pauses changes to the buckets while a snapshot copies them.
+func (s *S3) TryPause() (func(), bool) {
+	return state.TryLock(&s.mu)
+}
+
+// ExportState writes the buckets and their objects to the archive. Each distinct piece of object
+// data is written once, named by its MD5. Multipart uploads which haven't completed aren't
+// exported.
+func (s *S3) ExportState(w *state.Writer) error {
+	var exported bucketsState
+	unlock := w.Lock(&s.mu)
+	for name, bucket := range s.buckets {
+		b := bucketState{Name: name, TagSet: bucket.TagSet}
+		err := bucket.objects.Scan("", func(key string, object *Object) bool {
+			b.Objects = append(b.Objects, objectState{Key: key, Object: *object})
+			return true
+		})
+		if err != nil {
+			unlock()
+			return err
+		}
+		exported.Buckets = append(exported.Buckets, b)
+	}
+	unlock()
+	slices.SortFunc(exported.Buckets, func(a, b bucketState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if err := w.WriteJSON("buckets.json", exported); err != nil {
+		return err
+	}
+	// Blobs are never modified or deleted, so they can be read without holding the lock.
+	written := make(map[string]bool)
+	for _, bucket := range exported.Buckets {
+		for _, object := range bucket.Objects {
+			for _, blob := range object.Object.blobs() {
+				name := blobName(blob.MD5)
+				if written[name] {
+					continue
+				}
+				written[name] = true
+				path := s.filepath(blob.MD5)
+				err := w.WriteBlob(name, blob.Size, func() (io.ReadCloser, error) {
+					return os.Open(path)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ImportState replaces the buckets and their objects with the archive's, and aborts all multipart
+// uploads. The objects' data is streamed into the content-addressed files as it's checked, which
+// doesn't change any object even if the import then fails. The objects are kept in new maps in
+// storage, which are deleted if the archive is invalid, or when the emulator next starts if another
+// service's is.
+func (s *S3) ImportState(r *state.Reader) (func() error, error) {
+	var imported bucketsState
+	if err := r.ReadJSON("buckets.json", &imported); err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[string]*Bucket)
+	if err := s.importBuckets(r, imported.Buckets, buckets); err != nil {
+		for _, bucket := range buckets {
+			bucket.objects.Drop()
+		}
+		return nil, err
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for name, bucket := range buckets {
+			if awserr := s.lockedSaveBucket(name, bucket); awserr != nil {
+				return errors.New(awserr.Body.Message)
+			}
+		}
+		for name, bucket := range s.buckets {
+			if _, ok := buckets[name]; !ok {
+				if err := s.storedBuckets.Delete(name); err != nil {
+					return err
+				}
+			}
+			if err := bucket.objects.Drop(); err != nil {
+				s.logger.Error("Deleting bucket from storage", "bucket", name, "err", err)
+			}
+		}
+		s.buckets = buckets
+		s.multipartUploads = make(map[string]*multipartUpload)
+		return nil
+	}, nil
+}
+
+// importBuckets adds the archive's buckets to buckets, with their objects in new maps in storage.
+func (s *S3) importBuckets(r *state.Reader, imported []bucketState, buckets map[string]*Bucket) error {
+	for _, b := range imported {
+		if b.Name == "" {
+			return fmt.Errorf("bucket has no name")
+		}
+		bucket, err := s.newBucket(b.TagSet)
+		if err != nil {
+			return err
+		}
+		buckets[b.Name] = bucket
+		for _, o := range b.Objects {
+			object := o.Object
+			for _, blob := range object.blobs() {
+				if err := s.importBlob(r, blob); err != nil {
+					return fmt.Errorf("data of s3://%s/%s: %w", b.Name, o.Key, err)
+				}
+			}
+			if err := bucket.objects.Put(o.Key, &object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *S3) importBlob(r *state.Reader, blob Part) error {
+	f, err := r.Open(blobName(blob.MD5))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	MD5, n, err := s.drainReaderToMD5Store(f)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(MD5, blob.MD5) || n != blob.Size {
+		return fmt.Errorf("doesn't match its MD5")
+	}
+	return nil
+}
+
+// StateResources lists the buckets and objects in the archive, for diffing archives.
+func StateResources(r *state.Reader) (map[string]any, error) {
+	var exported bucketsState
+	if err := r.ReadJSON("buckets.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, bucket := range exported.Buckets {
+		resources["bucket/"+bucket.Name] = bucket.TagSet
+		for _, object := range bucket.Objects {
+			resources["object/"+bucket.Name+"/"+object.Key] = object.Object
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/storage"
+)
+
+const (
+	// The buckets are kept in the map with this name.
+	storedBucketsName = "s3/buckets"
+	// Each bucket's objects are kept in a map with this prefix and a random ID, so a bucket which
+	// replaces one with the same name doesn't see its objects.
+	objectsPrefix = "s3/objects/"
+)
+
+// storedBucket is what's kept in storage about a bucket besides its objects, so it can be restored
+// when the emulator restarts.
+type storedBucket struct {
+	TagSet TagSet
+	// The name of the map with the bucket's objects.
+	Objects string
+}
+
+// newBucket returns a bucket whose objects are kept in a new map in storage.
+func (s *S3) newBucket(tagSet TagSet) (*Bucket, error) {
+	objects, err := storage.OpenMap[*Object](s.storage, objectsPrefix+uuid.Must(uuid.NewV4()).String())
+	if err != nil {
+		return nil, err
+	}
+	return &Bucket{objects: objects, TagSet: tagSet}, nil
+}
+
+// lockedSaveBucket keeps the bucket in storage, after it's created or its tags change.
+func (s *S3) lockedSaveBucket(name string, b *Bucket) *awserrors.Error {
+	err := s.storedBuckets.Put(name, storedBucket{TagSet: b.TagSet, Objects: b.objects.Name()})
+	if err != nil {
+		return awserrors.XXX_TODO(err.Error())
+	}
+	return nil
+}
+
+// restore recreates the buckets kept in storage, and deletes any objects in storage which don't
+// belong to any of them, such as those of a bucket which was being deleted when the emulator
+// stopped.
+func (s *S3) restore() error {
+	// The objects can't be opened while the buckets are being scanned.
+	stored := make(map[string]storedBucket)
+	err := s.storedBuckets.Scan("", func(name string, bucket storedBucket) bool {
+		stored[name] = bucket
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for name, bucket := range stored {
+		objects, err := storage.OpenMap[*Object](s.storage, bucket.Objects)
+		if err != nil {
+			return err
+		}
+		s.buckets[name] = &Bucket{objects: objects, TagSet: bucket.TagSet}
+		used[bucket.Objects] = true
+	}
+
+	names, err := s.storage.Tables(objectsPrefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !used[name] {
+			if err := s.storage.DropTable(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -3,15 +3,18 @@ package s3
 import (
 	"encoding/xml"
 	"io"
+	"net/http"
 )
 
-type Response204 struct{}
+type Response204 struct {
+	Status int `rest:"status"`
+}
 
-var response204 = &Response204{}
+var response204 = &Response204{Status: http.StatusNoContent}
 
 type CreateBucketInput struct {
 	XMLName            xml.Name `xml:"CreateBucketConfiguration"`
-	Bucket             string   `s3:"bucket"`
+	Bucket             string   `xml:"-" rest:"path:Bucket" length:"3,63" pattern:"[a-z0-9][a-z0-9.-]*[a-z0-9]" error:"InvalidBucketName"`
 	LocationConstraint string
 }
 
@@ -20,17 +23,17 @@ type CreateBucketOutput struct {
 }
 
 type DeleteBucketInput struct {
-	Bucket string `s3:"bucket"`
+	Bucket string `rest:"path:Bucket"`
 }
 
 type HeadBucketInput struct {
-	Bucket string `s3:"bucket"`
+	Bucket string `rest:"path:Bucket"`
 }
 
 type HeadBucketOutput struct{}
 
 type GetBucketTaggingInput struct {
-	Bucket string `s3:"bucket"`
+	Bucket string `rest:"path:Bucket"`
 }
 
 type GetBucketTaggingOutput struct {
@@ -40,19 +43,19 @@ type GetBucketTaggingOutput struct {
 
 type PutBucketTaggingInput struct {
 	XMLName xml.Name `xml:"Tagging"`
-	Bucket  string   `s3:"bucket"`
+	Bucket  string   `xml:"-" rest:"path:Bucket"`
 	TagSet  TagSet
 }
 
 type PutBucketTaggingOutput struct{}
 
 type DeleteBucketTaggingInput struct {
-	Bucket string `s3:"bucket"`
+	Bucket string `rest:"path:Bucket"`
 }
 
 type GetObjectTaggingInput struct {
-	Bucket string `s3:"bucket"`
-	Key    string `s3:"key"`
+	Bucket string `rest:"path:Bucket"`
+	Key    string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
 }
 
 type GetObjectTaggingOutput struct {
@@ -61,89 +64,89 @@ type GetObjectTaggingOutput struct {
 }
 
 type TagSet struct {
-	Tag []APITag
+	Tag []APITag `length:",50" error:"InvalidTag"`
 }
 
 type APITag struct {
-	Key   string
-	Value string
+	Key   string `required:"true" length:"1,128" error:"InvalidTag"`
+	Value string `length:",256" error:"InvalidTag"`
 }
 
 type PutObjectTaggingInput struct {
 	XMLName xml.Name `xml:"Tagging"`
-	Bucket  string   `s3:"bucket"`
-	Key     string   `s3:"key"`
+	Bucket  string   `xml:"-" rest:"path:Bucket"`
+	Key     string   `xml:"-" rest:"path:Key" length:",1024" error:"KeyTooLongError"`
 	TagSet  TagSet
 }
 
 type PutObjectTaggingOutput struct{}
 
 type DeleteObjectTaggingInput struct {
-	Bucket string `s3:"bucket"`
-	Key    string `s3:"key"`
+	Bucket string `rest:"path:Bucket"`
+	Key    string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
 }
 
 type GetObjectInput struct {
-	Bucket               string `s3:"bucket"`
-	Key                  string `s3:"key"`
-	PartNumber           string `s3:"query:partNumber"`
-	SSECustomerAlgorithm string `s3:"header:x-amz-server-side-encryption-customer-algorithm"`
-	SSECustomerKey       string `s3:"header:x-amz-server-side-encryption-customer-key"`
-	Range                string `s3:"header:range"`
+	Bucket               string `rest:"path:Bucket"`
+	Key                  string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	PartNumber           string `rest:"query:partNumber"`
+	SSECustomerAlgorithm string `rest:"header:x-amz-server-side-encryption-customer-algorithm" enum:"AES256" error:"InvalidArgument"`
+	SSECustomerKey       string `rest:"header:x-amz-server-side-encryption-customer-key"`
+	Range                string `rest:"header:range"`
 	// TODO: md5 check
 }
 
 type GetObjectOutput struct {
-	ContentLength        int64  `s3:"header:content-length"`
-	ETag                 string `s3:"header:etag"`
-	ContentType          string `s3:"header:content-type"`
-	ServerSideEncryption string `s3:"header:x-amz-server-side-encryption"`
-	SSECustomerAlgorithm string `s3:"header:x-amz-server-side-encryption-customer-algorithm"`
-	SSECustomerKey       string `s3:"header:x-amz-server-side-encryption-customer-key"`
-	LastModified         string `s3:"header:Last-Modified"`
+	ContentLength        int64  `rest:"header:content-length"`
+	ETag                 string `rest:"header:etag"`
+	ContentType          string `rest:"header:content-type"`
+	ServerSideEncryption string `rest:"header:x-amz-server-side-encryption"`
+	SSECustomerAlgorithm string `rest:"header:x-amz-server-side-encryption-customer-algorithm"`
+	SSECustomerKey       string `rest:"header:x-amz-server-side-encryption-customer-key"`
+	LastModified         string `rest:"header:Last-Modified"`
 	// TODO: md5
-	SSEKMSKeyId string `s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
-	//PartsCount    int    `s3:"header:x-amz-mp-parts-count"`
-	Body io.Reader `s3:"body"`
+	SSEKMSKeyId string `rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	//PartsCount    int    `rest:"header:x-amz-mp-parts-count"`
+	Body io.Reader `rest:"body"`
 }
 
 type PutObjectInput struct {
-	Bucket                  string    `s3:"bucket"`
-	Key                     string    `s3:"key"`
-	Data                    io.Reader `s3:"body"`
-	CopySource              string    `s3:"header:x-amz-copy-source"`
-	MetadataDirective       string    `s3:"header:x-amz-metadata-directive"`
-	ContentType             string    `s3:"header:content-type"`
-	ServerSideEncryption    string    `s3:"header:x-amz-server-side-encryption"`
-	SSEKMSKeyId             string    `s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
-	SSEKMSEncryptionContext string    `s3:"header:x-amz-server-side-encryption-context"`
-	SSECustomerAlgorithm    string    `s3:"header:x-amz-server-side-encryption-customer-algorithm"`
+	Bucket                  string    `rest:"path:Bucket"`
+	Key                     string    `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	Data                    io.Reader `rest:"body"`
+	CopySource              string    `rest:"header:x-amz-copy-source"`
+	MetadataDirective       string    `rest:"header:x-amz-metadata-directive" enum:"COPY|REPLACE" error:"InvalidArgument"`
+	ContentType             string    `rest:"header:content-type"`
+	ServerSideEncryption    string    `rest:"header:x-amz-server-side-encryption" enum:"AES256|aws:kms|aws:kms:dsse" error:"InvalidArgument"`
+	SSEKMSKeyId             string    `rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	SSEKMSEncryptionContext string    `rest:"header:x-amz-server-side-encryption-context"`
+	SSECustomerAlgorithm    string    `rest:"header:x-amz-server-side-encryption-customer-algorithm" enum:"AES256" error:"InvalidArgument"`
 	// TODO: md5 check
-	SSECustomerKey   string `s3:"header:x-amz-server-side-encryption-customer-key"`
-	Tagging          string `s3:"header:x-amz-tagging"`
-	TaggingDirective string `s3:"header:x-amz-tagging-directive"`
+	SSECustomerKey   string `rest:"header:x-amz-server-side-encryption-customer-key"`
+	Tagging          string `rest:"header:x-amz-tagging"`
+	TaggingDirective string `rest:"header:x-amz-tagging-directive" enum:"COPY|REPLACE" error:"InvalidArgument"`
 }
 
 type PutObjectOutput struct {
-	ETag                    string `s3:"header:etag"`
-	SSECustomerAlgorithm    string `s3:"header:x-amz-server-side-encryption-customer-algorithm"`
-	SSEKMSKeyId             string `s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
-	SSEKMSEncryptionContext string `s3:"header:x-amz-server-side-encryption-context"`
+	ETag                    string `rest:"header:etag"`
+	SSECustomerAlgorithm    string `rest:"header:x-amz-server-side-encryption-customer-algorithm"`
+	SSEKMSKeyId             string `rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	SSEKMSEncryptionContext string `rest:"header:x-amz-server-side-encryption-context"`
 }
 
 type CopyObjectInput struct {
-	Bucket                  string `s3:"bucket"`
-	Key                     string `s3:"key"`
-	CopySource              string `s3:"header:x-amz-copy-source"`
-	MetadataDirective       string `s3:"header:x-amz-metadata-directive"`
-	ContentType             string `s3:"header:content-type"`
-	ServerSideEncryption    string `s3:"header:x-amz-server-side-encryption"`
-	SSEKMSKeyId             string `s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
-	SSEKMSEncryptionContext string `s3:"header:x-amz-server-side-encryption-context"`
-	SSECustomerAlgorithm    string `s3:"header:x-amz-server-side-encryption-customer-algorithm"`
-	SSECustomerKey          string `s3:"header:x-amz-server-side-encryption-customer-key"`
-	Tagging                 string `s3:"header:x-amz-tagging"`
-	TaggingDirective        string `s3:"header:x-amz-tagging-directive"`
+	Bucket                  string `rest:"path:Bucket"`
+	Key                     string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	CopySource              string `rest:"header:x-amz-copy-source" required:"true" error:"InvalidArgument"`
+	MetadataDirective       string `rest:"header:x-amz-metadata-directive" enum:"COPY|REPLACE" error:"InvalidArgument"`
+	ContentType             string `rest:"header:content-type"`
+	ServerSideEncryption    string `rest:"header:x-amz-server-side-encryption" enum:"AES256|aws:kms|aws:kms:dsse" error:"InvalidArgument"`
+	SSEKMSKeyId             string `rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	SSEKMSEncryptionContext string `rest:"header:x-amz-server-side-encryption-context"`
+	SSECustomerAlgorithm    string `rest:"header:x-amz-server-side-encryption-customer-algorithm" enum:"AES256" error:"InvalidArgument"`
+	SSECustomerKey          string `rest:"header:x-amz-server-side-encryption-customer-key"`
+	Tagging                 string `rest:"header:x-amz-tagging"`
+	TaggingDirective        string `rest:"header:x-amz-tagging-directive" enum:"COPY|REPLACE" error:"InvalidArgument"`
 }
 
 type CopyObjectOutput struct {
@@ -153,12 +156,12 @@ type CopyObjectOutput struct {
 }
 
 type CreateMultipartUploadInput struct {
-	Bucket                  string `s3:"bucket"`
-	Key                     string `s3:"key"`
-	ContentType             string `s3:"header:content-type"`
-	ServerSideEncryption    string `s3:"header:x-amz-server-side-encryption"`
-	SSEKMSKeyId             string `s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
-	SSEKMSEncryptionContext string `s3:"header:x-amz-server-side-encryption-context"`
+	Bucket                  string `rest:"path:Bucket"`
+	Key                     string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	ContentType             string `rest:"header:content-type"`
+	ServerSideEncryption    string `rest:"header:x-amz-server-side-encryption" enum:"AES256|aws:kms|aws:kms:dsse" error:"InvalidArgument"`
+	SSEKMSKeyId             string `rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	SSEKMSEncryptionContext string `rest:"header:x-amz-server-side-encryption-context"`
 }
 
 type CreateMultipartUploadOutput struct {
@@ -166,33 +169,33 @@ type CreateMultipartUploadOutput struct {
 	Bucket                  string
 	Key                     string
 	UploadId                string
-	ServerSideEncryption    string `xml:"-" s3:"header:x-amz-server-side-encryption"`
-	SSEKMSKeyId             string `xml:"-" s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
-	SSEKMSEncryptionContext string `xml:"-" s3:"header:x-amz-server-side-encryption-context"`
+	ServerSideEncryption    string `xml:"-" rest:"header:x-amz-server-side-encryption"`
+	SSEKMSKeyId             string `xml:"-" rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	SSEKMSEncryptionContext string `xml:"-" rest:"header:x-amz-server-side-encryption-context"`
 }
 
 type UploadPartInput struct {
-	Bucket     string    `s3:"bucket"`
-	Key        string    `s3:"key"`
-	UploadId   string    `s3:"query:uploadId"`
-	PartNumber int       `s3:"query:partNumber"`
-	Data       io.Reader `s3:"body"`
+	Bucket     string    `rest:"path:Bucket"`
+	Key        string    `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	UploadId   string    `rest:"query:uploadId" required:"true" error:"InvalidArgument"`
+	PartNumber int       `rest:"query:partNumber" required:"true" range:"1,10000" error:"InvalidArgument"`
+	Data       io.Reader `rest:"body"`
 }
 
 type UploadPartOutput struct {
-	ETag                 string `s3:"header:etag"`
-	ServerSideEncryption string `s3:"header:x-amz-server-side-encryption"`
-	SSEKMSKeyId          string `s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	ETag                 string `rest:"header:etag"`
+	ServerSideEncryption string `rest:"header:x-amz-server-side-encryption"`
+	SSEKMSKeyId          string `rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
 }
 
 type ListPartsInput struct {
-	Bucket               string `s3:"bucket"`
-	Key                  string `s3:"key"`
-	UploadId             string `s3:"query:uploadId"`
-	PartNumberMarker     *int   `s3:"query:part-number-marker"`
-	MaxParts             *int   `s3:"query:max-parts"`
-	SSECustomerAlgorithm string `s3:"header:x-amz-server-side-encryption-customer-algorithm"`
-	SSECustomerKey       string `s3:"header:x-amz-server-side-encryption-customer-key"`
+	Bucket               string `rest:"path:Bucket"`
+	Key                  string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	UploadId             string `rest:"query:uploadId" required:"true" error:"InvalidArgument"`
+	PartNumberMarker     *int   `rest:"query:part-number-marker" range:"0,10000" error:"InvalidArgument"`
+	MaxParts             *int   `rest:"query:max-parts" range:"0,1000" error:"InvalidArgument"`
+	SSECustomerAlgorithm string `rest:"header:x-amz-server-side-encryption-customer-algorithm" enum:"AES256" error:"InvalidArgument"`
+	SSECustomerKey       string `rest:"header:x-amz-server-side-encryption-customer-key"`
 	// TODO: md5 check
 }
 
@@ -216,23 +219,23 @@ type ListPartsOutputPart struct {
 }
 
 type AbortMultipartUploadInput struct {
-	UploadId string `s3:"query:uploadId"`
-	Bucket   string `s3:"bucket"`
-	Key      string `s3:"key"`
+	UploadId string `rest:"query:uploadId" required:"true" error:"InvalidArgument"`
+	Bucket   string `rest:"path:Bucket"`
+	Key      string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
 }
 
 type CompleteMultipartUploadInput struct {
-	XMLName  xml.Name `xml:"CompleteMultipartUpload"`
-	UploadId string   `s3:"query:uploadId"`
-	Bucket   string   `s3:"bucket"`
-	Key      string   `s3:"key"`
-	Part     []APIPart
+	XMLName  xml.Name  `xml:"CompleteMultipartUpload"`
+	UploadId string    `xml:"-" rest:"query:uploadId" required:"true" error:"InvalidArgument"`
+	Bucket   string    `xml:"-" rest:"path:Bucket"`
+	Key      string    `xml:"-" rest:"path:Key" length:",1024" error:"KeyTooLongError"`
+	Part     []APIPart `required:"true" length:"1,10000" error:"MalformedXML"`
 }
 
 type APIPart struct {
 	XMLName    xml.Name `xml:"Part"`
 	ETag       string
-	PartNumber int
+	PartNumber int `range:"1,10000" error:"InvalidPart"`
 }
 
 type CompleteMultipartUploadOutput struct {
@@ -241,24 +244,24 @@ type CompleteMultipartUploadOutput struct {
 	Bucket               string
 	Key                  string
 	ETag                 string
-	ServerSideEncryption string `xml:"-" s3:"header:x-amz-server-side-encryption"`
-	SSEKMSKeyId          string `xml:"-" s3:"header:x-amz-server-side-encryption-aws-kms-key-id"`
+	ServerSideEncryption string `xml:"-" rest:"header:x-amz-server-side-encryption"`
+	SSEKMSKeyId          string `xml:"-" rest:"header:x-amz-server-side-encryption-aws-kms-key-id"`
 }
 
 type DeleteObjectInput struct {
-	Bucket string `s3:"bucket"`
-	Key    string `s3:"key"`
+	Bucket string `rest:"path:Bucket"`
+	Key    string `rest:"path:Key" length:",1024" error:"KeyTooLongError"`
 }
 
 type DeleteObjectOutput struct{}
 
 type DeleteObjectsInput struct {
 	XMLName xml.Name `xml:"Delete"`
-	Bucket  string   `s3:"bucket"`
+	Bucket  string   `xml:"-" rest:"path:Bucket"`
 	Object  []struct {
 		Key       string
 		VersionId string
-	}
+	} `required:"true" length:"1,1000" error:"MalformedXML"`
 	Quiet bool
 }
 
@@ -283,11 +286,11 @@ type DeleteObjectsError struct {
 }
 
 type ListObjectsV2Input struct {
-	Bucket            string  `s3:"bucket"`
-	ContinuationToken *string `s3:"query:continuation-token"`
-	MaxKeys           *int    `s3:"query:max-keys"`
-	Prefix            *string `s3:"query:prefix"`
-	StartAfter        *string `s3:"query:start-after"`
+	Bucket            string  `rest:"path:Bucket"`
+	ContinuationToken *string `rest:"query:continuation-token"`
+	MaxKeys           *int    `rest:"query:max-keys" range:"0," error:"InvalidArgument"`
+	Prefix            *string `rest:"query:prefix" length:",1024" error:"KeyTooLongError"`
+	StartAfter        *string `rest:"query:start-after" length:",1024" error:"KeyTooLongError"`
 	// Not supported:
 	// Delimiter
 	// Encoding-Type
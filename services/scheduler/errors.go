@@ -0,0 +1,27 @@
+package scheduler
+
+import "aws-in-a-box/awserrors"
+
+func ConflictException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 409,
+		Body: awserrors.ErrorBody{
+			Type:    "ConflictException",
+			Message: message,
+		},
+	}
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    "ResourceNotFoundException",
+			Message: message,
+		},
+	}
+}
+
+func ValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationException", message)
+}
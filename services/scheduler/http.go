@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// EventBridge Scheduler only supports the REST-JSON protocol.
+func NewHandler(logger *slog.Logger, s *Scheduler, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/schedules/{Name}", "CreateSchedule", region.Method(s.regions, (*Scheduler).CreateSchedule))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/schedules/{Name}", "GetSchedule", region.Method(s.regions, (*Scheduler).GetSchedule))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, "/schedules/{Name}", "UpdateSchedule", region.Method(s.regions, (*Scheduler).UpdateSchedule))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/schedules/{Name}", "DeleteSchedule", region.Method(s.regions, (*Scheduler).DeleteSchedule))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/schedules", "ListSchedules", region.Method(s.regions, (*Scheduler).ListSchedules))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/schedule-groups/{Name}", "CreateScheduleGroup", region.Method(s.regions, (*Scheduler).CreateScheduleGroup))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/schedule-groups/{Name}", "GetScheduleGroup", region.Method(s.regions, (*Scheduler).GetScheduleGroup))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/schedule-groups/{Name}", "DeleteScheduleGroup", region.Method(s.regions, (*Scheduler).DeleteScheduleGroup))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/schedule-groups", "ListScheduleGroups", region.Method(s.regions, (*Scheduler).ListScheduleGroups))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/tags/{ResourceArn}", "ListTagsForResource", region.Method(s.regions, (*Scheduler).ListTagsForResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/tags/{ResourceArn}", "TagResource", region.Method(s.regions, (*Scheduler).TagResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/tags/{ResourceArn}", "UntagResource", region.Method(s.regions, (*Scheduler).UntagResource))
+	capabilityRegistry.Add("scheduler", registry.Operations()...)
+	handler := restjson.NewHandler(registry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		// Other services have the same tagging routes, so only handle the tags of schedule groups.
+		if strings.HasPrefix(r.URL.Path, "/tags/") && !strings.Contains(r.URL.Path, ":scheduler:") {
+			return false
+		}
+		return handler(w, r)
+	}
+}
@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/eventbridge"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/scheduler/latest/UserGuide/managing-targets.html
+
+// lockedScheduleNext sets when the schedule is next due after the given time, within its start and end dates.
+// Rate schedules are first due one period after they're created or updated, or after their start date.
+func (s *Scheduler) lockedScheduleNext(schedule *Schedule, after time.Time) {
+	schedule.nextScheduled = time.Time{}
+	schedule.nextInvocation = time.Time{}
+	if schedule.State != "ENABLED" {
+		return
+	}
+	if schedule.StartDate.After(after) {
+		after = schedule.StartDate
+	}
+	next := schedule.next(after)
+	if next.IsZero() || (!schedule.EndDate.IsZero() && next.After(schedule.EndDate)) {
+		return
+	}
+	schedule.nextScheduled = next
+	schedule.nextInvocation = next
+	if schedule.FlexibleTimeWindow.Mode == "FLEXIBLE" {
+		window := time.Duration(schedule.FlexibleTimeWindow.MaximumWindowInMinutes) * time.Minute
+		schedule.nextInvocation = next.Add(s.jitter(window))
+	}
+}
+
+// invocation is a schedule's target to invoke.
+type invocation struct {
+	scheduleArn   string
+	target        APITarget
+	scheduledTime time.Time
+}
+
+// fireSchedules invokes the targets of the enabled schedules which are due.
+// If a schedule was missed several times, for example because the clock jumped forward, it only fires once.
+// Schedules which won't be due again are deleted if their ActionAfterCompletion is DELETE.
+func (s *Scheduler) fireSchedules() {
+	s.mu.Lock()
+	now := s.clock()
+	var due []invocation
+	for _, group := range s.groups {
+		for _, schedule := range group.schedules {
+			if schedule.State != "ENABLED" {
+				continue
+			}
+			if !schedule.nextInvocation.IsZero() && !schedule.nextInvocation.After(now) {
+				scheduled := schedule.nextScheduled
+				for next := schedule.next(scheduled); !next.IsZero() && !next.After(now); next = schedule.next(next) {
+					if !schedule.EndDate.IsZero() && next.After(schedule.EndDate) {
+						break
+					}
+					scheduled = next
+				}
+				s.lockedScheduleNext(schedule, scheduled)
+				due = append(due, invocation{schedule.Arn, schedule.Target, scheduled})
+			}
+			if schedule.nextInvocation.IsZero() && schedule.ActionAfterCompletion == "DELETE" {
+				delete(group.schedules, schedule.Name)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, i := range due {
+		if err := s.invoke(i); err != nil {
+			s.logger.Warn("Failed to invoke schedule's target",
+				"schedule", i.scheduleArn, "target", i.target.Arn, "error", err)
+			s.sendToDeadLetterQueue(i, err)
+		}
+	}
+}
+
+// input returns the target's input, with its context attributes replaced.
+// https://docs.aws.amazon.com/scheduler/latest/UserGuide/managing-schedule-context-attributes.html
+func (i invocation) input(executionId string) string {
+	input := i.target.Input
+	if input == "" {
+		input = "{}"
+	}
+	return strings.NewReplacer(
+		"<aws.scheduler.schedule-arn>", i.scheduleArn,
+		"<aws.scheduler.scheduled-time>", i.scheduledTime.UTC().Format(time.RFC3339),
+		"<aws.scheduler.execution-id>", executionId,
+		"<aws.scheduler.attempt-number>", "1",
+	).Replace(input)
+}
+
+// invoke sends the target its input.
+func (s *Scheduler) invoke(i invocation) error {
+	executionId := uuid.Must(uuid.NewV4()).String()
+	input := i.input(executionId)
+	targetArn, err := arn.Parse(i.target.Arn)
+	if err != nil {
+		return err
+	}
+	switch service := targetArn.Service; service {
+	case "sqs":
+		if s.sqs == nil {
+			return fmt.Errorf("SQS is not enabled")
+		}
+		message := sqs.SendMessageInput{
+			MessageBody: input,
+		}
+		if i.target.SqsParameters != nil && i.target.SqsParameters.MessageGroupId != "" {
+			message.MessageGroupId = i.target.SqsParameters.MessageGroupId
+			message.MessageDeduplicationId = executionId
+		}
+		_, awserr := s.sqs.SendMessageToQueueArn(i.target.Arn, message)
+		return errorFromAWS(awserr)
+	case "sns":
+		if s.sns == nil {
+			return fmt.Errorf("SNS is not enabled")
+		}
+		topics, err := s.sns.ForARN(i.target.Arn)
+		if err != nil {
+			return err
+		}
+		_, awserr := topics.Publish(sns.PublishInput{
+			TopicArn: i.target.Arn,
+			Message:  input,
+		})
+		return errorFromAWS(awserr)
+	case "lambda":
+		if s.lambda == nil {
+			return fmt.Errorf("Lambda is not enabled")
+		}
+		return s.lambda.InvokeAsync(i.target.Arn, []byte(input))
+	case "kinesis":
+		if s.kinesis == nil {
+			return fmt.Errorf("Kinesis is not enabled")
+		}
+		streams, err := s.kinesis.ForARN(i.target.Arn)
+		if err != nil {
+			return err
+		}
+		_, awserr := streams.PutRecord(kinesis.PutRecordInput{
+			StreamARN:    i.target.Arn,
+			PartitionKey: i.target.KinesisParameters.PartitionKey,
+			Data:         base64.StdEncoding.EncodeToString([]byte(input)),
+		})
+		return errorFromAWS(awserr)
+	case "events":
+		if s.eventBridge == nil {
+			return fmt.Errorf("EventBridge is not enabled")
+		}
+		buses, err := s.eventBridge.ForARN(i.target.Arn)
+		if err != nil {
+			return err
+		}
+		output, awserr := buses.PutEvents(eventbridge.PutEventsInput{
+			Entries: []eventbridge.APIPutEventsRequestEntry{{
+				EventBusName: i.target.Arn,
+				Source:       i.target.EventBridgeParameters.Source,
+				DetailType:   i.target.EventBridgeParameters.DetailType,
+				Detail:       input,
+				Time:         timestamp.EpochSeconds(i.scheduledTime),
+			}},
+		})
+		if awserr != nil {
+			return errorFromAWS(awserr)
+		}
+		if entry := output.Entries[0]; entry.ErrorCode != "" {
+			return fmt.Errorf("%s: %s", entry.ErrorCode, entry.ErrorMessage)
+		}
+		return nil
+	default:
+		s.logger.Debug("Invoking is not supported for target", "target", i.target.Arn, "schedule", i.scheduleArn)
+		return nil
+	}
+}
+
+// sendToDeadLetterQueue sends the input of an invocation which failed to the target's dead-letter queue, if it has one.
+func (s *Scheduler) sendToDeadLetterQueue(i invocation, invokeErr error) {
+	if i.target.DeadLetterConfig == nil || i.target.DeadLetterConfig.Arn == "" || s.sqs == nil {
+		return
+	}
+	_, awserr := s.sqs.SendMessageToQueueArn(i.target.DeadLetterConfig.Arn, sqs.SendMessageInput{
+		MessageBody: i.input(uuid.Must(uuid.NewV4()).String()),
+		MessageAttributes: map[string]sqs.APIAttribute{
+			"ERROR_MESSAGE": {DataType: "String", StringValue: invokeErr.Error()},
+		},
+	})
+	if awserr != nil {
+		s.logger.Warn("Failed to send to dead-letter queue",
+			"schedule", i.scheduleArn, "queue", i.target.DeadLetterConfig.Arn, "error", errorFromAWS(awserr))
+	}
+}
+
+func errorFromAWS(awserr *awserrors.Error) error {
+	if awserr != nil {
+		return fmt.Errorf("%s: %s", awserr.Body.Type, awserr.Body.Message)
+	}
+	return nil
+}
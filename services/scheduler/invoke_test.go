@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/services/eventbridge"
+	"aws-in-a-box/services/sqs"
+)
+
+type fakeLambdaInvoker struct {
+	mu       sync.Mutex
+	payloads map[string][]string
+}
+
+func (f *fakeLambdaInvoker) InvokeAsync(functionArn string, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.payloads == nil {
+		f.payloads = make(map[string][]string)
+	}
+	f.payloads[functionArn] = append(f.payloads[functionArn], string(payload))
+	return nil
+}
+
+func createQueue(t *testing.T, s *sqs.SQS, name string) (string, string) {
+	output, awserr := s.CreateQueue(sqs.CreateQueueInput{QueueName: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.QueueUrl, generator.GenerateWithoutType("sqs", name)
+}
+
+func receiveMessages(t *testing.T, s *sqs.SQS, queueUrl string) []string {
+	output, awserr := s.ReceiveMessage(sqs.ReceiveMessageInput{QueueUrl: queueUrl, MaxNumberOfMessages: 10})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var bodies []string
+	for _, message := range output.Messages {
+		bodies = append(bodies, message.Body)
+	}
+	return bodies
+}
+
+func TestRecurringSchedule(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	queueUrl, queueArn := createQueue(t, q, "jobs")
+	s, advance := newScheduler(Options{SQS: q})
+	scheduleArn := createSchedule(t, s, CreateScheduleInput{
+		Name:               "every-five-minutes",
+		ScheduleExpression: "rate(5 minutes)",
+		Target: &APITarget{Arn: queueArn, RoleArn: roleArn,
+			Input: `{"schedule": "<aws.scheduler.schedule-arn>", "time": "<aws.scheduler.scheduled-time>"}`},
+	})
+
+	advance(4 * time.Minute)
+	s.fireSchedules()
+	if bodies := receiveMessages(t, q, queueUrl); len(bodies) != 0 {
+		t.Fatal("Expected schedule not to fire yet", bodies)
+	}
+	advance(time.Minute)
+	s.fireSchedules()
+	bodies := receiveMessages(t, q, queueUrl)
+	if len(bodies) != 1 || bodies[0] != `{"schedule": "`+scheduleArn+`", "time": "2023-11-14T22:18:20Z"}` {
+		t.Fatal("Unexpected messages", bodies)
+	}
+
+	// A schedule which was missed several times only fires once.
+	advance(22 * time.Minute)
+	s.fireSchedules()
+	bodies = receiveMessages(t, q, queueUrl)
+	if len(bodies) != 1 || bodies[0] != `{"schedule": "`+scheduleArn+`", "time": "2023-11-14T22:38:20Z"}` {
+		t.Fatal("Unexpected messages", bodies)
+	}
+
+	// Disabled schedules don't fire.
+	if _, awserr := s.UpdateSchedule(UpdateScheduleInput{
+		Name:               "every-five-minutes",
+		ScheduleExpression: "rate(5 minutes)",
+		FlexibleTimeWindow: &APIFlexibleTimeWindow{Mode: "OFF"},
+		Target:             &APITarget{Arn: queueArn, RoleArn: roleArn},
+		State:              "DISABLED",
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	advance(time.Hour)
+	s.fireSchedules()
+	if bodies := receiveMessages(t, q, queueUrl); len(bodies) != 0 {
+		t.Fatal("Expected disabled schedule not to fire", bodies)
+	}
+}
+
+func TestOneTimeSchedule(t *testing.T) {
+	lambda := &fakeLambdaInvoker{}
+	s, advance := newScheduler(Options{Lambda: lambda})
+	function := "arn:aws:lambda:us-east-1:123456789012:function:job"
+	createSchedule(t, s, CreateScheduleInput{
+		Name:                  "once",
+		ScheduleExpression:    "at(2023-11-14T23:00:00)",
+		Target:                &APITarget{Arn: function, RoleArn: roleArn},
+		ActionAfterCompletion: "DELETE",
+	})
+	createSchedule(t, s, CreateScheduleInput{
+		Name:               "kept",
+		ScheduleExpression: "at(2023-11-14T23:00:00)",
+		Target:             &APITarget{Arn: function, RoleArn: roleArn},
+	})
+
+	advance(time.Hour)
+	s.fireSchedules()
+	if payloads := lambda.payloads[function]; len(payloads) != 2 || payloads[0] != "{}" {
+		t.Fatal("Unexpected invocations", payloads)
+	}
+	s.fireSchedules()
+	if payloads := lambda.payloads[function]; len(payloads) != 2 {
+		t.Fatal("Expected one-time schedules to fire once", payloads)
+	}
+	schedules, awserr := s.ListSchedules(ListSchedulesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(schedules.Schedules) != 1 || schedules.Schedules[0].Name != "kept" {
+		t.Fatalf("Expected completed schedule to be deleted: %+v", schedules)
+	}
+}
+
+func TestFlexibleTimeWindow(t *testing.T) {
+	lambda := &fakeLambdaInvoker{}
+	s, advance := newScheduler(Options{Lambda: lambda})
+	s.jitter = func(window time.Duration) time.Duration { return window / 2 }
+	function := "arn:aws:lambda:us-east-1:123456789012:function:job"
+	// 17:00 in New York is 22:00 UTC, so the first invocation is due the next day.
+	createSchedule(t, s, CreateScheduleInput{
+		Name:                       "evening",
+		ScheduleExpression:         "cron(0 17 * * ? *)",
+		ScheduleExpressionTimezone: "America/New_York",
+		FlexibleTimeWindow:         &APIFlexibleTimeWindow{Mode: "FLEXIBLE", MaximumWindowInMinutes: 60},
+		Target:                     &APITarget{Arn: function, RoleArn: roleArn, Input: `"<aws.scheduler.scheduled-time>"`},
+	})
+
+	advance(24*time.Hour - 13*time.Minute)
+	s.fireSchedules()
+	if payloads := lambda.payloads[function]; len(payloads) != 0 {
+		t.Fatal("Expected schedule not to fire before its window", payloads)
+	}
+	advance(30 * time.Minute)
+	s.fireSchedules()
+	if payloads := lambda.payloads[function]; len(payloads) != 1 || payloads[0] != `"2023-11-15T22:00:00Z"` {
+		t.Fatal("Unexpected invocations", payloads)
+	}
+}
+
+func TestEventBridgeTarget(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	queueUrl, queueArn := createQueue(t, q, "events")
+	e := eventbridge.New(eventbridge.Options{ArnGenerator: generator, SQS: q})
+	if _, awserr := e.PutRule(eventbridge.PutRuleInput{Name: "jobs", EventPattern: `{"source": ["jobs"]}`}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := e.PutTargets(eventbridge.PutTargetsInput{
+		Rule:    "jobs",
+		Targets: []eventbridge.APITarget{{Id: "queue", Arn: queueArn, InputPath: "$.detail"}},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	s, advance := newScheduler(Options{EventBridge: e})
+	createSchedule(t, s, CreateScheduleInput{
+		Name:               "hourly",
+		ScheduleExpression: "rate(1 hour)",
+		Target: &APITarget{
+			Arn:                   "arn:aws:events:us-east-1:123456789012:event-bus/default",
+			RoleArn:               roleArn,
+			Input:                 `{"job": "cleanup"}`,
+			EventBridgeParameters: &APIEventBridgeParameters{Source: "jobs", DetailType: "Job"},
+		},
+	})
+	advance(time.Hour)
+	s.fireSchedules()
+	if bodies := receiveMessages(t, q, queueUrl); len(bodies) != 1 || bodies[0] != `{"job":"cleanup"}` {
+		t.Fatal("Unexpected messages", bodies)
+	}
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	queueUrl, queueArn := createQueue(t, q, "dead-letters")
+	// Lambda isn't enabled, so invoking the function fails.
+	s, advance := newScheduler(Options{SQS: q})
+	createSchedule(t, s, CreateScheduleInput{
+		Name:               "hourly",
+		ScheduleExpression: "rate(1 hour)",
+		Target: &APITarget{
+			Arn:              "arn:aws:lambda:us-east-1:123456789012:function:job",
+			RoleArn:          roleArn,
+			Input:            `{"job": "cleanup"}`,
+			DeadLetterConfig: &APIDeadLetterConfig{Arn: queueArn},
+		},
+	})
+	advance(time.Hour)
+	s.fireSchedules()
+	if bodies := receiveMessages(t, q, queueUrl); len(bodies) != 1 || bodies[0] != `{"job": "cleanup"}` {
+		t.Fatal("Unexpected messages", bodies)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	europeQueues, err := q.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queueUrl, _ := createQueue(t, europeQueues, "jobs")
+	s, _ := newScheduler(Options{SQS: q})
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	europe.clock = func() time.Time { return now }
+
+	scheduleArn := createSchedule(t, europe, CreateScheduleInput{
+		Name:               "hourly",
+		ScheduleExpression: "rate(1 hour)",
+		Target:             &APITarget{Arn: "arn:aws:sqs:eu-west-1:123456789012:jobs", RoleArn: roleArn, Input: "cleanup"},
+	})
+	if scheduleArn != "arn:aws:scheduler:eu-west-1:123456789012:schedule/default/hourly" {
+		t.Fatal("Unexpected ARN", scheduleArn)
+	}
+	if _, awserr := s.GetSchedule(GetScheduleInput{Name: "hourly"}); awserr == nil {
+		t.Fatal("Expected eu-west-1's schedule not to be in us-east-1")
+	}
+	now = now.Add(time.Hour)
+	europe.fireSchedules()
+	if bodies := receiveMessages(t, europeQueues, queueUrl); len(bodies) != 1 || bodies[0] != "cleanup" {
+		t.Fatal("Unexpected messages", bodies)
+	}
+}
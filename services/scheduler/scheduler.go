@@ -0,0 +1,645 @@
+package scheduler
+
+import (
+	"log/slog"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	// Schedules can be in any time zone, even if the system has no time zone database.
+	_ "time/tzdata"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/eventbridge"
+	"aws-in-a-box/services/kinesis"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultGroupName = "default"
+	maxListResults   = 100
+	// The longest flexible time window, in minutes.
+	maxWindowInMinutes = 1440
+)
+
+var (
+	nameRegex    = regexp.MustCompile(`^[0-9a-zA-Z\-_.]{1,64}$`)
+	roleArnRegex = regexp.MustCompile(`^arn:aws(-[a-z]+)?:iam::\d{12}:role\/[\w+=,.@\/-]+$`)
+	atRegex      = regexp.MustCompile(`^at\((\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2})\)$`)
+)
+
+type ScheduleGroup struct {
+	Name                 string
+	Arn                  string
+	CreationDate         time.Time
+	LastModificationDate time.Time
+	Tags                 map[string]string
+	// Keyed by name.
+	schedules map[string]*Schedule
+}
+
+func (g *ScheduleGroup) toAPI() APIScheduleGroup {
+	return APIScheduleGroup{
+		Arn:                  g.Arn,
+		Name:                 g.Name,
+		State:                "ACTIVE",
+		CreationDate:         timestamp.EpochSeconds(g.CreationDate),
+		LastModificationDate: timestamp.EpochSeconds(g.LastModificationDate),
+	}
+}
+
+type Schedule struct {
+	Name                       string
+	Arn                        string
+	GroupName                  string
+	ScheduleExpression         string
+	ScheduleExpressionTimezone string
+	StartDate                  time.Time
+	EndDate                    time.Time
+	FlexibleTimeWindow         APIFlexibleTimeWindow
+	Target                     APITarget
+	// ENABLED or DISABLED.
+	State       string
+	Description string
+	// NONE or DELETE.
+	ActionAfterCompletion string
+	KmsKeyArn             string
+	CreationDate          time.Time
+	LastModificationDate  time.Time
+	// next returns the first time the schedule is due after the given time, or the zero time if it never is.
+	next func(after time.Time) time.Time
+	// When the schedule is next due, and when its target is invoked, which is later if it has a flexible
+	// time window. Both are zero if the schedule isn't due again.
+	nextScheduled  time.Time
+	nextInvocation time.Time
+}
+
+func (s *Schedule) toAPI() APISchedule {
+	return APISchedule{
+		Arn:                        s.Arn,
+		Name:                       s.Name,
+		GroupName:                  s.GroupName,
+		ScheduleExpression:         s.ScheduleExpression,
+		ScheduleExpressionTimezone: s.ScheduleExpressionTimezone,
+		StartDate:                  timestamp.EpochSeconds(s.StartDate),
+		EndDate:                    timestamp.EpochSeconds(s.EndDate),
+		FlexibleTimeWindow:         s.FlexibleTimeWindow,
+		Target:                     s.Target,
+		State:                      s.State,
+		Description:                s.Description,
+		ActionAfterCompletion:      s.ActionAfterCompletion,
+		KmsKeyArn:                  s.KmsKeyArn,
+		CreationDate:               timestamp.EpochSeconds(s.CreationDate),
+		LastModificationDate:       timestamp.EpochSeconds(s.LastModificationDate),
+	}
+}
+
+// LambdaInvoker asynchronously invokes the Lambda functions schedules target.
+type LambdaInvoker interface {
+	InvokeAsync(functionArn string, payload []byte) error
+}
+
+type Scheduler struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+	// jitter returns how long into a flexible time window of the given length a target is invoked.
+	jitter func(window time.Duration) time.Duration
+	// Targets of these services are invoked if they're enabled.
+	sqs         *sqs.SQS
+	sns         *sns.SNS
+	lambda      LambdaInvoker
+	kinesis     *kinesis.Kinesis
+	eventBridge *eventbridge.EventBridge
+
+	mu sync.Mutex
+	// Keyed by name.
+	groups map[string]*ScheduleGroup
+	// Shared by the instances of all regions, which each have their own schedule groups.
+	regions *region.Regions[*Scheduler]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	SQS          *sqs.SQS
+	SNS          *sns.SNS
+	Lambda       LambdaInvoker
+	Kinesis      *kinesis.Kinesis
+	EventBridge  *eventbridge.EventBridge
+	// How often to check for schedules which are due. If 0, schedules never fire.
+	ScheduleInterval time.Duration
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Scheduler {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Targets are found by their ARNs, which say their regions, so every region invokes them
+	// through the same services.
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*Scheduler, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	if options.ScheduleInterval > 0 {
+		go func() {
+			for {
+				time.Sleep(options.ScheduleInterval)
+				for _, r := range s.regions.All() {
+					r.fireSchedules()
+				}
+			}
+		}()
+	}
+	return s
+}
+
+// newRegion returns the instance for the options' region, with its default schedule group.
+func newRegion(options Options) *Scheduler {
+	s := &Scheduler{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		clock:        clock.Now,
+		jitter: func(window time.Duration) time.Duration {
+			return time.Duration(rand.Int63n(int64(window)))
+		},
+		sqs:         options.SQS,
+		sns:         options.SNS,
+		lambda:      options.Lambda,
+		kinesis:     options.Kinesis,
+		eventBridge: options.EventBridge,
+		groups:      make(map[string]*ScheduleGroup),
+	}
+	s.lockedCreateGroup(defaultGroupName, nil)
+	return s
+}
+
+// InRegion returns the schedule groups of the region. It fails if the region isn't one of
+// Options.Regions.
+func (s *Scheduler) InRegion(name string) (*Scheduler, error) {
+	return s.regions.Get(name)
+}
+
+func fromEpochSeconds(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(seconds * 1000))
+}
+
+func validateName(name string, field string) *awserrors.Error {
+	if !nameRegex.MatchString(name) {
+		return ValidationException("1 validation error detected: Value '" + name + "' at '" + field +
+			"' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[0-9a-zA-Z-_.]+$")
+	}
+	return nil
+}
+
+func (s *Scheduler) lockedCreateGroup(name string, tags []APITag) *ScheduleGroup {
+	now := s.clock()
+	group := &ScheduleGroup{
+		Name:                 name,
+		Arn:                  s.arnGenerator.Generate("scheduler", "schedule-group", name),
+		CreationDate:         now,
+		LastModificationDate: now,
+		Tags:                 make(map[string]string),
+		schedules:            make(map[string]*Schedule),
+	}
+	for _, tag := range tags {
+		group.Tags[tag.Key] = tag.Value
+	}
+	s.groups[name] = group
+	return group
+}
+
+func (s *Scheduler) lockedGetGroup(name string) (*ScheduleGroup, *awserrors.Error) {
+	if name == "" {
+		name = defaultGroupName
+	}
+	group, ok := s.groups[name]
+	if !ok {
+		return nil, ResourceNotFoundException("Schedule group " + name + " does not exist.")
+	}
+	return group, nil
+}
+
+func (s *Scheduler) lockedGetSchedule(groupName string, name string) (*Schedule, *awserrors.Error) {
+	group, awserr := s.lockedGetGroup(groupName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	schedule, ok := group.schedules[name]
+	if !ok {
+		return nil, ResourceNotFoundException("Schedule " + name + " does not exist.")
+	}
+	return schedule, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_CreateScheduleGroup.html
+func (s *Scheduler) CreateScheduleGroup(input CreateScheduleGroupInput) (*CreateScheduleGroupOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name, "name"); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.groups[input.Name]; ok {
+		return nil, ConflictException("Schedule group " + input.Name + " already exists.")
+	}
+	group := s.lockedCreateGroup(input.Name, input.Tags)
+	return &CreateScheduleGroupOutput{
+		ScheduleGroupArn: group.Arn,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_GetScheduleGroup.html
+func (s *Scheduler) GetScheduleGroup(input GetScheduleGroupInput) (*GetScheduleGroupOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, awserr := s.lockedGetGroup(input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := group.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_ListScheduleGroups.html
+func (s *Scheduler) ListScheduleGroups(input ListScheduleGroupsInput) (*ListScheduleGroupsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, maxListResults, maxListResults, input.NextToken,
+		ValidationException("MaxResults must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var groups []APIScheduleGroup
+	for name, group := range s.groups {
+		if strings.HasPrefix(name, input.NamePrefix) {
+			groups = append(groups, group.toAPI())
+		}
+	}
+	slices.SortFunc(groups, func(a, b APIScheduleGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &ListScheduleGroupsOutput{}
+	output.ScheduleGroups, output.NextToken = pagination.Page(groups, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_DeleteScheduleGroup.html
+// The group's schedules are deleted with it.
+func (s *Scheduler) DeleteScheduleGroup(input DeleteScheduleGroupInput) (*DeleteScheduleGroupOutput, *awserrors.Error) {
+	if input.Name == defaultGroupName {
+		return nil, ValidationException("The default schedule group can't be deleted.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, awserr := s.lockedGetGroup(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	delete(s.groups, input.Name)
+	return &DeleteScheduleGroupOutput{}, nil
+}
+
+// lockedGetGroupByArn returns the group with the ARN. Only schedule groups can be tagged.
+func (s *Scheduler) lockedGetGroupByArn(resourceArn string) (*ScheduleGroup, *awserrors.Error) {
+	_, name, ok := strings.Cut(resourceArn, ":schedule-group/")
+	if !ok {
+		return nil, ResourceNotFoundException("Resource " + resourceArn + " does not exist.")
+	}
+	group, ok := s.groups[name]
+	if !ok || group.Arn != resourceArn {
+		return nil, ResourceNotFoundException("Resource " + resourceArn + " does not exist.")
+	}
+	return group, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_TagResource.html
+func (s *Scheduler) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, awserr := s.lockedGetGroupByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, tag := range input.Tags {
+		group.Tags[tag.Key] = tag.Value
+	}
+	return &TagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_UntagResource.html
+func (s *Scheduler) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, awserr := s.lockedGetGroupByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, key := range input.TagKeys {
+		delete(group.Tags, key)
+	}
+	return &UntagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_ListTagsForResource.html
+func (s *Scheduler) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, awserr := s.lockedGetGroupByArn(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListTagsForResourceOutput{
+		Tags: []APITag{},
+	}
+	for key, value := range group.Tags {
+		output.Tags = append(output.Tags, APITag{Key: key, Value: value})
+	}
+	slices.SortFunc(output.Tags, func(a, b APITag) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return output, nil
+}
+
+// parseScheduleExpression parses a one-time at() expression, or a recurring rate() or cron() expression.
+func parseScheduleExpression(expression string, location *time.Location) (func(after time.Time) time.Time, bool) {
+	if match := atRegex.FindStringSubmatch(expression); match != nil {
+		at, err := time.ParseInLocation("2006-01-02T15:04:05", match[1], location)
+		if err != nil {
+			return nil, false
+		}
+		return func(after time.Time) time.Time {
+			if at.After(after) {
+				return at
+			}
+			return time.Time{}
+		}, true
+	}
+	return eventbridge.ParseSchedule(expression, location)
+}
+
+func validateTarget(target *APITarget) *awserrors.Error {
+	if target == nil {
+		return ValidationException("Target is required.")
+	}
+	targetArn, err := arn.Parse(target.Arn)
+	if err != nil {
+		return ValidationException("Invalid target ARN " + target.Arn + ".")
+	}
+	// Roles are validated, but schedules can invoke any target.
+	if !roleArnRegex.MatchString(target.RoleArn) {
+		return ValidationException("Invalid RoleArn " + target.RoleArn + ".")
+	}
+	if len(target.Input) > 8192 {
+		return ValidationException("Input must be at most 8192 characters.")
+	}
+	service := targetArn.Service
+	if service == "events" && (target.EventBridgeParameters == nil ||
+		target.EventBridgeParameters.Source == "" || target.EventBridgeParameters.DetailType == "") {
+		return ValidationException("EventBridgeParameters are required for EventBridge targets.")
+	}
+	if service == "kinesis" && (target.KinesisParameters == nil || target.KinesisParameters.PartitionKey == "") {
+		return ValidationException("KinesisParameters are required for Kinesis targets.")
+	}
+	return nil
+}
+
+// parseSchedule validates the input and returns the schedule it describes.
+func parseSchedule(input CreateScheduleInput) (*Schedule, *awserrors.Error) {
+	if awserr := validateName(input.Name, "name"); awserr != nil {
+		return nil, awserr
+	}
+	if input.GroupName != "" {
+		if awserr := validateName(input.GroupName, "groupName"); awserr != nil {
+			return nil, awserr
+		}
+	}
+
+	location := time.UTC
+	if input.ScheduleExpressionTimezone != "" {
+		var err error
+		location, err = time.LoadLocation(input.ScheduleExpressionTimezone)
+		if err != nil {
+			return nil, ValidationException("Invalid timezone " + input.ScheduleExpressionTimezone + ".")
+		}
+	}
+	next, ok := parseScheduleExpression(input.ScheduleExpression, location)
+	if !ok {
+		return nil, ValidationException("Invalid Schedule Expression " + input.ScheduleExpression + ".")
+	}
+
+	startDate := fromEpochSeconds(input.StartDate)
+	endDate := fromEpochSeconds(input.EndDate)
+	if !startDate.IsZero() && !endDate.IsZero() && !endDate.After(startDate) {
+		return nil, ValidationException("The EndDate must be after the StartDate.")
+	}
+
+	window := input.FlexibleTimeWindow
+	if window == nil {
+		return nil, ValidationException("FlexibleTimeWindow is required.")
+	}
+	switch window.Mode {
+	case "OFF":
+		if window.MaximumWindowInMinutes != 0 {
+			return nil, ValidationException("MaximumWindowInMinutes must not be set when FlexibleTimeWindow is OFF.")
+		}
+	case "FLEXIBLE":
+		if window.MaximumWindowInMinutes < 1 || window.MaximumWindowInMinutes > maxWindowInMinutes {
+			return nil, ValidationException("MaximumWindowInMinutes must be between 1 and 1440.")
+		}
+	default:
+		return nil, ValidationException("Invalid FlexibleTimeWindow Mode " + window.Mode + ".")
+	}
+
+	if awserr := validateTarget(input.Target); awserr != nil {
+		return nil, awserr
+	}
+
+	state := input.State
+	if state == "" {
+		state = "ENABLED"
+	}
+	if state != "ENABLED" && state != "DISABLED" {
+		return nil, ValidationException("Invalid State " + state + ".")
+	}
+	actionAfterCompletion := input.ActionAfterCompletion
+	if actionAfterCompletion == "" {
+		actionAfterCompletion = "NONE"
+	}
+	if actionAfterCompletion != "NONE" && actionAfterCompletion != "DELETE" {
+		return nil, ValidationException("Invalid ActionAfterCompletion " + actionAfterCompletion + ".")
+	}
+
+	groupName := input.GroupName
+	if groupName == "" {
+		groupName = defaultGroupName
+	}
+	return &Schedule{
+		Name:                       input.Name,
+		GroupName:                  groupName,
+		ScheduleExpression:         input.ScheduleExpression,
+		ScheduleExpressionTimezone: input.ScheduleExpressionTimezone,
+		StartDate:                  startDate,
+		EndDate:                    endDate,
+		FlexibleTimeWindow:         *window,
+		Target:                     *input.Target,
+		State:                      state,
+		Description:                input.Description,
+		ActionAfterCompletion:      actionAfterCompletion,
+		KmsKeyArn:                  input.KmsKeyArn,
+		next:                       next,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_CreateSchedule.html
+func (s *Scheduler) CreateSchedule(input CreateScheduleInput) (*CreateScheduleOutput, *awserrors.Error) {
+	schedule, awserr := parseSchedule(input)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, awserr := s.lockedGetGroup(schedule.GroupName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, ok := group.schedules[schedule.Name]; ok {
+		return nil, ConflictException("Schedule " + schedule.Name + " already exists.")
+	}
+
+	now := s.clock()
+	schedule.Arn = s.arnGenerator.Generate("scheduler", "schedule", group.Name+"/"+schedule.Name)
+	schedule.CreationDate = now
+	schedule.LastModificationDate = now
+	s.lockedScheduleNext(schedule, now)
+	group.schedules[schedule.Name] = schedule
+	return &CreateScheduleOutput{
+		ScheduleArn: schedule.Arn,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_GetSchedule.html
+func (s *Scheduler) GetSchedule(input GetScheduleInput) (*GetScheduleOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, awserr := s.lockedGetSchedule(input.GroupName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := schedule.toAPI()
+	return &output, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_UpdateSchedule.html
+func (s *Scheduler) UpdateSchedule(input UpdateScheduleInput) (*UpdateScheduleOutput, *awserrors.Error) {
+	schedule, awserr := parseSchedule(CreateScheduleInput(input))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, awserr := s.lockedGetSchedule(schedule.GroupName, schedule.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	now := s.clock()
+	schedule.Arn = existing.Arn
+	schedule.CreationDate = existing.CreationDate
+	schedule.LastModificationDate = now
+	s.lockedScheduleNext(schedule, now)
+	s.groups[schedule.GroupName].schedules[schedule.Name] = schedule
+	return &UpdateScheduleOutput{
+		ScheduleArn: schedule.Arn,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_DeleteSchedule.html
+func (s *Scheduler) DeleteSchedule(input DeleteScheduleInput) (*DeleteScheduleOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, awserr := s.lockedGetSchedule(input.GroupName, input.Name)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(s.groups[schedule.GroupName].schedules, schedule.Name)
+	return &DeleteScheduleOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/scheduler/latest/APIReference/API_ListSchedules.html
+func (s *Scheduler) ListSchedules(input ListSchedulesInput) (*ListSchedulesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, maxListResults, maxListResults, input.NextToken,
+		ValidationException("MaxResults must be between 1 and 100."),
+		ValidationException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var schedules []APIScheduleSummary
+	for groupName, group := range s.groups {
+		if input.GroupName != "" && input.GroupName != groupName {
+			continue
+		}
+		for name, schedule := range group.schedules {
+			if !strings.HasPrefix(name, input.NamePrefix) || (input.State != "" && input.State != schedule.State) {
+				continue
+			}
+			schedules = append(schedules, APIScheduleSummary{
+				Arn:                  schedule.Arn,
+				Name:                 schedule.Name,
+				GroupName:            schedule.GroupName,
+				State:                schedule.State,
+				Target:               APITargetSummary{Arn: schedule.Target.Arn},
+				CreationDate:         timestamp.EpochSeconds(schedule.CreationDate),
+				LastModificationDate: timestamp.EpochSeconds(schedule.LastModificationDate),
+			})
+		}
+	}
+	slices.SortFunc(schedules, func(a, b APIScheduleSummary) int {
+		if c := strings.Compare(a.GroupName, b.GroupName); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &ListSchedulesOutput{}
+	output.Schedules, output.NextToken = pagination.Page(schedules, limit, start)
+	return output, nil
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+const roleArn = "arn:aws:iam::123456789012:role/scheduler"
+
+// newScheduler returns a Scheduler whose clock only moves when the returned function is called.
+func newScheduler(options Options) (*Scheduler, func(time.Duration)) {
+	options.ArnGenerator = generator
+	s := New(options)
+	now := time.Unix(1700000000, 0)
+	s.clock = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func createSchedule(t *testing.T, s *Scheduler, input CreateScheduleInput) string {
+	if input.FlexibleTimeWindow == nil {
+		input.FlexibleTimeWindow = &APIFlexibleTimeWindow{Mode: "OFF"}
+	}
+	output, awserr := s.CreateSchedule(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.ScheduleArn
+}
+
+func TestScheduleGroups(t *testing.T) {
+	s, _ := newScheduler(Options{})
+
+	output, awserr := s.CreateScheduleGroup(CreateScheduleGroupInput{Name: "jobs", Tags: []APITag{{Key: "team", Value: "a"}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.ScheduleGroupArn != "arn:aws:scheduler:us-east-1:123456789012:schedule-group/jobs" {
+		t.Fatal("Unexpected ARN", output.ScheduleGroupArn)
+	}
+	_, awserr = s.CreateScheduleGroup(CreateScheduleGroupInput{Name: "jobs"})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatal("Expected group to already exist", awserr)
+	}
+
+	groups, awserr := s.ListScheduleGroups(ListScheduleGroupsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(groups.ScheduleGroups) != 2 || groups.ScheduleGroups[0].Name != "default" || groups.ScheduleGroups[1].State != "ACTIVE" {
+		t.Fatalf("Unexpected groups: %+v", groups)
+	}
+
+	if _, awserr := s.TagResource(TagResourceInput{ResourceArn: output.ScheduleGroupArn, Tags: []APITag{{Key: "env", Value: "dev"}}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.UntagResource(UntagResourceInput{ResourceArn: output.ScheduleGroupArn, TagKeys: []string{"team"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := s.ListTagsForResource(ListTagsForResourceInput{ResourceArn: output.ScheduleGroupArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != (APITag{Key: "env", Value: "dev"}) {
+		t.Fatalf("Unexpected tags: %+v", tags)
+	}
+
+	// Deleting a group deletes its schedules.
+	createSchedule(t, s, CreateScheduleInput{Name: "nightly", GroupName: "jobs", ScheduleExpression: "rate(1 day)",
+		Target: &APITarget{Arn: "arn:aws:sqs:us-east-1:123456789012:jobs", RoleArn: roleArn}})
+	if _, awserr := s.DeleteScheduleGroup(DeleteScheduleGroupInput{Name: "jobs"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.GetSchedule(GetScheduleInput{Name: "nightly", GroupName: "jobs"})
+	if awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected schedule to be deleted", awserr)
+	}
+	_, awserr = s.DeleteScheduleGroup(DeleteScheduleGroupInput{Name: "default"})
+	if awserr == nil || awserr.Body.Type != "ValidationException" {
+		t.Fatal("Expected default group not to be deletable", awserr)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	s, advance := newScheduler(Options{})
+	target := &APITarget{Arn: "arn:aws:lambda:us-east-1:123456789012:function:job", RoleArn: roleArn, Input: `{"job": 1}`}
+
+	scheduleArn := createSchedule(t, s, CreateScheduleInput{
+		Name:                       "daily",
+		ScheduleExpression:         "cron(0 9 * * ? *)",
+		ScheduleExpressionTimezone: "Europe/London",
+		FlexibleTimeWindow:         &APIFlexibleTimeWindow{Mode: "FLEXIBLE", MaximumWindowInMinutes: 15},
+		Target:                     target,
+		Description:                "Daily job",
+	})
+	if scheduleArn != "arn:aws:scheduler:us-east-1:123456789012:schedule/default/daily" {
+		t.Fatal("Unexpected ARN", scheduleArn)
+	}
+	_, awserr := s.CreateSchedule(CreateScheduleInput{Name: "daily", ScheduleExpression: "rate(1 day)",
+		FlexibleTimeWindow: &APIFlexibleTimeWindow{Mode: "OFF"}, Target: target})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatal("Expected schedule to already exist", awserr)
+	}
+
+	schedule, awserr := s.GetSchedule(GetScheduleInput{Name: "daily"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if schedule.GroupName != "default" || schedule.State != "ENABLED" || schedule.ActionAfterCompletion != "NONE" ||
+		schedule.Target.Input != `{"job": 1}` || schedule.FlexibleTimeWindow.MaximumWindowInMinutes != 15 ||
+		schedule.CreationDate != 1700000000 {
+		t.Fatalf("Unexpected schedule: %+v", schedule)
+	}
+
+	advance(time.Minute)
+	if _, awserr := s.UpdateSchedule(UpdateScheduleInput{
+		Name:               "daily",
+		ScheduleExpression: "rate(1 day)",
+		FlexibleTimeWindow: &APIFlexibleTimeWindow{Mode: "OFF"},
+		Target:             target,
+		State:              "DISABLED",
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	schedule, awserr = s.GetSchedule(GetScheduleInput{Name: "daily"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if schedule.ScheduleExpression != "rate(1 day)" || schedule.Description != "" || schedule.State != "DISABLED" ||
+		schedule.CreationDate != 1700000000 || schedule.LastModificationDate != 1700000060 {
+		t.Fatalf("Unexpected schedule: %+v", schedule)
+	}
+
+	createSchedule(t, s, CreateScheduleInput{Name: "once", ScheduleExpression: "at(2030-01-01T00:00:00)", Target: target})
+	schedules, awserr := s.ListSchedules(ListSchedulesInput{State: "ENABLED"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(schedules.Schedules) != 1 || schedules.Schedules[0].Name != "once" || schedules.Schedules[0].Target.Arn != target.Arn {
+		t.Fatalf("Unexpected schedules: %+v", schedules)
+	}
+
+	if _, awserr := s.DeleteSchedule(DeleteScheduleInput{Name: "once"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.DeleteSchedule(DeleteScheduleInput{Name: "once"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected schedule not to exist", awserr)
+	}
+	_, awserr = s.GetSchedule(GetScheduleInput{Name: "daily", GroupName: "missing"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected group not to exist", awserr)
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+	s, _ := newScheduler(Options{})
+	valid := func() CreateScheduleInput {
+		return CreateScheduleInput{
+			Name:               "job",
+			ScheduleExpression: "rate(5 minutes)",
+			FlexibleTimeWindow: &APIFlexibleTimeWindow{Mode: "OFF"},
+			Target:             &APITarget{Arn: "arn:aws:sqs:us-east-1:123456789012:jobs", RoleArn: roleArn},
+		}
+	}
+	for name, modify := range map[string]func(input *CreateScheduleInput){
+		"name":            func(input *CreateScheduleInput) { input.Name = "a job" },
+		"expression":      func(input *CreateScheduleInput) { input.ScheduleExpression = "rate(5 seconds)" },
+		"at":              func(input *CreateScheduleInput) { input.ScheduleExpression = "at(2030-01-01)" },
+		"timezone":        func(input *CreateScheduleInput) { input.ScheduleExpressionTimezone = "Mars/Olympus_Mons" },
+		"window":          func(input *CreateScheduleInput) { input.FlexibleTimeWindow = nil },
+		"window mode":     func(input *CreateScheduleInput) { input.FlexibleTimeWindow.Mode = "SOMETIMES" },
+		"window size":     func(input *CreateScheduleInput) { input.FlexibleTimeWindow.MaximumWindowInMinutes = 5 },
+		"flexible window": func(input *CreateScheduleInput) { input.FlexibleTimeWindow.Mode = "FLEXIBLE" },
+		"target":          func(input *CreateScheduleInput) { input.Target = nil },
+		"target arn":      func(input *CreateScheduleInput) { input.Target.Arn = "jobs" },
+		"role":            func(input *CreateScheduleInput) { input.Target.RoleArn = "scheduler" },
+		"state":           func(input *CreateScheduleInput) { input.State = "PAUSED" },
+		"completion":      func(input *CreateScheduleInput) { input.ActionAfterCompletion = "ARCHIVE" },
+		"event bus": func(input *CreateScheduleInput) {
+			input.Target.Arn = "arn:aws:events:us-east-1:123456789012:event-bus/default"
+		},
+		"kinesis": func(input *CreateScheduleInput) {
+			input.Target.Arn = "arn:aws:kinesis:us-east-1:123456789012:stream/jobs"
+		},
+		"dates": func(input *CreateScheduleInput) {
+			input.StartDate = 1700000000
+			input.EndDate = 1600000000
+		},
+	} {
+		input := valid()
+		modify(&input)
+		_, awserr := s.CreateSchedule(input)
+		if awserr == nil || awserr.Body.Type != "ValidationException" {
+			t.Error("Expected invalid", name, awserr)
+		}
+	}
+	if _, awserr := s.CreateSchedule(valid()); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type scheduleState struct {
+	*Schedule
+	// Zero if the schedule isn't due again.
+	NextScheduled  time.Time
+	NextInvocation time.Time
+}
+
+type groupState struct {
+	*ScheduleGroup
+	// Sorted by name.
+	Schedules []scheduleState
+}
+
+type groupsState struct {
+	ScheduleGroups []groupState
+}
+
+// TryPause pauses changes to each region's schedule groups while a snapshot copies them.
+func (s *Scheduler) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *Scheduler) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's schedule groups and their schedules, with when they're next
+// due, to the archive.
+func (s *Scheduler) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*Scheduler).exportState)
+}
+
+func (s *Scheduler) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported groupsState
+	for _, group := range s.groups {
+		g := groupState{ScheduleGroup: group}
+		for _, schedule := range group.schedules {
+			g.Schedules = append(g.Schedules, scheduleState{
+				Schedule:       schedule,
+				NextScheduled:  schedule.nextScheduled,
+				NextInvocation: schedule.nextInvocation,
+			})
+		}
+		slices.SortFunc(g.Schedules, func(a, b scheduleState) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.ScheduleGroups = append(exported.ScheduleGroups, g)
+	}
+	slices.SortFunc(exported.ScheduleGroups, func(a, b groupState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("schedulegroups.json", exported)
+}
+
+// ImportState replaces each region's schedule groups and their schedules with the archive's.
+// Schedules which were due while the state was exported fire once they're imported.
+func (s *Scheduler) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*Scheduler).importState, (*Scheduler).exportState)
+}
+
+func (s *Scheduler) importState(r *state.Reader) (func() error, error) {
+	var imported groupsState
+	if err := r.ReadJSON("schedulegroups.json", &imported); err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*ScheduleGroup)
+	for _, g := range imported.ScheduleGroups {
+		group := g.ScheduleGroup
+		if group == nil || !nameRegex.MatchString(group.Name) || group.Arn == "" {
+			return nil, fmt.Errorf("invalid schedule group")
+		}
+		if group.Tags == nil {
+			group.Tags = make(map[string]string)
+		}
+		group.schedules = make(map[string]*Schedule)
+		for _, sc := range g.Schedules {
+			schedule := sc.Schedule
+			if schedule == nil || !nameRegex.MatchString(schedule.Name) || schedule.Arn == "" {
+				return nil, fmt.Errorf("schedule group %s: invalid schedule", group.Name)
+			}
+			location := time.UTC
+			if schedule.ScheduleExpressionTimezone != "" {
+				var err error
+				location, err = time.LoadLocation(schedule.ScheduleExpressionTimezone)
+				if err != nil {
+					return nil, fmt.Errorf("schedule %s: %v", schedule.Arn, err)
+				}
+			}
+			next, ok := parseScheduleExpression(schedule.ScheduleExpression, location)
+			if !ok {
+				return nil, fmt.Errorf("schedule %s: invalid schedule expression %q", schedule.Arn, schedule.ScheduleExpression)
+			}
+			schedule.GroupName = group.Name
+			schedule.next = next
+			schedule.nextScheduled = sc.NextScheduled
+			schedule.nextInvocation = sc.NextInvocation
+			group.schedules[schedule.Name] = schedule
+		}
+		groups[group.Name] = group
+	}
+	if _, ok := groups[defaultGroupName]; !ok {
+		return nil, fmt.Errorf("there's no %s schedule group", defaultGroupName)
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.groups = groups
+		return nil
+	}, nil
+}
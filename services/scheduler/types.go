@@ -0,0 +1,202 @@
+package scheduler
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128"`
+	Value string `required:"true" length:"1,256"`
+}
+
+type APIFlexibleTimeWindow struct {
+	// OFF or FLEXIBLE.
+	Mode                   string `required:"true" enum:"OFF|FLEXIBLE"`
+	MaximumWindowInMinutes int32  `json:",omitempty" range:"1,1440"`
+}
+
+type APIDeadLetterConfig struct {
+	Arn string `json:",omitempty" length:"1,1600"`
+}
+
+type APIRetryPolicy struct {
+	MaximumEventAgeInSeconds int32  `json:",omitempty" range:"60,86400"`
+	MaximumRetryAttempts     *int32 `json:",omitempty" range:"0,185"`
+}
+
+type APIEventBridgeParameters struct {
+	DetailType string `required:"true" length:"1,128"`
+	Source     string `required:"true" length:"1,256"`
+}
+
+type APIKinesisParameters struct {
+	PartitionKey string `required:"true" length:"1,256"`
+}
+
+type APISqsParameters struct {
+	MessageGroupId string `json:",omitempty" length:"1,128"`
+}
+
+type APITarget struct {
+	Arn                   string                    `required:"true" length:"1,1600"`
+	RoleArn               string                    `required:"true" length:"1,1600"`
+	Input                 string                    `json:",omitempty" length:"1,"`
+	DeadLetterConfig      *APIDeadLetterConfig      `json:",omitempty"`
+	RetryPolicy           *APIRetryPolicy           `json:",omitempty"`
+	EventBridgeParameters *APIEventBridgeParameters `json:",omitempty"`
+	KinesisParameters     *APIKinesisParameters     `json:",omitempty"`
+	SqsParameters         *APISqsParameters         `json:",omitempty"`
+	// Stored, but these targets aren't invoked.
+	EcsParameters               any `json:",omitempty"`
+	SageMakerPipelineParameters any `json:",omitempty"`
+}
+
+type APITargetSummary struct {
+	Arn string
+}
+
+type APISchedule struct {
+	Arn                        string
+	Name                       string
+	GroupName                  string
+	ScheduleExpression         string
+	ScheduleExpressionTimezone string  `json:",omitempty"`
+	StartDate                  float64 `json:",omitempty"`
+	EndDate                    float64 `json:",omitempty"`
+	FlexibleTimeWindow         APIFlexibleTimeWindow
+	Target                     APITarget
+	State                      string
+	Description                string `json:",omitempty"`
+	ActionAfterCompletion      string
+	KmsKeyArn                  string `json:",omitempty"`
+	CreationDate               float64
+	LastModificationDate       float64
+}
+
+type APIScheduleSummary struct {
+	Arn                  string
+	Name                 string
+	GroupName            string
+	State                string
+	Target               APITargetSummary
+	CreationDate         float64
+	LastModificationDate float64
+}
+
+type CreateScheduleInput struct {
+	Name                       string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	GroupName                  string `length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	ScheduleExpression         string `required:"true" length:"1,256"`
+	ScheduleExpressionTimezone string `length:"1,50"`
+	StartDate                  float64
+	EndDate                    float64
+	FlexibleTimeWindow         *APIFlexibleTimeWindow `required:"true"`
+	Target                     *APITarget             `required:"true"`
+	// ENABLED or DISABLED.
+	State       string `enum:"ENABLED|DISABLED"`
+	Description string `length:",512"`
+	// NONE or DELETE.
+	ActionAfterCompletion string `enum:"NONE|DELETE"`
+	KmsKeyArn             string `length:"1,2048"`
+	ClientToken           string `length:"1,64"`
+}
+
+type CreateScheduleOutput struct {
+	ScheduleArn string
+}
+
+type GetScheduleInput struct {
+	Name      string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	GroupName string `json:"-" rest:"query:groupName" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+}
+
+type GetScheduleOutput = APISchedule
+
+// UpdateSchedule replaces every field of the schedule.
+type UpdateScheduleInput CreateScheduleInput
+
+type UpdateScheduleOutput struct {
+	ScheduleArn string
+}
+
+type DeleteScheduleInput struct {
+	Name        string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	GroupName   string `json:"-" rest:"query:groupName" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	ClientToken string `json:"-" rest:"query:clientToken" length:"1,64"`
+}
+
+type DeleteScheduleOutput struct{}
+
+type ListSchedulesInput struct {
+	GroupName  string `json:"-" rest:"query:ScheduleGroup" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	NamePrefix string `json:"-" rest:"query:NamePrefix" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	State      string `json:"-" rest:"query:State" enum:"ENABLED|DISABLED"`
+	MaxResults int    `json:"-" rest:"query:MaxResults" range:"1,100"`
+	NextToken  string `json:"-" rest:"query:NextToken" length:"1,2048"`
+}
+
+type ListSchedulesOutput struct {
+	Schedules []APIScheduleSummary
+	NextToken string `json:",omitempty"`
+}
+
+type APIScheduleGroup struct {
+	Arn  string
+	Name string
+	// Always ACTIVE, since groups are deleted immediately.
+	State                string
+	CreationDate         float64
+	LastModificationDate float64
+}
+
+type CreateScheduleGroupInput struct {
+	Name        string   `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	Tags        []APITag `length:",200"`
+	ClientToken string   `length:"1,64"`
+}
+
+type CreateScheduleGroupOutput struct {
+	ScheduleGroupArn string
+}
+
+type GetScheduleGroupInput struct {
+	Name string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+}
+
+type GetScheduleGroupOutput = APIScheduleGroup
+
+type ListScheduleGroupsInput struct {
+	NamePrefix string `json:"-" rest:"query:NamePrefix" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	MaxResults int    `json:"-" rest:"query:MaxResults" range:"1,100"`
+	NextToken  string `json:"-" rest:"query:NextToken" length:"1,2048"`
+}
+
+type ListScheduleGroupsOutput struct {
+	ScheduleGroups []APIScheduleGroup
+	NextToken      string `json:",omitempty"`
+}
+
+type DeleteScheduleGroupInput struct {
+	Name        string `json:"-" rest:"path:Name" required:"true" length:"1,64" pattern:"[0-9a-zA-Z-_.]+"`
+	ClientToken string `json:"-" rest:"query:clientToken" length:"1,64"`
+}
+
+type DeleteScheduleGroupOutput struct{}
+
+type TagResourceInput struct {
+	ResourceArn string   `json:"-" rest:"path:ResourceArn" required:"true" length:"1,1011"`
+	Tags        []APITag `required:"true" length:"1,200"`
+}
+
+type TagResourceOutput struct{}
+
+type UntagResourceInput struct {
+	ResourceArn string   `json:"-" rest:"path:ResourceArn" required:"true" length:"1,1011"`
+	TagKeys     []string `json:"-" rest:"query:TagKeys" required:"true" length:"1,200"`
+}
+
+type UntagResourceOutput struct{}
+
+type ListTagsForResourceInput struct {
+	ResourceArn string `json:"-" rest:"path:ResourceArn" required:"true" length:"1,1011"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags []APITag
+}
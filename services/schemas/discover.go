@@ -0,0 +1,183 @@
+package schemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"aws-in-a-box/awserrors"
+)
+
+const maxDiscoveredEvents = 10
+
+// inferSchema returns the JSON schema of the value, which was decoded with UseNumber.
+// Every field of an object is required, and strings which are timestamps have the date-time format.
+func inferSchema(value any) map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		properties := make(map[string]any, len(v))
+		required := make([]string, 0, len(v))
+		for key, field := range v {
+			properties[key] = inferSchema(field)
+			required = append(required, key)
+		}
+		return objectSchema(properties, required)
+	case []any:
+		var items map[string]any
+		for _, item := range v {
+			items = mergeSchemas(items, inferSchema(item))
+		}
+		if items == nil {
+			items = map[string]any{}
+		}
+		return map[string]any{"type": "array", "items": items}
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		return map[string]any{"type": "string"}
+	case json.Number:
+		return map[string]any{"type": "number"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	default:
+		// Nulls could be anything.
+		return map[string]any{}
+	}
+}
+
+// objectSchema returns the schema of an object. Draft 4 schemas can't have empty required lists.
+func objectSchema(properties map[string]any, required []string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		slices.Sort(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// mergeSchemas combines the schemas of two values in the same place. Only fields which both objects have are
+// required. If the types differ, the first one is kept.
+func mergeSchemas(a map[string]any, b map[string]any) map[string]any {
+	if a == nil || a["type"] == nil {
+		return b
+	}
+	if a["type"] != b["type"] {
+		return a
+	}
+	switch a["type"] {
+	case "object":
+		aProperties := a["properties"].(map[string]any)
+		bProperties := b["properties"].(map[string]any)
+		properties := make(map[string]any, len(aProperties))
+		var required []string
+		for key, schema := range aProperties {
+			if other, ok := bProperties[key]; ok {
+				properties[key] = mergeSchemas(schema.(map[string]any), other.(map[string]any))
+				required = append(required, key)
+			} else {
+				properties[key] = schema
+			}
+		}
+		for key, schema := range bProperties {
+			if _, ok := aProperties[key]; !ok {
+				properties[key] = schema
+			}
+		}
+		return objectSchema(properties, required)
+	case "array":
+		return map[string]any{"type": "array", "items": mergeSchemas(a["items"].(map[string]any), b["items"].(map[string]any))}
+	case "string":
+		if a["format"] != b["format"] {
+			return map[string]any{"type": "string"}
+		}
+	}
+	return a
+}
+
+// schemaTitle turns the detail type into the name of the detail's schema, like OrderPlaced for "Order Placed".
+func schemaTitle(detailType string) string {
+	var title strings.Builder
+	upper := true
+	for _, r := range detailType {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		title.WriteRune(r)
+	}
+	if title.Len() == 0 || !unicode.IsLetter([]rune(title.String())[0]) {
+		return "Event" + title.String()
+	}
+	return title.String()
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discover.html#GetDiscoveredSchema
+func (s *Schemas) GetDiscoveredSchema(input GetDiscoveredSchemaInput) (*GetDiscoveredSchemaOutput, *awserrors.Error) {
+	if len(input.Events) == 0 || len(input.Events) > maxDiscoveredEvents {
+		return nil, BadRequestException("Events must have between 1 and 10 events.")
+	}
+	if input.Type != "OpenApi3" && input.Type != "JSONSchemaDraft4" {
+		return nil, BadRequestException("Type must be OpenApi3 or JSONSchemaDraft4.")
+	}
+
+	var eventSchema map[string]any
+	var source, detailType string
+	for _, event := range input.Events {
+		decoder := json.NewDecoder(bytes.NewReader([]byte(event)))
+		decoder.UseNumber()
+		var decoded map[string]any
+		if err := decoder.Decode(&decoded); err != nil || decoded == nil {
+			return nil, BadRequestException("Events must be JSON objects.")
+		}
+		if _, ok := decoded["detail"]; !ok {
+			return nil, BadRequestException("Events must be EventBridge events, with a detail field.")
+		}
+		if source == "" {
+			source, _ = decoded["source"].(string)
+			detailType, _ = decoded["detail-type"].(string)
+		}
+		eventSchema = mergeSchemas(eventSchema, inferSchema(decoded))
+	}
+
+	title := schemaTitle(detailType)
+	properties := eventSchema["properties"].(map[string]any)
+	detailSchema := properties["detail"].(map[string]any)
+	if source != "" {
+		eventSchema["x-amazon-events-source"] = source
+	}
+	if detailType != "" {
+		eventSchema["x-amazon-events-detail-type"] = detailType
+	}
+
+	var document map[string]any
+	if input.Type == "OpenApi3" {
+		properties["detail"] = map[string]any{"$ref": "#/components/schemas/" + title}
+		document = map[string]any{
+			"openapi": "3.0.0",
+			"info":    map[string]any{"version": "1.0.0", "title": title},
+			"paths":   map[string]any{},
+			"components": map[string]any{
+				"schemas": map[string]any{"AWSEvent": eventSchema, title: detailSchema},
+			},
+		}
+	} else {
+		properties["detail"] = map[string]any{"$ref": "#/definitions/" + title}
+		document = eventSchema
+		document["$schema"] = "http://json-schema.org/draft-04/schema#"
+		document["title"] = "AWSEvent"
+		document["definitions"] = map[string]any{title: detailSchema}
+	}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return &GetDiscoveredSchemaOutput{Content: string(content)}, nil
+}
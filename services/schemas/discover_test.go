@@ -0,0 +1,90 @@
+package schemas
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGetDiscoveredSchema(t *testing.T) {
+	s := newSchemas(Options{})
+	events := []string{
+		`{"version": "0", "id": "1", "detail-type": "Order Placed", "source": "shop", "account": "123456789012",
+			"time": "2023-11-14T22:13:20Z", "region": "us-east-1", "resources": [],
+			"detail": {"id": 1, "express": true, "items": [{"sku": "a"}], "note": "gift"}}`,
+		`{"version": "0", "id": "2", "detail-type": "Order Placed", "source": "shop", "account": "123456789012",
+			"time": "2023-11-14T22:14:20Z", "region": "us-east-1", "resources": ["order/2"],
+			"detail": {"id": 2, "express": false, "items": [{"sku": "b", "count": 2}]}}`,
+	}
+
+	output, awserr := s.GetDiscoveredSchema(GetDiscoveredSchemaInput{Events: events, Type: "OpenApi3"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var document struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]map[string]any
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(output.Content), &document); err != nil {
+		t.Fatal(err)
+	}
+	event := document.Components.Schemas["AWSEvent"]
+	detail := document.Components.Schemas["OrderPlaced"]
+	if document.OpenAPI != "3.0.0" || event["x-amazon-events-source"] != "shop" ||
+		event["x-amazon-events-detail-type"] != "Order Placed" {
+		t.Fatal("Unexpected schema", output.Content)
+	}
+	properties := event["properties"].(map[string]any)
+	if !reflect.DeepEqual(properties["detail"], map[string]any{"$ref": "#/components/schemas/OrderPlaced"}) ||
+		!reflect.DeepEqual(properties["time"], map[string]any{"type": "string", "format": "date-time"}) ||
+		!reflect.DeepEqual(properties["resources"], map[string]any{"type": "array", "items": map[string]any{"type": "string"}}) {
+		t.Fatal("Unexpected event schema", properties)
+	}
+	// Fields which aren't in every event aren't required.
+	expectedDetail := map[string]any{
+		"type":     "object",
+		"required": []any{"express", "id", "items"},
+		"properties": map[string]any{
+			"id":      map[string]any{"type": "number"},
+			"express": map[string]any{"type": "boolean"},
+			"note":    map[string]any{"type": "string"},
+			"items": map[string]any{"type": "array", "items": map[string]any{
+				"type":     "object",
+				"required": []any{"sku"},
+				"properties": map[string]any{
+					"sku":   map[string]any{"type": "string"},
+					"count": map[string]any{"type": "number"},
+				},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(detail, expectedDetail) {
+		t.Fatal("Unexpected detail schema", detail)
+	}
+
+	output, awserr = s.GetDiscoveredSchema(GetDiscoveredSchemaInput{Events: events[:1], Type: "JSONSchemaDraft4"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var draft4 map[string]any
+	if err := json.Unmarshal([]byte(output.Content), &draft4); err != nil {
+		t.Fatal(err)
+	}
+	definitions := draft4["definitions"].(map[string]any)
+	if draft4["$schema"] != "http://json-schema.org/draft-04/schema#" || definitions["OrderPlaced"] == nil {
+		t.Fatal("Unexpected schema", output.Content)
+	}
+
+	for name, input := range map[string]GetDiscoveredSchemaInput{
+		"no events": {Type: "OpenApi3"},
+		"type":      {Events: events, Type: "Avro"},
+		"not json":  {Events: []string{"event"}, Type: "OpenApi3"},
+		"no detail": {Events: []string{`{"source": "shop"}`}, Type: "OpenApi3"},
+	} {
+		if _, awserr := s.GetDiscoveredSchema(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Error("Expected", name, "to be invalid", awserr)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package schemas
+
+import (
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/services/eventbridge"
+)
+
+// Discoverers are stored and can be started and stopped, but they don't add the schemas of events on their event bus
+// to the discovered-schemas registry. GetDiscoveredSchema infers schemas from events instead.
+type Discoverer struct {
+	Id           string
+	Arn          string
+	Description  string
+	SourceArn    string
+	CrossAccount bool
+	// STARTED or STOPPED.
+	State string
+	Tags  map[string]string
+}
+
+func (d *Discoverer) output() *CreateDiscovererOutput {
+	return &CreateDiscovererOutput{
+		CrossAccount:  d.CrossAccount,
+		Description:   d.Description,
+		DiscovererArn: d.Arn,
+		DiscovererId:  d.Id,
+		SourceArn:     d.SourceArn,
+		State:         d.State,
+		Tags:          copyTags(d.Tags),
+	}
+}
+
+func (s *Schemas) lockedGetDiscoverer(id string) (*Discoverer, *awserrors.Error) {
+	discoverer, ok := s.discoverers[id]
+	if !ok {
+		return nil, NotFoundException("Discoverer " + id + " does not exist.")
+	}
+	return discoverer, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers.html#CreateDiscoverer
+func (s *Schemas) CreateDiscoverer(input CreateDiscovererInput) (*CreateDiscovererOutput, *awserrors.Error) {
+	_, busName, ok := strings.Cut(input.SourceArn, ":event-bus/")
+	if !strings.HasPrefix(input.SourceArn, "arn:") || !ok || busName == "" {
+		return nil, BadRequestException("SourceArn must be the ARN of an event bus.")
+	}
+	if s.eventBridge != nil {
+		buses, err := s.eventBridge.ForARN(input.SourceArn)
+		if err != nil {
+			return nil, BadRequestException(err.Error())
+		}
+		if _, awserr := buses.DescribeEventBus(eventbridge.DescribeEventBusInput{Name: busName}); awserr != nil {
+			return nil, BadRequestException("Event bus " + busName + " does not exist.")
+		}
+	}
+	crossAccount := true
+	if input.CrossAccount != nil {
+		crossAccount = *input.CrossAccount
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := "events-event-bus-" + busName
+	if _, ok := s.discoverers[id]; ok {
+		return nil, ConflictException("A discoverer for " + input.SourceArn + " already exists.")
+	}
+	discoverer := &Discoverer{
+		Id:           id,
+		Arn:          s.arnGenerator.Generate("schemas", "discoverer", id),
+		Description:  input.Description,
+		SourceArn:    input.SourceArn,
+		CrossAccount: crossAccount,
+		State:        "STARTED",
+		Tags:         copyTags(input.Tags),
+	}
+	s.discoverers[id] = discoverer
+	return discoverer.output(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers-id-discovererid.html#DescribeDiscoverer
+func (s *Schemas) DescribeDiscoverer(input DescribeDiscovererInput) (*DescribeDiscovererOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	discoverer, awserr := s.lockedGetDiscoverer(input.DiscovererId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return discoverer.output(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers-id-discovererid.html#UpdateDiscoverer
+func (s *Schemas) UpdateDiscoverer(input UpdateDiscovererInput) (*UpdateDiscovererOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	discoverer, awserr := s.lockedGetDiscoverer(input.DiscovererId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.CrossAccount != nil {
+		discoverer.CrossAccount = *input.CrossAccount
+	}
+	if input.Description != nil {
+		discoverer.Description = *input.Description
+	}
+	return discoverer.output(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers-id-discovererid.html#DeleteDiscoverer
+func (s *Schemas) DeleteDiscoverer(input DeleteDiscovererInput) (*DeleteDiscovererOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, awserr := s.lockedGetDiscoverer(input.DiscovererId); awserr != nil {
+		return nil, awserr
+	}
+	delete(s.discoverers, input.DiscovererId)
+	return &DeleteDiscovererOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers.html#ListDiscoverers
+func (s *Schemas) ListDiscoverers(input ListDiscoverersInput) (*ListDiscoverersOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		BadRequestException("Limit must be between 1 and 100."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var discoverers []APIDiscovererSummary
+	for id, discoverer := range s.discoverers {
+		if !strings.HasPrefix(id, input.DiscovererIdPrefix) || !strings.HasPrefix(discoverer.SourceArn, input.SourceArnPrefix) {
+			continue
+		}
+		discoverers = append(discoverers, APIDiscovererSummary{
+			CrossAccount:  discoverer.CrossAccount,
+			DiscovererArn: discoverer.Arn,
+			DiscovererId:  discoverer.Id,
+			SourceArn:     discoverer.SourceArn,
+			State:         discoverer.State,
+			Tags:          copyTags(discoverer.Tags),
+		})
+	}
+	slices.SortFunc(discoverers, func(a, b APIDiscovererSummary) int {
+		return strings.Compare(a.DiscovererId, b.DiscovererId)
+	})
+	output := &ListDiscoverersOutput{}
+	output.Discoverers, output.NextToken = pagination.Page(discoverers, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers-id-discovererid-start.html#StartDiscoverer
+func (s *Schemas) StartDiscoverer(input StartDiscovererInput) (*StartDiscovererOutput, *awserrors.Error) {
+	return s.setDiscovererState(input.DiscovererId, "STARTED")
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-discoverers-id-discovererid-stop.html#StopDiscoverer
+func (s *Schemas) StopDiscoverer(input StopDiscovererInput) (*StopDiscovererOutput, *awserrors.Error) {
+	return s.setDiscovererState(input.DiscovererId, "STOPPED")
+}
+
+func (s *Schemas) setDiscovererState(id string, state string) (*StartDiscovererOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	discoverer, awserr := s.lockedGetDiscoverer(id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	discoverer.State = state
+	return &StartDiscovererOutput{
+		DiscovererId: discoverer.Id,
+		State:        discoverer.State,
+	}, nil
+}
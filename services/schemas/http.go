@@ -0,0 +1,56 @@
+package schemas
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// EventBridge Schemas only supports the REST-JSON protocol.
+func NewHandler(logger *slog.Logger, s *Schemas, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	const registryPath = "/v1/registries/name/{RegistryName}"
+	const schemaPath = registryPath + "/schemas/name/{SchemaName}"
+	const discovererPath = "/v1/discoverers/id/{DiscovererId}"
+
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, registryPath, "CreateRegistry", region.Method(s.regions, (*Schemas).CreateRegistry))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, registryPath, "DescribeRegistry", region.Method(s.regions, (*Schemas).DescribeRegistry))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, registryPath, "UpdateRegistry", region.Method(s.regions, (*Schemas).UpdateRegistry))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, registryPath, "DeleteRegistry", region.Method(s.regions, (*Schemas).DeleteRegistry))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v1/registries", "ListRegistries", region.Method(s.regions, (*Schemas).ListRegistries))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, schemaPath, "CreateSchema", region.Method(s.regions, (*Schemas).CreateSchema))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, schemaPath, "DescribeSchema", region.Method(s.regions, (*Schemas).DescribeSchema))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, schemaPath, "UpdateSchema", region.Method(s.regions, (*Schemas).UpdateSchema))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, schemaPath, "DeleteSchema", region.Method(s.regions, (*Schemas).DeleteSchema))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, schemaPath+"/version/{SchemaVersion}", "DeleteSchemaVersion", region.Method(s.regions, (*Schemas).DeleteSchemaVersion))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, registryPath+"/schemas", "ListSchemas", region.Method(s.regions, (*Schemas).ListSchemas))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, schemaPath+"/versions", "ListSchemaVersions", region.Method(s.regions, (*Schemas).ListSchemaVersions))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, schemaPath+"/language/{Language}", "PutCodeBinding", region.Method(s.regions, (*Schemas).PutCodeBinding))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, schemaPath+"/language/{Language}", "DescribeCodeBinding", region.Method(s.regions, (*Schemas).DescribeCodeBinding))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, schemaPath+"/language/{Language}/source", "GetCodeBindingSource", region.Method(s.regions, (*Schemas).GetCodeBindingSource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v1/discover", "GetDiscoveredSchema", region.Method(s.regions, (*Schemas).GetDiscoveredSchema))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v1/discoverers", "CreateDiscoverer", region.Method(s.regions, (*Schemas).CreateDiscoverer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v1/discoverers", "ListDiscoverers", region.Method(s.regions, (*Schemas).ListDiscoverers))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, discovererPath, "DescribeDiscoverer", region.Method(s.regions, (*Schemas).DescribeDiscoverer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, discovererPath, "UpdateDiscoverer", region.Method(s.regions, (*Schemas).UpdateDiscoverer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, discovererPath, "DeleteDiscoverer", region.Method(s.regions, (*Schemas).DeleteDiscoverer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, discovererPath+"/start", "StartDiscoverer", region.Method(s.regions, (*Schemas).StartDiscoverer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, discovererPath+"/stop", "StopDiscoverer", region.Method(s.regions, (*Schemas).StopDiscoverer))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/tags/{ResourceArn}", "ListTagsForResource", region.Method(s.regions, (*Schemas).ListTagsForResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/tags/{ResourceArn}", "TagResource", region.Method(s.regions, (*Schemas).TagResource))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/tags/{ResourceArn}", "UntagResource", region.Method(s.regions, (*Schemas).UntagResource))
+	capabilityRegistry.Add("schemas", registry.Operations()...)
+	handler := restjson.NewHandler(registry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		// Other services have the same tagging routes, so only handle the tags of schemas resources.
+		if strings.HasPrefix(r.URL.Path, "/tags/") && !strings.Contains(r.URL.Path, ":schemas:") {
+			return false
+		}
+		return handler(w, r)
+	}
+}
@@ -0,0 +1,666 @@
+package schemas
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/eventbridge"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	maxListLimit     = 100
+	maxContentLength = 100000
+)
+
+var (
+	registryNameRegex = regexp.MustCompile(`^[\.\-_A-Za-z0-9@]{1,64}$`)
+	schemaNameRegex   = regexp.MustCompile(`^[\.\-_A-Za-z0-9@]{1,385}$`)
+	codeLanguages     = []string{"Go1", "Java8", "Python36", "TypeScript3"}
+)
+
+type Registry struct {
+	Name        string
+	Arn         string
+	Description string
+	Tags        map[string]string
+	// Keyed by name.
+	schemas map[string]*Schema
+}
+
+type Schema struct {
+	Name         string
+	Arn          string
+	Description  string
+	Tags         map[string]string
+	LastModified time.Time
+	// In the order they were created. Deleted versions are removed.
+	versions []*SchemaVersion
+	// Version numbers aren't reused after versions are deleted.
+	lastVersion int
+}
+
+type SchemaVersion struct {
+	Version string
+	// OpenApi3 or JSONSchemaDraft4.
+	Type    string
+	Content string
+	Created time.Time
+	// Keyed by language.
+	codeBindings map[string]*CodeBinding
+}
+
+type CodeBinding struct {
+	CreationDate time.Time
+	LastModified time.Time
+}
+
+type Schemas struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+	// Discoverers' sources are checked if EventBridge is enabled.
+	eventBridge *eventbridge.EventBridge
+
+	mu sync.Mutex
+	// Keyed by name.
+	registries map[string]*Registry
+	// Keyed by ID.
+	discoverers map[string]*Discoverer
+	// Shared by the instances of all regions, which each have their own registries.
+	regions *region.Regions[*Schemas]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	EventBridge  *eventbridge.EventBridge
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *Schemas {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Discoverers' event buses are found by their ARNs, which say their regions, so every region
+	// checks them through the same EventBridge.
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*Schemas, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *Schemas {
+	return &Schemas{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		clock:        clock.Now,
+		eventBridge:  options.EventBridge,
+		registries:   make(map[string]*Registry),
+		discoverers:  make(map[string]*Discoverer),
+	}
+}
+
+// InRegion returns the registries and discoverers of the region. It fails if the region isn't one
+// of Options.Regions.
+func (s *Schemas) InRegion(name string) (*Schemas, error) {
+	return s.regions.Get(name)
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
+
+func (r *Registry) output() *CreateRegistryOutput {
+	return &CreateRegistryOutput{
+		Description:  r.Description,
+		RegistryArn:  r.Arn,
+		RegistryName: r.Name,
+		Tags:         copyTags(r.Tags),
+	}
+}
+
+func (s *Schema) latest() *SchemaVersion {
+	return s.versions[len(s.versions)-1]
+}
+
+func (s *Schema) output(version *SchemaVersion) *CreateSchemaOutput {
+	return &CreateSchemaOutput{
+		Description:        s.Description,
+		LastModified:       timestamp.ISO8601(s.LastModified),
+		SchemaArn:          s.Arn,
+		SchemaName:         s.Name,
+		SchemaVersion:      version.Version,
+		Tags:               copyTags(s.Tags),
+		Type:               version.Type,
+		VersionCreatedDate: timestamp.ISO8601(version.Created),
+	}
+}
+
+// lockedAddVersion adds a new version of the schema with the content.
+func (s *Schema) lockedAddVersion(schemaType string, content string, now time.Time) *SchemaVersion {
+	s.lastVersion++
+	version := &SchemaVersion{
+		Version:      strconv.Itoa(s.lastVersion),
+		Type:         schemaType,
+		Content:      content,
+		Created:      now,
+		codeBindings: make(map[string]*CodeBinding),
+	}
+	s.versions = append(s.versions, version)
+	s.LastModified = now
+	return version
+}
+
+// validateContent checks that the content is a document of the schema type.
+func validateContent(schemaType string, content string) *awserrors.Error {
+	if schemaType != "OpenApi3" && schemaType != "JSONSchemaDraft4" {
+		return BadRequestException("Type must be OpenApi3 or JSONSchemaDraft4.")
+	}
+	if content == "" || len(content) > maxContentLength {
+		return BadRequestException("Content must be between 1 and 100000 characters.")
+	}
+	var document map[string]any
+	if err := json.Unmarshal([]byte(content), &document); err != nil {
+		return BadRequestException("Content is not a valid JSON document.")
+	}
+	if _, ok := document["openapi"]; schemaType == "OpenApi3" && !ok {
+		return BadRequestException("Content is not a valid OpenApi3 document: the openapi field is missing.")
+	}
+	return nil
+}
+
+func (s *Schemas) lockedGetRegistry(name string) (*Registry, *awserrors.Error) {
+	registry, ok := s.registries[name]
+	if !ok {
+		return nil, NotFoundException("Registry " + name + " does not exist.")
+	}
+	return registry, nil
+}
+
+func (s *Schemas) lockedGetSchema(registryName string, schemaName string) (*Schema, *awserrors.Error) {
+	registry, awserr := s.lockedGetRegistry(registryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	schema, ok := registry.schemas[schemaName]
+	if !ok {
+		return nil, NotFoundException("Schema " + schemaName + " does not exist.")
+	}
+	return schema, nil
+}
+
+// lockedGetVersion returns the version of the schema, or the latest version if it's empty.
+func (s *Schemas) lockedGetVersion(registryName string, schemaName string, version string) (*Schema, *SchemaVersion, *awserrors.Error) {
+	schema, awserr := s.lockedGetSchema(registryName, schemaName)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	if version == "" {
+		return schema, schema.latest(), nil
+	}
+	i := slices.IndexFunc(schema.versions, func(v *SchemaVersion) bool {
+		return v.Version == version
+	})
+	if i == -1 {
+		return nil, nil, NotFoundException("Version " + version + " of schema " + schemaName + " does not exist.")
+	}
+	return schema, schema.versions[i], nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname.html#CreateRegistry
+func (s *Schemas) CreateRegistry(input CreateRegistryInput) (*CreateRegistryOutput, *awserrors.Error) {
+	if !registryNameRegex.MatchString(input.RegistryName) {
+		return nil, BadRequestException("Invalid RegistryName " + input.RegistryName + ".")
+	}
+	if strings.HasPrefix(input.RegistryName, "aws.") {
+		return nil, BadRequestException("Registry names starting with aws. are reserved.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.registries[input.RegistryName]; ok {
+		return nil, ConflictException("Registry " + input.RegistryName + " already exists.")
+	}
+	registry := &Registry{
+		Name:        input.RegistryName,
+		Arn:         s.arnGenerator.Generate("schemas", "registry", input.RegistryName),
+		Description: input.Description,
+		Tags:        copyTags(input.Tags),
+		schemas:     make(map[string]*Schema),
+	}
+	s.registries[registry.Name] = registry
+	return registry.output(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname.html#DescribeRegistry
+func (s *Schemas) DescribeRegistry(input DescribeRegistryInput) (*DescribeRegistryOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registry, awserr := s.lockedGetRegistry(input.RegistryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return registry.output(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname.html#UpdateRegistry
+func (s *Schemas) UpdateRegistry(input UpdateRegistryInput) (*UpdateRegistryOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registry, awserr := s.lockedGetRegistry(input.RegistryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	registry.Description = input.Description
+	return registry.output(), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname.html#DeleteRegistry
+func (s *Schemas) DeleteRegistry(input DeleteRegistryInput) (*DeleteRegistryOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, awserr := s.lockedGetRegistry(input.RegistryName); awserr != nil {
+		return nil, awserr
+	}
+	delete(s.registries, input.RegistryName)
+	return &DeleteRegistryOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries.html#ListRegistries
+func (s *Schemas) ListRegistries(input ListRegistriesInput) (*ListRegistriesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		BadRequestException("Limit must be between 1 and 100."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var registries []APIRegistrySummary
+	for name, registry := range s.registries {
+		// Every registry is LOCAL.
+		if !strings.HasPrefix(name, input.RegistryNamePrefix) || (input.Scope != "" && input.Scope != "LOCAL") {
+			continue
+		}
+		registries = append(registries, APIRegistrySummary{
+			RegistryArn:  registry.Arn,
+			RegistryName: registry.Name,
+			Tags:         copyTags(registry.Tags),
+		})
+	}
+	slices.SortFunc(registries, func(a, b APIRegistrySummary) int {
+		return strings.Compare(a.RegistryName, b.RegistryName)
+	})
+	output := &ListRegistriesOutput{}
+	output.Registries, output.NextToken = pagination.Page(registries, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname.html#CreateSchema
+func (s *Schemas) CreateSchema(input CreateSchemaInput) (*CreateSchemaOutput, *awserrors.Error) {
+	if !schemaNameRegex.MatchString(input.SchemaName) {
+		return nil, BadRequestException("Invalid SchemaName " + input.SchemaName + ".")
+	}
+	if awserr := validateContent(input.Type, input.Content); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registry, awserr := s.lockedGetRegistry(input.RegistryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, ok := registry.schemas[input.SchemaName]; ok {
+		return nil, ConflictException("Schema " + input.SchemaName + " already exists.")
+	}
+	schema := &Schema{
+		Name:        input.SchemaName,
+		Arn:         s.arnGenerator.Generate("schemas", "schema", registry.Name+"/"+input.SchemaName),
+		Description: input.Description,
+		Tags:        copyTags(input.Tags),
+	}
+	version := schema.lockedAddVersion(input.Type, input.Content, s.clock())
+	registry.schemas[schema.Name] = schema
+	return schema.output(version), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname.html#DescribeSchema
+func (s *Schemas) DescribeSchema(input DescribeSchemaInput) (*DescribeSchemaOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schema, version, awserr := s.lockedGetVersion(input.RegistryName, input.SchemaName, input.SchemaVersion)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := schema.output(version)
+	return &DescribeSchemaOutput{
+		Content:            version.Content,
+		Description:        output.Description,
+		LastModified:       output.LastModified,
+		SchemaArn:          output.SchemaArn,
+		SchemaName:         output.SchemaName,
+		SchemaVersion:      output.SchemaVersion,
+		Tags:               output.Tags,
+		Type:               output.Type,
+		VersionCreatedDate: output.VersionCreatedDate,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname.html#UpdateSchema
+func (s *Schemas) UpdateSchema(input UpdateSchemaInput) (*UpdateSchemaOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schema, awserr := s.lockedGetSchema(input.RegistryName, input.SchemaName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	schemaType := input.Type
+	if schemaType == "" {
+		schemaType = schema.latest().Type
+	}
+	if input.Content != "" || schemaType != schema.latest().Type {
+		content := input.Content
+		if content == "" {
+			content = schema.latest().Content
+		}
+		if awserr := validateContent(schemaType, content); awserr != nil {
+			return nil, awserr
+		}
+		schema.lockedAddVersion(schemaType, content, s.clock())
+	}
+	if input.Description != nil {
+		schema.Description = *input.Description
+		schema.LastModified = s.clock()
+	}
+	return schema.output(schema.latest()), nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname.html#DeleteSchema
+func (s *Schemas) DeleteSchema(input DeleteSchemaInput) (*DeleteSchemaOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, awserr := s.lockedGetSchema(input.RegistryName, input.SchemaName); awserr != nil {
+		return nil, awserr
+	}
+	delete(s.registries[input.RegistryName].schemas, input.SchemaName)
+	return &DeleteSchemaOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname-version-schemaversion.html#DeleteSchemaVersion
+func (s *Schemas) DeleteSchemaVersion(input DeleteSchemaVersionInput) (*DeleteSchemaVersionOutput, *awserrors.Error) {
+	if input.SchemaVersion == "" {
+		return nil, BadRequestException("SchemaVersion is required.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schema, version, awserr := s.lockedGetVersion(input.RegistryName, input.SchemaName, input.SchemaVersion)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(schema.versions) == 1 {
+		return nil, BadRequestException("The only version of a schema can't be deleted. Delete the schema instead.")
+	}
+	schema.versions = slices.DeleteFunc(schema.versions, func(v *SchemaVersion) bool {
+		return v == version
+	})
+	return &DeleteSchemaVersionOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas.html#ListSchemas
+func (s *Schemas) ListSchemas(input ListSchemasInput) (*ListSchemasOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		BadRequestException("Limit must be between 1 and 100."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registry, awserr := s.lockedGetRegistry(input.RegistryName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var schemas []APISchemaSummary
+	for name, schema := range registry.schemas {
+		if !strings.HasPrefix(name, input.SchemaNamePrefix) {
+			continue
+		}
+		schemas = append(schemas, APISchemaSummary{
+			LastModified: timestamp.ISO8601(schema.LastModified),
+			SchemaArn:    schema.Arn,
+			SchemaName:   schema.Name,
+			Tags:         copyTags(schema.Tags),
+			VersionCount: int64(len(schema.versions)),
+		})
+	}
+	slices.SortFunc(schemas, func(a, b APISchemaSummary) int {
+		return strings.Compare(a.SchemaName, b.SchemaName)
+	})
+	output := &ListSchemasOutput{}
+	output.Schemas, output.NextToken = pagination.Page(schemas, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname-versions.html#ListSchemaVersions
+func (s *Schemas) ListSchemaVersions(input ListSchemaVersionsInput) (*ListSchemaVersionsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.Limit, maxListLimit, maxListLimit, input.NextToken,
+		BadRequestException("Limit must be between 1 and 100."),
+		BadRequestException("The specified NextToken is invalid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schema, awserr := s.lockedGetSchema(input.RegistryName, input.SchemaName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var versions []APISchemaVersionSummary
+	for _, version := range schema.versions {
+		versions = append(versions, APISchemaVersionSummary{
+			SchemaArn:     schema.Arn,
+			SchemaName:    schema.Name,
+			SchemaVersion: version.Version,
+			Type:          version.Type,
+		})
+	}
+	output := &ListSchemaVersionsOutput{}
+	output.SchemaVersions, output.NextToken = pagination.Page(versions, limit, start)
+	return output, nil
+}
+
+// lockedGetCodeBindingVersion returns the schema version whose code binding is in the language.
+func (s *Schemas) lockedGetCodeBindingVersion(input CodeBindingInput) (*SchemaVersion, *awserrors.Error) {
+	if !slices.Contains(codeLanguages, input.Language) {
+		return nil, BadRequestException("Language must be one of " + strings.Join(codeLanguages, ", ") + ".")
+	}
+	_, version, awserr := s.lockedGetVersion(input.RegistryName, input.SchemaName, input.SchemaVersion)
+	return version, awserr
+}
+
+// Code bindings are generated immediately, so they're always complete.
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname-language-language.html#PutCodeBinding
+func (s *Schemas) PutCodeBinding(input PutCodeBindingInput) (*PutCodeBindingOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, awserr := s.lockedGetCodeBindingVersion(input)
+	if awserr != nil {
+		return nil, awserr
+	}
+	now := s.clock()
+	binding, ok := version.codeBindings[input.Language]
+	if !ok {
+		binding = &CodeBinding{CreationDate: now}
+		version.codeBindings[input.Language] = binding
+	}
+	binding.LastModified = now
+	return &PutCodeBindingOutput{
+		StatusCode:    202,
+		CreationDate:  timestamp.ISO8601(binding.CreationDate),
+		LastModified:  timestamp.ISO8601(binding.LastModified),
+		SchemaVersion: version.Version,
+		Status:        "CREATE_COMPLETE",
+	}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname-language-language.html#DescribeCodeBinding
+func (s *Schemas) DescribeCodeBinding(input DescribeCodeBindingInput) (*DescribeCodeBindingOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, awserr := s.lockedGetCodeBindingVersion(input)
+	if awserr != nil {
+		return nil, awserr
+	}
+	binding, ok := version.codeBindings[input.Language]
+	if !ok {
+		return nil, NotFoundException("There is no " + input.Language + " code binding for version " + version.Version + ".")
+	}
+	return &DescribeCodeBindingOutput{
+		CreationDate:  timestamp.ISO8601(binding.CreationDate),
+		LastModified:  timestamp.ISO8601(binding.LastModified),
+		SchemaVersion: version.Version,
+		Status:        "CREATE_COMPLETE",
+	}, nil
+}
+
+// The code binding's zip file contains the schema, rather than generated code.
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/v1-registries-name-registryname-schemas-name-schemaname-language-language-source.html#GetCodeBindingSource
+func (s *Schemas) GetCodeBindingSource(input GetCodeBindingSourceInput) (*GetCodeBindingSourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, awserr := s.lockedGetCodeBindingVersion(input)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, ok := version.codeBindings[input.Language]; !ok {
+		return nil, NotFoundException("There is no " + input.Language + " code binding for version " + version.Version + ".")
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	f, err := archive.Create(input.SchemaName + ".json")
+	if err == nil {
+		_, err = f.Write([]byte(version.Content))
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		panic(err)
+	}
+	return &GetCodeBindingSourceOutput{Body: buf.Bytes()}, nil
+}
+
+// lockedGetTags returns the tags of the registry, schema or discoverer.
+func (s *Schemas) lockedGetTags(resourceArn string) (map[string]string, *awserrors.Error) {
+	if a, err := arn.Parse(resourceArn); err == nil {
+		name := a.ResourceId()
+		switch a.ResourceType() {
+		case "registry":
+			if registry, ok := s.registries[name]; ok && registry.Arn == resourceArn {
+				return registry.Tags, nil
+			}
+		case "schema":
+			registryName, schemaName, _ := strings.Cut(name, "/")
+			if schema, awserr := s.lockedGetSchema(registryName, schemaName); awserr == nil && schema.Arn == resourceArn {
+				return schema.Tags, nil
+			}
+		case "discoverer":
+			if discoverer, ok := s.discoverers[name]; ok && discoverer.Arn == resourceArn {
+				return discoverer.Tags, nil
+			}
+		}
+	}
+	return nil, NotFoundException("Resource " + resourceArn + " does not exist.")
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/tags-resource-arn.html#TagResource
+func (s *Schemas) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, awserr := s.lockedGetTags(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for key, value := range input.Tags {
+		tags[key] = value
+	}
+	return &TagResourceOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/tags-resource-arn.html#UntagResource
+func (s *Schemas) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, awserr := s.lockedGetTags(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, key := range input.TagKeys {
+		delete(tags, key)
+	}
+	return &UntagResourceOutput{StatusCode: 204}, nil
+}
+
+// https://docs.aws.amazon.com/eventbridge/latest/schema-reference/tags-resource-arn.html#ListTagsForResource
+func (s *Schemas) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, awserr := s.lockedGetTags(input.ResourceArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListTagsForResourceOutput{Tags: copyTags(tags)}, nil
+}
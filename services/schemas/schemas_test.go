@@ -0,0 +1,306 @@
+package schemas
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/eventbridge"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+const openAPIContent = `{"openapi": "3.0.0", "info": {"title": "Order", "version": "1.0.0"}, "paths": {}}`
+
+func newSchemas(options Options) *Schemas {
+	options.ArnGenerator = generator
+	s := New(options)
+	s.clock = func() time.Time { return time.Unix(1700000000, 0) }
+	return s
+}
+
+func createRegistry(t *testing.T, s *Schemas, name string) string {
+	output, awserr := s.CreateRegistry(CreateRegistryInput{RegistryName: name})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.RegistryArn
+}
+
+func TestRegistries(t *testing.T) {
+	s := newSchemas(Options{})
+	registryArn := createRegistry(t, s, "orders")
+	if registryArn != "arn:aws:schemas:us-east-1:123456789012:registry/orders" {
+		t.Fatal("Unexpected ARN", registryArn)
+	}
+	createRegistry(t, s, "payments")
+	for name, input := range map[string]CreateRegistryInput{
+		"ConflictException":   {RegistryName: "orders"},
+		"BadRequestException": {RegistryName: "aws.events"},
+	} {
+		if _, awserr := s.CreateRegistry(input); awserr == nil || awserr.Body.Type != name {
+			t.Error("Expected", name, "for", input.RegistryName, awserr)
+		}
+	}
+
+	if _, awserr := s.UpdateRegistry(UpdateRegistryInput{RegistryName: "orders", Description: "Orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	registry, awserr := s.DescribeRegistry(DescribeRegistryInput{RegistryName: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if registry.Description != "Orders" {
+		t.Fatalf("Unexpected registry: %+v", registry)
+	}
+
+	list, awserr := s.ListRegistries(ListRegistriesInput{Limit: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Registries) != 1 || list.Registries[0].RegistryName != "orders" || list.NextToken == "" {
+		t.Fatalf("Unexpected registries: %+v", list)
+	}
+	list, awserr = s.ListRegistries(ListRegistriesInput{Scope: "AWS"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Registries) != 0 {
+		t.Fatalf("Unexpected registries: %+v", list)
+	}
+
+	if _, awserr := s.DeleteRegistry(DeleteRegistryInput{RegistryName: "orders"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.DescribeRegistry(DescribeRegistryInput{RegistryName: "orders"})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected registry to be deleted", awserr)
+	}
+}
+
+func TestSchemaVersions(t *testing.T) {
+	s := newSchemas(Options{})
+	createRegistry(t, s, "orders")
+
+	created, awserr := s.CreateSchema(CreateSchemaInput{RegistryName: "orders", SchemaName: "shop@Order", Type: "OpenApi3",
+		Content: openAPIContent})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.SchemaArn != "arn:aws:schemas:us-east-1:123456789012:schema/orders/shop@Order" || created.SchemaVersion != "1" ||
+		created.VersionCreatedDate != "2023-11-14T22:13:20Z" {
+		t.Fatalf("Unexpected schema: %+v", created)
+	}
+	for name, input := range map[string]CreateSchemaInput{
+		"type":         {RegistryName: "orders", SchemaName: "s", Type: "Avro", Content: openAPIContent},
+		"json":         {RegistryName: "orders", SchemaName: "s", Type: "JSONSchemaDraft4", Content: "not json"},
+		"openapi":      {RegistryName: "orders", SchemaName: "s", Type: "OpenApi3", Content: `{"type": "object"}`},
+		"name":         {RegistryName: "orders", SchemaName: "not valid", Type: "OpenApi3", Content: openAPIContent},
+		"registry":     {RegistryName: "missing", SchemaName: "s", Type: "OpenApi3", Content: openAPIContent},
+		"already used": {RegistryName: "orders", SchemaName: "shop@Order", Type: "OpenApi3", Content: openAPIContent},
+	} {
+		if _, awserr := s.CreateSchema(input); awserr == nil {
+			t.Error("Expected", name, "to be invalid")
+		}
+	}
+
+	draft4 := `{"$schema": "http://json-schema.org/draft-04/schema#", "type": "object"}`
+	updated, awserr := s.UpdateSchema(UpdateSchemaInput{RegistryName: "orders", SchemaName: "shop@Order", Type: "JSONSchemaDraft4",
+		Content: draft4})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.SchemaVersion != "2" || updated.Type != "JSONSchemaDraft4" {
+		t.Fatalf("Unexpected schema: %+v", updated)
+	}
+
+	first, awserr := s.DescribeSchema(DescribeSchemaInput{RegistryName: "orders", SchemaName: "shop@Order", SchemaVersion: "1"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	latest, awserr := s.DescribeSchema(DescribeSchemaInput{RegistryName: "orders", SchemaName: "shop@Order"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if first.Content != openAPIContent || first.Type != "OpenApi3" || latest.Content != draft4 || latest.SchemaVersion != "2" {
+		t.Fatalf("Unexpected versions: %+v %+v", first, latest)
+	}
+
+	if _, awserr := s.DeleteSchemaVersion(DeleteSchemaVersionInput{RegistryName: "orders", SchemaName: "shop@Order",
+		SchemaVersion: "1"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.DeleteSchemaVersion(DeleteSchemaVersionInput{RegistryName: "orders", SchemaName: "shop@Order", SchemaVersion: "2"})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatal("Expected the only version not to be deletable", awserr)
+	}
+	versions, awserr := s.ListSchemaVersions(ListSchemaVersionsInput{RegistryName: "orders", SchemaName: "shop@Order"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(versions.SchemaVersions) != 1 || versions.SchemaVersions[0].SchemaVersion != "2" {
+		t.Fatalf("Unexpected versions: %+v", versions)
+	}
+
+	schemas, awserr := s.ListSchemas(ListSchemasInput{RegistryName: "orders", SchemaNamePrefix: "shop@"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(schemas.Schemas) != 1 || schemas.Schemas[0].VersionCount != 1 {
+		t.Fatalf("Unexpected schemas: %+v", schemas)
+	}
+
+	if _, awserr := s.DeleteSchema(DeleteSchemaInput{RegistryName: "orders", SchemaName: "shop@Order"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.DescribeSchema(DescribeSchemaInput{RegistryName: "orders", SchemaName: "shop@Order"})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected schema to be deleted", awserr)
+	}
+}
+
+func TestCodeBindings(t *testing.T) {
+	s := newSchemas(Options{})
+	createRegistry(t, s, "orders")
+	if _, awserr := s.CreateSchema(CreateSchemaInput{RegistryName: "orders", SchemaName: "Order", Type: "OpenApi3",
+		Content: openAPIContent}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	input := CodeBindingInput{RegistryName: "orders", SchemaName: "Order", Language: "Go1"}
+	_, awserr := s.DescribeCodeBinding(input)
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected code binding not to exist", awserr)
+	}
+	put, awserr := s.PutCodeBinding(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if put.Status != "CREATE_COMPLETE" || put.SchemaVersion != "1" {
+		t.Fatalf("Unexpected code binding: %+v", put)
+	}
+	_, awserr = s.PutCodeBinding(CodeBindingInput{RegistryName: "orders", SchemaName: "Order", Language: "Rust"})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatal("Expected language to be invalid", awserr)
+	}
+
+	source, awserr := s.GetCodeBindingSource(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(source.Body), int64(len(source.Body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "Order.json" {
+		t.Fatal("Unexpected files", archive.File)
+	}
+	f, err := archive.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != openAPIContent {
+		t.Fatal("Unexpected content", string(content))
+	}
+}
+
+func TestDiscoverersAndTags(t *testing.T) {
+	e := eventbridge.New(eventbridge.Options{ArnGenerator: generator})
+	s := newSchemas(Options{EventBridge: e})
+	busArn := "arn:aws:events:us-east-1:123456789012:event-bus/default"
+
+	created, awserr := s.CreateDiscoverer(CreateDiscovererInput{SourceArn: busArn, Tags: map[string]string{"team": "a"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.DiscovererId != "events-event-bus-default" || created.State != "STARTED" || !created.CrossAccount {
+		t.Fatalf("Unexpected discoverer: %+v", created)
+	}
+	_, awserr = s.CreateDiscoverer(CreateDiscovererInput{SourceArn: busArn})
+	if awserr == nil || awserr.Body.Type != "ConflictException" {
+		t.Fatal("Expected discoverer to already exist", awserr)
+	}
+	_, awserr = s.CreateDiscoverer(CreateDiscovererInput{SourceArn: "arn:aws:events:us-east-1:123456789012:event-bus/missing"})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" {
+		t.Fatal("Expected event bus not to exist", awserr)
+	}
+
+	stopped, awserr := s.StopDiscoverer(StopDiscovererInput{DiscovererId: created.DiscovererId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if stopped.State != "STOPPED" {
+		t.Fatalf("Unexpected state: %+v", stopped)
+	}
+	list, awserr := s.ListDiscoverers(ListDiscoverersInput{SourceArnPrefix: "arn:aws:events:"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Discoverers) != 1 || list.Discoverers[0].State != "STOPPED" {
+		t.Fatalf("Unexpected discoverers: %+v", list)
+	}
+
+	if _, awserr := s.TagResource(TagResourceInput{ResourceArn: created.DiscovererArn, Tags: map[string]string{"env": "dev"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.UntagResource(UntagResourceInput{ResourceArn: created.DiscovererArn, TagKeys: []string{"team"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := s.ListTagsForResource(ListTagsForResourceInput{ResourceArn: created.DiscovererArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tags.Tags) != 1 || tags.Tags["env"] != "dev" {
+		t.Fatalf("Unexpected tags: %+v", tags)
+	}
+	_, awserr = s.ListTagsForResource(ListTagsForResourceInput{ResourceArn: "arn:aws:schemas:us-east-1:123456789012:registry/missing"})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected registry not to exist", awserr)
+	}
+
+	if _, awserr := s.DeleteDiscoverer(DeleteDiscovererInput{DiscovererId: created.DiscovererId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.DescribeDiscoverer(DescribeDiscovererInput{DiscovererId: created.DiscovererId})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected discoverer to be deleted", awserr)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	e := eventbridge.New(eventbridge.Options{ArnGenerator: generator})
+	s := newSchemas(Options{EventBridge: e})
+	createRegistry(t, s, "orders")
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.DescribeRegistry(DescribeRegistryInput{RegistryName: "orders"}); awserr == nil {
+		t.Fatal("Expected the registry not to be in eu-west-1")
+	}
+	if registryArn := createRegistry(t, europe, "orders"); registryArn != "arn:aws:schemas:eu-west-1:123456789012:registry/orders" {
+		t.Fatal("Unexpected ARN", registryArn)
+	}
+
+	// Discoverers' event buses are in their ARNs' regions.
+	created, awserr := europe.CreateDiscoverer(CreateDiscovererInput{SourceArn: "arn:aws:events:eu-west-1:123456789012:event-bus/default"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if created.DiscovererArn != "arn:aws:schemas:eu-west-1:123456789012:discoverer/events-event-bus-default" {
+		t.Fatal("Unexpected ARN", created.DiscovererArn)
+	}
+}
@@ -0,0 +1,152 @@
+package schemas
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type versionState struct {
+	*SchemaVersion
+	// Keyed by language.
+	CodeBindings map[string]*CodeBinding `json:",omitempty"`
+}
+
+type schemaState struct {
+	*Schema
+	// In the order they were created.
+	Versions    []versionState
+	LastVersion int
+}
+
+type registryState struct {
+	*Registry
+	// Sorted by name.
+	Schemas []schemaState
+}
+
+type schemasState struct {
+	// Sorted by name.
+	Registries []registryState
+	// Sorted by ID.
+	Discoverers []*Discoverer
+}
+
+// TryPause pauses changes to each region's registries while a snapshot copies them.
+func (s *Schemas) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *Schemas) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's registries with their schemas and versions, and its
+// discoverers, to the archive.
+func (s *Schemas) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*Schemas).exportState)
+}
+
+func (s *Schemas) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported schemasState
+	for _, registry := range s.registries {
+		r := registryState{Registry: registry}
+		for _, schema := range registry.schemas {
+			ss := schemaState{Schema: schema, LastVersion: schema.lastVersion}
+			for _, version := range schema.versions {
+				ss.Versions = append(ss.Versions, versionState{
+					SchemaVersion: version,
+					CodeBindings:  version.codeBindings,
+				})
+			}
+			r.Schemas = append(r.Schemas, ss)
+		}
+		slices.SortFunc(r.Schemas, func(a, b schemaState) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		exported.Registries = append(exported.Registries, r)
+	}
+	slices.SortFunc(exported.Registries, func(a, b registryState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, discoverer := range s.discoverers {
+		exported.Discoverers = append(exported.Discoverers, discoverer)
+	}
+	slices.SortFunc(exported.Discoverers, func(a, b *Discoverer) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return w.WriteJSON("schemas.json", exported)
+}
+
+// ImportState replaces each region's registries and discoverers with the archive's.
+func (s *Schemas) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*Schemas).importState, (*Schemas).exportState)
+}
+
+func (s *Schemas) importState(r *state.Reader) (func() error, error) {
+	var imported schemasState
+	if err := r.ReadJSON("schemas.json", &imported); err != nil {
+		return nil, err
+	}
+
+	registries := make(map[string]*Registry)
+	for _, rs := range imported.Registries {
+		registry := rs.Registry
+		if registry == nil || !registryNameRegex.MatchString(registry.Name) || registry.Arn == "" {
+			return nil, fmt.Errorf("invalid registry")
+		}
+		if registry.Tags == nil {
+			registry.Tags = make(map[string]string)
+		}
+		registry.schemas = make(map[string]*Schema)
+		for _, ss := range rs.Schemas {
+			schema := ss.Schema
+			if schema == nil || !schemaNameRegex.MatchString(schema.Name) || schema.Arn == "" {
+				return nil, fmt.Errorf("registry %s: invalid schema", registry.Name)
+			}
+			// Schemas are deleted with their last version.
+			if len(ss.Versions) == 0 {
+				return nil, fmt.Errorf("schema %s has no versions", schema.Arn)
+			}
+			if schema.Tags == nil {
+				schema.Tags = make(map[string]string)
+			}
+			schema.versions = nil
+			for _, vs := range ss.Versions {
+				version := vs.SchemaVersion
+				if version == nil || version.Version == "" {
+					return nil, fmt.Errorf("schema %s: invalid version", schema.Arn)
+				}
+				version.codeBindings = vs.CodeBindings
+				if version.codeBindings == nil {
+					version.codeBindings = make(map[string]*CodeBinding)
+				}
+				schema.versions = append(schema.versions, version)
+			}
+			schema.lastVersion = ss.LastVersion
+			registry.schemas[schema.Name] = schema
+		}
+		registries[registry.Name] = registry
+	}
+
+	discoverers := make(map[string]*Discoverer)
+	for _, discoverer := range imported.Discoverers {
+		if discoverer == nil || discoverer.Id == "" || discoverer.Arn == "" {
+			return nil, fmt.Errorf("invalid discoverer")
+		}
+		if discoverer.Tags == nil {
+			discoverer.Tags = make(map[string]string)
+		}
+		discoverers[discoverer.Id] = discoverer
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.registries = registries
+		s.discoverers = discoverers
+		return nil
+	}, nil
+}
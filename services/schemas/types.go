@@ -0,0 +1,314 @@
+package schemas
+
+// Timestamps are ISO 8601 strings, and tags are lower case, unlike most REST-JSON services.
+
+type APIRegistrySummary struct {
+	RegistryArn  string
+	RegistryName string
+	Tags         map[string]string `json:"tags,omitempty"`
+}
+
+type CreateRegistryInput struct {
+	RegistryName string            `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	Description  string            `length:",256" error:"BadRequestException"`
+	Tags         map[string]string `json:"tags"`
+}
+
+type CreateRegistryOutput struct {
+	Description  string `json:",omitempty"`
+	RegistryArn  string
+	RegistryName string
+	Tags         map[string]string `json:"tags"`
+}
+
+type DescribeRegistryInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+}
+
+type DescribeRegistryOutput = CreateRegistryOutput
+
+type UpdateRegistryInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	Description  string `length:",256" error:"BadRequestException"`
+}
+
+type UpdateRegistryOutput = CreateRegistryOutput
+
+type DeleteRegistryInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+}
+
+type DeleteRegistryOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type ListRegistriesInput struct {
+	RegistryNamePrefix string `json:"-" rest:"query:registryNamePrefix"`
+	// LOCAL or AWS. There are no AWS registries.
+	Scope     string `json:"-" rest:"query:scope" enum:"LOCAL|AWS" error:"BadRequestException"`
+	Limit     int    `json:"-" rest:"query:limit" range:"1," error:"BadRequestException"`
+	NextToken string `json:"-" rest:"query:nextToken"`
+}
+
+type ListRegistriesOutput struct {
+	Registries []APIRegistrySummary
+	NextToken  string `json:",omitempty"`
+}
+
+type CreateSchemaInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName   string `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+	Content      string `required:"true" length:",100000" error:"BadRequestException"`
+	Description  string `length:",256" error:"BadRequestException"`
+	// OpenApi3 or JSONSchemaDraft4.
+	Type string            `required:"true" enum:"OpenApi3|JSONSchemaDraft4" error:"BadRequestException"`
+	Tags map[string]string `json:"tags"`
+}
+
+type CreateSchemaOutput struct {
+	Description        string `json:",omitempty"`
+	LastModified       string
+	SchemaArn          string
+	SchemaName         string
+	SchemaVersion      string
+	Tags               map[string]string `json:"tags"`
+	Type               string
+	VersionCreatedDate string
+}
+
+type DescribeSchemaInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName   string `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+	// The latest version if empty.
+	SchemaVersion string `json:"-" rest:"query:schemaVersion"`
+}
+
+type DescribeSchemaOutput struct {
+	Content            string
+	Description        string `json:",omitempty"`
+	LastModified       string
+	SchemaArn          string
+	SchemaName         string
+	SchemaVersion      string
+	Tags               map[string]string `json:"tags"`
+	Type               string
+	VersionCreatedDate string
+}
+
+// UpdateSchema creates a new version of the schema if its content is set.
+type UpdateSchemaInput struct {
+	RegistryName  string  `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName    string  `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+	ClientTokenId string  `length:",36" error:"BadRequestException"`
+	Content       string  `length:",100000" error:"BadRequestException"`
+	Description   *string `length:",256" error:"BadRequestException"`
+	Type          string  `enum:"OpenApi3|JSONSchemaDraft4" error:"BadRequestException"`
+}
+
+type UpdateSchemaOutput = CreateSchemaOutput
+
+type DeleteSchemaInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName   string `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+}
+
+type DeleteSchemaOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type DeleteSchemaVersionInput struct {
+	RegistryName  string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName    string `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+	SchemaVersion string `json:"-" rest:"path:SchemaVersion" required:"true" error:"BadRequestException"`
+}
+
+type DeleteSchemaVersionOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type APISchemaSummary struct {
+	LastModified string
+	SchemaArn    string
+	SchemaName   string
+	Tags         map[string]string `json:"tags,omitempty"`
+	VersionCount int64
+}
+
+type ListSchemasInput struct {
+	RegistryName     string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaNamePrefix string `json:"-" rest:"query:schemaNamePrefix"`
+	Limit            int    `json:"-" rest:"query:limit" range:"1," error:"BadRequestException"`
+	NextToken        string `json:"-" rest:"query:nextToken"`
+}
+
+type ListSchemasOutput struct {
+	Schemas   []APISchemaSummary
+	NextToken string `json:",omitempty"`
+}
+
+type APISchemaVersionSummary struct {
+	SchemaArn     string
+	SchemaName    string
+	SchemaVersion string
+	Type          string
+}
+
+type ListSchemaVersionsInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName   string `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+	Limit        int    `json:"-" rest:"query:limit" range:"1," error:"BadRequestException"`
+	NextToken    string `json:"-" rest:"query:nextToken"`
+}
+
+type ListSchemaVersionsOutput struct {
+	SchemaVersions []APISchemaVersionSummary
+	NextToken      string `json:",omitempty"`
+}
+
+type CodeBindingInput struct {
+	RegistryName string `json:"-" rest:"path:RegistryName" required:"true" error:"BadRequestException"`
+	SchemaName   string `json:"-" rest:"path:SchemaName" required:"true" error:"BadRequestException"`
+	// Java8, Python36, TypeScript3 or Go1.
+	Language string `json:"-" rest:"path:Language" required:"true" enum:"Java8|Python36|TypeScript3|Go1" error:"BadRequestException"`
+	// The latest version if empty.
+	SchemaVersion string `json:"-" rest:"query:schemaVersion"`
+}
+
+type PutCodeBindingInput = CodeBindingInput
+
+type PutCodeBindingOutput struct {
+	StatusCode    int `json:"-" rest:"status"`
+	CreationDate  string
+	LastModified  string
+	SchemaVersion string
+	// CREATE_IN_PROGRESS, CREATE_COMPLETE or CREATE_FAILED.
+	Status string
+}
+
+type DescribeCodeBindingInput = CodeBindingInput
+
+type DescribeCodeBindingOutput struct {
+	CreationDate  string
+	LastModified  string
+	SchemaVersion string
+	Status        string
+}
+
+type GetCodeBindingSourceInput = CodeBindingInput
+
+type GetCodeBindingSourceOutput struct {
+	// A zip file.
+	Body []byte `json:"-" rest:"body"`
+}
+
+type GetDiscoveredSchemaInput struct {
+	// EventBridge events, as JSON.
+	Events []string `required:"true" length:"1,10" error:"BadRequestException"`
+	// OpenApi3 or JSONSchemaDraft4.
+	Type string `required:"true" enum:"OpenApi3|JSONSchemaDraft4" error:"BadRequestException"`
+}
+
+type GetDiscoveredSchemaOutput struct {
+	Content string
+}
+
+type APIDiscovererSummary struct {
+	CrossAccount  bool
+	DiscovererArn string
+	DiscovererId  string
+	SourceArn     string
+	// STARTED or STOPPED.
+	State string
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+type CreateDiscovererInput struct {
+	CrossAccount *bool
+	Description  string `length:",256" error:"BadRequestException"`
+	// The ARN of an event bus.
+	SourceArn string            `required:"true" length:"20,1600" error:"BadRequestException"`
+	Tags      map[string]string `json:"tags"`
+}
+
+type CreateDiscovererOutput struct {
+	CrossAccount  bool
+	Description   string `json:",omitempty"`
+	DiscovererArn string
+	DiscovererId  string
+	SourceArn     string
+	State         string
+	Tags          map[string]string `json:"tags"`
+}
+
+type DescribeDiscovererInput struct {
+	DiscovererId string `json:"-" rest:"path:DiscovererId" required:"true" error:"BadRequestException"`
+}
+
+type DescribeDiscovererOutput = CreateDiscovererOutput
+
+type UpdateDiscovererInput struct {
+	DiscovererId string `json:"-" rest:"path:DiscovererId" required:"true" error:"BadRequestException"`
+	CrossAccount *bool
+	Description  *string `length:",256" error:"BadRequestException"`
+}
+
+type UpdateDiscovererOutput = CreateDiscovererOutput
+
+type DeleteDiscovererInput struct {
+	DiscovererId string `json:"-" rest:"path:DiscovererId" required:"true" error:"BadRequestException"`
+}
+
+type DeleteDiscovererOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type ListDiscoverersInput struct {
+	DiscovererIdPrefix string `json:"-" rest:"query:discovererIdPrefix"`
+	SourceArnPrefix    string `json:"-" rest:"query:sourceArnPrefix"`
+	Limit              int    `json:"-" rest:"query:limit" range:"1," error:"BadRequestException"`
+	NextToken          string `json:"-" rest:"query:nextToken"`
+}
+
+type ListDiscoverersOutput struct {
+	Discoverers []APIDiscovererSummary
+	NextToken   string `json:",omitempty"`
+}
+
+type StartDiscovererInput struct {
+	DiscovererId string `json:"-" rest:"path:DiscovererId" required:"true" error:"BadRequestException"`
+}
+
+type StartDiscovererOutput struct {
+	DiscovererId string
+	State        string
+}
+
+type StopDiscovererInput = StartDiscovererInput
+
+type StopDiscovererOutput = StartDiscovererOutput
+
+type TagResourceInput struct {
+	ResourceArn string            `json:"-" rest:"path:ResourceArn" required:"true" error:"BadRequestException"`
+	Tags        map[string]string `json:"tags" required:"true" error:"BadRequestException"`
+}
+
+type TagResourceOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type UntagResourceInput struct {
+	ResourceArn string   `json:"-" rest:"path:ResourceArn" required:"true" error:"BadRequestException"`
+	TagKeys     []string `json:"-" rest:"query:tagKeys" required:"true" error:"BadRequestException"`
+}
+
+type UntagResourceOutput struct {
+	StatusCode int `json:"-" rest:"status"`
+}
+
+type ListTagsForResourceInput struct {
+	ResourceArn string `json:"-" rest:"path:ResourceArn" required:"true" error:"BadRequestException"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags map[string]string `json:"tags"`
+}
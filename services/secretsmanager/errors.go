@@ -0,0 +1,23 @@
+package secretsmanager
+
+import "aws-in-a-box/awserrors"
+
+func InvalidParameterException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterException", message)
+}
+
+func InvalidRequestException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidRequestException", message)
+}
+
+func LimitExceededException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("LimitExceededException", message)
+}
+
+func ResourceExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceExistsException", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
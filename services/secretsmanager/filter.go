@@ -0,0 +1,83 @@
+package secretsmanager
+
+import (
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/secretsmanager/latest/userguide/manage_search-secret.html
+
+var filterKeys = []string{"description", "name", "tag-key", "tag-value", "primary-region", "owning-service", "all"}
+
+func validateFilters(filters []APIFilter) *awserrors.Error {
+	if len(filters) > 10 {
+		return InvalidParameterException("You can specify at most 10 filters.")
+	}
+	for _, filter := range filters {
+		if !slices.Contains(filterKeys, filter.Key) {
+			return InvalidParameterException("Invalid filter key: " + filter.Key)
+		}
+		if len(filter.Values) < 1 || len(filter.Values) > 10 {
+			return InvalidParameterException("Filters must have between 1 and 10 values.")
+		}
+	}
+	return nil
+}
+
+// matchesWord returns whether the value is a prefix of the text, or of any word in it.
+// Names like prod/db-password are split into words on punctuation, so "db" matches.
+func matchesWord(text string, value string) bool {
+	text = strings.ToLower(text)
+	value = strings.ToLower(value)
+	if strings.HasPrefix(text, value) {
+		return true
+	}
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return strings.ContainsRune(" /_+=.@-", r)
+	})
+	for _, word := range words {
+		if strings.HasPrefix(word, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether the secret matches the filter value. Tags are case-sensitive,
+// but names and descriptions aren't.
+func (s *Secret) matches(key string, value string) bool {
+	switch key {
+	case "name":
+		return matchesWord(s.Name, value)
+	case "description":
+		return matchesWord(s.Description, value)
+	case "tag-key":
+		return slices.ContainsFunc(s.Tags, func(tag APITag) bool { return strings.HasPrefix(tag.Key, value) })
+	case "tag-value":
+		return slices.ContainsFunc(s.Tags, func(tag APITag) bool { return strings.HasPrefix(tag.Value, value) })
+	case "all":
+		return s.matches("name", value) || s.matches("description", value) ||
+			s.matches("tag-key", value) || s.matches("tag-value", value)
+	}
+	// Secrets aren't replicated or managed by other services.
+	return false
+}
+
+// matchesFilters returns whether the secret matches all the filters,
+// and so any of each filter's values. Values starting with ! match secrets which don't match the rest.
+func (s *Secret) matchesFilters(filters []APIFilter) bool {
+	for _, filter := range filters {
+		matched := slices.ContainsFunc(filter.Values, func(value string) bool {
+			if negated, ok := strings.CutPrefix(value, "!"); ok {
+				return !s.matches(filter.Key, negated)
+			}
+			return s.matches(filter.Key, value)
+		})
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
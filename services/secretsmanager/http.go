@@ -0,0 +1,25 @@
+package secretsmanager
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "secretsmanager"
+
+func (s *SecretsManager) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateSecret", region.Method(s.regions, (*SecretsManager).CreateSecret))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteSecret", region.Method(s.regions, (*SecretsManager).DeleteSecret))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeSecret", region.Method(s.regions, (*SecretsManager).DescribeSecret))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetSecretValue", region.Method(s.regions, (*SecretsManager).GetSecretValue))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListSecretVersionIds", region.Method(s.regions, (*SecretsManager).ListSecretVersionIds))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListSecrets", region.Method(s.regions, (*SecretsManager).ListSecrets))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutSecretValue", region.Method(s.regions, (*SecretsManager).PutSecretValue))
+	http.RegisterWithRequest(logger, methodRegistry, service, "RestoreSecret", region.Method(s.regions, (*SecretsManager).RestoreSecret))
+	http.RegisterWithRequest(logger, methodRegistry, service, "TagResource", region.Method(s.regions, (*SecretsManager).TagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UntagResource", region.Method(s.regions, (*SecretsManager).UntagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateSecret", region.Method(s.regions, (*SecretsManager).UpdateSecret))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateSecretVersionStage", region.Method(s.regions, (*SecretsManager).UpdateSecretVersionStage))
+}
@@ -0,0 +1,700 @@
+package secretsmanager
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/random"
+	"aws-in-a-box/region"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/secretsmanager/latest/userguide/getting-started.html#term_version
+
+const (
+	stageCurrent  = "AWSCURRENT"
+	stagePrevious = "AWSPREVIOUS"
+
+	// Versions without staging labels are deleted once a secret has more than this many versions.
+	maxVersions         = 100
+	maxStagesPerVersion = 20
+	defaultRecoveryDays = 30
+	defaultMaxResults   = 100
+	maxMaxResults       = 100
+
+	// ARNs end with a hyphen and random characters.
+	arnSuffixLength     = 6
+	arnSuffixCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	markedForDeletionErrorMessage = "You can't perform this operation on the secret because it was marked for deletion."
+)
+
+var (
+	secretNameRegex   = regexp.MustCompile(`^[a-zA-Z0-9/_+=.@-]{1,512}$`)
+	versionIdRegex    = regexp.MustCompile(`^[a-zA-Z0-9-]{32,64}$`)
+	versionStageRegex = regexp.MustCompile(`^.{1,256}$`)
+)
+
+type SecretVersion struct {
+	VersionId    string
+	SecretString *string
+	SecretBinary []byte
+	CreatedDate  time.Time
+	// Truncated to the day, like in AWS.
+	LastAccessedDate time.Time
+	Stages           []string
+}
+
+// deprecated versions have no staging labels, and are deleted once there are too many versions.
+func (v *SecretVersion) deprecated() bool {
+	return len(v.Stages) == 0
+}
+
+func (v *SecretVersion) sameValue(secretString *string, secretBinary []byte) bool {
+	if (v.SecretString == nil) != (secretString == nil) {
+		return false
+	}
+	if v.SecretString != nil && *v.SecretString != *secretString {
+		return false
+	}
+	return bytes.Equal(v.SecretBinary, secretBinary)
+}
+
+type Secret struct {
+	Name        string
+	ARN         string
+	Description string
+	KmsKeyId    string
+	Tags        []APITag
+
+	CreatedDate      time.Time
+	LastChangedDate  time.Time
+	LastAccessedDate time.Time
+	// When the secret will be deleted, or zero if it isn't scheduled for deletion.
+	DeletedDate time.Time
+
+	// In the order they were created.
+	Versions []*SecretVersion
+}
+
+func (s *Secret) getVersion(versionId string) *SecretVersion {
+	for _, version := range s.Versions {
+		if version.VersionId == versionId {
+			return version
+		}
+	}
+	return nil
+}
+
+func (s *Secret) versionWithStage(stage string) *SecretVersion {
+	for _, version := range s.Versions {
+		if slices.Contains(version.Stages, stage) {
+			return version
+		}
+	}
+	return nil
+}
+
+// moveStage attaches the staging label to the version, removing it from the version which had it.
+// The version which was AWSCURRENT becomes AWSPREVIOUS.
+func (s *Secret) moveStage(stage string, to *SecretVersion) {
+	from := s.versionWithStage(stage)
+	if from == to {
+		return
+	}
+	if from != nil {
+		from.Stages = slices.DeleteFunc(from.Stages, func(other string) bool { return other == stage })
+	}
+	to.Stages = append(to.Stages, stage)
+	if stage == stageCurrent && from != nil {
+		s.moveStage(stagePrevious, from)
+	}
+}
+
+// removeDeprecatedVersions deletes the oldest versions without staging labels,
+// once there are too many versions.
+func (s *Secret) removeDeprecatedVersions() {
+	excess := len(s.Versions) - maxVersions
+	s.Versions = slices.DeleteFunc(s.Versions, func(v *SecretVersion) bool {
+		if excess > 0 && v.deprecated() {
+			excess--
+			return true
+		}
+		return false
+	})
+}
+
+func (s *Secret) versionIdsToStages() map[string][]string {
+	stages := make(map[string][]string)
+	for _, version := range s.Versions {
+		if !version.deprecated() {
+			stages[version.VersionId] = version.Stages
+		}
+	}
+	return stages
+}
+
+type SecretsManager struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu            sync.Mutex
+	secretsByName map[string]*Secret
+	// Shared by the instances of all regions, which each have their own secrets.
+	regions *region.Regions[*SecretsManager]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *SecretsManager {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*SecretsManager, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *SecretsManager {
+	return &SecretsManager{
+		logger:        options.Logger,
+		arnGenerator:  options.ArnGenerator,
+		clock:         clock.Now,
+		secretsByName: make(map[string]*Secret),
+	}
+}
+
+// InRegion returns the secrets of the region. It fails if the region isn't one of Options.Regions.
+func (s *SecretsManager) InRegion(name string) (*SecretsManager, error) {
+	return s.regions.Get(name)
+}
+
+// secretArn returns an ARN for the secret, which ends with random characters
+// so a new secret with the same name has a different ARN.
+func (s *SecretsManager) secretArn(name string) string {
+	suffix := random.String(arnSuffixCharacters, arnSuffixLength)
+	return s.arnGenerator.GenerateWithoutType("secretsmanager", "secret:"+name+"-"+suffix)
+}
+
+// lockedGetSecret accepts a secret's name, ARN, or ARN without the random suffix.
+// Secrets whose recovery window has passed are deleted.
+func (s *SecretsManager) lockedGetSecret(secretId string) (*Secret, *awserrors.Error) {
+	secret, ok := s.secretsByName[secretId]
+	if !ok && strings.HasPrefix(secretId, "arn:") {
+		for _, candidate := range s.secretsByName {
+			if candidate.ARN == secretId || candidate.ARN[:len(candidate.ARN)-arnSuffixLength-1] == secretId {
+				secret, ok = candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, ResourceNotFoundException("Secrets Manager can't find the specified secret.")
+	}
+	if !secret.DeletedDate.IsZero() && !s.clock().Before(secret.DeletedDate) {
+		delete(s.secretsByName, secret.Name)
+		return nil, ResourceNotFoundException("Secrets Manager can't find the specified secret.")
+	}
+	return secret, nil
+}
+
+// lockedGetActiveSecret is like lockedGetSecret, but fails if the secret is scheduled for deletion.
+func (s *SecretsManager) lockedGetActiveSecret(secretId string) (*Secret, *awserrors.Error) {
+	secret, awserr := s.lockedGetSecret(secretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if !secret.DeletedDate.IsZero() {
+		return nil, InvalidRequestException(markedForDeletionErrorMessage)
+	}
+	return secret, nil
+}
+
+func validateSecretValue(secretString *string, secretBinary []byte) *awserrors.Error {
+	if secretString != nil && len(secretBinary) > 0 {
+		return InvalidParameterException("You can't specify both a binary secret value and a string secret value in the same secret.")
+	}
+	return nil
+}
+
+func validateVersionId(versionId string) *awserrors.Error {
+	if versionId != "" && !versionIdRegex.MatchString(versionId) {
+		return InvalidParameterException("ClientRequestToken must be between 32 and 64 characters, and contain only letters, numbers and hyphens.")
+	}
+	return nil
+}
+
+// lockedAddVersion adds a version with the value and staging labels, unless a version with the ID exists.
+// Adding a version with an existing ID is idempotent if the value is the same.
+func (s *SecretsManager) lockedAddVersion(secret *Secret, versionId string, secretString *string, secretBinary []byte, stages []string) (*SecretVersion, *awserrors.Error) {
+	if versionId == "" {
+		versionId = uuid.Must(uuid.NewV4()).String()
+	}
+	if existing := secret.getVersion(versionId); existing != nil {
+		if !existing.sameValue(secretString, secretBinary) {
+			return nil, ResourceExistsException("You can't modify an existing version, you can only create a new version.")
+		}
+		return existing, nil
+	}
+
+	version := &SecretVersion{
+		VersionId:    versionId,
+		SecretString: secretString,
+		SecretBinary: secretBinary,
+		CreatedDate:  s.clock(),
+	}
+	secret.Versions = append(secret.Versions, version)
+	for _, stage := range stages {
+		secret.moveStage(stage, version)
+	}
+	// The first version is always current.
+	if secret.versionWithStage(stageCurrent) == nil {
+		secret.moveStage(stageCurrent, version)
+	}
+	secret.removeDeprecatedVersions()
+	secret.LastChangedDate = version.CreatedDate
+	return version, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_CreateSecret.html
+func (s *SecretsManager) CreateSecret(input CreateSecretInput) (*CreateSecretOutput, *awserrors.Error) {
+	if !secretNameRegex.MatchString(input.Name) {
+		return nil, InvalidParameterException("Invalid name. Must be a valid name containing alphanumeric characters, or any of the following: -/_+=.@!")
+	}
+	if len(input.AddReplicaRegions) > 0 {
+		return nil, InvalidParameterException("Replicating secrets to other regions is not supported.")
+	}
+	if awserr := validateSecretValue(input.SecretString, input.SecretBinary); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateVersionId(input.ClientRequestToken); awserr != nil {
+		return nil, awserr
+	}
+	tags, awserr := mergeTags(nil, input.Tags)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, awserr := s.lockedGetSecret(input.Name); awserr == nil {
+		if !existing.DeletedDate.IsZero() {
+			return nil, InvalidRequestException("You can't create this secret because a secret with this name is already scheduled for deletion.")
+		}
+		return nil, ResourceExistsException("The operation failed because the secret " + input.Name + " already exists.")
+	}
+
+	now := s.clock()
+	secret := &Secret{
+		Name:            input.Name,
+		ARN:             s.secretArn(input.Name),
+		Description:     input.Description,
+		KmsKeyId:        input.KmsKeyId,
+		Tags:            tags,
+		CreatedDate:     now,
+		LastChangedDate: now,
+	}
+	output := &CreateSecretOutput{
+		ARN:  secret.ARN,
+		Name: secret.Name,
+	}
+	// Secrets can be created without a value, in which case they have no versions.
+	if input.SecretString != nil || len(input.SecretBinary) > 0 {
+		version, awserr := s.lockedAddVersion(secret, input.ClientRequestToken, input.SecretString, input.SecretBinary, []string{stageCurrent})
+		if awserr != nil {
+			return nil, awserr
+		}
+		output.VersionId = version.VersionId
+	}
+	s.secretsByName[secret.Name] = secret
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_DescribeSecret.html
+func (s *SecretsManager) DescribeSecret(input DescribeSecretInput) (*DescribeSecretOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeSecretOutput{
+		ARN:                secret.ARN,
+		Name:               secret.Name,
+		Description:        secret.Description,
+		KmsKeyId:           secret.KmsKeyId,
+		CreatedDate:        timestamp.EpochSeconds(secret.CreatedDate),
+		LastChangedDate:    timestamp.EpochSeconds(secret.LastChangedDate),
+		LastAccessedDate:   timestamp.EpochSeconds(secret.LastAccessedDate),
+		DeletedDate:        timestamp.EpochSeconds(secret.DeletedDate),
+		Tags:               secret.Tags,
+		VersionIdsToStages: secret.versionIdsToStages(),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_GetSecretValue.html
+func (s *SecretsManager) GetSecretValue(input GetSecretValueInput) (*GetSecretValueOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetActiveSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	var version *SecretVersion
+	switch {
+	case input.VersionId != "":
+		version = secret.getVersion(input.VersionId)
+		if version == nil {
+			return nil, ResourceNotFoundException("Secrets Manager can't find the specified secret value for VersionId: " + input.VersionId)
+		}
+		if input.VersionStage != "" && !slices.Contains(version.Stages, input.VersionStage) {
+			return nil, InvalidRequestException("You provided a VersionStage that is not associated to the provided VersionId.")
+		}
+	default:
+		stage := input.VersionStage
+		if stage == "" {
+			stage = stageCurrent
+		}
+		version = secret.versionWithStage(stage)
+		if version == nil {
+			return nil, ResourceNotFoundException("Secrets Manager can't find the specified secret value for staging label: " + stage)
+		}
+	}
+
+	today := s.clock().UTC().Truncate(24 * time.Hour)
+	secret.LastAccessedDate = today
+	version.LastAccessedDate = today
+	return &GetSecretValueOutput{
+		ARN:           secret.ARN,
+		Name:          secret.Name,
+		VersionId:     version.VersionId,
+		SecretString:  version.SecretString,
+		SecretBinary:  version.SecretBinary,
+		VersionStages: version.Stages,
+		CreatedDate:   timestamp.EpochSeconds(version.CreatedDate),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_PutSecretValue.html
+func (s *SecretsManager) PutSecretValue(input PutSecretValueInput) (*PutSecretValueOutput, *awserrors.Error) {
+	if input.SecretString == nil && len(input.SecretBinary) == 0 {
+		return nil, InvalidParameterException("You must provide either SecretString or SecretBinary.")
+	}
+	if awserr := validateSecretValue(input.SecretString, input.SecretBinary); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateVersionId(input.ClientRequestToken); awserr != nil {
+		return nil, awserr
+	}
+	stages := input.VersionStages
+	if stages == nil {
+		stages = []string{stageCurrent}
+	}
+	if len(stages) > maxStagesPerVersion {
+		return nil, LimitExceededException("A version can have at most 20 staging labels.")
+	}
+	for _, stage := range stages {
+		if !versionStageRegex.MatchString(stage) {
+			return nil, InvalidParameterException("Invalid VersionStage: " + stage)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetActiveSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	version, awserr := s.lockedAddVersion(secret, input.ClientRequestToken, input.SecretString, input.SecretBinary, stages)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &PutSecretValueOutput{
+		ARN:           secret.ARN,
+		Name:          secret.Name,
+		VersionId:     version.VersionId,
+		VersionStages: version.Stages,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_UpdateSecret.html
+func (s *SecretsManager) UpdateSecret(input UpdateSecretInput) (*UpdateSecretOutput, *awserrors.Error) {
+	if awserr := validateSecretValue(input.SecretString, input.SecretBinary); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateVersionId(input.ClientRequestToken); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetActiveSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &UpdateSecretOutput{
+		ARN:  secret.ARN,
+		Name: secret.Name,
+	}
+	// Updating the value creates a new current version.
+	if input.SecretString != nil || len(input.SecretBinary) > 0 {
+		version, awserr := s.lockedAddVersion(secret, input.ClientRequestToken, input.SecretString, input.SecretBinary, []string{stageCurrent})
+		if awserr != nil {
+			return nil, awserr
+		}
+		output.VersionId = version.VersionId
+	}
+	if input.Description != nil {
+		secret.Description = *input.Description
+	}
+	if input.KmsKeyId != "" {
+		secret.KmsKeyId = input.KmsKeyId
+	}
+	secret.LastChangedDate = s.clock()
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_UpdateSecretVersionStage.html
+func (s *SecretsManager) UpdateSecretVersionStage(input UpdateSecretVersionStageInput) (*UpdateSecretVersionStageOutput, *awserrors.Error) {
+	if !versionStageRegex.MatchString(input.VersionStage) {
+		return nil, InvalidParameterException("Invalid VersionStage: " + input.VersionStage)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetActiveSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	holder := secret.versionWithStage(input.VersionStage)
+	if input.RemoveFromVersionId != "" && (holder == nil || holder.VersionId != input.RemoveFromVersionId) {
+		return nil, InvalidParameterException("The parameter RemoveFromVersionId doesn't match the version that currently has the staging label " +
+			input.VersionStage + ".")
+	}
+	if input.MoveToVersionId != "" {
+		target := secret.getVersion(input.MoveToVersionId)
+		if target == nil {
+			return nil, ResourceNotFoundException("Secrets Manager can't find the specified secret value for VersionId: " + input.MoveToVersionId)
+		}
+		if holder != nil && holder != target && input.RemoveFromVersionId == "" {
+			return nil, InvalidParameterException("The staging label " + input.VersionStage + " is currently attached to version " +
+				holder.VersionId + ", so you must explicitly reference that version in RemoveFromVersionId.")
+		}
+		if holder != target && len(target.Stages) == maxStagesPerVersion {
+			return nil, LimitExceededException("A version can have at most 20 staging labels.")
+		}
+		secret.moveStage(input.VersionStage, target)
+	} else if holder != nil {
+		if input.VersionStage == stageCurrent {
+			return nil, InvalidParameterException("You can only move staging label AWSCURRENT to a different secret version. It can't be completely removed.")
+		}
+		holder.Stages = slices.DeleteFunc(holder.Stages, func(stage string) bool { return stage == input.VersionStage })
+	}
+	secret.removeDeprecatedVersions()
+	secret.LastChangedDate = s.clock()
+
+	return &UpdateSecretVersionStageOutput{
+		ARN:  secret.ARN,
+		Name: secret.Name,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_ListSecretVersionIds.html
+func (s *SecretsManager) ListSecretVersionIds(input ListSecretVersionIdsInput) (*ListSecretVersionIdsOutput, *awserrors.Error) {
+	maxResults, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxMaxResults, input.NextToken,
+		InvalidParameterException("MaxResults must be between 1 and 100."),
+		InvalidParameterException("Invalid NextToken: "+input.NextToken))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Newest versions are listed first, and the token is the index of the next one.
+	var versions []*SecretVersion
+	for i := len(secret.Versions) - 1; i >= 0; i-- {
+		if input.IncludeDeprecated || !secret.Versions[i].deprecated() {
+			versions = append(versions, secret.Versions[i])
+		}
+	}
+	output := &ListSecretVersionIdsOutput{
+		ARN:      secret.ARN,
+		Name:     secret.Name,
+		Versions: []APISecretVersionsListEntry{},
+	}
+	for i := start; i < len(versions); i++ {
+		if len(output.Versions) == maxResults {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		output.Versions = append(output.Versions, APISecretVersionsListEntry{
+			VersionId:        versions[i].VersionId,
+			VersionStages:    versions[i].Stages,
+			CreatedDate:      timestamp.EpochSeconds(versions[i].CreatedDate),
+			LastAccessedDate: timestamp.EpochSeconds(versions[i].LastAccessedDate),
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_ListSecrets.html
+func (s *SecretsManager) ListSecrets(input ListSecretsInput) (*ListSecretsOutput, *awserrors.Error) {
+	maxResults, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxMaxResults, input.NextToken,
+		InvalidParameterException("MaxResults must be between 1 and 100."),
+		InvalidParameterException("Invalid NextToken: "+input.NextToken))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if input.SortOrder != "" && input.SortOrder != "asc" && input.SortOrder != "desc" {
+		return nil, InvalidParameterException("SortOrder must be asc or desc.")
+	}
+	if awserr := validateFilters(input.Filters); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var secrets []*Secret
+	for name := range s.secretsByName {
+		// Looking up the secret deletes it if its recovery window has passed.
+		secret, awserr := s.lockedGetSecret(name)
+		if awserr != nil || (!secret.DeletedDate.IsZero() && !input.IncludePlannedDeletion) || !secret.matchesFilters(input.Filters) {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	slices.SortFunc(secrets, func(a, b *Secret) int {
+		if c := a.CreatedDate.Compare(b.CreatedDate); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if input.SortOrder == "desc" {
+		slices.Reverse(secrets)
+	}
+
+	output := &ListSecretsOutput{
+		SecretList: []APISecretListEntry{},
+	}
+	for i := start; i < len(secrets); i++ {
+		if len(output.SecretList) == maxResults {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		secret := secrets[i]
+		output.SecretList = append(output.SecretList, APISecretListEntry{
+			ARN:                    secret.ARN,
+			Name:                   secret.Name,
+			Description:            secret.Description,
+			KmsKeyId:               secret.KmsKeyId,
+			CreatedDate:            timestamp.EpochSeconds(secret.CreatedDate),
+			LastChangedDate:        timestamp.EpochSeconds(secret.LastChangedDate),
+			LastAccessedDate:       timestamp.EpochSeconds(secret.LastAccessedDate),
+			DeletedDate:            timestamp.EpochSeconds(secret.DeletedDate),
+			Tags:                   secret.Tags,
+			SecretVersionsToStages: secret.versionIdsToStages(),
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_DeleteSecret.html
+func (s *SecretsManager) DeleteSecret(input DeleteSecretInput) (*DeleteSecretOutput, *awserrors.Error) {
+	if input.ForceDeleteWithoutRecovery && input.RecoveryWindowInDays != 0 {
+		return nil, InvalidParameterException("You can't use ForceDeleteWithoutRecovery in conjunction with RecoveryWindowInDays.")
+	}
+	recoveryDays := input.RecoveryWindowInDays
+	if recoveryDays == 0 {
+		recoveryDays = defaultRecoveryDays
+	}
+	if recoveryDays < 7 || recoveryDays > 30 {
+		return nil, InvalidParameterException("The RecoveryWindowInDays value must be between 7 and 30 days (inclusive).")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	now := s.clock()
+	if input.ForceDeleteWithoutRecovery {
+		delete(s.secretsByName, secret.Name)
+		return &DeleteSecretOutput{
+			ARN:          secret.ARN,
+			Name:         secret.Name,
+			DeletionDate: timestamp.EpochSeconds(now),
+		}, nil
+	}
+	if !secret.DeletedDate.IsZero() {
+		return nil, InvalidRequestException(markedForDeletionErrorMessage)
+	}
+	secret.DeletedDate = now.Add(time.Duration(recoveryDays) * 24 * time.Hour)
+	return &DeleteSecretOutput{
+		ARN:          secret.ARN,
+		Name:         secret.Name,
+		DeletionDate: timestamp.EpochSeconds(secret.DeletedDate),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_RestoreSecret.html
+func (s *SecretsManager) RestoreSecret(input RestoreSecretInput) (*RestoreSecretOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	secret.DeletedDate = time.Time{}
+	return &RestoreSecretOutput{
+		ARN:  secret.ARN,
+		Name: secret.Name,
+	}, nil
+}
@@ -0,0 +1,293 @@
+package secretsmanager
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/state"
+	"aws-in-a-box/timestamp"
+)
+
+func newSecretsManager() *SecretsManager {
+	return New(Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func createSecret(t *testing.T, s *SecretsManager, name string, value string) *CreateSecretOutput {
+	output, awserr := s.CreateSecret(CreateSecretInput{Name: name, SecretString: ptr(value)})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func putSecretValue(t *testing.T, s *SecretsManager, input PutSecretValueInput) *PutSecretValueOutput {
+	output, awserr := s.PutSecretValue(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+// getValue returns the value of the version, by ID or staging label.
+func getValue(t *testing.T, s *SecretsManager, input GetSecretValueInput) string {
+	output, awserr := s.GetSecretValue(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return *output.SecretString
+}
+
+func TestCreateSecret(t *testing.T) {
+	s := newSecretsManager()
+	created := createSecret(t, s, "app/db", "v1")
+	if !strings.HasPrefix(created.ARN, "arn:aws:secretsmanager:us-east-1:123456789012:secret:app/db-") ||
+		len(created.ARN) != len("arn:aws:secretsmanager:us-east-1:123456789012:secret:app/db-")+6 || created.VersionId == "" {
+		t.Fatalf("Unexpected secret: %+v", created)
+	}
+
+	// Secrets can be identified by name, ARN, or ARN without the suffix.
+	for _, id := range []string{"app/db", created.ARN, created.ARN[:len(created.ARN)-7]} {
+		if value := getValue(t, s, GetSecretValueInput{SecretId: id}); value != "v1" {
+			t.Fatalf("Unexpected value for %s: %s", id, value)
+		}
+	}
+
+	_, awserr := s.CreateSecret(CreateSecretInput{Name: "app/db", SecretString: ptr("v2")})
+	if awserr == nil || awserr.Body.Type != "ResourceExistsException" {
+		t.Fatal("Expected exists", awserr)
+	}
+	_, awserr = s.CreateSecret(CreateSecretInput{Name: "both", SecretString: ptr("v"), SecretBinary: []byte("v")})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+
+	// Secrets without a value have no versions.
+	empty, awserr := s.CreateSecret(CreateSecretInput{Name: "empty"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if empty.VersionId != "" {
+		t.Fatal("Unexpected version", empty.VersionId)
+	}
+	_, awserr = s.GetSecretValue(GetSecretValueInput{SecretId: "empty"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestStagingLabels(t *testing.T) {
+	s := newSecretsManager()
+	v1 := createSecret(t, s, "secret", "v1").VersionId
+	v2 := putSecretValue(t, s, PutSecretValueInput{SecretId: "secret", SecretString: ptr("v2")}).VersionId
+	pending := putSecretValue(t, s, PutSecretValueInput{
+		SecretId:      "secret",
+		SecretString:  ptr("v3"),
+		VersionStages: []string{"AWSPENDING"},
+	})
+	if !slices.Equal(pending.VersionStages, []string{"AWSPENDING"}) {
+		t.Fatal("Unexpected stages", pending.VersionStages)
+	}
+
+	expected := map[string]string{"AWSCURRENT": "v2", "AWSPREVIOUS": "v1", "AWSPENDING": "v3"}
+	for stage, value := range expected {
+		if got := getValue(t, s, GetSecretValueInput{SecretId: "secret", VersionStage: stage}); got != value {
+			t.Fatalf("Expected %s to be %s, got %s", stage, value, got)
+		}
+	}
+	if got := getValue(t, s, GetSecretValueInput{SecretId: "secret", VersionId: v1}); got != "v1" {
+		t.Fatal("Unexpected value", got)
+	}
+	_, awserr := s.GetSecretValue(GetSecretValueInput{SecretId: "secret", VersionId: v1, VersionStage: "AWSCURRENT"})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Fatal("Expected invalid request", awserr)
+	}
+
+	// Rotation finishes by moving AWSCURRENT to the pending version.
+	invalid := []UpdateSecretVersionStageInput{
+		{VersionStage: "AWSCURRENT", MoveToVersionId: pending.VersionId},
+		{VersionStage: "AWSCURRENT", MoveToVersionId: pending.VersionId, RemoveFromVersionId: v1},
+		{VersionStage: "AWSCURRENT", RemoveFromVersionId: v2},
+	}
+	for _, input := range invalid {
+		input.SecretId = "secret"
+		if _, awserr := s.UpdateSecretVersionStage(input); awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+			t.Fatalf("Expected invalid parameter for %+v, got %v", input, awserr)
+		}
+	}
+	_, awserr = s.UpdateSecretVersionStage(UpdateSecretVersionStageInput{
+		SecretId:            "secret",
+		VersionStage:        "AWSCURRENT",
+		MoveToVersionId:     pending.VersionId,
+		RemoveFromVersionId: v2,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.UpdateSecretVersionStage(UpdateSecretVersionStageInput{
+		SecretId:            "secret",
+		VersionStage:        "AWSPENDING",
+		RemoveFromVersionId: pending.VersionId,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	described, awserr := s.DescribeSecret(DescribeSecretInput{SecretId: "secret"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	// The first version has no staging labels left, so it's deprecated.
+	if len(described.VersionIdsToStages) != 2 ||
+		!slices.Equal(described.VersionIdsToStages[pending.VersionId], []string{"AWSCURRENT"}) ||
+		!slices.Equal(described.VersionIdsToStages[v2], []string{"AWSPREVIOUS"}) {
+		t.Fatalf("Unexpected stages: %+v", described.VersionIdsToStages)
+	}
+
+	list, awserr := s.ListSecretVersionIds(ListSecretVersionIdsInput{SecretId: "secret", IncludeDeprecated: true, MaxResults: 2})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Versions) != 2 || list.Versions[0].VersionId != pending.VersionId || list.NextToken == "" {
+		t.Fatalf("Unexpected versions: %+v", list)
+	}
+	list, awserr = s.ListSecretVersionIds(ListSecretVersionIdsInput{SecretId: "secret", IncludeDeprecated: true, NextToken: list.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Versions) != 1 || list.Versions[0].VersionId != v1 || len(list.Versions[0].VersionStages) != 0 {
+		t.Fatalf("Unexpected versions: %+v", list)
+	}
+}
+
+func TestClientRequestToken(t *testing.T) {
+	s := newSecretsManager()
+	createSecret(t, s, "secret", "v1")
+
+	token := "EXAMPLE1-90ab-cdef-fedc-ba987SECRET1"
+	first := putSecretValue(t, s, PutSecretValueInput{SecretId: "secret", ClientRequestToken: token, SecretString: ptr("v2")})
+	if first.VersionId != token {
+		t.Fatal("Unexpected version", first.VersionId)
+	}
+	// Retries with the same value are idempotent.
+	retried := putSecretValue(t, s, PutSecretValueInput{SecretId: "secret", ClientRequestToken: token, SecretString: ptr("v2")})
+	if retried.VersionId != token || !slices.Equal(retried.VersionStages, []string{"AWSCURRENT"}) {
+		t.Fatalf("Unexpected version: %+v", retried)
+	}
+	_, awserr := s.PutSecretValue(PutSecretValueInput{SecretId: "secret", ClientRequestToken: token, SecretString: ptr("v3")})
+	if awserr == nil || awserr.Body.Type != "ResourceExistsException" {
+		t.Fatal("Expected exists", awserr)
+	}
+}
+
+func TestDeprecatedVersions(t *testing.T) {
+	s := newSecretsManager()
+	first := createSecret(t, s, "secret", "0").VersionId
+	for i := 0; i < maxVersions+5; i++ {
+		putSecretValue(t, s, PutSecretValueInput{SecretId: "secret", SecretString: ptr("value")})
+	}
+
+	list, awserr := s.ListSecretVersionIds(ListSecretVersionIdsInput{SecretId: "secret", IncludeDeprecated: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Versions) != maxVersions || list.NextToken != "" {
+		t.Fatal("Unexpected number of versions", len(list.Versions))
+	}
+	_, awserr = s.GetSecretValue(GetSecretValueInput{SecretId: "secret", VersionId: first})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestDeleteSecret(t *testing.T) {
+	s := newSecretsManager()
+	now := time.Now()
+	s.clock = func() time.Time { return now }
+	createSecret(t, s, "secret", "v1")
+
+	deleted, awserr := s.DeleteSecret(DeleteSecretInput{SecretId: "secret", RecoveryWindowInDays: 7})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if deleted.DeletionDate != timestamp.EpochSeconds(now.Add(7*24*time.Hour)) {
+		t.Fatal("Unexpected deletion date", deleted.DeletionDate)
+	}
+	_, awserr = s.GetSecretValue(GetSecretValueInput{SecretId: "secret"})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Fatal("Expected invalid request", awserr)
+	}
+	list, awserr := s.ListSecrets(ListSecretsInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.SecretList) != 0 {
+		t.Fatal("Unexpected secrets", list.SecretList)
+	}
+
+	if _, awserr := s.RestoreSecret(RestoreSecretInput{SecretId: "secret"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if value := getValue(t, s, GetSecretValueInput{SecretId: "secret"}); value != "v1" {
+		t.Fatal("Unexpected value", value)
+	}
+
+	// Once the recovery window has passed, the secret is gone.
+	s.DeleteSecret(DeleteSecretInput{SecretId: "secret", RecoveryWindowInDays: 7})
+	now = now.Add(8 * 24 * time.Hour)
+	_, awserr = s.DescribeSecret(DescribeSecretInput{SecretId: "secret"})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+	createSecret(t, s, "secret", "v2")
+}
+
+func TestRegions(t *testing.T) {
+	s := newSecretsManager()
+	createSecret(t, s, "app/db", "us")
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := createSecret(t, europe, "app/db", "eu")
+	if !strings.HasPrefix(created.ARN, "arn:aws:secretsmanager:eu-west-1:123456789012:secret:app/db-") {
+		t.Fatal("Unexpected ARN", created.ARN)
+	}
+	if _, awserr := s.GetSecretValue(GetSecretValueInput{SecretId: created.ARN}); awserr == nil {
+		t.Fatal("Expected eu-west-1's secret not to be in us-east-1")
+	}
+
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"secretsmanager": s}, ""); err != nil {
+		t.Fatal(err)
+	}
+	imported := newSecretsManager()
+	if _, _, err := state.Import(&buf, state.Registry{"secretsmanager": imported}); err != nil {
+		t.Fatal(err)
+	}
+	importedEurope, err := imported.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := getValue(t, imported, GetSecretValueInput{SecretId: "app/db"}); value != "us" {
+		t.Fatal("Unexpected us-east-1 value", value)
+	}
+	if value := getValue(t, importedEurope, GetSecretValueInput{SecretId: "app/db"}); value != "eu" {
+		t.Fatal("Unexpected eu-west-1 value", value)
+	}
+}
@@ -0,0 +1,90 @@
+package secretsmanager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/region"
+	"aws-in-a-box/state"
+)
+
+type secretsState struct {
+	Secrets []*Secret
+}
+
+// StateVersion is 2 since archives have the secrets of every region which was used, with the
+// default region's where version 1 archives had all of them.
+func (s *SecretsManager) StateVersion() int {
+	return 2
+}
+
+func (s *SecretsManager) StateMigrations() []state.Migration {
+	return []state.Migration{
+		func(e *state.Entries) error { return nil },
+	}
+}
+
+// TryPause pauses changes to each region's secrets while a snapshot copies them.
+func (s *SecretsManager) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *SecretsManager) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's secrets, with all their versions and values, to the archive.
+func (s *SecretsManager) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*SecretsManager).exportState)
+}
+
+func (s *SecretsManager) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported secretsState
+	for _, secret := range s.secretsByName {
+		exported.Secrets = append(exported.Secrets, secret)
+	}
+	slices.SortFunc(exported.Secrets, func(a, b *Secret) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("secrets.json", exported)
+}
+
+// ImportState replaces each region's secrets with the archive's.
+func (s *SecretsManager) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*SecretsManager).importState, (*SecretsManager).exportState)
+}
+
+func (s *SecretsManager) importState(r *state.Reader) (func() error, error) {
+	var imported secretsState
+	if err := r.ReadJSON("secrets.json", &imported); err != nil {
+		return nil, err
+	}
+	secretsByName := make(map[string]*Secret)
+	for _, secret := range imported.Secrets {
+		if !secretNameRegex.MatchString(secret.Name) || secret.ARN == "" {
+			return nil, fmt.Errorf("invalid secret %q", secret.Name)
+		}
+		secretsByName[secret.Name] = secret
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.secretsByName = secretsByName
+		return nil
+	}, nil
+}
+
+// StateResources lists the secrets of each region in the archive, for diffing archives.
+var StateResources = region.StateResources(func(r *state.Reader) (map[string]any, error) {
+	var exported secretsState
+	if err := r.ReadJSON("secrets.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, secret := range exported.Secrets {
+		resources["secret/"+secret.Name] = secret
+	}
+	return resources, nil
+})
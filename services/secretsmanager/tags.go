@@ -0,0 +1,83 @@
+package secretsmanager
+
+import (
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/secretsmanager/latest/userguide/managing-secrets_tagging.html
+
+const (
+	maxTags           = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+func validateTags(tags []APITag) *awserrors.Error {
+	for _, tag := range tags {
+		if len(tag.Key) < 1 || len(tag.Key) > maxTagKeyLength {
+			return InvalidParameterException("Tag keys must be between 1 and 128 characters.")
+		}
+		if len(tag.Value) > maxTagValueLength {
+			return InvalidParameterException("Tag values must be at most 256 characters.")
+		}
+		if strings.HasPrefix(strings.ToLower(tag.Key), "aws:") {
+			return InvalidParameterException("Tag keys can't start with aws:, which is reserved for AWS use.")
+		}
+	}
+	return nil
+}
+
+// mergeTags adds the tags, replacing the values of existing keys.
+func mergeTags(existing []APITag, tags []APITag) ([]APITag, *awserrors.Error) {
+	if awserr := validateTags(tags); awserr != nil {
+		return nil, awserr
+	}
+	merged := slices.Clone(existing)
+	for _, tag := range tags {
+		i := slices.IndexFunc(merged, func(t APITag) bool { return t.Key == tag.Key })
+		if i == -1 {
+			merged = append(merged, tag)
+		} else {
+			merged[i].Value = tag.Value
+		}
+	}
+	if len(merged) > maxTags {
+		return nil, LimitExceededException("A secret can have at most 50 tags.")
+	}
+	return merged, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_TagResource.html
+func (s *SecretsManager) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetActiveSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	tags, awserr := mergeTags(secret.Tags, input.Tags)
+	if awserr != nil {
+		return nil, awserr
+	}
+	secret.Tags = tags
+	return &TagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_UntagResource.html
+func (s *SecretsManager) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, awserr := s.lockedGetActiveSecret(input.SecretId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	secret.Tags = slices.DeleteFunc(slices.Clone(secret.Tags), func(tag APITag) bool {
+		return slices.Contains(input.TagKeys, tag.Key)
+	})
+	return &UntagResourceOutput{}, nil
+}
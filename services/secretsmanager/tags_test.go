@@ -0,0 +1,96 @@
+package secretsmanager
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTagResource(t *testing.T) {
+	s := newSecretsManager()
+	_, awserr := s.CreateSecret(CreateSecretInput{
+		Name: "secret",
+		Tags: []APITag{{Key: "team", Value: "payments"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	_, awserr = s.TagResource(TagResourceInput{
+		SecretId: "secret",
+		Tags:     []APITag{{Key: "team", Value: "billing"}, {Key: "env", Value: "prod"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.UntagResource(UntagResourceInput{SecretId: "secret", TagKeys: []string{"env", "missing"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	described, awserr := s.DescribeSecret(DescribeSecretInput{SecretId: "secret"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !slices.Equal(described.Tags, []APITag{{Key: "team", Value: "billing"}}) {
+		t.Fatal("Unexpected tags", described.Tags)
+	}
+
+	_, awserr = s.TagResource(TagResourceInput{SecretId: "secret", Tags: []APITag{{Key: "aws:reserved", Value: "v"}}})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+	_, awserr = s.TagResource(TagResourceInput{SecretId: "missing", Tags: []APITag{{Key: "k", Value: "v"}}})
+	if awserr == nil || awserr.Body.Type != "ResourceNotFoundException" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+func TestListSecretsFilters(t *testing.T) {
+	s := newSecretsManager()
+	secrets := []CreateSecretInput{
+		{Name: "prod/db-password", Description: "Database password", Tags: []APITag{{Key: "Env", Value: "prod"}}},
+		{Name: "prod/api-key", Tags: []APITag{{Key: "Env", Value: "prod"}, {Key: "Team", Value: "payments"}}},
+		{Name: "dev/db-password", Tags: []APITag{{Key: "Env", Value: "dev"}}},
+	}
+	for _, input := range secrets {
+		if _, awserr := s.CreateSecret(input); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	cases := []struct {
+		filters  []APIFilter
+		expected []string
+	}{
+		{[]APIFilter{{Key: "name", Values: []string{"prod"}}}, []string{"prod/api-key", "prod/db-password"}},
+		// Words in names match, case-insensitively.
+		{[]APIFilter{{Key: "name", Values: []string{"DB"}}}, []string{"dev/db-password", "prod/db-password"}},
+		{[]APIFilter{{Key: "description", Values: []string{"database"}}}, []string{"prod/db-password"}},
+		{[]APIFilter{{Key: "tag-key", Values: []string{"Team"}}}, []string{"prod/api-key"}},
+		// Tags are case-sensitive.
+		{[]APIFilter{{Key: "tag-key", Values: []string{"team"}}}, nil},
+		{[]APIFilter{{Key: "tag-value", Values: []string{"dev", "pay"}}}, []string{"dev/db-password", "prod/api-key"}},
+		// All filters must match.
+		{[]APIFilter{{Key: "tag-value", Values: []string{"prod"}}, {Key: "name", Values: []string{"db"}}}, []string{"prod/db-password"}},
+		{[]APIFilter{{Key: "name", Values: []string{"!prod"}}}, []string{"dev/db-password"}},
+		{[]APIFilter{{Key: "all", Values: []string{"payments"}}}, []string{"prod/api-key"}},
+	}
+	for _, c := range cases {
+		output, awserr := s.ListSecrets(ListSecretsInput{Filters: c.filters})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		var names []string
+		for _, secret := range output.SecretList {
+			names = append(names, secret.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, c.expected) {
+			t.Fatalf("Expected %v for %+v, got %v", c.expected, c.filters, names)
+		}
+	}
+
+	_, awserr := s.ListSecrets(ListSecretsInput{Filters: []APIFilter{{Key: "owner", Values: []string{"me"}}}})
+	if awserr == nil || awserr.Body.Type != "InvalidParameterException" {
+		t.Fatal("Expected invalid parameter", awserr)
+	}
+}
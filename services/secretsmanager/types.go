@@ -0,0 +1,201 @@
+package secretsmanager
+
+type APITag struct {
+	Key   string `length:"1,128" error:"InvalidParameterException"`
+	Value string `length:",256" error:"InvalidParameterException"`
+}
+
+type APIReplicaRegion struct {
+	Region   string `length:"1,128" pattern:"[a-z0-9-]+" error:"InvalidParameterException"`
+	KmsKeyId string `length:",2048" error:"InvalidParameterException"`
+}
+
+type CreateSecretInput struct {
+	Name string `required:"true" length:"1,512" error:"InvalidParameterException"`
+	// Used as the ID of the first version.
+	ClientRequestToken          string  `length:"32,64" error:"InvalidParameterException"`
+	Description                 string  `length:",2048" error:"InvalidParameterException"`
+	KmsKeyId                    string  `length:",2048" error:"InvalidParameterException"`
+	SecretBinary                []byte  `length:",65536" error:"InvalidParameterException"`
+	SecretString                *string `length:",65536" error:"InvalidParameterException"`
+	Tags                        []APITag
+	AddReplicaRegions           []APIReplicaRegion `length:"1," error:"InvalidParameterException"`
+	ForceOverwriteReplicaSecret bool
+}
+
+type CreateSecretOutput struct {
+	ARN       string
+	Name      string
+	VersionId string `json:",omitempty"`
+}
+
+type DescribeSecretInput struct {
+	SecretId string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+}
+
+type DescribeSecretOutput struct {
+	ARN                string
+	Name               string
+	Description        string   `json:",omitempty"`
+	KmsKeyId           string   `json:",omitempty"`
+	CreatedDate        float64  `json:",omitempty"`
+	LastChangedDate    float64  `json:",omitempty"`
+	LastAccessedDate   float64  `json:",omitempty"`
+	DeletedDate        float64  `json:",omitempty"`
+	Tags               []APITag `json:",omitempty"`
+	VersionIdsToStages map[string][]string
+}
+
+type GetSecretValueInput struct {
+	SecretId     string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	VersionId    string `length:"32,64" error:"InvalidParameterException"`
+	VersionStage string `length:"1,256" error:"InvalidParameterException"`
+}
+
+type GetSecretValueOutput struct {
+	ARN           string
+	Name          string
+	VersionId     string
+	SecretBinary  []byte  `json:",omitempty"`
+	SecretString  *string `json:",omitempty"`
+	VersionStages []string
+	CreatedDate   float64
+}
+
+type PutSecretValueInput struct {
+	SecretId string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	// Used as the ID of the new version.
+	ClientRequestToken string  `length:"32,64" error:"InvalidParameterException"`
+	SecretBinary       []byte  `length:",65536" error:"InvalidParameterException"`
+	SecretString       *string `length:",65536" error:"InvalidParameterException"`
+	// Defaults to AWSCURRENT.
+	VersionStages []string `length:"1,20" error:"InvalidParameterException"`
+}
+
+type PutSecretValueOutput struct {
+	ARN           string
+	Name          string
+	VersionId     string
+	VersionStages []string
+}
+
+type UpdateSecretInput struct {
+	SecretId string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	// Used as the ID of the new version, if the value is updated.
+	ClientRequestToken string  `length:"32,64" error:"InvalidParameterException"`
+	Description        *string `length:",2048" error:"InvalidParameterException"`
+	KmsKeyId           string  `length:",2048" error:"InvalidParameterException"`
+	SecretBinary       []byte  `length:",65536" error:"InvalidParameterException"`
+	SecretString       *string `length:",65536" error:"InvalidParameterException"`
+}
+
+type UpdateSecretOutput struct {
+	ARN       string
+	Name      string
+	VersionId string `json:",omitempty"`
+}
+
+type UpdateSecretVersionStageInput struct {
+	SecretId            string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	VersionStage        string `required:"true" length:"1,256" error:"InvalidParameterException"`
+	MoveToVersionId     string `length:"32,64" error:"InvalidParameterException"`
+	RemoveFromVersionId string `length:"32,64" error:"InvalidParameterException"`
+}
+
+type UpdateSecretVersionStageOutput struct {
+	ARN  string
+	Name string
+}
+
+type ListSecretVersionIdsInput struct {
+	SecretId string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	// Whether to list versions without staging labels, which are deleted once there are too many.
+	IncludeDeprecated bool
+	MaxResults        int    `range:"1,100" error:"InvalidParameterException"`
+	NextToken         string `length:"1,4096" error:"InvalidParameterException"`
+}
+
+type APISecretVersionsListEntry struct {
+	VersionId        string
+	VersionStages    []string
+	CreatedDate      float64
+	LastAccessedDate float64 `json:",omitempty"`
+}
+
+type ListSecretVersionIdsOutput struct {
+	ARN       string
+	Name      string
+	Versions  []APISecretVersionsListEntry
+	NextToken string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_Filter.html
+type APIFilter struct {
+	// description, name, tag-key, tag-value, primary-region, owning-service or all.
+	Key string `enum:"description|name|tag-key|tag-value|primary-region|owning-service|all" error:"InvalidParameterException"`
+	// Secrets which match any of the values match the filter. Values starting with ! negate the match.
+	Values []string `length:"1,10" error:"InvalidParameterException"`
+}
+
+type ListSecretsInput struct {
+	// Secrets must match all the filters.
+	Filters                []APIFilter
+	IncludePlannedDeletion bool
+	MaxResults             int    `range:"1,100" error:"InvalidParameterException"`
+	NextToken              string `length:"1,4096" error:"InvalidParameterException"`
+	// asc or desc, by creation date.
+	SortOrder string `enum:"asc|desc" error:"InvalidParameterException"`
+}
+
+type APISecretListEntry struct {
+	ARN                    string
+	Name                   string
+	Description            string   `json:",omitempty"`
+	KmsKeyId               string   `json:",omitempty"`
+	CreatedDate            float64  `json:",omitempty"`
+	LastChangedDate        float64  `json:",omitempty"`
+	LastAccessedDate       float64  `json:",omitempty"`
+	DeletedDate            float64  `json:",omitempty"`
+	Tags                   []APITag `json:",omitempty"`
+	SecretVersionsToStages map[string][]string
+}
+
+type ListSecretsOutput struct {
+	SecretList []APISecretListEntry
+	NextToken  string `json:",omitempty"`
+}
+
+type DeleteSecretInput struct {
+	SecretId                   string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	RecoveryWindowInDays       int64  `range:"7,30" error:"InvalidParameterException"`
+	ForceDeleteWithoutRecovery bool
+}
+
+type DeleteSecretOutput struct {
+	ARN          string
+	Name         string
+	DeletionDate float64
+}
+
+type RestoreSecretInput struct {
+	SecretId string `required:"true" length:"1,2048" error:"InvalidParameterException"`
+}
+
+type RestoreSecretOutput struct {
+	ARN  string
+	Name string
+}
+
+type TagResourceInput struct {
+	SecretId string   `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	Tags     []APITag `required:"true" error:"InvalidParameterException"`
+}
+
+type TagResourceOutput struct{}
+
+type UntagResourceInput struct {
+	SecretId string   `required:"true" length:"1,2048" error:"InvalidParameterException"`
+	TagKeys  []string `required:"true" error:"InvalidParameterException"`
+}
+
+type UntagResourceOutput struct{}
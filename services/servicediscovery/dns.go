@@ -0,0 +1,135 @@
+package servicediscovery
+
+import (
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/services/route53"
+)
+
+var labelRegex = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?$`)
+
+type recordKey struct {
+	name       string
+	recordType string
+}
+
+// dnsName returns the fully qualified, lowercase DNS name of the labels.
+func dnsName(labels ...string) string {
+	return strings.ToLower(strings.Join(labels, ".")) + "."
+}
+
+// validHostname reports whether every label of the name, without its trailing dot, is valid.
+func validHostname(name string) bool {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if !labelRegex.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// dnsInstances returns the instances DNS queries are answered with: the healthy ones or, if none
+// are, all of them, like Route 53 does for multivalue answers.
+func dnsInstances(service *Service) []*Instance {
+	instances := sortedInstances(service)
+	if filtered := healthy(instances, false); len(filtered) > 0 {
+		return filtered
+	}
+	return instances
+}
+
+// lockedDesiredRecords returns the record sets the namespace's hosted zone should have for its
+// services' instances. Each name and type has one record set with every instance's value, so the
+// DNS server answers with all of them.
+func (s *ServiceDiscovery) lockedDesiredRecords(namespace *Namespace) map[recordKey]route53.APIResourceRecordSet {
+	desired := make(map[recordKey]route53.APIResourceRecordSet)
+	add := func(name, recordType string, ttl int64, value string) {
+		key := recordKey{name, recordType}
+		set, ok := desired[key]
+		if !ok {
+			set = route53.APIResourceRecordSet{Name: name, Type: recordType, TTL: &ttl}
+		}
+		set.ResourceRecords = append(set.ResourceRecords, route53.APIResourceRecord{Value: value})
+		desired[key] = set
+	}
+
+	for _, service := range s.services {
+		if service.NamespaceId != namespace.Id || service.DnsConfig == nil {
+			continue
+		}
+		name := dnsName(service.Name, namespace.Name)
+		for _, instance := range dnsInstances(service) {
+			for _, record := range service.DnsConfig.DnsRecords {
+				switch record.Type {
+				case "A":
+					add(name, "A", record.TTL, instance.Attributes[ipv4Attribute])
+				case "AAAA":
+					add(name, "AAAA", record.TTL, instance.Attributes[ipv6Attribute])
+				case "CNAME":
+					// A name has at most one CNAME, so it's the first instance's.
+					if _, ok := desired[recordKey{name, "CNAME"}]; ok {
+						continue
+					}
+					add(name, "CNAME", record.TTL, instance.Attributes[cnameAttribute])
+				case "SRV":
+					// The SRV record targets a name for the instance, which has its addresses.
+					hostname := dnsName(instance.Id, service.Name, namespace.Name)
+					if !validHostname(hostname) {
+						continue
+					}
+					add(name, "SRV", record.TTL, "1 1 "+instance.Attributes[portAttribute]+" "+hostname)
+					if ip, ok := instance.Attributes[ipv4Attribute]; ok {
+						add(hostname, "A", record.TTL, ip)
+					}
+					if ip, ok := instance.Attributes[ipv6Attribute]; ok {
+						add(hostname, "AAAA", record.TTL, ip)
+					}
+				}
+			}
+		}
+	}
+	return desired
+}
+
+// lockedSyncRecords updates the namespace's hosted zone to have the record sets of its services'
+// instances. Failures are logged rather than returned, since the instances are registered either way.
+func (s *ServiceDiscovery) lockedSyncRecords(namespace *Namespace) {
+	if namespace == nil || namespace.HostedZoneId == "" {
+		return
+	}
+	desired := s.lockedDesiredRecords(namespace)
+	var changes []route53.APIChange
+	for key, set := range desired {
+		if existing, ok := namespace.records[key]; !ok || !reflect.DeepEqual(existing, set) {
+			changes = append(changes, route53.APIChange{Action: "UPSERT", ResourceRecordSet: set})
+		}
+	}
+	for key, set := range namespace.records {
+		if _, ok := desired[key]; !ok {
+			changes = append(changes, route53.APIChange{Action: "DELETE", ResourceRecordSet: set})
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	slices.SortFunc(changes, func(a, b route53.APIChange) int {
+		return strings.Compare(a.ResourceRecordSet.Name+a.ResourceRecordSet.Type, b.ResourceRecordSet.Name+b.ResourceRecordSet.Type)
+	})
+
+	_, awserr := s.route53.ChangeResourceRecordSets(route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: namespace.HostedZoneId,
+		ChangeBatch: route53.APIChangeBatch{
+			Comment: "Cloud Map namespace " + namespace.Id,
+			Changes: changes,
+		},
+	})
+	if awserr != nil {
+		s.logger.Warn("Updating namespace's DNS records", "namespace", namespace.Id, "error", awserr.Body.Message)
+		return
+	}
+	namespace.records = maps.Clone(desired)
+}
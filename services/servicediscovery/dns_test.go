@@ -0,0 +1,100 @@
+package servicediscovery
+
+import (
+	"slices"
+	"testing"
+
+	"aws-in-a-box/services/route53"
+)
+
+// records returns the zone's record sets as "name type value..." strings, skipping its NS and SOA.
+func records(t *testing.T, r *route53.Route53, zoneId string) []string {
+	output, awserr := r.ListResourceRecordSets(route53.ListResourceRecordSetsInput{HostedZoneId: zoneId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var sets []string
+	for _, set := range output.ResourceRecordSets {
+		if set.Type == "NS" || set.Type == "SOA" {
+			continue
+		}
+		s := set.Name + " " + set.Type
+		for _, record := range set.ResourceRecords {
+			s += " " + record.Value
+		}
+		sets = append(sets, s)
+	}
+	slices.Sort(sets)
+	return sets
+}
+
+func TestDNSRecords(t *testing.T) {
+	r := route53.New(route53.Options{})
+	s := newServiceDiscovery(t, Options{Route53: r})
+	output, awserr := s.CreatePrivateDnsNamespace(CreatePrivateDnsNamespaceInput{Name: "shop.local", Vpc: "vpc-1"})
+	id := namespaceId(t, s, output, awserr)
+	namespace, awserr := s.GetNamespace(GetNamespaceInput{Id: id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	zoneId := namespace.Namespace.Properties.DnsProperties.HostedZoneId
+	if zoneId == "" {
+		t.Fatal("No hosted zone")
+	}
+
+	serviceId := createService(t, s, CreateServiceInput{
+		Name:        "api",
+		NamespaceId: id,
+		DnsConfig: &APIDnsConfig{DnsRecords: []APIDnsRecord{
+			{Type: "A", TTL: 10},
+			{Type: "SRV", TTL: 10},
+		}},
+		HealthCheckCustomConfig: &APIHealthCheckCustomConfig{},
+	})
+	registerInstance(t, s, serviceId, "task-1", map[string]string{"AWS_INSTANCE_IPV4": "10.0.0.1", "AWS_INSTANCE_PORT": "8080"})
+	registerInstance(t, s, serviceId, "task-2", map[string]string{"AWS_INSTANCE_IPV4": "10.0.0.2", "AWS_INSTANCE_PORT": "8080"})
+
+	got := records(t, r, zoneId)
+	want := []string{
+		"api.shop.local. A 10.0.0.1 10.0.0.2",
+		"api.shop.local. SRV 1 1 8080 task-1.api.shop.local. 1 1 8080 task-2.api.shop.local.",
+		"task-1.api.shop.local. A 10.0.0.1",
+		"task-2.api.shop.local. A 10.0.0.2",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Unexpected records\n%q\nwant\n%q", got, want)
+	}
+
+	// Unhealthy instances are left out, as long as some are healthy.
+	_, awserr = s.UpdateInstanceCustomHealthStatus(UpdateInstanceCustomHealthStatusInput{ServiceId: serviceId, InstanceId: "task-1", Status: "UNHEALTHY"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	got = records(t, r, zoneId)
+	want = []string{
+		"api.shop.local. A 10.0.0.2",
+		"api.shop.local. SRV 1 1 8080 task-2.api.shop.local.",
+		"task-2.api.shop.local. A 10.0.0.2",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Unexpected records\n%q\nwant\n%q", got, want)
+	}
+
+	for _, instanceId := range []string{"task-1", "task-2"} {
+		if _, awserr := s.DeregisterInstance(DeregisterInstanceInput{ServiceId: serviceId, InstanceId: instanceId}); awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+	if got := records(t, r, zoneId); len(got) != 0 {
+		t.Fatalf("Unexpected records %q", got)
+	}
+	if _, awserr := s.DeleteService(DeleteServiceInput{Id: serviceId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.DeleteNamespace(DeleteNamespaceInput{Id: id}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := r.ListResourceRecordSets(route53.ListResourceRecordSetsInput{HostedZoneId: zoneId}); awserr == nil {
+		t.Fatal("Expected the hosted zone to be deleted")
+	}
+}
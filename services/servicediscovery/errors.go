@@ -0,0 +1,43 @@
+package servicediscovery
+
+import "aws-in-a-box/awserrors"
+
+func CustomHealthNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("CustomHealthNotFound", message)
+}
+
+func InstanceNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InstanceNotFound", message)
+}
+
+func InvalidInput(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidInput", message)
+}
+
+func NamespaceAlreadyExists(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("NamespaceAlreadyExists", message)
+}
+
+func NamespaceNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("NamespaceNotFound", message)
+}
+
+func OperationNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("OperationNotFound", message)
+}
+
+func ResourceInUse(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceInUse", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
+
+func ServiceAlreadyExists(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ServiceAlreadyExists", message)
+}
+
+func ServiceNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ServiceNotFound", message)
+}
@@ -0,0 +1,37 @@
+package servicediscovery
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "Route53AutoNaming_v20170314"
+
+func (s *ServiceDiscovery) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateHttpNamespace", region.Method(s.regions, (*ServiceDiscovery).CreateHttpNamespace))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreatePrivateDnsNamespace", region.Method(s.regions, (*ServiceDiscovery).CreatePrivateDnsNamespace))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreatePublicDnsNamespace", region.Method(s.regions, (*ServiceDiscovery).CreatePublicDnsNamespace))
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateService", region.Method(s.regions, (*ServiceDiscovery).CreateService))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteNamespace", region.Method(s.regions, (*ServiceDiscovery).DeleteNamespace))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteService", region.Method(s.regions, (*ServiceDiscovery).DeleteService))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeregisterInstance", region.Method(s.regions, (*ServiceDiscovery).DeregisterInstance))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DiscoverInstances", region.Method(s.regions, (*ServiceDiscovery).DiscoverInstances))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DiscoverInstancesRevision", region.Method(s.regions, (*ServiceDiscovery).DiscoverInstancesRevision))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetInstance", region.Method(s.regions, (*ServiceDiscovery).GetInstance))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetInstancesHealthStatus", region.Method(s.regions, (*ServiceDiscovery).GetInstancesHealthStatus))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetNamespace", region.Method(s.regions, (*ServiceDiscovery).GetNamespace))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetOperation", region.Method(s.regions, (*ServiceDiscovery).GetOperation))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetService", region.Method(s.regions, (*ServiceDiscovery).GetService))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListInstances", region.Method(s.regions, (*ServiceDiscovery).ListInstances))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListNamespaces", region.Method(s.regions, (*ServiceDiscovery).ListNamespaces))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListOperations", region.Method(s.regions, (*ServiceDiscovery).ListOperations))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListServices", region.Method(s.regions, (*ServiceDiscovery).ListServices))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListTagsForResource", region.Method(s.regions, (*ServiceDiscovery).ListTagsForResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "RegisterInstance", region.Method(s.regions, (*ServiceDiscovery).RegisterInstance))
+	http.RegisterWithRequest(logger, methodRegistry, service, "TagResource", region.Method(s.regions, (*ServiceDiscovery).TagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UntagResource", region.Method(s.regions, (*ServiceDiscovery).UntagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateInstanceCustomHealthStatus", region.Method(s.regions, (*ServiceDiscovery).UpdateInstanceCustomHealthStatus))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateService", region.Method(s.regions, (*ServiceDiscovery).UpdateService))
+}
@@ -0,0 +1,398 @@
+package servicediscovery
+
+import (
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+const (
+	maxAttributes          = 30
+	defaultDiscoverResults = 100
+	maxDiscoverResults     = 1000
+	ipv4Attribute          = "AWS_INSTANCE_IPV4"
+	ipv6Attribute          = "AWS_INSTANCE_IPV6"
+	portAttribute          = "AWS_INSTANCE_PORT"
+	cnameAttribute         = "AWS_INSTANCE_CNAME"
+	initHealthAttribute    = "AWS_INIT_HEALTH_STATUS"
+	aliasDNSNameAttribute  = "AWS_ALIAS_DNS_NAME"
+)
+
+var instanceIdRegex = regexp.MustCompile(`^[0-9a-zA-Z_/:.@-]{1,64}$`)
+
+type Instance struct {
+	Id               string
+	CreatorRequestId string
+	Attributes       map[string]string
+	// HEALTHY or UNHEALTHY for services with health checks, and UNKNOWN otherwise.
+	HealthStatus string
+}
+
+func (s *ServiceDiscovery) lockedGetInstance(serviceId, instanceId string) (*Service, *Instance, *awserrors.Error) {
+	service, awserr := s.lockedGetService(serviceId)
+	if awserr != nil {
+		return nil, nil, awserr
+	}
+	instance, ok := service.instances[instanceId]
+	if !ok {
+		return nil, nil, InstanceNotFound("Instance not found: " + instanceId)
+	}
+	return service, instance, nil
+}
+
+// validateAttributes checks that the instance has the attributes its service's DNS records need.
+func validateAttributes(service *Service, attributes map[string]string) *awserrors.Error {
+	if len(attributes) > maxAttributes {
+		return InvalidInput("An instance can have at most 30 attributes")
+	}
+	for key, value := range attributes {
+		if len(key) > 255 || len(value) > 1024 {
+			return InvalidInput("Attribute keys can be at most 255 characters, and values 1024")
+		}
+	}
+	if ip, ok := attributes[ipv4Attribute]; ok && net.ParseIP(ip).To4() == nil {
+		return InvalidInput("Invalid " + ipv4Attribute + ": " + ip)
+	}
+	if ip, ok := attributes[ipv6Attribute]; ok && (net.ParseIP(ip) == nil || net.ParseIP(ip).To4() != nil) {
+		return InvalidInput("Invalid " + ipv6Attribute + ": " + ip)
+	}
+	if port, ok := attributes[portAttribute]; ok {
+		if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			return InvalidInput("Invalid " + portAttribute + ": " + port)
+		}
+	}
+	if status, ok := attributes[initHealthAttribute]; ok {
+		if service.HealthCheckCustomConfig == nil {
+			return InvalidInput(initHealthAttribute + " can only be given for services with a custom health check")
+		}
+		if status != "HEALTHY" && status != "UNHEALTHY" {
+			return InvalidInput(initHealthAttribute + " must be HEALTHY or UNHEALTHY")
+		}
+	}
+	if service.DnsConfig == nil {
+		return nil
+	}
+	if _, ok := attributes[aliasDNSNameAttribute]; ok {
+		return InvalidInput(aliasDNSNameAttribute + " is not supported")
+	}
+	for _, record := range service.DnsConfig.DnsRecords {
+		var required string
+		switch record.Type {
+		case "A":
+			required = ipv4Attribute
+		case "AAAA":
+			required = ipv6Attribute
+		case "CNAME":
+			required = cnameAttribute
+		case "SRV":
+			required = portAttribute
+		}
+		if _, ok := attributes[required]; !ok {
+			return InvalidInput("Instances of a service with " + record.Type + " records need " + required)
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_RegisterInstance.html
+func (s *ServiceDiscovery) RegisterInstance(input RegisterInstanceInput) (*OperationOutput, *awserrors.Error) {
+	if !instanceIdRegex.MatchString(input.InstanceId) {
+		return nil, InvalidInput("Invalid InstanceId: " + input.InstanceId)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, awserr := s.lockedGetService(input.ServiceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateAttributes(service, input.Attributes); awserr != nil {
+		return nil, awserr
+	}
+	if service.DnsConfig != nil && slices.ContainsFunc(service.DnsConfig.DnsRecords, func(r APIDnsRecord) bool { return r.Type == "CNAME" }) {
+		for id := range service.instances {
+			if id != input.InstanceId {
+				return nil, InvalidInput("A service with a CNAME record can only have one instance")
+			}
+		}
+	}
+
+	// Registering an existing instance again replaces its attributes.
+	instance := &Instance{
+		Id:               input.InstanceId,
+		CreatorRequestId: input.CreatorRequestId,
+		Attributes:       maps.Clone(input.Attributes),
+		HealthStatus:     "UNKNOWN",
+	}
+	if instance.Attributes == nil {
+		instance.Attributes = make(map[string]string)
+	}
+	if service.HealthCheckConfig != nil || service.HealthCheckCustomConfig != nil {
+		instance.HealthStatus = "HEALTHY"
+		if status, ok := instance.Attributes[initHealthAttribute]; ok {
+			instance.HealthStatus = status
+		}
+	}
+	service.instances[instance.Id] = instance
+	service.revision++
+	s.lockedSyncRecords(s.namespaces[service.NamespaceId])
+	operationId := s.lockedAddOperation("REGISTER_INSTANCE", map[string]string{
+		"NAMESPACE": service.NamespaceId,
+		"SERVICE":   service.Id,
+		"INSTANCE":  instance.Id,
+	})
+	return &OperationOutput{OperationId: operationId}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_DeregisterInstance.html
+func (s *ServiceDiscovery) DeregisterInstance(input DeregisterInstanceInput) (*OperationOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, instance, awserr := s.lockedGetInstance(input.ServiceId, input.InstanceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	delete(service.instances, instance.Id)
+	service.revision++
+	s.lockedSyncRecords(s.namespaces[service.NamespaceId])
+	operationId := s.lockedAddOperation("DEREGISTER_INSTANCE", map[string]string{
+		"NAMESPACE": service.NamespaceId,
+		"SERVICE":   service.Id,
+		"INSTANCE":  instance.Id,
+	})
+	return &OperationOutput{OperationId: operationId}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_GetInstance.html
+func (s *ServiceDiscovery) GetInstance(input GetInstanceInput) (*GetInstanceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, instance, awserr := s.lockedGetInstance(input.ServiceId, input.InstanceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetInstanceOutput{Instance: APIInstance{
+		Id:               instance.Id,
+		CreatorRequestId: instance.CreatorRequestId,
+		Attributes:       maps.Clone(instance.Attributes),
+	}}, nil
+}
+
+func sortedInstances(service *Service) []*Instance {
+	instances := make([]*Instance, 0, len(service.instances))
+	for _, instance := range service.instances {
+		instances = append(instances, instance)
+	}
+	slices.SortFunc(instances, func(a, b *Instance) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return instances
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_ListInstances.html
+func (s *ServiceDiscovery) ListInstances(input ListInstancesInput) (*ListInstancesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, awserr := s.lockedGetService(input.ServiceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var instances []APIInstanceSummary
+	for _, instance := range sortedInstances(service) {
+		instances = append(instances, APIInstanceSummary{Id: instance.Id, Attributes: maps.Clone(instance.Attributes)})
+	}
+	page, nextToken, awserr := paginate(instances, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListInstancesOutput{Instances: page, NextToken: nextToken}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_GetInstancesHealthStatus.html
+func (s *ServiceDiscovery) GetInstancesHealthStatus(input GetInstancesHealthStatusInput) (*GetInstancesHealthStatusOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, awserr := s.lockedGetService(input.ServiceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var ids []string
+	if len(input.Instances) > 0 {
+		for _, id := range input.Instances {
+			if _, ok := service.instances[id]; !ok {
+				return nil, InstanceNotFound("Instance not found: " + id)
+			}
+		}
+		ids = input.Instances
+	} else {
+		for _, instance := range sortedInstances(service) {
+			ids = append(ids, instance.Id)
+		}
+	}
+	page, nextToken, awserr := paginate(ids, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &GetInstancesHealthStatusOutput{
+		Status:    make(map[string]string),
+		NextToken: nextToken,
+	}
+	for _, id := range page {
+		output.Status[id] = service.instances[id].HealthStatus
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_UpdateInstanceCustomHealthStatus.html
+func (s *ServiceDiscovery) UpdateInstanceCustomHealthStatus(input UpdateInstanceCustomHealthStatusInput) (*UpdateInstanceCustomHealthStatusOutput, *awserrors.Error) {
+	if input.Status != "HEALTHY" && input.Status != "UNHEALTHY" {
+		return nil, InvalidInput("Status must be HEALTHY or UNHEALTHY")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, instance, awserr := s.lockedGetInstance(input.ServiceId, input.InstanceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if service.HealthCheckCustomConfig == nil {
+		return nil, CustomHealthNotFound("Service " + service.Id + " doesn't have a custom health check")
+	}
+	if instance.HealthStatus != input.Status {
+		instance.HealthStatus = input.Status
+		service.revision++
+		s.lockedSyncRecords(s.namespaces[service.NamespaceId])
+	}
+	return &UpdateInstanceCustomHealthStatusOutput{}, nil
+}
+
+// lockedDiscoverService finds the service with the name in the namespace with the name.
+func (s *ServiceDiscovery) lockedDiscoverService(namespaceName, serviceName string) (*Namespace, *Service, *awserrors.Error) {
+	if namespaceName == "" || serviceName == "" {
+		return nil, nil, InvalidInput("NamespaceName and ServiceName are required")
+	}
+	var namespace *Namespace
+	for _, candidate := range s.namespaces {
+		if strings.EqualFold(candidate.Name, strings.TrimSuffix(namespaceName, ".")) {
+			namespace = candidate
+		}
+	}
+	if namespace == nil {
+		return nil, nil, NamespaceNotFound("Namespace not found: " + namespaceName)
+	}
+	for _, service := range s.services {
+		if service.NamespaceId == namespace.Id && strings.EqualFold(service.Name, serviceName) {
+			return namespace, service, nil
+		}
+	}
+	return nil, nil, ServiceNotFound("Service not found: " + serviceName)
+}
+
+func hasAttributes(instance *Instance, attributes map[string]string) bool {
+	for key, value := range attributes {
+		if instance.Attributes[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_DiscoverInstances.html
+func (s *ServiceDiscovery) DiscoverInstances(input DiscoverInstancesInput) (*DiscoverInstancesOutput, *awserrors.Error) {
+	maxResults := input.MaxResults
+	if maxResults == 0 {
+		maxResults = defaultDiscoverResults
+	}
+	if maxResults < 1 || maxResults > maxDiscoverResults {
+		return nil, InvalidInput("MaxResults must be between 1 and 1000")
+	}
+	healthStatus := input.HealthStatus
+	switch healthStatus {
+	case "":
+		healthStatus = "HEALTHY"
+	case "HEALTHY", "UNHEALTHY", "ALL", "HEALTHY_OR_ELSE_ALL":
+	default:
+		return nil, InvalidInput("HealthStatus must be HEALTHY, UNHEALTHY, ALL or HEALTHY_OR_ELSE_ALL")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	namespace, service, awserr := s.lockedDiscoverService(input.NamespaceName, input.ServiceName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var instances []*Instance
+	for _, instance := range sortedInstances(service) {
+		if hasAttributes(instance, input.QueryParameters) {
+			instances = append(instances, instance)
+		}
+	}
+	if len(input.OptionalParameters) > 0 {
+		optional := slices.DeleteFunc(slices.Clone(instances), func(instance *Instance) bool {
+			return !hasAttributes(instance, input.OptionalParameters)
+		})
+		if len(optional) > 0 {
+			instances = optional
+		}
+	}
+	// Health status filters are ignored for services without health checks.
+	if service.HealthCheckConfig != nil || service.HealthCheckCustomConfig != nil {
+		filtered := healthy(instances, healthStatus == "UNHEALTHY")
+		if healthStatus == "HEALTHY_OR_ELSE_ALL" && len(filtered) == 0 {
+			filtered = instances
+		}
+		if healthStatus != "ALL" {
+			instances = filtered
+		}
+	}
+
+	output := &DiscoverInstancesOutput{
+		Instances:         []APIHttpInstanceSummary{},
+		InstancesRevision: service.revision,
+	}
+	for _, instance := range instances[:min(maxResults, len(instances))] {
+		output.Instances = append(output.Instances, APIHttpInstanceSummary{
+			InstanceId:    instance.Id,
+			NamespaceName: namespace.Name,
+			ServiceName:   service.Name,
+			HealthStatus:  instance.HealthStatus,
+			Attributes:    maps.Clone(instance.Attributes),
+		})
+	}
+	return output, nil
+}
+
+// healthy returns the instances which are healthy, or unhealthy if unhealthy is true.
+func healthy(instances []*Instance, unhealthy bool) []*Instance {
+	var filtered []*Instance
+	for _, instance := range instances {
+		if (instance.HealthStatus == "UNHEALTHY") == unhealthy {
+			filtered = append(filtered, instance)
+		}
+	}
+	return filtered
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_DiscoverInstancesRevision.html
+func (s *ServiceDiscovery) DiscoverInstancesRevision(input DiscoverInstancesRevisionInput) (*DiscoverInstancesRevisionOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, service, awserr := s.lockedDiscoverService(input.NamespaceName, input.ServiceName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DiscoverInstancesRevisionOutput{InstancesRevision: service.revision}, nil
+}
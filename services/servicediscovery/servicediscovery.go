@@ -0,0 +1,540 @@
+package servicediscovery
+
+import (
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/random"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/route53"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultMaxResults = 100
+	// The SOA TTL of DNS namespaces which don't give one.
+	defaultSOATTL = 15
+	// The oldest operations are forgotten beyond this many.
+	maxOperations = 10000
+	idCharacters  = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+var (
+	// HTTP namespace names are printable ASCII without spaces.
+	nameRegex = regexp.MustCompile(`^[!-~]+$`)
+	// DNS namespace names are domain names.
+	dnsNameRegex = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.)*[a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.?$`)
+)
+
+// HostedZones is the local Route 53 service, which holds the records of DNS namespaces so its
+// embedded DNS server answers for their services.
+type HostedZones interface {
+	CreateHostedZone(input route53.CreateHostedZoneInput) (*route53.CreateHostedZoneOutput, *awserrors.Error)
+	DeleteHostedZone(input route53.DeleteHostedZoneInput) (*route53.DeleteHostedZoneOutput, *awserrors.Error)
+	ChangeResourceRecordSets(input route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, *awserrors.Error)
+}
+
+type Namespace struct {
+	Id               string
+	Arn              string
+	Name             string
+	Type             string
+	Description      string
+	CreatorRequestId string
+	CreateDate       time.Time
+	Tags             map[string]string
+	// Only for DNS namespaces.
+	HostedZoneId string
+	SOATTL       int64
+	// The record sets written to the hosted zone, keyed by name and type, so they can be deleted.
+	records map[recordKey]route53.APIResourceRecordSet
+}
+
+type Operation struct {
+	Id         string
+	Type       string
+	CreateDate time.Time
+	Targets    map[string]string
+}
+
+type ServiceDiscovery struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	route53      HostedZones
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu sync.Mutex
+	// Keyed by ID.
+	namespaces map[string]*Namespace
+	services   map[string]*Service
+	operations map[string]*Operation
+	// Operation IDs in the order they were created.
+	operationIds []string
+	// Shared by the instances of all regions, which each have their own namespaces, services and
+	// operations.
+	regions *region.Regions[*ServiceDiscovery]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// If set, DNS namespaces create hosted zones in it, and registered instances are answered for
+	// by its DNS server.
+	Route53 HostedZones
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *ServiceDiscovery {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Route 53 is global, so every region's DNS namespaces have hosted zones in it.
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*ServiceDiscovery, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *ServiceDiscovery {
+	return &ServiceDiscovery{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		route53:      options.Route53,
+		clock:        clock.Now,
+		namespaces:   make(map[string]*Namespace),
+		services:     make(map[string]*Service),
+		operations:   make(map[string]*Operation),
+	}
+}
+
+// InRegion returns the namespaces, services and operations of the region. It fails if the region
+// isn't one of Options.Regions.
+func (s *ServiceDiscovery) InRegion(name string) (*ServiceDiscovery, error) {
+	return s.regions.Get(name)
+}
+
+func tagMap(tags []APITag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}
+
+// paginate returns a page of items, with the token for the next page.
+func paginate[T any](items []T, maxResults int, nextToken string) ([]T, string, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(maxResults, defaultMaxResults, math.MaxInt, nextToken,
+		InvalidInput("MaxResults must be at least 1"),
+		InvalidInput("Invalid NextToken"))
+	if awserr != nil {
+		return nil, "", awserr
+	}
+	page, next := pagination.Page(items, limit, start)
+	return page, next, nil
+}
+
+// matchFilter reports whether the value matches the filter's condition, which defaults to EQ.
+func matchFilter(filter APIFilter, value string) (bool, *awserrors.Error) {
+	switch filter.Condition {
+	case "", "EQ":
+		if len(filter.Values) != 1 {
+			return false, InvalidInput("The EQ condition takes exactly one value")
+		}
+		return value == filter.Values[0], nil
+	case "IN":
+		return slices.Contains(filter.Values, value), nil
+	case "BEGINS_WITH":
+		if len(filter.Values) != 1 {
+			return false, InvalidInput("The BEGINS_WITH condition takes exactly one value")
+		}
+		return strings.HasPrefix(value, filter.Values[0]), nil
+	}
+	return false, InvalidInput("Condition must be EQ, IN or BEGINS_WITH")
+}
+
+// lockedAddOperation records a completed operation. Operations complete immediately, so they're
+// only kept so GetOperation can report them.
+func (s *ServiceDiscovery) lockedAddOperation(operationType string, targets map[string]string) string {
+	operation := &Operation{
+		Id:         random.String(idCharacters, 32),
+		Type:       operationType,
+		CreateDate: s.clock(),
+		Targets:    targets,
+	}
+	s.operations[operation.Id] = operation
+	s.operationIds = append(s.operationIds, operation.Id)
+	if len(s.operationIds) > maxOperations {
+		delete(s.operations, s.operationIds[0])
+		s.operationIds = s.operationIds[1:]
+	}
+	return operation.Id
+}
+
+func (n *Namespace) toAPI(serviceCount int) APINamespace {
+	output := APINamespace{
+		Arn:          n.Arn,
+		Id:           n.Id,
+		Name:         n.Name,
+		Type:         n.Type,
+		Description:  n.Description,
+		ServiceCount: serviceCount,
+		Properties: APINamespaceProperties{
+			HttpProperties: APIHttpProperties{HttpName: n.Name},
+		},
+		CreateDate:       timestamp.EpochSeconds(n.CreateDate),
+		CreatorRequestId: n.CreatorRequestId,
+	}
+	if n.Type != "HTTP" {
+		output.Properties.DnsProperties = &APIDnsProperties{
+			HostedZoneId: n.HostedZoneId,
+			SOA:          &APISOA{TTL: n.SOATTL},
+		}
+	}
+	return output
+}
+
+func (s *ServiceDiscovery) lockedNamespaceToAPI(namespace *Namespace) APINamespace {
+	serviceCount := 0
+	for _, service := range s.services {
+		if service.NamespaceId == namespace.Id {
+			serviceCount++
+		}
+	}
+	return namespace.toAPI(serviceCount)
+}
+
+func (s *ServiceDiscovery) lockedGetNamespace(id string) (*Namespace, *awserrors.Error) {
+	namespace, ok := s.namespaces[id]
+	if !ok {
+		return nil, NamespaceNotFound("Namespace not found: " + id)
+	}
+	return namespace, nil
+}
+
+// lockedCreateNamespace creates a namespace, and its hosted zone if it's a DNS namespace.
+func (s *ServiceDiscovery) lockedCreateNamespace(namespace *Namespace, vpc string, tags []APITag) (*OperationOutput, *awserrors.Error) {
+	if namespace.Type == "HTTP" {
+		if !nameRegex.MatchString(namespace.Name) || len(namespace.Name) > 1024 {
+			return nil, InvalidInput("Invalid namespace name: " + namespace.Name)
+		}
+	} else {
+		namespace.Name = strings.TrimSuffix(namespace.Name, ".")
+		if !dnsNameRegex.MatchString(namespace.Name) || len(namespace.Name) > 253 {
+			return nil, InvalidInput("Invalid DNS namespace name: " + namespace.Name)
+		}
+	}
+	if len(namespace.Description) > 1024 {
+		return nil, InvalidInput("Description must be at most 1024 characters")
+	}
+	for _, existing := range s.namespaces {
+		if strings.EqualFold(existing.Name, namespace.Name) {
+			return nil, NamespaceAlreadyExists("Namespace already exists: " + existing.Id)
+		}
+	}
+
+	namespace.Id = "ns-" + random.String(idCharacters, 16)
+	namespace.Arn = s.arnGenerator.Generate("servicediscovery", "namespace", namespace.Id)
+	namespace.CreateDate = s.clock()
+	namespace.Tags = tagMap(tags)
+	namespace.records = make(map[recordKey]route53.APIResourceRecordSet)
+	if namespace.Type != "HTTP" && s.route53 != nil {
+		input := route53.CreateHostedZoneInput{
+			Name:            namespace.Name,
+			CallerReference: namespace.Id,
+			HostedZoneConfig: &route53.APIHostedZoneConfig{
+				Comment:     "Created by Cloud Map namespace " + namespace.Id,
+				PrivateZone: namespace.Type == "DNS_PRIVATE",
+			},
+		}
+		if namespace.Type == "DNS_PRIVATE" {
+			input.VPC = &route53.APIVPC{VPCId: vpc, VPCRegion: s.arnGenerator.Region}
+		}
+		output, awserr := s.route53.CreateHostedZone(input)
+		if awserr != nil {
+			return nil, InvalidInput("Creating the namespace's hosted zone: " + awserr.Body.Message)
+		}
+		namespace.HostedZoneId = strings.TrimPrefix(output.HostedZone.Id, "/hostedzone/")
+	}
+	s.namespaces[namespace.Id] = namespace
+	operationId := s.lockedAddOperation("CREATE_NAMESPACE", map[string]string{"NAMESPACE": namespace.Id})
+	return &OperationOutput{OperationId: operationId}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_CreateHttpNamespace.html
+func (s *ServiceDiscovery) CreateHttpNamespace(input CreateHttpNamespaceInput) (*OperationOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lockedCreateNamespace(&Namespace{
+		Name:             input.Name,
+		Type:             "HTTP",
+		Description:      input.Description,
+		CreatorRequestId: input.CreatorRequestId,
+	}, "", input.Tags)
+}
+
+func soaTTL(properties *APIDnsNamespaceProperties) int64 {
+	if properties != nil && properties.DnsProperties != nil && properties.DnsProperties.SOA != nil {
+		return properties.DnsProperties.SOA.TTL
+	}
+	return defaultSOATTL
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_CreatePrivateDnsNamespace.html
+func (s *ServiceDiscovery) CreatePrivateDnsNamespace(input CreatePrivateDnsNamespaceInput) (*OperationOutput, *awserrors.Error) {
+	if input.Vpc == "" {
+		return nil, InvalidInput("Vpc is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lockedCreateNamespace(&Namespace{
+		Name:             input.Name,
+		Type:             "DNS_PRIVATE",
+		Description:      input.Description,
+		CreatorRequestId: input.CreatorRequestId,
+		SOATTL:           soaTTL(input.Properties),
+	}, input.Vpc, input.Tags)
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_CreatePublicDnsNamespace.html
+func (s *ServiceDiscovery) CreatePublicDnsNamespace(input CreatePublicDnsNamespaceInput) (*OperationOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lockedCreateNamespace(&Namespace{
+		Name:             input.Name,
+		Type:             "DNS_PUBLIC",
+		Description:      input.Description,
+		CreatorRequestId: input.CreatorRequestId,
+		SOATTL:           soaTTL(input.Properties),
+	}, "", input.Tags)
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_GetNamespace.html
+func (s *ServiceDiscovery) GetNamespace(input GetNamespaceInput) (*GetNamespaceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	namespace, awserr := s.lockedGetNamespace(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetNamespaceOutput{Namespace: s.lockedNamespaceToAPI(namespace)}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_ListNamespaces.html
+func (s *ServiceDiscovery) ListNamespaces(input ListNamespacesInput) (*ListNamespacesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var namespaces []APINamespace
+	for _, namespace := range s.namespaces {
+		matched := true
+		for _, filter := range input.Filters {
+			var value string
+			switch filter.Name {
+			case "TYPE":
+				value = namespace.Type
+			case "NAME", "HTTP_NAME":
+				value = namespace.Name
+			default:
+				return nil, InvalidInput("Filter Name must be TYPE, NAME or HTTP_NAME")
+			}
+			ok, awserr := matchFilter(filter, value)
+			if awserr != nil {
+				return nil, awserr
+			}
+			matched = matched && ok
+		}
+		if matched {
+			namespaces = append(namespaces, s.lockedNamespaceToAPI(namespace))
+		}
+	}
+	slices.SortFunc(namespaces, func(a, b APINamespace) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, nextToken, awserr := paginate(namespaces, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListNamespacesOutput{Namespaces: page, NextToken: nextToken}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_DeleteNamespace.html
+func (s *ServiceDiscovery) DeleteNamespace(input DeleteNamespaceInput) (*OperationOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	namespace, awserr := s.lockedGetNamespace(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, service := range s.services {
+		if service.NamespaceId == namespace.Id {
+			return nil, ResourceInUse("Namespace " + namespace.Id + " has services")
+		}
+	}
+	if namespace.HostedZoneId != "" {
+		if _, awserr := s.route53.DeleteHostedZone(route53.DeleteHostedZoneInput{Id: namespace.HostedZoneId}); awserr != nil {
+			s.logger.Warn("Deleting namespace's hosted zone", "namespace", namespace.Id, "error", awserr.Body.Message)
+		}
+	}
+	delete(s.namespaces, namespace.Id)
+	operationId := s.lockedAddOperation("DELETE_NAMESPACE", map[string]string{"NAMESPACE": namespace.Id})
+	return &OperationOutput{OperationId: operationId}, nil
+}
+
+func (s *ServiceDiscovery) lockedOperationToAPI(operation *Operation) APIOperation {
+	return APIOperation{
+		Id:         operation.Id,
+		Type:       operation.Type,
+		Status:     "SUCCESS",
+		CreateDate: timestamp.EpochSeconds(operation.CreateDate),
+		UpdateDate: timestamp.EpochSeconds(operation.CreateDate),
+		Targets:    operation.Targets,
+	}
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_GetOperation.html
+func (s *ServiceDiscovery) GetOperation(input GetOperationInput) (*GetOperationOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation, ok := s.operations[input.OperationId]
+	if !ok {
+		return nil, OperationNotFound("Operation not found: " + input.OperationId)
+	}
+	return &GetOperationOutput{Operation: s.lockedOperationToAPI(operation)}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_ListOperations.html
+func (s *ServiceDiscovery) ListOperations(input ListOperationsInput) (*ListOperationsOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var operations []APIOperationSummary
+	// The most recent operations come first.
+	for i := len(s.operationIds) - 1; i >= 0; i-- {
+		operation := s.operations[s.operationIds[i]]
+		matched := true
+		for _, filter := range input.Filters {
+			var value string
+			switch filter.Name {
+			case "NAMESPACE_ID":
+				value = operation.Targets["NAMESPACE"]
+			case "SERVICE_ID":
+				value = operation.Targets["SERVICE"]
+			case "STATUS":
+				value = "SUCCESS"
+			case "TYPE":
+				value = operation.Type
+			case "UPDATE_DATE":
+				// Operations complete when they're created, so every one is in any date range.
+				continue
+			default:
+				return nil, InvalidInput("Filter Name must be NAMESPACE_ID, SERVICE_ID, STATUS, TYPE or UPDATE_DATE")
+			}
+			ok, awserr := matchFilter(filter, value)
+			if awserr != nil {
+				return nil, awserr
+			}
+			matched = matched && ok
+		}
+		if matched {
+			operations = append(operations, APIOperationSummary{Id: operation.Id, Status: "SUCCESS"})
+		}
+	}
+	page, nextToken, awserr := paginate(operations, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListOperationsOutput{Operations: page, NextToken: nextToken}, nil
+}
+
+// lockedGetTags returns the tags of the namespace or service with the ARN.
+func (s *ServiceDiscovery) lockedGetTags(resourceArn string) (map[string]string, *awserrors.Error) {
+	for _, namespace := range s.namespaces {
+		if namespace.Arn == resourceArn {
+			return namespace.Tags, nil
+		}
+	}
+	for _, service := range s.services {
+		if service.Arn == resourceArn {
+			return service.Tags, nil
+		}
+	}
+	return nil, ResourceNotFoundException("Resource not found: " + resourceArn)
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_TagResource.html
+func (s *ServiceDiscovery) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, awserr := s.lockedGetTags(input.ResourceARN)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, tag := range input.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return &TagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_UntagResource.html
+func (s *ServiceDiscovery) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, awserr := s.lockedGetTags(input.ResourceARN)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for _, key := range input.TagKeys {
+		delete(tags, key)
+	}
+	return &UntagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_ListTagsForResource.html
+func (s *ServiceDiscovery) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, awserr := s.lockedGetTags(input.ResourceARN)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListTagsForResourceOutput{
+		Tags: []APITag{},
+	}
+	for key, value := range tags {
+		output.Tags = append(output.Tags, APITag{Key: key, Value: value})
+	}
+	slices.SortFunc(output.Tags, func(a, b APITag) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return output, nil
+}
@@ -0,0 +1,238 @@
+package servicediscovery
+
+import (
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+)
+
+func newServiceDiscovery(t *testing.T, options Options) *ServiceDiscovery {
+	options.ArnGenerator = arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}
+	s := New(options)
+	s.clock = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	return s
+}
+
+// namespaceId returns the ID of the namespace created by the operation.
+func namespaceId(t *testing.T, s *ServiceDiscovery, output *OperationOutput, awserr *awserrors.Error) string {
+	t.Helper()
+	if output == nil {
+		t.Fatal(awserr)
+	}
+	operation, err := s.GetOperation(GetOperationInput{OperationId: output.OperationId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operation.Operation.Status != "SUCCESS" || operation.Operation.Type != "CREATE_NAMESPACE" {
+		t.Fatalf("Unexpected operation %+v", operation.Operation)
+	}
+	return operation.Operation.Targets["NAMESPACE"]
+}
+
+func createHttpNamespace(t *testing.T, s *ServiceDiscovery, name string) string {
+	output, awserr := s.CreateHttpNamespace(CreateHttpNamespaceInput{Name: name})
+	return namespaceId(t, s, output, awserr)
+}
+
+func createService(t *testing.T, s *ServiceDiscovery, input CreateServiceInput) string {
+	output, awserr := s.CreateService(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Service.Id
+}
+
+func registerInstance(t *testing.T, s *ServiceDiscovery, serviceId, instanceId string, attributes map[string]string) {
+	_, awserr := s.RegisterInstance(RegisterInstanceInput{ServiceId: serviceId, InstanceId: instanceId, Attributes: attributes})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func discoveredIds(t *testing.T, s *ServiceDiscovery, input DiscoverInstancesInput) []string {
+	output, awserr := s.DiscoverInstances(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var ids []string
+	for _, instance := range output.Instances {
+		ids = append(ids, instance.InstanceId)
+	}
+	return ids
+}
+
+func TestNamespacesAndServices(t *testing.T) {
+	s := newServiceDiscovery(t, Options{})
+	id := createHttpNamespace(t, s, "shop")
+	_, awserr := s.CreateHttpNamespace(CreateHttpNamespaceInput{Name: "shop"})
+	if awserr == nil || awserr.Body.Type != "NamespaceAlreadyExists" {
+		t.Fatalf("Expected NamespaceAlreadyExists, got %v", awserr)
+	}
+
+	_, awserr = s.CreateService(CreateServiceInput{
+		Name:        "api",
+		NamespaceId: id,
+		DnsConfig:   &APIDnsConfig{DnsRecords: []APIDnsRecord{{Type: "A", TTL: 60}}},
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidInput" {
+		t.Fatalf("Expected InvalidInput, got %v", awserr)
+	}
+	serviceId := createService(t, s, CreateServiceInput{Name: "api", NamespaceId: id})
+	_, awserr = s.CreateService(CreateServiceInput{Name: "api", NamespaceId: id})
+	if awserr == nil || awserr.Body.Type != "ServiceAlreadyExists" {
+		t.Fatalf("Expected ServiceAlreadyExists, got %v", awserr)
+	}
+
+	namespace, awserr := s.GetNamespace(GetNamespaceInput{Id: id})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if namespace.Namespace.ServiceCount != 1 || namespace.Namespace.Properties.HttpProperties.HttpName != "shop" {
+		t.Fatalf("Unexpected namespace %+v", namespace.Namespace)
+	}
+	services, awserr := s.ListServices(ListServicesInput{Filters: []APIFilter{{Name: "NAMESPACE_ID", Values: []string{id}}}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(services.Services) != 1 || services.Services[0].Type != "HTTP" {
+		t.Fatalf("Unexpected services %+v", services.Services)
+	}
+
+	registerInstance(t, s, serviceId, "i-1", nil)
+	_, awserr = s.DeleteService(DeleteServiceInput{Id: serviceId})
+	if awserr == nil || awserr.Body.Type != "ResourceInUse" {
+		t.Fatalf("Expected ResourceInUse, got %v", awserr)
+	}
+	_, awserr = s.DeleteNamespace(DeleteNamespaceInput{Id: id})
+	if awserr == nil || awserr.Body.Type != "ResourceInUse" {
+		t.Fatalf("Expected ResourceInUse, got %v", awserr)
+	}
+	if _, awserr := s.DeregisterInstance(DeregisterInstanceInput{ServiceId: serviceId, InstanceId: "i-1"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.DeleteService(DeleteServiceInput{Id: serviceId}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.DeleteNamespace(DeleteNamespaceInput{Id: id}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.GetNamespace(GetNamespaceInput{Id: id})
+	if awserr == nil || awserr.Body.Type != "NamespaceNotFound" {
+		t.Fatalf("Expected NamespaceNotFound, got %v", awserr)
+	}
+}
+
+func TestDiscoverInstances(t *testing.T) {
+	s := newServiceDiscovery(t, Options{})
+	id := createHttpNamespace(t, s, "shop")
+	serviceId := createService(t, s, CreateServiceInput{
+		Name:                    "api",
+		NamespaceId:             id,
+		HealthCheckCustomConfig: &APIHealthCheckCustomConfig{},
+	})
+	registerInstance(t, s, serviceId, "i-1", map[string]string{"stage": "prod", "zone": "a"})
+	registerInstance(t, s, serviceId, "i-2", map[string]string{"stage": "prod", "zone": "b"})
+	registerInstance(t, s, serviceId, "i-3", map[string]string{"stage": "dev", "AWS_INIT_HEALTH_STATUS": "UNHEALTHY"})
+
+	ids := discoveredIds(t, s, DiscoverInstancesInput{NamespaceName: "shop", ServiceName: "api"})
+	if len(ids) != 2 || ids[0] != "i-1" || ids[1] != "i-2" {
+		t.Fatalf("Unexpected instances %v", ids)
+	}
+	ids = discoveredIds(t, s, DiscoverInstancesInput{
+		NamespaceName:      "shop",
+		ServiceName:        "api",
+		QueryParameters:    map[string]string{"stage": "prod"},
+		OptionalParameters: map[string]string{"zone": "b"},
+	})
+	if len(ids) != 1 || ids[0] != "i-2" {
+		t.Fatalf("Unexpected instances %v", ids)
+	}
+	// Optional parameters are ignored if no instance has them.
+	ids = discoveredIds(t, s, DiscoverInstancesInput{
+		NamespaceName:      "shop",
+		ServiceName:        "api",
+		OptionalParameters: map[string]string{"zone": "c"},
+	})
+	if len(ids) != 2 {
+		t.Fatalf("Unexpected instances %v", ids)
+	}
+
+	revision, awserr := s.DiscoverInstancesRevision(DiscoverInstancesRevisionInput{NamespaceName: "shop", ServiceName: "api"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.UpdateInstanceCustomHealthStatus(UpdateInstanceCustomHealthStatusInput{ServiceId: serviceId, InstanceId: "i-1", Status: "UNHEALTHY"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	updated, awserr := s.DiscoverInstancesRevision(DiscoverInstancesRevisionInput{NamespaceName: "shop", ServiceName: "api"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if updated.InstancesRevision <= revision.InstancesRevision {
+		t.Fatalf("Revision didn't change: %d", updated.InstancesRevision)
+	}
+	ids = discoveredIds(t, s, DiscoverInstancesInput{NamespaceName: "shop", ServiceName: "api", HealthStatus: "UNHEALTHY"})
+	if len(ids) != 2 || ids[0] != "i-1" || ids[1] != "i-3" {
+		t.Fatalf("Unexpected instances %v", ids)
+	}
+
+	health, awserr := s.GetInstancesHealthStatus(GetInstancesHealthStatusInput{ServiceId: serviceId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if health.Status["i-1"] != "UNHEALTHY" || health.Status["i-2"] != "HEALTHY" {
+		t.Fatalf("Unexpected health %v", health.Status)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := newServiceDiscovery(t, Options{})
+	output, awserr := s.CreateHttpNamespace(CreateHttpNamespaceInput{Name: "shop", Tags: []APITag{{Key: "team", Value: "web"}}})
+	id := namespaceId(t, s, output, awserr)
+	resourceArn := "arn:aws:servicediscovery:us-east-1:123456789012:namespace/" + id
+
+	if _, awserr := s.TagResource(TagResourceInput{ResourceARN: resourceArn, Tags: []APITag{{Key: "stage", Value: "prod"}}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.UntagResource(UntagResourceInput{ResourceARN: resourceArn, TagKeys: []string{"team"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := s.ListTagsForResource(ListTagsForResourceInput{ResourceARN: resourceArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != (APITag{Key: "stage", Value: "prod"}) {
+		t.Fatalf("Unexpected tags %+v", tags.Tags)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	s := newServiceDiscovery(t, Options{})
+	id := createHttpNamespace(t, s, "shop")
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.GetNamespace(GetNamespaceInput{Id: id}); awserr == nil {
+		t.Fatal("Expected the namespace not to be in eu-west-1")
+	}
+	europeId := createHttpNamespace(t, europe, "shop")
+	namespace, awserr := europe.GetNamespace(GetNamespaceInput{Id: europeId})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if namespace.Namespace.Arn != "arn:aws:servicediscovery:eu-west-1:123456789012:namespace/"+europeId {
+		t.Fatal("Unexpected ARN", namespace.Namespace.Arn)
+	}
+	if _, awserr := s.GetNamespace(GetNamespaceInput{Id: europeId}); awserr == nil {
+		t.Fatal("Expected the namespace not to be in us-east-1")
+	}
+}
@@ -0,0 +1,296 @@
+package servicediscovery
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/random"
+	"aws-in-a-box/timestamp"
+)
+
+var dnsRecordTypes = []string{"A", "AAAA", "CNAME", "SRV"}
+
+type Service struct {
+	Id                      string
+	Arn                     string
+	Name                    string
+	NamespaceId             string
+	Description             string
+	DnsConfig               *APIDnsConfig
+	Type                    string
+	HealthCheckConfig       *APIHealthCheckConfig
+	HealthCheckCustomConfig *APIHealthCheckCustomConfig
+	CreatorRequestId        string
+	CreateDate              time.Time
+	Tags                    map[string]string
+	// Keyed by ID.
+	instances map[string]*Instance
+	// Incremented whenever the instances or their health change.
+	revision int64
+}
+
+func (s *Service) toAPI() APIService {
+	return APIService{
+		Arn:                     s.Arn,
+		Id:                      s.Id,
+		Name:                    s.Name,
+		NamespaceId:             s.NamespaceId,
+		Description:             s.Description,
+		InstanceCount:           len(s.instances),
+		DnsConfig:               s.DnsConfig,
+		Type:                    s.Type,
+		HealthCheckConfig:       s.HealthCheckConfig,
+		HealthCheckCustomConfig: s.HealthCheckCustomConfig,
+		CreateDate:              timestamp.EpochSeconds(s.CreateDate),
+		CreatorRequestId:        s.CreatorRequestId,
+	}
+}
+
+func (s *ServiceDiscovery) lockedGetService(id string) (*Service, *awserrors.Error) {
+	service, ok := s.services[id]
+	if !ok {
+		return nil, ServiceNotFound("Service not found: " + id)
+	}
+	return service, nil
+}
+
+func validateDnsRecords(records []APIDnsRecord) *awserrors.Error {
+	if len(records) == 0 {
+		return InvalidInput("DnsRecords must have at least one record")
+	}
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if !slices.Contains(dnsRecordTypes, record.Type) {
+			return InvalidInput("DnsRecord Type must be A, AAAA, CNAME or SRV")
+		}
+		if seen[record.Type] {
+			return InvalidInput("DnsRecords can only have one record of each type")
+		}
+		seen[record.Type] = true
+		if record.TTL < 0 || record.TTL > 2147483647 {
+			return InvalidInput("DnsRecord TTL must be between 0 and 2147483647")
+		}
+	}
+	if seen["CNAME"] && len(records) > 1 {
+		return InvalidInput("A CNAME record can't be combined with other records")
+	}
+	return nil
+}
+
+func validateDnsConfig(config *APIDnsConfig) *awserrors.Error {
+	switch config.RoutingPolicy {
+	case "":
+		config.RoutingPolicy = "MULTIVALUE"
+	case "MULTIVALUE", "WEIGHTED":
+	default:
+		return InvalidInput("RoutingPolicy must be MULTIVALUE or WEIGHTED")
+	}
+	if awserr := validateDnsRecords(config.DnsRecords); awserr != nil {
+		return awserr
+	}
+	if config.RoutingPolicy == "MULTIVALUE" && slices.ContainsFunc(config.DnsRecords, func(r APIDnsRecord) bool { return r.Type == "CNAME" }) {
+		return InvalidInput("CNAME records require the WEIGHTED routing policy")
+	}
+	return nil
+}
+
+func validateHealthCheckConfig(config *APIHealthCheckConfig) *awserrors.Error {
+	switch config.Type {
+	case "HTTP", "HTTPS", "TCP":
+	default:
+		return InvalidInput("HealthCheckConfig Type must be HTTP, HTTPS or TCP")
+	}
+	if config.FailureThreshold < 0 || config.FailureThreshold > 10 {
+		return InvalidInput("FailureThreshold must be between 1 and 10")
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_CreateService.html
+func (s *ServiceDiscovery) CreateService(input CreateServiceInput) (*CreateServiceOutput, *awserrors.Error) {
+	if !dnsNameRegex.MatchString(input.Name) || strings.HasSuffix(input.Name, ".") || len(input.Name) > 127 {
+		return nil, InvalidInput("Invalid service name: " + input.Name)
+	}
+	if len(input.Description) > 1024 {
+		return nil, InvalidInput("Description must be at most 1024 characters")
+	}
+	if input.HealthCheckConfig != nil && input.HealthCheckCustomConfig != nil {
+		return nil, InvalidInput("A service can't have both HealthCheckConfig and HealthCheckCustomConfig")
+	}
+	if input.HealthCheckConfig != nil {
+		if awserr := validateHealthCheckConfig(input.HealthCheckConfig); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if input.Type != "" && input.Type != "HTTP" {
+		return nil, InvalidInput("Type must be HTTP")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	namespaceId := input.NamespaceId
+	if namespaceId == "" && input.DnsConfig != nil {
+		namespaceId = input.DnsConfig.NamespaceId
+	}
+	namespace, awserr := s.lockedGetNamespace(namespaceId)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	var dnsConfig *APIDnsConfig
+	serviceType := "HTTP"
+	if namespace.Type == "HTTP" {
+		if input.DnsConfig != nil {
+			return nil, InvalidInput("Services in HTTP namespaces can't have a DnsConfig")
+		}
+	} else if input.Type != "HTTP" {
+		if input.DnsConfig == nil {
+			return nil, InvalidInput("Services in DNS namespaces need a DnsConfig, unless their Type is HTTP")
+		}
+		config := *input.DnsConfig
+		config.NamespaceId = namespace.Id
+		config.DnsRecords = slices.Clone(config.DnsRecords)
+		if awserr := validateDnsConfig(&config); awserr != nil {
+			return nil, awserr
+		}
+		dnsConfig = &config
+		serviceType = "DNS_HTTP"
+	}
+	if input.HealthCheckConfig != nil && namespace.Type == "DNS_PRIVATE" {
+		return nil, InvalidInput("Route 53 health checks can't be used in private DNS namespaces")
+	}
+	for _, existing := range s.services {
+		if existing.NamespaceId == namespace.Id && strings.EqualFold(existing.Name, input.Name) {
+			return nil, ServiceAlreadyExists("Service already exists: " + existing.Id)
+		}
+	}
+
+	id := "srv-" + random.String(idCharacters, 16)
+	service := &Service{
+		Id:                      id,
+		Arn:                     s.arnGenerator.Generate("servicediscovery", "service", id),
+		Name:                    input.Name,
+		NamespaceId:             namespace.Id,
+		Description:             input.Description,
+		DnsConfig:               dnsConfig,
+		Type:                    serviceType,
+		HealthCheckConfig:       input.HealthCheckConfig,
+		HealthCheckCustomConfig: input.HealthCheckCustomConfig,
+		CreatorRequestId:        input.CreatorRequestId,
+		CreateDate:              s.clock(),
+		Tags:                    tagMap(input.Tags),
+		instances:               make(map[string]*Instance),
+	}
+	s.services[id] = service
+	return &CreateServiceOutput{Service: service.toAPI()}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_GetService.html
+func (s *ServiceDiscovery) GetService(input GetServiceInput) (*GetServiceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, awserr := s.lockedGetService(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetServiceOutput{Service: service.toAPI()}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_UpdateService.html
+func (s *ServiceDiscovery) UpdateService(input UpdateServiceInput) (*OperationOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, awserr := s.lockedGetService(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	update := input.Service
+	if update.DnsConfig != nil {
+		if service.DnsConfig == nil {
+			return nil, InvalidInput("Service " + service.Id + " has no DnsConfig")
+		}
+		// Only the TTLs of the existing records can be changed.
+		records := slices.Clone(service.DnsConfig.DnsRecords)
+		for _, record := range update.DnsConfig.DnsRecords {
+			i := slices.IndexFunc(records, func(r APIDnsRecord) bool { return r.Type == record.Type })
+			if i < 0 {
+				return nil, InvalidInput("Service " + service.Id + " has no " + record.Type + " record")
+			}
+			records[i].TTL = record.TTL
+		}
+		if awserr := validateDnsRecords(records); awserr != nil {
+			return nil, awserr
+		}
+		config := *service.DnsConfig
+		config.DnsRecords = records
+		service.DnsConfig = &config
+	}
+	if update.HealthCheckConfig != nil {
+		if service.HealthCheckConfig == nil {
+			return nil, InvalidInput("Service " + service.Id + " has no HealthCheckConfig")
+		}
+		if awserr := validateHealthCheckConfig(update.HealthCheckConfig); awserr != nil {
+			return nil, awserr
+		}
+		service.HealthCheckConfig = update.HealthCheckConfig
+	}
+	if update.Description != nil {
+		service.Description = *update.Description
+	}
+	s.lockedSyncRecords(s.namespaces[service.NamespaceId])
+	operationId := s.lockedAddOperation("UPDATE_SERVICE", map[string]string{"SERVICE": service.Id})
+	return &OperationOutput{OperationId: operationId}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_DeleteService.html
+func (s *ServiceDiscovery) DeleteService(input DeleteServiceInput) (*DeleteServiceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, awserr := s.lockedGetService(input.Id)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if len(service.instances) > 0 {
+		return nil, ResourceInUse("Service " + service.Id + " has registered instances")
+	}
+	delete(s.services, service.Id)
+	return &DeleteServiceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_ListServices.html
+func (s *ServiceDiscovery) ListServices(input ListServicesInput) (*ListServicesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var services []APIService
+	for _, service := range s.services {
+		matched := true
+		for _, filter := range input.Filters {
+			if filter.Name != "NAMESPACE_ID" {
+				return nil, InvalidInput("Filter Name must be NAMESPACE_ID")
+			}
+			ok, awserr := matchFilter(filter, service.NamespaceId)
+			if awserr != nil {
+				return nil, awserr
+			}
+			matched = matched && ok
+		}
+		if matched {
+			services = append(services, service.toAPI())
+		}
+	}
+	slices.SortFunc(services, func(a, b APIService) int {
+		return strings.Compare(a.Name+"/"+a.Id, b.Name+"/"+b.Id)
+	})
+	page, nextToken, awserr := paginate(services, input.MaxResults, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ListServicesOutput{Services: page, NextToken: nextToken}, nil
+}
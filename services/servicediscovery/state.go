@@ -0,0 +1,140 @@
+package servicediscovery
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/services/route53"
+	"aws-in-a-box/state"
+)
+
+type serviceState struct {
+	*Service
+	// Sorted by ID.
+	Instances []*Instance
+	Revision  int64
+}
+
+type namespacesState struct {
+	// Sorted by ID.
+	Namespaces []*Namespace
+	Services   []serviceState
+	// In the order they were created.
+	Operations []*Operation
+}
+
+// TryPause pauses changes to each region's namespaces while a snapshot copies them.
+func (s *ServiceDiscovery) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *ServiceDiscovery) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's namespaces, services with their instances, and operations to
+// the archive.
+// The records of DNS namespaces are exported by Route 53, with their hosted zones.
+func (s *ServiceDiscovery) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*ServiceDiscovery).exportState)
+}
+
+func (s *ServiceDiscovery) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported namespacesState
+	for _, namespace := range s.namespaces {
+		exported.Namespaces = append(exported.Namespaces, namespace)
+	}
+	slices.SortFunc(exported.Namespaces, func(a, b *Namespace) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	for _, service := range s.services {
+		exported.Services = append(exported.Services, serviceState{
+			Service:   service,
+			Instances: sortedInstances(service),
+			Revision:  service.revision,
+		})
+	}
+	slices.SortFunc(exported.Services, func(a, b serviceState) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	for _, id := range s.operationIds {
+		exported.Operations = append(exported.Operations, s.operations[id])
+	}
+	return w.WriteJSON("namespaces.json", exported)
+}
+
+// ImportState replaces each region's namespaces, services and operations with the archive's. The
+// hosted zones of DNS namespaces are expected to be imported into Route 53 along with them.
+func (s *ServiceDiscovery) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*ServiceDiscovery).importState, (*ServiceDiscovery).exportState)
+}
+
+func (s *ServiceDiscovery) importState(r *state.Reader) (func() error, error) {
+	var imported namespacesState
+	if err := r.ReadJSON("namespaces.json", &imported); err != nil {
+		return nil, err
+	}
+
+	namespaces := make(map[string]*Namespace)
+	for _, namespace := range imported.Namespaces {
+		if namespace == nil || namespace.Id == "" || namespace.Arn == "" {
+			return nil, fmt.Errorf("invalid namespace")
+		}
+		if namespace.Tags == nil {
+			namespace.Tags = make(map[string]string)
+		}
+		namespace.records = make(map[recordKey]route53.APIResourceRecordSet)
+		namespaces[namespace.Id] = namespace
+	}
+	services := make(map[string]*Service)
+	for _, ss := range imported.Services {
+		service := ss.Service
+		if service == nil || service.Id == "" || service.Arn == "" {
+			return nil, fmt.Errorf("invalid service")
+		}
+		if _, ok := namespaces[service.NamespaceId]; !ok {
+			return nil, fmt.Errorf("service %s: namespace %s doesn't exist", service.Id, service.NamespaceId)
+		}
+		if service.Tags == nil {
+			service.Tags = make(map[string]string)
+		}
+		service.instances = make(map[string]*Instance)
+		for _, instance := range ss.Instances {
+			if instance == nil || instance.Id == "" {
+				return nil, fmt.Errorf("service %s: invalid instance", service.Id)
+			}
+			if instance.Attributes == nil {
+				instance.Attributes = make(map[string]string)
+			}
+			service.instances[instance.Id] = instance
+		}
+		service.revision = ss.Revision
+		services[service.Id] = service
+	}
+	operations := make(map[string]*Operation)
+	var operationIds []string
+	for _, operation := range imported.Operations {
+		if operation == nil || operation.Id == "" {
+			return nil, fmt.Errorf("invalid operation")
+		}
+		operations[operation.Id] = operation
+		operationIds = append(operationIds, operation.Id)
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.namespaces = namespaces
+		s.services = services
+		s.operations = operations
+		s.operationIds = operationIds
+		// The imported hosted zones hold the records of the imported instances.
+		for _, namespace := range namespaces {
+			if namespace.HostedZoneId != "" {
+				namespace.records = s.lockedDesiredRecords(namespace)
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,362 @@
+package servicediscovery
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128" error:"InvalidInput"`
+	Value string `length:",256" error:"InvalidInput"`
+}
+
+type APISOA struct {
+	TTL int64 `range:"0,2147483647" error:"InvalidInput"`
+}
+
+type APIDnsProperties struct {
+	HostedZoneId string  `json:",omitempty"`
+	SOA          *APISOA `json:",omitempty"`
+}
+
+type APIHttpProperties struct {
+	HttpName string
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_NamespaceProperties.html
+type APINamespaceProperties struct {
+	DnsProperties  *APIDnsProperties `json:",omitempty"`
+	HttpProperties APIHttpProperties
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_Namespace.html
+type APINamespace struct {
+	Arn  string
+	Id   string
+	Name string
+	// HTTP, DNS_PUBLIC or DNS_PRIVATE.
+	Type             string
+	Description      string `json:",omitempty"`
+	ServiceCount     int
+	Properties       APINamespaceProperties
+	CreateDate       float64
+	CreatorRequestId string `json:",omitempty"`
+}
+
+// The properties given when creating a DNS namespace.
+type APIDnsNamespaceProperties struct {
+	DnsProperties *struct {
+		SOA *APISOA
+	}
+}
+
+type CreateHttpNamespaceInput struct {
+	Name             string   `required:"true" length:",1024" pattern:"[!-~]+" error:"InvalidInput"`
+	CreatorRequestId string   `length:",64" error:"InvalidInput"`
+	Description      string   `length:",1024" error:"InvalidInput"`
+	Tags             []APITag `length:",200" error:"InvalidInput"`
+}
+
+type CreatePrivateDnsNamespaceInput struct {
+	Name             string `required:"true" length:",253" error:"InvalidInput"`
+	Vpc              string `required:"true" length:",64" error:"InvalidInput"`
+	CreatorRequestId string `length:",64" error:"InvalidInput"`
+	Description      string `length:",1024" error:"InvalidInput"`
+	Properties       *APIDnsNamespaceProperties
+	Tags             []APITag `length:",200" error:"InvalidInput"`
+}
+
+type CreatePublicDnsNamespaceInput struct {
+	Name             string `required:"true" length:",253" error:"InvalidInput"`
+	CreatorRequestId string `length:",64" error:"InvalidInput"`
+	Description      string `length:",1024" error:"InvalidInput"`
+	Properties       *APIDnsNamespaceProperties
+	Tags             []APITag `length:",200" error:"InvalidInput"`
+}
+
+type OperationOutput struct {
+	OperationId string
+}
+
+type GetNamespaceInput struct {
+	Id string `required:"true" length:",64" error:"InvalidInput"`
+}
+
+type GetNamespaceOutput struct {
+	Namespace APINamespace
+}
+
+type DeleteNamespaceInput struct {
+	Id string `required:"true" length:",64" error:"InvalidInput"`
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_NamespaceFilter.html
+type APIFilter struct {
+	Name   string   `required:"true" error:"InvalidInput"`
+	Values []string `required:"true" error:"InvalidInput"`
+	// EQ, IN or BEGINS_WITH.
+	Condition string `enum:"EQ|IN|BETWEEN|BEGINS_WITH" error:"InvalidInput"`
+}
+
+type ListNamespacesInput struct {
+	Filters    []APIFilter
+	MaxResults int    `range:"1,100" error:"InvalidInput"`
+	NextToken  string `length:",4096" error:"InvalidInput"`
+}
+
+type ListNamespacesOutput struct {
+	Namespaces []APINamespace
+	NextToken  string `json:",omitempty"`
+}
+
+type APIDnsRecord struct {
+	// A, AAAA, SRV or CNAME.
+	Type string `required:"true" enum:"SRV|A|AAAA|CNAME" error:"InvalidInput"`
+	TTL  int64  `range:"0,2147483647" error:"InvalidInput"`
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_DnsConfig.html
+type APIDnsConfig struct {
+	NamespaceId string `json:",omitempty" length:",64" error:"InvalidInput"`
+	// MULTIVALUE or WEIGHTED.
+	RoutingPolicy string         `enum:"MULTIVALUE|WEIGHTED" error:"InvalidInput"`
+	DnsRecords    []APIDnsRecord `required:"true" error:"InvalidInput"`
+}
+
+type APIHealthCheckConfig struct {
+	// HTTP, HTTPS or TCP.
+	Type             string `required:"true" enum:"HTTP|HTTPS|TCP" error:"InvalidInput"`
+	ResourcePath     string `json:",omitempty" length:",255" error:"InvalidInput"`
+	FailureThreshold int    `json:",omitempty" range:"1,10" error:"InvalidInput"`
+}
+
+type APIHealthCheckCustomConfig struct {
+	FailureThreshold int `json:",omitempty" range:"1,10" error:"InvalidInput"`
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_Service.html
+type APIService struct {
+	Arn         string
+	Id          string
+	Name        string
+	NamespaceId string
+	Description string `json:",omitempty"`
+	// The number of instances registered with the service.
+	InstanceCount int
+	DnsConfig     *APIDnsConfig `json:",omitempty"`
+	// HTTP, DNS_HTTP or DNS.
+	Type                    string
+	HealthCheckConfig       *APIHealthCheckConfig       `json:",omitempty"`
+	HealthCheckCustomConfig *APIHealthCheckCustomConfig `json:",omitempty"`
+	CreateDate              float64
+	CreatorRequestId        string `json:",omitempty"`
+}
+
+type CreateServiceInput struct {
+	Name                    string `required:"true" pattern:"((?:^[a-zA-Z0-9][a-zA-Z0-9_-]{0,61}[a-zA-Z0-9]$)|(?:^[a-zA-Z0-9]$)|(?:^_[a-zA-Z0-9][a-zA-Z0-9_-]{0,61}[a-zA-Z0-9]._(tcp|udp)$))" error:"InvalidInput"`
+	NamespaceId             string `length:",64" error:"InvalidInput"`
+	CreatorRequestId        string `length:",64" error:"InvalidInput"`
+	Description             string `length:",1024" error:"InvalidInput"`
+	DnsConfig               *APIDnsConfig
+	HealthCheckConfig       *APIHealthCheckConfig
+	HealthCheckCustomConfig *APIHealthCheckCustomConfig
+	Tags                    []APITag `length:",200" error:"InvalidInput"`
+	// Only HTTP, for a service in a DNS namespace which is only discovered with DiscoverInstances.
+	Type string `enum:"HTTP" error:"InvalidInput"`
+}
+
+type CreateServiceOutput struct {
+	Service APIService
+}
+
+type GetServiceInput struct {
+	Id string `required:"true" length:",64" error:"InvalidInput"`
+}
+
+type GetServiceOutput struct {
+	Service APIService
+}
+
+type UpdateServiceInput struct {
+	Id      string `required:"true" length:",64" error:"InvalidInput"`
+	Service struct {
+		Description       *string
+		DnsConfig         *struct{ DnsRecords []APIDnsRecord }
+		HealthCheckConfig *APIHealthCheckConfig
+	}
+}
+
+type DeleteServiceInput struct {
+	Id string `required:"true" length:",64" error:"InvalidInput"`
+}
+
+type DeleteServiceOutput struct{}
+
+type ListServicesInput struct {
+	Filters    []APIFilter
+	MaxResults int    `range:"1,100" error:"InvalidInput"`
+	NextToken  string `length:",4096" error:"InvalidInput"`
+}
+
+type ListServicesOutput struct {
+	Services  []APIService
+	NextToken string `json:",omitempty"`
+}
+
+type RegisterInstanceInput struct {
+	ServiceId        string            `required:"true" length:",64" error:"InvalidInput"`
+	InstanceId       string            `required:"true" length:",64" pattern:"[0-9a-zA-Z_/:.@-]+" error:"InvalidInput"`
+	CreatorRequestId string            `length:",64" error:"InvalidInput"`
+	Attributes       map[string]string `required:"true" length:",30" error:"InvalidInput"`
+}
+
+type DeregisterInstanceInput struct {
+	ServiceId  string `required:"true" length:",64" error:"InvalidInput"`
+	InstanceId string `required:"true" length:",64" error:"InvalidInput"`
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_Instance.html
+type APIInstance struct {
+	Id               string
+	CreatorRequestId string `json:",omitempty"`
+	Attributes       map[string]string
+}
+
+type GetInstanceInput struct {
+	ServiceId  string `required:"true" length:",64" error:"InvalidInput"`
+	InstanceId string `required:"true" length:",64" error:"InvalidInput"`
+}
+
+type GetInstanceOutput struct {
+	Instance APIInstance
+}
+
+type ListInstancesInput struct {
+	ServiceId  string `required:"true" length:",64" error:"InvalidInput"`
+	MaxResults int    `range:"1,100" error:"InvalidInput"`
+	NextToken  string `length:",4096" error:"InvalidInput"`
+}
+
+type APIInstanceSummary struct {
+	Id         string
+	Attributes map[string]string
+}
+
+type ListInstancesOutput struct {
+	Instances []APIInstanceSummary
+	NextToken string `json:",omitempty"`
+}
+
+type GetInstancesHealthStatusInput struct {
+	ServiceId  string   `required:"true" length:",64" error:"InvalidInput"`
+	Instances  []string `length:"1," error:"InvalidInput"`
+	MaxResults int      `range:"1,100" error:"InvalidInput"`
+	NextToken  string   `length:",4096" error:"InvalidInput"`
+}
+
+type GetInstancesHealthStatusOutput struct {
+	// HEALTHY, UNHEALTHY or UNKNOWN, keyed by instance ID.
+	Status    map[string]string
+	NextToken string `json:",omitempty"`
+}
+
+type UpdateInstanceCustomHealthStatusInput struct {
+	ServiceId  string `required:"true" length:",64" error:"InvalidInput"`
+	InstanceId string `required:"true" length:",64" error:"InvalidInput"`
+	// HEALTHY or UNHEALTHY.
+	Status string `required:"true" enum:"HEALTHY|UNHEALTHY" error:"InvalidInput"`
+}
+
+type UpdateInstanceCustomHealthStatusOutput struct{}
+
+type DiscoverInstancesInput struct {
+	NamespaceName string `required:"true" length:",1024" error:"InvalidInput"`
+	ServiceName   string `required:"true" error:"InvalidInput"`
+	MaxResults    int    `range:"1,1000" error:"InvalidInput"`
+	// Only instances with all of these attributes are returned.
+	QueryParameters map[string]string
+	// Of the instances matching QueryParameters, those which also have all of these attributes are
+	// returned, unless none do.
+	OptionalParameters map[string]string
+	// HEALTHY, UNHEALTHY, ALL or HEALTHY_OR_ELSE_ALL.
+	HealthStatus string `enum:"HEALTHY|UNHEALTHY|ALL|HEALTHY_OR_ELSE_ALL" error:"InvalidInput"`
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_HttpInstanceSummary.html
+type APIHttpInstanceSummary struct {
+	InstanceId    string
+	NamespaceName string
+	ServiceName   string
+	HealthStatus  string
+	Attributes    map[string]string
+}
+
+type DiscoverInstancesOutput struct {
+	Instances         []APIHttpInstanceSummary
+	InstancesRevision int64
+}
+
+type DiscoverInstancesRevisionInput struct {
+	NamespaceName string `required:"true" length:",1024" error:"InvalidInput"`
+	ServiceName   string `required:"true" error:"InvalidInput"`
+}
+
+type DiscoverInstancesRevisionOutput struct {
+	InstancesRevision int64
+}
+
+// https://docs.aws.amazon.com/cloud-map/latest/api/API_Operation.html
+type APIOperation struct {
+	Id string
+	// CREATE_NAMESPACE, DELETE_NAMESPACE, UPDATE_NAMESPACE, UPDATE_SERVICE, REGISTER_INSTANCE or
+	// DEREGISTER_INSTANCE.
+	Type         string
+	Status       string
+	ErrorMessage string `json:",omitempty"`
+	ErrorCode    string `json:",omitempty"`
+	CreateDate   float64
+	UpdateDate   float64
+	// Keyed by NAMESPACE, SERVICE or INSTANCE.
+	Targets map[string]string
+}
+
+type GetOperationInput struct {
+	OperationId string `required:"true" length:",255" error:"InvalidInput"`
+}
+
+type GetOperationOutput struct {
+	Operation APIOperation
+}
+
+type ListOperationsInput struct {
+	Filters    []APIFilter
+	MaxResults int    `range:"1,100" error:"InvalidInput"`
+	NextToken  string `length:",4096" error:"InvalidInput"`
+}
+
+type APIOperationSummary struct {
+	Id     string
+	Status string
+}
+
+type ListOperationsOutput struct {
+	Operations []APIOperationSummary
+	NextToken  string `json:",omitempty"`
+}
+
+type TagResourceInput struct {
+	ResourceARN string   `required:"true" length:"1,1011" error:"InvalidInput"`
+	Tags        []APITag `required:"true" length:",200" error:"InvalidInput"`
+}
+
+type TagResourceOutput struct{}
+
+type UntagResourceInput struct {
+	ResourceARN string   `required:"true" length:"1,1011" error:"InvalidInput"`
+	TagKeys     []string `required:"true" length:",200" error:"InvalidInput"`
+}
+
+type UntagResourceOutput struct{}
+
+type ListTagsForResourceInput struct {
+	ResourceARN string `required:"true" length:"1,1011" error:"InvalidInput"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags []APITag
+}
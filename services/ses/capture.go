@@ -0,0 +1,99 @@
+package ses
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"aws-in-a-box/admin"
+)
+
+// CapturedMessage is an email which would have been sent. Instead, it is kept so tests can inspect it
+// through the admin API, and written to the mailbox directory if there is one.
+type CapturedMessage struct {
+	MessageId string
+	From      string
+	// Everyone the message was sent to, including Bcc recipients.
+	Destinations []string
+	// The recipients in the message's headers.
+	To      []string `json:",omitempty"`
+	Cc      []string `json:",omitempty"`
+	ReplyTo []string `json:",omitempty"`
+	Subject string
+	Text    string `json:",omitempty"`
+	Html    string `json:",omitempty"`
+	// The whole message, as it would have been sent.
+	Raw       string
+	Timestamp time.Time
+}
+
+func (s *SES) mailboxPath(messageId string) string {
+	return filepath.Join(s.mailboxDir, messageId+".eml")
+}
+
+func (s *SES) lockedCapture(message CapturedMessage) {
+	s.capturedMessages = append(s.capturedMessages, message)
+	if s.mailboxDir == "" {
+		return
+	}
+	if err := os.WriteFile(s.mailboxPath(message.MessageId), []byte(message.Raw), 0644); err != nil {
+		s.logger.Warn("Writing message to mailbox", "messageId", message.MessageId, "error", err)
+	}
+}
+
+// CapturedMessages returns the messages every region sent, oldest first.
+func (s *SES) CapturedMessages() []CapturedMessage {
+	messages := []CapturedMessage{}
+	for _, r := range s.regions.All() {
+		r.mu.Lock()
+		messages = append(messages, r.capturedMessages...)
+		r.mu.Unlock()
+	}
+	slices.SortStableFunc(messages, func(a, b CapturedMessage) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return messages
+}
+
+// ClearCapturedMessages forgets the messages every region sent, and deletes them from the mailbox
+// directory.
+func (s *SES) ClearCapturedMessages() {
+	for _, r := range s.regions.All() {
+		r.mu.Lock()
+		if r.mailboxDir != "" {
+			for _, message := range r.capturedMessages {
+				os.Remove(r.mailboxPath(message.MessageId))
+			}
+		}
+		r.capturedMessages = nil
+		r.mu.Unlock()
+	}
+}
+
+// RegisterAdminHandlers adds the sent messages to the admin API.
+// GET ses/messages lists them, optionally filtered by the from and to query parameters,
+// and DELETE ses/messages clears them.
+func (s *SES) RegisterAdminHandlers(registry admin.Registry) {
+	registry["ses/messages"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			from := r.URL.Query().Get("from")
+			to := r.URL.Query().Get("to")
+			messages := []CapturedMessage{}
+			for _, message := range s.CapturedMessages() {
+				if (from == "" || message.From == from) &&
+					(to == "" || slices.Contains(message.Destinations, to)) {
+					messages = append(messages, message)
+				}
+			}
+			admin.WriteJSON(w, struct{ Messages []CapturedMessage }{messages})
+		case http.MethodDelete:
+			s.ClearCapturedMessages()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package ses
+
+import "aws-in-a-box/awserrors"
+
+func AlreadyExistsException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("AlreadyExistsException", message)
+}
+
+func BadRequestException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BadRequestException", message)
+}
+
+func MessageRejected(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("MessageRejected", message)
+}
+
+func NotFoundException(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    "NotFoundException",
+			Message: message,
+		},
+	}
+}
+
+// The original SES API's errors for templates which can't be rendered.
+
+func InvalidRenderingParameter(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidRenderingParameter", message)
+}
+
+func MissingRenderingAttribute(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("MissingRenderingAttribute", message)
+}
@@ -0,0 +1,361 @@
+package ses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SES renders templates with Handlebars. This implements the parts of it SES documents: variables, including
+// nested attributes, and the if, unless, each and with block helpers, with else.
+// https://docs.aws.amazon.com/ses/latest/dg/send-personalized-email-advanced.html
+
+type templateNode any
+
+type textNode string
+
+type variableNode struct {
+	path string
+	// Variables in triple braces, such as {{{html}}}, aren't HTML-escaped.
+	raw bool
+}
+
+type blockNode struct {
+	helper string
+	path   string
+	body   []templateNode
+	// The nodes after {{else}}.
+	inverse []templateNode
+}
+
+var blockHelpers = []string{"each", "if", "unless", "with"}
+
+// missingAttributeError is a variable which isn't in the rendering data.
+type missingAttributeError struct {
+	path string
+}
+
+func (e *missingAttributeError) Error() string {
+	return fmt.Sprintf("Attribute '%s' is not present in the rendering data.", e.path)
+}
+
+// parseTemplate parses a template's text into its nodes.
+func parseTemplate(text string) ([]templateNode, error) {
+	type frame struct {
+		block  *blockNode
+		inElse bool
+	}
+	root := &blockNode{}
+	stack := []*frame{{block: root}}
+	appendNode := func(node templateNode) {
+		top := stack[len(stack)-1]
+		if top.inElse {
+			top.block.inverse = append(top.block.inverse, node)
+		} else {
+			top.block.body = append(top.block.body, node)
+		}
+	}
+
+	for text != "" {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			appendNode(textNode(text))
+			break
+		}
+		if start > 0 {
+			appendNode(textNode(text[:start]))
+		}
+		text = text[start:]
+
+		var expression string
+		raw := false
+		switch {
+		case strings.HasPrefix(text, "{{!--"):
+			end := strings.Index(text, "--}}")
+			if end < 0 {
+				return nil, errors.New("unclosed comment")
+			}
+			text = text[end+len("--}}"):]
+			continue
+		case strings.HasPrefix(text, "{{{"):
+			end := strings.Index(text, "}}}")
+			if end < 0 {
+				return nil, errors.New("unclosed {{{")
+			}
+			expression, raw = text[len("{{{"):end], true
+			text = text[end+len("}}}"):]
+		default:
+			end := strings.Index(text, "}}")
+			if end < 0 {
+				return nil, errors.New("unclosed {{")
+			}
+			expression = text[len("{{"):end]
+			text = text[end+len("}}"):]
+		}
+		expression = strings.TrimSpace(expression)
+
+		switch {
+		case raw:
+			if !isTemplatePath(expression) {
+				return nil, fmt.Errorf("unsupported expression {{{%s}}}", expression)
+			}
+			appendNode(variableNode{path: expression, raw: true})
+		case strings.HasPrefix(expression, "!"):
+			// A comment.
+		case strings.HasPrefix(expression, "#"):
+			fields := strings.Fields(expression[1:])
+			if len(fields) != 2 || !slices.Contains(blockHelpers, fields[0]) || !isTemplatePath(fields[1]) {
+				return nil, fmt.Errorf("unsupported block {{%s}}", expression)
+			}
+			block := &blockNode{helper: fields[0], path: fields[1]}
+			appendNode(block)
+			stack = append(stack, &frame{block: block})
+		case expression == "else" || expression == "^":
+			top := stack[len(stack)-1]
+			if len(stack) == 1 || top.inElse {
+				return nil, errors.New("unexpected {{else}}")
+			}
+			top.inElse = true
+		case strings.HasPrefix(expression, "/"):
+			helper := strings.TrimSpace(expression[1:])
+			if len(stack) == 1 || stack[len(stack)-1].block.helper != helper {
+				return nil, fmt.Errorf("unexpected {{%s}}", expression)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			path, raw := strings.CutPrefix(expression, "&")
+			path = strings.TrimSpace(path)
+			if !isTemplatePath(path) {
+				return nil, fmt.Errorf("unsupported expression {{%s}}", expression)
+			}
+			appendNode(variableNode{path: path, raw: raw})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed {{#%s}}", stack[len(stack)-1].block.helper)
+	}
+	return root.body, nil
+}
+
+func isTemplatePath(path string) bool {
+	return path != "" && !strings.ContainsAny(path, " \t\r\n{}")
+}
+
+// templateContext is the value variables are looked up in.
+type templateContext struct {
+	value  any
+	parent *templateContext
+	// @index, @key, @first and @last, inside each blocks.
+	data map[string]any
+}
+
+// lookup returns the value at the path, and whether it's defined.
+func (c *templateContext) lookup(path string) (any, bool) {
+	if rest, ok := strings.CutPrefix(path, "@root"); ok {
+		for c.parent != nil {
+			c = c.parent
+		}
+		path = strings.TrimPrefix(rest, ".")
+		if path == "" {
+			return c.value, true
+		}
+	}
+	for {
+		rest, ok := strings.CutPrefix(path, "../")
+		if !ok {
+			break
+		}
+		if c.parent != nil {
+			c = c.parent
+		}
+		path = rest
+	}
+	if name, ok := strings.CutPrefix(path, "@"); ok {
+		for ; c != nil; c = c.parent {
+			if value, ok := c.data[name]; ok {
+				return value, true
+			}
+		}
+		return nil, false
+	}
+	if path == "this" || path == "." {
+		return c.value, true
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "this."), "./")
+
+	value := c.value
+	for _, segment := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[segment]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// isTruthy returns whether if blocks render the value, which is the same as in JavaScript,
+// except that empty lists are false.
+func isTruthy(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	case string:
+		return value != ""
+	case json.Number:
+		f, err := value.Float64()
+		return err != nil || f != 0
+	case []any:
+		return len(value) > 0
+	}
+	return true
+}
+
+// formatTemplateValue returns the value as it's rendered, like JavaScript's String function.
+func formatTemplateValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = formatTemplateValue(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return "[object Object]"
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&#x27;",
+	"`", "&#x60;",
+	"=", "&#x3D;",
+)
+
+func renderNodes(b *strings.Builder, nodes []templateNode, c *templateContext) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case textNode:
+			b.WriteString(string(node))
+		case variableNode:
+			value, ok := c.lookup(node.path)
+			if !ok {
+				return &missingAttributeError{path: node.path}
+			}
+			text := formatTemplateValue(value)
+			if !node.raw {
+				text = htmlEscaper.Replace(text)
+			}
+			b.WriteString(text)
+		case *blockNode:
+			if err := node.render(b, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (n *blockNode) render(b *strings.Builder, c *templateContext) error {
+	// Blocks on undefined attributes render their else branch, rather than failing.
+	value, _ := c.lookup(n.path)
+	switch n.helper {
+	case "if":
+		if isTruthy(value) {
+			return renderNodes(b, n.body, c)
+		}
+	case "unless":
+		if !isTruthy(value) {
+			return renderNodes(b, n.body, c)
+		}
+	case "with":
+		if isTruthy(value) {
+			return renderNodes(b, n.body, &templateContext{value: value, parent: c})
+		}
+	case "each":
+		switch value := value.(type) {
+		case []any:
+			if len(value) == 0 {
+				break
+			}
+			for i, item := range value {
+				err := renderNodes(b, n.body, &templateContext{value: item, parent: c, data: map[string]any{
+					"index": json.Number(strconv.Itoa(i)),
+					"first": i == 0,
+					"last":  i == len(value)-1,
+				}})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		case map[string]any:
+			if len(value) == 0 {
+				break
+			}
+			// JSON objects are decoded without their order, so their keys are iterated in sorted order.
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for i, key := range keys {
+				err := renderNodes(b, n.body, &templateContext{value: value[key], parent: c, data: map[string]any{
+					"key":   key,
+					"index": json.Number(strconv.Itoa(i)),
+					"first": i == 0,
+					"last":  i == len(keys)-1,
+				}})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return renderNodes(b, n.inverse, c)
+}
+
+// parseTemplateData parses the JSON object of a template's replacement values.
+func parseTemplateData(data string) (map[string]any, error) {
+	if strings.TrimSpace(data) == "" {
+		return map[string]any{}, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var parsed map[string]any
+	if err := decoder.Decode(&parsed); err != nil || parsed == nil || decoder.More() {
+		return nil, errors.New("Template data must be a JSON object.")
+	}
+	return parsed, nil
+}
+
+// renderTemplate renders the template's text with the data.
+func renderTemplate(text string, data map[string]any) (string, error) {
+	nodes, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := renderNodes(&b, nodes, &templateContext{value: data}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+package ses
+
+import (
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := `{
+		"name": "Ana <ana@example.com>",
+		"count": 0,
+		"vip": true,
+		"address": {"city": "Lisbon"},
+		"items": [{"name": "tea", "price": 3.5}, {"name": "cake", "price": 4}],
+		"tags": ["a", "b"],
+		"empty": [],
+		"nothing": null,
+		"scores": {"math": 90, "art": 75}
+	}`
+	for _, test := range []struct {
+		template string
+		expected string
+	}{
+		{"Hello {{name}}", "Hello Ana &lt;ana@example.com&gt;"},
+		{"Hello {{{name}}}", "Hello Ana <ana@example.com>"},
+		{"Hello {{& name}}", "Hello Ana <ana@example.com>"},
+		{"{{ address.city }}, {{this.address.city}}", "Lisbon, Lisbon"},
+		{"{{count}} {{vip}} {{tags}} {{nothing}} {{address}}", "0 true a,b  [object Object]"},
+		{"{{! a comment }}{{!-- {{name}} --}}x", "x"},
+		{"{{#if vip}}VIP{{else}}regular{{/if}}", "VIP"},
+		{"{{#if count}}some{{else}}none{{/if}}", "none"},
+		{"{{#if missing}}yes{{/if}}", ""},
+		{"{{#unless empty}}no items{{/unless}}", "no items"},
+		{"{{#with address}}{{city}} ({{../name}}){{/with}}", "Lisbon (Ana &lt;ana@example.com&gt;)"},
+		{"{{#each items}}{{@index}}:{{name}}={{price}}{{#unless @last}}, {{/unless}}{{/each}}", "0:tea=3.5, 1:cake=4"},
+		{"{{#each tags}}[{{this}}{{#if @first}} first{{/if}}]{{/each}}", "[a first][b]"},
+		{"{{#each scores}}{{@key}}={{this}};{{/each}}", "art=75;math=90;"},
+		{"{{#each empty}}item{{else}}no items{{/each}}", "no items"},
+		{"{{#each items}}{{@root.address.city}}{{/each}}", "LisbonLisbon"},
+		{"{{#if vip}}{{#each tags}}{{#if @first}}{{this}}{{else}}-{{this}}{{/if}}{{/each}}{{/if}}", "a-b"},
+	} {
+		parsed, err := parseTemplateData(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, err := renderTemplate(test.template, parsed)
+		if err != nil {
+			t.Errorf("%s: %v", test.template, err)
+			continue
+		}
+		if rendered != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.template, test.expected, rendered)
+		}
+	}
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	for _, template := range []string{
+		"{{name",
+		"{{{name}}",
+		"{{!-- comment",
+		"{{#if name}}",
+		"{{/if}}",
+		"{{else}}",
+		"{{#if name}}{{/each}}",
+		"{{#if name}}a{{else}}b{{else}}c{{/if}}",
+		"{{#lookup name}}{{/lookup}}",
+		"{{uppercase name}}",
+		"{{}}",
+	} {
+		if _, err := parseTemplate(template); err == nil {
+			t.Errorf("%s: expected a parse error", template)
+		}
+	}
+
+	// Blocks on missing attributes render nothing, rather than failing.
+	rendered, err := renderTemplate("Hello {{name}}{{#each items}}, {{city}}{{/each}}", map[string]any{"name": "Ana"})
+	if err != nil || rendered != "Hello Ana" {
+		t.Fatal("Unexpected rendering", rendered, err)
+	}
+	_, err = renderTemplate("Hello {{name}}", map[string]any{})
+	if err == nil || err.Error() != "Attribute 'name' is not present in the rendering data." {
+		t.Fatal("Expected a missing attribute error", err)
+	}
+
+	for _, data := range []string{`[]`, `"text"`, `{"a": 1`, `{} {}`, `null`} {
+		if _, err := parseTemplateData(data); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
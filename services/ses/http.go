@@ -0,0 +1,55 @@
+package ses
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/query"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// The original SES API, which many SDKs still use, only supports the Query protocol.
+var queryProtocol = query.Protocol{
+	Version:      "2010-12-01",
+	XMLNamespace: "http://ses.amazonaws.com/doc/2010-12-01/",
+	ErrorCodes: map[string]string{
+		"AlreadyExistsException": "AlreadyExists",
+		"BadRequestException":    "InvalidParameterValue",
+		"NotFoundException":      "TemplateDoesNotExist",
+	},
+}
+
+// SES v2 only supports the REST-JSON protocol.
+func NewHandler(logger *slog.Logger, s *SES, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v2/email/outbound-emails", "SendEmail", region.Method(s.regions, (*SES).SendEmail))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v2/email/outbound-bulk-emails", "SendBulkEmail", region.Method(s.regions, (*SES).SendBulkEmail))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v2/email/templates", "CreateEmailTemplate", region.Method(s.regions, (*SES).CreateEmailTemplate))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v2/email/templates", "ListEmailTemplates", region.Method(s.regions, (*SES).ListEmailTemplates))
+	restjson.RegisterWithRequest(logger, registry, http.MethodGet, "/v2/email/templates/{TemplateName}", "GetEmailTemplate", region.Method(s.regions, (*SES).GetEmailTemplate))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPut, "/v2/email/templates/{TemplateName}", "UpdateEmailTemplate", region.Method(s.regions, (*SES).UpdateEmailTemplate))
+	restjson.RegisterWithRequest(logger, registry, http.MethodDelete, "/v2/email/templates/{TemplateName}", "DeleteEmailTemplate", region.Method(s.regions, (*SES).DeleteEmailTemplate))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/v2/email/templates/{TemplateName}/render", "TestRenderEmailTemplate", region.Method(s.regions, (*SES).TestRenderEmailTemplate))
+	capabilityRegistry.Add("ses", registry.Operations()...)
+	restHandler := restjson.NewHandler(registry)
+
+	queryRegistry := query.NewRegistry(queryProtocol)
+	query.RegisterWithRequest(logger, queryRegistry, "SendEmail", region.Method(s.regions, (*SES).SendEmailV1))
+	query.RegisterWithRequest(logger, queryRegistry, "SendRawEmail", region.Method(s.regions, (*SES).SendRawEmail))
+	query.RegisterWithRequest(logger, queryRegistry, "SendTemplatedEmail", region.Method(s.regions, (*SES).SendTemplatedEmail))
+	query.RegisterWithRequest(logger, queryRegistry, "SendBulkTemplatedEmail", region.Method(s.regions, (*SES).SendBulkTemplatedEmail))
+	query.RegisterWithRequest(logger, queryRegistry, "CreateTemplate", region.Method(s.regions, (*SES).CreateTemplate))
+	query.RegisterWithRequest(logger, queryRegistry, "GetTemplate", region.Method(s.regions, (*SES).GetTemplate))
+	query.RegisterWithRequest(logger, queryRegistry, "UpdateTemplate", region.Method(s.regions, (*SES).UpdateTemplate))
+	query.RegisterWithRequest(logger, queryRegistry, "DeleteTemplate", region.Method(s.regions, (*SES).DeleteTemplate))
+	query.RegisterWithRequest(logger, queryRegistry, "ListTemplates", region.Method(s.regions, (*SES).ListTemplates))
+	query.RegisterWithRequest(logger, queryRegistry, "TestRenderTemplate", region.Method(s.regions, (*SES).TestRenderTemplate))
+	capabilityRegistry.Add("ses", queryRegistry.Operations()...)
+	queryHandler := query.NewHandler(queryRegistry)
+
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		return restHandler(w, r) || queryHandler(w, r)
+	}
+}
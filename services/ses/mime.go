@@ -0,0 +1,207 @@
+package ses
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// simpleMessage is a message SES formats from its parts.
+type simpleMessage struct {
+	messageId string
+	from      string
+	to        []string
+	cc        []string
+	replyTo   []string
+	subject   string
+	text      string
+	html      string
+	headers   []APIMessageHeader
+	date      time.Time
+}
+
+// format returns the message as MIME, the way SES sends it. The bodies are quoted-printable UTF-8,
+// in a multipart/alternative message if there's both a text and an HTML body.
+// Messages without a sender or ID are test renderings of templates, which don't have those headers.
+func (m *simpleMessage) format() []byte {
+	var b bytes.Buffer
+	writeHeader := func(name string, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	if m.from != "" {
+		writeHeader("From", m.from)
+	}
+	if len(m.to) > 0 {
+		writeHeader("To", strings.Join(m.to, ", "))
+	}
+	if len(m.cc) > 0 {
+		writeHeader("Cc", strings.Join(m.cc, ", "))
+	}
+	if len(m.replyTo) > 0 {
+		writeHeader("Reply-To", strings.Join(m.replyTo, ", "))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("UTF-8", m.subject))
+	writeHeader("Date", m.date.UTC().Format(time.RFC1123Z))
+	if m.messageId != "" {
+		writeHeader("Message-ID", "<"+m.messageId+"@email.amazonses.com>")
+	}
+	for _, header := range m.headers {
+		writeHeader(header.Name, header.Value)
+	}
+	writeHeader("MIME-Version", "1.0")
+
+	if m.text == "" || m.html == "" {
+		contentType, body := "text/plain", m.text
+		if m.html != "" {
+			contentType, body = "text/html", m.html
+		}
+		writeHeader("Content-Type", contentType+"; charset=UTF-8")
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		writeQuotedPrintable(&b, body)
+		return b.Bytes()
+	}
+
+	parts := multipart.NewWriter(&b)
+	writeHeader("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	b.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", m.text},
+		{"text/html", m.html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			panic(err)
+		}
+		writeQuotedPrintable(w, part.body)
+	}
+	if err := parts.Close(); err != nil {
+		panic(err)
+	}
+	return b.Bytes()
+}
+
+func writeQuotedPrintable(w io.Writer, text string) {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		panic(err)
+	}
+	if err := qp.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// parsedMessage is what the admin API shows of a message. Addresses are without their display names.
+type parsedMessage struct {
+	from    string
+	to      []string
+	cc      []string
+	bcc     []string
+	replyTo []string
+	subject string
+	// The first text and HTML bodies which aren't attachments.
+	text string
+	html string
+}
+
+// parseMessage parses a MIME message, such as those sent with SendRawEmail.
+func parseMessage(raw []byte) (*parsedMessage, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	parsed := &parsedMessage{}
+	if message.Header.Get("From") != "" {
+		from, err := message.Header.AddressList("From")
+		if err != nil || len(from) == 0 {
+			return nil, fmt.Errorf("invalid From header: %v", err)
+		}
+		parsed.from = from[0].Address
+	}
+	for _, field := range []struct {
+		header    string
+		addresses *[]string
+	}{
+		{"To", &parsed.to},
+		{"Cc", &parsed.cc},
+		{"Bcc", &parsed.bcc},
+		{"Reply-To", &parsed.replyTo},
+	} {
+		if message.Header.Get(field.header) == "" {
+			continue
+		}
+		list, err := message.Header.AddressList(field.header)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %v", field.header, err)
+		}
+		for _, address := range list {
+			*field.addresses = append(*field.addresses, address.Address)
+		}
+	}
+	decoder := &mime.WordDecoder{}
+	parsed.subject, err = decoder.DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		parsed.subject = message.Header.Get("Subject")
+	}
+	if err := parsed.readPart(textproto.MIMEHeader(message.Header), message.Body); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// readPart reads the text and HTML bodies from the part, and any parts nested in it.
+func (p *parsedMessage) readPart(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := parts.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := p.readPart(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return nil
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return nil
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if mediaType == "text/plain" && p.text == "" {
+		p.text = string(data)
+	} else if mediaType == "text/html" && p.html == "" {
+		p.html = string(data)
+	}
+	return nil
+}
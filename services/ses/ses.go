@@ -0,0 +1,311 @@
+package ses
+
+import (
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+)
+
+const (
+	maxRecipients = 50
+	// SES v2 accepts larger messages than the original API's 10 MB.
+	maxMessageSize = 40 * 1024 * 1024
+)
+
+type SES struct {
+	logger *slog.Logger
+	// Overridden in tests.
+	clock func() time.Time
+	// Sent messages are also written here, if it isn't empty.
+	mailboxDir string
+	region     string
+	// Access key IDs and secret access keys, for the SMTP interface.
+	smtpCredentials map[string]string
+
+	mu sync.Mutex
+	// Oldest first.
+	capturedMessages []CapturedMessage
+	templates        map[string]*emailTemplate
+	// Shared by the instances of all regions, which each have their own templates and captured
+	// messages.
+	regions *region.Regions[*SES]
+}
+
+type Options struct {
+	Logger *slog.Logger
+	// The directory to write sent messages to, as .eml files. If empty, they're only kept in memory.
+	MailboxDir string
+	// The default region, which requests are for unless they say otherwise.
+	Region string
+	// The regions other than Region which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+	// The access key IDs and secret access keys whose SMTP passwords the SMTP interface accepts.
+	// If empty, any credentials are accepted.
+	SMTPCredentials map[string]string
+}
+
+func New(options Options) (*SES, error) {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+	if options.MailboxDir != "" {
+		if err := os.MkdirAll(options.MailboxDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	// Message IDs are unique across regions, so they share the mailbox directory.
+	s := newRegion(options)
+	s.regions = region.New(options.Region, options.Regions, s, func(name string) (*SES, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.Region = name
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s, nil
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *SES {
+	return &SES{
+		logger:          options.Logger,
+		clock:           clock.Now,
+		mailboxDir:      options.MailboxDir,
+		region:          options.Region,
+		smtpCredentials: options.SMTPCredentials,
+		templates:       make(map[string]*emailTemplate),
+	}
+}
+
+// InRegion returns the templates and captured messages of the region. It fails if the region isn't
+// one of Options.Regions.
+func (s *SES) InRegion(name string) (*SES, error) {
+	return s.regions.Get(name)
+}
+
+// newMessageId returns an ID in the format SES uses, such as
+// 0100018bcfe0a5c3-6f29c4de-86d4-4d5e-a0a7-2bce4d6a4a8e-000000.
+func (s *SES) newMessageId() string {
+	return fmt.Sprintf("%016x-%s-000000", s.clock().UnixMilli(), uuid.Must(uuid.NewV4()))
+}
+
+// addresses returns the To, Cc and Bcc addresses.
+func (d APIDestination) addresses() []string {
+	addresses := append([]string{}, d.ToAddresses...)
+	addresses = append(addresses, d.CcAddresses...)
+	return append(addresses, d.BccAddresses...)
+}
+
+// validateAddresses checks the addresses are valid, and returns them without their display names.
+func validateAddresses(addresses []string) ([]string, *awserrors.Error) {
+	parsed := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		a, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, BadRequestException("Illegal address: " + address)
+		}
+		parsed = append(parsed, a.Address)
+	}
+	return parsed, nil
+}
+
+// send captures the message as sent from the address to the destinations.
+// If they're empty, they're read from the message's headers.
+func (s *SES) send(messageId string, from string, destinations []string, raw []byte) *awserrors.Error {
+	if len(raw) > maxMessageSize {
+		return MessageRejected(fmt.Sprintf("Message length is more than %d bytes long: '%d'.", maxMessageSize, len(raw)))
+	}
+	parsed, err := parseMessage(raw)
+	if err != nil {
+		return BadRequestException("Could not parse the message: " + err.Error())
+	}
+	if from == "" {
+		from = parsed.from
+	}
+	if from == "" {
+		return BadRequestException("Missing final '@domain'")
+	}
+	fromAddress, awserr := validateAddresses([]string{from})
+	if awserr != nil {
+		return awserr
+	}
+	if len(destinations) == 0 {
+		destinations = APIDestination{ToAddresses: parsed.to, CcAddresses: parsed.cc, BccAddresses: parsed.bcc}.addresses()
+	}
+	if len(destinations) == 0 {
+		return BadRequestException("Missing required header 'To'.")
+	}
+	if len(destinations) > maxRecipients {
+		return BadRequestException(fmt.Sprintf("Recipient count exceeds %d.", maxRecipients))
+	}
+	destinations, awserr = validateAddresses(destinations)
+	if awserr != nil {
+		return awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockedCapture(CapturedMessage{
+		MessageId:    messageId,
+		From:         fromAddress[0],
+		Destinations: destinations,
+		To:           parsed.to,
+		Cc:           parsed.cc,
+		ReplyTo:      parsed.replyTo,
+		Subject:      parsed.subject,
+		Text:         parsed.text,
+		Html:         parsed.html,
+		Raw:          string(raw),
+		Timestamp:    s.clock(),
+	})
+	return nil
+}
+
+// validateEnvelope checks the sender and recipients of a message SES formats. They're checked before
+// it's formatted, since addresses which don't parse would make its headers invalid.
+func validateEnvelope(from string, destination APIDestination, replyTo []string) *awserrors.Error {
+	if from == "" {
+		return BadRequestException("Missing required field FromEmailAddress.")
+	}
+	destinations := destination.addresses()
+	if len(destinations) == 0 {
+		return BadRequestException("Missing required field Destination.")
+	}
+	for _, addresses := range [][]string{{from}, destinations, replyTo} {
+		if _, awserr := validateAddresses(addresses); awserr != nil {
+			return awserr
+		}
+	}
+	return nil
+}
+
+// sendSimple formats a message from its parts, and sends it.
+func (s *SES) sendSimple(message *simpleMessage, destination APIDestination) (string, *awserrors.Error) {
+	if awserr := validateEnvelope(message.from, destination, message.replyTo); awserr != nil {
+		return "", awserr
+	}
+	if message.subject == "" {
+		return "", BadRequestException("Missing required field Subject.")
+	}
+	if message.text == "" && message.html == "" {
+		return "", BadRequestException("Missing required field Body.")
+	}
+	destinations := destination.addresses()
+	message.messageId = s.newMessageId()
+	message.to = destination.ToAddresses
+	message.cc = destination.CcAddresses
+	message.date = s.clock()
+	if awserr := s.send(message.messageId, message.from, destinations, message.format()); awserr != nil {
+		return "", awserr
+	}
+	return message.messageId, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_SendEmail.html
+func (s *SES) SendEmail(input SendEmailInput) (*SendEmailOutput, *awserrors.Error) {
+	content := input.Content
+	set := 0
+	for _, isSet := range []bool{content.Simple != nil, content.Raw != nil, content.Template != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, BadRequestException("Exactly one of Simple, Raw or Template content must be specified.")
+	}
+	var destination APIDestination
+	if input.Destination != nil {
+		destination = *input.Destination
+	}
+
+	switch {
+	case content.Simple != nil:
+		message := &simpleMessage{
+			from:    input.FromEmailAddress,
+			replyTo: input.ReplyToAddresses,
+			headers: content.Simple.Headers,
+		}
+		message.subject = contentData(content.Simple.Subject)
+		if body := content.Simple.Body; body != nil {
+			message.text = contentData(body.Text)
+			message.html = contentData(body.Html)
+		}
+		messageId, awserr := s.sendSimple(message, destination)
+		if awserr != nil {
+			return nil, awserr
+		}
+		return &SendEmailOutput{MessageId: messageId}, nil
+	case content.Raw != nil:
+		messageId := s.newMessageId()
+		if awserr := s.send(messageId, input.FromEmailAddress, destination.addresses(), content.Raw.Data); awserr != nil {
+			return nil, awserr
+		}
+		return &SendEmailOutput{MessageId: messageId}, nil
+	default:
+		template, awserr := s.getTemplate(templateName(content.Template.TemplateName, content.Template.TemplateArn))
+		if awserr != nil {
+			return nil, awserr
+		}
+		data, err := parseTemplateData(content.Template.TemplateData)
+		if err != nil {
+			return nil, BadRequestException(err.Error())
+		}
+		messageId, awserr := s.sendTemplated(&simpleMessage{
+			from:    input.FromEmailAddress,
+			replyTo: input.ReplyToAddresses,
+			headers: content.Template.Headers,
+		}, template, data, destination)
+		if awserr != nil {
+			return nil, awserr
+		}
+		return &SendEmailOutput{MessageId: messageId}, nil
+	}
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_SendEmail.html
+func (s *SES) SendEmailV1(input SendEmailV1Input) (*SendEmailV1Output, *awserrors.Error) {
+	messageId, awserr := s.sendSimple(&simpleMessage{
+		from:    input.Source,
+		replyTo: input.ReplyToAddresses,
+		subject: input.Message.Subject.Data,
+		text:    contentData(input.Message.Body.Text),
+		html:    contentData(input.Message.Body.Html),
+	}, input.Destination)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &SendEmailV1Output{MessageId: messageId}, nil
+}
+
+func contentData(content *APIContent) string {
+	if content == nil {
+		return ""
+	}
+	return content.Data
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_SendRawEmail.html
+func (s *SES) SendRawEmail(input SendRawEmailInput) (*SendRawEmailOutput, *awserrors.Error) {
+	if len(input.RawMessage.Data) == 0 {
+		return nil, BadRequestException("Missing required field RawMessage.")
+	}
+	messageId := s.newMessageId()
+	if awserr := s.send(messageId, input.Source, input.Destinations, input.RawMessage.Data); awserr != nil {
+		return nil, awserr
+	}
+	return &SendRawEmailOutput{MessageId: messageId}, nil
+}
@@ -0,0 +1,241 @@
+package ses
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/admin"
+)
+
+func newSES(t *testing.T, options Options) *SES {
+	s, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.clock = func() time.Time { return time.Unix(1700000000, 0) }
+	return s
+}
+
+func TestSendEmail(t *testing.T) {
+	mailbox := t.TempDir()
+	s := newSES(t, Options{MailboxDir: mailbox})
+
+	output, awserr := s.SendEmail(SendEmailInput{
+		FromEmailAddress: "Sender <sender@example.com>",
+		Destination: &APIDestination{
+			ToAddresses:  []string{"to@example.com"},
+			CcAddresses:  []string{"cc@example.com"},
+			BccAddresses: []string{"bcc@example.com"},
+		},
+		ReplyToAddresses: []string{"reply@example.com"},
+		Content: APIEmailContent{Simple: &APIMessage{
+			Subject: &APIContent{Data: "Your order ✓"},
+			Body: &APIBody{
+				Text: &APIContent{Data: "Thanks for your order."},
+				Html: &APIContent{Data: "<p>Thanks for your order.</p>"},
+			},
+			Headers: []APIMessageHeader{{Name: "X-Order", Value: "7"}},
+		}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(output.MessageId, "0000018bcfe56800-") || !strings.HasSuffix(output.MessageId, "-000000") {
+		t.Fatal("Unexpected message ID", output.MessageId)
+	}
+
+	messages := s.CapturedMessages()
+	if len(messages) != 1 {
+		t.Fatal("Expected one message", messages)
+	}
+	message := messages[0]
+	if message.MessageId != output.MessageId || message.From != "sender@example.com" || message.Subject != "Your order ✓" ||
+		message.Text != "Thanks for your order." || message.Html != "<p>Thanks for your order.</p>" ||
+		!slices.Equal(message.Destinations, []string{"to@example.com", "cc@example.com", "bcc@example.com"}) ||
+		!slices.Equal(message.To, []string{"to@example.com"}) || !slices.Equal(message.ReplyTo, []string{"reply@example.com"}) {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+	// Bcc recipients aren't in the message's headers.
+	if strings.Contains(message.Raw, "bcc@example.com") || !strings.Contains(message.Raw, "X-Order: 7\r\n") ||
+		!strings.Contains(message.Raw, "Message-ID: <"+output.MessageId+"@email.amazonses.com>") {
+		t.Fatal("Unexpected raw message", message.Raw)
+	}
+	written, err := os.ReadFile(filepath.Join(mailbox, output.MessageId+".eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != message.Raw {
+		t.Fatal("Unexpected mailbox file", string(written))
+	}
+
+	for _, input := range []SendEmailInput{
+		{FromEmailAddress: "sender@example.com", Destination: &APIDestination{ToAddresses: []string{"to@example.com"}}},
+		{Destination: &APIDestination{ToAddresses: []string{"to@example.com"}}, Content: APIEmailContent{Simple: &APIMessage{
+			Subject: &APIContent{Data: "Hi"}, Body: &APIBody{Text: &APIContent{Data: "Hi"}},
+		}}},
+		{FromEmailAddress: "sender@example.com", Content: APIEmailContent{Simple: &APIMessage{
+			Subject: &APIContent{Data: "Hi"}, Body: &APIBody{Text: &APIContent{Data: "Hi"}},
+		}}},
+		{FromEmailAddress: "sender@example.com", Destination: &APIDestination{ToAddresses: []string{"not an address"}},
+			Content: APIEmailContent{Simple: &APIMessage{Subject: &APIContent{Data: "Hi"}, Body: &APIBody{Text: &APIContent{Data: "Hi"}}}}},
+		{FromEmailAddress: "sender@example.com", Destination: &APIDestination{ToAddresses: []string{"to@example.com"}},
+			Content: APIEmailContent{Simple: &APIMessage{Subject: &APIContent{Data: "Hi"}}}},
+	} {
+		if _, awserr := s.SendEmail(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Errorf("Expected BadRequestException for %+v: %v", input, awserr)
+		}
+	}
+}
+
+const rawMessage = "From: Sender <sender@example.com>\r\n" +
+	"To: to@example.com, Other <other@example.com>\r\n" +
+	"Subject: =?UTF-8?Q?Caf=C3=A9?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=C3=A9 menu\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PGI+bWVudTwvYj4=\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Disposition: attachment; filename=menu.txt\r\n" +
+	"\r\n" +
+	"attached\r\n" +
+	"--outer--\r\n"
+
+func TestSendRawEmail(t *testing.T) {
+	s := newSES(t, Options{})
+
+	output, awserr := s.SendRawEmail(SendRawEmailInput{RawMessage: APIRawMessage{Data: []byte(rawMessage)}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	message := s.CapturedMessages()[0]
+	if message.MessageId != output.MessageId || message.From != "sender@example.com" || message.Subject != "Café" ||
+		message.Text != "Café menu" || message.Html != "<b>menu</b>" || message.Raw != rawMessage ||
+		!slices.Equal(message.Destinations, []string{"to@example.com", "other@example.com"}) {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	// The source and destinations override the message's headers.
+	if _, awserr := s.SendEmail(SendEmailInput{
+		FromEmailAddress: "bounces@example.com",
+		Destination:      &APIDestination{BccAddresses: []string{"hidden@example.com"}},
+		Content:          APIEmailContent{Raw: &APIRawMessage{Data: []byte(rawMessage)}},
+	}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	message = s.CapturedMessages()[1]
+	if message.From != "bounces@example.com" || !slices.Equal(message.Destinations, []string{"hidden@example.com"}) {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	for _, raw := range []string{
+		"",
+		"To: to@example.com\r\n\r\nNo sender",
+		"From: sender@example.com\r\n\r\nNo recipients",
+		"From: sender@example.com\r\nTo: not an address\r\n\r\nBody",
+	} {
+		if _, awserr := s.SendRawEmail(SendRawEmailInput{RawMessage: APIRawMessage{Data: []byte(raw)}}); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Errorf("Expected BadRequestException for %q: %v", raw, awserr)
+		}
+	}
+}
+
+func TestQueryProtocol(t *testing.T) {
+	s := newSES(t, Options{})
+	handler := NewHandler(slog.Default(), s, nil)
+
+	form := url.Values{
+		"Action":                           {"SendEmail"},
+		"Version":                          {"2010-12-01"},
+		"Source":                           {"sender@example.com"},
+		"Destination.ToAddresses.member.1": {"to@example.com"},
+		"Message.Subject.Data":             {"Hello"},
+		"Message.Body.Text.Data":           {"Hello there"},
+	}
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response := httptest.NewRecorder()
+	if !handler(response, request) {
+		t.Fatal("Expected the request to be handled")
+	}
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "<SendEmailResult><MessageId>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	if message := s.CapturedMessages()[0]; message.Text != "Hello there" || message.To[0] != "to@example.com" {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	form = url.Values{
+		"Action":          {"SendRawEmail"},
+		"Version":         {"2010-12-01"},
+		"RawMessage.Data": {base64.StdEncoding.EncodeToString([]byte("From: sender@example.com\r\n\r\nNo recipients"))},
+	}
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response = httptest.NewRecorder()
+	handler(response, request)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "<Code>InvalidParameterValue</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	mailbox := t.TempDir()
+	s := newSES(t, Options{MailboxDir: mailbox})
+	registry := make(admin.Registry)
+	s.RegisterAdminHandlers(registry)
+	handler := admin.NewHandler(registry)
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		_, awserr := s.SendEmailV1(SendEmailV1Input{
+			Source:      "sender@example.com",
+			Destination: APIDestination{ToAddresses: []string{to}},
+			Message:     APIMessageV1{Subject: APIContent{Data: "Hi"}, Body: APIBody{Text: &APIContent{Data: "Hi"}}},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/_admin/ses/messages?to=b@example.com", nil))
+	var listed struct{ Messages []CapturedMessage }
+	if err := json.Unmarshal(response.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Messages) != 1 || listed.Messages[0].Destinations[0] != "b@example.com" {
+		t.Fatalf("Unexpected messages: %+v", listed)
+	}
+
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodDelete, "/_admin/ses/messages", nil))
+	if response.Code != http.StatusNoContent || len(s.CapturedMessages()) != 0 {
+		t.Fatal("Expected the messages to be cleared", response.Code)
+	}
+	if files, _ := os.ReadDir(mailbox); len(files) != 0 {
+		t.Fatal("Expected the mailbox to be empty", files)
+	}
+}
@@ -0,0 +1,93 @@
+package ses
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/state"
+)
+
+type templateState struct {
+	Name    string
+	Subject string
+	Text    string `json:",omitempty"`
+	Html    string `json:",omitempty"`
+	Created time.Time
+}
+
+type sesState struct {
+	// Sorted by name.
+	Templates []templateState
+	// Oldest first.
+	CapturedMessages []CapturedMessage
+}
+
+// TryPause pauses changes to each region's templates and captured messages while a snapshot copies them.
+func (s *SES) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *SES) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's templates and captured messages to the archive.
+func (s *SES) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*SES).exportState)
+}
+
+func (s *SES) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	exported := sesState{CapturedMessages: s.capturedMessages}
+	for _, template := range s.templates {
+		exported.Templates = append(exported.Templates, templateState{
+			Name:    template.name,
+			Subject: template.subject,
+			Text:    template.text,
+			Html:    template.html,
+			Created: template.created,
+		})
+	}
+	slices.SortFunc(exported.Templates, func(a, b templateState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("ses.json", exported)
+}
+
+// ImportState replaces each region's templates and captured messages with the archive's. The
+// mailbox directory is left as it is: imported messages aren't written to it, and the previous ones
+// aren't deleted from it.
+func (s *SES) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*SES).importState, (*SES).exportState)
+}
+
+func (s *SES) importState(r *state.Reader) (func() error, error) {
+	var imported sesState
+	if err := r.ReadJSON("ses.json", &imported); err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*emailTemplate)
+	for _, t := range imported.Templates {
+		content := &APIEmailTemplateContent{Subject: t.Subject, Text: t.Text, Html: t.Html}
+		if awserr := validateTemplate(t.Name, content); awserr != nil {
+			return nil, fmt.Errorf("template %s: %s", t.Name, awserr.Body.Message)
+		}
+		templates[t.Name] = &emailTemplate{
+			name:    t.Name,
+			subject: t.Subject,
+			text:    t.Text,
+			html:    t.Html,
+			created: t.Created,
+		}
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.templates = templates
+		s.capturedMessages = imported.CapturedMessages
+		return nil
+	}, nil
+}
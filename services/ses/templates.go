@@ -0,0 +1,509 @@
+package ses
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultTemplatesPageSize = 10
+	maxTemplatesPageSize     = 100
+	maxBulkEntries           = 50
+)
+
+var templateNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// emailTemplate is shared by both SES APIs, which name its parts differently.
+type emailTemplate struct {
+	name    string
+	subject string
+	text    string
+	html    string
+	created time.Time
+}
+
+// render renders the template's parts with the data, into a message to be sent.
+func (t emailTemplate) render(data map[string]any) (*simpleMessage, error) {
+	message := &simpleMessage{}
+	for _, part := range []struct {
+		text     string
+		rendered *string
+	}{
+		{t.subject, &message.subject},
+		{t.text, &message.text},
+		{t.html, &message.html},
+	} {
+		rendered, err := renderTemplate(part.text, data)
+		if err != nil {
+			return nil, err
+		}
+		*part.rendered = rendered
+	}
+	return message, nil
+}
+
+func validateTemplate(name string, content *APIEmailTemplateContent) *awserrors.Error {
+	if !templateNameRegex.MatchString(name) {
+		return BadRequestException("Template name must be 1 to 64 alphanumeric characters, underscores or dashes.")
+	}
+	if content == nil {
+		return BadRequestException("Missing required field TemplateContent.")
+	}
+	for _, part := range []struct{ name, text string }{
+		{"Subject", content.Subject},
+		{"Text", content.Text},
+		{"Html", content.Html},
+	} {
+		if _, err := parseTemplate(part.text); err != nil {
+			return BadRequestException(fmt.Sprintf("The template's %s is invalid: %v", part.name, err))
+		}
+	}
+	return nil
+}
+
+// templateName returns the name of the template, which may be given by its ARN instead,
+// such as arn:aws:ses:us-east-1:123456789012:template/MyTemplate.
+func templateName(name string, arn string) string {
+	if name != "" {
+		return name
+	}
+	_, name, _ = strings.Cut(arn, ":template/")
+	return name
+}
+
+func (s *SES) lockedGetTemplate(name string) (*emailTemplate, *awserrors.Error) {
+	template, ok := s.templates[name]
+	if !ok {
+		return nil, NotFoundException(fmt.Sprintf("Template %s does not exist.", name))
+	}
+	return template, nil
+}
+
+// getTemplate returns a copy of the template, which can be rendered without holding the lock.
+func (s *SES) getTemplate(name string) (emailTemplate, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	template, awserr := s.lockedGetTemplate(name)
+	if awserr != nil {
+		return emailTemplate{}, awserr
+	}
+	return *template, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_CreateEmailTemplate.html
+func (s *SES) CreateEmailTemplate(input CreateEmailTemplateInput) (*CreateEmailTemplateOutput, *awserrors.Error) {
+	if awserr := validateTemplate(input.TemplateName, input.TemplateContent); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[input.TemplateName]; ok {
+		return nil, AlreadyExistsException(fmt.Sprintf("Template %s already exists.", input.TemplateName))
+	}
+	s.templates[input.TemplateName] = &emailTemplate{
+		name:    input.TemplateName,
+		subject: input.TemplateContent.Subject,
+		text:    input.TemplateContent.Text,
+		html:    input.TemplateContent.Html,
+		created: s.clock(),
+	}
+	return &CreateEmailTemplateOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_GetEmailTemplate.html
+func (s *SES) GetEmailTemplate(input GetEmailTemplateInput) (*GetEmailTemplateOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	template, awserr := s.lockedGetTemplate(input.TemplateName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetEmailTemplateOutput{
+		TemplateName: template.name,
+		TemplateContent: APIEmailTemplateContent{
+			Subject: template.subject,
+			Text:    template.text,
+			Html:    template.html,
+		},
+	}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_UpdateEmailTemplate.html
+func (s *SES) UpdateEmailTemplate(input UpdateEmailTemplateInput) (*UpdateEmailTemplateOutput, *awserrors.Error) {
+	if awserr := validateTemplate(input.TemplateName, input.TemplateContent); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	template, awserr := s.lockedGetTemplate(input.TemplateName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	template.subject = input.TemplateContent.Subject
+	template.text = input.TemplateContent.Text
+	template.html = input.TemplateContent.Html
+	return &UpdateEmailTemplateOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_DeleteEmailTemplate.html
+func (s *SES) DeleteEmailTemplate(input DeleteEmailTemplateInput) (*DeleteEmailTemplateOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, awserr := s.lockedGetTemplate(input.TemplateName); awserr != nil {
+		return nil, awserr
+	}
+	delete(s.templates, input.TemplateName)
+	return &DeleteEmailTemplateOutput{}, nil
+}
+
+// lockedListTemplates returns the page of templates, sorted by name, and the next token if there are more.
+func (s *SES) lockedListTemplates(nextToken string, pageSize int) ([]*emailTemplate, string, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(pageSize, defaultTemplatesPageSize, maxTemplatesPageSize, nextToken,
+		BadRequestException(fmt.Sprintf("The page size must be between 1 and %d.", maxTemplatesPageSize)),
+		BadRequestException("Invalid NextToken."))
+	if awserr != nil {
+		return nil, "", awserr
+	}
+
+	templates := make([]*emailTemplate, 0, len(s.templates))
+	for _, template := range s.templates {
+		templates = append(templates, template)
+	}
+	slices.SortFunc(templates, func(a, b *emailTemplate) int {
+		return strings.Compare(a.name, b.name)
+	})
+	page, next := pagination.Page(templates, limit, start)
+	return page, next, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_ListEmailTemplates.html
+func (s *SES) ListEmailTemplates(input ListEmailTemplatesInput) (*ListEmailTemplatesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, nextToken, awserr := s.lockedListTemplates(input.NextToken, input.PageSize)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListEmailTemplatesOutput{
+		TemplatesMetadata: []APIEmailTemplateMetadata{},
+		NextToken:         nextToken,
+	}
+	for _, template := range templates {
+		output.TemplatesMetadata = append(output.TemplatesMetadata, APIEmailTemplateMetadata{
+			TemplateName:     template.name,
+			CreatedTimestamp: timestamp.EpochSeconds(template.created),
+		})
+	}
+	return output, nil
+}
+
+// testRender renders the template with the data as the message SES would send, without its sender and recipients.
+func (s *SES) testRender(name string, data string) (string, *awserrors.Error) {
+	template, awserr := s.getTemplate(name)
+	if awserr != nil {
+		return "", awserr
+	}
+	parsed, err := parseTemplateData(data)
+	if err != nil {
+		return "", InvalidRenderingParameter(err.Error())
+	}
+	message, err := template.render(parsed)
+	if err != nil {
+		if _, ok := err.(*missingAttributeError); ok {
+			return "", MissingRenderingAttribute(err.Error())
+		}
+		return "", InvalidRenderingParameter(err.Error())
+	}
+	message.date = s.clock()
+	return string(message.format()), nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_TestRenderEmailTemplate.html
+func (s *SES) TestRenderEmailTemplate(input TestRenderEmailTemplateInput) (*TestRenderEmailTemplateOutput, *awserrors.Error) {
+	rendered, awserr := s.testRender(input.TemplateName, input.TemplateData)
+	if awserr != nil {
+		if awserr.Code == 400 {
+			// SES v2 doesn't have separate errors for rendering failures.
+			return nil, BadRequestException(awserr.Body.Message)
+		}
+		return nil, awserr
+	}
+	return &TestRenderEmailTemplateOutput{RenderedTemplate: rendered}, nil
+}
+
+// mergeTemplateData returns the default replacement values, overridden by a destination's.
+func mergeTemplateData(defaults string, replacements string) (map[string]any, error) {
+	data, err := parseTemplateData(defaults)
+	if err != nil {
+		return nil, err
+	}
+	replacementData, err := parseTemplateData(replacements)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range replacementData {
+		data[key] = value
+	}
+	return data, nil
+}
+
+// sendTemplated renders the template for the message, and sends it. Like in SES, the request succeeds if the
+// template can't be rendered with the data, such as when an attribute is missing: SES reports rendering failures
+// asynchronously. The message isn't sent, and the failure is logged instead.
+func (s *SES) sendTemplated(message *simpleMessage, template emailTemplate, data map[string]any, destination APIDestination) (string, *awserrors.Error) {
+	if awserr := validateEnvelope(message.from, destination, message.replyTo); awserr != nil {
+		return "", awserr
+	}
+	rendered, err := template.render(data)
+	if err != nil {
+		messageId := s.newMessageId()
+		s.logger.Warn("Rendering failure, so the email wasn't sent", "template", template.name, "messageId", messageId, "error", err)
+		return messageId, nil
+	}
+	message.subject = rendered.subject
+	message.text = rendered.text
+	message.html = rendered.html
+	return s.sendSimple(message, destination)
+}
+
+// mergeHeaders returns the template's headers, overridden by a destination's headers with the same name.
+func mergeHeaders(defaults []APIMessageHeader, replacements []APIMessageHeader) []APIMessageHeader {
+	headers := append([]APIMessageHeader{}, replacements...)
+	for _, header := range defaults {
+		if !slices.ContainsFunc(replacements, func(h APIMessageHeader) bool {
+			return strings.EqualFold(h.Name, header.Name)
+		}) {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_SendBulkEmail.html
+func (s *SES) SendBulkEmail(input SendBulkEmailInput) (*SendBulkEmailOutput, *awserrors.Error) {
+	defaultTemplate := input.DefaultContent.Template
+	if defaultTemplate == nil {
+		return nil, BadRequestException("Missing required field DefaultContent.Template.")
+	}
+	if len(input.BulkEmailEntries) == 0 || len(input.BulkEmailEntries) > maxBulkEntries {
+		return nil, BadRequestException(fmt.Sprintf("BulkEmailEntries must have between 1 and %d entries.", maxBulkEntries))
+	}
+	template, awserr := s.getTemplate(templateName(defaultTemplate.TemplateName, defaultTemplate.TemplateArn))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, err := parseTemplateData(defaultTemplate.TemplateData); err != nil {
+		return nil, BadRequestException(err.Error())
+	}
+
+	output := &SendBulkEmailOutput{}
+	for _, entry := range input.BulkEmailEntries {
+		var replacementData string
+		if content := entry.ReplacementEmailContent; content != nil && content.ReplacementTemplate != nil {
+			replacementData = content.ReplacementTemplate.ReplacementTemplateData
+		}
+		var destination APIDestination
+		if entry.Destination != nil {
+			destination = *entry.Destination
+		}
+
+		data, err := mergeTemplateData(defaultTemplate.TemplateData, replacementData)
+		if err != nil {
+			output.BulkEmailEntryResults = append(output.BulkEmailEntryResults, APIBulkEmailEntryResult{
+				Status: "INVALID_PARAMETER",
+				Error:  err.Error(),
+			})
+			continue
+		}
+		messageId, awserr := s.sendTemplated(&simpleMessage{
+			from:    input.FromEmailAddress,
+			replyTo: input.ReplyToAddresses,
+			headers: mergeHeaders(defaultTemplate.Headers, entry.ReplacementHeaders),
+		}, template, data, destination)
+		if awserr != nil {
+			status := "INVALID_PARAMETER"
+			if awserr.Body.Type == "MessageRejected" {
+				status = "MESSAGE_REJECTED"
+			}
+			output.BulkEmailEntryResults = append(output.BulkEmailEntryResults, APIBulkEmailEntryResult{
+				Status: status,
+				Error:  awserr.Body.Message,
+			})
+			continue
+		}
+		output.BulkEmailEntryResults = append(output.BulkEmailEntryResults, APIBulkEmailEntryResult{
+			Status:    "SUCCESS",
+			MessageId: messageId,
+		})
+	}
+	return output, nil
+}
+
+// The original SES API's template operations.
+
+func (t APITemplateV1) content() *APIEmailTemplateContent {
+	return &APIEmailTemplateContent{
+		Subject: t.SubjectPart,
+		Text:    t.TextPart,
+		Html:    t.HtmlPart,
+	}
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_CreateTemplate.html
+func (s *SES) CreateTemplate(input CreateTemplateInput) (*CreateTemplateOutput, *awserrors.Error) {
+	_, awserr := s.CreateEmailTemplate(CreateEmailTemplateInput{
+		TemplateName:    input.Template.TemplateName,
+		TemplateContent: input.Template.content(),
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &CreateTemplateOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_GetTemplate.html
+func (s *SES) GetTemplate(input GetTemplateInput) (*GetTemplateOutput, *awserrors.Error) {
+	output, awserr := s.GetEmailTemplate(GetEmailTemplateInput{TemplateName: input.TemplateName})
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetTemplateOutput{
+		Template: APITemplateV1{
+			TemplateName: output.TemplateName,
+			SubjectPart:  output.TemplateContent.Subject,
+			TextPart:     output.TemplateContent.Text,
+			HtmlPart:     output.TemplateContent.Html,
+		},
+	}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_UpdateTemplate.html
+func (s *SES) UpdateTemplate(input UpdateTemplateInput) (*UpdateTemplateOutput, *awserrors.Error) {
+	_, awserr := s.UpdateEmailTemplate(UpdateEmailTemplateInput{
+		TemplateName:    input.Template.TemplateName,
+		TemplateContent: input.Template.content(),
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &UpdateTemplateOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_DeleteTemplate.html
+func (s *SES) DeleteTemplate(input DeleteTemplateInput) (*DeleteTemplateOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Unlike SES v2, deleting a template which doesn't exist succeeds.
+	delete(s.templates, input.TemplateName)
+	return &DeleteTemplateOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_ListTemplates.html
+func (s *SES) ListTemplates(input ListTemplatesInput) (*ListTemplatesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, nextToken, awserr := s.lockedListTemplates(input.NextToken, input.MaxItems)
+	if awserr != nil {
+		return nil, awserr
+	}
+	output := &ListTemplatesOutput{NextToken: nextToken}
+	for _, template := range templates {
+		output.TemplatesMetadata = append(output.TemplatesMetadata, APITemplateMetadata{
+			Name:             template.name,
+			CreatedTimestamp: template.created.UTC().Format(time.RFC3339),
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_TestRenderTemplate.html
+func (s *SES) TestRenderTemplate(input TestRenderTemplateInput) (*TestRenderTemplateOutput, *awserrors.Error) {
+	rendered, awserr := s.testRender(input.TemplateName, input.TemplateData)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &TestRenderTemplateOutput{RenderedTemplate: rendered}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_SendTemplatedEmail.html
+func (s *SES) SendTemplatedEmail(input SendTemplatedEmailInput) (*SendTemplatedEmailOutput, *awserrors.Error) {
+	template, awserr := s.getTemplate(templateName(input.Template, input.TemplateArn))
+	if awserr != nil {
+		return nil, awserr
+	}
+	data, err := parseTemplateData(input.TemplateData)
+	if err != nil {
+		return nil, InvalidRenderingParameter(err.Error())
+	}
+	messageId, awserr := s.sendTemplated(&simpleMessage{
+		from:    input.Source,
+		replyTo: input.ReplyToAddresses,
+	}, template, data, input.Destination)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &SendTemplatedEmailOutput{MessageId: messageId}, nil
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_SendBulkTemplatedEmail.html
+func (s *SES) SendBulkTemplatedEmail(input SendBulkTemplatedEmailInput) (*SendBulkTemplatedEmailOutput, *awserrors.Error) {
+	if len(input.Destinations) == 0 || len(input.Destinations) > maxBulkEntries {
+		return nil, BadRequestException(fmt.Sprintf("Destinations must have between 1 and %d entries.", maxBulkEntries))
+	}
+	template, awserr := s.getTemplate(templateName(input.Template, input.TemplateArn))
+	if awserr != nil {
+		return nil, awserr
+	}
+	if _, err := parseTemplateData(input.DefaultTemplateData); err != nil {
+		return nil, InvalidRenderingParameter(err.Error())
+	}
+
+	output := &SendBulkTemplatedEmailOutput{}
+	for _, destination := range input.Destinations {
+		data, err := mergeTemplateData(input.DefaultTemplateData, destination.ReplacementTemplateData)
+		if err != nil {
+			output.Status = append(output.Status, APIBulkEmailDestinationStatus{
+				Status: "InvalidParameterValue",
+				Error:  err.Error(),
+			})
+			continue
+		}
+		messageId, awserr := s.sendTemplated(&simpleMessage{
+			from:    input.Source,
+			replyTo: input.ReplyToAddresses,
+		}, template, data, destination.Destination)
+		if awserr != nil {
+			status := "InvalidParameterValue"
+			if awserr.Body.Type == "MessageRejected" {
+				status = "MessageRejected"
+			}
+			output.Status = append(output.Status, APIBulkEmailDestinationStatus{
+				Status: status,
+				Error:  awserr.Body.Message,
+			})
+			continue
+		}
+		output.Status = append(output.Status, APIBulkEmailDestinationStatus{
+			Status:    "Success",
+			MessageId: messageId,
+		})
+	}
+	return output, nil
+}
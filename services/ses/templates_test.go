@@ -0,0 +1,298 @@
+package ses
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func createTemplate(t *testing.T, s *SES, name string, content APIEmailTemplateContent) {
+	_, awserr := s.CreateEmailTemplate(CreateEmailTemplateInput{TemplateName: name, TemplateContent: &content})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestEmailTemplates(t *testing.T) {
+	s := newSES(t, Options{})
+	content := APIEmailTemplateContent{Subject: "Hi {{name}}", Text: "Your order {{order.id}} shipped."}
+	createTemplate(t, s, "shipped", content)
+	createTemplate(t, s, "welcome", APIEmailTemplateContent{Subject: "Welcome", Html: "<p>Welcome</p>"})
+
+	_, awserr := s.CreateEmailTemplate(CreateEmailTemplateInput{TemplateName: "shipped", TemplateContent: &content})
+	if awserr == nil || awserr.Body.Type != "AlreadyExistsException" {
+		t.Fatal("Expected AlreadyExistsException", awserr)
+	}
+	for _, input := range []CreateEmailTemplateInput{
+		{TemplateName: "bad name", TemplateContent: &content},
+		{TemplateName: "missing-content"},
+		{TemplateName: "invalid", TemplateContent: &APIEmailTemplateContent{Subject: "{{#if a}}"}},
+	} {
+		if _, awserr := s.CreateEmailTemplate(input); awserr == nil || awserr.Body.Type != "BadRequestException" {
+			t.Errorf("Expected BadRequestException for %+v: %v", input, awserr)
+		}
+	}
+
+	got, awserr := s.GetEmailTemplate(GetEmailTemplateInput{TemplateName: "shipped"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if got.TemplateContent != content {
+		t.Fatalf("Unexpected template: %+v", got)
+	}
+
+	listed, awserr := s.ListEmailTemplates(ListEmailTemplatesInput{PageSize: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.TemplatesMetadata) != 1 || listed.TemplatesMetadata[0].TemplateName != "shipped" ||
+		listed.TemplatesMetadata[0].CreatedTimestamp != 1700000000 || listed.NextToken == "" {
+		t.Fatalf("Unexpected templates: %+v", listed)
+	}
+	listed, awserr = s.ListEmailTemplates(ListEmailTemplatesInput{PageSize: 1, NextToken: listed.NextToken})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(listed.TemplatesMetadata) != 1 || listed.TemplatesMetadata[0].TemplateName != "welcome" || listed.NextToken != "" {
+		t.Fatalf("Unexpected templates: %+v", listed)
+	}
+
+	rendered, awserr := s.TestRenderEmailTemplate(TestRenderEmailTemplateInput{
+		TemplateName: "shipped",
+		TemplateData: `{"name": "Ana", "order": {"id": 7}}`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.Contains(rendered.RenderedTemplate, "Subject: Hi Ana\r\n") ||
+		!strings.Contains(rendered.RenderedTemplate, "Your order 7 shipped.") ||
+		strings.Contains(rendered.RenderedTemplate, "From:") {
+		t.Fatal("Unexpected rendering", rendered.RenderedTemplate)
+	}
+	_, awserr = s.TestRenderEmailTemplate(TestRenderEmailTemplateInput{TemplateName: "shipped", TemplateData: `{"name": "Ana"}`})
+	if awserr == nil || awserr.Body.Type != "BadRequestException" || !strings.Contains(awserr.Body.Message, "'order.id'") {
+		t.Fatal("Expected BadRequestException", awserr)
+	}
+	_, awserr = s.TestRenderTemplate(TestRenderTemplateInput{TemplateName: "shipped", TemplateData: `{"name": "Ana"}`})
+	if awserr == nil || awserr.Body.Type != "MissingRenderingAttribute" {
+		t.Fatal("Expected MissingRenderingAttribute", awserr)
+	}
+
+	content.Subject = "Shipped"
+	if _, awserr := s.UpdateEmailTemplate(UpdateEmailTemplateInput{TemplateName: "shipped", TemplateContent: &content}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	v1, awserr := s.GetTemplate(GetTemplateInput{TemplateName: "shipped"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if v1.Template.SubjectPart != "Shipped" || v1.Template.TextPart != content.Text {
+		t.Fatalf("Unexpected template: %+v", v1)
+	}
+
+	if _, awserr := s.DeleteEmailTemplate(DeleteEmailTemplateInput{TemplateName: "shipped"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.DeleteEmailTemplate(DeleteEmailTemplateInput{TemplateName: "shipped"}); awserr == nil || awserr.Code != 404 {
+		t.Fatal("Expected NotFoundException", awserr)
+	}
+	if _, awserr := s.DeleteTemplate(DeleteTemplateInput{TemplateName: "shipped"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+}
+
+func TestSendTemplatedEmail(t *testing.T) {
+	s := newSES(t, Options{})
+	createTemplate(t, s, "order", APIEmailTemplateContent{
+		Subject: "Order {{id}}",
+		Text:    "Hi {{name}}, your {{#each items}}{{this}} {{/each}}shipped.",
+		Html:    "<p>Hi {{name}}</p>",
+	})
+
+	output, awserr := s.SendEmail(SendEmailInput{
+		FromEmailAddress: "shop@example.com",
+		Destination:      &APIDestination{ToAddresses: []string{"ana@example.com"}},
+		Content: APIEmailContent{Template: &APITemplate{
+			TemplateArn:  "arn:aws:ses:us-east-1:123456789012:template/order",
+			TemplateData: `{"id": 7, "name": "Ana & Bo", "items": ["tea", "cake"]}`,
+			Headers:      []APIMessageHeader{{Name: "X-Order", Value: "7"}},
+		}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	message := s.CapturedMessages()[0]
+	if message.MessageId != output.MessageId || message.Subject != "Order 7" ||
+		message.Text != "Hi Ana &amp; Bo, your tea cake shipped." || message.Html != "<p>Hi Ana &amp; Bo</p>" ||
+		!strings.Contains(message.Raw, "X-Order: 7\r\n") {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+
+	// Like in SES, rendering failures don't fail the request, but the email isn't sent.
+	_, awserr = s.SendTemplatedEmail(SendTemplatedEmailInput{
+		Source:       "shop@example.com",
+		Destination:  APIDestination{ToAddresses: []string{"ana@example.com"}},
+		Template:     "order",
+		TemplateData: `{"id": 8}`,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(s.CapturedMessages()) != 1 {
+		t.Fatal("Expected the email not to be sent", s.CapturedMessages())
+	}
+
+	for _, input := range []SendTemplatedEmailInput{
+		{Source: "shop@example.com", Destination: APIDestination{ToAddresses: []string{"ana@example.com"}}, Template: "missing"},
+		{Source: "shop@example.com", Destination: APIDestination{ToAddresses: []string{"ana@example.com"}}, Template: "order", TemplateData: "[]"},
+		{Source: "shop@example.com", Template: "order", TemplateData: `{"id": 8}`},
+	} {
+		if _, awserr := s.SendTemplatedEmail(input); awserr == nil {
+			t.Errorf("Expected an error for %+v", input)
+		}
+	}
+}
+
+func TestSendBulkEmail(t *testing.T) {
+	s := newSES(t, Options{})
+	createTemplate(t, s, "greeting", APIEmailTemplateContent{Subject: "{{greeting}} {{name}}", Text: "Hello"})
+
+	output, awserr := s.SendBulkEmail(SendBulkEmailInput{
+		FromEmailAddress: "shop@example.com",
+		DefaultContent: APIBulkEmailContent{Template: &APITemplate{
+			TemplateName: "greeting",
+			TemplateData: `{"greeting": "Hi", "name": "friend"}`,
+			Headers:      []APIMessageHeader{{Name: "X-Campaign", Value: "default"}, {Name: "X-Kind", Value: "greeting"}},
+		}},
+		BulkEmailEntries: []APIBulkEmailEntry{
+			{
+				Destination: &APIDestination{ToAddresses: []string{"ana@example.com"}},
+				ReplacementEmailContent: &APIReplacementEmailContent{ReplacementTemplate: &APIReplacementTemplate{
+					ReplacementTemplateData: `{"name": "Ana"}`,
+				}},
+				ReplacementHeaders: []APIMessageHeader{{Name: "x-campaign", Value: "ana"}},
+			},
+			{Destination: &APIDestination{ToAddresses: []string{"bo@example.com"}}},
+			{Destination: &APIDestination{ToAddresses: []string{"not an address"}}},
+		},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	results := output.BulkEmailEntryResults
+	if len(results) != 3 || results[0].Status != "SUCCESS" || results[1].Status != "SUCCESS" ||
+		results[2].Status != "INVALID_PARAMETER" || results[2].MessageId != "" {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	messages := s.CapturedMessages()
+	if len(messages) != 2 || messages[0].Subject != "Hi Ana" || messages[1].Subject != "Hi friend" ||
+		messages[0].MessageId != results[0].MessageId || messages[1].To[0] != "bo@example.com" {
+		t.Fatalf("Unexpected messages: %+v", messages)
+	}
+	if !strings.Contains(messages[0].Raw, "x-campaign: ana\r\n") || strings.Contains(messages[0].Raw, "X-Campaign: default") ||
+		!strings.Contains(messages[0].Raw, "X-Kind: greeting\r\n") {
+		t.Fatal("Unexpected headers", messages[0].Raw)
+	}
+
+	_, awserr = s.SendBulkEmail(SendBulkEmailInput{
+		FromEmailAddress: "shop@example.com",
+		DefaultContent:   APIBulkEmailContent{Template: &APITemplate{TemplateName: "missing"}},
+		BulkEmailEntries: []APIBulkEmailEntry{{Destination: &APIDestination{ToAddresses: []string{"ana@example.com"}}}},
+	})
+	if awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected NotFoundException", awserr)
+	}
+}
+
+func TestTemplatesQueryProtocol(t *testing.T) {
+	s := newSES(t, Options{})
+	handler := NewHandler(slog.Default(), s, nil)
+	request := func(form url.Values) *httptest.ResponseRecorder {
+		form.Set("Version", "2010-12-01")
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	response := request(url.Values{
+		"Action":                {"CreateTemplate"},
+		"Template.TemplateName": {"greeting"},
+		"Template.SubjectPart":  {"Hi {{name}}"},
+		"Template.TextPart":     {"Hello {{name}}"},
+	})
+	if response.Code != http.StatusOK {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	response = request(url.Values{
+		"Action":                {"CreateTemplate"},
+		"Template.TemplateName": {"greeting"},
+		"Template.SubjectPart":  {"Hi"},
+	})
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "<Code>AlreadyExists</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	response = request(url.Values{
+		"Action":              {"SendBulkTemplatedEmail"},
+		"Source":              {"shop@example.com"},
+		"Template":            {"greeting"},
+		"DefaultTemplateData": {`{"name": "friend"}`},
+		"Destinations.member.1.Destination.ToAddresses.member.1": {"ana@example.com"},
+		"Destinations.member.1.ReplacementTemplateData":          {`{"name": "Ana"}`},
+		"Destinations.member.2.Destination.ToAddresses.member.1": {"bo@example.com"},
+	})
+	if response.Code != http.StatusOK || strings.Count(response.Body.String(), "<Status>Success</Status>") != 2 {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	if messages := s.CapturedMessages(); len(messages) != 2 || messages[0].Text != "Hello Ana" || messages[1].Text != "Hello friend" {
+		t.Fatalf("Unexpected messages: %+v", messages)
+	}
+
+	response = request(url.Values{
+		"Action":                           {"SendTemplatedEmail"},
+		"Source":                           {"shop@example.com"},
+		"Template":                         {"missing"},
+		"TemplateData":                     {`{}`},
+		"Destination.ToAddresses.member.1": {"ana@example.com"},
+	})
+	if !strings.Contains(response.Body.String(), "<Code>TemplateDoesNotExist</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+}
+
+func TestRegions(t *testing.T) {
+	s := newSES(t, Options{})
+	createTemplate(t, s, "shipped", APIEmailTemplateContent{Subject: "Shipped", Text: "Your order has shipped"})
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.GetEmailTemplate(GetEmailTemplateInput{TemplateName: "shipped"}); awserr == nil || awserr.Body.Type != "NotFoundException" {
+		t.Fatal("Expected the template not to be in eu-west-1", awserr)
+	}
+
+	// Every region's messages are captured together.
+	_, awserr := europe.SendEmail(SendEmailInput{
+		FromEmailAddress: "sender@example.com",
+		Destination:      &APIDestination{ToAddresses: []string{"to@example.com"}},
+		Content:          APIEmailContent{Simple: &APIMessage{Subject: &APIContent{Data: "Hello"}, Body: &APIBody{Text: &APIContent{Data: "Hello from Europe"}}}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if messages := s.CapturedMessages(); len(messages) != 1 || messages[0].Subject != "Hello" {
+		t.Fatal("Unexpected messages", messages)
+	}
+	s.ClearCapturedMessages()
+	if messages := s.CapturedMessages(); len(messages) != 0 {
+		t.Fatal("Expected every region's messages to be cleared", messages)
+	}
+}
@@ -0,0 +1,342 @@
+package ses
+
+// SES v2 types, for the REST-JSON protocol.
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_Destination.html
+type APIDestination struct {
+	ToAddresses  []string
+	CcAddresses  []string
+	BccAddresses []string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_Content.html
+type APIContent struct {
+	Data    string `required:"true" error:"BadRequestException"`
+	Charset string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_Body.html
+type APIBody struct {
+	Text *APIContent
+	Html *APIContent
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_MessageHeader.html
+type APIMessageHeader struct {
+	Name  string `required:"true" length:"1,126" error:"BadRequestException"`
+	Value string `required:"true" length:",870" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_Message.html
+type APIMessage struct {
+	Subject *APIContent        `required:"true" error:"BadRequestException"`
+	Body    *APIBody           `required:"true" error:"BadRequestException"`
+	Headers []APIMessageHeader `length:",15" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_RawMessage.html
+type APIRawMessage struct {
+	Data []byte `required:"true" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_Template.html
+type APITemplate struct {
+	TemplateName string `length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"BadRequestException"`
+	TemplateArn  string
+	TemplateData string             `length:",262144" error:"BadRequestException"`
+	Headers      []APIMessageHeader `length:",15" error:"BadRequestException"`
+}
+
+// Exactly one of the fields should be set.
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_EmailContent.html
+type APIEmailContent struct {
+	Simple   *APIMessage
+	Raw      *APIRawMessage
+	Template *APITemplate
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_MessageTag.html
+type APIMessageTag struct {
+	Name  string
+	Value string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_ListManagementOptions.html
+type APIListManagementOptions struct {
+	ContactListName string
+	TopicName       string
+}
+
+type SendEmailInput struct {
+	FromEmailAddress                          string
+	FromEmailAddressIdentityArn               string
+	Destination                               *APIDestination
+	ReplyToAddresses                          []string
+	FeedbackForwardingEmailAddress            string
+	FeedbackForwardingEmailAddressIdentityArn string
+	Content                                   APIEmailContent `required:"true" error:"BadRequestException"`
+	EmailTags                                 []APIMessageTag `length:",50" error:"BadRequestException"`
+	ConfigurationSetName                      string
+	EndpointId                                string
+	ListManagementOptions                     *APIListManagementOptions
+}
+
+type SendEmailOutput struct {
+	MessageId string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_EmailTemplateContent.html
+type APIEmailTemplateContent struct {
+	Subject string `length:",998" error:"BadRequestException"`
+	Text    string
+	Html    string
+}
+
+type CreateEmailTemplateInput struct {
+	TemplateName    string                   `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"BadRequestException"`
+	TemplateContent *APIEmailTemplateContent `required:"true" error:"BadRequestException"`
+}
+
+type CreateEmailTemplateOutput struct{}
+
+type GetEmailTemplateInput struct {
+	TemplateName string `json:"-" rest:"path:TemplateName" required:"true" length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"BadRequestException"`
+}
+
+type GetEmailTemplateOutput struct {
+	TemplateName    string
+	TemplateContent APIEmailTemplateContent
+}
+
+type UpdateEmailTemplateInput struct {
+	TemplateName    string                   `json:"-" rest:"path:TemplateName" required:"true" length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"BadRequestException"`
+	TemplateContent *APIEmailTemplateContent `required:"true" error:"BadRequestException"`
+}
+
+type UpdateEmailTemplateOutput struct{}
+
+type DeleteEmailTemplateInput struct {
+	TemplateName string `json:"-" rest:"path:TemplateName" required:"true" length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"BadRequestException"`
+}
+
+type DeleteEmailTemplateOutput struct{}
+
+type ListEmailTemplatesInput struct {
+	NextToken string `json:"-" rest:"query:NextToken"`
+	PageSize  int    `json:"-" rest:"query:PageSize" range:"1,100" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_EmailTemplateMetadata.html
+type APIEmailTemplateMetadata struct {
+	TemplateName     string
+	CreatedTimestamp float64
+}
+
+type ListEmailTemplatesOutput struct {
+	TemplatesMetadata []APIEmailTemplateMetadata
+	NextToken         string `json:",omitempty"`
+}
+
+type TestRenderEmailTemplateInput struct {
+	TemplateName string `json:"-" rest:"path:TemplateName" required:"true" length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"BadRequestException"`
+	TemplateData string `required:"true" length:",262144" error:"BadRequestException"`
+}
+
+type TestRenderEmailTemplateOutput struct {
+	RenderedTemplate string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_BulkEmailContent.html
+type APIBulkEmailContent struct {
+	Template *APITemplate
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_ReplacementTemplate.html
+type APIReplacementTemplate struct {
+	ReplacementTemplateData string `length:",262144" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_ReplacementEmailContent.html
+type APIReplacementEmailContent struct {
+	ReplacementTemplate *APIReplacementTemplate
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_BulkEmailEntry.html
+type APIBulkEmailEntry struct {
+	Destination             *APIDestination `required:"true" error:"BadRequestException"`
+	ReplacementTags         []APIMessageTag
+	ReplacementEmailContent *APIReplacementEmailContent
+	ReplacementHeaders      []APIMessageHeader `length:",15" error:"BadRequestException"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_BulkEmailEntryResult.html
+type APIBulkEmailEntryResult struct {
+	Status    string
+	Error     string `json:",omitempty"`
+	MessageId string `json:",omitempty"`
+}
+
+type SendBulkEmailInput struct {
+	FromEmailAddress                          string
+	FromEmailAddressIdentityArn               string
+	ReplyToAddresses                          []string
+	FeedbackForwardingEmailAddress            string
+	FeedbackForwardingEmailAddressIdentityArn string
+	DefaultEmailTags                          []APIMessageTag
+	DefaultContent                            APIBulkEmailContent `required:"true" error:"BadRequestException"`
+	BulkEmailEntries                          []APIBulkEmailEntry `required:"true" length:"1,50" error:"BadRequestException"`
+	ConfigurationSetName                      string
+	EndpointId                                string
+}
+
+type SendBulkEmailOutput struct {
+	BulkEmailEntryResults []APIBulkEmailEntryResult
+}
+
+// SES v1 types, for the Query protocol.
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_Message.html
+type APIMessageV1 struct {
+	Subject APIContent
+	Body    APIBody
+}
+
+type SendEmailV1Input struct {
+	Source               string
+	SourceArn            string
+	Destination          APIDestination
+	Message              APIMessageV1
+	ReplyToAddresses     []string
+	ReturnPath           string
+	ReturnPathArn        string
+	Tags                 []APIMessageTag
+	ConfigurationSetName string
+}
+
+type SendEmailV1Output struct {
+	MessageId string
+}
+
+type SendRawEmailInput struct {
+	Source               string
+	SourceArn            string
+	FromArn              string
+	ReturnPathArn        string
+	Destinations         []string      `length:",50" error:"ValidationError"`
+	RawMessage           APIRawMessage `required:"true" error:"ValidationError"`
+	Tags                 []APIMessageTag
+	ConfigurationSetName string
+}
+
+type SendRawEmailOutput struct {
+	MessageId string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_Template.html
+type APITemplateV1 struct {
+	TemplateName string `required:"true" length:"1,64" pattern:"[a-zA-Z0-9_-]+" error:"ValidationError"`
+	SubjectPart  string `length:",998" error:"ValidationError"`
+	TextPart     string
+	HtmlPart     string
+}
+
+type CreateTemplateInput struct {
+	Template APITemplateV1 `required:"true" error:"ValidationError"`
+}
+
+type CreateTemplateOutput struct{}
+
+type GetTemplateInput struct {
+	TemplateName string `required:"true" error:"ValidationError"`
+}
+
+type GetTemplateOutput struct {
+	Template APITemplateV1
+}
+
+type UpdateTemplateInput struct {
+	Template APITemplateV1 `required:"true" error:"ValidationError"`
+}
+
+type UpdateTemplateOutput struct{}
+
+type DeleteTemplateInput struct {
+	TemplateName string `required:"true" error:"ValidationError"`
+}
+
+type DeleteTemplateOutput struct{}
+
+type ListTemplatesInput struct {
+	NextToken string
+	MaxItems  int `range:"1,100" error:"ValidationError"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_TemplateMetadata.html
+type APITemplateMetadata struct {
+	Name             string
+	CreatedTimestamp string
+}
+
+type ListTemplatesOutput struct {
+	TemplatesMetadata []APITemplateMetadata
+	NextToken         string
+}
+
+type TestRenderTemplateInput struct {
+	TemplateName string `required:"true" error:"ValidationError"`
+	TemplateData string `required:"true" length:",262144" error:"ValidationError"`
+}
+
+type TestRenderTemplateOutput struct {
+	RenderedTemplate string
+}
+
+type SendTemplatedEmailInput struct {
+	Source               string `required:"true" error:"ValidationError"`
+	SourceArn            string
+	Destination          APIDestination `required:"true" error:"ValidationError"`
+	ReplyToAddresses     []string
+	ReturnPath           string
+	ReturnPathArn        string
+	Tags                 []APIMessageTag `length:",50" error:"ValidationError"`
+	ConfigurationSetName string
+	Template             string
+	TemplateArn          string
+	TemplateData         string `required:"true" length:",262144" error:"ValidationError"`
+}
+
+type SendTemplatedEmailOutput struct {
+	MessageId string
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_BulkEmailDestination.html
+type APIBulkEmailDestination struct {
+	Destination             APIDestination `required:"true" error:"ValidationError"`
+	ReplacementTags         []APIMessageTag
+	ReplacementTemplateData string `length:",262144" error:"ValidationError"`
+}
+
+// https://docs.aws.amazon.com/ses/latest/APIReference/API_BulkEmailDestinationStatus.html
+type APIBulkEmailDestinationStatus struct {
+	Status    string
+	Error     string
+	MessageId string
+}
+
+type SendBulkTemplatedEmailInput struct {
+	Source               string `required:"true" error:"ValidationError"`
+	SourceArn            string
+	ReplyToAddresses     []string
+	ReturnPath           string
+	ReturnPathArn        string
+	ConfigurationSetName string
+	DefaultTags          []APIMessageTag
+	Template             string `required:"true" error:"ValidationError"`
+	TemplateArn          string
+	DefaultTemplateData  string                    `length:",262144" error:"ValidationError"`
+	Destinations         []APIBulkEmailDestination `required:"true" length:"1,50" error:"ValidationError"`
+}
+
+type SendBulkTemplatedEmailOutput struct {
+	Status []APIBulkEmailDestinationStatus
+}
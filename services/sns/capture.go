@@ -0,0 +1,122 @@
+package sns
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/awserrors"
+)
+
+// CapturedMessage is an SMS or email which would have been sent. Instead, it is kept
+// so tests can inspect it through the admin API.
+type CapturedMessage struct {
+	// sms, email or email-json
+	Protocol string
+	// The phone number or email address.
+	Destination string
+	MessageId   string
+	// Empty if the message was published directly to a phone number.
+	TopicArn        string `json:",omitempty"`
+	SubscriptionArn string `json:",omitempty"`
+	Subject         string `json:",omitempty"`
+	Message         string
+	Timestamp       time.Time
+}
+
+func (s *SNS) lockedCapture(topic *Topic, subscription *Subscription, message *Message) {
+	body := messageForProtocol(message, subscription.Protocol)
+	if subscription.Protocol == "email-json" {
+		body = s.notificationJSON(topic, subscription, message)
+	}
+	s.capturedMessages = append(s.capturedMessages, CapturedMessage{
+		Protocol:        subscription.Protocol,
+		Destination:     subscription.Endpoint,
+		MessageId:       message.MessageId,
+		TopicArn:        topic.ARN,
+		SubscriptionArn: subscription.ARN,
+		Subject:         message.Subject,
+		Message:         body,
+		Timestamp:       message.Timestamp,
+	})
+}
+
+// lockedPublishToPhoneNumber sends an SMS directly, rather than through a topic.
+func (s *SNS) lockedPublishToPhoneNumber(input PublishInput) (*PublishOutput, *awserrors.Error) {
+	if !phoneNumberRegex.MatchString(input.PhoneNumber) {
+		return nil, InvalidParameter("Invalid parameter: PhoneNumber Reason: " + input.PhoneNumber + " is not valid to publish to")
+	}
+	awserr := validateMessage(input.Message, input.Subject, input.MessageStructure, input.MessageAttributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	message := &Message{
+		MessageId:        uuid.Must(uuid.NewV4()).String(),
+		Message:          input.Message,
+		MessageStructure: input.MessageStructure,
+		Timestamp:        s.clock(),
+	}
+	s.capturedMessages = append(s.capturedMessages, CapturedMessage{
+		Protocol:    "sms",
+		Destination: input.PhoneNumber,
+		MessageId:   message.MessageId,
+		Message:     messageForProtocol(message, "sms"),
+		Timestamp:   message.Timestamp,
+	})
+	return &PublishOutput{
+		MessageId: message.MessageId,
+	}, nil
+}
+
+// CapturedMessages returns every region's captured SMS and email messages, oldest first.
+func (s *SNS) CapturedMessages() []CapturedMessage {
+	messages := []CapturedMessage{}
+	for _, r := range s.regions.All() {
+		r.mu.Lock()
+		messages = append(messages, r.capturedMessages...)
+		r.mu.Unlock()
+	}
+	slices.SortStableFunc(messages, func(a, b CapturedMessage) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return messages
+}
+
+// ClearCapturedMessages forgets every region's captured messages, for example between tests.
+func (s *SNS) ClearCapturedMessages() {
+	for _, r := range s.regions.All() {
+		r.mu.Lock()
+		r.capturedMessages = nil
+		r.mu.Unlock()
+	}
+}
+
+// RegisterAdminHandlers adds the captured messages to the admin API.
+// GET sns/messages lists them, optionally filtered by the protocol and destination query parameters,
+// and DELETE sns/messages clears them.
+func (s *SNS) RegisterAdminHandlers(registry admin.Registry) {
+	registry["sns/messages"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			protocol := r.URL.Query().Get("protocol")
+			destination := r.URL.Query().Get("destination")
+			messages := []CapturedMessage{}
+			for _, message := range s.CapturedMessages() {
+				if (protocol == "" || message.Protocol == protocol) &&
+					(destination == "" || message.Destination == destination) {
+					messages = append(messages, message)
+				}
+			}
+			admin.WriteJSON(w, struct{ Messages []CapturedMessage }{messages})
+		case http.MethodDelete:
+			s.ClearCapturedMessages()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package sns
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/services/sqs"
+)
+
+const timestampFormat = "2006-01-02T15:04:05.000Z"
+
+// notification is the JSON envelope messages are delivered in, unless raw delivery is enabled.
+// It is also used for subscription confirmations, which set the Token and SubscribeURL instead.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html
+type notification struct {
+	Type              string
+	MessageId         string
+	SequenceNumber    string `json:",omitempty"`
+	Token             string `json:",omitempty"`
+	TopicArn          string
+	Subject           string `json:",omitempty"`
+	Message           string
+	SubscribeURL      string `json:",omitempty"`
+	Timestamp         string
+	SignatureVersion  string
+	Signature         string
+	SigningCertURL    string
+	UnsubscribeURL    string                           `json:",omitempty"`
+	MessageAttributes map[string]notificationAttribute `json:",omitempty"`
+}
+
+type notificationAttribute struct {
+	Type  string
+	Value string
+}
+
+// messageForProtocol returns the message body to deliver to the protocol.
+// Messages with a json structure can have a body per protocol, with a default for the others.
+func messageForProtocol(message *Message, protocol string) string {
+	if message.MessageStructure != "json" {
+		return message.Message
+	}
+	// The structure was validated when the message was published.
+	var messages map[string]any
+	json.Unmarshal([]byte(message.Message), &messages)
+	if body, ok := messages[protocol].(string); ok {
+		return body
+	}
+	return messages["default"].(string)
+}
+
+func (s *SNS) unsubscribeURL(subscriptionArn string) string {
+	return fmt.Sprintf("http://%s/?Action=Unsubscribe&SubscriptionArn=%s", s.addr, url.QueryEscape(subscriptionArn))
+}
+
+func marshalNotification(n *notification) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	// Messages are delivered as they were published.
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(n)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// newNotification returns the signed notification for delivering the message to the subscription.
+func (s *SNS) newNotification(topic *Topic, subscription *Subscription, message *Message) *notification {
+	n := &notification{
+		Type:           "Notification",
+		MessageId:      message.MessageId,
+		SequenceNumber: message.SequenceNumber,
+		TopicArn:       message.TopicArn,
+		Subject:        message.Subject,
+		Message:        messageForProtocol(message, subscription.Protocol),
+		Timestamp:      message.Timestamp.UTC().Format(timestampFormat),
+		UnsubscribeURL: s.unsubscribeURL(subscription.ARN),
+	}
+	if len(message.MessageAttributes) > 0 {
+		n.MessageAttributes = make(map[string]notificationAttribute)
+		for name, attribute := range message.MessageAttributes {
+			value := attribute.StringValue
+			if strings.HasPrefix(attribute.DataType, "Binary") {
+				value = base64.StdEncoding.EncodeToString(attribute.BinaryValue)
+			}
+			n.MessageAttributes[name] = notificationAttribute{
+				Type:  attribute.DataType,
+				Value: value,
+			}
+		}
+	}
+	s.sign(n, topic.Attributes["SignatureVersion"])
+	return n
+}
+
+func (s *SNS) notificationJSON(topic *Topic, subscription *Subscription, message *Message) string {
+	return marshalNotification(s.newNotification(topic, subscription, message))
+}
+
+func isRawMessageDelivery(subscription *Subscription) bool {
+	raw, _ := strconv.ParseBool(subscription.Attributes["RawMessageDelivery"])
+	return raw
+}
+
+// lockedDeliver sends the message to each of the topic's subscriptions.
+func (s *SNS) lockedDeliver(topic *Topic, message *Message) {
+	for _, subscription := range topic.Subscriptions {
+		if subscription.filterPolicy != nil && !subscription.filterPolicy.matches(message) {
+			continue
+		}
+		switch subscription.Protocol {
+		case "sqs":
+			s.deliverToSQS(topic, subscription, message)
+		case "http", "https":
+			if !subscription.PendingConfirmation {
+				s.lockedDeliverToHTTP(topic, subscription, message)
+			}
+		case "lambda":
+			s.lockedDeliverToLambda(topic, subscription, message)
+		case "sms", "email", "email-json":
+			s.lockedCapture(topic, subscription, message)
+		default:
+			s.logger.Debug("Delivery is not supported for protocol",
+				"protocol", subscription.Protocol, "subscription", subscription.ARN)
+		}
+	}
+}
+
+func (s *SNS) deliverToSQS(topic *Topic, subscription *Subscription, message *Message) {
+	if s.sqs == nil {
+		s.logger.Warn("Cannot deliver to SQS subscription because SQS is not enabled", "subscription", subscription.ARN)
+		return
+	}
+
+	input := sqs.SendMessageInput{
+		MessageBody: s.notificationJSON(topic, subscription, message),
+	}
+	if isRawMessageDelivery(subscription) {
+		input.MessageBody = messageForProtocol(message, subscription.Protocol)
+		input.MessageAttributes = make(map[string]sqs.APIAttribute)
+		for name, attribute := range message.MessageAttributes {
+			input.MessageAttributes[name] = sqs.APIAttribute{
+				BinaryValue: attribute.BinaryValue,
+				DataType:    attribute.DataType,
+				StringValue: attribute.StringValue,
+			}
+		}
+	}
+
+	// Messages from FIFO topics keep their group, so FIFO queues receive them in order.
+	if topic.Fifo && strings.HasSuffix(subscription.Endpoint, ".fifo") {
+		input.MessageGroupId = message.MessageGroupId
+		input.MessageDeduplicationId = message.MessageDeduplicationId
+	}
+
+	_, err := s.sqs.SendMessageToQueueArn(subscription.Endpoint, input)
+	if err != nil {
+		s.logger.Warn("Failed to deliver message to SQS",
+			"subscription", subscription.ARN, "queue", subscription.Endpoint, "error", err.Body.Message)
+	}
+}
@@ -0,0 +1,41 @@
+package sns
+
+import "aws-in-a-box/awserrors"
+
+func NotFound(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 404,
+		Body: awserrors.ErrorBody{
+			Type:    "NotFound",
+			Message: message,
+		},
+	}
+}
+
+func InvalidParameter(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameter", message)
+}
+
+func InvalidParameterValue(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ParameterValueInvalid", message)
+}
+
+func EmptyBatchRequest(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("EmptyBatchRequest", message)
+}
+
+func TooManyEntriesInBatchRequest(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("TooManyEntriesInBatchRequest", message)
+}
+
+func BatchEntryIdsNotDistinct(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BatchEntryIdsNotDistinct", message)
+}
+
+func InvalidBatchEntryId(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidBatchEntryId", message)
+}
+
+func BatchRequestTooLong(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BatchRequestTooLong", message)
+}
@@ -0,0 +1,141 @@
+package sns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/sns/latest/dg/sns-fifo-topics.html
+
+const (
+	deduplicationInterval   = 5 * time.Minute
+	maxMessageGroupIdLength = 128
+)
+
+// Alphanumeric characters and punctuation.
+var messageGroupIdRegex = regexp.MustCompile("^[a-zA-Z0-9!\"#$%&'()*+,\\-./:;<=>?@\\[\\\\\\]^_`{|}~]+$")
+
+// deduplicationEntry records a message published to a FIFO topic, so that messages published again with
+// the same deduplication ID within the deduplication interval are accepted but not delivered.
+type deduplicationEntry struct {
+	deduplicationId string
+	messageId       string
+	sequenceNumber  string
+	expires         time.Time
+}
+
+// parseFifoAttributes checks the FIFO attributes of a new topic, and returns whether it is a FIFO topic
+// and whether it has content based deduplication.
+func parseFifoAttributes(name string, attributes map[string]string) (bool, bool, *awserrors.Error) {
+	fifo := false
+	if value, ok := attributes["FifoTopic"]; ok {
+		var err error
+		fifo, err = strconv.ParseBool(value)
+		if err != nil {
+			return false, false, InvalidParameter("Invalid parameter: Attributes Reason: FifoTopic: Invalid value [" + value + "]. Must be true or false.")
+		}
+	}
+	if fifo != strings.HasSuffix(name, ".fifo") {
+		return false, false, InvalidParameter(
+			"Invalid parameter: Fifo Topic names must end with .fifo and must be made up of only uppercase and lowercase ASCII letters, numbers, underscores, and hyphens, and must be between 1 and 256 characters long.")
+	}
+
+	contentBasedDeduplication := false
+	if value, ok := attributes["ContentBasedDeduplication"]; ok {
+		if !fifo {
+			return false, false, InvalidParameter("Invalid parameter: Attributes Reason: Content based deduplication can only be set for FIFO topics")
+		}
+		var err error
+		contentBasedDeduplication, err = strconv.ParseBool(value)
+		if err != nil {
+			return false, false, InvalidParameter("Invalid parameter: Attributes Reason: ContentBasedDeduplication: Invalid value [" + value + "]. Must be true or false.")
+		}
+	}
+	return fifo, contentBasedDeduplication, nil
+}
+
+// validateSubscriptionPairing checks the subscription protocol and endpoint can be used with the topic type.
+func (t *Topic) validateSubscriptionPairing(protocol string, endpoint string) *awserrors.Error {
+	if t.Fifo && protocol != "sqs" {
+		return InvalidParameter("Invalid parameter: Invalid protocol type: " + protocol + ". FIFO topics only support the sqs protocol")
+	}
+	if !t.Fifo && protocol == "sqs" && strings.HasSuffix(endpoint, ".fifo") {
+		return InvalidParameter("Invalid parameter: Invalid parameter: Endpoint Reason: FIFO SQS Queues can not be subscribed to standard SNS topics")
+	}
+	return nil
+}
+
+// validateFifoParameters checks the FIFO specific parameters of a message being published,
+// and returns its deduplication ID.
+func (t *Topic) validateFifoParameters(messageGroupId string, deduplicationId string, message string) (string, *awserrors.Error) {
+	if !t.Fifo {
+		if messageGroupId != "" || deduplicationId != "" {
+			return "", InvalidParameter(
+				"Invalid parameter: The request includes MessageGroupId or MessageDeduplicationId parameters that are not valid for this topic type")
+		}
+		return "", nil
+	}
+
+	if messageGroupId == "" {
+		return "", InvalidParameter("Invalid parameter: The MessageGroupId parameter is required for FIFO topics")
+	}
+	if len(messageGroupId) > maxMessageGroupIdLength || !messageGroupIdRegex.MatchString(messageGroupId) {
+		return "", InvalidParameter(
+			"Invalid parameter: MessageGroupId Reason: MessageGroupId can only include alphanumeric and punctuation characters. 1 to 128 in length.")
+	}
+	if deduplicationId == "" {
+		if !t.ContentBasedDeduplication {
+			return "", InvalidParameter(
+				"Invalid parameter: The topic should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+		}
+		hash := sha256.Sum256([]byte(message))
+		deduplicationId = hex.EncodeToString(hash[:])
+	} else if len(deduplicationId) > maxMessageGroupIdLength || !messageGroupIdRegex.MatchString(deduplicationId) {
+		return "", InvalidParameter(
+			"Invalid parameter: MessageDeduplicationId Reason: MessageDeduplicationId can only include alphanumeric and punctuation characters. 1 to 128 in length.")
+	}
+	return deduplicationId, nil
+}
+
+// lockedExpireDeduplicationIds forgets deduplication IDs older than the deduplication interval.
+// Entries are recorded in order, so the expired ones are at the front.
+func (t *Topic) lockedExpireDeduplicationIds(now time.Time) {
+	i := 0
+	for i < len(t.deduplicationEntries) && !now.Before(t.deduplicationEntries[i].expires) {
+		delete(t.deduplicationEntriesById, t.deduplicationEntries[i].deduplicationId)
+		i++
+	}
+	t.deduplicationEntries = t.deduplicationEntries[i:]
+}
+
+// lockedRecordFifoMessage assigns the next sequence number to the message, and remembers its
+// deduplication ID. It returns false if the message is a duplicate, in which case it is given the
+// original's ID and sequence number, and must not be delivered.
+func (t *Topic) lockedRecordFifoMessage(message *Message) bool {
+	t.lockedExpireDeduplicationIds(message.Timestamp)
+	if entry, ok := t.deduplicationEntriesById[message.MessageDeduplicationId]; ok {
+		message.MessageId = entry.messageId
+		message.SequenceNumber = entry.sequenceNumber
+		return false
+	}
+
+	t.lastSequenceNumber++
+	message.SequenceNumber = fmt.Sprintf("%020d", t.lastSequenceNumber)
+
+	entry := &deduplicationEntry{
+		deduplicationId: message.MessageDeduplicationId,
+		messageId:       message.MessageId,
+		sequenceNumber:  message.SequenceNumber,
+		expires:         message.Timestamp.Add(deduplicationInterval),
+	}
+	t.deduplicationEntries = append(t.deduplicationEntries, entry)
+	t.deduplicationEntriesById[entry.deduplicationId] = entry
+	return true
+}
@@ -0,0 +1,93 @@
+package sns
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/eventpattern"
+)
+
+const maxFilterPolicyKeys = 5
+
+// binaryAttribute stands in for Binary message attributes, which filter policies can only check exist.
+type binaryAttribute struct{}
+
+// https://docs.aws.amazon.com/sns/latest/dg/message-filtering.html
+type filterPolicy struct {
+	// Either MessageAttributes or MessageBody.
+	scope  string
+	policy *eventpattern.Pattern
+}
+
+func invalidFilterPolicy(reason string) *awserrors.Error {
+	return InvalidParameter("Invalid parameter: FilterPolicy: " + reason)
+}
+
+// parseFilterPolicy parses and validates the subscription's filter policy, if it has one.
+func parseFilterPolicy(attributes map[string]string) (*filterPolicy, *awserrors.Error) {
+	scope := attributes["FilterPolicyScope"]
+	switch scope {
+	case "":
+		scope = "MessageAttributes"
+	case "MessageAttributes", "MessageBody":
+	default:
+		return nil, InvalidParameter("Invalid parameter: Attributes Reason: FilterPolicyScope: Invalid value [" + scope + "]. Please use either MessageBody or MessageAttributes")
+	}
+
+	value, ok := attributes["FilterPolicy"]
+	if !ok || value == "" {
+		return nil, nil
+	}
+	var object map[string]any
+	if err := eventpattern.Decode(value, &object); err != nil {
+		return nil, invalidFilterPolicy("failed to parse JSON")
+	}
+	if len(object) == 0 {
+		return nil, invalidFilterPolicy("Empty policy")
+	}
+	// Only policies on the message body can have nested objects.
+	policy, err := eventpattern.New(object, eventpattern.Options{Flat: scope != "MessageBody", FilterPolicy: true})
+	if err != nil {
+		return nil, invalidFilterPolicy(err.Error())
+	}
+	if policy.Keys() > maxFilterPolicyKeys {
+		return nil, invalidFilterPolicy(fmt.Sprintf("Filter policy can not have more than %d keys", maxFilterPolicyKeys))
+	}
+	return &filterPolicy{
+		scope:  scope,
+		policy: policy,
+	}, nil
+}
+
+// matches reports whether the message passes the filter policy.
+func (f *filterPolicy) matches(message *Message) bool {
+	var data map[string]any
+	if f.scope == "MessageBody" {
+		if err := eventpattern.Decode(message.Message, &data); err != nil {
+			return false
+		}
+	} else {
+		data = make(map[string]any)
+		for name, attribute := range message.MessageAttributes {
+			switch attribute.DataType {
+			case "String.Array":
+				var values []any
+				if err := eventpattern.Decode(attribute.StringValue, &values); err != nil {
+					return false
+				}
+				data[name] = values
+			case "Binary":
+				data[name] = binaryAttribute{}
+			default:
+				if strings.HasPrefix(attribute.DataType, "Number") {
+					data[name] = json.Number(attribute.StringValue)
+				} else {
+					data[name] = attribute.StringValue
+				}
+			}
+		}
+	}
+	return f.policy.Matches(data)
+}
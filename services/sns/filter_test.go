@@ -0,0 +1,130 @@
+package sns
+
+import (
+	"testing"
+)
+
+var testMessage = &Message{
+	Message: `{"store": "example_corp", "order": {"price": 120.5, "items": ["pants", "shirt"]}, "ip": "10.0.0.12", "gift": null}`,
+	MessageAttributes: map[string]MessageAttributeValue{
+		"store":    {DataType: "String", StringValue: "example_corp"},
+		"event":    {DataType: "String", StringValue: "order_placed"},
+		"price":    {DataType: "Number", StringValue: "120.5"},
+		"colors":   {DataType: "String.Array", StringValue: `["red", "green", 3]`},
+		"customer": {DataType: "String.encrypted", StringValue: "Example"},
+		"payload":  {DataType: "Binary", BinaryValue: []byte("data")},
+	},
+}
+
+func TestFilterPolicyAttributes(t *testing.T) {
+	cases := map[string]bool{
+		`{"store": ["example_corp"]}`:                                       true,
+		`{"store": ["other_corp"]}`:                                         false,
+		`{"store": ["other_corp", "example_corp"]}`:                         true,
+		`{"store": ["example_corp"], "event": ["order_cancelled"]}`:         false,
+		`{"price": [120.5]}`:                                                true,
+		`{"price": ["120.5"]}`:                                              false,
+		`{"price": [{"numeric": [">", 100, "<=", 200]}]}`:                   true,
+		`{"price": [{"numeric": ["<", 100]}]}`:                              false,
+		`{"price": [{"numeric": ["=", 1.205e2]}]}`:                          true,
+		`{"colors": ["green"]}`:                                             true,
+		`{"colors": [3]}`:                                                   true,
+		`{"colors": ["blue"]}`:                                              false,
+		`{"event": [{"prefix": "order_"}]}`:                                 true,
+		`{"event": [{"suffix": "_placed"}]}`:                                true,
+		`{"event": [{"equals-ignore-case": "ORDER_PLACED"}]}`:               true,
+		`{"event": [{"anything-but": "order_placed"}]}`:                     false,
+		`{"event": [{"anything-but": ["order_cancelled", "refunded"]}]}`:    true,
+		`{"event": [{"anything-but": {"prefix": "order_"}}]}`:               false,
+		`{"price": [{"anything-but": [100, 200]}]}`:                         true,
+		`{"missing": [{"anything-but": "value"}]}`:                          false,
+		`{"payload": [{"exists": true}]}`:                                   true,
+		`{"missing": [{"exists": false}]}`:                                  true,
+		`{"store": [{"exists": false}]}`:                                    false,
+		`{"customer": ["Example"]}`:                                         true,
+		`{"$or": [{"store": ["other_corp"]}, {"event": ["order_placed"]}]}`: true,
+		`{"$or": [{"store": ["other_corp"]}, {"event": ["refunded"]}]}`:     false,
+	}
+
+	for policy, expected := range cases {
+		f, err := parseFilterPolicy(map[string]string{"FilterPolicy": policy})
+		if err != nil {
+			t.Fatal(policy, err)
+		}
+		if f.matches(testMessage) != expected {
+			t.Fatal("Wrong result for", policy)
+		}
+	}
+}
+
+func TestFilterPolicyBody(t *testing.T) {
+	cases := map[string]bool{
+		`{"store": ["example_corp"]}`:                                      true,
+		`{"order": {"price": [{"numeric": [">=", 120.5]}]}}`:               true,
+		`{"order": {"price": [{"numeric": [">", 120.5]}]}}`:                false,
+		`{"order": {"items": ["shirt"]}}`:                                  true,
+		`{"order": {"missing": [{"exists": false}]}}`:                      true,
+		`{"order": ["pants"]}`:                                             false,
+		`{"ip": [{"cidr": "10.0.0.0/24"}]}`:                                true,
+		`{"ip": [{"cidr": "10.0.1.0/24"}]}`:                                false,
+		`{"gift": [null]}`:                                                 true,
+		`{"$or": [{"store": ["other"]}, {"order": {"items": ["pants"]}}]}`: true,
+	}
+
+	for policy, expected := range cases {
+		f, err := parseFilterPolicy(map[string]string{
+			"FilterPolicy":      policy,
+			"FilterPolicyScope": "MessageBody",
+		})
+		if err != nil {
+			t.Fatal(policy, err)
+		}
+		if f.matches(testMessage) != expected {
+			t.Fatal("Wrong result for", policy)
+		}
+	}
+
+	// Messages which aren't JSON never match.
+	f, _ := parseFilterPolicy(map[string]string{
+		"FilterPolicy":      `{"store": [{"exists": false}]}`,
+		"FilterPolicyScope": "MessageBody",
+	})
+	if f.matches(&Message{Message: "not json"}) {
+		t.Fatal("Non-JSON message should not match")
+	}
+}
+
+func TestFilterPolicyErrors(t *testing.T) {
+	policies := []string{
+		`not json`,
+		`{}`,
+		`{"store": "example_corp"}`,
+		`{"store": []}`,
+		`{"order": {"price": [1]}}`,
+		`{"store": [{"unknown": "value"}]}`,
+		`{"store": [{"prefix": "a", "suffix": "b"}]}`,
+		`{"store": [{"prefix": ""}]}`,
+		`{"store": [{"exists": "yes"}]}`,
+		`{"store": [{"anything-but": []}]}`,
+		`{"store": [{"anything-but": {"exists": true}}]}`,
+		`{"price": [{"numeric": [">", "one"]}]}`,
+		`{"price": [{"numeric": [">", 10, "<", 5]}]}`,
+		`{"price": [{"numeric": ["=", 1, "<", 5]}]}`,
+		`{"price": [{"numeric": ["!", 1]}]}`,
+		`{"ip": [{"cidr": "10.0.0.1"}]}`,
+		`{"$or": [{"store": ["a"]}]}`,
+		`{"a": [1], "b": [1], "c": [1], "d": [1], "e": [1], "f": [1]}`,
+	}
+
+	for _, policy := range policies {
+		_, err := parseFilterPolicy(map[string]string{"FilterPolicy": policy})
+		if err == nil {
+			t.Fatal("Expected error for", policy)
+		}
+	}
+
+	_, err := parseFilterPolicy(map[string]string{"FilterPolicyScope": "Everything"})
+	if err == nil {
+		t.Fatal("Expected error for invalid scope")
+	}
+}
@@ -0,0 +1,62 @@
+package sns
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/query"
+	"aws-in-a-box/region"
+)
+
+// SNS only supports the Query protocol.
+var queryProtocol = query.Protocol{
+	Version:      "2010-03-31",
+	XMLNamespace: "http://sns.amazonaws.com/doc/2010-03-31/",
+}
+
+func NewHandler(logger *slog.Logger, s *SNS, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := query.NewRegistry(queryProtocol)
+	query.RegisterWithRequest(logger, registry, "ConfirmSubscription", forARN(s, (*SNS).ConfirmSubscription, func(input ConfirmSubscriptionInput) string {
+		return input.TopicArn
+	}))
+	query.RegisterWithRequest(logger, registry, "CreateTopic", region.Method(s.regions, (*SNS).CreateTopic))
+	query.RegisterWithRequest(logger, registry, "DeleteTopic", region.Method(s.regions, (*SNS).DeleteTopic))
+	query.RegisterWithRequest(logger, registry, "GetSubscriptionAttributes", region.Method(s.regions, (*SNS).GetSubscriptionAttributes))
+	query.RegisterWithRequest(logger, registry, "ListSubscriptionsByTopic", region.Method(s.regions, (*SNS).ListSubscriptionsByTopic))
+	query.RegisterWithRequest(logger, registry, "ListTopics", region.Method(s.regions, (*SNS).ListTopics))
+	query.RegisterWithRequest(logger, registry, "Publish", region.Method(s.regions, (*SNS).Publish))
+	query.RegisterWithRequest(logger, registry, "PublishBatch", region.Method(s.regions, (*SNS).PublishBatch))
+	query.RegisterWithRequest(logger, registry, "SetSubscriptionAttributes", region.Method(s.regions, (*SNS).SetSubscriptionAttributes))
+	query.RegisterWithRequest(logger, registry, "Subscribe", region.Method(s.regions, (*SNS).Subscribe))
+	query.RegisterWithRequest(logger, registry, "Unsubscribe", forARN(s, (*SNS).Unsubscribe, func(input UnsubscribeInput) string {
+		return input.SubscriptionArn
+	}))
+	capabilityRegistry.Add("sns", registry.Operations()...)
+	queryHandler := query.NewHandler(registry)
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		return s.serveSigningCert(w, r) || queryHandler(w, r)
+	}
+}
+
+// forARN returns a handler which calls method in the request's region or, if the request doesn't
+// say, in the region of the ARN arnOf returns. Notifications' SubscribeURL and UnsubscribeURL are
+// visited without signing, and AWS's are on the hosts of their topics' regions.
+func forARN[Input any, Output any](
+	s *SNS,
+	method func(s *SNS, input Input) (*Output, *awserrors.Error),
+	arnOf func(input Input) string,
+) func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+	inRequestRegion := region.Method(s.regions, method)
+	return func(r *http.Request, input Input) (*Output, *awserrors.Error) {
+		if region.FromRequest(r) != "" {
+			return inRequestRegion(r, input)
+		}
+		instance, err := s.regions.ForARN(arnOf(input))
+		if err != nil {
+			return nil, InvalidParameter("Invalid parameter: " + err.Error())
+		}
+		return method(instance, input)
+	}
+}
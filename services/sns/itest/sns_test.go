@@ -0,0 +1,1094 @@
+package itest
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqsTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/arn"
+	"aws-in-a-box/region"
+	"aws-in-a-box/server"
+	snsImpl "aws-in-a-box/services/sns"
+	sqsImpl "aws-in-a-box/services/sqs"
+)
+
+func makeClientServerPair(lambda snsImpl.LambdaInvoker) (*sns.Client, *sqs.Client, *http.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	addr := listener.Addr().String()
+	arnGenerator := arn.Generator{
+		AwsAccountId: "123456789012",
+		Region:       "us-east-1",
+	}
+
+	sqsService := sqsImpl.New(sqsImpl.Options{
+		ArnGenerator: arnGenerator,
+		Addr:         addr,
+	})
+	impl := snsImpl.New(snsImpl.Options{
+		ArnGenerator: arnGenerator,
+		Addr:         addr,
+		SQS:          sqsService,
+		Lambda:       lambda,
+	})
+
+	adminRegistry := make(admin.Registry)
+	impl.RegisterAdminHandlers(adminRegistry)
+
+	srv := server.NewWithHandlerChain(
+		admin.NewHandler(adminRegistry),
+		snsImpl.NewHandler(slog.Default(), impl, nil),
+		sqsImpl.NewHandler(slog.Default(), sqsService, nil),
+	)
+	srv.Addr = addr
+	go srv.Serve(listener)
+
+	client := sns.New(sns.Options{
+		EndpointResolver: sns.EndpointResolverFromURL("http://" + addr),
+		Retryer:          aws.NopRetryer{},
+	})
+	sqsClient := sqs.New(sqs.Options{
+		EndpointResolver: sqs.EndpointResolverFromURL("http://" + addr),
+		Retryer:          aws.NopRetryer{},
+	})
+
+	return client, sqsClient, srv
+}
+
+func TestTopics(t *testing.T) {
+	ctx := context.Background()
+	client, _, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := *createOutput.TopicArn
+	if topicArn != "arn:aws:sns:us-east-1:123456789012:topic" {
+		t.Fatalf("unexpected topic ARN %s", topicArn)
+	}
+
+	// Creating the same topic again returns the same ARN.
+	createOutput, err = client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *createOutput.TopicArn != topicArn {
+		t.Fatalf("expected %s, got %s", topicArn, *createOutput.TopicArn)
+	}
+
+	_, err = client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("bad topic"),
+	})
+	var invalidParameter *types.InvalidParameterException
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	listOutput, err := client.ListTopics(ctx, &sns.ListTopicsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listOutput.Topics) != 1 || *listOutput.Topics[0].TopicArn != topicArn {
+		t.Fatalf("unexpected topics %v", listOutput.Topics)
+	}
+
+	_, err = client.DeleteTopic(ctx, &sns.DeleteTopicInput{
+		TopicArn: aws.String(topicArn),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listOutput, err = client.ListTopics(ctx, &sns.ListTopicsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listOutput.Topics) != 0 {
+		t.Fatalf("unexpected topics %v", listOutput.Topics)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	client, _, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String("not-an-arn"),
+	})
+	var invalidParameter *types.InvalidParameterException
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: aws.String("arn:aws:sns:us-east-1:123456789012:missing"),
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String("arn:aws:sqs:us-east-1:123456789012:queue"),
+	})
+	var notFound *types.NotFoundException
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundException, got %v", err)
+	}
+
+	subscribeOutput, err := client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String("arn:aws:sqs:us-east-1:123456789012:queue"),
+		Attributes: map[string]string{
+			"RawMessageDelivery": "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptionArn := *subscribeOutput.SubscriptionArn
+	if !strings.HasPrefix(subscriptionArn, *topicArn+":") {
+		t.Fatalf("unexpected subscription ARN %s", subscriptionArn)
+	}
+
+	listOutput, err := client.ListSubscriptionsByTopic(ctx, &sns.ListSubscriptionsByTopicInput{
+		TopicArn: topicArn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listOutput.Subscriptions) != 1 {
+		t.Fatalf("unexpected subscriptions %v", listOutput.Subscriptions)
+	}
+	subscription := listOutput.Subscriptions[0]
+	if *subscription.SubscriptionArn != subscriptionArn || *subscription.Protocol != "sqs" || *subscription.Owner != "123456789012" {
+		t.Fatalf("unexpected subscription %+v", subscription)
+	}
+
+	_, err = client.Unsubscribe(ctx, &sns.UnsubscribeInput{
+		SubscriptionArn: aws.String(subscriptionArn),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listOutput, err = client.ListSubscriptionsByTopic(ctx, &sns.ListSubscriptionsByTopicInput{
+		TopicArn: topicArn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listOutput.Subscriptions) != 0 {
+		t.Fatalf("unexpected subscriptions %v", listOutput.Subscriptions)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	client, _, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	publishOutput, err := client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("hello"),
+		Subject:  aws.String("greeting"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"color": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String("blue"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publishOutput.MessageId == nil {
+		t.Fatal("expected a message id")
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn:         topicArn,
+		Message:          aws.String(`{"sqs": "hello"}`),
+		MessageStructure: aws.String("json"),
+	})
+	var invalidParameter *types.InvalidParameterException
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("hello"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"color": {
+				DataType:    aws.String("Colour"),
+				StringValue: aws.String("blue"),
+			},
+		},
+	})
+	var invalidParameterValue *types.InvalidParameterValueException
+	if !errors.As(err, &invalidParameterValue) {
+		t.Fatalf("expected InvalidParameterValueException, got %v", err)
+	}
+
+	batchOutput, err := client.PublishBatch(ctx, &sns.PublishBatchInput{
+		TopicArn: topicArn,
+		PublishBatchRequestEntries: []types.PublishBatchRequestEntry{
+			{
+				Id:      aws.String("good"),
+				Message: aws.String("hello"),
+			},
+			{
+				Id:      aws.String("bad"),
+				Message: aws.String(""),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batchOutput.Successful) != 1 || *batchOutput.Successful[0].Id != "good" {
+		t.Fatalf("unexpected successful entries %v", batchOutput.Successful)
+	}
+	if len(batchOutput.Failed) != 1 || *batchOutput.Failed[0].Id != "bad" {
+		t.Fatalf("unexpected failed entries %v", batchOutput.Failed)
+	}
+
+	_, err = client.PublishBatch(ctx, &sns.PublishBatchInput{
+		TopicArn: topicArn,
+		PublishBatchRequestEntries: []types.PublishBatchRequestEntry{
+			{Id: aws.String("same"), Message: aws.String("one")},
+			{Id: aws.String("same"), Message: aws.String("two")},
+		},
+	})
+	var notDistinct *types.BatchEntryIdsNotDistinctException
+	if !errors.As(err, &notDistinct) {
+		t.Fatalf("expected BatchEntryIdsNotDistinctException, got %v", err)
+	}
+}
+
+func TestSQSDelivery(t *testing.T) {
+	ctx := context.Background()
+	client, sqsClient, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	subscribeQueue := func(name string, raw bool) string {
+		createQueueOutput, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
+			QueueName: aws.String(name),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		attributesOutput, err := sqsClient.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       createQueueOutput.QueueUrl,
+			AttributeNames: []sqsTypes.QueueAttributeName{sqsTypes.QueueAttributeNameQueueArn},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+			TopicArn: topicArn,
+			Protocol: aws.String("sqs"),
+			Endpoint: aws.String(attributesOutput.Attributes["QueueArn"]),
+			Attributes: map[string]string{
+				"RawMessageDelivery": strconv.FormatBool(raw),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *createQueueOutput.QueueUrl
+	}
+	envelopeQueueUrl := subscribeQueue("envelope", false)
+	rawQueueUrl := subscribeQueue("raw", true)
+
+	publishOutput, err := client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("hello"),
+		Subject:  aws.String("greeting"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"color": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String("blue"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(queueUrl string) sqsTypes.Message {
+		receiveOutput, err := sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(queueUrl),
+			MessageAttributeNames: []string{"All"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(receiveOutput.Messages) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(receiveOutput.Messages))
+		}
+		return receiveOutput.Messages[0]
+	}
+
+	var envelope struct {
+		Type              string
+		MessageId         string
+		TopicArn          string
+		Subject           string
+		Message           string
+		MessageAttributes map[string]struct {
+			Type  string
+			Value string
+		}
+	}
+	err = json.Unmarshal([]byte(*receive(envelopeQueueUrl).Body), &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Type != "Notification" ||
+		envelope.MessageId != *publishOutput.MessageId ||
+		envelope.TopicArn != *topicArn ||
+		envelope.Subject != "greeting" ||
+		envelope.Message != "hello" ||
+		envelope.MessageAttributes["color"].Value != "blue" {
+		t.Fatalf("unexpected envelope %+v", envelope)
+	}
+
+	rawMessage := receive(rawQueueUrl)
+	if *rawMessage.Body != "hello" {
+		t.Fatalf("unexpected raw body %s", *rawMessage.Body)
+	}
+	if *rawMessage.MessageAttributes["color"].StringValue != "blue" {
+		t.Fatalf("unexpected raw attributes %v", rawMessage.MessageAttributes)
+	}
+
+	// Messages with a json structure deliver the sqs entry.
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn:         topicArn,
+		Message:          aws.String(`{"default": "default message", "sqs": "sqs message"}`),
+		MessageStructure: aws.String("json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := *receive(rawQueueUrl).Body; body != "sqs message" {
+		t.Fatalf("unexpected raw body %s", body)
+	}
+}
+
+type webhookMessage struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Subject          string
+	Message          string
+	SubscribeURL     string
+	Timestamp        string
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+}
+
+// verifySignature checks the message against the certificate from its SigningCertURL.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func verifySignature(t *testing.T, message webhookMessage) {
+	resp, err := http.Get(message.SigningCertURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	certPEM, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatalf("invalid certificate %s", certPEM)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var toSign strings.Builder
+	add := func(name string, value string) {
+		toSign.WriteString(name + "\n" + value + "\n")
+	}
+	add("Message", message.Message)
+	add("MessageId", message.MessageId)
+	if message.Type == "Notification" {
+		if message.Subject != "" {
+			add("Subject", message.Subject)
+		}
+		add("Timestamp", message.Timestamp)
+	} else {
+		add("SubscribeURL", message.SubscribeURL)
+		add("Timestamp", message.Timestamp)
+		add("Token", message.Token)
+	}
+	add("TopicArn", message.TopicArn)
+	add("Type", message.Type)
+
+	signature, err := base64.StdEncoding.DecodeString(message.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.SignatureVersion != "1" {
+		t.Fatalf("unexpected signature version %s", message.SignatureVersion)
+	}
+	err = cert.CheckSignature(x509.SHA1WithRSA, []byte(toSign.String()), signature)
+	if err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+}
+
+func TestHTTPDelivery(t *testing.T) {
+	ctx := context.Background()
+	client, _, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	// The endpoint fails the first notification, so that it is retried.
+	var failedNotification atomic.Bool
+	received := make(chan webhookMessage, 10)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message webhookMessage
+		err := json.NewDecoder(r.Body).Decode(&message)
+		if err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get("x-amz-sns-message-type") != message.Type {
+			t.Errorf("unexpected message type header %s", r.Header.Get("x-amz-sns-message-type"))
+		}
+		if message.Type == "Notification" && !failedNotification.Swap(true) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received <- message
+	}))
+	defer endpoint.Close()
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	subscribeOutput, err := client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("http"),
+		Endpoint: aws.String(endpoint.URL),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *subscribeOutput.SubscriptionArn != "pending confirmation" {
+		t.Fatalf("unexpected subscription ARN %s", *subscribeOutput.SubscriptionArn)
+	}
+
+	confirmation := <-received
+	if confirmation.Type != "SubscriptionConfirmation" || confirmation.Token == "" {
+		t.Fatalf("unexpected confirmation %+v", confirmation)
+	}
+	verifySignature(t, confirmation)
+
+	// Messages aren't delivered until the subscription is confirmed.
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("unconfirmed"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(confirmation.SubscribeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status confirming subscription %s", resp.Status)
+	}
+
+	listOutput, err := client.ListSubscriptionsByTopic(ctx, &sns.ListSubscriptionsByTopicInput{
+		TopicArn: topicArn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(*listOutput.Subscriptions[0].SubscriptionArn, *topicArn+":") {
+		t.Fatalf("expected a confirmed subscription, got %s", *listOutput.Subscriptions[0].SubscriptionArn)
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("hello <world>"),
+		Subject:  aws.String("greeting"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case notification := <-received:
+		if notification.Type != "Notification" || notification.Message != "hello <world>" {
+			t.Fatalf("unexpected notification %+v", notification)
+		}
+		verifySignature(t, notification)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+}
+
+func TestFilterPolicy(t *testing.T) {
+	ctx := context.Background()
+	client, sqsClient, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	createQueueOutput, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribeOutput, err := client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String("arn:aws:sqs:us-east-1:123456789012:queue"),
+		Attributes: map[string]string{
+			"RawMessageDelivery": "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.SetSubscriptionAttributes(ctx, &sns.SetSubscriptionAttributesInput{
+		SubscriptionArn: subscribeOutput.SubscriptionArn,
+		AttributeName:   aws.String("FilterPolicy"),
+		AttributeValue:  aws.String(`{"price": [{"numeric": [">", 100]}]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.SetSubscriptionAttributes(ctx, &sns.SetSubscriptionAttributesInput{
+		SubscriptionArn: subscribeOutput.SubscriptionArn,
+		AttributeName:   aws.String("FilterPolicy"),
+		AttributeValue:  aws.String(`{"price": [{"numeric": [">", "cheap"]}]}`),
+	})
+	var invalidParameter *types.InvalidParameterException
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	attributesOutput, err := client.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{
+		SubscriptionArn: subscribeOutput.SubscriptionArn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributesOutput.Attributes["FilterPolicy"] != `{"price": [{"numeric": [">", 100]}]}` {
+		t.Fatalf("unexpected filter policy %s", attributesOutput.Attributes["FilterPolicy"])
+	}
+
+	for _, price := range []string{"50", "150"} {
+		_, err = client.Publish(ctx, &sns.PublishInput{
+			TopicArn: topicArn,
+			Message:  aws.String("price " + price),
+			MessageAttributes: map[string]types.MessageAttributeValue{
+				"price": {
+					DataType:    aws.String("Number"),
+					StringValue: aws.String(price),
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receiveOutput, err := sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            createQueueOutput.QueueUrl,
+		MaxNumberOfMessages: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receiveOutput.Messages) != 1 || *receiveOutput.Messages[0].Body != "price 150" {
+		t.Fatalf("unexpected messages %v", receiveOutput.Messages)
+	}
+}
+
+func TestFifoTopic(t *testing.T) {
+	ctx := context.Background()
+	client, sqsClient, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	_, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+		Attributes: map[string]string{
+			"FifoTopic": "true",
+		},
+	})
+	var invalidParameter *types.InvalidParameterException
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic.fifo"),
+		Attributes: map[string]string{
+			"FifoTopic": "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+	standardOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("standard"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createQueueOutput, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue.fifo"),
+		Attributes: map[string]string{
+			"FifoQueue": "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queueArn := aws.String("arn:aws:sqs:us-east-1:123456789012:queue.fifo")
+
+	// FIFO queues can only subscribe to FIFO topics, which can only deliver to SQS.
+	_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: standardOutput.TopicArn,
+		Protocol: aws.String("sqs"),
+		Endpoint: queueArn,
+	})
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+	_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("https"),
+		Endpoint: aws.String("https://example.com"),
+	})
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+	_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("sqs"),
+		Endpoint: queueArn,
+		Attributes: map[string]string{
+			"RawMessageDelivery": "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn:               topicArn,
+		Message:                aws.String("no group"),
+		MessageDeduplicationId: aws.String("dedup"),
+	})
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	var outputs []*sns.PublishOutput
+	for _, dedupId := range []string{"first", "second", "first"} {
+		output, err := client.Publish(ctx, &sns.PublishInput{
+			TopicArn:               topicArn,
+			Message:                aws.String(dedupId),
+			MessageGroupId:         aws.String("group"),
+			MessageDeduplicationId: aws.String(dedupId),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, output)
+	}
+	if *outputs[0].SequenceNumber >= *outputs[1].SequenceNumber {
+		t.Fatalf("sequence numbers should increase, got %s and %s", *outputs[0].SequenceNumber, *outputs[1].SequenceNumber)
+	}
+	if *outputs[2].MessageId != *outputs[0].MessageId {
+		t.Fatal("duplicate should have the original's message id")
+	}
+
+	receiveOutput, err := sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            createQueueOutput.QueueUrl,
+		MaxNumberOfMessages: 10,
+		AttributeNames:      []sqsTypes.QueueAttributeName{"MessageGroupId"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receiveOutput.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(receiveOutput.Messages))
+	}
+	for i, expected := range []string{"first", "second"} {
+		message := receiveOutput.Messages[i]
+		if *message.Body != expected || message.Attributes["MessageGroupId"] != "group" {
+			t.Fatalf("unexpected message %s in group %s", *message.Body, message.Attributes["MessageGroupId"])
+		}
+	}
+}
+
+type invocation struct {
+	functionArn string
+	payload     []byte
+}
+
+// fakeLambda records invocations instead of running functions.
+type fakeLambda chan invocation
+
+func (f fakeLambda) InvokeAsync(functionArn string, payload []byte) error {
+	f <- invocation{functionArn, payload}
+	return nil
+}
+
+func TestLambdaDelivery(t *testing.T) {
+	ctx := context.Background()
+	lambda := make(fakeLambda, 1)
+	client, _, srv := makeClientServerPair(lambda)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	functionArn := "arn:aws:lambda:us-east-1:123456789012:function:handler"
+	subscribeOutput, err := client.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("lambda"),
+		Endpoint: aws.String(functionArn),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publishOutput, err := client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("hello"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"color": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String("blue"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var invoked invocation
+	select {
+	case invoked = <-lambda:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for invocation")
+	}
+	if invoked.functionArn != functionArn {
+		t.Fatalf("unexpected function %s", invoked.functionArn)
+	}
+
+	var event struct {
+		Records []struct {
+			EventSource          string
+			EventSubscriptionArn string
+			Sns                  struct {
+				Type              string
+				MessageId         string
+				TopicArn          string
+				Subject           *string
+				Message           string
+				SigningCertUrl    string
+				MessageAttributes map[string]struct {
+					Type  string
+					Value string
+				}
+			}
+		}
+	}
+	err = json.Unmarshal(invoked.payload, &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(event.Records) != 1 {
+		t.Fatalf("unexpected records %s", invoked.payload)
+	}
+	record := event.Records[0]
+	if record.EventSource != "aws:sns" ||
+		record.EventSubscriptionArn != *subscribeOutput.SubscriptionArn ||
+		record.Sns.Type != "Notification" ||
+		record.Sns.MessageId != *publishOutput.MessageId ||
+		record.Sns.TopicArn != *topicArn ||
+		record.Sns.Subject != nil ||
+		record.Sns.Message != "hello" ||
+		record.Sns.SigningCertUrl == "" ||
+		record.Sns.MessageAttributes["color"].Value != "blue" {
+		t.Fatalf("unexpected event %s", invoked.payload)
+	}
+}
+
+func TestCapturedMessages(t *testing.T) {
+	ctx := context.Background()
+	client, _, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+
+	createOutput, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("topic"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+
+	for protocol, endpoint := range map[string]string{
+		"sms":   "+15555550100",
+		"email": "someone@example.com",
+	} {
+		_, err = client.Subscribe(ctx, &sns.SubscribeInput{
+			TopicArn: topicArn,
+			Protocol: aws.String(protocol),
+			Endpoint: aws.String(endpoint),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: topicArn,
+		Message:  aws.String("to everyone"),
+		Subject:  aws.String("news"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		PhoneNumber: aws.String("+15555550199"),
+		Message:     aws.String("your code is 1234"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		PhoneNumber: aws.String("not a number"),
+		Message:     aws.String("hello"),
+	})
+	var invalidParameter *types.InvalidParameterException
+	if !errors.As(err, &invalidParameter) {
+		t.Fatalf("expected InvalidParameterException, got %v", err)
+	}
+
+	listCaptured := func(query string) []snsImpl.CapturedMessage {
+		resp, err := http.Get("http://" + srv.Addr + admin.PathPrefix + "sns/messages" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var output struct {
+			Messages []snsImpl.CapturedMessage
+		}
+		err = json.NewDecoder(resp.Body).Decode(&output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output.Messages
+	}
+
+	captured := listCaptured("")
+	if len(captured) != 3 {
+		t.Fatalf("expected 3 captured messages, got %+v", captured)
+	}
+	sms := listCaptured("?protocol=sms")
+	if len(sms) != 2 || sms[0].Destination != "+15555550100" || sms[1].Message != "your code is 1234" {
+		t.Fatalf("unexpected SMS messages %+v", sms)
+	}
+	email := listCaptured("?destination=someone@example.com")
+	if len(email) != 1 || email[0].Subject != "news" || email[0].Message != "to everyone" {
+		t.Fatalf("unexpected email messages %+v", email)
+	}
+
+	request, err := http.NewRequest(http.MethodDelete, "http://"+srv.Addr+admin.PathPrefix+"sns/messages", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if captured := listCaptured(""); len(captured) != 0 {
+		t.Fatalf("expected no captured messages, got %+v", captured)
+	}
+}
+
+// regionClient sends requests for the region, as the emulator's unsigned requests can't say.
+type regionClient string
+
+func (c regionClient) Do(r *http.Request) (*http.Response, error) {
+	r.Header.Set(region.Header, string(c))
+	return http.DefaultClient.Do(r)
+}
+
+func TestRegions(t *testing.T) {
+	ctx := context.Background()
+	client, _, srv := makeClientServerPair(nil)
+	defer srv.Shutdown(ctx)
+	europe := sns.New(sns.Options{
+		EndpointResolver: sns.EndpointResolverFromURL("http://" + srv.Addr),
+		Retryer:          aws.NopRetryer{},
+		HTTPClient:       regionClient("eu-west-1"),
+	})
+	europeSQS := sqs.New(sqs.Options{
+		EndpointResolver: sqs.EndpointResolverFromURL("http://" + srv.Addr),
+		Retryer:          aws.NopRetryer{},
+		HTTPClient:       regionClient("eu-west-1"),
+	})
+
+	createOutput, err := europe.CreateTopic(ctx, &sns.CreateTopicInput{Name: aws.String("topic")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicArn := createOutput.TopicArn
+	if *topicArn != "arn:aws:sns:eu-west-1:123456789012:topic" {
+		t.Fatal("Wrong topic ARN", *topicArn)
+	}
+	topics, err := client.ListTopics(ctx, &sns.ListTopicsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics.Topics) != 0 {
+		t.Fatal("Expected eu-west-1's topic not to be in us-east-1", topics.Topics)
+	}
+
+	// The topic delivers to a queue in its region.
+	queue, err := europeSQS.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("queue")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = europe.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn:   topicArn,
+		Protocol:   aws.String("sqs"),
+		Endpoint:   aws.String("arn:aws:sqs:eu-west-1:123456789012:queue"),
+		Attributes: map[string]string{"RawMessageDelivery": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := europe.Publish(ctx, &sns.PublishInput{TopicArn: topicArn, Message: aws.String("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	received, err := europeSQS.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: queue.QueueUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Messages) != 1 || *received.Messages[0].Body != "hello" {
+		t.Fatal("Expected the message in eu-west-1's queue", received.Messages)
+	}
+
+	// SubscribeURLs are visited without saying the region, so they confirm the topic's
+	// subscription.
+	confirmations := make(chan webhookMessage, 1)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message webhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+		if message.Type == "SubscriptionConfirmation" {
+			confirmations <- message
+		}
+	}))
+	defer endpoint.Close()
+	_, err = europe.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topicArn,
+		Protocol: aws.String("http"),
+		Endpoint: aws.String(endpoint.URL),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmation := <-confirmations
+	verifySignature(t, confirmation)
+	resp, err := http.Get(confirmation.SubscribeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status confirming subscription %s", resp.Status)
+	}
+}
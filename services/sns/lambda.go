@@ -0,0 +1,83 @@
+package sns
+
+import (
+	"encoding/json"
+)
+
+// LambdaInvoker asynchronously invokes the Lambda functions subscribed to topics.
+type LambdaInvoker interface {
+	InvokeAsync(functionArn string, payload []byte) error
+}
+
+// lambdaEvent is the payload subscribed functions are invoked with.
+// https://docs.aws.amazon.com/lambda/latest/dg/with-sns.html
+type lambdaEvent struct {
+	Records []lambdaEventRecord
+}
+
+type lambdaEventRecord struct {
+	EventVersion         string
+	EventSubscriptionArn string
+	EventSource          string
+	Sns                  lambdaEventMessage
+}
+
+// lambdaEventMessage is the notification envelope, but with some fields named differently.
+type lambdaEventMessage struct {
+	SignatureVersion  string
+	Timestamp         string
+	Signature         string
+	SigningCertUrl    string
+	MessageId         string
+	Message           string
+	MessageAttributes map[string]notificationAttribute
+	Type              string
+	UnsubscribeUrl    string
+	TopicArn          string
+	// Null if the message has no subject.
+	Subject *string
+}
+
+func (s *SNS) lockedDeliverToLambda(topic *Topic, subscription *Subscription, message *Message) {
+	if s.lambda == nil {
+		s.logger.Warn("Cannot deliver to Lambda subscription because Lambda is not enabled", "subscription", subscription.ARN)
+		return
+	}
+
+	n := s.newNotification(topic, subscription, message)
+	event := lambdaEvent{
+		Records: []lambdaEventRecord{{
+			EventVersion:         "1.0",
+			EventSubscriptionArn: subscription.ARN,
+			EventSource:          "aws:sns",
+			Sns: lambdaEventMessage{
+				SignatureVersion:  n.SignatureVersion,
+				Timestamp:         n.Timestamp,
+				Signature:         n.Signature,
+				SigningCertUrl:    n.SigningCertURL,
+				MessageId:         n.MessageId,
+				Message:           n.Message,
+				MessageAttributes: n.MessageAttributes,
+				Type:              n.Type,
+				UnsubscribeUrl:    n.UnsubscribeURL,
+				TopicArn:          n.TopicArn,
+			},
+		}},
+	}
+	if n.Subject != "" {
+		event.Records[0].Sns.Subject = &n.Subject
+	}
+	if event.Records[0].Sns.MessageAttributes == nil {
+		event.Records[0].Sns.MessageAttributes = make(map[string]notificationAttribute)
+	}
+	payload, _ := json.Marshal(event)
+
+	functionArn := subscription.Endpoint
+	subscriptionArn := subscription.ARN
+	go func() {
+		if err := s.lambda.InvokeAsync(functionArn, payload); err != nil {
+			s.logger.Warn("Failed to deliver message to Lambda",
+				"subscription", subscriptionArn, "function", functionArn, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,46 @@
+package sns
+
+import (
+	"aws-in-a-box/arn"
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of the SMS and email messages captured for each topic. Messages
+// published directly to a phone number are counted under their protocol, sms.
+func (s *SNS) MemoryUsage() []memory.Resource {
+	return s.regions.MemoryUsage((*SNS).memoryUsage)
+}
+
+func (s *SNS) memoryUsage() []memory.Resource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resources := make(map[string]*memory.Resource)
+	for _, topic := range s.topicsByArn {
+		resources[topic.ARN] = &memory.Resource{Name: topic.Name}
+	}
+	for _, message := range s.capturedMessages {
+		key := message.TopicArn
+		if key == "" {
+			key = message.Protocol
+		}
+		resource, ok := resources[key]
+		if !ok {
+			// The topic has been deleted since, or the message was published to a phone number.
+			name := key
+			if parsed, err := arn.Parse(key); err == nil {
+				name = parsed.Resource
+			}
+			resource = &memory.Resource{Name: name}
+			resources[key] = resource
+		}
+		resource.Count++
+		resource.Bytes += int64(len(message.Subject) + len(message.Message))
+	}
+
+	var usage []memory.Resource
+	for _, resource := range resources {
+		usage = append(usage, *resource)
+	}
+	return usage
+}
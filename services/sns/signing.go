@@ -0,0 +1,125 @@
+package sns
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// signer signs notifications so that subscribers can verify them
+// against the certificate served at the signing certificate URL.
+type signer struct {
+	once    sync.Once
+	key     *rsa.PrivateKey
+	certPEM []byte
+	// The last part of the certificate URL.
+	certName string
+}
+
+// init generates the key and certificate on first use, since generating keys is slow.
+func (s *signer) init() {
+	s.once.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		now := time.Now()
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(now.UnixNano()),
+			Subject: pkix.Name{
+				CommonName: "sns.amazonaws.com",
+			},
+			NotBefore: now.Add(-time.Hour),
+			NotAfter:  now.AddDate(10, 0, 0),
+			KeyUsage:  x509.KeyUsageDigitalSignature,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			panic(err)
+		}
+		s.key = key
+		s.certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		s.certName = "SimpleNotificationService-" + uuid.Must(uuid.NewV4()).String() + ".pem"
+	})
+}
+
+// stringToSign builds the canonical string for the notification, which depends on its type.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func stringToSign(n *notification) string {
+	var b strings.Builder
+	add := func(name string, value string) {
+		b.WriteString(name)
+		b.WriteString("\n")
+		b.WriteString(value)
+		b.WriteString("\n")
+	}
+	add("Message", n.Message)
+	add("MessageId", n.MessageId)
+	if n.Type == "Notification" {
+		if n.Subject != "" {
+			add("Subject", n.Subject)
+		}
+	} else {
+		add("SubscribeURL", n.SubscribeURL)
+	}
+	add("Timestamp", n.Timestamp)
+	if n.Type != "Notification" {
+		add("Token", n.Token)
+	}
+	add("TopicArn", n.TopicArn)
+	add("Type", n.Type)
+	return b.String()
+}
+
+// sign sets the signature fields of the notification. Version 1 signs with SHA1, and version 2 with SHA256.
+func (s *SNS) sign(n *notification, signatureVersion string) {
+	s.signer.init()
+
+	hash := crypto.SHA1
+	var digest []byte
+	if signatureVersion == "2" {
+		hash = crypto.SHA256
+		sum := sha256.Sum256([]byte(stringToSign(n)))
+		digest = sum[:]
+	} else {
+		signatureVersion = "1"
+		sum := sha1.Sum([]byte(stringToSign(n)))
+		digest = sum[:]
+	}
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.signer.key, hash, digest)
+	if err != nil {
+		panic(err)
+	}
+
+	n.SignatureVersion = signatureVersion
+	n.Signature = base64.StdEncoding.EncodeToString(signature)
+	n.SigningCertURL = fmt.Sprintf("http://%s/%s", s.addr, s.signer.certName)
+}
+
+// serveSigningCert serves the certificate used to sign notifications.
+func (s *SNS) serveSigningCert(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, "/SimpleNotificationService-") {
+		return false
+	}
+	s.signer.init()
+	if r.URL.Path != "/"+s.signer.certName {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(s.signer.certPEM)
+	return true
+}
@@ -0,0 +1,775 @@
+package sns
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/sqs"
+)
+
+const (
+	maxMessageSize     = 262_144
+	maxSubjectLength   = 100
+	listPageSize       = 100
+	maxEntriesPerBatch = 10
+)
+
+var (
+	topicNameRegex    = regexp.MustCompile(`^([a-zA-Z0-9_-]{1,256}|[a-zA-Z0-9_-]{1,251}\.fifo)$`)
+	phoneNumberRegex  = regexp.MustCompile(`^\+?[0-9]{1,15}$`)
+	batchEntryIdRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,80}$`)
+
+	// https://docs.aws.amazon.com/sns/latest/api/API_SetTopicAttributes.html
+	topicAttributeNames = []string{
+		"ArchivePolicy",
+		"ContentBasedDeduplication",
+		"DeliveryPolicy",
+		"DisplayName",
+		"FifoTopic",
+		"KmsMasterKeyId",
+		"Policy",
+		"SignatureVersion",
+		"TracingConfig",
+	}
+	// https://docs.aws.amazon.com/sns/latest/api/API_SetSubscriptionAttributes.html
+	subscriptionAttributeNames = []string{
+		"DeliveryPolicy",
+		"FilterPolicy",
+		"FilterPolicyScope",
+		"RawMessageDelivery",
+		"RedrivePolicy",
+		"ReplayPolicy",
+		"SubscriptionRoleArn",
+	}
+)
+
+type Topic struct {
+	Name       string
+	ARN        string
+	Attributes map[string]string
+	Tags       map[string]string
+
+	// In the order they were created.
+	Subscriptions []*Subscription
+
+	// FIFO topics only
+	Fifo                      bool
+	ContentBasedDeduplication bool
+	lastSequenceNumber        int64
+	// In the order they were published.
+	deduplicationEntries     []*deduplicationEntry
+	deduplicationEntriesById map[string]*deduplicationEntry
+}
+
+type Subscription struct {
+	ARN        string
+	TopicArn   string
+	Protocol   string
+	Endpoint   string
+	Attributes map[string]string
+
+	// HTTP/S subscriptions must be confirmed with the token before messages are delivered.
+	PendingConfirmation bool
+	Token               string
+
+	// Nil if all messages are delivered.
+	filterPolicy *filterPolicy
+}
+
+// Message is a message published to a topic.
+type Message struct {
+	MessageId         string
+	TopicArn          string
+	Subject           string
+	Message           string
+	MessageStructure  string
+	MessageAttributes map[string]MessageAttributeValue
+	Timestamp         time.Time
+
+	// FIFO topics only
+	MessageGroupId         string
+	MessageDeduplicationId string
+	SequenceNumber         string
+}
+
+type SNS struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	addr         string
+	// Nil if SQS is not enabled.
+	sqs *sqs.SQS
+	// Nil if Lambda is not enabled.
+	lambda LambdaInvoker
+	// Overridden in tests.
+	clock func() time.Time
+
+	// Shared by all regions, so there's one certificate to serve.
+	signer     *signer
+	httpClient *http.Client
+
+	mu                 sync.Mutex
+	topicsByArn        map[string]*Topic
+	subscriptionsByArn map[string]*Subscription
+	// SMS and email messages, instead of sending them.
+	capturedMessages []CapturedMessage
+	// Shared by the instances of all regions, which each have their own topics.
+	regions *region.Regions[*SNS]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// Used to build unsubscribe URLs.
+	Addr string
+	// Subscribed SQS queues receive messages from this instance.
+	SQS *sqs.SQS
+	// Subscribed Lambda functions are invoked through this.
+	Lambda LambdaInvoker
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *SNS {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Queues and functions are found by their ARNs, which say their regions, so every region
+	// delivers through the same SQS and Lambda.
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*SNS, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.signer = s.signer
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *SNS {
+	return &SNS{
+		logger:             options.Logger,
+		arnGenerator:       options.ArnGenerator,
+		addr:               options.Addr,
+		sqs:                options.SQS,
+		lambda:             options.Lambda,
+		clock:              clock.Now,
+		signer:             &signer{},
+		httpClient:         &http.Client{Timeout: 15 * time.Second},
+		topicsByArn:        make(map[string]*Topic),
+		subscriptionsByArn: make(map[string]*Subscription),
+	}
+}
+
+// InRegion returns the topics of the region. It fails if the region isn't one of Options.Regions.
+func (s *SNS) InRegion(name string) (*SNS, error) {
+	return s.regions.Get(name)
+}
+
+// ForARN returns the topics of the region the topic ARN is in, for services which publish to
+// topics by ARN.
+func (s *SNS) ForARN(topicArn string) (*SNS, error) {
+	return s.regions.ForARN(topicArn)
+}
+
+func (s *SNS) lockedGetTopic(topicArn string) (*Topic, *awserrors.Error) {
+	if topicArn == "" {
+		return nil, InvalidParameter("Invalid parameter: TopicArn")
+	}
+	topic, ok := s.topicsByArn[topicArn]
+	if !ok {
+		return nil, NotFound("Topic does not exist")
+	}
+	return topic, nil
+}
+
+func validateAttributeNames(attributes map[string]string, validNames []string) *awserrors.Error {
+	for name := range attributes {
+		if !slices.Contains(validNames, name) {
+			return InvalidParameter("Invalid parameter: AttributeName")
+		}
+	}
+	return nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_CreateTopic.html
+func (s *SNS) CreateTopic(input CreateTopicInput) (*CreateTopicOutput, *awserrors.Error) {
+	if !topicNameRegex.MatchString(input.Name) {
+		return nil, InvalidParameter(
+			"Invalid parameter: Topic Name must be made up of only uppercase and lowercase ASCII letters, numbers, underscores, and hyphens, and must be between 1 and 256 characters long.")
+	}
+	awserr := validateAttributeNames(input.Attributes, topicAttributeNames)
+	if awserr != nil {
+		return nil, awserr
+	}
+	fifo, contentBasedDeduplication, awserr := parseFifoAttributes(input.Name, input.Attributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range input.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	attributes := input.Attributes
+	if attributes == nil {
+		attributes = make(map[string]string)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topicArn := s.arnGenerator.GenerateWithoutType("sns", input.Name)
+	// Creating a topic which already exists returns it, as long as the attributes match.
+	if topic, ok := s.topicsByArn[topicArn]; ok {
+		for name, value := range attributes {
+			if topic.Attributes[name] != value {
+				return nil, InvalidParameter("Invalid parameter: Attributes Reason: Topic already exists with different attributes")
+			}
+		}
+		return &CreateTopicOutput{
+			TopicArn: topicArn,
+		}, nil
+	}
+
+	s.topicsByArn[topicArn] = &Topic{
+		Name:       input.Name,
+		ARN:        topicArn,
+		Attributes: attributes,
+		Tags:       tags,
+
+		Fifo:                      fifo,
+		ContentBasedDeduplication: contentBasedDeduplication,
+		deduplicationEntriesById:  make(map[string]*deduplicationEntry),
+	}
+
+	return &CreateTopicOutput{
+		TopicArn: topicArn,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_DeleteTopic.html
+func (s *SNS) DeleteTopic(input DeleteTopicInput) (*DeleteTopicOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Deleting a topic which doesn't exist succeeds.
+	topic, ok := s.topicsByArn[input.TopicArn]
+	if !ok {
+		return &DeleteTopicOutput{}, nil
+	}
+
+	for _, subscription := range topic.Subscriptions {
+		delete(s.subscriptionsByArn, subscription.ARN)
+	}
+	delete(s.topicsByArn, topic.ARN)
+
+	return &DeleteTopicOutput{}, nil
+}
+
+// paginate returns the page of items starting at the token, and the token for the next page.
+func paginate[T any](items []T, nextToken string) ([]T, string, *awserrors.Error) {
+	_, start, awserr := pagination.Parse(listPageSize, listPageSize, listPageSize, nextToken,
+		nil,
+		InvalidParameter("Invalid parameter: NextToken"))
+	if awserr != nil {
+		return nil, "", awserr
+	}
+	page, next := pagination.Page(items, listPageSize, start)
+	return page, next, nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_ListTopics.html
+func (s *SNS) ListTopics(input ListTopicsInput) (*ListTopicsOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var topicArns []string
+	for topicArn := range s.topicsByArn {
+		topicArns = append(topicArns, topicArn)
+	}
+	slices.Sort(topicArns)
+
+	page, nextToken, awserr := paginate(topicArns, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	output := &ListTopicsOutput{
+		NextToken: nextToken,
+	}
+	for _, topicArn := range page {
+		output.Topics = append(output.Topics, APITopic{
+			TopicArn: topicArn,
+		})
+	}
+	return output, nil
+}
+
+// validateEndpoint checks the endpoint is valid for the subscription protocol.
+// See https://docs.aws.amazon.com/sns/latest/api/API_Subscribe.html#API_Subscribe_RequestParameters
+func validateEndpoint(protocol string, endpoint string) *awserrors.Error {
+	var valid bool
+	switch protocol {
+	case "http":
+		valid = strings.HasPrefix(endpoint, "http://")
+	case "https":
+		valid = strings.HasPrefix(endpoint, "https://")
+	case "email", "email-json":
+		valid = strings.Contains(endpoint, "@")
+	case "sms":
+		valid = phoneNumberRegex.MatchString(endpoint)
+	case "sqs":
+		valid = strings.HasPrefix(endpoint, "arn:aws:sqs:")
+	case "lambda":
+		valid = strings.HasPrefix(endpoint, "arn:aws:lambda:")
+	case "firehose":
+		valid = strings.HasPrefix(endpoint, "arn:aws:firehose:")
+	case "application":
+		valid = strings.HasPrefix(endpoint, "arn:aws:sns:")
+	default:
+		return InvalidParameter("Invalid parameter: Protocol")
+	}
+	if !valid {
+		return InvalidParameter("Invalid parameter: Endpoint")
+	}
+	return nil
+}
+
+// validateSubscriptionAttributes checks the attributes, and returns the filter policy they set, if any.
+func validateSubscriptionAttributes(attributes map[string]string) (*filterPolicy, *awserrors.Error) {
+	awserr := validateAttributeNames(attributes, subscriptionAttributeNames)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if value, ok := attributes["RawMessageDelivery"]; ok {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return nil, InvalidParameter("Invalid parameter: Attributes Reason: RawMessageDelivery: Invalid value [" + value + "]. Must be true or false.")
+		}
+	}
+	return parseFilterPolicy(attributes)
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_Subscribe.html
+func (s *SNS) Subscribe(input SubscribeInput) (*SubscribeOutput, *awserrors.Error) {
+	awserr := validateEndpoint(input.Protocol, input.Endpoint)
+	if awserr != nil {
+		return nil, awserr
+	}
+	filterPolicy, awserr := validateSubscriptionAttributes(input.Attributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+	attributes := input.Attributes
+	if attributes == nil {
+		attributes = make(map[string]string)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, awserr := s.lockedGetTopic(input.TopicArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	awserr = topic.validateSubscriptionPairing(input.Protocol, input.Endpoint)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Subscribing the same endpoint again returns the existing subscription.
+	for _, subscription := range topic.Subscriptions {
+		if subscription.Protocol == input.Protocol && subscription.Endpoint == input.Endpoint {
+			if !maps.Equal(subscription.Attributes, attributes) {
+				return nil, InvalidParameter("Invalid parameter: Attributes Reason: Subscription already exists with different attributes")
+			}
+			if subscription.PendingConfirmation {
+				s.lockedSendConfirmation(topic, subscription)
+			}
+			return s.subscribeOutput(subscription, input.ReturnSubscriptionArn), nil
+		}
+	}
+
+	subscription := &Subscription{
+		ARN:        s.arnGenerator.GenerateWithoutType("sns", topic.Name+":"+uuid.Must(uuid.NewV4()).String()),
+		TopicArn:   topic.ARN,
+		Protocol:   input.Protocol,
+		Endpoint:   input.Endpoint,
+		Attributes: attributes,
+
+		filterPolicy: filterPolicy,
+	}
+	topic.Subscriptions = append(topic.Subscriptions, subscription)
+	s.subscriptionsByArn[subscription.ARN] = subscription
+
+	if subscription.Protocol == "http" || subscription.Protocol == "https" {
+		subscription.PendingConfirmation = true
+		subscription.Token = newConfirmationToken()
+		s.lockedSendConfirmation(topic, subscription)
+	}
+
+	return s.subscribeOutput(subscription, input.ReturnSubscriptionArn), nil
+}
+
+func (s *SNS) subscribeOutput(subscription *Subscription, returnSubscriptionArn bool) *SubscribeOutput {
+	if subscription.PendingConfirmation && !returnSubscriptionArn {
+		return &SubscribeOutput{
+			SubscriptionArn: "pending confirmation",
+		}
+	}
+	return &SubscribeOutput{
+		SubscriptionArn: subscription.ARN,
+	}
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_ConfirmSubscription.html
+func (s *SNS) ConfirmSubscription(input ConfirmSubscriptionInput) (*ConfirmSubscriptionOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, awserr := s.lockedGetTopic(input.TopicArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	for _, subscription := range topic.Subscriptions {
+		if subscription.Token != "" && subscription.Token == input.Token {
+			subscription.PendingConfirmation = false
+			return &ConfirmSubscriptionOutput{
+				SubscriptionArn: subscription.ARN,
+			}, nil
+		}
+	}
+	return nil, InvalidParameter("Invalid token")
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_Unsubscribe.html
+func (s *SNS) Unsubscribe(input UnsubscribeInput) (*UnsubscribeOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := s.subscriptionsByArn[input.SubscriptionArn]
+	if !ok {
+		return nil, NotFound("Subscription does not exist")
+	}
+
+	delete(s.subscriptionsByArn, subscription.ARN)
+	if topic, ok := s.topicsByArn[subscription.TopicArn]; ok {
+		topic.Subscriptions = slices.DeleteFunc(topic.Subscriptions, func(other *Subscription) bool {
+			return other == subscription
+		})
+	}
+
+	return &UnsubscribeOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_GetSubscriptionAttributes.html
+func (s *SNS) GetSubscriptionAttributes(input GetSubscriptionAttributesInput) (*GetSubscriptionAttributesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := s.subscriptionsByArn[input.SubscriptionArn]
+	if !ok {
+		return nil, NotFound("Subscription does not exist")
+	}
+
+	attributes := map[string]string{
+		"ConfirmationWasAuthenticated": "false",
+		"Endpoint":                     subscription.Endpoint,
+		"Owner":                        s.arnGenerator.AwsAccountId,
+		"PendingConfirmation":          strconv.FormatBool(subscription.PendingConfirmation),
+		"Protocol":                     subscription.Protocol,
+		"RawMessageDelivery":           "false",
+		"SubscriptionArn":              subscription.ARN,
+		"TopicArn":                     subscription.TopicArn,
+	}
+	for name, value := range subscription.Attributes {
+		attributes[name] = value
+	}
+	return &GetSubscriptionAttributesOutput{
+		Attributes: attributes,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_SetSubscriptionAttributes.html
+func (s *SNS) SetSubscriptionAttributes(input SetSubscriptionAttributesInput) (*SetSubscriptionAttributesOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := s.subscriptionsByArn[input.SubscriptionArn]
+	if !ok {
+		return nil, NotFound("Subscription does not exist")
+	}
+
+	attributes := maps.Clone(subscription.Attributes)
+	if input.AttributeValue == "" {
+		delete(attributes, input.AttributeName)
+	} else {
+		attributes[input.AttributeName] = input.AttributeValue
+	}
+	if _, ok := attributes["FilterPolicy"]; !ok {
+		// The scope only applies to a filter policy.
+		delete(attributes, "FilterPolicyScope")
+	}
+	filterPolicy, awserr := validateSubscriptionAttributes(attributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	subscription.Attributes = attributes
+	subscription.filterPolicy = filterPolicy
+	return &SetSubscriptionAttributesOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_ListSubscriptionsByTopic.html
+func (s *SNS) ListSubscriptionsByTopic(input ListSubscriptionsByTopicInput) (*ListSubscriptionsByTopicOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, awserr := s.lockedGetTopic(input.TopicArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	page, nextToken, awserr := paginate(topic.Subscriptions, input.NextToken)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	output := &ListSubscriptionsByTopicOutput{
+		NextToken: nextToken,
+	}
+	for _, subscription := range page {
+		subscriptionArn := subscription.ARN
+		if subscription.PendingConfirmation {
+			subscriptionArn = "PendingConfirmation"
+		}
+		output.Subscriptions = append(output.Subscriptions, APISubscription{
+			Endpoint:        subscription.Endpoint,
+			Owner:           s.arnGenerator.AwsAccountId,
+			Protocol:        subscription.Protocol,
+			SubscriptionArn: subscriptionArn,
+			TopicArn:        subscription.TopicArn,
+		})
+	}
+	return output, nil
+}
+
+// validateMessage checks a message being published.
+func validateMessage(
+	message string,
+	subject string,
+	messageStructure string,
+	messageAttributes map[string]MessageAttributeValue,
+) *awserrors.Error {
+	if message == "" {
+		return InvalidParameter("Invalid parameter: Empty message")
+	}
+
+	if subject != "" {
+		if utf8.RuneCountInString(subject) > maxSubjectLength {
+			return InvalidParameter("Invalid parameter: Subject")
+		}
+		for _, r := range subject {
+			if r < 0x20 || r > 0x7e {
+				return InvalidParameter("Invalid parameter: Subject")
+			}
+		}
+	}
+
+	switch messageStructure {
+	case "":
+	case "json":
+		// Each protocol can get a different message, with a default for the others.
+		var messages map[string]any
+		if err := json.Unmarshal([]byte(message), &messages); err != nil {
+			return InvalidParameter("Invalid parameter: Message Structure - JSON message body failed to parse")
+		}
+		if _, ok := messages["default"].(string); !ok {
+			return InvalidParameter("Invalid parameter: Message Structure - No default entry in JSON message body")
+		}
+	default:
+		return InvalidParameter("Invalid parameter: MessageStructure")
+	}
+
+	size := len(message)
+	for name, attribute := range messageAttributes {
+		switch baseType, _, _ := strings.Cut(attribute.DataType, "."); baseType {
+		case "String", "Number":
+			if attribute.StringValue == "" {
+				return InvalidParameterValue(fmt.Sprintf(
+					"The message attribute '%s' must contain non-empty message attribute value for message attribute type '%s'.",
+					name, attribute.DataType))
+			}
+		case "Binary":
+			if len(attribute.BinaryValue) == 0 {
+				return InvalidParameterValue(fmt.Sprintf(
+					"The message attribute '%s' must contain non-empty message attribute value for message attribute type 'Binary'.", name))
+			}
+		default:
+			return InvalidParameterValue(fmt.Sprintf(
+				"The message attribute '%s' has an invalid message attribute type, the set of supported type prefixes is Binary, Number, and String.", name))
+		}
+		size += len(name) + len(attribute.DataType) + len(attribute.StringValue) + len(attribute.BinaryValue)
+	}
+	if size > maxMessageSize {
+		return InvalidParameter("Invalid parameter: Message too long")
+	}
+	return nil
+}
+
+// lockedPublish publishes a validated message to the topic, and delivers it to the subscriptions.
+// It sets the message's ID and, for FIFO topics, its sequence number.
+func (s *SNS) lockedPublish(topic *Topic, message *Message) {
+	message.MessageId = uuid.Must(uuid.NewV4()).String()
+	message.TopicArn = topic.ARN
+	message.Timestamp = s.clock()
+	if topic.Fifo && !topic.lockedRecordFifoMessage(message) {
+		// Duplicates are accepted, but not delivered again.
+		return
+	}
+	s.lockedDeliver(topic, message)
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_Publish.html
+func (s *SNS) Publish(input PublishInput) (*PublishOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets := 0
+	for _, target := range []string{input.TopicArn, input.TargetArn, input.PhoneNumber} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return nil, InvalidParameter("Invalid parameter: TopicArn or TargetArn or PhoneNumber Reason: Only one of them may be specified")
+	}
+	if input.PhoneNumber != "" {
+		return s.lockedPublishToPhoneNumber(input)
+	}
+	topicArn := input.TopicArn
+	if topicArn == "" {
+		topicArn = input.TargetArn
+	}
+	topic, awserr := s.lockedGetTopic(topicArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	awserr = validateMessage(input.Message, input.Subject, input.MessageStructure, input.MessageAttributes)
+	if awserr != nil {
+		return nil, awserr
+	}
+	deduplicationId, awserr := topic.validateFifoParameters(input.MessageGroupId, input.MessageDeduplicationId, input.Message)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	message := &Message{
+		Subject:                input.Subject,
+		Message:                input.Message,
+		MessageStructure:       input.MessageStructure,
+		MessageAttributes:      input.MessageAttributes,
+		MessageGroupId:         input.MessageGroupId,
+		MessageDeduplicationId: deduplicationId,
+	}
+	s.lockedPublish(topic, message)
+	return &PublishOutput{
+		MessageId:      message.MessageId,
+		SequenceNumber: message.SequenceNumber,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/sns/latest/api/API_PublishBatch.html
+func (s *SNS) PublishBatch(input PublishBatchInput) (*PublishBatchOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, awserr := s.lockedGetTopic(input.TopicArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	entries := input.PublishBatchRequestEntries
+	if len(entries) == 0 {
+		return nil, EmptyBatchRequest("The batch request doesn't contain any entries")
+	}
+	if len(entries) > maxEntriesPerBatch {
+		return nil, TooManyEntriesInBatchRequest("The batch request contains more entries than permissible")
+	}
+	seen := make(map[string]struct{})
+	totalSize := 0
+	for _, entry := range entries {
+		if _, ok := seen[entry.Id]; ok {
+			return nil, BatchEntryIdsNotDistinct("Two or more batch entries in the request have the same Id")
+		}
+		seen[entry.Id] = struct{}{}
+		if !batchEntryIdRegex.MatchString(entry.Id) {
+			return nil, InvalidBatchEntryId("The Id of a batch entry in a batch request doesn't abide by the specification")
+		}
+		totalSize += len(entry.Message)
+	}
+	if totalSize > maxMessageSize {
+		return nil, BatchRequestTooLong("The length of all the messages put together is more than the limit")
+	}
+
+	output := &PublishBatchOutput{
+		Failed:     []BatchResultErrorEntry{},
+		Successful: []PublishBatchResultEntry{},
+	}
+	for _, entry := range entries {
+		var deduplicationId string
+		err := validateMessage(entry.Message, entry.Subject, entry.MessageStructure, entry.MessageAttributes)
+		if err == nil {
+			deduplicationId, err = topic.validateFifoParameters(entry.MessageGroupId, entry.MessageDeduplicationId, entry.Message)
+		}
+		if err != nil {
+			output.Failed = append(output.Failed, BatchResultErrorEntry{
+				Code:        err.Body.Type,
+				Id:          entry.Id,
+				Message:     err.Body.Message,
+				SenderFault: err.Code < 500,
+			})
+			continue
+		}
+
+		message := &Message{
+			Subject:                entry.Subject,
+			Message:                entry.Message,
+			MessageStructure:       entry.MessageStructure,
+			MessageAttributes:      entry.MessageAttributes,
+			MessageGroupId:         entry.MessageGroupId,
+			MessageDeduplicationId: deduplicationId,
+		}
+		s.lockedPublish(topic, message)
+		output.Successful = append(output.Successful, PublishBatchResultEntry{
+			Id:             entry.Id,
+			MessageId:      message.MessageId,
+			SequenceNumber: message.SequenceNumber,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,120 @@
+package sns
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type topicState struct {
+	Name                      string
+	ARN                       string
+	Attributes                map[string]string
+	Tags                      map[string]string
+	Fifo                      bool
+	ContentBasedDeduplication bool
+	LastSequenceNumber        int64
+	// In the order they were created.
+	Subscriptions []*Subscription
+}
+
+type topicsState struct {
+	Topics []topicState
+}
+
+// TryPause pauses changes to each region's topics while a snapshot copies them.
+func (s *SNS) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *SNS) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's topics and their subscriptions to the archive. FIFO topics'
+// deduplication IDs and the captured SMS and email messages aren't exported.
+func (s *SNS) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*SNS).exportState)
+}
+
+func (s *SNS) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported topicsState
+	for _, topic := range s.topicsByArn {
+		exported.Topics = append(exported.Topics, topicState{
+			Name:                      topic.Name,
+			ARN:                       topic.ARN,
+			Attributes:                topic.Attributes,
+			Tags:                      topic.Tags,
+			Fifo:                      topic.Fifo,
+			ContentBasedDeduplication: topic.ContentBasedDeduplication,
+			LastSequenceNumber:        topic.lastSequenceNumber,
+			Subscriptions:             topic.Subscriptions,
+		})
+	}
+	slices.SortFunc(exported.Topics, func(a, b topicState) int {
+		return strings.Compare(a.ARN, b.ARN)
+	})
+	return w.WriteJSON("topics.json", exported)
+}
+
+// ImportState replaces each region's topics and their subscriptions with the archive's.
+// Subscriptions' filter policies are parsed again.
+func (s *SNS) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*SNS).importState, (*SNS).exportState)
+}
+
+func (s *SNS) importState(r *state.Reader) (func() error, error) {
+	var imported topicsState
+	if err := r.ReadJSON("topics.json", &imported); err != nil {
+		return nil, err
+	}
+	topicsByArn := make(map[string]*Topic)
+	subscriptionsByArn := make(map[string]*Subscription)
+	for _, t := range imported.Topics {
+		if !topicNameRegex.MatchString(t.Name) || t.ARN == "" {
+			return nil, fmt.Errorf("invalid topic %q", t.Name)
+		}
+		topic := &Topic{
+			Name:                      t.Name,
+			ARN:                       t.ARN,
+			Attributes:                t.Attributes,
+			Tags:                      t.Tags,
+			Fifo:                      t.Fifo,
+			ContentBasedDeduplication: t.ContentBasedDeduplication,
+			lastSequenceNumber:        t.LastSequenceNumber,
+			deduplicationEntriesById:  make(map[string]*deduplicationEntry),
+		}
+		if topic.Attributes == nil {
+			topic.Attributes = make(map[string]string)
+		}
+		if topic.Tags == nil {
+			topic.Tags = make(map[string]string)
+		}
+		for _, subscription := range t.Subscriptions {
+			if subscription.ARN == "" || subscription.TopicArn != topic.ARN {
+				return nil, fmt.Errorf("topic %s: invalid subscription %q", topic.ARN, subscription.ARN)
+			}
+			if subscription.Attributes == nil {
+				subscription.Attributes = make(map[string]string)
+			}
+			filterPolicy, awserr := parseFilterPolicy(subscription.Attributes)
+			if awserr != nil {
+				return nil, fmt.Errorf("subscription %s: %s", subscription.ARN, awserr.Body.Message)
+			}
+			subscription.filterPolicy = filterPolicy
+			topic.Subscriptions = append(topic.Subscriptions, subscription)
+			subscriptionsByArn[subscription.ARN] = subscription
+		}
+		topicsByArn[topic.ARN] = topic
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.topicsByArn = topicsByArn
+		s.subscriptionsByArn = subscriptionsByArn
+		return nil
+	}, nil
+}
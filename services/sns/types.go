@@ -0,0 +1,154 @@
+package sns
+
+type Tag struct {
+	Key   string `required:"true" length:"1,128" error:"InvalidParameter"`
+	Value string `required:"true" length:",256" error:"InvalidParameter"`
+}
+
+type CreateTopicInput struct {
+	Attributes           map[string]string
+	DataProtectionPolicy string `length:",30720" error:"InvalidParameter"`
+	Name                 string `required:"true" length:"1,261" pattern:"[A-Za-z0-9_-]{1,256}(\\.fifo)?" error:"InvalidParameter"`
+	Tags                 []Tag  `length:",50" error:"InvalidParameter"`
+}
+
+type CreateTopicOutput struct {
+	TopicArn string
+}
+
+type DeleteTopicInput struct {
+	TopicArn string `required:"true" error:"InvalidParameter"`
+}
+
+type DeleteTopicOutput struct{}
+
+type ListTopicsInput struct {
+	NextToken string
+}
+
+type ListTopicsOutput struct {
+	NextToken string
+	Topics    []APITopic
+}
+
+type APITopic struct {
+	TopicArn string
+}
+
+type SubscribeInput struct {
+	Attributes            map[string]string
+	Endpoint              string
+	Protocol              string `required:"true" enum:"http|https|email|email-json|sms|sqs|application|lambda|firehose" error:"InvalidParameter"`
+	ReturnSubscriptionArn bool
+	TopicArn              string `required:"true" error:"InvalidParameter"`
+}
+
+type SubscribeOutput struct {
+	SubscriptionArn string
+}
+
+type ConfirmSubscriptionInput struct {
+	AuthenticateOnUnsubscribe string `enum:"true|false" error:"InvalidParameter"`
+	Token                     string `required:"true" error:"InvalidParameter"`
+	TopicArn                  string `required:"true" error:"InvalidParameter"`
+}
+
+type ConfirmSubscriptionOutput struct {
+	SubscriptionArn string
+}
+
+type GetSubscriptionAttributesInput struct {
+	SubscriptionArn string `required:"true" error:"InvalidParameter"`
+}
+
+type GetSubscriptionAttributesOutput struct {
+	Attributes map[string]string
+}
+
+type SetSubscriptionAttributesInput struct {
+	AttributeName   string `required:"true" error:"InvalidParameter"`
+	AttributeValue  string
+	SubscriptionArn string `required:"true" error:"InvalidParameter"`
+}
+
+type SetSubscriptionAttributesOutput struct{}
+
+type UnsubscribeInput struct {
+	SubscriptionArn string `required:"true" error:"InvalidParameter"`
+}
+
+type UnsubscribeOutput struct{}
+
+type ListSubscriptionsByTopicInput struct {
+	NextToken string
+	TopicArn  string `required:"true" error:"InvalidParameter"`
+}
+
+type ListSubscriptionsByTopicOutput struct {
+	NextToken     string
+	Subscriptions []APISubscription
+}
+
+type APISubscription struct {
+	Endpoint        string
+	Owner           string
+	Protocol        string
+	SubscriptionArn string
+	TopicArn        string
+}
+
+type MessageAttributeValue struct {
+	BinaryValue []byte
+	DataType    string `required:"true" error:"InvalidParameter"`
+	StringValue string
+}
+
+type PublishInput struct {
+	Message                string                           `length:",262144" error:"InvalidParameter"`
+	MessageAttributes      map[string]MessageAttributeValue `query:"MessageAttributes,Name,Value" length:",10" error:"InvalidParameter"`
+	MessageDeduplicationId string                           `length:",128" error:"InvalidParameter"`
+	MessageGroupId         string                           `length:",128" error:"InvalidParameter"`
+	MessageStructure       string                           `enum:"json" error:"InvalidParameter"`
+	PhoneNumber            string
+	Subject                string `length:"1,99" error:"InvalidParameter"`
+	TargetArn              string
+	TopicArn               string
+}
+
+type PublishOutput struct {
+	MessageId      string
+	SequenceNumber string
+}
+
+type PublishBatchInput struct {
+	PublishBatchRequestEntries []PublishBatchRequestEntry
+	TopicArn                   string `required:"true" error:"InvalidParameter"`
+}
+
+type PublishBatchRequestEntry struct {
+	Id                     string
+	Message                string
+	MessageAttributes      map[string]MessageAttributeValue `query:"MessageAttributes,Name,Value"`
+	MessageDeduplicationId string
+	MessageGroupId         string
+	MessageStructure       string
+	Subject                string
+}
+
+type PublishBatchOutput struct {
+	Failed     []BatchResultErrorEntry
+	Successful []PublishBatchResultEntry
+}
+
+type PublishBatchResultEntry struct {
+	Id             string
+	MessageId      string
+	SequenceNumber string
+}
+
+type BatchResultErrorEntry struct {
+	Code        string
+	Id          string
+	Message     string
+	SenderFault bool
+}
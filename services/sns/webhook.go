@@ -0,0 +1,133 @@
+package sns
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	// The first attempt, then the retries.
+	httpDeliveryAttempts = 4
+	// Doubled after each failed attempt.
+	httpRetryDelay = 250 * time.Millisecond
+)
+
+// webhookRequest is a message to POST to an http/https subscription.
+type webhookRequest struct {
+	endpoint        string
+	messageType     string
+	messageId       string
+	topicArn        string
+	subscriptionArn string
+	raw             bool
+	body            string
+}
+
+func newConfirmationToken() string {
+	token := make([]byte, 64)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(token)
+}
+
+func (s *SNS) subscribeURL(topicArn string, token string) string {
+	return fmt.Sprintf("http://%s/?Action=ConfirmSubscription&TopicArn=%s&Token=%s",
+		s.addr, url.QueryEscape(topicArn), token)
+}
+
+// lockedSendConfirmation asks the endpoint to confirm the subscription, by visiting the
+// SubscribeURL or calling ConfirmSubscription with the token.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html#http-subscription-confirmation-json
+func (s *SNS) lockedSendConfirmation(topic *Topic, subscription *Subscription) {
+	n := &notification{
+		Type:      "SubscriptionConfirmation",
+		MessageId: uuid.Must(uuid.NewV4()).String(),
+		Token:     subscription.Token,
+		TopicArn:  topic.ARN,
+		Message: fmt.Sprintf("You have chosen to subscribe to the topic %s.\n"+
+			"To confirm the subscription, visit the SubscribeURL included in this message.", topic.ARN),
+		SubscribeURL: s.subscribeURL(topic.ARN, subscription.Token),
+		Timestamp:    s.clock().UTC().Format(timestampFormat),
+	}
+	s.sign(n, topic.Attributes["SignatureVersion"])
+
+	go s.postWebhook(webhookRequest{
+		endpoint:    subscription.Endpoint,
+		messageType: n.Type,
+		messageId:   n.MessageId,
+		topicArn:    topic.ARN,
+		body:        marshalNotification(n),
+	})
+}
+
+func (s *SNS) lockedDeliverToHTTP(topic *Topic, subscription *Subscription, message *Message) {
+	request := webhookRequest{
+		endpoint:        subscription.Endpoint,
+		messageType:     "Notification",
+		messageId:       message.MessageId,
+		topicArn:        topic.ARN,
+		subscriptionArn: subscription.ARN,
+		raw:             isRawMessageDelivery(subscription),
+	}
+	if request.raw {
+		request.body = messageForProtocol(message, subscription.Protocol)
+	} else {
+		request.body = s.notificationJSON(topic, subscription, message)
+	}
+	go s.postWebhook(request)
+}
+
+// postWebhook delivers the request, retrying with backoff if the endpoint
+// fails or doesn't respond with a 2xx status.
+func (s *SNS) postWebhook(request webhookRequest) {
+	delay := httpRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := s.tryPostWebhook(request)
+		if err == nil {
+			return
+		}
+		if attempt == httpDeliveryAttempts {
+			s.logger.Warn("Failed to deliver message to HTTP endpoint",
+				"endpoint", request.endpoint, "messageId", request.messageId, "attempts", attempt, "error", err)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s *SNS) tryPostWebhook(request webhookRequest) error {
+	httpRequest, err := http.NewRequest(http.MethodPost, request.endpoint, strings.NewReader(request.body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "text/plain; charset=UTF-8")
+	httpRequest.Header.Set("User-Agent", "Amazon Simple Notification Service Agent")
+	httpRequest.Header.Set("x-amz-sns-message-type", request.messageType)
+	httpRequest.Header.Set("x-amz-sns-message-id", request.messageId)
+	httpRequest.Header.Set("x-amz-sns-topic-arn", request.topicArn)
+	if request.subscriptionArn != "" {
+		httpRequest.Header.Set("x-amz-sns-subscription-arn", request.subscriptionArn)
+	}
+	if request.raw {
+		httpRequest.Header.Set("x-amz-sns-rawdelivery", "true")
+	}
+
+	resp, err := s.httpClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package sqs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-message-metadata.html
+
+const (
+	maxMessageAttributes          = 10
+	maxMessageAttributeNameLength = 256
+)
+
+var messageAttributeNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// validateMessageAttributes checks the attributes of a message being sent, and returns their size,
+// which counts towards the maximum message size.
+func validateMessageAttributes(attributes map[string]APIAttribute) (int, *awserrors.Error) {
+	if len(attributes) > maxMessageAttributes {
+		return 0, InvalidParameterValue(fmt.Sprintf(
+			"Number of message attributes [%d] exceeds the allowed maximum [%d].", len(attributes), maxMessageAttributes))
+	}
+
+	size := 0
+	for name, attribute := range attributes {
+		if len(name) > maxMessageAttributeNameLength ||
+			!messageAttributeNameRegex.MatchString(name) ||
+			strings.HasPrefix(name, ".") ||
+			strings.HasSuffix(name, ".") ||
+			strings.Contains(name, "..") {
+			return 0, InvalidParameterValue(fmt.Sprintf("Message attribute name '%s' is invalid.", name))
+		}
+		lowerName := strings.ToLower(name)
+		if strings.HasPrefix(lowerName, "aws.") || strings.HasPrefix(lowerName, "amazon.") {
+			return 0, InvalidParameterValue(fmt.Sprintf(
+				"Message attribute name '%s' is invalid. Reason: The message attribute name can't start with 'AWS.' or 'Amazon.'.", name))
+		}
+
+		if attribute.DataType == "" {
+			return 0, InvalidParameterValue(fmt.Sprintf(
+				"The message attribute '%s' must contain non-empty message attribute type.", name))
+		}
+		// Data types may have a custom label, such as Number.float.
+		switch baseType, _, _ := strings.Cut(attribute.DataType, "."); baseType {
+		case "String":
+			if attribute.StringValue == "" {
+				return 0, InvalidParameterValue(fmt.Sprintf(
+					"The message attribute '%s' must contain non-empty message attribute value for message attribute type 'String'.", name))
+			}
+		case "Number":
+			if _, err := strconv.ParseFloat(attribute.StringValue, 64); err != nil {
+				return 0, InvalidParameterValue(fmt.Sprintf(
+					"Can't cast the value of message (user) attribute '%s' to a number.", name))
+			}
+		case "Binary":
+			if len(attribute.BinaryValue) == 0 {
+				return 0, InvalidParameterValue(fmt.Sprintf(
+					"The message attribute '%s' must contain non-empty message attribute value for message attribute type 'Binary'.", name))
+			}
+		default:
+			return 0, InvalidParameterValue(fmt.Sprintf(
+				"The type of message (user) attribute '%s' is invalid. You must use only the following supported type prefixes: Binary, Number, String.", name))
+		}
+		if len(attribute.StringListValues) > 0 || len(attribute.BinaryListValues) > 0 {
+			return 0, InvalidParameterValue(fmt.Sprintf(
+				"Message attribute list values in SendMessage operation are not supported. Attribute '%s'.", name))
+		}
+
+		size += len(name) + len(attribute.DataType) + len(attribute.StringValue) + len(attribute.BinaryValue)
+	}
+	return size, nil
+}
+
+// md5OfMessageAttributes hashes the attributes the same way as the SDKs do to verify them.
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-message-metadata.html#sqs-attributes-md5-message-digest-calculation
+func md5OfMessageAttributes(attributes map[string]APIAttribute) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	writeLengthPrefixed := func(data []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+
+	var names []string
+	for name := range attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		attribute := attributes[name]
+		writeLengthPrefixed([]byte(name))
+		writeLengthPrefixed([]byte(attribute.DataType))
+		if strings.HasPrefix(attribute.DataType, "Binary") {
+			buf.WriteByte(2)
+			writeLengthPrefixed(attribute.BinaryValue)
+		} else {
+			buf.WriteByte(1)
+			writeLengthPrefixed([]byte(attribute.StringValue))
+		}
+	}
+
+	hash := md5.Sum(buf.Bytes())
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *SQS) messageSystemAttributes(message *Message, attributeNames []AttributeName) map[string]string {
+	attributes := make(map[string]string)
+	set := func(name AttributeName, value string) {
+		if value == "" {
+			return
+		}
+		for _, requested := range attributeNames {
+			if requested == All || requested == name {
+				attributes[string(name)] = value
+				return
+			}
+		}
+	}
+
+	set(ApproximateReceiveCount, strconv.Itoa(message.ReceiveCount))
+	set(ApproximateFirstReceiveTimestamp, strconv.FormatInt(message.FirstReceiveTimestamp, 10))
+	set(SenderId, s.arnGenerator.AwsAccountId)
+	set(SentTimestamp, strconv.FormatInt(message.SentTimestamp, 10))
+	set(AWSTraceHeader, message.MessageSystemAttributes[AWSTraceHeaderAttributeName].StringValue)
+	set(DeadLetterQueueSourceArn, message.DeadLetterQueueSourceArn)
+	set(MessageGroupId, message.MessageGroupId)
+	set(MessageDeduplicationId, message.MessageDeduplicationId)
+	set(SequenceNumber, message.SequenceNumber)
+	return attributes
+}
+
+func filterAttributes(attributes map[string]APIAttribute, attributeNames []string) map[string]APIAttribute {
+	ret := make(map[string]APIAttribute)
+
+	for k, v := range attributes {
+		for _, name := range attributeNames {
+			if name == "All" ||
+				name == k ||
+				(strings.HasSuffix(name, ".*") && strings.HasPrefix(k, name[:len(name)-2])) {
+				ret[k] = v
+				break
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,108 @@
+package sqs
+
+import (
+	"fmt"
+	"regexp"
+
+	"aws-in-a-box/awserrors"
+)
+
+const (
+	maxEntriesInBatch     = 10
+	maxBatchEntryIdLength = 80
+	// The total size of all the messages in a SendMessageBatch.
+	maxBatchSize = 262_144
+)
+
+var batchEntryIdRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// validateBatchEntryIds checks the number of entries in a batch request and their IDs.
+// These failures apply to the whole request rather than individual entries.
+func validateBatchEntryIds(entryName string, ids []string) *awserrors.Error {
+	if len(ids) == 0 {
+		return EmptyBatchRequest(fmt.Sprintf("There should be at least one %s in the request.", entryName))
+	}
+	if len(ids) > maxEntriesInBatch {
+		return TooManyEntriesInBatchRequest(fmt.Sprintf(
+			"Maximum number of entries per request are %d. You have sent %d.", maxEntriesInBatch, len(ids)))
+	}
+
+	seen := make(map[string]struct{})
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			return BatchEntryIdsNotDistinct("Id " + id + " repeated.")
+		}
+		seen[id] = struct{}{}
+		if len(id) > maxBatchEntryIdLength || !batchEntryIdRegex.MatchString(id) {
+			return InvalidBatchEntryId(fmt.Sprintf(
+				"A batch entry id can only contain alphanumeric characters, hyphens and underscores. It can be at most %d letters long.",
+				maxBatchEntryIdLength))
+		}
+	}
+	return nil
+}
+
+func batchResultErrorEntry(id string, err *awserrors.Error) BatchResultErrorEntry {
+	return BatchResultErrorEntry{
+		Code:        err.Body.Type,
+		Id:          id,
+		Message:     err.Body.Message,
+		SenderFault: err.Code < 500,
+	}
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SendMessageBatch.html
+func (s *SQS) SendMessageBatch(input SendMessageBatchInput) (*SendMessageBatchOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	var ids []string
+	size := 0
+	for _, entry := range input.Entries {
+		ids = append(ids, entry.Id)
+		size += len(entry.MessageBody)
+	}
+	awserr = validateBatchEntryIds("SendMessageBatchRequestEntry", ids)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if size > maxBatchSize {
+		return nil, BatchRequestTooLong(fmt.Sprintf(
+			"Batch requests cannot be longer than %d bytes. You have sent %d bytes.", maxBatchSize, size))
+	}
+
+	output := &SendMessageBatchOutput{
+		Failed:     []BatchResultErrorEntry{},
+		Successful: []SendMessageBatchResultEntry{},
+	}
+	for _, entry := range input.Entries {
+		result, err := s.lockedSendMessage(queue, SendMessageInput{
+			DelaySeconds:            entry.DelaySeconds,
+			MessageAttributes:       entry.MessageAttributes,
+			MessageBody:             entry.MessageBody,
+			MessageDeduplicationId:  entry.MessageDeduplicationId,
+			MessageGroupId:          entry.MessageGroupId,
+			MessageSystemAttributes: entry.MessageSystemAttributes,
+			QueueUrl:                input.QueueUrl,
+		})
+		if err != nil {
+			output.Failed = append(output.Failed, batchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, SendMessageBatchResultEntry{
+			Id:                           entry.Id,
+			MD5OfMessageAttributes:       result.MD5OfMessageAttributes,
+			MD5OfMessageBody:             result.MD5OfMessageBody,
+			MD5OfMessageSystemAttributes: result.MD5OfMessageSystemAttributes,
+			MessageId:                    result.MessageId,
+			SequenceNumber:               result.SequenceNumber,
+		})
+	}
+
+	return output, nil
+}
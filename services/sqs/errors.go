@@ -39,3 +39,47 @@ func InvalidIdFormat(message string) *awserrors.Error {
 func ReceiptHandleIsInvalid(message string) *awserrors.Error {
 	return awserrors.Generate400Exception("ReceiptHandleIsInvalid", message)
 }
+
+func InvalidParameterValue(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidParameterValue", message)
+}
+
+func MissingParameter(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("MissingParameter", message)
+}
+
+func InvalidMessageContents(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidMessageContents", message)
+}
+
+func PurgeQueueInProgress(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("PurgeQueueInProgress", message)
+}
+
+func InvalidAttributeName(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidAttributeName", message)
+}
+
+func InvalidAttributeValue(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidAttributeValue", message)
+}
+
+func MessageNotInflight(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("MessageNotInflight", message)
+}
+
+func BatchEntryIdsNotDistinct(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BatchEntryIdsNotDistinct", message)
+}
+
+func InvalidBatchEntryId(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidBatchEntryId", message)
+}
+
+func ResourceNotFoundException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFoundException", message)
+}
+
+func BatchRequestTooLong(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("BatchRequestTooLong", message)
+}
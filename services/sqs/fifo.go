@@ -0,0 +1,157 @@
+package sqs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-fifo-queues.html
+
+const (
+	deduplicationInterval   = 5 * time.Minute
+	maxMessageGroupIdLength = 128
+)
+
+var (
+	fifoQueueNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,75}\.fifo$`)
+	// Alphanumeric characters and punctuation.
+	messageGroupIdRegex = regexp.MustCompile("^[a-zA-Z0-9!\"#$%&'()*+,\\-./:;<=>?@\\[\\\\\\]^_`{|}~]+$")
+)
+
+// deduplicationEntry records a message sent to a FIFO queue, so that messages sent again with
+// the same deduplication ID within the deduplication interval are accepted but not delivered.
+type deduplicationEntry struct {
+	key            string
+	messageId      string
+	sequenceNumber string
+	expires        time.Time
+}
+
+func (q *Queue) deduplicationKey(messageGroupId string, deduplicationId string) string {
+	// In high throughput mode, deduplication happens within each message group.
+	if q.DeduplicationScope == "messageGroup" {
+		return messageGroupId + "\x00" + deduplicationId
+	}
+	return deduplicationId
+}
+
+// lockedExpireDeduplicationIds forgets deduplication IDs older than the deduplication interval.
+// Entries are recorded in order, so the expired ones are at the front.
+func (q *Queue) lockedExpireDeduplicationIds(now time.Time) {
+	i := 0
+	for i < len(q.deduplicationEntries) && !now.Before(q.deduplicationEntries[i].expires) {
+		delete(q.deduplicationEntriesByKey, q.deduplicationEntries[i].key)
+		i++
+	}
+	q.deduplicationEntries = q.deduplicationEntries[i:]
+}
+
+// validateFifoParameters checks the FIFO specific parameters of a message being sent,
+// and returns its deduplication ID.
+func (q *Queue) validateFifoParameters(
+	messageGroupId string,
+	deduplicationId string,
+	delaySeconds int,
+	body string,
+) (string, *awserrors.Error) {
+	if !q.Fifo {
+		if deduplicationId != "" || messageGroupId != "" {
+			return "", InvalidParameterValue(
+				"The request include parameter that is not valid for this queue type")
+		}
+		return "", nil
+	}
+
+	if delaySeconds != 0 {
+		return "", InvalidParameterValue(fmt.Sprintf(
+			"Value %d for parameter DelaySeconds is invalid. Reason: The request include parameter that is not valid for this queue type.",
+			delaySeconds))
+	}
+	if messageGroupId == "" {
+		return "", MissingParameter("The request must contain the parameter MessageGroupId.")
+	}
+	if len(messageGroupId) > maxMessageGroupIdLength || !messageGroupIdRegex.MatchString(messageGroupId) {
+		return "", InvalidParameterValue(fmt.Sprintf(
+			"Value %s for parameter MessageGroupId is invalid. Reason: MessageGroupId can only include alphanumeric and punctuation characters. 1 to 128 in length.",
+			messageGroupId))
+	}
+	if deduplicationId == "" {
+		if !q.ContentBasedDeduplication {
+			return "", InvalidParameterValue(
+				"The queue should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+		}
+		hash := sha256.Sum256([]byte(body))
+		deduplicationId = hex.EncodeToString(hash[:])
+	} else if len(deduplicationId) > maxMessageGroupIdLength || !messageGroupIdRegex.MatchString(deduplicationId) {
+		return "", InvalidParameterValue(fmt.Sprintf(
+			"Value %s for parameter MessageDeduplicationId is invalid. Reason: MessageDeduplicationId can only include alphanumeric and punctuation characters. 1 to 128 in length.",
+			deduplicationId))
+	}
+	return deduplicationId, nil
+}
+
+// lockedRecordFifoMessage assigns the next sequence number to the message, and remembers its
+// deduplication key.
+func (q *Queue) lockedRecordFifoMessage(message *Message, deduplicationKey string, now time.Time) {
+	q.lastSequenceNumber++
+	message.SequenceNumber = fmt.Sprintf("%020d", q.lastSequenceNumber)
+
+	entry := &deduplicationEntry{
+		key:            deduplicationKey,
+		messageId:      message.UUID.String(),
+		sequenceNumber: message.SequenceNumber,
+		expires:        now.Add(deduplicationInterval),
+	}
+	q.deduplicationEntries = append(q.deduplicationEntries, entry)
+	q.deduplicationEntriesByKey[deduplicationKey] = entry
+}
+
+// setFifoAttribute applies one of the FIFO specific queue attributes.
+func (q *Queue) setFifoAttribute(name string, value string) *awserrors.Error {
+	if name == "FifoQueue" {
+		// This is fixed when the queue is created, based on its name.
+		fifo, err := strconv.ParseBool(value)
+		if err != nil || fifo != q.Fifo {
+			return InvalidAttributeValue("Invalid value for the parameter FifoQueue.")
+		}
+		return nil
+	}
+
+	if !q.Fifo {
+		return InvalidAttributeName("Unknown Attribute " + name + ".")
+	}
+	switch name {
+	case "ContentBasedDeduplication":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return InvalidAttributeValue("Invalid value for the parameter ContentBasedDeduplication.")
+		}
+		q.ContentBasedDeduplication = enabled
+	case "DeduplicationScope":
+		if value != "queue" && value != "messageGroup" {
+			return InvalidAttributeValue("Invalid value for the parameter DeduplicationScope.")
+		}
+		q.DeduplicationScope = value
+	case "FifoThroughputLimit":
+		if value != "perQueue" && value != "perMessageGroupId" {
+			return InvalidAttributeValue("Invalid value for the parameter FifoThroughputLimit.")
+		}
+		q.FifoThroughputLimit = value
+	}
+	return nil
+}
+
+// validateFifoAttributes checks the combination of FIFO attributes, after they've all been set.
+func (q *Queue) validateFifoAttributes() *awserrors.Error {
+	if q.FifoThroughputLimit == "perMessageGroupId" && q.DeduplicationScope != "messageGroup" {
+		return InvalidAttributeValue(
+			"The perMessageGroupId value is allowed only when the value for DeduplicationScope is messageGroup")
+	}
+	return nil
+}
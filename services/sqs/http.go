@@ -2,21 +2,56 @@ package sqs
 
 import (
 	"log/slog"
-	"net/http"
+
+	awshttp "aws-in-a-box/http"
+	"aws-in-a-box/http/query"
+	"aws-in-a-box/region"
 )
 
-func registerHTTPHandlers(logger *slog.Logger, registry map[string]http.HandlerFunc, s *SQS) {
-	register(logger, registry, "CreateQueue", s.CreateQueue)
-	register(logger, registry, "DeleteMessage", s.DeleteMessage)
-	register(logger, registry, "DeleteMessageBatch", s.DeleteMessageBatch)
-	register(logger, registry, "DeleteQueue", s.DeleteQueue)
-	register(logger, registry, "GetQueueAttributes", s.GetQueueAttributes)
-	register(logger, registry, "GetQueueUrl", s.GetQueueUrl)
-	register(logger, registry, "ListQueues", s.ListQueues)
-	register(logger, registry, "ListQueueTags", s.ListQueueTags)
-	register(logger, registry, "ReceiveMessage", s.ReceiveMessage)
-	register(logger, registry, "SendMessage", s.SendMessage)
-	register(logger, registry, "SetQueueAttributes", s.SetQueueAttributes)
-	register(logger, registry, "TagQueue", s.TagQueue)
-	register(logger, registry, "UntagQueue", s.UntagQueue)
+const service = "AmazonSQS"
+
+// registerQueryHandlers registers the handlers for the Query protocol.
+func registerQueryHandlers(logger *slog.Logger, registry *query.Registry, s *SQS) {
+	query.RegisterWithRequest(logger, registry, "ChangeMessageVisibility", region.Method(s.regions, (*SQS).ChangeMessageVisibility))
+	query.RegisterWithRequest(logger, registry, "ChangeMessageVisibilityBatch", region.Method(s.regions, (*SQS).ChangeMessageVisibilityBatch))
+	query.RegisterWithRequest(logger, registry, "CreateQueue", region.Method(s.regions, (*SQS).CreateQueue))
+	query.RegisterWithRequest(logger, registry, "DeleteMessage", region.Method(s.regions, (*SQS).DeleteMessage))
+	query.RegisterWithRequest(logger, registry, "DeleteMessageBatch", region.Method(s.regions, (*SQS).DeleteMessageBatch))
+	query.RegisterWithRequest(logger, registry, "DeleteQueue", region.Method(s.regions, (*SQS).DeleteQueue))
+	query.RegisterWithRequest(logger, registry, "GetQueueAttributes", region.Method(s.regions, (*SQS).GetQueueAttributes))
+	query.RegisterWithRequest(logger, registry, "GetQueueUrl", region.Method(s.regions, (*SQS).GetQueueUrl))
+	query.RegisterWithRequest(logger, registry, "ListMessageMoveTasks", region.Method(s.regions, (*SQS).ListMessageMoveTasks))
+	query.RegisterWithRequest(logger, registry, "ListQueues", region.Method(s.regions, (*SQS).ListQueues))
+	query.RegisterWithRequest(logger, registry, "ListQueueTags", region.Method(s.regions, (*SQS).ListQueueTags))
+	query.RegisterWithRequest(logger, registry, "PurgeQueue", region.Method(s.regions, (*SQS).PurgeQueue))
+	query.RegisterWithRequest(logger, registry, "ReceiveMessage", region.Method(s.regions, (*SQS).ReceiveMessage))
+	query.RegisterWithRequest(logger, registry, "SendMessage", region.Method(s.regions, (*SQS).SendMessage))
+	query.RegisterWithRequest(logger, registry, "SendMessageBatch", region.Method(s.regions, (*SQS).SendMessageBatch))
+	query.RegisterWithRequest(logger, registry, "SetQueueAttributes", region.Method(s.regions, (*SQS).SetQueueAttributes))
+	query.RegisterWithRequest(logger, registry, "StartMessageMoveTask", region.Method(s.regions, (*SQS).StartMessageMoveTask))
+	query.RegisterWithRequest(logger, registry, "TagQueue", region.Method(s.regions, (*SQS).TagQueue))
+	query.RegisterWithRequest(logger, registry, "UntagQueue", region.Method(s.regions, (*SQS).UntagQueue))
+}
+
+// RegisterHTTPHandlers registers the handlers for the JSON protocol, which newer SDKs use.
+func (s *SQS) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry awshttp.Registry) {
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ChangeMessageVisibility", region.Method(s.regions, (*SQS).ChangeMessageVisibility))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ChangeMessageVisibilityBatch", region.Method(s.regions, (*SQS).ChangeMessageVisibilityBatch))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "CreateQueue", region.Method(s.regions, (*SQS).CreateQueue))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DeleteMessage", region.Method(s.regions, (*SQS).DeleteMessage))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DeleteMessageBatch", region.Method(s.regions, (*SQS).DeleteMessageBatch))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "DeleteQueue", region.Method(s.regions, (*SQS).DeleteQueue))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "GetQueueAttributes", region.Method(s.regions, (*SQS).GetQueueAttributes))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "GetQueueUrl", region.Method(s.regions, (*SQS).GetQueueUrl))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ListMessageMoveTasks", region.Method(s.regions, (*SQS).ListMessageMoveTasks))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ListQueues", region.Method(s.regions, (*SQS).ListQueues))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ListQueueTags", region.Method(s.regions, (*SQS).ListQueueTags))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "PurgeQueue", region.Method(s.regions, (*SQS).PurgeQueue))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "ReceiveMessage", region.Method(s.regions, (*SQS).ReceiveMessage))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "SendMessage", region.Method(s.regions, (*SQS).SendMessage))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "SendMessageBatch", region.Method(s.regions, (*SQS).SendMessageBatch))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "SetQueueAttributes", region.Method(s.regions, (*SQS).SetQueueAttributes))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "StartMessageMoveTask", region.Method(s.regions, (*SQS).StartMessageMoveTask))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "TagQueue", region.Method(s.regions, (*SQS).TagQueue))
+	awshttp.RegisterWithRequest(logger, methodRegistry, service, "UntagQueue", region.Method(s.regions, (*SQS).UntagQueue))
 }
@@ -1,50 +1,73 @@
 package itest
 
 import (
+	"bytes"
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
 
+	"aws-in-a-box/arn"
+	awshttp "aws-in-a-box/http"
+	"aws-in-a-box/region"
 	"aws-in-a-box/server"
 	sqsImpl "aws-in-a-box/services/sqs"
 )
 
-func makeClientServerPair() (*sqs.Client, *http.Server) {
+func makeClientServerPair() (*sqs.Client, *http.Server, string) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		panic(err)
 	}
-	impl := sqsImpl.New(sqsImpl.Options{})
-	if err != nil {
-		panic(err)
-	}
+	addr := listener.Addr().String()
+	impl := sqsImpl.New(sqsImpl.Options{
+		ArnGenerator: arn.Generator{
+			AwsAccountId: "123456789012",
+			Region:       "us-east-1",
+		},
+		Addr: addr,
+	})
+
+	methodRegistry := make(awshttp.Registry)
+	impl.RegisterHTTPHandlers(slog.Default(), methodRegistry)
 
 	srv := server.NewWithHandlerChain(
-		sqsImpl.NewHandler(slog.Default(), impl),
+		server.HandlerFuncFromRegistry(slog.Default(), methodRegistry),
+		sqsImpl.NewHandler(slog.Default(), impl, nil),
 	)
 	go srv.Serve(listener)
 
-	client := sqs.New(sqs.Options{
-		EndpointResolver: sqs.EndpointResolverFromURL("http://" + listener.Addr().String()),
+	return newClient(addr), srv, addr
+}
+
+// newClient returns a client for the server. The SDK's URL endpoint resolver isn't safe for
+// concurrent use, so goroutines which call the server alongside the test need their own.
+func newClient(addr string) *sqs.Client {
+	return sqs.New(sqs.Options{
+		EndpointResolver: sqs.EndpointResolverFromURL("http://" + addr),
 		Retryer:          aws.NopRetryer{},
 	})
-
-	return client, srv
 }
 
 func TestQueue(t *testing.T) {
 	ctx := context.Background()
-	client, srv := makeClientServerPair()
+	client, srv, addr := makeClientServerPair()
 	defer srv.Shutdown(ctx)
 
 	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
 		QueueName: aws.String("queue"),
+		Attributes: map[string]string{
+			"VisibilityTimeout": "60",
+		},
 		Tags: map[string]string{
 			"k1": "v1",
 			"k2": "v2",
@@ -53,13 +76,972 @@ func TestQueue(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	if *resp.QueueUrl != "http://"+addr+"/123456789012/queue" {
+		t.Fatal("Wrong queue URL", *resp.QueueUrl)
+	}
+
+	getUrl, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+		QueueName: aws.String("queue"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *getUrl.QueueUrl != *resp.QueueUrl {
+		t.Fatal("Wrong queue URL", *getUrl.QueueUrl)
+	}
+
+	// Creating the same queue again is a no-op, but the attributes must match.
+	_, err = client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+		Attributes: map[string]string{
+			"VisibilityTimeout": "60",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+	})
+	var queueNameExists *types.QueueNameExists
+	if !errors.As(err, &queueNameExists) {
+		t.Fatal("Expected QueueNameExists", err)
+	}
+
+	for _, body := range []string{"first", "second"} {
+		msg, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    resp.QueueUrl,
+			MessageBody: aws.String(body),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *msg.MessageId == "" {
+			t.Fatal("Missing MessageId")
+		}
+	}
+
+	received, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl: resp.QueueUrl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Messages) != 1 || *received.Messages[0].Body != "first" {
+		t.Fatal("Wrong messages", received.Messages)
+	}
+
+	// Received messages are invisible until their visibility timeout expires.
+	received2, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            resp.QueueUrl,
+		MaxNumberOfMessages: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received2.Messages) != 1 || *received2.Messages[0].Body != "second" {
+		t.Fatal("Wrong messages", received2.Messages)
+	}
+
+	_, err = client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      resp.QueueUrl,
+		ReceiptHandle: received.Messages[0].ReceiptHandle,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      resp.QueueUrl,
+		ReceiptHandle: aws.String("garbage"),
+	})
+	var receiptHandleIsInvalid *types.ReceiptHandleIsInvalid
+	if !errors.As(err, &receiptHandleIsInvalid) {
+		t.Fatal("Expected ReceiptHandleIsInvalid", err)
+	}
+
+	_, err = client.PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: resp.QueueUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: resp.QueueUrl})
+	var purgeQueueInProgress *types.PurgeQueueInProgress
+	if !errors.As(err, &purgeQueueInProgress) {
+		t.Fatal("Expected PurgeQueueInProgress", err)
+	}
+
+	_, err = client.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: resp.QueueUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    resp.QueueUrl,
+		MessageBody: aws.String("too late"),
+	})
+	// This SDK version only has a typed error for some operations.
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AWS.SimpleQueueService.NonExistentQueue" {
+		t.Fatal("Expected QueueDoesNotExist", err)
+	}
+}
+
+// Newer SDKs use the JSON protocol rather than the Query protocol.
+func TestJSONProtocol(t *testing.T) {
+	ctx := context.Background()
+	_, srv, addr := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	call := func(method string, input any, output any) int {
+		body, err := json.Marshal(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, "http://"+addr, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+		req.Header.Set("X-Amz-Target", "AmazonSQS."+method)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if output != nil {
+			err = json.NewDecoder(resp.Body).Decode(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	var create struct{ QueueUrl string }
+	status := call("CreateQueue", map[string]any{
+		"QueueName": "queue",
+		"tags":      map[string]string{"k": "v"},
+	}, &create)
+	if status != http.StatusOK || create.QueueUrl == "" {
+		t.Fatal("CreateQueue failed", status)
+	}
+
+	status = call("SendMessage", map[string]any{
+		"QueueUrl":    create.QueueUrl,
+		"MessageBody": "hello",
+	}, nil)
+	if status != http.StatusOK {
+		t.Fatal("SendMessage failed", status)
+	}
+
+	var receive struct {
+		Messages []struct {
+			Body          string
+			ReceiptHandle string
+		}
+	}
+	status = call("ReceiveMessage", map[string]any{
+		"QueueUrl":            create.QueueUrl,
+		"MaxNumberOfMessages": 10,
+	}, &receive)
+	if status != http.StatusOK || len(receive.Messages) != 1 || receive.Messages[0].Body != "hello" {
+		t.Fatal("Wrong messages", status, receive)
+	}
+
+	var failure struct {
+		Type string `json:"__type"`
+	}
+	status = call("GetQueueUrl", map[string]any{"QueueName": "missing"}, &failure)
+	if status != http.StatusBadRequest || failure.Type != "QueueDoesNotExist" {
+		t.Fatal("Expected QueueDoesNotExist", status, failure)
+	}
+}
+
+func TestFifoQueue(t *testing.T) {
+	ctx := context.Background()
+	client, srv, _ := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	_, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+		Attributes: map[string]string{
+			"FifoQueue": "true",
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected error for FIFO queue without .fifo suffix")
+	}
+
+	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue.fifo"),
+		Attributes: map[string]string{
+			"FifoQueue":                 "true",
+			"ContentBasedDeduplication": "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sequenceNumbers []string
+	for _, message := range []struct{ group, body string }{
+		{"a", "a1"},
+		{"a", "a2"},
+		{"b", "b1"},
+	} {
+		output, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:       resp.QueueUrl,
+			MessageBody:    aws.String(message.body),
+			MessageGroupId: aws.String(message.group),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sequenceNumbers = append(sequenceNumbers, *output.SequenceNumber)
+	}
+	if !(sequenceNumbers[0] < sequenceNumbers[1] && sequenceNumbers[1] < sequenceNumbers[2]) {
+		t.Fatal("Sequence numbers aren't increasing", sequenceNumbers)
+	}
+
+	// Resending the same body is deduplicated.
+	duplicate, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:       resp.QueueUrl,
+		MessageBody:    aws.String("a1"),
+		MessageGroupId: aws.String("a"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *duplicate.SequenceNumber != sequenceNumbers[0] {
+		t.Fatal("Duplicate wasn't deduplicated", *duplicate.SequenceNumber)
+	}
 
-	msg, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
 		QueueUrl:    resp.QueueUrl,
-		MessageBody: aws.String("READ THIS AND WEEP"),
+		MessageBody: aws.String("no group"),
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing MessageGroupId")
+	}
+
+	receive := func() []string {
+		output, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            resp.QueueUrl,
+			MaxNumberOfMessages: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bodies []string
+		for _, message := range output.Messages {
+			bodies = append(bodies, *message.Body)
+			_, err := client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      resp.QueueUrl,
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return bodies
+	}
+
+	first, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            resp.QueueUrl,
+		MaxNumberOfMessages: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Messages) != 1 || *first.Messages[0].Body != "a1" {
+		t.Fatal("Wrong messages", first.Messages)
+	}
+
+	// Group a is blocked while a1 is in flight.
+	if bodies := receive(); len(bodies) != 1 || bodies[0] != "b1" {
+		t.Fatal("Wrong messages", bodies)
+	}
+	if bodies := receive(); len(bodies) != 0 {
+		t.Fatal("Wrong messages", bodies)
+	}
+
+	_, err = client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      resp.QueueUrl,
+		ReceiptHandle: first.Messages[0].ReceiptHandle,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bodies := receive(); len(bodies) != 1 || bodies[0] != "a2" {
+		t.Fatal("Wrong messages", bodies)
+	}
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+	client, srv, _ := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{"a", "b"} {
+		_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    resp.QueueUrl,
+			MessageBody: aws.String(body),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receive := func() []types.Message {
+		output, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            resp.QueueUrl,
+			MaxNumberOfMessages: 10,
+			VisibilityTimeout:   60,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output.Messages
+	}
+
+	first := receive()
+	if len(first) != 2 {
+		t.Fatal("Wrong messages", first)
+	}
+	if messages := receive(); len(messages) != 0 {
+		t.Fatal("Messages should be in flight", messages)
+	}
+
+	_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          resp.QueueUrl,
+		ReceiptHandle:     first[0].ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := receive()
+	if len(second) != 1 || *second[0].Body != *first[0].Body {
+		t.Fatal("Wrong messages", second)
+	}
+	if *second[0].ReceiptHandle == *first[0].ReceiptHandle {
+		t.Fatal("Receipt handle wasn't changed")
+	}
+
+	// The old receipt handle can no longer change the visibility.
+	_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          resp.QueueUrl,
+		ReceiptHandle:     first[0].ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+	if err == nil {
+		t.Fatal("Expected error for stale receipt handle")
+	}
+
+	batch, err := client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: resp.QueueUrl,
+		Entries: []types.ChangeMessageVisibilityBatchRequestEntry{
+			{
+				Id:                aws.String("a"),
+				ReceiptHandle:     second[0].ReceiptHandle,
+				VisibilityTimeout: 0,
+			},
+			{
+				Id:                aws.String("b"),
+				ReceiptHandle:     aws.String("invalid"),
+				VisibilityTimeout: 0,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Successful) != 1 || *batch.Successful[0].Id != "a" {
+		t.Fatal("Wrong successful entries", batch.Successful)
+	}
+	if len(batch.Failed) != 1 || *batch.Failed[0].Id != "b" || *batch.Failed[0].Code != "ReceiptHandleIsInvalid" {
+		t.Fatal("Wrong failed entries", batch.Failed)
+	}
+
+	// The message is visible again, but its handle is no longer in flight.
+	_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          resp.QueueUrl,
+		ReceiptHandle:     second[0].ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+	var notInflight *types.MessageNotInflight
+	if !errors.As(err, &notInflight) {
+		t.Fatal("Expected MessageNotInflight", err)
+	}
+	if messages := receive(); len(messages) != 1 {
+		t.Fatal("Wrong messages", messages)
+	}
+}
+
+func TestStaleReceiptHandle(t *testing.T) {
+	ctx := context.Background()
+	client, srv, addr := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+	other := newClient(addr)
+
+	for _, test := range []struct {
+		name       string
+		attributes map[string]string
+		group      *string
+		// Standard queues delete the message with any of its handles, like AWS.
+		wantErr bool
+	}{
+		{"queue", nil, nil, false},
+		{"queue.fifo", map[string]string{"FifoQueue": "true", "ContentBasedDeduplication": "true"}, aws.String("g"), true},
+	} {
+		resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String(test.name), Attributes: test.attributes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: resp.QueueUrl, MessageBody: aws.String("a"), MessageGroupId: test.group})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: resp.QueueUrl})
+		if err != nil || len(first.Messages) != 1 {
+			t.Fatal(test.name, first, err)
+		}
+		// The first consumer's visibility timeout expires, and another consumer receives the message.
+		_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:      resp.QueueUrl,
+			ReceiptHandle: first.Messages[0].ReceiptHandle,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := other.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: resp.QueueUrl, VisibilityTimeout: 60})
+		if err != nil || len(second.Messages) != 1 {
+			t.Fatal(test.name, second, err)
+		}
+
+		_, err = client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: resp.QueueUrl, ReceiptHandle: first.Messages[0].ReceiptHandle})
+		if (err != nil) != test.wantErr {
+			t.Fatal(test.name, "Unexpected error deleting with the stale handle", err)
+		}
+		if !test.wantErr {
+			continue
+		}
+		_, err = other.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: resp.QueueUrl, ReceiptHandle: second.Messages[0].ReceiptHandle})
+		if err != nil {
+			t.Fatal(test.name, "Expected the current handle to delete the message", err)
+		}
+	}
+}
+
+func TestLongPolling(t *testing.T) {
+	ctx := context.Background()
+	client, srv, addr := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+		Attributes: map[string]string{
+			"ReceiveMessageWaitTimeSeconds": "1",
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println("msg ", msg)
+
+	// The queue's wait time applies when the request doesn't set one.
+	start := time.Now()
+	output, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl: resp.QueueUrl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Messages) != 0 {
+		t.Fatal("Wrong messages", output.Messages)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatal("Receive returned before the wait time", elapsed)
+	}
+
+	sender := newClient(addr)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, err := sender.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    resp.QueueUrl,
+			MessageBody: aws.String("hello"),
+		})
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	start = time.Now()
+	output, err = client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:        resp.QueueUrl,
+		WaitTimeSeconds: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Messages) != 1 || *output.Messages[0].Body != "hello" {
+		t.Fatal("Wrong messages", output.Messages)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatal("Receive didn't return when the message arrived", elapsed)
+	}
+
+	_, err = client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:        resp.QueueUrl,
+		WaitTimeSeconds: 21,
+	})
+	if err == nil {
+		t.Fatal("Expected error for WaitTimeSeconds over 20")
+	}
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	ctx := context.Background()
+	client, srv, _ := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	dlq, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("dlq"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dlqArn := "arn:aws:sqs:us-east-1:123456789012:dlq"
+
+	_, err = client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("invalid"),
+		Attributes: map[string]string{
+			"RedrivePolicy": `{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123456789012:missing","maxReceiveCount":"2"}`,
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing dead-letter queue")
+	}
+
+	source, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("source"),
+		Attributes: map[string]string{
+			"RedrivePolicy": `{"deadLetterTargetArn":"` + dlqArn + `","maxReceiveCount":2}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    source.QueueUrl,
+		MessageBody: aws.String("poison"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(queueUrl *string) []types.Message {
+		output, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:       queueUrl,
+			AttributeNames: []types.QueueAttributeName{"ApproximateReceiveCount"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Fail processing the message, so it's returned to the queue.
+		for _, message := range output.Messages {
+			_, err := client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          queueUrl,
+				ReceiptHandle:     message.ReceiptHandle,
+				VisibilityTimeout: 0,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return output.Messages
+	}
+
+	for _, receiveCount := range []string{"1", "2"} {
+		messages := receive(source.QueueUrl)
+		if len(messages) != 1 || messages[0].Attributes["ApproximateReceiveCount"] != receiveCount {
+			t.Fatal("Wrong messages", messages)
+		}
+	}
+	// The third receive moves the message to the dead-letter queue.
+	if messages := receive(source.QueueUrl); len(messages) != 0 {
+		t.Fatal("Wrong messages", messages)
+	}
+	if messages := receive(dlq.QueueUrl); len(messages) != 1 || *messages[0].Body != "poison" {
+		t.Fatal("Wrong messages", messages)
+	}
+
+	_, err = client.StartMessageMoveTask(ctx, &sqs.StartMessageMoveTaskInput{
+		SourceArn: aws.String("arn:aws:sqs:us-east-1:123456789012:source"),
+	})
+	if err == nil {
+		t.Fatal("Expected error for source which isn't a dead-letter queue")
+	}
+
+	_, err = client.StartMessageMoveTask(ctx, &sqs.StartMessageMoveTaskInput{
+		SourceArn: aws.String(dlqArn),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := client.ListMessageMoveTasks(ctx, &sqs.ListMessageMoveTasksInput{
+		SourceArn: aws.String(dlqArn),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Results) != 1 || *tasks.Results[0].Status != "COMPLETED" || tasks.Results[0].ApproximateNumberOfMessagesMoved != 1 {
+		t.Fatal("Wrong tasks", tasks.Results)
+	}
+
+	// The message is back in the source queue, with its receive count reset.
+	if messages := receive(source.QueueUrl); len(messages) != 1 || messages[0].Attributes["ApproximateReceiveCount"] != "1" {
+		t.Fatal("Wrong messages", messages)
+	}
+}
+
+func TestMessageAttributes(t *testing.T) {
+	ctx := context.Background()
+	client, srv, _ := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    resp.QueueUrl,
+		MessageBody: aws.String("hello"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"count": {DataType: aws.String("Number"), StringValue: aws.String("three")},
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected error for invalid Number attribute")
+	}
+
+	const expectedMD5 = "c6315955eb2b9bbad68c3419375415fb"
+	sent, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    resp.QueueUrl,
+		MessageBody: aws.String("hello"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"count": {DataType: aws.String("Number"), StringValue: aws.String("3")},
+			"name":  {DataType: aws.String("String"), StringValue: aws.String("hello")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *sent.MD5OfMessageAttributes != expectedMD5 {
+		t.Fatal("Wrong MD5OfMessageAttributes", *sent.MD5OfMessageAttributes)
+	}
+
+	received, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              resp.QueueUrl,
+		AttributeNames:        []types.QueueAttributeName{"All"},
+		MessageAttributeNames: []string{"All"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Messages) != 1 {
+		t.Fatal("Wrong messages", received.Messages)
+	}
+	message := received.Messages[0]
+	if *message.MD5OfMessageAttributes != expectedMD5 {
+		t.Fatal("Wrong MD5OfMessageAttributes", *message.MD5OfMessageAttributes)
+	}
+	if *message.MessageAttributes["count"].StringValue != "3" || *message.MessageAttributes["name"].DataType != "String" {
+		t.Fatal("Wrong message attributes", message.MessageAttributes)
+	}
+	for _, name := range []string{"SentTimestamp", "ApproximateFirstReceiveTimestamp", "ApproximateReceiveCount", "SenderId"} {
+		if message.Attributes[name] == "" {
+			t.Fatal("Missing system attribute", name, message.Attributes)
+		}
+	}
+	if _, ok := message.Attributes["SequenceNumber"]; ok {
+		t.Fatal("Standard queue messages don't have a sequence number", message.Attributes)
+	}
+}
+
+func TestBatchesAndDelays(t *testing.T) {
+	ctx := context.Background()
+	client, srv, _ := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+		Attributes: map[string]string{
+			"DelaySeconds": "60",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: resp.QueueUrl,
+		Entries: []types.SendMessageBatchRequestEntry{
+			{Id: aws.String("a"), MessageBody: aws.String("a")},
+			{Id: aws.String("a"), MessageBody: aws.String("b")},
+		},
+	})
+	var notDistinct *types.BatchEntryIdsNotDistinct
+	if !errors.As(err, &notDistinct) {
+		t.Fatal("Expected BatchEntryIdsNotDistinct", err)
+	}
+
+	sent, err := client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: resp.QueueUrl,
+		Entries: []types.SendMessageBatchRequestEntry{
+			// Uses the queue's delay.
+			{Id: aws.String("delayed"), MessageBody: aws.String("delayed")},
+			{Id: aws.String("invalid"), MessageBody: aws.String("invalid"), DelaySeconds: 901},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent.Successful) != 1 || len(sent.Failed) != 1 || *sent.Failed[0].Id != "invalid" || !sent.Failed[0].SenderFault {
+		t.Fatal("Wrong results", sent.Successful, sent.Failed)
+	}
+
+	// The message's delay overrides the queue's.
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:     resp.QueueUrl,
+		MessageBody:  aws.String("short delay"),
+		DelaySeconds: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            resp.QueueUrl,
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Messages) != 1 || *received.Messages[0].Body != "short delay" {
+		t.Fatal("Wrong messages", received.Messages)
+	}
+
+	deleted, err := client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: resp.QueueUrl,
+		Entries: []types.DeleteMessageBatchRequestEntry{
+			{Id: aws.String("a"), ReceiptHandle: received.Messages[0].ReceiptHandle},
+			{Id: aws.String("b"), ReceiptHandle: aws.String("invalid")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted.Successful) != 1 || *deleted.Successful[0].Id != "a" {
+		t.Fatal("Wrong successful entries", deleted.Successful)
+	}
+	if len(deleted.Failed) != 1 || *deleted.Failed[0].Code != "ReceiptHandleIsInvalid" {
+		t.Fatal("Wrong failed entries", deleted.Failed)
+	}
+}
+
+func TestQueueAttributes(t *testing.T) {
+	ctx := context.Background()
+	client, srv, _ := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+
+	resp, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("queue"),
+		Attributes: map[string]string{
+			"MessageRetentionPeriod": "3600",
+		},
+		Tags: map[string]string{
+			"team": "platform",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, delay := range []int32{0, 0, 60} {
+		_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:     resp.QueueUrl,
+			MessageBody:  aws.String("hello"),
+			DelaySeconds: delay,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl: resp.QueueUrl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       resp.QueueUrl,
+		AttributeNames: []types.QueueAttributeName{"All"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{
+		"ApproximateNumberOfMessages":           "1",
+		"ApproximateNumberOfMessagesDelayed":    "1",
+		"ApproximateNumberOfMessagesNotVisible": "1",
+		"MessageRetentionPeriod":                "3600",
+		"QueueArn":                              "arn:aws:sqs:us-east-1:123456789012:queue",
+		"VisibilityTimeout":                     "30",
+	} {
+		if attributes.Attributes[name] != expected {
+			t.Fatal("Wrong attribute", name, attributes.Attributes[name])
+		}
+	}
+	if _, ok := attributes.Attributes["RedrivePolicy"]; ok {
+		t.Fatal("Unset attributes should be omitted", attributes.Attributes)
+	}
+
+	_, err = client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       resp.QueueUrl,
+		AttributeNames: []types.QueueAttributeName{"Unknown"},
+	})
+	if err == nil {
+		t.Fatal("Expected error for unknown attribute")
+	}
+
+	// Attributes are set all or nothing.
+	_, err = client.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl: resp.QueueUrl,
+		Attributes: map[string]string{
+			"DelaySeconds":      "10",
+			"VisibilityTimeout": "-1",
+		},
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InvalidAttributeValue" {
+		t.Fatal("Expected InvalidAttributeValue", err)
+	}
+	_, err = client.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl: resp.QueueUrl,
+		Attributes: map[string]string{
+			"VisibilityTimeout": "45",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes, err = client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       resp.QueueUrl,
+		AttributeNames: []types.QueueAttributeName{"DelaySeconds", "VisibilityTimeout"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attributes.Attributes) != 2 || attributes.Attributes["DelaySeconds"] != "0" || attributes.Attributes["VisibilityTimeout"] != "45" {
+		t.Fatal("Wrong attributes", attributes.Attributes)
+	}
+
+	_, err = client.TagQueue(ctx, &sqs.TagQueueInput{
+		QueueUrl: resp.QueueUrl,
+		Tags: map[string]string{
+			"env": "test",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.UntagQueue(ctx, &sqs.UntagQueueInput{
+		QueueUrl: resp.QueueUrl,
+		TagKeys:  []string{"team"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := client.ListQueueTags(ctx, &sqs.ListQueueTagsInput{
+		QueueUrl: resp.QueueUrl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags.Tags) != 1 || tags.Tags["env"] != "test" {
+		t.Fatal("Wrong tags", tags.Tags)
+	}
+
+	_, err = client.TagQueue(ctx, &sqs.TagQueueInput{
+		QueueUrl: resp.QueueUrl,
+		Tags: map[string]string{
+			"aws:reserved": "value",
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected error for reserved tag key")
+	}
+}
+
+// regionClient sends requests for the region, as the emulator's unsigned requests can't say.
+type regionClient string
+
+func (c regionClient) Do(r *http.Request) (*http.Response, error) {
+	r.Header.Set(region.Header, string(c))
+	return http.DefaultClient.Do(r)
+}
+
+func TestRegions(t *testing.T) {
+	ctx := context.Background()
+	client, srv, addr := makeClientServerPair()
+	defer srv.Shutdown(ctx)
+	europe := sqs.New(sqs.Options{
+		EndpointResolver: sqs.EndpointResolverFromURL("http://" + addr),
+		Retryer:          aws.NopRetryer{},
+		HTTPClient:       regionClient("eu-west-1"),
+	})
+
+	created, err := europe.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("queue")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes, err := europe.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       created.QueueUrl,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queueArn := attributes.Attributes["QueueArn"]; queueArn != "arn:aws:sqs:eu-west-1:123456789012:queue" {
+		t.Fatal("Wrong queue ARN", queueArn)
+	}
+
+	var doesNotExist *types.QueueDoesNotExist
+	if _, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("queue")}); !errors.As(err, &doesNotExist) {
+		t.Fatal("Expected eu-west-1's queue not to be in us-east-1", err)
+	}
+
+	nowhere := sqs.New(sqs.Options{
+		EndpointResolver: sqs.EndpointResolverFromURL("http://" + addr),
+		Retryer:          aws.NopRetryer{},
+		HTTPClient:       regionClient("xx-nowhere-1"),
+	})
+	var apiErr smithy.APIError
+	if _, err := nowhere.ListQueues(ctx, &sqs.ListQueuesInput{}); !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InvalidRegionException" {
+		t.Fatal("Expected regions which aren't enabled to fail", err)
+	}
 }
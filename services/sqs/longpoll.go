@@ -0,0 +1,33 @@
+package sqs
+
+import (
+	"fmt"
+	"time"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-short-and-long-polling.html
+
+const (
+	maxWaitTimeSeconds = 20
+	// Long polling receives also check for messages on this interval, to pick up
+	// messages whose delay or visibility timeout lapses while waiting.
+	longPollInterval = 100 * time.Millisecond
+)
+
+func parseWaitTime(seconds int) (time.Duration, *awserrors.Error) {
+	if seconds < 0 || seconds > maxWaitTimeSeconds {
+		return 0, InvalidParameterValue(fmt.Sprintf(
+			"Value %d for parameter WaitTimeSeconds is invalid. Reason: Must be >= 0 and <= %d, if provided.",
+			seconds, maxWaitTimeSeconds))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// lockedAddMessage adds the message to the queue, and wakes up any receives waiting for it.
+func (q *Queue) lockedAddMessage(message *Message) {
+	q.Messages = append(q.Messages, message)
+	close(q.messagesAdded)
+	q.messagesAdded = make(chan struct{})
+}
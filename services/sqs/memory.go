@@ -0,0 +1,27 @@
+package sqs
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each queue's messages, as counted against MaximumMessageSize.
+func (s *SQS) MemoryUsage() []memory.Resource {
+	return s.regions.MemoryUsage((*SQS).memoryUsage)
+}
+
+func (s *SQS) memoryUsage() []memory.Resource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resources []memory.Resource
+	for name, queue := range s.queuesByName {
+		resource := memory.Resource{Name: name, Count: len(queue.Messages)}
+		for _, message := range queue.Messages {
+			// The attributes were valid when the message was sent.
+			attributesSize, _ := validateMessageAttributes(message.MessageAttributes)
+			resource.Bytes += int64(len(message.Body) + attributesSize)
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
@@ -0,0 +1,220 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SetQueueAttributes.html
+
+const (
+	defaultMessageRetentionPeriod = 4 * 24 * time.Hour
+	minMessageRetentionSeconds    = 60
+	maxMessageRetentionSeconds    = 14 * 24 * 3600
+
+	defaultKmsDataKeyReusePeriod = 5 * time.Minute
+	minKmsDataKeyReuseSeconds    = 60
+	maxKmsDataKeyReuseSeconds    = 24 * 3600
+
+	maxTagsPerQueue = 50
+	maxTagKeyLength = 128
+	maxTagValueLen  = 256
+)
+
+// queueAttributeNames are all the attributes which can be requested from GetQueueAttributes.
+var queueAttributeNames = []string{
+	"ApproximateNumberOfMessages",
+	"ApproximateNumberOfMessagesDelayed",
+	"ApproximateNumberOfMessagesNotVisible",
+	"ContentBasedDeduplication",
+	"CreatedTimestamp",
+	"DeduplicationScope",
+	"DelaySeconds",
+	"FifoQueue",
+	"FifoThroughputLimit",
+	"KmsDataKeyReusePeriodSeconds",
+	"KmsMasterKeyId",
+	"LastModifiedTimestamp",
+	"MaximumMessageSize",
+	"MessageRetentionPeriod",
+	"Policy",
+	"QueueArn",
+	"ReceiveMessageWaitTimeSeconds",
+	"RedriveAllowPolicy",
+	"RedrivePolicy",
+	"SqsManagedSseEnabled",
+	"VisibilityTimeout",
+}
+
+func parseIntAttribute(name string, value string, min int, max int) (int, *awserrors.Error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, InvalidAttributeValue("Invalid value for the parameter " + name + ".")
+	}
+	return n, nil
+}
+
+func parseSecondsAttribute(name string, value string, min int, max int) (time.Duration, *awserrors.Error) {
+	seconds, err := parseIntAttribute(name, value, min, max)
+	return time.Duration(seconds) * time.Second, err
+}
+
+// lockedSetQueueAttributes applies the attributes to the queue. If any of them are invalid,
+// the queue is left unchanged.
+func (s *SQS) lockedSetQueueAttributes(queue *Queue, attributes map[string]string) *awserrors.Error {
+	updated := *queue
+	for name, value := range attributes {
+		var err *awserrors.Error
+		switch name {
+		case "DelaySeconds":
+			updated.DelayDuration, err = parseSecondsAttribute(name, value, minDelaySeconds, maxDelaySeconds)
+		case "MaximumMessageSize":
+			updated.MaximumMessageSize, err = parseIntAttribute(name, value, minMaximumMessageSize, maxMaximumMessageSize)
+		case "MessageRetentionPeriod":
+			updated.MessageRetentionPeriod, err = parseSecondsAttribute(
+				name, value, minMessageRetentionSeconds, maxMessageRetentionSeconds)
+		case "ReceiveMessageWaitTimeSeconds":
+			updated.ReceiveMessageWaitTime, err = parseSecondsAttribute(name, value, 0, maxWaitTimeSeconds)
+		case "VisibilityTimeout":
+			updated.VisibilityTimeout, err = parseSecondsAttribute(name, value, 0, maxVisibilityTimeoutSeconds)
+		case "KmsDataKeyReusePeriodSeconds":
+			updated.KmsDataKeyReusePeriod, err = parseSecondsAttribute(
+				name, value, minKmsDataKeyReuseSeconds, maxKmsDataKeyReuseSeconds)
+		case "KmsMasterKeyId":
+			updated.KmsMasterKeyId = value
+		case "SqsManagedSseEnabled":
+			enabled, parseErr := strconv.ParseBool(value)
+			if parseErr != nil {
+				err = InvalidAttributeValue("Invalid value for the parameter SqsManagedSseEnabled.")
+			}
+			updated.SqsManagedSseEnabled = enabled
+		case "Policy":
+			if value != "" && !json.Valid([]byte(value)) {
+				err = InvalidAttributeValue("Invalid value for the parameter Policy.")
+			}
+			updated.Policy = value
+		case "RedrivePolicy":
+			updated.RedrivePolicy, err = s.lockedParseRedrivePolicy(queue, value)
+		case "RedriveAllowPolicy":
+			updated.RedriveAllowPolicy, err = parseRedriveAllowPolicy(value)
+		case "FifoQueue", "ContentBasedDeduplication", "DeduplicationScope", "FifoThroughputLimit":
+			err = updated.setFifoAttribute(name, value)
+		default:
+			err = InvalidAttributeName("Unknown Attribute " + name + ".")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// SQS managed encryption is turned off by using a KMS key.
+	if _, ok := attributes["KmsMasterKeyId"]; ok && updated.KmsMasterKeyId != "" {
+		if attributes["SqsManagedSseEnabled"] == "true" {
+			return InvalidAttributeValue("You can use one type of server-side encryption (SSE) at one time.")
+		}
+		updated.SqsManagedSseEnabled = false
+	}
+	if updated.Fifo {
+		if err := updated.validateFifoAttributes(); err != nil {
+			return err
+		}
+	}
+
+	*queue = updated
+	return nil
+}
+
+// lockedGetQueueAttributes returns all the queue's attributes which have a value.
+func (s *SQS) lockedGetQueueAttributes(queue *Queue) map[string]string {
+	seconds := func(d time.Duration) string {
+		return strconv.Itoa(int(d / time.Second))
+	}
+
+	now := s.clock()
+	var visible, delayed, notVisible int
+	for _, message := range queue.Messages {
+		if message.DelayedUntil.After(now) {
+			delayed++
+		} else if message.VisibleAt.After(now) {
+			notVisible++
+		} else {
+			visible++
+		}
+	}
+
+	attributes := map[string]string{
+		"ApproximateNumberOfMessages":           strconv.Itoa(visible),
+		"ApproximateNumberOfMessagesDelayed":    strconv.Itoa(delayed),
+		"ApproximateNumberOfMessagesNotVisible": strconv.Itoa(notVisible),
+		"CreatedTimestamp":                      strconv.FormatInt(queue.CreationTimestamp, 10),
+		"DelaySeconds":                          seconds(queue.DelayDuration),
+		"LastModifiedTimestamp":                 strconv.FormatInt(queue.LastModifiedTimestamp, 10),
+		"MaximumMessageSize":                    strconv.Itoa(queue.MaximumMessageSize),
+		"MessageRetentionPeriod":                seconds(queue.MessageRetentionPeriod),
+		"QueueArn":                              queue.ARN,
+		"ReceiveMessageWaitTimeSeconds":         seconds(queue.ReceiveMessageWaitTime),
+		"SqsManagedSseEnabled":                  strconv.FormatBool(queue.SqsManagedSseEnabled),
+		"VisibilityTimeout":                     seconds(queue.VisibilityTimeout),
+	}
+	if queue.KmsMasterKeyId != "" {
+		attributes["KmsMasterKeyId"] = queue.KmsMasterKeyId
+		attributes["KmsDataKeyReusePeriodSeconds"] = seconds(queue.KmsDataKeyReusePeriod)
+	}
+	if queue.Policy != "" {
+		attributes["Policy"] = queue.Policy
+	}
+	if queue.RedrivePolicy != nil {
+		policy, _ := json.Marshal(queue.RedrivePolicy)
+		attributes["RedrivePolicy"] = string(policy)
+	}
+	if queue.RedriveAllowPolicy != nil {
+		policy, _ := json.Marshal(queue.RedriveAllowPolicy)
+		attributes["RedriveAllowPolicy"] = string(policy)
+	}
+	if queue.Fifo {
+		attributes["FifoQueue"] = "true"
+		attributes["ContentBasedDeduplication"] = strconv.FormatBool(queue.ContentBasedDeduplication)
+		attributes["DeduplicationScope"] = queue.DeduplicationScope
+		attributes["FifoThroughputLimit"] = queue.FifoThroughputLimit
+	}
+	return attributes
+}
+
+// lockedExpireMessages deletes messages which have been in the queue for longer than its
+// retention period.
+func (q *Queue) lockedExpireMessages(now time.Time) {
+	expiry := now.Add(-q.MessageRetentionPeriod).UnixMilli()
+	q.Messages = slices.DeleteFunc(q.Messages, func(message *Message) bool {
+		return message.SentTimestamp <= expiry
+	})
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-queue-tags.html
+func validateTags(existing map[string]string, tags map[string]string) *awserrors.Error {
+	count := len(existing)
+	for key, value := range tags {
+		if key == "" || utf8.RuneCountInString(key) > maxTagKeyLength {
+			return InvalidParameterValue(fmt.Sprintf("Tag key must be between 1 and %d characters long.", maxTagKeyLength))
+		}
+		if utf8.RuneCountInString(value) > maxTagValueLen {
+			return InvalidParameterValue(fmt.Sprintf("Tag value must be at most %d characters long.", maxTagValueLen))
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return InvalidParameterValue("Tag keys can't start with the reserved prefix aws:.")
+		}
+		if _, ok := existing[key]; !ok {
+			count++
+		}
+	}
+	if count > maxTagsPerQueue {
+		return InvalidParameterValue(fmt.Sprintf("Too many tags added for queue. Queues can have at most %d tags.", maxTagsPerQueue))
+	}
+	return nil
+}
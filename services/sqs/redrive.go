@@ -0,0 +1,286 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-dead-letter-queues.html
+
+const (
+	minMaxReceiveCount = 1
+	maxMaxReceiveCount = 1000
+
+	maxListMessageMoveTasksResults = 10
+
+	maxRedriveAllowPolicySourceQueues = 10
+)
+
+type RedrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     int    `json:"maxReceiveCount"`
+}
+
+// RedriveAllowPolicy controls which source queues can use a queue as their dead-letter queue.
+type RedriveAllowPolicy struct {
+	RedrivePermission string   `json:"redrivePermission"`
+	SourceQueueArns   []string `json:"sourceQueueArns,omitempty"`
+}
+
+func (p *RedriveAllowPolicy) allows(sourceQueueArn string) bool {
+	switch p.RedrivePermission {
+	case "denyAll":
+		return false
+	case "byQueue":
+		return slices.Contains(p.SourceQueueArns, sourceQueueArn)
+	}
+	return true
+}
+
+type MessageMoveTask struct {
+	TaskHandle                        string
+	SourceArn                         string
+	DestinationArn                    string
+	MaxNumberOfMessagesPerSecond      int
+	Status                            string
+	StartedTimestamp                  int64
+	ApproximateNumberOfMessagesMoved  int64
+	ApproximateNumberOfMessagesToMove int64
+}
+
+// The queue name is the resource of an SQS ARN.
+func (s *SQS) lockedGetQueueByArn(queueArn string) (*Queue, bool) {
+	a, err := arn.Parse(queueArn)
+	if err != nil {
+		return nil, false
+	}
+	queue, ok := s.queuesByName[a.ResourceId()]
+	if !ok || queue.ARN != queueArn {
+		return nil, false
+	}
+	return queue, true
+}
+
+func (s *SQS) lockedParseRedrivePolicy(queue *Queue, value string) (*RedrivePolicy, *awserrors.Error) {
+	invalid := func(reason string) *awserrors.Error {
+		return InvalidParameterValue(fmt.Sprintf(
+			"Value %s for parameter RedrivePolicy is invalid. Reason: %s", value, reason))
+	}
+
+	// An empty policy removes the dead-letter queue.
+	if value == "" {
+		return nil, nil
+	}
+
+	// maxReceiveCount may be given as either a string or a number.
+	var raw struct {
+		DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+	}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, invalid("Redrive policy is not a valid JSON map.")
+	}
+	if raw.DeadLetterTargetArn == "" {
+		return nil, invalid("Redrive policy does not contain mandatory attribute: deadLetterTargetArn.")
+	}
+	if raw.MaxReceiveCount == "" {
+		return nil, invalid("Redrive policy does not contain mandatory attribute: maxReceiveCount.")
+	}
+	maxReceiveCount, err := strconv.Atoi(string(raw.MaxReceiveCount))
+	if err != nil || maxReceiveCount < minMaxReceiveCount || maxReceiveCount > maxMaxReceiveCount {
+		return nil, invalid(fmt.Sprintf(
+			"Invalid value for maxReceiveCount: %s, valid values are from %d to %d both inclusive.",
+			raw.MaxReceiveCount, minMaxReceiveCount, maxMaxReceiveCount))
+	}
+
+	deadLetterQueue, ok := s.lockedGetQueueByArn(raw.DeadLetterTargetArn)
+	if !ok {
+		return nil, invalid("Dead letter target does not exist.")
+	}
+	if deadLetterQueue == queue {
+		return nil, invalid("Dead letter target can not be the queue itself.")
+	}
+	if deadLetterQueue.Fifo != queue.Fifo {
+		return nil, invalid("Dead-letter queue must be the same type of queue as the source.")
+	}
+	if deadLetterQueue.RedriveAllowPolicy != nil && !deadLetterQueue.RedriveAllowPolicy.allows(queue.ARN) {
+		return nil, invalid("Queue " + queue.ARN + " is not allowed to use the dead letter target.")
+	}
+
+	return &RedrivePolicy{
+		DeadLetterTargetArn: raw.DeadLetterTargetArn,
+		MaxReceiveCount:     maxReceiveCount,
+	}, nil
+}
+
+func parseRedriveAllowPolicy(value string) (*RedriveAllowPolicy, *awserrors.Error) {
+	invalid := func(reason string) *awserrors.Error {
+		return InvalidParameterValue(fmt.Sprintf(
+			"Value %s for parameter RedriveAllowPolicy is invalid. Reason: %s", value, reason))
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+
+	var policy RedriveAllowPolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return nil, invalid("Redrive allow policy is not a valid JSON map.")
+	}
+	switch policy.RedrivePermission {
+	case "allowAll", "denyAll":
+		if len(policy.SourceQueueArns) > 0 {
+			return nil, invalid("sourceQueueArns can only be specified when redrivePermission is byQueue.")
+		}
+	case "byQueue":
+		if len(policy.SourceQueueArns) == 0 || len(policy.SourceQueueArns) > maxRedriveAllowPolicySourceQueues {
+			return nil, invalid(fmt.Sprintf(
+				"sourceQueueArns must contain between 1 and %d queues when redrivePermission is byQueue.",
+				maxRedriveAllowPolicySourceQueues))
+		}
+	default:
+		return nil, invalid("Invalid value for redrivePermission.")
+	}
+	return &policy, nil
+}
+
+// lockedMoveToDeadLetterQueue moves the message to the queue's dead-letter queue if it has been
+// received too many times, and returns whether it was moved.
+func (s *SQS) lockedMoveToDeadLetterQueue(queue *Queue, message *Message) bool {
+	if queue.RedrivePolicy == nil || message.ReceiveCount < queue.RedrivePolicy.MaxReceiveCount {
+		return false
+	}
+	// If the dead-letter queue has been deleted, the message stays where it is.
+	deadLetterQueue, ok := s.lockedGetQueueByArn(queue.RedrivePolicy.DeadLetterTargetArn)
+	if !ok {
+		return false
+	}
+
+	message.DeadLetterQueueSourceArn = queue.ARN
+	message.ReceiptHandle = ""
+	deadLetterQueue.lockedAddMessage(message)
+	return true
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_StartMessageMoveTask.html
+func (s *SQS) StartMessageMoveTask(input StartMessageMoveTaskInput) (*StartMessageMoveTaskOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, ok := s.lockedGetQueueByArn(input.SourceArn)
+	if !ok {
+		return nil, ResourceNotFoundException("The resource that you specified for the SourceArn parameter doesn't exist.")
+	}
+	isDeadLetterQueue := false
+	for _, queue := range s.queuesByName {
+		if queue.RedrivePolicy != nil && queue.RedrivePolicy.DeadLetterTargetArn == source.ARN {
+			isDeadLetterQueue = true
+			break
+		}
+	}
+	if !isDeadLetterQueue {
+		return nil, InvalidParameterValue("Source queue must be configured as a Dead Letter Queue.")
+	}
+
+	var destination *Queue
+	if input.DestinationArn != "" {
+		destination, ok = s.lockedGetQueueByArn(input.DestinationArn)
+		if !ok {
+			return nil, ResourceNotFoundException("The resource that you specified for the DestinationArn parameter doesn't exist.")
+		}
+	}
+
+	now := s.clock()
+	task := &MessageMoveTask{
+		TaskHandle:                   uuid.Must(uuid.NewV4()).String(),
+		SourceArn:                    source.ARN,
+		DestinationArn:               input.DestinationArn,
+		MaxNumberOfMessagesPerSecond: input.MaxNumberOfMessagesPerSecond,
+		StartedTimestamp:             now.UnixMilli(),
+	}
+
+	// Tasks run to completion immediately. Messages go back to the queue they came from,
+	// unless a destination was given. Messages which are in flight are left behind.
+	var moved []*Message
+	for _, message := range source.Messages {
+		if message.VisibleAt.After(now) {
+			continue
+		}
+		target := destination
+		if target == nil {
+			target, ok = s.lockedGetQueueByArn(message.DeadLetterQueueSourceArn)
+			if !ok {
+				continue
+			}
+		}
+
+		moved = append(moved, message)
+		message.ReceiveCount = 0
+		message.ReceiptHandle = ""
+		message.DeadLetterQueueSourceArn = ""
+		target.lockedAddMessage(message)
+	}
+	source.Messages = slices.DeleteFunc(source.Messages, func(message *Message) bool {
+		return slices.Contains(moved, message)
+	})
+
+	task.Status = "COMPLETED"
+	task.ApproximateNumberOfMessagesMoved = int64(len(moved))
+	task.ApproximateNumberOfMessagesToMove = int64(len(moved))
+	source.messageMoveTasks = append(source.messageMoveTasks, task)
+
+	return &StartMessageMoveTaskOutput{
+		TaskHandle: task.TaskHandle,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ListMessageMoveTasks.html
+func (s *SQS) ListMessageMoveTasks(input ListMessageMoveTasksInput) (*ListMessageMoveTasksOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if input.MaxResults == 0 {
+		input.MaxResults = 1
+	}
+	if input.MaxResults < 1 || input.MaxResults > maxListMessageMoveTasksResults {
+		return nil, InvalidParameterValue(fmt.Sprintf(
+			"Value %d for parameter MaxResults is invalid. Reason: Must be between 1 and %d, if provided.",
+			input.MaxResults, maxListMessageMoveTasksResults))
+	}
+
+	source, ok := s.lockedGetQueueByArn(input.SourceArn)
+	if !ok {
+		return nil, ResourceNotFoundException("The resource that you specified for the SourceArn parameter doesn't exist.")
+	}
+
+	// The most recent tasks come first.
+	output := &ListMessageMoveTasksOutput{
+		Results: []ListMessageMoveTasksResultEntry{},
+	}
+	for i := len(source.messageMoveTasks) - 1; i >= 0 && len(output.Results) < input.MaxResults; i-- {
+		task := source.messageMoveTasks[i]
+		entry := ListMessageMoveTasksResultEntry{
+			ApproximateNumberOfMessagesMoved:  task.ApproximateNumberOfMessagesMoved,
+			ApproximateNumberOfMessagesToMove: task.ApproximateNumberOfMessagesToMove,
+			DestinationArn:                    task.DestinationArn,
+			MaxNumberOfMessagesPerSecond:      task.MaxNumberOfMessagesPerSecond,
+			SourceArn:                         task.SourceArn,
+			StartedTimestamp:                  task.StartedTimestamp,
+			Status:                            task.Status,
+		}
+		// Only running tasks have a handle, since it's only used to cancel them.
+		if task.Status == "RUNNING" {
+			entry.TaskHandle = task.TaskHandle
+		}
+		output.Results = append(output.Results, entry)
+	}
+
+	return output, nil
+}
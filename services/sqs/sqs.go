@@ -1,14 +1,13 @@
 package sqs
 
 import (
-	"bytes"
 	"crypto/md5"
-	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"log/slog"
 	"maps"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +16,8 @@ import (
 
 	"aws-in-a-box/arn"
 	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
 )
 
 const (
@@ -31,13 +32,11 @@ const (
 	minDelaySeconds      = 0
 	maxDelaySeconds      = 900
 
-	maxEntriesInDeleteBatch = 10
-	maxBatchEntryIdLength   = 80
+	maxMessagesPerReceive = 10
+	purgeInterval         = 60 * time.Second
 )
 
-var (
-	batchEntryIdRegex = regexp.MustCompile("[a-zA-Z0-9_-]+")
-)
+var queueNameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]{1,80}$")
 
 type Message struct {
 	UUID uuid.UUID
@@ -45,41 +44,92 @@ type Message struct {
 	Body              string
 	MD5OfBody         string
 	MessageAttributes map[string]APIAttribute
-	// TODO: is this how we want to store it?
+	// Only AWSTraceHeader can be set.
 	MessageSystemAttributes map[string]APIAttribute
 
-	Deleted      bool
+	// Milliseconds since the epoch.
+	SentTimestamp         int64
+	FirstReceiveTimestamp int64
+
 	VisibleAt    time.Time
 	DelayedUntil time.Time
+	// The handle from the latest receipt of the message.
+	ReceiptHandle string
+	ReceiveCount  int
+	// Set once the message has been moved to a dead-letter queue.
+	DeadLetterQueueSourceArn string
+
+	// Only set for FIFO queues.
+	MessageGroupId         string
+	MessageDeduplicationId string
+	SequenceNumber         string
 }
 
 type Queue struct {
 	// Immutable
 	CreationTimestamp int64
 	Attributes        map[string]string
+	Name              string
+	ARN               string
 	URL               string
 
 	// Mutable
-	Messages []*Message
-	Tags     map[string]string
+	LastModifiedTimestamp int64
+	Messages              []*Message
+	Tags                  map[string]string
+	// Zero if the queue has never been purged.
+	LastPurged time.Time
+
+	RedrivePolicy      *RedrivePolicy
+	RedriveAllowPolicy *RedriveAllowPolicy
+	messageMoveTasks   []*MessageMoveTask
+
+	// Closed and replaced whenever a message is added, to wake up long polling receives.
+	messagesAdded chan struct{}
 
 	// Attributes
-	VisibilityTimeout  time.Duration
-	MaximumMessageSize int
-	DelayDuration      time.Duration
+	VisibilityTimeout      time.Duration
+	MaximumMessageSize     int
+	DelayDuration          time.Duration
+	ReceiveMessageWaitTime time.Duration
+	MessageRetentionPeriod time.Duration
+	Policy                 string
+	KmsMasterKeyId         string
+	KmsDataKeyReusePeriod  time.Duration
+	SqsManagedSseEnabled   bool
+
+	// FIFO queues only
+	Fifo                      bool
+	ContentBasedDeduplication bool
+	DeduplicationScope        string
+	FifoThroughputLimit       string
+
+	lastSequenceNumber        int64
+	deduplicationEntries      []*deduplicationEntry
+	deduplicationEntriesByKey map[string]*deduplicationEntry
 }
 
 type SQS struct {
 	logger       *slog.Logger
 	arnGenerator arn.Generator
+	// We need the address to generate queue URLs.
+	addr string
+	// Overridden in tests.
+	clock func() time.Time
 
 	mu           sync.Mutex
 	queuesByName map[string]*Queue
+	// Shared by the instances of all regions, which each have their own queues.
+	regions *region.Regions[*SQS]
 }
 
 type Options struct {
 	Logger       *slog.Logger
 	ArnGenerator arn.Generator
+	Addr         string
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
 }
 
 func New(options Options) *SQS {
@@ -87,44 +137,103 @@ func New(options Options) *SQS {
 		options.Logger = slog.Default()
 	}
 
-	s := &SQS{
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*SQS, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *SQS {
+	return &SQS{
 		logger:       options.Logger,
 		arnGenerator: options.ArnGenerator,
+		addr:         options.Addr,
+		clock:        clock.Now,
 		queuesByName: make(map[string]*Queue),
 	}
+}
 
-	return s
+// InRegion returns the queues of the region, for services which use queues by URL. It fails if the
+// region isn't one of Options.Regions.
+func (s *SQS) InRegion(name string) (*SQS, error) {
+	return s.regions.Get(name)
+}
+
+// ForARN returns the queues of the region the queue ARN is in.
+func (s *SQS) ForARN(queueArn string) (*SQS, error) {
+	return s.regions.ForARN(queueArn)
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_CreateQueue.html
 func (s *SQS) CreateQueue(input CreateQueueInput) (*CreateQueueOutput, *awserrors.Error) {
+	fifo := input.Attributes["FifoQueue"] == "true"
+	if fifo && !fifoQueueNameRegex.MatchString(input.QueueName) {
+		return nil, InvalidParameterValue(
+			"The name of a FIFO queue can only include alphanumeric characters, hyphens, or underscores, must end with .fifo suffix and be 1 to 80 in length.")
+	}
+	if !fifo && !queueNameRegex.MatchString(input.QueueName) {
+		return nil, InvalidParameterValue(
+			"Can only include alphanumeric characters, hyphens, or underscores. 1 to 80 in length")
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if queue, ok := s.queuesByName[input.QueueName]; ok {
-		if maps.Equal(queue.Attributes, input.Attribute) {
+		if maps.Equal(queue.Attributes, input.Attributes) {
 			return &CreateQueueOutput{
 				QueueUrl: queue.URL,
 			}, nil
 		}
-		return nil, QueueNameExists("")
+		return nil, QueueNameExists("A queue already exists with the same name and a different value for attribute(s)")
 	}
 
 	url := s.getQueueUrl(input.QueueName)
+	tags := make(map[string]string)
+	awserr := validateTags(tags, input.Tags)
+	if awserr != nil {
+		return nil, awserr
+	}
+	for k, v := range input.Tags {
+		tags[k] = v
+	}
 
+	now := s.clock()
 	queue := &Queue{
-		Attributes: input.Attribute,
-		Tags:       input.Tag,
-		URL:        url,
+		CreationTimestamp:     now.Unix(),
+		LastModifiedTimestamp: now.Unix(),
+		Attributes:            input.Attributes,
+		Name:                  input.QueueName,
+		ARN:                   s.arnGenerator.GenerateWithoutType("sqs", input.QueueName),
+		Tags:                  tags,
+		URL:                   url,
 
-		VisibilityTimeout:  defaultVisibilityTimeout,
-		MaximumMessageSize: defaultMaximumMessageSize,
-		DelayDuration:      defaultDelayDuration,
+		messagesAdded: make(chan struct{}),
+
+		VisibilityTimeout:      defaultVisibilityTimeout,
+		MaximumMessageSize:     defaultMaximumMessageSize,
+		DelayDuration:          defaultDelayDuration,
+		MessageRetentionPeriod: defaultMessageRetentionPeriod,
+		KmsDataKeyReusePeriod:  defaultKmsDataKeyReusePeriod,
+		SqsManagedSseEnabled:   true,
+	}
+	if fifo {
+		queue.Fifo = true
+		queue.DeduplicationScope = "queue"
+		queue.FifoThroughputLimit = "perQueue"
+		queue.deduplicationEntriesByKey = make(map[string]*deduplicationEntry)
 	}
 
-	err := s.lockedSetQueueAttributes(queue, input.Attribute)
-	if err != nil {
-		return nil, err
+	awserr = s.lockedSetQueueAttributes(queue, input.Attributes)
+	if awserr != nil {
+		return nil, awserr
 	}
 
 	s.queuesByName[input.QueueName] = queue
@@ -139,26 +248,34 @@ func (s *SQS) DeleteQueue(input DeleteQueueInput) (*DeleteQueueOutput, *awserror
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queueName := s.getQueueName(input.QueueUrl)
-	if _, ok := s.queuesByName[queueName]; !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	delete(s.queuesByName, queueName)
+	delete(s.queuesByName, queue.Name)
 
-	return nil, nil
+	return &DeleteQueueOutput{}, nil
 }
 
+// Queue URLs have the form http://<addr>/<account>/<name>.
 func (s *SQS) getQueueUrl(queueName string) string {
-	// TODO: We should make these not match to catch mistakes.
-	// But this is expedient for now.
-	return queueName
+	return fmt.Sprintf("http://%s/%s/%s", s.addr, s.arnGenerator.AwsAccountId, queueName)
 }
 
 func (s *SQS) getQueueName(queueUrl string) string {
-	// TODO: We should make these not match to catch mistakes.
-	// But this is expedient for now.
-	return queueUrl
+	// Clients may use a different address for the server than the one in the URL,
+	// so only the name is used to look up the queue.
+	return queueUrl[strings.LastIndex(queueUrl, "/")+1:]
+}
+
+func (s *SQS) lockedGetQueue(queueUrl string) (*Queue, *awserrors.Error) {
+	queue, ok := s.queuesByName[s.getQueueName(queueUrl)]
+	if !ok {
+		return nil, QueueDoesNotExist("The specified queue does not exist.")
+	}
+	queue.lockedExpireMessages(s.clock())
+	return queue, nil
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SendMessage.html
@@ -166,44 +283,140 @@ func (s *SQS) SendMessage(input SendMessageInput) (*SendMessageOutput, *awserror
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	return s.lockedSendMessage(queue, input)
+}
+
+// SendMessageToQueueArn sends a message to the queue with the given ARN, in the ARN's region,
+// for services which deliver messages to SQS.
+func (s *SQS) SendMessageToQueueArn(queueArn string, input SendMessageInput) (*SendMessageOutput, *awserrors.Error) {
+	queues, err := s.regions.ForARN(queueArn)
+	if err != nil {
+		return nil, QueueDoesNotExist("The specified queue does not exist.")
+	}
+	queues.mu.Lock()
+	defer queues.mu.Unlock()
+
+	queue, ok := queues.lockedGetQueueByArn(queueArn)
 	if !ok {
-		return nil, QueueDoesNotExist("")
+		return nil, QueueDoesNotExist("The specified queue does not exist.")
 	}
+	queue.lockedExpireMessages(queues.clock())
 
-	for name := range input.MessageSystemAttributes {
+	return queues.lockedSendMessage(queue, input)
+}
+
+func (s *SQS) lockedSendMessage(queue *Queue, input SendMessageInput) (*SendMessageOutput, *awserrors.Error) {
+	if input.MessageBody == "" {
+		return nil, MissingParameter("The request must contain the parameter MessageBody.")
+	}
+	if !validMessageBody(input.MessageBody) {
+		return nil, InvalidMessageContents("Invalid characters found. Valid unicode characters are #x9 | #xA | #xD | #x20 to #xD7FF | #xE000 to #xFFFD | #x10000 to #x10FFFF")
+	}
+
+	for name, attribute := range input.MessageSystemAttributes {
 		if name != AWSTraceHeaderAttributeName {
-			return nil, ValidationException("Bad MessageSystemAttribute")
+			return nil, InvalidParameterValue(fmt.Sprintf("Message system attribute name '%s' is invalid.", name))
+		}
+		if attribute.DataType != "String" || attribute.StringValue == "" {
+			return nil, InvalidParameterValue(fmt.Sprintf(
+				"The message system attribute '%s' must contain a non-empty String value.", name))
 		}
 	}
+	attributesSize, awserr := validateMessageAttributes(input.MessageAttributes)
+	if awserr != nil {
+		return nil, awserr
+	}
 
-	if len(input.MessageBody) > queue.MaximumMessageSize {
-		return nil, ValidationException("Message too long")
+	if len(input.MessageBody)+attributesSize > queue.MaximumMessageSize {
+		return nil, InvalidParameterValue(fmt.Sprintf(
+			"One or more parameters are invalid. Reason: Message must be shorter than %d bytes.", queue.MaximumMessageSize))
 	}
 
-	delayDuration := time.Duration(input.DelaySeconds)
-	if delayDuration == 0 {
-		delayDuration = queue.DelayDuration
+	// The queue's delay applies unless the message has its own.
+	delayDuration := queue.DelayDuration
+	delaySeconds := 0
+	if input.DelaySeconds != nil {
+		delaySeconds = *input.DelaySeconds
+		if delaySeconds < minDelaySeconds || delaySeconds > maxDelaySeconds {
+			return nil, InvalidParameterValue(fmt.Sprintf(
+				"Value %d for parameter DelaySeconds is invalid. Reason: must be between %d and %d, if provided.",
+				delaySeconds, minDelaySeconds, maxDelaySeconds))
+		}
+		delayDuration = time.Duration(delaySeconds) * time.Second
 	}
 
-	now := time.Now()
+	deduplicationId, awserr := queue.validateFifoParameters(
+		input.MessageGroupId, input.MessageDeduplicationId, delaySeconds, input.MessageBody)
+	if awserr != nil {
+		return nil, awserr
+	}
+	deduplicationKey := queue.deduplicationKey(input.MessageGroupId, deduplicationId)
+
+	now := s.clock()
 
 	MD5OfBody := hexMD5([]byte(input.MessageBody))
-	queue.Messages = append(queue.Messages, &Message{
+	MD5OfMessageAttributes := md5OfMessageAttributes(input.MessageAttributes)
+	MD5OfMessageSystemAttributes := md5OfMessageAttributes(input.MessageSystemAttributes)
+	if queue.Fifo {
+		queue.lockedExpireDeduplicationIds(now)
+		if entry, ok := queue.deduplicationEntriesByKey[deduplicationKey]; ok {
+			// Duplicates are accepted, but not delivered again.
+			return &SendMessageOutput{
+				MD5OfMessageAttributes:       MD5OfMessageAttributes,
+				MD5OfMessageBody:             MD5OfBody,
+				MD5OfMessageSystemAttributes: MD5OfMessageSystemAttributes,
+				MessageId:                    entry.messageId,
+				SequenceNumber:               entry.sequenceNumber,
+			}, nil
+		}
+	}
+
+	message := &Message{
 		UUID:                    uuid.Must(uuid.NewV4()),
 		Body:                    input.MessageBody,
 		MD5OfBody:               MD5OfBody,
 		MessageAttributes:       input.MessageAttributes,
 		MessageSystemAttributes: input.MessageSystemAttributes,
+		SentTimestamp:           now.UnixMilli(),
 		VisibleAt:               now,
 		DelayedUntil:            now.Add(delayDuration),
-	})
+		MessageGroupId:          input.MessageGroupId,
+		MessageDeduplicationId:  deduplicationId,
+	}
+	if queue.Fifo {
+		queue.lockedRecordFifoMessage(message, deduplicationKey, now)
+	}
+	queue.lockedAddMessage(message)
 
 	return &SendMessageOutput{
-		MD5OfMessageBody: MD5OfBody,
+		MD5OfMessageAttributes:       MD5OfMessageAttributes,
+		MD5OfMessageBody:             MD5OfBody,
+		MD5OfMessageSystemAttributes: MD5OfMessageSystemAttributes,
+		MessageId:                    message.UUID.String(),
+		SequenceNumber:               message.SequenceNumber,
 	}, nil
 }
 
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SendMessage.html#SQS-SendMessage-request-MessageBody
+func validMessageBody(body string) bool {
+	for _, r := range body {
+		switch {
+		case r == 0x9 || r == 0xA || r == 0xD:
+		case r >= 0x20 && r <= 0xD7FF:
+		case r >= 0xE000 && r <= 0xFFFD:
+		case r >= 0x10000 && r <= 0x10FFFF:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func hexMD5(data []byte) string {
 	hash := md5.Sum(data)
 	return hex.EncodeToString(hash[:])
@@ -214,16 +427,20 @@ func (s *SQS) TagQueue(input TagQueueInput) (*TagQueueOutput, *awserrors.Error)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
+	awserr = validateTags(queue.Tags, input.Tags)
+	if awserr != nil {
+		return nil, awserr
+	}
 	for k, v := range input.Tags {
 		queue.Tags[k] = v
 	}
 
-	return nil, nil
+	return &TagQueueOutput{}, nil
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_UntagQueue.html
@@ -231,16 +448,16 @@ func (s *SQS) UntagQueue(input UntagQueueInput) (*UntagQueueOutput, *awserrors.E
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
 	for _, key := range input.TagKeys {
 		delete(queue.Tags, key)
 	}
 
-	return nil, nil
+	return &UntagQueueOutput{}, nil
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_GetQueueUrl.html
@@ -248,8 +465,13 @@ func (s *SQS) GetQueueUrl(input GetQueueUrlInput) (*GetQueueUrlOutput, *awserror
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	queue, ok := s.queuesByName[input.QueueName]
+	if !ok {
+		return nil, QueueDoesNotExist("The specified queue does not exist.")
+	}
+
 	return &GetQueueUrlOutput{
-		QueueUrl: s.getQueueUrl(input.QueueName),
+		QueueUrl: queue.URL,
 	}, nil
 }
 
@@ -282,14 +504,27 @@ func (s *SQS) GetQueueAttributes(input GetQueueAttributesInput) (*GetQueueAttrib
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	output := &GetQueueAttributesOutput{}
-	for _, name := range input.attributeNames {
-		output.Attributes[name] = queue.Attributes[name]
+	attributes := s.lockedGetQueueAttributes(queue)
+	output := &GetQueueAttributesOutput{
+		Attributes: make(map[string]string),
+	}
+	for _, name := range input.AttributeNames {
+		if name == "All" {
+			output.Attributes = attributes
+			break
+		}
+		if !slices.Contains(queueAttributeNames, name) {
+			return nil, InvalidAttributeName("Unknown Attribute " + name + ".")
+		}
+		// Optional attributes are omitted if they aren't set.
+		if value, ok := attributes[name]; ok {
+			output.Attributes[name] = value
+		}
 	}
 
 	return output, nil
@@ -300,9 +535,9 @@ func (s *SQS) ListQueueTags(input ListQueueTagsInput) (*ListQueueTagsOutput, *aw
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
 	return &ListQueueTagsOutput{
@@ -316,73 +551,125 @@ func (s *SQS) ReceiveMessage(input ReceiveMessageInput) (*ReceiveMessageOutput,
 	defer s.mu.Unlock()
 
 	if input.MaxNumberOfMessages == 0 {
-		input.MaxNumberOfMessages = 10
+		input.MaxNumberOfMessages = 1
 	}
-	if input.MaxNumberOfMessages < 1 || input.MaxNumberOfMessages > 10 {
-		return nil, ValidationException("")
+	if input.MaxNumberOfMessages < 1 || input.MaxNumberOfMessages > maxMessagesPerReceive {
+		return nil, InvalidParameterValue(fmt.Sprintf(
+			"Value %d for parameter MaxNumberOfMessages is invalid. Reason: Must be between 1 and %d, if provided.",
+			input.MaxNumberOfMessages, maxMessagesPerReceive))
 	}
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	now := time.Now()
+	visibilityTimeout := queue.VisibilityTimeout
+	if input.VisibilityTimeout != nil {
+		visibilityTimeout, awserr = parseVisibilityTimeout(*input.VisibilityTimeout)
+		if awserr != nil {
+			return nil, awserr
+		}
+	}
 
-	visibilityTimeout := time.Second * time.Duration(input.VisibilityTimeout)
-	if visibilityTimeout == 0 {
-		visibilityTimeout = queue.VisibilityTimeout
+	waitTime := queue.ReceiveMessageWaitTime
+	if input.WaitTimeSeconds != nil {
+		waitTime, awserr = parseWaitTime(*input.WaitTimeSeconds)
+		if awserr != nil {
+			return nil, awserr
+		}
 	}
 
+	output := s.lockedReceiveMessages(queue, input, visibilityTimeout)
+	if len(output.Messages) > 0 || waitTime == 0 {
+		return output, nil
+	}
+
+	// Long polling: wait until a message is received or the wait time lapses.
+	timer := time.NewTimer(waitTime)
+	defer timer.Stop()
+	for {
+		messagesAdded := queue.messagesAdded
+		s.mu.Unlock()
+		timedOut := false
+		select {
+		case <-messagesAdded:
+		case <-time.After(longPollInterval):
+		case <-timer.C:
+			timedOut = true
+		}
+		s.mu.Lock()
+
+		// The queue may have been deleted while we were waiting.
+		queue, awserr = s.lockedGetQueue(input.QueueUrl)
+		if awserr != nil {
+			return nil, awserr
+		}
+		output = s.lockedReceiveMessages(queue, input, visibilityTimeout)
+		if len(output.Messages) > 0 || timedOut {
+			return output, nil
+		}
+	}
+}
+
+func (s *SQS) lockedReceiveMessages(queue *Queue, input ReceiveMessageInput, visibilityTimeout time.Duration) *ReceiveMessageOutput {
+	now := s.clock()
+
+	// Messages in a FIFO queue are delivered in order within each message group, so groups
+	// with an earlier message which is in flight or delayed are skipped.
+	blockedGroups := make(map[string]bool)
+
+	attributeNames := append(slices.Clone(input.AttributeNames), input.MessageSystemAttributeNames...)
+
+	var movedToDeadLetterQueue []*Message
+	defer func() {
+		queue.Messages = slices.DeleteFunc(queue.Messages, func(message *Message) bool {
+			return slices.Contains(movedToDeadLetterQueue, message)
+		})
+	}()
+
 	output := &ReceiveMessageOutput{}
 	for _, message := range queue.Messages {
-		if message.Deleted {
+		if queue.Fifo && blockedGroups[message.MessageGroupId] {
 			continue
 		}
 
-		if message.VisibleAt.After(now) {
+		if message.VisibleAt.After(now) || message.DelayedUntil.After(now) {
+			blockedGroups[message.MessageGroupId] = true
 			continue
 		}
 
-		if message.DelayedUntil.After(now) {
+		if s.lockedMoveToDeadLetterQueue(queue, message) {
+			movedToDeadLetterQueue = append(movedToDeadLetterQueue, message)
 			continue
 		}
 
-		output.Message = append(output.Message, APIMessage{
-			Body:              message.Body,
-			MD5OfBody:         message.MD5OfBody,
-			MessageAttributes: filterAttributes(message.MessageAttributes, input.MessageAttributeNames),
-			MessageId:         message.UUID.String(),
-			// TODO: It seems AWS encodes additional data in here, there's some sort of binary blob
-			// This is fine for now as long as nobody tries to get clever with the representation.
-			ReceiptHandle: base64.StdEncoding.EncodeToString(message.UUID[:]),
-		})
+		message.ReceiveCount++
+		if message.FirstReceiveTimestamp == 0 {
+			message.FirstReceiveTimestamp = now.UnixMilli()
+		}
 
+		// The message becomes visible again once the timeout lapses, unless it's deleted first.
 		message.VisibleAt = now.Add(visibilityTimeout)
+		message.ReceiptHandle = newReceiptHandle(message)
+
+		messageAttributes := filterAttributes(message.MessageAttributes, input.MessageAttributeNames)
+		output.Messages = append(output.Messages, APIMessage{
+			Attributes:             s.messageSystemAttributes(message, attributeNames),
+			Body:                   message.Body,
+			MD5OfBody:              message.MD5OfBody,
+			MD5OfMessageAttributes: md5OfMessageAttributes(messageAttributes),
+			MessageAttributes:      messageAttributes,
+			MessageId:              message.UUID.String(),
+			ReceiptHandle:          message.ReceiptHandle,
+		})
 
-		if len(output.Message) == input.MaxNumberOfMessages {
+		if len(output.Messages) == input.MaxNumberOfMessages {
 			break
 		}
 	}
 
-	return output, nil
-}
-
-func filterAttributes(attributes map[string]APIAttribute, attributeNames []string) map[string]APIAttribute {
-	ret := make(map[string]APIAttribute)
-
-	for k, v := range attributes {
-		for _, name := range attributeNames {
-			if name == "All" ||
-				name == k ||
-				(strings.HasSuffix(name, ".*") && strings.HasPrefix(k, name[:len(name)-2])) {
-				ret[k] = v
-				break
-			}
-		}
-	}
-
-	return ret
+	return output
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_DeleteMessage.html
@@ -390,28 +677,61 @@ func (s *SQS) DeleteMessage(input DeleteMessageInput) (*DeleteMessageOutput, *aw
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	return nil, s.lockedDeleteMessage(queue, input.ReceiptHandle)
+	awserr = s.lockedDeleteMessage(queue, input.ReceiptHandle)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DeleteMessageOutput{}, nil
 }
 
 func (s *SQS) lockedDeleteMessage(queue *Queue, receiptHandle string) *awserrors.Error {
-	uuid, err := base64.StdEncoding.DecodeString(receiptHandle)
-	if err != nil {
-		return InvalidIdFormat("")
+	id, awserr := parseReceiptHandle(receiptHandle)
+	if awserr != nil {
+		return awserr
 	}
 
-	for _, message := range queue.Messages {
-		if bytes.Equal(message.UUID.Bytes(), uuid) {
-			message.Deleted = true
-			return nil
-		}
+	i := slices.IndexFunc(queue.Messages, func(message *Message) bool {
+		return message.UUID == id
+	})
+	// Deleting a message which has already been deleted succeeds.
+	if i == -1 {
+		return nil
+	}
+	// FIFO queues only delete a message with the handle from its latest receipt, so a consumer whose
+	// visibility timeout expired can't delete it from under the one which received it again.
+	if queue.Fifo && queue.Messages[i].ReceiptHandle != receiptHandle {
+		return InvalidParameterValue(fmt.Sprintf(
+			"Value %s for parameter ReceiptHandle is invalid. Reason: The receipt handle has expired.",
+			receiptHandle))
+	}
+	queue.Messages = slices.Delete(queue.Messages, i, i+1)
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_PurgeQueue.html
+func (s *SQS) PurgeQueue(input PurgeQueueInput) (*PurgeQueueOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	now := s.clock()
+	if !queue.LastPurged.IsZero() && now.Before(queue.LastPurged.Add(purgeInterval)) {
+		return nil, PurgeQueueInProgress(fmt.Sprintf(
+			"Only one PurgeQueue operation on %s is allowed every 60 seconds.", queue.Name))
 	}
+	queue.LastPurged = now
+	queue.Messages = nil
 
-	return ReceiptHandleIsInvalid("")
+	return &PurgeQueueOutput{}, nil
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_DeleteMessageBatch.html
@@ -419,60 +739,36 @@ func (s *SQS) DeleteMessageBatch(input DeleteMessageBatchInput) (*DeleteMessageB
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	if len(input.Entries) == 0 {
-		return nil, EmptyBatchRequest("")
+	var ids []string
+	for _, entry := range input.Entries {
+		ids = append(ids, entry.Id)
 	}
-
-	if len(input.Entries) > maxEntriesInDeleteBatch {
-		return nil, TooManyEntriesInBatchRequest("")
+	awserr = validateBatchEntryIds("DeleteMessageBatchRequestEntry", ids)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	seen := make(map[string]struct{})
-
-	output := &DeleteMessageBatchOutput{}
+	output := &DeleteMessageBatchOutput{
+		Failed:     []BatchResultErrorEntry{},
+		Successful: []DeleteMessageBatchResultEntry{},
+	}
 	for _, entry := range input.Entries {
-		if _, ok := seen[entry.Id]; ok {
-			output.Failed = append(output.Failed, BatchResultErrorEntry{
-				Code:        "BatchEntryIdsNotDistinct",
-				Message:     "",
-				Id:          entry.Id,
-				SenderFault: true,
-			})
-			continue
-		}
-
-		seen[entry.Id] = struct{}{}
-		if len(entry.Id) > maxBatchEntryIdLength || !batchEntryIdRegex.MatchString(entry.Id) {
-			output.Failed = append(output.Failed, BatchResultErrorEntry{
-				Code:        "InvalidBatchEntryId",
-				Message:     "",
-				Id:          entry.Id,
-				SenderFault: true,
-			})
-			continue
-		}
-
 		err := s.lockedDeleteMessage(queue, entry.ReceiptHandle)
 		if err == nil {
 			output.Successful = append(output.Successful, DeleteMessageBatchResultEntry{
 				Id: entry.Id,
 			})
 		} else {
-			output.Failed = append(output.Failed, BatchResultErrorEntry{
-				Code:        err.Body.Type,
-				Message:     err.Body.Message,
-				Id:          entry.Id,
-				SenderFault: err.Code == 400,
-			})
+			output.Failed = append(output.Failed, batchResultErrorEntry(entry.Id, err))
 		}
 	}
 
-	return nil, nil
+	return output, nil
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SetQueueAttributes.html
@@ -480,52 +776,16 @@ func (s *SQS) SetQueueAttributes(input SetQueueAttributesInput) (*SetQueueAttrib
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queue, ok := s.queuesByName[s.getQueueName(input.QueueUrl)]
-	if !ok {
-		return nil, QueueDoesNotExist("")
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
 	}
 
-	// TODO: is it ok to fail the request but stil change some of the attributes?
-	return nil, s.lockedSetQueueAttributes(queue, input.Attributes)
-}
-
-func (s *SQS) lockedSetQueueAttributes(queue *Queue, attributes map[string]string) *awserrors.Error {
-	for k, v := range attributes {
-		if k == "VisibilityTimeout" {
-			timeout, err := strconv.Atoi(v)
-			if err != nil {
-				return ValidationException(err.Error())
-			}
-
-			if timeout < 0 || timeout > maxVisibilityTimeoutSeconds {
-				return ValidationException("Bad VisibilityTimeout")
-			}
-
-			queue.VisibilityTimeout = time.Duration(timeout) * time.Second
-		} else if k == "MaximumMessageSize" {
-			size, err := strconv.Atoi(v)
-			if err != nil {
-				return ValidationException(err.Error())
-			}
-
-			if size < minMaximumMessageSize || size > maxMaximumMessageSize {
-				return ValidationException("Bad MaximumMessageSize")
-			}
-
-			queue.MaximumMessageSize = size
-		} else if k == "DelaySeconds" {
-			delay, err := strconv.Atoi(v)
-			if err != nil {
-				return ValidationException(err.Error())
-			}
-
-			if delay < minDelaySeconds || delay > int(maxDelaySeconds) {
-				return ValidationException("Bad DelaySeconds")
-			}
-
-			queue.DelayDuration = time.Duration(delay) * time.Second
-		}
+	awserr = s.lockedSetQueueAttributes(queue, input.Attributes)
+	if awserr != nil {
+		return nil, awserr
 	}
+	queue.LastModifiedTimestamp = s.clock().Unix()
 
-	return nil
+	return &SetQueueAttributesOutput{}, nil
 }
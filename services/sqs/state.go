@@ -0,0 +1,92 @@
+package sqs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type queueState struct {
+	*Queue
+	// FIFO queues only.
+	LastSequenceNumber int64 `json:",omitempty"`
+}
+
+type queuesState struct {
+	Queues []queueState
+}
+
+// TryPause pauses changes to each region's queues while a snapshot copies them.
+func (s *SQS) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *SQS) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's queues and their messages, including those in flight, to the
+// archive. Message move tasks and FIFO queues' deduplication IDs aren't exported.
+func (s *SQS) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*SQS).exportState)
+}
+
+func (s *SQS) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported queuesState
+	for _, queue := range s.queuesByName {
+		exported.Queues = append(exported.Queues, queueState{
+			Queue:              queue,
+			LastSequenceNumber: queue.lastSequenceNumber,
+		})
+	}
+	slices.SortFunc(exported.Queues, func(a, b queueState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("queues.json", exported)
+}
+
+// ImportState replaces each region's queues and their messages with the archive's. Their URLs are
+// generated again, for this emulator's address.
+func (s *SQS) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*SQS).importState, (*SQS).exportState)
+}
+
+func (s *SQS) importState(r *state.Reader) (func() error, error) {
+	var imported queuesState
+	if err := r.ReadJSON("queues.json", &imported); err != nil {
+		return nil, err
+	}
+	queuesByName := make(map[string]*Queue)
+	for _, q := range imported.Queues {
+		queue := q.Queue
+		if queue == nil {
+			return nil, fmt.Errorf("queue has no name")
+		}
+		if queue.ARN == "" || !queueNameRegex.MatchString(strings.TrimSuffix(queue.Name, ".fifo")) {
+			return nil, fmt.Errorf("invalid queue %q", queue.Name)
+		}
+		queue.URL = s.getQueueUrl(queue.Name)
+		queue.messagesAdded = make(chan struct{})
+		queue.lastSequenceNumber = q.LastSequenceNumber
+		if queue.Fifo {
+			queue.deduplicationEntriesByKey = make(map[string]*deduplicationEntry)
+		}
+		if queue.Attributes == nil {
+			queue.Attributes = make(map[string]string)
+		}
+		if queue.Tags == nil {
+			queue.Tags = make(map[string]string)
+		}
+		queuesByName[queue.Name] = queue
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Receives waiting on the previous queues return once their wait times out.
+		s.queuesByName = queuesByName
+		return nil
+	}, nil
+}
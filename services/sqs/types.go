@@ -1,20 +1,19 @@
 package sqs
 
-import "encoding/xml"
+// Field names are those of the JSON protocol. See the query package for how they map to the Query protocol.
 
 type CreateQueueInput struct {
-	Attribute map[string]string
-	QueueName string
-	Tag       map[string]string
+	Attributes map[string]string `query:"Attribute"`
+	QueueName  string            `required:"true" error:"MissingParameter"`
+	Tags       map[string]string `query:"Tag,Key"`
 }
 
 type CreateQueueOutput struct {
-	XMLName  xml.Name `xml:"CreateQueueResult"`
 	QueueUrl string
 }
 
 type DeleteQueueInput struct {
-	QueueUrl string
+	QueueUrl string `required:"true" error:"MissingParameter"`
 }
 
 type DeleteQueueOutput struct{}
@@ -22,49 +21,49 @@ type DeleteQueueOutput struct{}
 const AWSTraceHeaderAttributeName = "AWSTraceHeader"
 
 type SendMessageInput struct {
-	DelaySeconds            int
-	MessageAttributes       map[string]APIAttribute
+	// Nil to use the queue's delay.
+	DelaySeconds            *int                    `range:"0,900" error:"InvalidParameterValue"`
+	MessageAttributes       map[string]APIAttribute `query:"MessageAttribute"`
 	MessageBody             string
-	MessageDeduplicationId  string
-	MessageGroupId          string
-	MessageSystemAttributes map[string]APIAttribute
-	QueueUrl                string
+	MessageDeduplicationId  string                  `length:",128" error:"InvalidParameterValue"`
+	MessageGroupId          string                  `length:",128" error:"InvalidParameterValue"`
+	MessageSystemAttributes map[string]APIAttribute `query:"MessageSystemAttribute"`
+	QueueUrl                string                  `required:"true" error:"MissingParameter"`
 }
 
 type SendMessageOutput struct {
-	XMLName xml.Name `xml:"SendMessageResult"`
-
-	MD5OfMessageAttributes       string
+	MD5OfMessageAttributes       string `json:",omitempty"`
 	MD5OfMessageBody             string
-	MD5OfMessageSystemAttributes string
+	MD5OfMessageSystemAttributes string `json:",omitempty"`
 	MessageId                    string
-	SequenceNumber               string
+	SequenceNumber               string `json:",omitempty"`
 }
 
 type APIAttribute struct {
-	BinaryListValues [][]byte
-	BinaryValue      []byte
-	DataType         string
-	StringListValues []string
-	StringValue      string
+	BinaryListValues [][]byte `json:",omitempty" query:"BinaryListValue"`
+	BinaryValue      []byte   `json:",omitempty"`
+	DataType         string   `required:"true" error:"MissingParameter"`
+	StringListValues []string `json:",omitempty" query:"StringListValue"`
+	StringValue      string   `json:",omitempty"`
 }
 
 type TagQueueInput struct {
-	QueueUrl string
-	Tags     map[string]string
+	QueueUrl string            `required:"true" error:"MissingParameter"`
+	Tags     map[string]string `query:"Tag,Key" required:"true" error:"MissingParameter"`
 }
 
 type TagQueueOutput struct{}
 
 type UntagQueueInput struct {
-	QueueUrl string
-	TagKeys  []string
+	QueueUrl string   `required:"true" error:"MissingParameter"`
+	TagKeys  []string `query:"TagKey" required:"true" error:"MissingParameter"`
 }
 
 type UntagQueueOutput struct{}
 
 type GetQueueUrlInput struct {
-	QueueName string
+	QueueName              string `required:"true" error:"MissingParameter"`
+	QueueOwnerAWSAccountId string
 }
 
 type GetQueueUrlOutput struct {
@@ -72,30 +71,31 @@ type GetQueueUrlOutput struct {
 }
 
 type ListQueuesInput struct {
-	MaxResults      int
-	QueueNamePrefix string
+	MaxResults      int `range:"1,1000" error:"InvalidParameterValue"`
+	NextToken       string
+	QueueNamePrefix string `length:",80" error:"InvalidParameterValue"`
 }
 
 type ListQueuesOutput struct {
-	QueueUrls []string `xml:"queueUrls"`
+	NextToken string   `json:",omitempty"`
+	QueueUrls []string `query:"QueueUrl"`
 }
 
 type GetQueueAttributesInput struct {
-	attributeNames []string
-	//`query:"attributeNames"`
-	QueueUrl string
+	AttributeNames []string `query:"AttributeName"`
+	QueueUrl       string   `required:"true" error:"MissingParameter"`
 }
 
 type GetQueueAttributesOutput struct {
-	Attributes map[string]string `xml:"attributes"`
+	Attributes map[string]string `query:"Attribute"`
 }
 
 type ListQueueTagsInput struct {
-	QueueUrl string
+	QueueUrl string `required:"true" error:"MissingParameter"`
 }
 
 type ListQueueTagsOutput struct {
-	Tags map[string]string
+	Tags map[string]string `query:"Tag,Key"`
 }
 
 // https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ReceiveMessage.html#SQS-ReceiveMessage-request-AttributeNames
@@ -119,49 +119,52 @@ const (
 
 type ReceiveMessageInput struct {
 	// Deprecated
-	AttributeNames              []AttributeName
+	AttributeNames              []AttributeName `query:"AttributeName"`
 	MaxNumberOfMessages         int
-	MessageAttributeNames       []string
-	MessageSystemAttributeNames []AttributeName
-	QueueUrl                    string
-	// ReceiveRequestAttemptId
-	VisibilityTimeout int
-	WaitTimeSeconds   int
+	MessageAttributeNames       []string        `query:"MessageAttributeName"`
+	MessageSystemAttributeNames []AttributeName `query:"MessageSystemAttributeName"`
+	QueueUrl                    string          `required:"true" error:"MissingParameter"`
+	ReceiveRequestAttemptId     string          `length:",128" error:"InvalidParameterValue"`
+	// Nil to use the queue's visibility timeout.
+	VisibilityTimeout *int `range:"0,43200" error:"InvalidParameterValue"`
+	// Nil to use the queue's ReceiveMessageWaitTimeSeconds.
+	WaitTimeSeconds *int `range:"0,20" error:"InvalidParameterValue"`
 }
 
 type ReceiveMessageOutput struct {
-	XMLName xml.Name `xml:"ReceiveMessageResult"`
-	Message []APIMessage
+	Messages []APIMessage `json:",omitempty" query:"Message"`
 }
 
 type APIMessage struct {
-	Attributes             map[string]string
+	Attributes             map[string]string `json:",omitempty" query:"Attribute"`
 	Body                   string
 	MD5OfBody              string
-	MD5OfMessageAttributes string
-	MessageAttributes      map[string]APIAttribute
+	MD5OfMessageAttributes string                  `json:",omitempty"`
+	MessageAttributes      map[string]APIAttribute `json:",omitempty" query:"MessageAttribute"`
 	MessageId              string
 	ReceiptHandle          string
 }
 
 type DeleteMessageInput struct {
-	QueueUrl      string
-	ReceiptHandle string
+	QueueUrl      string `required:"true" error:"MissingParameter"`
+	ReceiptHandle string `required:"true" error:"MissingParameter"`
 }
 
 type DeleteMessageOutput struct{}
 
 type DeleteMessageBatchInput struct {
-	QueueUrl string
-	Entries  []struct {
-		Id            string
-		ReceiptHandle string
-	}
+	QueueUrl string                           `required:"true" error:"MissingParameter"`
+	Entries  []DeleteMessageBatchRequestEntry `query:"DeleteMessageBatchRequestEntry"`
+}
+
+type DeleteMessageBatchRequestEntry struct {
+	Id            string
+	ReceiptHandle string
 }
 
 type DeleteMessageBatchOutput struct {
-	Failed     []BatchResultErrorEntry
-	Successful []DeleteMessageBatchResultEntry
+	Failed     []BatchResultErrorEntry         `query:"BatchResultErrorEntry"`
+	Successful []DeleteMessageBatchResultEntry `query:"DeleteMessageBatchResultEntry"`
 }
 
 type BatchResultErrorEntry struct {
@@ -176,8 +179,103 @@ type DeleteMessageBatchResultEntry struct {
 }
 
 type SetQueueAttributesInput struct {
-	QueueUrl   string
-	Attributes map[string]string
+	QueueUrl   string            `required:"true" error:"MissingParameter"`
+	Attributes map[string]string `query:"Attribute"`
 }
 
 type SetQueueAttributesOutput struct{}
+
+type PurgeQueueInput struct {
+	QueueUrl string `required:"true" error:"MissingParameter"`
+}
+
+type PurgeQueueOutput struct{}
+
+type ChangeMessageVisibilityInput struct {
+	QueueUrl          string `required:"true" error:"MissingParameter"`
+	ReceiptHandle     string `required:"true" error:"MissingParameter"`
+	VisibilityTimeout int    `range:"0,43200" error:"InvalidParameterValue"`
+}
+
+type ChangeMessageVisibilityOutput struct{}
+
+type ChangeMessageVisibilityBatchInput struct {
+	QueueUrl string                                     `required:"true" error:"MissingParameter"`
+	Entries  []ChangeMessageVisibilityBatchRequestEntry `query:"ChangeMessageVisibilityBatchRequestEntry"`
+}
+
+type ChangeMessageVisibilityBatchRequestEntry struct {
+	Id                string
+	ReceiptHandle     string
+	VisibilityTimeout int
+}
+
+type ChangeMessageVisibilityBatchOutput struct {
+	Failed     []BatchResultErrorEntry                   `query:"BatchResultErrorEntry"`
+	Successful []ChangeMessageVisibilityBatchResultEntry `query:"ChangeMessageVisibilityBatchResultEntry"`
+}
+
+type ChangeMessageVisibilityBatchResultEntry struct {
+	Id string
+}
+
+type StartMessageMoveTaskInput struct {
+	DestinationArn               string
+	MaxNumberOfMessagesPerSecond int    `range:"1,500" error:"InvalidParameterValue"`
+	SourceArn                    string `required:"true" error:"MissingParameter"`
+}
+
+type StartMessageMoveTaskOutput struct {
+	TaskHandle string
+}
+
+type ListMessageMoveTasksInput struct {
+	MaxResults int    `range:"1,10" error:"InvalidParameterValue"`
+	SourceArn  string `required:"true" error:"MissingParameter"`
+}
+
+type ListMessageMoveTasksOutput struct {
+	Results []ListMessageMoveTasksResultEntry `query:"ListMessageMoveTasksResultEntry"`
+}
+
+type ListMessageMoveTasksResultEntry struct {
+	ApproximateNumberOfMessagesMoved  int64
+	ApproximateNumberOfMessagesToMove int64  `json:",omitempty"`
+	DestinationArn                    string `json:",omitempty"`
+	FailureReason                     string `json:",omitempty"`
+	MaxNumberOfMessagesPerSecond      int    `json:",omitempty"`
+	SourceArn                         string
+	StartedTimestamp                  int64
+	Status                            string
+	TaskHandle                        string `json:",omitempty"`
+}
+
+type SendMessageBatchInput struct {
+	QueueUrl string                         `required:"true" error:"MissingParameter"`
+	Entries  []SendMessageBatchRequestEntry `query:"SendMessageBatchRequestEntry"`
+}
+
+type SendMessageBatchRequestEntry struct {
+	// Nil to use the queue's delay.
+	DelaySeconds            *int
+	Id                      string
+	MessageAttributes       map[string]APIAttribute `query:"MessageAttribute"`
+	MessageBody             string
+	MessageDeduplicationId  string
+	MessageGroupId          string
+	MessageSystemAttributes map[string]APIAttribute `query:"MessageSystemAttribute"`
+}
+
+type SendMessageBatchOutput struct {
+	Failed     []BatchResultErrorEntry       `query:"BatchResultErrorEntry"`
+	Successful []SendMessageBatchResultEntry `query:"SendMessageBatchResultEntry"`
+}
+
+type SendMessageBatchResultEntry struct {
+	Id                           string
+	MD5OfMessageAttributes       string `json:",omitempty"`
+	MD5OfMessageBody             string
+	MD5OfMessageSystemAttributes string `json:",omitempty"`
+	MessageId                    string
+	SequenceNumber               string `json:",omitempty"`
+}
@@ -0,0 +1,127 @@
+package sqs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-visibility-timeout.html
+
+// newReceiptHandle returns a handle for a single receipt of the message. It encodes the
+// message ID, so the message can be found, and a nonce, so each receipt gets a different handle.
+func newReceiptHandle(message *Message) string {
+	handle := make([]byte, 2*uuid.Size)
+	copy(handle, message.UUID[:])
+	if _, err := rand.Read(handle[uuid.Size:]); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(handle)
+}
+
+// parseReceiptHandle returns the ID of the message the handle was issued for.
+func parseReceiptHandle(receiptHandle string) (uuid.UUID, *awserrors.Error) {
+	handle, err := base64.StdEncoding.DecodeString(receiptHandle)
+	if err != nil || len(handle) != 2*uuid.Size {
+		return uuid.Nil, ReceiptHandleIsInvalid(fmt.Sprintf(
+			"The input receipt handle \"%s\" is not a valid receipt handle.", receiptHandle))
+	}
+	return uuid.FromBytesOrNil(handle[:uuid.Size]), nil
+}
+
+func parseVisibilityTimeout(seconds int) (time.Duration, *awserrors.Error) {
+	if seconds < 0 || seconds > maxVisibilityTimeoutSeconds {
+		return 0, InvalidParameterValue(fmt.Sprintf(
+			"Value %d for parameter VisibilityTimeout is invalid. Reason: Must be between 0 and %d.",
+			seconds, maxVisibilityTimeoutSeconds))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (s *SQS) lockedChangeMessageVisibility(queue *Queue, receiptHandle string, seconds int) *awserrors.Error {
+	id, awserr := parseReceiptHandle(receiptHandle)
+	if awserr != nil {
+		return awserr
+	}
+	timeout, awserr := parseVisibilityTimeout(seconds)
+	if awserr != nil {
+		return awserr
+	}
+
+	i := slices.IndexFunc(queue.Messages, func(message *Message) bool {
+		return message.UUID == id
+	})
+	// Only the handle from the latest receipt of a message can change its visibility.
+	if i == -1 || queue.Messages[i].ReceiptHandle != receiptHandle {
+		return InvalidParameterValue(fmt.Sprintf(
+			"Value %s for parameter ReceiptHandle is invalid. Reason: Message does not exist or is not available for visibility timeout change.",
+			receiptHandle))
+	}
+
+	now := s.clock()
+	message := queue.Messages[i]
+	if !message.VisibleAt.After(now) {
+		return MessageNotInflight("")
+	}
+	message.VisibleAt = now.Add(timeout)
+	return nil
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ChangeMessageVisibility.html
+func (s *SQS) ChangeMessageVisibility(input ChangeMessageVisibilityInput) (*ChangeMessageVisibilityOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	awserr = s.lockedChangeMessageVisibility(queue, input.ReceiptHandle, input.VisibilityTimeout)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &ChangeMessageVisibilityOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ChangeMessageVisibilityBatch.html
+func (s *SQS) ChangeMessageVisibilityBatch(input ChangeMessageVisibilityBatchInput) (*ChangeMessageVisibilityBatchOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, awserr := s.lockedGetQueue(input.QueueUrl)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	var ids []string
+	for _, entry := range input.Entries {
+		ids = append(ids, entry.Id)
+	}
+	awserr = validateBatchEntryIds("ChangeMessageVisibilityBatchRequestEntry", ids)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	output := &ChangeMessageVisibilityBatchOutput{
+		Failed:     []BatchResultErrorEntry{},
+		Successful: []ChangeMessageVisibilityBatchResultEntry{},
+	}
+	for _, entry := range input.Entries {
+		err := s.lockedChangeMessageVisibility(queue, entry.ReceiptHandle, entry.VisibilityTimeout)
+		if err == nil {
+			output.Successful = append(output.Successful, ChangeMessageVisibilityBatchResultEntry{
+				Id: entry.Id,
+			})
+		} else {
+			output.Failed = append(output.Failed, batchResultErrorEntry(entry.Id, err))
+		}
+	}
+	return output, nil
+}
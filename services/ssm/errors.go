@@ -0,0 +1,49 @@
+package ssm
+
+import "aws-in-a-box/awserrors"
+
+func HierarchyLevelLimitExceededException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("HierarchyLevelLimitExceededException", message)
+}
+
+func InvalidKeyId(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidKeyId", message)
+}
+
+func ParameterAlreadyExists(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ParameterAlreadyExists", message)
+}
+
+func ParameterMaxVersionLimitExceeded(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ParameterMaxVersionLimitExceeded", message)
+}
+
+func ParameterNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ParameterNotFound", message)
+}
+
+func ParameterPatternMismatchException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ParameterPatternMismatchException", message)
+}
+
+func ParameterVersionNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ParameterVersionNotFound", message)
+}
+
+func UnsupportedParameterType(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("UnsupportedParameterType", message)
+}
+
+func ValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationException", message)
+}
+
+func InternalServerError(message string) *awserrors.Error {
+	return &awserrors.Error{
+		Code: 500,
+		Body: awserrors.ErrorBody{
+			Type:    "InternalServerError",
+			Message: message,
+		},
+	}
+}
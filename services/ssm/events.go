@@ -0,0 +1,42 @@
+package ssm
+
+import (
+	"encoding/json"
+)
+
+// EventPublisher puts events on the region's default event bus, like EventBridge does for AWS
+// services.
+type EventPublisher interface {
+	PutEvent(region string, source string, detailType string, resources []string, detail []byte) error
+}
+
+// parameterChangeDetail is the detail of Parameter Store Change events.
+// https://docs.aws.amazon.com/systems-manager/latest/userguide/sysman-paramstore-cwe.html
+type parameterChangeDetail struct {
+	// Create, Update or Delete.
+	Operation   string `json:"operation"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// publishChange publishes an event for the parameter change, if events are enabled.
+// It must be called without holding the lock, since the subscribers may call back into SSM.
+func (s *SSM) publishChange(operation string, parameterArn string, name string, version *ParameterVersion) {
+	if s.events == nil {
+		return
+	}
+	detail, err := json.Marshal(parameterChangeDetail{
+		Operation:   operation,
+		Name:        name,
+		Type:        version.Type,
+		Description: version.Description,
+	})
+	if err != nil {
+		panic(err)
+	}
+	err = s.events.PutEvent(s.arnGenerator.Region, "aws.ssm", "Parameter Store Change", []string{parameterArn}, detail)
+	if err != nil {
+		s.logger.Warn("Publishing parameter change event", "parameter", name, "error", err)
+	}
+}
@@ -0,0 +1,20 @@
+package ssm
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AmazonSSM"
+
+func (s *SSM) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteParameter", region.Method(s.regions, (*SSM).DeleteParameter))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteParameters", region.Method(s.regions, (*SSM).DeleteParameters))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetParameter", region.Method(s.regions, (*SSM).GetParameter))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetParameterHistory", region.Method(s.regions, (*SSM).GetParameterHistory))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetParameters", region.Method(s.regions, (*SSM).GetParameters))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetParametersByPath", region.Method(s.regions, (*SSM).GetParametersByPath))
+	http.RegisterWithRequest(logger, methodRegistry, service, "PutParameter", region.Method(s.regions, (*SSM).PutParameter))
+}
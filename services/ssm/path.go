@@ -0,0 +1,130 @@
+package ssm
+
+import (
+	"slices"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+const maxGetParametersByPath = 10
+
+// validatePathFilters validates the filters supported by GetParametersByPath,
+// which are Type, KeyId, Label and tag:<key>.
+func validatePathFilters(filters []APIParameterStringFilter) *awserrors.Error {
+	for _, filter := range filters {
+		if filter.Option != "" && filter.Option != "Equals" {
+			return ValidationException("The option " + filter.Option + " is not valid for the key " + filter.Key + ".")
+		}
+		switch {
+		case filter.Key == "Type", filter.Key == "KeyId", filter.Key == "Label":
+			if len(filter.Values) < 1 {
+				return ValidationException("The filter " + filter.Key + " must have at least one value.")
+			}
+		case strings.HasPrefix(filter.Key, "tag:") && len(filter.Key) > len("tag:"):
+			// Without values, the tag only has to exist.
+		default:
+			return ValidationException("The filter key " + filter.Key + " is not valid for GetParametersByPath.")
+		}
+	}
+	return nil
+}
+
+// matchesFilters returns whether the parameter's latest version matches all the filters,
+// and so any of each filter's values.
+func (p *Parameter) matchesFilters(filters []APIParameterStringFilter) bool {
+	version := p.latest()
+	for _, filter := range filters {
+		var matched bool
+		switch filter.Key {
+		case "Type":
+			matched = slices.Contains(filter.Values, version.Type)
+		case "KeyId":
+			matched = version.Type == "SecureString" && slices.Contains(filter.Values, version.KeyId)
+		case "Label":
+			// Labels aren't supported, so no parameter has any.
+			matched = false
+		default:
+			key := strings.TrimPrefix(filter.Key, "tag:")
+			matched = slices.ContainsFunc(p.Tags, func(tag APITag) bool {
+				return tag.Key == key && (len(filter.Values) == 0 || slices.Contains(filter.Values, tag.Value))
+			})
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// inPath returns whether the name is below the path, which must end in a /.
+// Unless recursive, the name must be directly below it.
+func inPath(name string, path string, recursive bool) bool {
+	rest, ok := strings.CutPrefix(name, path)
+	if !ok || rest == "" {
+		return false
+	}
+	return recursive || !strings.Contains(rest, "/")
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_GetParametersByPath.html
+func (s *SSM) GetParametersByPath(input GetParametersByPathInput) (*GetParametersByPathOutput, *awserrors.Error) {
+	if !strings.HasPrefix(input.Path, "/") {
+		return nil, ValidationException("The path must begin with a forward slash (/).")
+	}
+	if strings.Count(input.Path, "/") > maxHierarchyLevels {
+		return nil, HierarchyLevelLimitExceededException("The path can have at most 15 levels.")
+	}
+	maxResults := input.MaxResults
+	if maxResults == 0 {
+		maxResults = maxGetParametersByPath
+	}
+	if maxResults < 1 || maxResults > maxGetParametersByPath {
+		return nil, ValidationException("MaxResults must be between 1 and 10.")
+	}
+	if awserr := validatePathFilters(input.ParameterFilters); awserr != nil {
+		return nil, awserr
+	}
+	path := input.Path
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	s.mu.Lock()
+	var parameters []*Parameter
+	for name, parameter := range s.parametersByName {
+		// The token is the name of the next parameter.
+		if name < input.NextToken || !inPath(name, path, input.Recursive) {
+			continue
+		}
+		if parameter.matchesFilters(input.ParameterFilters) {
+			parameters = append(parameters, parameter)
+		}
+	}
+	// Take the latest versions while holding the lock, since they may change after.
+	versions := make(map[*Parameter]*ParameterVersion, len(parameters))
+	for _, parameter := range parameters {
+		versions[parameter] = parameter.latest()
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(parameters, func(a, b *Parameter) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &GetParametersByPathOutput{
+		Parameters: []APIParameter{},
+	}
+	if len(parameters) > maxResults {
+		output.NextToken = parameters[maxResults].Name
+		parameters = parameters[:maxResults]
+	}
+	// Decrypt without holding the lock, since it calls KMS.
+	for _, parameter := range parameters {
+		apiParameter, awserr := s.toAPI(parameter, versions[parameter], "", input.WithDecryption)
+		if awserr != nil {
+			return nil, awserr
+		}
+		output.Parameters = append(output.Parameters, apiParameter)
+	}
+	return output, nil
+}
@@ -0,0 +1,91 @@
+package ssm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetParametersByPath(t *testing.T) {
+	s, _ := newSSM(t)
+	parameters := []PutParameterInput{
+		{Name: "/app/url", Value: "http://app", Tags: []APITag{{Key: "env", Value: "prod"}}},
+		{Name: "/app/password", Value: "hunter2", Type: "SecureString"},
+		{Name: "/app/db/host", Value: "db", Tags: []APITag{{Key: "env", Value: "dev"}}},
+		{Name: "/app/db/ports", Value: "1,2", Type: "StringList"},
+		{Name: "/application", Value: "v"},
+		{Name: "root", Value: "v"},
+	}
+	for _, input := range parameters {
+		putParameter(t, s, input)
+	}
+
+	cases := []struct {
+		input    GetParametersByPathInput
+		expected []string
+	}{
+		{GetParametersByPathInput{Path: "/app"}, []string{"/app/password", "/app/url"}},
+		{GetParametersByPathInput{Path: "/app/", Recursive: true}, []string{"/app/db/host", "/app/db/ports", "/app/password", "/app/url"}},
+		{GetParametersByPathInput{Path: "/"}, []string{"/application"}},
+		{GetParametersByPathInput{Path: "/app", Recursive: true, ParameterFilters: []APIParameterStringFilter{
+			{Key: "Type", Values: []string{"StringList", "SecureString"}},
+		}}, []string{"/app/db/ports", "/app/password"}},
+		{GetParametersByPathInput{Path: "/app", Recursive: true, ParameterFilters: []APIParameterStringFilter{
+			{Key: "KeyId", Values: []string{"alias/aws/ssm"}},
+		}}, []string{"/app/password"}},
+		{GetParametersByPathInput{Path: "/app", Recursive: true, ParameterFilters: []APIParameterStringFilter{
+			{Key: "tag:env", Values: []string{"dev"}},
+		}}, []string{"/app/db/host"}},
+		{GetParametersByPathInput{Path: "/app", Recursive: true, ParameterFilters: []APIParameterStringFilter{
+			{Key: "tag:env"},
+		}}, []string{"/app/db/host", "/app/url"}},
+		{GetParametersByPathInput{Path: "/missing", Recursive: true}, nil},
+	}
+	for _, c := range cases {
+		output, awserr := s.GetParametersByPath(c.input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		var names []string
+		for _, parameter := range output.Parameters {
+			names = append(names, parameter.Name)
+		}
+		if !slices.Equal(names, c.expected) {
+			t.Fatalf("Expected %v for %+v, got %v", c.expected, c.input, names)
+		}
+	}
+
+	// Pages are in name order.
+	var names []string
+	input := GetParametersByPathInput{Path: "/app", Recursive: true, WithDecryption: true, MaxResults: 3}
+	for {
+		output, awserr := s.GetParametersByPath(input)
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		for _, parameter := range output.Parameters {
+			names = append(names, parameter.Name)
+			if parameter.Name == "/app/password" && parameter.Value != "hunter2" {
+				t.Fatal("Expected decrypted value", parameter.Value)
+			}
+		}
+		if output.NextToken == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	if !slices.Equal(names, []string{"/app/db/host", "/app/db/ports", "/app/password", "/app/url"}) {
+		t.Fatal("Unexpected pages", names)
+	}
+
+	invalid := []GetParametersByPathInput{
+		{Path: "app"},
+		{Path: "/app", MaxResults: 11},
+		{Path: "/app", ParameterFilters: []APIParameterStringFilter{{Key: "Name", Values: []string{"/app/url"}}}},
+		{Path: "/app", ParameterFilters: []APIParameterStringFilter{{Key: "Type", Option: "BeginsWith", Values: []string{"S"}}}},
+	}
+	for _, input := range invalid {
+		if _, awserr := s.GetParametersByPath(input); awserr == nil {
+			t.Fatalf("Expected error for %+v", input)
+		}
+	}
+}
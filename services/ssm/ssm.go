@@ -0,0 +1,566 @@
+package ssm
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/kms"
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/systems-manager/latest/userguide/systems-manager-parameter-store.html
+
+const (
+	// Once a parameter has this many versions, the oldest is deleted when a new one is added.
+	maxVersions         = 100
+	maxHierarchyLevels  = 15
+	maxNameLength       = 1011
+	maxDescriptionLen   = 1024
+	maxStandardValueLen = 4096
+	maxAdvancedValueLen = 8192
+	maxGetParameters    = 10
+	defaultMaxResults   = 50
+	maxMaxResults       = 50
+
+	defaultKeyId = "alias/aws/ssm"
+)
+
+var (
+	nameRegex     = regexp.MustCompile(`^[a-zA-Z0-9_.\-/]+$`)
+	reservedRegex = regexp.MustCompile(`(?i)^/?(aws|ssm)`)
+	dataTypes     = []string{"text", "aws:ec2:image", "aws:ssm:integration"}
+)
+
+// ParameterVersion is never modified, so it can be used without holding the lock.
+type ParameterVersion struct {
+	Version int64
+	Type    string
+	// The plaintext value of String and StringList parameters.
+	Value string
+	// The KMS ciphertext of SecureString parameters.
+	Ciphertext       []byte
+	KeyId            string
+	Description      string
+	AllowedPattern   string
+	Tier             string
+	DataType         string
+	LastModifiedDate time.Time
+}
+
+type Parameter struct {
+	Name string
+	ARN  string
+	Tags []APITag
+	// In order, but the oldest may have been deleted.
+	Versions []*ParameterVersion
+}
+
+func (p *Parameter) latest() *ParameterVersion {
+	return p.Versions[len(p.Versions)-1]
+}
+
+func (p *Parameter) getVersion(version int64) *ParameterVersion {
+	for _, v := range p.Versions {
+		if v.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+type SSM struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Nil if KMS is not enabled, in which case there can't be SecureString parameters.
+	kms *kms.KMS
+	// Nil if events are not enabled.
+	events EventPublisher
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu               sync.Mutex
+	parametersByName map[string]*Parameter
+	// Shared by the instances of all regions, which each have their own parameters.
+	regions *region.Regions[*SSM]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// SecureString parameters are encrypted with keys in this KMS.
+	KMS *kms.KMS
+	// Parameter changes are published as events through this.
+	Events EventPublisher
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *SSM {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*SSM, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.KMS != nil {
+			keys, err := options.KMS.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.KMS = keys
+		}
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *SSM {
+	return &SSM{
+		logger:           options.Logger,
+		arnGenerator:     options.ArnGenerator,
+		kms:              options.KMS,
+		events:           options.Events,
+		clock:            clock.Now,
+		parametersByName: make(map[string]*Parameter),
+	}
+}
+
+// InRegion returns the parameters of the region, for services which read parameters by name. It
+// fails if the region isn't one of Options.Regions.
+func (s *SSM) InRegion(name string) (*SSM, error) {
+	return s.regions.Get(name)
+}
+
+// parameterArn returns the ARN of the parameter, which for /a/b is arn:aws:ssm:region:account:parameter/a/b.
+func (s *SSM) parameterArn(name string) string {
+	return s.arnGenerator.Generate("ssm", "parameter", strings.TrimPrefix(name, "/"))
+}
+
+func validateName(name string) *awserrors.Error {
+	if len(name) == 0 || len(name) > maxNameLength || !nameRegex.MatchString(name) {
+		return ValidationException("Parameter name: must be a fully qualified name containing only letters, numbers, and the symbols _.-/")
+	}
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+		return ValidationException("Parameter name must be a fully qualified name.")
+	}
+	if reservedRegex.MatchString(name) {
+		return ValidationException("Parameter name: can't be prefixed with \"aws\" or \"ssm\" (case-insensitive). " +
+			"If formed as a path, it can consist of sub-paths divided by slash symbol; each sub-path can be formed as a mix of letters, numbers and the following 3 symbols .-_")
+	}
+	if strings.Count(name, "/") > maxHierarchyLevels {
+		return HierarchyLevelLimitExceededException("Parameter name hierarchy can't exceed 15 levels.")
+	}
+	return nil
+}
+
+// lockedGetParameter accepts a parameter's name or ARN.
+func (s *SSM) lockedGetParameter(name string) (*Parameter, *awserrors.Error) {
+	if strings.HasPrefix(name, "arn:") {
+		// The ARN of /a/b is ...:parameter/a/b, but a name without slashes doesn't start with one.
+		_, id, _ := strings.Cut(name, ":parameter/")
+		if parameter, ok := s.parametersByName["/"+id]; ok && parameter.ARN == name {
+			return parameter, nil
+		}
+		if parameter, ok := s.parametersByName[id]; ok && parameter.ARN == name {
+			return parameter, nil
+		}
+		return nil, ParameterNotFound("Parameter " + name + " not found.")
+	}
+	parameter, ok := s.parametersByName[name]
+	if !ok {
+		return nil, ParameterNotFound("Parameter " + name + " not found.")
+	}
+	return parameter, nil
+}
+
+// lockedGetParameterVersion accepts a name with an optional version selector, like /a/b:3.
+func (s *SSM) lockedGetParameterVersion(name string) (*Parameter, *ParameterVersion, string, *awserrors.Error) {
+	// Names can't contain colons, but ARNs have five.
+	colons := strings.Count(name, ":")
+	if strings.HasPrefix(name, "arn:") {
+		colons -= 5
+	}
+	var selector string
+	if colons > 0 {
+		i := strings.LastIndex(name, ":")
+		selector = name[i+1:]
+		name = name[:i]
+	}
+	parameter, awserr := s.lockedGetParameter(name)
+	if awserr != nil {
+		return nil, nil, "", awserr
+	}
+	if selector == "" {
+		return parameter, parameter.latest(), "", nil
+	}
+	n, err := strconv.ParseInt(selector, 10, 64)
+	if err != nil {
+		// Labels aren't supported, so there are none to select.
+		return nil, nil, "", ParameterNotFound("Parameter " + name + ":" + selector + " not found.")
+	}
+	version := parameter.getVersion(n)
+	if version == nil {
+		return nil, nil, "", ParameterVersionNotFound("Systems Manager could not find version " + selector + " of " + name + ". Verify the version and try again.")
+	}
+	return parameter, version, ":" + selector, nil
+}
+
+// encrypt encrypts a SecureString value, and returns the ciphertext and the key's ID.
+func (s *SSM) encrypt(parameterArn string, keyId string, value string) ([]byte, string, *awserrors.Error) {
+	if s.kms == nil {
+		return nil, "", ValidationException("SecureString parameters need KMS to be enabled.")
+	}
+	encryptionKeyId := keyId
+	if keyId == "" || keyId == defaultKeyId {
+		var awserr *awserrors.Error
+		encryptionKeyId, awserr = s.kms.AWSManagedKey("ssm")
+		if awserr != nil {
+			return nil, "", InternalServerError("Creating the default KMS key: " + awserr.Body.Message)
+		}
+		keyId = defaultKeyId
+	}
+	output, awserr := s.kms.Encrypt(kms.EncryptInput{
+		KeyId:             encryptionKeyId,
+		Plaintext:         []byte(value),
+		EncryptionContext: map[string]string{"PARAMETER_ARN": parameterArn},
+	})
+	if awserr != nil {
+		return nil, "", InvalidKeyId("Could not encrypt the parameter with KMS key " + keyId + ": " + awserr.Body.Type)
+	}
+	return output.CiphertextBlob, keyId, nil
+}
+
+// value returns the parameter's value. SecureString values are returned encrypted, as base64,
+// unless they are decrypted.
+func (s *SSM) value(parameterArn string, version *ParameterVersion, withDecryption bool) (string, *awserrors.Error) {
+	if version.Type != "SecureString" {
+		return version.Value, nil
+	}
+	if !withDecryption {
+		return base64.StdEncoding.EncodeToString(version.Ciphertext), nil
+	}
+	if s.kms == nil {
+		return "", InvalidKeyId("KMS is not enabled, so the parameter can't be decrypted.")
+	}
+	output, awserr := s.kms.Decrypt(kms.DecryptInput{
+		CiphertextBlob:    version.Ciphertext,
+		EncryptionContext: map[string]string{"PARAMETER_ARN": parameterArn},
+	})
+	if awserr != nil {
+		return "", InvalidKeyId("Could not decrypt the parameter with KMS key " + version.KeyId + ": " + awserr.Body.Type)
+	}
+	return string(output.Plaintext), nil
+}
+
+func (s *SSM) toAPI(parameter *Parameter, version *ParameterVersion, selector string, withDecryption bool) (APIParameter, *awserrors.Error) {
+	value, awserr := s.value(parameter.ARN, version, withDecryption)
+	if awserr != nil {
+		return APIParameter{}, awserr
+	}
+	return APIParameter{
+		ARN:              parameter.ARN,
+		Name:             parameter.Name,
+		Type:             version.Type,
+		Value:            value,
+		Version:          version.Version,
+		DataType:         version.DataType,
+		LastModifiedDate: timestamp.EpochSeconds(version.LastModifiedDate),
+		Selector:         selector,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_PutParameter.html
+func (s *SSM) PutParameter(input PutParameterInput) (*PutParameterOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if len(input.Description) > maxDescriptionLen {
+		return nil, ValidationException("Parameter description must be at most 1024 characters.")
+	}
+	if input.Overwrite && len(input.Tags) > 0 {
+		return nil, ValidationException("Invalid request: tags and overwrite can't be used together. To create a parameter with tags, " +
+			"please remove overwrite flag. To update tags for an existing parameter, please use AddTagsToResource or RemoveTagsFromResource.")
+	}
+	if input.Policies != "" {
+		return nil, ValidationException("Parameter policies are not supported.")
+	}
+
+	tier := input.Tier
+	switch tier {
+	case "", "Standard":
+		tier = "Standard"
+		if len(input.Value) > maxStandardValueLen {
+			return nil, ValidationException("Standard tier parameters support a maximum parameter value of 4096 characters. " +
+				"To create a larger parameter value, upgrade the parameter to use the advanced-parameter tier.")
+		}
+	case "Advanced":
+	case "Intelligent-Tiering":
+		tier = "Standard"
+		if len(input.Value) > maxStandardValueLen {
+			tier = "Advanced"
+		}
+	default:
+		return nil, ValidationException("Invalid Tier: " + input.Tier)
+	}
+	if len(input.Value) == 0 || len(input.Value) > maxAdvancedValueLen {
+		return nil, ValidationException("Parameter value must be between 1 and 8192 characters.")
+	}
+
+	dataType := input.DataType
+	if dataType == "" {
+		dataType = "text"
+	}
+	if !slices.Contains(dataTypes, dataType) {
+		return nil, ValidationException("The following data type is not supported: " + dataType)
+	}
+
+	if input.AllowedPattern != "" {
+		pattern, err := regexp.Compile(input.AllowedPattern)
+		if err != nil {
+			return nil, awserrors.Generate400Exception("InvalidAllowedPatternException", "The allowed pattern is not a valid regular expression.")
+		}
+		if !pattern.MatchString(input.Value) {
+			return nil, ParameterPatternMismatchException("Parameter value, cannot be validated against allowedPattern: " + input.AllowedPattern)
+		}
+	}
+
+	s.mu.Lock()
+	existing, ok := s.parametersByName[input.Name]
+	var existingType string
+	if ok {
+		existingType = existing.latest().Type
+	}
+	s.mu.Unlock()
+
+	if ok && !input.Overwrite {
+		return nil, ParameterAlreadyExists("The parameter already exists. To overwrite this value, set the overwrite option in the request to true.")
+	}
+	typ := input.Type
+	if typ == "" {
+		typ = existingType
+	}
+	if typ == "" {
+		typ = "String"
+	}
+	version := &ParameterVersion{
+		Type:             typ,
+		Value:            input.Value,
+		Description:      input.Description,
+		AllowedPattern:   input.AllowedPattern,
+		Tier:             tier,
+		DataType:         dataType,
+		LastModifiedDate: s.clock(),
+	}
+	switch typ {
+	case "String", "StringList":
+		if input.KeyId != "" {
+			return nil, ValidationException("KeyId is required for SecureString type parameter only.")
+		}
+	case "SecureString":
+		// Encrypt without holding the lock, since it calls KMS.
+		ciphertext, keyId, awserr := s.encrypt(s.parameterArn(input.Name), input.KeyId, input.Value)
+		if awserr != nil {
+			return nil, awserr
+		}
+		version.Value = ""
+		version.Ciphertext = ciphertext
+		version.KeyId = keyId
+	default:
+		return nil, UnsupportedParameterType("The parameter type " + typ + " is not supported.")
+	}
+
+	s.mu.Lock()
+	parameter, ok := s.parametersByName[input.Name]
+	operation := "Update"
+	if !ok {
+		operation = "Create"
+		parameter = &Parameter{
+			Name: input.Name,
+			ARN:  s.parameterArn(input.Name),
+			Tags: input.Tags,
+		}
+		s.parametersByName[input.Name] = parameter
+		version.Version = 1
+	} else {
+		if !input.Overwrite {
+			s.mu.Unlock()
+			return nil, ParameterAlreadyExists("The parameter already exists. To overwrite this value, set the overwrite option in the request to true.")
+		}
+		version.Version = parameter.latest().Version + 1
+	}
+	parameter.Versions = append(parameter.Versions, version)
+	if len(parameter.Versions) > maxVersions {
+		parameter.Versions = parameter.Versions[1:]
+	}
+	s.mu.Unlock()
+
+	s.publishChange(operation, parameter.ARN, parameter.Name, version)
+
+	return &PutParameterOutput{
+		Version: version.Version,
+		Tier:    version.Tier,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_GetParameter.html
+func (s *SSM) GetParameter(input GetParameterInput) (*GetParameterOutput, *awserrors.Error) {
+	s.mu.Lock()
+	parameter, version, selector, awserr := s.lockedGetParameterVersion(input.Name)
+	s.mu.Unlock()
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Decrypt without holding the lock, since it calls KMS.
+	apiParameter, awserr := s.toAPI(parameter, version, selector, input.WithDecryption)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &GetParameterOutput{
+		Parameter: apiParameter,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_GetParameters.html
+func (s *SSM) GetParameters(input GetParametersInput) (*GetParametersOutput, *awserrors.Error) {
+	if len(input.Names) < 1 || len(input.Names) > maxGetParameters {
+		return nil, ValidationException("Names must have between 1 and 10 members.")
+	}
+
+	output := &GetParametersOutput{
+		Parameters:        []APIParameter{},
+		InvalidParameters: []string{},
+	}
+	for _, name := range input.Names {
+		s.mu.Lock()
+		parameter, version, selector, awserr := s.lockedGetParameterVersion(name)
+		s.mu.Unlock()
+		if awserr != nil {
+			output.InvalidParameters = append(output.InvalidParameters, name)
+			continue
+		}
+		apiParameter, awserr := s.toAPI(parameter, version, selector, input.WithDecryption)
+		if awserr != nil {
+			return nil, awserr
+		}
+		output.Parameters = append(output.Parameters, apiParameter)
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_GetParameterHistory.html
+func (s *SSM) GetParameterHistory(input GetParameterHistoryInput) (*GetParameterHistoryOutput, *awserrors.Error) {
+	maxResults, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxMaxResults, input.NextToken,
+		ValidationException("MaxResults must be between 1 and 50."),
+		ValidationException("The NextToken is not valid."))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	parameter, awserr := s.lockedGetParameter(input.Name)
+	var versions []*ParameterVersion
+	if awserr == nil {
+		versions = append(versions, parameter.Versions...)
+	}
+	s.mu.Unlock()
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// Versions are listed oldest first, and the token is the index of the next one.
+	output := &GetParameterHistoryOutput{
+		Parameters: []APIParameterHistory{},
+	}
+	for i := start; i < len(versions); i++ {
+		if len(output.Parameters) == maxResults {
+			output.NextToken = strconv.Itoa(i)
+			break
+		}
+		version := versions[i]
+		value, awserr := s.value(parameter.ARN, version, input.WithDecryption)
+		if awserr != nil {
+			return nil, awserr
+		}
+		output.Parameters = append(output.Parameters, APIParameterHistory{
+			Name:             parameter.Name,
+			Type:             version.Type,
+			Value:            value,
+			Version:          version.Version,
+			DataType:         version.DataType,
+			Description:      version.Description,
+			KeyId:            version.KeyId,
+			AllowedPattern:   version.AllowedPattern,
+			Tier:             version.Tier,
+			LastModifiedDate: timestamp.EpochSeconds(version.LastModifiedDate),
+			LastModifiedUser: "arn:aws:iam::" + s.arnGenerator.AwsAccountId + ":root",
+			Labels:           []string{},
+			Policies:         []any{},
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_DeleteParameter.html
+func (s *SSM) DeleteParameter(input DeleteParameterInput) (*DeleteParameterOutput, *awserrors.Error) {
+	s.mu.Lock()
+	parameter, awserr := s.lockedGetParameter(input.Name)
+	if awserr == nil {
+		delete(s.parametersByName, parameter.Name)
+	}
+	s.mu.Unlock()
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.publishChange("Delete", parameter.ARN, parameter.Name, parameter.latest())
+	return &DeleteParameterOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_DeleteParameters.html
+func (s *SSM) DeleteParameters(input DeleteParametersInput) (*DeleteParametersOutput, *awserrors.Error) {
+	if len(input.Names) < 1 || len(input.Names) > maxGetParameters {
+		return nil, ValidationException("Names must have between 1 and 10 members.")
+	}
+
+	output := &DeleteParametersOutput{
+		DeletedParameters: []string{},
+		InvalidParameters: []string{},
+	}
+	var deleted []*Parameter
+	s.mu.Lock()
+	for _, name := range input.Names {
+		parameter, awserr := s.lockedGetParameter(name)
+		if awserr != nil {
+			output.InvalidParameters = append(output.InvalidParameters, name)
+			continue
+		}
+		delete(s.parametersByName, parameter.Name)
+		deleted = append(deleted, parameter)
+		output.DeletedParameters = append(output.DeletedParameters, name)
+	}
+	s.mu.Unlock()
+
+	for _, parameter := range deleted {
+		s.publishChange("Delete", parameter.ARN, parameter.Name, parameter.latest())
+	}
+	return output, nil
+}
@@ -0,0 +1,260 @@
+package ssm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/services/kms"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+func newSSM(t *testing.T) (*SSM, *kms.KMS) {
+	k, err := kms.New(kms.Options{ArnGenerator: generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(Options{ArnGenerator: generator, KMS: k}), k
+}
+
+func putParameter(t *testing.T, s *SSM, input PutParameterInput) *PutParameterOutput {
+	output, awserr := s.PutParameter(input)
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+func getParameter(t *testing.T, s *SSM, name string, withDecryption bool) APIParameter {
+	output, awserr := s.GetParameter(GetParameterInput{Name: name, WithDecryption: withDecryption})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.Parameter
+}
+
+func TestPutParameter(t *testing.T) {
+	s, _ := newSSM(t)
+	output := putParameter(t, s, PutParameterInput{Name: "/app/url", Value: "http://v1", Type: "String"})
+	if output.Version != 1 || output.Tier != "Standard" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+
+	_, awserr := s.PutParameter(PutParameterInput{Name: "/app/url", Value: "http://v2"})
+	if awserr == nil || awserr.Body.Type != "ParameterAlreadyExists" {
+		t.Fatal("Expected already exists", awserr)
+	}
+	output = putParameter(t, s, PutParameterInput{Name: "/app/url", Value: "http://v2", Overwrite: true})
+	if output.Version != 2 {
+		t.Fatal("Unexpected version", output.Version)
+	}
+
+	parameter := getParameter(t, s, "/app/url", false)
+	if parameter.Value != "http://v2" || parameter.Type != "String" || parameter.Version != 2 ||
+		parameter.ARN != "arn:aws:ssm:us-east-1:123456789012:parameter/app/url" || parameter.DataType != "text" {
+		t.Fatalf("Unexpected parameter: %+v", parameter)
+	}
+	// Versions can be selected, and parameters identified by ARN.
+	if parameter := getParameter(t, s, parameter.ARN+":1", false); parameter.Value != "http://v1" || parameter.Selector != ":1" {
+		t.Fatalf("Unexpected parameter: %+v", parameter)
+	}
+	_, awserr = s.GetParameter(GetParameterInput{Name: "/app/url:3"})
+	if awserr == nil || awserr.Body.Type != "ParameterVersionNotFound" {
+		t.Fatal("Expected version not found", awserr)
+	}
+
+	invalid := []PutParameterInput{
+		{Name: "app/url", Value: "v"},
+		{Name: "/aws/reserved", Value: "v"},
+		{Name: "/bad name", Value: "v"},
+		{Name: "/app/key", Value: "v", KeyId: "alias/aws/ssm"},
+		{Name: "/app/port", Value: "http", AllowedPattern: "^[0-9]+$"},
+		{Name: "/app/huge", Value: string(make([]byte, 4097))},
+	}
+	for _, input := range invalid {
+		if _, awserr := s.PutParameter(input); awserr == nil {
+			t.Fatalf("Expected error for %+v", input)
+		}
+	}
+}
+
+func TestSecureString(t *testing.T) {
+	s, k := newSSM(t)
+	putParameter(t, s, PutParameterInput{Name: "/app/password", Value: "hunter2", Type: "SecureString"})
+
+	key, awserr := k.CreateKey(kms.CreateKeyInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	putParameter(t, s, PutParameterInput{Name: "/app/token", Value: "secret", Type: "SecureString", KeyId: key.KeyMetadata.KeyId})
+
+	// Without decryption, the ciphertext is returned.
+	encrypted := getParameter(t, s, "/app/password", false)
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted.Value)
+	if err != nil || encrypted.Value == "hunter2" || encrypted.Type != "SecureString" {
+		t.Fatalf("Unexpected parameter: %+v", encrypted)
+	}
+	decrypted, awserr := k.Decrypt(kms.DecryptInput{
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: map[string]string{"PARAMETER_ARN": encrypted.ARN},
+	})
+	if awserr != nil || string(decrypted.Plaintext) != "hunter2" {
+		t.Fatal("Unexpected ciphertext", awserr)
+	}
+
+	output, awserr := s.GetParameters(GetParametersInput{
+		Names:          []string{"/app/password", "/app/token", "/app/missing"},
+		WithDecryption: true,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Parameters) != 2 || output.Parameters[0].Value != "hunter2" || output.Parameters[1].Value != "secret" ||
+		len(output.InvalidParameters) != 1 || output.InvalidParameters[0] != "/app/missing" {
+		t.Fatalf("Unexpected parameters: %+v", output)
+	}
+
+	history, awserr := s.GetParameterHistory(GetParameterHistoryInput{Name: "/app/password"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(history.Parameters) != 1 || history.Parameters[0].KeyId != "alias/aws/ssm" {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+
+	// Parameters can't be decrypted with a disabled key.
+	k.DisableKey(kms.DisableKeyInput{KeyId: key.KeyMetadata.KeyId})
+	_, awserr = s.GetParameter(GetParameterInput{Name: "/app/token", WithDecryption: true})
+	if awserr == nil || awserr.Body.Type != "InvalidKeyId" {
+		t.Fatal("Expected invalid key", awserr)
+	}
+	_, awserr = s.PutParameter(PutParameterInput{Name: "/app/other", Value: "v", Type: "SecureString", KeyId: "alias/missing"})
+	if awserr == nil || awserr.Body.Type != "InvalidKeyId" {
+		t.Fatal("Expected invalid key", awserr)
+	}
+
+	// SecureString parameters need KMS.
+	withoutKMS := New(Options{ArnGenerator: generator})
+	_, awserr = withoutKMS.PutParameter(PutParameterInput{Name: "/app/password", Value: "v", Type: "SecureString"})
+	if awserr == nil {
+		t.Fatal("Expected error without KMS")
+	}
+}
+
+func TestDeleteParameters(t *testing.T) {
+	s, _ := newSSM(t)
+	putParameter(t, s, PutParameterInput{Name: "a", Value: "v"})
+	putParameter(t, s, PutParameterInput{Name: "/b", Value: "v"})
+
+	output, awserr := s.DeleteParameters(DeleteParametersInput{Names: []string{"a", "/missing"}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.DeletedParameters) != 1 || len(output.InvalidParameters) != 1 {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	if _, awserr := s.DeleteParameter(DeleteParameterInput{Name: "/b"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	_, awserr = s.GetParameter(GetParameterInput{Name: "/b"})
+	if awserr == nil || awserr.Body.Type != "ParameterNotFound" {
+		t.Fatal("Expected not found", awserr)
+	}
+}
+
+type event struct {
+	region     string
+	source     string
+	detailType string
+	resources  []string
+	detail     parameterChangeDetail
+}
+
+type fakeEventPublisher struct {
+	events []event
+}
+
+func (f *fakeEventPublisher) PutEvent(region string, source string, detailType string, resources []string, detail []byte) error {
+	e := event{region: region, source: source, detailType: detailType, resources: resources}
+	if err := json.Unmarshal(detail, &e.detail); err != nil {
+		return err
+	}
+	f.events = append(f.events, e)
+	return nil
+}
+
+func TestParameterChangeEvents(t *testing.T) {
+	events := &fakeEventPublisher{}
+	s := New(Options{ArnGenerator: generator, Events: events})
+	putParameter(t, s, PutParameterInput{Name: "/app/url", Value: "http://v1", Description: "The URL"})
+	putParameter(t, s, PutParameterInput{Name: "/app/url", Value: "http://v2", Overwrite: true})
+	// Failed changes don't publish events.
+	s.PutParameter(PutParameterInput{Name: "/app/url", Value: "http://v3"})
+	if _, awserr := s.DeleteParameters(DeleteParametersInput{Names: []string{"/app/url", "/app/missing"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	var operations []string
+	for _, e := range events.events {
+		if e.region != "us-east-1" || e.source != "aws.ssm" || e.detailType != "Parameter Store Change" || e.detail.Name != "/app/url" ||
+			len(e.resources) != 1 || e.resources[0] != "arn:aws:ssm:us-east-1:123456789012:parameter/app/url" {
+			t.Fatalf("Unexpected event: %+v", e)
+		}
+		operations = append(operations, e.detail.Operation)
+	}
+	if !slices.Equal(operations, []string{"Create", "Update", "Delete"}) {
+		t.Fatal("Unexpected operations", operations)
+	}
+	if events.events[0].detail.Description != "The URL" || events.events[0].detail.Type != "String" {
+		t.Fatalf("Unexpected detail: %+v", events.events[0].detail)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	s, k := newSSM(t)
+	putParameter(t, s, PutParameterInput{Name: "/app/url", Value: "http://us"})
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected regions which aren't enabled to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, awserr := europe.GetParameter(GetParameterInput{Name: "/app/url"}); awserr == nil {
+		t.Fatal("Expected the parameter not to be in eu-west-1")
+	}
+
+	// SecureString parameters are encrypted with their region's keys.
+	putParameter(t, europe, PutParameterInput{Name: "/app/password", Value: "hunter2", Type: "SecureString"})
+	encrypted := getParameter(t, europe, "/app/password", false)
+	if encrypted.ARN != "arn:aws:ssm:eu-west-1:123456789012:parameter/app/password" {
+		t.Fatal("Unexpected ARN", encrypted.ARN)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := kms.DecryptInput{
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: map[string]string{"PARAMETER_ARN": encrypted.ARN},
+	}
+	if _, awserr := k.Decrypt(input); awserr == nil {
+		t.Fatal("Expected us-east-1's keys not to decrypt eu-west-1's parameter")
+	}
+	europeKeys, err := k.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, awserr := europeKeys.Decrypt(input); awserr != nil || string(decrypted.Plaintext) != "hunter2" {
+		t.Fatal("Unexpected ciphertext", awserr)
+	}
+	if decrypted := getParameter(t, europe, "/app/password", true); decrypted.Value != "hunter2" {
+		t.Fatal("Unexpected value", decrypted.Value)
+	}
+}
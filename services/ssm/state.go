@@ -0,0 +1,96 @@
+package ssm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"aws-in-a-box/region"
+	"aws-in-a-box/state"
+)
+
+type parametersState struct {
+	Parameters []*Parameter
+}
+
+// StateVersion is 2 since parameters of regions other than the default were added, under
+// regions/. Version 1 archives are read as the default region's parameters.
+func (s *SSM) StateVersion() int {
+	return 2
+}
+
+func (s *SSM) StateMigrations() []state.Migration {
+	return []state.Migration{
+		func(e *state.Entries) error { return nil },
+	}
+}
+
+// TryPause pauses changes to each region's parameters while a snapshot copies them.
+func (s *SSM) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *SSM) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's parameters, with all their versions, to the archive.
+// SecureString parameters stay encrypted, so they can only be read if the archive also has their
+// region's KMS keys.
+func (s *SSM) ExportState(w *state.Writer) error {
+	return s.regions.ExportState(w, (*SSM).exportState)
+}
+
+func (s *SSM) exportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported parametersState
+	for _, parameter := range s.parametersByName {
+		exported.Parameters = append(exported.Parameters, parameter)
+	}
+	slices.SortFunc(exported.Parameters, func(a, b *Parameter) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return w.WriteJSON("parameters.json", exported)
+}
+
+// ImportState replaces each region's parameters with the archive's. No events are published for
+// them.
+func (s *SSM) ImportState(r *state.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*SSM).importState, (*SSM).exportState)
+}
+
+func (s *SSM) importState(r *state.Reader) (func() error, error) {
+	var imported parametersState
+	if err := r.ReadJSON("parameters.json", &imported); err != nil {
+		return nil, err
+	}
+	parametersByName := make(map[string]*Parameter)
+	for _, parameter := range imported.Parameters {
+		if parameter.Name == "" || len(parameter.Versions) == 0 {
+			return nil, fmt.Errorf("parameter %q has no versions", parameter.Name)
+		}
+		if parameter.ARN == "" {
+			parameter.ARN = s.parameterArn(parameter.Name)
+		}
+		parametersByName[parameter.Name] = parameter
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.parametersByName = parametersByName
+		return nil
+	}, nil
+}
+
+// StateResources lists the parameters of each region in the archive, for diffing archives.
+var StateResources = region.StateResources(func(r *state.Reader) (map[string]any, error) {
+	var exported parametersState
+	if err := r.ReadJSON("parameters.json", &exported); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for _, parameter := range exported.Parameters {
+		resources["parameter/"+parameter.Name] = parameter
+	}
+	return resources, nil
+})
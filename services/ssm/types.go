@@ -0,0 +1,124 @@
+package ssm
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128" pattern:"[\\p{L}\\p{Z}\\p{N}_.:/=+\\-@]*"`
+	Value string `required:"true" length:",256" pattern:"[\\p{L}\\p{Z}\\p{N}_.:/=+\\-@]*"`
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_Parameter.html
+type APIParameter struct {
+	ARN              string
+	Name             string
+	Type             string
+	Value            string
+	Version          int64
+	DataType         string
+	LastModifiedDate float64
+	// The version, if the name had a selector like /name:3.
+	Selector string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_ParameterHistory.html
+type APIParameterHistory struct {
+	Name             string
+	Type             string
+	Value            string
+	Version          int64
+	DataType         string
+	Description      string `json:",omitempty"`
+	KeyId            string `json:",omitempty"`
+	AllowedPattern   string `json:",omitempty"`
+	Tier             string
+	LastModifiedDate float64
+	LastModifiedUser string
+	Labels           []string
+	Policies         []any
+}
+
+type PutParameterInput struct {
+	Name        string `required:"true" length:"1,2048"`
+	Value       string `required:"true"`
+	Type        string `enum:"String|StringList|SecureString"`
+	Description string `length:",1024"`
+	// The KMS key which encrypts SecureString parameters. Defaults to alias/aws/ssm.
+	KeyId          string `length:"1,256" pattern:"[a-zA-Z0-9:/_-]+"`
+	Overwrite      bool
+	AllowedPattern string   `length:",1024"`
+	Tags           []APITag `length:",1000"`
+	Tier           string   `enum:"Standard|Advanced|Intelligent-Tiering"`
+	Policies       string   `length:"1,4096"`
+	DataType       string   `length:",128"`
+}
+
+type PutParameterOutput struct {
+	Version int64
+	Tier    string
+}
+
+type GetParameterInput struct {
+	// May have a version selector, like /name:3.
+	Name           string `required:"true" length:"1,2048"`
+	WithDecryption bool
+}
+
+type GetParameterOutput struct {
+	Parameter APIParameter
+}
+
+type GetParametersInput struct {
+	Names          []string `required:"true" length:"1,10"`
+	WithDecryption bool
+}
+
+type GetParametersOutput struct {
+	Parameters        []APIParameter
+	InvalidParameters []string
+}
+
+type GetParameterHistoryInput struct {
+	Name           string `required:"true" length:"1,2048"`
+	WithDecryption bool
+	MaxResults     int `range:"1,50"`
+	NextToken      string
+}
+
+type GetParameterHistoryOutput struct {
+	Parameters []APIParameterHistory
+	NextToken  string `json:",omitempty"`
+}
+
+type DeleteParameterInput struct {
+	Name string `required:"true" length:"1,2048"`
+}
+
+type DeleteParameterOutput struct{}
+
+type DeleteParametersInput struct {
+	Names []string `required:"true" length:"1,10"`
+}
+
+type DeleteParametersOutput struct {
+	DeletedParameters []string
+	InvalidParameters []string
+}
+
+// https://docs.aws.amazon.com/systems-manager/latest/APIReference/API_ParameterStringFilter.html
+type APIParameterStringFilter struct {
+	Key    string   `required:"true" length:"1,132" pattern:"tag:.+|Name|Type|KeyId|Path|Label|Tier|DataType"`
+	Option string   `length:"1,10"`
+	Values []string `length:"1,50"`
+}
+
+type GetParametersByPathInput struct {
+	Path             string `required:"true" length:"1,2048"`
+	Recursive        bool
+	ParameterFilters []APIParameterStringFilter
+	WithDecryption   bool
+	MaxResults       int `range:"1,10"`
+	NextToken        string
+}
+
+type GetParametersByPathOutput struct {
+	Parameters []APIParameter
+	NextToken  string `json:",omitempty"`
+}
@@ -0,0 +1,325 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-choice-state.html
+
+// choiceRule is a Choice state's rule, or one of the rules of an And, Or or Not.
+type choiceRule struct {
+	Variable string
+	And      []*choiceRule
+	Or       []*choiceRule
+	Not      *choiceRule
+	// Only set on the top-level rules.
+	Next string
+
+	// The comparison operator, like StringEquals, and the value it's compared with.
+	operator string
+	value    any
+	// Parsed during validation.
+	variable *path
+	pattern  *regexp.Regexp
+}
+
+func (c *choiceRule) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "Variable":
+			err = json.Unmarshal(raw, &c.Variable)
+		case "And":
+			err = json.Unmarshal(raw, &c.And)
+		case "Or":
+			err = json.Unmarshal(raw, &c.Or)
+		case "Not":
+			err = json.Unmarshal(raw, &c.Not)
+		case "Next":
+			err = json.Unmarshal(raw, &c.Next)
+		case "Comment":
+		default:
+			if c.operator != "" {
+				return fmt.Errorf("the choice rule has more than one comparison operator: %s and %s", c.operator, key)
+			}
+			c.operator = key
+			err = json.Unmarshal(raw, &c.value)
+		}
+		if err != nil {
+			return fmt.Errorf("the field %s of the choice rule is invalid: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// operatorType returns the type of the values the operator compares, and the comparison.
+// For example, NumericLessThanEqualsPath is Numeric and LessThanEquals.
+func operatorType(operator string) (string, string) {
+	operator = strings.TrimSuffix(operator, "Path")
+	for _, kind := range []string{"String", "Numeric", "Boolean", "Timestamp"} {
+		if comparison, ok := strings.CutPrefix(operator, kind); ok {
+			return kind, comparison
+		}
+	}
+	return "", ""
+}
+
+var comparisons = map[string]bool{
+	"Equals":            true,
+	"LessThan":          true,
+	"GreaterThan":       true,
+	"LessThanEquals":    true,
+	"GreaterThanEquals": true,
+}
+
+var typeTests = map[string]bool{
+	"IsNull":      true,
+	"IsPresent":   true,
+	"IsNumeric":   true,
+	"IsString":    true,
+	"IsBoolean":   true,
+	"IsTimestamp": true,
+}
+
+// validate checks the rule and prepares it for evaluation. Only the top-level rules have a Next.
+func (c *choiceRule) validate(topLevel bool) error {
+	if topLevel && c.Next == "" {
+		return fmt.Errorf("the choice rule has no Next")
+	}
+	if !topLevel && c.Next != "" {
+		return fmt.Errorf("only top-level choice rules can have a Next")
+	}
+
+	compound := 0
+	if len(c.And) > 0 {
+		compound++
+	}
+	if len(c.Or) > 0 {
+		compound++
+	}
+	if c.Not != nil {
+		compound++
+	}
+	if compound > 0 {
+		if compound > 1 || c.operator != "" || c.Variable != "" {
+			return fmt.Errorf("a choice rule with And, Or or Not can't have other operators")
+		}
+		rules := append([]*choiceRule{}, c.And...)
+		rules = append(rules, c.Or...)
+		if c.Not != nil {
+			rules = append(rules, c.Not)
+		}
+		for _, rule := range rules {
+			if err := rule.validate(false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if c.operator == "" {
+		return fmt.Errorf("the choice rule has no comparison operator")
+	}
+	variable, err := parsePath(c.Variable)
+	if err != nil {
+		return fmt.Errorf("the Variable of the choice rule is invalid: %v", err)
+	}
+	c.variable = variable
+
+	if typeTests[c.operator] {
+		if _, ok := c.value.(bool); !ok {
+			return fmt.Errorf("the value of %s must be a boolean", c.operator)
+		}
+		return nil
+	}
+	if c.operator == "StringMatches" {
+		pattern, ok := c.value.(string)
+		if !ok {
+			return fmt.Errorf("the value of StringMatches must be a string")
+		}
+		c.pattern = wildcardPattern(pattern)
+		return nil
+	}
+
+	kind, comparison := operatorType(c.operator)
+	if kind == "" || !comparisons[comparison] || (kind == "Boolean" && comparison != "Equals") {
+		return fmt.Errorf("unknown comparison operator %s", c.operator)
+	}
+	if strings.HasSuffix(c.operator, "Path") {
+		text, ok := c.value.(string)
+		if !ok {
+			return fmt.Errorf("the value of %s must be a path", c.operator)
+		}
+		if _, err := parsePath(text); err != nil {
+			return fmt.Errorf("the value of %s is invalid: %v", c.operator, err)
+		}
+		return nil
+	}
+	if _, ok := comparable(kind, c.value); !ok {
+		return fmt.Errorf("the value of %s must be a %s", c.operator, strings.ToLower(kind))
+	}
+	return nil
+}
+
+// wildcardPattern converts a StringMatches pattern, where * matches any characters and \* is a literal *,
+// to a regular expression.
+func wildcardPattern(pattern string) *regexp.Regexp {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case pattern[i] == '*':
+			expression.WriteString(".*")
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.MustCompile(expression.String())
+}
+
+// comparable converts the value to one which can be compared for the type, and returns false if it has another type.
+func comparable(kind string, value any) (any, bool) {
+	switch kind {
+	case "String":
+		s, ok := value.(string)
+		return s, ok
+	case "Numeric":
+		n, ok := value.(float64)
+		return n, ok
+	case "Boolean":
+		b, ok := value.(bool)
+		return b, ok
+	case "Timestamp":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	}
+	return nil, false
+}
+
+// compare returns -1, 0 or 1 for values converted by comparable.
+func compare(a any, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		return 1
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 1
+}
+
+// matches evaluates the rule against the Choice state's input.
+func (c *choiceRule) matches(input any, context any) (bool, *stateError) {
+	switch {
+	case len(c.And) > 0:
+		for _, rule := range c.And {
+			matched, err := rule.matches(input, context)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	case len(c.Or) > 0:
+		for _, rule := range c.Or {
+			matched, err := rule.matches(input, context)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	case c.Not != nil:
+		matched, err := c.Not.matches(input, context)
+		return !matched, err
+	}
+
+	root := input
+	if c.variable.context {
+		root = context
+	}
+	value, present := c.variable.get(root)
+	if c.operator == "IsPresent" {
+		return present == c.value.(bool), nil
+	}
+	if !present {
+		return false, runtimeError(fmt.Sprintf("Invalid path '%s': The choice state's condition path references an invalid value.", c.Variable))
+	}
+
+	var matched bool
+	switch c.operator {
+	case "IsNull":
+		matched = value == nil
+	case "IsNumeric":
+		_, matched = value.(float64)
+	case "IsString":
+		_, matched = value.(string)
+	case "IsBoolean":
+		_, matched = value.(bool)
+	case "IsTimestamp":
+		_, matched = comparable("Timestamp", value)
+	case "StringMatches":
+		s, ok := value.(string)
+		return ok && c.pattern.MatchString(s), nil
+	default:
+		expected := c.value
+		if strings.HasSuffix(c.operator, "Path") {
+			p, _ := parsePath(c.value.(string))
+			root := input
+			if p.context {
+				root = context
+			}
+			var ok bool
+			if expected, ok = p.get(root); !ok {
+				return false, runtimeError(fmt.Sprintf("Invalid path '%s': The choice state's condition path references an invalid value.", p.text))
+			}
+		}
+		kind, comparison := operatorType(c.operator)
+		a, ok := comparable(kind, value)
+		b, expectedOk := comparable(kind, expected)
+		if !ok || !expectedOk {
+			return false, nil
+		}
+		result := compare(a, b)
+		switch comparison {
+		case "Equals":
+			return result == 0, nil
+		case "LessThan":
+			return result < 0, nil
+		case "GreaterThan":
+			return result > 0, nil
+		case "LessThanEquals":
+			return result <= 0, nil
+		case "GreaterThanEquals":
+			return result >= 0, nil
+		}
+	}
+	return matched == c.value.(bool), nil
+}
@@ -0,0 +1,83 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func parseRule(t *testing.T, text string) *choiceRule {
+	var rule choiceRule
+	if err := json.Unmarshal([]byte(text), &rule); err != nil {
+		t.Fatal(text, err)
+	}
+	if err := rule.validate(false); err != nil {
+		t.Fatal(text, err)
+	}
+	return &rule
+}
+
+func TestChoiceRules(t *testing.T) {
+	input := decode(t, `{"name": "order-123", "count": 5, "limit": 10, "paid": true, "time": "2023-11-14T22:13:20Z", "nothing": null}`)
+	for text, expected := range map[string]bool{
+		`{"Variable": "$.name", "StringEquals": "order-123"}`:                                                         true,
+		`{"Variable": "$.name", "StringMatches": "order-*"}`:                                                          true,
+		`{"Variable": "$.name", "StringMatches": "order-\\*"}`:                                                        false,
+		`{"Variable": "$.name", "StringLessThan": "order-2"}`:                                                         true,
+		`{"Variable": "$.count", "NumericEquals": 5}`:                                                                 true,
+		`{"Variable": "$.count", "NumericGreaterThanEquals": 6}`:                                                      false,
+		`{"Variable": "$.count", "NumericLessThanPath": "$.limit"}`:                                                   true,
+		`{"Variable": "$.count", "StringEquals": "5"}`:                                                                false,
+		`{"Variable": "$.paid", "BooleanEquals": true}`:                                                               true,
+		`{"Variable": "$.time", "TimestampGreaterThan": "2023-01-01T00:00:00Z"}`:                                      true,
+		`{"Variable": "$.time", "TimestampEquals": "2023-11-14T23:13:20+01:00"}`:                                      true,
+		`{"Variable": "$.missing", "IsPresent": false}`:                                                               true,
+		`{"Variable": "$.nothing", "IsNull": true}`:                                                                   true,
+		`{"Variable": "$.count", "IsNumeric": true}`:                                                                  true,
+		`{"Variable": "$.name", "IsTimestamp": true}`:                                                                 false,
+		`{"Variable": "$$.Execution.Name", "StringEquals": "run"}`:                                                    true,
+		`{"And": [{"Variable": "$.paid", "BooleanEquals": true}, {"Variable": "$.count", "NumericGreaterThan": 1}]}`:  true,
+		`{"Or": [{"Variable": "$.paid", "BooleanEquals": false}, {"Variable": "$.count", "NumericGreaterThan": 10}]}`: false,
+		`{"Not": {"Variable": "$.paid", "BooleanEquals": false}}`:                                                     true,
+	} {
+		matched, err := parseRule(t, text).matches(input, decode(t, `{"Execution": {"Name": "run"}}`))
+		if err != nil {
+			t.Error(text, err)
+			continue
+		}
+		if matched != expected {
+			t.Error("Unexpected match for", text, matched)
+		}
+	}
+
+	// Comparing a variable which doesn't exist fails the execution.
+	_, err := parseRule(t, `{"Variable": "$.missing", "StringEquals": "x"}`).matches(input, nil)
+	if err == nil || err.Error != "States.Runtime" {
+		t.Fatal("Expected a runtime error", err)
+	}
+
+	for _, text := range []string{
+		`{"Variable": "$.name"}`,
+		`{"Variable": "$.name", "StringEquals": 1}`,
+		`{"Variable": "$.name", "NumericEquals": "1"}`,
+		`{"Variable": "$.name", "BooleanLessThan": true}`,
+		`{"Variable": "$.name", "TimestampEquals": "yesterday"}`,
+		`{"Variable": "$.name", "StringEqualsPath": "name"}`,
+		`{"Variable": "name", "StringEquals": "x"}`,
+		`{"Variable": "$.name", "Unknown": "x"}`,
+		`{"Variable": "$.name", "IsNull": "yes"}`,
+		`{"Variable": "$.name", "StringEquals": "x", "Next": "Nested"}`,
+		`{"Variable": "$.name", "And": [{"Variable": "$.name", "IsNull": true}]}`,
+	} {
+		var rule choiceRule
+		if err := json.Unmarshal([]byte(text), &rule); err != nil {
+			continue
+		}
+		if err := rule.validate(false); err == nil {
+			t.Error("Expected invalid rule", text)
+		}
+	}
+	var rule choiceRule
+	if err := json.Unmarshal([]byte(`{"Variable": "$.a", "StringEquals": "x", "NumericEquals": 1}`), &rule); err == nil {
+		t.Error("Expected rules with two operators to be invalid")
+	}
+}
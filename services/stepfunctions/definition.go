@@ -0,0 +1,438 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// https://states-language.net/spec.html
+
+type definition struct {
+	Comment        string
+	StartAt        string
+	States         map[string]*state
+	TimeoutSeconds int
+	Version        string
+}
+
+type state struct {
+	Type    string
+	Comment string
+	Next    string
+	End     bool
+
+	InputPath      optionalPath
+	OutputPath     optionalPath
+	ResultPath     optionalPath
+	Parameters     any
+	ResultSelector any
+	// Pass states' result. Nil if it isn't set.
+	Result json.RawMessage
+
+	// Task states.
+	Resource           string
+	TimeoutSeconds     int
+	TimeoutSecondsPath string
+	HeartbeatSeconds   int
+	Retry              []retrier
+	Catch              []catcher
+
+	// Choice states.
+	Choices []*choiceRule
+	Default string
+
+	// Wait states.
+	Seconds       *float64
+	SecondsPath   string
+	Timestamp     string
+	TimestampPath string
+
+	// Fail states.
+	Error     string
+	ErrorPath string
+	Cause     string
+	CausePath string
+
+	// Parallel states.
+	Branches []*definition
+
+	// Map states. Iterator and Parameters are the older names of ItemProcessor and ItemSelector.
+	ItemsPath      optionalPath
+	ItemProcessor  *definition
+	Iterator       *definition
+	ItemSelector   any
+	MaxConcurrency int
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-error-handling.html#error-handling-retrying-after-an-error
+type retrier struct {
+	ErrorEquals     []string
+	IntervalSeconds *float64
+	MaxAttempts     *int
+	BackoffRate     *float64
+	MaxDelaySeconds float64
+	// FULL or NONE.
+	JitterStrategy string
+}
+
+func (r retrier) interval() float64 {
+	if r.IntervalSeconds == nil {
+		return 1
+	}
+	return *r.IntervalSeconds
+}
+
+func (r retrier) maxAttempts() int {
+	if r.MaxAttempts == nil {
+		return 3
+	}
+	return *r.MaxAttempts
+}
+
+func (r retrier) backoffRate() float64 {
+	if r.BackoffRate == nil {
+		return 2
+	}
+	return *r.BackoffRate
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-error-handling.html#error-handling-fallback-states
+type catcher struct {
+	ErrorEquals []string
+	Next        string
+	ResultPath  optionalPath
+}
+
+// itemProcessor returns the definition Map states run for each item.
+func (s *state) itemProcessor() *definition {
+	if s.ItemProcessor != nil {
+		return s.ItemProcessor
+	}
+	return s.Iterator
+}
+
+// itemSelector returns the template Map states create each item's input with, or nil.
+func (s *state) itemSelector() any {
+	if s.ItemSelector != nil {
+		return s.ItemSelector
+	}
+	return s.Parameters
+}
+
+// definitionError is a problem with a definition, and where in the definition it is.
+type definitionError struct {
+	Code     string
+	Message  string
+	Location string
+}
+
+func (e *definitionError) Error() string {
+	return fmt.Sprintf("Invalid State Machine Definition: '%s: %s at %s'", e.Code, e.Message, e.Location)
+}
+
+func schemaError(location string, format string, args ...any) *definitionError {
+	return &definitionError{Code: "SCHEMA_VALIDATION_FAILED", Message: fmt.Sprintf(format, args...), Location: location}
+}
+
+// parseDefinition parses and validates a state machine's definition.
+func parseDefinition(text string) (*definition, *definitionError) {
+	var def definition
+	if err := json.Unmarshal([]byte(text), &def); err != nil {
+		return nil, &definitionError{Code: "INVALID_JSON_DESCRIPTION", Message: err.Error(), Location: "/"}
+	}
+	if err := def.validate(""); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+var stateTypes = []string{"Pass", "Task", "Choice", "Wait", "Succeed", "Fail", "Parallel", "Map"}
+
+// validate checks the definition, whose states are at location. Branches and item processors are nested
+// definitions, so they're checked the same way.
+func (d *definition) validate(location string) *definitionError {
+	if d.StartAt == "" {
+		return schemaError(location+"/StartAt", "The field 'StartAt' is required")
+	}
+	if len(d.States) == 0 {
+		return schemaError(location+"/States", "The field 'States' is required")
+	}
+	if d.TimeoutSeconds < 0 {
+		return schemaError(location+"/TimeoutSeconds", "TimeoutSeconds must be positive")
+	}
+	if _, ok := d.States[d.StartAt]; !ok {
+		return &definitionError{Code: "MISSING_TRANSITION_TARGET", Message: "Missing 'Next' target: " + d.StartAt, Location: location + "/StartAt"}
+	}
+
+	names := make([]string, 0, len(d.States))
+	for name := range d.States {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if len(name) > 80 {
+			return schemaError(location+"/States/"+name, "State names must be at most 80 characters")
+		}
+		if err := d.validateState(d.States[name], location+"/States/"+name); err != nil {
+			return err
+		}
+	}
+
+	// Every state must be reachable from StartAt.
+	reached := map[string]bool{}
+	pending := []string{d.StartAt}
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if reached[name] {
+			continue
+		}
+		reached[name] = true
+		pending = append(pending, d.States[name].transitions()...)
+	}
+	for _, name := range names {
+		if !reached[name] {
+			return &definitionError{Code: "UNREACHABLE_STATE", Message: "State '" + name + "' is not reachable", Location: location + "/States/" + name}
+		}
+	}
+	return nil
+}
+
+// transitions returns the names of the states the state can transition to.
+func (s *state) transitions() []string {
+	var next []string
+	if s.Next != "" {
+		next = append(next, s.Next)
+	}
+	if s.Default != "" {
+		next = append(next, s.Default)
+	}
+	for _, rule := range s.Choices {
+		next = append(next, rule.Next)
+	}
+	for _, c := range s.Catch {
+		next = append(next, c.Next)
+	}
+	return next
+}
+
+func (d *definition) validateTransition(next string, location string) *definitionError {
+	if _, ok := d.States[next]; !ok {
+		return &definitionError{Code: "MISSING_TRANSITION_TARGET", Message: "Missing 'Next' target: " + next, Location: location}
+	}
+	return nil
+}
+
+func validatePaths(s *state, location string) *definitionError {
+	for field, p := range map[string]optionalPath{"InputPath": s.InputPath, "OutputPath": s.OutputPath} {
+		if err := p.validate(false); err != nil {
+			return schemaError(location+"/"+field, "%v", err)
+		}
+	}
+	if err := s.ResultPath.validate(true); err != nil {
+		return schemaError(location+"/ResultPath", "%v", err)
+	}
+	for field, template := range map[string]any{"Parameters": s.Parameters, "ResultSelector": s.ResultSelector, "ItemSelector": s.ItemSelector} {
+		if err := validateTemplate(template); err != nil {
+			return schemaError(location+"/"+field, "%v", err)
+		}
+	}
+	return nil
+}
+
+func validateErrorEquals(errorEquals []string, location string) *definitionError {
+	if len(errorEquals) == 0 {
+		return schemaError(location+"/ErrorEquals", "ErrorEquals must not be empty")
+	}
+	if i := slices.Index(errorEquals, "States.ALL"); i != -1 && len(errorEquals) != 1 {
+		return schemaError(location+"/ErrorEquals", "States.ALL must appear alone in ErrorEquals")
+	}
+	return nil
+}
+
+func (d *definition) validateState(s *state, location string) *definitionError {
+	if !slices.Contains(stateTypes, s.Type) {
+		return schemaError(location+"/Type", "The value for the field 'Type' must be one of %s", strings.Join(stateTypes, ", "))
+	}
+
+	switch s.Type {
+	case "Choice", "Succeed", "Fail":
+		if s.Next != "" || s.End {
+			return schemaError(location, "%s states can't have Next or End", s.Type)
+		}
+	default:
+		if (s.Next == "") == !s.End {
+			return schemaError(location, "Exactly one of 'Next' or 'End' is required")
+		}
+		if s.Next != "" {
+			if err := d.validateTransition(s.Next, location+"/Next"); err != nil {
+				return err
+			}
+		}
+	}
+	if err := validatePaths(s, location); err != nil {
+		return err
+	}
+
+	for i, r := range s.Retry {
+		retryLocation := fmt.Sprintf("%s/Retry[%d]", location, i)
+		if err := validateErrorEquals(r.ErrorEquals, retryLocation); err != nil {
+			return err
+		}
+		if slices.Contains(r.ErrorEquals, "States.ALL") && i != len(s.Retry)-1 {
+			return schemaError(retryLocation, "The retrier with States.ALL must be the last one")
+		}
+		if r.maxAttempts() < 0 || r.interval() < 1 || r.backoffRate() < 1 || r.MaxDelaySeconds < 0 {
+			return schemaError(retryLocation, "IntervalSeconds and BackoffRate must be at least 1, and MaxAttempts and MaxDelaySeconds must be positive")
+		}
+	}
+	for i, c := range s.Catch {
+		catchLocation := fmt.Sprintf("%s/Catch[%d]", location, i)
+		if err := validateErrorEquals(c.ErrorEquals, catchLocation); err != nil {
+			return err
+		}
+		if slices.Contains(c.ErrorEquals, "States.ALL") && i != len(s.Catch)-1 {
+			return schemaError(catchLocation, "The catcher with States.ALL must be the last one")
+		}
+		if err := d.validateTransition(c.Next, catchLocation+"/Next"); err != nil {
+			return err
+		}
+		if err := c.ResultPath.validate(true); err != nil {
+			return schemaError(catchLocation+"/ResultPath", "%v", err)
+		}
+	}
+	if (len(s.Retry) > 0 || len(s.Catch) > 0) && !slices.Contains([]string{"Task", "Parallel", "Map"}, s.Type) {
+		return schemaError(location, "%s states can't have Retry or Catch", s.Type)
+	}
+
+	switch s.Type {
+	case "Task":
+		if s.Resource == "" {
+			return schemaError(location+"/Resource", "The field 'Resource' is required")
+		}
+		if s.TimeoutSeconds < 0 || s.HeartbeatSeconds < 0 {
+			return schemaError(location, "TimeoutSeconds and HeartbeatSeconds must be positive")
+		}
+		if s.TimeoutSecondsPath != "" {
+			if _, err := parsePath(s.TimeoutSecondsPath); err != nil {
+				return schemaError(location+"/TimeoutSecondsPath", "%v", err)
+			}
+		}
+	case "Choice":
+		if len(s.Choices) == 0 {
+			return schemaError(location+"/Choices", "The field 'Choices' must not be empty")
+		}
+		for i, rule := range s.Choices {
+			ruleLocation := fmt.Sprintf("%s/Choices[%d]", location, i)
+			if err := rule.validate(true); err != nil {
+				return schemaError(ruleLocation, "%v", err)
+			}
+			if err := d.validateTransition(rule.Next, ruleLocation+"/Next"); err != nil {
+				return err
+			}
+		}
+		if s.Default != "" {
+			if err := d.validateTransition(s.Default, location+"/Default"); err != nil {
+				return err
+			}
+		}
+	case "Wait":
+		set := 0
+		for _, field := range []bool{s.Seconds != nil, s.SecondsPath != "", s.Timestamp != "", s.TimestampPath != ""} {
+			if field {
+				set++
+			}
+		}
+		if set != 1 {
+			return schemaError(location, "Exactly one of 'Seconds', 'SecondsPath', 'Timestamp' or 'TimestampPath' is required")
+		}
+		if s.Seconds != nil && *s.Seconds < 0 {
+			return schemaError(location+"/Seconds", "Seconds must be positive")
+		}
+		if s.Timestamp != "" {
+			if _, err := time.Parse(time.RFC3339Nano, s.Timestamp); err != nil {
+				return schemaError(location+"/Timestamp", "The Timestamp must be an RFC3339 timestamp")
+			}
+		}
+		for field, text := range map[string]string{"SecondsPath": s.SecondsPath, "TimestampPath": s.TimestampPath} {
+			if text == "" {
+				continue
+			}
+			if _, err := parsePath(text); err != nil {
+				return schemaError(location+"/"+field, "%v", err)
+			}
+		}
+	case "Fail":
+		for field, text := range map[string]string{"ErrorPath": s.ErrorPath, "CausePath": s.CausePath} {
+			if text == "" {
+				continue
+			}
+			if _, err := parseExpression(text); err != nil {
+				return schemaError(location+"/"+field, "%v", err)
+			}
+		}
+	case "Parallel":
+		if len(s.Branches) == 0 {
+			return schemaError(location+"/Branches", "The field 'Branches' must not be empty")
+		}
+		for i, branch := range s.Branches {
+			if err := branch.validate(fmt.Sprintf("%s/Branches[%d]", location, i)); err != nil {
+				return err
+			}
+		}
+	case "Map":
+		processor := s.itemProcessor()
+		if processor == nil {
+			return schemaError(location, "The field 'ItemProcessor' is required")
+		}
+		if err := s.ItemsPath.validate(false); err != nil {
+			return schemaError(location+"/ItemsPath", "%v", err)
+		}
+		if s.MaxConcurrency < 0 {
+			return schemaError(location+"/MaxConcurrency", "MaxConcurrency must be positive")
+		}
+		if err := processor.validate(location + "/ItemProcessor"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExpress checks the definition, whose states are at location, only has Task states which express state
+// machines can run. They can't wait for jobs to complete, or for task tokens.
+func (d *definition) validateExpress(location string) *definitionError {
+	names := make([]string, 0, len(d.States))
+	for name := range d.States {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		s := d.States[name]
+		stateLocation := location + "/States/" + name
+		switch s.Type {
+		case "Task":
+			if !strings.HasPrefix(s.Resource, integrationPrefix) {
+				continue
+			}
+			for _, pattern := range []string{".sync", ".waitForTaskToken"} {
+				if strings.Contains(s.Resource, pattern) {
+					return schemaError(stateLocation+"/Resource", "Express state machine does not support '%s' service integration", pattern)
+				}
+			}
+		case "Parallel":
+			for i, branch := range s.Branches {
+				if err := branch.validateExpress(fmt.Sprintf("%s/Branches[%d]", stateLocation, i)); err != nil {
+					return err
+				}
+			}
+		case "Map":
+			if err := s.itemProcessor().validateExpress(stateLocation + "/ItemProcessor"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
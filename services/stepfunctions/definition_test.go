@@ -0,0 +1,75 @@
+package stepfunctions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDefinition(t *testing.T) {
+	def, err := parseDefinition(`{
+		"StartAt": "Check",
+		"TimeoutSeconds": 60,
+		"States": {
+			"Check": {"Type": "Choice", "Choices": [{"Variable": "$.ok", "BooleanEquals": true, "Next": "Work"}], "Default": "Failed"},
+			"Work": {
+				"Type": "Task",
+				"Resource": "arn:aws:lambda:us-east-1:123456789012:function:work",
+				"Retry": [{"ErrorEquals": ["Busy"]}, {"ErrorEquals": ["States.ALL"], "MaxAttempts": 0}],
+				"Catch": [{"ErrorEquals": ["States.ALL"], "Next": "Failed", "ResultPath": "$.error"}],
+				"Next": "Each"
+			},
+			"Each": {
+				"Type": "Map",
+				"ItemsPath": "$.items",
+				"ItemProcessor": {"StartAt": "Item", "States": {"Item": {"Type": "Pass", "End": true}}},
+				"Next": "Both"
+			},
+			"Both": {
+				"Type": "Parallel",
+				"Branches": [{"StartAt": "Wait", "States": {"Wait": {"Type": "Wait", "Seconds": 1, "End": true}}}],
+				"End": true
+			},
+			"Failed": {"Type": "Fail", "Error": "Failed"}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := def.States["Work"]
+	if def.TimeoutSeconds != 60 || work.Retry[0].maxAttempts() != 3 || work.Retry[0].interval() != 1 ||
+		work.Retry[0].backoffRate() != 2 || work.Retry[1].maxAttempts() != 0 || work.Catch[0].ResultPath.text != "$.error" {
+		t.Fatalf("Unexpected definition: %+v", def)
+	}
+}
+
+func TestInvalidDefinitions(t *testing.T) {
+	for expected, text := range map[string]string{
+		"INVALID_JSON_DESCRIPTION":                                                          `{`,
+		"SCHEMA_VALIDATION_FAILED: The field 'StartAt'":                                     `{"States": {"A": {"Type": "Succeed"}}}`,
+		"MISSING_TRANSITION_TARGET: Missing 'Next' target: B at /StartAt":                   `{"StartAt": "B", "States": {"A": {"Type": "Succeed"}}}`,
+		"MISSING_TRANSITION_TARGET: Missing 'Next' target: B at /States/A/Next":             `{"StartAt": "A", "States": {"A": {"Type": "Pass", "Next": "B"}}}`,
+		"SCHEMA_VALIDATION_FAILED: Exactly one of 'Next' or 'End' is required at /States/A": `{"StartAt": "A", "States": {"A": {"Type": "Pass"}}}`,
+		"SCHEMA_VALIDATION_FAILED: The value for the field 'Type'":                          `{"StartAt": "A", "States": {"A": {"Type": "Sleep", "End": true}}}`,
+		"UNREACHABLE_STATE: State 'B' is not reachable at /States/B":                        `{"StartAt": "A", "States": {"A": {"Type": "Succeed"}, "B": {"Type": "Succeed"}}}`,
+		"The field 'Resource' is required at /States/A/Resource":                            `{"StartAt": "A", "States": {"A": {"Type": "Task", "End": true}}}`,
+		"States.ALL must appear alone in ErrorEquals at /States/A/Retry[0]/ErrorEquals":     `{"StartAt": "A", "States": {"A": {"Type": "Task", "Resource": "x", "End": true, "Retry": [{"ErrorEquals": ["States.ALL", "X"]}]}}}`,
+		"Missing 'Next' target: C at /States/A/Catch[0]/Next":                               `{"StartAt": "A", "States": {"A": {"Type": "Task", "Resource": "x", "End": true, "Catch": [{"ErrorEquals": ["X"], "Next": "C"}]}}}`,
+		"Pass states can't have Retry or Catch":                                             `{"StartAt": "A", "States": {"A": {"Type": "Pass", "End": true, "Retry": [{"ErrorEquals": ["X"]}]}}}`,
+		"the choice rule has no comparison operator at /States/A/Choices[0]":                `{"StartAt": "A", "States": {"A": {"Type": "Choice", "Choices": [{"Variable": "$.a", "Next": "A"}]}}}`,
+		"Exactly one of 'Seconds', 'SecondsPath', 'Timestamp' or 'TimestampPath'":           `{"StartAt": "A", "States": {"A": {"Type": "Wait", "End": true}}}`,
+		"at /States/A/Branches[0]/States/B/Next":                                            `{"StartAt": "A", "States": {"A": {"Type": "Parallel", "End": true, "Branches": [{"StartAt": "B", "States": {"B": {"Type": "Pass", "Next": "C"}}}]}}}`,
+		"The field 'ItemProcessor' is required":                                             `{"StartAt": "A", "States": {"A": {"Type": "Map", "End": true}}}`,
+		"at /States/A/InputPath":                                                            `{"StartAt": "A", "States": {"A": {"Type": "Pass", "End": true, "InputPath": "a"}}}`,
+		"at /States/A/ResultPath":                                                           `{"StartAt": "A", "States": {"A": {"Type": "Pass", "End": true, "ResultPath": "$.a[*]"}}}`,
+		"at /States/A/Parameters":                                                           `{"StartAt": "A", "States": {"A": {"Type": "Pass", "End": true, "Parameters": {"a.$": "States.Unknown()"}}}}`,
+	} {
+		_, err := parseDefinition(text)
+		if err == nil {
+			t.Error("Expected invalid definition", text)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Error("Unexpected error for", text, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package stepfunctions
+
+import "aws-in-a-box/awserrors"
+
+func ExecutionAlreadyExists(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ExecutionAlreadyExists", message)
+}
+
+func ExecutionDoesNotExist(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ExecutionDoesNotExist", message)
+}
+
+func InvalidArn(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidArn", message)
+}
+
+func InvalidDefinition(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidDefinition", message)
+}
+
+func InvalidExecutionInput(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidExecutionInput", message)
+}
+
+func InvalidLoggingConfiguration(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidLoggingConfiguration", message)
+}
+
+func InvalidName(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidName", message)
+}
+
+func ResourceNotFound(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ResourceNotFound", message)
+}
+
+func StateMachineAlreadyExists(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("StateMachineAlreadyExists", message)
+}
+
+func StateMachineDoesNotExist(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("StateMachineDoesNotExist", message)
+}
+
+func StateMachineTypeNotSupported(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("StateMachineTypeNotSupported", message)
+}
+
+func TooManyTags(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("TooManyTags", message)
+}
+
+func ValidationException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationException", message)
+}
@@ -0,0 +1,473 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+type Execution struct {
+	Arn             string
+	Name            string
+	StateMachineArn string
+	// RUNNING, SUCCEEDED, FAILED, TIMED_OUT or ABORTED.
+	Status      string
+	Input       string
+	Output      string
+	Error       string
+	Cause       string
+	TraceHeader string
+	StartDate   time.Time
+	StopDate    time.Time
+	// The state machine as it was when the execution started.
+	stateMachine StateMachine
+	// For listing executions newest first.
+	number  int
+	history []APIHistoryEvent
+	// Stops the execution's states.
+	cancel context.CancelFunc
+	// Closed once the execution stops running states.
+	done chan struct{}
+}
+
+func (e *Execution) toAPI() APIExecutionListItem {
+	return APIExecutionListItem{
+		ExecutionArn:    e.Arn,
+		StateMachineArn: e.StateMachineArn,
+		Name:            e.Name,
+		Status:          e.Status,
+		StartDate:       timestamp.EpochSeconds(e.StartDate),
+		StopDate:        timestamp.EpochSeconds(e.StopDate),
+	}
+}
+
+func (s *StepFunctions) lockedGetExecution(executionArn string) (*Execution, *awserrors.Error) {
+	if !strings.HasPrefix(executionArn, "arn:") {
+		return nil, InvalidArn("Invalid Arn: '" + executionArn + "'")
+	}
+	// Express executions are only recorded in their logs.
+	if strings.Contains(executionArn, ":express:") {
+		return nil, StateMachineTypeNotSupported("This operation is not supported by this type of state machine")
+	}
+	if !strings.Contains(executionArn, ":execution:") {
+		return nil, InvalidArn("Invalid Arn: '" + executionArn + "'")
+	}
+	execution, ok := s.executions[executionArn]
+	if !ok {
+		return nil, ExecutionDoesNotExist("Execution Does Not Exist: '" + executionArn + "'")
+	}
+	return execution, nil
+}
+
+// lockedAddEvent adds the event to the execution's history, and logs it.
+func (s *StepFunctions) lockedAddEvent(execution *Execution, event APIHistoryEvent) {
+	event.Id = int64(len(execution.history) + 1)
+	event.PreviousEventId = event.Id - 1
+	event.Timestamp = timestamp.EpochSeconds(s.clock())
+	execution.history = append(execution.history, event)
+	s.lockedLogEvent(execution, event)
+}
+
+// lockedEnd records how the execution ended. The error is only used if it didn't succeed.
+func (s *StepFunctions) lockedEnd(execution *Execution, status string, output any, stateErr *stateError) {
+	execution.Status = status
+	execution.StopDate = s.clock()
+	if stateErr != nil {
+		execution.Error = stateErr.Error
+		execution.Cause = stateErr.Cause
+	}
+	switch status {
+	case "SUCCEEDED":
+		execution.Output = toJSON(output)
+		s.lockedAddEvent(execution, APIHistoryEvent{
+			Type:                           "ExecutionSucceeded",
+			ExecutionSucceededEventDetails: &APIExecutionSucceededEventDetails{Output: execution.Output},
+		})
+	case "FAILED":
+		s.lockedAddEvent(execution, APIHistoryEvent{
+			Type:                        "ExecutionFailed",
+			ExecutionFailedEventDetails: &APIErrorEventDetails{Error: execution.Error, Cause: execution.Cause},
+		})
+	case "TIMED_OUT":
+		s.lockedAddEvent(execution, APIHistoryEvent{
+			Type:                          "ExecutionTimedOut",
+			ExecutionTimedOutEventDetails: &APIErrorEventDetails{Error: execution.Error, Cause: execution.Cause},
+		})
+	case "ABORTED":
+		s.lockedAddEvent(execution, APIHistoryEvent{
+			Type:                         "ExecutionAborted",
+			ExecutionAbortedEventDetails: &APIErrorEventDetails{Error: execution.Error, Cause: execution.Cause},
+		})
+	}
+}
+
+// parseExecutionInput fills in the input's defaults, and returns the decoded execution input.
+func parseExecutionInput(input *StartExecutionInput) (any, *awserrors.Error) {
+	if input.Name == "" {
+		input.Name = uuid.Must(uuid.NewV4()).String()
+	}
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if input.Input == "" {
+		input.Input = "{}"
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(input.Input), &decoded); err != nil {
+		return nil, InvalidExecutionInput("Invalid State Machine Execution Input: '" + err.Error() + "'")
+	}
+	return decoded, nil
+}
+
+// lockedNewExecution starts recording a new execution, and returns the context its states run in.
+// Standard executions are kept so they can be described, but express executions are only logged.
+func (s *StepFunctions) lockedNewExecution(stateMachine *StateMachine, executionArn string, input StartExecutionInput) (*Execution, context.Context) {
+	timeout := time.Duration(stateMachine.definition.TimeoutSeconds) * time.Second
+	if stateMachine.Type == "EXPRESS" && (timeout == 0 || timeout > maxExpressDuration) {
+		timeout = maxExpressDuration
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	s.executionCount++
+	execution := &Execution{
+		Arn:             executionArn,
+		Name:            input.Name,
+		StateMachineArn: stateMachine.Arn,
+		Status:          "RUNNING",
+		Input:           input.Input,
+		TraceHeader:     input.TraceHeader,
+		StartDate:       s.clock(),
+		stateMachine:    *stateMachine,
+		number:          s.executionCount,
+		cancel:          cancel,
+		done:            make(chan struct{}),
+	}
+	if stateMachine.Type == "STANDARD" {
+		s.executions[executionArn] = execution
+	}
+	s.lockedAddEvent(execution, APIHistoryEvent{
+		Type: "ExecutionStarted",
+		ExecutionStartedEventDetails: &APIExecutionStartedEventDetails{
+			Input:   input.Input,
+			RoleArn: stateMachine.RoleArn,
+		},
+	})
+	return execution, ctx
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_StartExecution.html
+func (s *StepFunctions) StartExecution(input StartExecutionInput) (*StartExecutionOutput, *awserrors.Error) {
+	decoded, awserr := parseExecutionInput(&input)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, awserr := s.lockedGetStateMachine(input.StateMachineArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	var executionArn string
+	if stateMachine.Type == "EXPRESS" {
+		// Express execution names don't have to be unique.
+		executionArn = s.arnGenerator.GenerateWithoutType("states",
+			"express:"+stateMachine.Name+":"+input.Name+":"+uuid.Must(uuid.NewV4()).String())
+	} else {
+		executionArn = s.arnGenerator.GenerateWithoutType("states", "execution:"+stateMachine.Name+":"+input.Name)
+		if existing, ok := s.executions[executionArn]; ok {
+			// Starting a running execution again with the same input is idempotent.
+			if existing.Status == "RUNNING" && existing.Input == input.Input {
+				return &StartExecutionOutput{
+					ExecutionArn: existing.Arn,
+					StartDate:    timestamp.EpochSeconds(existing.StartDate),
+				}, nil
+			}
+			return nil, ExecutionAlreadyExists("Execution Already Exists: '" + executionArn + "'")
+		}
+	}
+
+	execution, ctx := s.lockedNewExecution(stateMachine, executionArn, input)
+	go s.runExecution(ctx, execution, decoded)
+
+	return &StartExecutionOutput{
+		ExecutionArn: executionArn,
+		StartDate:    timestamp.EpochSeconds(execution.StartDate),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_StartSyncExecution.html
+func (s *StepFunctions) StartSyncExecution(input StartSyncExecutionInput) (*StartSyncExecutionOutput, *awserrors.Error) {
+	if input.IncludedData != "" && input.IncludedData != "ALL_DATA" && input.IncludedData != "METADATA_ONLY" {
+		return nil, ValidationException("1 validation error detected: Value '" + input.IncludedData +
+			"' at 'includedData' failed to satisfy constraint: Member must satisfy enum value set: [ALL_DATA, METADATA_ONLY]")
+	}
+	startInput := StartExecutionInput{
+		StateMachineArn: input.StateMachineArn,
+		Name:            input.Name,
+		Input:           input.Input,
+		TraceHeader:     input.TraceHeader,
+	}
+	decoded, awserr := parseExecutionInput(&startInput)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	stateMachine, awserr := s.lockedGetStateMachine(input.StateMachineArn)
+	if awserr != nil {
+		s.mu.Unlock()
+		return nil, awserr
+	}
+	if stateMachine.Type != "EXPRESS" {
+		s.mu.Unlock()
+		return nil, StateMachineTypeNotSupported("This operation is not supported by this type of state machine")
+	}
+	executionArn := s.arnGenerator.GenerateWithoutType("states",
+		"express:"+stateMachine.Name+":"+startInput.Name+":"+uuid.Must(uuid.NewV4()).String())
+	execution, ctx := s.lockedNewExecution(stateMachine, executionArn, startInput)
+	s.mu.Unlock()
+
+	s.runExecution(ctx, execution, decoded)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Express executions are billed for at least 100ms, in 100ms increments, and 64MB of memory.
+	billedDuration := execution.StopDate.Sub(execution.StartDate).Milliseconds()
+	billedDuration = max((billedDuration+99)/100*100, 100)
+	output := &StartSyncExecutionOutput{
+		ExecutionArn:    execution.Arn,
+		StateMachineArn: execution.StateMachineArn,
+		Name:            execution.Name,
+		Status:          execution.Status,
+		StartDate:       timestamp.EpochSeconds(execution.StartDate),
+		StopDate:        timestamp.EpochSeconds(execution.StopDate),
+		Input:           execution.Input,
+		Output:          execution.Output,
+		Error:           execution.Error,
+		Cause:           execution.Cause,
+		TraceHeader:     execution.TraceHeader,
+		BillingDetails: &APIBillingDetails{
+			BilledMemoryUsedInMB:         64,
+			BilledDurationInMilliseconds: billedDuration,
+		},
+	}
+	if input.IncludedData == "METADATA_ONLY" {
+		output.Input = ""
+		output.Output = ""
+	}
+	return output, nil
+}
+
+// runExecution runs the execution's states, and records how it ended.
+func (s *StepFunctions) runExecution(ctx context.Context, execution *Execution, input any) {
+	defer close(execution.done)
+	defer execution.cancel()
+
+	r := &run{
+		s:         s,
+		ctx:       ctx,
+		execution: execution,
+	}
+	output, stateErr := r.runDefinition(execution.stateMachine.definition, input, r.contextObject(input))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Stopped executions already recorded how they ended.
+	if execution.Status != "RUNNING" {
+		return
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		s.lockedEnd(execution, "TIMED_OUT", nil, &stateError{Error: "States.Timeout"})
+	case stateErr != nil:
+		s.lockedEnd(execution, "FAILED", nil, stateErr)
+	default:
+		s.lockedEnd(execution, "SUCCEEDED", output, nil)
+	}
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_DescribeExecution.html
+func (s *StepFunctions) DescribeExecution(input DescribeExecutionInput) (*DescribeExecutionOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, awserr := s.lockedGetExecution(input.ExecutionArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeExecutionOutput{
+		ExecutionArn:    execution.Arn,
+		StateMachineArn: execution.StateMachineArn,
+		Name:            execution.Name,
+		Status:          execution.Status,
+		StartDate:       timestamp.EpochSeconds(execution.StartDate),
+		StopDate:        timestamp.EpochSeconds(execution.StopDate),
+		Input:           execution.Input,
+		Output:          execution.Output,
+		Error:           execution.Error,
+		Cause:           execution.Cause,
+		TraceHeader:     execution.TraceHeader,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_DescribeStateMachineForExecution.html
+func (s *StepFunctions) DescribeStateMachineForExecution(input DescribeStateMachineForExecutionInput) (*DescribeStateMachineForExecutionOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, awserr := s.lockedGetExecution(input.ExecutionArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	stateMachine := execution.stateMachine
+	return &DescribeStateMachineForExecutionOutput{
+		StateMachineArn:      stateMachine.Arn,
+		Name:                 stateMachine.Name,
+		Definition:           stateMachine.Definition,
+		RoleArn:              stateMachine.RoleArn,
+		UpdateDate:           timestamp.EpochSeconds(stateMachine.UpdateDate),
+		LoggingConfiguration: stateMachine.LoggingConfiguration,
+		TracingConfiguration: stateMachine.TracingConfiguration,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ListExecutions.html
+func (s *StepFunctions) ListExecutions(input ListExecutionsInput) (*ListExecutionsOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		ValidationException("Invalid Token: '"+input.NextToken+"'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, awserr := s.lockedGetStateMachine(input.StateMachineArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if stateMachine.Type == "EXPRESS" {
+		return nil, StateMachineTypeNotSupported("This operation is not supported by this type of state machine")
+	}
+	var executions []*Execution
+	for _, execution := range s.executions {
+		if execution.StateMachineArn != input.StateMachineArn {
+			continue
+		}
+		if input.StatusFilter != "" && input.StatusFilter != execution.Status {
+			continue
+		}
+		executions = append(executions, execution)
+	}
+	slices.SortFunc(executions, func(a, b *Execution) int {
+		return b.number - a.number
+	})
+	items := make([]APIExecutionListItem, 0, len(executions))
+	for _, execution := range executions {
+		items = append(items, execution.toAPI())
+	}
+	output := &ListExecutionsOutput{}
+	output.Executions, output.NextToken = pagination.Page(items, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_StopExecution.html
+func (s *StepFunctions) StopExecution(input StopExecutionInput) (*StopExecutionOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, awserr := s.lockedGetExecution(input.ExecutionArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	// Stopping an execution which already ended does nothing.
+	if execution.Status != "RUNNING" {
+		return &StopExecutionOutput{StopDate: timestamp.EpochSeconds(execution.StopDate)}, nil
+	}
+	s.lockedEnd(execution, "ABORTED", nil, &stateError{Error: input.Error, Cause: input.Cause})
+	execution.cancel()
+	return &StopExecutionOutput{StopDate: timestamp.EpochSeconds(execution.StopDate)}, nil
+}
+
+// withoutExecutionData returns the event without its inputs and outputs.
+func withoutExecutionData(event APIHistoryEvent) APIHistoryEvent {
+	if d := event.ExecutionStartedEventDetails; d != nil {
+		event.ExecutionStartedEventDetails = &APIExecutionStartedEventDetails{RoleArn: d.RoleArn}
+	}
+	if event.ExecutionSucceededEventDetails != nil {
+		event.ExecutionSucceededEventDetails = &APIExecutionSucceededEventDetails{}
+	}
+	if d := event.StateEnteredEventDetails; d != nil {
+		event.StateEnteredEventDetails = &APIStateEnteredEventDetails{Name: d.Name}
+	}
+	if d := event.StateExitedEventDetails; d != nil {
+		event.StateExitedEventDetails = &APIStateExitedEventDetails{Name: d.Name}
+	}
+	if d := event.LambdaFunctionScheduledEventDetails; d != nil {
+		event.LambdaFunctionScheduledEventDetails = &APILambdaFunctionScheduledEventDetails{Resource: d.Resource, TimeoutInSeconds: d.TimeoutInSeconds}
+	}
+	if event.LambdaFunctionSucceededEventDetails != nil {
+		event.LambdaFunctionSucceededEventDetails = &APIOutputEventDetails{}
+	}
+	if d := event.TaskScheduledEventDetails; d != nil {
+		withoutParameters := *d
+		withoutParameters.Parameters = ""
+		event.TaskScheduledEventDetails = &withoutParameters
+	}
+	if d := event.TaskSucceededEventDetails; d != nil {
+		withoutOutput := *d
+		withoutOutput.Output = ""
+		event.TaskSucceededEventDetails = &withoutOutput
+	}
+	return event
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_GetExecutionHistory.html
+func (s *StepFunctions) GetExecutionHistory(input GetExecutionHistoryInput) (*GetExecutionHistoryOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		ValidationException("Invalid Token: '"+input.NextToken+"'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, awserr := s.lockedGetExecution(input.ExecutionArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	events := slices.Clone(execution.history)
+	if input.ReverseOrder {
+		slices.Reverse(events)
+	}
+	if input.IncludeExecutionData != nil && !*input.IncludeExecutionData {
+		for i, event := range events {
+			events[i] = withoutExecutionData(event)
+		}
+	}
+	output := &GetExecutionHistoryOutput{}
+	output.Events, output.NextToken = pagination.Page(events, limit, start)
+	return output, nil
+}
@@ -0,0 +1,656 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/cloudwatchlogs"
+	"aws-in-a-box/services/lambda"
+)
+
+const functionArn = "arn:aws:lambda:us-east-1:123456789012:function:"
+
+// fakeLambda calls the handler of the function, or echoes the payload if it doesn't have one.
+type fakeLambda struct {
+	mu       sync.Mutex
+	handlers map[string]func(payload []byte) *lambda.InvokeOutput
+	payloads map[string][]string
+}
+
+func (f *fakeLambda) Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error) {
+	f.mu.Lock()
+	if f.payloads == nil {
+		f.payloads = make(map[string][]string)
+	}
+	f.payloads[input.FunctionName] = append(f.payloads[input.FunctionName], string(input.Payload))
+	handler := f.handlers[input.FunctionName]
+	f.mu.Unlock()
+
+	if strings.HasSuffix(input.FunctionName, "missing") {
+		return nil, awserrors.Generate400Exception("ResourceNotFoundException", "Function not found")
+	}
+	if handler == nil {
+		return &lambda.InvokeOutput{StatusCode: 200, ExecutedVersion: "$LATEST", Payload: input.Payload}, nil
+	}
+	return handler(input.Payload), nil
+}
+
+func (f *fakeLambda) handle(functionName string, handler func(payload []byte) *lambda.InvokeOutput) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.handlers == nil {
+		f.handlers = make(map[string]func(payload []byte) *lambda.InvokeOutput)
+	}
+	f.handlers[functionName] = handler
+}
+
+func startExecution(t *testing.T, s *StepFunctions, stateMachineArn string, input string) string {
+	output, awserr := s.StartExecution(StartExecutionInput{StateMachineArn: stateMachineArn, Input: input})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.ExecutionArn
+}
+
+func waitForExecution(t *testing.T, s *StepFunctions, executionArn string) *DescribeExecutionOutput {
+	s.mu.Lock()
+	execution := s.executions[executionArn]
+	s.mu.Unlock()
+	select {
+	case <-execution.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the execution")
+	}
+	output, awserr := s.DescribeExecution(DescribeExecutionInput{ExecutionArn: executionArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output
+}
+
+// execute runs the definition with the input, and returns how the execution ended.
+func execute(t *testing.T, s *StepFunctions, definition string, input string) *DescribeExecutionOutput {
+	s.mu.Lock()
+	name := "machine" + string(rune('a'+len(s.stateMachines)))
+	s.mu.Unlock()
+	stateMachineArn := createStateMachine(t, s, name, definition)
+	return waitForExecution(t, s, startExecution(t, s, stateMachineArn, input))
+}
+
+func expectOutput(t *testing.T, execution *DescribeExecutionOutput, expected string) {
+	t.Helper()
+	if execution.Status != "SUCCEEDED" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(expected), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if execution.Output != toJSON(decoded) {
+		t.Fatal("Unexpected output", execution.Output)
+	}
+}
+
+func historyTypes(t *testing.T, s *StepFunctions, executionArn string) []string {
+	history, awserr := s.GetExecutionHistory(GetExecutionHistoryInput{ExecutionArn: executionArn, MaxResults: 1000})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	var types []string
+	for _, event := range history.Events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestPassStates(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	execution := execute(t, s, `{
+		"StartAt": "Parameters",
+		"States": {
+			"Parameters": {
+				"Type": "Pass",
+				"InputPath": "$.order",
+				"Parameters": {
+					"id.$": "$.id",
+					"greeting.$": "States.Format('Order {} of {}', $.id, $$.StateMachine.Name)",
+					"constant": {"nested.$": "$.items[0]"},
+					"state.$": "$$.State.Name"
+				},
+				"ResultPath": "$.order",
+				"Next": "Result"
+			},
+			"Result": {"Type": "Pass", "Result": {"ok": true}, "ResultPath": "$.result", "Next": "Output"},
+			"Output": {"Type": "Pass", "ResultPath": null, "OutputPath": "$.order", "Next": "Done"},
+			"Done": {"Type": "Succeed"}
+		}
+	}`, `{"order": {"id": 7, "items": ["a"]}}`)
+	expectOutput(t, execution, `{"id": 7, "greeting": "Order 7 of machinea", "constant": {"nested": "a"}, "state": "Parameters"}`)
+
+	types := historyTypes(t, s, execution.ExecutionArn)
+	expected := []string{"ExecutionStarted", "PassStateEntered", "PassStateExited", "PassStateEntered", "PassStateExited",
+		"PassStateEntered", "PassStateExited", "SucceedStateEntered", "SucceedStateExited", "ExecutionSucceeded"}
+	if !slices.Equal(types, expected) {
+		t.Fatal("Unexpected history", types)
+	}
+}
+
+func TestLambdaTasks(t *testing.T) {
+	fake := &fakeLambda{}
+	fake.handle(functionArn+"double", func(payload []byte) *lambda.InvokeOutput {
+		var input struct{ N int }
+		json.Unmarshal(payload, &input)
+		result, _ := json.Marshal(map[string]int{"n": input.N * 2})
+		return &lambda.InvokeOutput{StatusCode: 200, ExecutedVersion: "$LATEST", Payload: result}
+	})
+	s, _ := newStepFunctions(Options{Lambda: fake})
+
+	execution := execute(t, s, `{
+		"StartAt": "Direct",
+		"States": {
+			"Direct": {"Type": "Task", "Resource": "`+functionArn+`double", "Parameters": {"N.$": "$.n"}, "Next": "Integration"},
+			"Integration": {
+				"Type": "Task",
+				"Resource": "arn:aws:states:::lambda:invoke",
+				"Parameters": {"FunctionName": "`+functionArn+`double", "Payload": {"N.$": "$.n"}},
+				"ResultSelector": {"doubled.$": "$.Payload.n", "status.$": "$.StatusCode"},
+				"ResultPath": "$.result",
+				"End": true
+			}
+		}
+	}`, `{"n": 3}`)
+	expectOutput(t, execution, `{"n": 6, "result": {"doubled": 12, "status": 200}}`)
+	if payloads := fake.payloads[functionArn+"double"]; len(payloads) != 2 || payloads[0] != `{"N":3}` || payloads[1] != `{"N":6}` {
+		t.Fatal("Unexpected payloads", payloads)
+	}
+
+	types := historyTypes(t, s, execution.ExecutionArn)
+	expected := []string{"ExecutionStarted",
+		"TaskStateEntered", "LambdaFunctionScheduled", "LambdaFunctionStarted", "LambdaFunctionSucceeded", "TaskStateExited",
+		"TaskStateEntered", "TaskScheduled", "TaskStarted", "TaskSucceeded", "TaskStateExited",
+		"ExecutionSucceeded"}
+	if !slices.Equal(types, expected) {
+		t.Fatal("Unexpected history", types)
+	}
+
+	// Tasks can't invoke functions which don't exist, or without Lambda.
+	execution = execute(t, s, `{"StartAt": "Task", "States": {"Task": {"Type": "Task", "Resource": "`+functionArn+`missing", "End": true}}}`, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "Lambda.ResourceNotFoundException" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	s, _ = newStepFunctions(Options{})
+	execution = execute(t, s, `{"StartAt": "Task", "States": {"Task": {"Type": "Task", "Resource": "`+functionArn+`double", "End": true}}}`, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "Lambda.ServiceException" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
+
+func TestRetryAndCatch(t *testing.T) {
+	fake := &fakeLambda{}
+	calls := 0
+	fake.handle(functionArn+"flaky", func(payload []byte) *lambda.InvokeOutput {
+		calls++
+		if calls < 3 {
+			return &lambda.InvokeOutput{StatusCode: 200, FunctionError: "Unhandled", Payload: []byte(`{"errorType": "Busy", "errorMessage": "Try again"}`)}
+		}
+		return &lambda.InvokeOutput{StatusCode: 200, Payload: []byte(`"done"`)}
+	})
+	fake.handle(functionArn+"broken", func(payload []byte) *lambda.InvokeOutput {
+		return &lambda.InvokeOutput{StatusCode: 200, FunctionError: "Unhandled", Payload: []byte(`{"errorMessage": "Broken"}`)}
+	})
+	s, slept := newStepFunctions(Options{Lambda: fake})
+
+	execution := execute(t, s, `{
+		"StartAt": "Flaky",
+		"States": {
+			"Flaky": {
+				"Type": "Task",
+				"Resource": "`+functionArn+`flaky",
+				"Retry": [
+					{"ErrorEquals": ["Other"], "MaxAttempts": 10},
+					{"ErrorEquals": ["Busy"], "IntervalSeconds": 2, "BackoffRate": 3}
+				],
+				"ResultPath": "$.flaky",
+				"Next": "Broken"
+			},
+			"Broken": {
+				"Type": "Task",
+				"Resource": "`+functionArn+`broken",
+				"Retry": [{"ErrorEquals": ["States.TaskFailed"], "MaxAttempts": 1, "IntervalSeconds": 5, "MaxDelaySeconds": 4}],
+				"Catch": [{"ErrorEquals": ["States.ALL"], "ResultPath": "$.error", "Next": "Recovered"}],
+				"Next": "Recovered"
+			},
+			"Recovered": {"Type": "Pass", "End": true}
+		}
+	}`, `{}`)
+	expectOutput(t, execution, `{"flaky": "done", "error": {"Error": "Lambda.Unknown", "Cause": "{\"errorMessage\": \"Broken\"}"}}`)
+	if !slices.Equal(slept.durations, []time.Duration{2 * time.Second, 6 * time.Second, 4 * time.Second}) {
+		t.Fatal("Unexpected retry delays", slept.durations)
+	}
+
+	// Without a matching catcher, the error fails the execution.
+	execution = execute(t, s, `{
+		"StartAt": "Broken",
+		"States": {
+			"Broken": {
+				"Type": "Task",
+				"Resource": "`+functionArn+`broken",
+				"Catch": [{"ErrorEquals": ["Busy"], "Next": "Recovered"}],
+				"End": true
+			},
+			"Recovered": {"Type": "Pass", "End": true}
+		}
+	}`, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "Lambda.Unknown" || execution.Cause != `{"errorMessage": "Broken"}` {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	types := historyTypes(t, s, execution.ExecutionArn)
+	if types[len(types)-2] != "LambdaFunctionFailed" || types[len(types)-1] != "ExecutionFailed" {
+		t.Fatal("Unexpected history", types)
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	fake := &fakeLambda{}
+	release := make(chan struct{})
+	defer close(release)
+	fake.handle(functionArn+"slow", func(payload []byte) *lambda.InvokeOutput {
+		<-release
+		return &lambda.InvokeOutput{StatusCode: 200, Payload: payload}
+	})
+	s, _ := newStepFunctions(Options{Lambda: fake})
+
+	execution := execute(t, s, `{
+		"StartAt": "Slow",
+		"States": {
+			"Slow": {
+				"Type": "Task",
+				"Resource": "`+functionArn+`slow",
+				"TimeoutSecondsPath": "$.timeout",
+				"Catch": [{"ErrorEquals": ["States.TaskFailed"], "Next": "Failed"}, {"ErrorEquals": ["States.Timeout"], "Next": "TimedOut"}],
+				"End": true
+			},
+			"Failed": {"Type": "Fail", "Error": "Failed"},
+			"TimedOut": {"Type": "Pass", "End": true}
+		}
+	}`, `{"timeout": 1}`)
+	expectOutput(t, execution, `{"Error": "States.Timeout", "Cause": "The task timed out."}`)
+	types := historyTypes(t, s, execution.ExecutionArn)
+	if !slices.Contains(types, "LambdaFunctionTimedOut") {
+		t.Fatal("Unexpected history", types)
+	}
+}
+
+func TestChoiceAndFailStates(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	definition := `{
+		"StartAt": "Route",
+		"States": {
+			"Route": {
+				"Type": "Choice",
+				"Choices": [
+					{"Variable": "$.total", "NumericGreaterThan": 100, "Next": "Large"},
+					{"And": [{"Variable": "$.total", "IsPresent": true}, {"Variable": "$.total", "NumericGreaterThan": 0}], "Next": "Small"}
+				]
+			},
+			"Large": {"Type": "Fail", "ErrorPath": "$.error", "CausePath": "States.Format('Total {} is too large', $.total)"},
+			"Small": {"Type": "Pass", "Result": "small", "End": true}
+		}
+	}`
+	execution := execute(t, s, definition, `{"total": 10}`)
+	expectOutput(t, execution, `"small"`)
+
+	s.DeleteStateMachine(DeleteStateMachineInput{StateMachineArn: execution.StateMachineArn})
+	execution = execute(t, s, definition, `{"total": 1000, "error": "TooLarge"}`)
+	if execution.Status != "FAILED" || execution.Error != "TooLarge" || execution.Cause != "Total 1000 is too large" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	types := historyTypes(t, s, execution.ExecutionArn)
+	expected := []string{"ExecutionStarted", "ChoiceStateEntered", "ChoiceStateExited", "FailStateEntered", "ExecutionFailed"}
+	if !slices.Equal(types, expected) {
+		t.Fatal("Unexpected history", types)
+	}
+
+	s.DeleteStateMachine(DeleteStateMachineInput{StateMachineArn: execution.StateMachineArn})
+	execution = execute(t, s, definition, `{"total": -1}`)
+	if execution.Status != "FAILED" || execution.Error != "States.NoChoiceMatched" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
+
+func TestWaitStates(t *testing.T) {
+	s, slept := newStepFunctions(Options{})
+	execution := execute(t, s, `{
+		"StartAt": "Seconds",
+		"States": {
+			"Seconds": {"Type": "Wait", "Seconds": 5, "Next": "SecondsPath"},
+			"SecondsPath": {"Type": "Wait", "SecondsPath": "$.delay", "Next": "TimestampPath"},
+			"TimestampPath": {"Type": "Wait", "TimestampPath": "$.until", "Next": "Past"},
+			"Past": {"Type": "Wait", "Timestamp": "2000-01-01T00:00:00Z", "End": true}
+		}
+	}`, `{"delay": 3, "until": "2023-11-14T22:13:30Z"}`)
+	expectOutput(t, execution, `{"delay": 3, "until": "2023-11-14T22:13:30Z"}`)
+	if !slices.Equal(slept.durations, []time.Duration{5 * time.Second, 3 * time.Second, 10 * time.Second, 0}) {
+		t.Fatal("Unexpected waits", slept.durations)
+	}
+
+	execution = execute(t, s, `{"StartAt": "Wait", "States": {"Wait": {"Type": "Wait", "SecondsPath": "$.delay", "End": true}}}`, `{"delay": "soon"}`)
+	if execution.Status != "FAILED" || execution.Error != "States.Runtime" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
+
+func TestMapAndParallelStates(t *testing.T) {
+	fake := &fakeLambda{}
+	fake.handle(functionArn+"check", func(payload []byte) *lambda.InvokeOutput {
+		if strings.Contains(string(payload), "bad") {
+			return &lambda.InvokeOutput{StatusCode: 200, FunctionError: "Unhandled", Payload: []byte(`{"errorType": "BadItem"}`)}
+		}
+		return &lambda.InvokeOutput{StatusCode: 200, Payload: payload}
+	})
+	s, _ := newStepFunctions(Options{Lambda: fake})
+
+	definition := `{
+		"StartAt": "Both",
+		"States": {
+			"Both": {
+				"Type": "Parallel",
+				"Branches": [
+					{
+						"StartAt": "Each",
+						"States": {
+							"Each": {
+								"Type": "Map",
+								"ItemsPath": "$.items",
+								"MaxConcurrency": 1,
+								"ItemSelector": {"index.$": "$$.Map.Item.Index", "item.$": "$$.Map.Item.Value", "prefix.$": "$.prefix"},
+								"ItemProcessor": {
+									"StartAt": "Check",
+									"States": {"Check": {"Type": "Task", "Resource": "` + functionArn + `check", "End": true}}
+								},
+								"End": true
+							}
+						}
+					},
+					{"StartAt": "Count", "States": {"Count": {"Type": "Pass", "Parameters": {"count.$": "States.ArrayLength($.items)"}, "End": true}}}
+				],
+				"Catch": [{"ErrorEquals": ["BadItem"], "Next": "Caught"}],
+				"End": true
+			},
+			"Caught": {"Type": "Pass", "End": true}
+		}
+	}`
+	execution := execute(t, s, definition, `{"prefix": "item-", "items": ["a", "b"]}`)
+	expectOutput(t, execution, `[[{"index": 0, "item": "a", "prefix": "item-"}, {"index": 1, "item": "b", "prefix": "item-"}], {"count": 2}]`)
+
+	types := historyTypes(t, s, execution.ExecutionArn)
+	for _, expected := range []string{"ParallelStateStarted", "MapStateStarted", "MapIterationStarted", "MapIterationSucceeded", "MapStateSucceeded", "ParallelStateSucceeded"} {
+		if !slices.Contains(types, expected) {
+			t.Fatal("Expected a", expected, "event", types)
+		}
+	}
+
+	s.DeleteStateMachine(DeleteStateMachineInput{StateMachineArn: execution.StateMachineArn})
+	execution = execute(t, s, definition, `{"prefix": "item-", "items": ["a", "bad", "c"]}`)
+	expectOutput(t, execution, `{"Error": "BadItem", "Cause": "{\"errorType\": \"BadItem\"}"}`)
+	types = historyTypes(t, s, execution.ExecutionArn)
+	for _, expected := range []string{"MapIterationFailed", "MapStateFailed", "ParallelStateFailed"} {
+		if !slices.Contains(types, expected) {
+			t.Fatal("Expected a", expected, "event", types)
+		}
+	}
+
+	execution = execute(t, s, `{"StartAt": "Each", "States": {"Each": {"Type": "Map", "ItemsPath": "$.items", "Iterator": {"StartAt": "A", "States": {"A": {"Type": "Succeed"}}}, "End": true}}}`, `{"items": "none"}`)
+	if execution.Status != "FAILED" || execution.Error != "States.Runtime" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
+
+func TestStopExecution(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	// Waits last until the execution stops.
+	s.sleep = func(ctx context.Context, d time.Duration) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	stateMachineArn := createStateMachine(t, s, "waits", `{"StartAt": "Wait", "States": {"Wait": {"Type": "Wait", "Seconds": 60, "End": true}}}`)
+	executionArn := startExecution(t, s, stateMachineArn, `{}`)
+	for !slices.Contains(historyTypes(t, s, executionArn), "WaitStateEntered") {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, awserr := s.StopExecution(StopExecutionInput{ExecutionArn: executionArn, Error: "Stopped", Cause: "Testing"}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	execution := waitForExecution(t, s, executionArn)
+	if execution.Status != "ABORTED" || execution.Error != "Stopped" || execution.Cause != "Testing" || execution.StopDate == 0 {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	types := historyTypes(t, s, executionArn)
+	if !slices.Equal(types, []string{"ExecutionStarted", "WaitStateEntered", "ExecutionAborted"}) {
+		t.Fatal("Unexpected history", types)
+	}
+	// Stopping it again does nothing.
+	if _, awserr := s.StopExecution(StopExecutionInput{ExecutionArn: executionArn}); awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	timeoutArn := createStateMachine(t, s, "timeout", `{"StartAt": "Wait", "TimeoutSeconds": 1, "States": {"Wait": {"Type": "Wait", "Seconds": 60, "End": true}}}`)
+	execution = waitForExecution(t, s, startExecution(t, s, timeoutArn, `{}`))
+	if execution.Status != "TIMED_OUT" || execution.Error != "States.Timeout" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
+
+func TestExecutions(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	stateMachineArn := createStateMachine(t, s, "orders", passDefinition)
+
+	output, awserr := s.StartExecution(StartExecutionInput{StateMachineArn: stateMachineArn, Name: "first", Input: `{"a": 1}`})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.ExecutionArn != "arn:aws:states:us-east-1:123456789012:execution:orders:first" {
+		t.Fatal("Unexpected ARN", output.ExecutionArn)
+	}
+	first := waitForExecution(t, s, output.ExecutionArn)
+	expectOutput(t, first, `{"a": 1}`)
+	second := waitForExecution(t, s, startExecution(t, s, stateMachineArn, ""))
+	if second.Input != "{}" || second.Name == "" {
+		t.Fatalf("Unexpected execution: %+v", second)
+	}
+
+	for _, test := range []struct {
+		input StartExecutionInput
+		code  string
+	}{
+		{StartExecutionInput{StateMachineArn: stateMachineArn, Name: "first"}, "ExecutionAlreadyExists"},
+		{StartExecutionInput{StateMachineArn: stateMachineArn, Input: "{"}, "InvalidExecutionInput"},
+		{StartExecutionInput{StateMachineArn: stateMachineArn + "x"}, "StateMachineDoesNotExist"},
+		{StartExecutionInput{StateMachineArn: stateMachineArn, Name: "a/b"}, "InvalidName"},
+	} {
+		_, awserr := s.StartExecution(test.input)
+		if awserr == nil || awserr.Body.Type != test.code {
+			t.Errorf("Expected %s for %+v: %v", test.code, test.input, awserr)
+		}
+	}
+
+	list, awserr := s.ListExecutions(ListExecutionsInput{StateMachineArn: stateMachineArn, StatusFilter: "SUCCEEDED"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.Executions) != 2 || list.Executions[0].Name != second.Name || list.Executions[1].Name != "first" {
+		t.Fatalf("Unexpected executions: %+v", list)
+	}
+
+	// Executions keep the definition they started with.
+	s.UpdateStateMachine(UpdateStateMachineInput{StateMachineArn: stateMachineArn, Definition: `{"StartAt": "A", "States": {"A": {"Type": "Succeed"}}}`})
+	described, awserr := s.DescribeStateMachineForExecution(DescribeStateMachineForExecutionInput{ExecutionArn: first.ExecutionArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if described.Definition != passDefinition {
+		t.Fatal("Unexpected definition", described.Definition)
+	}
+
+	history, awserr := s.GetExecutionHistory(GetExecutionHistoryInput{ExecutionArn: first.ExecutionArn, MaxResults: 2, ReverseOrder: true})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(history.Events) != 2 || history.Events[0].Type != "ExecutionSucceeded" || history.Events[0].Id != 4 ||
+		history.Events[0].PreviousEventId != 3 || history.NextToken == "" {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+	withoutData := false
+	history, _ = s.GetExecutionHistory(GetExecutionHistoryInput{ExecutionArn: first.ExecutionArn, IncludeExecutionData: &withoutData})
+	if history.Events[0].ExecutionStartedEventDetails.Input != "" || history.Events[1].StateEnteredEventDetails.Name != "Pass" ||
+		history.Events[1].StateEnteredEventDetails.Input != "" {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+
+	if _, awserr := s.DescribeExecution(DescribeExecutionInput{ExecutionArn: first.ExecutionArn + "x"}); awserr == nil || awserr.Body.Type != "ExecutionDoesNotExist" {
+		t.Fatal("Expected the execution not to exist", awserr)
+	}
+}
+
+func TestExpressExecutions(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	output, awserr := s.CreateStateMachine(CreateStateMachineInput{Name: "express", Definition: passDefinition, RoleArn: roleArn, Type: "EXPRESS"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	expressArn := output.StateMachineArn
+
+	execution, awserr := s.StartSyncExecution(StartSyncExecutionInput{StateMachineArn: expressArn, Name: "sync", Input: `{"a": 1}`})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if !strings.HasPrefix(execution.ExecutionArn, "arn:aws:states:us-east-1:123456789012:express:express:sync:") ||
+		execution.Status != "SUCCEEDED" || execution.Output != `{"a":1}` || execution.BillingDetails.BilledDurationInMilliseconds != 100 {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	// Express execution names don't have to be unique.
+	again, awserr := s.StartSyncExecution(StartSyncExecutionInput{StateMachineArn: expressArn, Name: "sync", IncludedData: "METADATA_ONLY"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if again.ExecutionArn == execution.ExecutionArn || again.Input != "" || again.Output != "" {
+		t.Fatalf("Unexpected execution: %+v", again)
+	}
+
+	// Express executions can't be described, and standard ones can't be run synchronously.
+	if _, awserr := s.DescribeExecution(DescribeExecutionInput{ExecutionArn: execution.ExecutionArn}); awserr == nil || awserr.Body.Type != "StateMachineTypeNotSupported" {
+		t.Fatal("Expected StateMachineTypeNotSupported", awserr)
+	}
+	if _, awserr := s.ListExecutions(ListExecutionsInput{StateMachineArn: expressArn}); awserr == nil || awserr.Body.Type != "StateMachineTypeNotSupported" {
+		t.Fatal("Expected StateMachineTypeNotSupported", awserr)
+	}
+	standardArn := createStateMachine(t, s, "standard", passDefinition)
+	if _, awserr := s.StartSyncExecution(StartSyncExecutionInput{StateMachineArn: standardArn}); awserr == nil || awserr.Body.Type != "StateMachineTypeNotSupported" {
+		t.Fatal("Expected StateMachineTypeNotSupported", awserr)
+	}
+
+	output, awserr = s.CreateStateMachine(CreateStateMachineInput{Name: "failing", RoleArn: roleArn, Type: "EXPRESS",
+		Definition: `{"StartAt": "Fail", "States": {"Fail": {"Type": "Fail", "Error": "Oops", "Cause": "Broken"}}}`})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	failed, awserr := s.StartSyncExecution(StartSyncExecutionInput{StateMachineArn: output.StateMachineArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if failed.Status != "FAILED" || failed.Error != "Oops" || failed.Cause != "Broken" {
+		t.Fatalf("Unexpected execution: %+v", failed)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	s.maxHistoryEvents = 10
+	execution := execute(t, s, `{
+		"StartAt": "Loop",
+		"States": {
+			"Loop": {"Type": "Pass", "Next": "Loop"}
+		}
+	}`, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "States.Runtime" ||
+		execution.Cause != "The execution reached the maximum number of history events (10)." {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	types := historyTypes(t, s, execution.ExecutionArn)
+	if len(types) != 10 || types[9] != "ExecutionFailed" {
+		t.Fatal("Unexpected history", types)
+	}
+}
+
+func TestExecutionLogging(t *testing.T) {
+	logs := cloudwatchlogs.New(cloudwatchlogs.Options{})
+	s, _ := newStepFunctions(Options{Logs: logs})
+	for _, level := range []string{"ALL", "ERROR"} {
+		_, awserr := s.CreateStateMachine(CreateStateMachineInput{
+			Name:       strings.ToLower(level),
+			Definition: `{"StartAt": "Fail", "States": {"Fail": {"Type": "Fail", "Error": "Oops"}}}`,
+			RoleArn:    roleArn,
+			Type:       "EXPRESS",
+			LoggingConfiguration: &APILoggingConfiguration{
+				Level: level,
+				Destinations: []APILogDestination{{CloudWatchLogsLogGroup: &APICloudWatchLogsLogGroup{
+					LogGroupArn: "arn:aws:logs:us-east-1:123456789012:log-group:/aws/states/" + level + ":*",
+				}}},
+			},
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+	}
+
+	for level, expected := range map[string][]string{
+		"ALL":   {"ExecutionStarted", "FailStateEntered", "ExecutionFailed"},
+		"ERROR": {"ExecutionFailed"},
+	} {
+		name := strings.ToLower(level)
+		execution, awserr := s.StartSyncExecution(StartSyncExecutionInput{
+			StateMachineArn: "arn:aws:states:us-east-1:123456789012:stateMachine:" + name,
+			Name:            "run",
+			Input:           `{"secret": true}`,
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		events, awserr := logs.GetLogEvents(cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  "/aws/states/" + level,
+			LogStreamName: "states/" + name + "/2023-11-14-22-13/run",
+			StartFromHead: true,
+		})
+		if awserr != nil {
+			t.Fatal(awserr)
+		}
+		var types []string
+		for _, event := range events.Events {
+			var logged logEvent
+			if err := json.Unmarshal([]byte(event.Message), &logged); err != nil {
+				t.Fatal(err)
+			}
+			if logged.ExecutionArn != execution.ExecutionArn || logged.EventTimestamp != "1700000000000" {
+				t.Fatalf("Unexpected event: %+v", logged)
+			}
+			// Execution data isn't logged unless includeExecutionData is set.
+			if logged.Type == "ExecutionStarted" && (logged.Details["roleArn"] != roleArn || logged.Details["input"] != "") {
+				t.Fatalf("Unexpected event: %+v", logged)
+			}
+			if logged.Type == "ExecutionFailed" && logged.Details["error"] != "Oops" {
+				t.Fatalf("Unexpected event: %+v", logged)
+			}
+			types = append(types, logged.Type)
+		}
+		if !slices.Equal(types, expected) {
+			t.Errorf("Unexpected %s events: %v", level, types)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package stepfunctions
+
+import (
+	"log/slog"
+
+	"aws-in-a-box/http"
+	"aws-in-a-box/region"
+)
+
+const service = "AWSStepFunctions"
+
+func (s *StepFunctions) RegisterHTTPHandlers(logger *slog.Logger, methodRegistry http.Registry) {
+	http.RegisterWithRequest(logger, methodRegistry, service, "CreateStateMachine", region.Method(s.regions, (*StepFunctions).CreateStateMachine))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DeleteStateMachine", region.Method(s.regions, (*StepFunctions).DeleteStateMachine))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeExecution", region.Method(s.regions, (*StepFunctions).DescribeExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeStateMachine", region.Method(s.regions, (*StepFunctions).DescribeStateMachine))
+	http.RegisterWithRequest(logger, methodRegistry, service, "DescribeStateMachineForExecution", region.Method(s.regions, (*StepFunctions).DescribeStateMachineForExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "GetExecutionHistory", region.Method(s.regions, (*StepFunctions).GetExecutionHistory))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListExecutions", region.Method(s.regions, (*StepFunctions).ListExecutions))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListStateMachines", region.Method(s.regions, (*StepFunctions).ListStateMachines))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ListTagsForResource", region.Method(s.regions, (*StepFunctions).ListTagsForResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StartExecution", region.Method(s.regions, (*StepFunctions).StartExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StartSyncExecution", region.Method(s.regions, (*StepFunctions).StartSyncExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "StopExecution", region.Method(s.regions, (*StepFunctions).StopExecution))
+	http.RegisterWithRequest(logger, methodRegistry, service, "TagResource", region.Method(s.regions, (*StepFunctions).TagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UntagResource", region.Method(s.regions, (*StepFunctions).UntagResource))
+	http.RegisterWithRequest(logger, methodRegistry, service, "UpdateStateMachine", region.Method(s.regions, (*StepFunctions).UpdateStateMachine))
+	http.RegisterWithRequest(logger, methodRegistry, service, "ValidateStateMachineDefinition", region.Method(s.regions, (*StepFunctions).ValidateStateMachineDefinition))
+}
@@ -0,0 +1,284 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+)
+
+// Optimized integrations with the other local services. Their parameters are the service's request, and their
+// result is its response, with the same field names as the AWS SDKs.
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-supported-services.html
+
+// serviceError raises an error a service returned, named like the SDK's exception.
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-supported-services.html#connect-error-names
+func serviceError(service string, awserr *awserrors.Error) *stateError {
+	errorType := awserr.Body.Type
+	if !strings.HasSuffix(errorType, "Exception") {
+		errorType += "Exception"
+	}
+	return &stateError{Error: service + "." + errorType, Cause: awserr.Body.Message}
+}
+
+// decodeParameters decodes the parameters of the integration into the service's input.
+func decodeParameters(resource string, parameters map[string]any, input any) *stateError {
+	decoder := json.NewDecoder(strings.NewReader(toJSON(parameters)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(input); err != nil {
+		return runtimeError("The parameters of " + resource + " are invalid: " + err.Error())
+	}
+	return nil
+}
+
+// withStringField returns the parameters with the field encoded as JSON text, if it isn't already a string.
+// Messages and execution inputs can be given as JSON objects.
+func withStringField(parameters map[string]any, field string) map[string]any {
+	value, ok := parameters[field]
+	if _, isString := value.(string); !ok || isString {
+		return parameters
+	}
+	copied := make(map[string]any, len(parameters))
+	for key, v := range parameters {
+		copied[key] = v
+	}
+	copied[field] = toJSON(value)
+	return copied
+}
+
+// taskResult returns the service's output as the task's result, without the fields which aren't set.
+func taskResult(output any) map[string]any {
+	var result map[string]any
+	if err := json.Unmarshal([]byte(toJSON(output)), &result); err != nil {
+		panic(err)
+	}
+	for field, value := range result {
+		if value == nil {
+			delete(result, field)
+		}
+	}
+	return result
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-sqs.html
+func sendMessageIntegration(r *run, parameters map[string]any) (any, *stateError) {
+	if r.s.sqs == nil {
+		return nil, &stateError{Error: "SQS.ServiceException", Cause: "SQS is not enabled."}
+	}
+	var input sqs.SendMessageInput
+	if err := decodeParameters("sqs:sendMessage", withStringField(parameters, "MessageBody"), &input); err != nil {
+		return nil, err
+	}
+	output, awserr := r.s.sqs.SendMessage(input)
+	if awserr != nil {
+		return nil, serviceError("SQS", awserr)
+	}
+	return taskResult(output), nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-sns.html
+func publishIntegration(r *run, parameters map[string]any) (any, *stateError) {
+	if r.s.sns == nil {
+		return nil, &stateError{Error: "SNS.ServiceException", Cause: "SNS is not enabled."}
+	}
+	var input sns.PublishInput
+	if err := decodeParameters("sns:publish", withStringField(parameters, "Message"), &input); err != nil {
+		return nil, err
+	}
+	// Topics may be in other regions, but messages to phone numbers are sent from the state
+	// machine's.
+	topicArn := input.TopicArn
+	if topicArn == "" {
+		topicArn = input.TargetArn
+	}
+	topics := r.s.sns
+	if topicArn != "" {
+		var err error
+		if topics, err = r.s.sns.ForARN(topicArn); err != nil {
+			return nil, &stateError{Error: "SNS.InvalidParameterException", Cause: err.Error()}
+		}
+	}
+	output, awserr := topics.Publish(input)
+	if awserr != nil {
+		return nil, serviceError("SNS", awserr)
+	}
+	return taskResult(output), nil
+}
+
+// dynamoDBIntegration returns an integration which calls the DynamoDB operation.
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+func dynamoDBIntegration[Input any, Output any](action string, call func(d *dynamodb.DynamoDB, input Input) (*Output, *awserrors.Error)) integration {
+	return func(r *run, parameters map[string]any) (any, *stateError) {
+		if r.s.dynamoDB == nil {
+			return nil, &stateError{Error: "DynamoDB.ServiceException", Cause: "DynamoDB is not enabled."}
+		}
+		var input Input
+		if err := decodeParameters("dynamodb:"+action, parameters, &input); err != nil {
+			return nil, err
+		}
+		output, awserr := call(r.s.dynamoDB, input)
+		if awserr != nil {
+			return nil, serviceError("DynamoDB", awserr)
+		}
+		return taskResult(output), nil
+	}
+}
+
+type getItemInput struct {
+	TableName                string
+	Key                      dynamodb.APIItem
+	ConsistentRead           bool
+	ExpressionAttributeNames map[string]string
+	ProjectionExpression     string
+	ReturnConsumedCapacity   string
+}
+
+type getItemOutput struct {
+	Item dynamodb.APIItem `json:",omitempty"`
+}
+
+// getItem gets an item with a BatchGetItem of its key, which DynamoDB reads the same way.
+func getItem(d *dynamodb.DynamoDB, input getItemInput) (*getItemOutput, *awserrors.Error) {
+	output, awserr := d.BatchGetItem(dynamodb.BatchGetItemInput{
+		RequestItems: map[string]dynamodb.APIKeysAndAttributes{
+			input.TableName: {
+				ConsistentRead:           input.ConsistentRead,
+				ExpressionAttributeNames: input.ExpressionAttributeNames,
+				Keys:                     []dynamodb.APIItem{input.Key},
+				ProjectionExpression:     input.ProjectionExpression,
+			},
+		},
+	})
+	if awserr != nil {
+		return nil, awserr
+	}
+	if items := output.Responses[input.TableName]; len(items) > 0 {
+		return &getItemOutput{Item: items[0]}, nil
+	}
+	return &getItemOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-stepfunctions.html
+func startExecutionIntegration(r *run, parameters map[string]any) (any, *stateError) {
+	var input StartExecutionInput
+	if err := decodeParameters("states:startExecution", withStringField(parameters, "Input"), &input); err != nil {
+		return nil, err
+	}
+	output, awserr := r.s.StartExecution(input)
+	if awserr != nil {
+		return nil, serviceError("StepFunctions", awserr)
+	}
+	return map[string]any{
+		"ExecutionArn": output.ExecutionArn,
+		"StartDate":    epochMilliseconds(output.StartDate),
+	}, nil
+}
+
+func epochMilliseconds(seconds float64) float64 {
+	return math.Round(seconds * 1000)
+}
+
+// startExecutionSyncIntegration returns an integration which starts an execution of another state machine,
+// and waits for it to complete. The result's Input and Output are JSON text, or decoded JSON if decode is set.
+// Executions which don't succeed fail the task, with the result as the cause.
+func startExecutionSyncIntegration(decode bool) integration {
+	return func(r *run, parameters map[string]any) (any, *stateError) {
+		var input StartExecutionInput
+		if err := decodeParameters("states:startExecution.sync", withStringField(parameters, "Input"), &input); err != nil {
+			return nil, err
+		}
+
+		r.s.mu.Lock()
+		stateMachine, awserr := r.s.lockedGetStateMachine(input.StateMachineArn)
+		var stateMachineType string
+		if awserr == nil {
+			stateMachineType = stateMachine.Type
+		}
+		r.s.mu.Unlock()
+		if awserr != nil {
+			return nil, serviceError("StepFunctions", awserr)
+		}
+
+		var described *DescribeExecutionOutput
+		if stateMachineType == "EXPRESS" {
+			// Express executions aren't kept, so they're run synchronously instead.
+			output, awserr := r.s.StartSyncExecution(StartSyncExecutionInput{
+				StateMachineArn: input.StateMachineArn,
+				Name:            input.Name,
+				Input:           input.Input,
+				TraceHeader:     input.TraceHeader,
+			})
+			if awserr != nil {
+				return nil, serviceError("StepFunctions", awserr)
+			}
+			described = &DescribeExecutionOutput{
+				ExecutionArn:    output.ExecutionArn,
+				StateMachineArn: output.StateMachineArn,
+				Name:            output.Name,
+				Status:          output.Status,
+				StartDate:       output.StartDate,
+				StopDate:        output.StopDate,
+				Input:           output.Input,
+				Output:          output.Output,
+				Error:           output.Error,
+				Cause:           output.Cause,
+				TraceHeader:     output.TraceHeader,
+			}
+		} else {
+			output, awserr := r.s.StartExecution(input)
+			if awserr != nil {
+				return nil, serviceError("StepFunctions", awserr)
+			}
+			r.s.mu.Lock()
+			child := r.s.executions[output.ExecutionArn]
+			r.s.mu.Unlock()
+			select {
+			case <-child.done:
+			case <-r.ctx.Done():
+				// Stopping the execution stops the executions it's waiting for.
+				r.s.StopExecution(StopExecutionInput{ExecutionArn: child.Arn})
+				return nil, stoppedError
+			}
+			if described, awserr = r.s.DescribeExecution(DescribeExecutionInput{ExecutionArn: child.Arn}); awserr != nil {
+				return nil, serviceError("StepFunctions", awserr)
+			}
+		}
+
+		result := map[string]any{
+			"ExecutionArn":    described.ExecutionArn,
+			"StateMachineArn": described.StateMachineArn,
+			"Name":            described.Name,
+			"Status":          described.Status,
+			"StartDate":       epochMilliseconds(described.StartDate),
+			"StopDate":        epochMilliseconds(described.StopDate),
+			"Input":           described.Input,
+			"InputDetails":    map[string]any{"Included": true},
+		}
+		if described.Status == "SUCCEEDED" {
+			result["Output"] = described.Output
+			result["OutputDetails"] = map[string]any{"Included": true}
+		} else {
+			result["Error"] = described.Error
+			result["Cause"] = described.Cause
+		}
+		if decode {
+			for _, field := range []string{"Input", "Output"} {
+				if text, ok := result[field].(string); ok {
+					var decoded any
+					if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+						panic(err)
+					}
+					result[field] = decoded
+				}
+			}
+		}
+		if described.Status != "SUCCEEDED" {
+			return nil, &stateError{Error: "States.TaskFailed", Cause: toJSON(result)}
+		}
+		return result, nil
+	}
+}
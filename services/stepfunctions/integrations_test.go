@@ -0,0 +1,224 @@
+package stepfunctions
+
+import (
+	"strings"
+	"testing"
+
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+)
+
+func TestServiceIntegrations(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	queue, awserr := q.CreateQueue(sqs.CreateQueueInput{QueueName: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	d, err := dynamodb.New(dynamodb.Options{ArnGenerator: generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, awserr = d.CreateTable(dynamodb.CreateTableInput{
+		TableName:            "orders",
+		AttributeDefinitions: []dynamodb.APIAttributeDefinition{{AttributeName: "pk", AttributeType: "S"}},
+		KeySchema:            []dynamodb.APIKeySchemaElement{{AttributeName: "pk", KeyType: "HASH"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	s, _ := newStepFunctions(Options{SQS: q, DynamoDB: d})
+
+	definition := `{
+		"StartAt": "Send",
+		"States": {
+			"Send": {
+				"Type": "Task",
+				"Resource": "arn:aws:states:::sqs:sendMessage",
+				"Parameters": {"QueueUrl": "` + queue.QueueUrl + `", "MessageBody.$": "$"},
+				"ResultSelector": {"sent.$": "States.ArrayLength(States.Array($.MessageId))"},
+				"ResultPath": "$.send",
+				"Next": "Put"
+			},
+			"Put": {
+				"Type": "Task",
+				"Resource": "arn:aws:states:::dynamodb:putItem",
+				"Parameters": {
+					"TableName": "orders",
+					"Item": {"pk": {"S.$": "$.id"}},
+					"ConditionExpression": "attribute_not_exists(pk)"
+				},
+				"ResultPath": null,
+				"Catch": [{"ErrorEquals": ["DynamoDB.ConditionalCheckFailedException"], "ResultPath": "$.error", "Next": "Get"}],
+				"Next": "Get"
+			},
+			"Get": {
+				"Type": "Task",
+				"Resource": "arn:aws:states:::dynamodb:getItem",
+				"Parameters": {"TableName": "orders", "Key": {"pk": {"S.$": "$.id"}}},
+				"ResultSelector": {"pk.$": "$.Item.pk.S"},
+				"ResultPath": "$.got",
+				"End": true
+			}
+		}
+	}`
+	execution := execute(t, s, definition, `{"id": "a"}`)
+	expectOutput(t, execution, `{"id": "a", "send": {"sent": 1}, "got": {"pk": "a"}}`)
+	messages, awserr := q.ReceiveMessage(sqs.ReceiveMessageInput{QueueUrl: queue.QueueUrl})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(messages.Messages) != 1 || messages.Messages[0].Body != `{"id":"a"}` {
+		t.Fatalf("Unexpected messages: %+v", messages)
+	}
+
+	// The item exists now, so the condition fails the second time.
+	output, awserr := s.StartExecution(StartExecutionInput{StateMachineArn: execution.StateMachineArn, Input: `{"id": "a"}`})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	execution = waitForExecution(t, s, output.ExecutionArn)
+	if execution.Status != "SUCCEEDED" || !strings.Contains(execution.Output, "DynamoDB.ConditionalCheckFailedException") {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+
+	execution = execute(t, s, `{
+		"StartAt": "Send",
+		"States": {
+			"Send": {"Type": "Task", "Resource": "arn:aws:states:::sqs:sendMessage", "End": true,
+				"Parameters": {"QueueUrl": "missing", "MessageBody": "hello"}}
+		}
+	}`, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "SQS.QueueDoesNotExistException" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	execution = execute(t, s, `{
+		"StartAt": "Publish",
+		"States": {
+			"Publish": {"Type": "Task", "Resource": "arn:aws:states:::sns:publish", "End": true,
+				"Parameters": {"TopicArn": "topic", "Message": "hello"}}
+		}
+	}`, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "SNS.ServiceException" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
+
+func TestStartExecutionIntegrations(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	childArn := createStateMachine(t, s, "child", `{
+		"StartAt": "Check",
+		"States": {
+			"Check": {"Type": "Choice", "Choices": [{"Variable": "$.fail", "IsPresent": true, "Next": "Fail"}], "Default": "Done"},
+			"Fail": {"Type": "Fail", "Error": "Oops"},
+			"Done": {"Type": "Pass", "End": true}
+		}
+	}`)
+	output, awserr := s.CreateStateMachine(CreateStateMachineInput{Name: "express", Definition: passDefinition, RoleArn: roleArn, Type: "EXPRESS"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	expressArn := output.StateMachineArn
+
+	parent := func(resource string, stateMachineArn string) string {
+		return `{
+			"StartAt": "Child",
+			"States": {
+				"Child": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::` + resource + `",
+					"Parameters": {"StateMachineArn": "` + stateMachineArn + `", "Input.$": "$"},
+					"End": true
+				}
+			}
+		}`
+	}
+	execution := execute(t, s, parent("states:startExecution.sync:2", childArn), `{"x": 1}`)
+	if execution.Status != "SUCCEEDED" || !strings.Contains(execution.Output, `"Output":{"x":1}`) ||
+		!strings.Contains(execution.Output, `"Status":"SUCCEEDED"`) {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	execution = execute(t, s, parent("states:startExecution.sync", childArn), `{"x": 1}`)
+	if execution.Status != "SUCCEEDED" || !strings.Contains(execution.Output, `"Output":"{\"x\":1}"`) {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	execution = execute(t, s, parent("states:startExecution.sync:2", expressArn), `{"x": 1}`)
+	if execution.Status != "SUCCEEDED" || !strings.Contains(execution.Output, `"Output":{"x":1}`) {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	execution = execute(t, s, parent("states:startExecution.sync:2", childArn), `{"fail": true}`)
+	if execution.Status != "FAILED" || execution.Error != "States.TaskFailed" || !strings.Contains(execution.Cause, `"Error":"Oops"`) {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	execution = execute(t, s, parent("states:startExecution", childArn), `{}`)
+	if execution.Status != "SUCCEEDED" || !strings.Contains(execution.Output, `"ExecutionArn":"arn:aws:states:us-east-1:123456789012:execution:child:`) {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+
+	// Express state machines can't wait for other executions.
+	_, awserr = s.CreateStateMachine(CreateStateMachineInput{Name: "waits", Definition: parent("states:startExecution.sync", childArn), RoleArn: roleArn, Type: "EXPRESS"})
+	if awserr == nil || awserr.Body.Type != "InvalidDefinition" || !strings.Contains(awserr.Body.Message, "/States/Child/Resource") {
+		t.Fatal("Expected InvalidDefinition", awserr)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	q := sqs.New(sqs.Options{ArnGenerator: generator})
+	europeQueues, err := q.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue, awserr := europeQueues.CreateQueue(sqs.CreateQueueInput{QueueName: "orders"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	topics := sns.New(sns.Options{ArnGenerator: generator})
+	europeTopics, err := topics.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, awserr := europeTopics.CreateTopic(sns.CreateTopicInput{Name: "alerts"})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	s, _ := newStepFunctions(Options{SQS: q, SNS: topics})
+	if _, err := s.InRegion("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected an unknown region to fail")
+	}
+	europe, err := s.InRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue is found by its URL in the state machine's region.
+	definition := `{
+		"StartAt": "Send",
+		"States": {
+			"Send": {"Type": "Task", "Resource": "arn:aws:states:::sqs:sendMessage", "End": true,
+				"Parameters": {"QueueUrl": "` + queue.QueueUrl + `", "MessageBody": "hello"}}
+		}
+	}`
+	execution := execute(t, europe, definition, `{}`)
+	if execution.Status != "SUCCEEDED" || !strings.HasPrefix(execution.StateMachineArn, "arn:aws:states:eu-west-1:123456789012:stateMachine:") {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+	if _, awserr := s.DescribeStateMachine(DescribeStateMachineInput{StateMachineArn: execution.StateMachineArn}); awserr == nil {
+		t.Fatal("Expected another region's state machine to be hidden")
+	}
+	execution = execute(t, s, definition, `{}`)
+	if execution.Status != "FAILED" || execution.Error != "SQS.QueueDoesNotExistException" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+
+	// Topics are found by their ARNs.
+	execution = execute(t, s, `{
+		"StartAt": "Publish",
+		"States": {
+			"Publish": {"Type": "Task", "Resource": "arn:aws:states:::sns:publish", "End": true,
+				"Parameters": {"TopicArn": "`+topic.TopicArn+`", "Message": "hello"}}
+		}
+	}`, `{}`)
+	if execution.Status != "SUCCEEDED" {
+		t.Fatalf("Unexpected execution: %+v", execution)
+	}
+}
@@ -0,0 +1,493 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"aws-in-a-box/timestamp"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-states.html
+
+// stateError is an error states raise, which retriers and catchers match by name.
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-error-handling.html
+type stateError struct {
+	Error string
+	Cause string
+}
+
+// runtimeError fails the execution. It can't be retried or caught.
+func runtimeError(cause string) *stateError {
+	return &stateError{Error: "States.Runtime", Cause: cause}
+}
+
+// stoppedError is returned by states which stop because the execution was stopped or timed out.
+var stoppedError = runtimeError("The execution stopped.")
+
+// run runs the states of an execution, or of one of its branches or Map iterations.
+type run struct {
+	s         *StepFunctions
+	ctx       context.Context
+	execution *Execution
+}
+
+// record adds the event to the execution's history, unless it was stopped.
+// Standard executions fail instead once their history is full, leaving room for the ExecutionFailed event.
+func (r *run) record(event APIHistoryEvent) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.execution.Status != "RUNNING" {
+		return
+	}
+	if r.execution.stateMachine.Type == "STANDARD" && len(r.execution.history)+1 >= r.s.maxHistoryEvents {
+		r.s.lockedEnd(r.execution, "FAILED", nil, runtimeError(fmt.Sprintf(
+			"The execution reached the maximum number of history events (%d).", r.s.maxHistoryEvents)))
+		r.execution.cancel()
+		return
+	}
+	r.s.lockedAddEvent(r.execution, event)
+}
+
+// contextObject returns the context object, which paths starting with $$ select from.
+// https://docs.aws.amazon.com/step-functions/latest/dg/input-output-contextobject.html
+func (r *run) contextObject(input any) map[string]any {
+	return map[string]any{
+		"Execution": map[string]any{
+			"Id":        r.execution.Arn,
+			"Input":     input,
+			"Name":      r.execution.Name,
+			"RoleArn":   r.execution.stateMachine.RoleArn,
+			"StartTime": timestamp.ISO8601Milli(r.execution.StartDate),
+		},
+		"StateMachine": map[string]any{
+			"Id":   r.execution.stateMachine.Arn,
+			"Name": r.execution.stateMachine.Name,
+		},
+	}
+}
+
+// withContext returns a copy of the context object with the field set.
+func withContext(contextObject map[string]any, field string, value any) map[string]any {
+	copied := make(map[string]any, len(contextObject)+1)
+	for key, v := range contextObject {
+		copied[key] = v
+	}
+	copied[field] = value
+	return copied
+}
+
+// runDefinition runs the states from StartAt until one ends, and returns its output.
+func (r *run) runDefinition(d *definition, input any, contextObject map[string]any) (any, *stateError) {
+	name := d.StartAt
+	for {
+		if r.ctx.Err() != nil {
+			return nil, stoppedError
+		}
+		output, next, err := r.runState(name, d.States[name], input, contextObject)
+		if err != nil {
+			return nil, err
+		}
+		if next == "" {
+			return output, nil
+		}
+		name, input = next, output
+	}
+}
+
+// runState runs the state, and returns its output and the name of the next state, or "" if it's the last one.
+func (r *run) runState(name string, s *state, input any, contextObject map[string]any) (any, string, *stateError) {
+	contextObject = withContext(contextObject, "State", map[string]any{
+		"Name":        name,
+		"EnteredTime": timestamp.ISO8601Milli(r.s.clock()),
+		"RetryCount":  0.0,
+	})
+	r.record(APIHistoryEvent{
+		Type:                     s.Type + "StateEntered",
+		StateEnteredEventDetails: &APIStateEnteredEventDetails{Name: name, Input: toJSON(input)},
+	})
+
+	var output any
+	next := s.Next
+	var err *stateError
+	switch s.Type {
+	case "Succeed":
+		output, err = r.runSucceed(s, input)
+	case "Fail":
+		return nil, "", r.runFail(s, input, contextObject)
+	case "Choice":
+		output, next, err = r.runChoice(s, input, contextObject)
+	case "Wait":
+		output, err = r.runWait(s, input, contextObject)
+	default:
+		output, err = r.runWithResult(name, s, input, contextObject)
+		if err != nil && r.ctx.Err() == nil {
+			if c := findCatcher(s, err); c != nil {
+				output, err = c.ResultPath.applyResult(input, map[string]any{"Error": err.Error, "Cause": err.Cause})
+				next = c.Next
+			}
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.record(APIHistoryEvent{
+		Type:                    s.Type + "StateExited",
+		StateExitedEventDetails: &APIStateExitedEventDetails{Name: name, Output: toJSON(output)},
+	})
+	return output, next, nil
+}
+
+// matchesError returns whether the error is one of the names of a retrier or catcher. States.ALL matches every
+// error but States.Runtime, and States.TaskFailed matches every error of Task states but States.Timeout.
+func matchesError(errorEquals []string, s *state, err *stateError) bool {
+	if err.Error == "States.Runtime" {
+		return false
+	}
+	for _, name := range errorEquals {
+		switch {
+		case name == err.Error, name == "States.ALL":
+			return true
+		case name == "States.TaskFailed" && s.Type == "Task" && err.Error != "States.Timeout":
+			return true
+		}
+	}
+	return false
+}
+
+func findCatcher(s *state, err *stateError) *catcher {
+	for i, c := range s.Catch {
+		if matchesError(c.ErrorEquals, s, err) {
+			return &s.Catch[i]
+		}
+	}
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// withRetries calls attempt until it succeeds, or the state's retriers don't retry its error.
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-error-handling.html#error-handling-retrying-after-an-error
+func (r *run) withRetries(s *state, contextObject map[string]any, attempt func(contextObject map[string]any) (any, *stateError)) (any, *stateError) {
+	attempts := make([]int, len(s.Retry))
+	retryCount := 0
+	for {
+		result, err := attempt(contextObject)
+		if err == nil || r.ctx.Err() != nil {
+			return result, err
+		}
+		// Only the first retrier which matches the error is used.
+		i := slices.IndexFunc(s.Retry, func(retry retrier) bool {
+			return matchesError(retry.ErrorEquals, s, err)
+		})
+		if i == -1 || attempts[i] >= s.Retry[i].maxAttempts() {
+			return nil, err
+		}
+		retry := s.Retry[i]
+		delay := retry.interval() * math.Pow(retry.backoffRate(), float64(attempts[i]))
+		if retry.MaxDelaySeconds > 0 {
+			delay = min(delay, retry.MaxDelaySeconds)
+		}
+		if retry.JitterStrategy == "FULL" {
+			delay = rand.Float64() * delay
+		}
+		attempts[i]++
+		retryCount++
+		if r.s.sleep(r.ctx, seconds(delay)) != nil {
+			return nil, stoppedError
+		}
+		state := contextObject["State"].(map[string]any)
+		contextObject = withContext(contextObject, "State", map[string]any{
+			"Name":        state["Name"],
+			"EnteredTime": state["EnteredTime"],
+			"RetryCount":  float64(retryCount),
+		})
+	}
+}
+
+// runWithResult runs the states which have a result: Pass, Task, Parallel and Map.
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-input-output-filtering.html
+func (r *run) runWithResult(name string, s *state, input any, contextObject map[string]any) (any, *stateError) {
+	effectiveInput, err := s.InputPath.selectInput(input)
+	if err != nil {
+		return nil, err
+	}
+	// Map states create each item's input with their Parameters instead.
+	if s.Parameters != nil && s.Type != "Map" {
+		if effectiveInput, err = applyTemplate(s.Parameters, effectiveInput, contextObject); err != nil {
+			return nil, err
+		}
+	}
+
+	var result any
+	switch s.Type {
+	case "Pass":
+		result = effectiveInput
+		if s.Result != nil {
+			if err := json.Unmarshal(s.Result, &result); err != nil {
+				panic(err)
+			}
+		}
+	case "Task":
+		result, err = r.withRetries(s, contextObject, func(contextObject map[string]any) (any, *stateError) {
+			return r.runTask(s, effectiveInput)
+		})
+	case "Parallel":
+		result, err = r.withRetries(s, contextObject, func(contextObject map[string]any) (any, *stateError) {
+			return r.runParallel(s, effectiveInput, contextObject)
+		})
+	case "Map":
+		result, err = r.withRetries(s, contextObject, func(contextObject map[string]any) (any, *stateError) {
+			return r.runMap(name, s, effectiveInput, contextObject)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ResultSelector != nil {
+		if result, err = applyTemplate(s.ResultSelector, result, contextObject); err != nil {
+			return nil, err
+		}
+	}
+	output, err := s.ResultPath.applyResult(input, result)
+	if err != nil {
+		return nil, err
+	}
+	return s.OutputPath.selectInput(output)
+}
+
+func (r *run) runSucceed(s *state, input any) (any, *stateError) {
+	effectiveInput, err := s.InputPath.selectInput(input)
+	if err != nil {
+		return nil, err
+	}
+	return s.OutputPath.selectInput(effectiveInput)
+}
+
+// runFail returns the state's error. ErrorPath and CausePath can be paths or intrinsic functions.
+func (r *run) runFail(s *state, input any, contextObject map[string]any) *stateError {
+	failure := &stateError{Error: s.Error, Cause: s.Cause}
+	for field, text := range map[string]string{"ErrorPath": s.ErrorPath, "CausePath": s.CausePath} {
+		if text == "" {
+			continue
+		}
+		e, _ := parseExpression(text)
+		value, err := e.eval(field, input, contextObject)
+		if err != nil {
+			return err
+		}
+		str, ok := value.(string)
+		if !ok {
+			return runtimeError(fmt.Sprintf("The value of %s must be a string, but was %s", field, toJSON(value)))
+		}
+		if field == "ErrorPath" {
+			failure.Error = str
+		} else {
+			failure.Cause = str
+		}
+	}
+	return failure
+}
+
+func (r *run) runChoice(s *state, input any, contextObject map[string]any) (any, string, *stateError) {
+	effectiveInput, err := s.InputPath.selectInput(input)
+	if err != nil {
+		return nil, "", err
+	}
+	next := s.Default
+	for _, rule := range s.Choices {
+		matched, err := rule.matches(effectiveInput, contextObject)
+		if err != nil {
+			return nil, "", err
+		}
+		if matched {
+			next = rule.Next
+			break
+		}
+	}
+	if next == "" {
+		return nil, "", &stateError{Error: "States.NoChoiceMatched", Cause: "No Matches!"}
+	}
+	output, err := s.OutputPath.selectInput(effectiveInput)
+	return output, next, err
+}
+
+func (r *run) runWait(s *state, input any, contextObject map[string]any) (any, *stateError) {
+	effectiveInput, err := s.InputPath.selectInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var delay time.Duration
+	switch {
+	case s.Seconds != nil:
+		delay = seconds(*s.Seconds)
+	case s.SecondsPath != "":
+		value, err := (&expression{path: mustParsePath(s.SecondsPath)}).eval("SecondsPath", effectiveInput, contextObject)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := value.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			return nil, runtimeError(fmt.Sprintf("The SecondsPath parameter does not reference a valid integer value: %s", toJSON(value)))
+		}
+		delay = seconds(n)
+	default:
+		timestamp := s.Timestamp
+		if s.TimestampPath != "" {
+			value, err := (&expression{path: mustParsePath(s.TimestampPath)}).eval("TimestampPath", effectiveInput, contextObject)
+			if err != nil {
+				return nil, err
+			}
+			timestamp, _ = value.(string)
+		}
+		t, parseErr := time.Parse(time.RFC3339Nano, timestamp)
+		if parseErr != nil {
+			return nil, runtimeError(fmt.Sprintf("The TimestampPath parameter does not reference a valid ISO-8601 extended offset date-time format string: %s", timestamp))
+		}
+		delay = max(t.Sub(r.s.clock()), 0)
+	}
+
+	if r.s.sleep(r.ctx, delay) != nil {
+		return nil, stoppedError
+	}
+	return s.OutputPath.selectInput(effectiveInput)
+}
+
+// mustParsePath parses a path which was validated with the definition.
+func mustParsePath(text string) *path {
+	p, err := parsePath(text)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// runConcurrently calls f for each index, at most limit at a time if limit isn't 0. It returns the results in
+// order, or the first error, after which the runs which haven't finished are stopped.
+func (r *run) runConcurrently(n int, limit int, f func(child *run, i int) (any, *stateError)) ([]any, *stateError) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	child := &run{s: r.s, ctx: ctx, execution: r.execution}
+
+	if limit == 0 {
+		limit = n
+	}
+	semaphore := make(chan struct{}, max(limit, 1))
+	results := make([]any, n)
+	var mu sync.Mutex
+	var firstErr *stateError
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+			result, err := f(child, i)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	if r.ctx.Err() != nil {
+		return nil, stoppedError
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-parallel-state.html
+func (r *run) runParallel(s *state, input any, contextObject map[string]any) (any, *stateError) {
+	r.record(APIHistoryEvent{Type: "ParallelStateStarted"})
+	results, err := r.runConcurrently(len(s.Branches), 0, func(child *run, i int) (any, *stateError) {
+		return child.runDefinition(s.Branches[i], input, contextObject)
+	})
+	if err != nil {
+		if r.ctx.Err() == nil {
+			r.record(APIHistoryEvent{Type: "ParallelStateFailed"})
+		}
+		return nil, err
+	}
+	r.record(APIHistoryEvent{Type: "ParallelStateSucceeded"})
+	return results, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-map-state.html
+func (r *run) runMap(name string, s *state, input any, contextObject map[string]any) (any, *stateError) {
+	selected, err := s.ItemsPath.selectInput(input)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := selected.([]any)
+	if !ok {
+		return nil, runtimeError(fmt.Sprintf("Invalid path '%s': The ItemsPath must reference a JSON array, but was %s",
+			s.ItemsPath, toJSON(selected)))
+	}
+
+	r.record(APIHistoryEvent{
+		Type:                        "MapStateStarted",
+		MapStateStartedEventDetails: &APIMapStateStartedEventDetails{Length: len(items)},
+	})
+	processor := s.itemProcessor()
+	results, err := r.runConcurrently(len(items), s.MaxConcurrency, func(child *run, i int) (any, *stateError) {
+		itemContext := withContext(contextObject, "Map", map[string]any{
+			"Item": map[string]any{"Index": float64(i), "Value": items[i]},
+		})
+		itemInput := items[i]
+		if selector := s.itemSelector(); selector != nil {
+			var err *stateError
+			if itemInput, err = applyTemplate(selector, input, itemContext); err != nil {
+				return nil, err
+			}
+		}
+
+		iteration := &APIMapIterationEventDetails{Name: name, Index: i}
+		child.record(APIHistoryEvent{Type: "MapIterationStarted", MapIterationStartedEventDetails: iteration})
+		output, err := child.runDefinition(processor, itemInput, itemContext)
+		switch {
+		case child.ctx.Err() != nil:
+			child.record(APIHistoryEvent{Type: "MapIterationAborted", MapIterationAbortedEventDetails: iteration})
+		case err != nil:
+			child.record(APIHistoryEvent{Type: "MapIterationFailed", MapIterationFailedEventDetails: iteration})
+		default:
+			child.record(APIHistoryEvent{Type: "MapIterationSucceeded", MapIterationSucceededEventDetails: iteration})
+		}
+		return output, err
+	})
+	if err != nil {
+		if r.ctx.Err() == nil {
+			r.record(APIHistoryEvent{Type: "MapStateFailed"})
+		}
+		return nil, err
+	}
+	r.record(APIHistoryEvent{Type: "MapStateSucceeded"})
+	return results, nil
+}
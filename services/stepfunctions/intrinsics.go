@@ -0,0 +1,523 @@
+package stepfunctions
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-intrinsic-functions.html
+
+type intrinsicArg struct {
+	// Exactly one of these is set, unless the literal is null.
+	literal any
+	path    *path
+	call    *intrinsic
+	// String literals with the \{ and \} escapes kept, for States.Format.
+	template string
+}
+
+type intrinsic struct {
+	name string
+	args []intrinsicArg
+}
+
+type intrinsicParser struct {
+	text string
+	pos  int
+}
+
+func parseIntrinsic(text string) (*intrinsic, error) {
+	p := &intrinsicParser{text: text}
+	call, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(text) {
+		return nil, fmt.Errorf("unexpected %q after the intrinsic function in %q", text[p.pos:], text)
+	}
+	return call, nil
+}
+
+func (p *intrinsicParser) skipSpaces() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *intrinsicParser) parseCall() (*intrinsic, error) {
+	open := strings.IndexByte(p.text[p.pos:], '(')
+	if open == -1 {
+		return nil, fmt.Errorf("%q is not an intrinsic function", p.text)
+	}
+	name := p.text[p.pos : p.pos+open]
+	if _, ok := intrinsicFunctions[name]; !ok {
+		return nil, fmt.Errorf("unknown intrinsic function %q", name)
+	}
+	p.pos += open + 1
+
+	call := &intrinsic{name: name}
+	p.skipSpaces()
+	if p.pos < len(p.text) && p.text[p.pos] == ')' {
+		p.pos++
+		return call, nil
+	}
+	for {
+		p.skipSpaces()
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		p.skipSpaces()
+		if p.pos >= len(p.text) {
+			return nil, fmt.Errorf("unterminated intrinsic function in %q", p.text)
+		}
+		if p.text[p.pos] == ')' {
+			p.pos++
+			return call, nil
+		}
+		if p.text[p.pos] != ',' {
+			return nil, fmt.Errorf("expected , or ) in %q", p.text)
+		}
+		p.pos++
+	}
+}
+
+func (p *intrinsicParser) parseArg() (intrinsicArg, error) {
+	rest := p.text[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "'"):
+		return p.parseString()
+	case strings.HasPrefix(rest, "$"):
+		end := strings.IndexAny(rest, ", )")
+		if end == -1 {
+			end = len(rest)
+		}
+		path, err := parsePath(rest[:end])
+		if err != nil {
+			return intrinsicArg{}, err
+		}
+		p.pos += end
+		return intrinsicArg{path: path}, nil
+	case strings.HasPrefix(rest, "States."):
+		call, err := p.parseCall()
+		return intrinsicArg{call: call}, err
+	}
+
+	end := strings.IndexAny(rest, ", )")
+	if end == -1 {
+		end = len(rest)
+	}
+	token := rest[:end]
+	p.pos += end
+	switch token {
+	case "null":
+		return intrinsicArg{}, nil
+	case "true", "false":
+		return intrinsicArg{literal: token == "true"}, nil
+	}
+	number, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return intrinsicArg{}, fmt.Errorf("invalid argument %q in %q", token, p.text)
+	}
+	return intrinsicArg{literal: number}, nil
+}
+
+func (p *intrinsicParser) parseString() (intrinsicArg, error) {
+	var value, template strings.Builder
+	for i := p.pos + 1; i < len(p.text); i++ {
+		c := p.text[i]
+		switch {
+		case c == '\\' && i+1 < len(p.text):
+			i++
+			escaped := p.text[i]
+			if escaped == '{' || escaped == '}' {
+				template.WriteByte('\\')
+			}
+			value.WriteByte(escaped)
+			template.WriteByte(escaped)
+		case c == '\'':
+			p.pos = i + 1
+			return intrinsicArg{literal: value.String(), template: template.String()}, nil
+		default:
+			value.WriteByte(c)
+			template.WriteByte(c)
+		}
+	}
+	return intrinsicArg{}, fmt.Errorf("unterminated string in %q", p.text)
+}
+
+func intrinsicError(format string, args ...any) *stateError {
+	return &stateError{Error: "States.IntrinsicFailure", Cause: fmt.Sprintf(format, args...)}
+}
+
+func (call *intrinsic) eval(input any, context any) (any, *stateError) {
+	args := make([]any, len(call.args))
+	for i, arg := range call.args {
+		switch {
+		case arg.path != nil:
+			root := input
+			if arg.path.context {
+				root = context
+			}
+			value, ok := arg.path.get(root)
+			if !ok {
+				return nil, intrinsicError("The path %s in %s could not be found", arg.path.text, call.name)
+			}
+			args[i] = value
+		case arg.call != nil:
+			value, err := arg.call.eval(input, context)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		default:
+			args[i] = arg.literal
+		}
+	}
+	if call.name == "States.Format" && len(call.args) > 0 && call.args[0].path == nil && call.args[0].call == nil {
+		args[0] = call.args[0].template
+	}
+
+	f := intrinsicFunctions[call.name]
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, intrinsicError("%s was called with %d arguments", call.name, len(args))
+	}
+	return f.eval(args)
+}
+
+type intrinsicFunction struct {
+	minArgs int
+	// -1 for any number of arguments.
+	maxArgs int
+	eval    func(args []any) (any, *stateError)
+}
+
+var intrinsicFunctions map[string]intrinsicFunction
+
+func init() {
+	intrinsicFunctions = map[string]intrinsicFunction{
+		"States.Format":         {1, -1, format},
+		"States.StringToJson":   {1, 1, stringToJSON},
+		"States.JsonToString":   {1, 1, jsonToString},
+		"States.Array":          {0, -1, func(args []any) (any, *stateError) { return args, nil }},
+		"States.ArrayPartition": {2, 2, arrayPartition},
+		"States.ArrayContains":  {2, 2, arrayContains},
+		"States.ArrayRange":     {3, 3, arrayRange},
+		"States.ArrayGetItem":   {2, 2, arrayGetItem},
+		"States.ArrayLength":    {1, 1, arrayLength},
+		"States.ArrayUnique":    {1, 1, arrayUnique},
+		"States.Base64Encode":   {1, 1, base64Encode},
+		"States.Base64Decode":   {1, 1, base64Decode},
+		"States.Hash":           {2, 2, hashData},
+		"States.JsonMerge":      {3, 3, jsonMerge},
+		"States.MathRandom":     {2, 3, mathRandom},
+		"States.MathAdd":        {2, 2, mathAdd},
+		"States.StringSplit":    {2, 2, stringSplit},
+		"States.UUID":           {0, 0, func(args []any) (any, *stateError) { return uuid.Must(uuid.NewV4()).String(), nil }},
+	}
+}
+
+func stringArg(name string, value any) (string, *stateError) {
+	s, ok := value.(string)
+	if !ok {
+		return "", intrinsicError("The %s argument must be a string", name)
+	}
+	return s, nil
+}
+
+func arrayArg(name string, value any) ([]any, *stateError) {
+	array, ok := value.([]any)
+	if !ok {
+		return nil, intrinsicError("The %s argument must be an array", name)
+	}
+	return array, nil
+}
+
+func integerArg(name string, value any) (int, *stateError) {
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, intrinsicError("The %s argument must be an integer", name)
+	}
+	return int(number), nil
+}
+
+// formatValue is how values are inserted into strings: strings as they are, and other values as JSON.
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func format(args []any) (any, *stateError) {
+	template, err := stringArg("template", args[0])
+	if err != nil {
+		return nil, err
+	}
+	var formatted strings.Builder
+	next := 1
+	for i := 0; i < len(template); i++ {
+		switch {
+		case template[i] == '\\' && i+1 < len(template):
+			i++
+			formatted.WriteByte(template[i])
+		case strings.HasPrefix(template[i:], "{}"):
+			if next >= len(args) {
+				return nil, intrinsicError("States.Format has more placeholders than arguments")
+			}
+			formatted.WriteString(formatValue(args[next]))
+			next++
+			i++
+		default:
+			formatted.WriteByte(template[i])
+		}
+	}
+	if next != len(args) {
+		return nil, intrinsicError("States.Format has more arguments than placeholders")
+	}
+	return formatted.String(), nil
+}
+
+func stringToJSON(args []any) (any, *stateError) {
+	s, err := stringArg("string", args[0])
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return nil, intrinsicError("States.StringToJson could not parse %q", s)
+	}
+	return value, nil
+}
+
+func jsonToString(args []any) (any, *stateError) {
+	encoded, _ := json.Marshal(args[0])
+	return string(encoded), nil
+}
+
+func arrayPartition(args []any) (any, *stateError) {
+	array, err := arrayArg("array", args[0])
+	if err != nil {
+		return nil, err
+	}
+	size, err := integerArg("chunk size", args[1])
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, intrinsicError("The chunk size must be greater than 0")
+	}
+	partitions := []any{}
+	for len(array) > 0 {
+		n := min(size, len(array))
+		partitions = append(partitions, slices.Clone(array[:n]))
+		array = array[n:]
+	}
+	return partitions, nil
+}
+
+func arrayContains(args []any) (any, *stateError) {
+	array, err := arrayArg("array", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return slices.ContainsFunc(array, func(item any) bool {
+		return jsonEqual(item, args[1])
+	}), nil
+}
+
+func arrayRange(args []any) (any, *stateError) {
+	var bounds [3]int
+	for i, name := range []string{"start", "end", "step"} {
+		var err *stateError
+		if bounds[i], err = integerArg(name, args[i]); err != nil {
+			return nil, err
+		}
+	}
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return nil, intrinsicError("The step must not be 0")
+	}
+	values := []any{}
+	for i := start; (step > 0 && i <= end) || (step < 0 && i >= end); i += step {
+		values = append(values, float64(i))
+		if len(values) > 1000 {
+			return nil, intrinsicError("States.ArrayRange can return at most 1000 items")
+		}
+	}
+	return values, nil
+}
+
+func arrayGetItem(args []any) (any, *stateError) {
+	array, err := arrayArg("array", args[0])
+	if err != nil {
+		return nil, err
+	}
+	index, err := integerArg("index", args[1])
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(array) {
+		return nil, intrinsicError("The index %d is out of bounds", index)
+	}
+	return array[index], nil
+}
+
+func arrayLength(args []any) (any, *stateError) {
+	array, err := arrayArg("array", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return float64(len(array)), nil
+}
+
+func arrayUnique(args []any) (any, *stateError) {
+	array, err := arrayArg("array", args[0])
+	if err != nil {
+		return nil, err
+	}
+	unique := []any{}
+	for _, item := range array {
+		if !slices.ContainsFunc(unique, func(u any) bool { return jsonEqual(u, item) }) {
+			unique = append(unique, item)
+		}
+	}
+	return unique, nil
+}
+
+func base64Encode(args []any) (any, *stateError) {
+	s, err := stringArg("data", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+}
+
+func base64Decode(args []any) (any, *stateError) {
+	s, err := stringArg("data", args[0])
+	if err != nil {
+		return nil, err
+	}
+	decoded, decodeErr := base64.StdEncoding.DecodeString(s)
+	if decodeErr != nil {
+		return nil, intrinsicError("States.Base64Decode could not decode %q", s)
+	}
+	return string(decoded), nil
+}
+
+func hashData(args []any) (any, *stateError) {
+	algorithm, err := stringArg("algorithm", args[1])
+	if err != nil {
+		return nil, err
+	}
+	var h hash.Hash
+	switch algorithm {
+	case "MD5":
+		h = md5.New()
+	case "SHA-1":
+		h = sha1.New()
+	case "SHA-256":
+		h = sha256.New()
+	case "SHA-384":
+		h = sha512.New384()
+	case "SHA-512":
+		h = sha512.New()
+	default:
+		return nil, intrinsicError("Unsupported hash algorithm %q", algorithm)
+	}
+	h.Write([]byte(formatValue(args[0])))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func jsonMerge(args []any) (any, *stateError) {
+	a, ok := args[0].(map[string]any)
+	b, bOk := args[1].(map[string]any)
+	if !ok || !bOk {
+		return nil, intrinsicError("States.JsonMerge can only merge objects")
+	}
+	if deep, _ := args[2].(bool); deep {
+		return nil, intrinsicError("States.JsonMerge only supports shallow merges")
+	}
+	merged := make(map[string]any, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+	for key, value := range b {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+func mathRandom(args []any) (any, *stateError) {
+	start, err := integerArg("start", args[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := integerArg("end", args[1])
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, intrinsicError("The end must be greater than the start")
+	}
+	random := rand.Intn(end-start) + start
+	if len(args) == 3 {
+		seed, err := integerArg("seed", args[2])
+		if err != nil {
+			return nil, err
+		}
+		random = rand.New(rand.NewSource(int64(seed))).Intn(end-start) + start
+	}
+	return float64(random), nil
+}
+
+func mathAdd(args []any) (any, *stateError) {
+	a, err := integerArg("first", args[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := integerArg("second", args[1])
+	if err != nil {
+		return nil, err
+	}
+	return float64(a + b), nil
+}
+
+func stringSplit(args []any) (any, *stateError) {
+	s, err := stringArg("string", args[0])
+	if err != nil {
+		return nil, err
+	}
+	delimiters, err := stringArg("delimiter", args[1])
+	if err != nil {
+		return nil, err
+	}
+	parts := []any{}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(delimiters, r) }) {
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// jsonEqual compares decoded JSON values.
+func jsonEqual(a any, b any) bool {
+	encodedA, _ := json.Marshal(a)
+	encodedB, _ := json.Marshal(b)
+	return string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,77 @@
+package stepfunctions
+
+import (
+	"testing"
+)
+
+func TestIntrinsics(t *testing.T) {
+	input := decode(t, `{"name": "World", "list": [1, 2, 2, 3], "json": "{\"a\":1}", "object": {"a": 1}, "other": {"b": 2}}`)
+	context := decode(t, `{"Execution": {"Name": "run"}}`)
+	for text, expected := range map[string]string{
+		`States.Format('Hello, {}!', $.name)`:                    `"Hello, World!"`,
+		`States.Format('{} \{\} {}', $.list, $$.Execution.Name)`: `"[1,2,2,3] {} run"`,
+		`States.Format('it\'s')`:                                 `"it's"`,
+		`States.StringToJson($.json)`:                            `{"a":1}`,
+		`States.JsonToString($.object)`:                          `"{\"a\":1}"`,
+		`States.Array(1, 'two', true, null, $.name)`:             `[1,"two",true,null,"World"]`,
+		`States.ArrayPartition($.list, 3)`:                       `[[1,2,2],[3]]`,
+		`States.ArrayContains($.list, 3)`:                        `true`,
+		`States.ArrayRange(1, 9, 2)`:                             `[1,3,5,7,9]`,
+		`States.ArrayGetItem($.list, 3)`:                         `3`,
+		`States.ArrayLength($.list)`:                             `4`,
+		`States.ArrayUnique($.list)`:                             `[1,2,3]`,
+		`States.Base64Encode('Data')`:                            `"RGF0YQ=="`,
+		`States.Base64Decode('RGF0YQ==')`:                        `"Data"`,
+		`States.Hash('Data', 'SHA-1')`:                           `"e5e429bcc9c2e4a41a3c7a4d96203be6cb273b11"`,
+		`States.JsonMerge($.object, $.other, false)`:             `{"a":1,"b":2}`,
+		`States.MathAdd($.list[3], -1)`:                          `2`,
+		`States.StringSplit('a,b;c', ',;')`:                      `["a","b","c"]`,
+		`States.ArrayLength(States.StringSplit('a b', ' '))`:     `2`,
+	} {
+		call, err := parseIntrinsic(text)
+		if err != nil {
+			t.Fatal(text, err)
+		}
+		value, stateErr := call.eval(input, context)
+		if stateErr != nil {
+			t.Error(text, stateErr)
+			continue
+		}
+		if toJSON(value) != expected {
+			t.Error("Unexpected value for", text, toJSON(value))
+		}
+	}
+
+	call, _ := parseIntrinsic("States.UUID()")
+	if value, _ := call.eval(nil, nil); len(value.(string)) != 36 {
+		t.Error("Unexpected UUID", value)
+	}
+	call, _ = parseIntrinsic("States.MathRandom(1, 10)")
+	if value, _ := call.eval(nil, nil); value.(float64) < 1 || value.(float64) >= 10 {
+		t.Error("Unexpected random number", value)
+	}
+
+	for _, text := range []string{
+		`States.Format('{} {}', 'one')`,
+		`States.StringToJson('not json')`,
+		`States.ArrayGetItem($.list, 10)`,
+		`States.MathAdd(1.5, 1)`,
+		`States.Base64Encode($.list)`,
+		`States.Format($.missing)`,
+		`States.JsonMerge($.object, $.other, true)`,
+	} {
+		call, err := parseIntrinsic(text)
+		if err != nil {
+			t.Fatal(text, err)
+		}
+		if _, stateErr := call.eval(input, context); stateErr == nil || stateErr.Error != "States.IntrinsicFailure" {
+			t.Error("Expected an intrinsic failure for", text, stateErr)
+		}
+	}
+
+	for _, text := range []string{"States.Unknown()", "States.Format('unterminated)", "States.Array(1", "States.Array(x)", "$.name"} {
+		if _, err := parseIntrinsic(text); err == nil {
+			t.Error("Expected invalid intrinsic", text)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/awserrors"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/cw-logs.html
+
+func validateLoggingConfiguration(config *APILoggingConfiguration) *awserrors.Error {
+	if config == nil {
+		return nil
+	}
+	switch config.Level {
+	case "", "OFF":
+		if len(config.Destinations) > 1 {
+			return InvalidLoggingConfiguration("Invalid Logging Configuration: Must specify at most one Log Destination.")
+		}
+	case "ALL", "ERROR", "FATAL":
+		if len(config.Destinations) != 1 {
+			return InvalidLoggingConfiguration("Invalid Logging Configuration: Must specify exactly one Log Destination.")
+		}
+	default:
+		return ValidationException("1 validation error detected: Value '" + config.Level +
+			"' at 'loggingConfiguration.level' failed to satisfy constraint: Member must satisfy enum value set: [ALL, ERROR, FATAL, OFF]")
+	}
+	for _, destination := range config.Destinations {
+		if destination.CloudWatchLogsLogGroup == nil || logGroupName(destination.CloudWatchLogsLogGroup.LogGroupArn) == "" {
+			return InvalidLoggingConfiguration("Invalid Logging Configuration: Log Destination is not a valid CloudWatch Logs log group ARN.")
+		}
+	}
+	return nil
+}
+
+// logGroupName returns the name of the log group with the ARN, which may end in :*.
+func logGroupName(logGroupArn string) string {
+	if !strings.HasPrefix(logGroupArn, "arn:") {
+		return ""
+	}
+	_, name, ok := strings.Cut(logGroupArn, ":log-group:")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(name, ":*")
+}
+
+// shouldLog returns whether events of the type are logged at the level.
+func shouldLog(level string, eventType string) bool {
+	switch level {
+	case "ALL":
+		return true
+	case "ERROR":
+		return strings.HasSuffix(eventType, "Failed") || strings.HasSuffix(eventType, "TimedOut") || strings.HasSuffix(eventType, "Aborted")
+	case "FATAL":
+		return eventType == "ExecutionFailed" || eventType == "ExecutionTimedOut" || eventType == "ExecutionAborted"
+	}
+	return false
+}
+
+// logEvent is how history events are written to log groups.
+type logEvent struct {
+	Id              string         `json:"id"`
+	Type            string         `json:"type"`
+	Details         map[string]any `json:"details"`
+	PreviousEventId string         `json:"previous_event_id"`
+	EventTimestamp  string         `json:"event_timestamp"`
+	ExecutionArn    string         `json:"execution_arn"`
+}
+
+// lockedLogEvent writes the event to the log group of the execution's state machine,
+// if its logging configuration includes events of the type.
+func (s *StepFunctions) lockedLogEvent(execution *Execution, event APIHistoryEvent) {
+	config := execution.stateMachine.LoggingConfiguration
+	if s.logs == nil || config == nil || len(config.Destinations) == 0 || !shouldLog(config.Level, event.Type) {
+		return
+	}
+	if !config.IncludeExecutionData {
+		event = withoutExecutionData(event)
+	}
+
+	// Events have at most one details field, which is the only field left after removing the common ones.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(toJSON(event)), &fields); err != nil {
+		panic(err)
+	}
+	details := map[string]any{}
+	for field, value := range fields {
+		if strings.HasSuffix(field, "EventDetails") {
+			if err := json.Unmarshal(value, &details); err != nil {
+				panic(err)
+			}
+		}
+	}
+	message := toJSON(logEvent{
+		Id:              strconv.FormatInt(event.Id, 10),
+		Type:            event.Type,
+		Details:         details,
+		PreviousEventId: strconv.FormatInt(event.PreviousEventId, 10),
+		EventTimestamp:  strconv.FormatInt(int64(math.Round(event.Timestamp*1000)), 10),
+		ExecutionArn:    execution.Arn,
+	})
+
+	groupName := logGroupName(config.Destinations[0].CloudWatchLogsLogGroup.LogGroupArn)
+	streamName := "states/" + execution.stateMachine.Name + "/" + execution.StartDate.UTC().Format("2006-01-02-15-04") + "/" + execution.Name
+	if err := s.logs.WriteLogs(groupName, streamName, []string{message}); err != nil {
+		s.logger.Warn("Writing execution logs", "execution", execution.Arn, "error", err)
+	}
+}
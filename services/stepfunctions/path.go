@@ -0,0 +1,290 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-input-output-filtering.html
+
+type segmentKind int
+
+const (
+	fieldSegment segmentKind = iota
+	indexSegment
+	wildcardSegment
+)
+
+type pathSegment struct {
+	kind  segmentKind
+	field string
+	index int
+}
+
+// path is a JSONPath, like $.items[0].id, with the subset of the syntax state machines use.
+// Paths starting with $$ select from the context object instead of the state's input.
+type path struct {
+	text     string
+	context  bool
+	segments []pathSegment
+}
+
+func parsePath(text string) (*path, error) {
+	p := &path{text: text}
+	rest, ok := strings.CutPrefix(text, "$$")
+	if ok {
+		p.context = true
+	} else if rest, ok = strings.CutPrefix(text, "$"); !ok {
+		return nil, fmt.Errorf("the path %q must start with $", text)
+	}
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".*"):
+			p.segments = append(p.segments, pathSegment{kind: wildcardSegment})
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			field := rest[1 : end+1]
+			if field == "" {
+				return nil, fmt.Errorf("the path %q has an empty field name", text)
+			}
+			p.segments = append(p.segments, pathSegment{kind: fieldSegment, field: field})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "['"), strings.HasPrefix(rest, `["`):
+			quote := rest[1:2]
+			end := strings.Index(rest[2:], quote+"]")
+			if end == -1 {
+				return nil, fmt.Errorf("the path %q has an unterminated field name", text)
+			}
+			p.segments = append(p.segments, pathSegment{kind: fieldSegment, field: rest[2 : end+2]})
+			rest = rest[end+4:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("the path %q has an unterminated index", text)
+			}
+			if rest[1:end] == "*" {
+				p.segments = append(p.segments, pathSegment{kind: wildcardSegment})
+			} else {
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("the path %q has an invalid index %q", text, rest[1:end])
+				}
+				p.segments = append(p.segments, pathSegment{kind: indexSegment, index: index})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("the path %q is not valid", text)
+		}
+	}
+	return p, nil
+}
+
+// isReference returns whether the path selects a single node, so that it can be used where values are set.
+func (p *path) isReference() bool {
+	for _, segment := range p.segments {
+		if segment.kind == wildcardSegment {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns the value the path selects, or false if it doesn't select anything. Paths with wildcards return an
+// array of the values they match.
+func (p *path) get(root any) (any, bool) {
+	values := []any{root}
+	for _, segment := range p.segments {
+		var next []any
+		for _, value := range values {
+			switch segment.kind {
+			case fieldSegment:
+				if object, ok := value.(map[string]any); ok {
+					if field, ok := object[segment.field]; ok {
+						next = append(next, field)
+					}
+				}
+			case indexSegment:
+				if array, ok := value.([]any); ok && segment.index < len(array) {
+					next = append(next, array[segment.index])
+				}
+			case wildcardSegment:
+				switch v := value.(type) {
+				case []any:
+					next = append(next, v...)
+				case map[string]any:
+					for _, key := range sortedKeys(v) {
+						next = append(next, v[key])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	if !p.isReference() {
+		if values == nil {
+			values = []any{}
+		}
+		return values, true
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// set returns a copy of root with the value at the path, creating the objects on the way which don't exist.
+func (p *path) set(root any, value any) (any, error) {
+	if len(p.segments) == 0 {
+		return value, nil
+	}
+	return setSegments(copyJSON(root), p.segments, value, p.text)
+}
+
+func setSegments(node any, segments []pathSegment, value any, text string) (any, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+	switch segment.kind {
+	case fieldSegment:
+		object, ok := node.(map[string]any)
+		if node == nil {
+			object, ok = make(map[string]any), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unable to apply ResultPath %s: %s is not an object", text, segment.field)
+		}
+		child, err := setSegments(object[segment.field], segments[1:], value, text)
+		if err != nil {
+			return nil, err
+		}
+		object[segment.field] = child
+		return object, nil
+	case indexSegment:
+		array, ok := node.([]any)
+		if !ok || segment.index >= len(array) {
+			return nil, fmt.Errorf("unable to apply ResultPath %s: index %d is out of range", text, segment.index)
+		}
+		child, err := setSegments(array[segment.index], segments[1:], value, text)
+		if err != nil {
+			return nil, err
+		}
+		array[segment.index] = child
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unable to apply ResultPath %s: it must be a reference path", text)
+	}
+}
+
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// copyJSON deep copies the objects and arrays of a decoded JSON value, so that it can be modified.
+func copyJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, field := range v {
+			copied[key] = copyJSON(field)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = copyJSON(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// optionalPath is a path field, which is $ if it's missing, and selects nothing if it's null.
+type optionalPath struct {
+	set  bool
+	null bool
+	text string
+}
+
+func (o *optionalPath) UnmarshalJSON(data []byte) error {
+	o.set = true
+	if string(data) == "null" {
+		o.null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.text)
+}
+
+func (o optionalPath) String() string {
+	if !o.set {
+		return "$"
+	}
+	if o.null {
+		return "null"
+	}
+	return o.text
+}
+
+// validate checks the path's syntax. Result paths must be reference paths.
+func (o optionalPath) validate(reference bool) error {
+	if !o.set || o.null {
+		return nil
+	}
+	p, err := parsePath(o.text)
+	if err != nil {
+		return err
+	}
+	if p.context {
+		return fmt.Errorf("the path %q can't select from the context object", o.text)
+	}
+	if reference && !p.isReference() {
+		return fmt.Errorf("the path %q must be a reference path", o.text)
+	}
+	return nil
+}
+
+// selectInput applies an InputPath or OutputPath to the value. A null path selects an empty object.
+func (o optionalPath) selectInput(value any) (any, *stateError) {
+	if !o.set {
+		return value, nil
+	}
+	if o.null {
+		return map[string]any{}, nil
+	}
+	p, _ := parsePath(o.text)
+	selected, ok := p.get(value)
+	if !ok {
+		return nil, runtimeError(fmt.Sprintf("Invalid path '%s' : No results for path: $%s", o.text, strings.TrimPrefix(o.text, "$")))
+	}
+	return selected, nil
+}
+
+// applyResult applies a ResultPath, combining the state's input with its result. A null path discards the result.
+func (o optionalPath) applyResult(input any, result any) (any, *stateError) {
+	if !o.set {
+		return result, nil
+	}
+	if o.null {
+		return input, nil
+	}
+	p, _ := parsePath(o.text)
+	output, err := p.set(input, result)
+	if err != nil {
+		return nil, &stateError{Error: "States.ResultPathMatchFailure", Cause: err.Error()}
+	}
+	return output, nil
+}
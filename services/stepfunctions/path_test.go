@@ -0,0 +1,122 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decode(t *testing.T, text string) any {
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestPathGet(t *testing.T) {
+	input := decode(t, `{"order": {"id": 1, "items": [{"sku": "a"}, {"sku": "b"}], "tag.name": "x"}}`)
+	for text, expected := range map[string]string{
+		"$":                       `{"order":{"id":1,"items":[{"sku":"a"},{"sku":"b"}],"tag.name":"x"}}`,
+		"$.order.id":              `1`,
+		"$.order.items[1].sku":    `"b"`,
+		"$.order['tag.name']":     `"x"`,
+		"$.order.items[*].sku":    `["a","b"]`,
+		"$.order.items.*.sku":     `["a","b"]`,
+		"$.order.missing[*]":      `[]`,
+		"$['order'].items[0]":     `{"sku":"a"}`,
+		`$["order"]["id"]`:        `1`,
+		"$.order.items[0].sku.id": ``,
+		"$.order.items[5]":        ``,
+	} {
+		p, err := parsePath(text)
+		if err != nil {
+			t.Fatal(text, err)
+		}
+		value, ok := p.get(input)
+		if expected == "" {
+			if ok {
+				t.Error("Expected no value for", text, value)
+			}
+			continue
+		}
+		if !ok || toJSON(value) != expected {
+			t.Error("Unexpected value for", text, value)
+		}
+	}
+
+	for _, text := range []string{"", "order", "$.", "$[x]", "$['unterminated", "$..x", "$[-1]"} {
+		if _, err := parsePath(text); err == nil {
+			t.Error("Expected invalid path", text)
+		}
+	}
+}
+
+func TestPathSet(t *testing.T) {
+	input := decode(t, `{"a": {"b": 1}, "list": [1, 2]}`)
+	for text, expected := range map[string]string{
+		"$":           `"result"`,
+		"$.a.b":       `{"a":{"b":"result"},"list":[1,2]}`,
+		"$.new.field": `{"a":{"b":1},"list":[1,2],"new":{"field":"result"}}`,
+		"$.list[1]":   `{"a":{"b":1},"list":[1,"result"]}`,
+	} {
+		p, _ := parsePath(text)
+		output, err := p.set(input, "result")
+		if err != nil {
+			t.Fatal(text, err)
+		}
+		if toJSON(output) != expected {
+			t.Error("Unexpected output for", text, toJSON(output))
+		}
+	}
+	// The input isn't modified.
+	if toJSON(input) != `{"a":{"b":1},"list":[1,2]}` {
+		t.Fatal("Input was modified", toJSON(input))
+	}
+
+	for _, text := range []string{"$.a.b.c", "$.list[5]"} {
+		p, _ := parsePath(text)
+		if _, err := p.set(input, "result"); err == nil {
+			t.Error("Expected an error for", text)
+		}
+	}
+}
+
+func TestOptionalPath(t *testing.T) {
+	var paths struct {
+		Missing optionalPath
+		Null    optionalPath
+		Set     optionalPath
+	}
+	if err := json.Unmarshal([]byte(`{"Null": null, "Set": "$.a"}`), &paths); err != nil {
+		t.Fatal(err)
+	}
+	input := decode(t, `{"a": 1, "b": 2}`)
+
+	for _, test := range []struct {
+		path     optionalPath
+		selected string
+		applied  string
+	}{
+		{paths.Missing, `{"a":1,"b":2}`, `"result"`},
+		{paths.Null, `{}`, `{"a":1,"b":2}`},
+		{paths.Set, `1`, `{"a":"result","b":2}`},
+	} {
+		selected, err := test.path.selectInput(input)
+		if err != nil || toJSON(selected) != test.selected {
+			t.Error("Unexpected selection for", test.path, selected, err)
+		}
+		applied, err := test.path.applyResult(input, "result")
+		if err != nil || toJSON(applied) != test.applied {
+			t.Error("Unexpected result for", test.path, applied, err)
+		}
+	}
+
+	missing := optionalPath{set: true, text: "$.missing"}
+	if _, err := missing.selectInput(input); err == nil || err.Error != "States.Runtime" {
+		t.Fatal("Expected a runtime error", err)
+	}
+	wildcard := optionalPath{set: true, text: "$.a[*]"}
+	if err := wildcard.validate(true); err == nil {
+		t.Fatal("Expected result paths to be reference paths")
+	}
+}
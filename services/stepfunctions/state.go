@@ -0,0 +1,135 @@
+package stepfunctions
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	awsstate "aws-in-a-box/state"
+)
+
+type executionState struct {
+	*Execution
+	// The state machine as it was when the execution started.
+	StateMachine StateMachine
+	History      []APIHistoryEvent
+}
+
+type stateMachinesState struct {
+	StateMachines []*StateMachine
+	// Oldest first.
+	Executions []executionState
+}
+
+// TryPause pauses changes to each region's state machines while a snapshot copies them.
+func (s *StepFunctions) TryPause() (func(), bool) {
+	return s.regions.TryPause(func(s *StepFunctions) *sync.Mutex { return &s.mu })
+}
+
+// ExportState writes each region's state machines, and its standard executions with their
+// histories, to the archive.
+func (s *StepFunctions) ExportState(w *awsstate.Writer) error {
+	return s.regions.ExportState(w, (*StepFunctions).exportState)
+}
+
+func (s *StepFunctions) exportState(w *awsstate.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported stateMachinesState
+	for _, stateMachine := range s.stateMachines {
+		exported.StateMachines = append(exported.StateMachines, stateMachine)
+	}
+	slices.SortFunc(exported.StateMachines, func(a, b *StateMachine) int {
+		return strings.Compare(a.Arn, b.Arn)
+	})
+	var executions []*Execution
+	for _, execution := range s.executions {
+		executions = append(executions, execution)
+	}
+	slices.SortFunc(executions, func(a, b *Execution) int {
+		return cmp.Compare(a.number, b.number)
+	})
+	for _, execution := range executions {
+		exported.Executions = append(exported.Executions, executionState{
+			Execution:    execution,
+			StateMachine: execution.stateMachine,
+			History:      execution.history,
+		})
+	}
+	return w.WriteJSON("statemachines.json", exported)
+}
+
+// parseStateMachine parses the imported state machine's definition.
+func parseStateMachine(stateMachine *StateMachine) error {
+	if !nameRegex.MatchString(stateMachine.Name) || stateMachine.Arn == "" {
+		return fmt.Errorf("invalid state machine %q", stateMachine.Name)
+	}
+	definition, err := parseDefinition(stateMachine.Definition)
+	if err != nil {
+		return fmt.Errorf("state machine %s: %v", stateMachine.Arn, err)
+	}
+	stateMachine.definition = definition
+	return nil
+}
+
+// ImportState replaces each region's state machines and executions with the archive's. Executions
+// which were running aren't resumed, and are aborted once they're imported.
+func (s *StepFunctions) ImportState(r *awsstate.Reader) (func() error, error) {
+	return s.regions.ImportState(r, (*StepFunctions).importState, (*StepFunctions).exportState)
+}
+
+func (s *StepFunctions) importState(r *awsstate.Reader) (func() error, error) {
+	var imported stateMachinesState
+	if err := r.ReadJSON("statemachines.json", &imported); err != nil {
+		return nil, err
+	}
+	stateMachines := make(map[string]*StateMachine)
+	for _, stateMachine := range imported.StateMachines {
+		if err := parseStateMachine(stateMachine); err != nil {
+			return nil, err
+		}
+		if stateMachine.Tags == nil {
+			stateMachine.Tags = make(map[string]string)
+		}
+		stateMachines[stateMachine.Arn] = stateMachine
+	}
+	executions := make(map[string]*Execution)
+	var running []*Execution
+	for i, e := range imported.Executions {
+		execution := e.Execution
+		if execution == nil || execution.Arn == "" {
+			return nil, fmt.Errorf("invalid execution")
+		}
+		execution.stateMachine = e.StateMachine
+		if err := parseStateMachine(&execution.stateMachine); err != nil {
+			return nil, fmt.Errorf("execution %s: %w", execution.Arn, err)
+		}
+		execution.number = i + 1
+		execution.history = e.History
+		execution.cancel = func() {}
+		execution.done = make(chan struct{})
+		close(execution.done)
+		if execution.Status == "RUNNING" {
+			running = append(running, execution)
+		}
+		executions[execution.Arn] = execution
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.stateMachines = stateMachines
+		s.executions = executions
+		s.executionCount = len(imported.Executions)
+		for _, execution := range running {
+			s.lockedEnd(execution, "ABORTED", nil, &stateError{
+				Error: "States.Runtime",
+				Cause: "The execution was interrupted by a state import.",
+			})
+		}
+		return nil
+	}, nil
+}
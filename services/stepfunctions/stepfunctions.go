@@ -0,0 +1,490 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/region"
+	"aws-in-a-box/services/cloudwatchlogs"
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/lambda"
+	"aws-in-a-box/services/sns"
+	"aws-in-a-box/services/sqs"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	maxListResults    = 1000
+	defaultMaxResults = 100
+	maxTags           = 50
+	// Standard executions fail once their history has this many events.
+	maxHistoryEvents = 25000
+	// Express executions time out after five minutes.
+	maxExpressDuration = 5 * time.Minute
+)
+
+// Names can't have whitespace, brackets, wildcards, special characters or control characters.
+var nameRegex = regexp.MustCompile("^[^\\s<>{}\\[\\]?*\"#%\\\\^|~`$&,;:/\\x00-\\x1f\\x7f-\\x9f]{1,80}$")
+
+type StateMachine struct {
+	Name       string
+	Arn        string
+	Definition string
+	RoleArn    string
+	// STANDARD or EXPRESS.
+	Type                 string
+	LoggingConfiguration *APILoggingConfiguration
+	TracingConfiguration *APITracingConfiguration
+	Tags                 map[string]string
+	CreationDate         time.Time
+	UpdateDate           time.Time
+	definition           *definition
+}
+
+// LambdaInvoker invokes the Lambda functions of Task states.
+type LambdaInvoker interface {
+	Invoke(input lambda.InvokeInput) (*lambda.InvokeOutput, *awserrors.Error)
+}
+
+type StepFunctions struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+	// Waits for the duration, unless the context is done first. Overridden in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+	// Task states can call these services if they're enabled.
+	lambda   LambdaInvoker
+	sqs      *sqs.SQS
+	sns      *sns.SNS
+	dynamoDB *dynamodb.DynamoDB
+	// Nil if CloudWatch Logs is not enabled, in which case executions aren't logged.
+	logs *cloudwatchlogs.CloudWatchLogs
+	// Overridden in tests.
+	maxHistoryEvents int
+
+	mu sync.Mutex
+	// Keyed by ARN.
+	stateMachines map[string]*StateMachine
+	// Keyed by ARN. Executions are kept after their state machine is deleted.
+	executions map[string]*Execution
+	// The number of executions started, for listing them newest first.
+	executionCount int
+	// Shared by the instances of all regions, which each have their own state machines and executions.
+	regions *region.Regions[*StepFunctions]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	Lambda       LambdaInvoker
+	SQS          *sqs.SQS
+	SNS          *sns.SNS
+	DynamoDB     *dynamodb.DynamoDB
+	// Executions are logged to log groups in the state machine's region of this CloudWatch Logs.
+	Logs *cloudwatchlogs.CloudWatchLogs
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *StepFunctions {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	// Task states find queues and tables by URL and name, and log groups by ARN, in the state
+	// machine's region. Lambda's functions and SNS's topics may be named by ARNs, which say their
+	// regions, so Lambda is shared and topics are found by their ARNs when they're published to.
+	s := newRegion(options)
+	s.regions = region.New(options.ArnGenerator.Region, options.Regions, s, func(name string) (*StepFunctions, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		if options.SQS != nil {
+			queues, err := options.SQS.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.SQS = queues
+		}
+		if options.SNS != nil {
+			topics, err := options.SNS.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.SNS = topics
+		}
+		if options.DynamoDB != nil {
+			tables, err := options.DynamoDB.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.DynamoDB = tables
+		}
+		if options.Logs != nil {
+			logs, err := options.Logs.InRegion(name)
+			if err != nil {
+				return nil, err
+			}
+			regionOptions.Logs = logs
+		}
+		r := newRegion(regionOptions)
+		r.regions = s.regions
+		return r, nil
+	})
+	return s
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *StepFunctions {
+	return &StepFunctions{
+		logger:           options.Logger,
+		arnGenerator:     options.ArnGenerator,
+		clock:            clock.Now,
+		sleep:            sleep,
+		lambda:           options.Lambda,
+		sqs:              options.SQS,
+		sns:              options.SNS,
+		dynamoDB:         options.DynamoDB,
+		logs:             options.Logs,
+		maxHistoryEvents: maxHistoryEvents,
+		stateMachines:    make(map[string]*StateMachine),
+		executions:       make(map[string]*Execution),
+	}
+}
+
+// InRegion returns the state machines and executions of the region. It fails if the region isn't
+// one of Options.Regions.
+func (s *StepFunctions) InRegion(name string) (*StepFunctions, error) {
+	return s.regions.Get(name)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// toJSON encodes a decoded JSON value the way Step Functions shows it in outputs and history events.
+func toJSON(value any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		panic(err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func validateName(name string) *awserrors.Error {
+	if !nameRegex.MatchString(name) {
+		return InvalidName("Invalid Name: '" + name + "'")
+	}
+	return nil
+}
+
+func validateRoleArn(roleArn string) *awserrors.Error {
+	if roleArn == "" {
+		return ValidationException("1 validation error detected: Value null at 'roleArn' failed to satisfy constraint: Member must not be null")
+	}
+	if !strings.HasPrefix(roleArn, "arn:") || !strings.Contains(roleArn, ":role/") {
+		return InvalidArn("Invalid Arn: 'Resource type not valid in this context: " + roleArn + "'")
+	}
+	return nil
+}
+
+func tagsFromAPI(tags []APITag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}
+
+func (s *StepFunctions) lockedGetStateMachine(stateMachineArn string) (*StateMachine, *awserrors.Error) {
+	if !strings.HasPrefix(stateMachineArn, "arn:") || !strings.Contains(stateMachineArn, ":stateMachine:") {
+		return nil, InvalidArn("Invalid Arn: '" + stateMachineArn + "'")
+	}
+	stateMachine, ok := s.stateMachines[stateMachineArn]
+	if !ok {
+		return nil, StateMachineDoesNotExist("State Machine Does Not Exist: '" + stateMachineArn + "'")
+	}
+	return stateMachine, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_CreateStateMachine.html
+func (s *StepFunctions) CreateStateMachine(input CreateStateMachineInput) (*CreateStateMachineOutput, *awserrors.Error) {
+	if awserr := validateName(input.Name); awserr != nil {
+		return nil, awserr
+	}
+	if awserr := validateRoleArn(input.RoleArn); awserr != nil {
+		return nil, awserr
+	}
+	switch input.Type {
+	case "":
+		input.Type = "STANDARD"
+	case "STANDARD", "EXPRESS":
+	default:
+		return nil, ValidationException("1 validation error detected: Value '" + input.Type +
+			"' at 'type' failed to satisfy constraint: Member must satisfy enum value set: [STANDARD, EXPRESS]")
+	}
+	if awserr := validateLoggingConfiguration(input.LoggingConfiguration); awserr != nil {
+		return nil, awserr
+	}
+	if len(input.Tags) > maxTags {
+		return nil, TooManyTags("Too many tags.")
+	}
+	def, err := parseDefinition(input.Definition)
+	if err != nil {
+		return nil, InvalidDefinition(err.Error())
+	}
+	if input.Type == "EXPRESS" {
+		if err := def.validateExpress(""); err != nil {
+			return nil, InvalidDefinition(err.Error())
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachineArn := s.arnGenerator.GenerateWithoutType("states", "stateMachine:"+input.Name)
+	if existing, ok := s.stateMachines[stateMachineArn]; ok {
+		// Creating a state machine again is idempotent, unless it's different.
+		if existing.Definition == input.Definition && existing.RoleArn == input.RoleArn && existing.Type == input.Type {
+			return &CreateStateMachineOutput{
+				StateMachineArn: existing.Arn,
+				CreationDate:    timestamp.EpochSeconds(existing.CreationDate),
+			}, nil
+		}
+		return nil, StateMachineAlreadyExists("State Machine Already Exists: '" + stateMachineArn + "'")
+	}
+
+	now := s.clock()
+	stateMachine := &StateMachine{
+		Name:                 input.Name,
+		Arn:                  stateMachineArn,
+		Definition:           input.Definition,
+		RoleArn:              input.RoleArn,
+		Type:                 input.Type,
+		LoggingConfiguration: input.LoggingConfiguration,
+		TracingConfiguration: input.TracingConfiguration,
+		Tags:                 tagsFromAPI(input.Tags),
+		CreationDate:         now,
+		UpdateDate:           now,
+		definition:           def,
+	}
+	s.stateMachines[stateMachineArn] = stateMachine
+	return &CreateStateMachineOutput{
+		StateMachineArn: stateMachineArn,
+		CreationDate:    timestamp.EpochSeconds(now),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_DescribeStateMachine.html
+func (s *StepFunctions) DescribeStateMachine(input DescribeStateMachineInput) (*DescribeStateMachineOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, awserr := s.lockedGetStateMachine(input.StateMachineArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	return &DescribeStateMachineOutput{
+		StateMachineArn:      stateMachine.Arn,
+		Name:                 stateMachine.Name,
+		Status:               "ACTIVE",
+		Definition:           stateMachine.Definition,
+		RoleArn:              stateMachine.RoleArn,
+		Type:                 stateMachine.Type,
+		CreationDate:         timestamp.EpochSeconds(stateMachine.CreationDate),
+		LoggingConfiguration: stateMachine.LoggingConfiguration,
+		TracingConfiguration: stateMachine.TracingConfiguration,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_UpdateStateMachine.html
+func (s *StepFunctions) UpdateStateMachine(input UpdateStateMachineInput) (*UpdateStateMachineOutput, *awserrors.Error) {
+	if input.Definition == "" && input.RoleArn == "" && input.LoggingConfiguration == nil && input.TracingConfiguration == nil {
+		return nil, ValidationException("Either the definition, the role ARN, the LoggingConfiguration, or the TracingConfiguration must be specified")
+	}
+	var def *definition
+	if input.Definition != "" {
+		var err *definitionError
+		if def, err = parseDefinition(input.Definition); err != nil {
+			return nil, InvalidDefinition(err.Error())
+		}
+	}
+	if input.RoleArn != "" {
+		if awserr := validateRoleArn(input.RoleArn); awserr != nil {
+			return nil, awserr
+		}
+	}
+	if awserr := validateLoggingConfiguration(input.LoggingConfiguration); awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, awserr := s.lockedGetStateMachine(input.StateMachineArn)
+	if awserr != nil {
+		return nil, awserr
+	}
+	if def != nil && stateMachine.Type == "EXPRESS" {
+		if err := def.validateExpress(""); err != nil {
+			return nil, InvalidDefinition(err.Error())
+		}
+	}
+	// Running executions keep the definition they started with.
+	if def != nil {
+		stateMachine.Definition = input.Definition
+		stateMachine.definition = def
+	}
+	if input.RoleArn != "" {
+		stateMachine.RoleArn = input.RoleArn
+	}
+	if input.LoggingConfiguration != nil {
+		stateMachine.LoggingConfiguration = input.LoggingConfiguration
+	}
+	if input.TracingConfiguration != nil {
+		stateMachine.TracingConfiguration = input.TracingConfiguration
+	}
+	stateMachine.UpdateDate = s.clock()
+	return &UpdateStateMachineOutput{
+		UpdateDate: timestamp.EpochSeconds(stateMachine.UpdateDate),
+	}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_DeleteStateMachine.html
+func (s *StepFunctions) DeleteStateMachine(input DeleteStateMachineInput) (*DeleteStateMachineOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Deleting a state machine which doesn't exist succeeds. Its running executions keep running.
+	if _, awserr := s.lockedGetStateMachine(input.StateMachineArn); awserr != nil && awserr.Body.Type == "InvalidArn" {
+		return nil, awserr
+	}
+	delete(s.stateMachines, input.StateMachineArn)
+	return &DeleteStateMachineOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ListStateMachines.html
+func (s *StepFunctions) ListStateMachines(input ListStateMachinesInput) (*ListStateMachinesOutput, *awserrors.Error) {
+	limit, start, awserr := pagination.Parse(input.MaxResults, defaultMaxResults, maxListResults, input.NextToken,
+		ValidationException("1 validation error detected: Value at 'maxResults' failed to satisfy constraint: "+
+			"Member must have value less than or equal to 1000"),
+		ValidationException("Invalid Token: '"+input.NextToken+"'"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachines := make([]APIStateMachineListItem, 0, len(s.stateMachines))
+	for _, stateMachine := range s.stateMachines {
+		stateMachines = append(stateMachines, APIStateMachineListItem{
+			StateMachineArn: stateMachine.Arn,
+			Name:            stateMachine.Name,
+			Type:            stateMachine.Type,
+			CreationDate:    timestamp.EpochSeconds(stateMachine.CreationDate),
+		})
+	}
+	slices.SortFunc(stateMachines, func(a, b APIStateMachineListItem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	output := &ListStateMachinesOutput{}
+	output.StateMachines, output.NextToken = pagination.Page(stateMachines, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ValidateStateMachineDefinition.html
+func (s *StepFunctions) ValidateStateMachineDefinition(input ValidateStateMachineDefinitionInput) (*ValidateStateMachineDefinitionOutput, *awserrors.Error) {
+	output := &ValidateStateMachineDefinitionOutput{
+		Result:      "OK",
+		Diagnostics: []APIValidateStateMachineDefinitionDiagnostic{},
+	}
+	if _, err := parseDefinition(input.Definition); err != nil {
+		output.Result = "FAIL"
+		output.Diagnostics = append(output.Diagnostics, APIValidateStateMachineDefinitionDiagnostic{
+			Severity: "ERROR",
+			Code:     err.Code,
+			Message:  err.Message,
+			Location: err.Location,
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_TagResource.html
+func (s *StepFunctions) TagResource(input TagResourceInput) (*TagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, ok := s.stateMachines[input.ResourceArn]
+	if !ok {
+		return nil, ResourceNotFound("Resource not found: '" + input.ResourceArn + "'")
+	}
+	tags := tagsFromAPI(input.Tags)
+	for key := range stateMachine.Tags {
+		tags[key] = ""
+	}
+	if len(tags) > maxTags {
+		return nil, TooManyTags("Too many tags.")
+	}
+	for _, tag := range input.Tags {
+		stateMachine.Tags[tag.Key] = tag.Value
+	}
+	return &TagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_UntagResource.html
+func (s *StepFunctions) UntagResource(input UntagResourceInput) (*UntagResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, ok := s.stateMachines[input.ResourceArn]
+	if !ok {
+		return nil, ResourceNotFound("Resource not found: '" + input.ResourceArn + "'")
+	}
+	for _, key := range input.TagKeys {
+		delete(stateMachine.Tags, key)
+	}
+	return &UntagResourceOutput{}, nil
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ListTagsForResource.html
+func (s *StepFunctions) ListTagsForResource(input ListTagsForResourceInput) (*ListTagsForResourceOutput, *awserrors.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateMachine, ok := s.stateMachines[input.ResourceArn]
+	if !ok {
+		return nil, ResourceNotFound("Resource not found: '" + input.ResourceArn + "'")
+	}
+	tags := make([]APITag, 0, len(stateMachine.Tags))
+	for key, value := range stateMachine.Tags {
+		tags = append(tags, APITag{Key: key, Value: value})
+	}
+	slices.SortFunc(tags, func(a, b APITag) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return &ListTagsForResourceOutput{Tags: tags}, nil
+}
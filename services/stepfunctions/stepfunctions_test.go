@@ -0,0 +1,177 @@
+package stepfunctions
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+)
+
+var generator = arn.Generator{
+	AwsAccountId: "123456789012",
+	Region:       "us-east-1",
+}
+
+const roleArn = "arn:aws:iam::123456789012:role/states"
+
+// sleeps records how long states slept for, without sleeping.
+type sleeps struct {
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+func (s *sleeps) sleep(ctx context.Context, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durations = append(s.durations, d)
+	return ctx.Err()
+}
+
+func newStepFunctions(options Options) (*StepFunctions, *sleeps) {
+	options.ArnGenerator = generator
+	s := New(options)
+	now := time.Unix(1700000000, 0)
+	s.clock = func() time.Time { return now }
+	slept := &sleeps{}
+	s.sleep = slept.sleep
+	return s, slept
+}
+
+func createStateMachine(t *testing.T, s *StepFunctions, name string, definition string) string {
+	output, awserr := s.CreateStateMachine(CreateStateMachineInput{Name: name, Definition: definition, RoleArn: roleArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	return output.StateMachineArn
+}
+
+const passDefinition = `{"StartAt": "Pass", "States": {"Pass": {"Type": "Pass", "End": true}}}`
+
+func TestStateMachines(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+
+	stateMachineArn := createStateMachine(t, s, "orders", passDefinition)
+	if stateMachineArn != "arn:aws:states:us-east-1:123456789012:stateMachine:orders" {
+		t.Fatal("Unexpected ARN", stateMachineArn)
+	}
+	// Creating the same state machine again is idempotent.
+	if createStateMachine(t, s, "orders", passDefinition) != stateMachineArn {
+		t.Fatal("Expected the same state machine")
+	}
+	for _, test := range []struct {
+		input CreateStateMachineInput
+		code  string
+	}{
+		{CreateStateMachineInput{Name: "orders", Definition: `{"StartAt": "A", "States": {"A": {"Type": "Succeed"}}}`, RoleArn: roleArn}, "StateMachineAlreadyExists"},
+		{CreateStateMachineInput{Name: "bad name", Definition: passDefinition, RoleArn: roleArn}, "InvalidName"},
+		{CreateStateMachineInput{Name: "invalid", Definition: `{}`, RoleArn: roleArn}, "InvalidDefinition"},
+		{CreateStateMachineInput{Name: "invalid", Definition: passDefinition}, "ValidationException"},
+		{CreateStateMachineInput{Name: "invalid", Definition: passDefinition, RoleArn: "role"}, "InvalidArn"},
+		{CreateStateMachineInput{Name: "invalid", Definition: passDefinition, RoleArn: roleArn, Type: "BATCH"}, "ValidationException"},
+		{CreateStateMachineInput{Name: "invalid", Definition: passDefinition, RoleArn: roleArn,
+			LoggingConfiguration: &APILoggingConfiguration{Level: "ALL"}}, "InvalidLoggingConfiguration"},
+		{CreateStateMachineInput{Name: "invalid", Definition: passDefinition, RoleArn: roleArn,
+			LoggingConfiguration: &APILoggingConfiguration{Level: "ERROR", Destinations: []APILogDestination{
+				{CloudWatchLogsLogGroup: &APICloudWatchLogsLogGroup{LogGroupArn: "group"}},
+			}}}, "InvalidLoggingConfiguration"},
+		{CreateStateMachineInput{Name: "invalid", Definition: passDefinition, RoleArn: roleArn,
+			LoggingConfiguration: &APILoggingConfiguration{Level: "DEBUG"}}, "ValidationException"},
+	} {
+		_, awserr := s.CreateStateMachine(test.input)
+		if awserr == nil || awserr.Body.Type != test.code {
+			t.Errorf("Expected %s for %+v: %v", test.code, test.input, awserr)
+		}
+	}
+
+	description, awserr := s.DescribeStateMachine(DescribeStateMachineInput{StateMachineArn: stateMachineArn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if description.Name != "orders" || description.Status != "ACTIVE" || description.Type != "STANDARD" ||
+		description.Definition != passDefinition || description.RoleArn != roleArn || description.CreationDate != 1700000000 {
+		t.Fatalf("Unexpected description: %+v", description)
+	}
+
+	updated := `{"StartAt": "Done", "States": {"Done": {"Type": "Succeed"}}}`
+	if _, awserr := s.UpdateStateMachine(UpdateStateMachineInput{StateMachineArn: stateMachineArn, Definition: updated}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.UpdateStateMachine(UpdateStateMachineInput{StateMachineArn: stateMachineArn, Definition: "{"}); awserr == nil {
+		t.Fatal("Expected an invalid definition")
+	}
+	description, _ = s.DescribeStateMachine(DescribeStateMachineInput{StateMachineArn: stateMachineArn})
+	if description.Definition != updated {
+		t.Fatal("Unexpected definition", description.Definition)
+	}
+
+	createStateMachine(t, s, "billing", passDefinition)
+	list, awserr := s.ListStateMachines(ListStateMachinesInput{MaxResults: 1})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(list.StateMachines) != 1 || list.StateMachines[0].Name != "billing" || list.NextToken == "" {
+		t.Fatalf("Unexpected state machines: %+v", list)
+	}
+	list, _ = s.ListStateMachines(ListStateMachinesInput{NextToken: list.NextToken})
+	if len(list.StateMachines) != 1 || list.StateMachines[0].Name != "orders" || list.NextToken != "" {
+		t.Fatalf("Unexpected state machines: %+v", list)
+	}
+
+	if _, awserr := s.DeleteStateMachine(DeleteStateMachineInput{StateMachineArn: stateMachineArn}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.DescribeStateMachine(DescribeStateMachineInput{StateMachineArn: stateMachineArn}); awserr == nil || awserr.Body.Type != "StateMachineDoesNotExist" {
+		t.Fatal("Expected the state machine to be deleted", awserr)
+	}
+	if _, awserr := s.DescribeStateMachine(DescribeStateMachineInput{StateMachineArn: "orders"}); awserr == nil || awserr.Body.Type != "InvalidArn" {
+		t.Fatal("Expected an invalid ARN", awserr)
+	}
+}
+
+func TestValidateStateMachineDefinition(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	output, _ := s.ValidateStateMachineDefinition(ValidateStateMachineDefinitionInput{Definition: passDefinition})
+	if output.Result != "OK" || len(output.Diagnostics) != 0 {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	output, _ = s.ValidateStateMachineDefinition(ValidateStateMachineDefinitionInput{
+		Definition: `{"StartAt": "A", "States": {"A": {"Type": "Pass", "Next": "B"}}}`,
+	})
+	if output.Result != "FAIL" || len(output.Diagnostics) != 1 || output.Diagnostics[0].Code != "MISSING_TRANSITION_TARGET" ||
+		output.Diagnostics[0].Location != "/States/A/Next" {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s, _ := newStepFunctions(Options{})
+	output, awserr := s.CreateStateMachine(CreateStateMachineInput{
+		Name:       "orders",
+		Definition: passDefinition,
+		RoleArn:    roleArn,
+		Tags:       []APITag{{Key: "team", Value: "shop"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	arn := output.StateMachineArn
+
+	if _, awserr := s.TagResource(TagResourceInput{ResourceArn: arn, Tags: []APITag{{Key: "env", Value: "dev"}}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	if _, awserr := s.UntagResource(UntagResourceInput{ResourceArn: arn, TagKeys: []string{"team"}}); awserr != nil {
+		t.Fatal(awserr)
+	}
+	tags, awserr := s.ListTagsForResource(ListTagsForResourceInput{ResourceArn: arn})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != (APITag{Key: "env", Value: "dev"}) {
+		t.Fatalf("Unexpected tags: %+v", tags)
+	}
+	if _, awserr := s.ListTagsForResource(ListTagsForResourceInput{ResourceArn: arn + "x"}); awserr == nil || awserr.Body.Type != "ResourceNotFound" {
+		t.Fatal("Expected resource not found", awserr)
+	}
+}
@@ -0,0 +1,270 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"aws-in-a-box/services/dynamodb"
+	"aws-in-a-box/services/lambda"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-task-state.html
+
+const integrationPrefix = "arn:aws:states:::"
+
+// integration calls a service for Task states whose resource is arn:aws:states:::<service>:<action>,
+// with the state's input as the parameters.
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-supported-services.html
+type integration func(r *run, parameters map[string]any) (any, *stateError)
+
+// Set in init, since integrations which start executions refer back to it.
+var integrations map[string]integration
+
+func init() {
+	integrations = map[string]integration{
+		"dynamodb:deleteItem":          dynamoDBIntegration("deleteItem", (*dynamodb.DynamoDB).DeleteItem),
+		"dynamodb:getItem":             dynamoDBIntegration("getItem", getItem),
+		"dynamodb:putItem":             dynamoDBIntegration("putItem", (*dynamodb.DynamoDB).PutItem),
+		"dynamodb:updateItem":          dynamoDBIntegration("updateItem", (*dynamodb.DynamoDB).UpdateItem),
+		"lambda:invoke":                invokeLambdaIntegration,
+		"sns:publish":                  publishIntegration,
+		"sqs:sendMessage":              sendMessageIntegration,
+		"states:startExecution":        startExecutionIntegration,
+		"states:startExecution.sync":   startExecutionSyncIntegration(false),
+		"states:startExecution.sync:2": startExecutionSyncIntegration(true),
+	}
+}
+
+// await calls f in a goroutine, so that the task can time out, or stop with the execution, while f runs.
+func (r *run) await(timeout time.Duration, f func() (any, *stateError)) (any, *stateError) {
+	type result struct {
+		value any
+		err   *stateError
+	}
+	results := make(chan result, 1)
+	go func() {
+		value, err := f()
+		results <- result{value, err}
+	}()
+
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	select {
+	case res := <-results:
+		return res.value, res.err
+	case <-timedOut:
+		return nil, &stateError{Error: "States.Timeout", Cause: "The task timed out."}
+	case <-r.ctx.Done():
+		return nil, stoppedError
+	}
+}
+
+// timeout returns how long the task can run for, or 0 if it can run for as long as the execution.
+func (r *run) timeout(s *state, input any) (time.Duration, *stateError) {
+	if s.TimeoutSecondsPath == "" {
+		return time.Duration(s.TimeoutSeconds) * time.Second, nil
+	}
+	value, err := (&expression{path: mustParsePath(s.TimeoutSecondsPath)}).eval("TimeoutSecondsPath", input, nil)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := value.(float64)
+	if !ok || n <= 0 || n != math.Trunc(n) {
+		return 0, runtimeError(fmt.Sprintf("The TimeoutSecondsPath parameter does not reference a valid positive integer value: %s", toJSON(value)))
+	}
+	return seconds(n), nil
+}
+
+// runTask calls the task's resource with its effective input, and returns its result.
+func (r *run) runTask(s *state, input any) (any, *stateError) {
+	timeout, err := r.timeout(s, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if name, ok := strings.CutPrefix(s.Resource, integrationPrefix); ok {
+		resourceType, resource, _ := strings.Cut(name, ":")
+		call, ok := integrations[name]
+		if !ok {
+			return nil, runtimeError("The resource " + s.Resource + " is not supported.")
+		}
+		parameters, ok := input.(map[string]any)
+		if !ok {
+			return nil, runtimeError("The parameters of " + s.Resource + " must be an object.")
+		}
+		return r.runIntegration(resourceType, resource, timeout, parameters, call)
+	}
+	if strings.HasPrefix(s.Resource, "arn:aws:lambda:") {
+		return r.runLambdaFunction(s.Resource, timeout, input)
+	}
+	return nil, runtimeError("The resource " + s.Resource + " is not supported.")
+}
+
+func (r *run) runIntegration(resourceType string, resource string, timeout time.Duration, parameters map[string]any, call integration) (any, *stateError) {
+	r.record(APIHistoryEvent{
+		Type: "TaskScheduled",
+		TaskScheduledEventDetails: &APITaskScheduledEventDetails{
+			ResourceType:     resourceType,
+			Resource:         resource,
+			Region:           r.s.arnGenerator.Region,
+			Parameters:       toJSON(parameters),
+			TimeoutInSeconds: int64(timeout / time.Second),
+		},
+	})
+	r.record(APIHistoryEvent{
+		Type:                    "TaskStarted",
+		TaskStartedEventDetails: &APITaskEventDetails{ResourceType: resourceType, Resource: resource},
+	})
+
+	result, err := r.await(timeout, func() (any, *stateError) {
+		return call(r, parameters)
+	})
+	details := &APITaskEventDetails{ResourceType: resourceType, Resource: resource}
+	switch {
+	case r.ctx.Err() != nil:
+		return nil, stoppedError
+	case err != nil && err.Error == "States.Timeout":
+		details.Error, details.Cause = err.Error, err.Cause
+		r.record(APIHistoryEvent{Type: "TaskTimedOut", TaskTimedOutEventDetails: details})
+	case err != nil:
+		details.Error, details.Cause = err.Error, err.Cause
+		r.record(APIHistoryEvent{Type: "TaskFailed", TaskFailedEventDetails: details})
+	default:
+		details.Output = toJSON(result)
+		r.record(APIHistoryEvent{Type: "TaskSucceeded", TaskSucceededEventDetails: details})
+	}
+	return result, err
+}
+
+// invokeLambda invokes the function synchronously. Function errors are raised with the function's error type.
+// https://docs.aws.amazon.com/step-functions/latest/dg/concepts-error-handling.html#error-handling-lambda-error-names
+func (r *run) invokeLambda(input lambda.InvokeInput) (*lambda.InvokeOutput, *stateError) {
+	if r.s.lambda == nil {
+		return nil, &stateError{Error: "Lambda.ServiceException", Cause: "Lambda is not enabled."}
+	}
+	// Lambda invokes functions named without their ARNs in its default region, so they're named by
+	// their ARNs in the state machine's region.
+	switch {
+	case strings.HasPrefix(input.FunctionName, "arn:"):
+	case strings.Contains(input.FunctionName, ":function:"):
+		input.FunctionName = "arn:aws:lambda:" + r.s.arnGenerator.Region + ":" + input.FunctionName
+	default:
+		input.FunctionName = r.s.arnGenerator.GenerateWithoutType("lambda", "function:"+input.FunctionName)
+	}
+	output, awserr := r.s.lambda.Invoke(input)
+	if awserr != nil {
+		return nil, &stateError{Error: "Lambda." + awserr.Body.Type, Cause: awserr.Body.Message}
+	}
+	if output.FunctionError != "" {
+		var functionError struct {
+			ErrorType string `json:"errorType"`
+		}
+		_ = json.Unmarshal(output.Payload, &functionError)
+		if functionError.ErrorType == "" {
+			functionError.ErrorType = "Lambda.Unknown"
+		}
+		return nil, &stateError{Error: functionError.ErrorType, Cause: string(output.Payload)}
+	}
+	return output, nil
+}
+
+func decodePayload(payload []byte) (any, *stateError) {
+	if len(payload) == 0 {
+		return nil, nil
+	}
+	var decoded any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, runtimeError("The Lambda function returned invalid JSON: " + err.Error())
+	}
+	return decoded, nil
+}
+
+// runLambdaFunction invokes the function of a Task state whose resource is the function's ARN.
+// The function's output is the task's result.
+func (r *run) runLambdaFunction(functionArn string, timeout time.Duration, input any) (any, *stateError) {
+	payload := toJSON(input)
+	r.record(APIHistoryEvent{
+		Type: "LambdaFunctionScheduled",
+		LambdaFunctionScheduledEventDetails: &APILambdaFunctionScheduledEventDetails{
+			Resource:         functionArn,
+			Input:            payload,
+			TimeoutInSeconds: int64(timeout / time.Second),
+		},
+	})
+	r.record(APIHistoryEvent{Type: "LambdaFunctionStarted"})
+
+	result, err := r.await(timeout, func() (any, *stateError) {
+		output, err := r.invokeLambda(lambda.InvokeInput{
+			FunctionName: functionArn,
+			Payload:      []byte(payload),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return decodePayload(output.Payload)
+	})
+	switch {
+	case r.ctx.Err() != nil:
+		return nil, stoppedError
+	case err != nil && err.Error == "States.Timeout":
+		r.record(APIHistoryEvent{
+			Type:                               "LambdaFunctionTimedOut",
+			LambdaFunctionTimedOutEventDetails: &APIErrorEventDetails{Error: err.Error, Cause: err.Cause},
+		})
+	case err != nil:
+		r.record(APIHistoryEvent{
+			Type:                             "LambdaFunctionFailed",
+			LambdaFunctionFailedEventDetails: &APIErrorEventDetails{Error: err.Error, Cause: err.Cause},
+		})
+	default:
+		r.record(APIHistoryEvent{
+			Type:                                "LambdaFunctionSucceeded",
+			LambdaFunctionSucceededEventDetails: &APIOutputEventDetails{Output: toJSON(result)},
+		})
+	}
+	return result, err
+}
+
+// invokeLambdaIntegration invokes a function for arn:aws:states:::lambda:invoke. The result has the function's
+// output as its Payload.
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-lambda.html
+func invokeLambdaIntegration(r *run, parameters map[string]any) (any, *stateError) {
+	functionName, _ := parameters["FunctionName"].(string)
+	if functionName == "" {
+		return nil, runtimeError("The field 'FunctionName' is required.")
+	}
+	input := lambda.InvokeInput{FunctionName: functionName}
+	input.Qualifier, _ = parameters["Qualifier"].(string)
+	input.InvocationType, _ = parameters["InvocationType"].(string)
+	input.ClientContext, _ = parameters["ClientContext"].(string)
+	if payload, ok := parameters["Payload"]; ok {
+		input.Payload = []byte(toJSON(payload))
+	}
+
+	output, err := r.invokeLambda(input)
+	if err != nil {
+		return nil, err
+	}
+	statusCode := output.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+	var payload any = ""
+	if len(output.Payload) > 0 {
+		if payload, err = decodePayload(output.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]any{
+		"ExecutedVersion": output.ExecutedVersion,
+		"Payload":         payload,
+		"StatusCode":      float64(statusCode),
+	}, nil
+}
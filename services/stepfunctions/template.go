@@ -0,0 +1,104 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expression is the value of a payload template field whose name ends with .$: a path, or an intrinsic function.
+type expression struct {
+	path *path
+	call *intrinsic
+}
+
+func parseExpression(text string) (*expression, error) {
+	if strings.HasPrefix(text, "$") {
+		p, err := parsePath(text)
+		return &expression{path: p}, err
+	}
+	call, err := parseIntrinsic(text)
+	return &expression{call: call}, err
+}
+
+func (e *expression) eval(field string, input any, context any) (any, *stateError) {
+	if e.call != nil {
+		return e.call.eval(input, context)
+	}
+	root := input
+	if e.path.context {
+		root = context
+	}
+	value, ok := e.path.get(root)
+	if !ok {
+		return nil, runtimeError(fmt.Sprintf("The JSONPath '%s' specified for the field '%s.$' could not be found in the input '%s'",
+			e.path.text, field, formatValue(root)))
+	}
+	return value, nil
+}
+
+// validateTemplate checks the paths and intrinsic functions of a payload template, like Parameters.
+func validateTemplate(template any) error {
+	switch v := template.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if field, ok := strings.CutSuffix(key, ".$"); ok {
+				text, isString := value.(string)
+				if !isString {
+					return fmt.Errorf("the value of the field %q must be a path or an intrinsic function", key)
+				}
+				if _, err := parseExpression(text); err != nil {
+					return fmt.Errorf("the value of the field %q is invalid: %v", field+".$", err)
+				}
+			} else if err := validateTemplate(value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := validateTemplate(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyTemplate evaluates a payload template, like Parameters. Fields whose names end with .$ are replaced by the
+// value of their path or intrinsic function, without the suffix.
+// https://docs.aws.amazon.com/step-functions/latest/dg/input-output-inputpath-params.html
+func applyTemplate(template any, input any, context any) (any, *stateError) {
+	switch v := template.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			if field, ok := strings.CutSuffix(key, ".$"); ok {
+				// Templates were validated with the definition.
+				e, _ := parseExpression(value.(string))
+				evaluated, err := e.eval(field, input, context)
+				if err != nil {
+					return nil, err
+				}
+				result[field] = evaluated
+				continue
+			}
+			applied, err := applyTemplate(value, input, context)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = applied
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			applied, err := applyTemplate(item, input, context)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = applied
+		}
+		return result, nil
+	default:
+		return template, nil
+	}
+}
@@ -0,0 +1,375 @@
+package stepfunctions
+
+// Step Functions' JSON fields are camelCase, unlike most services.
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_Tag.html
+type APITag struct {
+	Key   string `json:"key" length:"1,128"`
+	Value string `json:"value" length:",256"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_LoggingConfiguration.html
+type APILoggingConfiguration struct {
+	// ALL, ERROR, FATAL or OFF.
+	Level                string              `json:"level,omitempty" enum:"ALL|ERROR|FATAL|OFF"`
+	IncludeExecutionData bool                `json:"includeExecutionData"`
+	Destinations         []APILogDestination `json:"destinations,omitempty"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_LogDestination.html
+type APILogDestination struct {
+	CloudWatchLogsLogGroup *APICloudWatchLogsLogGroup `json:"cloudWatchLogsLogGroup,omitempty"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_CloudWatchLogsLogGroup.html
+type APICloudWatchLogsLogGroup struct {
+	LogGroupArn string `json:"logGroupArn" length:"1,256"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_BillingDetails.html
+type APIBillingDetails struct {
+	BilledMemoryUsedInMB         int64 `json:"billedMemoryUsedInMB"`
+	BilledDurationInMilliseconds int64 `json:"billedDurationInMilliseconds"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_TracingConfiguration.html
+type APITracingConfiguration struct {
+	Enabled bool `json:"enabled"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_StateMachineListItem.html
+type APIStateMachineListItem struct {
+	StateMachineArn string  `json:"stateMachineArn"`
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	CreationDate    float64 `json:"creationDate"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ExecutionListItem.html
+type APIExecutionListItem struct {
+	ExecutionArn    string  `json:"executionArn"`
+	StateMachineArn string  `json:"stateMachineArn"`
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	StartDate       float64 `json:"startDate"`
+	StopDate        float64 `json:"stopDate,omitempty"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_HistoryEvent.html
+type APIHistoryEvent struct {
+	Id              int64   `json:"id"`
+	PreviousEventId int64   `json:"previousEventId"`
+	Timestamp       float64 `json:"timestamp"`
+	Type            string  `json:"type"`
+
+	ExecutionStartedEventDetails   *APIExecutionStartedEventDetails   `json:"executionStartedEventDetails,omitempty"`
+	ExecutionSucceededEventDetails *APIExecutionSucceededEventDetails `json:"executionSucceededEventDetails,omitempty"`
+	ExecutionFailedEventDetails    *APIErrorEventDetails              `json:"executionFailedEventDetails,omitempty"`
+	ExecutionAbortedEventDetails   *APIErrorEventDetails              `json:"executionAbortedEventDetails,omitempty"`
+	ExecutionTimedOutEventDetails  *APIErrorEventDetails              `json:"executionTimedOutEventDetails,omitempty"`
+
+	StateEnteredEventDetails *APIStateEnteredEventDetails `json:"stateEnteredEventDetails,omitempty"`
+	StateExitedEventDetails  *APIStateExitedEventDetails  `json:"stateExitedEventDetails,omitempty"`
+
+	LambdaFunctionScheduledEventDetails *APILambdaFunctionScheduledEventDetails `json:"lambdaFunctionScheduledEventDetails,omitempty"`
+	LambdaFunctionSucceededEventDetails *APIOutputEventDetails                  `json:"lambdaFunctionSucceededEventDetails,omitempty"`
+	LambdaFunctionFailedEventDetails    *APIErrorEventDetails                   `json:"lambdaFunctionFailedEventDetails,omitempty"`
+	LambdaFunctionTimedOutEventDetails  *APIErrorEventDetails                   `json:"lambdaFunctionTimedOutEventDetails,omitempty"`
+
+	TaskScheduledEventDetails *APITaskScheduledEventDetails `json:"taskScheduledEventDetails,omitempty"`
+	TaskStartedEventDetails   *APITaskEventDetails          `json:"taskStartedEventDetails,omitempty"`
+	TaskSucceededEventDetails *APITaskEventDetails          `json:"taskSucceededEventDetails,omitempty"`
+	TaskFailedEventDetails    *APITaskEventDetails          `json:"taskFailedEventDetails,omitempty"`
+	TaskTimedOutEventDetails  *APITaskEventDetails          `json:"taskTimedOutEventDetails,omitempty"`
+
+	MapStateStartedEventDetails       *APIMapStateStartedEventDetails `json:"mapStateStartedEventDetails,omitempty"`
+	MapIterationStartedEventDetails   *APIMapIterationEventDetails    `json:"mapIterationStartedEventDetails,omitempty"`
+	MapIterationSucceededEventDetails *APIMapIterationEventDetails    `json:"mapIterationSucceededEventDetails,omitempty"`
+	MapIterationFailedEventDetails    *APIMapIterationEventDetails    `json:"mapIterationFailedEventDetails,omitempty"`
+	MapIterationAbortedEventDetails   *APIMapIterationEventDetails    `json:"mapIterationAbortedEventDetails,omitempty"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ExecutionStartedEventDetails.html
+type APIExecutionStartedEventDetails struct {
+	Input   string `json:"input"`
+	RoleArn string `json:"roleArn"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ExecutionSucceededEventDetails.html
+type APIExecutionSucceededEventDetails struct {
+	Output string `json:"output"`
+}
+
+// The details of the events for failures, like ExecutionFailed and LambdaFunctionFailed.
+type APIErrorEventDetails struct {
+	Error string `json:"error,omitempty"`
+	Cause string `json:"cause,omitempty"`
+}
+
+// The details of the events for successes with an output, like LambdaFunctionSucceeded.
+type APIOutputEventDetails struct {
+	Output string `json:"output"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_StateEnteredEventDetails.html
+type APIStateEnteredEventDetails struct {
+	Name  string `json:"name"`
+	Input string `json:"input"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_StateExitedEventDetails.html
+type APIStateExitedEventDetails struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_LambdaFunctionScheduledEventDetails.html
+type APILambdaFunctionScheduledEventDetails struct {
+	Resource         string `json:"resource"`
+	Input            string `json:"input"`
+	TimeoutInSeconds int64  `json:"timeoutInSeconds,omitempty"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_TaskScheduledEventDetails.html
+type APITaskScheduledEventDetails struct {
+	ResourceType     string `json:"resourceType"`
+	Resource         string `json:"resource"`
+	Region           string `json:"region"`
+	Parameters       string `json:"parameters"`
+	TimeoutInSeconds int64  `json:"timeoutInSeconds,omitempty"`
+}
+
+// The details of the task events after it's scheduled, like TaskSucceeded.
+type APITaskEventDetails struct {
+	ResourceType string `json:"resourceType"`
+	Resource     string `json:"resource"`
+	Output       string `json:"output,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Cause        string `json:"cause,omitempty"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_MapStateStartedEventDetails.html
+type APIMapStateStartedEventDetails struct {
+	Length int `json:"length"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_MapIterationEventDetails.html
+type APIMapIterationEventDetails struct {
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+}
+
+// https://docs.aws.amazon.com/step-functions/latest/apireference/API_ValidateStateMachineDefinitionDiagnostic.html
+type APIValidateStateMachineDefinitionDiagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Location string `json:"location,omitempty"`
+}
+
+type CreateStateMachineInput struct {
+	Name                    string                   `json:"name" required:"true" length:"1,80"`
+	Definition              string                   `json:"definition" required:"true" length:"1,1048576"`
+	RoleArn                 string                   `json:"roleArn" required:"true" length:"1,256"`
+	Type                    string                   `json:"type" enum:"STANDARD|EXPRESS"`
+	LoggingConfiguration    *APILoggingConfiguration `json:"loggingConfiguration"`
+	TracingConfiguration    *APITracingConfiguration `json:"tracingConfiguration"`
+	EncryptionConfiguration any                      `json:"encryptionConfiguration"`
+	Tags                    []APITag                 `json:"tags"`
+	Publish                 bool                     `json:"publish"`
+	VersionDescription      string                   `json:"versionDescription" length:",256"`
+}
+
+type CreateStateMachineOutput struct {
+	StateMachineArn string  `json:"stateMachineArn"`
+	CreationDate    float64 `json:"creationDate"`
+}
+
+type DescribeStateMachineInput struct {
+	StateMachineArn string `json:"stateMachineArn" required:"true" length:"1,256"`
+	// Ignored, since state machines aren't encrypted.
+	IncludedData string `json:"includedData" enum:"ALL_DATA|METADATA_ONLY"`
+}
+
+type DescribeStateMachineOutput struct {
+	StateMachineArn      string                   `json:"stateMachineArn"`
+	Name                 string                   `json:"name"`
+	Status               string                   `json:"status"`
+	Definition           string                   `json:"definition"`
+	RoleArn              string                   `json:"roleArn"`
+	Type                 string                   `json:"type"`
+	CreationDate         float64                  `json:"creationDate"`
+	LoggingConfiguration *APILoggingConfiguration `json:"loggingConfiguration,omitempty"`
+	TracingConfiguration *APITracingConfiguration `json:"tracingConfiguration,omitempty"`
+}
+
+type UpdateStateMachineInput struct {
+	StateMachineArn         string                   `json:"stateMachineArn" required:"true" length:"1,256"`
+	Definition              string                   `json:"definition" length:"1,1048576"`
+	RoleArn                 string                   `json:"roleArn" length:"1,256"`
+	LoggingConfiguration    *APILoggingConfiguration `json:"loggingConfiguration"`
+	TracingConfiguration    *APITracingConfiguration `json:"tracingConfiguration"`
+	EncryptionConfiguration any                      `json:"encryptionConfiguration"`
+	Publish                 bool                     `json:"publish"`
+	VersionDescription      string                   `json:"versionDescription" length:",256"`
+}
+
+type UpdateStateMachineOutput struct {
+	UpdateDate float64 `json:"updateDate"`
+}
+
+type DeleteStateMachineInput struct {
+	StateMachineArn string `json:"stateMachineArn" required:"true" length:"1,256"`
+}
+
+type DeleteStateMachineOutput struct{}
+
+type ListStateMachinesInput struct {
+	MaxResults int    `json:"maxResults" range:"0,1000"`
+	NextToken  string `json:"nextToken" length:"1,1024"`
+}
+
+type ListStateMachinesOutput struct {
+	StateMachines []APIStateMachineListItem `json:"stateMachines"`
+	NextToken     string                    `json:"nextToken,omitempty"`
+}
+
+type ValidateStateMachineDefinitionInput struct {
+	Definition string `json:"definition" required:"true" length:"1,1048576"`
+	Type       string `json:"type" enum:"STANDARD|EXPRESS"`
+}
+
+type ValidateStateMachineDefinitionOutput struct {
+	// OK or FAIL.
+	Result      string                                        `json:"result"`
+	Diagnostics []APIValidateStateMachineDefinitionDiagnostic `json:"diagnostics"`
+}
+
+type StartExecutionInput struct {
+	StateMachineArn string `json:"stateMachineArn" required:"true" length:"1,256"`
+	Name            string `json:"name" length:"1,80"`
+	Input           string `json:"input" length:",262144"`
+	TraceHeader     string `json:"traceHeader" length:",256"`
+}
+
+type StartExecutionOutput struct {
+	ExecutionArn string  `json:"executionArn"`
+	StartDate    float64 `json:"startDate"`
+}
+
+type StartSyncExecutionInput struct {
+	StateMachineArn string `json:"stateMachineArn" required:"true" length:"1,256"`
+	Name            string `json:"name" length:"1,80"`
+	Input           string `json:"input" length:",262144"`
+	TraceHeader     string `json:"traceHeader" length:",256"`
+	IncludedData    string `json:"includedData" enum:"ALL_DATA|METADATA_ONLY"`
+}
+
+type StartSyncExecutionOutput struct {
+	ExecutionArn    string `json:"executionArn"`
+	StateMachineArn string `json:"stateMachineArn"`
+	Name            string `json:"name"`
+	// SUCCEEDED, FAILED or TIMED_OUT.
+	Status         string             `json:"status"`
+	StartDate      float64            `json:"startDate"`
+	StopDate       float64            `json:"stopDate"`
+	Input          string             `json:"input"`
+	Output         string             `json:"output,omitempty"`
+	Error          string             `json:"error,omitempty"`
+	Cause          string             `json:"cause,omitempty"`
+	TraceHeader    string             `json:"traceHeader,omitempty"`
+	BillingDetails *APIBillingDetails `json:"billingDetails"`
+}
+
+type DescribeExecutionInput struct {
+	ExecutionArn string `json:"executionArn" required:"true" length:"1,256"`
+	// Ignored, since executions aren't encrypted.
+	IncludedData string `json:"includedData" enum:"ALL_DATA|METADATA_ONLY"`
+}
+
+type DescribeExecutionOutput struct {
+	ExecutionArn    string  `json:"executionArn"`
+	StateMachineArn string  `json:"stateMachineArn"`
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	StartDate       float64 `json:"startDate"`
+	StopDate        float64 `json:"stopDate,omitempty"`
+	Input           string  `json:"input"`
+	Output          string  `json:"output,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	Cause           string  `json:"cause,omitempty"`
+	TraceHeader     string  `json:"traceHeader,omitempty"`
+}
+
+type DescribeStateMachineForExecutionInput struct {
+	ExecutionArn string `json:"executionArn" required:"true" length:"1,256"`
+	IncludedData string `json:"includedData" enum:"ALL_DATA|METADATA_ONLY"`
+}
+
+type DescribeStateMachineForExecutionOutput struct {
+	StateMachineArn      string                   `json:"stateMachineArn"`
+	Name                 string                   `json:"name"`
+	Definition           string                   `json:"definition"`
+	RoleArn              string                   `json:"roleArn"`
+	UpdateDate           float64                  `json:"updateDate"`
+	LoggingConfiguration *APILoggingConfiguration `json:"loggingConfiguration,omitempty"`
+	TracingConfiguration *APITracingConfiguration `json:"tracingConfiguration,omitempty"`
+}
+
+type ListExecutionsInput struct {
+	StateMachineArn string `json:"stateMachineArn" length:"1,256"`
+	StatusFilter    string `json:"statusFilter" enum:"RUNNING|SUCCEEDED|FAILED|TIMED_OUT|ABORTED|PENDING_REDRIVE"`
+	MaxResults      int    `json:"maxResults" range:"0,1000"`
+	NextToken       string `json:"nextToken" length:"1,3096"`
+}
+
+type ListExecutionsOutput struct {
+	Executions []APIExecutionListItem `json:"executions"`
+	NextToken  string                 `json:"nextToken,omitempty"`
+}
+
+type StopExecutionInput struct {
+	ExecutionArn string `json:"executionArn" required:"true" length:"1,256"`
+	Error        string `json:"error" length:",256"`
+	Cause        string `json:"cause" length:",32768"`
+}
+
+type StopExecutionOutput struct {
+	StopDate float64 `json:"stopDate"`
+}
+
+type GetExecutionHistoryInput struct {
+	ExecutionArn string `json:"executionArn" required:"true" length:"1,256"`
+	MaxResults   int    `json:"maxResults" range:"0,1000"`
+	NextToken    string `json:"nextToken" length:"1,3096"`
+	ReverseOrder bool   `json:"reverseOrder"`
+	// Defaults to true.
+	IncludeExecutionData *bool `json:"includeExecutionData"`
+}
+
+type GetExecutionHistoryOutput struct {
+	Events    []APIHistoryEvent `json:"events"`
+	NextToken string            `json:"nextToken,omitempty"`
+}
+
+type TagResourceInput struct {
+	ResourceArn string   `json:"resourceArn" required:"true" length:"1,256"`
+	Tags        []APITag `json:"tags" required:"true"`
+}
+
+type TagResourceOutput struct{}
+
+type UntagResourceInput struct {
+	ResourceArn string   `json:"resourceArn" required:"true" length:"1,256"`
+	TagKeys     []string `json:"tagKeys" required:"true"`
+}
+
+type UntagResourceOutput struct{}
+
+type ListTagsForResourceInput struct {
+	ResourceArn string `json:"resourceArn" required:"true" length:"1,256"`
+}
+
+type ListTagsForResourceOutput struct {
+	Tags []APITag `json:"tags"`
+}
@@ -0,0 +1,11 @@
+package sts
+
+import "aws-in-a-box/awserrors"
+
+func ValidationError(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("ValidationError", message)
+}
+
+func InvalidIdentityTokenException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidIdentityToken", message)
+}
@@ -0,0 +1,29 @@
+package sts
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/query"
+)
+
+// STS only supports the Query protocol.
+var queryProtocol = query.Protocol{
+	Version:      "2011-06-15",
+	XMLNamespace: "https://sts.amazonaws.com/doc/2011-06-15/",
+	ErrorCodes: map[string]string{
+		"ValidationException": "ValidationError",
+	},
+}
+
+func NewHandler(logger *slog.Logger, s *STS, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := query.NewRegistry(queryProtocol)
+	query.Register(logger, registry, "AssumeRole", s.AssumeRole)
+	query.Register(logger, registry, "AssumeRoleWithWebIdentity", s.AssumeRoleWithWebIdentity)
+	query.Register(logger, registry, "GetAccessKeyInfo", s.GetAccessKeyInfo)
+	query.RegisterWithRequest(logger, registry, "GetCallerIdentity", s.GetCallerIdentity)
+	query.RegisterWithRequest(logger, registry, "GetSessionToken", s.GetSessionToken)
+	capabilityRegistry.Add("sts", registry.Operations()...)
+	return query.NewHandler(registry)
+}
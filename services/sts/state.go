@@ -0,0 +1,69 @@
+package sts
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"aws-in-a-box/state"
+)
+
+type credentialsState struct {
+	// Sorted by access key ID.
+	Credentials []issuedCredentials
+}
+
+// TryPause pauses changes to the credentials while a snapshot copies them.
+func (s *STS) TryPause() (func(), bool) {
+	return state.TryLock(&s.mu)
+}
+
+// ExportState writes the unexpired credentials STS issued to the archive, so they're accepted once
+// they're imported.
+func (s *STS) ExportState(w *state.Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+
+	var exported credentialsState
+	now := s.clock()
+	for _, issued := range s.issued {
+		if issued.Expiration.After(now) {
+			exported.Credentials = append(exported.Credentials, issued)
+		}
+	}
+	slices.SortFunc(exported.Credentials, func(a, b issuedCredentials) int {
+		return strings.Compare(a.AccessKeyId, b.AccessKeyId)
+	})
+	return w.WriteJSON("credentials.json", exported)
+}
+
+// ImportState replaces the credentials STS issued with the archive's. The previously issued
+// credentials are no longer accepted.
+func (s *STS) ImportState(r *state.Reader) (func() error, error) {
+	var imported credentialsState
+	if err := r.ReadJSON("credentials.json", &imported); err != nil {
+		return nil, err
+	}
+	issued := make(map[string]issuedCredentials)
+	for _, credentials := range imported.Credentials {
+		if !strings.HasPrefix(credentials.AccessKeyId, "ASIA") || credentials.SecretAccessKey == "" || credentials.Account == "" {
+			return nil, fmt.Errorf("invalid credentials %s", credentials.AccessKeyId)
+		}
+		issued[credentials.AccessKeyId] = credentials
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.authenticator != nil {
+			for accessKeyId := range s.issued {
+				s.authenticator.Remove(accessKeyId)
+			}
+			for _, credentials := range issued {
+				s.authenticator.Add(credentials.Credential)
+			}
+		}
+		s.issued = issued
+		return nil
+	}, nil
+}
@@ -0,0 +1,245 @@
+package sts
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/auth"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/random"
+)
+
+const (
+	defaultAssumeRoleDuration   = time.Hour
+	defaultSessionTokenDuration = 12 * time.Hour
+)
+
+// roleResourceRegex matches the resource of a role's ARN, which is role/<path>/<name>.
+var roleResourceRegex = regexp.MustCompile(`^role/(?:[!-~]+/)?([\w+=,.@-]{1,64})$`)
+
+// STS issues temporary credentials. The authenticator accepts them like any other access key, and
+// verifies requests signed with them when it verifies signatures.
+type STS struct {
+	logger        *slog.Logger
+	arnGenerator  arn.Generator
+	authenticator *auth.Authenticator
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu sync.Mutex
+	// The credentials added to the authenticator, keyed by access key ID, so they can be exported.
+	issued map[string]issuedCredentials
+}
+
+type issuedCredentials struct {
+	auth.Credential
+	Expiration time.Time
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// Issued credentials are added to the authenticator, if any, so they're accepted even when only
+	// some access keys are, and requests made with them are made by the role they're for.
+	Authenticator *auth.Authenticator
+}
+
+func New(options Options) *STS {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	return &STS{
+		logger:        options.Logger,
+		arnGenerator:  options.ArnGenerator,
+		authenticator: options.Authenticator,
+		clock:         clock.Now,
+		issued:        make(map[string]issuedCredentials),
+	}
+}
+
+// newCredentials issues credentials for the identity's account and principal.
+func (s *STS) newCredentials(durationSeconds int32, defaultDuration time.Duration, identity auth.Credential) APICredentials {
+	duration := defaultDuration
+	if durationSeconds != 0 {
+		duration = time.Duration(durationSeconds) * time.Second
+	}
+	credentials := APICredentials{
+		AccessKeyId:     "ASIA" + random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", 16),
+		SecretAccessKey: random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/", 40),
+		SessionToken:    random.String("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/", 256),
+		Expiration:      s.clock().Add(duration).Truncate(time.Second),
+	}
+	if s.authenticator != nil {
+		identity.AccessKeyId = credentials.AccessKeyId
+		identity.SecretAccessKey = credentials.SecretAccessKey
+		s.authenticator.Add(identity)
+		s.mu.Lock()
+		s.issued[identity.AccessKeyId] = issuedCredentials{Credential: identity, Expiration: credentials.Expiration}
+		s.mu.Unlock()
+	}
+	return credentials
+}
+
+// caller returns who made the request, which is the default account's root user if it isn't signed.
+func (s *STS) caller(r *http.Request) auth.Credential {
+	if credential, ok := auth.Caller(r); ok {
+		return credential
+	}
+	account := s.arnGenerator.AwsAccountId
+	return auth.Credential{Account: account, Principal: auth.RootPrincipal(account)}
+}
+
+// assumedRoleUser returns the identity of a session of the role. Roles' unique IDs are derived from
+// their ARNs, so they're the same for every session.
+func assumedRoleUser(roleArn string, sessionName string) (APIAssumedRoleUser, *awserrors.Error) {
+	role, err := parseRoleArn(roleArn)
+	if err != nil {
+		return APIAssumedRoleUser{}, ValidationError(fmt.Sprintf("%s is invalid", roleArn))
+	}
+	roleName := roleResourceRegex.FindStringSubmatch(role.Resource)[1]
+	hash := sha256.Sum256([]byte(roleArn))
+	roleId := "AROA" + base32.StdEncoding.EncodeToString(hash[:])[:17]
+	return APIAssumedRoleUser{
+		Arn:           fmt.Sprintf("arn:%s:sts::%s:assumed-role/%s/%s", role.Partition, role.AccountId, roleName, sessionName),
+		AssumedRoleId: roleId + ":" + sessionName,
+	}, nil
+}
+
+// parseRoleArn checks the ARN is an IAM role's.
+func parseRoleArn(roleArn string) (arn.ARN, error) {
+	role, err := arn.Parse(roleArn)
+	if err != nil {
+		return arn.ARN{}, err
+	}
+	if role.Service != "iam" || role.Region != "" || len(role.AccountId) != 12 || !roleResourceRegex.MatchString(role.Resource) {
+		return arn.ARN{}, fmt.Errorf("%s is not a role ARN", roleArn)
+	}
+	return role, nil
+}
+
+// roleIdentity returns who requests made with a role session's credentials are made by. The role
+// ARN must have been validated by assumedRoleUser.
+func roleIdentity(roleArn string, user APIAssumedRoleUser) auth.Credential {
+	role, _ := parseRoleArn(roleArn)
+	return auth.Credential{
+		Account:   role.AccountId,
+		Principal: user.Arn,
+		UserId:    user.AssumedRoleId,
+	}
+}
+
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_GetCallerIdentity.html
+func (s *STS) GetCallerIdentity(r *http.Request, input GetCallerIdentityInput) (*GetCallerIdentityOutput, *awserrors.Error) {
+	caller := s.caller(r)
+	userId := caller.UserId
+	if userId == "" {
+		userId = caller.AccessKeyId
+		if caller.Principal == auth.RootPrincipal(caller.Account) {
+			userId = caller.Account
+		}
+	}
+	return &GetCallerIdentityOutput{
+		Account: caller.Account,
+		Arn:     caller.Principal,
+		UserId:  userId,
+	}, nil
+}
+
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_GetAccessKeyInfo.html
+func (s *STS) GetAccessKeyInfo(input GetAccessKeyInfoInput) (*GetAccessKeyInfoOutput, *awserrors.Error) {
+	if s.authenticator != nil {
+		if credential, ok := s.authenticator.Lookup(input.AccessKeyId); ok {
+			return &GetAccessKeyInfoOutput{Account: credential.Account}, nil
+		}
+	}
+	return &GetAccessKeyInfoOutput{Account: s.arnGenerator.AwsAccountId}, nil
+}
+
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_GetSessionToken.html
+func (s *STS) GetSessionToken(r *http.Request, input GetSessionTokenInput) (*GetSessionTokenOutput, *awserrors.Error) {
+	credentials := s.newCredentials(input.DurationSeconds, defaultSessionTokenDuration, s.caller(r))
+	s.logger.Debug("Issued session token", "accessKeyId", credentials.AccessKeyId)
+	return &GetSessionTokenOutput{Credentials: credentials}, nil
+}
+
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html
+func (s *STS) AssumeRole(input AssumeRoleInput) (*AssumeRoleOutput, *awserrors.Error) {
+	user, awserr := assumedRoleUser(input.RoleArn, input.RoleSessionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+	credentials := s.newCredentials(input.DurationSeconds, defaultAssumeRoleDuration, roleIdentity(input.RoleArn, user))
+	s.logger.Debug("Assumed role", "role", input.RoleArn, "session", input.RoleSessionName, "accessKeyId", credentials.AccessKeyId)
+	return &AssumeRoleOutput{
+		AssumedRoleUser: user,
+		Credentials:     credentials,
+		SourceIdentity:  input.SourceIdentity,
+	}, nil
+}
+
+// webIdentityClaims are the claims of a web identity token STS uses.
+type webIdentityClaims struct {
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"`
+	Audience any    `json:"aud"`
+}
+
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRoleWithWebIdentity.html
+func (s *STS) AssumeRoleWithWebIdentity(input AssumeRoleWithWebIdentityInput) (*AssumeRoleWithWebIdentityOutput, *awserrors.Error) {
+	user, awserr := assumedRoleUser(input.RoleArn, input.RoleSessionName)
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	// The token's signature isn't verified, but it must be a JWT, since its claims are returned.
+	parts := strings.Split(input.WebIdentityToken, ".")
+	if len(parts) != 3 {
+		return nil, InvalidIdentityTokenException("The web identity token is not a valid JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, InvalidIdentityTokenException("The web identity token is not a valid JWT")
+	}
+	var claims webIdentityClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, InvalidIdentityTokenException("The web identity token is not a valid JWT")
+	}
+	if claims.Subject == "" {
+		return nil, InvalidIdentityTokenException("The web identity token has no subject")
+	}
+	audience := ""
+	switch aud := claims.Audience.(type) {
+	case string:
+		audience = aud
+	case []any:
+		if len(aud) > 0 {
+			audience, _ = aud[0].(string)
+		}
+	}
+	provider := input.ProviderId
+	if provider == "" {
+		provider = strings.TrimPrefix(claims.Issuer, "https://")
+	}
+
+	credentials := s.newCredentials(input.DurationSeconds, defaultAssumeRoleDuration, roleIdentity(input.RoleArn, user))
+	s.logger.Debug("Assumed role with web identity", "role", input.RoleArn, "subject", claims.Subject, "accessKeyId", credentials.AccessKeyId)
+	return &AssumeRoleWithWebIdentityOutput{
+		AssumedRoleUser:             user,
+		Audience:                    audience,
+		Credentials:                 credentials,
+		Provider:                    provider,
+		SubjectFromWebIdentityToken: claims.Subject,
+	}, nil
+}
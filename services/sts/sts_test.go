@@ -0,0 +1,181 @@
+package sts
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/auth"
+)
+
+var now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newSTS() *STS {
+	s := New(Options{ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}})
+	s.clock = func() time.Time { return now }
+	return s
+}
+
+func TestAssumeRole(t *testing.T) {
+	s := newSTS()
+	output, awserr := s.AssumeRole(AssumeRoleInput{
+		RoleArn:         "arn:aws:iam::210987654321:role/service/deployer",
+		RoleSessionName: "ci",
+		DurationSeconds: 900,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.AssumedRoleUser.Arn != "arn:aws:sts::210987654321:assumed-role/deployer/ci" {
+		t.Fatalf("Unexpected ARN %s", output.AssumedRoleUser.Arn)
+	}
+	if !strings.HasPrefix(output.AssumedRoleUser.AssumedRoleId, "AROA") || !strings.HasSuffix(output.AssumedRoleUser.AssumedRoleId, ":ci") {
+		t.Fatalf("Unexpected role ID %s", output.AssumedRoleUser.AssumedRoleId)
+	}
+	if !strings.HasPrefix(output.Credentials.AccessKeyId, "ASIA") || !output.Credentials.Expiration.Equal(now.Add(15*time.Minute)) {
+		t.Fatalf("Unexpected credentials %+v", output.Credentials)
+	}
+
+	// Sessions of the same role have the same role ID.
+	other, _ := s.AssumeRole(AssumeRoleInput{
+		RoleArn:         "arn:aws:iam::210987654321:role/service/deployer",
+		RoleSessionName: "other",
+	})
+	if strings.TrimSuffix(other.AssumedRoleUser.AssumedRoleId, ":other") != strings.TrimSuffix(output.AssumedRoleUser.AssumedRoleId, ":ci") {
+		t.Fatalf("Expected the same role ID, got %s and %s", other.AssumedRoleUser.AssumedRoleId, output.AssumedRoleUser.AssumedRoleId)
+	}
+	if other.Credentials.AccessKeyId == output.Credentials.AccessKeyId {
+		t.Fatal("Expected different credentials")
+	}
+
+	_, awserr = s.AssumeRole(AssumeRoleInput{RoleArn: "arn:aws:iam::123:user/someone", RoleSessionName: "ci"})
+	if awserr == nil || awserr.Body.Type != "ValidationError" {
+		t.Fatalf("Expected a ValidationError, got %v", awserr)
+	}
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	s := newSTS()
+	claims := `{"iss":"https://token.actions.githubusercontent.com","sub":"repo:org/repo:ref:refs/heads/main","aud":["sts.amazonaws.com"]}`
+	token := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	output, awserr := s.AssumeRoleWithWebIdentity(AssumeRoleWithWebIdentityInput{
+		RoleArn:          "arn:aws:iam::123456789012:role/github",
+		RoleSessionName:  "deploy",
+		WebIdentityToken: token,
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if output.SubjectFromWebIdentityToken != "repo:org/repo:ref:refs/heads/main" ||
+		output.Audience != "sts.amazonaws.com" ||
+		output.Provider != "token.actions.githubusercontent.com" {
+		t.Fatalf("Unexpected output %+v", output)
+	}
+
+	_, awserr = s.AssumeRoleWithWebIdentity(AssumeRoleWithWebIdentityInput{
+		RoleArn:          "arn:aws:iam::123456789012:role/github",
+		RoleSessionName:  "deploy",
+		WebIdentityToken: "not a token",
+	})
+	if awserr == nil || awserr.Body.Type != "InvalidIdentityToken" {
+		t.Fatalf("Expected an InvalidIdentityToken error, got %v", awserr)
+	}
+}
+
+func postQuery(t *testing.T, form url.Values) *httptest.ResponseRecorder {
+	handler := NewHandler(slog.Default(), newSTS(), nil)
+	form.Set("Version", "2011-06-15")
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response := httptest.NewRecorder()
+	if !handler(response, request) {
+		t.Fatal("Expected the request to be handled")
+	}
+	return response
+}
+
+func TestQueryProtocol(t *testing.T) {
+	response := postQuery(t, url.Values{"Action": {"GetCallerIdentity"}})
+	var identity struct {
+		Result GetCallerIdentityOutput `xml:"GetCallerIdentityResult"`
+	}
+	if err := xml.Unmarshal(response.Body.Bytes(), &identity); err != nil {
+		t.Fatal(err)
+	}
+	if identity.Result.Account != "123456789012" || identity.Result.Arn != "arn:aws:iam::123456789012:root" {
+		t.Fatalf("Unexpected identity %+v", identity.Result)
+	}
+
+	response = postQuery(t, url.Values{
+		"Action":              {"AssumeRole"},
+		"RoleArn":             {"arn:aws:iam::123456789012:role/deployer"},
+		"RoleSessionName":     {"ci"},
+		"Tags.member.1.Key":   {"team"},
+		"Tags.member.1.Value": {"platform"},
+	})
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "<Expiration>2024-01-02T04:04:05Z</Expiration>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+
+	// Requests are validated before they're handled.
+	response = postQuery(t, url.Values{
+		"Action":          {"AssumeRole"},
+		"RoleArn":         {"arn:aws:iam::123456789012:role/deployer"},
+		"RoleSessionName": {"ci"},
+		"DurationSeconds": {"60"},
+	})
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "<Code>ValidationError</Code>") {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+}
+
+func TestCallerIdentity(t *testing.T) {
+	authenticator := auth.New(auth.Options{DefaultAccount: "123456789012"})
+	s := New(Options{
+		ArnGenerator:  arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"},
+		Authenticator: authenticator,
+	})
+
+	assumed, awserr := s.AssumeRole(AssumeRoleInput{
+		RoleArn:         "arn:aws:iam::210987654321:role/deployer",
+		RoleSessionName: "ci",
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+
+	// Requests made with the role's credentials are made by the role session.
+	request := httptest.NewRequest(http.MethodPost, "/", nil)
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+assumed.Credentials.AccessKeyId+"/20240102/us-east-1/sts/aws4_request")
+	request, _ = authenticator.Authenticate(request)
+	identity, awserr := s.GetCallerIdentity(request, GetCallerIdentityInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	want := GetCallerIdentityOutput{
+		Account: "210987654321",
+		Arn:     "arn:aws:sts::210987654321:assumed-role/deployer/ci",
+		UserId:  assumed.AssumedRoleUser.AssumedRoleId,
+	}
+	if *identity != want {
+		t.Fatalf("got %+v, want %+v", *identity, want)
+	}
+
+	info, _ := s.GetAccessKeyInfo(GetAccessKeyInfoInput{AccessKeyId: assumed.Credentials.AccessKeyId})
+	if info.Account != "210987654321" {
+		t.Fatalf("Unexpected account %s", info.Account)
+	}
+
+	// Session tokens are for whoever requested them.
+	session, _ := s.GetSessionToken(request, GetSessionTokenInput{})
+	if credential, ok := authenticator.Lookup(session.Credentials.AccessKeyId); !ok || credential.Principal != want.Arn {
+		t.Fatalf("Unexpected session credential %+v", credential)
+	}
+}
@@ -0,0 +1,79 @@
+package sts
+
+import "time"
+
+type APICredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+type APIAssumedRoleUser struct {
+	Arn           string
+	AssumedRoleId string
+}
+
+type APITag struct {
+	Key   string `required:"true" length:"1,128"`
+	Value string `length:",256"`
+}
+
+type GetCallerIdentityInput struct{}
+
+type GetCallerIdentityOutput struct {
+	Account string
+	Arn     string
+	UserId  string
+}
+
+type GetAccessKeyInfoInput struct {
+	AccessKeyId string `required:"true" length:"16,128" pattern:"[A-Z0-9]+"`
+}
+
+type GetAccessKeyInfoOutput struct {
+	Account string
+}
+
+type GetSessionTokenInput struct {
+	DurationSeconds int32 `range:"900,129600"`
+	SerialNumber    string
+	TokenCode       string
+}
+
+type GetSessionTokenOutput struct {
+	Credentials APICredentials
+}
+
+type AssumeRoleInput struct {
+	RoleArn         string `required:"true" length:"20,2048"`
+	RoleSessionName string `required:"true" length:"2,64" pattern:"[a-zA-Z0-9_+=,.@-]+"`
+	DurationSeconds int32  `range:"900,43200"`
+	ExternalId      string `length:"2,1224"`
+	Policy          string
+	SourceIdentity  string   `length:"2,64"`
+	Tags            []APITag `length:",50"`
+}
+
+type AssumeRoleOutput struct {
+	AssumedRoleUser APIAssumedRoleUser
+	Credentials     APICredentials
+	SourceIdentity  string
+}
+
+type AssumeRoleWithWebIdentityInput struct {
+	RoleArn          string `required:"true" length:"20,2048"`
+	RoleSessionName  string `required:"true" length:"2,64" pattern:"[a-zA-Z0-9_+=,.@-]+"`
+	WebIdentityToken string `required:"true" length:"4,20000"`
+	DurationSeconds  int32  `range:"900,43200"`
+	Policy           string
+	ProviderId       string `length:"4,2048"`
+}
+
+type AssumeRoleWithWebIdentityOutput struct {
+	AssumedRoleUser             APIAssumedRoleUser
+	Audience                    string
+	Credentials                 APICredentials
+	Provider                    string
+	SubjectFromWebIdentityToken string
+}
@@ -0,0 +1,52 @@
+package xray
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ServeDaemon receives segments the way the X-Ray daemon does, as UDP packets which each have a
+// header line, {"format": "json", "version": 1}, followed by one segment document. It runs until
+// the connection is closed. Packets don't say their regions, so the segments are stored in the
+// default region's traces, as a daemon configured with that region would send them.
+func (x *XRay) ServeDaemon(conn net.PacketConn) error {
+	// The daemon accepts packets of up to 64 KB.
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if err := x.handleDaemonPacket(buf[:n]); err != nil {
+			x.logger.Debug("Handling X-Ray daemon packet", "error", err)
+		}
+	}
+}
+
+func (x *XRay) handleDaemonPacket(packet []byte) error {
+	header, document, found := bytes.Cut(packet, []byte("\n"))
+	if !found {
+		return errors.New("missing header")
+	}
+	var parsed struct {
+		Format  string `json:"format"`
+		Version int    `json:"version"`
+	}
+	if err := json.Unmarshal(header, &parsed); err != nil || parsed.Format != "json" || parsed.Version != 1 {
+		return fmt.Errorf("invalid header %q", header)
+	}
+
+	output, _ := x.PutTraceSegments(PutTraceSegmentsInput{
+		TraceSegmentDocuments: []string{string(bytes.TrimSpace(document))},
+	})
+	if len(output.UnprocessedTraceSegments) > 0 {
+		return errors.New(output.UnprocessedTraceSegments[0].Message)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package xray
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServeDaemon(t *testing.T) {
+	x := newXRay(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go x.ServeDaemon(conn)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	header := `{"format": "json", "version": 1}` + "\n"
+	for _, packet := range []string{
+		"no header",
+		header + "not json",
+		header + segment(t, map[string]any{"id": "0000000000000001", "trace_id": traceId(1)}),
+	} {
+		if _, err := client.Write([]byte(packet)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(x.Traces()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the segment")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	traces := x.Traces()
+	if len(traces) != 1 || traces[0].Id != traceId(1) || traces[0].Segments[0].Id != "0000000000000001" {
+		t.Errorf("Unexpected traces: %+v", traces)
+	}
+}
@@ -0,0 +1,7 @@
+package xray
+
+import "aws-in-a-box/awserrors"
+
+func InvalidRequestException(message string) *awserrors.Error {
+	return awserrors.Generate400Exception("InvalidRequestException", message)
+}
@@ -0,0 +1,327 @@
+package xray
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// traceFilter reports whether a trace matches a filter expression.
+type traceFilter func(trace *Trace, summary *APITraceSummary) bool
+
+// parseFilterExpression parses the subset of X-Ray's filter expressions which can be answered from
+// a trace's summary: the keywords ok, error, fault, throttle and partial, service("name"),
+// comparisons of duration, responsetime, http.status, http.url, http.method, http.useragent,
+// http.clientip, user and annotation.KEY, and combinations of those with AND, OR, NOT and
+// parentheses.
+// See https://docs.aws.amazon.com/xray/latest/devguide/xray-console-filters.html
+func parseFilterExpression(expression string) (traceFilter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	filter, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return filter, nil
+}
+
+type filterToken struct {
+	text string
+	// Whether the token was a quoted string.
+	quoted bool
+}
+
+var filterOperators = []string{"&&", "||", "!=", "<=", ">=", "=", "<", ">", "!", "(", ")"}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expression); {
+		c := rune(expression[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := strings.IndexByte(expression[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, filterToken{text: expression[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		default:
+			matched := false
+			for _, operator := range filterOperators {
+				if strings.HasPrefix(expression[i:], operator) {
+					tokens = append(tokens, filterToken{text: operator})
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			start := i
+			for i < len(expression) && !unicode.IsSpace(rune(expression[i])) &&
+				!strings.ContainsRune(`"=!<>()&|`, rune(expression[i])) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected %q", expression[i:i+1])
+			}
+			tokens = append(tokens, filterToken{text: expression[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// accept consumes the next token if it's one of the given keywords or operators.
+func (p *filterParser) accept(texts ...string) bool {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return false
+	}
+	for _, text := range texts {
+		if strings.EqualFold(p.tokens[p.pos].text, text) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *filterParser) or() (traceFilter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR", "||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t *Trace, s *APITraceSummary) bool { return l(t, s) || right(t, s) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) and() (traceFilter, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND", "&&") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t *Trace, s *APITraceSummary) bool { return l(t, s) && right(t, s) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) not() (traceFilter, error) {
+	if p.accept("NOT", "!") {
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(t *Trace, s *APITraceSummary) bool { return !inner(t, s) }, nil
+	}
+	return p.term()
+}
+
+func (p *filterParser) term() (traceFilter, error) {
+	if p.accept("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if token.quoted {
+		return nil, fmt.Errorf("unexpected %q", token.text)
+	}
+	keyword := strings.ToLower(token.text)
+	switch keyword {
+	case "ok":
+		return func(t *Trace, s *APITraceSummary) bool { return !s.HasError && !s.HasFault && !s.HasThrottle }, nil
+	case "error":
+		return func(t *Trace, s *APITraceSummary) bool { return s.HasError }, nil
+	case "fault":
+		return func(t *Trace, s *APITraceSummary) bool { return s.HasFault }, nil
+	case "throttle":
+		return func(t *Trace, s *APITraceSummary) bool { return s.HasThrottle }, nil
+	case "partial":
+		return func(t *Trace, s *APITraceSummary) bool { return s.IsPartial }, nil
+	case "service":
+		if !p.accept("(") {
+			return nil, fmt.Errorf("expected ( after service")
+		}
+		name, err := p.next()
+		if err != nil || !name.quoted {
+			return nil, fmt.Errorf("expected a quoted service name")
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return func(t *Trace, s *APITraceSummary) bool {
+			for _, service := range s.ServiceIds {
+				if service.Name == name.text {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	values, err := filterKey(keyword, token.text)
+	if err != nil {
+		return nil, err
+	}
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToUpper(operator.text)
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=", "CONTAINS", "BEGINSWITH", "ENDSWITH":
+	default:
+		return nil, fmt.Errorf("unexpected %q after %s", operator.text, token.text)
+	}
+	operand, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	return func(t *Trace, s *APITraceSummary) bool {
+		for _, value := range values(t, s) {
+			if compareFilterValue(value, op, operand) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// filterKey returns a function which returns the values of a key for a trace. A comparison matches
+// if any of the values do.
+func filterKey(keyword string, key string) (func(t *Trace, s *APITraceSummary) []any, error) {
+	switch keyword {
+	case "duration":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{s.Duration} }, nil
+	case "responsetime":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{s.ResponseTime} }, nil
+	case "http.status":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{float64(s.Http.HttpStatus)} }, nil
+	case "http.url":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{s.Http.HttpURL} }, nil
+	case "http.method":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{s.Http.HttpMethod} }, nil
+	case "http.useragent":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{s.Http.UserAgent} }, nil
+	case "http.clientip":
+		return func(t *Trace, s *APITraceSummary) []any { return []any{s.Http.ClientIp} }, nil
+	case "user":
+		return func(t *Trace, s *APITraceSummary) []any {
+			var users []any
+			for _, user := range s.Users {
+				users = append(users, user.UserName)
+			}
+			return users
+		}, nil
+	}
+	// Annotation names are case sensitive, unlike the keywords.
+	if name, ok := strings.CutPrefix(key, "annotation."); ok && name != "" {
+		return func(t *Trace, s *APITraceSummary) []any {
+			var values []any
+			walkSegments(t, func(document *segmentDocument) {
+				if value, ok := document.Annotations[name]; ok {
+					values = append(values, value)
+				}
+			})
+			return values
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key %q", key)
+}
+
+// compareFilterValue compares a trace's value with a filter's operand, as numbers if the value is a
+// number and as strings otherwise.
+func compareFilterValue(value any, op string, operand filterToken) bool {
+	switch value := value.(type) {
+	case float64:
+		n, err := strconv.ParseFloat(operand.text, 64)
+		if err != nil || operand.quoted {
+			return false
+		}
+		switch op {
+		case "=":
+			return value == n
+		case "!=":
+			return value != n
+		case "<":
+			return value < n
+		case "<=":
+			return value <= n
+		case ">":
+			return value > n
+		case ">=":
+			return value >= n
+		}
+		return false
+	case bool:
+		b, err := strconv.ParseBool(operand.text)
+		if err != nil || operand.quoted {
+			return false
+		}
+		switch op {
+		case "=":
+			return value == b
+		case "!=":
+			return value != b
+		}
+		return false
+	case string:
+		switch op {
+		case "=":
+			return value == operand.text
+		case "!=":
+			return value != operand.text
+		case "CONTAINS":
+			return strings.Contains(value, operand.text)
+		case "BEGINSWITH":
+			return strings.HasPrefix(value, operand.text)
+		case "ENDSWITH":
+			return strings.HasSuffix(value, operand.text)
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package xray
+
+import (
+	"log/slog"
+	"net/http"
+
+	"aws-in-a-box/capabilities"
+	"aws-in-a-box/http/restjson"
+	"aws-in-a-box/region"
+)
+
+// X-Ray only supports the REST-JSON protocol. Its paths are capitalized, so they never clash with
+// path-style S3 requests, whose bucket names are lowercase.
+func NewHandler(logger *slog.Logger, x *XRay, capabilityRegistry *capabilities.Registry) func(w http.ResponseWriter, r *http.Request) bool {
+	registry := restjson.NewRegistry()
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/TraceSegments", "PutTraceSegments", region.Method(x.regions, (*XRay).PutTraceSegments))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/TraceSummaries", "GetTraceSummaries", region.Method(x.regions, (*XRay).GetTraceSummaries))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/Traces", "BatchGetTraces", region.Method(x.regions, (*XRay).BatchGetTraces))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/GetSamplingRules", "GetSamplingRules", region.Method(x.regions, (*XRay).GetSamplingRules))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/SamplingTargets", "GetSamplingTargets", region.Method(x.regions, (*XRay).GetSamplingTargets))
+	restjson.RegisterWithRequest(logger, registry, http.MethodPost, "/TelemetryRecords", "PutTelemetryRecords", region.Method(x.regions, (*XRay).PutTelemetryRecords))
+	capabilityRegistry.Add("xray", registry.Operations()...)
+	return restjson.NewHandler(registry)
+}
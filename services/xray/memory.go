@@ -0,0 +1,23 @@
+package xray
+
+import (
+	"aws-in-a-box/memory"
+)
+
+// MemoryUsage returns the size of each region's stored traces' segment documents.
+func (x *XRay) MemoryUsage() []memory.Resource {
+	return x.regions.MemoryUsage((*XRay).memoryUsage)
+}
+
+func (x *XRay) memoryUsage() []memory.Resource {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	resource := memory.Resource{Name: "traces", Count: len(x.tracesById)}
+	for _, trace := range x.tracesById {
+		for _, segment := range trace.Segments {
+			resource.Bytes += int64(len(segment.Document))
+		}
+	}
+	return []memory.Resource{resource}
+}
@@ -0,0 +1,76 @@
+package xray
+
+import (
+	"time"
+
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	defaultRuleName = "Default"
+	// How often SDKs should ask for new sampling targets, in seconds.
+	samplingTargetInterval = 10
+)
+
+// The only sampling rule is the default one. Unlike AWS's, which samples the first request each
+// second and 5% of the rest, it samples every request, since locally every trace is of interest.
+func (x *XRay) defaultRule() APISamplingRule {
+	return APISamplingRule{
+		Attributes:    map[string]string{},
+		FixedRate:     1,
+		HTTPMethod:    "*",
+		Host:          "*",
+		Priority:      10000,
+		ReservoirSize: 1,
+		ResourceARN:   "*",
+		RuleARN:       x.arnGenerator.Generate("xray", "sampling-rule", defaultRuleName),
+		RuleName:      defaultRuleName,
+		ServiceName:   "*",
+		ServiceType:   "*",
+		URLPath:       "*",
+		Version:       1,
+	}
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_GetSamplingRules.html
+func (x *XRay) GetSamplingRules(input GetSamplingRulesInput) (*GetSamplingRulesOutput, *awserrors.Error) {
+	return &GetSamplingRulesOutput{
+		SamplingRuleRecords: []APISamplingRuleRecord{{
+			SamplingRule: x.defaultRule(),
+		}},
+	}, nil
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_GetSamplingTargets.html
+func (x *XRay) GetSamplingTargets(input GetSamplingTargetsInput) (*GetSamplingTargetsOutput, *awserrors.Error) {
+	output := &GetSamplingTargetsOutput{
+		SamplingTargetDocuments: []APISamplingTargetDocument{},
+		UnprocessedStatistics:   []APIUnprocessedStatistics{},
+	}
+	now := x.clock()
+	for _, statistics := range input.SamplingStatisticsDocuments {
+		if statistics.RuleName != defaultRuleName {
+			output.UnprocessedStatistics = append(output.UnprocessedStatistics, APIUnprocessedStatistics{
+				ErrorCode: "400",
+				Message:   "Unknown rule",
+				RuleName:  statistics.RuleName,
+			})
+			continue
+		}
+		output.SamplingTargetDocuments = append(output.SamplingTargetDocuments, APISamplingTargetDocument{
+			FixedRate:         1,
+			Interval:          samplingTargetInterval,
+			ReservoirQuota:    1,
+			ReservoirQuotaTTL: timestamp.EpochSeconds(now.Add(samplingTargetInterval * time.Second)),
+			RuleName:          statistics.RuleName,
+		})
+	}
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_PutTelemetryRecords.html
+// The X-Ray daemon reports its own health with these. They're accepted and ignored.
+func (x *XRay) PutTelemetryRecords(input PutTelemetryRecordsInput) (*PutTelemetryRecordsOutput, *awserrors.Error) {
+	return &PutTelemetryRecordsOutput{}, nil
+}
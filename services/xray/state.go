@@ -0,0 +1,82 @@
+package xray
+
+import (
+	"fmt"
+	"sync"
+
+	"aws-in-a-box/state"
+)
+
+type tracesState struct {
+	// In the order they were first received.
+	Traces []*Trace
+}
+
+// TryPause pauses changes to each region's traces while a snapshot copies them.
+func (x *XRay) TryPause() (func(), bool) {
+	return x.regions.TryPause(func(x *XRay) *sync.Mutex { return &x.mu })
+}
+
+// ExportState writes each region's traces, with their segment documents, to the archive.
+func (x *XRay) ExportState(w *state.Writer) error {
+	return x.regions.ExportState(w, (*XRay).exportState)
+}
+
+func (x *XRay) exportState(w *state.Writer) error {
+	unlock := w.Lock(&x.mu)
+	defer unlock()
+
+	var exported tracesState
+	for _, id := range x.traceIds {
+		exported.Traces = append(exported.Traces, x.tracesById[id])
+	}
+	return w.WriteJSON("traces.json", exported)
+}
+
+// ImportState replaces each region's traces with the archive's. Segment documents are parsed again,
+// and traces which are past their retention period are forgotten.
+func (x *XRay) ImportState(r *state.Reader) (func() error, error) {
+	return x.regions.ImportState(r, (*XRay).importState, (*XRay).exportState)
+}
+
+func (x *XRay) importState(r *state.Reader) (func() error, error) {
+	var imported tracesState
+	if err := r.ReadJSON("traces.json", &imported); err != nil {
+		return nil, err
+	}
+
+	tracesById := make(map[string]*Trace)
+	var traceIds []string
+	for _, trace := range imported.Traces {
+		if trace == nil || !traceIdRegex.MatchString(trace.Id) {
+			return nil, fmt.Errorf("invalid trace")
+		}
+		if _, ok := tracesById[trace.Id]; ok {
+			return nil, fmt.Errorf("trace %s is duplicated", trace.Id)
+		}
+		for _, segment := range trace.Segments {
+			if segment == nil {
+				return nil, fmt.Errorf("trace %s: invalid segment", trace.Id)
+			}
+			parsed, err := parseSegment(segment.Document)
+			if err != nil {
+				return nil, fmt.Errorf("trace %s: %v", trace.Id, err)
+			}
+			if parsed.Id != segment.Id || parsed.TraceId != trace.Id {
+				return nil, fmt.Errorf("trace %s: segment %s's document has a different ID", trace.Id, segment.Id)
+			}
+			segment.parsed = parsed
+		}
+		tracesById[trace.Id] = trace
+		traceIds = append(traceIds, trace.Id)
+	}
+
+	return func() error {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		x.tracesById = tracesById
+		x.traceIds = traceIds
+		x.lockedExpireTraces()
+		return nil
+	}, nil
+}
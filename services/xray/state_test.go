@@ -0,0 +1,40 @@
+package xray
+
+import (
+	"bytes"
+	"testing"
+
+	"aws-in-a-box/state"
+)
+
+func TestExportImportState(t *testing.T) {
+	x := newXRay(t)
+	putSegments(t, x,
+		segment(t, map[string]any{"id": "0000000000000001", "trace_id": traceId(1)}),
+		segment(t, map[string]any{"id": "0000000000000002", "trace_id": traceId(2)}),
+	)
+
+	var buf bytes.Buffer
+	if err := state.Export(&buf, state.Registry{"xray": x}, ""); err != nil {
+		t.Fatal(err)
+	}
+	imported := newXRay(t)
+	putSegments(t, imported, segment(t, map[string]any{"id": "0000000000000003", "trace_id": traceId(3)}))
+	if _, _, err := state.Import(&buf, state.Registry{"xray": imported}); err != nil {
+		t.Fatal(err)
+	}
+
+	output, awserr := imported.BatchGetTraces(BatchGetTracesInput{TraceIds: []string{traceId(1), traceId(2), traceId(3)}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Traces) != 2 || len(output.UnprocessedTraceIds) != 1 || output.UnprocessedTraceIds[0] != traceId(3) {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	// The imported segments were parsed again.
+	for _, trace := range output.Traces {
+		if trace.Duration != 0.5 {
+			t.Errorf("Unexpected trace: %+v", trace)
+		}
+	}
+}
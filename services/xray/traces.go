@@ -0,0 +1,299 @@
+package xray
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/pagination"
+	"aws-in-a-box/timestamp"
+)
+
+const (
+	maxTraceSummaries = 100
+	// BatchGetTraces takes at most this many trace IDs.
+	maxBatchTraceIds = 5
+)
+
+// traceTime returns when the trace started according to its ID, which begins with the epoch
+// seconds it started in.
+func traceTime(id string) float64 {
+	seconds, _ := strconv.ParseInt(strings.Split(id, "-")[1], 16, 64)
+	return float64(seconds)
+}
+
+// walkSegments calls f for each segment and subsegment of the trace.
+func walkSegments(trace *Trace, f func(document *segmentDocument)) {
+	var walk func(document *segmentDocument)
+	walk = func(document *segmentDocument) {
+		f(document)
+		for i := range document.Subsegments {
+			walk(&document.Subsegments[i])
+		}
+	}
+	for _, segment := range trace.Segments {
+		walk(&segment.parsed)
+	}
+}
+
+// summarize builds the trace's summary from its segments. The trace's root segment is the one
+// without a parent.
+func summarize(trace *Trace) APITraceSummary {
+	summary := APITraceSummary{
+		Id:         trace.Id,
+		ServiceIds: []APIServiceId{},
+	}
+	start, end := math.Inf(1), math.Inf(-1)
+	users := make(map[string]bool)
+	for _, segment := range trace.Segments {
+		document := segment.parsed
+		start = min(start, document.StartTime)
+		end = max(end, document.EndTime, document.StartTime)
+		if document.InProgress {
+			summary.IsPartial = true
+		}
+		if document.Type == "subsegment" {
+			continue
+		}
+
+		service := APIServiceId{Name: document.Name, Names: []string{document.Name}, Type: document.Origin}
+		if !slices.ContainsFunc(summary.ServiceIds, func(s APIServiceId) bool { return s.Name == service.Name }) {
+			summary.ServiceIds = append(summary.ServiceIds, service)
+		}
+		if document.User != "" && !users[document.User] {
+			users[document.User] = true
+			summary.Users = append(summary.Users, APITraceUser{UserName: document.User, ServiceIds: []APIServiceId{service}})
+		}
+		for key, value := range document.Annotations {
+			if summary.Annotations == nil {
+				summary.Annotations = make(map[string][]APIValueWithServiceIds)
+			}
+			summary.Annotations[key] = append(summary.Annotations[key], APIValueWithServiceIds{
+				AnnotationValue: annotationValue(value),
+				ServiceIds:      []APIServiceId{service},
+			})
+		}
+
+		if document.ParentId == "" && summary.EntryPoint == nil {
+			summary.EntryPoint = &service
+			if !document.InProgress {
+				summary.ResponseTime = document.EndTime - document.StartTime
+			}
+			if document.HTTP != nil {
+				summary.Http = APIHttp{
+					ClientIp:   document.HTTP.Request.ClientIP,
+					HttpMethod: document.HTTP.Request.Method,
+					HttpStatus: document.HTTP.Response.Status,
+					HttpURL:    document.HTTP.Request.URL,
+					UserAgent:  document.HTTP.Request.UserAgent,
+				}
+			}
+		}
+	}
+	walkSegments(trace, func(document *segmentDocument) {
+		summary.HasError = summary.HasError || document.Error
+		summary.HasFault = summary.HasFault || document.Fault
+		summary.HasThrottle = summary.HasThrottle || document.Throttle
+	})
+	if len(trace.Segments) > 0 {
+		summary.StartTime = start
+		summary.Duration = end - start
+	}
+	return summary
+}
+
+// annotationValue converts an annotation to an AnnotationValue, which has one of NumberValue,
+// BooleanValue or StringValue.
+func annotationValue(value any) map[string]any {
+	switch value := value.(type) {
+	case float64:
+		return map[string]any{"NumberValue": value}
+	case bool:
+		return map[string]any{"BooleanValue": value}
+	default:
+		s, _ := value.(string)
+		return map[string]any{"StringValue": s}
+	}
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_GetTraceSummaries.html
+func (x *XRay) GetTraceSummaries(input GetTraceSummariesInput) (*GetTraceSummariesOutput, *awserrors.Error) {
+	if input.StartTime == 0 || input.EndTime == 0 {
+		return nil, InvalidRequestException("StartTime and EndTime are required")
+	}
+	if input.EndTime < input.StartTime {
+		return nil, InvalidRequestException("EndTime must be after StartTime")
+	}
+	switch input.TimeRangeType {
+	case "", "TraceId", "Event", "Service":
+	default:
+		return nil, InvalidRequestException("TimeRangeType must be TraceId, Event or Service")
+	}
+	var filter traceFilter
+	if strings.TrimSpace(input.FilterExpression) != "" {
+		var err error
+		filter, err = parseFilterExpression(input.FilterExpression)
+		if err != nil {
+			return nil, InvalidRequestException("Invalid filter expression: " + err.Error())
+		}
+	}
+	// Pages are always the maximum size, since there's no MaxResults.
+	limit, start, awserr := pagination.Parse(0, maxTraceSummaries, maxTraceSummaries, input.NextToken,
+		nil, InvalidRequestException("Invalid NextToken"))
+	if awserr != nil {
+		return nil, awserr
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.lockedExpireTraces()
+	output := &GetTraceSummariesOutput{
+		ApproximateTime: timestamp.EpochSeconds(x.clock()),
+		TraceSummaries:  []APITraceSummary{},
+	}
+	var matched []APITraceSummary
+	for _, id := range x.traceIds {
+		summary := summarize(x.tracesById[id])
+		when := traceTime(id)
+		if input.TimeRangeType == "Event" || input.TimeRangeType == "Service" {
+			when = summary.StartTime
+		}
+		if when < input.StartTime || when > input.EndTime {
+			continue
+		}
+		output.TracesProcessedCount++
+		if filter != nil && !filter(x.tracesById[id], &summary) {
+			continue
+		}
+		if input.TimeRangeType == "Event" {
+			summary.MatchedEventTime = summary.StartTime
+		}
+		matched = append(matched, summary)
+	}
+	// The most recent traces come first.
+	slices.Reverse(matched)
+	output.TraceSummaries, output.NextToken = pagination.Page(matched, limit, start)
+	return output, nil
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_BatchGetTraces.html
+func (x *XRay) BatchGetTraces(input BatchGetTracesInput) (*BatchGetTracesOutput, *awserrors.Error) {
+	if len(input.TraceIds) == 0 || len(input.TraceIds) > maxBatchTraceIds {
+		return nil, InvalidRequestException("TraceIds must have between 1 and 5 trace IDs")
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	output := &BatchGetTracesOutput{
+		Traces:              []APITrace{},
+		UnprocessedTraceIds: []string{},
+	}
+	for _, id := range input.TraceIds {
+		trace, ok := x.tracesById[id]
+		if !ok || trace.Updated.Before(x.clock().Add(-traceRetention)) {
+			output.UnprocessedTraceIds = append(output.UnprocessedTraceIds, id)
+			continue
+		}
+		apiTrace := APITrace{
+			Id:       trace.Id,
+			Duration: summarize(trace).Duration,
+			Segments: make([]APISegment, len(trace.Segments)),
+		}
+		for i, segment := range trace.Segments {
+			apiTrace.Segments[i] = APISegment{Id: segment.Id, Document: segment.Document}
+		}
+		output.Traces = append(output.Traces, apiTrace)
+	}
+	return output, nil
+}
+
+// Traces returns every region's stored traces, oldest first.
+func (x *XRay) Traces() []Trace {
+	traces := []Trace{}
+	for _, r := range x.regions.All() {
+		traces = append(traces, r.traces()...)
+	}
+	slices.SortStableFunc(traces, func(a, b Trace) int {
+		return a.firstReceived().Compare(b.firstReceived())
+	})
+	return traces
+}
+
+func (x *XRay) traces() []Trace {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	traces := make([]Trace, len(x.traceIds))
+	for i, id := range x.traceIds {
+		trace := x.tracesById[id]
+		traces[i] = Trace{Id: trace.Id, Segments: slices.Clone(trace.Segments), Updated: trace.Updated}
+	}
+	return traces
+}
+
+// ClearTraces forgets every region's stored traces, for example between tests.
+func (x *XRay) ClearTraces() {
+	for _, r := range x.regions.All() {
+		r.mu.Lock()
+		r.tracesById = make(map[string]*Trace)
+		r.traceIds = nil
+		r.mu.Unlock()
+	}
+}
+
+// adminTrace is a trace as the admin API returns it, with its segments' documents as JSON objects
+// rather than strings.
+type adminTrace struct {
+	Id        string
+	StartTime float64
+	Duration  float64
+	HasError  bool
+	HasFault  bool
+	Segments  []json.RawMessage
+}
+
+// RegisterAdminHandlers adds the stored traces to the admin API.
+// GET xray/traces lists them, newest first, optionally filtered by the traceId and service query
+// parameters, and DELETE xray/traces clears them.
+func (x *XRay) RegisterAdminHandlers(registry admin.Registry) {
+	registry["xray/traces"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			traceId := r.URL.Query().Get("traceId")
+			service := r.URL.Query().Get("service")
+			traces := []adminTrace{}
+			for _, trace := range x.Traces() {
+				summary := summarize(&trace)
+				if (traceId != "" && trace.Id != traceId) ||
+					(service != "" && !slices.ContainsFunc(summary.ServiceIds, func(s APIServiceId) bool { return s.Name == service })) {
+					continue
+				}
+				view := adminTrace{
+					Id:        trace.Id,
+					StartTime: summary.StartTime,
+					Duration:  summary.Duration,
+					HasError:  summary.HasError,
+					HasFault:  summary.HasFault,
+				}
+				for _, segment := range trace.Segments {
+					view.Segments = append(view.Segments, json.RawMessage(segment.Document))
+				}
+				traces = append(traces, view)
+			}
+			slices.Reverse(traces)
+			admin.WriteJSON(w, struct{ Traces []adminTrace }{traces})
+		case http.MethodDelete:
+			x.ClearTraces()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package xray
+
+type PutTraceSegmentsInput struct {
+	// Each is a segment or subsegment, as JSON.
+	TraceSegmentDocuments []string `required:"true" error:"InvalidRequestException"`
+}
+
+type PutTraceSegmentsOutput struct {
+	UnprocessedTraceSegments []APIUnprocessedTraceSegment
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_UnprocessedTraceSegment.html
+type APIUnprocessedTraceSegment struct {
+	ErrorCode string `json:",omitempty"`
+	Id        string `json:",omitempty"`
+	Message   string `json:",omitempty"`
+}
+
+type GetTraceSummariesInput struct {
+	StartTime float64 `required:"true" error:"InvalidRequestException"`
+	EndTime   float64 `required:"true" error:"InvalidRequestException"`
+	// TraceId, Event or Service. Defaults to TraceId.
+	TimeRangeType    string `enum:"TraceId|Event|Service" error:"InvalidRequestException"`
+	FilterExpression string
+	Sampling         bool
+	NextToken        string
+}
+
+type GetTraceSummariesOutput struct {
+	ApproximateTime      float64
+	NextToken            string `json:",omitempty"`
+	TraceSummaries       []APITraceSummary
+	TracesProcessedCount int64
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_TraceSummary.html
+type APITraceSummary struct {
+	Id               string
+	StartTime        float64
+	Duration         float64
+	ResponseTime     float64
+	HasError         bool
+	HasFault         bool
+	HasThrottle      bool
+	IsPartial        bool
+	Http             APIHttp
+	Annotations      map[string][]APIValueWithServiceIds `json:",omitempty"`
+	Users            []APITraceUser                      `json:",omitempty"`
+	ServiceIds       []APIServiceId
+	EntryPoint       *APIServiceId `json:",omitempty"`
+	MatchedEventTime float64       `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_Http.html
+type APIHttp struct {
+	ClientIp   string `json:",omitempty"`
+	HttpMethod string `json:",omitempty"`
+	HttpStatus int    `json:",omitempty"`
+	HttpURL    string `json:",omitempty"`
+	UserAgent  string `json:",omitempty"`
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_ValueWithServiceIds.html
+type APIValueWithServiceIds struct {
+	AnnotationValue map[string]any
+	ServiceIds      []APIServiceId
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_TraceUser.html
+type APITraceUser struct {
+	ServiceIds []APIServiceId
+	UserName   string
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_ServiceId.html
+type APIServiceId struct {
+	Name  string   `json:",omitempty"`
+	Names []string `json:",omitempty"`
+	Type  string   `json:",omitempty"`
+}
+
+type BatchGetTracesInput struct {
+	TraceIds  []string `required:"true" length:"1,5" error:"InvalidRequestException"`
+	NextToken string
+}
+
+type BatchGetTracesOutput struct {
+	NextToken           string `json:",omitempty"`
+	Traces              []APITrace
+	UnprocessedTraceIds []string
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_Trace.html
+type APITrace struct {
+	Id            string
+	Duration      float64
+	LimitExceeded bool
+	Segments      []APISegment
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_Segment.html
+type APISegment struct {
+	Id       string
+	Document string
+}
+
+type GetSamplingRulesInput struct {
+	NextToken string
+}
+
+type GetSamplingRulesOutput struct {
+	NextToken           string `json:",omitempty"`
+	SamplingRuleRecords []APISamplingRuleRecord
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingRuleRecord.html
+type APISamplingRuleRecord struct {
+	CreatedAt    float64
+	ModifiedAt   float64
+	SamplingRule APISamplingRule
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingRule.html
+type APISamplingRule struct {
+	Attributes    map[string]string
+	FixedRate     float64
+	HTTPMethod    string
+	Host          string
+	Priority      int
+	ReservoirSize int
+	ResourceARN   string
+	RuleARN       string
+	RuleName      string
+	ServiceName   string
+	ServiceType   string
+	URLPath       string
+	Version       int
+}
+
+type GetSamplingTargetsInput struct {
+	SamplingStatisticsDocuments []APISamplingStatisticsDocument `required:"true" length:",25" error:"InvalidRequestException"`
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingStatisticsDocument.html
+type APISamplingStatisticsDocument struct {
+	BorrowCount  int64
+	ClientID     string `required:"true" length:"24,24" error:"InvalidRequestException"`
+	RequestCount int64
+	RuleName     string `required:"true" length:"1,32" error:"InvalidRequestException"`
+	SampledCount int64
+	Timestamp    float64 `required:"true" error:"InvalidRequestException"`
+}
+
+type GetSamplingTargetsOutput struct {
+	LastRuleModification    float64
+	SamplingTargetDocuments []APISamplingTargetDocument
+	UnprocessedStatistics   []APIUnprocessedStatistics
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingTargetDocument.html
+type APISamplingTargetDocument struct {
+	FixedRate         float64
+	Interval          int     `json:",omitempty"`
+	ReservoirQuota    int     `json:",omitempty"`
+	ReservoirQuotaTTL float64 `json:",omitempty"`
+	RuleName          string
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_UnprocessedStatistics.html
+type APIUnprocessedStatistics struct {
+	ErrorCode string
+	Message   string
+	RuleName  string
+}
+
+type PutTelemetryRecordsInput struct {
+	EC2InstanceId    string `length:",20" error:"InvalidRequestException"`
+	Hostname         string `length:",255" error:"InvalidRequestException"`
+	ResourceARN      string `length:",500" error:"InvalidRequestException"`
+	TelemetryRecords []any  `required:"true" error:"InvalidRequestException"`
+}
+
+type PutTelemetryRecordsOutput struct{}
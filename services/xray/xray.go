@@ -0,0 +1,226 @@
+package xray
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"aws-in-a-box/arn"
+	"aws-in-a-box/awserrors"
+	"aws-in-a-box/clock"
+	"aws-in-a-box/region"
+)
+
+const (
+	// X-Ray keeps traces for 30 days.
+	traceRetention = 30 * 24 * time.Hour
+	// The oldest traces are forgotten beyond this many, so the store doesn't grow without bound.
+	maxTraces = 10000
+	// Segment documents can be at most 64 KB.
+	maxDocumentSize = 64 * 1024
+)
+
+var (
+	traceIdRegex   = regexp.MustCompile(`^1-[0-9a-f]{8}-[0-9a-f]{24}$`)
+	segmentIdRegex = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+// segmentDocument is the part of a segment document X-Ray uses to summarize traces.
+// See https://docs.aws.amazon.com/xray/latest/devguide/xray-api-segmentdocuments.html
+type segmentDocument struct {
+	Id         string  `json:"id"`
+	TraceId    string  `json:"trace_id"`
+	ParentId   string  `json:"parent_id"`
+	Name       string  `json:"name"`
+	StartTime  float64 `json:"start_time"`
+	EndTime    float64 `json:"end_time"`
+	InProgress bool    `json:"in_progress"`
+	// "subsegment" for subsegments sent separately from their segments.
+	Type     string `json:"type"`
+	Origin   string `json:"origin"`
+	User     string `json:"user"`
+	Error    bool   `json:"error"`
+	Fault    bool   `json:"fault"`
+	Throttle bool   `json:"throttle"`
+	HTTP     *struct {
+		Request struct {
+			Method    string `json:"method"`
+			URL       string `json:"url"`
+			ClientIP  string `json:"client_ip"`
+			UserAgent string `json:"user_agent"`
+		} `json:"request"`
+		Response struct {
+			Status int `json:"status"`
+		} `json:"response"`
+	} `json:"http"`
+	Annotations map[string]any    `json:"annotations"`
+	Subsegments []segmentDocument `json:"subsegments"`
+}
+
+type Segment struct {
+	Id       string
+	Document string
+	Received time.Time
+
+	parsed segmentDocument
+}
+
+type Trace struct {
+	Id string
+	// In the order they were first received. Later versions of a segment, such as once an
+	// in-progress segment completes, replace earlier ones.
+	Segments []*Segment
+	Updated  time.Time
+}
+
+// firstReceived returns when the earliest of the trace's segments was received.
+func (t *Trace) firstReceived() time.Time {
+	var first time.Time
+	for _, segment := range t.Segments {
+		if first.IsZero() || segment.Received.Before(first) {
+			first = segment.Received
+		}
+	}
+	return first
+}
+
+type XRay struct {
+	logger       *slog.Logger
+	arnGenerator arn.Generator
+	// Overridden in tests.
+	clock func() time.Time
+
+	mu         sync.Mutex
+	tracesById map[string]*Trace
+	// Trace IDs in the order they were first received.
+	traceIds []string
+	// Shared by the instances of all regions, which each have their own traces.
+	regions *region.Regions[*XRay]
+}
+
+type Options struct {
+	Logger       *slog.Logger
+	ArnGenerator arn.Generator
+	// The regions other than ArnGenerator's which requests can be for, or region.Known if empty.
+	// Requests for others fail.
+	Regions []string
+}
+
+func New(options Options) *XRay {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	x := newRegion(options)
+	x.regions = region.New(options.ArnGenerator.Region, options.Regions, x, func(name string) (*XRay, error) {
+		regionOptions := options
+		regionOptions.Logger = options.Logger.With("region", name)
+		regionOptions.ArnGenerator.Region = name
+		r := newRegion(regionOptions)
+		r.regions = x.regions
+		return r, nil
+	})
+	return x
+}
+
+// newRegion returns the instance for the options' region.
+func newRegion(options Options) *XRay {
+	return &XRay{
+		logger:       options.Logger,
+		arnGenerator: options.ArnGenerator,
+		clock:        clock.Now,
+		tracesById:   make(map[string]*Trace),
+	}
+}
+
+func parseSegment(document string) (segmentDocument, error) {
+	var parsed segmentDocument
+	if len(document) > maxDocumentSize {
+		return parsed, fmt.Errorf("Segment document is larger than %d bytes", maxDocumentSize)
+	}
+	if err := json.Unmarshal([]byte(document), &parsed); err != nil {
+		return parsed, fmt.Errorf("Invalid segment document: %v", err)
+	}
+	switch {
+	case !segmentIdRegex.MatchString(parsed.Id):
+		return parsed, fmt.Errorf("Invalid segment ID: %q", parsed.Id)
+	case !traceIdRegex.MatchString(parsed.TraceId):
+		return parsed, fmt.Errorf("Invalid trace ID: %q", parsed.TraceId)
+	case parsed.Name == "":
+		return parsed, fmt.Errorf("Segment name is required")
+	case parsed.StartTime == 0:
+		return parsed, fmt.Errorf("Segment start_time is required")
+	case parsed.EndTime == 0 && !parsed.InProgress:
+		return parsed, fmt.Errorf("Segment end_time is required unless in_progress is true")
+	case parsed.Type == "subsegment" && parsed.ParentId == "":
+		return parsed, fmt.Errorf("Subsegment parent_id is required")
+	}
+	return parsed, nil
+}
+
+// lockedExpireTraces forgets traces which haven't been updated within the retention period, and the
+// oldest traces beyond the maximum number.
+func (x *XRay) lockedExpireTraces() {
+	cutoff := x.clock().Add(-traceRetention)
+	kept := x.traceIds[:0]
+	for i, id := range x.traceIds {
+		trace := x.tracesById[id]
+		if trace.Updated.Before(cutoff) || len(x.traceIds)-i > maxTraces {
+			delete(x.tracesById, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	x.traceIds = kept
+}
+
+// https://docs.aws.amazon.com/xray/latest/api/API_PutTraceSegments.html
+func (x *XRay) PutTraceSegments(input PutTraceSegmentsInput) (*PutTraceSegmentsOutput, *awserrors.Error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	output := &PutTraceSegmentsOutput{
+		UnprocessedTraceSegments: []APIUnprocessedTraceSegment{},
+	}
+	now := x.clock()
+	for _, document := range input.TraceSegmentDocuments {
+		parsed, err := parseSegment(document)
+		if err != nil {
+			output.UnprocessedTraceSegments = append(output.UnprocessedTraceSegments, APIUnprocessedTraceSegment{
+				ErrorCode: "InvalidSegment",
+				Id:        parsed.Id,
+				Message:   err.Error(),
+			})
+			continue
+		}
+
+		trace, ok := x.tracesById[parsed.TraceId]
+		if !ok {
+			trace = &Trace{Id: parsed.TraceId}
+			x.tracesById[trace.Id] = trace
+			x.traceIds = append(x.traceIds, trace.Id)
+		}
+		trace.Updated = now
+		segment := &Segment{
+			Id:       parsed.Id,
+			Document: document,
+			Received: now,
+			parsed:   parsed,
+		}
+		replaced := false
+		for i, existing := range trace.Segments {
+			if existing.Id == segment.Id {
+				trace.Segments[i] = segment
+				replaced = true
+			}
+		}
+		if !replaced {
+			trace.Segments = append(trace.Segments, segment)
+		}
+	}
+	x.lockedExpireTraces()
+	return output, nil
+}
@@ -0,0 +1,311 @@
+package xray
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aws-in-a-box/admin"
+	"aws-in-a-box/arn"
+)
+
+const (
+	// 1700000000 in hex.
+	traceEpoch = "6553f100"
+	now        = 1700000000
+)
+
+func newXRay(t *testing.T) *XRay {
+	x := New(Options{ArnGenerator: arn.Generator{AwsAccountId: "123456789012", Region: "us-east-1"}})
+	x.clock = func() time.Time {
+		return time.Unix(now, 0)
+	}
+	return x
+}
+
+func traceId(n int) string {
+	return fmt.Sprintf("1-%s-%024x", traceEpoch, n)
+}
+
+func segment(t *testing.T, fields map[string]any) string {
+	document := map[string]any{
+		"name":       "api",
+		"start_time": float64(now),
+		"end_time":   float64(now) + 0.5,
+	}
+	for key, value := range fields {
+		if value == nil {
+			delete(document, key)
+		} else {
+			document[key] = value
+		}
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func putSegments(t *testing.T, x *XRay, documents ...string) {
+	output, awserr := x.PutTraceSegments(PutTraceSegmentsInput{TraceSegmentDocuments: documents})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.UnprocessedTraceSegments) > 0 {
+		t.Fatalf("Unprocessed segments: %+v", output.UnprocessedTraceSegments)
+	}
+}
+
+func TestPutTraceSegmentsValidation(t *testing.T) {
+	x := newXRay(t)
+
+	output, awserr := x.PutTraceSegments(PutTraceSegmentsInput{TraceSegmentDocuments: []string{
+		"not json",
+		segment(t, map[string]any{"id": "0000000000000001", "trace_id": "bad"}),
+		segment(t, map[string]any{"id": "0000000000000002", "trace_id": traceId(1), "end_time": nil}),
+		segment(t, map[string]any{"id": "0000000000000003", "trace_id": traceId(1), "type": "subsegment"}),
+		segment(t, map[string]any{"id": "0000000000000004", "trace_id": traceId(1)}),
+	}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.UnprocessedTraceSegments) != 4 {
+		t.Fatalf("Unexpected unprocessed segments: %+v", output.UnprocessedTraceSegments)
+	}
+	if output.UnprocessedTraceSegments[1].Id != "0000000000000001" {
+		t.Errorf("Unexpected unprocessed segment: %+v", output.UnprocessedTraceSegments[1])
+	}
+	if len(x.Traces()) != 1 {
+		t.Errorf("Expected one trace, got %d", len(x.Traces()))
+	}
+}
+
+func TestTraceSummaries(t *testing.T) {
+	x := newXRay(t)
+
+	putSegments(t, x,
+		segment(t, map[string]any{
+			"id":       "0000000000000001",
+			"trace_id": traceId(1),
+			"origin":   "AWS::Lambda::Function",
+			"user":     "alice",
+			"http": map[string]any{
+				"request":  map[string]any{"method": "GET", "url": "https://example.com/orders"},
+				"response": map[string]any{"status": 500},
+			},
+			"annotations": map[string]any{"tenant": "acme"},
+			"subsegments": []any{map[string]any{
+				"id": "0000000000000002", "name": "dynamodb", "start_time": float64(now), "end_time": float64(now) + 0.2, "fault": true,
+			}},
+		}),
+		// An independent subsegment from a downstream service.
+		segment(t, map[string]any{
+			"id": "0000000000000003", "trace_id": traceId(1), "parent_id": "0000000000000001",
+			"name": "worker", "start_time": float64(now) + 0.1, "end_time": float64(now) + 2,
+		}),
+		segment(t, map[string]any{"id": "0000000000000004", "trace_id": traceId(2), "name": "other"}),
+	)
+
+	output, awserr := x.GetTraceSummaries(GetTraceSummariesInput{StartTime: now - 60, EndTime: now + 60})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.TraceSummaries) != 2 || output.TracesProcessedCount != 2 {
+		t.Fatalf("Unexpected summaries: %+v", output)
+	}
+	if usage := x.MemoryUsage(); len(usage) != 1 || usage[0].Count != 2 || usage[0].Bytes == 0 {
+		t.Fatalf("Unexpected memory usage %+v", usage)
+	}
+	// The most recent trace comes first.
+	summary := output.TraceSummaries[1]
+	if summary.Id != traceId(1) || summary.Duration != 2 || summary.ResponseTime != 0.5 ||
+		!summary.HasFault || summary.HasError || summary.Http.HttpStatus != 500 ||
+		len(summary.ServiceIds) != 2 || summary.EntryPoint.Name != "api" || summary.Users[0].UserName != "alice" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	for _, test := range []struct {
+		expression string
+		want       []string
+	}{
+		{"fault", []string{traceId(1)}},
+		{"ok", []string{traceId(2)}},
+		{`service("other")`, []string{traceId(2)}},
+		{`http.status >= 500 AND annotation.tenant = "acme"`, []string{traceId(1)}},
+		{`http.url CONTAINS "orders" OR service("other")`, []string{traceId(2), traceId(1)}},
+		{`NOT (duration > 1)`, []string{traceId(2)}},
+		{`user = "bob"`, nil},
+	} {
+		output, awserr := x.GetTraceSummaries(GetTraceSummariesInput{
+			StartTime:        now - 60,
+			EndTime:          now + 60,
+			FilterExpression: test.expression,
+		})
+		if awserr != nil {
+			t.Fatalf("%s: %v", test.expression, awserr)
+		}
+		var got []string
+		for _, summary := range output.TraceSummaries {
+			got = append(got, summary.Id)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got %v, want %v", test.expression, got, test.want)
+		}
+	}
+
+	for _, expression := range []string{`service(`, `bogus.key = 1`, `duration >`, `"quoted"`} {
+		_, awserr := x.GetTraceSummaries(GetTraceSummariesInput{StartTime: now - 60, EndTime: now + 60, FilterExpression: expression})
+		if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+			t.Errorf("%s: expected InvalidRequestException, got %v", expression, awserr)
+		}
+	}
+
+	output, awserr = x.GetTraceSummaries(GetTraceSummariesInput{StartTime: now + 60, EndTime: now + 120})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.TraceSummaries) != 0 {
+		t.Errorf("Expected no summaries outside the time range, got %+v", output.TraceSummaries)
+	}
+}
+
+func TestBatchGetTraces(t *testing.T) {
+	x := newXRay(t)
+
+	inProgress := segment(t, map[string]any{"id": "0000000000000001", "trace_id": traceId(1), "end_time": nil, "in_progress": true})
+	putSegments(t, x, inProgress)
+	complete := segment(t, map[string]any{"id": "0000000000000001", "trace_id": traceId(1)})
+	putSegments(t, x, complete)
+
+	output, awserr := x.BatchGetTraces(BatchGetTracesInput{TraceIds: []string{traceId(1), traceId(2)}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Traces) != 1 || len(output.UnprocessedTraceIds) != 1 || output.UnprocessedTraceIds[0] != traceId(2) {
+		t.Fatalf("Unexpected output: %+v", output)
+	}
+	// The completed segment replaced the in-progress one.
+	trace := output.Traces[0]
+	if trace.Duration != 0.5 || len(trace.Segments) != 1 || trace.Segments[0].Document != complete {
+		t.Errorf("Unexpected trace: %+v", trace)
+	}
+
+	_, awserr = x.BatchGetTraces(BatchGetTracesInput{TraceIds: []string{"1", "2", "3", "4", "5", "6"}})
+	if awserr == nil || awserr.Body.Type != "InvalidRequestException" {
+		t.Errorf("Expected InvalidRequestException, got %v", awserr)
+	}
+
+	// Traces expire after 30 days.
+	x.clock = func() time.Time {
+		return time.Unix(now, 0).Add(31 * 24 * time.Hour)
+	}
+	output, awserr = x.BatchGetTraces(BatchGetTracesInput{TraceIds: []string{traceId(1)}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Traces) != 0 {
+		t.Errorf("Expected the trace to have expired, got %+v", output.Traces)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	x := newXRay(t)
+
+	rules, awserr := x.GetSamplingRules(GetSamplingRulesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	rule := rules.SamplingRuleRecords[0].SamplingRule
+	if rule.RuleName != "Default" || rule.FixedRate != 1 || rule.RuleARN != "arn:aws:xray:us-east-1:123456789012:sampling-rule/Default" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	targets, awserr := x.GetSamplingTargets(GetSamplingTargetsInput{
+		SamplingStatisticsDocuments: []APISamplingStatisticsDocument{{RuleName: "Default"}, {RuleName: "Other"}},
+	})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(targets.SamplingTargetDocuments) != 1 || len(targets.UnprocessedStatistics) != 1 {
+		t.Errorf("Unexpected targets: %+v", targets)
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	x := newXRay(t)
+	registry := make(admin.Registry)
+	x.RegisterAdminHandlers(registry)
+	handler := admin.NewHandler(registry)
+
+	putSegments(t, x,
+		segment(t, map[string]any{"id": "0000000000000001", "trace_id": traceId(1)}),
+		segment(t, map[string]any{"id": "0000000000000002", "trace_id": traceId(2), "name": "other"}),
+	)
+
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/_admin/xray/traces?service=other", nil))
+	var listed struct {
+		Traces []struct {
+			Id       string
+			Segments []map[string]any
+		}
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Traces) != 1 || listed.Traces[0].Id != traceId(2) || listed.Traces[0].Segments[0]["name"] != "other" {
+		t.Fatalf("Unexpected traces: %s", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodDelete, "/_admin/xray/traces", nil))
+	if response.Code != http.StatusNoContent || len(x.Traces()) != 0 {
+		t.Fatal("Expected the traces to be cleared", response.Code)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	x := newXRay(t)
+	if _, err := x.regions.Get("xx-nowhere-1"); err == nil {
+		t.Fatal("Expected an unknown region to fail")
+	}
+	europe, err := x.regions.Get("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	europe.clock = func() time.Time {
+		return time.Unix(now+1, 0)
+	}
+
+	putSegments(t, europe, segment(t, map[string]any{"id": "0000000000000002", "trace_id": traceId(2)}))
+	putSegments(t, x, segment(t, map[string]any{"id": "0000000000000001", "trace_id": traceId(1)}))
+	output, awserr := x.BatchGetTraces(BatchGetTracesInput{TraceIds: []string{traceId(1), traceId(2)}})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if len(output.Traces) != 1 || output.Traces[0].Id != traceId(1) {
+		t.Fatalf("Expected another region's trace to be hidden: %+v", output)
+	}
+
+	rules, awserr := europe.GetSamplingRules(GetSamplingRulesInput{})
+	if awserr != nil {
+		t.Fatal(awserr)
+	}
+	if rules.SamplingRuleRecords[0].SamplingRule.RuleARN != "arn:aws:xray:eu-west-1:123456789012:sampling-rule/Default" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	// The admin API sees every region's traces, in the order they were received.
+	traces := x.Traces()
+	if len(traces) != 2 || traces[0].Id != traceId(1) || traces[1].Id != traceId(2) {
+		t.Fatalf("Unexpected traces: %+v", traces)
+	}
+	x.ClearTraces()
+	if len(europe.Traces()) != 0 {
+		t.Fatal("Expected every region's traces to be cleared")
+	}
+}
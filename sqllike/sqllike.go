@@ -0,0 +1,27 @@
+// Package sqllike matches strings against SQL LIKE patterns, which Glue's partition expressions and
+// Athena's queries use.
+package sqllike
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Regexp converts a LIKE pattern, where % matches any characters, including newlines, and _
+// matches one, to a regular expression.
+func Regexp(pattern string) *regexp.Regexp {
+	var regex strings.Builder
+	regex.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			regex.WriteString(".*")
+		case '_':
+			regex.WriteString(".")
+		default:
+			regex.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	regex.WriteString("$")
+	return regexp.MustCompile(regex.String())
+}
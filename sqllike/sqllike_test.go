@@ -0,0 +1,25 @@
+package sqllike
+
+import (
+	"testing"
+)
+
+func TestRegexp(t *testing.T) {
+	for _, test := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"2024-%", "2024-01-02", true},
+		{"2024-%", "2023-01-02", false},
+		{"a_c", "abc", true},
+		{"a_c", "abbc", false},
+		{"%.csv", "data.csv", true},
+		{"%.csv", "dataxcsv", false},
+		{"a%", "a\nb", true},
+		{"(a)", "(a)", true},
+	} {
+		if got := Regexp(test.pattern).MatchString(test.s); got != test.want {
+			t.Errorf("%q LIKE %q = %v, want %v", test.s, test.pattern, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"net/http"
+
+	"aws-in-a-box/admin"
+)
+
+// RegisterAdminHandlers adds exporting and importing to the admin API.
+// GET state returns an archive of the services' state, and PUT state imports one. GET snapshot returns
+// the same archive, but writes it to a temporary file before sending it, so it can report errors
+// and has a Content-Length. Both pause every service at once while their state is copied, so the
+// archive is consistent across services, and then send it while the services serve requests.
+func RegisterAdminHandlers(adminRegistry admin.Registry, registry Registry, version string) {
+	adminRegistry["state"] = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if err := CheckExportable(registry); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", `attachment; filename="aws-in-a-box-state.tar.gz"`)
+			// Once the archive has started, errors can't change the status, but they truncate it so
+			// it fails to import.
+			Export(w, registry, version)
+		case http.MethodPut, http.MethodPost:
+			imported, skipped, err := Import(r.Body, registry)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			admin.WriteJSON(w, struct {
+				Imported []string
+				Skipped  []string
+			}{imported, skipped})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+	adminRegistry["snapshot"] = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		serveSnapshot(w, registry, version)
+	}
+}
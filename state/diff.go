@@ -0,0 +1,137 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ResourcesFunc lists the resources in a service's entries, keyed by names such as
+// "object/bucket/key". Two resources with the same name are equal if their values marshal to the
+// same JSON.
+type ResourcesFunc func(r *Reader) (map[string]any, error)
+
+// ChangeKind is how a resource differs between two archives.
+type ChangeKind string
+
+const (
+	Created  ChangeKind = "created"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+)
+
+// Change is a resource which differs between two archives.
+type Change struct {
+	Service  string
+	Resource string
+	Kind     ChangeKind
+}
+
+// resources returns the resources of the service in the archive, marshaled to JSON. Services
+// without a ResourcesFunc have their entries compared instead.
+func resources(files map[string]entry, service string, resourcesFunc ResourcesFunc) (map[string][]byte, error) {
+	marshaled := make(map[string][]byte)
+	if resourcesFunc == nil {
+		for name, e := range files {
+			if strings.HasPrefix(name, service+"/") {
+				data, err := e.read()
+				if err != nil {
+					return nil, err
+				}
+				marshaled["entry/"+strings.TrimPrefix(name, service+"/")] = data
+			}
+		}
+		return marshaled, nil
+	}
+
+	listed, err := resourcesFunc(&Reader{files: files, dir: service})
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range listed {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path.Join(service, name), err)
+		}
+		marshaled[name] = data
+	}
+	return marshaled, nil
+}
+
+// Diff reads two tar.gz archives written by Export, and returns the resources which were created,
+// modified or deleted in after compared to before, sorted by service and resource. A service which
+// is only in one of the archives has all its resources created or deleted.
+func Diff(before, after io.Reader, resourcesFuncs map[string]ResourcesFunc) ([]Change, error) {
+	dir, err := os.MkdirTemp("", "aws-in-a-box-diff-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "before"), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(filepath.Join(dir, "after"), 0700); err != nil {
+		return nil, err
+	}
+	beforeManifest, beforeFiles, err := readArchive(before, filepath.Join(dir, "before"))
+	if err != nil {
+		return nil, fmt.Errorf("before: %w", err)
+	}
+	afterManifest, afterFiles, err := readArchive(after, filepath.Join(dir, "after"))
+	if err != nil {
+		return nil, fmt.Errorf("after: %w", err)
+	}
+
+	services := append(slices.Clone(beforeManifest.Services), afterManifest.Services...)
+	slices.Sort(services)
+	services = slices.Compact(services)
+
+	var changes []Change
+	for _, service := range services {
+		// Services' documents are only comparable if they're the same version.
+		if slices.Contains(beforeManifest.Services, service) && slices.Contains(afterManifest.Services, service) &&
+			beforeManifest.serviceVersion(service) != afterManifest.serviceVersion(service) {
+			return nil, fmt.Errorf("%s state is version %d before and %d after; load both archives into the same emulator and dump them again to compare them",
+				service, beforeManifest.serviceVersion(service), afterManifest.serviceVersion(service))
+		}
+		beforeResources := make(map[string][]byte)
+		if slices.Contains(beforeManifest.Services, service) {
+			beforeResources, err = resources(beforeFiles, service, resourcesFuncs[service])
+			if err != nil {
+				return nil, fmt.Errorf("before: %s: %w", service, err)
+			}
+		}
+		afterResources := make(map[string][]byte)
+		if slices.Contains(afterManifest.Services, service) {
+			afterResources, err = resources(afterFiles, service, resourcesFuncs[service])
+			if err != nil {
+				return nil, fmt.Errorf("after: %s: %w", service, err)
+			}
+		}
+
+		var serviceChanges []Change
+		for name, data := range afterResources {
+			if beforeData, ok := beforeResources[name]; !ok {
+				serviceChanges = append(serviceChanges, Change{service, name, Created})
+			} else if !bytes.Equal(beforeData, data) {
+				serviceChanges = append(serviceChanges, Change{service, name, Modified})
+			}
+		}
+		for name := range beforeResources {
+			if _, ok := afterResources[name]; !ok {
+				serviceChanges = append(serviceChanges, Change{service, name, Deleted})
+			}
+		}
+		slices.SortFunc(serviceChanges, func(a, b Change) int {
+			return strings.Compare(a.Resource, b.Resource)
+		})
+		changes = append(changes, serviceChanges...)
+	}
+	return changes, nil
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func export(t *testing.T, registry Registry) *bytes.Reader {
+	var buf bytes.Buffer
+	if err := Export(&buf, registry, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func fakeResources(r *Reader) (map[string]any, error) {
+	var values map[string]string
+	if err := r.ReadJSON("values.json", &values); err != nil {
+		return nil, err
+	}
+	resources := make(map[string]any)
+	for k, v := range values {
+		resources["value/"+k] = v
+	}
+	return resources, nil
+}
+
+func TestDiff(t *testing.T) {
+	before := export(t, Registry{
+		"fake":    &fakeService{Values: map[string]string{"same": "1", "modified": "2", "deleted": "3"}},
+		"entries": &fakeService{Values: map[string]string{"a": "1"}, Blob: []byte("data")},
+		"deleted": &fakeService{Values: map[string]string{"a": "1"}},
+	})
+	after := export(t, Registry{
+		"fake":    &fakeService{Values: map[string]string{"same": "1", "modified": "4", "created": "5"}},
+		"entries": &fakeService{Values: map[string]string{"a": "1"}, Blob: []byte("other data")},
+		"created": &fakeService{Values: map[string]string{"a": "1"}},
+	})
+
+	changes, err := Diff(before, after, map[string]ResourcesFunc{
+		"fake":    fakeResources,
+		"created": fakeResources,
+		"deleted": fakeResources,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{"created", "value/a", Created},
+		{"deleted", "value/a", Deleted},
+		// Services without a ResourcesFunc have their entries compared.
+		{"entries", "entry/blob", Modified},
+		{"fake", "value/created", Created},
+		{"fake", "value/deleted", Deleted},
+		{"fake", "value/modified", Modified},
+	}
+	if !slices.Equal(changes, expected) {
+		t.Fatalf("Expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	registry := Registry{"fake": &fakeService{Values: map[string]string{"a": "1"}}}
+	changes, err := Diff(export(t, registry), export(t, registry), map[string]ResourcesFunc{"fake": fakeResources})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,151 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"aws-in-a-box/atomicfile"
+)
+
+// Versioned is implemented by services whose documents have changed since they were first
+// exported, so state saved by an earlier version of the emulator is upgraded before it's imported
+// rather than misread. Services which don't implement it write version 1 of their documents.
+type Versioned interface {
+	// StateVersion is the version of the documents ExportState writes.
+	StateVersion() int
+	// StateMigrations upgrade the documents of earlier versions, in order: the first upgrades
+	// version 1 to 2, the second 2 to 3, and so on, so there's one fewer than StateVersion.
+	StateMigrations() []Migration
+}
+
+// Migration upgrades a service's entries to the next version of its documents, in place.
+type Migration func(e *Entries) error
+
+// Entries are a service's entries while they're migrated.
+type Entries struct {
+	Reader
+}
+
+// WriteJSON replaces the entry with the value, or adds it.
+func (e *Entries) WriteJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	e.WriteFile(name, data)
+	return nil
+}
+
+// WriteFile replaces the entry's contents, or adds it.
+func (e *Entries) WriteFile(name string, data []byte) {
+	e.files[path.Join(e.dir, name)] = entry{data: data}
+}
+
+// Delete removes the entry, if it exists.
+func (e *Entries) Delete(name string) {
+	delete(e.files, path.Join(e.dir, name))
+}
+
+// serviceVersion returns the version of the documents the service writes.
+func serviceVersion(service Service) int {
+	if v, ok := service.(Versioned); ok {
+		return v.StateVersion()
+	}
+	return 1
+}
+
+// migrate upgrades the service's entries from the version they were written with to the service's
+// version. State written by a later version of the service isn't imported, since it would be
+// misread.
+func migrate(name string, service Service, from int, files map[string]entry) error {
+	to := serviceVersion(service)
+	if from > to {
+		return fmt.Errorf("%s state is version %d, which is newer than this emulator's version %d; upgrade the emulator to load it",
+			name, from, to)
+	}
+	if from == to {
+		return nil
+	}
+
+	// Only Versioned services have a version above 1.
+	migrations := service.(Versioned).StateMigrations()
+	if len(migrations) != to-1 {
+		return fmt.Errorf("%s state version %d has %d migrations, expected %d", name, to, len(migrations), to-1)
+	}
+	entries := &Entries{Reader{files: files, dir: name}}
+	for version := from; version < to; version++ {
+		if err := migrations[version-1](entries); err != nil {
+			return fmt.Errorf("migrating %s state from version %d to %d: %w", name, version, version+1, err)
+		}
+	}
+	return nil
+}
+
+// versionFile is the name of the file which records the version of the files in a service's
+// -persistDir directory.
+const versionFile = "VERSION"
+
+// DirMigration upgrades the files in a service's -persistDir directory to the next version of
+// their layout, in place.
+type DirMigration func(dir string) error
+
+// MigrateDir upgrades the files a service keeps in dir, under -persistDir, from the version they
+// were written with to version, and records the version so later emulators can tell. Like
+// StateMigrations, the first migration upgrades version 1 to 2. Directories written before versions
+// were recorded are version 1, and files written by a later version of the emulator are left as they
+// are, with an error, rather than misread.
+func MigrateDir(dir string, version int, migrations []DirMigration) error {
+	if len(migrations) != version-1 {
+		return fmt.Errorf("%s version %d has %d migrations, expected %d", dir, version, len(migrations), version-1)
+	}
+	from, recorded, err := dirVersion(dir)
+	if err != nil {
+		return err
+	}
+	if from > version {
+		return fmt.Errorf("%s is version %d, which is newer than this emulator's version %d; upgrade the emulator to use it",
+			dir, from, version)
+	}
+	if from == version && recorded {
+		return nil
+	}
+
+	for v := from; v < version; v++ {
+		if err := migrations[v-1](dir); err != nil {
+			return fmt.Errorf("migrating %s from version %d to %d: %w", dir, v, v+1, err)
+		}
+		// Recorded after each migration, so an interrupted upgrade resumes where it stopped.
+		if err := writeDirVersion(dir, v+1); err != nil {
+			return err
+		}
+	}
+	return writeDirVersion(dir, version)
+}
+
+// dirVersion returns the version recorded in dir, and false if none is.
+func dirVersion(dir string) (int, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, versionFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 1, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || version < 1 {
+		return 0, false, fmt.Errorf("%s has an invalid version %q", dir, data)
+	}
+	return version, true, nil
+}
+
+func writeDirVersion(dir string, version int) error {
+	_, err := atomicfile.Write(filepath.Join(dir, versionFile), strings.NewReader(strconv.Itoa(version)+"\n"), 0600)
+	return err
+}
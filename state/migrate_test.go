@@ -0,0 +1,171 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// versionedService renamed values.json to settings.json in version 2, and made its values
+// numbers in version 3.
+type versionedService struct {
+	Settings map[string]int
+}
+
+func (v *versionedService) StateVersion() int { return 3 }
+
+func (v *versionedService) StateMigrations() []Migration {
+	return []Migration{
+		func(e *Entries) error {
+			data, err := e.ReadFile("values.json")
+			if err != nil {
+				return err
+			}
+			e.Delete("values.json")
+			e.WriteFile("settings.json", data)
+			return nil
+		},
+		func(e *Entries) error {
+			var values map[string]string
+			if err := e.ReadJSON("settings.json", &values); err != nil {
+				return err
+			}
+			settings := make(map[string]int)
+			for k, value := range values {
+				settings[k] = len(value)
+			}
+			return e.WriteJSON("settings.json", settings)
+		},
+	}
+}
+
+func (v *versionedService) ExportState(w *Writer) error {
+	return w.WriteJSON("settings.json", v.Settings)
+}
+
+func (v *versionedService) ImportState(r *Reader) (func() error, error) {
+	var settings map[string]int
+	if err := r.ReadJSON("settings.json", &settings); err != nil {
+		return nil, err
+	}
+	return func() error {
+		v.Settings = settings
+		return nil
+	}, nil
+}
+
+func TestMigrate(t *testing.T) {
+	tests := map[string]map[string]string{
+		"format 1": {
+			"manifest.json":     `{"FormatVersion": 1, "Services": ["fake"]}`,
+			"fake/values.json":  `{"a": "xyz"}`,
+			"other/values.json": `{}`,
+		},
+		"version 2": {
+			"manifest.json":      `{"FormatVersion": 2, "Services": ["fake"], "ServiceVersions": {"fake": 2}}`,
+			"fake/settings.json": `{"a": "xyz"}`,
+		},
+		"version 3": {
+			"manifest.json":      `{"FormatVersion": 2, "Services": ["fake"], "ServiceVersions": {"fake": 3}}`,
+			"fake/settings.json": `{"a": 3}`,
+		},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := &versionedService{}
+			if _, _, err := Import(bytes.NewReader(archive(t, files)), Registry{"fake": service}); err != nil {
+				t.Fatal(err)
+			}
+			if len(service.Settings) != 1 || service.Settings["a"] != 3 {
+				t.Fatalf("Unexpected settings %v", service.Settings)
+			}
+		})
+	}
+
+	// State from a later version of the service isn't imported.
+	data := archive(t, map[string]string{
+		"manifest.json":      `{"FormatVersion": 2, "Services": ["fake"], "ServiceVersions": {"fake": 4}}`,
+		"fake/settings.json": `{}`,
+	})
+	_, _, err := Import(bytes.NewReader(data), Registry{"fake": &versionedService{}})
+	if err == nil || !strings.Contains(err.Error(), "fake state is version 4") {
+		t.Fatal("Expected a newer version error, got", err)
+	}
+
+	// A failed migration is reported.
+	data = archive(t, map[string]string{"manifest.json": `{"FormatVersion": 1, "Services": ["fake"]}`})
+	_, _, err = Import(bytes.NewReader(data), Registry{"fake": &versionedService{}})
+	if err == nil || !strings.Contains(err.Error(), "migrating fake state from version 1 to 2") {
+		t.Fatal("Expected a migration error, got", err)
+	}
+
+	// Exports record the service's version.
+	var buf bytes.Buffer
+	if err := Export(&buf, Registry{"fake": &versionedService{Settings: map[string]int{"b": 1}}}, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	manifest, _, err := readArchive(bytes.NewReader(buf.Bytes()), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.FormatVersion != FormatVersion || manifest.serviceVersion("fake") != 3 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+
+	// Archives with different versions of a service can't be compared.
+	_, err = Diff(bytes.NewReader(archive(t, tests["format 1"])), bytes.NewReader(buf.Bytes()), nil)
+	if err == nil || !strings.Contains(err.Error(), "fake state is version 1 before and 3 after") {
+		t.Fatal("Expected a version error, got", err)
+	}
+}
+
+func TestMigrateDir(t *testing.T) {
+	dir := t.TempDir()
+	readVersion := func() string {
+		data, err := os.ReadFile(filepath.Join(dir, "VERSION"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	// Directories written before versions were recorded are version 1.
+	if err := MigrateDir(dir, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := readVersion(); v != "1" {
+		t.Fatalf("Expected version 1 to be recorded, got %q", v)
+	}
+
+	var migrated []string
+	migrations := []DirMigration{
+		func(dir string) error {
+			migrated = append(migrated, "1 to 2")
+			return nil
+		},
+		func(dir string) error {
+			migrated = append(migrated, "2 to 3")
+			return nil
+		},
+	}
+	if err := MigrateDir(dir, 3, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(migrated, ",") != "1 to 2,2 to 3" || readVersion() != "3" {
+		t.Fatalf("Unexpected migrations %v to version %s", migrated, readVersion())
+	}
+	// Directories which are up to date aren't migrated again.
+	if err := MigrateDir(dir, 3, migrations); err != nil || len(migrated) != 2 {
+		t.Fatal("Expected no migrations", migrated, err)
+	}
+
+	err := MigrateDir(dir, 2, migrations[:1])
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatal("Expected a later version to be rejected", err)
+	}
+	if v := readVersion(); v != "3" {
+		t.Fatalf("Expected the version to be left as it was, got %q", v)
+	}
+}
@@ -0,0 +1,219 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Pausable is implemented by services whose changes can be paused, so a snapshot has the state of
+// every service as it was at the same moment. Requests which change several services, such as an
+// SNS delivery to an SQS queue, are then either in every service's state or in none's.
+type Pausable interface {
+	// TryPause locks the mutexes which guard the state ExportState writes, if none of them are
+	// locked, without waiting for them, and returns the function which unlocks them. ExportState
+	// takes them with Writer.Lock, which doesn't wait for them while the service is paused.
+	TryPause() (resume func(), ok bool)
+}
+
+// TryLock locks all of the mutexes if none of them are locked, without waiting, and returns the
+// function which unlocks them.
+func TryLock(mutexes ...*sync.Mutex) (unlock func(), ok bool) {
+	for i, mu := range mutexes {
+		if !mu.TryLock() {
+			for _, locked := range mutexes[:i] {
+				locked.Unlock()
+			}
+			return nil, false
+		}
+	}
+	return func() {
+		for _, mu := range mutexes {
+			mu.Unlock()
+		}
+	}, true
+}
+
+// pauseTimeout is how long pauseAll keeps trying to pause the services before it gives up.
+const pauseTimeout = 10 * time.Second
+
+// pauseAll pauses every Pausable service in the registry, and returns the function which resumes
+// them. The services are paused all at once or not at all: a request can hold one service's lock
+// while it waits for another's, so waiting for a service's locks while holding others' could
+// deadlock. If any service is busy, the others are resumed and pausing is tried again shortly after.
+func pauseAll(registry Registry, names []string) (resume func(), err error) {
+	deadline := time.Now().Add(pauseTimeout)
+	backoff := time.Millisecond
+	for {
+		if resume, ok := tryPauseAll(registry, names); ok {
+			return resume, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("the services were too busy to be paused for a snapshot")
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 100*time.Millisecond)
+	}
+}
+
+func tryPauseAll(registry Registry, names []string) (resume func(), ok bool) {
+	var resumes []func()
+	resumeAll := func() {
+		for i := len(resumes) - 1; i >= 0; i-- {
+			resumes[i]()
+		}
+	}
+	for _, name := range names {
+		service, pausable := registry[name].(Pausable)
+		if !pausable {
+			continue
+		}
+		resume, ok := service.TryPause()
+		if !ok {
+			resumeAll()
+			return nil, false
+		}
+		resumes = append(resumes, resume)
+	}
+	return resumeAll, true
+}
+
+// snapshot is a copy of the services' state, taken while they were paused, which is written to an
+// archive once they've resumed.
+type snapshot struct {
+	created time.Time
+	// In the order they were written.
+	entries []snapshotEntry
+}
+
+type snapshotEntry struct {
+	name string
+	data []byte
+	// Set instead of data for blobs, which aren't copied while the services are paused.
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// read returns the entry's contents, reading them if it's a blob.
+func (e snapshotEntry) read() ([]byte, error) {
+	if e.open == nil {
+		return e.data, nil
+	}
+	r, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != e.size {
+		return nil, fmt.Errorf("%s: read %d bytes, expected %d", e.name, len(data), e.size)
+	}
+	return data, nil
+}
+
+// takeSnapshot pauses the services in the registry, copies their state, and resumes them, so
+// they're only paused for as long as it takes to copy their state in memory. Services which aren't
+// Pausable, such as plugins', are exported while the others are paused, so their state is only
+// consistent with itself.
+func takeSnapshot(registry Registry, version string) (*snapshot, error) {
+	if err := CheckExportable(registry); err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	resume, err := pauseAll(registry, names)
+	if err != nil {
+		return nil, err
+	}
+	defer resume()
+
+	s := &snapshot{created: time.Now().UTC()}
+	root := &Writer{snapshot: s}
+	if err := root.WriteJSON(manifestName, newManifest(registry, version, s.created)); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		service := registry[name]
+		_, paused := service.(Pausable)
+		if err := service.ExportState(&Writer{snapshot: s, dir: name, paused: paused}); err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+// writeTo writes the snapshot's entries to an archive's writer.
+func (s *snapshot) writeTo(w *Writer) error {
+	for _, e := range s.entries {
+		if e.open == nil {
+			if err := w.write(e.name, e.data); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.WriteBlob(e.name, e.size, e.open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot exports the state of every service in the registry to a new temporary file in dir, and
+// returns the file positioned at its start. The caller closes and removes it.
+//
+// The services are paused together while their state is copied in memory, so the snapshot has every
+// service's state as it was at the same moment. Blobs, such as S3's objects' data, never change, so
+// they're read once the services have resumed, while the archive is written. Writing it to a local
+// file means the snapshot can then be streamed to a slow client without holding anything up.
+func Snapshot(dir string, registry Registry, version string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "aws-in-a-box-snapshot-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	if err := Export(f, registry, version); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// serveSnapshot writes a snapshot of the services' state as the response. Unlike GET state, errors
+// are reported with a 500 rather than a truncated archive, and the response has a Content-Length,
+// so clients can tell a complete snapshot from an interrupted one.
+func serveSnapshot(w http.ResponseWriter, registry Registry, version string) {
+	f, err := Snapshot(os.TempDir(), registry, version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="aws-in-a-box-state.tar.gz"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	io.Copy(w, f)
+}
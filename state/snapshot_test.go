@@ -0,0 +1,154 @@
+package state
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"aws-in-a-box/admin"
+)
+
+// exportTracker records whether any service was still exporting when the response was written.
+type exportTracker struct {
+	*httptest.ResponseRecorder
+	exporting        *bool
+	writtenExporting bool
+}
+
+func (e *exportTracker) Write(data []byte) (int, error) {
+	e.writtenExporting = e.writtenExporting || *e.exporting
+	return e.ResponseRecorder.Write(data)
+}
+
+type trackedService struct {
+	fakeService
+	exporting *bool
+}
+
+func (s *trackedService) ExportState(w *Writer) error {
+	*s.exporting = true
+	defer func() { *s.exporting = false }()
+	return s.fakeService.ExportState(w)
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	exported := &fakeService{Values: map[string]string{"a": "1"}, Blob: []byte("data")}
+	f, err := Snapshot(dir, Registry{"fake": exported}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := &fakeService{}
+	if _, _, err := Import(f, Registry{"fake": imported}); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Values["a"] != "1" || string(imported.Blob) != "data" {
+		t.Fatalf("Unexpected imported state %+v", imported)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	var exporting bool
+	adminRegistry := admin.Registry{}
+	RegisterAdminHandlers(adminRegistry, Registry{"fake": &trackedService{fakeService: *exported, exporting: &exporting}}, "v1")
+	response := &exportTracker{ResponseRecorder: httptest.NewRecorder(), exporting: &exporting}
+	adminRegistry["snapshot"](response, httptest.NewRequest(http.MethodGet, "/_admin/snapshot", nil))
+	if response.Code != http.StatusOK {
+		t.Fatal("Unexpected response", response.Code, response.Body.String())
+	}
+	if response.writtenExporting {
+		t.Fatal("Expected the snapshot to be written after the services were exported")
+	}
+	if response.Header().Get("Content-Length") != strconv.Itoa(response.Body.Len()) {
+		t.Fatalf("Content-Length %s doesn't match the body's %d bytes", response.Header().Get("Content-Length"), response.Body.Len())
+	}
+	if _, _, err := Import(bytes.NewReader(response.Body.Bytes()), Registry{"fake": &fakeService{}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pausableService records whether the other services were paused while it was exported, and
+// whether its blob was read while it was paused.
+type pausableService struct {
+	fakeService
+	mu             sync.Mutex
+	others         []*pausableService
+	othersPaused   bool
+	blobReadPaused bool
+}
+
+func (s *pausableService) TryPause() (func(), bool) {
+	return TryLock(&s.mu)
+}
+
+func (s *pausableService) ExportState(w *Writer) error {
+	unlock := w.Lock(&s.mu)
+	defer unlock()
+	s.othersPaused = true
+	for _, other := range s.others {
+		if other.mu.TryLock() {
+			other.mu.Unlock()
+			s.othersPaused = false
+		}
+	}
+	if err := w.WriteJSON("values.json", s.Values); err != nil {
+		return err
+	}
+	return w.WriteBlob("blob", int64(len(s.Blob)), func() (io.ReadCloser, error) {
+		if s.mu.TryLock() {
+			s.mu.Unlock()
+		} else {
+			s.blobReadPaused = true
+		}
+		return io.NopCloser(bytes.NewReader(s.Blob)), nil
+	})
+}
+
+func TestExportPausesServicesTogether(t *testing.T) {
+	a := &pausableService{fakeService: fakeService{Values: map[string]string{"a": "1"}, Blob: []byte("a")}}
+	b := &pausableService{fakeService: fakeService{Values: map[string]string{"b": "2"}, Blob: []byte("b")}}
+	a.others, b.others = []*pausableService{b}, []*pausableService{a}
+	var buf bytes.Buffer
+	if err := Export(&buf, Registry{"a": a, "b": b}, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*pausableService{a, b} {
+		if !s.othersPaused {
+			t.Fatal("Expected every service to be paused while each was exported")
+		}
+		if s.blobReadPaused {
+			t.Fatal("Expected blobs to be read once the services resumed")
+		}
+	}
+	imported := &fakeService{}
+	if _, _, err := Import(bytes.NewReader(buf.Bytes()), Registry{"b": imported}); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Values["b"] != "2" || string(imported.Blob) != "b" {
+		t.Fatalf("Unexpected imported state %+v", imported)
+	}
+}
+
+func TestPauseWaitsForBusyService(t *testing.T) {
+	a := &pausableService{}
+	b := &pausableService{}
+	b.mu.Lock()
+	time.AfterFunc(20*time.Millisecond, b.mu.Unlock)
+	resume, err := pauseAll(Registry{"a": a, "b": b}, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.mu.TryLock() || b.mu.TryLock() {
+		t.Fatal("Expected both services to be paused")
+	}
+	resume()
+	if !a.mu.TryLock() || !b.mu.TryLock() {
+		t.Fatal("Expected both services to be resumed")
+	}
+}
@@ -0,0 +1,444 @@
+// Package state exports the services' state to a single tar.gz archive and imports it again, so
+// environments can be shared and reproduced.
+//
+// The archive has a manifest.json, and each service's entries under a directory named after it,
+// such as s3/buckets.json. Services write their state as JSON documents, which ignore fields they
+// don't know and default ones they're missing, so archives can be loaded by later versions.
+//
+// Exporting pauses the services together while it copies their state, so an archive has every
+// service's state as it was at the same moment, and writes the archive once they've resumed.
+//
+// Importing copies an archive's entries to a temporary directory rather than into memory, and
+// has every service check its entries before any service's state is replaced, so an archive
+// which can't be imported leaves the services as they were.
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatVersion is the version of the archive layout. Archives written with a later version can't
+// be imported. Version 2 added the services' versions, which emulators that only support version 1
+// wouldn't check, so they reject archives which may have documents they'd misread.
+const FormatVersion = 2
+
+const manifestName = "manifest.json"
+
+// Manifest describes an archive.
+type Manifest struct {
+	FormatVersion int
+	// The version of the emulator which wrote the archive, for information only.
+	Version string
+	Created time.Time
+	// The services with entries in the archive, sorted.
+	Services []string
+	// The version of each service's documents, as Versioned returns it. Services which aren't in
+	// it, including every service in archives of format version 1, are at version 1.
+	ServiceVersions map[string]int `json:",omitempty"`
+}
+
+// serviceVersion returns the version of the service's documents in the archive.
+func (m *Manifest) serviceVersion(name string) int {
+	if version, ok := m.ServiceVersions[name]; ok {
+		return version
+	}
+	return 1
+}
+
+// Service is implemented by services whose state can be exported.
+type Service interface {
+	// ExportState writes the service's state to the archive.
+	ExportState(w *Writer) error
+	// ImportState reads and checks the archive's state, without changing the service's, and
+	// returns a function which replaces the service's state with it. Replacing only fails if the
+	// service can't write its new state to disk.
+	ImportState(r *Reader) (replace func() error, err error)
+}
+
+// Registry maps service names, such as "s3", to their services.
+type Registry = map[string]Service
+
+// Unsupported is registered for enabled services whose state can't be exported, such as plugins
+// which don't implement Service, so exporting fails rather than leaving their state out.
+type Unsupported struct{}
+
+var errUnsupported = errors.New("the service's state can't be exported")
+
+func (Unsupported) ExportState(w *Writer) error {
+	return errUnsupported
+}
+
+func (Unsupported) ImportState(r *Reader) (func() error, error) {
+	return nil, errUnsupported
+}
+
+// CheckExportable returns an error naming the services in the registry whose state can't be
+// exported, so callers can fail before they start writing an archive.
+func CheckExportable(registry Registry) error {
+	var unsupported []string
+	for name, service := range registry {
+		if _, ok := service.(Unsupported); ok {
+			unsupported = append(unsupported, name)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	slices.Sort(unsupported)
+	return fmt.Errorf("the state of %s can't be exported", strings.Join(unsupported, ", "))
+}
+
+// Writer writes one service's entries to an archive or a snapshot.
+type Writer struct {
+	tw      *tar.Writer
+	dir     string
+	modTime time.Time
+
+	// Set instead of tw when copying the services' state to a snapshot.
+	snapshot *snapshot
+	// Whether the service is paused for the snapshot, in which case it already holds the locks
+	// ExportState takes.
+	paused bool
+}
+
+// Dir returns a writer of the entries in the subdirectory, such as those of a region other than
+// the default.
+func (w *Writer) Dir(name string) *Writer {
+	dir := *w
+	dir.dir = path.Join(w.dir, name)
+	return &dir
+}
+
+func (w *Writer) header(name string, size int64) error {
+	return w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(w.dir, name),
+		Size:     size,
+		Mode:     0600,
+		ModTime:  w.modTime,
+	})
+}
+
+// Lock locks the mutex, which guards state ExportState writes, and returns the function which
+// unlocks it. It doesn't wait for the mutex if the service is paused for a snapshot, since the
+// pause already holds it.
+func (w *Writer) Lock(mu *sync.Mutex) (unlock func()) {
+	if w.paused {
+		return func() {}
+	}
+	mu.Lock()
+	return mu.Unlock
+}
+
+// WriteJSON writes the value as a JSON entry.
+func (w *Writer) WriteJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.write(name, data)
+}
+
+func (w *Writer) write(name string, data []byte) error {
+	if w.snapshot != nil {
+		w.snapshot.entries = append(w.snapshot.entries, snapshotEntry{name: path.Join(w.dir, name), data: data})
+		return nil
+	}
+	if err := w.header(name, int64(len(data))); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// WriteFile writes size bytes from r as an entry.
+func (w *Writer) WriteFile(name string, size int64, r io.Reader) error {
+	if w.snapshot != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != size {
+			return fmt.Errorf("%s: read %d bytes, expected %d", name, len(data), size)
+		}
+		return w.write(name, data)
+	}
+	if err := w.header(name, size); err != nil {
+		return err
+	}
+	n, err := io.Copy(w.tw, r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("%s: wrote %d bytes, expected %d", name, n, size)
+	}
+	return nil
+}
+
+// WriteBlob writes size bytes, which open reads, as an entry, like WriteFile, for entries which
+// never change once they're written, such as S3's blobs. Snapshots open them once the services have
+// resumed, rather than copying them while the services are paused.
+func (w *Writer) WriteBlob(name string, size int64, open func() (io.ReadCloser, error)) error {
+	if w.snapshot != nil {
+		w.snapshot.entries = append(w.snapshot.entries, snapshotEntry{name: path.Join(w.dir, name), size: size, open: open})
+		return nil
+	}
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return w.WriteFile(name, size, r)
+}
+
+// entry is an archive's entry, either in memory or in a file an archive was copied to.
+type entry struct {
+	data []byte
+	// Set instead of data for entries in files.
+	path string
+}
+
+func (e entry) open() (io.ReadCloser, error) {
+	if e.path != "" {
+		return os.Open(e.path)
+	}
+	return io.NopCloser(bytes.NewReader(e.data)), nil
+}
+
+func (e entry) read() ([]byte, error) {
+	if e.path != "" {
+		return os.ReadFile(e.path)
+	}
+	return e.data, nil
+}
+
+// Reader reads one service's entries from an archive.
+type Reader struct {
+	files map[string]entry
+	dir   string
+}
+
+// Dir returns a reader of the entries in the subdirectory, such as those of a region other than
+// the default.
+func (r *Reader) Dir(name string) *Reader {
+	return &Reader{files: r.files, dir: path.Join(r.dir, name)}
+}
+
+// ReadExported returns a reader of the entries export writes, which are kept in memory, such as to
+// import the state of a new instance of a service to reset it.
+func ReadExported(export func(w *Writer) error) (*Reader, error) {
+	s := &snapshot{}
+	if err := export(&Writer{snapshot: s, dir: "exported"}); err != nil {
+		return nil, err
+	}
+	files := make(map[string]entry, len(s.entries))
+	for _, e := range s.entries {
+		data, err := e.read()
+		if err != nil {
+			return nil, err
+		}
+		files[e.name] = entry{data: data}
+	}
+	return &Reader{files: files, dir: "exported"}, nil
+}
+
+// Names returns the names of the service's entries, sorted.
+func (r *Reader) Names() []string {
+	var names []string
+	for name := range r.files {
+		if rest, ok := strings.CutPrefix(name, r.dir+"/"); ok {
+			names = append(names, rest)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Open returns a reader of the entry's contents, for entries too large to read into memory, such as
+// S3's blobs. The error wraps fs.ErrNotExist if there's no such entry.
+func (r *Reader) Open(name string) (io.ReadCloser, error) {
+	e, ok := r.files[path.Join(r.dir, name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path.Join(r.dir, name), fs.ErrNotExist)
+	}
+	return e.open()
+}
+
+// ReadFile returns the entry's contents. The error wraps fs.ErrNotExist if there's no such entry.
+func (r *Reader) ReadFile(name string) ([]byte, error) {
+	e, ok := r.files[path.Join(r.dir, name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path.Join(r.dir, name), fs.ErrNotExist)
+	}
+	return e.read()
+}
+
+// ReadJSON unmarshals a JSON entry into v.
+func (r *Reader) ReadJSON(name string, v any) error {
+	data, err := r.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", path.Join(r.dir, name), err)
+	}
+	return nil
+}
+
+func newManifest(registry Registry, version string, created time.Time) Manifest {
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		Version:       version,
+		Created:       created,
+	}
+	for name, service := range registry {
+		manifest.Services = append(manifest.Services, name)
+		if version := serviceVersion(service); version != 1 {
+			if manifest.ServiceVersions == nil {
+				manifest.ServiceVersions = make(map[string]int)
+			}
+			manifest.ServiceVersions[name] = version
+		}
+	}
+	slices.Sort(manifest.Services)
+	return manifest
+}
+
+// Export writes a snapshot of the state of every service in the registry to w, as a tar.gz
+// archive. Nothing is written if any of the services' state can't be exported.
+func Export(w io.Writer, registry Registry, version string) error {
+	s, err := takeSnapshot(registry, version)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := s.writeTo(&Writer{tw: tw, modTime: s.created}); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readArchive copies an archive's entries to files in dir, one at a time, so large archives aren't
+// read into memory, and checks its manifest.
+func readArchive(r io.Reader, dir string) (*Manifest, map[string]entry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a tar.gz archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]entry)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Entries' files are numbered rather than named after them, so names can't escape dir.
+		e := entry{path: filepath.Join(dir, strconv.Itoa(len(files)))}
+		if err := copyToFile(e.path, tr); err != nil {
+			return nil, nil, err
+		}
+		files[path.Clean(strings.TrimPrefix(header.Name, "./"))] = e
+	}
+
+	manifest, err := readManifest(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, files, nil
+}
+
+func copyToFile(name string, r io.Reader) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readManifest reads and checks the manifest of an archive's entries.
+func readManifest(files map[string]entry) (*Manifest, error) {
+	var manifest Manifest
+	root := &Reader{files: files}
+	if err := root.ReadJSON(manifestName, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %d, this emulator supports up to %d",
+			manifest.FormatVersion, FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// Import reads a tar.gz archive written by Export, and replaces the state of each service in both
+// the archive and the registry with the archive's, after upgrading services' entries which were
+// written by an earlier version of the emulator. It returns the services which were imported, and
+// those in the archive which were skipped because they aren't in the registry. Services which aren't
+// in the archive are left as they are, and so is every service if any service's entries are invalid.
+func Import(r io.Reader, registry Registry) (imported []string, skipped []string, err error) {
+	dir, err := os.MkdirTemp("", "aws-in-a-box-import-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+	manifest, files, err := readArchive(r, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Every service's entries are checked before any service's state is replaced.
+	var names []string
+	var replacements []func() error
+	for _, name := range manifest.Services {
+		service, ok := registry[name]
+		if !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		if err := migrate(name, service, manifest.serviceVersion(name), files); err != nil {
+			return nil, nil, fmt.Errorf("importing %s: %w", name, err)
+		}
+		replace, err := service.ImportState(&Reader{files: files, dir: name})
+		if err != nil {
+			return nil, nil, fmt.Errorf("importing %s: %w", name, err)
+		}
+		names = append(names, name)
+		replacements = append(replacements, replace)
+	}
+	for i, replace := range replacements {
+		if err := replace(); err != nil {
+			return imported, skipped, fmt.Errorf("importing %s: %w", names[i], err)
+		}
+		imported = append(imported, names[i])
+	}
+	return imported, skipped, nil
+}
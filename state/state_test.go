@@ -0,0 +1,133 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeService struct {
+	Values map[string]string
+	Blob   []byte
+}
+
+func (f *fakeService) ExportState(w *Writer) error {
+	if err := w.WriteJSON("values.json", f.Values); err != nil {
+		return err
+	}
+	return w.WriteFile("blob", int64(len(f.Blob)), bytes.NewReader(f.Blob))
+}
+
+func (f *fakeService) ImportState(r *Reader) (func() error, error) {
+	var values map[string]string
+	if err := r.ReadJSON("values.json", &values); err != nil {
+		return nil, err
+	}
+	blob, err := r.ReadFile("blob")
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		f.Values, f.Blob = values, blob
+		return nil
+	}, nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	exported := &fakeService{Values: map[string]string{"a": "1"}, Blob: []byte("data")}
+	other := &fakeService{Values: map[string]string{"b": "2"}}
+	var buf bytes.Buffer
+	if err := Export(&buf, Registry{"fake": exported, "other": other}, "v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := &fakeService{}
+	untouched := &fakeService{Values: map[string]string{"c": "3"}}
+	done, skipped, err := Import(bytes.NewReader(buf.Bytes()), Registry{"fake": imported, "untouched": untouched})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(done, []string{"fake"}) || !slices.Equal(skipped, []string{"other"}) {
+		t.Fatalf("Unexpected imported %v and skipped %v", done, skipped)
+	}
+	if imported.Values["a"] != "1" || string(imported.Blob) != "data" {
+		t.Fatalf("Unexpected imported state %+v", imported)
+	}
+	if untouched.Values["c"] != "3" {
+		t.Fatalf("Unexpected untouched state %+v", untouched)
+	}
+}
+
+func archive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: int64(len(content)), Mode: 0600})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want string
+	}{
+		"not an archive": {[]byte("hello"), "not a tar.gz archive"},
+		"no manifest":    {archive(t, map[string]string{"fake/values.json": "{}"}), "manifest.json"},
+		"later version":  {archive(t, map[string]string{"manifest.json": `{"FormatVersion": 99}`}), "unsupported archive format version 99"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := Import(bytes.NewReader(test.data), Registry{})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("Expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+
+	// Entries a service needs but the archive doesn't have are reported as not existing.
+	data := archive(t, map[string]string{"manifest.json": `{"FormatVersion": 1, "Services": ["fake"]}`})
+	_, _, err := Import(bytes.NewReader(data), Registry{"fake": &fakeService{}})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected a not exist error, got %v", err)
+	}
+}
+
+func TestImportChecksEveryServiceFirst(t *testing.T) {
+	data := archive(t, map[string]string{
+		"manifest.json": `{"FormatVersion": 2, "Services": ["a", "b"]}`,
+		"a/values.json": `{"a": "1"}`,
+		"a/blob":        "data",
+		// b's blob is missing, so its state can't be imported.
+		"b/values.json": `{"b": "2"}`,
+	})
+	a := &fakeService{Values: map[string]string{"old": "a"}}
+	b := &fakeService{Values: map[string]string{"old": "b"}}
+	imported, _, err := Import(bytes.NewReader(data), Registry{"a": a, "b": b})
+	if err == nil || len(imported) != 0 {
+		t.Fatal("Expected the import to fail", imported, err)
+	}
+	if a.Values["old"] != "a" || b.Values["old"] != "b" {
+		t.Fatalf("Expected neither service to be imported, got %+v and %+v", a, b)
+	}
+}
+
+func TestExportUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	err := Export(&buf, Registry{"fake": &fakeService{}, "plugin": Unsupported{}}, "v1")
+	if err == nil || !strings.Contains(err.Error(), "plugin") {
+		t.Fatal("Expected the export to fail", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("Expected nothing to be written", buf.Len())
+	}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bolt is a backend in a BoltDB file, with a bucket for each table.
+type Bolt struct {
+	db *bolt.DB
+}
+
+func OpenBolt(path string) (*Bolt, error) {
+	// Another process having the file open is an error, rather than waiting for it forever.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Table(name string) (Table, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &boltTable{db: b.db, name: []byte(name)}, nil
+}
+
+func (b *Bolt) DropTable(name string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(name))
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (b *Bolt) Tables(prefix string) ([]string, error) {
+	var names []string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if bytes.HasPrefix(name, []byte(prefix)) {
+				names = append(names, string(name))
+			}
+			return nil
+		})
+	})
+	slices.Sort(names)
+	return names, err
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+type boltTable struct {
+	db   *bolt.DB
+	name []byte
+}
+
+// bucket returns the table's bucket, which DropTable may have deleted.
+func (t *boltTable) bucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	bucket := tx.Bucket(t.name)
+	if bucket == nil {
+		return nil, bolt.ErrBucketNotFound
+	}
+	return bucket, nil
+}
+
+func (t *boltTable) Get(key string) ([]byte, bool, error) {
+	var value []byte
+	err := t.db.View(func(tx *bolt.Tx) error {
+		bucket, err := t.bucket(tx)
+		if err != nil {
+			return err
+		}
+		// Values are only valid during the transaction.
+		if v := bucket.Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, value != nil, err
+}
+
+func (t *boltTable) Put(key string, value []byte) error {
+	return t.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := t.bucket(tx)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), value)
+	})
+}
+
+func (t *boltTable) Delete(key string) error {
+	return t.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := t.bucket(tx)
+		if err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+func (t *boltTable) Scan(start string, f func(key string, value []byte) bool) error {
+	return t.db.View(func(tx *bolt.Tx) error {
+		bucket, err := t.bucket(tx)
+		if err != nil {
+			return err
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek([]byte(start)); k != nil; k, v = c.Next() {
+			if !f(string(k), v) {
+				break
+			}
+		}
+		return nil
+	})
+}
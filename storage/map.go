@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Map is a map of values by key, which services use in place of a Go map for resources which are
+// kept in the backend. In Memory, its values are kept as they are, so Get returns the value which
+// was Put. Other backends encode them as JSON, so Get returns a copy, and changing a value
+// doesn't change the map until it's Put again.
+type Map[V any] struct {
+	backend Backend
+	name    string
+	// Set in Memory.
+	values map[string]V
+	// Set in other backends.
+	table Table
+	len   int
+}
+
+// OpenMap opens the map kept in the backend's table with the name, creating it if it doesn't exist.
+func OpenMap[V any](backend Backend, name string) (*Map[V], error) {
+	m := &Map[V]{backend: backend, name: name}
+	if _, ok := backend.(*Memory); ok {
+		m.values = make(map[string]V)
+		return m, nil
+	}
+	var err error
+	m.table, err = backend.Table(name)
+	if err != nil {
+		return nil, err
+	}
+	err = m.table.Scan("", func(string, []byte) bool {
+		m.len++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Name returns the name of the map's table.
+func (m *Map[V]) Name() string {
+	return m.name
+}
+
+// InMemory returns whether the map's values are kept in memory.
+func (m *Map[V]) InMemory() bool {
+	return m.values != nil
+}
+
+// Len returns the number of keys with values.
+func (m *Map[V]) Len() int {
+	if m.values != nil {
+		return len(m.values)
+	}
+	return m.len
+}
+
+// Get returns the key's value, and whether it has one.
+func (m *Map[V]) Get(key string) (V, bool, error) {
+	var value V
+	if m.values != nil {
+		value, ok := m.values[key]
+		return value, ok, nil
+	}
+	data, ok, err := m.table.Get(key)
+	if err != nil || !ok {
+		return value, false, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false, fmt.Errorf("%s %q: %w", m.name, key, err)
+	}
+	return value, true, nil
+}
+
+// Put sets the key's value, replacing any it had.
+func (m *Map[V]) Put(key string, value V) error {
+	if m.values != nil {
+		m.values[key] = value
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, exists, err := m.table.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := m.table.Put(key, data); err != nil {
+		return err
+	}
+	if !exists {
+		m.len++
+	}
+	return nil
+}
+
+// Delete removes the key's value. Keys without one are ignored.
+func (m *Map[V]) Delete(key string) error {
+	if m.values != nil {
+		delete(m.values, key)
+		return nil
+	}
+	_, exists, err := m.table.Get(key)
+	if err != nil || !exists {
+		return err
+	}
+	if err := m.table.Delete(key); err != nil {
+		return err
+	}
+	m.len--
+	return nil
+}
+
+// Scan calls f with each key which isn't before start, and its value, in key order, until f
+// returns false. f mustn't change the map.
+func (m *Map[V]) Scan(start string, f func(key string, value V) bool) error {
+	if m.values != nil {
+		for _, key := range sortedKeys(m.values, start) {
+			if !f(key, m.values[key]) {
+				break
+			}
+		}
+		return nil
+	}
+	var err error
+	scanErr := m.table.Scan(start, func(key string, data []byte) bool {
+		var value V
+		if err = json.Unmarshal(data, &value); err != nil {
+			err = fmt.Errorf("%s %q: %w", m.name, key, err)
+			return false
+		}
+		return f(key, value)
+	})
+	if scanErr != nil {
+		return scanErr
+	}
+	return err
+}
+
+// Drop deletes the map and its values from the backend.
+func (m *Map[V]) Drop() error {
+	if m.values != nil {
+		clear(m.values)
+		return nil
+	}
+	m.len = 0
+	return m.backend.DropTable(m.name)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Memory is a backend which only lasts as long as the process. Maps opened in it keep their values
+// as they are, rather than encoding them.
+type Memory struct {
+	mu     sync.Mutex
+	tables map[string]*memoryTable
+}
+
+func NewMemory() *Memory {
+	return &Memory{tables: make(map[string]*memoryTable)}
+}
+
+func (m *Memory) Table(name string) (Table, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tables[name]
+	if !ok {
+		t = &memoryTable{entries: make(map[string][]byte)}
+		m.tables[name] = t
+	}
+	return t, nil
+}
+
+func (m *Memory) DropTable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tables, name)
+	return nil
+}
+
+func (m *Memory) Tables(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name := range m.tables {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+type memoryTable struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (t *memoryTable) Get(key string) ([]byte, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	value, ok := t.entries[key]
+	return value, ok, nil
+}
+
+func (t *memoryTable) Put(key string, value []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[key] = value
+	return nil
+}
+
+func (t *memoryTable) Delete(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+	return nil
+}
+
+func (t *memoryTable) Scan(start string, f func(key string, value []byte) bool) error {
+	t.mu.Lock()
+	keys := sortedKeys(t.entries, start)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = t.entries[key]
+	}
+	t.mu.Unlock()
+
+	for i, key := range keys {
+		if !f(key, values[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map which aren't before start, sorted.
+func sortedKeys[V any](m map[string]V, start string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if key >= start {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
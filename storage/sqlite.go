@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"database/sql"
+
+	// The pure Go driver, so the emulator can still be built without cgo.
+	_ "modernc.org/sqlite"
+)
+
+// SQLite is a backend in an SQLite database, with every table's entries in one SQL table.
+type SQLite struct {
+	db *sql.DB
+}
+
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only allows one writer at a time anyway.
+	db.SetMaxOpenConns(1)
+	// Keys are blobs, which are compared byte by byte, like Go compares strings, and can hold any
+	// bytes, such as DynamoDB's binary keys.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS entries (
+		tbl TEXT NOT NULL,
+		key BLOB NOT NULL,
+		value BLOB NOT NULL,
+		PRIMARY KEY (tbl, key)
+	) WITHOUT ROWID`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Table doesn't need to create anything, since tables without entries don't have any rows.
+func (s *SQLite) Table(name string) (Table, error) {
+	return &sqliteTable{db: s.db, name: name}, nil
+}
+
+func (s *SQLite) DropTable(name string) error {
+	_, err := s.db.Exec(`DELETE FROM entries WHERE tbl = ?`, name)
+	return err
+}
+
+// Tables only returns the tables which have entries.
+func (s *SQLite) Tables(prefix string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT tbl FROM entries WHERE substr(tbl, 1, length(?)) = ? ORDER BY tbl`,
+		prefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+type sqliteTable struct {
+	db   *sql.DB
+	name string
+}
+
+func (t *sqliteTable) Get(key string) ([]byte, bool, error) {
+	var value []byte
+	err := t.db.QueryRow(`SELECT value FROM entries WHERE tbl = ? AND key = ?`, t.name, []byte(key)).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	return value, err == nil, err
+}
+
+func (t *sqliteTable) Put(key string, value []byte) error {
+	_, err := t.db.Exec(`INSERT INTO entries (tbl, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (tbl, key) DO UPDATE SET value = excluded.value`, t.name, []byte(key), value)
+	return err
+}
+
+func (t *sqliteTable) Delete(key string) error {
+	_, err := t.db.Exec(`DELETE FROM entries WHERE tbl = ? AND key = ?`, t.name, []byte(key))
+	return err
+}
+
+func (t *sqliteTable) Scan(start string, f func(key string, value []byte) bool) error {
+	rows, err := t.db.Query(`SELECT key, value FROM entries WHERE tbl = ? AND key >= ? ORDER BY key`, t.name, []byte(start))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		if !f(string(key), value) {
+			break
+		}
+	}
+	return rows.Err()
+}
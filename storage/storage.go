@@ -0,0 +1,54 @@
+// Package storage has the backends services keep the resources there can be too many of to hold in
+// memory in: S3's objects and DynamoDB's items. Memory keeps them in maps, like the rest of the
+// services' state, which is the fastest. Bolt and sqlite keep them in a file, encoded as JSON, so
+// they can be larger than memory and last across restarts, but each read and write goes to disk.
+package storage
+
+import (
+	"fmt"
+)
+
+// Kinds are the names of the backends, as -storage takes them.
+var Kinds = []string{"memory", "bolt", "sqlite"}
+
+// Backend keeps tables of values by key.
+type Backend interface {
+	// Table returns the table with the name, creating it if it doesn't exist.
+	Table(name string) (Table, error)
+	// DropTable deletes the table and its entries. Tables which don't exist are ignored.
+	DropTable(name string) error
+	// Tables returns the names of the tables which start with the prefix, sorted.
+	Tables(prefix string) ([]string, error)
+	Close() error
+}
+
+// Table is a table of values by key, kept in key order.
+type Table interface {
+	// Get returns the key's value, and whether it has one.
+	Get(key string) ([]byte, bool, error)
+	// Put sets the key's value, replacing any it had.
+	Put(key string, value []byte) error
+	// Delete removes the key's value. Keys without one are ignored.
+	Delete(key string) error
+	// Scan calls f with each entry whose key isn't before start, in key order, until f returns false.
+	// The value is only valid until f returns, and f mustn't use the backend.
+	Scan(start string, f func(key string, value []byte) bool) error
+}
+
+// Open opens the kind of backend at path, creating it if it doesn't exist. Memory backends don't
+// have a path.
+func Open(kind string, path string) (Backend, error) {
+	if kind != "memory" && path == "" {
+		return nil, fmt.Errorf("%s storage needs a path", kind)
+	}
+	switch kind {
+	case "memory":
+		return NewMemory(), nil
+	case "bolt":
+		return OpenBolt(path)
+	case "sqlite":
+		return OpenSQLite(path)
+	default:
+		return nil, fmt.Errorf("unknown storage %q, expected one of %v", kind, Kinds)
+	}
+}
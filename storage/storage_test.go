@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type value struct {
+	Name string
+}
+
+// scan returns the keys of the map from start, and the names of their values.
+func scan(t *testing.T, m *Map[*value], start string) []string {
+	var scanned []string
+	err := m.Scan(start, func(key string, v *value) bool {
+		scanned = append(scanned, key+"="+v.Name)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scanned
+}
+
+func TestBackends(t *testing.T) {
+	for _, kind := range Kinds {
+		t.Run(kind, func(t *testing.T) {
+			path := ""
+			if kind != "memory" {
+				path = filepath.Join(t.TempDir(), "storage")
+			}
+			backend, err := Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { backend.Close() }()
+
+			m, err := OpenMap[*value](backend, "service/things")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"b", "a", "c\x00binary", "c"} {
+				if err := m.Put(key, &value{Name: key}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Replacing a value doesn't add a key.
+			if err := m.Put("a", &value{Name: "replaced"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Delete("b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Delete("missing"); err != nil {
+				t.Fatal(err)
+			}
+			if m.Len() != 3 {
+				t.Fatalf("got %d keys, want 3", m.Len())
+			}
+			if v, ok, err := m.Get("a"); err != nil || !ok || v.Name != "replaced" {
+				t.Fatal("Unexpected value", v, ok, err)
+			}
+			if _, ok, err := m.Get("b"); err != nil || ok {
+				t.Fatal("Expected the deleted key to have no value", ok, err)
+			}
+			if got, want := scan(t, m, ""), []string{"a=replaced", "c=c", "c\x00binary=c\x00binary"}; !slices.Equal(got, want) {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+			if got, want := scan(t, m, "b"), []string{"c=c", "c\x00binary=c\x00binary"}; !slices.Equal(got, want) {
+				t.Fatalf("got %q from b, want %q", got, want)
+			}
+
+			other, err := OpenMap[*value](backend, "service/others")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := other.Put("x", &value{Name: "x"}); err != nil {
+				t.Fatal(err)
+			}
+			if kind != "memory" {
+				if tables, err := backend.Tables("service/"); err != nil || !slices.Equal(tables, []string{"service/others", "service/things"}) {
+					t.Fatal("Unexpected tables", tables, err)
+				}
+				// The maps are kept across restarts.
+				if err := backend.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if backend, err = Open(kind, path); err != nil {
+					t.Fatal(err)
+				}
+				if m, err = OpenMap[*value](backend, "service/things"); err != nil {
+					t.Fatal(err)
+				}
+				if m.Len() != 3 {
+					t.Fatalf("got %d keys after reopening, want 3", m.Len())
+				}
+				if got, want := scan(t, m, ""), []string{"a=replaced", "c=c", "c\x00binary=c\x00binary"}; !slices.Equal(got, want) {
+					t.Fatalf("got %q after reopening, want %q", got, want)
+				}
+			}
+
+			if err := m.Drop(); err != nil {
+				t.Fatal(err)
+			}
+			if m, err = OpenMap[*value](backend, "service/things"); err != nil {
+				t.Fatal(err)
+			}
+			if m.Len() != 0 || len(scan(t, m, "")) != 0 {
+				t.Fatal("Expected a dropped map to be empty")
+			}
+			if kind != "memory" {
+				if tables, err := backend.Tables("service/"); err != nil || !slices.Contains(tables, "service/others") {
+					t.Fatal("Expected other maps to be kept", tables, err)
+				}
+			}
+		})
+	}
+
+	if _, err := Open("bolt", ""); err == nil {
+		t.Error("Expected a path to be required")
+	}
+	if _, err := Open("cassandra", "path"); err == nil {
+		t.Error("Expected unknown kinds to be rejected")
+	}
+}
@@ -0,0 +1,25 @@
+// Package timestamp formats times the way AWS's protocols send them.
+package timestamp
+
+import "time"
+
+// EpochSeconds returns the time as seconds since the Unix epoch, with millisecond precision, like
+// JSON protocol services' timestamps. The zero time, for times which haven't happened yet, is 0.
+func EpochSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixMilli()) / 1000
+}
+
+// ISO8601 returns the time in UTC as an ISO 8601 timestamp, with whole seconds, like REST-JSON
+// and XML protocol services' timestamps.
+func ISO8601(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ISO8601Milli is like ISO8601, with milliseconds, like the times in Step Functions' context
+// object.
+func ISO8601Milli(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
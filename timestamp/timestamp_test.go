@@ -0,0 +1,25 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEpochSeconds(t *testing.T) {
+	if s := EpochSeconds(time.UnixMilli(1700000000123)); s != 1700000000.123 {
+		t.Fatal("Unexpected seconds", s)
+	}
+	if s := EpochSeconds(time.Time{}); s != 0 {
+		t.Fatal("Unexpected seconds for the zero time", s)
+	}
+}
+
+func TestISO8601(t *testing.T) {
+	when := time.UnixMilli(1700000000123).In(time.FixedZone("UTC+2", 2*60*60))
+	if s := ISO8601(when); s != "2023-11-14T22:13:20Z" {
+		t.Fatal("Unexpected timestamp", s)
+	}
+	if s := ISO8601Milli(when); s != "2023-11-14T22:13:20.123Z" {
+		t.Fatal("Unexpected timestamp", s)
+	}
+}
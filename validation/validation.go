@@ -0,0 +1,383 @@
+// Package validation checks requests against constraints declared in their input structs' tags,
+// like AWS validates requests against its API models before they reach the service.
+//
+// The tags are:
+//   - required:"true": the field must be set.
+//   - length:"min,max": the length of a string, or the number of elements of a list or map. Either
+//     bound can be left out, such as length:"1,".
+//   - range:"min,max": the value of a number. Either bound can be left out.
+//   - pattern:"regexp": a string, or each string of a list, must fully match the regular expression.
+//   - enum:"A|B": a string, or each string of a list, must be one of the values.
+//   - error:"Type": the error type to return if the field is invalid, rather than ValidationException.
+//
+// Fields which are nil pointers or, if they aren't pointers, have the zero value, such as "" or 0,
+// aren't set, and only the required constraint applies to them. Structs, and lists and maps of structs, are validated too.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"aws-in-a-box/awserrors"
+)
+
+type bounds struct {
+	min, max       float64
+	hasMin, hasMax bool
+}
+
+type fieldRules struct {
+	index []int
+	// The field's name in messages, such as streamName.
+	name       string
+	required   bool
+	length     *bounds
+	valueRange *bounds
+	pattern    *regexp.Regexp
+	enum       []string
+	errorType  string
+	// The rules of the field's struct type, or of its elements' struct type.
+	elem *typeRules
+}
+
+func (f *fieldRules) hasConstraints() bool {
+	return f.required || f.length != nil || f.valueRange != nil || f.pattern != nil || f.enum != nil
+}
+
+type typeRules struct {
+	fields []*fieldRules
+	// Whether the type, or any type it contains, has constraints.
+	constrained bool
+	children    []*typeRules
+}
+
+// Validator checks values of one type.
+type Validator[T any] struct {
+	rules *typeRules
+}
+
+// compiled caches the rules of the types validators were created for, by type.
+var compiled sync.Map
+
+// New returns a validator for the type. It panics if the type's tags are invalid, so it should be
+// called when the handler using it is registered. The type's rules are cached, so later calls are
+// cheap.
+func New[T any]() *Validator[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if rules, ok := compiled.Load(t); ok {
+		return &Validator[T]{rules: rules.(*typeRules)}
+	}
+
+	cache := make(map[reflect.Type]*typeRules)
+	rules, err := compile(t, cache)
+	if err != nil {
+		panic(err)
+	}
+	prune(cache)
+	if rules != nil && !rules.constrained {
+		rules = nil
+	}
+	compiled.Store(t, rules)
+	return &Validator[T]{rules: rules}
+}
+
+// compile returns the rules of a struct type, or nil if it isn't a struct. The cache breaks cycles
+// between recursive types.
+func compile(t reflect.Type, cache map[reflect.Type]*typeRules) (*typeRules, error) {
+	t = elemType(t)
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	if rules, ok := cache[t]; ok {
+		return rules, nil
+	}
+	rules := &typeRules{}
+	cache[t] = rules
+
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		f, err := compileField(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", t, field.Name, err)
+		}
+		f.elem, err = compile(field.Type, cache)
+		if err != nil {
+			return nil, err
+		}
+		if f.elem != nil {
+			rules.children = append(rules.children, f.elem)
+		}
+		if f.hasConstraints() {
+			rules.constrained = true
+		}
+		if f.hasConstraints() || f.elem != nil {
+			rules.fields = append(rules.fields, f)
+		}
+	}
+	return rules, nil
+}
+
+// prune marks the types containing constrained types as constrained, and drops the fields of
+// unconstrained types, so validation doesn't walk values which can't be invalid.
+func prune(cache map[reflect.Type]*typeRules) {
+	for changed := true; changed; {
+		changed = false
+		for _, rules := range cache {
+			if rules.constrained {
+				continue
+			}
+			for _, child := range rules.children {
+				if child.constrained {
+					rules.constrained = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for _, rules := range cache {
+		var fields []*fieldRules
+		for _, f := range rules.fields {
+			if f.elem != nil && !f.elem.constrained {
+				f.elem = nil
+			}
+			if f.hasConstraints() || f.elem != nil {
+				fields = append(fields, f)
+			}
+		}
+		rules.fields = fields
+	}
+}
+
+// elemType returns the type of the values a field holds, through pointers, lists and maps.
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+func compileField(field reflect.StructField) (*fieldRules, error) {
+	f := &fieldRules{
+		index:     field.Index,
+		name:      memberName(field),
+		required:  field.Tag.Get("required") == "true",
+		errorType: field.Tag.Get("error"),
+	}
+	var err error
+	if tag, ok := field.Tag.Lookup("length"); ok {
+		if f.length, err = parseBounds(tag); err != nil {
+			return nil, fmt.Errorf("length: %v", err)
+		}
+	}
+	if tag, ok := field.Tag.Lookup("range"); ok {
+		if f.valueRange, err = parseBounds(tag); err != nil {
+			return nil, fmt.Errorf("range: %v", err)
+		}
+	}
+	if tag, ok := field.Tag.Lookup("pattern"); ok {
+		if f.pattern, err = regexp.Compile("^(?:" + tag + ")$"); err != nil {
+			return nil, fmt.Errorf("pattern: %v", err)
+		}
+	}
+	if tag, ok := field.Tag.Lookup("enum"); ok {
+		f.enum = strings.Split(tag, "|")
+	}
+	return f, nil
+}
+
+// memberName returns the field's name like AWS's messages have it: its JSON name, or its Go name,
+// starting with a lowercase letter.
+func memberName(field reflect.StructField) string {
+	name := field.Name
+	if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		name = tag
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+func parseBounds(tag string) (*bounds, error) {
+	minText, maxText, ok := strings.Cut(tag, ",")
+	if !ok {
+		return nil, fmt.Errorf("expected min,max, got %q", tag)
+	}
+	var b bounds
+	var err error
+	if minText != "" {
+		b.hasMin = true
+		if b.min, err = strconv.ParseFloat(minText, 64); err != nil {
+			return nil, err
+		}
+	}
+	if maxText != "" {
+		b.hasMax = true
+		if b.max, err = strconv.ParseFloat(maxText, 64); err != nil {
+			return nil, err
+		}
+	}
+	return &b, nil
+}
+
+type violation struct {
+	message   string
+	errorType string
+}
+
+// Validate checks the value against its type's constraints. The error lists every constraint
+// which isn't satisfied, like AWS's do.
+func (v *Validator[T]) Validate(value T) *awserrors.Error {
+	if v.rules == nil {
+		return nil
+	}
+	var violations []violation
+	validateStruct(v.rules, reflect.ValueOf(value), "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	errorType := "ValidationException"
+	var messages []string
+	for _, violation := range violations {
+		if violation.errorType != "" && errorType == "ValidationException" {
+			errorType = violation.errorType
+		}
+		messages = append(messages, violation.message)
+	}
+	plural := ""
+	if len(violations) > 1 {
+		plural = "s"
+	}
+	return awserrors.Generate400Exception(errorType, fmt.Sprintf("%d validation error%s detected: %s",
+		len(violations), plural, strings.Join(messages, "; ")))
+}
+
+func validateStruct(rules *typeRules, value reflect.Value, prefix string, violations *[]violation) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	for _, f := range rules.fields {
+		// Fields promoted through nil embedded pointers aren't set.
+		field, err := value.FieldByIndexErr(f.index)
+		if err != nil {
+			continue
+		}
+		validateField(f, field, prefix+f.name, violations)
+	}
+}
+
+func validateField(f *fieldRules, value reflect.Value, path string, violations *[]violation) {
+	fail := func(value any, constraint string) {
+		*violations = append(*violations, violation{
+			message: fmt.Sprintf("Value %s at '%s' failed to satisfy constraint: Member must %s",
+				formatValue(value), path, constraint),
+			errorType: f.errorType,
+		})
+	}
+
+	// Pointers are set unless they're nil, so their zero values are validated.
+	set := !value.IsZero()
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if !set {
+		if f.required {
+			fail(nil, "not be null")
+		}
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		s := value.String()
+		checkBounds(f.length, float64(utf8.RuneCountInString(s)), func(constraint string) {
+			fail(s, "have length "+constraint)
+		})
+		checkString(f, s, fail)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		checkBounds(f.length, float64(value.Len()), func(constraint string) {
+			fail(value.Interface(), "have length "+constraint)
+		})
+		if value.Kind() == reflect.Map {
+			if f.elem != nil {
+				iter := value.MapRange()
+				for iter.Next() {
+					validateStruct(f.elem, iter.Value(), fmt.Sprintf("%s.%v.", path, iter.Key()), violations)
+				}
+			}
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			elem := value.Index(i)
+			if f.elem != nil {
+				validateStruct(f.elem, elem, fmt.Sprintf("%s.%d.member.", path, i+1), violations)
+			} else if elem.Kind() == reflect.String {
+				checkString(f, elem.String(), fail)
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := value.Int()
+		checkBounds(f.valueRange, float64(n), func(constraint string) {
+			fail(n, "have value "+constraint)
+		})
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := value.Uint()
+		checkBounds(f.valueRange, float64(n), func(constraint string) {
+			fail(n, "have value "+constraint)
+		})
+	case reflect.Float32, reflect.Float64:
+		n := value.Float()
+		checkBounds(f.valueRange, n, func(constraint string) {
+			fail(n, "have value "+constraint)
+		})
+	case reflect.Struct:
+		if f.elem != nil {
+			validateStruct(f.elem, value, path+".", violations)
+		}
+	}
+}
+
+func checkBounds(b *bounds, n float64, fail func(constraint string)) {
+	if b == nil {
+		return
+	}
+	if b.hasMin && n < b.min {
+		fail("greater than or equal to " + strconv.FormatFloat(b.min, 'f', -1, 64))
+	}
+	if b.hasMax && n > b.max {
+		fail("less than or equal to " + strconv.FormatFloat(b.max, 'f', -1, 64))
+	}
+}
+
+func checkString(f *fieldRules, s string, fail func(value any, constraint string)) {
+	if f.pattern != nil && !f.pattern.MatchString(s) {
+		pattern := strings.TrimSuffix(strings.TrimPrefix(f.pattern.String(), "^(?:"), ")$")
+		fail(s, "satisfy regular expression pattern: "+pattern)
+	}
+	if f.enum != nil && !slices.Contains(f.enum, s) {
+		fail(s, "satisfy enum value set: ["+strings.Join(f.enum, ", ")+"]")
+	}
+}
+
+func formatValue(value any) string {
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("'%v'", value)
+}
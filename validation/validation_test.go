@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+type tag struct {
+	Key   string `required:"true" length:"1,128"`
+	Value string `length:",256"`
+}
+
+type input struct {
+	StreamName string   `required:"true" length:"1,128" pattern:"[a-zA-Z0-9_.-]+"`
+	ShardCount *int32   `range:"1,"`
+	Limit      int      `range:"1,10000"`
+	Type       string   `json:"type" enum:"AT_SEQUENCE_NUMBER|LATEST"`
+	TagKeys    []string `length:",50" pattern:"[a-z]+"`
+	Tags       []tag
+	Nested     *input
+}
+
+// Types without constraints aren't walked, even if they're recursive.
+type unconstrained struct {
+	Values []unconstrained
+}
+
+func TestValid(t *testing.T) {
+	count := int32(1)
+	valid := input{
+		StreamName: "stream.1",
+		ShardCount: &count,
+		Type:       "LATEST",
+		TagKeys:    []string{"a", "b"},
+		Tags:       []tag{{Key: "k"}},
+		Nested:     &input{StreamName: "nested"},
+	}
+	if awserr := New[input]().Validate(valid); awserr != nil {
+		t.Fatal(awserr.Body.Message)
+	}
+	if v := New[unconstrained](); v.rules != nil {
+		t.Fatal("Expected no rules")
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	count := int32(0)
+	tests := map[string]struct {
+		input input
+		want  []string
+	}{
+		"required": {
+			input{},
+			[]string{"1 validation error detected: Value null at 'streamName' failed to satisfy constraint: Member must not be null"},
+		},
+		"pattern and length": {
+			input{StreamName: "stream name" + strings.Repeat("a", 128)},
+			[]string{
+				"2 validation errors detected",
+				"at 'streamName' failed to satisfy constraint: Member must have length less than or equal to 128",
+				"at 'streamName' failed to satisfy constraint: Member must satisfy regular expression pattern: [a-zA-Z0-9_.-]+",
+			},
+		},
+		"range": {
+			input{StreamName: "s", ShardCount: &count, Limit: 10001},
+			[]string{
+				"Value '0' at 'shardCount' failed to satisfy constraint: Member must have value greater than or equal to 1",
+				"Value '10001' at 'limit' failed to satisfy constraint: Member must have value less than or equal to 10000",
+			},
+		},
+		"enum": {
+			input{StreamName: "s", Type: "OLDEST"},
+			[]string{"Value 'OLDEST' at 'type' failed to satisfy constraint: Member must satisfy enum value set: [AT_SEQUENCE_NUMBER, LATEST]"},
+		},
+		"list elements": {
+			input{StreamName: "s", TagKeys: []string{"a", "B"}},
+			[]string{"Value 'B' at 'tagKeys' failed to satisfy constraint: Member must satisfy regular expression pattern: [a-z]+"},
+		},
+		"nested": {
+			input{StreamName: "s", Tags: []tag{{Key: "k"}, {Value: "v"}}, Nested: &input{StreamName: "a b"}},
+			[]string{
+				"Value null at 'tags.2.member.key' failed to satisfy constraint",
+				"Value 'a b' at 'nested.streamName' failed to satisfy constraint",
+			},
+		},
+	}
+	v := New[input]()
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			awserr := v.Validate(test.input)
+			if awserr == nil {
+				t.Fatal("Expected an error")
+			}
+			if awserr.Body.Type != "ValidationException" {
+				t.Fatalf("Unexpected type %s", awserr.Body.Type)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(awserr.Body.Message, want) {
+					t.Fatalf("Expected %q to contain %q", awserr.Body.Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	type createBucketInput struct {
+		Bucket string `length:"3,63" error:"InvalidBucketName"`
+	}
+	awserr := New[createBucketInput]().Validate(createBucketInput{Bucket: "b"})
+	if awserr == nil || awserr.Body.Type != "InvalidBucketName" {
+		t.Fatalf("Expected an InvalidBucketName error, got %+v", awserr)
+	}
+}
+
+func TestInvalidTags(t *testing.T) {
+	type badInput struct {
+		Name string `pattern:"[a-z"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic")
+		}
+	}()
+	New[badInput]()
+}
@@ -0,0 +1,181 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// This package parses YAML documents into the same types as encoding/json decodes into an any, so
+// configuration files and templates can be written in either. Only single documents are supported.
+
+// TagFunc converts a tagged value, such as CloudFormation's !Ref, to its value.
+type TagFunc func(tag string, value any) (any, error)
+
+// maxNodes bounds the nodes a document expands to through aliases, so a small document can't
+// expand to an enormous one.
+const maxNodes = 1 << 20
+
+// Parse parses a YAML document. Tagged values are converted with applyTag, and are rejected if it's nil.
+func Parse(body string, applyTag TagFunc) (any, error) {
+	if applyTag == nil {
+		applyTag = func(tag string, value any) (any, error) {
+			return nil, fmt.Errorf("unsupported tag %s", tag)
+		}
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(body))
+	var document yaml.Node
+	if err := decoder.Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("line %d: multiple documents aren't supported", next.Line)
+	}
+	c := &converter{applyTag: applyTag}
+	return c.convert(&document)
+}
+
+type converter struct {
+	applyTag TagFunc
+	nodes    int
+}
+
+func (c *converter) errorf(node *yaml.Node, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", node.Line, fmt.Sprintf(format, args...))
+}
+
+func (c *converter) convert(node *yaml.Node) (any, error) {
+	c.nodes++
+	if c.nodes > maxNodes {
+		return nil, c.errorf(node, "document expands to more than %d nodes", maxNodes)
+	}
+
+	var value any
+	var err error
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return c.convert(node.Content[0])
+	case yaml.AliasNode:
+		return c.convert(node.Alias)
+	case yaml.MappingNode:
+		value, err = c.convertMapping(node)
+	case yaml.SequenceNode:
+		items := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			converted, err := c.convert(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, converted)
+		}
+		value = items
+	case yaml.ScalarNode:
+		value, err = c.convertScalar(node)
+	default:
+		return nil, c.errorf(node, "unexpected node")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tag := node.ShortTag(); !strings.HasPrefix(tag, "!!") {
+		value, err = c.applyTag(tag, value)
+		if err != nil {
+			return nil, c.errorf(node, "%s", err)
+		}
+	}
+	return value, nil
+}
+
+func (c *converter) convertMapping(node *yaml.Node) (map[string]any, error) {
+	mapping := make(map[string]any, len(node.Content)/2)
+	defined := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		for keyNode.Kind == yaml.AliasNode {
+			keyNode = keyNode.Alias
+		}
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, c.errorf(keyNode, "keys must be scalars")
+		}
+		if keyNode.ShortTag() == "!!merge" {
+			if err := c.merge(mapping, valueNode); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := keyNode.Value
+		if keyNode.ShortTag() == "!!null" {
+			key = "null"
+		}
+		if defined[key] {
+			return nil, c.errorf(keyNode, "mapping key %q already defined", key)
+		}
+		defined[key] = true
+		value, err := c.convert(valueNode)
+		if err != nil {
+			return nil, err
+		}
+		mapping[key] = value
+	}
+	return mapping, nil
+}
+
+// merge adds the entries of a merge key's (<<) mappings which the mapping doesn't have yet.
+func (c *converter) merge(mapping map[string]any, node *yaml.Node) error {
+	sources := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	}
+	for _, source := range sources {
+		converted, err := c.convert(source)
+		if err != nil {
+			return err
+		}
+		merged, ok := converted.(map[string]any)
+		if !ok {
+			return c.errorf(source, "merge keys can only merge mappings")
+		}
+		for key, value := range merged {
+			if _, ok := mapping[key]; !ok {
+				mapping[key] = value
+			}
+		}
+	}
+	return nil
+}
+
+// convertScalar converts a scalar to a string, float64, bool or nil. Scalars with tags other than
+// YAML's own are strings.
+func (c *converter) convertScalar(node *yaml.Node) (any, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case "!!int", "!!float":
+		var value float64
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return node.Value, nil
+}
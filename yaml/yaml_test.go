@@ -0,0 +1,190 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	document, err := Parse(`
+buckets:
+  - name: assets # A comment
+    objects:
+      config.json: '{"debug": true}'
+  - name: "logs"
+streams: [{name: orders, shards: 4}]
+`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"buckets": []any{
+			map[string]any{
+				"name":    "assets",
+				"objects": map[string]any{"config.json": `{"debug": true}`},
+			},
+			map[string]any{"name": "logs"},
+		},
+		"streams": []any{map[string]any{"name": "orders", "shards": float64(4)}},
+	}
+	if !reflect.DeepEqual(document, want) {
+		t.Errorf("got %#v, want %#v", document, want)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		document string
+		want     any
+	}{
+		{"empty", "", nil},
+		{"only comments", "# Nothing here\n", nil},
+		{"document markers", "---\na: 1\n...\n", map[string]any{"a": float64(1)}},
+		{"integer", "a: 42", map[string]any{"a": float64(42)}},
+		{"negative", "a: -7", map[string]any{"a": float64(-7)}},
+		{"float", "a: 1.5", map[string]any{"a": 1.5}},
+		{"exponent", "c: 1e3", map[string]any{"c": float64(1000)}},
+		{"signed exponent", "c: -2.5E-2", map[string]any{"c": -0.025}},
+		{"hex", "a: 0x1F", map[string]any{"a": float64(31)}},
+		{"octal", "a: 0o17", map[string]any{"a": float64(15)}},
+		{"infinity", "a: .inf", map[string]any{"a": math.Inf(1)}},
+		{"booleans", "a: true\nb: false", map[string]any{"a": true, "b": false}},
+		{"yes is a string", "a: yes", map[string]any{"a": "yes"}},
+		{"null", "a: null\nb: ~\nc:", map[string]any{"a": nil, "b": nil, "c": nil}},
+		{"quoted number", `a: "1e3"`, map[string]any{"a": "1e3"}},
+		{"single quoted", "a: 'it''s'", map[string]any{"a": "it's"}},
+		{"double quoted escapes", `a: "tab\there\n"`, map[string]any{"a": "tab\there\n"}},
+		{"explicit string tag", "a: !!str 123", map[string]any{"a": "123"}},
+		{"explicit float tag", "a: !!float 1", map[string]any{"a": float64(1)}},
+		{"date is a string", "a: 2010-09-09", map[string]any{"a": "2010-09-09"}},
+		{"version is a string", "a: 1.2.3", map[string]any{"a": "1.2.3"}},
+		{"plain with colon", "a: http://example.com", map[string]any{"a": "http://example.com"}},
+		{"plain with hash", "a: b#c", map[string]any{"a": "b#c"}},
+		{"numeric key", "1: a", map[string]any{"1": "a"}},
+		{"multiline plain", "a: one\n  two\n  three", map[string]any{"a": "one two three"}},
+		{"literal block", "a: |\n  one\n  two\nb: 1", map[string]any{"a": "one\ntwo\n", "b": float64(1)}},
+		{"literal strip", "a: |-\n  one\n  two\n", map[string]any{"a": "one\ntwo"}},
+		{"literal keep", "a: |+\n  one\n\n", map[string]any{"a": "one\n\n"}},
+		{"folded block", "a: >\n  one\n  two\n\n  three\n", map[string]any{"a": "one two\nthree\n"}},
+		{"block sequence", "- a\n- b", []any{"a", "b"}},
+		{"nested sequences", "- - a\n  - b\n- - c", []any{[]any{"a", "b"}, []any{"c"}}},
+		{"sequence in mapping without indent", "a:\n- 1\n- 2\nb: 3", map[string]any{"a": []any{float64(1), float64(2)}, "b": float64(3)}},
+		{"mapping in sequence", "- a: 1\n  b: 2\n- c: 3", []any{map[string]any{"a": float64(1), "b": float64(2)}, map[string]any{"c": float64(3)}}},
+		{"flow sequence", "a: [1, 'two', \"three\", [4]]", map[string]any{"a": []any{float64(1), "two", "three", []any{float64(4)}}}},
+		{"flow mapping", "a: {b: 1, c: [d]}", map[string]any{"a": map[string]any{"b": float64(1), "c": []any{"d"}}}},
+		{"multiline flow", "a: [\n  1,\n  2,\n]", map[string]any{"a": []any{float64(1), float64(2)}}},
+		{"empty flow", "a: []\nb: {}", map[string]any{"a": []any{}, "b": map[string]any{}}},
+		{"anchor and alias", "a: &x {b: 1}\nc: *x", map[string]any{"a": map[string]any{"b": float64(1)}, "c": map[string]any{"b": float64(1)}}},
+		{"merge key", "base: &b {x: 1, y: 2}\nderived:\n  <<: *b\n  y: 3", map[string]any{
+			"base":    map[string]any{"x": float64(1), "y": float64(2)},
+			"derived": map[string]any{"x": float64(1), "y": float64(3)},
+		}},
+		{"crlf", "a: 1\r\nb: 2\r\n", map[string]any{"a": float64(1), "b": float64(2)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.document, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		document string
+	}{
+		{"nested mapping on one line", "a: b: c"},
+		{"nested mapping in sequence", "- a: b: c"},
+		{"bad indentation", "a:\n  b: 1\n c: 2"},
+		{"tab indentation", "a:\n\tb: 1"},
+		{"unclosed flow sequence", "a: [1, 2"},
+		{"unclosed flow mapping", "a: {b: 1"},
+		{"unclosed quote", "a: 'b"},
+		{"duplicate key", "a: 1\na: 2"},
+		{"mapping key", "? [a]\n: 1"},
+		{"undefined alias", "a: *x"},
+		{"multiple documents", "a: 1\n---\nb: 2"},
+		{"bad escape", `a: "\q"`},
+		{"sequence after mapping", "a: 1\n- b"},
+		{"merge of scalar", "a:\n  <<: 1"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := Parse(test.document, nil); err == nil {
+				t.Fatalf("expected an error, got %#v", got)
+			}
+		})
+	}
+}
+
+func TestParseRejectsTagsWithoutTagFunc(t *testing.T) {
+	if _, err := Parse("key: !Ref Value", nil); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	applyTag := func(tag string, value any) (any, error) {
+		if tag == "!Bad" {
+			return nil, fmt.Errorf("bad tag")
+		}
+		return map[string]any{strings.TrimPrefix(tag, "!"): value}, nil
+	}
+	document, err := Parse(`
+a: !Ref Bucket
+b: !Join [",", [x, !Ref y]]
+c: !If
+  - Cond
+  - 1
+d: !Sub
+  Key: value
+e: !GetAZs ''
+f: !Tag 1e3
+`, applyTag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"a": map[string]any{"Ref": "Bucket"},
+		"b": map[string]any{"Join": []any{",", []any{"x", map[string]any{"Ref": "y"}}}},
+		"c": map[string]any{"If": []any{"Cond", float64(1)}},
+		"d": map[string]any{"Sub": map[string]any{"Key": "value"}},
+		"e": map[string]any{"GetAZs": ""},
+		// Tagged scalars aren't resolved, so are strings.
+		"f": map[string]any{"Tag": "1e3"},
+	}
+	if !reflect.DeepEqual(document, want) {
+		t.Errorf("got %#v, want %#v", document, want)
+	}
+
+	_, err = Parse("a:\n  b: !Bad x", applyTag)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestParseLimitsAliasExpansion(t *testing.T) {
+	var document strings.Builder
+	document.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i < 10; i++ {
+		fmt.Fprintf(&document, "a%d: &a%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				document.WriteString(", ")
+			}
+			fmt.Fprintf(&document, "*a%d", i-1)
+		}
+		document.WriteString("]\n")
+	}
+	if _, err := Parse(document.String(), nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}